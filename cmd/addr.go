@@ -0,0 +1,37 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/inspect"
+)
+
+// AddrCommand contains the addr sub-command configuration.
+var AddrCommand = &cli.Command{
+	Name:  "addr",
+	Usage: "inspect multiaddrs with the same parsing code that the crawlers use",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "parse",
+			Usage:     "parse a multiaddr, printing its components, IP family, port and peer ID as JSON",
+			ArgsUsage: "<multiaddr>",
+			Action:    LaunchAddrParse,
+		},
+	},
+}
+
+// LaunchAddrParse is the function that is called when running `addr parse`.
+func LaunchAddrParse(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("addr parse needs the multiaddr to parse")
+	}
+	info, err := inspect.ParseAddr(c.Args().First())
+	if err != nil {
+		return err
+	}
+	return printJSON(info)
+}
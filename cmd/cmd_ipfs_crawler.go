@@ -3,39 +3,559 @@ Copyright © 2021 Miga Labs
 */
 package cmd
 
-// // CrawlCommand contains the crawl sub-command configuration.
-// var IpfsCrawlerCommand = &cli.Command{
-// 	Name:   "ipfs",
-// 	Usage:  "crawl the ipfs network with the given configuration in the conf-file",
-// 	Action: LaunchIpfsCrawler,
-// 	Flags: []cli.Flag{
-// 		&cli.StringFlag{
-// 			Name:        "config-file",
-// 			Usage:       "path to the <config.json> file used to configure the crawler",
-// 			EnvVars:     []string{"ARMIARMA_CONFIG_FILE_NAME"},
-// 			DefaultText: info.DefaultIpfsConfigFile,
-// 			Value:       info.DefaultIpfsConfigFile,
-// 		},
-// 	},
-// }
-
-// // CrawlAction is the function that is called when running `ipfs`.
-// func LaunchIpfsCrawler(c *cli.Context) error {
-// 	log.Infoln("Starting IPFS crawler...")
-
-// 	// Load configuration file
-// 	infObj, err := info.InitIpfs(c)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	// Generate the Eth2 crawler struct
-// 	ipfsc, err := crawler.NewIpfsCrawler(c, infObj)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	// launch the subroutines
-// 	ipfsc.Run()
-// 	return nil
-// }
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/crawler"
+)
+
+// IpfsCrawlerCommand contains the ipfs sub-command configuration.
+var IpfsCrawlerCommand = &cli.Command{
+	Name:   "ipfs",
+	Usage:  "crawl an IPFS-like network (IPFS or Filecoin) through its Kademlia DHT",
+	Action: LaunchIpfsCrawler,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config-file",
+			Usage:   "Path to a YAML, TOML or JSON file with the settings of the crawler, keyed by the flag names (the flags take precedence)",
+			EnvVars: []string{"ARMIARMA_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Verbosity level for the Crawler's logs",
+			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+			DefaultText: config.DefaultLogLevel,
+		},
+		&cli.StringFlag{
+			Name:    "log-levels",
+			Usage:   "Verbosity level of specific modules (host, discovery, gossip, db, peering) over the global one, i.e. host=debug,db=warn",
+			EnvVars: []string{"ARMIARMA_LOG_LEVELS"},
+		},
+		&cli.StringFlag{
+			Name:    "priv-key",
+			Usage:   "String representation of the PrivateKey to be used by the crawler",
+			EnvVars: []string{"ARMIARMA_PRIV_KEY"},
+		},
+		&cli.StringFlag{
+			Name:        "ip",
+			Usage:       "IP in the machine that we want to asign to the crawler",
+			EnvVars:     []string{"ARMIARMA_IP"},
+			DefaultText: config.DefaultIP,
+		},
+		&cli.StringFlag{
+			Name:        "ip6",
+			Usage:       "IPv6 in the machine that we want to asign to the crawler (only used with ip-family v6 or both)",
+			EnvVars:     []string{"ARMIARMA_IP6"},
+			DefaultText: config.DefaultIP6,
+		},
+		&cli.StringFlag{
+			Name:        "ip-family",
+			Usage:       "IP family that the crawler will listen on and prefer to dial (v4, v6, both)",
+			EnvVars:     []string{"ARMIARMA_IP_FAMILY"},
+			DefaultText: config.DefaultIPFamily,
+		},
+		&cli.IntFlag{
+			Name:        "port",
+			Usage:       "TCP port that the crawler with advertise to establish connections",
+			EnvVars:     []string{"ARMIARMA_PORT"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultPort),
+		},
+		&cli.StringFlag{
+			Name:        "metrics-ip",
+			Usage:       "IP in the machine that will expose the metrics of the crawler",
+			EnvVars:     []string{"ARMIARMA_METRICS_IP"},
+			DefaultText: config.DefaultMetricsIP,
+		},
+		&cli.IntFlag{
+			Name:        "metrics-port",
+			Usage:       "Port that the crawler with to expose pprof and prometheus metrics",
+			EnvVars:     []string{"ARMIARMA_METRICS_PORT"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMetricsPort),
+		},
+		&cli.StringFlag{
+			Name:        "user-agent",
+			Usage:       "Agent name that will identify the crawler in the network",
+			EnvVars:     []string{"ARMIARMA_USER_AGENT"},
+			DefaultText: config.DefaultUserAgent,
+		},
+		&cli.StringFlag{
+			Name:        "psql-endpoint",
+			Aliases:     []string{"db"},
+			Usage:       "PSQL enpoint where the crwaler will submit the all the gathered info, or sqlite:<file> to store it in a SQLite file",
+			EnvVars:     []string{"ARMIARMA_PSQL"},
+			DefaultText: config.DefaultPSQLEndpoint,
+		},
+		&cli.StringFlag{
+			Name:        "peers-backup",
+			Usage:       "Time interval that will be use to backup the peer_ids into a single table - allowing to recontruct the network in past-crawled times",
+			EnvVars:     []string{"ARMIARMA_BACKUP_INTERVAL"},
+			DefaultText: config.DefaultActivePeersBackupInterval,
+		},
+		&cli.StringFlag{
+			Name:        "peers-history",
+			Usage:       "Time interval between the snapshots of the active peers (client, version, country, subnets and connectivity) appended to the peers_history table (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_PEERS_HISTORY"},
+			DefaultText: config.DefaultPeersHistoryInterval,
+		},
+		&cli.StringFlag{
+			Name:        "diversity-interval",
+			Usage:       "Time interval between the refreshes of the client, country, asn and attnet diversity gauges (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_DIVERSITY_INTERVAL"},
+			DefaultText: config.DefaultDiversityInterval,
+		},
+		&cli.StringFlag{
+			Name:    "geoip-city-db",
+			Usage:   "Path to a GeoLite2 City database (mmdb) to locate the IPs offline, falling back to the IP-API only for the IPs it misses",
+			EnvVars: []string{"ARMIARMA_GEOIP_CITY_DB"},
+		},
+		&cli.StringFlag{
+			Name:    "geoip-asn-db",
+			Usage:   "Path to a GeoLite2 ASN database (mmdb) to enrich the IPs located offline with their autonomous system",
+			EnvVars: []string{"ARMIARMA_GEOIP_ASN_DB"},
+		},
+		&cli.StringFlag{
+			Name:        "ip-cache-ttl",
+			Usage:       "Time that the located IPs are cached in the DB before locating them again",
+			EnvVars:     []string{"ARMIARMA_IP_CACHE_TTL"},
+			DefaultText: config.DefaultIpCacheTTL,
+		},
+		&cli.StringFlag{
+			Name:        "ip-refresh-interval",
+			Usage:       "Time interval between the background refreshes of the expired IPs of the cache (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_IP_REFRESH_INTERVAL"},
+			DefaultText: config.DefaultIpRefreshInterval,
+		},
+		&cli.BoolFlag{
+			Name:    "rdns-lookup",
+			Usage:   "Resolve the reverse DNS (PTR) name of the located IPs, also used to tag their cloud provider",
+			EnvVars: []string{"ARMIARMA_RDNS_LOOKUP"},
+		},
+		&cli.BoolFlag{
+			Name:    "cloud-tagging",
+			Usage:   "Tag the located IPs with the cloud provider hosting them (AWS, GCP, Azure, OVH, Hetzner), downloading the ranges published by AWS and GCP",
+			EnvVars: []string{"ARMIARMA_CLOUD_TAGGING"},
+		},
+		&cli.StringFlag{
+			Name:    "cloud-ranges-file",
+			Usage:   "Path to a file with extra cloud ranges to tag the IPs with, one '<provider> <cidr>' per line",
+			EnvVars: []string{"ARMIARMA_CLOUD_RANGES_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:    "tor-exit-list",
+			Usage:   "Flag the located IPs that are Tor exit nodes, downloading the exit list of the Tor project periodically",
+			EnvVars: []string{"ARMIARMA_TOR_EXIT_LIST"},
+		},
+		&cli.StringFlag{
+			Name:    "vpn-list-file",
+			Usage:   "Path to a file with the IPs or CIDRs of known VPN endpoints (one per line) to flag the located IPs with",
+			EnvVars: []string{"ARMIARMA_VPN_LIST_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "resource-usage-interval",
+			Usage:       "Time interval to record the resources (CPU, memory, bandwidth, connections) used by the crawler in the run metadata",
+			EnvVars:     []string{"ARMIARMA_RESOURCE_USAGE_INTERVAL"},
+			DefaultText: config.DefaultResourceUsageInterval,
+		},
+		&cli.StringFlag{
+			Name:        "network",
+			Usage:       "IPFS-like network that we want to crawl (ipfs, filecoin, waku)",
+			EnvVars:     []string{"ARMIARMA_NETWORK"},
+			DefaultText: config.DefaultIpfsNetwork,
+		},
+		&cli.StringSliceFlag{
+			Name:    "bootnode",
+			Usage:   "List of boondes (multiaddresses, or ENRs for waku) that the crawler will use to discover more peers in the network (One --bootnode <bootnode> per bootnode)",
+			EnvVars: []string{"ARMIARMA_BOOTNODES"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "discovery-source",
+			Usage:   "Discovery sources that run concurrently, tagging in the DB the peers that each of them finds: dht (default), dv5 (default and only network source of waku), static, db (One --discovery-source <source> per source)",
+			EnvVars: []string{"ARMIARMA_DISCOVERY_SOURCES"},
+		},
+		&cli.StringFlag{
+			Name:    "discovery-file",
+			Usage:   "JSON file with the multiaddrs of the peers notified by the static discovery source (same format than the bootnode files)",
+			EnvVars: []string{"ARMIARMA_DISCOVERY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "redial-interval",
+			Usage:       "Interval at which the db discovery source queues the deprecated peers to be re-dialed",
+			EnvVars:     []string{"ARMIARMA_REDIAL_INTERVAL"},
+			DefaultText: config.DefaultRedialInterval,
+		},
+		&cli.BoolFlag{
+			Name:    "persist-connevents",
+			Usage:   "Decide whether we want to track the connection-events into the DB (Disk intense)",
+			EnvVars: []string{"ARMIARMA_PERSIST_CONNEVENTS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "security",
+			Usage:   "List of security protocols (noise, tls) that the crawler will negotiate, ordered by preference (One --security <protocol> per protocol)",
+			EnvVars: []string{"ARMIARMA_SECURITY"},
+		},
+		&cli.BoolFlag{
+			Name:    "signed-peer-record",
+			Usage:   "Decide whether the crawler shares its own signed peer record through identify (disabled by default)",
+			EnvVars: []string{"ARMIARMA_SIGNED_PEER_RECORD"},
+		},
+		&cli.BoolFlag{
+			Name:    "observed-addrs",
+			Usage:   "Decide whether the crawler advertises the addresses that remote peers observed for it (enabled by default)",
+			EnvVars: []string{"ARMIARMA_OBSERVED_ADDRS"},
+		},
+		&cli.BoolFlag{
+			Name:    "collect-peer-records",
+			Usage:   "Request and store the signed peer records (envelope, sequence number, addresses) of the remote peers (disabled by default)",
+			EnvVars: []string{"ARMIARMA_COLLECT_PEER_RECORDS"},
+		},
+		&cli.BoolFlag{
+			Name:    "negotiation-timing",
+			Usage:   "Time the multistream-select negotiation of each protocol shared with the peers, recording how long it takes and which ones fail (disabled by default)",
+			EnvVars: []string{"ARMIARMA_NEGOTIATION_TIMING"},
+		},
+		&cli.Float64Flag{
+			Name:        "eclipse-threshold",
+			Usage:       "Share of the connected peers (0-1] gathered by a single client, country, or ASN that raises an eclipse alert",
+			EnvVars:     []string{"ARMIARMA_ECLIPSE_THRESHOLD"},
+			DefaultText: fmt.Sprintf("%.2f", config.DefaultEclipseThreshold),
+		},
+		&cli.IntFlag{
+			Name:        "eclipse-min-peers",
+			Usage:       "Minimum number of peers in a set to raise eclipse alerts",
+			EnvVars:     []string{"ARMIARMA_ECLIPSE_MIN_PEERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultEclipseMinPeers),
+		},
+		&cli.StringSliceFlag{
+			Name:    "static-peer",
+			Usage:   "Multiaddress (including /p2p/<peer_id>) of a trusted peer (i.e. our own nodes) that the crawler will always keep connected (One --static-peer <multiaddr> per peer)",
+			EnvVars: []string{"ARMIARMA_STATIC_PEERS"},
+		},
+		&cli.BoolFlag{
+			Name:    "static-peers-in-stats",
+			Usage:   "Decide whether the static peers are included in the crawl statistics (excluded by default)",
+			EnvVars: []string{"ARMIARMA_STATIC_PEERS_IN_STATS"},
+		},
+		&cli.IntFlag{
+			Name:        "max-conns",
+			Usage:       "Maximum number of connections that the crawler will keep open (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_CONNS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxConns),
+		},
+		&cli.IntFlag{
+			Name:        "max-conns-per-peer",
+			Usage:       "Maximum number of connections that the crawler will keep open with a single peer (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_CONNS_PER_PEER"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxConnsPerPeer),
+		},
+		&cli.IntFlag{
+			Name:        "max-streams-per-peer",
+			Usage:       "Maximum number of streams that the crawler will keep open with a single peer (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_STREAMS_PER_PEER"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxStreamsPerPeer),
+		},
+		&cli.IntFlag{
+			Name:        "max-streams-per-protocol",
+			Usage:       "Maximum number of streams that the crawler will keep open for a single protocol (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_STREAMS_PER_PROTOCOL"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxStreamsPerProtocol),
+		},
+		&cli.StringFlag{
+			Name:    "rcmgr-limits",
+			Usage:   "JSON file with the limits of the libp2p resource manager (go-libp2p format), applied over the max-* flags",
+			EnvVars: []string{"ARMIARMA_RCMGR_LIMITS"},
+		},
+		&cli.StringFlag{
+			Name:    "blocklist",
+			Usage:   "File with the CIDRs, IPs, ASNs (AS1234), and peer IDs that the crawler won't connect to (one entry per line)",
+			EnvVars: []string{"ARMIARMA_BLOCKLIST"},
+		},
+		&cli.BoolFlag{
+			Name:    "blocklist-db",
+			Usage:   "Decide whether the crawler also loads the blocklist entries from the blocklist table of the DB",
+			EnvVars: []string{"ARMIARMA_BLOCKLIST_DB"},
+		},
+		&cli.StringFlag{
+			Name:    "targets",
+			Usage:   "File with the only peers that the crawler dials, ignoring the discovery (ENRs, multiaddrs with /p2p/, or peer IDs, one per line)",
+			EnvVars: []string{"ARMIARMA_TARGETS"},
+		},
+		&cli.BoolFlag{
+			Name:    "targets-db",
+			Usage:   "Decide whether the crawler only dials the peers of the target_peers table of the DB (together with the ones of --targets), ignoring the discovery",
+			EnvVars: []string{"ARMIARMA_TARGETS_DB"},
+		},
+		&cli.BoolFlag{
+			Name:    "soak",
+			Usage:   "Run in soak mode for unattended long-running deployments (observer mode, retention, daily rollups, watchdog, and weekly summaries)",
+			EnvVars: []string{"ARMIARMA_SOAK"},
+		},
+		&cli.BoolFlag{
+			Name:    "observer",
+			Usage:   "Decide whether the crawler only observes the network (no active dialing of the discovered peers)",
+			EnvVars: []string{"ARMIARMA_OBSERVER"},
+		},
+		&cli.StringFlag{
+			Name:        "soak-retention",
+			Usage:       "Age over which the time-series rows (connection events, backups, gossip messages) are deleted in soak mode",
+			EnvVars:     []string{"ARMIARMA_SOAK_RETENTION"},
+			DefaultText: config.DefaultSoakRetention,
+		},
+		&cli.StringFlag{
+			Name:        "soak-export-dir",
+			Usage:       "Folder where the weekly summaries are exported in soak mode",
+			EnvVars:     []string{"ARMIARMA_SOAK_EXPORT_DIR"},
+			DefaultText: config.DefaultSoakExportDir,
+		},
+		&cli.StringFlag{
+			Name:        "watchdog-timeout",
+			Usage:       "Time without open connections after which the crawler is restarted in soak mode",
+			EnvVars:     []string{"ARMIARMA_WATCHDOG_TIMEOUT"},
+			DefaultText: config.DefaultWatchdogTimeout,
+		},
+		&cli.StringFlag{
+			Name:    "key-store",
+			Usage:   "Where the identity of the host is persisted between restarts (\"postgres\" or path of a key file), ephemeral if empty",
+			EnvVars: []string{"ARMIARMA_KEY_STORE"},
+		},
+		&cli.StringFlag{
+			Name:        "key-rotation",
+			Usage:       "Age after which the persisted identity of the host is rotated, restarting the crawler (0s never rotates it)",
+			EnvVars:     []string{"ARMIARMA_KEY_ROTATION"},
+			DefaultText: config.DefaultKeyRotation,
+		},
+		&cli.IntFlag{
+			Name:        "hosts",
+			Usage:       "Number of libp2p hosts (consecutive ports, own identities) that share the dials of the crawler",
+			EnvVars:     []string{"ARMIARMA_HOSTS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultHosts),
+		},
+		&cli.IntFlag{
+			Name:        "event-queue-size",
+			Usage:       "Max number of connection/identification events buffered by each host before dropping new ones",
+			EnvVars:     []string{"ARMIARMA_EVENT_QUEUE_SIZE"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultEventQueueSize),
+		},
+		&cli.StringFlag{
+			Name:    "influx-url",
+			Usage:   "URL of the InfluxDB (or Telegraf listener) where the summaries of each round of metrics are exported (disabled if empty)",
+			EnvVars: []string{"ARMIARMA_INFLUX_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "influx-org",
+			Usage:   "InfluxDB organization (uses the v2 write API if set, the v1 one otherwise)",
+			EnvVars: []string{"ARMIARMA_INFLUX_ORG"},
+		},
+		&cli.StringFlag{
+			Name:        "influx-bucket",
+			Usage:       "InfluxDB bucket (v2) or database (v1) where the summaries are written",
+			EnvVars:     []string{"ARMIARMA_INFLUX_BUCKET"},
+			DefaultText: config.DefaultInfluxBucket,
+		},
+		&cli.StringFlag{
+			Name:    "influx-token",
+			Usage:   "Token to authenticate the writes into InfluxDB",
+			EnvVars: []string{"ARMIARMA_INFLUX_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "clickhouse-url",
+			Usage:   "URL of the ClickHouse HTTP interface where the high-volume tables (conn_events, attestations, aggregates) are mirrored (disabled if empty)",
+			EnvVars: []string{"ARMIARMA_CLICKHOUSE_URL"},
+		},
+		&cli.StringFlag{
+			Name:        "clickhouse-db",
+			Usage:       "ClickHouse database where the mirrored tables are created",
+			EnvVars:     []string{"ARMIARMA_CLICKHOUSE_DB"},
+			DefaultText: config.DefaultClickHouseDB,
+		},
+		&cli.StringFlag{
+			Name:    "clickhouse-user",
+			Usage:   "User to authenticate the writes into ClickHouse",
+			EnvVars: []string{"ARMIARMA_CLICKHOUSE_USER"},
+		},
+		&cli.StringFlag{
+			Name:    "clickhouse-password",
+			Usage:   "Password to authenticate the writes into ClickHouse",
+			EnvVars: []string{"ARMIARMA_CLICKHOUSE_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "retention",
+			Usage:   "Comma separated retention window of each table (<table>=<window>[:rollup]), rolled up rows are aggregated per day before being deleted, i.e. conn_events=720h:rollup,eth_node_records=2160h (disabled if empty)",
+			EnvVars: []string{"ARMIARMA_RETENTION"},
+		},
+		&cli.StringFlag{
+			Name:        "retention-schedule",
+			Usage:       "Cron-like schedule of the retention passes (5-field cron, @hourly, @daily, @weekly, @monthly or @every <duration>)",
+			EnvVars:     []string{"ARMIARMA_RETENTION_SCHEDULE"},
+			DefaultText: config.DefaultRetentionSchedule,
+		},
+		&cli.Int64Flag{
+			Name:    "crawl-seed",
+			Usage:   "Seed of the discovery random walks and peer selection, recorded in the crawler_runs table to replay the exploration of a run (a new one is picked if 0)",
+			EnvVars: []string{"ARMIARMA_CRAWL_SEED"},
+		},
+		&cli.StringFlag{
+			Name:        "peering-strategy",
+			Usage:       "Strategy to dial the known peers: pruning (backoff and deprecation of the failing peers), redial (all of them every round), holder (keep long-lived connections), quota (random samples with a dial quota per peer), or priority (all of them sorted by a dial score)",
+			EnvVars:     []string{"ARMIARMA_PEERING_STRATEGY"},
+			DefaultText: config.DefaultPeeringStrategy,
+		},
+		&cli.IntFlag{
+			Name:        "peering-quota",
+			Usage:       "Number of peers sampled and dialed on each round by the quota peering strategy",
+			EnvVars:     []string{"ARMIARMA_PEERING_QUOTA"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultPeeringQuota),
+		},
+		&cli.StringFlag{
+			Name:        "dial-score-weights",
+			Usage:       "Weights of the components of the dial score of the priority peering strategy: freshness (of the ENR), success (rate of the dials), gaps (never identified)",
+			EnvVars:     []string{"ARMIARMA_DIAL_SCORE_WEIGHTS"},
+			DefaultText: config.DefaultDialScoreWeights,
+		},
+		&cli.BoolFlag{
+			Name:    "adaptive-dialing",
+			Usage:   "Adapt the concurrent dials and the dial rate to the dial timeouts, the open file descriptors and the resource manager errors",
+			EnvVars: []string{"ARMIARMA_ADAPTIVE_DIALING"},
+		},
+		&cli.Float64Flag{
+			Name:        "max-dial-rate",
+			Usage:       "Maximum dials per second that the adaptive dialing can reach",
+			EnvVars:     []string{"ARMIARMA_MAX_DIAL_RATE"},
+			DefaultText: fmt.Sprintf("%.0f", config.DefaultMaxDialRate),
+		},
+		&cli.IntFlag{
+			Name:        "max-dial-workers",
+			Usage:       "Maximum concurrent dials that the adaptive dialing can reach",
+			EnvVars:     []string{"ARMIARMA_MAX_DIAL_WORKERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxDialWorkers),
+		},
+		&cli.BoolFlag{
+			Name:    "resume-dial-queue",
+			Usage:   "Resume the dial queue persisted on the last shutdown, keeping the backoff of the peers (enabled by default)",
+			EnvVars: []string{"ARMIARMA_RESUME_DIAL_QUEUE"},
+		},
+		&cli.StringFlag{
+			Name:        "dial-timeout",
+			Usage:       "Time before giving up on a dial (recorded as a dial_timeout error)",
+			EnvVars:     []string{"ARMIARMA_DIAL_TIMEOUT"},
+			DefaultText: config.DefaultDialTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "identify-timeout",
+			Usage:       "Time to wait for the identification of a connected peer (recorded as an identify_timeout error)",
+			EnvVars:     []string{"ARMIARMA_IDENTIFY_TIMEOUT"},
+			DefaultText: config.DefaultIdentifyTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "reqresp-timeout",
+			Usage:       "Time to wait for the req/resp answers of a connected peer (recorded as a reqresp_timeout error)",
+			EnvVars:     []string{"ARMIARMA_REQRESP_TIMEOUT"},
+			DefaultText: config.DefaultReqRespTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "class-timeouts",
+			Usage:       "Overrides of the timeouts for the peers behind relays or tor, i.e. relay.dial=60s,tor.dial=90s,tor.identify=30s",
+			EnvVars:     []string{"ARMIARMA_CLASS_TIMEOUTS"},
+			DefaultText: config.DefaultClassTimeouts,
+		},
+		&cli.IntFlag{
+			Name:        "deprecation-attempts",
+			Usage:       "Failed dials in a row after which the pruning strategy deprecates a peer (0 for no limit)",
+			EnvVars:     []string{"ARMIARMA_DEPRECATION_ATTEMPTS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultDeprecationAttempts),
+		},
+		&cli.StringFlag{
+			Name:        "deprecation-backoff",
+			Usage:       "Backoff curve between the failed dials of a peer: by-error (fixed delay per error, exponential for timeouts), constant, linear, or exponential",
+			EnvVars:     []string{"ARMIARMA_DEPRECATION_BACKOFF"},
+			DefaultText: config.DefaultDeprecationBackoff,
+		},
+		&cli.StringFlag{
+			Name:        "deprecation-window",
+			Usage:       "Time without a successful connection after which the pruning strategy deprecates a peer",
+			EnvVars:     []string{"ARMIARMA_DEPRECATION_WINDOW"},
+			DefaultText: config.DefaultDeprecationWindow,
+		},
+		&cli.IntFlag{
+			Name:        "dht-walkers",
+			Usage:       "Number of peers whose k-buckets are enumerated concurrently during the DHT walk",
+			EnvVars:     []string{"ARMIARMA_DHT_WALKERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultDhtWalkers),
+		},
+		&cli.StringFlag{
+			Name:        "dht-walk-interval",
+			Usage:       "Interval between the lookups of random keys (random walk) in the DHT",
+			EnvVars:     []string{"ARMIARMA_DHT_WALK_INTERVAL"},
+			DefaultText: config.DefaultDhtWalkInterval,
+		},
+		&cli.StringFlag{
+			Name:        "dht-recrawl-interval",
+			Usage:       "Time after which the k-buckets of an already enumerated peer are requested again",
+			EnvVars:     []string{"ARMIARMA_DHT_RECRAWL_INTERVAL"},
+			DefaultText: config.DefaultDhtRecrawlInterval,
+		},
+		&cli.IntFlag{
+			Name:        "waku-cluster-id",
+			Usage:       "Cluster of the waku relay shards whose messages are accounted in the gossip metrics",
+			EnvVars:     []string{"ARMIARMA_WAKU_CLUSTER_ID"},
+			DefaultText: fmt.Sprint(config.DefaultWakuClusterID),
+		},
+		&cli.IntSliceFlag{
+			Name:        "waku-shard",
+			Usage:       "Waku relay shards whose messages are accounted in the gossip metrics (One --waku-shard <shard> per shard)",
+			EnvVars:     []string{"ARMIARMA_WAKU_SHARDS"},
+			DefaultText: "0-7",
+		},
+	},
+}
+
+// LaunchIpfsCrawler is the function that is called when running `ipfs`.
+func LaunchIpfsCrawler(c *cli.Context) error {
+	log.Infoln("Starting IPFS Crawler...")
+
+	conf := config.NewIpfsCrawlerConfig()
+	conf.Apply(c)
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	// Generate the IPFS crawler struct
+	ipfsCrawler, err := crawler.NewIpfsCrawler(c, *conf)
+	if err != nil {
+		return err
+	}
+
+	// launch the subroutines
+	ipfsCrawler.Run()
+
+	// check the shutdown signal
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, syscall.SIGTERM)
+
+	// keep the app running until syscall.SIGTERM, until the watchdog detects a stall, or until the identity gets rotated
+	select {
+	case sig := <-sigs:
+		log.Printf("Received %s signal - Stopping...\n", sig.String())
+		signal.Stop(sigs)
+		ipfsCrawler.Close()
+	case <-ipfsCrawler.Stalled():
+		signal.Stop(sigs)
+		ipfsCrawler.Close()
+		// exit with an error, so that the supervisor (i.e. docker) restarts the crawler
+		return errors.New("crawler stalled, restart required")
+	case <-ipfsCrawler.IdentityRotated():
+		signal.Stop(sigs)
+		ipfsCrawler.Close()
+		// the new identity is only picked up by a new host
+		return errors.New("host identity rotated, restart required")
+	}
+
+	return nil
+}
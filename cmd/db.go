@@ -0,0 +1,20 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/db/storage"
+)
+
+// postgresOnly rejects the SQLite endpoints in the commands that only run against Postgres
+func postgresOnly(command, endpoint string) error {
+	if strings.HasPrefix(endpoint, storage.SQLiteScheme) {
+		return errors.Errorf("the %s command only supports Postgres endpoints, not %s", command, endpoint)
+	}
+	return nil
+}
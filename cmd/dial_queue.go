@@ -0,0 +1,101 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/peering"
+)
+
+var (
+	DefaultStatusTimeout = 10 * time.Second
+)
+
+// DialQueueCommand contains the dial-queue sub-command configuration.
+var DialQueueCommand = &cli.Command{
+	Name:   "dial-queue",
+	Usage:  "inspect the dial queue of a running crawler (backoff timers and deprecation state of the peers)",
+	Action: LaunchDialQueue,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "endpoint",
+			Usage:   "Metrics endpoint (ip:port) of the running crawler",
+			EnvVars: []string{"ARMIARMA_STATUS_ENDPOINT"},
+			Value:   fmt.Sprintf("localhost:%d", config.DefaultMetricsPort),
+		},
+		&cli.StringFlag{
+			Name:  "peer",
+			Usage: "Peer ID whose dial state will be shown (lists the queue if empty)",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "Number of peers of the queue that will be listed (0 for all of them)",
+			Value: peering.DefaultDialQueueLimit,
+		},
+	},
+}
+
+// LaunchDialQueue is the function that is called when running `dial-queue`.
+func LaunchDialQueue(c *cli.Context) error {
+	url := "http://" + strings.TrimPrefix(c.String("endpoint"), "http://") + peering.DialQueuePath
+	if peerID := c.String("peer"); peerID != "" {
+		var entry peering.DialQueueEntry
+		err := getStatus(url+"/"+peerID, &entry)
+		if err != nil {
+			return err
+		}
+		content, _ := json.MarshalIndent(entry, "", "  ")
+		fmt.Println(string(content))
+		return nil
+	}
+
+	var status peering.DialQueueStatus
+	err := getStatus(fmt.Sprintf("%s?limit=%d", url, c.Int("limit")), &status)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("strategy: %s, queued peers: %d, ready to dial: %d, pointer: %d\n",
+		status.Strategy, status.Len, status.Ready, status.Pointer)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "POS\tPEER\tDELAY\tDEGREE\tLAST ERROR\tNEXT DIAL\tDEPRECATION")
+	for _, entry := range status.Peers {
+		nextDial := "now"
+		if !entry.ReadyToDial {
+			nextDial = "in " + time.Until(entry.NextDial).Round(time.Second).String()
+		}
+		deprecation := "in " + time.Until(entry.DeprecationTime).Round(time.Second).String()
+		if entry.Deprecable {
+			deprecation = "deprecable (" + entry.Reason + ")"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			entry.Position, entry.PeerID, entry.Delay, entry.DelayDegree, entry.LastError, nextDial, deprecation)
+	}
+	return w.Flush()
+}
+
+func getStatus(url string, v interface{}) error {
+	client := http.Client{Timeout: DefaultStatusTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return errors.Wrap(err, "unable to reach the crawler")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(v), "unable to decode status")
+}
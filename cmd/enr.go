@@ -0,0 +1,188 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/inspect"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// EnrCommand contains the enr sub-command configuration.
+var EnrCommand = &cli.Command{
+	Name:  "enr",
+	Usage: "decode and build ENRs with the same parsing code that the crawlers use",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "decode",
+			Usage:     "decode an ENR, printing its entries as JSON",
+			ArgsUsage: "<enr>",
+			Action:    LaunchEnrDecode,
+		},
+		{
+			Name:   "build",
+			Usage:  "compose and sign an ENR",
+			Action: LaunchEnrBuild,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:        "priv-key",
+					Usage:       "String representation of the PrivateKey that signs the ENR",
+					EnvVars:     []string{"ARMIARMA_PRIV_KEY"},
+					DefaultText: "a new one",
+				},
+				&cli.Uint64Flag{
+					Name:  "seq",
+					Usage: "Sequence number of the ENR",
+					Value: 1,
+				},
+				&cli.StringFlag{
+					Name:  "ip",
+					Usage: "IP (v4 or v6) advertised in the ENR",
+				},
+				&cli.IntFlag{
+					Name:  "tcp",
+					Usage: "TCP (libp2p) port advertised in the ENR",
+				},
+				&cli.IntFlag{
+					Name:  "udp",
+					Usage: "UDP (discovery) port advertised in the ENR",
+				},
+				&cli.IntFlag{
+					Name:  "quic",
+					Usage: "QUIC (libp2p) port advertised in the ENR",
+				},
+				&cli.StringFlag{
+					Name:  "network",
+					Usage: "Ethereum CL network whose eth2 entry is added (i.e. mainnet, holesky)",
+				},
+				&cli.StringFlag{
+					Name:        "fork-digest",
+					Usage:       "Fork digest of the eth2 entry",
+					DefaultText: "the one of the network",
+				},
+				&cli.StringFlag{
+					Name:        "next-fork-version",
+					Usage:       "Version of the next fork of the eth2 entry",
+					DefaultText: "the current one of the network",
+				},
+				&cli.Uint64Flag{
+					Name:  "next-fork-epoch",
+					Usage: "Epoch of the next fork of the eth2 entry",
+					Value: ^uint64(0),
+				},
+				&cli.StringFlag{
+					Name:  "attnets",
+					Usage: "Hex bitvector of the attestation subnets (i.e. ffffffffffffffff)",
+				},
+				&cli.StringFlag{
+					Name:  "syncnets",
+					Usage: "Hex bitvector of the sync committee subnets (i.e. 0f)",
+				},
+			},
+		},
+	},
+}
+
+// LaunchEnrDecode is the function that is called when running `enr decode`.
+func LaunchEnrDecode(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("enr decode needs the ENR to decode")
+	}
+	info, err := inspect.DecodeEnr(c.Args().First())
+	if err != nil {
+		return err
+	}
+	return printJSON(info)
+}
+
+// LaunchEnrBuild is the function that is called when running `enr build`.
+func LaunchEnrBuild(c *cli.Context) error {
+	record := inspect.EnrRecord{
+		Seq:             c.Uint64("seq"),
+		TCP:             c.Int("tcp"),
+		UDP:             c.Int("udp"),
+		QUIC:            c.Int("quic"),
+		ForkDigest:      c.String("fork-digest"),
+		NextForkVersion: c.String("next-fork-version"),
+		NextForkEpoch:   c.Uint64("next-fork-epoch"),
+		Attnets:         c.String("attnets"),
+		Syncnets:        c.String("syncnets"),
+	}
+	if rawKey := c.String("priv-key"); rawKey != "" {
+		privKey, err := utils.ParseECDSAPrivateKey(rawKey)
+		if err != nil {
+			return errors.Wrap(err, "invalid priv-key")
+		}
+		record.PrivKey = privKey
+	}
+	if rawIP := c.String("ip"); rawIP != "" {
+		record.IP = net.ParseIP(rawIP)
+		if record.IP == nil {
+			return errors.Errorf("invalid ip %q", rawIP)
+		}
+	}
+	if network := c.String("network"); network != "" {
+		preset, ok := eth.Preset(network)
+		if !ok {
+			return errors.Errorf("unsupported network %q", network)
+		}
+		if record.ForkDigest == "" {
+			record.ForkDigest = preset.ForkDigest
+		}
+		if record.NextForkVersion == "" {
+			record.NextForkVersion = preset.ForkVersion
+		}
+		if record.ForkDigest == "" {
+			return errors.Errorf("the fork digest of %s has to be given", preset.Name)
+		}
+	}
+	if record.ForkDigest != "" && record.NextForkVersion == "" {
+		return errors.New("the eth2 entry needs the next-fork-version (or the network)")
+	}
+
+	node, privKey, err := inspect.BuildEnr(record)
+	if err != nil {
+		return err
+	}
+	libp2pKey, err := utils.AdaptSecp256k1FromECDSA(privKey)
+	if err != nil {
+		return err
+	}
+	peerID, err := peer.IDFromPrivateKey(libp2pKey)
+	if err != nil {
+		return err
+	}
+	built := struct {
+		Enr    string `json:"enr"`
+		NodeID string `json:"node_id"`
+		PeerID string `json:"peer_id"`
+		// only shown when a new key was generated
+		PrivKey string `json:"priv_key,omitempty"`
+	}{
+		Enr:    node.String(),
+		NodeID: node.ID().String(),
+		PeerID: peerID.String(),
+	}
+	if record.PrivKey == nil {
+		built.PrivKey = utils.Secp256k1ToString(libp2pKey)
+	}
+	return printJSON(built)
+}
+
+func printJSON(v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to compose the output")
+	}
+	fmt.Println(string(content))
+	return nil
+}
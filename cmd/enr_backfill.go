@@ -0,0 +1,91 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	DefaultBackfillPageSize = 1000
+)
+
+// EnrBackfillCommand contains the enr-backfill sub-command configuration.
+var EnrBackfillCommand = &cli.Command{
+	Name:   "enr-backfill",
+	Usage:  "re-decode the raw ENRs stored in the DB with the current decoder, backfilling the eth_nodes columns",
+	Action: LaunchEnrBackfill,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Verbosity level for the Crawler's logs",
+			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+			DefaultText: config.DefaultLogLevel,
+		},
+		&cli.StringFlag{
+			Name:        "psql-endpoint",
+			Usage:       "PSQL enpoint where the crawler stored the gathered info",
+			EnvVars:     []string{"ARMIARMA_PSQL"},
+			DefaultText: config.DefaultPSQLEndpoint,
+		},
+		&cli.IntFlag{
+			Name:    "page-size",
+			Usage:   "Number of ENRs that will be re-decoded and updated on each DB round",
+			EnvVars: []string{"ARMIARMA_BACKFILL_PAGE_SIZE"},
+			Value:   DefaultBackfillPageSize,
+		},
+	},
+}
+
+// LaunchEnrBackfill is the function that is called when running `enr-backfill`.
+func LaunchEnrBackfill(c *cli.Context) error {
+	log.Infoln("Starting ENR backfill...")
+
+	logLevel := config.DefaultLogLevel
+	if c.IsSet("log-level") {
+		logLevel = c.String("log-level")
+	}
+	log.SetLevel(utils.ParseLogLevel(logLevel))
+
+	endpoint := config.DefaultPSQLEndpoint
+	if c.IsSet("psql-endpoint") {
+		endpoint = c.String("psql-endpoint")
+	}
+	if err := postgresOnly("enr-backfill", endpoint); err != nil {
+		return err
+	}
+
+	dbClient, err := psql.NewDBClient(
+		c.Context,
+		utils.EthereumNetwork,
+		endpoint,
+		24*time.Hour,
+		psql.InitializeTables(true),
+		psql.WithActivePeersBackup(false),
+	)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	start := time.Now()
+	updated, failed, err := dbClient.BackfillEnrs(c.Int("page-size"))
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"updated":  updated,
+		"failed":   failed,
+		"duration": time.Since(start),
+	}).Info("ENR backfill finished")
+
+	return nil
+}
@@ -9,11 +9,13 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 
 	"github.com/migalabs/armiarma/pkg/config"
 	"github.com/migalabs/armiarma/pkg/crawler"
+	"github.com/migalabs/armiarma/pkg/metrics"
 	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
 )
 
@@ -23,12 +25,28 @@ var Eth2CrawlerCommand = &cli.Command{
 	Usage:  "crawl the given Ethereum CL network (selected by fork_digest)",
 	Action: LaunchEth2Crawler,
 	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config-file",
+			Usage:   "Path to a YAML, TOML or JSON file with the settings of the crawler, keyed by the flag names (the flags take precedence)",
+			EnvVars: []string{"ARMIARMA_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "network",
+			Usage:       "Ethereum CL network that we want to crawl (mainnet, gnosis, holesky, sepolia, ephemery), selects the preset fork digest, bootnodes and genesis of the network (or the only block of the networks of the config file to crawl)",
+			EnvVars:     []string{"ARMIARMA_NETWORK"},
+			DefaultText: config.DefaultEthNetwork,
+		},
 		&cli.StringFlag{
 			Name:        "log-level",
 			Usage:       "Verbosity level for the Crawler's logs",
 			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
 			DefaultText: config.DefaultLogLevel,
 		},
+		&cli.StringFlag{
+			Name:    "log-levels",
+			Usage:   "Verbosity level of specific modules (host, discovery, gossip, db, peering) over the global one, i.e. host=debug,db=warn",
+			EnvVars: []string{"ARMIARMA_LOG_LEVELS"},
+		},
 		&cli.StringFlag{
 			Name:    "priv-key",
 			Usage:   "String representation of the PrivateKey to be used by the crawler",
@@ -40,6 +58,18 @@ var Eth2CrawlerCommand = &cli.Command{
 			EnvVars:     []string{"ARMIARMA_IP"},
 			DefaultText: config.DefaultIP,
 		},
+		&cli.StringFlag{
+			Name:        "ip6",
+			Usage:       "IPv6 in the machine that we want to asign to the crawler (only used with ip-family v6 or both)",
+			EnvVars:     []string{"ARMIARMA_IP6"},
+			DefaultText: config.DefaultIP6,
+		},
+		&cli.StringFlag{
+			Name:        "ip-family",
+			Usage:       "IP family that the crawler will listen on and prefer to dial (v4, v6, both)",
+			EnvVars:     []string{"ARMIARMA_IP_FAMILY"},
+			DefaultText: config.DefaultIPFamily,
+		},
 		&cli.IntFlag{
 			Name:        "port",
 			Usage:       "TCP and UDP port that the crawler with advertise to establish connections",
@@ -66,7 +96,8 @@ var Eth2CrawlerCommand = &cli.Command{
 		},
 		&cli.StringFlag{
 			Name:        "psql-endpoint",
-			Usage:       "PSQL enpoint where the crwaler will submit the all the gathered info",
+			Aliases:     []string{"db"},
+			Usage:       "PSQL enpoint where the crwaler will submit the all the gathered info, or sqlite:<file> to store it in a SQLite file",
 			EnvVars:     []string{"ARMIARMA_PSQL"},
 			DefaultText: config.DefaultPSQLEndpoint,
 		},
@@ -76,6 +107,77 @@ var Eth2CrawlerCommand = &cli.Command{
 			EnvVars:     []string{"ARMIARMA_BACKUP_INTERVAL"},
 			DefaultText: config.DefaultActivePeersBackupInterval,
 		},
+		&cli.StringFlag{
+			Name:        "peers-history",
+			Usage:       "Time interval between the snapshots of the active peers (client, version, country, subnets and connectivity) appended to the peers_history table (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_PEERS_HISTORY"},
+			DefaultText: config.DefaultPeersHistoryInterval,
+		},
+		&cli.StringFlag{
+			Name:        "validator-estimates",
+			Usage:       "Time interval between the estimates of the validators behind each active peer (out of its subnets and attestations) appended to the eth_validator_estimates table (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_VALIDATOR_ESTIMATES"},
+			DefaultText: config.DefaultValidatorEstimates,
+		},
+		&cli.StringFlag{
+			Name:        "diversity-interval",
+			Usage:       "Time interval between the refreshes of the client, country, asn and attnet diversity gauges (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_DIVERSITY_INTERVAL"},
+			DefaultText: config.DefaultDiversityInterval,
+		},
+		&cli.StringFlag{
+			Name:    "geoip-city-db",
+			Usage:   "Path to a GeoLite2 City database (mmdb) to locate the IPs offline, falling back to the IP-API only for the IPs it misses",
+			EnvVars: []string{"ARMIARMA_GEOIP_CITY_DB"},
+		},
+		&cli.StringFlag{
+			Name:    "geoip-asn-db",
+			Usage:   "Path to a GeoLite2 ASN database (mmdb) to enrich the IPs located offline with their autonomous system",
+			EnvVars: []string{"ARMIARMA_GEOIP_ASN_DB"},
+		},
+		&cli.StringFlag{
+			Name:        "ip-cache-ttl",
+			Usage:       "Time that the located IPs are cached in the DB before locating them again",
+			EnvVars:     []string{"ARMIARMA_IP_CACHE_TTL"},
+			DefaultText: config.DefaultIpCacheTTL,
+		},
+		&cli.StringFlag{
+			Name:        "ip-refresh-interval",
+			Usage:       "Time interval between the background refreshes of the expired IPs of the cache (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_IP_REFRESH_INTERVAL"},
+			DefaultText: config.DefaultIpRefreshInterval,
+		},
+		&cli.BoolFlag{
+			Name:    "rdns-lookup",
+			Usage:   "Resolve the reverse DNS (PTR) name of the located IPs, also used to tag their cloud provider",
+			EnvVars: []string{"ARMIARMA_RDNS_LOOKUP"},
+		},
+		&cli.BoolFlag{
+			Name:    "cloud-tagging",
+			Usage:   "Tag the located IPs with the cloud provider hosting them (AWS, GCP, Azure, OVH, Hetzner), downloading the ranges published by AWS and GCP",
+			EnvVars: []string{"ARMIARMA_CLOUD_TAGGING"},
+		},
+		&cli.StringFlag{
+			Name:    "cloud-ranges-file",
+			Usage:   "Path to a file with extra cloud ranges to tag the IPs with, one '<provider> <cidr>' per line",
+			EnvVars: []string{"ARMIARMA_CLOUD_RANGES_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:    "tor-exit-list",
+			Usage:   "Flag the located IPs that are Tor exit nodes, downloading the exit list of the Tor project periodically",
+			EnvVars: []string{"ARMIARMA_TOR_EXIT_LIST"},
+		},
+		&cli.StringFlag{
+			Name:    "vpn-list-file",
+			Usage:   "Path to a file with the IPs or CIDRs of known VPN endpoints (one per line) to flag the located IPs with",
+			EnvVars: []string{"ARMIARMA_VPN_LIST_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "resource-usage-interval",
+			Usage:       "Time interval to record the resources (CPU, memory, bandwidth, connections) used by the crawler in the run metadata",
+			EnvVars:     []string{"ARMIARMA_RESOURCE_USAGE_INTERVAL"},
+			DefaultText: config.DefaultResourceUsageInterval,
+		},
 		&cli.StringFlag{
 			Name:        "remote-cl-endpoint",
 			Usage:       "Remote Ethereum Consensus Layer Client to request metadata (experimental)",
@@ -93,9 +195,36 @@ var Eth2CrawlerCommand = &cli.Command{
 			Usage:   "List of boondes that the crawler will use to discover more peers in the network (One --bootnode <bootnode> per bootnode)",
 			EnvVars: []string{"ARMIARMA_BOOTNODES"},
 		},
+		&cli.StringFlag{
+			Name:    "bootnodes-source",
+			Usage:   "Local file or HTTPS URL with more bootnodes (bootnode JSON file, JSON array or one ENR per line), read at start and on every config reload",
+			EnvVars: []string{"ARMIARMA_BOOTNODES_SOURCE"},
+		},
+		&cli.StringFlag{
+			Name:        "bootnode-ping-interval",
+			Usage:       "Interval between the discv5 pings that check the health of the bootnodes",
+			EnvVars:     []string{"ARMIARMA_BOOTNODE_PING_INTERVAL"},
+			DefaultText: config.DefaultBootnodePingInterval,
+		},
+		&cli.StringSliceFlag{
+			Name:    "discovery-source",
+			Usage:   "Discovery sources that run concurrently, tagging in the DB the peers that each of them finds: dv5 (default), static, db (One --discovery-source <source> per source)",
+			EnvVars: []string{"ARMIARMA_DISCOVERY_SOURCES"},
+		},
+		&cli.StringFlag{
+			Name:    "discovery-file",
+			Usage:   "JSON file with the multiaddrs of the peers notified by the static discovery source (same format than the bootnode files)",
+			EnvVars: []string{"ARMIARMA_DISCOVERY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "redial-interval",
+			Usage:       "Interval at which the db discovery source queues the deprecated peers to be re-dialed",
+			EnvVars:     []string{"ARMIARMA_REDIAL_INTERVAL"},
+			DefaultText: config.DefaultRedialInterval,
+		},
 		&cli.StringSliceFlag{
 			Name:        "gossip-topic",
-			Usage:       "List of gossipsub topics that the crawler will subscribe to",
+			Usage:       "List of gossipsub topics (message types of the fork, e.g. beacon_block, sync_committee_0, blob_sidecar_0) that the crawler will subscribe to",
 			EnvVars:     []string{"ARMIARMA_GOSSIP_TOPICS"},
 			DefaultText: "One --gossip-topic <topic> per topic",
 		},
@@ -104,16 +233,457 @@ var Eth2CrawlerCommand = &cli.Command{
 			Usage:   "List of subnets (gossipsub topics) that we want to subscribe the crawler to (One --subnet <subnet_id> per subnet)",
 			EnvVars: []string{"ARMIARMA_SUBNETS"},
 		},
+		&cli.BoolFlag{
+			Name:    "subscribe-all-subnets",
+			Usage:   "Subscribe to all the attestation and sync committee subnets at once, exporting the message rate of each subnet",
+			EnvVars: []string{"ARMIARMA_SUBSCRIBE_ALL_SUBNETS"},
+		},
 		&cli.BoolFlag{
 			Name:    "persist-connevents",
 			Usage:   "Decide whether we want to track the connection-events into the DB (Disk intense)",
 			EnvVars: []string{"ARMIARMA_PERSIST_CONNEVENTS"},
 		},
+		&cli.StringSliceFlag{
+			Name:    "security",
+			Usage:   "List of security protocols (noise, tls) that the crawler will negotiate, ordered by preference (One --security <protocol> per protocol)",
+			EnvVars: []string{"ARMIARMA_SECURITY"},
+		},
+		&cli.BoolFlag{
+			Name:    "signed-peer-record",
+			Usage:   "Decide whether the crawler shares its own signed peer record through identify (disabled by default)",
+			EnvVars: []string{"ARMIARMA_SIGNED_PEER_RECORD"},
+		},
+		&cli.BoolFlag{
+			Name:    "observed-addrs",
+			Usage:   "Decide whether the crawler advertises the addresses that remote peers observed for it (enabled by default)",
+			EnvVars: []string{"ARMIARMA_OBSERVED_ADDRS"},
+		},
+		&cli.BoolFlag{
+			Name:    "collect-peer-records",
+			Usage:   "Request and store the signed peer records (envelope, sequence number, addresses) of the remote peers (disabled by default)",
+			EnvVars: []string{"ARMIARMA_COLLECT_PEER_RECORDS"},
+		},
+		&cli.BoolFlag{
+			Name:    "negotiation-timing",
+			Usage:   "Time the multistream-select negotiation of each protocol shared with the peers, recording how long it takes and which ones fail (disabled by default)",
+			EnvVars: []string{"ARMIARMA_NEGOTIATION_TIMING"},
+		},
+		&cli.IntFlag{
+			Name:    "blocks-by-range-probe",
+			Usage:   "Number of blocks requested (BlocksByRange) to each connected peer to measure whether and how fast it serves historical data (disabled by default)",
+			EnvVars: []string{"ARMIARMA_BLOCKS_BY_RANGE_PROBE"},
+		},
+		&cli.Float64Flag{
+			Name:        "eclipse-threshold",
+			Usage:       "Share of the connected peers (0-1] gathered by a single client, country, or ASN that raises an eclipse alert",
+			EnvVars:     []string{"ARMIARMA_ECLIPSE_THRESHOLD"},
+			DefaultText: fmt.Sprintf("%.2f", config.DefaultEclipseThreshold),
+		},
+		&cli.IntFlag{
+			Name:        "eclipse-min-peers",
+			Usage:       "Minimum number of peers in a set to raise eclipse alerts",
+			EnvVars:     []string{"ARMIARMA_ECLIPSE_MIN_PEERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultEclipseMinPeers),
+		},
+		&cli.StringSliceFlag{
+			Name:    "static-peer",
+			Usage:   "Multiaddress (including /p2p/<peer_id>) of a trusted peer (i.e. our own beacon nodes) that the crawler will always keep connected (One --static-peer <multiaddr> per peer)",
+			EnvVars: []string{"ARMIARMA_STATIC_PEERS"},
+		},
+		&cli.BoolFlag{
+			Name:    "static-peers-in-stats",
+			Usage:   "Decide whether the static peers are included in the crawl statistics (excluded by default)",
+			EnvVars: []string{"ARMIARMA_STATIC_PEERS_IN_STATS"},
+		},
+		&cli.IntFlag{
+			Name:        "max-conns",
+			Usage:       "Maximum number of connections that the crawler will keep open (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_CONNS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxConns),
+		},
+		&cli.IntFlag{
+			Name:        "max-conns-per-peer",
+			Usage:       "Maximum number of connections that the crawler will keep open with a single peer (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_CONNS_PER_PEER"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxConnsPerPeer),
+		},
+		&cli.IntFlag{
+			Name:        "max-streams-per-peer",
+			Usage:       "Maximum number of streams that the crawler will keep open with a single peer (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_STREAMS_PER_PEER"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxStreamsPerPeer),
+		},
+		&cli.IntFlag{
+			Name:        "max-streams-per-protocol",
+			Usage:       "Maximum number of streams that the crawler will keep open for a single protocol (0 means unlimited)",
+			EnvVars:     []string{"ARMIARMA_MAX_STREAMS_PER_PROTOCOL"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxStreamsPerProtocol),
+		},
+		&cli.StringFlag{
+			Name:    "rcmgr-limits",
+			Usage:   "JSON file with the limits of the libp2p resource manager (go-libp2p format), applied over the max-* flags",
+			EnvVars: []string{"ARMIARMA_RCMGR_LIMITS"},
+		},
+		&cli.StringFlag{
+			Name:    "blocklist",
+			Usage:   "File with the CIDRs, IPs, ASNs (AS1234), and peer IDs that the crawler won't connect to (one entry per line)",
+			EnvVars: []string{"ARMIARMA_BLOCKLIST"},
+		},
+		&cli.BoolFlag{
+			Name:    "blocklist-db",
+			Usage:   "Decide whether the crawler also loads the blocklist entries from the blocklist table of the DB",
+			EnvVars: []string{"ARMIARMA_BLOCKLIST_DB"},
+		},
+		&cli.StringFlag{
+			Name:    "targets",
+			Usage:   "File with the only peers that the crawler dials, ignoring the discovery (ENRs, multiaddrs with /p2p/, or peer IDs, one per line)",
+			EnvVars: []string{"ARMIARMA_TARGETS"},
+		},
+		&cli.BoolFlag{
+			Name:    "targets-db",
+			Usage:   "Decide whether the crawler only dials the peers of the target_peers table of the DB (together with the ones of --targets), ignoring the discovery",
+			EnvVars: []string{"ARMIARMA_TARGETS_DB"},
+		},
+		&cli.BoolFlag{
+			Name:    "soak",
+			Usage:   "Run in soak mode for unattended long-running deployments (observer mode, retention, daily rollups, watchdog, and weekly summaries)",
+			EnvVars: []string{"ARMIARMA_SOAK"},
+		},
+		&cli.BoolFlag{
+			Name:    "observer",
+			Usage:   "Decide whether the crawler only observes the network (no active dialing of the discovered peers)",
+			EnvVars: []string{"ARMIARMA_OBSERVER"},
+		},
+		&cli.BoolFlag{
+			Name:    "discovery-only",
+			Usage:   "Run a passive census that only walks discv5, storing the ENRs and the geolocation of the discovered nodes without opening any libp2p connection (implies observer mode)",
+			EnvVars: []string{"ARMIARMA_DISCOVERY_ONLY"},
+		},
+		&cli.BoolFlag{
+			Name:    "listen-only",
+			Usage:   "Run a passive crawl that never dials, only accepting the inbound connections and gossip of the peers that find us (implies observer mode)",
+			EnvVars: []string{"ARMIARMA_LISTEN_ONLY"},
+		},
+		&cli.BoolFlag{
+			Name:    "discoverable",
+			Usage:   "Publish the TCP and UDP ports of the crawler (and its IP, if it listens on a public one) in its ENR, so that the discv5 nodes can find and dial it",
+			EnvVars: []string{"ARMIARMA_DISCOVERABLE"},
+		},
+		&cli.StringFlag{
+			Name:        "soak-retention",
+			Usage:       "Age over which the time-series rows (connection events, backups, gossip messages) are deleted in soak mode",
+			EnvVars:     []string{"ARMIARMA_SOAK_RETENTION"},
+			DefaultText: config.DefaultSoakRetention,
+		},
+		&cli.StringFlag{
+			Name:        "soak-export-dir",
+			Usage:       "Folder where the weekly summaries are exported in soak mode",
+			EnvVars:     []string{"ARMIARMA_SOAK_EXPORT_DIR"},
+			DefaultText: config.DefaultSoakExportDir,
+		},
+		&cli.StringFlag{
+			Name:        "watchdog-timeout",
+			Usage:       "Time without open connections after which the crawler is restarted in soak mode",
+			EnvVars:     []string{"ARMIARMA_WATCHDOG_TIMEOUT"},
+			DefaultText: config.DefaultWatchdogTimeout,
+		},
+		&cli.StringFlag{
+			Name:    "key-store",
+			Usage:   "Where the identity of the host is persisted between restarts (\"postgres\" or path of a key file), ephemeral if empty",
+			EnvVars: []string{"ARMIARMA_KEY_STORE"},
+		},
+		&cli.StringFlag{
+			Name:        "key-rotation",
+			Usage:       "Age after which the persisted identity of the host is rotated, restarting the crawler (0s never rotates it)",
+			EnvVars:     []string{"ARMIARMA_KEY_ROTATION"},
+			DefaultText: config.DefaultKeyRotation,
+		},
+		&cli.IntFlag{
+			Name:        "hosts",
+			Usage:       "Number of libp2p hosts (consecutive ports, own identities) that share the dials of the crawler",
+			EnvVars:     []string{"ARMIARMA_HOSTS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultHosts),
+		},
+		&cli.IntFlag{
+			Name:        "event-queue-size",
+			Usage:       "Max number of connection/identification events buffered by each host before dropping new ones",
+			EnvVars:     []string{"ARMIARMA_EVENT_QUEUE_SIZE"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultEventQueueSize),
+		},
+		&cli.StringFlag{
+			Name:    "influx-url",
+			Usage:   "URL of the InfluxDB (or Telegraf listener) where the summaries of each round of metrics are exported (disabled if empty)",
+			EnvVars: []string{"ARMIARMA_INFLUX_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "influx-org",
+			Usage:   "InfluxDB organization (uses the v2 write API if set, the v1 one otherwise)",
+			EnvVars: []string{"ARMIARMA_INFLUX_ORG"},
+		},
+		&cli.StringFlag{
+			Name:        "influx-bucket",
+			Usage:       "InfluxDB bucket (v2) or database (v1) where the summaries are written",
+			EnvVars:     []string{"ARMIARMA_INFLUX_BUCKET"},
+			DefaultText: config.DefaultInfluxBucket,
+		},
+		&cli.StringFlag{
+			Name:    "influx-token",
+			Usage:   "Token to authenticate the writes into InfluxDB",
+			EnvVars: []string{"ARMIARMA_INFLUX_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "clickhouse-url",
+			Usage:   "URL of the ClickHouse HTTP interface where the high-volume tables (conn_events, attestations, aggregates) are mirrored (disabled if empty)",
+			EnvVars: []string{"ARMIARMA_CLICKHOUSE_URL"},
+		},
+		&cli.StringFlag{
+			Name:        "clickhouse-db",
+			Usage:       "ClickHouse database where the mirrored tables are created",
+			EnvVars:     []string{"ARMIARMA_CLICKHOUSE_DB"},
+			DefaultText: config.DefaultClickHouseDB,
+		},
+		&cli.StringFlag{
+			Name:    "clickhouse-user",
+			Usage:   "User to authenticate the writes into ClickHouse",
+			EnvVars: []string{"ARMIARMA_CLICKHOUSE_USER"},
+		},
+		&cli.StringFlag{
+			Name:    "clickhouse-password",
+			Usage:   "Password to authenticate the writes into ClickHouse",
+			EnvVars: []string{"ARMIARMA_CLICKHOUSE_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "retention",
+			Usage:   "Comma separated retention window of each table (<table>=<window>[:rollup]), rolled up rows are aggregated per day before being deleted, i.e. conn_events=720h:rollup,eth_node_records=2160h (disabled if empty)",
+			EnvVars: []string{"ARMIARMA_RETENTION"},
+		},
+		&cli.StringFlag{
+			Name:        "retention-schedule",
+			Usage:       "Cron-like schedule of the retention passes (5-field cron, @hourly, @daily, @weekly, @monthly or @every <duration>)",
+			EnvVars:     []string{"ARMIARMA_RETENTION_SCHEDULE"},
+			DefaultText: config.DefaultRetentionSchedule,
+		},
+		&cli.Int64Flag{
+			Name:    "crawl-seed",
+			Usage:   "Seed of the discovery random walks and peer selection, recorded in the crawler_runs table to replay the exploration of a run (a new one is picked if 0)",
+			EnvVars: []string{"ARMIARMA_CRAWL_SEED"},
+		},
+		&cli.StringFlag{
+			Name:        "peering-strategy",
+			Usage:       "Strategy to dial the known peers: pruning (backoff and deprecation of the failing peers), redial (all of them every round), holder (keep long-lived connections), quota (random samples with a dial quota per peer), or priority (all of them sorted by a dial score)",
+			EnvVars:     []string{"ARMIARMA_PEERING_STRATEGY"},
+			DefaultText: config.DefaultPeeringStrategy,
+		},
+		&cli.IntFlag{
+			Name:        "peering-quota",
+			Usage:       "Number of peers sampled and dialed on each round by the quota peering strategy",
+			EnvVars:     []string{"ARMIARMA_PEERING_QUOTA"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultPeeringQuota),
+		},
+		&cli.StringFlag{
+			Name:        "dial-score-weights",
+			Usage:       "Weights of the components of the dial score of the priority peering strategy: freshness (of the ENR), success (rate of the dials), gaps (never identified)",
+			EnvVars:     []string{"ARMIARMA_DIAL_SCORE_WEIGHTS"},
+			DefaultText: config.DefaultDialScoreWeights,
+		},
+		&cli.BoolFlag{
+			Name:    "adaptive-dialing",
+			Usage:   "Adapt the concurrent dials and the dial rate to the dial timeouts, the open file descriptors and the resource manager errors",
+			EnvVars: []string{"ARMIARMA_ADAPTIVE_DIALING"},
+		},
+		&cli.Float64Flag{
+			Name:        "max-dial-rate",
+			Usage:       "Maximum dials per second that the adaptive dialing can reach",
+			EnvVars:     []string{"ARMIARMA_MAX_DIAL_RATE"},
+			DefaultText: fmt.Sprintf("%.0f", config.DefaultMaxDialRate),
+		},
+		&cli.IntFlag{
+			Name:        "max-dial-workers",
+			Usage:       "Maximum concurrent dials that the adaptive dialing can reach",
+			EnvVars:     []string{"ARMIARMA_MAX_DIAL_WORKERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMaxDialWorkers),
+		},
+		&cli.BoolFlag{
+			Name:    "resume-dial-queue",
+			Usage:   "Resume the dial queue persisted on the last shutdown, keeping the backoff of the peers (enabled by default)",
+			EnvVars: []string{"ARMIARMA_RESUME_DIAL_QUEUE"},
+		},
+		&cli.StringFlag{
+			Name:        "dial-timeout",
+			Usage:       "Time before giving up on a dial (recorded as a dial_timeout error)",
+			EnvVars:     []string{"ARMIARMA_DIAL_TIMEOUT"},
+			DefaultText: config.DefaultDialTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "identify-timeout",
+			Usage:       "Time to wait for the identification of a connected peer (recorded as an identify_timeout error)",
+			EnvVars:     []string{"ARMIARMA_IDENTIFY_TIMEOUT"},
+			DefaultText: config.DefaultIdentifyTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "reqresp-timeout",
+			Usage:       "Time to wait for the req/resp answers of a connected peer (recorded as a reqresp_timeout error)",
+			EnvVars:     []string{"ARMIARMA_REQRESP_TIMEOUT"},
+			DefaultText: config.DefaultReqRespTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "class-timeouts",
+			Usage:       "Overrides of the timeouts for the peers behind relays or tor, i.e. relay.dial=60s,tor.dial=90s,tor.identify=30s",
+			EnvVars:     []string{"ARMIARMA_CLASS_TIMEOUTS"},
+			DefaultText: config.DefaultClassTimeouts,
+		},
+		&cli.IntFlag{
+			Name:        "deprecation-attempts",
+			Usage:       "Failed dials in a row after which the pruning strategy deprecates a peer (0 for no limit)",
+			EnvVars:     []string{"ARMIARMA_DEPRECATION_ATTEMPTS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultDeprecationAttempts),
+		},
+		&cli.StringFlag{
+			Name:        "deprecation-backoff",
+			Usage:       "Backoff curve between the failed dials of a peer: by-error (fixed delay per error, exponential for timeouts), constant, linear, or exponential",
+			EnvVars:     []string{"ARMIARMA_DEPRECATION_BACKOFF"},
+			DefaultText: config.DefaultDeprecationBackoff,
+		},
+		&cli.StringFlag{
+			Name:        "deprecation-window",
+			Usage:       "Time without a successful connection after which the pruning strategy deprecates a peer",
+			EnvVars:     []string{"ARMIARMA_DEPRECATION_WINDOW"},
+			DefaultText: config.DefaultDeprecationWindow,
+		},
+		&cli.BoolFlag{
+			Name:    "resurrect-on-new-enr",
+			Usage:   "Only bring back the deprecated peers when they are re-discovered with a newer ENR (by default any re-discovery brings them back)",
+			EnvVars: []string{"ARMIARMA_RESURRECT_ON_NEW_ENR"},
+		},
+		&cli.StringFlag{
+			Name:        "quality-interval",
+			Usage:       "Interval at which the quality score of the peers (gossip score, duplicates, invalid messages, req/resp reliability) is refreshed",
+			EnvVars:     []string{"ARMIARMA_QUALITY_INTERVAL"},
+			DefaultText: config.DefaultQualityInterval,
+		},
+		&cli.StringFlag{
+			Name:        "quality-weights",
+			Usage:       "Weights of the components of the peer quality score (score, duplicates, invalid, reqresp)",
+			EnvVars:     []string{"ARMIARMA_QUALITY_WEIGHTS"},
+			DefaultText: config.DefaultQualityWeights,
+		},
+		&cli.StringFlag{
+			Name:        "reputation-interval",
+			Usage:       "Interval at which the reputation of the peers (dial reliability, identify success, gossip score, invalid messages) is refreshed and stored in peer_reputation, deprioritizing the bad peers in the priority peering strategy (disabled if 0)",
+			EnvVars:     []string{"ARMIARMA_REPUTATION_INTERVAL"},
+			DefaultText: config.DefaultReputationInterval,
+		},
+		&cli.StringFlag{
+			Name:        "reputation-weights",
+			Usage:       "Weights of the components of the peer reputation (dials, identify, gossip, invalid)",
+			EnvVars:     []string{"ARMIARMA_REPUTATION_WEIGHTS"},
+			DefaultText: config.DefaultReputationWeights,
+		},
+		&cli.BoolFlag{
+			Name:    "churn",
+			Usage:   "Run a connection churn experiment: connect/disconnect a sampled set of peers on a fixed schedule recording their reactions (implies observer mode)",
+			EnvVars: []string{"ARMIARMA_CHURN"},
+		},
+		&cli.IntFlag{
+			Name:        "churn-sample-size",
+			Usage:       "Number of peers sampled from the DB for the churn experiment",
+			EnvVars:     []string{"ARMIARMA_CHURN_SAMPLE_SIZE"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultChurnSampleSize),
+		},
+		&cli.IntFlag{
+			Name:        "churn-cycles",
+			Usage:       "Number of connect/disconnect cycles of the churn experiment",
+			EnvVars:     []string{"ARMIARMA_CHURN_CYCLES"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultChurnCycles),
+		},
+		&cli.StringFlag{
+			Name:        "churn-connect-time",
+			Usage:       "Time that the sampled peers are kept connected on each cycle of the churn experiment",
+			EnvVars:     []string{"ARMIARMA_CHURN_CONNECT_TIME"},
+			DefaultText: config.DefaultChurnConnectTime,
+		},
+		&cli.StringFlag{
+			Name:        "churn-disconnect-time",
+			Usage:       "Time that the sampled peers are kept disconnected on each cycle of the churn experiment",
+			EnvVars:     []string{"ARMIARMA_CHURN_DISCONNECT_TIME"},
+			DefaultText: config.DefaultChurnDisconnectTime,
+		},
+		&cli.Int64Flag{
+			Name:        "churn-seed",
+			Usage:       "Seed used to sample the peers of the churn experiment (same seed and DB give the same sample)",
+			EnvVars:     []string{"ARMIARMA_CHURN_SEED"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultChurnSeed),
+		},
+		&cli.IntFlag{
+			Name:        "hold-peers",
+			Usage:       "Number of peers to keep connected for hours with keepalive pings, re-dialing them on drop (0 to disable)",
+			EnvVars:     []string{"ARMIARMA_HOLD_PEERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultHoldPeers),
+		},
+		&cli.StringFlag{
+			Name:        "hold-stratify",
+			Usage:       "Attribute used to stratify the held peers: client, country, subnet (empty for a uniform sample)",
+			EnvVars:     []string{"ARMIARMA_HOLD_STRATIFY"},
+			DefaultText: config.DefaultHoldStratify,
+		},
+		&cli.StringFlag{
+			Name:        "hold-ping-interval",
+			Usage:       "Interval between the keepalive pings of the held peers",
+			EnvVars:     []string{"ARMIARMA_HOLD_PING_INTERVAL"},
+			DefaultText: config.DefaultHoldPingInterval,
+		},
+		&cli.IntFlag{
+			Name:        "hold-budget",
+			Usage:       "Failed pings or re-dials in a row before a held peer is replaced by another one of its stratum",
+			EnvVars:     []string{"ARMIARMA_HOLD_BUDGET"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultHoldBudget),
+		},
+		&cli.StringFlag{
+			Name:        "dv5-strategy",
+			Usage:       "Strategy to walk the discv5 DHT: random walk, buckets (lookups at --dv5-distances), sweep (decreasing distances), or fork (only nodes of --dv5-fork-digest)",
+			EnvVars:     []string{"ARMIARMA_DV5_STRATEGY"},
+			DefaultText: config.DefaultDv5Strategy,
+		},
+		&cli.StringFlag{
+			Name:        "dv5-distances",
+			Usage:       "Comma separated log-distances from the crawler that the buckets strategy looks up",
+			EnvVars:     []string{"ARMIARMA_DV5_DISTANCES"},
+			DefaultText: config.DefaultDv5Distances,
+		},
+		&cli.StringSliceFlag{
+			Name:        "dv5-fork-digest",
+			Usage:       "Fork digests that the fork strategy looks for in the ENRs (One --dv5-fork-digest <digest> per digest)",
+			EnvVars:     []string{"ARMIARMA_DV5_FORK_DIGESTS"},
+			DefaultText: "the --fork-digest of the crawler",
+		},
+		&cli.StringSliceFlag{
+			Name:        "allowed-fork-digest",
+			Usage:       "Fork digests of the discovered nodes that are dialed, the rest are filtered out at discovery time (One --allowed-fork-digest <digest> per digest)",
+			EnvVars:     []string{"ARMIARMA_ALLOWED_FORK_DIGESTS"},
+			DefaultText: "the --fork-digest of the crawler",
+		},
+		&cli.BoolFlag{
+			Name:    "store-mismatched-forks",
+			Usage:   "Store the discovered nodes of other forks in the DB without dialing them",
+			EnvVars: []string{"ARMIARMA_STORE_MISMATCHED_FORKS"},
+		},
 		&cli.BoolFlag{
 			Name:    "persist-msgs",
-			Usage:   "Decide whether we want to track the msgs-metadata into the DB",
+			Usage:   "Decide whether we want to track the msgs-metadata into the DB (including the first arrival and duplicates of each msg)",
 			EnvVars: []string{"ARMIARMA_PERSIST_MSGS"},
 		},
+		&cli.StringFlag{
+			Name:        "mesh-snapshot-interval",
+			Usage:       "Time interval to snapshot our gossipsub mesh of each topic and the control messages exchanged with the peers (0s disables it)",
+			EnvVars:     []string{"ARMIARMA_MESH_SNAPSHOT_INTERVAL"},
+			DefaultText: config.DefaultMeshSnapshotInterval,
+		},
+		&cli.StringFlag{
+			Name:        "gossip-validation",
+			Usage:       "Validation of the gossip messages: none (accept all), ssz (decodable and stateless checks), or full (also checked against the --remote-cl-endpoint). Rejected messages are accounted as invalid deliveries of the peers",
+			EnvVars:     []string{"ARMIARMA_GOSSIP_VALIDATION"},
+			DefaultText: config.DefaultGossipValidation,
+		},
 		&cli.StringFlag{
 			Name:    "val-pubkeys",
 			Usage:   "Path of the file that has the pubkeys of those validators that we want to track (experimental)",
@@ -136,27 +706,74 @@ var Eth2CrawlerCommand = &cli.Command{
 func LaunchEth2Crawler(c *cli.Context) error {
 	log.Infoln("Starting Ethereum Crawler...")
 
-	conf := config.NewEthereumCrawlerConfig()
-	conf.Apply(c)
-
-	// Generate the Eth2 crawler struct
-	ethCrawler, err := crawler.NewEthereumCrawler(c, *conf)
+	// one config per block of networks of the config file (a single one if there are no blocks)
+	confs, err := config.EthereumNetworkConfigs(c)
 	if err != nil {
 		return err
 	}
 
+	// the crawlers of several networks share the metrics server, and tag their data in the DB with their network
+	var metricsServer *metrics.MetricsServer
+	if len(confs) > 1 {
+		metricsServer = metrics.NewMetricsServer(confs[0].MetricsIP, confs[0].MetricsPort)
+	}
+	ethCrawlers := make([]*crawler.EthereumCrawler, 0, len(confs))
+	for _, conf := range confs {
+		crawlerOpts := make([]crawler.EthereumCrawlerOption, 0)
+		if metricsServer != nil {
+			log.Infof("Starting the crawler of %s...", conf.Network)
+			crawlerOpts = append(crawlerOpts,
+				crawler.WithMetricsServer(metricsServer),
+				crawler.WithNetworkName(conf.Network),
+			)
+		}
+		// Generate the Eth2 crawler struct (one after the other, so that they don't race to create the tables)
+		ethCrawler, err := crawler.NewEthereumCrawler(c, conf, crawlerOpts...)
+		if err != nil {
+			return errors.Wrapf(err, "unable to create the crawler of %s", conf.Network)
+		}
+		ethCrawlers = append(ethCrawlers, ethCrawler)
+	}
+
 	// launch the subroutines
-	ethCrawler.Run()
+	exitC := make(chan error, len(ethCrawlers))
+	for _, ethCrawler := range ethCrawlers {
+		ethCrawler.Run()
+		go func(ethCrawler *crawler.EthereumCrawler) {
+			exitC <- crawlerExit(ethCrawler)
+		}(ethCrawler)
+	}
 
 	// check the shutdown signal
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, syscall.SIGTERM)
 
-	// keep the app running until syscall.SIGTERM
-	sig := <-sigs
-	log.Printf("Received %s signal - Stopping...\n", sig.String())
+	// keep the app running until syscall.SIGTERM, or until any of the crawlers has to stop
+	var exitErr error
+	select {
+	case sig := <-sigs:
+		log.Printf("Received %s signal - Stopping...\n", sig.String())
+	case exitErr = <-exitC:
+	}
 	signal.Stop(sigs)
-	ethCrawler.Close()
+	for _, ethCrawler := range ethCrawlers {
+		ethCrawler.Close()
+	}
+	return exitErr
+}
 
-	return nil
+// crawlerExit blocks until the crawler has to stop on its own: the watchdog detected a stall, the identity
+// got rotated, or the churn experiment finished (the only one that doesn't require a restart)
+func crawlerExit(ethCrawler *crawler.EthereumCrawler) error {
+	select {
+	case <-ethCrawler.Stalled():
+		// exit with an error, so that the supervisor (i.e. docker) restarts the crawler
+		return errors.New("crawler stalled, restart required")
+	case <-ethCrawler.IdentityRotated():
+		// the new identity is only picked up by a new host
+		return errors.New("host identity rotated, restart required")
+	case <-ethCrawler.ChurnDone():
+		log.Info("churn experiment finished - Stopping...")
+		return nil
+	}
 }
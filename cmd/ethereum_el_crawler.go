@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/crawler"
+)
+
+// EthELCrawlerCommand contains the eth-el sub-command configuration.
+var EthELCrawlerCommand = &cli.Command{
+	Name:   "eth-el",
+	Usage:  "crawl the given Ethereum EL network through its discv4 DHT",
+	Action: LaunchEthELCrawler,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Verbosity level for the Crawler's logs",
+			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+			DefaultText: config.DefaultLogLevel,
+		},
+		&cli.StringFlag{
+			Name:    "priv-key",
+			Usage:   "String representation of the PrivateKey to be used by the crawler",
+			EnvVars: []string{"ARMIARMA_PRIV_KEY"},
+		},
+		&cli.IntFlag{
+			Name:        "port",
+			Usage:       "UDP port where the crawler listens for discv4",
+			EnvVars:     []string{"ARMIARMA_PORT"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultPort),
+		},
+		&cli.StringFlag{
+			Name:        "metrics-ip",
+			Usage:       "IP in the machine that will expose the metrics of the crawler",
+			EnvVars:     []string{"ARMIARMA_METRICS_IP"},
+			DefaultText: config.DefaultMetricsIP,
+		},
+		&cli.IntFlag{
+			Name:        "metrics-port",
+			Usage:       "Port that the crawler with to expose pprof and prometheus metrics",
+			EnvVars:     []string{"ARMIARMA_METRICS_PORT"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMetricsPort),
+		},
+		&cli.StringFlag{
+			Name:        "user-agent",
+			Usage:       "Client name that will identify the crawler in the devp2p hello",
+			EnvVars:     []string{"ARMIARMA_USER_AGENT"},
+			DefaultText: config.DefaultUserAgent,
+		},
+		&cli.StringFlag{
+			Name:        "psql-endpoint",
+			Aliases:     []string{"db"},
+			Usage:       "PSQL enpoint where the crwaler will submit the all the gathered info, or sqlite:<file> to store it in a SQLite file",
+			EnvVars:     []string{"ARMIARMA_PSQL"},
+			DefaultText: config.DefaultPSQLEndpoint,
+		},
+		&cli.StringFlag{
+			Name:        "network",
+			Usage:       "EL network whose bootnodes are used (mainnet, sepolia, holesky)",
+			EnvVars:     []string{"ARMIARMA_EL_NETWORK"},
+			DefaultText: config.DefaultELNetwork,
+		},
+		&cli.StringSliceFlag{
+			Name:    "bootnode",
+			Usage:   "List of bootnodes (enode://) that the crawler will use to discover more nodes in the network (One --bootnode <bootnode> per bootnode)",
+			EnvVars: []string{"ARMIARMA_BOOTNODES"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "fork-hash",
+			Usage:   "Fork hashes (EIP-2124) advertised in the ENRs of the nodes of the chain that we want to crawl, all the discovered nodes are crawled if none is given (One --fork-hash <hash> per hash)",
+			EnvVars: []string{"ARMIARMA_FORK_HASHES"},
+		},
+		&cli.StringFlag{
+			Name:        "hello-timeout",
+			Usage:       "Timeout of the dial, RLPx handshake, and devp2p hello used to identify the client of each node",
+			EnvVars:     []string{"ARMIARMA_HELLO_TIMEOUT"},
+			DefaultText: config.DefaultHelloTimeout,
+		},
+		&cli.IntFlag{
+			Name:        "hello-workers",
+			Usage:       "Number of devp2p hellos performed concurrently",
+			EnvVars:     []string{"ARMIARMA_HELLO_WORKERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultHelloWorkers),
+		},
+		&cli.StringFlag{
+			Name:        "recrawl-interval",
+			Usage:       "Time after which an already crawled node is identified again if the walk finds it",
+			EnvVars:     []string{"ARMIARMA_RECRAWL_INTERVAL"},
+			DefaultText: config.DefaultRecrawlInterval,
+		},
+	},
+}
+
+// LaunchEthELCrawler is the function that is called when running `eth-el`.
+func LaunchEthELCrawler(c *cli.Context) error {
+	log.Infoln("Starting Ethereum EL Crawler...")
+
+	conf := config.NewEthereumELCrawlerConfig()
+	conf.Apply(c)
+
+	// Generate the EL crawler struct
+	elCrawler, err := crawler.NewEthereumELCrawler(c, *conf)
+	if err != nil {
+		return err
+	}
+
+	// launch the subroutines
+	elCrawler.Run()
+
+	// check the shutdown signal
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+
+	// keep the app running until syscall.SIGTERM
+	sig := <-sigs
+	log.Printf("Received %s signal - Stopping...\n", sig.String())
+	signal.Stop(sigs)
+	elCrawler.Close()
+
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/export"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// ExportCommand contains the export sub-command configuration.
+var ExportCommand = &cli.Command{
+	Name:   "export",
+	Usage:  "dump the peers, connection events or gossip metrics stored in the DB to CSV or Parquet",
+	Action: LaunchExport,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Verbosity level for the Crawler's logs",
+			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+			DefaultText: config.DefaultLogLevel,
+		},
+		&cli.StringFlag{
+			Name:        "psql-endpoint",
+			Usage:       "PSQL enpoint where the crawler stored the gathered info",
+			EnvVars:     []string{"ARMIARMA_PSQL"},
+			DefaultText: config.DefaultPSQLEndpoint,
+		},
+		&cli.StringFlag{
+			Name:  "network",
+			Usage: "Network whose DB is exported (ethereum, ethereum-el, ipfs, filecoin)",
+			Value: "ethereum",
+		},
+		&cli.StringFlag{
+			Name:     "dataset",
+			Usage:    "Dataset to export (" + strings.Join(psql.ExportDatasets(), ", ") + ")",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Format of the exported file (csv, parquet)",
+			Value: export.CSVFormat,
+		},
+		&cli.StringFlag{
+			Name:  "columns",
+			Usage: "Comma separated columns of the dataset to export (all of them if empty)",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "Only export the rows from this time on (RFC3339 or YYYY-MM-DD)",
+		},
+		&cli.StringFlag{
+			Name:  "until",
+			Usage: "Only export the rows before this time (RFC3339 or YYYY-MM-DD)",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "File where the dataset is exported (stdout if empty)",
+		},
+	},
+}
+
+// LaunchExport is the function that is called when running `export`.
+func LaunchExport(c *cli.Context) error {
+	logLevel := config.DefaultLogLevel
+	if c.IsSet("log-level") {
+		logLevel = c.String("log-level")
+	}
+	log.SetLevel(utils.ParseLogLevel(logLevel))
+
+	endpoint := config.DefaultPSQLEndpoint
+	if c.IsSet("psql-endpoint") {
+		endpoint = c.String("psql-endpoint")
+	}
+	if err := postgresOnly("export", endpoint); err != nil {
+		return err
+	}
+	network, ok := sampleNetworks[strings.ToLower(c.String("network"))]
+	if !ok {
+		return errors.Errorf("unknown network %s", c.String("network"))
+	}
+	since, err := parseExportTime(c.String("since"))
+	if err != nil {
+		return err
+	}
+	until, err := parseExportTime(c.String("until"))
+	if err != nil {
+		return err
+	}
+	var selected []string
+	if c.String("columns") != "" {
+		for _, col := range strings.Split(c.String("columns"), ",") {
+			selected = append(selected, strings.TrimSpace(col))
+		}
+	}
+	dataset := c.String("dataset")
+	columns, err := psql.ExportColumns(dataset, selected)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if c.String("output") != "" {
+		file, err := os.Create(c.String("output"))
+		if err != nil {
+			return errors.Wrap(err, "unable to create the export file")
+		}
+		defer file.Close()
+		out = file
+	}
+	w, err := export.NewWriter(c.String("format"), out, columns)
+	if err != nil {
+		return err
+	}
+
+	dbClient, err := psql.NewDBClient(
+		c.Context,
+		network,
+		endpoint,
+		24*time.Hour,
+		psql.WithActivePeersBackup(false),
+		psql.WithMigrations(false),
+	)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	exported, err := dbClient.ExportDataset(dataset, selected, since, until, w)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return errors.Wrap(err, "unable to finish the export")
+	}
+	log.WithFields(log.Fields{
+		"dataset": dataset,
+		"format":  c.String("format"),
+		"rows":    exported,
+	}).Info("dataset exported")
+	return nil
+}
+
+// parseExportTime parses RFC3339 times or days (zero if empty)
+func parseExportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid time %s (RFC3339 or YYYY-MM-DD)", value)
+	}
+	return t, nil
+}
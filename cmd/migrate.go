@@ -0,0 +1,139 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// MigrateCommand contains the migrate sub-command configuration.
+var MigrateCommand = &cli.Command{
+	Name:   "migrate",
+	Usage:  "apply (or roll back) the schema migrations of the DB, optionally as a dry-run",
+	Action: LaunchMigrate,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Verbosity level for the Crawler's logs",
+			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+			DefaultText: config.DefaultLogLevel,
+		},
+		&cli.StringFlag{
+			Name:        "psql-endpoint",
+			Usage:       "PSQL enpoint where the crawler stored the gathered info",
+			EnvVars:     []string{"ARMIARMA_PSQL"},
+			DefaultText: config.DefaultPSQLEndpoint,
+		},
+		&cli.StringFlag{
+			Name:  "network",
+			Usage: "Network whose tables are migrated (ethereum, ethereum-el, ipfs, filecoin)",
+			Value: "ethereum",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only list the migrations that would be applied or rolled back",
+		},
+		&cli.IntFlag{
+			Name:  "rollback",
+			Usage: "Number of applied migrations to roll back, from the newest to the oldest",
+		},
+		&cli.BoolFlag{
+			Name:  "status",
+			Usage: "List the applied and the pending migrations",
+		},
+	},
+}
+
+// LaunchMigrate is the function that is called when running `migrate`.
+func LaunchMigrate(c *cli.Context) error {
+	logLevel := config.DefaultLogLevel
+	if c.IsSet("log-level") {
+		logLevel = c.String("log-level")
+	}
+	log.SetLevel(utils.ParseLogLevel(logLevel))
+
+	endpoint := config.DefaultPSQLEndpoint
+	if c.IsSet("psql-endpoint") {
+		endpoint = c.String("psql-endpoint")
+	}
+	if err := postgresOnly("migrate", endpoint); err != nil {
+		return err
+	}
+	network, ok := sampleNetworks[strings.ToLower(c.String("network"))]
+	if !ok {
+		return errors.Errorf("unknown network %s", c.String("network"))
+	}
+	if c.Int("rollback") < 0 {
+		return errors.New("the number of migrations to roll back can't be negative")
+	}
+
+	// the migrations are applied here, not by the client itself
+	dbClient, err := psql.NewDBClient(
+		c.Context,
+		network,
+		endpoint,
+		24*time.Hour,
+		psql.InitializeTables(true),
+		psql.WithActivePeersBackup(false),
+		psql.WithMigrations(false),
+	)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	if c.Bool("status") {
+		err = dbClient.InitSchemaMigrationsTable()
+		if err != nil {
+			return err
+		}
+		applied, err := dbClient.GetAppliedMigrations()
+		if err != nil {
+			return err
+		}
+		pending, err := dbClient.PendingMigrations()
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s (%s)\n", m.Version, m.Name, m.AppliedTime.Format(time.RFC3339))
+		}
+		for _, m := range pending {
+			fmt.Printf("pending  %04d_%s\n", m.Version, m.Name)
+		}
+		return nil
+	}
+
+	action := "applied"
+	var migrations []psql.Migration
+	if c.Int("rollback") > 0 {
+		action = "rolled back"
+		migrations, err = dbClient.RollbackMigrations(c.Int("rollback"), c.Bool("dry-run"))
+	} else {
+		migrations, err = dbClient.ApplyMigrations(c.Bool("dry-run"))
+	}
+	if c.Bool("dry-run") {
+		action = "would be " + action
+	}
+	for _, m := range migrations {
+		fmt.Printf("%s  %04d_%s\n", action, m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		fmt.Println("no migrations to run, nothing to do")
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/db/sqlite"
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/sampling"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	// networks whose peers can be sampled
+	sampleNetworks = map[string]utils.NetworkType{
+		"ethereum":    utils.EthereumNetwork,
+		"ethereum-el": utils.EthereumELNetwork,
+		"ipfs":        utils.IpfsNetwork,
+		"filecoin":    utils.FilecoinNetwork,
+		"waku":        utils.WakuNetwork,
+	}
+)
+
+// PeerSampleCommand contains the peer-sample sub-command configuration.
+var PeerSampleCommand = &cli.Command{
+	Name:   "peer-sample",
+	Usage:  "pick a uniformly random sample of the known peers (optionally stratified), recording its seed in the DB",
+	Action: LaunchPeerSample,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Verbosity level for the Crawler's logs",
+			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+			DefaultText: config.DefaultLogLevel,
+		},
+		&cli.StringFlag{
+			Name:        "psql-endpoint",
+			Usage:       "PSQL enpoint where the crawler stored the gathered info, or sqlite:<file> for a SQLite file",
+			EnvVars:     []string{"ARMIARMA_PSQL"},
+			DefaultText: config.DefaultPSQLEndpoint,
+		},
+		&cli.StringFlag{
+			Name:  "network",
+			Usage: "Network whose known peers are sampled (ethereum, ethereum-el, ipfs, filecoin)",
+			Value: "ethereum",
+		},
+		&cli.IntFlag{
+			Name:  "size",
+			Usage: "Number of peers of the sample",
+			Value: sampling.DefaultSampleSize,
+		},
+		&cli.StringFlag{
+			Name:  "stratify",
+			Usage: "Attribute whose strata are sampled proportionally (client or country, none if empty)",
+		},
+		&cli.Int64Flag{
+			Name:        "seed",
+			Usage:       "Seed of the sample, the same seed over the same known peers gives the same sample",
+			DefaultText: "random",
+		},
+	},
+}
+
+// LaunchPeerSample is the function that is called when running `peer-sample`.
+func LaunchPeerSample(c *cli.Context) error {
+	logLevel := config.DefaultLogLevel
+	if c.IsSet("log-level") {
+		logLevel = c.String("log-level")
+	}
+	log.SetLevel(utils.ParseLogLevel(logLevel))
+
+	endpoint := config.DefaultPSQLEndpoint
+	if c.IsSet("psql-endpoint") {
+		endpoint = c.String("psql-endpoint")
+	}
+	network, ok := sampleNetworks[strings.ToLower(c.String("network"))]
+	if !ok {
+		return errors.Errorf("unknown network %s", c.String("network"))
+	}
+
+	// the candidates can be sampled from the SQLite file of a single-machine crawl too
+	var dbClient storage.Client
+	var err error
+	if file, ok := strings.CutPrefix(endpoint, storage.SQLiteScheme); ok {
+		dbClient, err = sqlite.NewDBClient(
+			c.Context,
+			network,
+			file,
+			24*time.Hour,
+			sqlite.WithActivePeersBackup(false),
+		)
+	} else {
+		dbClient, err = psql.NewDBClient(
+			c.Context,
+			network,
+			endpoint,
+			24*time.Hour,
+			psql.InitializeTables(true),
+			psql.WithActivePeersBackup(false),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	sample, err := sampling.NewSampler(network, dbClient).Sample(c.Int("size"), c.String("stratify"), c.Int64("seed"))
+	if err != nil {
+		return err
+	}
+	content, _ := json.MarshalIndent(sample, "", "  ")
+	fmt.Println(string(content))
+	return nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/crawler"
+)
+
+// PortalCrawlerCommand contains the portal sub-command configuration.
+var PortalCrawlerCommand = &cli.Command{
+	Name:   "portal",
+	Usage:  "crawl the Portal Network through the discv5 DHT, identifying its nodes with the pings of its sub-protocols",
+	Action: LaunchPortalCrawler,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Verbosity level for the Crawler's logs",
+			EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+			DefaultText: config.DefaultLogLevel,
+		},
+		&cli.StringFlag{
+			Name:    "priv-key",
+			Usage:   "String representation of the PrivateKey to be used by the crawler",
+			EnvVars: []string{"ARMIARMA_PRIV_KEY"},
+		},
+		&cli.IntFlag{
+			Name:        "port",
+			Usage:       "UDP port where the crawler listens for discv5",
+			EnvVars:     []string{"ARMIARMA_PORT"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultPort),
+		},
+		&cli.StringFlag{
+			Name:        "metrics-ip",
+			Usage:       "IP in the machine that will expose the metrics of the crawler",
+			EnvVars:     []string{"ARMIARMA_METRICS_IP"},
+			DefaultText: config.DefaultMetricsIP,
+		},
+		&cli.IntFlag{
+			Name:        "metrics-port",
+			Usage:       "Port that the crawler with to expose pprof and prometheus metrics",
+			EnvVars:     []string{"ARMIARMA_METRICS_PORT"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultMetricsPort),
+		},
+		&cli.StringFlag{
+			Name:        "user-agent",
+			Usage:       "Client info that will identify the crawler in the Portal pings",
+			EnvVars:     []string{"ARMIARMA_USER_AGENT"},
+			DefaultText: config.DefaultUserAgent,
+		},
+		&cli.StringFlag{
+			Name:        "psql-endpoint",
+			Aliases:     []string{"db"},
+			Usage:       "PSQL enpoint where the crwaler will submit the all the gathered info, or sqlite:<file> to store it in a SQLite file",
+			EnvVars:     []string{"ARMIARMA_PSQL"},
+			DefaultText: config.DefaultPSQLEndpoint,
+		},
+		&cli.StringSliceFlag{
+			Name:    "bootnode",
+			Usage:   "List of Portal bootnodes (enr:) that the crawler will use to discover more nodes in the network (One --bootnode <bootnode> per bootnode)",
+			EnvVars: []string{"ARMIARMA_BOOTNODES"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "sub-network",
+			Usage:   "Portal sub-networks where the discovered nodes are pinged (state, history, beacon, transaction_index, verkle, transaction_gossip), all of them if none is given (One --sub-network <name> per sub-network)",
+			EnvVars: []string{"ARMIARMA_PORTAL_SUB_NETWORKS"},
+		},
+		&cli.IntFlag{
+			Name:        "ping-workers",
+			Usage:       "Number of nodes pinged concurrently",
+			EnvVars:     []string{"ARMIARMA_PING_WORKERS"},
+			DefaultText: fmt.Sprintf("%d", config.DefaultPortalWorkers),
+		},
+		&cli.StringFlag{
+			Name:        "recrawl-interval",
+			Usage:       "Time after which an already crawled node is pinged again if the walk finds it",
+			EnvVars:     []string{"ARMIARMA_RECRAWL_INTERVAL"},
+			DefaultText: config.DefaultPortalRecrawlInterval,
+		},
+	},
+}
+
+// LaunchPortalCrawler is the function that is called when running `portal`.
+func LaunchPortalCrawler(c *cli.Context) error {
+	log.Infoln("Starting Portal Crawler...")
+
+	conf := config.NewPortalCrawlerConfig()
+	conf.Apply(c)
+
+	// Generate the Portal crawler struct
+	portalCrawler, err := crawler.NewPortalCrawler(c, *conf)
+	if err != nil {
+		return err
+	}
+
+	// launch the subroutines
+	portalCrawler.Run()
+
+	// check the shutdown signal
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+
+	// keep the app running until syscall.SIGTERM
+	sig := <-sigs
+	log.Printf("Received %s signal - Stopping...\n", sig.String())
+	signal.Stop(sigs)
+	portalCrawler.Close()
+
+	return nil
+}
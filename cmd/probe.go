@@ -0,0 +1,103 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/probe"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// ProbeCommand contains the probe sub-command configuration.
+var ProbeCommand = &cli.Command{
+	Name:      "probe",
+	Usage:     "dial a single peer, identify it and run the req/resps of its network (optionally its gossip), printing a JSON report",
+	ArgsUsage: "<enr|multiaddr>",
+	Action:    LaunchProbe,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "network",
+			Usage: "Network of the peer, an Ethereum CL network (i.e. mainnet, holesky) or an IPFS-like one (ipfs, filecoin, waku)",
+			Value: config.DefaultEthNetwork,
+		},
+		&cli.StringFlag{
+			Name:        "fork-digest",
+			Usage:       "Fork digest of the Ethereum CL network that we announce to the peer",
+			DefaultText: "the one of the ENR, or the one of the network",
+		},
+		&cli.StringFlag{
+			Name:  "user-agent",
+			Usage: "Agent name that will identify the probe to the peer",
+			Value: probe.DefaultUserAgent,
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Max time to connect to the peer, and to get it identified",
+			Value: probe.DefaultTimeout,
+		},
+		&cli.DurationFlag{
+			Name:  "gossip-duration",
+			Usage: "Time that we stay subscribed to the gossipsub topics with the peer (0 to skip the gossip)",
+		},
+		&cli.StringSliceFlag{
+			Name:        "gossip-topic",
+			Usage:       "Gossipsub topic to subscribe during the gossip-duration (One --gossip-topic <topic> per topic)",
+			DefaultText: "beacon_block topic of the fork digest in Ethereum CL",
+		},
+	},
+}
+
+// LaunchProbe is the function that is called when running `probe`.
+func LaunchProbe(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("probe needs the ENR or the multiaddr of the peer")
+	}
+	target, err := probe.ParseTarget(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	network := strings.ToLower(c.String("network"))
+	conf := probe.DefaultConfig(utils.EthereumNetwork)
+	if netType, ok := config.IpfsNetworks[network]; ok {
+		conf.Network = netType
+	} else {
+		preset, ok := eth.Preset(network)
+		if !ok {
+			return errors.Errorf("unsupported network %q", network)
+		}
+		// the fork digest of the ENR is the one of the peer, unless it is overridden
+		forkDigest := c.String("fork-digest")
+		if forkDigest == "" {
+			forkDigest = target.ForkDigest()
+		}
+		if forkDigest == "" {
+			forkDigest = preset.ForkDigest
+		}
+		if forkDigest != "" {
+			validDigest, valid := eth.CheckValidForkDigest(forkDigest)
+			if !valid {
+				return errors.Errorf("invalid fork digest %q", forkDigest)
+			}
+			forkDigest = validDigest
+		}
+		conf.ForkDigest = forkDigest
+	}
+	conf.UserAgent = c.String("user-agent")
+	conf.Timeout = c.Duration("timeout")
+	conf.GossipDuration = c.Duration("gossip-duration")
+	conf.GossipTopics = c.StringSlice("gossip-topic")
+
+	report, err := probe.Probe(c.Context, target, conf)
+	if err != nil {
+		return err
+	}
+	return printJSON(report)
+}
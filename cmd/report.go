@@ -0,0 +1,159 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/analysis"
+	"github.com/migalabs/armiarma/pkg/config"
+	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/export"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+const JSONReportFormat = "json"
+
+// ReportCommand contains the report sub-command configuration.
+var ReportCommand = &cli.Command{
+	Name:  "report",
+	Usage: "generate analysis reports out of the data stored in the DB",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "churn",
+			Usage:  "daily join/leave rates, median session length and peer half-life out of the connection events",
+			Action: LaunchChurnReport,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:        "log-level",
+					Usage:       "Verbosity level for the Crawler's logs",
+					EnvVars:     []string{"ARMIARMA_LOG_LEVEL"},
+					DefaultText: config.DefaultLogLevel,
+				},
+				&cli.StringFlag{
+					Name:        "psql-endpoint",
+					Usage:       "PSQL enpoint where the crawler stored the gathered info",
+					EnvVars:     []string{"ARMIARMA_PSQL"},
+					DefaultText: config.DefaultPSQLEndpoint,
+				},
+				&cli.StringFlag{
+					Name:  "network",
+					Usage: "Network whose DB is analysed (ethereum, ethereum-el, ipfs, filecoin, waku)",
+					Value: "ethereum",
+				},
+				&cli.StringFlag{
+					Name:  "since",
+					Usage: "Only analyse the connections from this time on (RFC3339 or YYYY-MM-DD)",
+				},
+				&cli.StringFlag{
+					Name:  "until",
+					Usage: "Only analyse the connections before this time (RFC3339 or YYYY-MM-DD)",
+				},
+				&cli.DurationFlag{
+					Name:  "presence-grace",
+					Usage: "Time from the edges of the window within which the first (last) sighting of a peer isn't counted as a join (leave)",
+					Value: analysis.DefaultPresenceGrace,
+				},
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Format of the report (json, or csv for the daily series)",
+					Value: JSONReportFormat,
+				},
+				&cli.StringFlag{
+					Name:  "output",
+					Usage: "File where the report is written (stdout if empty)",
+				},
+			},
+		},
+	},
+}
+
+// LaunchChurnReport is the function that is called when running `report churn`.
+func LaunchChurnReport(c *cli.Context) error {
+	logLevel := config.DefaultLogLevel
+	if c.IsSet("log-level") {
+		logLevel = c.String("log-level")
+	}
+	log.SetLevel(utils.ParseLogLevel(logLevel))
+
+	endpoint := config.DefaultPSQLEndpoint
+	if c.IsSet("psql-endpoint") {
+		endpoint = c.String("psql-endpoint")
+	}
+	if err := postgresOnly("report", endpoint); err != nil {
+		return err
+	}
+	network, ok := sampleNetworks[strings.ToLower(c.String("network"))]
+	if !ok {
+		return errors.Errorf("unknown network %s", c.String("network"))
+	}
+	since, err := parseExportTime(c.String("since"))
+	if err != nil {
+		return err
+	}
+	until, err := parseExportTime(c.String("until"))
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(c.String("format"))
+	if format != JSONReportFormat && format != export.CSVFormat {
+		return errors.Errorf("unknown report format %s (%s, %s)", format, JSONReportFormat, export.CSVFormat)
+	}
+
+	dbClient, err := psql.NewDBClient(
+		c.Context,
+		network,
+		endpoint,
+		24*time.Hour,
+		psql.WithActivePeersBackup(false),
+		psql.WithMigrations(false),
+	)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	sessions, err := dbClient.GetConnSessions(since, until)
+	if err != nil {
+		return err
+	}
+	report := analysis.ComputeChurn(sessions, since, until, c.Duration("presence-grace"))
+
+	var out io.Writer = os.Stdout
+	if c.String("output") != "" {
+		file, err := os.Create(c.String("output"))
+		if err != nil {
+			return errors.Wrap(err, "unable to create the report file")
+		}
+		defer file.Close()
+		out = file
+	}
+	switch format {
+	case export.CSVFormat:
+		err = report.WriteDailyCSV(out)
+	default:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to write the churn report")
+	}
+	log.WithFields(log.Fields{
+		"peers":          report.Peers,
+		"sessions":       report.Sessions,
+		"median-session": time.Duration(report.MedianSessionSecs * float64(time.Second)),
+		"half-life":      time.Duration(report.HalfLifeSecs * float64(time.Second)),
+		"half-life-seen": report.HalfLifeReached,
+	}).Info("churn report generated")
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2021 Miga Labs
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/tui"
+)
+
+// TuiCommand contains the tui sub-command configuration.
+var TuiCommand = &cli.Command{
+	Name:   "tui",
+	Usage:  "live terminal dashboard of a running crawler (dial and discovery rates, clients of the connected peers, dial errors)",
+	Action: LaunchTui,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "endpoint",
+			Usage:   "Metrics endpoint (ip:port) of the running crawler",
+			EnvVars: []string{"ARMIARMA_STATUS_ENDPOINT"},
+			Value:   fmt.Sprintf("localhost:%d", config.DefaultMetricsPort),
+		},
+		&cli.StringFlag{
+			Name:        "network",
+			Usage:       "Network whose crawler is shown, when several crawlers share the endpoint (i.e. mainnet, holesky, ipfs)",
+			DefaultText: "first one",
+		},
+		&cli.DurationFlag{
+			Name:  "refresh",
+			Usage: "Interval between the refreshes of the dashboard",
+			Value: tui.DefaultRefreshInterval,
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Usage: "Rows of each of the breakdowns (0 to show all of them)",
+			Value: tui.DefaultTopEntries,
+		},
+	},
+}
+
+// LaunchTui is the function that is called when running `tui`.
+func LaunchTui(c *cli.Context) error {
+	scraper := tui.NewScraper(c.String("endpoint"), DefaultStatusTimeout)
+	dashboard := tui.NewDashboard(scraper, c.String("network"), c.Duration("refresh"), c.Int("top"))
+	return dashboard.Run(c.Context, os.Stdin, os.Stdout)
+}
@@ -44,7 +44,19 @@ func main() {
 		EnableBashCompletion: true,
 		Commands: []*cli.Command{
 			cmd.Eth2CrawlerCommand,
-			// cmd.IpfsCrawlerCommand,
+			cmd.EthELCrawlerCommand,
+			cmd.PortalCrawlerCommand,
+			cmd.EnrBackfillCommand,
+			cmd.IpfsCrawlerCommand,
+			cmd.DialQueueCommand,
+			cmd.TuiCommand,
+			cmd.ProbeCommand,
+			cmd.EnrCommand,
+			cmd.AddrCommand,
+			cmd.PeerSampleCommand,
+			cmd.MigrateCommand,
+			cmd.ExportCommand,
+			cmd.ReportCommand,
 		},
 	}
 
@@ -0,0 +1,229 @@
+package analysis
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/export"
+)
+
+// DefaultPresenceGrace is the time from the start (end) of the analysed window within which a peer
+// first (last) seen isn't counted as a join (leave), as it was likely there before (after) it
+var DefaultPresenceGrace = 24 * time.Hour
+
+// DailyChurn is the churn of the network on a given (UTC) day
+type DailyChurn struct {
+	Day         time.Time `json:"day"`
+	ActivePeers int       `json:"active_peers"`
+	Joins       int       `json:"joins"`
+	Leaves      int       `json:"leaves"`
+	JoinRate    float64   `json:"join_rate"`
+	LeaveRate   float64   `json:"leave_rate"`
+	// sessions started on the day
+	Sessions          int     `json:"sessions"`
+	MedianSessionSecs float64 `json:"median_session_secs"`
+}
+
+// ChurnReport summarizes the stability of the network over the analysed window
+type ChurnReport struct {
+	From              time.Time `json:"from"`
+	To                time.Time `json:"to"`
+	Peers             int       `json:"peers"`
+	Sessions          int       `json:"sessions"`
+	MedianSessionSecs float64   `json:"median_session_secs"`
+	// time after which half of the peers have left the network (Kaplan-Meier over their lifetimes,
+	// the peers still there at the end of the window being censored)
+	HalfLifeSecs float64 `json:"half_life_secs"`
+	// whether the half of the peers left within the window, otherwise the half-life is longer than it
+	HalfLifeReached bool          `json:"half_life_reached"`
+	Days            []*DailyChurn `json:"days"`
+}
+
+type peerLifetime struct {
+	first time.Time
+	last  time.Time
+}
+
+// ComputeChurn computes the churn report of the given connection sessions. The window defaults
+// to the span of the sessions when from or to are zero
+func ComputeChurn(sessions []models.ConnSession, from, to time.Time, grace time.Duration) *ChurnReport {
+	valid := make([]models.ConnSession, 0, len(sessions))
+	for _, s := range sessions {
+		if s.End.Before(s.Start) {
+			continue
+		}
+		valid = append(valid, s)
+	}
+	if len(valid) > 0 && (from.IsZero() || to.IsZero()) {
+		first, last := valid[0].Start, valid[0].End
+		for _, s := range valid {
+			if s.Start.Before(first) {
+				first = s.Start
+			}
+			if s.End.After(last) {
+				last = s.End
+			}
+		}
+		if from.IsZero() {
+			from = first
+		}
+		if to.IsZero() {
+			to = last
+		}
+	}
+	report := &ChurnReport{
+		From:     from.UTC(),
+		To:       to.UTC(),
+		Sessions: len(valid),
+		Days:     make([]*DailyChurn, 0),
+	}
+
+	lifetimes := make(map[string]*peerLifetime)
+	durations := make([]time.Duration, 0, len(valid))
+	for _, s := range valid {
+		durations = append(durations, s.End.Sub(s.Start))
+		l, ok := lifetimes[s.PeerID]
+		if !ok {
+			lifetimes[s.PeerID] = &peerLifetime{first: s.Start, last: s.End}
+			continue
+		}
+		if s.Start.Before(l.first) {
+			l.first = s.Start
+		}
+		if s.End.After(l.last) {
+			l.last = s.End
+		}
+	}
+	report.Peers = len(lifetimes)
+	report.MedianSessionSecs = median(durations).Seconds()
+
+	joinsFrom := from.Add(grace)
+	leavesUntil := to.Add(-grace)
+
+	// daily series
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		daily := &DailyChurn{Day: day}
+		for _, l := range lifetimes {
+			if l.first.Before(next) && !l.last.Before(day) {
+				daily.ActivePeers++
+			}
+			if !l.first.Before(day) && l.first.Before(next) && !l.first.Before(joinsFrom) {
+				daily.Joins++
+			}
+			if !l.last.Before(day) && l.last.Before(next) && l.last.Before(leavesUntil) {
+				daily.Leaves++
+			}
+		}
+		if daily.ActivePeers > 0 {
+			daily.JoinRate = float64(daily.Joins) / float64(daily.ActivePeers)
+			daily.LeaveRate = float64(daily.Leaves) / float64(daily.ActivePeers)
+		}
+		dayDurations := make([]time.Duration, 0)
+		for _, s := range valid {
+			if !s.Start.Before(day) && s.Start.Before(next) {
+				dayDurations = append(dayDurations, s.End.Sub(s.Start))
+			}
+		}
+		daily.Sessions = len(dayDurations)
+		daily.MedianSessionSecs = median(dayDurations).Seconds()
+		report.Days = append(report.Days, daily)
+	}
+
+	halfLife, reached := halfLife(lifetimes, leavesUntil)
+	report.HalfLifeSecs = halfLife.Seconds()
+	report.HalfLifeReached = reached
+	return report
+}
+
+// halfLife returns the time at which the Kaplan-Meier survival of the peers drops to one half,
+// the peers last seen after leavesUntil being censored (still in the network)
+func halfLife(lifetimes map[string]*peerLifetime, leavesUntil time.Time) (time.Duration, bool) {
+	type observation struct {
+		lifetime time.Duration
+		left     bool
+	}
+	observations := make([]observation, 0, len(lifetimes))
+	for _, l := range lifetimes {
+		observations = append(observations, observation{
+			lifetime: l.last.Sub(l.first),
+			left:     l.last.Before(leavesUntil),
+		})
+	}
+	sort.Slice(observations, func(i, j int) bool {
+		return observations[i].lifetime < observations[j].lifetime
+	})
+
+	survival := 1.0
+	atRisk := len(observations)
+	for i := 0; i < len(observations); {
+		lifetime := observations[i].lifetime
+		left, seen := 0, 0
+		for ; i < len(observations) && observations[i].lifetime == lifetime; i++ {
+			seen++
+			if observations[i].left {
+				left++
+			}
+		}
+		survival *= 1 - float64(left)/float64(atRisk)
+		if survival <= 0.5 {
+			return lifetime, true
+		}
+		atRisk -= seen
+	}
+	return 0, false
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// DailyChurnColumns are the columns of the CSV churn report, one row per day
+var DailyChurnColumns = []export.Column{
+	{Name: "day", Kind: export.TimeKind},
+	{Name: "active_peers", Kind: export.IntKind},
+	{Name: "joins", Kind: export.IntKind},
+	{Name: "leaves", Kind: export.IntKind},
+	{Name: "join_rate", Kind: export.FloatKind},
+	{Name: "leave_rate", Kind: export.FloatKind},
+	{Name: "sessions", Kind: export.IntKind},
+	{Name: "median_session_secs", Kind: export.FloatKind},
+}
+
+// WriteDailyCSV writes the daily series of the report as CSV
+func (r *ChurnReport) WriteDailyCSV(w io.Writer) error {
+	csvW, err := export.NewCSVWriter(w, DailyChurnColumns)
+	if err != nil {
+		return err
+	}
+	for _, d := range r.Days {
+		err = csvW.WriteRow([]interface{}{
+			d.Day,
+			d.ActivePeers,
+			d.Joins,
+			d.Leaves,
+			d.JoinRate,
+			d.LeaveRate,
+			d.Sessions,
+			d.MedianSessionSecs,
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to write the daily churn")
+		}
+	}
+	return csvW.Close()
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func Test_ComputeChurn(t *testing.T) {
+	day0 := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	at := func(day int, clock time.Duration) time.Time {
+		return day0.Add(time.Duration(day)*24*time.Hour + clock)
+	}
+	sessions := []models.ConnSession{
+		// there since the beginning and still there at the end
+		{PeerID: "A", Start: at(0, 1*time.Hour), End: at(0, 2*time.Hour)},
+		{PeerID: "A", Start: at(2, 10*time.Hour), End: at(2, 12*time.Hour)},
+		// joined and left on the second day
+		{PeerID: "B", Start: at(1, 5*time.Hour), End: at(1, 6*time.Hour)},
+		{PeerID: "C", Start: at(1, 8*time.Hour), End: at(1, 11*time.Hour)},
+		{PeerID: "C", Start: at(1, 20*time.Hour), End: at(1, 21*time.Hour)},
+		// there since the beginning, left on the first day
+		{PeerID: "D", Start: at(0, 30*time.Minute), End: at(0, 40*time.Minute)},
+		// inconsistent session
+		{PeerID: "E", Start: at(1, 2*time.Hour), End: at(1, time.Hour)},
+	}
+	report := ComputeChurn(sessions, day0, at(3, 0), DefaultPresenceGrace)
+
+	require.Equal(t, 4, report.Peers)
+	require.Equal(t, 6, report.Sessions)
+	require.Equal(t, time.Hour.Seconds(), report.MedianSessionSecs)
+	// D and B leave after 10m and 1h, A is censored
+	require.True(t, report.HalfLifeReached)
+	require.Equal(t, time.Hour.Seconds(), report.HalfLifeSecs)
+
+	require.Len(t, report.Days, 3)
+	first, second, third := report.Days[0], report.Days[1], report.Days[2]
+	require.Equal(t, day0, first.Day)
+	require.Equal(t, 2, first.ActivePeers)
+	require.Equal(t, 0, first.Joins)
+	require.Equal(t, 1, first.Leaves)
+	require.Equal(t, 0.5, first.LeaveRate)
+	require.Equal(t, (35 * time.Minute).Seconds(), first.MedianSessionSecs)
+
+	require.Equal(t, 3, second.ActivePeers)
+	require.Equal(t, 2, second.Joins)
+	require.Equal(t, 2, second.Leaves)
+	require.InDelta(t, 2.0/3.0, second.JoinRate, 0.0001)
+	require.Equal(t, 3, second.Sessions)
+	require.Equal(t, time.Hour.Seconds(), second.MedianSessionSecs)
+
+	// the peers last seen within the grace period aren't counted as leaving
+	require.Equal(t, 1, third.ActivePeers)
+	require.Equal(t, 0, third.Leaves)
+
+	// the window defaults to the span of the sessions
+	report = ComputeChurn(sessions, time.Time{}, time.Time{}, DefaultPresenceGrace)
+	require.Equal(t, at(0, 30*time.Minute), report.From)
+	require.Equal(t, at(2, 12*time.Hour), report.To)
+
+	// nobody left
+	report = ComputeChurn(sessions[:2], day0, at(3, 0), DefaultPresenceGrace)
+	require.False(t, report.HalfLifeReached)
+
+	var buf bytes.Buffer
+	require.NoError(t, ComputeChurn(sessions, day0, at(3, 0), DefaultPresenceGrace).WriteDailyCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, "day,active_peers,joins,leaves,join_rate,leave_rate,sessions,median_session_secs", lines[0])
+	require.Equal(t, "2023-03-01T00:00:00Z,2,0,1,0,0.5,2,2100", lines[1])
+}
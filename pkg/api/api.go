@@ -0,0 +1,309 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/libp2p/go-libp2p/core/peer"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	// Path is the prefix of the http endpoints of the API
+	Path = "/api/v1/"
+
+	// window of the DB aggregates if the request doesn't give one
+	DefaultWindow = 24 * time.Hour
+	// window of the gossip topic rates if the request doesn't give one
+	DefaultTopicsWindow = 1 * time.Hour
+	// max number of peers listed by default
+	DefaultPeersLimit = 1000
+)
+
+type database interface {
+	GetActivePeers(window time.Duration) ([]models.PeerSummary, error)
+	GetPeerSummary(pID peer.ID) (*models.PeerSummary, error)
+	GetPeersByClient(window time.Duration) ([]models.ClientCount, error)
+	GetPeerTimeline(pID peer.ID) ([]models.PeerTimelineEvent, error)
+	GetTopicRates(window time.Duration) ([]models.TopicRate, error)
+	GetPeerAddressHistory(pID peer.ID) ([]models.PeerAddressRecord, error)
+	GetMovedPeers(window time.Duration) (models.MovedPeers, error)
+	GetInconsistentIdentities() ([]models.IdentityLink, error)
+	QueryDataset(dataset string, filter models.DatasetFilter) ([]map[string]interface{}, error)
+}
+
+type liveState interface {
+	IsConnected(pID peer.ID) bool
+	OpenConnections() int
+}
+
+// API serves the live state of the crawler and the recent aggregates of the DB as JSON:
+//   - GET /api/v1/peers?window=24h&client=lighthouse&limit=N lists the active peers
+//   - GET /api/v1/peers/<peer-id> returns the state of a peer, its connections and its address history
+//   - GET /api/v1/summary?window=24h returns the open connections, the clients and the last metrics
+//   - GET /api/v1/topics?window=1h returns the message rates of the gossip topics
+//   - GET /api/v1/moved-peers?window=24h returns how many peers showed up at a new address
+//   - GET /api/v1/identity-mismatches lists the nodes whose discv5 and libp2p identities don't match
+//   - POST /api/v1/graphql queries the peers, connection events, ENRs and gossip datasets of the DB
+type API struct {
+	network string
+	db      database
+	live    liveState
+	graphql http.Handler
+
+	// last summaries of the metrics modules, received as a summary exporter
+	m             sync.RWMutex
+	summaryTime   time.Time
+	lastSummaries map[string]map[string]interface{}
+}
+
+func NewAPI(network string, db database, live liveState) *API {
+	schema := graphql.MustParseSchema(
+		graphqlSchema,
+		&graphqlResolver{db: db},
+		graphql.UseFieldResolvers(),
+		graphql.MaxDepth(MaxGraphQLDepth),
+	)
+	return &API{
+		network:       network,
+		db:            db,
+		live:          live,
+		graphql:       &relay.Handler{Schema: schema},
+		lastSummaries: make(map[string]map[string]interface{}),
+	}
+}
+
+// Export keeps the summaries of the last round of metrics (as a metrics.SummaryExporter)
+func (a *API) Export(t time.Time, summaries map[string]map[string]interface{}) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.summaryTime = t
+	a.lastSummaries = summaries
+	return nil
+}
+
+// PeerState is the state of a peer in the DB, and whether we are connected to it right now
+type PeerState struct {
+	models.PeerSummary
+	Connected bool                       `json:"connected"`
+	Timeline  []models.PeerTimelineEvent `json:"timeline,omitempty"`
+	Addresses []models.PeerAddressRecord `json:"addresses,omitempty"`
+}
+
+// Summary is the overview of the crawler and the network
+type Summary struct {
+	Network         string                            `json:"network"`
+	Timestamp       time.Time                         `json:"timestamp"`
+	OpenConnections int                               `json:"open_connections"`
+	ActivePeers     int64                             `json:"active_peers"`
+	Clients         []models.ClientCount              `json:"clients"`
+	MetricsTime     time.Time                         `json:"metrics_time"`
+	Metrics         map[string]map[string]interface{} `json:"metrics"`
+}
+
+// Handler returns the http handler of all the endpoints of the API
+func (a *API) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := strings.Trim(strings.TrimPrefix(r.URL.Path, Path), "/")
+		if route == "graphql" && r.Method == http.MethodPost {
+			a.graphql.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch {
+		case route == "peers":
+			a.servePeers(w, r)
+		case strings.HasPrefix(route, "peers/"):
+			a.servePeer(w, strings.TrimPrefix(route, "peers/"))
+		case route == "summary":
+			a.serveSummary(w, r)
+		case route == "topics":
+			a.serveTopics(w, r)
+		case route == "moved-peers":
+			a.serveMovedPeers(w, r)
+		case route == "identity-mismatches":
+			a.serveIdentityMismatches(w)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func (a *API) servePeers(w http.ResponseWriter, r *http.Request) {
+	window, ok := parseWindow(w, r, DefaultWindow)
+	if !ok {
+		return
+	}
+	limit := DefaultPeersLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 0 {
+			http.Error(w, "invalid limit "+limitStr, http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+	client := strings.ToLower(r.URL.Query().Get("client"))
+
+	summaries, err := a.db.GetActivePeers(window)
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the active peers")
+		http.Error(w, "unable to read the active peers", http.StatusInternalServerError)
+		return
+	}
+	peers := make([]PeerState, 0, len(summaries))
+	for _, summary := range summaries {
+		if client != "" && strings.ToLower(summary.ClientName) != client {
+			continue
+		}
+		if limit > 0 && len(peers) >= limit {
+			break
+		}
+		peers = append(peers, PeerState{
+			PeerSummary: summary,
+			Connected:   a.live.IsConnected(summary.PeerID),
+		})
+	}
+	writeJSON(w, peers)
+}
+
+func (a *API) servePeer(w http.ResponseWriter, peerStr string) {
+	peerID, err := peer.Decode(peerStr)
+	if err != nil {
+		http.Error(w, "invalid peer id "+peerStr, http.StatusBadRequest)
+		return
+	}
+	summary, err := a.db.GetPeerSummary(peerID)
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the peer")
+		http.Error(w, "unable to read the peer", http.StatusInternalServerError)
+		return
+	}
+	if summary == nil {
+		http.Error(w, "unknown peer "+peerStr, http.StatusNotFound)
+		return
+	}
+	timeline, err := a.db.GetPeerTimeline(peerID)
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the timeline of the peer")
+		http.Error(w, "unable to read the timeline of the peer", http.StatusInternalServerError)
+		return
+	}
+	addresses, err := a.db.GetPeerAddressHistory(peerID)
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the address history of the peer")
+		http.Error(w, "unable to read the address history of the peer", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, PeerState{
+		PeerSummary: *summary,
+		Connected:   a.live.IsConnected(peerID),
+		Timeline:    timeline,
+		Addresses:   addresses,
+	})
+}
+
+func (a *API) serveSummary(w http.ResponseWriter, r *http.Request) {
+	window, ok := parseWindow(w, r, DefaultWindow)
+	if !ok {
+		return
+	}
+	clients, err := a.db.GetPeersByClient(window)
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the summary")
+		http.Error(w, "unable to read the clients", http.StatusInternalServerError)
+		return
+	}
+	summary := Summary{
+		Network:         a.network,
+		Timestamp:       time.Now(),
+		OpenConnections: a.live.OpenConnections(),
+		Clients:         clients,
+	}
+	for _, client := range clients {
+		summary.ActivePeers += client.Peers
+	}
+	a.m.RLock()
+	summary.MetricsTime = a.summaryTime
+	summary.Metrics = a.lastSummaries
+	a.m.RUnlock()
+	writeJSON(w, summary)
+}
+
+func (a *API) serveTopics(w http.ResponseWriter, r *http.Request) {
+	window, ok := parseWindow(w, r, DefaultTopicsWindow)
+	if !ok {
+		return
+	}
+	rates, err := a.db.GetTopicRates(window)
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the topic rates")
+		http.Error(w, "unable to read the topic rates", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, rates)
+}
+
+func (a *API) serveMovedPeers(w http.ResponseWriter, r *http.Request) {
+	window, ok := parseWindow(w, r, DefaultWindow)
+	if !ok {
+		return
+	}
+	moved, err := a.db.GetMovedPeers(window)
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the moved peers")
+		http.Error(w, "unable to read the moved peers", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, moved)
+}
+
+func (a *API) serveIdentityMismatches(w http.ResponseWriter) {
+	links, err := a.db.GetInconsistentIdentities()
+	if err != nil {
+		log.WithError(err).Warn("unable to serve the identity mismatches")
+		http.Error(w, "unable to read the identity mismatches", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, links)
+}
+
+// parseWindow returns the window of the request, answering with a bad request if it is invalid
+func parseWindow(w http.ResponseWriter, r *http.Request, def time.Duration) (time.Duration, bool) {
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		return def, true
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		http.Error(w, "invalid window "+windowStr, http.StatusBadRequest)
+		return 0, false
+	}
+	return window, true
+}
+
+// writeJSON encodes the whole response before writing it, so that an encoding error
+// (i.e. a NaN in the metrics) ends up as an error instead of a truncated body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		log.WithError(err).Warn("unable to encode api response")
+		http.Error(w, "unable to encode the response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	testPeer1, _ = peer.Decode("16Uiu2HAm4cDNiYEDVddDbkb6PUFQRrk6Jxo9WqQULNHE2UbYTJE7")
+	testPeer2, _ = peer.Decode("12D3KooW9pdHR2n4xvYU1RBEgrJMH1kd557QSXYURzEFWeEECjGn")
+)
+
+type fakeDB struct {
+	window  time.Duration
+	filters map[string]models.DatasetFilter
+}
+
+func (d *fakeDB) GetActivePeers(window time.Duration) ([]models.PeerSummary, error) {
+	d.window = window
+	return []models.PeerSummary{
+		{PeerID: testPeer1, ClientName: "lighthouse"},
+		{PeerID: testPeer2, ClientName: "prysm"},
+	}, nil
+}
+
+func (d *fakeDB) GetPeerSummary(pID peer.ID) (*models.PeerSummary, error) {
+	if pID != testPeer1 {
+		return nil, nil
+	}
+	return &models.PeerSummary{PeerID: testPeer1, ClientName: "lighthouse"}, nil
+}
+
+func (d *fakeDB) GetPeersByClient(window time.Duration) ([]models.ClientCount, error) {
+	return []models.ClientCount{{Client: "lighthouse", Version: "v4.5.0", Peers: 3}, {Client: "prysm", Peers: 2}}, nil
+}
+
+func (d *fakeDB) GetPeerTimeline(pID peer.ID) ([]models.PeerTimelineEvent, error) {
+	return []models.PeerTimelineEvent{{Direction: "outbound"}}, nil
+}
+
+func (d *fakeDB) GetTopicRates(window time.Duration) ([]models.TopicRate, error) {
+	d.window = window
+	return []models.TopicRate{{Topic: "beacon_block", Messages: 60, MsgsPerMinute: 1}}, nil
+}
+
+func (d *fakeDB) GetPeerAddressHistory(pID peer.ID) ([]models.PeerAddressRecord, error) {
+	return []models.PeerAddressRecord{{IP: "1.2.3.4", Port: 9000}, {IP: "5.6.7.8", Port: 9000}}, nil
+}
+
+func (d *fakeDB) GetMovedPeers(window time.Duration) (models.MovedPeers, error) {
+	d.window = window
+	return models.MovedPeers{Window: window.String(), Peers: 10, MovedPeers: 2, Moves: 3}, nil
+}
+
+func (d *fakeDB) GetInconsistentIdentities() ([]models.IdentityLink, error) {
+	return []models.IdentityLink{{NodeID: "a1b2", PeerID: testPeer1.String(), Source: models.DialIdentity, Inconsistency: models.PeerIDMismatch}}, nil
+}
+
+func (d *fakeDB) QueryDataset(dataset string, filter models.DatasetFilter) ([]map[string]interface{}, error) {
+	if d.filters == nil {
+		d.filters = make(map[string]models.DatasetFilter)
+	}
+	d.filters[dataset] = filter
+	ts := time.Date(2023, time.January, 2, 3, 4, 5, 0, time.UTC)
+	var rows []map[string]interface{}
+	switch dataset {
+	case "peers":
+		rows = []map[string]interface{}{
+			{"peer_id": testPeer1.String(), "client_name": "lighthouse", "port": int64(9000), "deprecated": false, "last_activity": ts},
+			{"peer_id": testPeer2.String(), "client_name": nil},
+		}
+	case "conn_events":
+		rows = []map[string]interface{}{
+			{"peer_id": testPeer1.String(), "direction": "outbound", "conn_time": ts, "latency": int64(120)},
+		}
+	case "eth_nodes":
+		rows = []map[string]interface{}{
+			{"node_id": "a1b2", "peer_id": testPeer1.String(), "seq": int64(1680000000000)},
+		}
+	}
+	if filter.PeerID != "" {
+		filtered := make([]map[string]interface{}, 0)
+		for _, row := range rows {
+			if row["peer_id"] == filter.PeerID {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+	if filter.Limit > 0 && len(rows) > filter.Limit {
+		rows = rows[:filter.Limit]
+	}
+	return rows, nil
+}
+
+type fakeLive struct{}
+
+func (fakeLive) IsConnected(pID peer.ID) bool { return pID == testPeer1 }
+func (fakeLive) OpenConnections() int         { return 42 }
+
+func get(t *testing.T, h http.Handler, url string, v interface{}) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if rec.Code == http.StatusOK && v != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	return rec.Code
+}
+
+func TestAPI(t *testing.T) {
+	db := &fakeDB{}
+	a := NewAPI("ethereum", db, fakeLive{})
+	h := a.Handler()
+
+	var peers []PeerState
+	require.Equal(t, http.StatusOK, get(t, h, "/api/v1/peers?window=1h&client=Lighthouse", &peers))
+	require.Equal(t, time.Hour, db.window)
+	require.Len(t, peers, 1)
+	require.Equal(t, testPeer1, peers[0].PeerID)
+	require.True(t, peers[0].Connected)
+
+	require.Equal(t, http.StatusOK, get(t, h, "/api/v1/peers?limit=1", &peers))
+	require.Equal(t, DefaultWindow, db.window)
+	require.Len(t, peers, 1)
+	require.Equal(t, http.StatusBadRequest, get(t, h, "/api/v1/peers?window=forever", nil))
+
+	var state PeerState
+	require.Equal(t, http.StatusOK, get(t, h, "/api/v1/peers/"+testPeer1.String(), &state))
+	require.Equal(t, "lighthouse", state.ClientName)
+	require.Len(t, state.Timeline, 1)
+	require.Len(t, state.Addresses, 2)
+	require.Equal(t, http.StatusNotFound, get(t, h, "/api/v1/peers/"+testPeer2.String(), nil))
+	require.Equal(t, http.StatusBadRequest, get(t, h, "/api/v1/peers/not-a-peer", nil))
+
+	a.Export(time.Now(), map[string]map[string]interface{}{"crawler": {"total_peers": 5}})
+	var summary Summary
+	require.Equal(t, http.StatusOK, get(t, h, "/api/v1/summary", &summary))
+	require.Equal(t, 42, summary.OpenConnections)
+	require.Equal(t, int64(5), summary.ActivePeers)
+	require.Equal(t, float64(5), summary.Metrics["crawler"]["total_peers"])
+
+	var rates []models.TopicRate
+	require.Equal(t, http.StatusOK, get(t, h, "/api/v1/topics", &rates))
+	require.Equal(t, DefaultTopicsWindow, db.window)
+	require.Len(t, rates, 1)
+
+	var moved models.MovedPeers
+	require.Equal(t, http.StatusOK, get(t, h, "/api/v1/moved-peers?window=6h", &moved))
+	require.Equal(t, 6*time.Hour, db.window)
+	require.Equal(t, int64(2), moved.MovedPeers)
+
+	var links []models.IdentityLink
+	require.Equal(t, http.StatusOK, get(t, h, "/api/v1/identity-mismatches", &links))
+	require.Len(t, links, 1)
+	require.Equal(t, models.PeerIDMismatch, links[0].Inconsistency)
+
+	require.Equal(t, http.StatusNotFound, get(t, h, "/api/v1/unknown", nil))
+}
@@ -0,0 +1,581 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	// rows of each page of the GraphQL queries if the query doesn't give a limit
+	DefaultGraphQLLimit = 100
+	// max rows of each page of the GraphQL queries
+	MaxGraphQLLimit = 1000
+	// max nesting of the GraphQL queries (i.e. peers > connEvents > peer > enrs)
+	MaxGraphQLDepth = 8
+)
+
+// graphqlSchema exposes the exported datasets of the DB, where each list is a page of the rows within
+// the [since, until) range (open-ended if not given) in chronological order, and the rows of the
+// different datasets are linked through their peer IDs
+const graphqlSchema = `
+	schema {
+		query: Query
+	}
+
+	scalar Time
+
+	type Query {
+		peer(peerId: String!): Peer
+		peers(since: Time, until: Time, limit: Int, offset: Int): PeerPage!
+		connEvents(since: Time, until: Time, peerId: String, limit: Int, offset: Int): ConnEventPage!
+		enrs(since: Time, until: Time, peerId: String, limit: Int, offset: Int): EnrPage!
+		gossipMessages(since: Time, until: Time, peerId: String, limit: Int, offset: Int): GossipMessagePage!
+		gossipScores(since: Time, until: Time, peerId: String, limit: Int, offset: Int): GossipScorePage!
+	}
+
+	type PageInfo {
+		offset: Int!
+		limit: Int!
+		hasNextPage: Boolean!
+	}
+
+	type Peer {
+		peerId: String!
+		network: String
+		networkName: String
+		ip: String
+		port: Int
+		countryCode: String
+		clientName: String
+		clientVersion: String
+		userAgent: String
+		clientOs: String
+		protocolVersion: String
+		securityProtocol: String
+		origin: String
+		latency: Int
+		deprecated: Boolean
+		attempted: Boolean
+		connAttempts: Int
+		connSuccesses: Int
+		lastActivity: Time
+		lastConnAttempt: Time
+		lastError: String
+		connEvents(since: Time, until: Time, limit: Int, offset: Int): ConnEventPage!
+		enrs(since: Time, until: Time, limit: Int, offset: Int): EnrPage!
+		# messages that the peer delivered first
+		gossipMessages(since: Time, until: Time, limit: Int, offset: Int): GossipMessagePage!
+		gossipScores(since: Time, until: Time, limit: Int, offset: Int): GossipScorePage!
+	}
+
+	type PeerPage {
+		items: [Peer!]!
+		pageInfo: PageInfo!
+	}
+
+	type ConnEvent {
+		peerId: String!
+		direction: String
+		connTime: Time
+		disconnTime: Time
+		latency: Int
+		identified: Boolean
+		addrFamily: String
+		error: String
+		runId: Int
+		peer: Peer
+	}
+
+	type ConnEventPage {
+		items: [ConnEvent!]!
+		pageInfo: PageInfo!
+	}
+
+	type Enr {
+		nodeId: String!
+		peerId: String
+		timestamp: Time
+		# 64 bit sequence number, as a string
+		seq: String
+		ip: String
+		tcp: Int
+		udp: Int
+		quic: Int
+		pubkey: String
+		forkDigest: String
+		nextForkVersion: String
+		attnets: String
+		attnetsNumber: Int
+		enr: String
+		peer: Peer
+	}
+
+	type EnrPage {
+		items: [Enr!]!
+		pageInfo: PageInfo!
+	}
+
+	type GossipMessage {
+		msgId: String!
+		topic: String
+		firstSeen: Time
+		firstPeerId: String
+		duplicates: Int
+		lastSeen: Time
+		runId: Int
+		firstPeer: Peer
+	}
+
+	type GossipMessagePage {
+		items: [GossipMessage!]!
+		pageInfo: PageInfo!
+	}
+
+	type GossipScore {
+		peerId: String!
+		timestamp: Time
+		score: Float
+		timeInMeshS: Float
+		firstDeliveries: Float
+		meshDeliveries: Float
+		invalidDeliveries: Float
+		behaviourPenalty: Float
+		runId: Int
+		peer: Peer
+	}
+
+	type GossipScorePage {
+		items: [GossipScore!]!
+		pageInfo: PageInfo!
+	}
+`
+
+// graphqlResolver is the root resolver of the GraphQL schema
+type graphqlResolver struct {
+	db database
+}
+
+type pageArgs struct {
+	Since  *graphql.Time
+	Until  *graphql.Time
+	Limit  *int32
+	Offset *int32
+}
+
+type datasetArgs struct {
+	pageArgs
+	PeerID *string
+}
+
+type pageInfo struct {
+	Offset      int32
+	Limit       int32
+	HasNextPage bool
+}
+
+// page reads the page of the dataset given by the arguments, fetching one more row than the limit
+// to tell whether there is a next page
+func (g *graphqlResolver) page(dataset, peerID string, args pageArgs) ([]map[string]interface{}, *pageInfo, error) {
+	info := &pageInfo{Limit: int32(DefaultGraphQLLimit)}
+	if args.Limit != nil {
+		info.Limit = *args.Limit
+	}
+	if args.Offset != nil {
+		info.Offset = *args.Offset
+	}
+	if info.Limit <= 0 || int(info.Limit) > MaxGraphQLLimit {
+		return nil, nil, errors.Errorf("invalid limit %d (1 to %d)", info.Limit, MaxGraphQLLimit)
+	}
+	if info.Offset < 0 {
+		return nil, nil, errors.Errorf("invalid offset %d", info.Offset)
+	}
+	filter := models.DatasetFilter{
+		PeerID: peerID,
+		Limit:  int(info.Limit) + 1,
+		Offset: int(info.Offset),
+	}
+	if args.Since != nil {
+		filter.Since = args.Since.Time
+	}
+	if args.Until != nil {
+		filter.Until = args.Until.Time
+	}
+	rows, err := g.db.QueryDataset(dataset, filter)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to read "+dataset)
+	}
+	if len(rows) > int(info.Limit) {
+		rows = rows[:info.Limit]
+		info.HasNextPage = true
+	}
+	return rows, info, nil
+}
+
+func (g *graphqlResolver) Peer(args struct{ PeerID string }) (*gqlPeer, error) {
+	return g.peer(args.PeerID)
+}
+
+func (g *graphqlResolver) peer(peerID string) (*gqlPeer, error) {
+	if peerID == "" {
+		return nil, nil
+	}
+	rows, err := g.db.QueryDataset("peers", models.DatasetFilter{PeerID: peerID, Limit: 1})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the peer")
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return newGqlPeer(g, rows[0]), nil
+}
+
+func (g *graphqlResolver) Peers(args pageArgs) (*gqlPeerPage, error) {
+	rows, info, err := g.page("peers", "", args)
+	if err != nil {
+		return nil, err
+	}
+	page := &gqlPeerPage{Items: make([]*gqlPeer, len(rows)), PageInfo: info}
+	for i, row := range rows {
+		page.Items[i] = newGqlPeer(g, row)
+	}
+	return page, nil
+}
+
+func (g *graphqlResolver) ConnEvents(args datasetArgs) (*gqlConnEventPage, error) {
+	return g.connEvents(stringValue(args.PeerID), args.pageArgs)
+}
+
+func (g *graphqlResolver) connEvents(peerID string, args pageArgs) (*gqlConnEventPage, error) {
+	rows, info, err := g.page("conn_events", peerID, args)
+	if err != nil {
+		return nil, err
+	}
+	page := &gqlConnEventPage{Items: make([]*gqlConnEvent, len(rows)), PageInfo: info}
+	for i, row := range rows {
+		page.Items[i] = &gqlConnEvent{
+			g:           g,
+			PeerID:      rowString(row, "peer_id"),
+			Direction:   rowStringPtr(row, "direction"),
+			ConnTime:    rowTime(row, "conn_time"),
+			DisconnTime: rowTime(row, "disconn_time"),
+			Latency:     rowInt(row, "latency"),
+			Identified:  rowBool(row, "identified"),
+			AddrFamily:  rowStringPtr(row, "addr_family"),
+			Error:       rowStringPtr(row, "error"),
+			RunID:       rowInt(row, "run_id"),
+		}
+	}
+	return page, nil
+}
+
+func (g *graphqlResolver) Enrs(args datasetArgs) (*gqlEnrPage, error) {
+	return g.enrs(stringValue(args.PeerID), args.pageArgs)
+}
+
+func (g *graphqlResolver) enrs(peerID string, args pageArgs) (*gqlEnrPage, error) {
+	rows, info, err := g.page("eth_nodes", peerID, args)
+	if err != nil {
+		return nil, err
+	}
+	page := &gqlEnrPage{Items: make([]*gqlEnr, len(rows)), PageInfo: info}
+	for i, row := range rows {
+		enr := &gqlEnr{
+			g:               g,
+			NodeID:          rowString(row, "node_id"),
+			PeerID:          rowStringPtr(row, "peer_id"),
+			Timestamp:       rowTime(row, "timestamp"),
+			IP:              rowStringPtr(row, "ip"),
+			TCP:             rowInt(row, "tcp"),
+			UDP:             rowInt(row, "udp"),
+			Quic:            rowInt(row, "quic"),
+			Pubkey:          rowStringPtr(row, "pubkey"),
+			ForkDigest:      rowStringPtr(row, "fork_digest"),
+			NextForkVersion: rowStringPtr(row, "next_fork_version"),
+			Attnets:         rowStringPtr(row, "attnets"),
+			AttnetsNumber:   rowInt(row, "attnets_number"),
+			Enr:             rowStringPtr(row, "enr"),
+		}
+		if seq, ok := row["seq"].(int64); ok {
+			s := strconv.FormatInt(seq, 10)
+			enr.Seq = &s
+		}
+		page.Items[i] = enr
+	}
+	return page, nil
+}
+
+func (g *graphqlResolver) GossipMessages(args datasetArgs) (*gqlGossipMessagePage, error) {
+	return g.gossipMessages(stringValue(args.PeerID), args.pageArgs)
+}
+
+func (g *graphqlResolver) gossipMessages(peerID string, args pageArgs) (*gqlGossipMessagePage, error) {
+	rows, info, err := g.page("gossip_messages", peerID, args)
+	if err != nil {
+		return nil, err
+	}
+	page := &gqlGossipMessagePage{Items: make([]*gqlGossipMessage, len(rows)), PageInfo: info}
+	for i, row := range rows {
+		page.Items[i] = &gqlGossipMessage{
+			g:           g,
+			MsgID:       rowString(row, "msg_id"),
+			Topic:       rowStringPtr(row, "topic"),
+			FirstSeen:   rowTime(row, "first_seen"),
+			FirstPeerID: rowStringPtr(row, "first_peer"),
+			Duplicates:  rowInt(row, "duplicates"),
+			LastSeen:    rowTime(row, "last_seen"),
+			RunID:       rowInt(row, "run_id"),
+		}
+	}
+	return page, nil
+}
+
+func (g *graphqlResolver) GossipScores(args datasetArgs) (*gqlGossipScorePage, error) {
+	return g.gossipScores(stringValue(args.PeerID), args.pageArgs)
+}
+
+func (g *graphqlResolver) gossipScores(peerID string, args pageArgs) (*gqlGossipScorePage, error) {
+	rows, info, err := g.page("peer_gossip_scores", peerID, args)
+	if err != nil {
+		return nil, err
+	}
+	page := &gqlGossipScorePage{Items: make([]*gqlGossipScore, len(rows)), PageInfo: info}
+	for i, row := range rows {
+		page.Items[i] = &gqlGossipScore{
+			g:                 g,
+			PeerID:            rowString(row, "peer_id"),
+			Timestamp:         rowTime(row, "timestamp"),
+			Score:             rowFloat(row, "score"),
+			TimeInMeshS:       rowFloat(row, "time_in_mesh_s"),
+			FirstDeliveries:   rowFloat(row, "first_deliveries"),
+			MeshDeliveries:    rowFloat(row, "mesh_deliveries"),
+			InvalidDeliveries: rowFloat(row, "invalid_deliveries"),
+			BehaviourPenalty:  rowFloat(row, "behaviour_penalty"),
+			RunID:             rowInt(row, "run_id"),
+		}
+	}
+	return page, nil
+}
+
+type gqlPeer struct {
+	g                *graphqlResolver
+	PeerID           string
+	Network          *string
+	NetworkName      *string
+	IP               *string
+	Port             *int32
+	CountryCode      *string
+	ClientName       *string
+	ClientVersion    *string
+	UserAgent        *string
+	ClientOs         *string
+	ProtocolVersion  *string
+	SecurityProtocol *string
+	Origin           *string
+	Latency          *int32
+	Deprecated       *bool
+	Attempted        *bool
+	ConnAttempts     *int32
+	ConnSuccesses    *int32
+	LastActivity     *graphql.Time
+	LastConnAttempt  *graphql.Time
+	LastError        *string
+}
+
+func newGqlPeer(g *graphqlResolver, row map[string]interface{}) *gqlPeer {
+	return &gqlPeer{
+		g:                g,
+		PeerID:           rowString(row, "peer_id"),
+		Network:          rowStringPtr(row, "network"),
+		NetworkName:      rowStringPtr(row, "network_name"),
+		IP:               rowStringPtr(row, "ip"),
+		Port:             rowInt(row, "port"),
+		CountryCode:      rowStringPtr(row, "country_code"),
+		ClientName:       rowStringPtr(row, "client_name"),
+		ClientVersion:    rowStringPtr(row, "client_version"),
+		UserAgent:        rowStringPtr(row, "user_agent"),
+		ClientOs:         rowStringPtr(row, "client_os"),
+		ProtocolVersion:  rowStringPtr(row, "protocol_version"),
+		SecurityProtocol: rowStringPtr(row, "security_protocol"),
+		Origin:           rowStringPtr(row, "origin"),
+		Latency:          rowInt(row, "latency"),
+		Deprecated:       rowBool(row, "deprecated"),
+		Attempted:        rowBool(row, "attempted"),
+		ConnAttempts:     rowInt(row, "conn_attempts"),
+		ConnSuccesses:    rowInt(row, "conn_successes"),
+		LastActivity:     rowTime(row, "last_activity"),
+		LastConnAttempt:  rowTime(row, "last_conn_attempt"),
+		LastError:        rowStringPtr(row, "last_error"),
+	}
+}
+
+func (p *gqlPeer) ConnEvents(args pageArgs) (*gqlConnEventPage, error) {
+	return p.g.connEvents(p.PeerID, args)
+}
+
+func (p *gqlPeer) Enrs(args pageArgs) (*gqlEnrPage, error) {
+	return p.g.enrs(p.PeerID, args)
+}
+
+func (p *gqlPeer) GossipMessages(args pageArgs) (*gqlGossipMessagePage, error) {
+	return p.g.gossipMessages(p.PeerID, args)
+}
+
+func (p *gqlPeer) GossipScores(args pageArgs) (*gqlGossipScorePage, error) {
+	return p.g.gossipScores(p.PeerID, args)
+}
+
+type gqlPeerPage struct {
+	Items    []*gqlPeer
+	PageInfo *pageInfo
+}
+
+type gqlConnEvent struct {
+	g           *graphqlResolver
+	PeerID      string
+	Direction   *string
+	ConnTime    *graphql.Time
+	DisconnTime *graphql.Time
+	Latency     *int32
+	Identified  *bool
+	AddrFamily  *string
+	Error       *string
+	RunID       *int32
+}
+
+func (e *gqlConnEvent) Peer() (*gqlPeer, error) {
+	return e.g.peer(e.PeerID)
+}
+
+type gqlConnEventPage struct {
+	Items    []*gqlConnEvent
+	PageInfo *pageInfo
+}
+
+type gqlEnr struct {
+	g               *graphqlResolver
+	NodeID          string
+	PeerID          *string
+	Timestamp       *graphql.Time
+	Seq             *string
+	IP              *string
+	TCP             *int32
+	UDP             *int32
+	Quic            *int32
+	Pubkey          *string
+	ForkDigest      *string
+	NextForkVersion *string
+	Attnets         *string
+	AttnetsNumber   *int32
+	Enr             *string
+}
+
+func (e *gqlEnr) Peer() (*gqlPeer, error) {
+	return e.g.peer(stringValue(e.PeerID))
+}
+
+type gqlEnrPage struct {
+	Items    []*gqlEnr
+	PageInfo *pageInfo
+}
+
+type gqlGossipMessage struct {
+	g           *graphqlResolver
+	MsgID       string
+	Topic       *string
+	FirstSeen   *graphql.Time
+	FirstPeerID *string
+	Duplicates  *int32
+	LastSeen    *graphql.Time
+	RunID       *int32
+}
+
+func (m *gqlGossipMessage) FirstPeer() (*gqlPeer, error) {
+	return m.g.peer(stringValue(m.FirstPeerID))
+}
+
+type gqlGossipMessagePage struct {
+	Items    []*gqlGossipMessage
+	PageInfo *pageInfo
+}
+
+type gqlGossipScore struct {
+	g                 *graphqlResolver
+	PeerID            string
+	Timestamp         *graphql.Time
+	Score             *float64
+	TimeInMeshS       *float64
+	FirstDeliveries   *float64
+	MeshDeliveries    *float64
+	InvalidDeliveries *float64
+	BehaviourPenalty  *float64
+	RunID             *int32
+}
+
+func (s *gqlGossipScore) Peer() (*gqlPeer, error) {
+	return s.g.peer(s.PeerID)
+}
+
+type gqlGossipScorePage struct {
+	Items    []*gqlGossipScore
+	PageInfo *pageInfo
+}
+
+// the values of the rows come normalized as string, int64, float64, bool or time.Time (nil if NULL)
+
+func rowString(row map[string]interface{}, col string) string {
+	s, _ := row[col].(string)
+	return s
+}
+
+func rowStringPtr(row map[string]interface{}, col string) *string {
+	s, ok := row[col].(string)
+	if !ok {
+		return nil
+	}
+	return &s
+}
+
+func rowInt(row map[string]interface{}, col string) *int32 {
+	i, ok := row[col].(int64)
+	if !ok {
+		return nil
+	}
+	i32 := int32(i)
+	return &i32
+}
+
+func rowFloat(row map[string]interface{}, col string) *float64 {
+	f, ok := row[col].(float64)
+	if !ok {
+		return nil
+	}
+	return &f
+}
+
+func rowBool(row map[string]interface{}, col string) *bool {
+	b, ok := row[col].(bool)
+	if !ok {
+		return nil
+	}
+	return &b
+}
+
+func rowTime(row map[string]interface{}, col string) *graphql.Time {
+	t, ok := row[col].(time.Time)
+	if !ok {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
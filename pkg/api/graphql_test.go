@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func postGraphQL(t *testing.T, h http.Handler, query string, v interface{}) []interface{} {
+	body, err := json.Marshal(map[string]string{"query": query})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/graphql", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	resp := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []interface{}   `json:"errors"`
+	}{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if v != nil && len(resp.Errors) == 0 {
+		require.NoError(t, json.Unmarshal(resp.Data, v))
+	}
+	return resp.Errors
+}
+
+func TestGraphQL(t *testing.T) {
+	db := &fakeDB{}
+	h := NewAPI("ethereum", db, fakeLive{}).Handler()
+
+	// pages, with the rows of the other datasets nested through the peer IDs
+	var peers struct {
+		Peers struct {
+			Items []struct {
+				PeerID       string  `json:"peerId"`
+				ClientName   *string `json:"clientName"`
+				Port         *int    `json:"port"`
+				LastActivity *string `json:"lastActivity"`
+				ConnEvents   struct {
+					Items []struct {
+						Direction string `json:"direction"`
+						Latency   int    `json:"latency"`
+					} `json:"items"`
+				} `json:"connEvents"`
+			} `json:"items"`
+			PageInfo struct {
+				Offset      int  `json:"offset"`
+				Limit       int  `json:"limit"`
+				HasNextPage bool `json:"hasNextPage"`
+			} `json:"pageInfo"`
+		} `json:"peers"`
+	}
+	errs := postGraphQL(t, h, `{
+		peers(since: "2023-01-01T00:00:00Z", limit: 1, offset: 3) {
+			items { peerId clientName port lastActivity connEvents { items { direction latency } } }
+			pageInfo { offset limit hasNextPage }
+		}
+	}`, &peers)
+	require.Empty(t, errs)
+	require.Len(t, peers.Peers.Items, 1)
+	require.Equal(t, testPeer1.String(), peers.Peers.Items[0].PeerID)
+	require.Equal(t, "lighthouse", *peers.Peers.Items[0].ClientName)
+	require.Equal(t, 9000, *peers.Peers.Items[0].Port)
+	require.Equal(t, "2023-01-02T03:04:05Z", *peers.Peers.Items[0].LastActivity)
+	require.Len(t, peers.Peers.Items[0].ConnEvents.Items, 1)
+	require.Equal(t, 120, peers.Peers.Items[0].ConnEvents.Items[0].Latency)
+	require.True(t, peers.Peers.PageInfo.HasNextPage)
+	require.Equal(t, 3, peers.Peers.PageInfo.Offset)
+	require.Equal(t, time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), db.filters["peers"].Since.UTC())
+	require.Equal(t, 2, db.filters["peers"].Limit)
+	require.Equal(t, 3, db.filters["peers"].Offset)
+	require.Equal(t, testPeer1.String(), db.filters["conn_events"].PeerID)
+	require.Equal(t, DefaultGraphQLLimit+1, db.filters["conn_events"].Limit)
+
+	// from the ENRs back to the peers
+	var enrs struct {
+		Enrs struct {
+			Items []struct {
+				NodeID string `json:"nodeId"`
+				Seq    string `json:"seq"`
+				Peer   *struct {
+					ClientName string `json:"clientName"`
+				} `json:"peer"`
+			} `json:"items"`
+		} `json:"enrs"`
+	}
+	errs = postGraphQL(t, h, `{ enrs(peerId: "`+testPeer1.String()+`") { items { nodeId seq peer { clientName } } } }`, &enrs)
+	require.Empty(t, errs)
+	require.Len(t, enrs.Enrs.Items, 1)
+	require.Equal(t, "1680000000000", enrs.Enrs.Items[0].Seq)
+	require.Equal(t, "lighthouse", enrs.Enrs.Items[0].Peer.ClientName)
+
+	var peer struct {
+		Peer *struct {
+			ClientName *string `json:"clientName"`
+		} `json:"peer"`
+	}
+	require.Empty(t, postGraphQL(t, h, `{ peer(peerId: "`+testPeer2.String()+`") { clientName } }`, &peer))
+	require.Nil(t, peer.Peer.ClientName)
+	require.Empty(t, postGraphQL(t, h, `{ peer(peerId: "unknown") { clientName } }`, &peer))
+	require.Nil(t, peer.Peer)
+
+	// the pages are bounded
+	require.NotEmpty(t, postGraphQL(t, h, `{ gossipMessages(limit: 100000) { items { msgId } } }`, nil))
+	require.NotEmpty(t, postGraphQL(t, h, `{ peers { items { unknownField } } }`, nil))
+
+	// only the graphql endpoint takes POSTs
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/peers", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
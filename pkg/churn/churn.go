@@ -0,0 +1,335 @@
+package churn
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	DefaultSampleSize     = 20
+	DefaultCycles         = 10
+	DefaultConnectTime    = 5 * time.Minute
+	DefaultDisconnectTime = 5 * time.Minute
+	DefaultSeed           = int64(1)
+
+	// time given to the dials of each connected phase
+	DialTimeout = 20 * time.Second
+)
+
+type database interface {
+	GetNonDeprecatedPeers() ([]*models.RemoteConnectablePeer, error)
+	PersistToDB(interface{})
+}
+
+type gossipStats interface {
+	PeerGossipStats() map[peer.ID]gossipsub.PeerGossipStats
+}
+
+type phase int
+
+const (
+	idlePhase phase = iota
+	connectedPhase
+	disconnectedPhase
+)
+
+// ChurnExperiment connects to and disconnects from a sampled set of peers following a fixed schedule,
+// recording how the peers react to it (remote disconnections, goodbyes, re-dials, and gossip scores),
+// so that the peer-management logic of the clients can be studied in a reproducible way
+type ChurnExperiment struct {
+	ctx     context.Context
+	runID   int
+	network utils.NetworkType
+	h       host.Host
+	db      database
+	gossip  gossipStats
+
+	sampleSize     int
+	cycles         int
+	connectTime    time.Duration
+	disconnectTime time.Duration
+	seed           int64
+
+	m          sync.Mutex
+	sample     map[peer.ID]peer.AddrInfo
+	cycle      int
+	phase      phase
+	phaseStart time.Time
+	closing    map[peer.ID]struct{} // disconnections triggered by us
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+func NewChurnExperiment(
+	ctx context.Context,
+	runID int,
+	network utils.NetworkType,
+	h host.Host,
+	db database,
+	opts ...ChurnOption) (*ChurnExperiment, error) {
+
+	exp := &ChurnExperiment{
+		ctx:            ctx,
+		runID:          runID,
+		network:        network,
+		h:              h,
+		db:             db,
+		sampleSize:     DefaultSampleSize,
+		cycles:         DefaultCycles,
+		connectTime:    DefaultConnectTime,
+		disconnectTime: DefaultDisconnectTime,
+		seed:           DefaultSeed,
+		sample:         make(map[peer.ID]peer.AddrInfo),
+		closing:        make(map[peer.ID]struct{}),
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		err := opt(exp)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply churn option")
+		}
+	}
+	return exp, nil
+}
+
+// Start samples the peers and runs the cycles of the experiment in the background
+func (e *ChurnExperiment) Start() error {
+	peers, err := e.db.GetNonDeprecatedPeers()
+	if err != nil {
+		return errors.Wrap(err, "unable to read the peers to sample")
+	}
+	sample := samplePeers(peers, e.network, e.sampleSize, e.seed)
+	if len(sample) == 0 {
+		return errors.New("no peers to sample for the churn experiment")
+	}
+	e.m.Lock()
+	for _, p := range sample {
+		e.sample[p.ID] = p
+	}
+	e.m.Unlock()
+
+	e.h.Network().Notify(&network.NotifyBundle{
+		ConnectedF:    e.connected,
+		DisconnectedF: e.disconnected,
+	})
+
+	log.WithFields(log.Fields{
+		"sampled":         len(sample),
+		"cycles":          e.cycles,
+		"connect-time":    e.connectTime,
+		"disconnect-time": e.disconnectTime,
+		"seed":            e.seed,
+	}).Info("starting connection churn experiment")
+
+	e.wg.Add(1)
+	go e.run(sample)
+	return nil
+}
+
+// Done returns a channel that gets closed once all the cycles have been completed
+func (e *ChurnExperiment) Done() <-chan struct{} {
+	return e.done
+}
+
+// Stop waits until the ongoing cycle is interrupted (the routine dies with the context)
+func (e *ChurnExperiment) Stop() {
+	e.wg.Wait()
+}
+
+// OnGoodbye records the goodbyes that the sampled peers send us
+func (e *ChurnExperiment) OnGoodbye(p peer.ID, reason uint64) {
+	e.record(p, models.ChurnGoodbye, float64(reason), "")
+}
+
+func (e *ChurnExperiment) run(sample []peer.AddrInfo) {
+	defer e.wg.Done()
+	defer close(e.done)
+
+	// the phases are scheduled over the start of the experiment, so that slow dials don't shift them
+	start := time.Now()
+	for cycle := 0; cycle < e.cycles; cycle++ {
+		connectAt, disconnectAt := e.schedule(start, cycle)
+		if !e.waitUntil(connectAt) {
+			return
+		}
+		e.setPhase(cycle, connectedPhase)
+		e.connectAll(sample)
+
+		if !e.waitUntil(disconnectAt) {
+			return
+		}
+		e.recordScores()
+		e.setPhase(cycle, disconnectedPhase)
+		e.disconnectAll(sample)
+		log.Infof("churn experiment completed cycle %d/%d", cycle+1, e.cycles)
+	}
+	// keep listening for re-dials during the last disconnected phase
+	endAt, _ := e.schedule(start, e.cycles)
+	if !e.waitUntil(endAt) {
+		return
+	}
+	e.setPhase(e.cycles, idlePhase)
+	log.Info("churn experiment finished")
+}
+
+// schedule returns when the connected and the disconnected phases of the given cycle start
+func (e *ChurnExperiment) schedule(start time.Time, cycle int) (connectAt time.Time, disconnectAt time.Time) {
+	connectAt = start.Add(time.Duration(cycle) * (e.connectTime + e.disconnectTime))
+	return connectAt, connectAt.Add(e.connectTime)
+}
+
+func (e *ChurnExperiment) waitUntil(t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-e.ctx.Done():
+		return false
+	}
+}
+
+func (e *ChurnExperiment) setPhase(cycle int, ph phase) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.cycle = cycle
+	e.phase = ph
+	e.phaseStart = time.Now()
+}
+
+func (e *ChurnExperiment) connectAll(sample []peer.AddrInfo) {
+	var wg sync.WaitGroup
+	for _, p := range sample {
+		wg.Add(1)
+		go func(p peer.AddrInfo) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(e.ctx, DialTimeout)
+			defer cancel()
+			errStr := ""
+			if err := e.h.Connect(ctx, p); err != nil {
+				errStr = err.Error()
+			}
+			e.record(p.ID, models.ChurnDial, 0, errStr)
+		}(p)
+	}
+	wg.Wait()
+}
+
+func (e *ChurnExperiment) disconnectAll(sample []peer.AddrInfo) {
+	for _, p := range sample {
+		if e.h.Network().Connectedness(p.ID) != network.Connected {
+			continue
+		}
+		e.m.Lock()
+		e.closing[p.ID] = struct{}{}
+		e.m.Unlock()
+		errStr := ""
+		if err := e.h.Network().ClosePeer(p.ID); err != nil {
+			errStr = err.Error()
+		}
+		e.record(p.ID, models.ChurnDisconnect, 0, errStr)
+	}
+}
+
+func (e *ChurnExperiment) recordScores() {
+	if e.gossip == nil {
+		return
+	}
+	stats := e.gossip.PeerGossipStats()
+	e.m.Lock()
+	sampled := make([]peer.ID, 0, len(e.sample))
+	for p := range e.sample {
+		sampled = append(sampled, p)
+	}
+	e.m.Unlock()
+	for _, p := range sampled {
+		if s, ok := stats[p]; ok && s.HasScore {
+			e.record(p, models.ChurnScore, s.Score, "")
+		}
+	}
+}
+
+func (e *ChurnExperiment) connected(net network.Network, conn network.Conn) {
+	// inbound connections while we keep the peer disconnected are re-dials from the peer
+	if conn.Stat().Direction != network.DirInbound {
+		return
+	}
+	e.m.Lock()
+	ph := e.phase
+	e.m.Unlock()
+	if ph == disconnectedPhase {
+		e.record(conn.RemotePeer(), models.ChurnRedial, 0, "")
+	}
+}
+
+func (e *ChurnExperiment) disconnected(net network.Network, conn network.Conn) {
+	e.m.Lock()
+	_, ours := e.closing[conn.RemotePeer()]
+	delete(e.closing, conn.RemotePeer())
+	ph := e.phase
+	e.m.Unlock()
+	if !ours && ph == connectedPhase {
+		e.record(conn.RemotePeer(), models.ChurnRemoteDisconnect, 0, "")
+	}
+}
+
+// record persists the event if the peer belongs to the sample
+func (e *ChurnExperiment) record(p peer.ID, evType models.ChurnEventType, value float64, errStr string) {
+	t := time.Now()
+	e.m.Lock()
+	_, sampled := e.sample[p]
+	cycle := e.cycle
+	phaseStart := e.phaseStart
+	e.m.Unlock()
+	if !sampled {
+		return
+	}
+	e.db.PersistToDB(&models.ChurnEvent{
+		RunID:     e.runID,
+		Cycle:     cycle,
+		PeerID:    p,
+		Type:      evType,
+		Timestamp: t,
+		Delay:     t.Sub(phaseStart),
+		Value:     value,
+		Error:     errStr,
+	})
+}
+
+// samplePeers picks the given number of peers of the network with a deterministic seed,
+// the same set of peers and seed always give the same sample
+func samplePeers(peers []*models.RemoteConnectablePeer, network utils.NetworkType, size int, seed int64) []peer.AddrInfo {
+	candidates := make([]peer.AddrInfo, 0, len(peers))
+	for _, p := range peers {
+		if p.Network != network || len(p.Addrs) == 0 {
+			continue
+		}
+		candidates = append(candidates, peer.AddrInfo{ID: p.ID, Addrs: append([]ma.Multiaddr{}, p.Addrs...)})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ID < candidates[j].ID
+	})
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if size < len(candidates) {
+		candidates = candidates[:size]
+	}
+	return candidates
+}
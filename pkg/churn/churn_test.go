@@ -0,0 +1,57 @@
+package churn
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func testPeers(n int) []*models.RemoteConnectablePeer {
+	peers := make([]*models.RemoteConnectablePeer, 0, n)
+	for i := 0; i < n; i++ {
+		addr := ma.StringCast(fmt.Sprintf("/ip4/10.0.0.%d/tcp/9000", i))
+		peers = append(peers, models.NewRemoteConnectablePeer(peer.ID(fmt.Sprintf("peer-%02d", i)), []ma.Multiaddr{addr}, utils.EthereumNetwork))
+	}
+	return peers
+}
+
+func Test_SamplePeers(t *testing.T) {
+	peers := testPeers(50)
+	sample := samplePeers(peers, utils.EthereumNetwork, 10, 42)
+	require.Len(t, sample, 10)
+
+	// same seed gives the same sample, no matter the order of the peers
+	reversed := make([]*models.RemoteConnectablePeer, len(peers))
+	for i, p := range peers {
+		reversed[len(peers)-1-i] = p
+	}
+	require.Equal(t, sample, samplePeers(reversed, utils.EthereumNetwork, 10, 42))
+	require.NotEqual(t, sample, samplePeers(peers, utils.EthereumNetwork, 10, 43))
+
+	// peers of other networks or without addrs are never sampled
+	require.Empty(t, samplePeers(peers, utils.IpfsNetwork, 10, 42))
+	peers[0].Addrs = nil
+	require.Len(t, samplePeers(peers, utils.EthereumNetwork, 100, 42), 49)
+}
+
+func Test_ChurnSchedule(t *testing.T) {
+	e, err := NewChurnExperiment(context.Background(), 1, utils.EthereumNetwork, nil, nil,
+		WithSchedule(2*time.Minute, 3*time.Minute))
+	require.NoError(t, err)
+
+	start := time.Now()
+	connectAt, disconnectAt := e.schedule(start, 2)
+	require.Equal(t, start.Add(10*time.Minute), connectAt)
+	require.Equal(t, start.Add(12*time.Minute), disconnectAt)
+
+	_, err = NewChurnExperiment(context.Background(), 1, utils.EthereumNetwork, nil, nil, WithSchedule(0, time.Minute))
+	require.Error(t, err)
+}
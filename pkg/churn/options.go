@@ -0,0 +1,59 @@
+package churn
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type ChurnOption func(*ChurnExperiment) error
+
+// WithSampleSize sets the number of peers that are connected and disconnected on each cycle
+func WithSampleSize(size int) ChurnOption {
+	return func(e *ChurnExperiment) error {
+		if size <= 0 {
+			return errors.Errorf("invalid churn sample size %d", size)
+		}
+		e.sampleSize = size
+		return nil
+	}
+}
+
+// WithCycles sets the number of connect/disconnect cycles of the experiment
+func WithCycles(cycles int) ChurnOption {
+	return func(e *ChurnExperiment) error {
+		if cycles <= 0 {
+			return errors.Errorf("invalid number of churn cycles %d", cycles)
+		}
+		e.cycles = cycles
+		return nil
+	}
+}
+
+// WithSchedule sets how long the sampled peers are kept connected and disconnected on each cycle
+func WithSchedule(connectTime, disconnectTime time.Duration) ChurnOption {
+	return func(e *ChurnExperiment) error {
+		if connectTime <= 0 || disconnectTime <= 0 {
+			return errors.Errorf("invalid churn schedule %s connected / %s disconnected", connectTime, disconnectTime)
+		}
+		e.connectTime = connectTime
+		e.disconnectTime = disconnectTime
+		return nil
+	}
+}
+
+// WithSeed sets the seed used to sample the peers (same seed and peers give the same sample)
+func WithSeed(seed int64) ChurnOption {
+	return func(e *ChurnExperiment) error {
+		e.seed = seed
+		return nil
+	}
+}
+
+// WithGossipStats sets the source of the gossipsub scores recorded at the end of each connected phase
+func WithGossipStats(gossip gossipStats) ChurnOption {
+	return func(e *ChurnExperiment) error {
+		e.gossip = gossip
+		return nil
+	}
+}
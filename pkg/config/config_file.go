@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfigFile reads the settings of a YAML, TOML or JSON config file (picked by its extension).
+// The settings are keyed by the same names as the flags, i.e. "log-level: debug"
+func LoadConfigFile(path string) (map[string]interface{}, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read config file")
+	}
+	settings := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &settings)
+	case ".json":
+		err = json.Unmarshal(raw, &settings)
+	case ".toml":
+		settings, err = parseTOML(raw)
+	default:
+		return nil, errors.Errorf("unsupported config file extension %q (yaml, yml, toml or json)", ext)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse config file %s", path)
+	}
+	return settings, nil
+}
+
+// applySettings overrides the fields of the given config with the settings, matched by their json tags.
+// Unknown settings and values of the wrong type are rejected, so that typos don't go unnoticed
+func applySettings(settings map[string]interface{}, conf interface{}) error {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode config settings")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(conf); err != nil {
+		return errors.Wrap(err, "invalid config file")
+	}
+	return nil
+}
+
+// applyFile applies the defaults of the network (given by flag or in the file) and the settings of
+// the config file, which the flags override afterwards
+func (c *EthereumCrawlerConfig) applyFile(ctx *cli.Context) error {
+	settings, network, _, err := readFileSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if network != "" {
+		if err := c.ApplyNetwork(network); err != nil {
+			return err
+		}
+	}
+	return applySettings(settings, c)
+}
+
+// applyFile applies the defaults of the network (given by flag or in the file) and the settings of
+// the config file, which the flags override afterwards
+func (c *IpfsCrawlerConfig) applyFile(ctx *cli.Context) error {
+	settings, network, blocks, err := readFileSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if len(blocks) > 0 {
+		return errors.Errorf("%s: the blocks of networks are only supported by the eth2 crawler", NetworksKey)
+	}
+	if network != "" {
+		if err := c.ApplyNetwork(network); err != nil {
+			return err
+		}
+	}
+	return applySettings(settings, c)
+}
+
+// readFileSettings returns the settings of the config file (if any) without the network nor the
+// blocks of networks, the network given by flag or in the file, and the blocks of networks
+func readFileSettings(ctx *cli.Context) (map[string]interface{}, string, []map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	if ctx.IsSet("config-file") {
+		var err error
+		settings, err = LoadConfigFile(ctx.String("config-file"))
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+	blocks, err := networkBlocks(settings)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	delete(settings, NetworksKey)
+	network := settingsNetwork(settings)
+	delete(settings, "network")
+	if ctx.IsSet("network") {
+		network = strings.ToLower(ctx.String("network"))
+	}
+	return settings, network, blocks, nil
+}
+
+// settingsNetwork returns the network of the settings, empty if there isn't any
+func settingsNetwork(settings map[string]interface{}) string {
+	network, _ := settings["network"].(string)
+	return strings.ToLower(network)
+}
+
+// parseTOML parses the flat subset of TOML that the config files need: "key = value" lines with
+// strings, numbers, booleans and single-line arrays of them, and # comments
+func parseTOML(raw []byte) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, errors.Errorf("line %d: tables aren't supported, keep the settings at the top level", lineNum)
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, errors.Errorf("line %d: expected key = value", lineNum)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		parsed, err := parseTOMLValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNum)
+		}
+		settings[key] = parsed
+	}
+	return settings, scanner.Err()
+}
+
+func parseTOMLValue(value string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return nil, errors.New("arrays must be in a single line")
+		}
+		items := make([]interface{}, 0)
+		for _, item := range splitTOMLArray(value[1 : len(value)-1]) {
+			parsed, err := parseTOMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, parsed)
+		}
+		return items, nil
+	case strings.HasPrefix(value, `"`), strings.HasPrefix(value, "'"):
+		if len(value) < 2 || value[len(value)-1] != value[0] {
+			return nil, errors.Errorf("unterminated string %s", value)
+		}
+		if value[0] == '\'' {
+			return value[1 : len(value)-1], nil
+		}
+		return strconv.Unquote(value)
+	case value == "true", value == "false":
+		return value == "true", nil
+	}
+	if i, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err == nil {
+		return f, nil
+	}
+	return nil, errors.Errorf("invalid value %s", value)
+}
+
+// splitTOMLArray splits the items of an array by the commas that aren't within a string
+func splitTOMLArray(items string) []string {
+	split := make([]string, 0)
+	var quote rune
+	start := 0
+	for i, r := range items {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			split = append(split, strings.TrimSpace(items[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(items[start:]); last != "" {
+		split = append(split, last)
+	}
+	return split
+}
+
+// stripTOMLComment removes the # comment of the line, unless it is within a string
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func Test_LoadConfigFile(t *testing.T) {
+	expected := map[string]interface{}{
+		"log-level":         "debug",
+		"port":              9021,
+		"eclipse-threshold": 0.25,
+		"bootnodes":         []interface{}{"enr:-a", "enr:-b"},
+		"persist-msgs":      true,
+	}
+	files := map[string]string{
+		"armiarma.yaml": `
+log-level: debug
+port: 9021
+eclipse-threshold: 0.25
+bootnodes: ["enr:-a", "enr:-b"]
+persist-msgs: true
+`,
+		"armiarma.toml": `
+# crawler settings
+log-level = "debug" # inline comment
+port = 9021
+eclipse-threshold = 0.25
+bootnodes = ["enr:-a", 'enr:-b']
+persist-msgs = true
+`,
+		"armiarma.json": `{"log-level": "debug", "port": 9021, "eclipse-threshold": 0.25, "bootnodes": ["enr:-a", "enr:-b"], "persist-msgs": true}`,
+	}
+	for name, content := range files {
+		settings, err := LoadConfigFile(writeConfigFile(t, name, content))
+		require.NoError(t, err, name)
+
+		conf := NewEthereumCrawlerConfig()
+		require.NoError(t, applySettings(settings, conf), name)
+		require.Equal(t, expected["log-level"], conf.LogLevel, name)
+		require.Equal(t, expected["port"], conf.Port, name)
+		require.Equal(t, expected["eclipse-threshold"], conf.EclipseThreshold, name)
+		require.Equal(t, []string{"enr:-a", "enr:-b"}, conf.Bootnodes, name)
+		require.True(t, conf.PersistMsgs, name)
+		// the settings that aren't in the file keep their defaults
+		require.Equal(t, DefaultIpCacheTTL, conf.IpCacheTTL, name)
+	}
+
+	_, err := LoadConfigFile(writeConfigFile(t, "armiarma.ini", "port=1"))
+	require.Error(t, err)
+	_, err = LoadConfigFile(writeConfigFile(t, "armiarma.toml", "[crawler]\nport = 1\n"))
+	require.Error(t, err)
+
+	// typos and values of the wrong type are rejected
+	conf := NewEthereumCrawlerConfig()
+	require.Error(t, applySettings(map[string]interface{}{"prot": 9021}, conf))
+	require.Error(t, applySettings(map[string]interface{}{"port": "9021"}, conf))
+}
+
+func Test_ConfigValidation(t *testing.T) {
+	require.NoError(t, NewEthereumCrawlerConfig().Validate())
+	require.NoError(t, NewIpfsCrawlerConfig().Validate())
+
+	conf := NewEthereumCrawlerConfig()
+	require.NoError(t, conf.ApplyNetwork("Gnosis"))
+	require.Equal(t, DefaultGnosisBootnodes, conf.Bootnodes)
+	require.NoError(t, conf.Validate())
+	require.Error(t, conf.ApplyNetwork("goerli"))
+
+	// the testnets need the bootnodes to be given
+	require.NoError(t, conf.ApplyNetwork("holesky"))
+	conf.LogLevel = "verbose"
+	conf.DialTimeout = "20"
+	conf.Hosts = 0
+	err := conf.Validate()
+	require.Error(t, err)
+	validationErr, ok := err.(*ValidationError)
+	require.True(t, ok)
+	require.Len(t, validationErr.Issues, 4)
+
+	// the discovery-only mode never dials
+	conf = NewEthereumCrawlerConfig()
+	conf.DiscoveryOnly, conf.ObserverMode = true, true
+	require.NoError(t, conf.Validate())
+	conf.HoldPeers = 10
+	conf.ObserverMode = false
+	err = conf.Validate()
+	require.Error(t, err)
+	require.Len(t, err.(*ValidationError).Issues, 2)
+
+	// the listen-only mode never dials either
+	conf = NewEthereumCrawlerConfig()
+	conf.ListenOnly, conf.ObserverMode, conf.Discoverable = true, true, true
+	require.NoError(t, conf.Validate())
+	conf.StaticPeers = []string{"/ip4/1.2.3.4/tcp/9000/p2p/16Uiu2HAm..."}
+	conf.DiscoveryOnly = true
+	err = conf.Validate()
+	require.Error(t, err)
+	require.Len(t, err.(*ValidationError).Issues, 4)
+
+	ipfsConf := NewIpfsCrawlerConfig()
+	require.NoError(t, ipfsConf.ApplyNetwork("filecoin"))
+	require.Equal(t, DefaultFilecoinBootnodes, ipfsConf.Bootnodes)
+	ipfsConf.GeoIPCityDB = "/not/a/file.mmdb"
+	require.Error(t, ipfsConf.Validate())
+}
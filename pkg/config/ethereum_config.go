@@ -2,6 +2,7 @@ package config
 
 import (
 	"strconv"
+	"strings"
 
 	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
 	rendp "github.com/migalabs/armiarma/pkg/networks/ethereum/remoteendpoint"
@@ -13,70 +14,291 @@ import (
 )
 
 var (
+	// Discovery sources that can be composed by the Ethereum crawler
+	EthDiscoverySources        []string = []string{"dv5", "static", "db"}
+	DefaultEthDiscoverySources []string = []string{"dv5"}
+
 	// GossipSub Topics
 	DefaultEthereumGossipTopics []string = []string{}
 	AllEthereumGossipTopics     []string = eth.MessageTypes
 	DefaultSubnets              []int    = []int{}
 	DefaultValPubkeys           []string = []string{}
 
+	// Security protocols ordered by preference (Eth2 specs only require Noise)
+	DefaultEthereumSecurity []string = []string{"noise"}
+
 	// remote RemoteEth client's endpoint
 	DefaultCLRemoteEndpoint = ""
 )
 
 type EthereumCrawlerConfig struct {
 	LogLevel                  string   `json:"log-level"`
+	LogLevels                 string   `json:"log-levels"`
 	PrivateKey                string   `json:"priv-key"`
 	IP                        string   `json:"ip"`
+	IP6                       string   `json:"ip6"`
+	IPFamily                  string   `json:"ip-family"`
 	Port                      int      `json:"port"`
 	MetricsIP                 string   `json:"metrics-ip"`
 	MetricsPort               int      `json:"metrics-port"`
 	UserAgent                 string   `json:"user-agent"`
+	Network                   string   `json:"network"`
 	EthCLRemoteEndpoint       string   `json:"remote-cl-endpoint"`
 	PsqlEndpoint              string   `json:"psql-endpoint"`
-	ActivePeersBackupInterval string   `json:ActivePeersBackupInterval`
+	ActivePeersBackupInterval string   `json:"peers-backup"`
+	PeersHistoryInterval      string   `json:"peers-history"`
+	ValidatorEstimates        string   `json:"validator-estimates"`
+	DiversityInterval         string   `json:"diversity-interval"`
+	GeoIPCityDB               string   `json:"geoip-city-db"`
+	GeoIPASNDB                string   `json:"geoip-asn-db"`
+	IpCacheTTL                string   `json:"ip-cache-ttl"`
+	IpRefreshInterval         string   `json:"ip-refresh-interval"`
+	RdnsLookup                bool     `json:"rdns-lookup"`
+	CloudTagging              bool     `json:"cloud-tagging"`
+	CloudRangesFile           string   `json:"cloud-ranges-file"`
+	TorExitList               bool     `json:"tor-exit-list"`
+	VPNListFile               string   `json:"vpn-list-file"`
+	ResourceUsageInterval     string   `json:"resource-usage-interval"`
 	ForkDigest                string   `json:"fork-digest"`
 	Bootnodes                 []string `json:"bootnodes"`
+	BootnodesSource           string   `json:"bootnodes-source"`
+	BootnodePingInterval      string   `json:"bootnode-ping-interval"`
+	DiscoverySources          []string `json:"discovery-sources"`
+	DiscoveryFile             string   `json:"discovery-file"`
+	RedialInterval            string   `json:"redial-interval"`
 	GossipTopics              []string `json:"gossip-topics"`
 	Subnets                   []int    `json:"subnets"`
+	SubscribeAllSubnets       bool     `json:"subscribe-all-subnets"`
 	PersistConnEvents         bool     `json:"persist-connevents"`
 	PersistMsgs               bool     `json:"persist-msgs"`
+	MeshSnapshotInterval      string   `json:"mesh-snapshot-interval"`
+	GossipValidation          string   `json:"gossip-validation"`
 	ValPubkeys                []string `json:"val-pubkeys"`
 	SSEIP                     string   `json:"sse-ip"`
 	SSEPort                   int      `json:"sse-port"`
+	Security                  []string `json:"security"`
+	SignedPeerRecord          bool     `json:"signed-peer-record"`
+	ObservedAddrs             bool     `json:"observed-addrs"`
+	CollectPeerRecords        bool     `json:"collect-peer-records"`
+	NegotiationTiming         bool     `json:"negotiation-timing"`
+	BlocksByRangeProbe        int      `json:"blocks-by-range-probe"`
+	EclipseThreshold          float64  `json:"eclipse-threshold"`
+	EclipseMinPeers           int      `json:"eclipse-min-peers"`
+	StaticPeers               []string `json:"static-peers"`
+	StaticPeersInStats        bool     `json:"static-peers-in-stats"`
+	MaxConns                  int      `json:"max-conns"`
+	MaxConnsPerPeer           int      `json:"max-conns-per-peer"`
+	MaxStreamsPerPeer         int      `json:"max-streams-per-peer"`
+	MaxStreamsPerProtocol     int      `json:"max-streams-per-protocol"`
+	ResourceLimitsFile        string   `json:"rcmgr-limits"`
+	BlocklistFile             string   `json:"blocklist"`
+	BlocklistFromDB           bool     `json:"blocklist-db"`
+	TargetsFile               string   `json:"targets"`
+	TargetsFromDB             bool     `json:"targets-db"`
+	Soak                      bool     `json:"soak"`
+	ObserverMode              bool     `json:"observer"`
+	DiscoveryOnly             bool     `json:"discovery-only"`
+	ListenOnly                bool     `json:"listen-only"`
+	Discoverable              bool     `json:"discoverable"`
+	SoakRetention             string   `json:"soak-retention"`
+	SoakExportDir             string   `json:"soak-export-dir"`
+	WatchdogTimeout           string   `json:"watchdog-timeout"`
+	KeyStore                  string   `json:"key-store"`
+	KeyRotation               string   `json:"key-rotation"`
+	Hosts                     int      `json:"hosts"`
+	EventQueueSize            int      `json:"event-queue-size"`
+	InfluxURL                 string   `json:"influx-url"`
+	InfluxOrg                 string   `json:"influx-org"`
+	InfluxBucket              string   `json:"influx-bucket"`
+	InfluxToken               string   `json:"influx-token"`
+	ClickHouseURL             string   `json:"clickhouse-url"`
+	ClickHouseDB              string   `json:"clickhouse-db"`
+	ClickHouseUser            string   `json:"clickhouse-user"`
+	ClickHousePassword        string   `json:"clickhouse-password"`
+	Retention                 string   `json:"retention"`
+	RetentionSchedule         string   `json:"retention-schedule"`
+	CrawlSeed                 int64    `json:"crawl-seed"`
+	PeeringStrategy           string   `json:"peering-strategy"`
+	PeeringQuota              int      `json:"peering-quota"`
+	DialScoreWeights          string   `json:"dial-score-weights"`
+	AdaptiveDialing           bool     `json:"adaptive-dialing"`
+	MaxDialRate               float64  `json:"max-dial-rate"`
+	MaxDialWorkers            int      `json:"max-dial-workers"`
+	ResumeDialQueue           bool     `json:"resume-dial-queue"`
+	DialTimeout               string   `json:"dial-timeout"`
+	IdentifyTimeout           string   `json:"identify-timeout"`
+	ReqRespTimeout            string   `json:"reqresp-timeout"`
+	ClassTimeouts             string   `json:"class-timeouts"`
+	DeprecationAttempts       int      `json:"deprecation-attempts"`
+	DeprecationBackoff        string   `json:"deprecation-backoff"`
+	DeprecationWindow         string   `json:"deprecation-window"`
+	ResurrectOnNewENR         bool     `json:"resurrect-on-new-enr"`
+	QualityInterval           string   `json:"quality-interval"`
+	QualityWeights            string   `json:"quality-weights"`
+	ReputationInterval        string   `json:"reputation-interval"`
+	ReputationWeights         string   `json:"reputation-weights"`
+	Churn                     bool     `json:"churn"`
+	ChurnSampleSize           int      `json:"churn-sample-size"`
+	ChurnCycles               int      `json:"churn-cycles"`
+	ChurnConnectTime          string   `json:"churn-connect-time"`
+	ChurnDisconnectTime       string   `json:"churn-disconnect-time"`
+	ChurnSeed                 int64    `json:"churn-seed"`
+	HoldPeers                 int      `json:"hold-peers"`
+	HoldStratify              string   `json:"hold-stratify"`
+	HoldPingInterval          string   `json:"hold-ping-interval"`
+	HoldBudget                int      `json:"hold-budget"`
+	Dv5Strategy               string   `json:"dv5-strategy"`
+	Dv5Distances              string   `json:"dv5-distances"`
+	Dv5ForkDigests            []string `json:"dv5-fork-digests"`
+	ForkDigestAllowlist       []string `json:"fork-digest-allowlist"`
+	StoreMismatchedForks      bool     `json:"store-mismatched-forks"`
 }
 
-// TODO: read from config-file
 func NewEthereumCrawlerConfig() *EthereumCrawlerConfig {
 	// Return Default values for the ethereum configuration
 	return &EthereumCrawlerConfig{
 		LogLevel:                  DefaultLogLevel,
+		LogLevels:                 DefaultLogLevels,
 		PrivateKey:                DefaultPrivKey,
 		IP:                        DefaultIP,
+		IP6:                       DefaultIP6,
+		IPFamily:                  DefaultIPFamily,
 		Port:                      DefaultPort,
 		MetricsIP:                 DefaultMetricsIP,
 		MetricsPort:               DefaultMetricsPort,
 		UserAgent:                 DefaultUserAgent,
+		Network:                   DefaultEthNetwork,
 		EthCLRemoteEndpoint:       DefaultCLRemoteEndpoint,
 		PsqlEndpoint:              DefaultPSQLEndpoint,
 		ActivePeersBackupInterval: DefaultActivePeersBackupInterval,
+		PeersHistoryInterval:      DefaultPeersHistoryInterval,
+		ValidatorEstimates:        DefaultValidatorEstimates,
+		DiversityInterval:         DefaultDiversityInterval,
+		GeoIPCityDB:               DefaultGeoIPCityDB,
+		GeoIPASNDB:                DefaultGeoIPASNDB,
+		IpCacheTTL:                DefaultIpCacheTTL,
+		IpRefreshInterval:         DefaultIpRefreshInterval,
+		RdnsLookup:                DefaultRdnsLookup,
+		CloudTagging:              DefaultCloudTagging,
+		CloudRangesFile:           DefaultCloudRangesFile,
+		TorExitList:               DefaultTorExitList,
+		VPNListFile:               DefaultVPNListFile,
+		ResourceUsageInterval:     DefaultResourceUsageInterval,
 		ForkDigest:                eth.DefaultForkDigest,
 		Bootnodes:                 DefaultEthereumBootnodes,
+		BootnodesSource:           DefaultBootnodesSource,
+		BootnodePingInterval:      DefaultBootnodePingInterval,
+		DiscoverySources:          DefaultEthDiscoverySources,
+		DiscoveryFile:             DefaultDiscoveryFile,
+		RedialInterval:            DefaultRedialInterval,
 		Subnets:                   DefaultSubnets,
 		GossipTopics:              DefaultEthereumGossipTopics,
+		SubscribeAllSubnets:       DefaultSubscribeAllSubnets,
 		PersistConnEvents:         DefaultPersistConnEvents,
 		PersistMsgs:               false,
+		MeshSnapshotInterval:      DefaultMeshSnapshotInterval,
+		GossipValidation:          DefaultGossipValidation,
 		ValPubkeys:                DefaultValPubkeys,
 		SSEIP:                     DefaultSSEIP,
 		SSEPort:                   DefaultSSEPort,
+		Security:                  DefaultEthereumSecurity,
+		SignedPeerRecord:          DefaultSignedPeerRecord,
+		ObservedAddrs:             DefaultObservedAddrs,
+		CollectPeerRecords:        DefaultCollectPeerRecords,
+		NegotiationTiming:         DefaultNegotiationTiming,
+		BlocksByRangeProbe:        DefaultBlocksByRangeProbe,
+		EclipseThreshold:          DefaultEclipseThreshold,
+		EclipseMinPeers:           DefaultEclipseMinPeers,
+		StaticPeers:               make([]string, 0),
+		StaticPeersInStats:        DefaultStaticPeersInStats,
+		MaxConns:                  DefaultMaxConns,
+		MaxConnsPerPeer:           DefaultMaxConnsPerPeer,
+		MaxStreamsPerPeer:         DefaultMaxStreamsPerPeer,
+		MaxStreamsPerProtocol:     DefaultMaxStreamsPerProtocol,
+		ResourceLimitsFile:        DefaultResourceLimitsFile,
+		BlocklistFile:             DefaultBlocklistFile,
+		BlocklistFromDB:           DefaultBlocklistFromDB,
+		TargetsFile:               DefaultTargetsFile,
+		TargetsFromDB:             DefaultTargetsFromDB,
+		Soak:                      DefaultSoak,
+		ObserverMode:              DefaultObserverMode,
+		DiscoveryOnly:             DefaultDiscoveryOnly,
+		ListenOnly:                DefaultListenOnly,
+		Discoverable:              DefaultDiscoverable,
+		SoakRetention:             DefaultSoakRetention,
+		SoakExportDir:             DefaultSoakExportDir,
+		WatchdogTimeout:           DefaultWatchdogTimeout,
+		KeyStore:                  DefaultKeyStore,
+		KeyRotation:               DefaultKeyRotation,
+		Hosts:                     DefaultHosts,
+		EventQueueSize:            DefaultEventQueueSize,
+		InfluxURL:                 DefaultInfluxURL,
+		InfluxOrg:                 DefaultInfluxOrg,
+		InfluxBucket:              DefaultInfluxBucket,
+		InfluxToken:               DefaultInfluxToken,
+		ClickHouseURL:             DefaultClickHouseURL,
+		ClickHouseDB:              DefaultClickHouseDB,
+		ClickHouseUser:            DefaultClickHouseUser,
+		ClickHousePassword:        DefaultClickHousePassword,
+		Retention:                 DefaultRetention,
+		RetentionSchedule:         DefaultRetentionSchedule,
+		CrawlSeed:                 DefaultCrawlSeed,
+		PeeringStrategy:           DefaultPeeringStrategy,
+		PeeringQuota:              DefaultPeeringQuota,
+		DialScoreWeights:          DefaultDialScoreWeights,
+		AdaptiveDialing:           DefaultAdaptiveDialing,
+		MaxDialRate:               DefaultMaxDialRate,
+		MaxDialWorkers:            DefaultMaxDialWorkers,
+		ResumeDialQueue:           DefaultResumeDialQueue,
+		DialTimeout:               DefaultDialTimeout,
+		IdentifyTimeout:           DefaultIdentifyTimeout,
+		ReqRespTimeout:            DefaultReqRespTimeout,
+		ClassTimeouts:             DefaultClassTimeouts,
+		DeprecationAttempts:       DefaultDeprecationAttempts,
+		DeprecationBackoff:        DefaultDeprecationBackoff,
+		DeprecationWindow:         DefaultDeprecationWindow,
+		ResurrectOnNewENR:         DefaultResurrectOnNewENR,
+		QualityInterval:           DefaultQualityInterval,
+		QualityWeights:            DefaultQualityWeights,
+		ReputationInterval:        DefaultReputationInterval,
+		ReputationWeights:         DefaultReputationWeights,
+		Churn:                     DefaultChurn,
+		ChurnSampleSize:           DefaultChurnSampleSize,
+		ChurnCycles:               DefaultChurnCycles,
+		ChurnConnectTime:          DefaultChurnConnectTime,
+		ChurnDisconnectTime:       DefaultChurnDisconnectTime,
+		ChurnSeed:                 DefaultChurnSeed,
+		HoldPeers:                 DefaultHoldPeers,
+		HoldStratify:              DefaultHoldStratify,
+		HoldPingInterval:          DefaultHoldPingInterval,
+		HoldBudget:                DefaultHoldBudget,
+		Dv5Strategy:               DefaultDv5Strategy,
+		Dv5Distances:              DefaultDv5Distances,
+		Dv5ForkDigests:            make([]string, 0),
+		ForkDigestAllowlist:       make([]string, 0),
+		StoreMismatchedForks:      DefaultStoreMismatchedForks,
 	}
 }
 
 func (c *EthereumCrawlerConfig) Apply(ctx *cli.Context) {
+	// network defaults and config file, overridden by the set flags
+	if err := c.applyFile(ctx); err != nil {
+		log.Panic(err)
+	}
+	c.applyFlags(ctx)
+}
+
+// applyFlags overrides the config with the flags that were set
+func (c *EthereumCrawlerConfig) applyFlags(ctx *cli.Context) {
 	// apply to the existing Default configuration the set flags
 	// log level
 	if ctx.IsSet("log-level") {
 		c.LogLevel = ctx.String("log-level")
 	}
+	// per-module log levels (host, discovery, gossip, db, peering)
+	if ctx.IsSet("log-levels") {
+		c.LogLevels = ctx.String("log-levels")
+	}
 	// private key
 	if ctx.IsSet("priv-key") {
 		c.PrivateKey = ctx.String("priv-key")
@@ -85,6 +307,18 @@ func (c *EthereumCrawlerConfig) Apply(ctx *cli.Context) {
 	if ctx.IsSet("ip") {
 		c.IP = ctx.String("ip")
 	}
+	// ip6
+	if ctx.IsSet("ip6") {
+		c.IP6 = ctx.String("ip6")
+	}
+	// ip family (v4, v6, both)
+	if ctx.IsSet("ip-family") {
+		ipFamily := strings.ToLower(ctx.String("ip-family"))
+		if !checkValidIPFamily(ipFamily) {
+			log.Panicf("unsupported ip family %s", ipFamily)
+		}
+		c.IPFamily = ipFamily
+	}
 	// port
 	if ctx.IsSet("port") {
 		port := ctx.Int("port")
@@ -143,10 +377,88 @@ func (c *EthereumCrawlerConfig) Apply(ctx *cli.Context) {
 		c.ActivePeersBackupInterval = ctx.String("peers-backup")
 	}
 
+	// peers history snapshots interval
+	if ctx.IsSet("peers-history") {
+		c.PeersHistoryInterval = ctx.String("peers-history")
+	}
+
+	// validator estimates interval
+	if ctx.IsSet("validator-estimates") {
+		c.ValidatorEstimates = ctx.String("validator-estimates")
+	}
+
+	// diversity gauges refresh interval
+	if ctx.IsSet("diversity-interval") {
+		c.DiversityInterval = ctx.String("diversity-interval")
+	}
+
+	// offline geolocation databases
+	if ctx.IsSet("geoip-city-db") {
+		c.GeoIPCityDB = ctx.String("geoip-city-db")
+	}
+	if ctx.IsSet("geoip-asn-db") {
+		c.GeoIPASNDB = ctx.String("geoip-asn-db")
+	}
+
+	// cache of the located ips
+	if ctx.IsSet("ip-cache-ttl") {
+		c.IpCacheTTL = ctx.String("ip-cache-ttl")
+	}
+	if ctx.IsSet("ip-refresh-interval") {
+		c.IpRefreshInterval = ctx.String("ip-refresh-interval")
+	}
+
+	// enrichment of the located ips
+	if ctx.IsSet("rdns-lookup") {
+		c.RdnsLookup = ctx.Bool("rdns-lookup")
+	}
+	if ctx.IsSet("cloud-tagging") {
+		c.CloudTagging = ctx.Bool("cloud-tagging")
+	}
+	if ctx.IsSet("cloud-ranges-file") {
+		c.CloudRangesFile = ctx.String("cloud-ranges-file")
+	}
+	if ctx.IsSet("tor-exit-list") {
+		c.TorExitList = ctx.Bool("tor-exit-list")
+	}
+	if ctx.IsSet("vpn-list-file") {
+		c.VPNListFile = ctx.String("vpn-list-file")
+	}
+
+	// resource usage interval
+	if ctx.IsSet("resource-usage-interval") {
+		c.ResourceUsageInterval = ctx.String("resource-usage-interval")
+	}
+
 	// bootnodes
 	if ctx.IsSet("bootnode") {
 		c.Bootnodes = ctx.StringSlice("bootnode")
 	}
+	if ctx.IsSet("bootnodes-source") {
+		c.BootnodesSource = ctx.String("bootnodes-source")
+	}
+	if ctx.IsSet("bootnode-ping-interval") {
+		c.BootnodePingInterval = ctx.String("bootnode-ping-interval")
+	}
+
+	// discovery sources that run concurrently
+	if ctx.IsSet("discovery-source") {
+		sources := make([]string, 0)
+		for _, src := range ctx.StringSlice("discovery-source") {
+			src = strings.ToLower(src)
+			if !checkValidDiscoverySource(src, EthDiscoverySources) {
+				log.Panicf("unsupported discovery source %s", src)
+			}
+			sources = append(sources, src)
+		}
+		c.DiscoverySources = sources
+	}
+	if ctx.IsSet("discovery-file") {
+		c.DiscoveryFile = ctx.String("discovery-file")
+	}
+	if ctx.IsSet("redial-interval") {
+		c.RedialInterval = ctx.String("redial-interval")
+	}
 
 	// gossip topics
 	if ctx.IsSet("gossip-topic") {
@@ -170,8 +482,18 @@ func (c *EthereumCrawlerConfig) Apply(ctx *cli.Context) {
 			}
 		}
 		if allF {
-			for i := 1; i <= 64; i++ {
-				c.Subnets = append(c.Subnets, i)
+			c.Subnets = allAttnets()
+		}
+	}
+	// all the attestation and sync committee subnets at once (to measure the whole message load)
+	if ctx.IsSet("subscribe-all-subnets") {
+		c.SubscribeAllSubnets = ctx.Bool("subscribe-all-subnets")
+	}
+	if c.SubscribeAllSubnets {
+		c.Subnets = allAttnets()
+		for _, msgType := range eth.SyncCommitteeMessageTypes() {
+			if !containsTopic(c.GossipTopics, msgType) {
+				c.GossipTopics = append(c.GossipTopics, msgType)
 			}
 		}
 	}
@@ -179,10 +501,308 @@ func (c *EthereumCrawlerConfig) Apply(ctx *cli.Context) {
 		c.PersistConnEvents = ctx.Bool("persist-connevents")
 	}
 
+	// security protocols (ordered by preference)
+	if ctx.IsSet("security") {
+		security := make([]string, 0)
+		for _, sec := range ctx.StringSlice("security") {
+			security = append(security, strings.ToLower(sec))
+		}
+		c.Security = security
+	}
+
+	// identify mode of the host
+	if ctx.IsSet("signed-peer-record") {
+		c.SignedPeerRecord = ctx.Bool("signed-peer-record")
+	}
+	if ctx.IsSet("observed-addrs") {
+		c.ObservedAddrs = ctx.Bool("observed-addrs")
+	}
+	if ctx.IsSet("collect-peer-records") {
+		c.CollectPeerRecords = ctx.Bool("collect-peer-records")
+	}
+
+	// timing of the protocol negotiations
+	if ctx.IsSet("negotiation-timing") {
+		c.NegotiationTiming = ctx.Bool("negotiation-timing")
+	}
+
+	// probing of the historical blocks served by the peers
+	if ctx.IsSet("blocks-by-range-probe") {
+		c.BlocksByRangeProbe = ctx.Int("blocks-by-range-probe")
+	}
+
+	// eclipse monitor
+	if ctx.IsSet("eclipse-threshold") {
+		c.EclipseThreshold = ctx.Float64("eclipse-threshold")
+	}
+	if ctx.IsSet("eclipse-min-peers") {
+		c.EclipseMinPeers = ctx.Int("eclipse-min-peers")
+	}
+
+	// static peers (always connected)
+	if ctx.IsSet("static-peer") {
+		c.StaticPeers = ctx.StringSlice("static-peer")
+	}
+	if ctx.IsSet("static-peers-in-stats") {
+		c.StaticPeersInStats = ctx.Bool("static-peers-in-stats")
+	}
+
+	// resource manager limits
+	if ctx.IsSet("max-conns") {
+		c.MaxConns = ctx.Int("max-conns")
+	}
+	if ctx.IsSet("max-conns-per-peer") {
+		c.MaxConnsPerPeer = ctx.Int("max-conns-per-peer")
+	}
+	if ctx.IsSet("max-streams-per-peer") {
+		c.MaxStreamsPerPeer = ctx.Int("max-streams-per-peer")
+	}
+	if ctx.IsSet("max-streams-per-protocol") {
+		c.MaxStreamsPerProtocol = ctx.Int("max-streams-per-protocol")
+	}
+	if ctx.IsSet("rcmgr-limits") {
+		c.ResourceLimitsFile = ctx.String("rcmgr-limits")
+	}
+
+	// connection gater
+	if ctx.IsSet("blocklist") {
+		c.BlocklistFile = ctx.String("blocklist")
+	}
+	if ctx.IsSet("blocklist-db") {
+		c.BlocklistFromDB = ctx.Bool("blocklist-db")
+	}
+
+	// target-list crawl mode
+	if ctx.IsSet("targets") {
+		c.TargetsFile = ctx.String("targets")
+	}
+	if ctx.IsSet("targets-db") {
+		c.TargetsFromDB = ctx.Bool("targets-db")
+	}
+
+	// soak mode, churn experiments, discovery-only and listen-only modes (imply observer mode unless it is explicitly disabled)
+	if ctx.IsSet("discovery-only") {
+		c.DiscoveryOnly = ctx.Bool("discovery-only")
+	}
+	if ctx.IsSet("listen-only") {
+		c.ListenOnly = ctx.Bool("listen-only")
+	}
+	if ctx.IsSet("discoverable") {
+		c.Discoverable = ctx.Bool("discoverable")
+	}
+	if ctx.IsSet("soak") {
+		c.Soak = ctx.Bool("soak")
+	}
+	if ctx.IsSet("churn") {
+		c.Churn = ctx.Bool("churn")
+	}
+	if ctx.IsSet("observer") {
+		c.ObserverMode = ctx.Bool("observer")
+	} else if c.Soak || c.Churn || c.DiscoveryOnly || c.ListenOnly {
+		c.ObserverMode = true
+	}
+	if ctx.IsSet("soak-retention") {
+		c.SoakRetention = ctx.String("soak-retention")
+	}
+	if ctx.IsSet("soak-export-dir") {
+		c.SoakExportDir = ctx.String("soak-export-dir")
+	}
+	if ctx.IsSet("watchdog-timeout") {
+		c.WatchdogTimeout = ctx.String("watchdog-timeout")
+	}
+
+	// persisted identity of the host
+	if ctx.IsSet("key-store") {
+		c.KeyStore = ctx.String("key-store")
+	}
+	if ctx.IsSet("key-rotation") {
+		c.KeyRotation = ctx.String("key-rotation")
+	}
+
+	// pool of hosts that share the dials
+	if ctx.IsSet("hosts") {
+		c.Hosts = ctx.Int("hosts")
+	}
+
+	// buffering of the host events
+	if ctx.IsSet("event-queue-size") {
+		c.EventQueueSize = ctx.Int("event-queue-size")
+	}
+
+	// influx exporter of the round summaries
+	if ctx.IsSet("influx-url") {
+		c.InfluxURL = ctx.String("influx-url")
+	}
+	if ctx.IsSet("influx-org") {
+		c.InfluxOrg = ctx.String("influx-org")
+	}
+	if ctx.IsSet("influx-bucket") {
+		c.InfluxBucket = ctx.String("influx-bucket")
+	}
+	if ctx.IsSet("influx-token") {
+		c.InfluxToken = ctx.String("influx-token")
+	}
+
+	// clickhouse mirror of the high-volume tables
+	if ctx.IsSet("clickhouse-url") {
+		c.ClickHouseURL = ctx.String("clickhouse-url")
+	}
+	if ctx.IsSet("clickhouse-db") {
+		c.ClickHouseDB = ctx.String("clickhouse-db")
+	}
+	if ctx.IsSet("clickhouse-user") {
+		c.ClickHouseUser = ctx.String("clickhouse-user")
+	}
+	if ctx.IsSet("clickhouse-password") {
+		c.ClickHousePassword = ctx.String("clickhouse-password")
+	}
+
+	// retention of the time-series tables
+	if ctx.IsSet("retention") {
+		c.Retention = ctx.String("retention")
+	}
+	if ctx.IsSet("retention-schedule") {
+		c.RetentionSchedule = ctx.String("retention-schedule")
+	}
+
+	// seed of the discovery walks and peer selection
+	if ctx.IsSet("crawl-seed") {
+		c.CrawlSeed = ctx.Int64("crawl-seed")
+	}
+
+	// peering strategy
+	if ctx.IsSet("peering-strategy") {
+		c.PeeringStrategy = ctx.String("peering-strategy")
+	}
+	if ctx.IsSet("peering-quota") {
+		c.PeeringQuota = ctx.Int("peering-quota")
+	}
+	if ctx.IsSet("dial-score-weights") {
+		c.DialScoreWeights = ctx.String("dial-score-weights")
+	}
+	// adaptive dial rate
+	if ctx.IsSet("adaptive-dialing") {
+		c.AdaptiveDialing = ctx.Bool("adaptive-dialing")
+	}
+	if ctx.IsSet("max-dial-rate") {
+		c.MaxDialRate = ctx.Float64("max-dial-rate")
+	}
+	if ctx.IsSet("max-dial-workers") {
+		c.MaxDialWorkers = ctx.Int("max-dial-workers")
+	}
+	if ctx.IsSet("resume-dial-queue") {
+		c.ResumeDialQueue = ctx.Bool("resume-dial-queue")
+	}
+
+	// timeouts
+	if ctx.IsSet("dial-timeout") {
+		c.DialTimeout = ctx.String("dial-timeout")
+	}
+	if ctx.IsSet("identify-timeout") {
+		c.IdentifyTimeout = ctx.String("identify-timeout")
+	}
+	if ctx.IsSet("reqresp-timeout") {
+		c.ReqRespTimeout = ctx.String("reqresp-timeout")
+	}
+	if ctx.IsSet("class-timeouts") {
+		c.ClassTimeouts = ctx.String("class-timeouts")
+	}
+	// deprecation policy
+	if ctx.IsSet("deprecation-attempts") {
+		c.DeprecationAttempts = ctx.Int("deprecation-attempts")
+	}
+	if ctx.IsSet("deprecation-backoff") {
+		c.DeprecationBackoff = ctx.String("deprecation-backoff")
+	}
+	if ctx.IsSet("deprecation-window") {
+		c.DeprecationWindow = ctx.String("deprecation-window")
+	}
+	if ctx.IsSet("resurrect-on-new-enr") {
+		c.ResurrectOnNewENR = ctx.Bool("resurrect-on-new-enr")
+	}
+
+	// peer quality score
+	if ctx.IsSet("quality-interval") {
+		c.QualityInterval = ctx.String("quality-interval")
+	}
+	if ctx.IsSet("quality-weights") {
+		c.QualityWeights = ctx.String("quality-weights")
+	}
+
+	// peer reputation
+	if ctx.IsSet("reputation-interval") {
+		c.ReputationInterval = ctx.String("reputation-interval")
+	}
+	if ctx.IsSet("reputation-weights") {
+		c.ReputationWeights = ctx.String("reputation-weights")
+	}
+
+	// connection churn experiment
+	if ctx.IsSet("churn-sample-size") {
+		c.ChurnSampleSize = ctx.Int("churn-sample-size")
+	}
+	if ctx.IsSet("churn-cycles") {
+		c.ChurnCycles = ctx.Int("churn-cycles")
+	}
+	if ctx.IsSet("churn-connect-time") {
+		c.ChurnConnectTime = ctx.String("churn-connect-time")
+	}
+	if ctx.IsSet("churn-disconnect-time") {
+		c.ChurnDisconnectTime = ctx.String("churn-disconnect-time")
+	}
+	if ctx.IsSet("churn-seed") {
+		c.ChurnSeed = ctx.Int64("churn-seed")
+	}
+
+	// connection holding
+	if ctx.IsSet("hold-peers") {
+		c.HoldPeers = ctx.Int("hold-peers")
+	}
+	if ctx.IsSet("hold-stratify") {
+		c.HoldStratify = ctx.String("hold-stratify")
+	}
+	if ctx.IsSet("hold-ping-interval") {
+		c.HoldPingInterval = ctx.String("hold-ping-interval")
+	}
+	if ctx.IsSet("hold-budget") {
+		c.HoldBudget = ctx.Int("hold-budget")
+	}
+
+	// discv5 strategy
+	if ctx.IsSet("dv5-strategy") {
+		c.Dv5Strategy = ctx.String("dv5-strategy")
+	}
+	if ctx.IsSet("dv5-distances") {
+		c.Dv5Distances = ctx.String("dv5-distances")
+	}
+	if ctx.IsSet("dv5-fork-digest") {
+		c.Dv5ForkDigests = ctx.StringSlice("dv5-fork-digest")
+	}
+
+	// fork digest filter of the discovered nodes
+	if ctx.IsSet("allowed-fork-digest") {
+		c.ForkDigestAllowlist = ctx.StringSlice("allowed-fork-digest")
+	}
+	if ctx.IsSet("store-mismatched-forks") {
+		c.StoreMismatchedForks = ctx.Bool("store-mismatched-forks")
+	}
+
 	// check if we want to track the Msgs in the SQL database
 	if ctx.IsSet("persist-msgs") {
 		c.PersistMsgs = ctx.Bool("persist-msgs")
 	}
+	if ctx.IsSet("mesh-snapshot-interval") {
+		c.MeshSnapshotInterval = ctx.String("mesh-snapshot-interval")
+	}
+	if ctx.IsSet("gossip-validation") {
+		c.GossipValidation = ctx.String("gossip-validation")
+		if err := eth.CheckValidationStrategy(c.GossipValidation); err != nil {
+			log.Panic(err)
+		}
+		if c.GossipValidation == eth.FullValidation && c.EthCLRemoteEndpoint == "" {
+			log.Panic("full gossip validation requires a remote-cl-endpoint")
+		}
+	}
 
 	// read validator-pubkeys .csv file if it exists
 	if ctx.IsSet("val-pubkeys") {
@@ -205,22 +825,139 @@ func (c *EthereumCrawlerConfig) Apply(ctx *cli.Context) {
 	}
 
 	log.WithFields(log.Fields{
-		"log-level":          c.LogLevel,
-		"priv-key":           c.PrivateKey,
-		"ip":                 c.IP,
-		"port":               c.Port,
-		"user-agent":         c.UserAgent,
-		"psql":               c.PsqlEndpoint,
-		"backup-interval":    c.ActivePeersBackupInterval,
-		"fork-digest":        c.ForkDigest,
-		"cl-endpoint":        c.EthCLRemoteEndpoint,
-		"bootnodes":          c.Bootnodes,
-		"gossip-topics":      c.GossipTopics,
-		"subnets":            c.Subnets,
-		"persist-connevents": c.PersistConnEvents,
-		"persist-msgs":       c.PersistMsgs,
-		"val-pubkeys":        len(c.ValPubkeys),
-		"sse-ip":             c.SSEIP,
-		"sse-port":           c.SSEPort,
+		"log-level":            c.LogLevel,
+		"log-levels":           c.LogLevels,
+		"priv-key":             c.PrivateKey,
+		"ip":                   c.IP,
+		"ip6":                  c.IP6,
+		"ip-family":            c.IPFamily,
+		"port":                 c.Port,
+		"user-agent":           c.UserAgent,
+		"network":              c.Network,
+		"psql":                 c.PsqlEndpoint,
+		"backup-interval":      c.ActivePeersBackupInterval,
+		"peers-history":        c.PeersHistoryInterval,
+		"validator-estimates":  c.ValidatorEstimates,
+		"diversity-interval":   c.DiversityInterval,
+		"geoip-city-db":        c.GeoIPCityDB,
+		"geoip-asn-db":         c.GeoIPASNDB,
+		"ip-cache-ttl":         c.IpCacheTTL,
+		"ip-refresh-interval":  c.IpRefreshInterval,
+		"rdns-lookup":          c.RdnsLookup,
+		"cloud-tagging":        c.CloudTagging,
+		"cloud-ranges-file":    c.CloudRangesFile,
+		"tor-exit-list":        c.TorExitList,
+		"vpn-list-file":        c.VPNListFile,
+		"usage-interval":       c.ResourceUsageInterval,
+		"fork-digest":          c.ForkDigest,
+		"cl-endpoint":          c.EthCLRemoteEndpoint,
+		"bootnodes":            c.Bootnodes,
+		"bootnodes-source":     c.BootnodesSource,
+		"bootnode-ping":        c.BootnodePingInterval,
+		"discovery-sources":    c.DiscoverySources,
+		"discovery-file":       c.DiscoveryFile,
+		"redial-interval":      c.RedialInterval,
+		"gossip-topics":        c.GossipTopics,
+		"subnets":              c.Subnets,
+		"all-subnets":          c.SubscribeAllSubnets,
+		"persist-connevents":   c.PersistConnEvents,
+		"persist-msgs":         c.PersistMsgs,
+		"mesh-interval":        c.MeshSnapshotInterval,
+		"gossip-validation":    c.GossipValidation,
+		"val-pubkeys":          len(c.ValPubkeys),
+		"sse-ip":               c.SSEIP,
+		"sse-port":             c.SSEPort,
+		"security":             c.Security,
+		"signed-peer-record":   c.SignedPeerRecord,
+		"observed-addrs":       c.ObservedAddrs,
+		"collect-peer-records": c.CollectPeerRecords,
+		"negotiation-timing":   c.NegotiationTiming,
+		"blocks-probe":         c.BlocksByRangeProbe,
+		"eclipse-threshold":    c.EclipseThreshold,
+		"eclipse-min-peers":    c.EclipseMinPeers,
+		"static-peers":         c.StaticPeers,
+		"static-in-stats":      c.StaticPeersInStats,
+		"max-conns":            c.MaxConns,
+		"max-conns-per-peer":   c.MaxConnsPerPeer,
+		"max-streams-peer":     c.MaxStreamsPerPeer,
+		"max-streams-proto":    c.MaxStreamsPerProtocol,
+		"rcmgr-limits":         c.ResourceLimitsFile,
+		"blocklist":            c.BlocklistFile,
+		"blocklist-db":         c.BlocklistFromDB,
+		"targets":              c.TargetsFile,
+		"targets-db":           c.TargetsFromDB,
+		"soak":                 c.Soak,
+		"observer":             c.ObserverMode,
+		"discovery-only":       c.DiscoveryOnly,
+		"listen-only":          c.ListenOnly,
+		"discoverable":         c.Discoverable,
+		"soak-retention":       c.SoakRetention,
+		"soak-export-dir":      c.SoakExportDir,
+		"watchdog-timeout":     c.WatchdogTimeout,
+		"key-store":            c.KeyStore,
+		"key-rotation":         c.KeyRotation,
+		"hosts":                c.Hosts,
+		"event-queue-size":     c.EventQueueSize,
+		"influx-url":           c.InfluxURL,
+		"influx-org":           c.InfluxOrg,
+		"influx-bucket":        c.InfluxBucket,
+		"clickhouse-url":       c.ClickHouseURL,
+		"clickhouse-db":        c.ClickHouseDB,
+		"clickhouse-user":      c.ClickHouseUser,
+		"retention":            c.Retention,
+		"retention-schedule":   c.RetentionSchedule,
+		"crawl-seed":           c.CrawlSeed,
+		"peering-strategy":     c.PeeringStrategy,
+		"peering-quota":        c.PeeringQuota,
+		"dial-score-weights":   c.DialScoreWeights,
+		"adaptive-dialing":     c.AdaptiveDialing,
+		"max-dial-rate":        c.MaxDialRate,
+		"max-dial-workers":     c.MaxDialWorkers,
+		"resume-dial-queue":    c.ResumeDialQueue,
+		"dial-timeout":         c.DialTimeout,
+		"identify-timeout":     c.IdentifyTimeout,
+		"reqresp-timeout":      c.ReqRespTimeout,
+		"class-timeouts":       c.ClassTimeouts,
+		"deprecation-attempts": c.DeprecationAttempts,
+		"deprecation-backoff":  c.DeprecationBackoff,
+		"deprecation-window":   c.DeprecationWindow,
+		"resurrect-on-new-enr": c.ResurrectOnNewENR,
+		"quality-interval":     c.QualityInterval,
+		"quality-weights":      c.QualityWeights,
+		"reputation-interval":  c.ReputationInterval,
+		"reputation-weights":   c.ReputationWeights,
+		"churn":                c.Churn,
+		"churn-sample-size":    c.ChurnSampleSize,
+		"churn-cycles":         c.ChurnCycles,
+		"churn-connect-time":   c.ChurnConnectTime,
+		"churn-disconnect":     c.ChurnDisconnectTime,
+		"churn-seed":           c.ChurnSeed,
+		"hold-peers":           c.HoldPeers,
+		"hold-stratify":        c.HoldStratify,
+		"hold-ping-interval":   c.HoldPingInterval,
+		"hold-budget":          c.HoldBudget,
+		"dv5-strategy":         c.Dv5Strategy,
+		"dv5-distances":        c.Dv5Distances,
+		"dv5-fork-digests":     c.Dv5ForkDigests,
+		"allowed-forks":        c.ForkDigestAllowlist,
+		"store-mismatched":     c.StoreMismatchedForks,
 	}).Info("config for the Ethereum crawler")
 }
+
+// allAttnets returns the indexes of all the attestation subnets
+func allAttnets() []int {
+	subnets := make([]int, 0, eth.SubnetLimit)
+	for i := 0; i < eth.SubnetLimit; i++ {
+		subnets = append(subnets, i)
+	}
+	return subnets
+}
+
+func containsTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/urfave/cli/v2"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+func Test_SubscribeAllSubnets(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.Bool("subscribe-all-subnets", false, "")
+	require.NoError(t, set.Set("subscribe-all-subnets", "true"))
+
+	conf := NewEthereumCrawlerConfig()
+	conf.GossipTopics = []string{eth.BeaconBlockTopicBase, "sync_committee_1"}
+	conf.applyFlags(cli.NewContext(nil, set, nil))
+
+	require.True(t, conf.SubscribeAllSubnets)
+	require.Len(t, conf.Subnets, eth.SubnetLimit)
+	require.Equal(t, 0, conf.Subnets[0])
+	require.Equal(t, eth.SubnetLimit-1, conf.Subnets[eth.SubnetLimit-1])
+	// the sync committee topics are added once, next to the configured ones
+	require.ElementsMatch(t, append([]string{eth.BeaconBlockTopicBase}, eth.SyncCommitteeMessageTypes()...), conf.GossipTopics)
+
+	// without the flag, the configured subnets are kept
+	conf = NewEthereumCrawlerConfig()
+	conf.Subnets = []int{3}
+	conf.applyFlags(cli.NewContext(nil, flag.NewFlagSet("test", flag.ContinueOnError), nil))
+	require.False(t, conf.SubscribeAllSubnets)
+	require.Equal(t, []int{3}, conf.Subnets)
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/params"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+var (
+	DefaultELNetwork       string = "mainnet"
+	DefaultHelloTimeout    string = "10s"
+	DefaultHelloWorkers    int    = 50
+	DefaultRecrawlInterval string = "30m"
+
+	// Bootnodes of the EL networks that can be crawled with the discv4 crawler
+	ELNetworkBootnodes map[string][]string = map[string][]string{
+		"mainnet": params.MainnetBootnodes,
+		"sepolia": params.SepoliaBootnodes,
+		"holesky": params.HoleskyBootnodes,
+	}
+)
+
+type EthereumELCrawlerConfig struct {
+	LogLevel        string   `json:"log-level"`
+	PrivateKey      string   `json:"priv-key"`
+	Port            int      `json:"port"`
+	MetricsIP       string   `json:"metrics-ip"`
+	MetricsPort     int      `json:"metrics-port"`
+	UserAgent       string   `json:"user-agent"`
+	PsqlEndpoint    string   `json:"psql-endpoint"`
+	Network         string   `json:"network"`
+	Bootnodes       []string `json:"bootnodes"`
+	ForkHashes      []string `json:"fork-hashes"`
+	HelloTimeout    string   `json:"hello-timeout"`
+	HelloWorkers    int      `json:"hello-workers"`
+	RecrawlInterval string   `json:"recrawl-interval"`
+}
+
+func NewEthereumELCrawlerConfig() *EthereumELCrawlerConfig {
+	// Return Default values for the ethereum EL configuration
+	return &EthereumELCrawlerConfig{
+		LogLevel:        DefaultLogLevel,
+		PrivateKey:      DefaultPrivKey,
+		Port:            DefaultPort,
+		MetricsIP:       DefaultMetricsIP,
+		MetricsPort:     DefaultMetricsPort,
+		UserAgent:       DefaultUserAgent,
+		PsqlEndpoint:    DefaultPSQLEndpoint,
+		Network:         DefaultELNetwork,
+		Bootnodes:       ELNetworkBootnodes[DefaultELNetwork],
+		ForkHashes:      make([]string, 0),
+		HelloTimeout:    DefaultHelloTimeout,
+		HelloWorkers:    DefaultHelloWorkers,
+		RecrawlInterval: DefaultRecrawlInterval,
+	}
+}
+
+func (c *EthereumELCrawlerConfig) Apply(ctx *cli.Context) {
+	// apply to the existing Default configuration the set flags
+	// log level
+	if ctx.IsSet("log-level") {
+		c.LogLevel = ctx.String("log-level")
+	}
+	// private key
+	if ctx.IsSet("priv-key") {
+		c.PrivateKey = ctx.String("priv-key")
+	}
+	// port (discv4 over UDP)
+	if ctx.IsSet("port") {
+		port := ctx.Int("port")
+		if checkValidPort(port) {
+			c.Port = port
+		}
+	}
+	// metrics-ip (pprof + prometheus)
+	if ctx.IsSet("metrics-ip") {
+		c.MetricsIP = ctx.String("metrics-ip")
+	}
+	// metrics-port (pprof + prometheus)
+	if ctx.IsSet("metrics-port") {
+		mPort := ctx.Int("metrics-port")
+		if checkValidPort(mPort) {
+			c.MetricsPort = mPort
+		}
+	}
+	// user agent (client name of our devp2p hello)
+	if ctx.IsSet("user-agent") {
+		c.UserAgent = ctx.String("user-agent")
+	}
+
+	// postgresql endpoint
+	if ctx.IsSet("psql-endpoint") {
+		c.PsqlEndpoint = ctx.String("psql-endpoint")
+	}
+
+	// network (selects as well the default bootnodes)
+	if ctx.IsSet("network") {
+		network := strings.ToLower(ctx.String("network"))
+		bootnodes, ok := ELNetworkBootnodes[network]
+		if !ok {
+			log.Panicf("unsupported network %s", network)
+		}
+		c.Network = network
+		c.Bootnodes = bootnodes
+	}
+
+	// bootnodes
+	if ctx.IsSet("bootnode") {
+		c.Bootnodes = ctx.StringSlice("bootnode")
+	}
+
+	// fork hashes of the chain that we want to crawl
+	if ctx.IsSet("fork-hash") {
+		c.ForkHashes = ctx.StringSlice("fork-hash")
+	}
+
+	// client identification through the devp2p hello
+	if ctx.IsSet("hello-timeout") {
+		c.HelloTimeout = ctx.String("hello-timeout")
+	}
+	if ctx.IsSet("hello-workers") {
+		c.HelloWorkers = ctx.Int("hello-workers")
+	}
+	if ctx.IsSet("recrawl-interval") {
+		c.RecrawlInterval = ctx.String("recrawl-interval")
+	}
+
+	log.WithFields(log.Fields{
+		"log-level":        c.LogLevel,
+		"priv-key":         c.PrivateKey,
+		"port":             c.Port,
+		"user-agent":       c.UserAgent,
+		"psql":             c.PsqlEndpoint,
+		"network":          c.Network,
+		"bootnodes":        c.Bootnodes,
+		"fork-hashes":      c.ForkHashes,
+		"hello-timeout":    c.HelloTimeout,
+		"hello-workers":    c.HelloWorkers,
+		"recrawl-interval": c.RecrawlInterval,
+	}).Info("config for the Ethereum EL crawler")
+}
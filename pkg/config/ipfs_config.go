@@ -0,0 +1,676 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+	"github.com/migalabs/armiarma/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+var (
+	DefaultIpfsNetwork string = "ipfs"
+
+	// Discovery sources that can be composed by the IPFS crawler
+	IpfsDiscoverySources        []string = []string{"dht", "dv5", "static", "db"}
+	DefaultIpfsDiscoverySources []string = []string{"dht"}
+
+	// DHT walk (bucket enumeration + random walk)
+	DefaultDhtWalkers         int    = 25
+	DefaultDhtWalkInterval    string = "30s"
+	DefaultDhtRecrawlInterval string = "30m"
+
+	// Security protocols ordered by preference
+	DefaultIpfsSecurity []string = []string{"noise", "tls"}
+
+	// Networks that can be crawled with the IPFS crawler
+	IpfsNetworks map[string]utils.NetworkType = map[string]utils.NetworkType{
+		"ipfs":     utils.IpfsNetwork,
+		"filecoin": utils.FilecoinNetwork,
+		"waku":     utils.WakuNetwork,
+	}
+
+	// Relay shards of the Waku network whose messages are accounted in the gossip metrics
+	DefaultWakuClusterID int   = waku.DefaultClusterID
+	DefaultWakuShards    []int = []int{0, 1, 2, 3, 4, 5, 6, 7}
+)
+
+type IpfsCrawlerConfig struct {
+	LogLevel                  string   `json:"log-level"`
+	LogLevels                 string   `json:"log-levels"`
+	PrivateKey                string   `json:"priv-key"`
+	IP                        string   `json:"ip"`
+	IP6                       string   `json:"ip6"`
+	IPFamily                  string   `json:"ip-family"`
+	Port                      int      `json:"port"`
+	MetricsIP                 string   `json:"metrics-ip"`
+	MetricsPort               int      `json:"metrics-port"`
+	UserAgent                 string   `json:"user-agent"`
+	PsqlEndpoint              string   `json:"psql-endpoint"`
+	ActivePeersBackupInterval string   `json:"peers-backup"`
+	PeersHistoryInterval      string   `json:"peers-history"`
+	DiversityInterval         string   `json:"diversity-interval"`
+	GeoIPCityDB               string   `json:"geoip-city-db"`
+	GeoIPASNDB                string   `json:"geoip-asn-db"`
+	IpCacheTTL                string   `json:"ip-cache-ttl"`
+	IpRefreshInterval         string   `json:"ip-refresh-interval"`
+	RdnsLookup                bool     `json:"rdns-lookup"`
+	CloudTagging              bool     `json:"cloud-tagging"`
+	CloudRangesFile           string   `json:"cloud-ranges-file"`
+	TorExitList               bool     `json:"tor-exit-list"`
+	VPNListFile               string   `json:"vpn-list-file"`
+	ResourceUsageInterval     string   `json:"resource-usage-interval"`
+	Network                   string   `json:"network"`
+	Bootnodes                 []string `json:"bootnodes"`
+	DiscoverySources          []string `json:"discovery-sources"`
+	DiscoveryFile             string   `json:"discovery-file"`
+	RedialInterval            string   `json:"redial-interval"`
+	PersistConnEvents         bool     `json:"persist-connevents"`
+	Security                  []string `json:"security"`
+	SignedPeerRecord          bool     `json:"signed-peer-record"`
+	ObservedAddrs             bool     `json:"observed-addrs"`
+	CollectPeerRecords        bool     `json:"collect-peer-records"`
+	NegotiationTiming         bool     `json:"negotiation-timing"`
+	EclipseThreshold          float64  `json:"eclipse-threshold"`
+	EclipseMinPeers           int      `json:"eclipse-min-peers"`
+	StaticPeers               []string `json:"static-peers"`
+	StaticPeersInStats        bool     `json:"static-peers-in-stats"`
+	MaxConns                  int      `json:"max-conns"`
+	MaxConnsPerPeer           int      `json:"max-conns-per-peer"`
+	MaxStreamsPerPeer         int      `json:"max-streams-per-peer"`
+	MaxStreamsPerProtocol     int      `json:"max-streams-per-protocol"`
+	ResourceLimitsFile        string   `json:"rcmgr-limits"`
+	BlocklistFile             string   `json:"blocklist"`
+	BlocklistFromDB           bool     `json:"blocklist-db"`
+	TargetsFile               string   `json:"targets"`
+	TargetsFromDB             bool     `json:"targets-db"`
+	Soak                      bool     `json:"soak"`
+	ObserverMode              bool     `json:"observer"`
+	SoakRetention             string   `json:"soak-retention"`
+	SoakExportDir             string   `json:"soak-export-dir"`
+	WatchdogTimeout           string   `json:"watchdog-timeout"`
+	KeyStore                  string   `json:"key-store"`
+	KeyRotation               string   `json:"key-rotation"`
+	Hosts                     int      `json:"hosts"`
+	EventQueueSize            int      `json:"event-queue-size"`
+	InfluxURL                 string   `json:"influx-url"`
+	InfluxOrg                 string   `json:"influx-org"`
+	InfluxBucket              string   `json:"influx-bucket"`
+	InfluxToken               string   `json:"influx-token"`
+	ClickHouseURL             string   `json:"clickhouse-url"`
+	ClickHouseDB              string   `json:"clickhouse-db"`
+	ClickHouseUser            string   `json:"clickhouse-user"`
+	ClickHousePassword        string   `json:"clickhouse-password"`
+	Retention                 string   `json:"retention"`
+	RetentionSchedule         string   `json:"retention-schedule"`
+	CrawlSeed                 int64    `json:"crawl-seed"`
+	PeeringStrategy           string   `json:"peering-strategy"`
+	PeeringQuota              int      `json:"peering-quota"`
+	DialScoreWeights          string   `json:"dial-score-weights"`
+	AdaptiveDialing           bool     `json:"adaptive-dialing"`
+	MaxDialRate               float64  `json:"max-dial-rate"`
+	MaxDialWorkers            int      `json:"max-dial-workers"`
+	ResumeDialQueue           bool     `json:"resume-dial-queue"`
+	DialTimeout               string   `json:"dial-timeout"`
+	IdentifyTimeout           string   `json:"identify-timeout"`
+	ReqRespTimeout            string   `json:"reqresp-timeout"`
+	ClassTimeouts             string   `json:"class-timeouts"`
+	DeprecationAttempts       int      `json:"deprecation-attempts"`
+	DeprecationBackoff        string   `json:"deprecation-backoff"`
+	DeprecationWindow         string   `json:"deprecation-window"`
+	DhtWalkers                int      `json:"dht-walkers"`
+	DhtWalkInterval           string   `json:"dht-walk-interval"`
+	DhtRecrawlInterval        string   `json:"dht-recrawl-interval"`
+	WakuClusterID             int      `json:"waku-cluster-id"`
+	WakuShards                []int    `json:"waku-shards"`
+}
+
+func NewIpfsCrawlerConfig() *IpfsCrawlerConfig {
+	// Return Default values for the ipfs configuration
+	return &IpfsCrawlerConfig{
+		LogLevel:                  DefaultLogLevel,
+		LogLevels:                 DefaultLogLevels,
+		PrivateKey:                DefaultPrivKey,
+		IP:                        DefaultIP,
+		IP6:                       DefaultIP6,
+		IPFamily:                  DefaultIPFamily,
+		Port:                      DefaultPort,
+		MetricsIP:                 DefaultMetricsIP,
+		MetricsPort:               DefaultMetricsPort,
+		UserAgent:                 DefaultUserAgent,
+		PsqlEndpoint:              DefaultPSQLEndpoint,
+		ActivePeersBackupInterval: DefaultActivePeersBackupInterval,
+		PeersHistoryInterval:      DefaultPeersHistoryInterval,
+		DiversityInterval:         DefaultDiversityInterval,
+		GeoIPCityDB:               DefaultGeoIPCityDB,
+		GeoIPASNDB:                DefaultGeoIPASNDB,
+		IpCacheTTL:                DefaultIpCacheTTL,
+		IpRefreshInterval:         DefaultIpRefreshInterval,
+		RdnsLookup:                DefaultRdnsLookup,
+		CloudTagging:              DefaultCloudTagging,
+		CloudRangesFile:           DefaultCloudRangesFile,
+		TorExitList:               DefaultTorExitList,
+		VPNListFile:               DefaultVPNListFile,
+		ResourceUsageInterval:     DefaultResourceUsageInterval,
+		Network:                   DefaultIpfsNetwork,
+		Bootnodes:                 DefaultIPFSBootnodes,
+		DiscoverySources:          DefaultIpfsDiscoverySources,
+		DiscoveryFile:             DefaultDiscoveryFile,
+		RedialInterval:            DefaultRedialInterval,
+		PersistConnEvents:         DefaultPersistConnEvents,
+		Security:                  DefaultIpfsSecurity,
+		SignedPeerRecord:          DefaultSignedPeerRecord,
+		ObservedAddrs:             DefaultObservedAddrs,
+		CollectPeerRecords:        DefaultCollectPeerRecords,
+		NegotiationTiming:         DefaultNegotiationTiming,
+		EclipseThreshold:          DefaultEclipseThreshold,
+		EclipseMinPeers:           DefaultEclipseMinPeers,
+		StaticPeers:               make([]string, 0),
+		StaticPeersInStats:        DefaultStaticPeersInStats,
+		MaxConns:                  DefaultMaxConns,
+		MaxConnsPerPeer:           DefaultMaxConnsPerPeer,
+		MaxStreamsPerPeer:         DefaultMaxStreamsPerPeer,
+		MaxStreamsPerProtocol:     DefaultMaxStreamsPerProtocol,
+		ResourceLimitsFile:        DefaultResourceLimitsFile,
+		BlocklistFile:             DefaultBlocklistFile,
+		BlocklistFromDB:           DefaultBlocklistFromDB,
+		TargetsFile:               DefaultTargetsFile,
+		TargetsFromDB:             DefaultTargetsFromDB,
+		Soak:                      DefaultSoak,
+		ObserverMode:              DefaultObserverMode,
+		SoakRetention:             DefaultSoakRetention,
+		SoakExportDir:             DefaultSoakExportDir,
+		WatchdogTimeout:           DefaultWatchdogTimeout,
+		KeyStore:                  DefaultKeyStore,
+		KeyRotation:               DefaultKeyRotation,
+		Hosts:                     DefaultHosts,
+		EventQueueSize:            DefaultEventQueueSize,
+		InfluxURL:                 DefaultInfluxURL,
+		InfluxOrg:                 DefaultInfluxOrg,
+		InfluxBucket:              DefaultInfluxBucket,
+		InfluxToken:               DefaultInfluxToken,
+		ClickHouseURL:             DefaultClickHouseURL,
+		ClickHouseDB:              DefaultClickHouseDB,
+		ClickHouseUser:            DefaultClickHouseUser,
+		ClickHousePassword:        DefaultClickHousePassword,
+		Retention:                 DefaultRetention,
+		RetentionSchedule:         DefaultRetentionSchedule,
+		CrawlSeed:                 DefaultCrawlSeed,
+		PeeringStrategy:           DefaultPeeringStrategy,
+		PeeringQuota:              DefaultPeeringQuota,
+		DialScoreWeights:          DefaultDialScoreWeights,
+		AdaptiveDialing:           DefaultAdaptiveDialing,
+		MaxDialRate:               DefaultMaxDialRate,
+		MaxDialWorkers:            DefaultMaxDialWorkers,
+		ResumeDialQueue:           DefaultResumeDialQueue,
+		DialTimeout:               DefaultDialTimeout,
+		IdentifyTimeout:           DefaultIdentifyTimeout,
+		ReqRespTimeout:            DefaultReqRespTimeout,
+		ClassTimeouts:             DefaultClassTimeouts,
+		DeprecationAttempts:       DefaultDeprecationAttempts,
+		DeprecationBackoff:        DefaultDeprecationBackoff,
+		DeprecationWindow:         DefaultDeprecationWindow,
+		DhtWalkers:                DefaultDhtWalkers,
+		DhtWalkInterval:           DefaultDhtWalkInterval,
+		DhtRecrawlInterval:        DefaultDhtRecrawlInterval,
+		WakuClusterID:             DefaultWakuClusterID,
+		WakuShards:                DefaultWakuShards,
+	}
+}
+
+func (c *IpfsCrawlerConfig) Apply(ctx *cli.Context) {
+	// network defaults and config file, overridden by the set flags
+	if err := c.applyFile(ctx); err != nil {
+		log.Panic(err)
+	}
+
+	// apply to the existing Default configuration the set flags
+	// log level
+	if ctx.IsSet("log-level") {
+		c.LogLevel = ctx.String("log-level")
+	}
+	// per-module log levels (host, discovery, gossip, db, peering)
+	if ctx.IsSet("log-levels") {
+		c.LogLevels = ctx.String("log-levels")
+	}
+	// private key
+	if ctx.IsSet("priv-key") {
+		c.PrivateKey = ctx.String("priv-key")
+	}
+	// ip
+	if ctx.IsSet("ip") {
+		c.IP = ctx.String("ip")
+	}
+	// ip6
+	if ctx.IsSet("ip6") {
+		c.IP6 = ctx.String("ip6")
+	}
+	// ip family (v4, v6, both)
+	if ctx.IsSet("ip-family") {
+		ipFamily := strings.ToLower(ctx.String("ip-family"))
+		if !checkValidIPFamily(ipFamily) {
+			log.Panicf("unsupported ip family %s", ipFamily)
+		}
+		c.IPFamily = ipFamily
+	}
+	// port
+	if ctx.IsSet("port") {
+		port := ctx.Int("port")
+		if checkValidPort(port) {
+			c.Port = port
+		}
+	}
+	// metrics-ip (pprof + prometheus)
+	if ctx.IsSet("metrics-ip") {
+		c.MetricsIP = ctx.String("metrics-ip")
+	}
+	// metrics-port (pprof + prometheus)
+	if ctx.IsSet("metrics-port") {
+		mPort := ctx.Int("metrics-port")
+		if checkValidPort(mPort) {
+			c.MetricsPort = mPort
+		}
+	}
+	// user agent
+	if ctx.IsSet("user-agent") {
+		c.UserAgent = ctx.String("user-agent")
+	}
+
+	// postgresql endpoint
+	if ctx.IsSet("psql-endpoint") {
+		c.PsqlEndpoint = ctx.String("psql-endpoint")
+	}
+
+	// active peers' backup interval
+	if ctx.IsSet("peers-backup") {
+		c.ActivePeersBackupInterval = ctx.String("peers-backup")
+	}
+
+	// peers history snapshots interval
+	if ctx.IsSet("peers-history") {
+		c.PeersHistoryInterval = ctx.String("peers-history")
+	}
+
+	// diversity gauges refresh interval
+	if ctx.IsSet("diversity-interval") {
+		c.DiversityInterval = ctx.String("diversity-interval")
+	}
+
+	// offline geolocation databases
+	if ctx.IsSet("geoip-city-db") {
+		c.GeoIPCityDB = ctx.String("geoip-city-db")
+	}
+	if ctx.IsSet("geoip-asn-db") {
+		c.GeoIPASNDB = ctx.String("geoip-asn-db")
+	}
+
+	// cache of the located ips
+	if ctx.IsSet("ip-cache-ttl") {
+		c.IpCacheTTL = ctx.String("ip-cache-ttl")
+	}
+	if ctx.IsSet("ip-refresh-interval") {
+		c.IpRefreshInterval = ctx.String("ip-refresh-interval")
+	}
+
+	// enrichment of the located ips
+	if ctx.IsSet("rdns-lookup") {
+		c.RdnsLookup = ctx.Bool("rdns-lookup")
+	}
+	if ctx.IsSet("cloud-tagging") {
+		c.CloudTagging = ctx.Bool("cloud-tagging")
+	}
+	if ctx.IsSet("cloud-ranges-file") {
+		c.CloudRangesFile = ctx.String("cloud-ranges-file")
+	}
+	if ctx.IsSet("tor-exit-list") {
+		c.TorExitList = ctx.Bool("tor-exit-list")
+	}
+	if ctx.IsSet("vpn-list-file") {
+		c.VPNListFile = ctx.String("vpn-list-file")
+	}
+
+	// resource usage interval
+	if ctx.IsSet("resource-usage-interval") {
+		c.ResourceUsageInterval = ctx.String("resource-usage-interval")
+	}
+
+	// bootnodes
+	if ctx.IsSet("bootnode") {
+		c.Bootnodes = ctx.StringSlice("bootnode")
+	}
+
+	// discovery sources that run concurrently
+	if ctx.IsSet("discovery-source") {
+		sources := make([]string, 0)
+		for _, src := range ctx.StringSlice("discovery-source") {
+			src = strings.ToLower(src)
+			if !checkValidDiscoverySource(src, IpfsDiscoverySources) {
+				log.Panicf("unsupported discovery source %s", src)
+			}
+			sources = append(sources, src)
+		}
+		c.DiscoverySources = sources
+	}
+	if ctx.IsSet("discovery-file") {
+		c.DiscoveryFile = ctx.String("discovery-file")
+	}
+	if ctx.IsSet("redial-interval") {
+		c.RedialInterval = ctx.String("redial-interval")
+	}
+
+	if ctx.IsSet("persist-connevents") {
+		c.PersistConnEvents = ctx.Bool("persist-connevents")
+	}
+
+	// security protocols (ordered by preference)
+	if ctx.IsSet("security") {
+		security := make([]string, 0)
+		for _, sec := range ctx.StringSlice("security") {
+			security = append(security, strings.ToLower(sec))
+		}
+		c.Security = security
+	}
+
+	// identify mode of the host
+	if ctx.IsSet("signed-peer-record") {
+		c.SignedPeerRecord = ctx.Bool("signed-peer-record")
+	}
+	if ctx.IsSet("observed-addrs") {
+		c.ObservedAddrs = ctx.Bool("observed-addrs")
+	}
+	if ctx.IsSet("collect-peer-records") {
+		c.CollectPeerRecords = ctx.Bool("collect-peer-records")
+	}
+
+	// timing of the protocol negotiations
+	if ctx.IsSet("negotiation-timing") {
+		c.NegotiationTiming = ctx.Bool("negotiation-timing")
+	}
+
+	// eclipse monitor
+	if ctx.IsSet("eclipse-threshold") {
+		c.EclipseThreshold = ctx.Float64("eclipse-threshold")
+	}
+	if ctx.IsSet("eclipse-min-peers") {
+		c.EclipseMinPeers = ctx.Int("eclipse-min-peers")
+	}
+
+	// static peers (always connected)
+	if ctx.IsSet("static-peer") {
+		c.StaticPeers = ctx.StringSlice("static-peer")
+	}
+	if ctx.IsSet("static-peers-in-stats") {
+		c.StaticPeersInStats = ctx.Bool("static-peers-in-stats")
+	}
+
+	// resource manager limits
+	if ctx.IsSet("max-conns") {
+		c.MaxConns = ctx.Int("max-conns")
+	}
+	if ctx.IsSet("max-conns-per-peer") {
+		c.MaxConnsPerPeer = ctx.Int("max-conns-per-peer")
+	}
+	if ctx.IsSet("max-streams-per-peer") {
+		c.MaxStreamsPerPeer = ctx.Int("max-streams-per-peer")
+	}
+	if ctx.IsSet("max-streams-per-protocol") {
+		c.MaxStreamsPerProtocol = ctx.Int("max-streams-per-protocol")
+	}
+	if ctx.IsSet("rcmgr-limits") {
+		c.ResourceLimitsFile = ctx.String("rcmgr-limits")
+	}
+
+	// connection gater
+	if ctx.IsSet("blocklist") {
+		c.BlocklistFile = ctx.String("blocklist")
+	}
+	if ctx.IsSet("blocklist-db") {
+		c.BlocklistFromDB = ctx.Bool("blocklist-db")
+	}
+
+	// target-list crawl mode
+	if ctx.IsSet("targets") {
+		c.TargetsFile = ctx.String("targets")
+	}
+	if ctx.IsSet("targets-db") {
+		c.TargetsFromDB = ctx.Bool("targets-db")
+	}
+
+	// soak mode (implies observer mode unless it is explicitly disabled)
+	if ctx.IsSet("soak") {
+		c.Soak = ctx.Bool("soak")
+	}
+	if ctx.IsSet("observer") {
+		c.ObserverMode = ctx.Bool("observer")
+	} else if c.Soak {
+		c.ObserverMode = true
+	}
+	if ctx.IsSet("soak-retention") {
+		c.SoakRetention = ctx.String("soak-retention")
+	}
+	if ctx.IsSet("soak-export-dir") {
+		c.SoakExportDir = ctx.String("soak-export-dir")
+	}
+	if ctx.IsSet("watchdog-timeout") {
+		c.WatchdogTimeout = ctx.String("watchdog-timeout")
+	}
+
+	// persisted identity of the host
+	if ctx.IsSet("key-store") {
+		c.KeyStore = ctx.String("key-store")
+	}
+	if ctx.IsSet("key-rotation") {
+		c.KeyRotation = ctx.String("key-rotation")
+	}
+
+	// pool of hosts that share the dials
+	if ctx.IsSet("hosts") {
+		c.Hosts = ctx.Int("hosts")
+	}
+
+	// buffering of the host events
+	if ctx.IsSet("event-queue-size") {
+		c.EventQueueSize = ctx.Int("event-queue-size")
+	}
+
+	// influx exporter of the round summaries
+	if ctx.IsSet("influx-url") {
+		c.InfluxURL = ctx.String("influx-url")
+	}
+	if ctx.IsSet("influx-org") {
+		c.InfluxOrg = ctx.String("influx-org")
+	}
+	if ctx.IsSet("influx-bucket") {
+		c.InfluxBucket = ctx.String("influx-bucket")
+	}
+	if ctx.IsSet("influx-token") {
+		c.InfluxToken = ctx.String("influx-token")
+	}
+
+	// clickhouse mirror of the high-volume tables
+	if ctx.IsSet("clickhouse-url") {
+		c.ClickHouseURL = ctx.String("clickhouse-url")
+	}
+	if ctx.IsSet("clickhouse-db") {
+		c.ClickHouseDB = ctx.String("clickhouse-db")
+	}
+	if ctx.IsSet("clickhouse-user") {
+		c.ClickHouseUser = ctx.String("clickhouse-user")
+	}
+	if ctx.IsSet("clickhouse-password") {
+		c.ClickHousePassword = ctx.String("clickhouse-password")
+	}
+
+	// retention of the time-series tables
+	if ctx.IsSet("retention") {
+		c.Retention = ctx.String("retention")
+	}
+	if ctx.IsSet("retention-schedule") {
+		c.RetentionSchedule = ctx.String("retention-schedule")
+	}
+
+	// seed of the discovery walks and peer selection
+	if ctx.IsSet("crawl-seed") {
+		c.CrawlSeed = ctx.Int64("crawl-seed")
+	}
+
+	// peering strategy
+	if ctx.IsSet("peering-strategy") {
+		c.PeeringStrategy = ctx.String("peering-strategy")
+	}
+	if ctx.IsSet("peering-quota") {
+		c.PeeringQuota = ctx.Int("peering-quota")
+	}
+	if ctx.IsSet("dial-score-weights") {
+		c.DialScoreWeights = ctx.String("dial-score-weights")
+	}
+	// adaptive dial rate
+	if ctx.IsSet("adaptive-dialing") {
+		c.AdaptiveDialing = ctx.Bool("adaptive-dialing")
+	}
+	if ctx.IsSet("max-dial-rate") {
+		c.MaxDialRate = ctx.Float64("max-dial-rate")
+	}
+	if ctx.IsSet("max-dial-workers") {
+		c.MaxDialWorkers = ctx.Int("max-dial-workers")
+	}
+	if ctx.IsSet("resume-dial-queue") {
+		c.ResumeDialQueue = ctx.Bool("resume-dial-queue")
+	}
+
+	// timeouts
+	if ctx.IsSet("dial-timeout") {
+		c.DialTimeout = ctx.String("dial-timeout")
+	}
+	if ctx.IsSet("identify-timeout") {
+		c.IdentifyTimeout = ctx.String("identify-timeout")
+	}
+	if ctx.IsSet("reqresp-timeout") {
+		c.ReqRespTimeout = ctx.String("reqresp-timeout")
+	}
+	if ctx.IsSet("class-timeouts") {
+		c.ClassTimeouts = ctx.String("class-timeouts")
+	}
+	// deprecation policy
+	if ctx.IsSet("deprecation-attempts") {
+		c.DeprecationAttempts = ctx.Int("deprecation-attempts")
+	}
+	if ctx.IsSet("deprecation-backoff") {
+		c.DeprecationBackoff = ctx.String("deprecation-backoff")
+	}
+	if ctx.IsSet("deprecation-window") {
+		c.DeprecationWindow = ctx.String("deprecation-window")
+	}
+
+	// dht walk
+	if ctx.IsSet("dht-walkers") {
+		c.DhtWalkers = ctx.Int("dht-walkers")
+	}
+	if ctx.IsSet("dht-walk-interval") {
+		c.DhtWalkInterval = ctx.String("dht-walk-interval")
+	}
+	if ctx.IsSet("dht-recrawl-interval") {
+		c.DhtRecrawlInterval = ctx.String("dht-recrawl-interval")
+	}
+
+	// relay shards of the waku network
+	if ctx.IsSet("waku-cluster-id") {
+		c.WakuClusterID = ctx.Int("waku-cluster-id")
+	}
+	if ctx.IsSet("waku-shard") {
+		c.WakuShards = ctx.IntSlice("waku-shard")
+	}
+
+	log.WithFields(log.Fields{
+		"log-level":            c.LogLevel,
+		"log-levels":           c.LogLevels,
+		"priv-key":             c.PrivateKey,
+		"ip":                   c.IP,
+		"ip6":                  c.IP6,
+		"ip-family":            c.IPFamily,
+		"port":                 c.Port,
+		"user-agent":           c.UserAgent,
+		"psql":                 c.PsqlEndpoint,
+		"backup-interval":      c.ActivePeersBackupInterval,
+		"peers-history":        c.PeersHistoryInterval,
+		"diversity-interval":   c.DiversityInterval,
+		"geoip-city-db":        c.GeoIPCityDB,
+		"geoip-asn-db":         c.GeoIPASNDB,
+		"ip-cache-ttl":         c.IpCacheTTL,
+		"ip-refresh-interval":  c.IpRefreshInterval,
+		"rdns-lookup":          c.RdnsLookup,
+		"cloud-tagging":        c.CloudTagging,
+		"cloud-ranges-file":    c.CloudRangesFile,
+		"tor-exit-list":        c.TorExitList,
+		"vpn-list-file":        c.VPNListFile,
+		"usage-interval":       c.ResourceUsageInterval,
+		"network":              c.Network,
+		"bootnodes":            c.Bootnodes,
+		"discovery-sources":    c.DiscoverySources,
+		"discovery-file":       c.DiscoveryFile,
+		"redial-interval":      c.RedialInterval,
+		"persist-connevents":   c.PersistConnEvents,
+		"security":             c.Security,
+		"signed-peer-record":   c.SignedPeerRecord,
+		"observed-addrs":       c.ObservedAddrs,
+		"collect-peer-records": c.CollectPeerRecords,
+		"negotiation-timing":   c.NegotiationTiming,
+		"eclipse-threshold":    c.EclipseThreshold,
+		"eclipse-min-peers":    c.EclipseMinPeers,
+		"static-peers":         c.StaticPeers,
+		"static-in-stats":      c.StaticPeersInStats,
+		"max-conns":            c.MaxConns,
+		"max-conns-per-peer":   c.MaxConnsPerPeer,
+		"max-streams-peer":     c.MaxStreamsPerPeer,
+		"max-streams-proto":    c.MaxStreamsPerProtocol,
+		"rcmgr-limits":         c.ResourceLimitsFile,
+		"blocklist":            c.BlocklistFile,
+		"blocklist-db":         c.BlocklistFromDB,
+		"targets":              c.TargetsFile,
+		"targets-db":           c.TargetsFromDB,
+		"soak":                 c.Soak,
+		"observer":             c.ObserverMode,
+		"soak-retention":       c.SoakRetention,
+		"soak-export-dir":      c.SoakExportDir,
+		"watchdog-timeout":     c.WatchdogTimeout,
+		"key-store":            c.KeyStore,
+		"key-rotation":         c.KeyRotation,
+		"hosts":                c.Hosts,
+		"event-queue-size":     c.EventQueueSize,
+		"influx-url":           c.InfluxURL,
+		"influx-org":           c.InfluxOrg,
+		"influx-bucket":        c.InfluxBucket,
+		"clickhouse-url":       c.ClickHouseURL,
+		"clickhouse-db":        c.ClickHouseDB,
+		"clickhouse-user":      c.ClickHouseUser,
+		"retention":            c.Retention,
+		"retention-schedule":   c.RetentionSchedule,
+		"crawl-seed":           c.CrawlSeed,
+		"peering-strategy":     c.PeeringStrategy,
+		"peering-quota":        c.PeeringQuota,
+		"dial-score-weights":   c.DialScoreWeights,
+		"adaptive-dialing":     c.AdaptiveDialing,
+		"max-dial-rate":        c.MaxDialRate,
+		"max-dial-workers":     c.MaxDialWorkers,
+		"resume-dial-queue":    c.ResumeDialQueue,
+		"dial-timeout":         c.DialTimeout,
+		"identify-timeout":     c.IdentifyTimeout,
+		"reqresp-timeout":      c.ReqRespTimeout,
+		"class-timeouts":       c.ClassTimeouts,
+		"deprecation-attempts": c.DeprecationAttempts,
+		"deprecation-backoff":  c.DeprecationBackoff,
+		"deprecation-window":   c.DeprecationWindow,
+		"dht-walkers":          c.DhtWalkers,
+		"dht-walk-interval":    c.DhtWalkInterval,
+		"dht-recrawl-interval": c.DhtRecrawlInterval,
+		"waku-cluster-id":      c.WakuClusterID,
+		"waku-shards":          c.WakuShards,
+	}).Info("config for the IPFS crawler")
+}
+
+// NetworkType returns the utils.NetworkType of the configured network
+func (c *IpfsCrawlerConfig) NetworkType() utils.NetworkType {
+	return IpfsNetworks[c.Network]
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/urfave/cli/v2"
+)
+
+func blocksContext(t *testing.T, path, network string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("config-file", "", "")
+	set.String("network", "", "")
+	require.NoError(t, set.Set("config-file", path))
+	if network != "" {
+		require.NoError(t, set.Set("network", network))
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func Test_EthereumNetworkConfigs(t *testing.T) {
+	path := writeConfigFile(t, "armiarma.yaml", `
+log-level: debug
+networks:
+  - network: mainnet
+  - network: gnosis
+    port: 9030
+    sse-port: 9031
+`)
+	confs, err := EthereumNetworkConfigs(blocksContext(t, path, ""))
+	require.NoError(t, err)
+	require.Len(t, confs, 2)
+	// each block gets the defaults of its network, the shared settings, and its own ones on top
+	require.Equal(t, "mainnet", confs[0].Network)
+	require.Equal(t, DefaultPort, confs[0].Port)
+	require.Equal(t, "gnosis", confs[1].Network)
+	require.Equal(t, EthNetworks["gnosis"].ForkDigest, confs[1].ForkDigest)
+	require.Equal(t, 9030, confs[1].Port)
+	for _, conf := range confs {
+		require.Equal(t, "debug", conf.LogLevel)
+	}
+
+	// the network flag picks its block alone
+	confs, err = EthereumNetworkConfigs(blocksContext(t, path, "gnosis"))
+	require.NoError(t, err)
+	require.Len(t, confs, 1)
+	require.Equal(t, 9030, confs[0].Port)
+	_, err = EthereumNetworkConfigs(blocksContext(t, path, "holesky"))
+	require.Error(t, err)
+
+	// the crawlers of the blocks can't share their ports, nor have several blocks of the same network
+	for _, content := range []string{
+		"networks:\n  - network: mainnet\n  - network: gnosis\n",
+		"networks:\n  - network: mainnet\n  - network: mainnet\n    port: 9030\n    sse-port: 9031\n",
+		"networks:\n  - port: 9030\n",
+		"networks: mainnet\n",
+	} {
+		_, err = EthereumNetworkConfigs(blocksContext(t, writeConfigFile(t, "armiarma.yaml", content), ""))
+		require.Error(t, err, content)
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+var DefaultEthNetwork string = "mainnet"
+
+// Ethereum CL networks that can be crawled (see the presets of the ethereum package),
+// the bootnodes of the testnets, and the fork digest of ephemery, have to be given
+var EthNetworks = eth.NetworkPresets
+
+// ApplyNetwork sets the fork digest and the bootnodes of the given network
+func (c *EthereumCrawlerConfig) ApplyNetwork(network string) error {
+	preset, ok := eth.Preset(network)
+	if !ok {
+		return errors.Errorf("unsupported network %q (%s)", network, strings.Join(networkNames(EthNetworks), ", "))
+	}
+	c.Network = preset.Name
+	c.ForkDigest = preset.ForkDigest
+	c.Bootnodes = preset.Bootnodes
+	return nil
+}
+
+// ApplyNetwork sets the bootnodes of the given network (the ENRs of the waku bootnodes have
+// to be given, as its nodes are discovered walking discv5 instead of a Kademlia DHT)
+func (c *IpfsCrawlerConfig) ApplyNetwork(network string) error {
+	network = strings.ToLower(network)
+	switch network {
+	case "ipfs":
+		c.Bootnodes = DefaultIPFSBootnodes
+	case "filecoin":
+		c.Bootnodes = DefaultFilecoinBootnodes
+	case "waku":
+		c.Bootnodes = make([]string, 0)
+		c.DiscoverySources = []string{"dv5"}
+	default:
+		return errors.Errorf("unsupported network %q (%s)", network, strings.Join(networkNames(IpfsNetworks), ", "))
+	}
+	c.Network = network
+	return nil
+}
+
+// networkNames returns the sorted names of the networks of the given map
+func networkNames[T any](networks map[string]T) []string {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
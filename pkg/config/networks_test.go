@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+func Test_NetworkPresets(t *testing.T) {
+	for name, preset := range EthNetworks {
+		if preset.ResetPeriod > 0 {
+			require.Empty(t, preset.ForkDigest, name)
+			continue
+		}
+		// the fork digest of each preset matches its genesis data
+		digest, err := eth.ComputeForkDigest(preset.ForkVersion, preset.GenesisValidatorsRoot)
+		require.NoError(t, err, name)
+		require.Equal(t, preset.ForkDigest, digest, name)
+		require.Equal(t, name, eth.ForkDigestNetwork(preset.ForkDigest))
+		require.Equal(t, preset.GenesisTime, eth.NetworkGenesis(preset.ForkDigest), name)
+	}
+	_, err := eth.ComputeForkDigest("0x0300", EthNetworks["gnosis"].GenesisValidatorsRoot)
+	require.Error(t, err)
+
+	gnosis, ok := eth.Preset("Gnosis")
+	require.True(t, ok)
+	require.Equal(t, "/eth2/3ebfd484/", gnosis.TopicPrefix())
+	require.Equal(t, []string{"/eth2/3ebfd484/beacon_block/ssz_snappy"}, gnosis.Topics([]string{eth.BeaconBlockTopicBase}))
+
+	// the fork digest of ephemery changes at every reset, so it has to be given
+	conf := NewEthereumCrawlerConfig()
+	require.NoError(t, conf.ApplyNetwork("ephemery"))
+	conf.Bootnodes = DefaultEthereumBootnodes
+	require.Error(t, conf.Validate())
+	conf.ForkDigest = "0x0a1b2c3d"
+	require.NoError(t, conf.Validate())
+}
+
+func Test_WakuNetwork(t *testing.T) {
+	// the waku nodes are only discovered over discv5, from the given bootnodes
+	conf := NewIpfsCrawlerConfig()
+	require.NoError(t, conf.ApplyNetwork("waku"))
+	require.Equal(t, []string{"dv5"}, conf.DiscoverySources)
+	require.Error(t, conf.Validate())
+	conf.Bootnodes = []string{"enr:-placeholder"}
+	require.NoError(t, conf.Validate())
+
+	conf.DiscoverySources = []string{"dht"}
+	conf.WakuShards = []int{1024}
+	err := conf.Validate()
+	require.Error(t, err)
+	require.Len(t, err.(*ValidationError).Issues, 2)
+
+	// discv5 isn't a discovery source of the rest of the networks
+	ipfsConf := NewIpfsCrawlerConfig()
+	ipfsConf.DiscoverySources = []string{"dv5"}
+	require.Error(t, ipfsConf.Validate())
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+var (
+	DefaultPortalWorkers         int    = 50
+	DefaultPortalRecrawlInterval string = "30m"
+)
+
+type PortalCrawlerConfig struct {
+	LogLevel        string   `json:"log-level"`
+	PrivateKey      string   `json:"priv-key"`
+	Port            int      `json:"port"`
+	MetricsIP       string   `json:"metrics-ip"`
+	MetricsPort     int      `json:"metrics-port"`
+	UserAgent       string   `json:"user-agent"`
+	PsqlEndpoint    string   `json:"psql-endpoint"`
+	Bootnodes       []string `json:"bootnodes"`
+	SubNetworks     []string `json:"sub-networks"`
+	PingWorkers     int      `json:"ping-workers"`
+	RecrawlInterval string   `json:"recrawl-interval"`
+}
+
+func NewPortalCrawlerConfig() *PortalCrawlerConfig {
+	// Return Default values for the Portal configuration (the bootnodes have to be given)
+	return &PortalCrawlerConfig{
+		LogLevel:        DefaultLogLevel,
+		PrivateKey:      DefaultPrivKey,
+		Port:            DefaultPort,
+		MetricsIP:       DefaultMetricsIP,
+		MetricsPort:     DefaultMetricsPort,
+		UserAgent:       DefaultUserAgent,
+		PsqlEndpoint:    DefaultPSQLEndpoint,
+		Bootnodes:       make([]string, 0),
+		SubNetworks:     make([]string, 0),
+		PingWorkers:     DefaultPortalWorkers,
+		RecrawlInterval: DefaultPortalRecrawlInterval,
+	}
+}
+
+func (c *PortalCrawlerConfig) Apply(ctx *cli.Context) {
+	// apply to the existing Default configuration the set flags
+	// log level
+	if ctx.IsSet("log-level") {
+		c.LogLevel = ctx.String("log-level")
+	}
+	// private key
+	if ctx.IsSet("priv-key") {
+		c.PrivateKey = ctx.String("priv-key")
+	}
+	// port (discv5 over UDP)
+	if ctx.IsSet("port") {
+		port := ctx.Int("port")
+		if checkValidPort(port) {
+			c.Port = port
+		}
+	}
+	// metrics-ip (pprof + prometheus)
+	if ctx.IsSet("metrics-ip") {
+		c.MetricsIP = ctx.String("metrics-ip")
+	}
+	// metrics-port (pprof + prometheus)
+	if ctx.IsSet("metrics-port") {
+		mPort := ctx.Int("metrics-port")
+		if checkValidPort(mPort) {
+			c.MetricsPort = mPort
+		}
+	}
+	// user agent (client info of our pings)
+	if ctx.IsSet("user-agent") {
+		c.UserAgent = ctx.String("user-agent")
+	}
+
+	// postgresql endpoint
+	if ctx.IsSet("psql-endpoint") {
+		c.PsqlEndpoint = ctx.String("psql-endpoint")
+	}
+
+	// bootnodes
+	if ctx.IsSet("bootnode") {
+		c.Bootnodes = ctx.StringSlice("bootnode")
+	}
+
+	// sub-networks where the nodes are pinged
+	if ctx.IsSet("sub-network") {
+		c.SubNetworks = ctx.StringSlice("sub-network")
+	}
+	if ctx.IsSet("ping-workers") {
+		c.PingWorkers = ctx.Int("ping-workers")
+	}
+	if ctx.IsSet("recrawl-interval") {
+		c.RecrawlInterval = ctx.String("recrawl-interval")
+	}
+
+	log.WithFields(log.Fields{
+		"log-level":        c.LogLevel,
+		"priv-key":         c.PrivateKey,
+		"port":             c.Port,
+		"user-agent":       c.UserAgent,
+		"psql":             c.PsqlEndpoint,
+		"bootnodes":        c.Bootnodes,
+		"sub-networks":     c.SubNetworks,
+		"ping-workers":     c.PingWorkers,
+		"recrawl-interval": c.RecrawlInterval,
+	}).Info("config for the Portal crawler")
+}
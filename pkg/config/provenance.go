@@ -0,0 +1,29 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Provenance returns the settings of the config that the run was launched with (encoded as JSON,
+// without the secrets) and their hash, so that the runs with the same settings can be grouped and
+// any of them can be reproduced
+func Provenance(conf interface{}) (hash string, settings string, err error) {
+	all, err := configSettings(conf)
+	if err != nil {
+		return "", "", err
+	}
+	for name := range secretSettings {
+		delete(all, name)
+	}
+	// the keys of the maps are encoded sorted, so the encoding is the same for the same settings
+	raw, err := json.Marshal(all)
+	if err != nil {
+		return "", "", errors.Wrap(err, "unable to encode config settings")
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), string(raw), nil
+}
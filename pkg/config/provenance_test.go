@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Provenance(t *testing.T) {
+	conf := testReloadConfig{LogLevel: "info", Port: 9020, DialRate: 10, PrivKey: "secret"}
+	hash, settings, err := Provenance(conf)
+	require.NoError(t, err)
+	require.Len(t, hash, 64)
+	// the secrets are kept out of the recorded settings
+	require.Equal(t, `{"log-level":"info","max-dial-rate":10,"port":9020}`, settings)
+
+	// the secrets don't change the hash, the rest of the settings do
+	conf.PrivKey = "other-secret"
+	sameHash, _, err := Provenance(conf)
+	require.NoError(t, err)
+	require.Equal(t, hash, sameHash)
+
+	conf.Port = 9021
+	otherHash, _, err := Provenance(conf)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, otherHash)
+}
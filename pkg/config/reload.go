@@ -0,0 +1,271 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+/**
+The reloader re-reads the configuration of a running crawler (on SIGHUP or through the
+reload endpoint), and applies the settings that changed through the appliers that the
+crawler registered for them. The rest of the changed settings are only reported, as they
+require a restart. Every change is written to the log and kept in the history of the
+reloader, so that the changes of a long crawl can be audited afterwards.
+*/
+
+var (
+	// path to trigger a reload and check the history of the changes
+	ConfigReloadPath = "/config/reload"
+
+	// number of changes kept in the history of the reloader
+	MaxReloadHistory = 512
+
+	ReloadSourceSignal = "sighup"
+	ReloadSourceHTTP   = "http"
+
+	// settings whose values are kept out of the audit log
+	secretSettings = map[string]struct{}{
+		"priv-key":            {},
+		"psql-endpoint":       {},
+		"influx-token":        {},
+		"clickhouse-password": {},
+	}
+)
+
+// SettingChange is the audit record of a setting that changed on a reload
+type SettingChange struct {
+	Time    time.Time   `json:"time"`
+	Source  string      `json:"source"`
+	Setting string      `json:"setting"`
+	Old     interface{} `json:"old"`
+	New     interface{} `json:"new"`
+	Applied bool        `json:"applied"`
+	Reason  string      `json:"reason,omitempty"`
+}
+
+// Reloader applies the runtime-tunable settings of a reloaded configuration
+type Reloader[C any] struct {
+	m        sync.Mutex
+	load     func() (C, error)
+	appliers map[string]func(next C) error
+	// settings in effect, keyed by their json tags
+	current map[string]interface{}
+	history []SettingChange
+}
+
+// NewReloader returns a reloader over the config in effect, that uses the given function to
+// load (and validate) the new one
+func NewReloader[C any](current C, load func() (C, error)) (*Reloader[C], error) {
+	settings, err := configSettings(current)
+	if err != nil {
+		return nil, err
+	}
+	return &Reloader[C]{
+		load:     load,
+		appliers: make(map[string]func(next C) error),
+		current:  settings,
+		history:  make([]SettingChange, 0),
+	}, nil
+}
+
+// Handle registers the function that applies the new value of the setting (by its json tag) at runtime
+func (r *Reloader[C]) Handle(setting string, apply func(next C) error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.appliers[setting] = apply
+}
+
+// Reload loads the config and applies the settings that changed, returning the audit records of the changes
+func (r *Reloader[C]) Reload(source string) ([]SettingChange, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	next, err := r.load()
+	if err != nil {
+		log.WithError(err).WithField("source", source).Error("config reload failed")
+		return nil, err
+	}
+	settings, err := configSettings(next)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := make([]SettingChange, 0)
+	now := time.Now()
+	for _, name := range names {
+		old, value := r.current[name], settings[name]
+		if reflect.DeepEqual(old, value) {
+			continue
+		}
+		change := SettingChange{
+			Time:    now,
+			Source:  source,
+			Setting: name,
+			Old:     old,
+			New:     value,
+		}
+		if _, ok := secretSettings[name]; ok {
+			change.Old, change.New = "<redacted>", "<redacted>"
+		}
+		if apply, ok := r.appliers[name]; !ok {
+			change.Reason = "requires restart"
+		} else if err := apply(next); err != nil {
+			change.Reason = err.Error()
+		} else {
+			change.Applied = true
+			r.current[name] = value
+		}
+		r.audit(change)
+		changes = append(changes, change)
+	}
+	log.WithFields(log.Fields{
+		"source":  source,
+		"changes": len(changes),
+	}).Info("config reloaded")
+	return changes, nil
+}
+
+// History returns the changes of the previous reloads, oldest first
+func (r *Reloader[C]) History() []SettingChange {
+	r.m.Lock()
+	defer r.m.Unlock()
+	history := make([]SettingChange, len(r.history))
+	copy(history, r.history)
+	return history
+}
+
+// Run reloads the config on each SIGHUP until the context dies
+func (r *Reloader[C]) Run(ctx context.Context) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-sigs:
+				// the errors are already logged
+				_, _ = r.Reload(ReloadSourceSignal)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Handler returns the history of the changes on GET, and reloads the config on POST,
+// returning the changes of the reload
+func (r *Reloader[C]) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var resp interface{}
+		switch req.Method {
+		case http.MethodGet:
+			resp = r.History()
+		case http.MethodPost:
+			changes, err := r.Reload(ReloadSourceHTTP)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			resp = changes
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// audit logs the change and adds it to the history (needs the lock)
+func (r *Reloader[C]) audit(change SettingChange) {
+	logEntry := log.WithFields(log.Fields{
+		"source":  change.Source,
+		"setting": change.Setting,
+		"old":     change.Old,
+		"new":     change.New,
+	})
+	if change.Applied {
+		logEntry.Info("config setting updated")
+	} else {
+		logEntry.WithField("reason", change.Reason).Warn("config setting not updated")
+	}
+	r.history = append(r.history, change)
+	if len(r.history) > MaxReloadHistory {
+		r.history = r.history[len(r.history)-MaxReloadHistory:]
+	}
+}
+
+// configSettings returns the settings of the config keyed by their json tags
+func configSettings(conf interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to encode config")
+	}
+	settings := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, errors.Wrap(err, "unable to decode config")
+	}
+	return settings, nil
+}
+
+// ReloadEthereumConfig builds the config of the given network again from the network defaults, the
+// config file (and its block of the network, if any) and the flags of the running crawler, validating it
+func ReloadEthereumConfig(ctx *cli.Context, network string) (conf EthereumCrawlerConfig, err error) {
+	confs, err := EthereumNetworkConfigs(ctx)
+	if err != nil {
+		return conf, err
+	}
+	if len(confs) == 1 {
+		return confs[0], nil
+	}
+	for _, next := range confs {
+		if next.Network == network {
+			return next, nil
+		}
+	}
+	return conf, errors.Errorf("network %s is no longer in the config file", network)
+}
+
+// ReloadIpfsConfig builds the config again from the network defaults, the config file and the
+// flags of the running crawler, validating it
+func ReloadIpfsConfig(ctx *cli.Context) (conf IpfsCrawlerConfig, err error) {
+	defer recoverApply(&err)
+	next := NewIpfsCrawlerConfig()
+	next.Apply(ctx)
+	if err := next.Validate(); err != nil {
+		return conf, err
+	}
+	return *next, nil
+}
+
+// recoverApply turns the panics of the Apply of a config (invalid settings) into an error,
+// as a broken config file mustn't take down the running crawler
+func recoverApply(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if entry, ok := r.(*log.Entry); ok {
+		*err = errors.New(entry.Message)
+		return
+	}
+	*err = errors.Errorf("invalid config: %v", r)
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testReloadConfig struct {
+	LogLevel string `json:"log-level"`
+	Port     int    `json:"port"`
+	DialRate int    `json:"max-dial-rate"`
+	PrivKey  string `json:"priv-key"`
+}
+
+func Test_Reloader(t *testing.T) {
+	current := testReloadConfig{LogLevel: "info", Port: 9020, DialRate: 10, PrivKey: "secret"}
+	next := current
+	var loadErr error
+	reloader, err := NewReloader(current, func() (testReloadConfig, error) {
+		return next, loadErr
+	})
+	require.NoError(t, err)
+
+	logLevel := current.LogLevel
+	reloader.Handle("log-level", func(c testReloadConfig) error {
+		logLevel = c.LogLevel
+		return nil
+	})
+	reloader.Handle("max-dial-rate", func(c testReloadConfig) error {
+		return errors.New("adaptive dialing is disabled")
+	})
+
+	// nothing changed
+	changes, err := reloader.Reload(ReloadSourceHTTP)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	next = testReloadConfig{LogLevel: "debug", Port: 9021, DialRate: 20, PrivKey: "other"}
+	changes, err = reloader.Reload(ReloadSourceSignal)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	bySetting := make(map[string]SettingChange)
+	for _, change := range changes {
+		require.Equal(t, ReloadSourceSignal, change.Source)
+		bySetting[change.Setting] = change
+	}
+	require.True(t, bySetting["log-level"].Applied)
+	require.Equal(t, "debug", logLevel)
+	require.False(t, bySetting["max-dial-rate"].Applied)
+	require.Equal(t, "adaptive dialing is disabled", bySetting["max-dial-rate"].Reason)
+	require.False(t, bySetting["port"].Applied)
+	require.Equal(t, "requires restart", bySetting["port"].Reason)
+	// the secrets are kept out of the audit log
+	require.Equal(t, "<redacted>", bySetting["priv-key"].New)
+
+	// the applied settings are in effect, the rest are reported again
+	changes, err = reloader.Reload(ReloadSourceHTTP)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	require.Len(t, reloader.History(), 7)
+
+	// a broken config doesn't change anything
+	loadErr = errors.New("invalid config")
+	_, err = reloader.Reload(ReloadSourceHTTP)
+	require.Error(t, err)
+	require.Len(t, reloader.History(), 7)
+
+	// the endpoint returns the history on GET
+	rec := httptest.NewRecorder()
+	reloader.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ConfigReloadPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	history := make([]SettingChange, 0)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&history))
+	require.Len(t, history, 7)
+
+	rec = httptest.NewRecorder()
+	reloader.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ConfigReloadPath, nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
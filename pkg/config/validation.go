@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// ValidationError gathers all the invalid settings of a config, so that they can be fixed at once
+type ValidationError struct {
+	Issues []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config:\n  - %s", strings.Join(e.Issues, "\n  - "))
+}
+
+type validator struct {
+	issues []string
+}
+
+func (v *validator) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.issues = append(v.issues, fmt.Sprintf(format, args...))
+	}
+}
+
+func (v *validator) duration(name, value string) {
+	d, err := time.ParseDuration(value)
+	v.check(err == nil && d >= 0, "%s: %q isn't a valid duration (i.e. 30s, 5m, 12h)", name, value)
+}
+
+func (v *validator) port(name string, port int) {
+	v.check(checkValidPort(port), "%s: %d out of the valid range (%d, %d]", name, port, MinPort, MaxPort)
+}
+
+func (v *validator) ip(name, value string) {
+	v.check(net.ParseIP(value) != nil, "%s: %q isn't a valid ip", name, value)
+}
+
+// file checks that the file exists, if any was given
+func (v *validator) file(name, path string) {
+	v.check(path == "" || utils.CheckFileExists(path), "%s: file %s doesn't exist", name, path)
+}
+
+func (v *validator) oneOf(name, value string, options []string) {
+	for _, option := range options {
+		if option == value {
+			return
+		}
+	}
+	v.issues = append(v.issues, fmt.Sprintf("%s: %q isn't one of %s", name, value, strings.Join(options, ", ")))
+}
+
+func (v *validator) err() error {
+	if len(v.issues) == 0 {
+		return nil
+	}
+	sort.Strings(v.issues)
+	return &ValidationError{Issues: v.issues}
+}
+
+// validateCommon checks the settings shared by the crawlers
+func (v *validator) validateCommon(logLevel, ipFamily, ip, ip6 string, port, metricsPort int) {
+	v.check(checkValidLogLevel(logLevel), "log-level: %q isn't one of %s", logLevel, strings.Join(PossibleLogLevels, ", "))
+	v.check(checkValidIPFamily(ipFamily), "ip-family: %q isn't one of %s", ipFamily, strings.Join(PossibleIPFamilies, ", "))
+	v.ip("ip", ip)
+	v.ip("ip6", ip6)
+	v.port("port", port)
+	v.port("metrics-port", metricsPort)
+}
+
+// Validate checks every setting of the config, returning a ValidationError with all the invalid ones
+func (c *EthereumCrawlerConfig) Validate() error {
+	v := &validator{}
+	v.validateCommon(c.LogLevel, c.IPFamily, c.IP, c.IP6, c.Port, c.MetricsPort)
+	v.port("sse-port", c.SSEPort)
+	v.oneOf("network", c.Network, networkNames(EthNetworks))
+	if preset, ok := eth.Preset(c.Network); ok && preset.ResetPeriod > 0 && c.ForkDigest == "" {
+		v.check(false, "fork-digest: network %s resets its genesis periodically, give the fork digest of its current iteration", c.Network)
+	} else {
+		_, validDigest := eth.CheckValidForkDigest(c.ForkDigest)
+		v.check(validDigest, "fork-digest: %q isn't a known fork name nor a 4 bytes hex digest", c.ForkDigest)
+	}
+	for _, src := range c.DiscoverySources {
+		v.oneOf("discovery-source", src, EthDiscoverySources)
+	}
+	v.check(!containsTopic(c.DiscoverySources, "dv5") || len(c.Bootnodes) > 0 || c.BootnodesSource != "",
+		"bootnodes: network %s has no default bootnodes, give them with --bootnode, --bootnodes-source or in the config file", c.Network)
+	for _, subnet := range c.Subnets {
+		v.check(subnet >= 0 && subnet < eth.SubnetLimit, "subnet: %d out of the valid range [0, %d)", subnet, eth.SubnetLimit)
+	}
+
+	for name, value := range map[string]string{
+		"peers-backup":            c.ActivePeersBackupInterval,
+		"peers-history":           c.PeersHistoryInterval,
+		"validator-estimates":     c.ValidatorEstimates,
+		"diversity-interval":      c.DiversityInterval,
+		"ip-cache-ttl":            c.IpCacheTTL,
+		"ip-refresh-interval":     c.IpRefreshInterval,
+		"resource-usage-interval": c.ResourceUsageInterval,
+		"redial-interval":         c.RedialInterval,
+		"bootnode-ping-interval":  c.BootnodePingInterval,
+		"mesh-snapshot-interval":  c.MeshSnapshotInterval,
+		"soak-retention":          c.SoakRetention,
+		"watchdog-timeout":        c.WatchdogTimeout,
+		"key-rotation":            c.KeyRotation,
+		"dial-timeout":            c.DialTimeout,
+		"identify-timeout":        c.IdentifyTimeout,
+		"reqresp-timeout":         c.ReqRespTimeout,
+		"deprecation-window":      c.DeprecationWindow,
+		"quality-interval":        c.QualityInterval,
+		"reputation-interval":     c.ReputationInterval,
+		"churn-connect-time":      c.ChurnConnectTime,
+		"churn-disconnect-time":   c.ChurnDisconnectTime,
+		"hold-ping-interval":      c.HoldPingInterval,
+	} {
+		v.duration(name, value)
+	}
+	for name, path := range map[string]string{
+		"geoip-city-db":     c.GeoIPCityDB,
+		"geoip-asn-db":      c.GeoIPASNDB,
+		"cloud-ranges-file": c.CloudRangesFile,
+		"vpn-list-file":     c.VPNListFile,
+		"discovery-file":    c.DiscoveryFile,
+		"rcmgr-limits":      c.ResourceLimitsFile,
+		"blocklist":         c.BlocklistFile,
+		"targets":           c.TargetsFile,
+	} {
+		v.file(name, path)
+	}
+
+	v.check(c.Hosts >= 1, "hosts: at least 1 host is needed, got %d", c.Hosts)
+	v.check(c.EventQueueSize > 0, "event-queue-size: must be positive, got %d", c.EventQueueSize)
+	v.check(c.EclipseThreshold > 0 && c.EclipseThreshold <= 1, "eclipse-threshold: %v out of the valid range (0, 1]", c.EclipseThreshold)
+	v.check(c.PeeringQuota > 0, "peering-quota: must be positive, got %d", c.PeeringQuota)
+	v.check(c.MaxDialRate > 0, "max-dial-rate: must be positive, got %v", c.MaxDialRate)
+	v.check(c.MaxDialWorkers >= MinDialWorkers && c.MaxDialWorkers <= DefaultMaxDialWorkers,
+		"max-dial-workers: %d out of the valid range [%d, %d]", c.MaxDialWorkers, MinDialWorkers, DefaultMaxDialWorkers)
+	v.check(c.DeprecationAttempts >= 0, "deprecation-attempts: can't be negative, got %d", c.DeprecationAttempts)
+	v.check(c.HoldPeers >= 0, "hold-peers: can't be negative, got %d", c.HoldPeers)
+	// the discovery-only mode never opens a libp2p connection
+	if c.DiscoveryOnly {
+		v.check(containsTopic(c.DiscoverySources, "dv5"), "discovery-source: the discovery-only mode needs the dv5 source")
+		v.check(c.ObserverMode, "observer: the discovery-only mode can't dial the discovered peers")
+		v.check(c.TargetsFile == "" && !c.TargetsFromDB, "targets: the target-list crawl mode can't run in discovery-only mode")
+		v.check(len(c.StaticPeers) == 0, "static-peer: the static peers can't be kept in discovery-only mode")
+		v.check(!c.Churn, "churn: the churn experiment can't run in discovery-only mode")
+		v.check(c.HoldPeers == 0, "hold-peers: the peers can't be held in discovery-only mode")
+		v.check(!c.Soak, "soak: the soak watchdog would stall in discovery-only mode")
+		v.check(!c.ListenOnly, "listen-only: the host doesn't listen in discovery-only mode")
+		v.check(!c.Discoverable, "discoverable: the host doesn't listen in discovery-only mode")
+	}
+	// the listen-only mode never dials
+	if c.ListenOnly {
+		v.check(c.ObserverMode, "observer: the listen-only mode can't dial the discovered peers")
+		v.check(c.TargetsFile == "" && !c.TargetsFromDB, "targets: the target-list crawl mode can't run in listen-only mode")
+		v.check(len(c.StaticPeers) == 0, "static-peer: the static peers can't be kept in listen-only mode")
+		v.check(!c.Churn, "churn: the churn experiment can't run in listen-only mode")
+		v.check(c.HoldPeers == 0, "hold-peers: the peers can't be held in listen-only mode")
+	}
+	v.check(!c.Discoverable || containsTopic(c.DiscoverySources, "dv5"), "discoverable: our ENR is only published over the dv5 source")
+	return v.err()
+}
+
+// Validate checks every setting of the config, returning a ValidationError with all the invalid ones
+func (c *IpfsCrawlerConfig) Validate() error {
+	v := &validator{}
+	v.validateCommon(c.LogLevel, c.IPFamily, c.IP, c.IP6, c.Port, c.MetricsPort)
+	v.oneOf("network", c.Network, networkNames(IpfsNetworks))
+	for _, src := range c.DiscoverySources {
+		v.oneOf("discovery-source", src, IpfsDiscoverySources)
+	}
+	v.check(!containsTopic(c.DiscoverySources, "dht") || len(c.Bootnodes) > 0,
+		"bootnodes: no bootnodes to walk the dht, give them with --bootnode or in the config file")
+	// waku nodes are only discovered over discv5, the rest of the networks over their Kademlia DHT
+	if c.NetworkType() == utils.WakuNetwork {
+		v.check(!containsTopic(c.DiscoverySources, "dht"), "discovery-source: network %s has no Kademlia DHT, use dv5", c.Network)
+		v.check(!containsTopic(c.DiscoverySources, "dv5") || len(c.Bootnodes) > 0,
+			"bootnodes: no bootnodes to walk discv5, give their ENRs with --bootnode or in the config file")
+		v.check(c.WakuClusterID >= 0 && c.WakuClusterID <= math.MaxUint16, "waku-cluster-id: %d out of the valid range [0, %d]", c.WakuClusterID, math.MaxUint16)
+		for _, shard := range c.WakuShards {
+			v.check(shard >= 0 && shard < 1024, "waku-shards: %d out of the valid range [0, 1023]", shard)
+		}
+	} else {
+		v.check(!containsTopic(c.DiscoverySources, "dv5"), "discovery-source: dv5 is only supported by the waku network")
+	}
+
+	for name, value := range map[string]string{
+		"peers-backup":            c.ActivePeersBackupInterval,
+		"peers-history":           c.PeersHistoryInterval,
+		"diversity-interval":      c.DiversityInterval,
+		"ip-cache-ttl":            c.IpCacheTTL,
+		"ip-refresh-interval":     c.IpRefreshInterval,
+		"resource-usage-interval": c.ResourceUsageInterval,
+		"redial-interval":         c.RedialInterval,
+		"soak-retention":          c.SoakRetention,
+		"watchdog-timeout":        c.WatchdogTimeout,
+		"key-rotation":            c.KeyRotation,
+		"dial-timeout":            c.DialTimeout,
+		"identify-timeout":        c.IdentifyTimeout,
+		"reqresp-timeout":         c.ReqRespTimeout,
+		"deprecation-window":      c.DeprecationWindow,
+		"dht-walk-interval":       c.DhtWalkInterval,
+		"dht-recrawl-interval":    c.DhtRecrawlInterval,
+	} {
+		v.duration(name, value)
+	}
+	for name, path := range map[string]string{
+		"geoip-city-db":     c.GeoIPCityDB,
+		"geoip-asn-db":      c.GeoIPASNDB,
+		"cloud-ranges-file": c.CloudRangesFile,
+		"vpn-list-file":     c.VPNListFile,
+		"discovery-file":    c.DiscoveryFile,
+		"rcmgr-limits":      c.ResourceLimitsFile,
+		"blocklist":         c.BlocklistFile,
+		"targets":           c.TargetsFile,
+	} {
+		v.file(name, path)
+	}
+
+	v.check(c.Hosts >= 1, "hosts: at least 1 host is needed, got %d", c.Hosts)
+	v.check(c.EventQueueSize > 0, "event-queue-size: must be positive, got %d", c.EventQueueSize)
+	v.check(c.EclipseThreshold > 0 && c.EclipseThreshold <= 1, "eclipse-threshold: %v out of the valid range (0, 1]", c.EclipseThreshold)
+	v.check(c.PeeringQuota > 0, "peering-quota: must be positive, got %d", c.PeeringQuota)
+	v.check(c.MaxDialRate > 0, "max-dial-rate: must be positive, got %v", c.MaxDialRate)
+	v.check(c.MaxDialWorkers >= MinDialWorkers && c.MaxDialWorkers <= DefaultMaxDialWorkers,
+		"max-dial-workers: %d out of the valid range [%d, %d]", c.MaxDialWorkers, MinDialWorkers, DefaultMaxDialWorkers)
+	v.check(c.DhtWalkers >= 1, "dht-walkers: at least 1 walker is needed, got %d", c.DhtWalkers)
+	return v.err()
+}
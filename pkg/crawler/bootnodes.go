@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/discovery"
+	"github.com/migalabs/armiarma/pkg/discovery/dv5"
+)
+
+// bootnodeLoader merges the bootnodes of the bootnodes source (local file or HTTPS URL) with
+// the ones of the config, at start and on every reload
+type bootnodeLoader struct {
+	ctx    context.Context
+	loaded []string
+}
+
+// load merges the bootnodes of the source of the config into its bootnodes
+func (l *bootnodeLoader) load(conf *config.EthereumCrawlerConfig) error {
+	if conf.BootnodesSource == "" {
+		l.loaded = nil
+		return nil
+	}
+	bootnodes, err := discovery.LoadBootnodes(l.ctx, conf.BootnodesSource)
+	if err != nil {
+		return err
+	}
+	if _, err := dv5.ParseBootnodes(bootnodes); err != nil {
+		return err
+	}
+	l.loaded = bootnodes
+	conf.Bootnodes = discovery.MergeBootnodes(conf.Bootnodes, bootnodes)
+	log.WithFields(log.Fields{
+		"source":    conf.BootnodesSource,
+		"loaded":    len(bootnodes),
+		"bootnodes": len(conf.Bootnodes),
+	}).Info("loaded bootnodes")
+	return nil
+}
+
+// reload is the load of a config reload, which keeps the last loaded bootnodes if the source
+// can't be read, as an unreachable source mustn't block the reload of the rest of the settings
+func (l *bootnodeLoader) reload(conf *config.EthereumCrawlerConfig) {
+	if err := l.load(conf); err != nil {
+		log.WithError(err).Warn("unable to reload the bootnodes source, keeping the last loaded bootnodes")
+		conf.Bootnodes = discovery.MergeBootnodes(conf.Bootnodes, l.loaded)
+	}
+}
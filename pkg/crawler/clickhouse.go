@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/migalabs/armiarma/pkg/db/clickhouse"
+	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/pkg/errors"
+)
+
+// clickHouseMirror returns the mirror of the high-volume tables into ClickHouse
+// (nil if no endpoint is given)
+func clickHouseMirror(ctx context.Context, endpoint, database, user, password string) (psql.RowsMirror, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+	sink, err := clickhouse.NewSink(ctx, endpoint, database, user, password)
+	if err != nil {
+		return nil, err
+	}
+	err = sink.InitTables()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to init the clickhouse mirror")
+	}
+	return sink, nil
+}
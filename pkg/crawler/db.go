@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/db/sqlite"
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// dbConfig gathers the settings of the DB of a crawler, which are the same for both backends
+type dbConfig struct {
+	network            utils.NetworkType
+	endpoint           string
+	backupInterval     time.Duration
+	skipBackup         bool // the crawler has no connections to backup
+	persistConnEvents  bool
+	staticPeersInStats bool
+	resurrectOnNewENR  bool
+	peersHistory       time.Duration
+	validatorEstimates time.Duration
+	networkName        string
+	mirror             psql.RowsMirror // only supported by Postgres
+}
+
+// openDB connects to the DB of the endpoint, which is a SQLite file if the endpoint is
+// "sqlite:<file>" or a Postgres server otherwise, initializing its tables
+func openDB(ctx context.Context, conf dbConfig) (storage.Client, error) {
+	if file, ok := strings.CutPrefix(conf.endpoint, storage.SQLiteScheme); ok {
+		if conf.mirror != nil {
+			return nil, errors.New("the clickhouse mirror is not supported by the sqlite backend")
+		}
+		dbClient, err := sqlite.NewDBClient(
+			ctx,
+			conf.network,
+			file,
+			conf.backupInterval,
+			sqlite.WithActivePeersBackup(!conf.skipBackup),
+			sqlite.WithConnectionEventsPersist(conf.persistConnEvents),
+			sqlite.WithStaticPeersInStats(conf.staticPeersInStats),
+			sqlite.WithResurrectOnNewENR(conf.resurrectOnNewENR),
+			sqlite.WithPeersHistory(conf.peersHistory),
+			sqlite.WithValidatorEstimates(conf.validatorEstimates),
+			sqlite.WithNetworkName(conf.networkName),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return dbClient, nil
+	}
+
+	opts := []psql.DBOption{
+		psql.InitializeTables(true),
+		psql.WithActivePeersBackup(!conf.skipBackup),
+		psql.WithConnectionEventsPersist(conf.persistConnEvents),
+		psql.WithStaticPeersInStats(conf.staticPeersInStats),
+		psql.WithResurrectOnNewENR(conf.resurrectOnNewENR),
+		psql.WithPeersHistory(conf.peersHistory),
+		psql.WithValidatorEstimates(conf.validatorEstimates),
+		psql.WithNetworkName(conf.networkName),
+	}
+	if conf.mirror != nil {
+		opts = append(opts, psql.WithRowsMirror(conf.mirror))
+	}
+	dbClient, err := psql.NewDBClient(ctx, conf.network, conf.endpoint, conf.backupInterval, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dbClient, nil
+}
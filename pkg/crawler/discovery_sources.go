@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/discovery"
+	"github.com/migalabs/armiarma/pkg/peering"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	// the peers of the static file are notified again with the same frequency than the db re-dials
+	staticRenotifyInterval = 1 * time.Hour
+)
+
+// sharedDiscoverySources composes the selected discovery sources that every crawler supports (static, db),
+// returning as well whether the source of the network itself (dv5, dht) was selected
+func sharedDiscoverySources(
+	ctx context.Context,
+	sources []string,
+	networkSource string,
+	network utils.NetworkType,
+	discFile string,
+	redialInterval string,
+	db storage.Client) ([]discovery.DiscoveryOption, bool, error) {
+
+	opts := make([]discovery.DiscoveryOption, 0, len(sources))
+	withNetworkSource := false
+	for _, source := range sources {
+		switch source {
+		case networkSource:
+			withNetworkSource = true
+
+		case discovery.SourceStatic:
+			if discFile == "" {
+				return nil, false, errors.New("the static discovery source needs a discovery file")
+			}
+			peers, err := discovery.ReadPeersFile(discFile)
+			if err != nil {
+				return nil, false, err
+			}
+			opts = append(opts, discovery.WithSource(source, discovery.NewStaticDiscovery(ctx, network, peers, staticRenotifyInterval)))
+
+		case discovery.SourceDB:
+			interval, err := time.ParseDuration(redialInterval)
+			if err != nil {
+				return nil, false, errors.Wrap(err, "unable to parse the redial interval")
+			}
+			opts = append(opts, discovery.WithSource(source, discovery.NewRedialDiscovery(ctx, db, network, interval)))
+
+		default:
+			return nil, false, errors.Errorf("unsupported discovery source %s", source)
+		}
+	}
+	return opts, withNetworkSource, nil
+}
+
+// loadTargets returns the peers of the target-list crawl mode, read from the targets file and/or the
+// target_peers table (nil if the mode is disabled)
+func loadTargets(targetsFile string, fromDB bool, db storage.Client) ([]peer.AddrInfo, error) {
+	if targetsFile == "" && !fromDB {
+		return nil, nil
+	}
+	entries := make([]string, 0)
+	if targetsFile != "" {
+		fileEntries, err := peering.LoadTargetsFile(targetsFile)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	if fromDB {
+		dbEntries, err := db.GetTargetEntries()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, dbEntries...)
+	}
+	targets, err := peering.ParseTargets(entries)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("the target-list crawl mode needs at least one target peer")
+	}
+	return targets, nil
+}
+
+// targetDiscoverySource replaces the discovery sources in the target-list crawl mode: it only
+// notifies the targets whose addresses we know, so that they are stored in the DB
+func targetDiscoverySource(ctx context.Context, network utils.NetworkType, targets []peer.AddrInfo) []discovery.DiscoveryOption {
+	withAddrs := make([]peer.AddrInfo, 0, len(targets))
+	for _, target := range targets {
+		if len(target.Addrs) > 0 {
+			withAddrs = append(withAddrs, target)
+		}
+	}
+	return []discovery.DiscoveryOption{
+		discovery.WithSource(discovery.SourceStatic, discovery.NewStaticDiscovery(ctx, network, withAddrs, staticRenotifyInterval)),
+	}
+}
@@ -0,0 +1,22 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/monitor"
+)
+
+// newDiversityExporter returns the exporter that refreshes the diversity gauges every
+// given interval (nil if the interval is 0)
+func newDiversityExporter(ctx context.Context, db storage.Client, interval string) (*monitor.DiversityExporter, error) {
+	refresh, err := time.ParseDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	if refresh == 0 {
+		return nil, nil
+	}
+	return monitor.NewDiversityExporter(ctx, db, refresh)
+}
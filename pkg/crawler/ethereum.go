@@ -5,23 +5,32 @@ package crawler
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"strings"
+	"net"
 	"time"
 
-	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
 	cli "github.com/urfave/cli/v2"
 
+	"github.com/migalabs/armiarma/pkg/api"
+	"github.com/migalabs/armiarma/pkg/churn"
 	"github.com/migalabs/armiarma/pkg/config"
-	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/db/storage"
 	"github.com/migalabs/armiarma/pkg/discovery"
 	"github.com/migalabs/armiarma/pkg/discovery/dv5"
 	"github.com/migalabs/armiarma/pkg/events"
 	"github.com/migalabs/armiarma/pkg/gossipsub"
 	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/identity"
 	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/migalabs/armiarma/pkg/monitor"
 	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	rendp "github.com/migalabs/armiarma/pkg/networks/ethereum/remoteendpoint"
 	"github.com/migalabs/armiarma/pkg/peering"
+	"github.com/migalabs/armiarma/pkg/retention"
+	"github.com/migalabs/armiarma/pkg/sampling"
+	"github.com/migalabs/armiarma/pkg/soak"
 	"github.com/migalabs/armiarma/pkg/utils"
 	"github.com/migalabs/armiarma/pkg/utils/apis"
 	log "github.com/sirupsen/logrus"
@@ -31,41 +40,127 @@ import (
 type EthereumCrawler struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
+	cancelDB  context.CancelFunc
+	RunID     int
 	Host      *hosts.BasicLibp2pHost
+	Pool      *hosts.HostPool
 	EthNode   *eth.LocalEthereumNode
-	DB        *psql.DBClient
+	DB        storage.Client
 	Disc      *discovery.Discovery
 	Peering   peering.PeeringService
 	Gossipsub *gossipsub.GossipSub
 	IpLocator *apis.IpLocator
 	Metrics   *metrics.PrometheusMetrics
 	Events    *events.Forwarder
+	Eclipse   *monitor.EclipseMonitor
+	Resources *monitor.ResourceMonitor
+	Snapshots *monitor.RoundSnapshotter
+	Quality   *monitor.QualityMonitor
+	Repute    *monitor.ReputationMonitor
+	Static    *peering.StaticPeersKeeper
+	Soak      *soak.SoakService
+	Watchdog  *soak.Watchdog
+	Retention *retention.Manager
+	Diversity *monitor.DiversityExporter
+	Identity  *identity.KeyManager
+	Churn     *churn.ChurnExperiment
+	Holder    *peering.ConnectionHolder
+	Reloader  *config.Reloader[config.EthereumCrawlerConfig]
 }
 
-func NewEthereumCrawler(mainCtx *cli.Context, conf config.EthereumCrawlerConfig) (*EthereumCrawler, error) {
-	// Setup the configuration
-	log.SetLevel(utils.ParseLogLevel(conf.LogLevel))
-
-	ctx, cancel := context.WithCancel(mainCtx.Context)
-	var err error
-
-	// parse or create a private key for the host
-	var gethPrivKey *ecdsa.PrivateKey
-	var libp2pPrivKey crypto.PrivKey
-	if conf.PrivateKey == "" {
-		gethPrivKey, err = utils.GenerateECDSAPrivKey()
-		if err != nil {
-			cancel()
+func NewEthereumCrawler(mainCtx *cli.Context, conf config.EthereumCrawlerConfig, opts ...EthereumCrawlerOption) (*EthereumCrawler, error) {
+	crawlerOpts := &ethereumCrawlerOptions{}
+	for _, opt := range opts {
+		if err := opt(crawlerOpts); err != nil {
 			return nil, err
 		}
+	}
+
+	// Setup the configuration (global and per-module log levels)
+	moduleLevels, err := utils.ParseModuleLevels(conf.LogLevels)
+	if err != nil {
+		return nil, err
+	}
+	logLevels := utils.NewModuleLevels(log.StandardLogger(), utils.ParseLogLevel(conf.LogLevel), moduleLevels)
+
+	// the DB outlives the rest of the modules on shutdown, so that it can flush what they persisted
+	ctx, cancelModules := context.WithCancel(mainCtx.Context)
+	dbCtx, cancelDB := context.WithCancel(mainCtx.Context)
+	cancel := func() {
+		cancelModules()
+		cancelDB()
+	}
+
+	// generate the central exporting service (or join the one shared by the crawlers of the process)
+	var promethMetrics *metrics.PrometheusMetrics
+	if crawlerOpts.metricsServer != nil {
+		promethMetrics = crawlerOpts.metricsServer.NewPrometheusMetrics(ctx, eth.ForkDigestNetwork(conf.ForkDigest))
 	} else {
-		gethPrivKey, err = utils.ParseECDSAPrivateKey(conf.PrivateKey)
+		promethMetrics = metrics.NewPrometheusMetrics(ctx, eth.ForkDigestNetwork(conf.ForkDigest), conf.MetricsIP, conf.MetricsPort)
+	}
+	// optionally, export the summaries of each round into InfluxDB
+	if conf.InfluxURL != "" {
+		influxExporter, err := metrics.NewInfluxExporter(ctx, eth.ForkDigestNetwork(conf.ForkDigest), conf.InfluxURL, conf.InfluxOrg, conf.InfluxBucket, conf.InfluxToken)
 		if err != nil {
 			cancel()
 			return nil, err
 		}
+		promethMetrics.AddSummaryExporter(influxExporter)
+	}
+
+	// generate/connect to PSQL Database
+	backupInterval, err := time.ParseDuration(conf.ActivePeersBackupInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	historyInterval, err := time.ParseDuration(conf.PeersHistoryInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	estimatesInterval, err := time.ParseDuration(conf.ValidatorEstimates)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// optionally, mirror the high-volume tables into ClickHouse
+	mirror, err := clickHouseMirror(ctx, conf.ClickHouseURL, conf.ClickHouseDB, conf.ClickHouseUser, conf.ClickHousePassword)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
-	libp2pPrivKey, err = utils.AdaptSecp256k1FromECDSA(gethPrivKey)
+	dbClient, err := openDB(dbCtx, dbConfig{
+		network:            utils.EthereumNetwork,
+		endpoint:           conf.PsqlEndpoint,
+		backupInterval:     backupInterval,
+		persistConnEvents:  conf.PersistConnEvents,
+		staticPeersInStats: conf.StaticPeersInStats,
+		resurrectOnNewENR:  conf.ResurrectOnNewENR,
+		peersHistory:       historyInterval,
+		validatorEstimates: estimatesInterval,
+		networkName:        crawlerOpts.networkName,
+		mirror:             mirror,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// load the persisted identity of the host, or create a new one
+	gethPrivKey, keyManager, err := loadHostIdentity(
+		ctx,
+		utils.EthereumNetwork,
+		conf.PrivateKey,
+		conf.KeyStore,
+		conf.KeyRotation,
+		dbClient,
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	libp2pPrivKey, err := utils.AdaptSecp256k1FromECDSA(gethPrivKey)
 	if err != nil {
 		cancel()
 		return nil, err
@@ -79,71 +174,263 @@ func NewEthereumCrawler(mainCtx *cli.Context, conf config.EthereumCrawlerConfig)
 		eth.ComposeQuickBeaconMetaData(),
 		conf.ForkDigest,
 	)
-	// subscribre to all attestnets and set forkdigest
-	ethNode.SetAttNetworks("ffffffffffffffff")
-	ethNode.SetForkDigest(strings.Trim(conf.ForkDigest, "0x"))
-
-	// generate the central exporting service
-	promethMetrics := metrics.NewPrometheusMetrics(ctx, conf.MetricsIP, conf.MetricsPort)
+	// publish the fork digest in the ENR (the attnets are the ones of the subnets that we subscribe to).
+	// The peers that we hold or that find us in discv5 check the eth2 entry, so it has to be valid
+	if forkVersion, ok := eth.ForkVersionOfDigest(conf.ForkDigest); ok {
+		if err := ethNode.SetForkDigest(conf.ForkDigest, forkVersion); err != nil {
+			cancel()
+			return nil, err
+		}
+	} else if conf.Discoverable || conf.HoldPeers > 0 {
+		cancel()
+		return nil, errors.Errorf("unknown fork version of fork digest %s, needed to publish the eth2 entry of the ENR", conf.ForkDigest)
+	} else {
+		log.WithField("fork-digest", conf.ForkDigest).Warn("unknown fork version of the fork digest, the ENR won't have an eth2 entry")
+	}
+	if conf.Discoverable {
+		// a non-public listen IP is left to the prediction of discv5
+		var publicIP net.IP
+		if ip := net.ParseIP(conf.IP); ip != nil && utils.IsIPPublic(ip) {
+			publicIP = ip
+		}
+		ethNode.PublishEndpoint(publicIP, conf.Port, conf.Port)
+		log.WithField("ip", publicIP).Info("publishing the endpoint of the crawler in its ENR")
+	}
 
-	// generate/connect to PSQL Database
-	backupInterval, err := time.ParseDuration(conf.ActivePeersBackupInterval)
+	// compose the blocklist of the connection gater
+	blocklistEntries := make([]string, 0)
+	if conf.BlocklistFile != "" {
+		fileEntries, err := hosts.LoadBlocklistFile(conf.BlocklistFile)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		blocklistEntries = append(blocklistEntries, fileEntries...)
+	}
+	if conf.BlocklistFromDB {
+		dbEntries, err := dbClient.GetBlocklistEntries()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		blocklistEntries = append(blocklistEntries, dbEntries...)
+	}
+	blocklist, err := hosts.NewBlocklist(blocklistEntries)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
-	dbClient, err := psql.NewDBClient(
-		ctx,
-		ethNode.Network(),
-		conf.PsqlEndpoint,
-		backupInterval,
-		psql.InitializeTables(true),
-		psql.WithConnectionEventsPersist(conf.PersistConnEvents),
-	)
+
+	// timeouts of the interactions with each class of peer
+	timeoutPolicy, err := parseTimeoutPolicy(conf.DialTimeout, conf.IdentifyTimeout, conf.ReqRespTimeout, conf.ClassTimeouts)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 
 	// create an ip-locator instance
-	ipLocator := apis.NewIpLocator(ctx, dbClient)
+	ipLocator, err := newIpLocator(ctx, dbClient, ipLocatorConfig{
+		CityDB:          conf.GeoIPCityDB,
+		ASNDB:           conf.GeoIPASNDB,
+		CacheTTL:        conf.IpCacheTTL,
+		RefreshInterval: conf.IpRefreshInterval,
+		RdnsLookup:      conf.RdnsLookup,
+		CloudTagging:    conf.CloudTagging,
+		CloudRangesFile: conf.CloudRangesFile,
+		TorExitList:     conf.TorExitList,
+		VPNListFile:     conf.VPNListFile,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
 	// generate libp2pHostd
-	host, err := hosts.NewBasicLibp2pEth2Host(
+	hostPool, err := hosts.NewHostPool(
 		ctx,
-		conf.IP,
-		conf.Port,
-		libp2pPrivKey,
-		conf.UserAgent,
 		ethNode, // ethereum local node
 		ipLocator,
+		conf.Hosts,
+		hosts.WithListenAddr(conf.IP, conf.Port),
+		hosts.WithListenAddr6(conf.IP6),
+		hosts.WithIPFamily(conf.IPFamily),
+		hosts.WithNoListen(conf.DiscoveryOnly),
+		hosts.WithNoDial(conf.ListenOnly),
+		hosts.WithIdentity(libp2pPrivKey),
+		hosts.WithUserAgent(conf.UserAgent),
+		hosts.WithSecurity(conf.Security...),
+		hosts.WithSignedPeerRecord(conf.SignedPeerRecord),
+		hosts.WithObservedAddrs(conf.ObservedAddrs),
+		hosts.WithPeerRecordCollection(conf.CollectPeerRecords),
+		hosts.WithNegotiationTiming(conf.NegotiationTiming),
+		hosts.WithBlocksByRangeProbe(conf.BlocksByRangeProbe),
+		hosts.WithResourceLimits(hosts.ResourceLimits{
+			MaxConns:              conf.MaxConns,
+			MaxConnsPerPeer:       conf.MaxConnsPerPeer,
+			MaxStreamsPerPeer:     conf.MaxStreamsPerPeer,
+			MaxStreamsPerProtocol: conf.MaxStreamsPerProtocol,
+			LimitsFile:            conf.ResourceLimitsFile,
+		}),
+		hosts.WithBlocklist(blocklist),
+		hosts.WithEventQueueSize(conf.EventQueueSize),
+		hosts.WithTimeouts(timeoutPolicy),
+		hosts.WithMetricsRegisterer(promethMetrics.Registerer()),
 	)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
+	// the primary host keeps the identity of the crawler
+	host := hostPool.Primary()
 
-	// create a new discovery5 service to discover peers in the Ethereum network
-	dv5, err := dv5.NewDiscovery5(
+	// seed of the discovery walks, recorded to replay the exploration of the run
+	crawlSeed := utils.ResolveSeed(conf.CrawlSeed)
+	log.Infof("crawl seed: %d", crawlSeed)
+
+	// record the run, the identify mode that peers will perceive, and its provenance
+	configHash, configSettings, err := config.Provenance(conf)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	runID, err := dbClient.InsertCrawlerRun(models.NewCrawlerRun(
+		string(ethNode.Network()),
+		host.Host().ID(),
+		host.Options().SignedPeerRecord,
+		host.Options().ObservedAddrs,
+		crawlSeed,
+	).WithProvenance(utils.BuildVersion(), configHash, configSettings))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// track the resources used by the crawler during the run
+	usageInterval, err := time.ParseDuration(conf.ResourceUsageInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resourceMonitor, err := monitor.NewResourceMonitor(ctx, runID, hostPool, dbClient, usageInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// compose the discovery sources that run concurrently
+	discOpts, withDv5, err := sharedDiscoverySources(
 		ctx,
-		ethNode,
-		gethPrivKey,
-		dv5.ParseBootnodesFromStringSlice(conf.Bootnodes),
-		conf.ForkDigest,
-		conf.Port)
+		conf.DiscoverySources,
+		discovery.SourceDv5,
+		utils.EthereumNetwork,
+		conf.DiscoveryFile,
+		conf.RedialInterval,
+		dbClient,
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the target-list crawl mode only dials the given peers, without discovering new ones
+	targets, err := loadTargets(conf.TargetsFile, conf.TargetsFromDB, dbClient)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
-	disc := discovery.NewDiscovery(
+	if targets != nil {
+		log.Infof("target-list crawl mode, dialing only %d target peers", len(targets))
+		discOpts, withDv5 = targetDiscoverySource(ctx, ethNode.Network(), targets), false
+	}
+	if conf.DiscoveryOnly {
+		log.Info("discovery-only mode, storing the discovered nodes without opening any libp2p connection")
+	}
+	if conf.ListenOnly {
+		log.Info("listen-only mode, only accepting the inbound connections of the peers that find us")
+	}
+	// create a new discovery5 service to discover peers in the Ethereum network
+	var dv5Serv *dv5.Discovery5
+	bootnodes := &bootnodeLoader{ctx: ctx}
+	if withDv5 {
+		if err := bootnodes.load(&conf); err != nil {
+			cancel()
+			return nil, err
+		}
+		pingInterval, err := time.ParseDuration(conf.BootnodePingInterval)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		dv5Strategy, err := dv5.ParseStrategy(conf.Dv5Strategy)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		dv5Distances, err := dv5.ParseDistances(conf.Dv5Distances)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		dv5Opts := []dv5.Discovery5Option{
+			dv5.WithStrategy(dv5Strategy),
+			dv5.WithBucketDistances(dv5Distances),
+			dv5.WithSeed(crawlSeed),
+			dv5.WithForkDigestFilter(conf.ForkDigestAllowlist, conf.StoreMismatchedForks),
+			dv5.WithBootnodePingInterval(pingInterval),
+		}
+		if len(conf.Dv5ForkDigests) > 0 {
+			dv5Opts = append(dv5Opts, dv5.WithTargetForkDigests(conf.Dv5ForkDigests))
+		}
+		dv5Serv, err = dv5.NewDiscovery5(
+			ctx,
+			ethNode,
+			gethPrivKey,
+			dv5.ParseBootnodesFromStringSlice(conf.Bootnodes),
+			conf.ForkDigest,
+			conf.Port,
+			dv5Opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		discOpts = append(discOpts, discovery.WithSource(discovery.SourceDv5, dv5Serv))
+	}
+	disc, err := discovery.NewDiscovery(
 		ctx,
-		dv5,
 		dbClient,
 		ipLocator,
+		discOpts...,
 	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
-	// create a gossipsub routing
-	gs := gossipsub.NewGossipSub(ctx, host.Host(), dbClient)
+	// create a gossipsub routing (tracking the propagation of the messages if they are persisted)
+	gsOpts := []gossipsub.GossipSubOption{
+		gossipsub.WithMsgIDFunction(eth.MsgIDFunction),
+		gossipsub.WithSubnetMetrics(eth.SubnetOfTopic),
+	}
+	if conf.PersistMsgs {
+		gsOpts = append(gsOpts, gossipsub.WithPropagationTracking(gossipsub.DefaultPropagationWindow))
+	}
+	meshInterval, err := time.ParseDuration(conf.MeshSnapshotInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if meshInterval > 0 {
+		gsOpts = append(gsOpts, gossipsub.WithMeshSnapshots(meshInterval))
+	}
+	gs, err := gossipsub.NewGossipSub(
+		ctx,
+		host.Host(),
+		dbClient,
+		gsOpts...,
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
 	// generate a new subnets-handler
 	ethMsgHandler, err := eth.NewEthMessageHandler(ethNode.GetNetworkGenesis(), conf.ValPubkeys)
@@ -151,56 +438,406 @@ func NewEthereumCrawler(mainCtx *cli.Context, conf config.EthereumCrawlerConfig)
 		cancel()
 		return nil, err
 	}
-	// subscribe the topics
-	for _, top := range conf.GossipTopics {
-		var msgHandler gossipsub.MessageHandler
-		switch top {
-		case eth.BeaconBlockTopicBase:
-			msgHandler = ethMsgHandler.BeaconBlockMessageHandler
-		default:
-			log.Error("untraceable gossipsub topic", top)
-			continue
-
+	// the messages of each topic are validated (before being handled) with the configured strategy
+	var beaconNode *rendp.InfuraClient
+	if conf.GossipValidation == eth.FullValidation {
+		clEndp, err := rendp.NewInfuraClient(conf.EthCLRemoteEndpoint)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		beaconNode = &clEndp
+	}
+	subscribe := func(msgType, topic string, msgHandler gossipsub.MessageHandler) error {
+		validator, err := eth.NewMessageValidator(conf.GossipValidation, msgType, ethNode.GetNetworkGenesis(), beaconNode)
+		if err != nil {
+			return err
+		}
+		if validator != nil {
+			if err := gs.RegisterValidator(topic, validator); err != nil {
+				return err
+			}
 		}
-		topic := eth.ComposeTopic(conf.ForkDigest, top)
 		gs.JoinAndSubscribe(topic, msgHandler, conf.PersistMsgs)
+		return nil
+	}
+
+	// subscribe the topics (only the ones of the fork of the network), they can be changed at runtime
+	fork := eth.ForkOfDigest(conf.ForkDigest)
+	ethMsgHandlers := ethMsgHandler.MessageHandlers()
+	syncTopics := func(next config.EthereumCrawlerConfig) error {
+		subscribed := make(map[string]struct{})
+		for _, topic := range gs.Topics() {
+			subscribed[topic] = struct{}{}
+		}
+		wanted := make(map[string]struct{})
+		join := func(msgType, topic string, msgHandler gossipsub.MessageHandler) error {
+			wanted[topic] = struct{}{}
+			if _, ok := subscribed[topic]; ok {
+				return nil
+			}
+			return subscribe(msgType, topic, msgHandler)
+		}
+		for _, top := range next.GossipTopics {
+			if !eth.IsForkTopic(fork, top) {
+				log.Errorf("untraceable gossipsub topic %s in fork %s", top, fork)
+				continue
+			}
+			msgHandler, ok := ethMsgHandlers[top]
+			if !ok {
+				// the rest of the topics are only accounted in the gossip metrics
+				msgHandler = gossipsub.CountMessageHandler
+			}
+			if err := join(top, eth.ComposeTopic(conf.ForkDigest, top), msgHandler); err != nil {
+				return err
+			}
+		}
+		// subcribe to attestation subnets
+		for _, subnet := range next.Subnets {
+			subTopics := eth.ComposeAttnetsTopic(conf.ForkDigest, subnet)
+			msgType := eth.SubnetMessageType(eth.AttestationTopicBase, subnet)
+			if err := join(msgType, subTopics, ethMsgHandler.SubnetMessageHandler); err != nil {
+				return err
+			}
+		}
+		// leave the topics that were dropped from the config
+		for topic := range subscribed {
+			if _, ok := wanted[topic]; ok {
+				continue
+			}
+			if err := gs.Leave(topic); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// the attnets of the ENR and the metadata follow the attestation subnets that we are subscribed to
+	publishAttnets := func() {
+		subnets := make([]int, 0)
+		for _, topic := range gs.Topics() {
+			if subnetType, subnet, ok := eth.SubnetOfTopic(topic); ok && subnetType == eth.AttnetSubnet {
+				subnets = append(subnets, subnet)
+			}
+		}
+		ethNode.SetAttnets(subnets)
+		log.WithField("subnets", subnets).Debug("published the attnets of the crawler")
+	}
+	syncSubscriptions := func(next config.EthereumCrawlerConfig) error {
+		err := syncTopics(next)
+		publishAttnets()
+		return err
+	}
+	if err := syncSubscriptions(conf); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// deprecation policy of the pruning strategy
+	deprecationWindow, err := time.ParseDuration(conf.DeprecationWindow)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
-	// subcribe to attestation subnets
-	for _, subnet := range conf.Subnets {
-		subTopics := eth.ComposeAttnetsTopic(conf.ForkDigest, subnet)
-		gs.JoinAndSubscribe(subTopics, ethMsgHandler.SubnetMessageHandler, conf.PersistMsgs)
+	policy := peering.DefaultDeprecationPolicy()
+	policy.MaxAttempts = conf.DeprecationAttempts
+	policy.Backoff = conf.DeprecationBackoff
+	policy.ExpiryWindow = deprecationWindow
+
+	// weights of the dial score of the priority strategy
+	dialWeights, err := peering.ParseDialScoreWeights(conf.DialScoreWeights)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
-	// generate the peering strategy
-	pStrategy, err := peering.NewPruningStrategy(
+	// generate the peering strategy (the targets replace the selected one)
+	var pStrategy peering.PeeringStrategy
+	if targets != nil {
+		var selector *peering.TargetSelector
+		selector, err = peering.NewTargetSelector(ethNode.Network(), targets)
+		if err == nil {
+			pStrategy, err = peering.NewBatchStrategy(ctx, ethNode.Network(), dbClient, selector)
+		}
+	} else {
+		pStrategy, err = peering.NewPeeringStrategy(
+			ctx,
+			conf.PeeringStrategy,
+			ethNode.Network(),
+			dbClient,
+			crawlSeed,
+			conf.PeeringQuota,
+			policy,
+			dialWeights,
+		)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// keep the backoff of the peers dialed before the last shutdown
+	if conf.ResumeDialQueue {
+		resumed, err := pStrategy.ResumeDialQueue()
+		if err != nil {
+			log.Warn(err)
+		} else {
+			log.Infof("resumed the dial queue of %d peers", resumed)
+		}
+	}
+	// snapshot the network at the end of each crawl round
+	snapshotter := monitor.NewRoundSnapshotter(ctx, runID, pStrategy.Rounds(), dbClient, dbClient)
+	peeringOpts := []peering.PeeringOption{
+		peering.WithPeeringStrategy(pStrategy),
+		peering.WithObserverMode(conf.ObserverMode),
+		peering.WithTimeoutPolicy(timeoutPolicy),
+	}
+	var rateCtl *peering.RateController
+	if conf.AdaptiveDialing {
+		rateCtl, err = peering.NewRateController(ctx, conf.MaxDialWorkers, conf.MaxDialRate)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		peeringOpts = append(peeringOpts, peering.WithRateController(rateCtl))
+	}
+	// Generate the PeeringService
+	peeringServ, err := peering.NewPeeringService(
 		ctx,
-		ethNode.Network(),
+		hostPool,
 		dbClient,
+		peeringOpts...,
 	)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
-	// Generate the PeeringService
-	peeringServ, err := peering.NewPeeringService(
+
+	// keep the trusted/static peers always connected
+	staticPeers, err := peering.ParseStaticPeers(conf.StaticPeers)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	staticKeeper, err := peering.NewStaticPeersKeeper(ctx, host, dbClient, staticPeers)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// generate the monitor of our own connected set and gossip mesh
+	eclipseMonitor, err := monitor.NewEclipseMonitor(
 		ctx,
-		host,
+		host.Host(),
 		dbClient,
-		peering.WithPeeringStrategy(pStrategy),
+		monitor.WithGossipPeers(gs),
+		monitor.WithConcentrationThreshold(conf.EclipseThreshold),
+		monitor.WithMinMonitoredPeers(conf.EclipseMinPeers),
 	)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 
+	// score the quality of the peers from their gossip and req/resp behaviour
+	qualityInterval, err := time.ParseDuration(conf.QualityInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	qualityWeights, err := monitor.ParseQualityWeights(conf.QualityWeights)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	qualityMonitor, err := monitor.NewQualityMonitor(
+		ctx,
+		dbClient,
+		monitor.WithGossipStats(gs),
+		monitor.WithReqRespStats(hostPool),
+		monitor.WithQualityWeights(qualityWeights),
+		monitor.WithQualityInterval(qualityInterval),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// optionally, aggregate the behaviour of the peers into their reputation
+	reputationInterval, err := time.ParseDuration(conf.ReputationInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	var reputationMonitor *monitor.ReputationMonitor
+	if reputationInterval > 0 {
+		reputationWeights, err := monitor.ParseReputationWeights(conf.ReputationWeights)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		reputationMonitor, err = monitor.NewReputationMonitor(
+			ctx,
+			dbClient,
+			monitor.WithReputationWeights(reputationWeights),
+			monitor.WithReputationInterval(reputationInterval),
+		)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
 	// Build the event forwarder
-	eventHandler := events.NewForwarder(conf.SSEIP, conf.SSEPort, host, ethMsgHandler)
+	eventHandler := events.NewForwarder(conf.SSEIP, conf.SSEPort, host, hostPool, ethMsgHandler)
+
+	// soak mode for unattended long-running deployments
+	var soakServ *soak.SoakService
+	var watchdog *soak.Watchdog
+	if conf.Soak {
+		retention, err := time.ParseDuration(conf.SoakRetention)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		soakServ, err = soak.NewSoakService(
+			ctx,
+			string(ethNode.Network()),
+			dbClient,
+			soak.WithRetention(retention),
+			soak.WithExportDir(conf.SoakExportDir),
+		)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		watchdogTimeout, err := time.ParseDuration(conf.WatchdogTimeout)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		watchdog, err = soak.NewWatchdog(ctx, hostPool, watchdogTimeout)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// pruning (or rollup) of the rows older than the retention window of each table
+	retentionManager, err := newRetentionManager(ctx, dbClient, conf.Retention, conf.RetentionSchedule)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// client, country, asn and attnet diversity gauges refreshed from the DB
+	diversityExporter, err := newDiversityExporter(ctx, dbClient, conf.DiversityInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// connection churn experiment over a sample of the known peers
+	var churnExp *churn.ChurnExperiment
+	if conf.Churn {
+		connectTime, err := time.ParseDuration(conf.ChurnConnectTime)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		disconnectTime, err := time.ParseDuration(conf.ChurnDisconnectTime)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		churnExp, err = churn.NewChurnExperiment(
+			ctx,
+			runID,
+			ethNode.Network(),
+			host.Host(),
+			dbClient,
+			churn.WithSampleSize(conf.ChurnSampleSize),
+			churn.WithCycles(conf.ChurnCycles),
+			churn.WithSchedule(connectTime, disconnectTime),
+			churn.WithSeed(conf.ChurnSeed),
+			churn.WithGossipStats(gs),
+		)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// long-lived connections to a stratified set of the known peers
+	var holder *peering.ConnectionHolder
+	if conf.HoldPeers > 0 {
+		pingInterval, err := time.ParseDuration(conf.HoldPingInterval)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		holdHost := host.Host()
+		holder, err = peering.NewConnectionHolder(
+			ctx,
+			runID,
+			ethNode.Network(),
+			holdHost,
+			dbClient,
+			conf.HoldPeers,
+			peering.WithHoldStratify(conf.HoldStratify),
+			peering.WithHoldSeed(crawlSeed),
+			peering.WithHoldPingInterval(pingInterval),
+			peering.WithKeepAliveBudget(conf.HoldBudget),
+			peering.WithKeepAlive(func(ctx context.Context, p peer.ID) (time.Duration, error) {
+				return ethNode.BeaconPing(ctx, holdHost, p)
+			}),
+		)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// the runtime-tunable settings get reloaded on SIGHUP or through the reload endpoint
+	reloader, err := config.NewReloader(conf, func() (config.EthereumCrawlerConfig, error) {
+		next, err := config.ReloadEthereumConfig(mainCtx, conf.Network)
+		if err == nil && dv5Serv != nil {
+			bootnodes.reload(&next)
+		}
+		return next, err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	handleRuntimeSettings(reloader, logLevels, rateCtl, func(c config.EthereumCrawlerConfig) runtimeSettings {
+		return runtimeSettings{c.LogLevel, c.LogLevels, c.MaxDialWorkers, c.MaxDialRate}
+	})
+	reloader.Handle("gossip-topics", syncSubscriptions)
+	reloader.Handle("subnets", syncSubscriptions)
+	if dv5Serv != nil {
+		// the health of the new bootnodes is checked right away, although the discv5 table
+		// is only seeded from them on a restart
+		reloader.Handle("bootnodes", func(next config.EthereumCrawlerConfig) error {
+			nodes, err := dv5.ParseBootnodes(next.Bootnodes)
+			if err != nil {
+				return err
+			}
+			dv5Serv.SetBootnodes(nodes)
+			return nil
+		})
+		// the bootnodes of the new source are applied through the bootnodes setting
+		reloader.Handle("bootnodes-source", func(config.EthereumCrawlerConfig) error {
+			return nil
+		})
+	}
 
 	// generate the CrawlerBase
 	crawler := &EthereumCrawler{
 		ctx:       ctx,
-		cancel:    cancel,
+		cancel:    cancelModules,
+		cancelDB:  cancelDB,
+		RunID:     runID,
 		Host:      host,
+		Pool:      hostPool,
 		DB:        dbClient,
 		EthNode:   ethNode,
 		Disc:      disc,
@@ -209,6 +846,20 @@ func NewEthereumCrawler(mainCtx *cli.Context, conf config.EthereumCrawlerConfig)
 		IpLocator: ipLocator,
 		Metrics:   promethMetrics,
 		Events:    eventHandler,
+		Resources: resourceMonitor,
+		Snapshots: snapshotter,
+		Quality:   qualityMonitor,
+		Repute:    reputationMonitor,
+		Static:    staticKeeper,
+		Soak:      soakServ,
+		Watchdog:  watchdog,
+		Retention: retentionManager,
+		Diversity: diversityExporter,
+		Identity:  keyManager,
+		Eclipse:   eclipseMonitor,
+		Churn:     churnExp,
+		Holder:    holder,
+		Reloader:  reloader,
 	}
 
 	// Register the metrics for the crawler and submodules
@@ -217,10 +868,28 @@ func NewEthereumCrawler(mainCtx *cli.Context, conf config.EthereumCrawlerConfig)
 
 	pruneMetricsMod := peeringServ.GetMetrics()
 	promethMetrics.AddMeticsModule(pruneMetricsMod)
+	// the dial queue is exposed next to the metrics
+	promethMetrics.AddHandler(peering.DialQueuePath, peeringServ.StatusHandler())
+	promethMetrics.AddHandler(peering.DialQueuePath+"/", peeringServ.StatusHandler())
+	// as well as the log levels, that can be changed at runtime
+	promethMetrics.AddHandler(utils.LogLevelPath, logLevels.Handler())
+	// and the reload of the config, with the audit log of the changes
+	promethMetrics.AddHandler(config.ConfigReloadPath, reloader.Handler())
+	// and the random samples of the known peers for the measurement experiments
+	promethMetrics.AddHandler(sampling.SamplePath, sampling.NewSampler(utils.EthereumNetwork, dbClient).Handler())
+	// and the REST API over the live state of the crawler and the recent aggregates of the DB
+	restAPI := api.NewAPI(string(utils.EthereumNetwork), dbClient, hostPool)
+	promethMetrics.AddSummaryExporter(restAPI)
+	promethMetrics.AddHandler(api.Path, restAPI.Handler())
 
 	discoveryMetricsMod := disc.GetEthereumMetrics()
 	promethMetrics.AddMeticsModule(discoveryMetricsMod)
 
+	if dv5Serv != nil {
+		dv5MetricsMod := dv5Serv.GetMetrics()
+		promethMetrics.AddMeticsModule(dv5MetricsMod)
+	}
+
 	hostMetricsMod := host.GetMetrics()
 	promethMetrics.AddMeticsModule(hostMetricsMod)
 
@@ -230,30 +899,138 @@ func NewEthereumCrawler(mainCtx *cli.Context, conf config.EthereumCrawlerConfig)
 	ethNodeMetricsMod := ethNode.GetMetrics()
 	promethMetrics.AddMeticsModule(ethNodeMetricsMod)
 
+	eclipseMetricsMod := eclipseMonitor.GetMetrics()
+	promethMetrics.AddMeticsModule(eclipseMetricsMod)
+
+	if diversityExporter != nil {
+		promethMetrics.AddMeticsModule(diversityExporter.GetMetrics())
+	}
+
+	// hit rate of the cache of located IPs
+	ipLocatorMetricsMod := ipLocator.GetMetrics()
+	promethMetrics.AddMeticsModule(ipLocatorMetricsMod)
+
+	// queue depth and flush latency of the DB writer
+	dbMetricsMod := dbClient.GetMetrics()
+	promethMetrics.AddMeticsModule(dbMetricsMod)
+
 	return crawler, nil
 }
 
 // generate new CrawlerBase
 func (c *EthereumCrawler) Run() {
 	// init all the eth_protocols
-	c.EthNode.ServeBeaconPing(c.Host.Host())
-	c.EthNode.ServeBeaconStatus(c.Host.Host())
-	c.EthNode.ServeBeaconMetadata(c.Host.Host())
+	for _, h := range c.Pool.Hosts() {
+		c.EthNode.ServeBeaconPing(h.Host())
+		c.EthNode.ServeBeaconStatus(h.Host())
+		c.EthNode.ServeBeaconMetadata(h.Host())
+		c.EthNode.ServeBeaconGoodbye(h.Host())
+	}
 
 	// initialization secuence for the crawler
 	c.Events.Start(c.ctx)
 	c.IpLocator.Run()
-	c.Host.Start()
+	c.Pool.Start()
 	c.Disc.Start()
 	c.Peering.Run()
+	c.Static.Run()
 	c.Metrics.Start()
+	c.Resources.Start()
+	c.Snapshots.Start()
+	c.Quality.Start()
+	if c.Repute != nil {
+		c.Repute.Start()
+	}
+	c.Reloader.Run(c.ctx)
+	if c.Soak != nil {
+		c.Soak.Start()
+		c.Watchdog.Start()
+	}
+	if c.Retention != nil {
+		c.Retention.Start()
+	}
+	if c.Diversity != nil {
+		c.Diversity.Start()
+	}
+	if c.Identity != nil {
+		c.Identity.Start()
+	}
+	if c.Churn != nil {
+		c.EthNode.OnGoodbye(c.Churn.OnGoodbye)
+		if err := c.Churn.Start(); err != nil {
+			log.WithError(err).Error("unable to start the churn experiment")
+		}
+	}
+	if c.Holder != nil {
+		if err := c.Holder.Start(); err != nil {
+			log.WithError(err).Error("unable to start the connection holder")
+		}
+	}
 }
 
+// ChurnDone returns a channel that gets closed once the churn experiment finished
+// (it never gets closed if there is no experiment running)
+func (c *EthereumCrawler) ChurnDone() <-chan struct{} {
+	if c.Churn == nil {
+		return nil
+	}
+	return c.Churn.Done()
+}
+
+// Stalled returns a channel that gets closed if the watchdog considers that the crawler stalled
+// (it never gets closed if the watchdog isn't running)
+func (c *EthereumCrawler) Stalled() <-chan struct{} {
+	if c.Watchdog == nil {
+		return nil
+	}
+	return c.Watchdog.Stalled()
+}
+
+// IdentityRotated returns a channel that gets closed once the identity of the host was rotated
+// (it never gets closed if the identity isn't persisted or rotated)
+func (c *EthereumCrawler) IdentityRotated() <-chan struct{} {
+	if c.Identity == nil {
+		return nil
+	}
+	return c.Identity.Rotated()
+}
+
+// Close shuts the crawler down following the flow of the data, so that nothing gets lost on the way:
+// the discovery and the peering stop first (the peering persisting its dial queue), then the hosts
+// and the rest of the modules, and finally the DB writer flushes everything that they persisted
 func (c *EthereumCrawler) Close() {
+	log.Info("shutting down the crawler")
 	c.Disc.Stop()
-	c.Host.Host().Close()
-	c.DB.Close()
-	c.Metrics.Close()
+	c.Peering.Stop()
+	c.Resources.Stop()
+	c.Snapshots.Stop()
+	c.Pool.Close()
 	c.Events.Stop()
+	c.Metrics.Close()
 	c.cancel()
+	c.Quality.Stop()
+	if c.Repute != nil {
+		c.Repute.Stop()
+	}
+	if c.Soak != nil {
+		c.Soak.Stop()
+	}
+	if c.Retention != nil {
+		c.Retention.Stop()
+	}
+	if c.Diversity != nil {
+		c.Diversity.Stop()
+	}
+	if c.Churn != nil {
+		c.Churn.Stop()
+	}
+	if c.Holder != nil {
+		c.Holder.Stop()
+	}
+	if err := c.DB.FinishCrawlerRun(c.RunID, time.Now()); err != nil {
+		log.Error(err)
+	}
+	c.DB.Close()
+	c.cancelDB()
+	log.Info("crawler shut down")
 }
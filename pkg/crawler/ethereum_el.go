@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/discovery"
+	"github.com/migalabs/armiarma/pkg/discovery/dv4"
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/apis"
+	log "github.com/sirupsen/logrus"
+)
+
+// EthereumELCrawler walks the discv4 DHT of the execution layer, identifying the clients
+// through the devp2p hello (there are no libp2p hosts nor peering involved)
+type EthereumELCrawler struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	DB        storage.Client
+	Disc      *discovery.Discovery
+	IpLocator *apis.IpLocator
+	Metrics   *metrics.PrometheusMetrics
+}
+
+func NewEthereumELCrawler(mainCtx *cli.Context, conf config.EthereumELCrawlerConfig) (*EthereumELCrawler, error) {
+	// Setup the configuration
+	log.SetLevel(utils.ParseLogLevel(conf.LogLevel))
+
+	ctx, cancel := context.WithCancel(mainCtx.Context)
+
+	// generate the central exporting service
+	promethMetrics := metrics.NewPrometheusMetrics(ctx, conf.Network, conf.MetricsIP, conf.MetricsPort)
+
+	// generate/connect to PSQL Database (there are no connections to backup)
+	dbClient, err := openDB(ctx, dbConfig{
+		network:           utils.EthereumELNetwork,
+		endpoint:          conf.PsqlEndpoint,
+		backupInterval:    24 * time.Hour,
+		skipBackup:        true,
+		persistConnEvents: true,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	privKey, _, err := loadHostIdentity(ctx, utils.EthereumELNetwork, conf.PrivateKey, "", "", dbClient)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// create an ip-locator instance
+	ipLocator := apis.NewIpLocator(ctx, dbClient)
+
+	// create a new discovery4 service to discover the nodes of the EL network
+	helloTimeout, err := time.ParseDuration(conf.HelloTimeout)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	recrawlInterval, err := time.ParseDuration(conf.RecrawlInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	bootnodes, err := dv4.ParseBootnodes(conf.Bootnodes)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	dv4Serv, err := dv4.NewDiscovery4(
+		ctx,
+		privKey,
+		bootnodes,
+		conf.Port,
+		dv4.WithUserAgent(conf.UserAgent),
+		dv4.WithForkHashes(conf.ForkHashes),
+		dv4.WithHelloSettings(helloTimeout, conf.HelloWorkers),
+		dv4.WithRecrawlInterval(recrawlInterval),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	disc, err := discovery.NewDiscovery(
+		ctx,
+		dbClient,
+		ipLocator,
+		discovery.WithSource(discovery.SourceDv4, dv4Serv),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	crawler := &EthereumELCrawler{
+		ctx:       ctx,
+		cancel:    cancel,
+		DB:        dbClient,
+		Disc:      disc,
+		IpLocator: ipLocator,
+		Metrics:   promethMetrics,
+	}
+
+	// Register the metrics for the crawler (client, geo, etc. distributions of the identified nodes)
+	promethMetrics.AddMeticsModule(composeCrawlerMetrics(dbClient, newCrawlerMetrics()))
+	// the peers notified by the discovery
+	promethMetrics.AddMeticsModule(disc.GetMetrics())
+	// as well as the queue depth and flush latency of the DB writer
+	promethMetrics.AddMeticsModule(dbClient.GetMetrics())
+
+	return crawler, nil
+}
+
+func (c *EthereumELCrawler) Run() {
+	// initialization secuence for the crawler
+	c.IpLocator.Run()
+	c.Disc.Start()
+	c.Metrics.Start()
+}
+
+func (c *EthereumELCrawler) Close() {
+	c.Disc.Stop()
+	c.DB.Close()
+	c.Metrics.Close()
+	c.cancel()
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/identity"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// loadHostIdentity returns the private key of the host, which can be the configured one, a persisted one
+// (managed by the returned KeyManager), or an ephemeral one when there is no key store
+func loadHostIdentity(
+	ctx context.Context,
+	network utils.NetworkType,
+	privKey string,
+	keyStore string,
+	keyRotation string,
+	db storage.Client) (*ecdsa.PrivateKey, *identity.KeyManager, error) {
+
+	if privKey != "" {
+		if keyStore != "" {
+			log.Warn("priv-key was given, ignoring the key-store")
+		}
+		key, err := utils.ParseECDSAPrivateKey(privKey)
+		return key, nil, err
+	}
+	if keyStore == "" {
+		key, err := utils.GenerateECDSAPrivKey()
+		return key, nil, err
+	}
+
+	rotation, err := time.ParseDuration(keyRotation)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to parse key rotation")
+	}
+	var store identity.KeyStore = db
+	if keyStore != identity.PostgresKeyStore {
+		store, err = identity.NewFileKeyStore(keyStore)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	keyManager, err := identity.NewKeyManager(
+		ctx,
+		string(network),
+		store,
+		db,
+		identity.WithRotation(rotation),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyManager.PrivKey(), keyManager, nil
+}
@@ -0,0 +1,111 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/utils/apis"
+)
+
+// ipLocatorConfig gathers the settings of the IP locator shared by the crawlers
+type ipLocatorConfig struct {
+	CityDB          string
+	ASNDB           string
+	CacheTTL        string
+	RefreshInterval string
+	RdnsLookup      bool
+	CloudTagging    bool
+	CloudRangesFile string
+	TorExitList     bool
+	VPNListFile     string
+}
+
+// newIpLocator returns the IP locator of the crawler, which caches the located IPs in the DB for the
+// given ttl and locates them with the given GeoLite2 databases before calling the IP-API (if the city one is given)
+func newIpLocator(ctx context.Context, db storage.Client, conf ipLocatorConfig) (*apis.IpLocator, error) {
+	ttl, err := time.ParseDuration(conf.CacheTTL)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse ip cache ttl")
+	}
+	refresh, err := time.ParseDuration(conf.RefreshInterval)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse ip refresh interval")
+	}
+	opts := []apis.IpLocatorOption{
+		apis.WithIpTTL(ttl),
+		apis.WithIpRefreshInterval(refresh),
+	}
+	if conf.CityDB != "" {
+		provider, err := apis.NewMMDBProvider(conf.CityDB, conf.ASNDB)
+		if err != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{
+			"city-db": conf.CityDB,
+			"asn-db":  conf.ASNDB,
+		}).Info("locating the IPs with the offline geoip databases")
+		opts = append(opts, apis.WithIpProvider(provider))
+	}
+	tagger, err := newCloudTagger(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	if tagger != nil {
+		opts = append(opts, apis.WithCloudTagger(tagger))
+	}
+	detector, err := newAnonymizerDetector(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, apis.WithAnonymizerDetector(detector))
+	return apis.NewIpLocator(ctx, db, opts...), nil
+}
+
+// newCloudTagger returns the tagger of the rdns and cloud provider of the IPs (nil if both are disabled)
+func newCloudTagger(ctx context.Context, conf ipLocatorConfig) (*apis.CloudTagger, error) {
+	if !conf.RdnsLookup && !conf.CloudTagging && conf.CloudRangesFile == "" {
+		return nil, nil
+	}
+	ranges := apis.NewCloudRanges()
+	if conf.CloudTagging {
+		// the tagger still works with the ASNs and the rdns of the providers without the published ranges
+		if err := ranges.FetchPublishedRanges(ctx); err != nil {
+			log.Warnf("unable to fetch the published cloud ranges - %s", err.Error())
+		}
+	}
+	if conf.CloudRangesFile != "" {
+		if err := ranges.LoadRangesFile(conf.CloudRangesFile); err != nil {
+			return nil, err
+		}
+	}
+	log.WithFields(log.Fields{
+		"rdns":         conf.RdnsLookup,
+		"cloud-ranges": ranges.Len(),
+	}).Info("tagging the located IPs with their cloud provider")
+	return apis.NewCloudTagger(ranges, conf.RdnsLookup), nil
+}
+
+// newAnonymizerDetector returns the detector of the Tor, VPN and proxy IPs, which always relies on
+// the proxy flag of the IP-API and optionally on the Tor exit list and a list of VPN endpoints
+func newAnonymizerDetector(ctx context.Context, conf ipLocatorConfig) (*apis.AnonymizerDetector, error) {
+	sources := make([]apis.AnonymizerSource, 0)
+	if conf.TorExitList {
+		torExits := apis.NewIpListAnonymizer(apis.TorSource)
+		torExits.RunTorExitList(ctx, apis.DefaultTorExitListInterval)
+		sources = append(sources, torExits)
+	}
+	if conf.VPNListFile != "" {
+		vpns, err := apis.LoadIpListFile("vpn", conf.VPNListFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("flagging the IPs of %d known vpn endpoints", vpns.Len())
+		sources = append(sources, vpns)
+	}
+	sources = append(sources, apis.IpApiAnonymizer{})
+	return apis.NewAnonymizerDetector(sources...), nil
+}
@@ -3,133 +3,708 @@ Copyright © 2021 Miga Labs
 */
 package crawler
 
-// // TEMPORARY data for the running the filecoin demo
-// var (
-// 	workers       = 100
-// 	minWaitTime   = 5 * time.Second
-// 	timeout       = 10 * time.Second
-// 	ipfsprotocols = []string{
-// 		"/ipfs/kad/1.0.0",
-// 		"/ipfs/kad/2.0.0",
-// 	}
-// 	filecoinprotocols = []string{
-// 		"/fil/kad/testnetnet/kad/1.0.0",
-// 	}
-// 	// logging variables
-// 	ipfslog = logrus.WithField(
-// 		"module", "IPFS_CRAWLER",
-// 	)
-// )
-
-// // crawler status containing the main basemodule and info that the app will ConnectedF
-// type IpfsCrawler struct {
-// 	Host            *hosts.BasicLibp2pHost
-// 	DBClient        *psql.DBClient
-// 	Disc            *discovery.Discovery
-// 	Peering         peering.PeeringService
-// 	Gs              *gossipsub.GossipSub
-// 	Info            *info.IpfsInfoData
-// 	IpLocator       *apis.IpLocator
-// 	ExporterService *exporters.ExporterService
-// }
-
-// func NewIpfsCrawler(ctx *cli.Context, infObj info.IpfsInfoData) (*IpfsCrawler, error) {
-
-// 	exporterService := exporters.NewExporterService(ctx.Context)
-
-// 	// generate Eth2 network model for the PSQL
-// 	ipfsmodel := postgresql.NewIpfsPeerModel("ipfs")
-
-// 	// Generate new DB for the peerstore
-// 	db := db.NewPeerStore(ctx.Context, exporterService, infObj.OutputPath, infObj.DbEndpoint, &ipfsmodel)
-
-// 	// IpLocalizer
-// 	ipLocalizer := apis.NewPeerLocalizer(ctx.Context, IpCacheSize)
-
-// 	// Host
-// 	host, err := hosts.NewBasicLibp2pIpfsHost(ctx.Context, infObj, &ipLocalizer, &db)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	// IPFS protocols
-// 	protocols := make([]string, 0)
-// 	// filter the network to select needed protocols
-// 	switch infObj.Network {
-// 	case "ipfs":
-// 		protocols = info.Ipfsprotocols
-// 	case "filecoin":
-// 		protocols = info.Filecoinprotocols
-// 	case "":
-// 		ipfslog.Warn("network not defined. setting ipfs by default")
-// 		protocols = info.Ipfsprotocols
-// 	default:
-// 		ipfslog.Warnf("network not recognized. network=%s. setting ipfs by default", infObj.Network)
-// 		protocols = info.Ipfsprotocols
-// 	}
-// 	ipfslog.Infoln("running peer discovery with protocols:", protocols)
-
-// 	// discovery nodes
-// 	bootnodes, err := kdht.ReadIpfsBootnodeFile(infObj.BootNodesFile)
-// 	if err != nil {
-// 		return nil, errors.Wrap(err, "unable to retreive the bootnodes")
-// 	}
-// 	// generate KDHT peer discovery
-// 	kdhtd := kdht.NewIPFSDiscService(ctx.Context, host.Host(), protocols, bootnodes, timeout)
-
-// 	// Peer discovery
-// 	disc := discovery.NewDiscovery(ctx.Context, &kdhtd, &db, &ipLocalizer)
-
-// 	// GossipSup
-// 	gs := gossipsub.NewGossipSub(ctx.Context, exporterService, host, &db)
-
-// 	// generate the peering strategy
-
-// 	pStrategy, err := peering.NewPruningStrategy(ctx.Context, "ipfs", &db)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	// Generate the PeeringService
-// 	peeringServ, err := peering.NewPeeringService(ctx.Context, host, &db,
-// 		peering.WithPeeringStrategy(&pStrategy),
-// 	)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	// generate the CrawlerBase
-// 	crawler := &IpfsCrawler{
-// 		Host:            host,
-// 		Info:            &infObj,
-// 		DB:              &db,
-// 		Disc:            disc,
-// 		Peering:         peeringServ,
-// 		Gs:              gs,
-// 		IpLocalizer:     ipLocalizer,
-// 		ExporterService: exporterService,
-// 	}
-// 	return &IpfsCrawler{}, nil
-// }
-
-// // generate new CrawlerBase
-// func (c *IpfsCrawler) Run() {
-// 	// IMPORTANT
-// 	// Set the VENV Variable for handling too many opened connections
-// 	os.Setenv("LIBP2P_SWARM_FD_LIMIT", "10000")
-
-// 	// initialization secuence for the crawler
-// 	c.ExporterService.Run()
-// 	c.IpLocator.Run()
-// 	c.Host.Start()
-// 	c.Disc.Start()
-// 	/*
-// 		// no topics to join so far
-// 		for _, topic := range topics {
-// 			c.Gs.JoinAndSubscribe(topic)
-// 		}
-// 	*/
-// 	c.Peering.Run()
-// 	c.Gs.ServeMetrics()
-// 	//c.DB.ServeMetrics()
-// }
+import (
+	"context"
+	"os"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/api"
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/discovery"
+	"github.com/migalabs/armiarma/pkg/discovery/dv4"
+	"github.com/migalabs/armiarma/pkg/discovery/dv5"
+	"github.com/migalabs/armiarma/pkg/discovery/kdht"
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/identity"
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/migalabs/armiarma/pkg/monitor"
+	"github.com/migalabs/armiarma/pkg/networks/ipfs"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+	"github.com/migalabs/armiarma/pkg/peering"
+	"github.com/migalabs/armiarma/pkg/retention"
+	"github.com/migalabs/armiarma/pkg/sampling"
+	"github.com/migalabs/armiarma/pkg/soak"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/apis"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	kdhtTimeout = 10 * time.Second
+)
+
+// crawler status containing the main basemodule and info that the app will ConnectedF
+type IpfsCrawler struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelDB  context.CancelFunc
+	RunID     int
+	Host      *hosts.BasicLibp2pHost
+	Pool      *hosts.HostPool
+	IpfsNode  *ipfs.LocalIpfsNode
+	DB        storage.Client
+	Disc      *discovery.Discovery
+	Peering   *peering.PeeringService
+	Gossipsub *gossipsub.GossipSub
+	IpLocator *apis.IpLocator
+	Metrics   *metrics.PrometheusMetrics
+	Eclipse   *monitor.EclipseMonitor
+	Resources *monitor.ResourceMonitor
+	Snapshots *monitor.RoundSnapshotter
+	Static    *peering.StaticPeersKeeper
+	Soak      *soak.SoakService
+	Watchdog  *soak.Watchdog
+	Retention *retention.Manager
+	Diversity *monitor.DiversityExporter
+	Identity  *identity.KeyManager
+	Reloader  *config.Reloader[config.IpfsCrawlerConfig]
+}
+
+func NewIpfsCrawler(mainCtx *cli.Context, conf config.IpfsCrawlerConfig) (*IpfsCrawler, error) {
+	// Setup the configuration (global and per-module log levels)
+	moduleLevels, err := utils.ParseModuleLevels(conf.LogLevels)
+	if err != nil {
+		return nil, err
+	}
+	logLevels := utils.NewModuleLevels(log.StandardLogger(), utils.ParseLogLevel(conf.LogLevel), moduleLevels)
+
+	// the DB outlives the rest of the modules on shutdown, so that it can flush what they persisted
+	ctx, cancelModules := context.WithCancel(mainCtx.Context)
+	dbCtx, cancelDB := context.WithCancel(mainCtx.Context)
+	cancel := func() {
+		cancelModules()
+		cancelDB()
+	}
+
+	// generate local node for the ipfs-like network
+	ipfsNode, err := ipfs.NewLocalIpfsNode(conf.NetworkType())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// generate the central exporting service
+	promethMetrics := metrics.NewPrometheusMetrics(ctx, conf.Network, conf.MetricsIP, conf.MetricsPort)
+	// optionally, export the summaries of each round into InfluxDB
+	if conf.InfluxURL != "" {
+		influxExporter, err := metrics.NewInfluxExporter(ctx, conf.Network, conf.InfluxURL, conf.InfluxOrg, conf.InfluxBucket, conf.InfluxToken)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		promethMetrics.AddSummaryExporter(influxExporter)
+	}
+
+	// generate/connect to PSQL Database
+	backupInterval, err := time.ParseDuration(conf.ActivePeersBackupInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	historyInterval, err := time.ParseDuration(conf.PeersHistoryInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// optionally, mirror the high-volume tables into ClickHouse
+	mirror, err := clickHouseMirror(ctx, conf.ClickHouseURL, conf.ClickHouseDB, conf.ClickHouseUser, conf.ClickHousePassword)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	dbClient, err := openDB(dbCtx, dbConfig{
+		network:            ipfsNode.Network(),
+		endpoint:           conf.PsqlEndpoint,
+		backupInterval:     backupInterval,
+		persistConnEvents:  conf.PersistConnEvents,
+		staticPeersInStats: conf.StaticPeersInStats,
+		peersHistory:       historyInterval,
+		mirror:             mirror,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// load the persisted identity of the host, or create a new one
+	ecdsaPrivKey, keyManager, err := loadHostIdentity(
+		ctx,
+		ipfsNode.Network(),
+		conf.PrivateKey,
+		conf.KeyStore,
+		conf.KeyRotation,
+		dbClient,
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	libp2pPrivKey, err := utils.AdaptSecp256k1FromECDSA(ecdsaPrivKey)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// compose the blocklist of the connection gater
+	blocklistEntries := make([]string, 0)
+	if conf.BlocklistFile != "" {
+		fileEntries, err := hosts.LoadBlocklistFile(conf.BlocklistFile)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		blocklistEntries = append(blocklistEntries, fileEntries...)
+	}
+	if conf.BlocklistFromDB {
+		dbEntries, err := dbClient.GetBlocklistEntries()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		blocklistEntries = append(blocklistEntries, dbEntries...)
+	}
+	blocklist, err := hosts.NewBlocklist(blocklistEntries)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// timeouts of the interactions with each class of peer
+	timeoutPolicy, err := parseTimeoutPolicy(conf.DialTimeout, conf.IdentifyTimeout, conf.ReqRespTimeout, conf.ClassTimeouts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// create an ip-locator instance
+	ipLocator, err := newIpLocator(ctx, dbClient, ipLocatorConfig{
+		CityDB:          conf.GeoIPCityDB,
+		ASNDB:           conf.GeoIPASNDB,
+		CacheTTL:        conf.IpCacheTTL,
+		RefreshInterval: conf.IpRefreshInterval,
+		RdnsLookup:      conf.RdnsLookup,
+		CloudTagging:    conf.CloudTagging,
+		CloudRangesFile: conf.CloudRangesFile,
+		TorExitList:     conf.TorExitList,
+		VPNListFile:     conf.VPNListFile,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// generate libp2pHost
+	hostPool, err := hosts.NewHostPool(
+		ctx,
+		ipfsNode,
+		ipLocator,
+		conf.Hosts,
+		hosts.WithListenAddr(conf.IP, conf.Port),
+		hosts.WithListenAddr6(conf.IP6),
+		hosts.WithIPFamily(conf.IPFamily),
+		hosts.WithIdentity(libp2pPrivKey),
+		hosts.WithUserAgent(conf.UserAgent),
+		hosts.WithSecurity(conf.Security...),
+		hosts.WithSignedPeerRecord(conf.SignedPeerRecord),
+		hosts.WithObservedAddrs(conf.ObservedAddrs),
+		hosts.WithPeerRecordCollection(conf.CollectPeerRecords),
+		hosts.WithNegotiationTiming(conf.NegotiationTiming),
+		hosts.WithResourceLimits(hosts.ResourceLimits{
+			MaxConns:              conf.MaxConns,
+			MaxConnsPerPeer:       conf.MaxConnsPerPeer,
+			MaxStreamsPerPeer:     conf.MaxStreamsPerPeer,
+			MaxStreamsPerProtocol: conf.MaxStreamsPerProtocol,
+			LimitsFile:            conf.ResourceLimitsFile,
+		}),
+		hosts.WithBlocklist(blocklist),
+		hosts.WithEventQueueSize(conf.EventQueueSize),
+		hosts.WithTimeouts(timeoutPolicy),
+		hosts.WithMetricsRegisterer(promethMetrics.Registerer()),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the primary host keeps the identity of the crawler
+	host := hostPool.Primary()
+
+	// seed of the discovery walks, recorded to replay the exploration of the run
+	crawlSeed := utils.ResolveSeed(conf.CrawlSeed)
+	log.Infof("crawl seed: %d", crawlSeed)
+
+	// record the run, the identify mode that peers will perceive, and its provenance
+	configHash, configSettings, err := config.Provenance(conf)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	runID, err := dbClient.InsertCrawlerRun(models.NewCrawlerRun(
+		string(ipfsNode.Network()),
+		host.Host().ID(),
+		host.Options().SignedPeerRecord,
+		host.Options().ObservedAddrs,
+		crawlSeed,
+	).WithProvenance(utils.BuildVersion(), configHash, configSettings))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// track the resources used by the crawler during the run
+	usageInterval, err := time.ParseDuration(conf.ResourceUsageInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resourceMonitor, err := monitor.NewResourceMonitor(ctx, runID, hostPool, dbClient, usageInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// compose the discovery sources that run concurrently (the waku nodes are discovered over discv5)
+	networkSource := discovery.SourceDHT
+	if ipfsNode.Network() == utils.WakuNetwork {
+		networkSource = discovery.SourceDv5
+	}
+	discOpts, withNetworkSource, err := sharedDiscoverySources(
+		ctx,
+		conf.DiscoverySources,
+		networkSource,
+		ipfsNode.Network(),
+		conf.DiscoveryFile,
+		conf.RedialInterval,
+		dbClient,
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the target-list crawl mode only dials the given peers, without discovering new ones
+	targets, err := loadTargets(conf.TargetsFile, conf.TargetsFromDB, dbClient)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if targets != nil {
+		log.Infof("target-list crawl mode, dialing only %d target peers", len(targets))
+		discOpts, withNetworkSource = targetDiscoverySource(ctx, ipfsNode.Network(), targets), false
+	}
+	if withNetworkSource && networkSource == discovery.SourceDv5 {
+		// create a new discv5 discovery service (the bootnodes are given in their enr: representation)
+		bootnodes, err := dv4.ParseBootnodes(conf.Bootnodes)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		wakuDisc, err := dv5.NewWakuDiscovery(ctx, ecdsaPrivKey, bootnodes, conf.Port)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		discOpts = append(discOpts, discovery.WithSource(discovery.SourceDv5, wakuDisc))
+	} else if withNetworkSource {
+		// select the Kademlia protocols of the network
+		var protocols []string
+		switch ipfsNode.Network() {
+		case utils.FilecoinNetwork:
+			protocols = config.Filecoinprotocols
+		default:
+			protocols = config.Ipfsprotocols
+		}
+		log.Infoln("running peer discovery with protocols:", protocols)
+
+		// create a new Kademlia DHT discovery service
+		bootnodes, err := kdht.ParseBootnodesFromStringSlice(conf.Bootnodes)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		walkInterval, err := time.ParseDuration(conf.DhtWalkInterval)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		recrawlInterval, err := time.ParseDuration(conf.DhtRecrawlInterval)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		kdhtDisc := kdht.NewKadDHTDiscService(
+			ctx,
+			host.Host(),
+			ipfsNode.Network(),
+			protocols,
+			bootnodes,
+			kdhtTimeout,
+			kdht.WithSeed(crawlSeed),
+			kdht.WithWalkSettings(conf.DhtWalkers, walkInterval, recrawlInterval),
+		)
+		discOpts = append(discOpts, discovery.WithSource(discovery.SourceDHT, kdhtDisc))
+	}
+	disc, err := discovery.NewDiscovery(
+		ctx,
+		dbClient,
+		ipLocator,
+		discOpts...,
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the messages of the waku relay shards are accounted in the gossip metrics
+	var gs *gossipsub.GossipSub
+	if ipfsNode.Network() == utils.WakuNetwork && len(conf.WakuShards) > 0 {
+		gs, err = gossipsub.NewGossipSub(
+			ctx,
+			host.Host(),
+			dbClient,
+			gossipsub.WithMsgIDFunction(waku.MsgIDFunction),
+			gossipsub.WithSubnetMetrics(waku.SubnetOfTopic),
+		)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		for _, topic := range waku.ShardTopics(conf.WakuClusterID, conf.WakuShards) {
+			gs.JoinAndSubscribe(topic, gossipsub.CountMessageHandler, false)
+		}
+	}
+
+	// deprecation policy of the pruning strategy
+	deprecationWindow, err := time.ParseDuration(conf.DeprecationWindow)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	policy := peering.DefaultDeprecationPolicy()
+	policy.MaxAttempts = conf.DeprecationAttempts
+	policy.Backoff = conf.DeprecationBackoff
+	policy.ExpiryWindow = deprecationWindow
+
+	// weights of the dial score of the priority strategy
+	dialWeights, err := peering.ParseDialScoreWeights(conf.DialScoreWeights)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// generate the peering strategy (the targets replace the selected one)
+	var pStrategy peering.PeeringStrategy
+	if targets != nil {
+		var selector *peering.TargetSelector
+		selector, err = peering.NewTargetSelector(ipfsNode.Network(), targets)
+		if err == nil {
+			pStrategy, err = peering.NewBatchStrategy(ctx, ipfsNode.Network(), dbClient, selector)
+		}
+	} else {
+		pStrategy, err = peering.NewPeeringStrategy(
+			ctx,
+			conf.PeeringStrategy,
+			ipfsNode.Network(),
+			dbClient,
+			crawlSeed,
+			conf.PeeringQuota,
+			policy,
+			dialWeights,
+		)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// keep the backoff of the peers dialed before the last shutdown
+	if conf.ResumeDialQueue {
+		resumed, err := pStrategy.ResumeDialQueue()
+		if err != nil {
+			log.Warn(err)
+		} else {
+			log.Infof("resumed the dial queue of %d peers", resumed)
+		}
+	}
+	// snapshot the network at the end of each crawl round
+	snapshotter := monitor.NewRoundSnapshotter(ctx, runID, pStrategy.Rounds(), dbClient, dbClient)
+	peeringOpts := []peering.PeeringOption{
+		peering.WithPeeringStrategy(pStrategy),
+		peering.WithObserverMode(conf.ObserverMode),
+		peering.WithTimeoutPolicy(timeoutPolicy),
+	}
+	var rateCtl *peering.RateController
+	if conf.AdaptiveDialing {
+		rateCtl, err = peering.NewRateController(ctx, conf.MaxDialWorkers, conf.MaxDialRate)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		peeringOpts = append(peeringOpts, peering.WithRateController(rateCtl))
+	}
+	// Generate the PeeringService
+	peeringServ, err := peering.NewPeeringService(
+		ctx,
+		hostPool,
+		dbClient,
+		peeringOpts...,
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// keep the trusted/static peers always connected
+	staticPeers, err := peering.ParseStaticPeers(conf.StaticPeers)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	staticKeeper, err := peering.NewStaticPeersKeeper(ctx, host, dbClient, staticPeers)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// generate the monitor of our own connected set
+	eclipseMonitor, err := monitor.NewEclipseMonitor(
+		ctx,
+		host.Host(),
+		dbClient,
+		monitor.WithConcentrationThreshold(conf.EclipseThreshold),
+		monitor.WithMinMonitoredPeers(conf.EclipseMinPeers),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// soak mode for unattended long-running deployments
+	var soakServ *soak.SoakService
+	var watchdog *soak.Watchdog
+	if conf.Soak {
+		retention, err := time.ParseDuration(conf.SoakRetention)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		soakServ, err = soak.NewSoakService(
+			ctx,
+			string(ipfsNode.Network()),
+			dbClient,
+			soak.WithRetention(retention),
+			soak.WithExportDir(conf.SoakExportDir),
+		)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		watchdogTimeout, err := time.ParseDuration(conf.WatchdogTimeout)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		watchdog, err = soak.NewWatchdog(ctx, hostPool, watchdogTimeout)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// pruning (or rollup) of the rows older than the retention window of each table
+	retentionManager, err := newRetentionManager(ctx, dbClient, conf.Retention, conf.RetentionSchedule)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// client, country, asn and attnet diversity gauges refreshed from the DB
+	diversityExporter, err := newDiversityExporter(ctx, dbClient, conf.DiversityInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the runtime-tunable settings get reloaded on SIGHUP or through the reload endpoint
+	reloader, err := config.NewReloader(conf, func() (config.IpfsCrawlerConfig, error) {
+		return config.ReloadIpfsConfig(mainCtx)
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	handleRuntimeSettings(reloader, logLevels, rateCtl, func(c config.IpfsCrawlerConfig) runtimeSettings {
+		return runtimeSettings{c.LogLevel, c.LogLevels, c.MaxDialWorkers, c.MaxDialRate}
+	})
+
+	// generate the CrawlerBase
+	crawler := &IpfsCrawler{
+		ctx:       ctx,
+		cancel:    cancelModules,
+		cancelDB:  cancelDB,
+		RunID:     runID,
+		Host:      host,
+		Pool:      hostPool,
+		IpfsNode:  ipfsNode,
+		DB:        dbClient,
+		Disc:      disc,
+		Peering:   &peeringServ,
+		Gossipsub: gs,
+		IpLocator: ipLocator,
+		Metrics:   promethMetrics,
+		Resources: resourceMonitor,
+		Snapshots: snapshotter,
+		Static:    staticKeeper,
+		Soak:      soakServ,
+		Watchdog:  watchdog,
+		Retention: retentionManager,
+		Diversity: diversityExporter,
+		Identity:  keyManager,
+		Eclipse:   eclipseMonitor,
+		Reloader:  reloader,
+	}
+
+	// Register the metrics for the crawler and submodules
+	crawlMetricsMod := crawler.GetMetrics()
+	promethMetrics.AddMeticsModule(crawlMetricsMod)
+
+	discoveryMetricsMod := disc.GetMetrics()
+	promethMetrics.AddMeticsModule(discoveryMetricsMod)
+
+	pruneMetricsMod := peeringServ.GetMetrics()
+	promethMetrics.AddMeticsModule(pruneMetricsMod)
+	// the dial queue is exposed next to the metrics
+	promethMetrics.AddHandler(peering.DialQueuePath, peeringServ.StatusHandler())
+	promethMetrics.AddHandler(peering.DialQueuePath+"/", peeringServ.StatusHandler())
+	// as well as the log levels, that can be changed at runtime
+	promethMetrics.AddHandler(utils.LogLevelPath, logLevels.Handler())
+	// and the reload of the config, with the audit log of the changes
+	promethMetrics.AddHandler(config.ConfigReloadPath, reloader.Handler())
+	// and the random samples of the known peers for the measurement experiments
+	promethMetrics.AddHandler(sampling.SamplePath, sampling.NewSampler(ipfsNode.Network(), dbClient).Handler())
+	// and the REST API over the live state of the crawler and the recent aggregates of the DB
+	restAPI := api.NewAPI(string(ipfsNode.Network()), dbClient, hostPool)
+	promethMetrics.AddSummaryExporter(restAPI)
+	promethMetrics.AddHandler(api.Path, restAPI.Handler())
+
+	hostMetricsMod := host.GetMetrics()
+	promethMetrics.AddMeticsModule(hostMetricsMod)
+
+	eclipseMetricsMod := eclipseMonitor.GetMetrics()
+	promethMetrics.AddMeticsModule(eclipseMetricsMod)
+
+	if gs != nil {
+		promethMetrics.AddMeticsModule(gs.GetMetrics())
+	}
+
+	if diversityExporter != nil {
+		promethMetrics.AddMeticsModule(diversityExporter.GetMetrics())
+	}
+
+	// hit rate of the cache of located IPs
+	ipLocatorMetricsMod := ipLocator.GetMetrics()
+	promethMetrics.AddMeticsModule(ipLocatorMetricsMod)
+
+	// queue depth and flush latency of the DB writer
+	dbMetricsMod := dbClient.GetMetrics()
+	promethMetrics.AddMeticsModule(dbMetricsMod)
+
+	return crawler, nil
+}
+
+func (c *IpfsCrawler) GetMetrics() *metrics.MetricsModule {
+	m := newCrawlerMetrics()
+	metricsMod := composeCrawlerMetrics(c.DB, m)
+	// the waku peers are also aggregated by the services they serve
+	if c.IpfsNode.Network() == utils.WakuNetwork {
+		metricsMod.AddIndvMetric(getWakuServices(c.DB, m))
+	}
+	return metricsMod
+}
+
+// generate new CrawlerBase
+func (c *IpfsCrawler) Run() {
+	// IMPORTANT
+	// Set the VENV Variable for handling too many opened connections
+	os.Setenv("LIBP2P_SWARM_FD_LIMIT", "10000")
+
+	// answer the hellos of the Filecoin nodes (announced through identify, so before connecting to anyone)
+	if hello := c.IpfsNode.Hello(); hello != nil {
+		for _, h := range c.Pool.Hosts() {
+			hello.Serve(h.Host())
+		}
+	}
+
+	// initialization secuence for the crawler
+	c.IpLocator.Run()
+	c.Pool.Start()
+	c.Disc.Start()
+	c.Peering.Run()
+	c.Static.Run()
+	c.Metrics.Start()
+	c.Resources.Start()
+	c.Snapshots.Start()
+	c.Reloader.Run(c.ctx)
+	if c.Soak != nil {
+		c.Soak.Start()
+		c.Watchdog.Start()
+	}
+	if c.Retention != nil {
+		c.Retention.Start()
+	}
+	if c.Diversity != nil {
+		c.Diversity.Start()
+	}
+	if c.Identity != nil {
+		c.Identity.Start()
+	}
+}
+
+// Stalled returns a channel that gets closed if the watchdog considers that the crawler stalled
+// (it never gets closed if the watchdog isn't running)
+func (c *IpfsCrawler) Stalled() <-chan struct{} {
+	if c.Watchdog == nil {
+		return nil
+	}
+	return c.Watchdog.Stalled()
+}
+
+// IdentityRotated returns a channel that gets closed once the identity of the host was rotated
+// (it never gets closed if the identity isn't persisted or rotated)
+func (c *IpfsCrawler) IdentityRotated() <-chan struct{} {
+	if c.Identity == nil {
+		return nil
+	}
+	return c.Identity.Rotated()
+}
+
+// Close shuts the crawler down following the flow of the data, so that nothing gets lost on the way:
+// the discovery and the peering stop first (the peering persisting its dial queue), then the hosts
+// and the rest of the modules, and finally the DB writer flushes everything that they persisted
+func (c *IpfsCrawler) Close() {
+	log.Info("shutting down the crawler")
+	c.Disc.Stop()
+	c.Peering.Stop()
+	c.Resources.Stop()
+	c.Snapshots.Stop()
+	c.Pool.Close()
+	c.Metrics.Close()
+	c.cancel()
+	if c.Soak != nil {
+		c.Soak.Stop()
+	}
+	if c.Retention != nil {
+		c.Retention.Stop()
+	}
+	if c.Diversity != nil {
+		c.Diversity.Stop()
+	}
+	if err := c.DB.FinishCrawlerRun(c.RunID, time.Now()); err != nil {
+		log.Error(err)
+	}
+	c.DB.Close()
+	c.cancelDB()
+	log.Info("crawler shut down")
+}
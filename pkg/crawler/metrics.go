@@ -2,118 +2,256 @@ package crawler
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/migalabs/armiarma/pkg/db/storage"
 	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/pkg/errors"
 )
 
 var (
+	// slots over which the gossip arrival baselines are computed (one epoch)
+	BaselineSlots = 32
+	// window over which the address changes of the peers are counted
+	MovedPeersWindow = 24 * time.Hour
+
 	modName    = "crawler"
 	modDetails = "general metrics about the crawler"
-
-	// List of metrics that we are going to export
-	ClientDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "observed_client_distribution",
-		Help:      "Number of peers from each of the clients observed",
-	},
-		[]string{"client"},
-	)
-	VersionDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "observed_client_version_distribution",
-		Help:      "Number of peers from each of the clients versions observed",
-	},
-		[]string{"client_version"},
-	)
-	GeoDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "geographical_distribution",
-		Help:      "Number of peers from each of the crawled countries",
-	},
-		[]string{"country"},
-	)
-	NodeDistribution = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "node_distribution",
-		Help:      "Number of peers from each of the crawled countries",
-	})
-	DeprecatedCount = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "deprecated_nodes",
-		Help:      "Total number of deprecated peers",
-	})
-	OsDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "os_distribution",
-		Help:      "Distribution of OS used by the connected peers",
-	},
-		[]string{"os"},
-	)
-	ArchDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "arch_distribution",
-		Help:      "Architecture distribution of the active peers in the network",
-	},
-		[]string{"arch"},
-	)
-	HostedPeers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "hosted_peers_distribution",
-		Help:      "Distribution of nodes that are hosted on non-residential networks",
-	},
-		[]string{"ip_host"},
-	)
-	RttDist = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "observed_rtt_distribution",
-		Help:      "Distribution of RTT between our tool and nodes in the network",
-	},
-		[]string{"secs"},
-	)
-	IPDist = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "observed_ip_distribution",
-		Help:      "Distribution of IPs hosting nodes in the network",
-	},
-		[]string{"numbernodes"},
-	)
 )
 
+// crawlerMetrics are the collectors of the general metrics of the crawler, one set per crawler
+type crawlerMetrics struct {
+	ClientDistribution          *prometheus.GaugeVec
+	VersionDistribution         *prometheus.GaugeVec
+	MajorVersionDistribution    *prometheus.GaugeVec
+	GeoDistribution             *prometheus.GaugeVec
+	NodeDistribution            prometheus.Gauge
+	DeprecatedCount             prometheus.Gauge
+	OsDistribution              *prometheus.GaugeVec
+	ArchDistribution            *prometheus.GaugeVec
+	PlatformDistribution        *prometheus.GaugeVec
+	SecurityDistribution        *prometheus.GaugeVec
+	OriginDistribution          *prometheus.GaugeVec
+	DiscoverySourceDistribution *prometheus.GaugeVec
+	ProtocolDistribution        *prometheus.GaugeVec
+	HostedPeers                 *prometheus.GaugeVec
+	IpClassRatio                *prometheus.GaugeVec
+	RttDist                     *prometheus.GaugeVec
+	IPDist                      *prometheus.GaugeVec
+	NegotiationTime             *prometheus.GaugeVec
+	NegotiationFailures         *prometheus.GaugeVec
+	GossipArrivalBaseline       *prometheus.GaugeVec
+	WakuServiceDistribution     *prometheus.GaugeVec
+	MovedPeers                  *prometheus.GaugeVec
+}
+
+func newCrawlerMetrics() *crawlerMetrics {
+	return &crawlerMetrics{
+		ClientDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "observed_client_distribution",
+			Help:      "Number of peers from each of the clients observed",
+		},
+			[]string{"client"},
+		),
+		VersionDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "observed_client_version_distribution",
+			Help:      "Number of peers from each of the clients versions observed",
+		},
+			[]string{"client_version"},
+		),
+		MajorVersionDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "observed_client_major_version_distribution",
+			Help:      "Number of peers from each of the clients major versions observed",
+		},
+			[]string{"client_major_version"},
+		),
+		GeoDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "geographical_distribution",
+			Help:      "Number of peers from each of the crawled countries",
+		},
+			[]string{"country"},
+		),
+		NodeDistribution: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "node_distribution",
+			Help:      "Number of peers from each of the crawled countries",
+		}),
+		DeprecatedCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "deprecated_nodes",
+			Help:      "Total number of deprecated peers",
+		}),
+		OsDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "os_distribution",
+			Help:      "Distribution of OS used by the connected peers",
+		},
+			[]string{"os"},
+		),
+		ArchDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "arch_distribution",
+			Help:      "Architecture distribution of the active peers in the network",
+		},
+			[]string{"arch"},
+		),
+		PlatformDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "platform_distribution",
+			Help:      "Distribution of OS and architecture pairs used by the active peers in the network",
+		},
+			[]string{"platform"},
+		),
+		SecurityDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "security_distribution",
+			Help:      "Distribution of the security protocols (noise, tls) negotiated with the active peers in the network",
+		},
+			[]string{"security"},
+		),
+		OriginDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "origin_distribution",
+			Help:      "Distribution of the active peers by how we got to know them (discovery, inbound)",
+		},
+			[]string{"origin"},
+		),
+		DiscoverySourceDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "discovery_source_distribution",
+			Help:      "Number of active peers found by each of the discovery sources (a peer can be found by several)",
+		},
+			[]string{"source"},
+		),
+		ProtocolDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "protocol_distribution",
+			Help:      "Number of active peers that support each of the protocols announced through identify",
+		},
+			[]string{"protocol"},
+		),
+		HostedPeers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "hosted_peers_distribution",
+			Help:      "Distribution of nodes that are hosted on non-residential networks",
+		},
+			[]string{"ip_host"},
+		),
+		IpClassRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "ip_class_ratio",
+			Help:      "Share of the located active peers hosted at each class of network (hosting, mobile, residential)",
+		},
+			[]string{"class"},
+		),
+		RttDist: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "observed_rtt_distribution",
+			Help:      "Distribution of RTT between our tool and nodes in the network",
+		},
+			[]string{"secs"},
+		),
+		IPDist: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "observed_ip_distribution",
+			Help:      "Distribution of IPs hosting nodes in the network",
+		},
+			[]string{"numbernodes"},
+		),
+		NegotiationTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "protocol_negotiation_ms",
+			Help:      "Average time (ms) that the active peers of each client take to agree on each protocol through multistream-select",
+		},
+			[]string{"client", "protocol"},
+		),
+		NegotiationFailures: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "protocol_negotiation_failure_rate",
+			Help:      "Share of the negotiations of each protocol that failed with the active peers of each client",
+		},
+			[]string{"client", "protocol"},
+		),
+		GossipArrivalBaseline: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "gossip_arrival_time_in_slot",
+			Help:      "Average time in slot (secs) at which the messages arrive, from our static peers (propagation baseline) or from the rest of the network",
+		},
+			[]string{"msg_type", "source"},
+		),
+		WakuServiceDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "waku_service_distribution",
+			Help:      "Number of active peers that serve each of the Waku services (relay, store, filter...), out of their identify protocols",
+		},
+			[]string{"service"},
+		),
+		MovedPeers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "moved_peers",
+			Help:      "Number of known peers that showed up at a new IP, or at a new port of the same IP, in the last 24 hours",
+		},
+			[]string{"change"},
+		),
+	}
+}
+
 func (c *EthereumCrawler) GetMetrics() *metrics.MetricsModule {
+	m := newCrawlerMetrics()
+	metricsMod := composeCrawlerMetrics(c.DB, m)
+	metricsMod.AddIndvMetric(getGossipArrivalBaselines(c.DB, m))
+	return metricsMod
+}
+
+// composeCrawlerMetrics compiles the network-agnostic metrics that can be extracted from the DB
+func composeCrawlerMetrics(db storage.Client, m *crawlerMetrics) *metrics.MetricsModule {
 	metricsMod := metrics.NewMetricsModule(
 		modName,
 		modDetails,
 	)
 	// compose all the metrics
-	metricsMod.AddIndvMetric(c.clientDistributionMetrics())
-	metricsMod.AddIndvMetric(c.versionDistributionMetrics())
-	metricsMod.AddIndvMetric(c.geoDistributionMetrics())
-	metricsMod.AddIndvMetric(c.nodeDistributionMetrics())
-	metricsMod.AddIndvMetric(c.deprecatedNodeMetrics())
-	metricsMod.AddIndvMetric(c.getPeersOs())
-	metricsMod.AddIndvMetric(c.getPeersArch())
-	metricsMod.AddIndvMetric(c.getHostedPeers())
-	metricsMod.AddIndvMetric(c.getRTTDist())
-	metricsMod.AddIndvMetric(c.getIPDist())
+	metricsMod.AddIndvMetric(clientDistributionMetrics(db, m))
+	metricsMod.AddIndvMetric(versionDistributionMetrics(db, m))
+	metricsMod.AddIndvMetric(majorVersionDistributionMetrics(db, m))
+	metricsMod.AddIndvMetric(geoDistributionMetrics(db, m))
+	metricsMod.AddIndvMetric(nodeDistributionMetrics(db, m))
+	metricsMod.AddIndvMetric(deprecatedNodeMetrics(db, m))
+	metricsMod.AddIndvMetric(getPeersOs(db, m))
+	metricsMod.AddIndvMetric(getPeersArch(db, m))
+	metricsMod.AddIndvMetric(getPeersPlatform(db, m))
+	metricsMod.AddIndvMetric(getPeersSecurity(db, m))
+	metricsMod.AddIndvMetric(getPeersOrigin(db, m))
+	metricsMod.AddIndvMetric(getDiscoverySources(db, m))
+	metricsMod.AddIndvMetric(getPeersProtocols(db, m))
+	metricsMod.AddIndvMetric(getHostedPeers(db, m))
+	metricsMod.AddIndvMetric(getIpClassRatio(db, m))
+	metricsMod.AddIndvMetric(getRTTDist(db, m))
+	metricsMod.AddIndvMetric(getIPDist(db, m))
+	metricsMod.AddIndvMetric(getNegotiationStats(db, m))
+	metricsMod.AddIndvMetric(getMovedPeers(db, m))
 
 	return metricsMod
 }
 
-func (c *EthereumCrawler) clientDistributionMetrics() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(ClientDistribution)
+func clientDistributionMetrics(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.ClientDistribution)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		summary, err := c.DB.GetClientDistribution()
+		summary, err := db.GetClientDistribution()
 		if err != nil {
 			return nil, err
 		}
 		for cliName, cnt := range summary {
-			ClientDistribution.WithLabelValues(cliName).Set(float64(cnt.(int)))
+			m.ClientDistribution.WithLabelValues(cliName).Set(float64(cnt.(int)))
 		}
 		return summary, nil
 	}
@@ -128,18 +266,18 @@ func (c *EthereumCrawler) clientDistributionMetrics() *metrics.IndvMetrics {
 	return cliDist
 }
 
-func (c *EthereumCrawler) versionDistributionMetrics() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(VersionDistribution)
+func versionDistributionMetrics(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.VersionDistribution)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		summary, err := c.DB.GetVersionDistribution()
+		summary, err := db.GetVersionDistribution()
 		if err != nil {
 			return nil, err
 		}
 		for cliVer, cnt := range summary {
-			VersionDistribution.WithLabelValues(cliVer).Set(float64(cnt.(int)))
+			m.VersionDistribution.WithLabelValues(cliVer).Set(float64(cnt.(int)))
 		}
 		return summary, nil
 	}
@@ -154,19 +292,45 @@ func (c *EthereumCrawler) versionDistributionMetrics() *metrics.IndvMetrics {
 	return versDist
 }
 
-func (c *EthereumCrawler) geoDistributionMetrics() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(GeoDistribution)
+func majorVersionDistributionMetrics(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.MajorVersionDistribution)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		summary, err := db.GetMajorVersionDistribution()
+		if err != nil {
+			return nil, err
+		}
+		for cliVer, cnt := range summary {
+			m.MajorVersionDistribution.WithLabelValues(cliVer).Set(float64(cnt.(int)))
+		}
+		return summary, nil
+	}
+	versDist, err := metrics.NewIndvMetrics(
+		"client_major_version_distribution",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return versDist
+}
+
+func geoDistributionMetrics(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.GeoDistribution)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		summary, err := c.DB.GetGeoDistribution()
+		summary, err := db.GetGeoDistribution()
 		if err != nil {
 			fmt.Println(errors.Wrap(err, "unable to get GeoDist"))
 			return nil, err
 		}
 		for country, cnt := range summary {
-			GeoDistribution.WithLabelValues(country).Set(float64(cnt.(int)))
+			m.GeoDistribution.WithLabelValues(country).Set(float64(cnt.(int)))
 		}
 		return summary, nil
 	}
@@ -181,18 +345,18 @@ func (c *EthereumCrawler) geoDistributionMetrics() *metrics.IndvMetrics {
 	return versDist
 }
 
-func (c *EthereumCrawler) nodeDistributionMetrics() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(NodeDistribution)
+func nodeDistributionMetrics(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.NodeDistribution)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		peerLs, err := c.DB.GetNonDeprecatedPeers()
+		peerLs, err := db.GetNonDeprecatedPeers()
 		if err != nil {
 			return nil, err
 		}
 
-		NodeDistribution.Set(float64(len(peerLs)))
+		m.NodeDistribution.Set(float64(len(peerLs)))
 
 		return len(peerLs), nil
 	}
@@ -207,17 +371,17 @@ func (c *EthereumCrawler) nodeDistributionMetrics() *metrics.IndvMetrics {
 	return nodeDist
 }
 
-func (c *EthereumCrawler) deprecatedNodeMetrics() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(DeprecatedCount)
+func deprecatedNodeMetrics(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.DeprecatedCount)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		nodeCnt, err := c.DB.GetDeprecatedNodes()
+		nodeCnt, err := db.GetDeprecatedNodes()
 		if err != nil {
 			return nil, err
 		}
-		DeprecatedCount.Set(float64(nodeCnt))
+		m.DeprecatedCount.Set(float64(nodeCnt))
 		return nodeCnt, nil
 	}
 	depNodes, err := metrics.NewIndvMetrics(
@@ -231,18 +395,18 @@ func (c *EthereumCrawler) deprecatedNodeMetrics() *metrics.IndvMetrics {
 	return depNodes
 }
 
-func (c *EthereumCrawler) getPeersOs() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(OsDistribution)
+func getPeersOs(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.OsDistribution)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		osDist, err := c.DB.GetOsDistribution()
+		osDist, err := db.GetOsDistribution()
 		if err != nil {
 			return nil, err
 		}
 		for key, val := range osDist {
-			OsDistribution.WithLabelValues(key).Set(float64(val.(int)))
+			m.OsDistribution.WithLabelValues(key).Set(float64(val.(int)))
 		}
 		return osDist, nil
 	}
@@ -257,18 +421,18 @@ func (c *EthereumCrawler) getPeersOs() *metrics.IndvMetrics {
 	return osMetr
 }
 
-func (c *EthereumCrawler) getPeersArch() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(ArchDistribution)
+func getPeersArch(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.ArchDistribution)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		archDist, err := c.DB.GetArchDistribution()
+		archDist, err := db.GetArchDistribution()
 		if err != nil {
 			return nil, err
 		}
 		for key, val := range archDist {
-			ArchDistribution.WithLabelValues(key).Set(float64(val.(int)))
+			m.ArchDistribution.WithLabelValues(key).Set(float64(val.(int)))
 		}
 		return archDist, nil
 	}
@@ -283,18 +447,70 @@ func (c *EthereumCrawler) getPeersArch() *metrics.IndvMetrics {
 	return archMetr
 }
 
-func (c *EthereumCrawler) getHostedPeers() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(HostedPeers)
+func getPeersPlatform(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.PlatformDistribution)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		platformDist, err := db.GetPlatformDistribution()
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range platformDist {
+			m.PlatformDistribution.WithLabelValues(key).Set(float64(val.(int)))
+		}
+		return platformDist, nil
+	}
+	platformMetr, err := metrics.NewIndvMetrics(
+		"platform_distribution",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return platformMetr
+}
+
+func getPeersSecurity(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.SecurityDistribution)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		ipSummary, err := c.DB.GetHostingDistribution()
+		securityDist, err := db.GetSecurityDistribution()
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range securityDist {
+			m.SecurityDistribution.WithLabelValues(key).Set(float64(val.(int)))
+		}
+		return securityDist, nil
+	}
+	securityMetr, err := metrics.NewIndvMetrics(
+		"security_distribution",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return securityMetr
+}
+
+func getHostedPeers(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.HostedPeers)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		ipSummary, err := db.GetHostingDistribution()
 		if err != nil {
 			return nil, err
 		}
 		for key, val := range ipSummary {
-			HostedPeers.WithLabelValues(key).Set(float64(val.(int)))
+			m.HostedPeers.WithLabelValues(key).Set(float64(val.(int)))
 		}
 		return ipSummary, nil
 	}
@@ -309,19 +525,57 @@ func (c *EthereumCrawler) getHostedPeers() *metrics.IndvMetrics {
 	return ipHosting
 }
 
+func getIpClassRatio(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.IpClassRatio)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		classDist, err := db.GetIpClassDistribution()
+		if err != nil {
+			return nil, err
+		}
+		// the ratios are computed over the peers whose IP was already located
+		located := 0
+		for class, cnt := range classDist {
+			if class != "unknown" {
+				located += cnt
+			}
+		}
+		summary := make(map[string]interface{})
+		m.IpClassRatio.Reset()
+		for class, cnt := range classDist {
+			summary[class] = cnt
+			if class == "unknown" || located == 0 {
+				continue
+			}
+			m.IpClassRatio.WithLabelValues(class).Set(float64(cnt) / float64(located))
+		}
+		return summary, nil
+	}
+	ipClass, err := metrics.NewIndvMetrics(
+		"ip_class_ratio",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return ipClass
+}
 
-func (c *EthereumCrawler) getRTTDist() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(RttDist)
+func getRTTDist(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.RttDist)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		summary, err := c.DB.GetRTTDistribution()
+		summary, err := db.GetRTTDistribution()
 		if err != nil {
 			return nil, err
 		}
 		for key, val := range summary {
-			RttDist.WithLabelValues(key).Set(float64(val.(int)))
+			m.RttDist.WithLabelValues(key).Set(float64(val.(int)))
 		}
 		return summary, nil
 	}
@@ -336,18 +590,18 @@ func (c *EthereumCrawler) getRTTDist() *metrics.IndvMetrics {
 	return indvMetric
 }
 
-func (c *EthereumCrawler) getIPDist() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(IPDist)
+func getIPDist(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.IPDist)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
-		summary, err := c.DB.GetIPDistribution()
+		summary, err := db.GetIPDistribution()
 		if err != nil {
 			return nil, err
 		}
 		for key, val := range summary {
-			IPDist.WithLabelValues(key).Set(float64(val.(int)))
+			m.IPDist.WithLabelValues(key).Set(float64(val.(int)))
 		}
 		return summary, nil
 	}
@@ -361,3 +615,201 @@ func (c *EthereumCrawler) getIPDist() *metrics.IndvMetrics {
 	}
 	return indvMetric
 }
+
+func getGossipArrivalBaselines(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.GossipArrivalBaseline)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		baselines, err := db.GetGossipArrivalBaselines(BaselineSlots)
+		if err != nil {
+			return nil, err
+		}
+		for msgType, sources := range baselines {
+			for source, avgTime := range sources {
+				m.GossipArrivalBaseline.WithLabelValues(msgType, source).Set(avgTime)
+			}
+		}
+		return baselines, nil
+	}
+	baselineMetr, err := metrics.NewIndvMetrics(
+		"gossip_arrival_baseline",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return baselineMetr
+}
+
+func getWakuServices(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.WakuServiceDistribution)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		peersProtocols, err := db.GetActivePeersProtocols()
+		if err != nil {
+			return nil, err
+		}
+		serviceDist := make(map[string]int)
+		for _, protocols := range peersProtocols {
+			for _, service := range waku.Services(protocols) {
+				serviceDist[service]++
+			}
+		}
+		m.WakuServiceDistribution.Reset()
+		for service, count := range serviceDist {
+			m.WakuServiceDistribution.WithLabelValues(service).Set(float64(count))
+		}
+		return serviceDist, nil
+	}
+	serviceMetr, err := metrics.NewIndvMetrics(
+		"waku_service_distribution",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return serviceMetr
+}
+
+func getMovedPeers(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.MovedPeers)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		moved, err := db.GetMovedPeers(MovedPeersWindow)
+		if err != nil {
+			return nil, err
+		}
+		m.MovedPeers.WithLabelValues("ip").Set(float64(moved.MovedPeers))
+		m.MovedPeers.WithLabelValues("port").Set(float64(moved.PortChangedPeers))
+		return moved, nil
+	}
+	movedMetr, err := metrics.NewIndvMetrics(
+		"moved_peers",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return movedMetr
+}
+
+func getPeersOrigin(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.OriginDistribution)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		originDist, err := db.GetOriginDistribution()
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range originDist {
+			m.OriginDistribution.WithLabelValues(key).Set(float64(val.(int)))
+		}
+		return originDist, nil
+	}
+	originMetr, err := metrics.NewIndvMetrics(
+		"origin_distribution",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return originMetr
+}
+
+func getDiscoverySources(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.DiscoverySourceDistribution)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		srcDist, err := db.GetDiscoverySourceDistribution()
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range srcDist {
+			m.DiscoverySourceDistribution.WithLabelValues(key).Set(float64(val.(int)))
+		}
+		return srcDist, nil
+	}
+	srcMetr, err := metrics.NewIndvMetrics(
+		"discovery_source_distribution",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return srcMetr
+}
+
+func getPeersProtocols(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.ProtocolDistribution)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		protDist, err := db.GetProtocolDistribution()
+		if err != nil {
+			return nil, err
+		}
+		// protocols that nobody supports anymore shouldn't linger
+		m.ProtocolDistribution.Reset()
+		for key, val := range protDist {
+			m.ProtocolDistribution.WithLabelValues(key).Set(float64(val.(int)))
+		}
+		return protDist, nil
+	}
+	protMetr, err := metrics.NewIndvMetrics(
+		"protocol_distribution",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return protMetr
+}
+
+func getNegotiationStats(db storage.Client, m *crawlerMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.NegotiationTime)
+		reg.MustRegister(m.NegotiationFailures)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		stats, err := db.GetNegotiationStatsByClient()
+		if err != nil {
+			return nil, err
+		}
+		m.NegotiationTime.Reset()
+		m.NegotiationFailures.Reset()
+		summary := make(map[string]interface{}, len(stats))
+		for _, s := range stats {
+			m.NegotiationTime.WithLabelValues(s.Client, s.Protocol).Set(s.AvgMs)
+			m.NegotiationFailures.WithLabelValues(s.Client, s.Protocol).Set(s.FailureRate)
+			summary[s.Client+" "+s.Protocol] = s
+		}
+		return summary, nil
+	}
+	negMetr, err := metrics.NewIndvMetrics(
+		"protocol_negotiation",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return negMetr
+}
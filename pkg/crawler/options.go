@@ -0,0 +1,31 @@
+package crawler
+
+import (
+	"github.com/migalabs/armiarma/pkg/metrics"
+)
+
+// EthereumCrawlerOption sets how the crawler shares the process with the crawlers of other networks
+type EthereumCrawlerOption func(*ethereumCrawlerOptions) error
+
+type ethereumCrawlerOptions struct {
+	metricsServer *metrics.MetricsServer
+	networkName   string
+}
+
+// WithMetricsServer exposes the metrics of the crawler in the given server, shared with the
+// crawlers of the other networks, instead of in its own one
+func WithMetricsServer(server *metrics.MetricsServer) EthereumCrawlerOption {
+	return func(opts *ethereumCrawlerOptions) error {
+		opts.metricsServer = server
+		return nil
+	}
+}
+
+// WithNetworkName tags the data of the crawler in the DB with the name of its network,
+// so that the crawlers of several networks can share the DB
+func WithNetworkName(name string) EthereumCrawlerOption {
+	return func(opts *ethereumCrawlerOptions) error {
+		opts.networkName = name
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/discovery"
+	"github.com/migalabs/armiarma/pkg/discovery/dv4"
+	"github.com/migalabs/armiarma/pkg/discovery/dv5"
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/apis"
+	log "github.com/sirupsen/logrus"
+)
+
+// PortalCrawler walks the discv5 DHT identifying the Portal Network nodes through the pings
+// of its sub-protocols (there are no libp2p hosts nor peering involved)
+type PortalCrawler struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	DB        storage.Client
+	Disc      *discovery.Discovery
+	IpLocator *apis.IpLocator
+	Metrics   *metrics.PrometheusMetrics
+}
+
+func NewPortalCrawler(mainCtx *cli.Context, conf config.PortalCrawlerConfig) (*PortalCrawler, error) {
+	// Setup the configuration
+	log.SetLevel(utils.ParseLogLevel(conf.LogLevel))
+
+	ctx, cancel := context.WithCancel(mainCtx.Context)
+
+	// generate the central exporting service
+	promethMetrics := metrics.NewPrometheusMetrics(ctx, "portal", conf.MetricsIP, conf.MetricsPort)
+
+	// generate/connect to PSQL Database (there are no connections to backup)
+	dbClient, err := openDB(ctx, dbConfig{
+		network:           utils.PortalNetwork,
+		endpoint:          conf.PsqlEndpoint,
+		backupInterval:    24 * time.Hour,
+		skipBackup:        true,
+		persistConnEvents: true,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	privKey, _, err := loadHostIdentity(ctx, utils.PortalNetwork, conf.PrivateKey, "", "", dbClient)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// create an ip-locator instance
+	ipLocator := apis.NewIpLocator(ctx, dbClient)
+
+	// create a new portal discovery to walk the discv5 DHT
+	recrawlInterval, err := time.ParseDuration(conf.RecrawlInterval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the bootnodes are given in their enr: representation
+	bootnodes, err := dv4.ParseBootnodes(conf.Bootnodes)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	portalServ, err := dv5.NewPortalDiscovery(
+		ctx,
+		privKey,
+		bootnodes,
+		conf.Port,
+		dv5.WithPortalUserAgent(conf.UserAgent),
+		dv5.WithPortalSubNetworks(conf.SubNetworks),
+		dv5.WithPortalPingSettings(conf.PingWorkers, recrawlInterval),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	disc, err := discovery.NewDiscovery(
+		ctx,
+		dbClient,
+		ipLocator,
+		discovery.WithSource(discovery.SourceDv5, portalServ),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	crawler := &PortalCrawler{
+		ctx:       ctx,
+		cancel:    cancel,
+		DB:        dbClient,
+		Disc:      disc,
+		IpLocator: ipLocator,
+		Metrics:   promethMetrics,
+	}
+
+	// Register the metrics for the crawler (client, geo, etc. distributions of the identified nodes)
+	promethMetrics.AddMeticsModule(composeCrawlerMetrics(dbClient, newCrawlerMetrics()))
+	// the peers notified by the discovery
+	promethMetrics.AddMeticsModule(disc.GetMetrics())
+	// as well as the queue depth and flush latency of the DB writer
+	promethMetrics.AddMeticsModule(dbClient.GetMetrics())
+
+	return crawler, nil
+}
+
+func (c *PortalCrawler) Run() {
+	// initialization secuence for the crawler
+	c.IpLocator.Run()
+	c.Disc.Start()
+	c.Metrics.Start()
+}
+
+func (c *PortalCrawler) Close() {
+	c.Disc.Stop()
+	c.DB.Close()
+	c.Metrics.Close()
+	c.cancel()
+}
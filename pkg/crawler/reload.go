@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/config"
+	"github.com/migalabs/armiarma/pkg/peering"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// runtimeSettings are the settings shared by the crawlers that can be changed without a restart
+type runtimeSettings struct {
+	LogLevel       string
+	LogLevels      string
+	MaxDialWorkers int
+	MaxDialRate    float64
+}
+
+// handleRuntimeSettings registers in the reloader the appliers of the log levels and the limits
+// of the adaptive dialing (if enabled)
+func handleRuntimeSettings[C any](
+	reloader *config.Reloader[C],
+	logLevels *utils.ModuleLevels,
+	rateCtl *peering.RateController,
+	settings func(C) runtimeSettings) {
+
+	reloader.Handle("log-level", func(next C) error {
+		return logLevels.SetLevel(utils.GlobalLogModule, utils.ParseLogLevel(settings(next).LogLevel))
+	})
+	reloader.Handle("log-levels", func(next C) error {
+		levels, err := utils.ParseModuleLevels(settings(next).LogLevels)
+		if err != nil {
+			return err
+		}
+		return logLevels.SetModuleLevels(levels)
+	})
+	setDialLimits := func(next C) error {
+		if rateCtl == nil {
+			return errors.New("adaptive dialing is disabled")
+		}
+		s := settings(next)
+		return rateCtl.SetLimits(s.MaxDialWorkers, s.MaxDialRate)
+	}
+	reloader.Handle("max-dial-workers", setDialLimits)
+	reloader.Handle("max-dial-rate", setDialLimits)
+}
@@ -0,0 +1,25 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/retention"
+)
+
+// newRetentionManager returns the manager that applies the given retention policy on the given
+// schedule (nil if there is no policy)
+func newRetentionManager(ctx context.Context, db storage.Client, policy, schedule string) (*retention.Manager, error) {
+	rules, err := retention.ParsePolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	sched, err := retention.ParseSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+	return retention.NewManager(ctx, db, rules, sched)
+}
@@ -0,0 +1,25 @@
+package crawler
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/hosts"
+)
+
+// parseTimeoutPolicy composes the timeouts of the host and the peering from the configured ones
+func parseTimeoutPolicy(dial, identify, reqResp, classOverrides string) (hosts.TimeoutPolicy, error) {
+	var timeouts hosts.Timeouts
+	var err error
+	if timeouts.Dial, err = time.ParseDuration(dial); err != nil {
+		return hosts.TimeoutPolicy{}, errors.Wrap(err, "unable to parse the dial timeout")
+	}
+	if timeouts.Identify, err = time.ParseDuration(identify); err != nil {
+		return hosts.TimeoutPolicy{}, errors.Wrap(err, "unable to parse the identify timeout")
+	}
+	if timeouts.ReqResp, err = time.ParseDuration(reqResp); err != nil {
+		return hosts.TimeoutPolicy{}, errors.Wrap(err, "unable to parse the reqresp timeout")
+	}
+	return hosts.ParseTimeoutPolicy(timeouts, classOverrides)
+}
@@ -0,0 +1,192 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	WriteTimeout = 30 * time.Second
+
+	// layout of the DateTime64(3) values in the JSONEachRow format
+	timeLayout = "2006-01-02 15:04:05.000"
+)
+
+// tables that are mirrored into ClickHouse, with the same columns as in Postgres
+var tableSchemas = map[string]string{
+	"conn_events": `
+		CREATE TABLE IF NOT EXISTS %s.conn_events(
+			peer_id String,
+			direction LowCardinality(String),
+			conn_time Int64,
+			latency Int64,
+			disconn_time Int64,
+			identified Bool,
+			addr_family LowCardinality(String),
+			error String,
+			run_id Nullable(Int32)
+		)
+		ENGINE = MergeTree
+		ORDER BY (conn_time, peer_id)`,
+	"eth_attestations": `
+		CREATE TABLE IF NOT EXISTS %s.eth_attestations(
+			msg_id String,
+			sender String,
+			subnet Int32,
+			slot Int64,
+			arrival_time DateTime64(3),
+			time_in_slot Float32,
+			val_pubkey String,
+			committee_index Int64,
+			aggregation_bit Int64,
+			block_root String,
+			target_epoch Int64,
+			run_id Nullable(Int32)
+		)
+		ENGINE = ReplacingMergeTree
+		ORDER BY (slot, msg_id)`,
+	"eth_aggregates": `
+		CREATE TABLE IF NOT EXISTS %s.eth_aggregates(
+			msg_id String,
+			sender String,
+			slot Int64,
+			arrival_time DateTime64(3),
+			time_in_slot Float32,
+			aggregator_idx Int64,
+			committee_index Int64,
+			participants Int64,
+			block_root String,
+			target_epoch Int64,
+			run_id Nullable(Int32)
+		)
+		ENGINE = ReplacingMergeTree
+		ORDER BY (slot, msg_id)`,
+}
+
+// columns added to the mirrored tables after their first version
+var addedColumns = []string{
+	"run_id Nullable(Int32)",
+}
+
+// Sink mirrors the rows of the high-volume tables into ClickHouse through its HTTP interface,
+// so that the analytical queries over them don't load Postgres
+type Sink struct {
+	ctx      context.Context
+	endpoint string
+	database string
+	user     string
+	password string
+	client   *http.Client
+}
+
+func NewSink(ctx context.Context, endpoint, database, user, password string) (*Sink, error) {
+	if endpoint == "" || database == "" {
+		return nil, errors.New("clickhouse endpoint and database are required")
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, errors.Wrap(err, "invalid clickhouse endpoint")
+	}
+	return &Sink{
+		ctx:      ctx,
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/",
+		database: database,
+		user:     user,
+		password: password,
+		client:   &http.Client{Timeout: WriteTimeout},
+	}, nil
+}
+
+// InitTables creates the database and the mirrored tables if they don't exist
+func (s *Sink) InitTables() error {
+	log.Info("init clickhouse mirror tables")
+	err := s.exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", s.database), nil)
+	if err != nil {
+		return err
+	}
+	for table, schema := range tableSchemas {
+		err = s.exec(fmt.Sprintf(schema, s.database), nil)
+		if err != nil {
+			return errors.Wrap(err, "initializing clickhouse table "+table)
+		}
+		for _, column := range addedColumns {
+			err = s.exec(fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS %s", s.database, table, column), nil)
+			if err != nil {
+				return errors.Wrap(err, "updating the columns of clickhouse table "+table)
+			}
+		}
+	}
+	return nil
+}
+
+// MirrorRows inserts the given rows into the ClickHouse copy of the table
+func (s *Sink) MirrorRows(table string, columns []string, rows [][]interface{}) error {
+	if _, ok := tableSchemas[table]; !ok {
+		return errors.Errorf("table %s is not mirrored into clickhouse", table)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	body, err := encodeRows(columns, rows)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s (%s) FORMAT JSONEachRow", s.database, table, strings.Join(columns, ", "))
+	return s.exec(query, body)
+}
+
+// encodeRows composes the JSONEachRow body of the insert (one JSON object per row)
+func encodeRows(columns []string, rows [][]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, row := range rows {
+		if len(row) != len(columns) {
+			return nil, errors.Errorf("row with %d values for %d columns", len(row), len(columns))
+		}
+		obj := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			switch v := row[i].(type) {
+			case time.Time:
+				obj[col] = v.UTC().Format(timeLayout)
+			default:
+				obj[col] = v
+			}
+		}
+		err := enc.Encode(obj)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to encode row")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Sink) exec(query string, body []byte) error {
+	params := url.Values{}
+	params.Set("query", query)
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.endpoint+"?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "unable to compose clickhouse request")
+	}
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to reach clickhouse")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("clickhouse query failed with status %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
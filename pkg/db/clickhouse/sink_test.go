@@ -0,0 +1,44 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MirrorRows(t *testing.T) {
+	var query, user, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		user, _, _ = r.BasicAuth()
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(context.Background(), server.URL, "armiarma", "crawler", "secret")
+	require.NoError(t, err)
+
+	columns := []string{"msg_id", "slot", "arrival_time"}
+	rows := [][]interface{}{
+		{"0xaa", int64(10), time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC)},
+		{"0xbb", int64(11), time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC)},
+	}
+	require.NoError(t, sink.MirrorRows("eth_attestations", columns, rows))
+	require.Equal(t, "INSERT INTO armiarma.eth_attestations (msg_id, slot, arrival_time) FORMAT JSONEachRow", query)
+	require.Equal(t, "crawler", user)
+	require.Equal(t,
+		`{"arrival_time":"2024-01-02 03:04:05.006","msg_id":"0xaa","slot":10}`+"\n"+
+			`{"arrival_time":"2024-01-02 03:04:06.000","msg_id":"0xbb","slot":11}`+"\n",
+		body)
+
+	// only the high-volume tables are mirrored
+	require.Error(t, sink.MirrorRows("peer_info", columns, rows))
+	// and every row needs a value per column
+	require.Error(t, sink.MirrorRows("eth_attestations", columns, [][]interface{}{{"0xcc"}}))
+}
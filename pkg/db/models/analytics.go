@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerSummary is the latest known state of a peer, as returned by the read API of the DB
+type PeerSummary struct {
+	PeerID          peer.ID   `json:"peer_id"`
+	Network         string    `json:"network"`
+	IP              string    `json:"ip"`
+	Country         string    `json:"country"`
+	ClientName      string    `json:"client_name"`
+	ClientVersion   string    `json:"client_version"`
+	UserAgent       string    `json:"user_agent"`
+	Latency         int64     `json:"latency_ms"`
+	Deprecated      bool      `json:"deprecated"`
+	LastActivity    time.Time `json:"last_activity"`
+	LastConnAttempt time.Time `json:"last_conn_attempt"`
+	LastError       string    `json:"last_error"`
+}
+
+// ClientCount is the number of peers running a client (and version)
+type ClientCount struct {
+	Client  string `json:"client"`
+	Version string `json:"version"`
+	Peers   int64  `json:"peers"`
+}
+
+// PeerTimelineEvent is a connection to a peer, in the order it happened
+type PeerTimelineEvent struct {
+	ConnTime    time.Time     `json:"conn_time"`
+	DisconnTime time.Time     `json:"disconn_time"`
+	Direction   string        `json:"direction"`
+	Latency     time.Duration `json:"latency"`
+	Identified  bool          `json:"identified"`
+	Error       string        `json:"error"`
+}
+
+// TopicRate is the rate of the unique messages (and their duplicates) of a gossip topic
+type TopicRate struct {
+	Topic         string  `json:"topic"`
+	Messages      int64   `json:"messages"`
+	Duplicates    int64   `json:"duplicates"`
+	MsgsPerMinute float64 `json:"msgs_per_minute"`
+}
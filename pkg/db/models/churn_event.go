@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+type ChurnEventType string
+
+const (
+	// actions of the crawler
+	ChurnDial       ChurnEventType = "dial"
+	ChurnDisconnect ChurnEventType = "disconnect"
+	// reactions of the remote peer
+	ChurnRemoteDisconnect ChurnEventType = "remote-disconnect" // the peer closed the connection while we kept it open
+	ChurnGoodbye          ChurnEventType = "goodbye"           // Value is the reason of the goodbye
+	ChurnRedial           ChurnEventType = "redial"            // the peer dialed us while we kept it disconnected
+	ChurnScore            ChurnEventType = "score"             // Value is the gossipsub score at the end of the connected phase
+)
+
+// ChurnEvent is either an action of the connection churn experiment over one of the sampled peers
+// or a reaction of the peer to it
+type ChurnEvent struct {
+	RunID     int
+	Cycle     int
+	PeerID    peer.ID
+	Type      ChurnEventType
+	Timestamp time.Time
+	Delay     time.Duration // since the start of the phase of the cycle
+	Value     float64
+	Error     string
+}
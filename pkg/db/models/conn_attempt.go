@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 type AttemptStatus string
@@ -32,4 +33,12 @@ type ConnectionAttempt struct {
 	Error       string
 	Deprecable  bool
 	LeftNetwork bool
+	// why the peer was deprecated (if Deprecable)
+	DeprecationReason string
+	// addresses that were dialed in the attempt
+	Addrs []ma.Multiaddr
+	// peer that answered at the addresses instead of the dialed one (if any)
+	MismatchedPeer peer.ID
+	// raw error that the code of the error was taken from
+	RawError string
 }
@@ -50,6 +50,7 @@ type ConnInfo struct {
 	ConnTime   time.Time
 	Latency    time.Duration
 	Identified bool
+	AddrFamily string // "ip4"/"ip6"
 	Att        map[string]interface{}
 	Error      string
 }
@@ -76,6 +77,7 @@ func (c *ConnEvent) AddConnInfo(connInfo ConnInfo) {
 	c.ConnTime = connInfo.ConnTime
 	c.Latency = connInfo.Latency
 	c.Identified = connInfo.Identified
+	c.AddrFamily = connInfo.AddrFamily
 
 	// filter in the Error to avoid overwriting important info
 	// only write the error if it's none or err_requesting_metadata
@@ -110,3 +112,10 @@ func (c *ConnEvent) IsReadyToPersist() bool {
 		c.DiscTime != (time.Time{}) &&
 		c.ConnDuration != time.Duration(uint64(0)))
 }
+
+// ConnSession is the time that a peer stayed connected on one of its connection events
+type ConnSession struct {
+	PeerID string
+	Start  time.Time
+	End    time.Time
+}
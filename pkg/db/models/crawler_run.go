@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func NewCrawlerRun(network string, hostID peer.ID, signedPeerRecord, observedAddrs bool, seed int64) *CrawlerRun {
+	return &CrawlerRun{
+		Network:          network,
+		HostID:           hostID,
+		StartTime:        time.Now(),
+		SignedPeerRecord: signedPeerRecord,
+		ObservedAddrs:    observedAddrs,
+		Seed:             seed,
+	}
+}
+
+// CrawlerRun tracks the metadata of each of the executions of the crawler,
+// including the settings that affect how remote peers perceive the crawler
+type CrawlerRun struct {
+	ID        int
+	Network   string
+	HostID    peer.ID
+	StartTime time.Time
+
+	// identify mode of the host
+	SignedPeerRecord bool
+	ObservedAddrs    bool
+
+	// seed of the discovery walks and peer selection, to replay the exploration of the run
+	Seed int64
+
+	// provenance of the run: version of the crawler, and the settings (without secrets) it ran with
+	Version    string
+	ConfigHash string
+	Config     string
+}
+
+// WithProvenance sets the version of the crawler and the settings of the run
+func (r *CrawlerRun) WithProvenance(version, configHash, config string) *CrawlerRun {
+	r.Version = version
+	r.ConfigHash = configHash
+	r.Config = config
+	return r
+}
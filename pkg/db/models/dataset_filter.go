@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DatasetFilter narrows the rows read from one of the exported datasets: within the [Since, Until)
+// time range (open-ended if any of the times is zero), of the given peer (any if empty), and
+// paginated in chronological order (all the rows if the limit is zero)
+type DatasetFilter struct {
+	Since  time.Time
+	Until  time.Time
+	PeerID string
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DialQueueState is the backoff state of a peer of the dial queue, persisted on shutdown so that
+// the next run resumes the dials where the previous one left them
+type DialQueueState struct {
+	PeerID       peer.ID
+	Position     int
+	Pending      bool // not dialed yet in the round that was interrupted
+	LastError    string
+	Delay        string
+	DelayDegree  int
+	Failures     int
+	LastAttempt  time.Time // zero if the peer was never dialed
+	LastPositive time.Time // start of the deprecation window of the peer
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DialScoreInput gathers what the DB knows about a peer to prioritize its dials
+type DialScoreInput struct {
+	PeerID peer.ID
+	// last time that we saw an ENR of the peer (zero if we never did)
+	LastENR time.Time
+	// connections ever established with the peer
+	Connections int64
+	// whether we ever identified the peer (its client is known)
+	Identified bool
+	// latest reputation of the peer (nil if it was never computed)
+	Reputation *float64
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DiscoverySource tracks that a peer was found by one of the discovery sources (dv5, dht, static, db...)
+type DiscoverySource struct {
+	PeerID    peer.ID
+	Source    string
+	Timestamp time.Time
+}
+
+func NewDiscoverySource(peerID peer.ID, source string) *DiscoverySource {
+	return &DiscoverySource{
+		PeerID:    peerID,
+		Source:    source,
+		Timestamp: time.Now(),
+	}
+}
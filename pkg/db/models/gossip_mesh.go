@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// GossipMeshSnapshot is the state of our mesh in a topic, and how it changed since the previous snapshot
+type GossipMeshSnapshot struct {
+	Timestamp  time.Time
+	Topic      string
+	Mesh       []peer.ID // peers in our mesh
+	TopicPeers int       // peers subscribed to the topic that we are connected to
+	Grafted    []peer.ID // peers that we grafted since the previous snapshot
+	Pruned     []peer.ID // peers that we pruned since the previous snapshot
+	// control messages of the topic that we received since the previous snapshot
+	RecvGraft int64
+	RecvPrune int64
+	RecvIHave int64
+}
+
+// GossipControlCounts is the number of control messages that we sent to a peer since the previous snapshot
+type GossipControlCounts struct {
+	Timestamp time.Time
+	PeerID    peer.ID
+	Graft     int64
+	Prune     int64
+	IHave     int64
+	IWant     int64
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// GossipMessage is the propagation of a gossip message: when and from which peer we got it
+// for the first time, and how many times it was delivered to us again afterwards
+type GossipMessage struct {
+	MsgID      string
+	Topic      string
+	FirstSeen  time.Time
+	FirstPeer  peer.ID
+	Duplicates int64
+	LastSeen   time.Time // arrival of the last duplicate
+}
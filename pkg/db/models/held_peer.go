@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// HeldPeer is the record of one of the peers that the connection holder keeps connected
+// for long periods, with the keepalives and re-dials that it took to keep it
+type HeldPeer struct {
+	RunID         int
+	PeerID        peer.ID
+	Stratum       string // client, country or subnet it was chosen for
+	HeldSince     time.Time
+	LastUpdate    time.Time
+	Connected     bool
+	Uptime        time.Duration // connected time since it was held
+	Drops         int
+	Redials       int
+	FailedRedials int
+	Pings         int
+	FailedPings   int
+	LastRTT       time.Duration
+	Released      bool // replaced after exhausting its keepalive budget
+}
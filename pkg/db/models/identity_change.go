@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	NewIdentity     = "new"      // first identity of the crawler (nothing was persisted)
+	RotatedIdentity = "rotation" // the previous identity expired
+)
+
+// IdentityChange tracks each time the crawler changes its peer identity,
+// so that the sessions of the different identities can be stitched together
+type IdentityChange struct {
+	Network    string
+	PrevPeerID string // empty if there wasn't any previous identity
+	PeerID     string
+	Reason     string
+	Timestamp  time.Time
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	Discv5Identity = "discv5" // node ID of a discovered ENR, linked to the peer ID of its pubkey
+	Libp2pIdentity = "libp2p" // peer ID of an identified peer, linked to the node ID of its pubkey
+	DialIdentity   = "dial"   // peer that answered at the addresses of an ENR
+
+	// the addresses of the ENR are served by a different key than the one of the ENR
+	PeerIDMismatch = "peer_id_mismatch"
+	// the stored node ID or peer ID don't derive from the stored pubkey
+	PubkeyMismatch = "pubkey_mismatch"
+)
+
+// IdentityLink cross-links the discv5 node ID and the libp2p peer ID of a node, which derive from
+// the same secp256k1 pubkey unless the link is flagged as inconsistent
+type IdentityLink struct {
+	NodeID string `json:"node_id"`
+	PeerID string `json:"peer_id"`
+	Source string `json:"source"`
+	// why the link is inconsistent (empty if both identities match)
+	Inconsistency string    `json:"inconsistency,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+func NewIdentityLink(nodeID, peerID, source, inconsistency string) *IdentityLink {
+	return &IdentityLink{
+		NodeID:        nodeID,
+		PeerID:        peerID,
+		Source:        source,
+		Inconsistency: inconsistency,
+		Timestamp:     time.Now(),
+	}
+}
@@ -43,5 +43,28 @@ type ApiResp struct {
 
 type IpInfo struct {
 	IpApiMsg
+	// when the IP was located, the cached info is valid until the ExpirationTime
+	FetchedAt      time.Time
 	ExpirationTime time.Time
+	// number of the autonomous system (0 if unknown) and class of the network (hosting, mobile, residential)
+	ASN     int
+	IpClass string
+	// reverse DNS name of the IP and cloud provider hosting it (empty if unknown or not tagged)
+	Rdns          string
+	CloudProvider string
+	// whether the IP is a Tor exit, VPN endpoint or proxy, and the source that reported it
+	Anonymous       bool
+	AnonymousSource string
+	// sanity check of the location against the measured RTT (zero if not checked yet)
+	MinRTT         time.Duration
+	GeoConfidence  float64
+	GeoImplausible bool
+}
+
+// IpGeoCheck is the result of cross-checking the location of an IP with the lowest RTT measured to it
+type IpGeoCheck struct {
+	IP          string
+	MinRTT      time.Duration
+	Confidence  float64 // [0, 1], below 1 the RTT is too low for the distance to the location
+	Implausible bool
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	AdvertisedAddr = "advertised" // discovered or announced by the peer through identify
+	InboundAddr    = "inbound"    // remote address of a connection that the peer opened
+	DialedAddr     = "dialed"     // address that we dialed
+)
+
+// MultiaddrObservation tracks that the multiaddrs of a peer were seen (or dialed) at a given time,
+// composing the address history of the peer
+type MultiaddrObservation struct {
+	PeerID    peer.ID
+	Addrs     []ma.Multiaddr
+	Source    string
+	Dialed    bool
+	Success   bool // only meaningful for dialed addresses
+	Timestamp time.Time
+}
+
+func NewMultiaddrObservation(peerID peer.ID, addrs []ma.Multiaddr, source string, dialed, success bool) *MultiaddrObservation {
+	return &MultiaddrObservation{
+		PeerID:    peerID,
+		Addrs:     addrs,
+		Source:    source,
+		Dialed:    dialed,
+		Success:   success,
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerAddress tracks that a peer was seen at the given IP and port at a given time.
+// The identity of the peer (its peer ID, and its node ID for the ENR networks) comes from its key,
+// so the same node is linked across its address changes instead of looking like a new peer
+type PeerAddress struct {
+	PeerID peer.ID
+	// node ID of the ENR of the peer (empty if it wasn't discovered through an ENR)
+	NodeID    string
+	IP        string
+	Port      int
+	Timestamp time.Time
+}
+
+func NewPeerAddress(peerID peer.ID, nodeID, ip string, port int) *PeerAddress {
+	return &PeerAddress{
+		PeerID:    peerID,
+		NodeID:    nodeID,
+		IP:        ip,
+		Port:      port,
+		Timestamp: time.Now(),
+	}
+}
+
+// PeerAddressRecord is each of the addresses that a peer had, as returned by the read API of the DB
+type PeerAddressRecord struct {
+	IP           string    `json:"ip"`
+	Port         int       `json:"port"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Observations int64     `json:"observations"`
+}
+
+// MovedPeers summarizes the address changes of the peers within a window
+type MovedPeers struct {
+	Window string `json:"window"`
+	// peers seen within the window
+	Peers int64 `json:"peers"`
+	// peers that showed up at a new IP within the window
+	MovedPeers int64 `json:"moved_peers"`
+	// peers that kept their IP but showed up at a new port within the window
+	PortChangedPeers int64 `json:"port_changed_peers"`
+	// new addresses of the already known peers within the window
+	Moves int64 `json:"moves"`
+}
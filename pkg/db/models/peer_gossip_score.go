@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerGossipScore is a snapshot of the gossipsub v1.1 score that we give to a peer, and of its components
+type PeerGossipScore struct {
+	PeerID             peer.ID
+	Timestamp          time.Time
+	Score              float64
+	TimeInMesh         time.Duration // P1
+	FirstDeliveries    float64       // P2
+	MeshDeliveries     float64       // P3
+	InvalidDeliveries  float64       // P4
+	AppSpecificScore   float64       // P5
+	IPColocationFactor float64       // P6
+	BehaviourPenalty   float64       // P7
+}
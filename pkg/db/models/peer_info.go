@@ -2,12 +2,14 @@ package models
 
 import (
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/migalabs/armiarma/pkg/utils"
 	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,6 +21,10 @@ const (
 	// say that it left the network
 	// unless discv5 says the opposite
 	LeftNetworkTime = 24 * time.Hour * 60
+
+	// how we first got to know the peer
+	DiscoveredOrigin = "discovery" // found through any of the discovery services
+	InboundOrigin    = "inbound"   // it dialed us before being discovered
 )
 
 type RemoteConnectablePeer struct {
@@ -49,6 +55,10 @@ type HostInfo struct {
 
 	// network
 	Network utils.NetworkType
+	Origin  string
+
+	// stored but never dialed (i.e. discovered nodes of other forks)
+	NotDialable bool
 
 	// Indetification
 	PeerInfo PeerInfo
@@ -89,8 +99,12 @@ func WithIPAndPorts(ip string, port int) RemoteHostOptions {
 		h.IP = ip
 		h.Port = port
 
-		// Compose Multiaddress from data
-		mAddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/%s/tcp/%d", ip, port))
+		// Compose Multiaddress from data, in the family of the IP
+		parsedIP := net.ParseIP(ip)
+		if parsedIP == nil {
+			return errors.Errorf("invalid ip %q", ip)
+		}
+		mAddr, err := ma.NewMultiaddr(fmt.Sprintf("/%s/%s/tcp/%d", utils.GetIPFamily(parsedIP), parsedIP.String(), port))
 		if err != nil {
 			return err
 		}
@@ -126,6 +140,36 @@ func WithMultiaddress(mAddrs []ma.Multiaddr) RemoteHostOptions {
 	}
 }
 
+func WithOrigin(origin string) RemoteHostOptions {
+	return func(h *HostInfo) error {
+		h.Lock()
+		defer h.Unlock()
+
+		h.Origin = origin
+		return nil
+	}
+}
+
+// WithNotDialable stores the host without adding it to the dial queue
+func WithNotDialable() RemoteHostOptions {
+	return func(h *HostInfo) error {
+		h.Lock()
+		defer h.Unlock()
+
+		h.NotDialable = true
+		return nil
+	}
+}
+
+// ReplaceMultiaddrs overwrites the multiaddresses of the host, updating the public IP and port
+// (i.e. to replace the ephemeral address of an inbound connection with the advertised listen addrs)
+func (h *HostInfo) ReplaceMultiaddrs(mAddrs []ma.Multiaddr) {
+	h.Lock()
+	h.MAddrs = make([]ma.Multiaddr, 0, len(mAddrs))
+	h.Unlock()
+	WithMultiaddress(mAddrs)(h)
+}
+
 // ComposeAddrsInfo returns the PeerId and Multiaddres in the peer.AddrsInfo format
 // Essential for libp2p.Connect() operation
 func (h *HostInfo) ComposeAddrsInfo() peer.AddrInfo {
@@ -176,6 +220,7 @@ type PeerInfo struct {
 	ProtocolVersion string
 	Protocols       []string
 	Latency         time.Duration
+	Security        string // security protocol negotiated with the peer (noise, tls)
 }
 
 func NewEmptyPeerInfo() *PeerInfo {
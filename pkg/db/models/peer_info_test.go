@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func Test_WithIPAndPorts(t *testing.T) {
+	pid := peer.ID("test-peer")
+
+	// ipv4 host
+	hInfo := NewHostInfo(pid, utils.EthereumNetwork, WithIPAndPorts("1.2.3.4", 9000))
+	require.Len(t, hInfo.MAddrs, 1)
+	require.Equal(t, "/ip4/1.2.3.4/tcp/9000", hInfo.MAddrs[0].String())
+
+	// ipv6-only host
+	hInfo = NewHostInfo(pid, utils.EthereumNetwork, WithIPAndPorts("2001:db8::1", 9000))
+	require.Len(t, hInfo.MAddrs, 1)
+	require.Equal(t, "/ip6/2001:db8::1/tcp/9000", hInfo.MAddrs[0].String())
+	require.Equal(t, "2001:db8::1", hInfo.IP)
+	require.Equal(t, 9000, hInfo.Port)
+
+	// ipv4-mapped ipv6 addresses are dialed as ipv4
+	hInfo = NewHostInfo(pid, utils.EthereumNetwork, WithIPAndPorts("::ffff:1.2.3.4", 9000))
+	require.Len(t, hInfo.MAddrs, 1)
+	require.Equal(t, "/ip4/1.2.3.4/tcp/9000", hInfo.MAddrs[0].String())
+
+	// invalid ips don't compose any multiaddr
+	hInfo = NewHostInfo(pid, utils.EthereumNetwork, WithIPAndPorts("not-an-ip", 9000))
+	require.Empty(t, hInfo.MAddrs)
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerQuality summarizes the gossip and req/resp behaviour of a peer into a single score (0-1),
+// the components that couldn't be measured for the peer are left as nil
+type PeerQuality struct {
+	PeerID    peer.ID
+	Timestamp time.Time
+
+	GossipScore        *float64
+	DuplicateRatio     *float64
+	InvalidMsgs        *int64
+	ReqRespReliability *float64
+
+	Score float64
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// SignedPeerRecord is the signed peer record (routing record) that a peer shared through identify,
+// which certifies the addresses of the peer at the given sequence number
+type SignedPeerRecord struct {
+	PeerID    peer.ID
+	Seq       uint64
+	Addrs     []ma.Multiaddr
+	Envelope  []byte // marshalled signed envelope, to verify the record offline
+	Timestamp time.Time
+}
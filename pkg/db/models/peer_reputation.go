@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ReputationInput gathers what the DB knows about the behaviour of a peer to compute its reputation
+type ReputationInput struct {
+	PeerID        peer.ID
+	DialAttempts  int64
+	DialSuccesses int64
+	// connections with the peer, and the ones on which it got identified
+	Connections int64
+	Identified  int64
+	// latest gossipsub score and invalid messages of the peer (nil if never measured)
+	GossipScore *float64
+	InvalidMsgs *int64
+}
+
+// PeerReputation aggregates the dial reliability, identification success and gossip behaviour of a
+// peer into a single score (0-1), the components that couldn't be measured for the peer are left as nil
+type PeerReputation struct {
+	PeerID    peer.ID
+	Timestamp time.Time
+
+	DialReliability *float64
+	IdentifySuccess *float64
+	GossipBehaviour *float64
+	InvalidMsgs     *int64
+
+	Score float64
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// SampledPeer is a known peer that can be part of a measurement sample, with the
+// attributes that the sample can be stratified by
+type SampledPeer struct {
+	PeerID  peer.ID  `json:"peer_id"`
+	Client  string   `json:"client"`
+	Country string   `json:"country"`
+	Addrs   []string `json:"addrs"`
+}
+
+// PeerSample is a random subset of the known peers, that can be reproduced with the same
+// seed over the same set of known peers
+type PeerSample struct {
+	Timestamp  time.Time      `json:"timestamp"`
+	Network    string         `json:"network"`
+	Seed       int64          `json:"seed"`
+	Stratify   string         `json:"stratify"`
+	Size       int            `json:"size"`
+	Candidates int            `json:"candidates"`
+	Peers      []*SampledPeer `json:"peers"`
+}
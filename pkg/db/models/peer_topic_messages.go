@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerTopicMessages is the number of gossip messages that a peer delivered us on a topic
+// since the previous report
+type PeerTopicMessages struct {
+	PeerID    peer.ID
+	Topic     string
+	Messages  int64
+	Timestamp time.Time
+}
+
+func NewPeerTopicMessages(peerID peer.ID, topic string, messages int64) *PeerTopicMessages {
+	return &PeerTopicMessages{
+		PeerID:    peerID,
+		Topic:     topic,
+		Messages:  messages,
+		Timestamp: time.Now(),
+	}
+}
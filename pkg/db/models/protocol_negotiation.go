@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ProtocolNegotiation is the result of proposing a protocol to a peer through multistream-select,
+// and how long the peer took to agree on it
+type ProtocolNegotiation struct {
+	PeerID    peer.ID
+	Protocol  string
+	Duration  time.Duration
+	Error     string // empty if the protocol was agreed
+	Timestamp time.Time
+}
+
+func NewProtocolNegotiation(peerID peer.ID, protocol string, duration time.Duration, err error) *ProtocolNegotiation {
+	negotiation := &ProtocolNegotiation{
+		PeerID:    peerID,
+		Protocol:  protocol,
+		Duration:  duration,
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		negotiation.Error = err.Error()
+	}
+	return negotiation
+}
+
+// Failed returns whether the peer didn't agree on the protocol
+func (n *ProtocolNegotiation) Failed() bool {
+	return n.Error != ""
+}
+
+// NegotiationStats aggregates the protocol negotiations with the peers of a client
+type NegotiationStats struct {
+	Client      string
+	Protocol    string
+	Attempts    int64
+	Failures    int64
+	AvgMs       float64 // of the agreed negotiations
+	FailureRate float64
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// ResourceUsage is a snapshot of the resources that the crawler was using at a given time of a run
+type ResourceUsage struct {
+	RunID     int
+	Timestamp time.Time
+
+	// Process
+	CpuSeconds     float64 // accumulated CPU time (user + system) since the start of the process
+	MemSysBytes    uint64  // memory obtained from the OS
+	HeapAllocBytes uint64
+	Goroutines     int
+
+	// Host
+	BytesIn   int64 // accumulated since the start of the run
+	BytesOut  int64
+	OpenConns int
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// RoundSnapshot is a compact summary of the network at the end of a crawl round,
+// so that the evolution of the network can be plotted without aggregating the raw events
+type RoundSnapshot struct {
+	RunID           int
+	Round           int64
+	Start           time.Time
+	End             time.Time
+	AttemptedPeers  int
+	TotalPeers      int
+	ActivePeers     int
+	DeprecatedPeers int
+	// shares (0-1) of the active peers / of the attempted peers for the errors
+	ClientShares map[string]float64
+	GeoShares    map[string]float64
+	ErrorShares  map[string]float64
+}
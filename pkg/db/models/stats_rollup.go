@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// StatsRollup is the aggregated value of a crawl statistic (i.e. number of peers of a client) over a day
+type StatsRollup struct {
+	Day       time.Time
+	Network   string
+	Dimension string // client, version, country, os, arch, security
+	Key       string
+	Value     int64
+}
@@ -46,9 +46,11 @@ func (c *DBClient) getActivePeers() ([]int, error) {
 			id,
 			peer_id
 		FROM peer_info
-		WHERE deprecated = 'false' and attempted = 'true' and client_name IS NOT NULL and to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		WHERE deprecated = 'false' and attempted = 'true' and client_name IS NOT NULL and to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY') and
+		      ($2 = '' OR network_name = $2)
 		`,
 		LastActivityValidRange,
+		c.networkName,
 	)
 	if err != nil {
 		return activePeers, errors.Wrap(err, "unable to retrieve active peer's ids")
@@ -104,9 +104,18 @@ func (q *QueryBatch) persistBatch() error {
 	logEntry.Trace("readed all the result of the queries inside the batch")
 	// check if there was any error
 	if qerr.Error() != noQueryResult {
-		log.Errorf("unable to persist betch because an error on row %d \n %+v \n %+v", cnt, rows, err)
-		errRollback := tx.Commit(q.ctx)
-		log.Errorf("rolled back with err %+v", errRollback)
+		log.Errorf("unable to persist betch because an error on row %d \n %+v \n %+v", cnt, rows, qerr)
+		// none of the queries of the batch is applied, so the merge rules never see half of it
+		batchResults.Close()
+		errRollback := tx.Rollback(q.ctx)
+		if errRollback != nil {
+			log.Errorf("rolled back with err %+v", errRollback)
+		}
+		return qerr
+	}
+	err = batchResults.Close()
+	if err != nil {
+		tx.Rollback(q.ctx)
 		return err
 	}
 	return tx.Commit(q.ctx)
@@ -0,0 +1,63 @@
+package postgresql
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) DropBlocklistTable() error {
+	log.Info("dropping table blocklist")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE blocklist;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitBlocklistTable() error {
+	log.Info("init blocklist table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS blocklist(
+				entry TEXT NOT NULL,
+				reason TEXT,
+				added_time TIMESTAMP NOT NULL DEFAULT NOW(),
+
+				PRIMARY KEY(entry)
+			);
+		`,
+	)
+	return err
+}
+
+// GetBlocklistEntries returns the CIDRs, IPs, ASNs, and peer IDs that the crawler shouldn't connect to
+func (c *DBClient) GetBlocklistEntries() ([]string, error) {
+	log.Debug("fetching blocklist entries")
+	entries := make([]string, 0)
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT entry
+		FROM blocklist;
+		`,
+	)
+	if err != nil {
+		return entries, errors.Wrap(err, "unable to fetch blocklist entries")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry string
+		err = rows.Scan(&entry)
+		if err != nil {
+			return entries, errors.Wrap(err, "unable to parse fetched blocklist entry")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package postgresql
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) DropChurnEventsTable() error {
+	log.Info("dropping table churn_events")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE churn_events;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitChurnEventsTable() error {
+	log.Info("init churn_events table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS churn_events(
+				id SERIAL,
+				run_id INT NOT NULL,
+				cycle INT NOT NULL,
+				peer_id TEXT NOT NULL,
+				event_type TEXT NOT NULL,
+				timestamp TIMESTAMP NOT NULL,
+				delay_ms BIGINT NOT NULL,
+				value REAL NOT NULL,
+				error TEXT NOT NULL,
+
+				PRIMARY KEY(id)
+			);
+		`,
+	)
+	return err
+}
+
+// InsertChurnEvent records an action or reaction of the connection churn experiment
+func (c *DBClient) InsertChurnEvent(ev *models.ChurnEvent) (query string, args []interface{}) {
+	log.Trace("inserting churn event of peer ", ev.PeerID.String())
+
+	query = `
+		INSERT INTO churn_events(
+			run_id,
+			cycle,
+			peer_id,
+			event_type,
+			timestamp,
+			delay_ms,
+			value,
+			error)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8);
+	`
+
+	args = append(args, ev.RunID)
+	args = append(args, ev.Cycle)
+	args = append(args, ev.PeerID.String())
+	args = append(args, string(ev.Type))
+	args = append(args, ev.Timestamp)
+	args = append(args, ev.Delay.Milliseconds())
+	args = append(args, ev.Value)
+	args = append(args, ev.Error)
+
+	return query, args
+}
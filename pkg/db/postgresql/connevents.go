@@ -18,6 +18,7 @@ func (c *DBClient) InitConnEventTable() error {
 			latency BIGINT,
 			disconn_time BIGINT NOT NULL,
 			identified BOOL,
+			addr_family TEXT,
 			error TEXT NOT NULL,
 
 			PRIMARY KEY (id)
@@ -28,6 +29,15 @@ func (c *DBClient) InitConnEventTable() error {
 		return errors.Wrap(err, "initializing conn_events table")
 	}
 
+	// add the columns that weren't there in previous versions of the table
+	_, err = c.psqlPool.Exec(c.ctx, `
+		ALTER TABLE conn_events
+			ADD COLUMN IF NOT EXISTS addr_family TEXT;
+		`)
+	if err != nil {
+		return errors.Wrap(err, "updating the columns of conn_events table")
+	}
+
 	return nil
 }
 
@@ -42,17 +52,44 @@ func (c *DBClient) InsertNewConnEvent(connEv *models.ConnEvent) (query string, a
 			latency,
 			disconn_time,
 			identified,
-			error)
-			VALUES ($1,$2,$3,$4,$5,$6,$7)
+			addr_family,
+			error,
+			run_id)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
 		`
 
-	args = append(args, connEv.PeerID.String())
-	args = append(args, models.DirectionIndexToString(connEv.Direction))
-	args = append(args, connEv.ConnTime.Unix())
-	args = append(args, connEv.Latency.Milliseconds())
-	args = append(args, connEv.DiscTime.Unix())
-	args = append(args, connEv.Identified)
-	args = append(args, connEv.Error)
+	args = connEventRow(connEv, c.RunID())
 
 	return query, args
 }
+
+// ConnEventsCopyTable persists the conn_events through COPY, as they are the highest-volume rows
+var ConnEventsCopyTable = CopyTable{
+	Name: "conn_events",
+	Columns: []string{
+		"peer_id",
+		"direction",
+		"conn_time",
+		"latency",
+		"disconn_time",
+		"identified",
+		"addr_family",
+		"error",
+		"run_id",
+	},
+}
+
+// connEventRow returns the values of the conn_event in the order of the ConnEventsCopyTable columns
+func connEventRow(connEv *models.ConnEvent, runID interface{}) []interface{} {
+	return []interface{}{
+		connEv.PeerID.String(),
+		models.DirectionIndexToString(connEv.Direction),
+		connEv.ConnTime.Unix(),
+		connEv.Latency.Milliseconds(),
+		connEv.DiscTime.Unix(),
+		connEv.Identified,
+		connEv.AddrFamily,
+		connEv.Error,
+		runID,
+	}
+}
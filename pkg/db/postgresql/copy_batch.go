@@ -0,0 +1,140 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// CopyTable describes a high-volume table whose rows are persisted through COPY instead of
+// single-row inserts
+type CopyTable struct {
+	Name    string
+	Columns []string
+	// the rows of tables with unique keys are copied into a staging table first, so that the
+	// duplicated rows are skipped instead of aborting the entire COPY
+	SkipDuplicates bool
+}
+
+// RowsMirror receives a copy of the rows of the high-volume tables once they are flushed
+// (i.e. an analytical DB that mirrors them)
+type RowsMirror interface {
+	MirrorRows(table string, columns []string, rows [][]interface{}) error
+}
+
+// CopyBatch aggregates the rows of a single table until they are flushed with a single COPY
+type CopyBatch struct {
+	ctx     context.Context
+	pgxPool *pgxpool.Pool
+	table   CopyTable
+	rows    [][]interface{}
+	size    int
+}
+
+func NewCopyBatch(ctx context.Context, pgxPool *pgxpool.Pool, table CopyTable, batchSize int) *CopyBatch {
+	return &CopyBatch{
+		ctx:     ctx,
+		pgxPool: pgxPool,
+		table:   table,
+		rows:    make([][]interface{}, 0, batchSize),
+		size:    batchSize,
+	}
+}
+
+func (b *CopyBatch) Table() string {
+	return b.table.Name
+}
+
+func (b *CopyBatch) Columns() []string {
+	return b.table.Columns
+}
+
+// Rows returns the rows added since the last flush
+func (b *CopyBatch) Rows() [][]interface{} {
+	return b.rows
+}
+
+func (b *CopyBatch) IsReadyToPersist() bool {
+	return len(b.rows) >= b.size
+}
+
+func (b *CopyBatch) AddRow(row ...interface{}) {
+	b.rows = append(b.rows, row)
+}
+
+func (b *CopyBatch) Len() int {
+	return len(b.rows)
+}
+
+func (b *CopyBatch) PersistBatch() error {
+	logEntry := log.WithFields(log.Fields{
+		"mod":   "copy-persister",
+		"table": b.table.Name,
+	})
+	var err error
+persistRetryLoop:
+	for i := 0; i <= MaxRetries; i++ {
+		t := time.Now()
+		err = b.persistBatch()
+		duration := time.Since(t)
+		switch err {
+		case nil:
+			logEntry.Debugf("copied %d rows in %s", b.Len(), duration)
+			break persistRetryLoop
+		default:
+			logEntry.Debugf("attempt numb %d failed %s", i+1, err.Error())
+		}
+	}
+	b.rows = make([][]interface{}, 0, b.size)
+	return errors.Wrap(err, "unable to copy rows into "+b.table.Name)
+}
+
+func (b *CopyBatch) persistBatch() error {
+	if b.Len() == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := b.pgxPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	target := b.table.Name
+	if b.table.SkipDuplicates {
+		target = "staging_" + b.table.Name
+		_, err = tx.Exec(ctx, fmt.Sprintf(
+			`CREATE TEMP TABLE IF NOT EXISTS %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DELETE ROWS;`,
+			target, b.table.Name,
+		))
+		if err != nil {
+			return errors.Wrap(err, "unable to create the staging table")
+		}
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{target}, b.table.Columns, pgx.CopyFromRows(b.rows))
+	if err != nil {
+		return err
+	}
+
+	if b.table.SkipDuplicates {
+		columns := strings.Join(b.table.Columns, ", ")
+		_, err = tx.Exec(ctx, fmt.Sprintf(
+			`INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT DO NOTHING;`,
+			b.table.Name, columns, columns, target,
+		))
+		if err != nil {
+			return errors.Wrap(err, "unable to move the staged rows")
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -28,11 +28,15 @@ func (db *DBClient) GetClientDistribution() (map[string]interface{}, error) {
 			deprecated = 'false' and 
 		    attempted = 'true' and 
 		    client_name IS NOT NULL and 
+		    ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+		    ($3 = '' OR peer_info.network_name = $3) and 
 		    to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		GROUP BY client_name
 		ORDER BY count DESC;
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	)
 	// make sure we close the rows and we free the connection/session
 	defer rows.Close()
@@ -70,11 +74,15 @@ func (db *DBClient) GetVersionDistribution() (map[string]interface{}, error) {
 			deprecated = 'false' and 
 			attempted = 'true' and 
 			client_name IS NOT NULL and 
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			($3 = '' OR peer_info.network_name = $3) and 
 			to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		GROUP BY client_name, client_version
 		ORDER BY client_name DESC, cnt DESC;
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	)
 	// make sure we close the rows and we free the connection/session
 	defer rows.Close()
@@ -96,7 +104,95 @@ func (db *DBClient) GetVersionDistribution() (map[string]interface{}, error) {
 	return verDist, nil
 }
 
+// GetMajorVersionDistribution returns the number of active peers per client and major version (e.g. lighthouse_v5)
+func (db *DBClient) GetMajorVersionDistribution() (map[string]interface{}, error) {
+	log.Debug("fetching client major version distribution metrics")
+	verDist := make(map[string]interface{}, 0)
+
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT client_name,
+			client_version_major,
+			count(client_version_major) as cnt
+		FROM peer_info
+		WHERE 
+			deprecated = 'false' and 
+			attempted = 'true' and 
+			client_name IS NOT NULL and 
+			client_version_major IS NOT NULL and 
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			($3 = '' OR peer_info.network_name = $3) and 
+			to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY client_name, client_version_major
+		ORDER BY client_name DESC, cnt DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return verDist, errors.Wrap(err, "unable to fetch client major version distribution")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cliName string
+		var major int
+		var count int
+		err = rows.Scan(&cliName, &major, &count)
+		if err != nil {
+			return verDist, errors.Wrap(err, "unable to parse fetch client major version distribution")
+		}
+		verDist[fmt.Sprintf("%s_v%d", cliName, major)] = count
+	}
+
+	return verDist, nil
+}
+
+// GetClientVersionAdoption returns the percentage of the active peers of the given client
+// that are running a version equal or higher than major.minor.patch
+func (db *DBClient) GetClientVersionAdoption(cliName string, major, minor, patch int) (float64, error) {
+	log.Debugf("fetching adoption of %s >= v%d.%d.%d", cliName, major, minor, patch)
+
+	var total, adopted int
+	err := db.psqlPool.QueryRow(
+		db.ctx,
+		`
+		SELECT
+			count(*) as total,
+			count(*) FILTER (
+				WHERE (client_version_major, client_version_minor, client_version_patch) >= ($2, $3, $4)
+			) as adopted
+		FROM peer_info
+		WHERE 
+			deprecated = 'false' and 
+			attempted = 'true' and 
+			client_name = $1 and 
+			client_version_major IS NOT NULL and 
+			($6 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			($7 = '' OR peer_info.network_name = $7) and 
+			to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($5 * INTERVAL '1 DAY');
+		`,
+		cliName,
+		major,
+		minor,
+		patch,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	).Scan(&total, &adopted)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to fetch client version adoption")
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(adopted) * 100 / float64(total), nil
+}
+
 // Basic call over the whole list of non-deprecated peers
+// (locations that don't match the RTT measured to the peer are accounted as unknown)
 func (db *DBClient) GetGeoDistribution() (map[string]interface{}, error) {
 	log.Debug("fetching client distribution metrics")
 	geoDist := make(map[string]interface{}, 0)
@@ -110,18 +206,22 @@ func (db *DBClient) GetGeoDistribution() (map[string]interface{}, error) {
 		FROM (
 			SELECT peer_info.peer_id, 
 				ips.ip,
-				ips.country_code
+				CASE WHEN ips.geo_implausible THEN 'unknown' ELSE ips.country_code END as country_code
 			FROM peer_info
 			RIGHT JOIN ips on peer_info.ip = ips.ip
 			WHERE deprecated = 'false' and 
 			      attempted = 'true' and 
 			      client_name IS NOT NULL and 
+			      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			      ($3 = '' OR peer_info.network_name = $3) and 
 			      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		) as aux 
 		GROUP BY country_code
 		ORDER BY cnt DESC;
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	)
 	// make sure we close the rows and we free the connection/session
 	defer rows.Close()
@@ -154,11 +254,15 @@ func (db *DBClient) GetOsDistribution() (map[string]interface{}, error) {
 		WHERE deprecated='false' and 
 		      attempted='true' and 
 		      client_name IS NOT NULL and 
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+		      ($3 = '' OR peer_info.network_name = $3) and 
 		      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		GROUP BY client_os
 		ORDER BY nodes DESC;
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	)
 	if err != nil {
 		return summary, err
@@ -184,11 +288,15 @@ func (db *DBClient) GetArchDistribution() (map[string]interface{}, error) {
 		WHERE deprecated='false' and 
 		      attempted='true' and 
 		      client_name IS NOT NULL and 
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+		      ($3 = '' OR peer_info.network_name = $3) and 
 		      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		GROUP BY client_arch
 		ORDER BY nodes DESC;
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	)
 	if err != nil {
 		return summary, err
@@ -202,6 +310,49 @@ func (db *DBClient) GetArchDistribution() (map[string]interface{}, error) {
 	return summary, nil
 }
 
+// GetPlatformDistribution returns the number of active peers per OS and architecture pair (e.g. linux_x86_64)
+func (db *DBClient) GetPlatformDistribution() (map[string]interface{}, error) {
+	summary := make(map[string]interface{}, 0)
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			client_os,
+			client_arch,
+			count(*) as nodes
+		FROM peer_info
+		WHERE deprecated='false' and 
+		      attempted='true' and 
+		      client_name IS NOT NULL and 
+		      client_os IS NOT NULL and 
+		      client_arch IS NOT NULL and 
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+		      ($3 = '' OR peer_info.network_name = $3) and 
+		      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY client_os, client_arch
+		ORDER BY nodes DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return summary, errors.Wrap(err, "unable to fetch platform distribution")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var os, arch string
+		var count int
+		err = rows.Scan(&os, &arch, &count)
+		if err != nil {
+			return summary, errors.Wrap(err, "unable to parse fetch platform distribution")
+		}
+		summary[os+"_"+arch] = count
+	}
+	return summary, nil
+}
+
 func (db *DBClient) GetHostingDistribution() (map[string]interface{}, error) {
 	summary := make(map[string]interface{})
 	// get the number of mobile hosts
@@ -225,10 +376,14 @@ func (db *DBClient) GetHostingDistribution() (map[string]interface{}, error) {
 			      attempted = 'true' and 
 			      client_name IS NOT NULL and 
 			      ips.mobile='true' and 
+			      ($2 OR pi.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			      ($3 = '' OR pi.network_name = $3) and 
 			      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		) as aux
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	).Scan(&mobile)
 	if err != nil {
 		return summary, err
@@ -255,10 +410,14 @@ func (db *DBClient) GetHostingDistribution() (map[string]interface{}, error) {
 			WHERE pi.deprecated='false' and 
 			      attempted = 'true' and 
 			      client_name IS NOT NULL and ips.proxy='true' and 
+			      ($2 OR pi.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			      ($3 = '' OR pi.network_name = $3) and 
 			      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		) as aux
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	).Scan(&proxy)
 	if err != nil {
 		return summary, err
@@ -286,10 +445,14 @@ func (db *DBClient) GetHostingDistribution() (map[string]interface{}, error) {
 			      attempted = 'true' and 
 			      client_name IS NOT NULL and 
 			      ips.hosting='true' and 
+			      ($2 OR pi.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			      ($3 = '' OR pi.network_name = $3) and 
 			      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		) as aux		
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	).Scan(&hosted)
 	if err != nil {
 		return summary, err
@@ -298,6 +461,51 @@ func (db *DBClient) GetHostingDistribution() (map[string]interface{}, error) {
 	return summary, nil
 }
 
+// GetIpClassDistribution returns the number of active peers hosted at each class of network
+// (hosting, mobile, residential), the peers whose IP wasn't located yet are accounted as unknown
+func (db *DBClient) GetIpClassDistribution() (map[string]int, error) {
+	log.Debug("fetching ip class distribution")
+	classDist := make(map[string]int)
+
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			COALESCE(ips.ip_class, 'unknown') as ip_class,
+			count(*) as peers
+		FROM peer_info
+		LEFT JOIN ips ON peer_info.ip = ips.ip
+		WHERE
+			deprecated = 'false' and
+			attempted = 'true' and
+			client_name IS NOT NULL and
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and
+			($3 = '' OR peer_info.network_name = $3) and
+			to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY ip_class;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return classDist, errors.Wrap(err, "unable to fetch ip class distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var class string
+		var count int
+		err = rows.Scan(&class, &count)
+		if err != nil {
+			return classDist, errors.Wrap(err, "unable to parse fetched ip class distribution")
+		}
+		classDist[class] = count
+	}
+	return classDist, nil
+}
+
 func (db *DBClient) GetRTTDistribution() (map[string]interface{}, error) {
 	summary := make(map[string]interface{}, 0)
 
@@ -325,12 +533,16 @@ func (db *DBClient) GetRTTDistribution() (map[string]interface{}, error) {
 			FROM peer_info 
 			WHERE deprecated=false and 
 			      client_name IS NOT NULL and 
+			      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			      ($3 = '' OR peer_info.network_name = $3) and 
 			      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 		) as t 
 		GROUP BY t.latency 
 		ORDER BY nodes DESC;	
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	)
 	if err != nil {
 		return summary, err
@@ -367,6 +579,8 @@ func (db *DBClient) GetIPDistribution() (map[string]interface{}, error) {
 			FROM peer_info 
 			WHERE deprecated = false and 
 			      client_name IS NOT NULL and 
+			      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+			      ($3 = '' OR peer_info.network_name = $3) and 
 			      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
 			GROUP BY ip 
 			ORDER BY nodes DESC 
@@ -375,6 +589,8 @@ func (db *DBClient) GetIPDistribution() (map[string]interface{}, error) {
 		ORDER BY number_of_ips DESC;	
 		`,
 		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
 	)
 	if err != nil {
 		return summary, err
@@ -394,3 +610,132 @@ func (db *DBClient) GetIPDistribution() (map[string]interface{}, error) {
 	}
 	return summary, nil
 }
+
+// GetSecurityDistribution returns the distribution of the security protocols (noise, tls) negotiated with the active peers
+func (db *DBClient) GetSecurityDistribution() (map[string]interface{}, error) {
+	summary := make(map[string]interface{}, 0)
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			security_protocol,
+			count(*) as nodes
+		FROM peer_info
+		WHERE deprecated='false' and 
+		      attempted='true' and 
+		      security_protocol IS NOT NULL and 
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+		      ($3 = '' OR peer_info.network_name = $3) and 
+		      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY security_protocol
+		ORDER BY nodes DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return summary, errors.Wrap(err, "unable to fetch security distribution")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var security string
+		var count int
+		err = rows.Scan(&security, &count)
+		if err != nil {
+			return summary, errors.Wrap(err, "unable to parse fetch security distribution")
+		}
+		summary[security] = count
+	}
+	return summary, nil
+}
+
+// GetOriginDistribution returns how many of the active peers were discovered, and how many only reached us through inbound connections
+func (db *DBClient) GetOriginDistribution() (map[string]interface{}, error) {
+	summary := make(map[string]interface{}, 0)
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			origin,
+			count(*) as nodes
+		FROM peer_info
+		WHERE deprecated='false' and 
+		      attempted='true' and 
+		      origin IS NOT NULL and 
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and 
+		      ($3 = '' OR peer_info.network_name = $3) and 
+		      to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY origin
+		ORDER BY nodes DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return summary, errors.Wrap(err, "unable to fetch origin distribution")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var origin string
+		var count int
+		err = rows.Scan(&origin, &count)
+		if err != nil {
+			return summary, errors.Wrap(err, "unable to parse fetch origin distribution")
+		}
+		summary[origin] = count
+	}
+	return summary, nil
+}
+
+// GetPeerSetDistributions returns how the given set of peers (i.e. the ones we are connected to) is
+// distributed across clients, countries, and ASNs
+func (db *DBClient) GetPeerSetDistributions(peerIDs []string) (clients, countries, asns map[string]int, err error) {
+	log.Debug("fetching client, country, and asn distributions of a peer set")
+	clients = make(map[string]int)
+	countries = make(map[string]int)
+	asns = make(map[string]int)
+
+	if len(peerIDs) == 0 {
+		return clients, countries, asns, nil
+	}
+
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			COALESCE(NULLIF(peer_info.client_name, ''), 'unknown') as client,
+			COALESCE(NULLIF(ips.country_code, ''), 'unknown') as country,
+			COALESCE(NULLIF(ips.as_raw, ''), 'unknown') as asn
+		FROM peer_info
+		LEFT JOIN ips ON peer_info.ip = ips.ip
+		WHERE peer_info.peer_id = ANY($1) and 
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and
+		      ($3 = '' OR peer_info.network_name = $3);
+		`,
+		peerIDs,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return clients, countries, asns, errors.Wrap(err, "unable to fetch peer set distributions")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var client, country, asn string
+		err = rows.Scan(&client, &country, &asn)
+		if err != nil {
+			return clients, countries, asns, errors.Wrap(err, "unable to parse peer set distributions")
+		}
+		clients[client]++
+		countries[country]++
+		asns[asn]++
+	}
+
+	return clients, countries, asns, nil
+}
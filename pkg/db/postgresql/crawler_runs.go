@@ -0,0 +1,215 @@
+package postgresql
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) DropCrawlerRunsTable() error {
+	log.Info("dropping table crawler_runs")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE crawler_runs;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitCrawlerRunsTable() error {
+	log.Info("init crawler_runs table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS crawler_runs(
+				id SERIAL,
+				network TEXT NOT NULL,
+				peer_id TEXT NOT NULL,
+				start_time TIMESTAMP NOT NULL,
+				signed_peer_record BOOL NOT NULL,
+				observed_addrs BOOL NOT NULL,
+				seed BIGINT,
+
+				PRIMARY KEY(id)
+			);
+		`,
+	)
+	if err != nil {
+		return err
+	}
+
+	// add the columns that weren't there in previous versions of the table
+	_, err = c.psqlPool.Exec(
+		c.ctx,
+		`
+			ALTER TABLE crawler_runs
+				ADD COLUMN IF NOT EXISTS seed BIGINT,
+				ADD COLUMN IF NOT EXISTS stop_time TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS version TEXT,
+				ADD COLUMN IF NOT EXISTS config_hash TEXT,
+				ADD COLUMN IF NOT EXISTS config JSONB;
+		`,
+	)
+	return err
+}
+
+// runEventTables are the tables whose rows get tagged with the run that recorded them,
+// so that the datasets of several runs over the same DB can be told apart
+var runEventTables = []string{
+	"conn_events",
+	"gossip_messages",
+	"gossip_mesh_snapshots",
+	"gossip_control_msgs",
+	"peer_gossip_scores",
+	"eth_blocks",
+	"eth_attestations",
+	"eth_aggregates",
+	"eth_blob_sidecars",
+}
+
+// InitRunIDColumns adds the run_id column to the event tables (the ones of other networks are skipped)
+func (c *DBClient) InitRunIDColumns() error {
+	log.Info("init run_id column of the event tables")
+
+	for _, table := range runEventTables {
+		_, err := c.psqlPool.Exec(
+			c.ctx,
+			fmt.Sprintf(`
+				ALTER TABLE IF EXISTS %s
+					ADD COLUMN IF NOT EXISTS run_id INT;
+			`, table),
+		)
+		if err != nil {
+			return errors.Wrap(err, "unable to add run_id column to "+table)
+		}
+	}
+	return nil
+}
+
+// RunID returns the id of the run that the event rows get tagged with (nil until the run is inserted)
+func (c *DBClient) RunID() interface{} {
+	runID := atomic.LoadInt64(&c.runID)
+	if runID == 0 {
+		return nil
+	}
+	return int(runID)
+}
+
+// InsertCrawlerRun persists the metadata of the current crawler execution, returning its run id
+func (c *DBClient) InsertCrawlerRun(run *models.CrawlerRun) (int, error) {
+	log.Debug("inserting new crawler run into crawler_runs")
+
+	var runID int
+	err := c.psqlPool.QueryRow(
+		c.ctx,
+		`
+			INSERT INTO crawler_runs(
+				network,
+				peer_id,
+				start_time,
+				signed_peer_record,
+				observed_addrs,
+				seed,
+				version,
+				config_hash,
+				config,
+				network_name)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,NULLIF($10, ''))
+			RETURNING id
+		`,
+		run.Network,
+		run.HostID.String(),
+		run.StartTime,
+		run.SignedPeerRecord,
+		run.ObservedAddrs,
+		run.Seed,
+		run.Version,
+		run.ConfigHash,
+		run.Config,
+		c.networkName,
+	).Scan(&runID)
+	if err != nil {
+		return runID, errors.Wrap(err, "unable to insert crawler run")
+	}
+	run.ID = runID
+	// from now on, the events get tagged with the run
+	atomic.StoreInt64(&c.runID, int64(runID))
+	return runID, nil
+}
+
+// FinishCrawlerRun sets the stop time of the run
+func (c *DBClient) FinishCrawlerRun(runID int, stopTime time.Time) error {
+	log.Debugf("finishing crawler run %d", runID)
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			UPDATE crawler_runs SET stop_time = $2
+			WHERE id = $1;
+		`,
+		runID,
+		stopTime,
+	)
+	return errors.Wrap(err, "unable to finish crawler run")
+}
+
+func (c *DBClient) InitRunResourceUsageTable() error {
+	log.Info("init run_resource_usage table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS run_resource_usage(
+				id SERIAL,
+				run_id INT NOT NULL,
+				timestamp TIMESTAMP NOT NULL,
+				cpu_seconds REAL NOT NULL,
+				mem_sys_bytes BIGINT NOT NULL,
+				heap_alloc_bytes BIGINT NOT NULL,
+				goroutines INT NOT NULL,
+				bytes_in BIGINT NOT NULL,
+				bytes_out BIGINT NOT NULL,
+				open_conns INT NOT NULL,
+
+				PRIMARY KEY(id)
+			);
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InsertRunResourceUsage(usage *models.ResourceUsage) (query string, args []interface{}) {
+	log.Trace("inserting new resource usage snapshot into run_resource_usage")
+	query = `
+		INSERT INTO run_resource_usage(
+			run_id,
+			timestamp,
+			cpu_seconds,
+			mem_sys_bytes,
+			heap_alloc_bytes,
+			goroutines,
+			bytes_in,
+			bytes_out,
+			open_conns)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`
+
+	args = append(args, usage.RunID)
+	args = append(args, usage.Timestamp)
+	args = append(args, usage.CpuSeconds)
+	args = append(args, int64(usage.MemSysBytes))
+	args = append(args, int64(usage.HeapAllocBytes))
+	args = append(args, usage.Goroutines)
+	args = append(args, usage.BytesIn)
+	args = append(args, usage.BytesOut)
+	args = append(args, usage.OpenConns)
+
+	return query, args
+}
@@ -0,0 +1,158 @@
+package postgresql
+
+import (
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var dialQueueColumns = []string{
+	"network",
+	"peer_id",
+	"position",
+	"pending",
+	"last_error",
+	"delay",
+	"delay_degree",
+	"failures",
+	"last_attempt",
+	"last_positive",
+	"saved_time",
+}
+
+func (c *DBClient) InitDialQueueTable() error {
+	log.Info("init dial_queue table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS dial_queue(
+				network TEXT NOT NULL,
+				peer_id TEXT NOT NULL,
+				position INT NOT NULL,
+				pending BOOL NOT NULL,
+				last_error TEXT NOT NULL,
+				delay TEXT NOT NULL,
+				delay_degree INT NOT NULL,
+				failures INT NOT NULL,
+				last_attempt TIMESTAMP,
+				last_positive TIMESTAMP NOT NULL,
+				saved_time TIMESTAMP NOT NULL,
+
+				PRIMARY KEY(network, peer_id)
+			);
+		`,
+	)
+	return err
+}
+
+// ReplaceDialQueue replaces the persisted dial queue of the network with the given one, in a single
+// transaction so that an interrupted shutdown doesn't leave half of the queue behind
+func (c *DBClient) ReplaceDialQueue(states []models.DialQueueState) error {
+	log.Debugf("persisting dial queue of %d peers", len(states))
+
+	tx, err := c.psqlPool.Begin(c.ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to begin dial queue transaction")
+	}
+	defer tx.Rollback(c.ctx)
+
+	_, err = tx.Exec(c.ctx, `DELETE FROM dial_queue WHERE network = $1;`, c.networkKey())
+	if err != nil {
+		return errors.Wrap(err, "unable to drop previous dial queue")
+	}
+	savedTime := time.Now()
+	rows := make([][]interface{}, 0, len(states))
+	for _, state := range states {
+		var lastAttempt interface{}
+		if !state.LastAttempt.IsZero() {
+			lastAttempt = state.LastAttempt
+		}
+		rows = append(rows, []interface{}{
+			c.networkKey(),
+			state.PeerID.String(),
+			state.Position,
+			state.Pending,
+			state.LastError,
+			state.Delay,
+			state.DelayDegree,
+			state.Failures,
+			lastAttempt,
+			state.LastPositive,
+			savedTime,
+		})
+	}
+	_, err = tx.CopyFrom(c.ctx, pgx.Identifier{"dial_queue"}, dialQueueColumns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return errors.Wrap(err, "unable to persist dial queue")
+	}
+	return errors.Wrap(tx.Commit(c.ctx), "unable to commit dial queue")
+}
+
+// LoadDialQueue returns the dial queue of the network persisted by the previous run, sorted by position
+func (c *DBClient) LoadDialQueue() ([]models.DialQueueState, time.Time, error) {
+	log.Debug("loading persisted dial queue")
+	states := make([]models.DialQueueState, 0)
+	var savedTime time.Time
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT
+			peer_id,
+			position,
+			pending,
+			last_error,
+			delay,
+			delay_degree,
+			failures,
+			last_attempt,
+			last_positive,
+			saved_time
+		FROM dial_queue
+		WHERE network = $1
+		ORDER BY position;
+		`,
+		c.networkKey(),
+	)
+	if err != nil {
+		return states, savedTime, errors.Wrap(err, "unable to fetch persisted dial queue")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var state models.DialQueueState
+		var peerIDStr string
+		var lastAttempt *time.Time
+		err = rows.Scan(
+			&peerIDStr,
+			&state.Position,
+			&state.Pending,
+			&state.LastError,
+			&state.Delay,
+			&state.DelayDegree,
+			&state.Failures,
+			&lastAttempt,
+			&state.LastPositive,
+			&savedTime,
+		)
+		if err != nil {
+			return states, savedTime, errors.Wrap(err, "unable to parse persisted dial queue")
+		}
+		state.PeerID, err = peer.Decode(peerIDStr)
+		if err != nil {
+			log.Errorf("unable to get peerID from DB %s \n", peerIDStr)
+			continue
+		}
+		if lastAttempt != nil {
+			state.LastAttempt = *lastAttempt
+		}
+		states = append(states, state)
+	}
+	return states, savedTime, rows.Err()
+}
@@ -0,0 +1,70 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetDialScoreInputs returns, for each of the known (non-deprecated and dialable) peers, the time of
+// its last ENR (only for the Ethereum network), its connections, and whether it was ever identified
+func (c *DBClient) GetDialScoreInputs() (map[peer.ID]*models.DialScoreInput, error) {
+	inputs := make(map[peer.ID]*models.DialScoreInput)
+
+	// the ENRs are only recorded for the Ethereum network
+	lastENR := `0`
+	if c.Network == utils.EthereumNetwork {
+		lastENR = `COALESCE((
+			SELECT max(eth_nodes.timestamp)
+			FROM eth_nodes
+			WHERE eth_nodes.peer_id = peer_info.peer_id), 0)`
+	}
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT
+			peer_info.peer_id,
+			`+lastENR+`,
+			COALESCE(conns.total, 0),
+			COALESCE(peer_info.client_name, '') != '',
+			peer_info.reputation::DOUBLE PRECISION
+		FROM peer_info
+		LEFT JOIN (
+			SELECT peer_id, count(*) AS total
+			FROM conn_events
+			GROUP BY peer_id
+		) AS conns ON conns.peer_id = peer_info.peer_id
+		WHERE peer_info.deprecated='false' and peer_info.dialable='true' and
+		      ($1 = '' OR peer_info.network_name = $1);
+		`,
+		c.networkName,
+	)
+	if err != nil {
+		return inputs, errors.Wrap(err, "unable to retrieve the dial score inputs")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peerIDStr string
+		var enrTime int64
+		input := &models.DialScoreInput{}
+		err := rows.Scan(&peerIDStr, &enrTime, &input.Connections, &input.Identified, &input.Reputation)
+		if err != nil {
+			return inputs, errors.Wrap(err, "unable to parse the dial score inputs")
+		}
+		input.PeerID, err = peer.Decode(peerIDStr)
+		if err != nil {
+			log.Errorf("unable to get peerID from DB %s", peerIDStr)
+			continue
+		}
+		if enrTime > 0 {
+			input.LastENR = time.Unix(enrTime, 0)
+		}
+		inputs[input.PeerID] = input
+	}
+	return inputs, nil
+}
@@ -0,0 +1,93 @@
+package postgresql
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitDiscoverySourcesTable() error {
+	log.Info("init peer_discovery_sources table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS peer_discovery_sources(
+				peer_id TEXT NOT NULL,
+				source TEXT NOT NULL,
+				first_seen TIMESTAMP NOT NULL,
+				last_seen TIMESTAMP NOT NULL,
+				discoveries INT NOT NULL DEFAULT 1,
+
+				PRIMARY KEY(peer_id, source)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertDiscoverySource records that the peer was found by the given discovery source,
+// keeping when the source found it for the first and last time
+func (c *DBClient) UpsertDiscoverySource(src *models.DiscoverySource) (query string, args []interface{}) {
+	log.Trace("upserting discovery source of peer ", src.PeerID.String())
+
+	query = `
+		INSERT INTO peer_discovery_sources(
+			peer_id,
+			source,
+			first_seen,
+			last_seen)
+		VALUES ($1,$2,$3,$3)
+		ON CONFLICT (peer_id, source)
+		DO UPDATE SET
+			last_seen = excluded.last_seen,
+			discoveries = peer_discovery_sources.discoveries + 1;
+	`
+
+	args = append(args, src.PeerID.String())
+	args = append(args, src.Source)
+	args = append(args, src.Timestamp)
+
+	return query, args
+}
+
+// GetDiscoverySourceDistribution returns how many of the active peers were found by each of the discovery sources
+func (db *DBClient) GetDiscoverySourceDistribution() (map[string]interface{}, error) {
+	summary := make(map[string]interface{}, 0)
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			src.source,
+			count(*) as nodes
+		FROM peer_discovery_sources AS src
+		INNER JOIN peer_info ON peer_info.peer_id = src.peer_id
+		WHERE peer_info.deprecated='false' and
+		      peer_info.attempted='true' and
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and
+		      ($3 = '' OR peer_info.network_name = $3) and
+		      to_timestamp(peer_info.last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY src.source
+		ORDER BY nodes DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return summary, errors.Wrap(err, "unable to fetch discovery source distribution")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source string
+		var count int
+		err = rows.Scan(&source, &count)
+		if err != nil {
+			return summary, errors.Wrap(err, "unable to parse discovery source distribution")
+		}
+		summary[source] = count
+	}
+	return summary, nil
+}
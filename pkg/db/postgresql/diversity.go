@@ -0,0 +1,194 @@
+package postgresql
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// this file contains the queries that summarize the diversity of the active peers of the network
+// (the same set of peers as the crawler metrics)
+
+// GetClientVersionDistribution returns the number of active peers of each client and version
+func (db *DBClient) GetClientVersionDistribution() ([]models.ClientCount, error) {
+	log.Debug("fetching client and version distribution")
+	counts := make([]models.ClientCount, 0)
+
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			client_name,
+			COALESCE(NULLIF(client_version, ''), 'unknown') as version,
+			count(*) as peers
+		FROM peer_info
+		WHERE
+			deprecated = 'false' and
+			attempted = 'true' and
+			client_name IS NOT NULL and
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and
+			($3 = '' OR peer_info.network_name = $3) and
+			to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY client_name, version
+		ORDER BY peers DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return counts, errors.Wrap(err, "unable to fetch client and version distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var count models.ClientCount
+		err = rows.Scan(&count.Client, &count.Version, &count.Peers)
+		if err != nil {
+			return counts, errors.Wrap(err, "unable to parse fetched client and version distribution")
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// GetASNDistribution returns the number of active peers hosted at each autonomous system
+func (db *DBClient) GetASNDistribution() (map[string]int, error) {
+	log.Debug("fetching asn distribution")
+	asnDist := make(map[string]int)
+
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			COALESCE(NULLIF(ips.as_raw, ''), 'unknown') as asn,
+			count(*) as peers
+		FROM peer_info
+		LEFT JOIN ips ON peer_info.ip = ips.ip
+		WHERE
+			deprecated = 'false' and
+			attempted = 'true' and
+			client_name IS NOT NULL and
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and
+			($3 = '' OR peer_info.network_name = $3) and
+			to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY asn
+		ORDER BY peers DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return asnDist, errors.Wrap(err, "unable to fetch asn distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var asn string
+		var count int
+		err = rows.Scan(&asn, &count)
+		if err != nil {
+			return asnDist, errors.Wrap(err, "unable to parse fetched asn distribution")
+		}
+		asnDist[asn] = count
+	}
+	return asnDist, nil
+}
+
+// GetCloudProviderDistribution returns the number of active peers hosted at each cloud provider
+// ("none" for the IPs that aren't hosted at any of the tagged providers)
+func (db *DBClient) GetCloudProviderDistribution() (map[string]int, error) {
+	log.Debug("fetching cloud provider distribution")
+	cloudDist := make(map[string]int)
+
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			COALESCE(NULLIF(ips.cloud_provider, ''), 'none') as provider,
+			count(*) as peers
+		FROM peer_info
+		LEFT JOIN ips ON peer_info.ip = ips.ip
+		WHERE
+			deprecated = 'false' and
+			attempted = 'true' and
+			client_name IS NOT NULL and
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and
+			($3 = '' OR peer_info.network_name = $3) and
+			to_timestamp(last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY provider
+		ORDER BY peers DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return cloudDist, errors.Wrap(err, "unable to fetch cloud provider distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var provider string
+		var count int
+		err = rows.Scan(&provider, &count)
+		if err != nil {
+			return cloudDist, errors.Wrap(err, "unable to parse fetched cloud provider distribution")
+		}
+		cloudDist[provider] = count
+	}
+	return cloudDist, nil
+}
+
+// GetAttnetSubscriptions returns the number of nodes seen in the last day that advertise each of the
+// attestation subnets in their ENR. Only Ethereum tracks attnets, empty for the rest of networks
+func (db *DBClient) GetAttnetSubscriptions() (map[int]int, error) {
+	subscriptions := make(map[int]int)
+	if db.Network != utils.EthereumNetwork {
+		return subscriptions, nil
+	}
+	log.Debug("fetching attnet subscriptions")
+
+	// the attnets are the hex encoded SSZ bitvector, whose bits are ordered from
+	// the least significant one in each byte (same as get_bit)
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			subnets.subnet,
+			count(*) as nodes
+		FROM eth_nodes
+		CROSS JOIN generate_series(0, $1 - 1) as subnets(subnet)
+		WHERE
+			fork_digest IS NOT NULL and
+			length(attnets) = $1 / 4 and
+			to_timestamp(timestamp) > CURRENT_TIMESTAMP - INTERVAL '1 DAY' and
+			get_bit(decode(attnets, 'hex'), subnets.subnet) = 1
+		GROUP BY subnets.subnet
+		ORDER BY subnets.subnet;
+		`,
+		eth.SubnetLimit,
+	)
+	if err != nil {
+		return subscriptions, errors.Wrap(err, "unable to fetch attnet subscriptions")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var subnet, count int
+		err = rows.Scan(&subnet, &count)
+		if err != nil {
+			return subscriptions, errors.Wrap(err, "unable to parse fetched attnet subscriptions")
+		}
+		subscriptions[subnet] = count
+	}
+	return subscriptions, nil
+}
@@ -99,8 +99,11 @@ func (db *DBClient) GetDeprecatedNodes() (int, error) {
 		select
 			count(deprecated)
 		from peer_info
-		where deprecated='true';
-		`).Scan(
+		where deprecated='true' and
+		      ($1 = '' OR network_name = $1);
+		`,
+		db.networkName,
+	).Scan(
 		&deprecatedCount,
 	)
 	if err != nil {
@@ -109,3 +112,51 @@ func (db *DBClient) GetDeprecatedNodes() (int, error) {
 
 	return deprecatedCount, nil
 }
+
+// GetGossipArrivalBaselines returns the average time in slot at which the beacon blocks and the attestations
+// of the last given slots arrived, distinguishing those first received from our static peers (the
+// propagation-delay baseline) from those first received from the rest of the network
+func (db *DBClient) GetGossipArrivalBaselines(slots int) (map[string]map[string]float64, error) {
+	log.Debug("fetching gossip arrival baselines")
+	baselines := make(map[string]map[string]float64)
+
+	msgTables := map[string]string{
+		"beacon_block": "eth_blocks",
+		"attestation":  "eth_attestations",
+	}
+	for msgType, table := range msgTables {
+		rows, err := db.psqlPool.Query(
+			db.ctx,
+			fmt.Sprintf(`
+			SELECT
+				CASE
+					WHEN sender IN (SELECT peer_id FROM static_peers WHERE active = 'true') THEN 'static'
+					ELSE 'network'
+				END as source,
+				avg(time_in_slot) as avg_time_in_slot
+			FROM %s
+			WHERE slot > (SELECT max(slot) FROM %s) - $1
+			GROUP BY source;
+			`, table, table),
+			slots,
+		)
+		if err != nil {
+			return baselines, errors.Wrap(err, "unable to fetch arrival baselines from "+table)
+		}
+
+		baselines[msgType] = make(map[string]float64)
+		for rows.Next() {
+			var source string
+			var avgTime float64
+			err = rows.Scan(&source, &avgTime)
+			if err != nil {
+				rows.Close()
+				return baselines, errors.Wrap(err, "unable to parse fetched arrival baselines from "+table)
+			}
+			baselines[msgType][source] = avgTime
+		}
+		rows.Close()
+	}
+
+	return baselines, nil
+}
@@ -1,6 +1,8 @@
 package postgresql
 
 import (
+	"encoding/json"
+
 	"github.com/pkg/errors"
 
 	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
@@ -37,6 +39,10 @@ func (d *DBClient) InitEthNodesTable() error {
 			next_fork_version TEXT,
 			attnets TEXT, 
 			attnets_number INT,
+			quic INT,
+			csc INT,
+			enr TEXT,
+			enr_fields JSONB,
 
 			PRIMARY KEY(node_id),	
 			UNIQUE(peer_id, pubkey)
@@ -47,6 +53,38 @@ func (d *DBClient) InitEthNodesTable() error {
 		return errors.Wrap(err, "unable to create table eth_nodes in the db")
 	}
 
+	// add the columns that weren't there in previous versions of the table
+	_, err = d.psqlPool.Exec(
+		d.ctx, `
+		ALTER TABLE eth_nodes
+			ADD COLUMN IF NOT EXISTS quic INT,
+			ADD COLUMN IF NOT EXISTS csc INT,
+			ADD COLUMN IF NOT EXISTS enr TEXT,
+			ADD COLUMN IF NOT EXISTS enr_fields JSONB;
+		`,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to update the columns of eth_nodes in the db")
+	}
+
+	// every version (seq) of the ENR of each node
+	_, err = d.psqlPool.Exec(
+		d.ctx, `
+		CREATE TABLE IF NOT EXISTS eth_node_records(
+			node_id TEXT NOT NULL,
+			seq BIGINT NOT NULL,
+			first_seen BIGINT NOT NULL,
+			fields JSONB NOT NULL,
+			enr TEXT NOT NULL,
+
+			PRIMARY KEY(node_id, seq)
+		);
+		`,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to create table eth_node_records in the db")
+	}
+
 	return nil
 }
 
@@ -68,8 +106,12 @@ func (d *DBClient) UpsertEnrInfo(enr *eth.EnrNode) (query string, args []interfa
 			fork_digest,
 			next_fork_version,
 			attnets,
-			attnets_number)
-		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)	
+			attnets_number,
+			quic,
+			csc,
+			enr,
+			enr_fields)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)	
 		ON CONFLICT (node_id)
 		DO UPDATE SET
 			timestamp = excluded.timestamp,
@@ -80,7 +122,11 @@ func (d *DBClient) UpsertEnrInfo(enr *eth.EnrNode) (query string, args []interfa
 			fork_digest = excluded.fork_digest,
 			next_fork_version = excluded.next_fork_version,
 			attnets = excluded.attnets,
-			attnets_number = excluded.attnets_number;
+			attnets_number = excluded.attnets_number,
+			quic = excluded.quic,
+			csc = excluded.csc,
+			enr = excluded.enr,
+			enr_fields = excluded.enr_fields;
 		`
 
 	// if peer_id goes empty, not my fault here we should have checked it before
@@ -102,6 +148,154 @@ func (d *DBClient) UpsertEnrInfo(enr *eth.EnrNode) (query string, args []interfa
 	args = append(args, enr.Eth2Data.NextForkVersion.String())
 	args = append(args, enr.GetAttnetsString())
 	args = append(args, enr.Attnets.NetNumber)
+	args = append(args, enr.QUIC)
+	args = append(args, enr.CSC)
+	args = append(args, enr.Raw)
+	args = append(args, enrFieldsJSON(enr))
+
+	return query, args
+}
+
+// UpsertEnrRecord keeps the version (seq) of the node's ENR, the first time it was seen
+func (d *DBClient) UpsertEnrRecord(enr *eth.EnrNode) (query string, args []interface{}) {
+	log.Trace("upserting new enr record to eth_node_records in psql-db")
+
+	query = `
+		INSERT INTO eth_node_records(
+			node_id,
+			seq,
+			first_seen,
+			fields,
+			enr)
+		VALUES($1,$2,$3,$4,$5)
+		ON CONFLICT (node_id, seq)
+		DO NOTHING;
+		`
+
+	args = append(args, enr.ID.String())
+	args = append(args, enr.Seq)
+	args = append(args, enr.Timestamp.Unix())
+	args = append(args, enrFieldsJSON(enr))
+	args = append(args, enr.Raw)
+
+	return query, args
+}
+
+// enrFieldsJSON serializes the decoded fields of the ENR ("{}" if they couldn't be serialized)
+func enrFieldsJSON(enr *eth.EnrNode) string {
+	if enr.Fields == nil {
+		return "{}"
+	}
+	fields, err := json.Marshal(enr.Fields)
+	if err != nil {
+		log.Warnf("unable to serialize the enr fields of node %s - %s", enr.ID.String(), err.Error())
+		return "{}"
+	}
+	return string(fields)
+}
+
+// UpdateDecodedEnrInfo overrides the structured columns of an existing eth_nodes row
+// with the fields decoded from its raw ENR (timestamp and ids remain untouched)
+func (d *DBClient) UpdateDecodedEnrInfo(enr *eth.EnrNode) (query string, args []interface{}) {
+	log.Trace("updating decoded enr fields in eth_nodes in psql-db")
+
+	query = `
+		UPDATE eth_nodes
+		SET
+			seq = $2,
+			ip = $3,
+			tcp = $4,
+			udp = $5,
+			fork_digest = $6,
+			next_fork_version = $7,
+			attnets = $8,
+			attnets_number = $9,
+			quic = $10,
+			csc = $11,
+			enr_fields = $12
+		WHERE node_id = $1;
+		`
+
+	args = append(args, enr.ID.String())
+	args = append(args, enr.Seq)
+	args = append(args, enr.IP)
+	args = append(args, enr.TCP)
+	args = append(args, enr.UDP)
+	args = append(args, enr.Eth2Data.ForkDigest.String())
+	args = append(args, enr.Eth2Data.NextForkVersion.String())
+	args = append(args, enr.GetAttnetsString())
+	args = append(args, enr.Attnets.NetNumber)
+	args = append(args, enr.QUIC)
+	args = append(args, enr.CSC)
+	args = append(args, enrFieldsJSON(enr))
 
 	return query, args
 }
+
+// BackfillEnrs re-decodes all the raw ENRs stored in the eth_nodes table with the current
+// decoder, updating the structured columns of each row.
+// Returns the number of updated rows and the number of ENRs that couldn't be decoded
+func (d *DBClient) BackfillEnrs(pageSize int) (updated int, failed int, err error) {
+	log.Info("backfilling decoded ENR fields in eth_nodes")
+
+	batch := NewQueryBatch(d.ctx, d.psqlPool, pageSize)
+	// paginate over the node_id (primary key) to avoid loading the entire table at once
+	lastNodeID := ""
+	for {
+		rows, err := d.psqlPool.Query(
+			d.ctx, `
+			SELECT
+				node_id,
+				enr
+			FROM eth_nodes
+			WHERE enr IS NOT NULL AND node_id > $1
+			ORDER BY node_id
+			LIMIT $2;
+			`,
+			lastNodeID,
+			pageSize,
+		)
+		if err != nil {
+			return updated, failed, errors.Wrap(err, "unable to retrieve raw enrs from eth_nodes")
+		}
+
+		var pageLen, decoded int
+		for rows.Next() {
+			var nodeID, rawEnr string
+			err = rows.Scan(&nodeID, &rawEnr)
+			if err != nil {
+				rows.Close()
+				return updated, failed, errors.Wrap(err, "unable to parse raw enr row from eth_nodes")
+			}
+			pageLen++
+			lastNodeID = nodeID
+
+			enrNode, err := eth.ParseEnrString(rawEnr)
+			if err != nil {
+				log.Warnf("unable to decode stored enr of node %s - %s", nodeID, err.Error())
+				failed++
+				continue
+			}
+			q, args := d.UpdateDecodedEnrInfo(enrNode)
+			batch.AddQuery(q, args...)
+			q, args = d.UpsertEnrRecord(enrNode)
+			batch.AddQuery(q, args...)
+			decoded++
+		}
+		rows.Close()
+
+		if batch.Len() > 0 {
+			err = batch.PersistBatch()
+			if err != nil {
+				return updated, failed, errors.Wrap(err, "unable to persist backfilled enrs")
+			}
+			updated += decoded
+		}
+		log.Infof("backfilled %d ENRs so far (%d failed)", updated, failed)
+
+		if pageLen < pageSize {
+			break
+		}
+	}
+	return updated, failed, nil
+}
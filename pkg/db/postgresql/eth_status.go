@@ -35,6 +35,18 @@ func (d *DBClient) InitEthereumNodeStatus() error {
 			PRIMARY KEY (peer_id)
 		);
 	`)
+	if err != nil {
+		return err
+	}
+	// add the columns that weren't there in previous versions of the table
+	_, err = d.psqlPool.Exec(
+		d.ctx, `
+		ALTER TABLE eth_status
+			ADD COLUMN IF NOT EXISTS ping_seq_number BIGINT,
+			ADD COLUMN IF NOT EXISTS ping_latency INT,
+			ADD COLUMN IF NOT EXISTS ping_timestamp BIGINT,
+			ADD COLUMN IF NOT EXISTS metadata_timestamp BIGINT;
+	`)
 	return err
 }
 
@@ -74,21 +86,26 @@ func (d *DBClient) UpsertEthereumNodeStatus(bstatus eth.BeaconStatusStamped) (qu
 
 func (d *DBClient) UpsertEthereumNodeMetadata(bmetadata eth.BeaconMetadataStamped) (query string, args []interface{}) {
 	log.Trace("upserting beacon metadata to eth_status in psql-db")
+	// merge rules: only the successful metadata requests are persisted, so the metadata of a peer
+	// is never downgraded by a failed one, and an older metadata persisted after a newer one
+	// (i.e. from an interleaved connection) never replaces it
 	query = `
 		INSERT INTO eth_status(
 			peer_id,
 			timestamp,
+			metadata_timestamp,
 			seq_number,
 			attnets,
 			syncnets)
-		VALUES ($1,$2,$3,$4,$5)
+		VALUES ($1,$2,$2,$3,$4,$5)
 		ON CONFLICT (peer_id)
 		DO UPDATE SET
 			peer_id = excluded.peer_id,
-			timestamp = excluded.timestamp,
-			seq_number = excluded.seq_number,
-			attnets = excluded.attnets,
-			syncnets = excluded.syncnets;
+			timestamp = GREATEST(eth_status.timestamp, excluded.timestamp),
+			metadata_timestamp = GREATEST(eth_status.metadata_timestamp, excluded.metadata_timestamp),
+			seq_number = CASE WHEN excluded.metadata_timestamp >= COALESCE(eth_status.metadata_timestamp, 0) THEN excluded.seq_number ELSE eth_status.seq_number END,
+			attnets = CASE WHEN excluded.metadata_timestamp >= COALESCE(eth_status.metadata_timestamp, 0) THEN excluded.attnets ELSE eth_status.attnets END,
+			syncnets = CASE WHEN excluded.metadata_timestamp >= COALESCE(eth_status.metadata_timestamp, 0) THEN excluded.syncnets ELSE eth_status.syncnets END;
 		`
 
 	args = append(args, bmetadata.PeerID.String())
@@ -99,3 +116,27 @@ func (d *DBClient) UpsertEthereumNodeMetadata(bmetadata eth.BeaconMetadataStampe
 
 	return query, args
 }
+
+func (d *DBClient) UpsertEthereumNodePing(bping eth.BeaconPingStamped) (query string, args []interface{}) {
+	log.Trace("upserting beacon ping to eth_status in psql-db")
+	query = `
+		INSERT INTO eth_status(
+			peer_id,
+			ping_timestamp,
+			ping_seq_number,
+			ping_latency)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (peer_id)
+		DO UPDATE SET
+			ping_timestamp = excluded.ping_timestamp,
+			ping_seq_number = excluded.ping_seq_number,
+			ping_latency = excluded.ping_latency;
+		`
+
+	args = append(args, bping.PeerID.String())
+	args = append(args, bping.Timestamp.Unix())
+	args = append(args, bping.SeqNumber)
+	args = append(args, bping.RTT.Milliseconds())
+
+	return query, args
+}
@@ -0,0 +1,78 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// the metadata of a peer can be persisted out of order, so the newest one has to win
+func TestEthMetadataMergeRules(t *testing.T) {
+	dbCli := newTestDBClient(
+		t,
+		context.Background(),
+		utils.EthereumNetwork,
+		WithActivePeersBackup(false),
+		WithMigrations(false),
+	)
+	defer dbCli.Close()
+	err := dbCli.InitEthereumNodeStatus()
+	require.NoError(t, err)
+
+	peerStr := "12D3KooWKZbTx9hcXqkvEUVBDBaSpdd1TjoXcGpm1ZrmNxDyR6xe"
+	peerID, err := peer.Decode(peerStr)
+	require.NoError(t, err)
+
+	now := time.Now()
+	newer := eth.BeaconMetadataStamped{
+		Timestamp: now,
+		PeerID:    peerID,
+		Metadata:  common.MetaData{SeqNumber: 8},
+	}
+	newer.Metadata.Attnets[0] = 0x03
+	older := eth.BeaconMetadataStamped{
+		Timestamp: now.Add(-1 * time.Minute),
+		PeerID:    peerID,
+		Metadata:  common.MetaData{SeqNumber: 7},
+	}
+	newerQ, newerArgs := dbCli.UpsertEthereumNodeMetadata(newer)
+	olderQ, olderArgs := dbCli.UpsertEthereumNodeMetadata(older)
+
+	for i, steps := range [][]func() error{
+		{
+			func() error { _, err := dbCli.SingleQuery(olderQ, olderArgs...); return err },
+			func() error { _, err := dbCli.SingleQuery(newerQ, newerArgs...); return err },
+		},
+		{
+			func() error { _, err := dbCli.SingleQuery(newerQ, newerArgs...); return err },
+			func() error { _, err := dbCli.SingleQuery(olderQ, olderArgs...); return err },
+		},
+	} {
+		_, err = dbCli.SingleQuery(`DELETE FROM eth_status WHERE peer_id=$1;`, peerStr)
+		require.NoError(t, err)
+		for _, step := range steps {
+			require.NoError(t, step())
+		}
+
+		var seqNumber, timestamp, metadataTimestamp int64
+		var attnets string
+		err = dbCli.psqlPool.QueryRow(context.Background(), `
+			SELECT seq_number, attnets, timestamp, metadata_timestamp
+			FROM eth_status WHERE peer_id=$1;`,
+			peerStr,
+		).Scan(&seqNumber, &attnets, &timestamp, &metadataTimestamp)
+		require.NoError(t, err, "interleaving %d", i)
+
+		require.Equal(t, int64(newer.Metadata.SeqNumber), seqNumber, "interleaving %d", i)
+		require.Equal(t, newer.Metadata.Attnets.String(), attnets, "interleaving %d", i)
+		require.Equal(t, newer.Timestamp.Unix(), timestamp, "interleaving %d", i)
+		require.Equal(t, newer.Timestamp.Unix(), metadataTimestamp, "interleaving %d", i)
+	}
+}
@@ -0,0 +1,156 @@
+package postgresql
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+const (
+	AttnetSubnet  = eth.AttnetSubnet
+	SyncnetSubnet = eth.SyncnetSubnet
+)
+
+func (c *DBClient) InitEthNodeSubnetsTable() error {
+	log.Info("init eth_node_subnets table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS eth_node_subnets(
+				node_id TEXT NOT NULL,
+				subnet_type TEXT NOT NULL,
+				subnet INT NOT NULL,
+				first_seen BIGINT NOT NULL,
+				last_seen BIGINT NOT NULL,
+
+				PRIMARY KEY(node_id, subnet_type, subnet)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertEthNodeSubnets records the attestation and sync committee subnets advertised in the ENR of
+// the node, keeping when the node was seen in each subnet for the first and the last time
+func (c *DBClient) UpsertEthNodeSubnets(enr *eth.EnrNode) (query string, args []interface{}) {
+	log.Trace("upserting subnets of eth node ", enr.ID.String())
+
+	query = `
+		INSERT INTO eth_node_subnets(
+			node_id,
+			subnet_type,
+			subnet,
+			first_seen,
+			last_seen)
+		SELECT $1, subnets.subnet_type, subnets.subnet, $4, $4
+		FROM unnest($2::TEXT[], $3::INT[]) AS subnets(subnet_type, subnet)
+		ON CONFLICT (node_id, subnet_type, subnet)
+		DO UPDATE SET
+			last_seen = GREATEST(eth_node_subnets.last_seen, excluded.last_seen);
+	`
+
+	subnetTypes := make([]string, 0)
+	subnets := make([]int, 0)
+	for _, subnet := range enr.AttSubnets() {
+		subnetTypes = append(subnetTypes, AttnetSubnet)
+		subnets = append(subnets, subnet)
+	}
+	for _, subnet := range enr.SyncSubnets() {
+		subnetTypes = append(subnetTypes, SyncnetSubnet)
+		subnets = append(subnets, subnet)
+	}
+
+	args = append(args, enr.ID.String())
+	args = append(args, subnetTypes)
+	args = append(args, subnets)
+	args = append(args, enr.Timestamp.Unix())
+
+	return query, args
+}
+
+// GetPeersPerSubnet returns the number of nodes that advertise each of the subnets in their
+// latest ENR (only the nodes seen within the last day), by subnet type
+func (db *DBClient) GetPeersPerSubnet() (map[string]map[int]int, error) {
+	summary := map[string]map[int]int{
+		AttnetSubnet:  make(map[int]int),
+		SyncnetSubnet: make(map[int]int),
+	}
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			sub.subnet_type,
+			sub.subnet,
+			count(*) as nodes
+		FROM eth_node_subnets AS sub
+		INNER JOIN eth_nodes ON eth_nodes.node_id = sub.node_id
+		WHERE sub.last_seen >= eth_nodes.timestamp and
+		      to_timestamp(eth_nodes.timestamp) > CURRENT_TIMESTAMP - INTERVAL '1 DAY'
+		GROUP BY sub.subnet_type, sub.subnet;
+		`,
+	)
+	if err != nil {
+		return summary, errors.Wrap(err, "unable to fetch peers per subnet")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subnetType string
+		var subnet int
+		var count int
+		err = rows.Scan(&subnetType, &subnet, &count)
+		if err != nil {
+			return summary, errors.Wrap(err, "unable to parse peers per subnet")
+		}
+		if _, ok := summary[subnetType]; !ok {
+			summary[subnetType] = make(map[int]int)
+		}
+		summary[subnetType][subnet] = count
+	}
+	return summary, nil
+}
+
+// GetPeerAttnets returns the attestation subnets advertised in the latest ENR of each peer
+// (only the nodes seen within the last day)
+func (db *DBClient) GetPeerAttnets() (map[peer.ID][]int, error) {
+	attnets := make(map[peer.ID][]int)
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			eth_nodes.peer_id,
+			array_agg(sub.subnet ORDER BY sub.subnet)
+		FROM eth_node_subnets AS sub
+		INNER JOIN eth_nodes ON eth_nodes.node_id = sub.node_id
+		WHERE sub.subnet_type = $1 and
+		      sub.last_seen >= eth_nodes.timestamp and
+		      to_timestamp(eth_nodes.timestamp) > CURRENT_TIMESTAMP - INTERVAL '1 DAY' and
+		      eth_nodes.peer_id IS NOT NULL and eth_nodes.peer_id != ''
+		GROUP BY eth_nodes.peer_id;
+		`,
+		AttnetSubnet,
+	)
+	if err != nil {
+		return attnets, errors.Wrap(err, "unable to fetch the attnets of the peers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peerIDStr string
+		var subnets []int
+		err = rows.Scan(&peerIDStr, &subnets)
+		if err != nil {
+			return attnets, errors.Wrap(err, "unable to parse the attnets of the peers")
+		}
+		peerID, err := peer.Decode(peerIDStr)
+		if err != nil {
+			log.Errorf("unable to get peerID from DB %s", peerIDStr)
+			continue
+		}
+		attnets[peerID] = subnets
+	}
+	return attnets, nil
+}
@@ -35,7 +35,20 @@ func (c *DBClient) initEthereumAttestationsTable() error {
 			PRIMARY KEY(msg_id)
 		)
 		`)
+	if err != nil {
+		return err
+	}
 
+	// decoded content of the attestation
+	_, err = c.psqlPool.Exec(
+		c.ctx,
+		`
+		ALTER TABLE eth_attestations
+			ADD COLUMN IF NOT EXISTS committee_index BIGINT,
+			ADD COLUMN IF NOT EXISTS aggregation_bit BIGINT,
+			ADD COLUMN IF NOT EXISTS block_root TEXT,
+			ADD COLUMN IF NOT EXISTS target_epoch BIGINT;
+		`)
 	return err
 }
 
@@ -49,23 +62,60 @@ func (c *DBClient) InsertNewEthereumAttestation(attMsg *eth.TrackedAttestation)
 		slot,
 		arrival_time,
 		time_in_slot,
-		val_pubkey)
-	VALUES($1,$2,$3,$4,$5,$6,$7)
+		val_pubkey,
+		committee_index,
+		aggregation_bit,
+		block_root,
+		target_epoch,
+		run_id)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
 	ON CONFLICT (msg_id) DO NOTHING
 	`
 
 	// args
-	args = append(args, attMsg.MsgID)
-	args = append(args, attMsg.Sender.String())
-	args = append(args, attMsg.Subnet)
-	args = append(args, attMsg.Slot)
-	args = append(args, attMsg.ArrivalTime)
-	args = append(args, float64(attMsg.TimeInSlot)/float64(time.Second))
-	args = append(args, attMsg.ValPubkey)
+	args = attestationRow(attMsg, c.RunID())
 
 	return query, args
 }
 
+// AttestationsCopyTable persists the eth_attestations through COPY, skipping the duplicated msg_ids
+var AttestationsCopyTable = CopyTable{
+	Name: "eth_attestations",
+	Columns: []string{
+		"msg_id",
+		"sender",
+		"subnet",
+		"slot",
+		"arrival_time",
+		"time_in_slot",
+		"val_pubkey",
+		"committee_index",
+		"aggregation_bit",
+		"block_root",
+		"target_epoch",
+		"run_id",
+	},
+	SkipDuplicates: true,
+}
+
+// attestationRow returns the values of the attestation in the order of the AttestationsCopyTable columns
+func attestationRow(attMsg *eth.TrackedAttestation, runID interface{}) []interface{} {
+	return []interface{}{
+		attMsg.MsgID,
+		attMsg.Sender.String(),
+		attMsg.Subnet,
+		attMsg.Slot,
+		attMsg.ArrivalTime,
+		float64(attMsg.TimeInSlot) / float64(time.Second),
+		attMsg.ValPubkey,
+		attMsg.CommitteeIndex,
+		attMsg.AggregationBit,
+		attMsg.BlockRoot,
+		attMsg.TargetEpoch,
+		runID,
+	}
+}
+
 // Beacon Blocks
 func (c *DBClient) dropEtherumBeaconBlocksTable() error {
 	log.Info("droping the eth_blocks table")
@@ -94,22 +144,48 @@ func (c *DBClient) initEthereumBeaconBlocksTable() error {
 			PRIMARY KEY(msg_id)
 		)
 		`)
+	if err != nil {
+		return err
+	}
 
+	// roots of the block, to link it with its parent and with the attestations
+	_, err = c.psqlPool.Exec(
+		c.ctx,
+		`
+		ALTER TABLE eth_blocks
+			ADD COLUMN IF NOT EXISTS block_root TEXT,
+			ADD COLUMN IF NOT EXISTS parent_root TEXT,
+			ADD COLUMN IF NOT EXISTS blob_count BIGINT;
+		`)
 	return err
 }
 
+// InsertNewEthereumBeaconBlock records the block, and the delay of the blobs of the block
+// that arrived before it
 func (c *DBClient) InsertNewEthereumBeaconBlock(bblock *eth.TrackedBeaconBlock) (query string, args []interface{}) {
 
 	query = `
-	INSERT INTO eth_blocks(
-		msg_id,
-		sender,
-		slot,
-		arrival_time,
-		time_in_slot,
-		val_idx)
-	VALUES($1,$2,$3,$4,$5,$6)
-	ON CONFLICT (msg_id) DO NOTHING
+	WITH block AS (
+		INSERT INTO eth_blocks(
+			msg_id,
+			sender,
+			slot,
+			arrival_time,
+			time_in_slot,
+			val_idx,
+			block_root,
+			parent_root,
+			blob_count,
+			run_id)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		ON CONFLICT (msg_id) DO NOTHING
+		RETURNING block_root, time_in_slot
+	)
+	UPDATE eth_blob_sidecars
+	SET block_delay = eth_blob_sidecars.time_in_slot - block.time_in_slot
+	FROM block
+	WHERE eth_blob_sidecars.block_root = block.block_root AND
+		eth_blob_sidecars.block_delay IS NULL
 	`
 
 	// args
@@ -119,6 +195,197 @@ func (c *DBClient) InsertNewEthereumBeaconBlock(bblock *eth.TrackedBeaconBlock)
 	args = append(args, bblock.ArrivalTime)
 	args = append(args, float64(bblock.TimeInSlot)/float64(time.Second))
 	args = append(args, bblock.ValIndex)
+	args = append(args, bblock.BlockRoot)
+	args = append(args, bblock.ParentRoot)
+	args = append(args, bblock.BlobCount)
+	args = append(args, c.RunID())
+
+	return query, args
+}
+
+// Aggregates
+func (c *DBClient) initEthereumAggregatesTable() error {
+	log.Info("init eth_aggregates table in psql-db")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		CREATE TABLE IF NOT EXISTS eth_aggregates(
+			id SERIAL,
+			msg_id TEXT NOT NULL,
+			sender TEXT NOT NULL,
+			slot BIGINT NOT NULL,
+			arrival_time TIME NOT NULL,
+			time_in_slot REAL NOT NULL,
+			aggregator_idx BIGINT NOT NULL,
+			committee_index BIGINT NOT NULL,
+			participants BIGINT NOT NULL,
+			block_root TEXT NOT NULL,
+			target_epoch BIGINT NOT NULL,
+
+			PRIMARY KEY(msg_id)
+		)
+		`)
+
+	return err
+}
+
+func (c *DBClient) InsertNewEthereumAggregate(aggregate *eth.TrackedAggregateAndProof) (query string, args []interface{}) {
+
+	query = `
+	INSERT INTO eth_aggregates(
+		msg_id,
+		sender,
+		slot,
+		arrival_time,
+		time_in_slot,
+		aggregator_idx,
+		committee_index,
+		participants,
+		block_root,
+		target_epoch,
+		run_id)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	ON CONFLICT (msg_id) DO NOTHING
+	`
+
+	// args
+	args = aggregateRow(aggregate, c.RunID())
+
+	return query, args
+}
+
+// AggregatesCopyTable persists the eth_aggregates through COPY, skipping the duplicated msg_ids
+var AggregatesCopyTable = CopyTable{
+	Name: "eth_aggregates",
+	Columns: []string{
+		"msg_id",
+		"sender",
+		"slot",
+		"arrival_time",
+		"time_in_slot",
+		"aggregator_idx",
+		"committee_index",
+		"participants",
+		"block_root",
+		"target_epoch",
+		"run_id",
+	},
+	SkipDuplicates: true,
+}
+
+// aggregateRow returns the values of the aggregate in the order of the AggregatesCopyTable columns
+func aggregateRow(aggregate *eth.TrackedAggregateAndProof, runID interface{}) []interface{} {
+	return []interface{}{
+		aggregate.MsgID,
+		aggregate.Sender.String(),
+		aggregate.Slot,
+		aggregate.ArrivalTime,
+		float64(aggregate.TimeInSlot) / float64(time.Second),
+		aggregate.AggregatorIndex,
+		aggregate.CommitteeIndex,
+		aggregate.Participants,
+		aggregate.BlockRoot,
+		aggregate.TargetEpoch,
+		runID,
+	}
+}
+
+// Blob sidecars
+func (c *DBClient) initEthereumBlobSidecarsTable() error {
+	log.Info("init eth_blob_sidecars table in psql-db")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		CREATE TABLE IF NOT EXISTS eth_blob_sidecars(
+			id SERIAL,
+			msg_id TEXT NOT NULL,
+			sender TEXT NOT NULL,
+			subnet INT NOT NULL,
+			slot BIGINT NOT NULL,
+			arrival_time TIME NOT NULL,
+			time_in_slot REAL NOT NULL,
+			blob_index BIGINT NOT NULL,
+			proposer_idx BIGINT NOT NULL,
+			block_root TEXT NOT NULL,
+			kzg_commitment TEXT NOT NULL,
+			block_delay REAL,
+
+			PRIMARY KEY(msg_id)
+		)
+		`)
+	if err != nil {
+		return err
+	}
+
+	// blobs delivered by each of the peers (the ones that they delivered first to us)
+	_, err = c.psqlPool.Exec(
+		c.ctx,
+		`
+		CREATE TABLE IF NOT EXISTS eth_blob_peers(
+			peer_id TEXT NOT NULL,
+			blobs BIGINT NOT NULL,
+			total_time_in_slot REAL NOT NULL,
+			first_slot BIGINT NOT NULL,
+			last_slot BIGINT NOT NULL,
+
+			PRIMARY KEY(peer_id)
+		)
+		`)
+	return err
+}
+
+// InsertNewEthereumBlobSidecar records the blob and accounts it in the delivery stats of its sender.
+// The delay of the blob relative to its block (in seconds) is set if the block arrived before it,
+// otherwise it is set once the block arrives (negative)
+func (c *DBClient) InsertNewEthereumBlobSidecar(blob *eth.TrackedBlobSidecar) (query string, args []interface{}) {
+
+	query = `
+	WITH blob AS (
+		INSERT INTO eth_blob_sidecars(
+			msg_id,
+			sender,
+			subnet,
+			slot,
+			arrival_time,
+			time_in_slot,
+			blob_index,
+			proposer_idx,
+			block_root,
+			kzg_commitment,
+			block_delay,
+			run_id)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,
+			(SELECT $6 - eth_blocks.time_in_slot FROM eth_blocks WHERE eth_blocks.block_root = $9 LIMIT 1),
+			$11)
+		ON CONFLICT (msg_id) DO NOTHING
+		RETURNING sender, slot, time_in_slot
+	)
+	INSERT INTO eth_blob_peers(
+		peer_id,
+		blobs,
+		total_time_in_slot,
+		first_slot,
+		last_slot)
+	SELECT sender, 1, time_in_slot, slot, slot FROM blob
+	ON CONFLICT (peer_id) DO UPDATE SET
+		blobs = eth_blob_peers.blobs + 1,
+		total_time_in_slot = eth_blob_peers.total_time_in_slot + excluded.total_time_in_slot,
+		first_slot = LEAST(eth_blob_peers.first_slot, excluded.first_slot),
+		last_slot = GREATEST(eth_blob_peers.last_slot, excluded.last_slot)
+	`
+
+	// args
+	args = append(args, blob.MsgID)
+	args = append(args, blob.Sender.String())
+	args = append(args, blob.Subnet)
+	args = append(args, blob.Slot)
+	args = append(args, blob.ArrivalTime)
+	args = append(args, float64(blob.TimeInSlot)/float64(time.Second))
+	args = append(args, blob.Index)
+	args = append(args, blob.ProposerIndex)
+	args = append(args, blob.BlockRoot)
+	args = append(args, blob.KZGCommitment)
+	args = append(args, c.RunID())
 
 	return query, args
 }
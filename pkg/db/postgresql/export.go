@@ -0,0 +1,338 @@
+package postgresql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/export"
+)
+
+// exportColumn is a column of an exported dataset and the SQL expression that reads it
+type exportColumn struct {
+	export.Column
+	expr string
+}
+
+// exportDataset is a table (or join) that can be exported, filtered by the time of each row and,
+// if it has a peer expression, by peer. The key breaks the ties of the time between the rows, so
+// that the pages of the dataset don't overlap
+type exportDataset struct {
+	from     string
+	timeExpr string
+	peerExpr string
+	keyExpr  string
+	columns  []exportColumn
+}
+
+var exportDatasets = map[string]exportDataset{
+	"peers": {
+		from:     `peer_info p LEFT JOIN ips i ON i.ip = p.ip`,
+		timeExpr: `to_timestamp(p.last_activity)`,
+		peerExpr: `p.peer_id`,
+		keyExpr:  `p.peer_id`,
+		columns: []exportColumn{
+			{export.Column{Name: "peer_id", Kind: export.StringKind}, `p.peer_id`},
+			{export.Column{Name: "network", Kind: export.StringKind}, `p.network`},
+			{export.Column{Name: "network_name", Kind: export.StringKind}, `p.network_name`},
+			{export.Column{Name: "ip", Kind: export.StringKind}, `p.ip`},
+			{export.Column{Name: "port", Kind: export.IntKind}, `p.port`},
+			{export.Column{Name: "country_code", Kind: export.StringKind}, `i.country_code`},
+			{export.Column{Name: "client_name", Kind: export.StringKind}, `p.client_name`},
+			{export.Column{Name: "client_version", Kind: export.StringKind}, `p.client_version`},
+			{export.Column{Name: "user_agent", Kind: export.StringKind}, `p.user_agent`},
+			{export.Column{Name: "client_os", Kind: export.StringKind}, `p.client_os`},
+			{export.Column{Name: "protocol_version", Kind: export.StringKind}, `p.protocol_version`},
+			{export.Column{Name: "security_protocol", Kind: export.StringKind}, `p.security_protocol`},
+			{export.Column{Name: "origin", Kind: export.StringKind}, `p.origin`},
+			{export.Column{Name: "latency", Kind: export.IntKind}, `p.latency`},
+			{export.Column{Name: "deprecated", Kind: export.BoolKind}, `p.deprecated`},
+			{export.Column{Name: "attempted", Kind: export.BoolKind}, `p.attempted`},
+			{export.Column{Name: "conn_attempts", Kind: export.IntKind}, `p.conn_attempts`},
+			{export.Column{Name: "conn_successes", Kind: export.IntKind}, `p.conn_successes`},
+			{export.Column{Name: "last_activity", Kind: export.TimeKind}, `to_timestamp(p.last_activity)`},
+			{export.Column{Name: "last_conn_attempt", Kind: export.TimeKind}, `to_timestamp(p.last_conn_attempt)`},
+			{export.Column{Name: "last_error", Kind: export.StringKind}, `p.last_error`},
+		},
+	},
+	"conn_events": {
+		from:     `conn_events`,
+		timeExpr: `to_timestamp(conn_time)`,
+		peerExpr: `peer_id`,
+		keyExpr:  `id`,
+		columns: []exportColumn{
+			{export.Column{Name: "peer_id", Kind: export.StringKind}, `peer_id`},
+			{export.Column{Name: "direction", Kind: export.StringKind}, `direction`},
+			{export.Column{Name: "conn_time", Kind: export.TimeKind}, `to_timestamp(conn_time)`},
+			{export.Column{Name: "disconn_time", Kind: export.TimeKind}, `to_timestamp(disconn_time)`},
+			{export.Column{Name: "latency", Kind: export.IntKind}, `latency`},
+			{export.Column{Name: "identified", Kind: export.BoolKind}, `identified`},
+			{export.Column{Name: "addr_family", Kind: export.StringKind}, `addr_family`},
+			{export.Column{Name: "error", Kind: export.StringKind}, `error`},
+			{export.Column{Name: "run_id", Kind: export.IntKind}, `run_id`},
+		},
+	},
+	"gossip_messages": {
+		from:     `gossip_messages`,
+		timeExpr: `first_seen`,
+		peerExpr: `first_peer`,
+		keyExpr:  `msg_id`,
+		columns: []exportColumn{
+			{export.Column{Name: "msg_id", Kind: export.StringKind}, `msg_id`},
+			{export.Column{Name: "topic", Kind: export.StringKind}, `topic`},
+			{export.Column{Name: "first_seen", Kind: export.TimeKind}, `first_seen`},
+			{export.Column{Name: "first_peer", Kind: export.StringKind}, `first_peer`},
+			{export.Column{Name: "duplicates", Kind: export.IntKind}, `duplicates`},
+			{export.Column{Name: "last_seen", Kind: export.TimeKind}, `last_seen`},
+			{export.Column{Name: "run_id", Kind: export.IntKind}, `run_id`},
+		},
+	},
+	"peer_topic_messages": {
+		from:     `peer_topic_messages`,
+		timeExpr: `last_seen`,
+		peerExpr: `peer_id`,
+		keyExpr:  `peer_id, topic`,
+		columns: []exportColumn{
+			{export.Column{Name: "peer_id", Kind: export.StringKind}, `peer_id`},
+			{export.Column{Name: "topic", Kind: export.StringKind}, `topic`},
+			{export.Column{Name: "messages", Kind: export.IntKind}, `messages`},
+			{export.Column{Name: "first_seen", Kind: export.TimeKind}, `first_seen`},
+			{export.Column{Name: "last_seen", Kind: export.TimeKind}, `last_seen`},
+		},
+	},
+	"peer_gossip_scores": {
+		from:     `peer_gossip_scores`,
+		timeExpr: `timestamp`,
+		peerExpr: `peer_id`,
+		keyExpr:  `peer_id`,
+		columns: []exportColumn{
+			{export.Column{Name: "peer_id", Kind: export.StringKind}, `peer_id`},
+			{export.Column{Name: "timestamp", Kind: export.TimeKind}, `timestamp`},
+			{export.Column{Name: "score", Kind: export.FloatKind}, `score`},
+			{export.Column{Name: "time_in_mesh_s", Kind: export.FloatKind}, `time_in_mesh_s`},
+			{export.Column{Name: "first_deliveries", Kind: export.FloatKind}, `first_deliveries`},
+			{export.Column{Name: "mesh_deliveries", Kind: export.FloatKind}, `mesh_deliveries`},
+			{export.Column{Name: "invalid_deliveries", Kind: export.FloatKind}, `invalid_deliveries`},
+			{export.Column{Name: "behaviour_penalty", Kind: export.FloatKind}, `behaviour_penalty`},
+			{export.Column{Name: "run_id", Kind: export.IntKind}, `run_id`},
+		},
+	},
+	"runs": {
+		from:     `crawler_runs`,
+		timeExpr: `start_time`,
+		keyExpr:  `id`,
+		columns: []exportColumn{
+			{export.Column{Name: "run_id", Kind: export.IntKind}, `id`},
+			{export.Column{Name: "network", Kind: export.StringKind}, `network`},
+			{export.Column{Name: "network_name", Kind: export.StringKind}, `network_name`},
+			{export.Column{Name: "peer_id", Kind: export.StringKind}, `peer_id`},
+			{export.Column{Name: "start_time", Kind: export.TimeKind}, `start_time`},
+			{export.Column{Name: "stop_time", Kind: export.TimeKind}, `stop_time`},
+			{export.Column{Name: "version", Kind: export.StringKind}, `version`},
+			{export.Column{Name: "config_hash", Kind: export.StringKind}, `config_hash`},
+			{export.Column{Name: "seed", Kind: export.IntKind}, `seed`},
+		},
+	},
+	"peers_history": {
+		from:     `peers_history`,
+		timeExpr: `timestamp`,
+		peerExpr: `peer_id`,
+		keyExpr:  `peer_id`,
+		columns: []exportColumn{
+			{export.Column{Name: "timestamp", Kind: export.TimeKind}, `timestamp`},
+			{export.Column{Name: "peer_id", Kind: export.StringKind}, `peer_id`},
+			{export.Column{Name: "client_name", Kind: export.StringKind}, `client_name`},
+			{export.Column{Name: "client_version", Kind: export.StringKind}, `client_version`},
+			{export.Column{Name: "country_code", Kind: export.StringKind}, `country_code`},
+			{export.Column{Name: "attnets_number", Kind: export.IntKind}, `attnets_number`},
+			{export.Column{Name: "latency", Kind: export.IntKind}, `latency`},
+			{export.Column{Name: "last_error", Kind: export.StringKind}, `last_error`},
+		},
+	},
+	"eth_nodes": {
+		from:     `eth_nodes`,
+		timeExpr: `to_timestamp(timestamp)`,
+		peerExpr: `peer_id`,
+		keyExpr:  `node_id`,
+		columns: []exportColumn{
+			{export.Column{Name: "node_id", Kind: export.StringKind}, `node_id`},
+			{export.Column{Name: "peer_id", Kind: export.StringKind}, `peer_id`},
+			{export.Column{Name: "timestamp", Kind: export.TimeKind}, `to_timestamp(timestamp)`},
+			{export.Column{Name: "seq", Kind: export.IntKind}, `seq`},
+			{export.Column{Name: "ip", Kind: export.StringKind}, `ip`},
+			{export.Column{Name: "tcp", Kind: export.IntKind}, `tcp`},
+			{export.Column{Name: "udp", Kind: export.IntKind}, `udp`},
+			{export.Column{Name: "quic", Kind: export.IntKind}, `quic`},
+			{export.Column{Name: "pubkey", Kind: export.StringKind}, `pubkey`},
+			{export.Column{Name: "fork_digest", Kind: export.StringKind}, `fork_digest`},
+			{export.Column{Name: "next_fork_version", Kind: export.StringKind}, `next_fork_version`},
+			{export.Column{Name: "attnets", Kind: export.StringKind}, `attnets`},
+			{export.Column{Name: "attnets_number", Kind: export.IntKind}, `attnets_number`},
+			{export.Column{Name: "enr", Kind: export.StringKind}, `enr`},
+		},
+	},
+}
+
+// ExportDatasets returns the names of the datasets that can be exported
+func ExportDatasets() []string {
+	names := make([]string, 0, len(exportDatasets))
+	for name := range exportDatasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExportColumns returns the selected columns of the dataset (all of them if none is selected)
+func ExportColumns(dataset string, selected []string) ([]export.Column, error) {
+	cols, err := exportColumns(dataset, selected)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]export.Column, len(cols))
+	for i, col := range cols {
+		columns[i] = col.Column
+	}
+	return columns, nil
+}
+
+func exportColumns(dataset string, selected []string) ([]exportColumn, error) {
+	ds, ok := exportDatasets[dataset]
+	if !ok {
+		return nil, errors.Errorf("unknown dataset %s (%s)", dataset, strings.Join(ExportDatasets(), ", "))
+	}
+	if len(selected) == 0 {
+		return ds.columns, nil
+	}
+	cols := make([]exportColumn, 0, len(selected))
+	for _, name := range selected {
+		found := false
+		for _, col := range ds.columns {
+			if col.Name == name {
+				cols = append(cols, col)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unknown column %s in dataset %s", name, dataset)
+		}
+	}
+	return cols, nil
+}
+
+// exportQuery composes the query of the selected columns of the dataset, within the [since, until)
+// time range (open-ended if any of the times is zero)
+func exportQuery(dataset string, selected []string, since, until time.Time) (string, []interface{}, error) {
+	return datasetQuery(dataset, selected, models.DatasetFilter{Since: since, Until: until})
+}
+
+// datasetQuery composes the query of the selected columns of the dataset that match the filter
+func datasetQuery(dataset string, selected []string, filter models.DatasetFilter) (string, []interface{}, error) {
+	cols, err := exportColumns(dataset, selected)
+	if err != nil {
+		return "", nil, err
+	}
+	exprs := make([]string, len(cols))
+	for i, col := range cols {
+		exprs[i] = col.expr
+	}
+	ds := exportDatasets[dataset]
+
+	conditions := make([]string, 0, 3)
+	args := make([]interface{}, 0, 5)
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("%s >= $%d", ds.timeExpr, len(args)))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		conditions = append(conditions, fmt.Sprintf("%s < $%d", ds.timeExpr, len(args)))
+	}
+	if filter.PeerID != "" {
+		if ds.peerExpr == "" {
+			return "", nil, errors.Errorf("dataset %s can't be filtered by peer", dataset)
+		}
+		args = append(args, filter.PeerID)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", ds.peerExpr, len(args)))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), ds.from)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if filter.Limit <= 0 {
+		return query + fmt.Sprintf(" ORDER BY %s;", ds.timeExpr), args, nil
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY %s, %s LIMIT $%d OFFSET $%d;", ds.timeExpr, ds.keyExpr, len(args)-1, len(args))
+	return query, args, nil
+}
+
+// ExportDataset streams the rows of the selected columns of the dataset within the [since, until)
+// time range to the given writer, returning the number of exported rows
+func (c *DBClient) ExportDataset(dataset string, selected []string, since, until time.Time, w export.Writer) (int64, error) {
+	query, args, err := exportQuery(dataset, selected, since, until)
+	if err != nil {
+		return 0, err
+	}
+	log.Debugf("exporting %s: %s", dataset, query)
+
+	rows, err := c.psqlPool.Query(c.ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to query dataset "+dataset)
+	}
+	defer rows.Close()
+
+	var exported int64
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return exported, errors.Wrap(err, "unable to read row of "+dataset)
+		}
+		err = w.WriteRow(values)
+		if err != nil {
+			return exported, errors.Wrap(err, "unable to export row of "+dataset)
+		}
+		exported++
+	}
+	return exported, errors.Wrap(rows.Err(), "unable to export "+dataset)
+}
+
+// QueryDataset returns the rows of the dataset that match the filter, with the values of each row
+// by column name (nil for the NULL values)
+func (c *DBClient) QueryDataset(dataset string, filter models.DatasetFilter) ([]map[string]interface{}, error) {
+	query, args, err := datasetQuery(dataset, nil, filter)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("querying %s: %s", dataset, query)
+	cols := exportDatasets[dataset].columns
+
+	rows, err := c.psqlPool.Query(c.ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query dataset "+dataset)
+	}
+	defer rows.Close()
+
+	results := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return results, errors.Wrap(err, "unable to read row of "+dataset)
+		}
+		row := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			row[col.Name], err = export.Normalize(col.Kind, values[i])
+			if err != nil {
+				return results, errors.Wrap(err, col.Name)
+			}
+		}
+		results = append(results, row)
+	}
+	return results, errors.Wrap(rows.Err(), "unable to query "+dataset)
+}
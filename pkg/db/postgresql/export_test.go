@@ -0,0 +1,41 @@
+package postgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func TestExportQuery(t *testing.T) {
+	query, args, err := exportQuery("conn_events", []string{"peer_id", "conn_time"}, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT peer_id, to_timestamp(conn_time) FROM conn_events ORDER BY to_timestamp(conn_time);", query)
+	require.Empty(t, args)
+
+	since := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+	query, args, err = exportQuery("gossip_messages", []string{"topic"}, since, until)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT topic FROM gossip_messages WHERE first_seen >= $1 AND first_seen < $2 ORDER BY first_seen;", query)
+	require.Equal(t, []interface{}{since, until}, args)
+
+	_, _, err = exportQuery("gossip_messages", []string{"peer_id"}, since, until)
+	require.Error(t, err)
+	_, _, err = exportQuery("eth_blocks", nil, since, until)
+	require.Error(t, err)
+
+	// pages of a peer's rows
+	query, args, err = datasetQuery("eth_nodes", []string{"node_id"}, models.DatasetFilter{Since: since, PeerID: "16Uiu2HAm", Limit: 10, Offset: 20})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT node_id FROM eth_nodes WHERE to_timestamp(timestamp) >= $1 AND peer_id = $2 ORDER BY to_timestamp(timestamp), node_id LIMIT $3 OFFSET $4;", query)
+	require.Equal(t, []interface{}{since, "16Uiu2HAm", 10, 20}, args)
+	_, _, err = datasetQuery("runs", nil, models.DatasetFilter{PeerID: "16Uiu2HAm"})
+	require.Error(t, err)
+
+	cols, err := ExportColumns("peers", nil)
+	require.NoError(t, err)
+	require.Len(t, cols, len(exportDatasets["peers"].columns))
+}
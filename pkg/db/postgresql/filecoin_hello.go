@@ -0,0 +1,56 @@
+package postgresql
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/networks/filecoin"
+)
+
+func (d *DBClient) InitFilecoinHelloTable() error {
+	log.Debug("init filecoin_hello table in psql-db")
+	_, err := d.psqlPool.Exec(
+		d.ctx, `
+		CREATE TABLE IF NOT EXISTS filecoin_hello(
+			peer_id TEXT NOT NULL,
+			timestamp BIGINT NOT NULL,
+			genesis_hash TEXT NOT NULL,
+			head_tipset TEXT[] NOT NULL,
+			head_height BIGINT NOT NULL,
+			head_weight NUMERIC NOT NULL,
+
+			PRIMARY KEY (peer_id)
+		);
+	`)
+	return err
+}
+
+// UpsertFilecoinHello records the genesis and the chain head that the peer advertised in its last hello
+func (d *DBClient) UpsertFilecoinHello(hello filecoin.HelloStamped) (query string, args []interface{}) {
+	log.Trace("upserting filecoin hello to filecoin_hello in psql-db")
+	query = `
+		INSERT INTO filecoin_hello(
+			peer_id,
+			timestamp,
+			genesis_hash,
+			head_tipset,
+			head_height,
+			head_weight)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (peer_id)
+		DO UPDATE SET
+			timestamp = excluded.timestamp,
+			genesis_hash = excluded.genesis_hash,
+			head_tipset = excluded.head_tipset,
+			head_height = excluded.head_height,
+			head_weight = excluded.head_weight;
+	`
+
+	args = append(args, hello.PeerID.String())
+	args = append(args, hello.Timestamp.Unix())
+	args = append(args, hello.GenesisHash)
+	args = append(args, hello.HeadTipSet)
+	args = append(args, hello.HeadHeight)
+	args = append(args, hello.HeadWeight.String())
+
+	return query, args
+}
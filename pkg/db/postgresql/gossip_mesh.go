@@ -0,0 +1,125 @@
+package postgresql
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitGossipMeshTables() error {
+	log.Info("init gossip_mesh_snapshots and gossip_control_msgs tables")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS gossip_mesh_snapshots(
+				timestamp TIMESTAMP NOT NULL,
+				topic TEXT NOT NULL,
+				mesh_peers TEXT[] NOT NULL,
+				topic_peers INT NOT NULL,
+				grafted TEXT[] NOT NULL,
+				pruned TEXT[] NOT NULL,
+				recv_graft INT NOT NULL,
+				recv_prune INT NOT NULL,
+				recv_ihave INT NOT NULL,
+
+				PRIMARY KEY(timestamp, topic)
+			);
+		`,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to init gossip_mesh_snapshots")
+	}
+
+	_, err = c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS gossip_control_msgs(
+				timestamp TIMESTAMP NOT NULL,
+				peer_id TEXT NOT NULL,
+				sent_graft INT NOT NULL,
+				sent_prune INT NOT NULL,
+				sent_ihave INT NOT NULL,
+				sent_iwant INT NOT NULL,
+
+				PRIMARY KEY(timestamp, peer_id)
+			);
+		`,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to init gossip_control_msgs")
+	}
+	return nil
+}
+
+// InsertGossipMeshSnapshot records the state of our mesh in a topic
+func (c *DBClient) InsertGossipMeshSnapshot(snapshot *models.GossipMeshSnapshot) (query string, args []interface{}) {
+	log.Trace("inserting mesh snapshot of topic ", snapshot.Topic)
+
+	query = `
+		INSERT INTO gossip_mesh_snapshots(
+			timestamp,
+			topic,
+			mesh_peers,
+			topic_peers,
+			grafted,
+			pruned,
+			recv_graft,
+			recv_prune,
+			recv_ihave,
+			run_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		ON CONFLICT DO NOTHING;
+	`
+
+	args = append(args, snapshot.Timestamp)
+	args = append(args, snapshot.Topic)
+	args = append(args, peerIDStrings(snapshot.Mesh))
+	args = append(args, snapshot.TopicPeers)
+	args = append(args, peerIDStrings(snapshot.Grafted))
+	args = append(args, peerIDStrings(snapshot.Pruned))
+	args = append(args, snapshot.RecvGraft)
+	args = append(args, snapshot.RecvPrune)
+	args = append(args, snapshot.RecvIHave)
+	args = append(args, c.RunID())
+
+	return query, args
+}
+
+// InsertGossipControlCounts records the control messages that we sent to a peer since the previous snapshot
+func (c *DBClient) InsertGossipControlCounts(counts *models.GossipControlCounts) (query string, args []interface{}) {
+	log.Trace("inserting control messages sent to peer ", counts.PeerID.String())
+
+	query = `
+		INSERT INTO gossip_control_msgs(
+			timestamp,
+			peer_id,
+			sent_graft,
+			sent_prune,
+			sent_ihave,
+			sent_iwant,
+			run_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT DO NOTHING;
+	`
+
+	args = append(args, counts.Timestamp)
+	args = append(args, counts.PeerID.String())
+	args = append(args, counts.Graft)
+	args = append(args, counts.Prune)
+	args = append(args, counts.IHave)
+	args = append(args, counts.IWant)
+	args = append(args, c.RunID())
+
+	return query, args
+}
+
+func peerIDStrings(peers []peer.ID) []string {
+	strs := make([]string, 0, len(peers))
+	for _, p := range peers {
+		strs = append(strs, p.String())
+	}
+	return strs
+}
@@ -0,0 +1,61 @@
+package postgresql
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitGossipMessagesTable() error {
+	log.Info("init gossip_messages table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS gossip_messages(
+				msg_id TEXT NOT NULL,
+				topic TEXT NOT NULL,
+				first_seen TIMESTAMP NOT NULL,
+				first_peer TEXT NOT NULL,
+				duplicates INT NOT NULL DEFAULT 0,
+				last_seen TIMESTAMP NOT NULL,
+
+				PRIMARY KEY(msg_id)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertGossipMessage records the first arrival of the message and its duplicates. If the message
+// was already recorded (a duplicate that arrived after the tracking window), the first arrival is
+// kept and the new arrivals are accounted as duplicates
+func (c *DBClient) UpsertGossipMessage(msg *models.GossipMessage) (query string, args []interface{}) {
+	log.Trace("upserting gossip message ", msg.MsgID)
+
+	query = `
+		INSERT INTO gossip_messages(
+			msg_id,
+			topic,
+			first_seen,
+			first_peer,
+			duplicates,
+			last_seen,
+			run_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (msg_id)
+		DO UPDATE SET
+			duplicates = gossip_messages.duplicates + excluded.duplicates + 1,
+			last_seen = GREATEST(gossip_messages.last_seen, excluded.last_seen);
+	`
+
+	args = append(args, msg.MsgID)
+	args = append(args, msg.Topic)
+	args = append(args, msg.FirstSeen)
+	args = append(args, msg.FirstPeer.String())
+	args = append(args, msg.Duplicates)
+	args = append(args, msg.LastSeen)
+	args = append(args, c.RunID())
+
+	return query, args
+}
@@ -0,0 +1,140 @@
+package postgresql
+
+import (
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) DropHostIdentitiesTable() error {
+	log.Info("dropping table host_identities")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE host_identities;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitHostIdentitiesTable() error {
+	log.Info("init host_identities table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS host_identities(
+				network TEXT NOT NULL,
+				peer_id TEXT NOT NULL,
+				priv_key TEXT NOT NULL,
+				created_time TIMESTAMP NOT NULL,
+
+				PRIMARY KEY(network)
+			);
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitIdentityChangesTable() error {
+	log.Info("init identity_changes table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS identity_changes(
+				id SERIAL,
+				network TEXT NOT NULL,
+				prev_peer_id TEXT,
+				peer_id TEXT NOT NULL,
+				reason TEXT NOT NULL,
+				timestamp TIMESTAMP NOT NULL,
+
+				PRIMARY KEY(id)
+			);
+		`,
+	)
+	return err
+}
+
+// LoadIdentityKey returns the persisted private key of the crawler for the network (empty if there is none)
+func (c *DBClient) LoadIdentityKey() (key string, created time.Time, err error) {
+	log.Debug("loading host identity from host_identities")
+
+	err = c.psqlPool.QueryRow(
+		c.ctx,
+		`
+		SELECT priv_key, created_time
+		FROM host_identities
+		WHERE network = $1;
+		`,
+		c.networkKey(),
+	).Scan(&key, &created)
+	if err == pgx.ErrNoRows {
+		return "", created, nil
+	}
+	if err != nil {
+		return "", created, errors.Wrap(err, "unable to load host identity")
+	}
+	return key, created, nil
+}
+
+// StoreIdentityKey persists the private key of the crawler for the network, replacing the previous one
+func (c *DBClient) StoreIdentityKey(peerID string, key string, created time.Time) error {
+	log.Debugf("storing host identity %s into host_identities", peerID)
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		INSERT INTO host_identities(
+			network,
+			peer_id,
+			priv_key,
+			created_time)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (network) DO UPDATE SET
+			peer_id = excluded.peer_id,
+			priv_key = excluded.priv_key,
+			created_time = excluded.created_time;
+		`,
+		c.networkKey(),
+		peerID,
+		key,
+		created,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to store host identity")
+	}
+	return nil
+}
+
+// InsertIdentityChange records a change of the peer identity of the crawler
+func (c *DBClient) InsertIdentityChange(change *models.IdentityChange) error {
+	log.Debugf("inserting identity change %s -> %s into identity_changes", change.PrevPeerID, change.PeerID)
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		INSERT INTO identity_changes(
+			network,
+			prev_peer_id,
+			peer_id,
+			reason,
+			timestamp)
+		VALUES ($1,NULLIF($2, ''),$3,$4,$5);
+		`,
+		change.Network,
+		change.PrevPeerID,
+		change.PeerID,
+		change.Reason,
+		change.Timestamp,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to insert identity change")
+	}
+	return nil
+}
@@ -41,13 +41,50 @@ func (c *DBClient) InitIpTable() error {
 	if err != nil {
 		return errors.Wrap(err, "error init ips table")
 	}
+
+	// add the columns that weren't there in previous versions of the table
+	_, err = c.psqlPool.Exec(c.ctx, `
+		ALTER TABLE ips
+			ADD COLUMN IF NOT EXISTS min_rtt_ms BIGINT,
+			ADD COLUMN IF NOT EXISTS geo_confidence REAL,
+			ADD COLUMN IF NOT EXISTS geo_implausible BOOL NOT NULL DEFAULT false,
+			ADD COLUMN IF NOT EXISTS asn INT,
+			ADD COLUMN IF NOT EXISTS ip_class TEXT,
+			ADD COLUMN IF NOT EXISTS fetched_at TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS rdns TEXT,
+			ADD COLUMN IF NOT EXISTS cloud_provider TEXT,
+			ADD COLUMN IF NOT EXISTS anonymous BOOL NOT NULL DEFAULT false,
+			ADD COLUMN IF NOT EXISTS anonymous_source TEXT;
+		`)
+	if err != nil {
+		return errors.Wrap(err, "updating the columns of ips table")
+	}
+
+	// derive the asn and the class of the IPs located before the columns existed
+	_, err = c.psqlPool.Exec(c.ctx, `
+		UPDATE ips SET
+			asn = COALESCE(substring(as_raw from '^AS([0-9]+)')::INT, 0),
+			ip_class = CASE WHEN hosting THEN 'hosting' WHEN mobile THEN 'mobile' ELSE 'residential' END
+		WHERE ip_class IS NULL;
+		`)
+	if err != nil {
+		return errors.Wrap(err, "backfilling the asn and class of the ips")
+	}
+	// the IPs located before fetched_at existed were cached with the default TTL
+	_, err = c.psqlPool.Exec(c.ctx, `
+		UPDATE ips SET fetched_at = expiration_time - $1 * INTERVAL '1 SECOND'
+		WHERE fetched_at IS NULL;
+		`, models.IpInfoTTL.Seconds())
+	if err != nil {
+		return errors.Wrap(err, "backfilling the fetch time of the ips")
+	}
 	return nil
 }
 
 // UpsertIP attemtps to insert IP in the DB - or Updates the data info if they where already there
 func (c *DBClient) UpsertIpInfo(ipInfo models.IpInfo) (query string, args []interface{}) {
 	log.Trace("upsert ip_info in psql-db")
-	// compose query
+	// compose query (the geo sanity check is only kept while the location doesn't change)
 	query = `
 		INSERT INTO ips(
 			ip,
@@ -68,8 +105,15 @@ func (c *DBClient) UpsertIpInfo(ipInfo models.IpInfo) (query string, args []inte
 			asname,
 			mobile,
 			proxy,
-			hosting)
-		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
+			hosting,
+			asn,
+			ip_class,
+			fetched_at,
+			rdns,
+			cloud_provider,
+			anonymous,
+			anonymous_source)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26)
 		ON CONFLICT (ip)
 		DO UPDATE SET
 			expiration_time = excluded.expiration_time,
@@ -89,7 +133,17 @@ func (c *DBClient) UpsertIpInfo(ipInfo models.IpInfo) (query string, args []inte
 			asname = excluded.asname,
 			mobile = excluded.mobile,
 			proxy = excluded.proxy,
-			hosting = excluded.hosting;
+			hosting = excluded.hosting,
+			asn = excluded.asn,
+			ip_class = excluded.ip_class,
+			fetched_at = excluded.fetched_at,
+			rdns = excluded.rdns,
+			cloud_provider = excluded.cloud_provider,
+			anonymous = excluded.anonymous,
+			anonymous_source = excluded.anonymous_source,
+			min_rtt_ms = CASE WHEN ips.lat = excluded.lat AND ips.lon = excluded.lon THEN ips.min_rtt_ms ELSE NULL END,
+			geo_confidence = CASE WHEN ips.lat = excluded.lat AND ips.lon = excluded.lon THEN ips.geo_confidence ELSE NULL END,
+			geo_implausible = CASE WHEN ips.lat = excluded.lat AND ips.lon = excluded.lon THEN ips.geo_implausible ELSE false END;
 		`
 
 	args = append(args, ipInfo.IP)
@@ -111,6 +165,33 @@ func (c *DBClient) UpsertIpInfo(ipInfo models.IpInfo) (query string, args []inte
 	args = append(args, ipInfo.Mobile)
 	args = append(args, ipInfo.Proxy)
 	args = append(args, ipInfo.Hosting)
+	args = append(args, ipInfo.ASN)
+	args = append(args, ipInfo.IpClass)
+	args = append(args, ipInfo.FetchedAt)
+	args = append(args, ipInfo.Rdns)
+	args = append(args, ipInfo.CloudProvider)
+	args = append(args, ipInfo.Anonymous)
+	args = append(args, ipInfo.AnonymousSource)
+
+	return query, args
+}
+
+// UpdateIpGeoCheck stores the result of the RTT sanity check of the location of an IP,
+// as long as the RTT is the lowest one measured so far
+func (c *DBClient) UpdateIpGeoCheck(check models.IpGeoCheck) (query string, args []interface{}) {
+	log.Trace("updating geo check of ip in psql-db")
+	query = `
+		UPDATE ips SET
+			min_rtt_ms = $2,
+			geo_confidence = $3,
+			geo_implausible = $4
+		WHERE ip = $1 AND (min_rtt_ms IS NULL OR min_rtt_ms >= $2);
+		`
+
+	args = append(args, check.IP)
+	args = append(args, check.MinRTT.Milliseconds())
+	args = append(args, check.Confidence)
+	args = append(args, check.Implausible)
 
 	return query, args
 }
@@ -119,9 +200,11 @@ func (c *DBClient) UpsertIpInfo(ipInfo models.IpInfo) (query string, args []inte
 func (c *DBClient) ReadIpInfo(ip string) (models.IpInfo, error) {
 	log.Tracef("reading ip_info for ip %s from psql-db", ip)
 	var ipInfo models.IpInfo
+	var minRTTMillis int64
 	err := c.psqlPool.QueryRow(c.ctx, `
 		SELECT 
 			ip,
+			COALESCE(fetched_at, expiration_time),
 			expiration_time,
 			continent,
 			continent_code,
@@ -139,11 +222,21 @@ func (c *DBClient) ReadIpInfo(ip string) (models.IpInfo, error) {
 			asname,
 			mobile,
 			proxy,
-			hosting
+			hosting,
+			COALESCE(asn, 0),
+			COALESCE(ip_class, ''),
+			COALESCE(rdns, ''),
+			COALESCE(cloud_provider, ''),
+			anonymous,
+			COALESCE(anonymous_source, ''),
+			COALESCE(min_rtt_ms, 0),
+			COALESCE(geo_confidence, 0),
+			geo_implausible
 		FROM ips
 		WHERE ip=$1
 	`, ip).Scan(
 		&ipInfo.IP,
+		&ipInfo.FetchedAt,
 		&ipInfo.ExpirationTime,
 		&ipInfo.Continent,
 		&ipInfo.ContinentCode,
@@ -162,24 +255,36 @@ func (c *DBClient) ReadIpInfo(ip string) (models.IpInfo, error) {
 		&ipInfo.Mobile,
 		&ipInfo.Proxy,
 		&ipInfo.Hosting,
+		&ipInfo.ASN,
+		&ipInfo.IpClass,
+		&ipInfo.Rdns,
+		&ipInfo.CloudProvider,
+		&ipInfo.Anonymous,
+		&ipInfo.AnonymousSource,
+		&minRTTMillis,
+		&ipInfo.GeoConfidence,
+		&ipInfo.GeoImplausible,
 	)
 	if err != nil {
 		return models.IpInfo{}, err
 	}
+	ipInfo.MinRTT = time.Duration(minRTTMillis) * time.Millisecond
 
 	return ipInfo, nil
 
 }
 
-// GetExpiredIpInfo returns all the IP whos' TTL has already expired
-func (c *DBClient) GetExpiredIpInfo() ([]string, error) {
+// GetExpiredIpInfo returns up to limit IPs whos' TTL has already expired, the longest expired first
+func (c *DBClient) GetExpiredIpInfo(limit int) ([]string, error) {
 	log.Trace("fetching expired ips from psql-db")
 	expIps := make([]string, 0)
 	ipRows, err := c.psqlPool.Query(c.ctx, `
 		SELECT ip 
 		FROM ips
-		WHERE expiration_time < NOW();
-	`)
+		WHERE expiration_time < NOW()
+		ORDER BY expiration_time
+		LIMIT $1;
+	`, limit)
 	if err != nil {
 		return expIps, errors.Wrap(err, "unable to get expired ip records")
 	}
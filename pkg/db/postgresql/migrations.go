@@ -0,0 +1,298 @@
+package postgresql
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// migrationsLockID is the key of the advisory lock that prevents two crawlers from
+// migrating the same DB at the same time
+const migrationsLockID = 0x61726d69
+
+var (
+	//go:embed migrations/*.sql
+	embeddedMigrations embed.FS
+
+	// migration files are named <version>_<name>.<up|down>.sql
+	migrationFileRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+)
+
+// Migration is a versioned change of the schema, with the SQL to apply it and to roll it back
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// AppliedMigration is a migration that was already applied to the DB
+type AppliedMigration struct {
+	Version     int
+	Name        string
+	AppliedTime time.Time
+}
+
+// LoadMigrations returns the migrations embedded in the binary sorted by version
+func LoadMigrations() ([]Migration, error) {
+	return parseMigrations(embeddedMigrations, "migrations")
+}
+
+func parseMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the migrations dir")
+	}
+	migrations := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, errors.Errorf("malformed migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read migration "+entry.Name())
+		}
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			migrations[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, errors.Errorf("migration version %d has two names (%s, %s)", version, m.Name, match[2])
+		}
+		switch match[3] {
+		case "up":
+			m.Up = string(content)
+		case "down":
+			m.Down = string(content)
+		}
+	}
+
+	sorted := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if m.Up == "" || m.Down == "" {
+			return nil, errors.Errorf("migration %d_%s needs both the up and the down files", m.Version, m.Name)
+		}
+		sorted = append(sorted, *m)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted, nil
+}
+
+func (c *DBClient) InitSchemaMigrationsTable() error {
+	log.Debug("init schema_migrations table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS schema_migrations(
+				version INT NOT NULL,
+				name TEXT NOT NULL,
+				applied_time TIMESTAMP NOT NULL DEFAULT NOW(),
+
+				PRIMARY KEY(version)
+			);
+		`,
+	)
+	return err
+}
+
+// GetAppliedMigrations returns the migrations already applied to the DB sorted by version
+func (c *DBClient) GetAppliedMigrations() ([]AppliedMigration, error) {
+	applied := make([]AppliedMigration, 0)
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT version, name, applied_time
+		FROM schema_migrations
+		ORDER BY version;
+		`,
+	)
+	if err != nil {
+		return applied, errors.Wrap(err, "unable to fetch applied migrations")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m AppliedMigration
+		err = rows.Scan(&m.Version, &m.Name, &m.AppliedTime)
+		if err != nil {
+			return applied, errors.Wrap(err, "unable to parse applied migration")
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// PendingMigrations returns the embedded migrations that weren't applied yet to the DB
+func (c *DBClient) PendingMigrations() ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := c.GetAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	appliedVersions := make(map[int]struct{}, len(applied))
+	for _, m := range applied {
+		appliedVersions[m.Version] = struct{}{}
+	}
+	pending := make([]Migration, 0)
+	for _, m := range migrations {
+		if _, ok := appliedVersions[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// ApplyMigrations applies all the pending migrations in order, each of them in its own transaction.
+// With dryRun it only returns the migrations that would be applied
+func (c *DBClient) ApplyMigrations(dryRun bool) ([]Migration, error) {
+	err := c.InitSchemaMigrationsTable()
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing schema_migrations table")
+	}
+	pending, err := c.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return pending, nil
+	}
+
+	applied := make([]Migration, 0, len(pending))
+	for _, m := range pending {
+		done, err := c.runMigration(m, true)
+		if err != nil {
+			return applied, errors.Wrapf(err, "applying migration %d_%s", m.Version, m.Name)
+		}
+		if done {
+			log.WithFields(log.Fields{
+				"version": m.Version,
+				"name":    m.Name,
+			}).Info("applied db migration")
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// RollbackMigrations rolls back the last given number of applied migrations, from the newest to
+// the oldest. With dryRun it only returns the migrations that would be rolled back
+func (c *DBClient) RollbackMigrations(steps int, dryRun bool) ([]Migration, error) {
+	err := c.InitSchemaMigrationsTable()
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing schema_migrations table")
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	applied, err := c.GetAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	toRollback := make([]Migration, 0, steps)
+	for i := len(applied) - 1; i >= 0 && len(toRollback) < steps; i-- {
+		m, ok := known[applied[i].Version]
+		if !ok {
+			return nil, errors.Errorf("applied migration %d_%s is not known by this version of the crawler", applied[i].Version, applied[i].Name)
+		}
+		toRollback = append(toRollback, m)
+	}
+	if dryRun {
+		return toRollback, nil
+	}
+
+	rolledBack := make([]Migration, 0, len(toRollback))
+	for _, m := range toRollback {
+		done, err := c.runMigration(m, false)
+		if err != nil {
+			return rolledBack, errors.Wrapf(err, "rolling back migration %d_%s", m.Version, m.Name)
+		}
+		if done {
+			log.WithFields(log.Fields{
+				"version": m.Version,
+				"name":    m.Name,
+			}).Info("rolled back db migration")
+			rolledBack = append(rolledBack, m)
+		}
+	}
+	return rolledBack, nil
+}
+
+// runMigration applies (or rolls back) the migration in a single transaction together with its
+// record at schema_migrations. It returns false if another crawler already did it
+func (c *DBClient) runMigration(m Migration, up bool) (bool, error) {
+	tx, err := c.psqlPool.Begin(c.ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(c.ctx)
+
+	// serialize the migrations of concurrent crawlers, the lock is released with the tx
+	_, err = tx.Exec(c.ctx, `SELECT pg_advisory_xact_lock($1);`, migrationsLockID)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to lock the migrations")
+	}
+	var isApplied bool
+	err = tx.QueryRow(
+		c.ctx,
+		`SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version=$1);`,
+		m.Version,
+	).Scan(&isApplied)
+	if err != nil {
+		return false, err
+	}
+	if isApplied == up {
+		return false, nil
+	}
+
+	if up {
+		_, err = tx.Exec(c.ctx, m.Up)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.Exec(
+			c.ctx,
+			`INSERT INTO schema_migrations(version, name, applied_time) VALUES ($1, $2, $3);`,
+			m.Version, m.Name, time.Now(),
+		)
+	} else {
+		_, err = tx.Exec(c.ctx, m.Down)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.Exec(c.ctx, `DELETE FROM schema_migrations WHERE version=$1;`, m.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+	err = tx.Commit(c.ctx)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
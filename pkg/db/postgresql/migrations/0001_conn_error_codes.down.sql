@@ -0,0 +1,38 @@
+-- the renamed rows get back the old code kept as their raw error, the newer ones (with a real
+-- raw error) are mapped back to the closest old code
+UPDATE peer_info SET
+		last_error = CASE
+			WHEN last_error_raw IN (
+				'connection_refused',
+				'connection_reset_by_peer',
+				'security_protocol_negotiation',
+				'negotiate_security_protocol_no_trailing_new_line',
+				'dial to self attempted',
+				'error requesting metadata'
+			) THEN last_error_raw
+			ELSE CASE last_error
+				WHEN 'conn_refused' THEN 'connection_refused'
+				WHEN 'peer_reset' THEN 'connection_reset_by_peer'
+				WHEN 'security_negotiation_failed' THEN 'security_protocol_negotiation'
+				WHEN 'dial_self' THEN 'dial to self attempted'
+				WHEN 'metadata_request_failed' THEN 'error requesting metadata'
+			END
+		END,
+		last_error_raw = CASE
+			WHEN last_error_raw IN (
+				'connection_refused',
+				'connection_reset_by_peer',
+				'security_protocol_negotiation',
+				'negotiate_security_protocol_no_trailing_new_line',
+				'dial to self attempted',
+				'error requesting metadata'
+			) THEN NULL
+			ELSE last_error_raw
+		END
+	WHERE last_error IN (
+		'conn_refused',
+		'peer_reset',
+		'security_negotiation_failed',
+		'dial_self',
+		'metadata_request_failed'
+	);
@@ -0,0 +1,20 @@
+-- the errors of the connection attempts are stored with the codes of the structured classifier,
+-- keeping the old code as the raw error so that the migration can be rolled back
+UPDATE peer_info SET
+		last_error_raw = COALESCE(last_error_raw, last_error),
+		last_error = CASE last_error
+			WHEN 'connection_refused' THEN 'conn_refused'
+			WHEN 'connection_reset_by_peer' THEN 'peer_reset'
+			WHEN 'security_protocol_negotiation' THEN 'security_negotiation_failed'
+			WHEN 'negotiate_security_protocol_no_trailing_new_line' THEN 'security_negotiation_failed'
+			WHEN 'dial to self attempted' THEN 'dial_self'
+			WHEN 'error requesting metadata' THEN 'metadata_request_failed'
+		END
+	WHERE last_error IN (
+		'connection_refused',
+		'connection_reset_by_peer',
+		'security_protocol_negotiation',
+		'negotiate_security_protocol_no_trailing_new_line',
+		'dial to self attempted',
+		'error requesting metadata'
+	);
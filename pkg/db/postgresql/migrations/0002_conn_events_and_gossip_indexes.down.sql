@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS gossip_messages_topic_idx;
+DROP INDEX IF EXISTS conn_events_peer_id_idx;
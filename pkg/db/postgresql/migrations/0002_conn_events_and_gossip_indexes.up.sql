@@ -0,0 +1,3 @@
+-- the conn_events and gossip_messages are queried per peer and per topic
+CREATE INDEX IF NOT EXISTS conn_events_peer_id_idx ON conn_events(peer_id);
+CREATE INDEX IF NOT EXISTS gossip_messages_topic_idx ON gossip_messages(topic);
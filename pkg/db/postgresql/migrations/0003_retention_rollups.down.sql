@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS gossip_messages_rollups;
+DROP TABLE IF EXISTS conn_events_rollups;
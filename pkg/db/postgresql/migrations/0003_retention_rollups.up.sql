@@ -0,0 +1,20 @@
+-- daily rollups of the conn_events and gossip_messages pruned by the retention manager
+CREATE TABLE IF NOT EXISTS conn_events_rollups(
+	day DATE NOT NULL,
+	direction TEXT NOT NULL,
+	error TEXT NOT NULL,
+	events BIGINT NOT NULL,
+	peers BIGINT NOT NULL,
+	total_duration BIGINT NOT NULL,
+
+	PRIMARY KEY(day, direction, error)
+);
+
+CREATE TABLE IF NOT EXISTS gossip_messages_rollups(
+	day DATE NOT NULL,
+	topic TEXT NOT NULL,
+	messages BIGINT NOT NULL,
+	duplicates BIGINT NOT NULL,
+
+	PRIMARY KEY(day, topic)
+);
@@ -0,0 +1,47 @@
+package postgresql
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		require.NotEmpty(t, m.Up)
+		require.NotEmpty(t, m.Down)
+		if i > 0 {
+			require.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestParseMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.up.sql":   {Data: []byte("CREATE TABLE b();")},
+		"m/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"m/0001_first.up.sql":    {Data: []byte("CREATE TABLE a();")},
+		"m/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+	migrations, err := parseMigrations(fsys, "m")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, 1, migrations[0].Version)
+	require.Equal(t, "first", migrations[0].Name)
+	require.Equal(t, "DROP TABLE a;", migrations[0].Down)
+	require.Equal(t, 2, migrations[1].Version)
+
+	// missing rollback
+	delete(fsys, "m/0002_second.down.sql")
+	_, err = parseMigrations(fsys, "m")
+	require.Error(t, err)
+
+	// malformed name
+	fsys["m/second.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE b;")}
+	_, err = parseMigrations(fsys, "m")
+	require.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package postgresql
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 )
 
@@ -26,3 +28,75 @@ func WithConnectionEventsPersist(persist bool) DBOption {
 	}
 }
 
+func WithActivePeersBackup(backup bool) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.backupActivePeers = backup
+		return nil
+	}
+}
+
+func WithStaticPeersInStats(include bool) DBOption {
+	return func (dbCli *DBClient) error {
+		dbCli.staticPeersInStats = include
+		return nil
+	}
+}
+
+// WithResurrectOnNewENR only un-deprecates the peers re-discovered with an ENR if its seq is
+// higher than the one of the last known ENR (the peers without ENR are always un-deprecated)
+func WithResurrectOnNewENR(resurrect bool) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.resurrectOnNewENR = resurrect
+		return nil
+	}
+}
+
+// WithMigrations applies the pending schema migrations when the client is created (enabled by default)
+func WithMigrations(apply bool) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.applyMigrations = apply
+		return nil
+	}
+}
+
+// WithRowsMirror copies the rows of the high-volume tables (conn_events, eth_attestations,
+// eth_aggregates) into the given mirror after each flush
+func WithRowsMirror(mirror RowsMirror) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.rowsMirror = mirror
+		return nil
+	}
+}
+
+// WithPeersHistory appends a snapshot of the active peers to peers_history on every interval
+// (disabled if 0)
+func WithPeersHistory(interval time.Duration) DBOption {
+	return func(dbCli *DBClient) error {
+		if interval < 0 {
+			return errors.Errorf("invalid peers history interval %s", interval)
+		}
+		dbCli.peersHistoryInterval = interval
+		return nil
+	}
+}
+
+// WithValidatorEstimates estimates the validators behind each of the active peers on every interval,
+// appending the estimates to eth_validator_estimates (disabled if 0)
+func WithValidatorEstimates(interval time.Duration) DBOption {
+	return func(dbCli *DBClient) error {
+		if interval < 0 {
+			return errors.Errorf("invalid validator estimates interval %s", interval)
+		}
+		dbCli.validatorEstimatesInterval = interval
+		return nil
+	}
+}
+
+// WithNetworkName tags the peers and the run with the name of the network, so that several networks
+// of the same family can share the DB (i.e. mainnet and gnosis)
+func WithNetworkName(name string) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.networkName = name
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+func (c *DBClient) DropPeerAddressHistoryTable() error {
+	log.Info("dropping table peer_address_history")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE peer_address_history;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitPeerAddressHistoryTable() error {
+	log.Info("init peer_address_history table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS peer_address_history(
+				peer_id TEXT NOT NULL,
+				node_id TEXT,
+				ip TEXT NOT NULL,
+				port INT NOT NULL,
+				first_seen TIMESTAMP NOT NULL,
+				last_seen TIMESTAMP NOT NULL,
+				observations INT NOT NULL DEFAULT 0,
+
+				PRIMARY KEY(peer_id, ip, port)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertPeerAddress adds the observation to the address history of the peer
+func (c *DBClient) UpsertPeerAddress(addr *models.PeerAddress) (query string, args []interface{}) {
+	log.Trace("upserting address of peer ", addr.PeerID.String())
+
+	query = `
+		INSERT INTO peer_address_history(
+			peer_id,
+			node_id,
+			ip,
+			port,
+			first_seen,
+			last_seen,
+			observations)
+		VALUES ($1,NULLIF($2, ''),$3,$4,$5,$5,1)
+		ON CONFLICT (peer_id, ip, port)
+		DO UPDATE SET
+			node_id = COALESCE(excluded.node_id, peer_address_history.node_id),
+			last_seen = GREATEST(peer_address_history.last_seen, excluded.last_seen),
+			observations = peer_address_history.observations + 1;
+	`
+
+	args = append(args, addr.PeerID.String())
+	args = append(args, addr.NodeID)
+	args = append(args, addr.IP)
+	args = append(args, addr.Port)
+	args = append(args, addr.Timestamp)
+
+	return query, args
+}
+
+// enrNodeID returns the node ID of the ENR the host was discovered with (empty if it came without ENR)
+func enrNodeID(hInfo *models.HostInfo) string {
+	hInfo.RLock()
+	defer hInfo.RUnlock()
+	enr, ok := hInfo.Attr[eth.EnrHostInfoAttribute].(*eth.EnrNode)
+	if !ok {
+		return ""
+	}
+	return enr.ID.String()
+}
+
+// GetPeerAddressHistory returns the addresses of the given peer in the order they were first seen
+func (c *DBClient) GetPeerAddressHistory(pID peer.ID) ([]models.PeerAddressRecord, error) {
+	log.Debugf("fetching address history of peer %s", pID.String())
+	history := make([]models.PeerAddressRecord, 0)
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT
+			ip,
+			port,
+			first_seen,
+			last_seen,
+			observations
+		FROM peer_address_history
+		WHERE peer_id = $1
+		ORDER BY first_seen, ip, port;
+		`,
+		pID.String(),
+	)
+	if err != nil {
+		return history, errors.Wrap(err, "unable to fetch peer address history")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record models.PeerAddressRecord
+		err = rows.Scan(&record.IP, &record.Port, &record.FirstSeen, &record.LastSeen, &record.Observations)
+		if err != nil {
+			return history, errors.Wrap(err, "unable to parse fetched peer address")
+		}
+		history = append(history, record)
+	}
+	return history, nil
+}
+
+// GetMovedPeers returns how many of the peers seen within the window showed up at a new address,
+// which would otherwise count as a peer leaving the network and another one joining it
+func (c *DBClient) GetMovedPeers(window time.Duration) (models.MovedPeers, error) {
+	log.Debugf("fetching peers moved in the last %s", window)
+	moved := models.MovedPeers{Window: window.String()}
+
+	// each new address is compared with the previous one of the peer
+	err := c.psqlPool.QueryRow(
+		c.ctx,
+		`
+		SELECT
+			count(DISTINCT peer_id) FILTER (WHERE last_seen > $1),
+			count(DISTINCT peer_id) FILTER (WHERE moved AND ip <> prev_ip),
+			count(DISTINCT peer_id) FILTER (WHERE moved AND ip = prev_ip),
+			count(*) FILTER (WHERE moved)
+		FROM (
+			SELECT
+				peer_id,
+				ip,
+				prev_ip,
+				last_seen,
+				first_seen > $1 AND prev_ip IS NOT NULL AS moved
+			FROM (
+				SELECT
+					peer_id,
+					ip,
+					first_seen,
+					last_seen,
+					lag(ip) OVER (PARTITION BY peer_id ORDER BY first_seen) AS prev_ip
+				FROM peer_address_history
+			) AS addrs
+		) AS changes;
+		`,
+		time.Now().Add(-window),
+	).Scan(&moved.Peers, &moved.MovedPeers, &moved.PortChangedPeers, &moved.Moves)
+	if err != nil {
+		return moved, errors.Wrap(err, "unable to fetch moved peers")
+	}
+	return moved, nil
+}
@@ -6,7 +6,9 @@ import (
 	pgx "github.com/jackc/pgx/v4"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
 	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/clientinfo"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -25,21 +27,35 @@ func (c *DBClient) InitPeerInfoTable() error {
 			multi_addrs TEXT[] NOT NULL,
 			ip TEXT NOT NULL,
 			port INT,
+			origin TEXT,
 
 			user_agent TEXT,
 			client_name TEXT,
 			client_version TEXT, 
+			client_version_major INT,
+			client_version_minor INT,
+			client_version_patch INT,
+			client_version_commit TEXT,
+			security_protocol TEXT,
 			client_os TEXT,
 			client_arch TEXT,
 			protocol_version TEXT,
 			sup_protocols TEXT[],
 			latency INT,
+
+			gossip_score REAL,
+			duplicate_ratio REAL,
+			invalid_msgs BIGINT,
+			reqresp_reliability REAL,
+			quality_score REAL,
+			quality_time TIMESTAMP,
 			
 			deprecated BOOL,
 			attempted BOOL,
 			last_activity BIGINT, 
 			last_conn_attempt BIGINT,
 			last_error TEXT,
+			last_error_raw TEXT,
 
 			PRIMARY KEY (peer_id)
 		);
@@ -49,12 +65,53 @@ func (c *DBClient) InitPeerInfoTable() error {
 		return errors.Wrap(err, "initializing peer_info table")
 	}
 
+	// add the columns that weren't there in previous versions of the table
+	_, err = c.psqlPool.Exec(c.ctx, `
+		ALTER TABLE peer_info
+			ADD COLUMN IF NOT EXISTS client_version_major INT,
+			ADD COLUMN IF NOT EXISTS client_version_minor INT,
+			ADD COLUMN IF NOT EXISTS client_version_patch INT,
+			ADD COLUMN IF NOT EXISTS client_version_commit TEXT,
+			ADD COLUMN IF NOT EXISTS security_protocol TEXT,
+			ADD COLUMN IF NOT EXISTS origin TEXT,
+			ADD COLUMN IF NOT EXISTS gossip_score REAL,
+			ADD COLUMN IF NOT EXISTS duplicate_ratio REAL,
+			ADD COLUMN IF NOT EXISTS invalid_msgs BIGINT,
+			ADD COLUMN IF NOT EXISTS reqresp_reliability REAL,
+			ADD COLUMN IF NOT EXISTS quality_score REAL,
+			ADD COLUMN IF NOT EXISTS quality_time TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS dialable BOOLEAN DEFAULT true,
+			ADD COLUMN IF NOT EXISTS deprecation_reason TEXT,
+			ADD COLUMN IF NOT EXISTS deprecated_at BIGINT,
+			ADD COLUMN IF NOT EXISTS conn_attempts BIGINT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS conn_successes BIGINT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS network_name TEXT,
+			ADD COLUMN IF NOT EXISTS reputation REAL,
+			ADD COLUMN IF NOT EXISTS reputation_time TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS last_error_raw TEXT;
+		`)
+	if err != nil {
+		return errors.Wrap(err, "updating the columns of peer_info table")
+	}
+
 	return nil
 }
 
 // InsertNewPeerInfo
 func (c *DBClient) UpsertHostInfo(hInfo *models.HostInfo) (q string, args []interface{}) {
 	log.Trace("upserting host in peer_info table")
+	// merge rules: a host without addresses (i.e. an inbound peer we only know by its ID) never
+	// overrides the known addresses of the peer
+	// a re-discovered peer is un-deprecated, unless it has to come with a newer ENR
+	// (only with the eth_nodes table, where the ENRs of the peers are)
+	deprecated := "excluded.deprecated"
+	if c.resurrectOnNewENR {
+		deprecated = `CASE
+				WHEN peer_info.deprecated AND $10::BIGINT IS NOT NULL AND
+					$10::BIGINT <= COALESCE((SELECT max(seq) FROM eth_nodes WHERE eth_nodes.peer_id = excluded.peer_id), -1)
+				THEN true
+				ELSE excluded.deprecated END`
+	}
 	// compose the query
 	q = `INSERT INTO peer_info (
 			peer_id,
@@ -62,14 +119,20 @@ func (c *DBClient) UpsertHostInfo(hInfo *models.HostInfo) (q string, args []inte
 			multi_addrs,
 			ip,
 			port,
-			deprecated)
-		VALUES ($1,$2,$3,$4,$5,$6)
+			deprecated,
+			origin,
+			dialable,
+			network_name)
+		VALUES ($1,$2,$3,$4,$5,$6,NULLIF($7, ''),$8,NULLIF($9, ''))
 		ON CONFLICT (peer_id)
 		DO UPDATE SET
-			multi_addrs = excluded.multi_addrs,
-			ip = excluded.ip,
-			port = excluded.port,
-			deprecated = excluded.deprecated;
+			multi_addrs = CASE WHEN cardinality(excluded.multi_addrs) > 0 THEN excluded.multi_addrs ELSE peer_info.multi_addrs END,
+			ip = COALESCE(NULLIF(excluded.ip, ''), peer_info.ip),
+			port = CASE WHEN NULLIF(excluded.ip, '') IS NULL THEN peer_info.port ELSE excluded.port END,
+			deprecated = ` + deprecated + `,
+			origin = COALESCE(peer_info.origin, excluded.origin),
+			dialable = excluded.dialable,
+			network_name = COALESCE(excluded.network_name, peer_info.network_name);
 		`
 
 	args = append(args, hInfo.ID.String())
@@ -78,73 +141,147 @@ func (c *DBClient) UpsertHostInfo(hInfo *models.HostInfo) (q string, args []inte
 	args = append(args, hInfo.IP)
 	args = append(args, hInfo.Port)
 	args = append(args, false)
+	// the origin is only set the first time we get to know the peer
+	args = append(args, hInfo.Origin)
+	// the last discovery decides whether the peer has to be dialed
+	args = append(args, !hInfo.NotDialable)
+	// the name of the network tells apart the peers of the networks that share the DB
+	args = append(args, c.networkName)
+	if c.resurrectOnNewENR {
+		args = append(args, enrSeq(hInfo))
+	}
 
 	return q, args
 }
 
+// enrSeq returns the seq of the ENR the host was discovered with (nil if it came without ENR)
+func enrSeq(hInfo *models.HostInfo) interface{} {
+	hInfo.RLock()
+	defer hInfo.RUnlock()
+	enr, ok := hInfo.Attr[eth.EnrHostInfoAttribute].(*eth.EnrNode)
+	if !ok {
+		return nil
+	}
+	return int64(enr.Seq)
+}
+
 // InsertNewPeerInfo
 func (c *DBClient) UpdatePeerInfo(pInfo *models.PeerInfo) (q string, args []interface{}) {
 	log.Trace("upserting peer in peer_info table")
-	// compose the query
+	// merge rules: an empty identification (i.e. an identify that timed out while a previous one
+	// succeeded) never downgrades the one we already have, field by field
 	q = `
 		UPDATE peer_info
 		SET
-			user_agent=$2,
-			client_name=$3,
-			client_version=$4,
-			client_os=$5,
-			client_arch=$6,
-			protocol_version=$7,
-			sup_protocols=$8,
-			latency=$9
+			user_agent=COALESCE(NULLIF($2, ''), user_agent),
+			client_name=CASE WHEN NULLIF($2, '') IS NULL THEN client_name ELSE $3 END,
+			client_version=CASE WHEN NULLIF($2, '') IS NULL THEN client_version ELSE $4 END,
+			client_os=CASE WHEN NULLIF($2, '') IS NULL THEN client_os ELSE $5 END,
+			client_arch=CASE WHEN NULLIF($2, '') IS NULL THEN client_arch ELSE $6 END,
+			protocol_version=COALESCE(NULLIF($7, ''), protocol_version),
+			sup_protocols=CASE WHEN cardinality($8::TEXT[]) > 0 THEN $8 ELSE sup_protocols END,
+			latency=COALESCE(NULLIF($9, 0), latency),
+			client_version_major=CASE WHEN NULLIF($2, '') IS NULL THEN client_version_major ELSE $10 END,
+			client_version_minor=CASE WHEN NULLIF($2, '') IS NULL THEN client_version_minor ELSE $11 END,
+			client_version_patch=CASE WHEN NULLIF($2, '') IS NULL THEN client_version_patch ELSE $12 END,
+			client_version_commit=CASE WHEN NULLIF($2, '') IS NULL THEN client_version_commit ELSE $13 END,
+			security_protocol=COALESCE(NULLIF($14, ''), security_protocol)
 		WHERE peer_id=$1;
 		`
 
 	// filter UserAgent to get client name, version, os, and arch
-	cliName, cliVers, cliOS, cliArch := utils.ParseClientType(c.Network, pInfo.UserAgent)
+	cliInfo := clientinfo.Parse(c.Network, pInfo.UserAgent)
+	semVer := cliInfo.SemVer
 
 	args = append(args, pInfo.RemotePeer.String())
 	args = append(args, pInfo.UserAgent)
-	args = append(args, cliName)
-	args = append(args, cliVers)
-	args = append(args, cliOS)
-	args = append(args, cliArch)
+	args = append(args, string(cliInfo.Name))
+	args = append(args, cliInfo.Version)
+	args = append(args, string(cliInfo.OS))
+	args = append(args, string(cliInfo.Arch))
 	args = append(args, pInfo.ProtocolVersion)
 	args = append(args, pInfo.Protocols)
 	args = append(args, pInfo.Latency.Milliseconds())
+	// leave the semver columns as NULL if the version couldn't be parsed
+	if semVer.Valid {
+		args = append(args, semVer.Major)
+		args = append(args, semVer.Minor)
+		args = append(args, semVer.Patch)
+		args = append(args, semVer.Commit)
+	} else {
+		args = append(args, nil, nil, nil, nil)
+	}
+	// keep the previous security protocol if we don't know the current one
+	args = append(args, pInfo.Security)
+
+	return q, args
+}
+
+// UpdatePeerQuality sets the quality score of the peer, keeping the previous value of the components that weren't measured
+func (c *DBClient) UpdatePeerQuality(quality *models.PeerQuality) (q string, args []interface{}) {
+	log.Trace("updating quality score in peer_info table")
+	q = `
+		UPDATE peer_info
+		SET
+			gossip_score=COALESCE($2, gossip_score),
+			duplicate_ratio=COALESCE($3, duplicate_ratio),
+			invalid_msgs=COALESCE($4, invalid_msgs),
+			reqresp_reliability=COALESCE($5, reqresp_reliability),
+			quality_score=$6,
+			quality_time=$7
+		WHERE peer_id=$1;
+		`
+
+	args = append(args, quality.PeerID.String())
+	args = append(args, quality.GossipScore)
+	args = append(args, quality.DuplicateRatio)
+	args = append(args, quality.InvalidMsgs)
+	args = append(args, quality.ReqRespReliability)
+	args = append(args, quality.Score)
+	args = append(args, quality.Timestamp)
 
 	return q, args
 }
 
 func (c *DBClient) UpdateConnAttempt(connAttempt *models.ConnectionAttempt) (query string, args []interface{}) {
 	log.Tracef("updating peer_info because of new conn attempt %+v", connAttempt)
-	// logic to determine how to update the table
+	// merge rules, as the attempts of a peer can be persisted out of order:
+	// - the attempt counters are only incremented
+	// - last_activity and last_conn_attempt keep the max of the timestamps
+	// - the state of the peer (deprecation, last error) only changes with the newest attempt
 	if connAttempt.Status == models.PossitiveAttempt {
 		// we have the chance to un-deprecate the peer
 		query = `
 				UPDATE peer_info
 				SET 
-					deprecated=$2,
+					deprecated=CASE WHEN $4 >= COALESCE(last_conn_attempt, 0) THEN $2 ELSE deprecated END,
 					attempted=$3,
-					last_activity=$4, 
-					last_conn_attempt=$5,
-					last_error=$6
+					last_activity=GREATEST(last_activity, $4), 
+					last_conn_attempt=GREATEST(last_conn_attempt, $4),
+					last_error=CASE WHEN $4 >= COALESCE(last_conn_attempt, 0) THEN $5 ELSE last_error END,
+					last_error_raw=CASE WHEN $4 >= COALESCE(last_conn_attempt, 0) THEN NULLIF($6, '') ELSE last_error_raw END,
+					conn_attempts=conn_attempts + 1,
+					conn_successes=conn_successes + 1
 				WHERE peer_id=$1;
 			`
 		args = append(args, connAttempt.RemotePeer.String())
 		args = append(args, false)                        // Un-Deprecate peer
 		args = append(args, true)                         // Connection was attempted
 		args = append(args, connAttempt.Timestamp.Unix()) // attempt timestamp (same as our new last activity)
-		args = append(args, connAttempt.Timestamp.Unix()) // attempt timestamp (same as our new last activity)
 		args = append(args, connAttempt.Error)
+		args = append(args, connAttempt.RawError)
 	} else {
 		query = `
 			UPDATE peer_info
 			SET 
-				deprecated=$2,
+				deprecated=CASE WHEN $4 >= COALESCE(last_conn_attempt, 0) THEN $2 ELSE deprecated END,
 				attempted=$3,
-				last_conn_attempt=$4,
-				last_error=$5
+				last_conn_attempt=GREATEST(last_conn_attempt, $4),
+				last_error=CASE WHEN $4 >= COALESCE(last_conn_attempt, 0) THEN $5 ELSE last_error END,
+				last_error_raw=CASE WHEN $4 >= COALESCE(last_conn_attempt, 0) THEN NULLIF($7, '') ELSE last_error_raw END,
+				deprecation_reason=CASE WHEN $2 AND $4 >= COALESCE(last_conn_attempt, 0) THEN NULLIF($6, '') ELSE deprecation_reason END,
+				deprecated_at=CASE WHEN $2 AND $4 >= COALESCE(last_conn_attempt, 0) AND NOT COALESCE(deprecated, false) THEN $4 ELSE deprecated_at END,
+				conn_attempts=conn_attempts + 1
 			WHERE peer_id=$1;
 		`
 		args = append(args, connAttempt.RemotePeer.String())
@@ -152,6 +289,8 @@ func (c *DBClient) UpdateConnAttempt(connAttempt *models.ConnectionAttempt) (que
 		args = append(args, true) // connection attempted
 		args = append(args, connAttempt.Timestamp.Unix())
 		args = append(args, connAttempt.Error)
+		args = append(args, connAttempt.DeprecationReason)
+		args = append(args, connAttempt.RawError)
 	}
 
 	return query, args
@@ -177,6 +316,7 @@ func (c *DBClient) GetFullHostInfo(pID peer.ID) (*models.HostInfo, error) {
 			multi_addrs,
 			ip,
 			port,
+			COALESCE(origin, ''),
 			user_agent,
 			protocol_version,
 			sup_protocols,
@@ -193,6 +333,7 @@ func (c *DBClient) GetFullHostInfo(pID peer.ID) (*models.HostInfo, error) {
 		&maddresses,
 		&hInfo.IP,
 		&hInfo.Port,
+		&hInfo.Origin,
 		&pInfo.UserAgent,
 		&pInfo.ProtocolVersion,
 		&pInfo.Protocols,
@@ -320,7 +461,10 @@ func (c *DBClient) GetNonDeprecatedPeers() ([]*models.RemoteConnectablePeer, err
 			network,
 			multi_addrs
 		FROM peer_info
-		WHERE deprecated='false';`)
+		WHERE deprecated='false' and dialable='true' and
+		      ($1 = '' OR network_name = $1);`,
+		c.networkName,
+	)
 
 	// If there are no rows, don't panic
 	if err != nil && err != pgx.ErrNoRows {
@@ -370,3 +514,59 @@ func (c *DBClient) GetNonDeprecatedPeers() ([]*models.RemoteConnectablePeer, err
 	}
 	return connectPeers, nil
 }
+
+// GetDeprecatedPeers returns the deprecated peers of the network that weren't attempted since the given time,
+// the ones that were attempted the longest ago first
+func (c *DBClient) GetDeprecatedPeers(network utils.NetworkType, before time.Time, limit int) ([]*models.RemoteConnectablePeer, error) {
+	log.Tracef("retrieving the deprecated peers not attempted since %s\n", before)
+	connectPeers := make([]*models.RemoteConnectablePeer, 0)
+
+	rows, err := c.psqlPool.Query(c.ctx, `
+		SELECT
+			peer_id,
+			multi_addrs
+		FROM peer_info
+		WHERE deprecated='true' and
+		      dialable='true' and
+		      network=$1 and
+		      ($4 = '' OR network_name = $4) and
+		      cardinality(multi_addrs) > 0 and
+		      (last_conn_attempt IS NULL OR last_conn_attempt < $2)
+		ORDER BY last_conn_attempt ASC NULLS FIRST
+		LIMIT $3;`,
+		string(network),
+		before.Unix(),
+		limit,
+		c.networkName,
+	)
+	if err != nil {
+		return connectPeers, errors.Wrap(err, "unable to retrieve deprecated peers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peerIDStr string
+		var mAddrsStr []string
+
+		err := rows.Scan(&peerIDStr, &mAddrsStr)
+		if err != nil {
+			return connectPeers, err
+		}
+		peerID, err := peer.Decode(peerIDStr)
+		if err != nil {
+			log.Errorf("unable to get peerID from DB %s \n", peerIDStr)
+			continue
+		}
+		maddrs := make([]ma.Multiaddr, 0, len(mAddrsStr))
+		for _, element := range mAddrsStr {
+			mAddr, err := ma.NewMultiaddr(element)
+			if err != nil {
+				log.Error(errors.Wrap(err, "unable to parse mAddrs reading deprecated peers"))
+				continue
+			}
+			maddrs = append(maddrs, mAddr)
+		}
+		connectPeers = append(connectPeers, models.NewRemoteConnectablePeer(peerID, maddrs, network))
+	}
+	return connectPeers, nil
+}
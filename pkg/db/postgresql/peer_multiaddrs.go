@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) DropPeerMultiaddrsTable() error {
+	log.Info("dropping table peer_multiaddrs")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE peer_multiaddrs;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitPeerMultiaddrsTable() error {
+	log.Info("init peer_multiaddrs table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS peer_multiaddrs(
+				peer_id TEXT NOT NULL,
+				multi_addr TEXT NOT NULL,
+				sources TEXT[] NOT NULL,
+				first_seen TIMESTAMP NOT NULL,
+				last_seen TIMESTAMP NOT NULL,
+				observations INT NOT NULL DEFAULT 0,
+				dial_attempts INT NOT NULL DEFAULT 0,
+				dial_successes INT NOT NULL DEFAULT 0,
+				last_success TIMESTAMP,
+
+				PRIMARY KEY(peer_id, multi_addr)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertPeerMultiaddrs adds the observation to the history of each of the multiaddrs of the peer
+func (c *DBClient) UpsertPeerMultiaddrs(obs *models.MultiaddrObservation) (query string, args []interface{}) {
+	log.Trace("upserting multiaddrs of peer ", obs.PeerID.String())
+
+	query = `
+		INSERT INTO peer_multiaddrs(
+			peer_id,
+			multi_addr,
+			sources,
+			first_seen,
+			last_seen,
+			observations,
+			dial_attempts,
+			dial_successes,
+			last_success)
+		SELECT $1, addr, ARRAY[$3::TEXT], $4, $4, 1, $5::INT, $6::INT, CASE WHEN $6::INT > 0 THEN $4::TIMESTAMP END
+		FROM unnest($2::TEXT[]) AS addr
+		ON CONFLICT (peer_id, multi_addr)
+		DO UPDATE SET
+			sources = CASE WHEN $3::TEXT = ANY(peer_multiaddrs.sources) THEN peer_multiaddrs.sources
+				ELSE array_append(peer_multiaddrs.sources, $3::TEXT) END,
+			last_seen = excluded.last_seen,
+			observations = peer_multiaddrs.observations + 1,
+			dial_attempts = peer_multiaddrs.dial_attempts + excluded.dial_attempts,
+			dial_successes = peer_multiaddrs.dial_successes + excluded.dial_successes,
+			last_success = COALESCE(excluded.last_success, peer_multiaddrs.last_success);
+	`
+
+	addrs := make([]string, 0, len(obs.Addrs))
+	for _, addr := range obs.Addrs {
+		addrs = append(addrs, addr.String())
+	}
+	dialed, succeeded := 0, 0
+	if obs.Dialed {
+		dialed = 1
+		if obs.Success {
+			succeeded = 1
+		}
+	}
+	args = append(args, obs.PeerID.String())
+	args = append(args, addrs)
+	args = append(args, obs.Source)
+	args = append(args, obs.Timestamp)
+	args = append(args, dialed)
+	args = append(args, succeeded)
+
+	return query, args
+}
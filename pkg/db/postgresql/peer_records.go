@@ -0,0 +1,72 @@
+package postgresql
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) DropSignedPeerRecordsTable() error {
+	log.Info("dropping table signed_peer_records")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE signed_peer_records;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitSignedPeerRecordsTable() error {
+	log.Info("init signed_peer_records table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS signed_peer_records(
+				peer_id TEXT NOT NULL,
+				seq BIGINT NOT NULL,
+				multi_addrs TEXT[] NOT NULL,
+				envelope BYTEA NOT NULL,
+				first_seen TIMESTAMP NOT NULL,
+				last_seen TIMESTAMP NOT NULL,
+
+				PRIMARY KEY(peer_id, seq)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertSignedPeerRecord inserts a new sequence of the signed peer record of a peer,
+// or refreshes the last time that an already known sequence was seen
+func (c *DBClient) UpsertSignedPeerRecord(rec *models.SignedPeerRecord) (query string, args []interface{}) {
+	log.Trace("upserting signed peer record of ", rec.PeerID.String())
+
+	query = `
+		INSERT INTO signed_peer_records(
+			peer_id,
+			seq,
+			multi_addrs,
+			envelope,
+			first_seen,
+			last_seen)
+		VALUES ($1,$2,$3,$4,$5,$5)
+		ON CONFLICT (peer_id, seq)
+		DO UPDATE SET
+			last_seen = excluded.last_seen;
+	`
+
+	addrs := make([]string, 0, len(rec.Addrs))
+	for _, addr := range rec.Addrs {
+		addrs = append(addrs, addr.String())
+	}
+	args = append(args, rec.PeerID.String())
+	// libp2p uses unix nanoseconds as sequence numbers, which fit in a BIGINT
+	args = append(args, int64(rec.Seq))
+	args = append(args, addrs)
+	args = append(args, rec.Envelope)
+	args = append(args, rec.Timestamp)
+
+	return query, args
+}
@@ -0,0 +1,149 @@
+package postgresql
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) DropPeerReputationTable() error {
+	log.Info("dropping table peer_reputation")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE peer_reputation;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitPeerReputationTable() error {
+	log.Info("init peer_reputation table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS peer_reputation(
+				peer_id TEXT NOT NULL,
+				timestamp TIMESTAMP NOT NULL,
+				dial_reliability REAL,
+				identify_success REAL,
+				gossip_behaviour REAL,
+				invalid_msgs BIGINT,
+				score REAL NOT NULL,
+
+				PRIMARY KEY(peer_id, timestamp)
+			);
+		`,
+	)
+	return err
+}
+
+// InsertPeerReputation appends the reputation of the peer to its history
+func (c *DBClient) InsertPeerReputation(rep *models.PeerReputation) (query string, args []interface{}) {
+	log.Trace("inserting reputation of peer ", rep.PeerID.String())
+
+	query = `
+		INSERT INTO peer_reputation(
+			peer_id,
+			timestamp,
+			dial_reliability,
+			identify_success,
+			gossip_behaviour,
+			invalid_msgs,
+			score)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (peer_id, timestamp) DO NOTHING;
+	`
+
+	args = append(args, rep.PeerID.String())
+	args = append(args, rep.Timestamp)
+	args = append(args, rep.DialReliability)
+	args = append(args, rep.IdentifySuccess)
+	args = append(args, rep.GossipBehaviour)
+	args = append(args, rep.InvalidMsgs)
+	args = append(args, rep.Score)
+
+	return query, args
+}
+
+// UpdatePeerReputation sets the latest reputation of the peer in peer_info, where the peering reads it from
+func (c *DBClient) UpdatePeerReputation(rep *models.PeerReputation) (query string, args []interface{}) {
+	log.Trace("updating reputation in peer_info table")
+
+	query = `
+		UPDATE peer_info
+		SET
+			reputation=$2,
+			reputation_time=$3
+		WHERE peer_id=$1 and (reputation_time IS NULL OR reputation_time <= $3);
+	`
+
+	args = append(args, rep.PeerID.String())
+	args = append(args, rep.Score)
+	args = append(args, rep.Timestamp)
+
+	return query, args
+}
+
+// GetReputationInputs returns, for each of the non-deprecated peers, its dials, the connections on
+// which it got identified, and its last gossip score and invalid messages
+func (c *DBClient) GetReputationInputs() (map[peer.ID]*models.ReputationInput, error) {
+	log.Debug("fetching the reputation inputs of the peers")
+	inputs := make(map[peer.ID]*models.ReputationInput)
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT
+			p.peer_id,
+			p.conn_attempts,
+			p.conn_successes,
+			COALESCE(e.connections, 0),
+			COALESCE(e.identified, 0),
+			p.gossip_score::DOUBLE PRECISION,
+			p.invalid_msgs
+		FROM peer_info p
+		LEFT JOIN (
+			SELECT
+				peer_id,
+				count(*) AS connections,
+				count(*) FILTER (WHERE identified) AS identified
+			FROM conn_events
+			GROUP BY peer_id
+		) AS e ON e.peer_id = p.peer_id
+		WHERE p.deprecated = 'false' and ($1 = '' OR p.network_name = $1);
+		`,
+		c.networkName,
+	)
+	if err != nil {
+		return inputs, errors.Wrap(err, "unable to fetch the reputation inputs")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peerIDStr string
+		input := &models.ReputationInput{}
+		err = rows.Scan(
+			&peerIDStr,
+			&input.DialAttempts,
+			&input.DialSuccesses,
+			&input.Connections,
+			&input.Identified,
+			&input.GossipScore,
+			&input.InvalidMsgs,
+		)
+		if err != nil {
+			return inputs, errors.Wrap(err, "unable to parse the reputation inputs")
+		}
+		input.PeerID, err = peer.Decode(peerIDStr)
+		if err != nil {
+			log.Errorf("unable to get peerID from DB %s", peerIDStr)
+			continue
+		}
+		inputs[input.PeerID] = input
+	}
+	return inputs, rows.Err()
+}
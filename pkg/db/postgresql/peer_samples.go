@@ -0,0 +1,112 @@
+package postgresql
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func (c *DBClient) InitPeerSamplesTable() error {
+	log.Info("init peer_samples table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS peer_samples(
+				id SERIAL,
+				timestamp TIMESTAMP NOT NULL,
+				network TEXT NOT NULL,
+				seed BIGINT NOT NULL,
+				stratify TEXT,
+				size INT NOT NULL,
+				candidates INT NOT NULL,
+				peers TEXT[] NOT NULL,
+
+				PRIMARY KEY(id)
+			);
+		`,
+	)
+	return err
+}
+
+// InsertPeerSample records the seed and the peers of a sample, so that the experiments
+// run on top of it can be traced back to it
+func (c *DBClient) InsertPeerSample(sample *models.PeerSample) (query string, args []interface{}) {
+	log.Tracef("inserting peer sample with seed %d", sample.Seed)
+
+	query = `
+		INSERT INTO peer_samples(
+			timestamp,
+			network,
+			seed,
+			stratify,
+			size,
+			candidates,
+			peers)
+		VALUES ($1,$2,$3,NULLIF($4, ''),$5,$6,$7);
+	`
+
+	peers := make([]string, 0, len(sample.Peers))
+	for _, p := range sample.Peers {
+		peers = append(peers, p.PeerID.String())
+	}
+
+	args = append(args, sample.Timestamp)
+	args = append(args, sample.Network)
+	args = append(args, sample.Seed)
+	args = append(args, sample.Stratify)
+	args = append(args, sample.Size)
+	args = append(args, sample.Candidates)
+	args = append(args, peers)
+
+	return query, args
+}
+
+// GetSampleCandidates returns the known (non-deprecated and dialable) peers of the network,
+// with their client and country ("unknown" if they weren't identified/located yet)
+func (c *DBClient) GetSampleCandidates(network utils.NetworkType) ([]*models.SampledPeer, error) {
+	candidates := make([]*models.SampledPeer, 0)
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT
+			peer_info.peer_id,
+			COALESCE(NULLIF(peer_info.client_name, ''), 'unknown'),
+			COALESCE(NULLIF(ips.country_code, ''), 'unknown'),
+			peer_info.multi_addrs
+		FROM peer_info
+		LEFT JOIN ips ON ips.ip = peer_info.ip
+		WHERE peer_info.deprecated='false' and
+		      peer_info.dialable='true' and
+		      peer_info.network=$1 and
+		      ($2 = '' OR peer_info.network_name = $2) and
+		      cardinality(peer_info.multi_addrs) > 0;
+		`,
+		string(network),
+		c.networkName,
+	)
+	if err != nil {
+		return candidates, errors.Wrap(err, "unable to retrieve the sample candidates")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peerIDStr string
+		candidate := &models.SampledPeer{}
+		err := rows.Scan(&peerIDStr, &candidate.Client, &candidate.Country, &candidate.Addrs)
+		if err != nil {
+			return candidates, errors.Wrap(err, "unable to parse the sample candidates")
+		}
+		candidate.PeerID, err = peer.Decode(peerIDStr)
+		if err != nil {
+			log.Errorf("unable to get peerID from DB %s", peerIDStr)
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
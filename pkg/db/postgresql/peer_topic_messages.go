@@ -0,0 +1,54 @@
+package postgresql
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitPeerTopicMessagesTable() error {
+	log.Info("init peer_topic_messages table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS peer_topic_messages(
+				peer_id TEXT NOT NULL,
+				topic TEXT NOT NULL,
+				messages BIGINT NOT NULL DEFAULT 0,
+				first_seen TIMESTAMP NOT NULL,
+				last_seen TIMESTAMP NOT NULL,
+
+				PRIMARY KEY(peer_id, topic)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertPeerTopicMessages adds the messages that the peer delivered on the topic to the ones
+// of the previous reports
+func (c *DBClient) UpsertPeerTopicMessages(topicMsgs *models.PeerTopicMessages) (query string, args []interface{}) {
+	log.Trace("upserting topic messages of peer ", topicMsgs.PeerID.String())
+
+	query = `
+		INSERT INTO peer_topic_messages(
+			peer_id,
+			topic,
+			messages,
+			first_seen,
+			last_seen)
+		VALUES ($1,$2,$3,$4,$4)
+		ON CONFLICT (peer_id, topic)
+		DO UPDATE SET
+			messages = peer_topic_messages.messages + excluded.messages,
+			last_seen = excluded.last_seen;
+	`
+
+	args = append(args, topicMsgs.PeerID.String())
+	args = append(args, topicMsgs.Topic)
+	args = append(args, topicMsgs.Messages)
+	args = append(args, topicMsgs.Timestamp)
+
+	return query, args
+}
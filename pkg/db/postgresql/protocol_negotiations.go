@@ -0,0 +1,125 @@
+package postgresql
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitProtocolNegotiationsTable() error {
+	log.Info("init peer_protocol_negotiations table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS peer_protocol_negotiations(
+				peer_id TEXT NOT NULL,
+				protocol TEXT NOT NULL,
+				attempts INT NOT NULL DEFAULT 1,
+				failures INT NOT NULL DEFAULT 0,
+				total_ms BIGINT NOT NULL DEFAULT 0,
+				last_ms BIGINT NOT NULL,
+				last_error TEXT,
+				last_seen TIMESTAMP NOT NULL,
+
+				PRIMARY KEY(peer_id, protocol)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertProtocolNegotiation accounts the negotiation of a protocol with the peer, aggregating
+// the times of the agreed negotiations and the failures
+func (c *DBClient) UpsertProtocolNegotiation(negotiation *models.ProtocolNegotiation) (query string, args []interface{}) {
+	log.Trace("upserting protocol negotiation of peer ", negotiation.PeerID.String())
+
+	query = `
+		INSERT INTO peer_protocol_negotiations(
+			peer_id,
+			protocol,
+			failures,
+			total_ms,
+			last_ms,
+			last_error,
+			last_seen)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (peer_id, protocol)
+		DO UPDATE SET
+			attempts = peer_protocol_negotiations.attempts + 1,
+			failures = peer_protocol_negotiations.failures + excluded.failures,
+			total_ms = peer_protocol_negotiations.total_ms + excluded.total_ms,
+			last_ms = excluded.last_ms,
+			last_error = excluded.last_error,
+			last_seen = excluded.last_seen;
+	`
+
+	failures := 0
+	totalMs := negotiation.Duration.Milliseconds()
+	if negotiation.Failed() {
+		// only the agreed negotiations account for the time
+		failures = 1
+		totalMs = 0
+	}
+
+	args = append(args, negotiation.PeerID.String())
+	args = append(args, negotiation.Protocol)
+	args = append(args, failures)
+	args = append(args, totalMs)
+	args = append(args, negotiation.Duration.Milliseconds())
+	args = append(args, negotiation.Error)
+	args = append(args, negotiation.Timestamp)
+
+	return query, args
+}
+
+// GetNegotiationStatsByClient returns the negotiation time and failure rate of each protocol
+// with the active peers of each client, surfacing clients with slow or broken multistream implementations
+func (db *DBClient) GetNegotiationStatsByClient() ([]models.NegotiationStats, error) {
+	stats := make([]models.NegotiationStats, 0)
+	rows, err := db.psqlPool.Query(
+		db.ctx,
+		`
+		SELECT
+			peer_info.client_name,
+			neg.protocol,
+			sum(neg.attempts) as attempts,
+			sum(neg.failures) as failures,
+			sum(neg.total_ms) as total_ms
+		FROM peer_protocol_negotiations AS neg
+		INNER JOIN peer_info ON peer_info.peer_id = neg.peer_id
+		WHERE peer_info.deprecated='false' and
+		      peer_info.client_name IS NOT NULL and
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = 'true')) and
+		      ($3 = '' OR peer_info.network_name = $3) and
+		      to_timestamp(peer_info.last_activity) > CURRENT_TIMESTAMP - ($1 * INTERVAL '1 DAY')
+		GROUP BY peer_info.client_name, neg.protocol
+		ORDER BY peer_info.client_name, neg.protocol;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return stats, errors.Wrap(err, "unable to fetch negotiation stats")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.NegotiationStats
+		var totalMs int64
+		err = rows.Scan(&s.Client, &s.Protocol, &s.Attempts, &s.Failures, &totalMs)
+		if err != nil {
+			return stats, errors.Wrap(err, "unable to parse negotiation stats")
+		}
+		if agreed := s.Attempts - s.Failures; agreed > 0 {
+			s.AvgMs = float64(totalMs) / float64(agreed)
+		}
+		if s.Attempts > 0 {
+			s.FailureRate = float64(s.Failures) / float64(s.Attempts)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
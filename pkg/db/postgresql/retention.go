@@ -0,0 +1,102 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// retention queries of each table, where $1 is the time limit (or the number of slots for
+// the slot-based tables)
+var (
+	pruneQueries = map[string]string{
+		"conn_events":     `DELETE FROM conn_events WHERE disconn_time < $1;`,
+		"gossip_messages": `DELETE FROM gossip_messages WHERE last_seen < $1;`,
+		"peers_history":   `DELETE FROM peers_history WHERE timestamp < $1;`,
+		// keep always the latest version of the ENR of each node
+		"eth_node_records": `
+			DELETE FROM eth_node_records r
+			WHERE r.first_seen < $1 AND r.seq < (
+				SELECT max(seq) FROM eth_node_records l WHERE l.node_id = r.node_id);`,
+		"eth_attestations":  `DELETE FROM eth_attestations WHERE slot < (SELECT max(slot) FROM eth_attestations) - $1;`,
+		"eth_aggregates":    `DELETE FROM eth_aggregates WHERE slot < (SELECT max(slot) FROM eth_aggregates) - $1;`,
+		"eth_blob_sidecars": `DELETE FROM eth_blob_sidecars WHERE slot < (SELECT max(slot) FROM eth_blob_sidecars) - $1;`,
+		"eth_blocks":        `DELETE FROM eth_blocks WHERE slot < (SELECT max(slot) FROM eth_blocks) - $1;`,
+	}
+
+	// the deleted rows are aggregated per day into the rollup tables (the distinct peers of a day
+	// are summed if the day is split across two retention passes)
+	rollupQueries = map[string]string{
+		"conn_events": `
+			WITH moved AS (
+				DELETE FROM conn_events WHERE disconn_time < $1
+				RETURNING peer_id, direction, error, conn_time, disconn_time
+			), rollup AS (
+				INSERT INTO conn_events_rollups(day, direction, error, events, peers, total_duration)
+				SELECT to_timestamp(conn_time)::date, direction, error, count(*), count(DISTINCT peer_id), sum(disconn_time - conn_time)
+				FROM moved
+				GROUP BY 1, 2, 3
+				ON CONFLICT (day, direction, error) DO UPDATE SET
+					events = conn_events_rollups.events + excluded.events,
+					peers = conn_events_rollups.peers + excluded.peers,
+					total_duration = conn_events_rollups.total_duration + excluded.total_duration
+			)
+			SELECT count(*) FROM moved;`,
+		"gossip_messages": `
+			WITH moved AS (
+				DELETE FROM gossip_messages WHERE last_seen < $1
+				RETURNING topic, first_seen, duplicates
+			), rollup AS (
+				INSERT INTO gossip_messages_rollups(day, topic, messages, duplicates)
+				SELECT first_seen::date, topic, count(*), sum(duplicates)
+				FROM moved
+				GROUP BY 1, 2
+				ON CONFLICT (day, topic) DO UPDATE SET
+					messages = gossip_messages_rollups.messages + excluded.messages,
+					duplicates = gossip_messages_rollups.duplicates + excluded.duplicates
+			)
+			SELECT count(*) FROM moved;`,
+	}
+)
+
+// ApplyTableRetention deletes the rows of the table older than the given window, aggregating
+// them first into the daily rollups of the table if rollup is set. Returns the number of deleted rows
+func (c *DBClient) ApplyTableRetention(table string, window time.Duration, rollup bool) (int64, error) {
+	limit := time.Now().Add(-window)
+	log.Debugf("applying retention to %s (older than %s, rollup %t)", table, limit, rollup)
+
+	var arg interface{}
+	switch table {
+	case "conn_events", "eth_node_records":
+		arg = limit.Unix()
+	case "gossip_messages", "peers_history":
+		arg = limit
+	default:
+		// gossip messages only track the time inside the slot, so use the slots instead
+		arg = int64(window / ethSlotDuration)
+	}
+
+	if rollup {
+		query, ok := rollupQueries[table]
+		if !ok {
+			return 0, errors.Errorf("no rollup for table %s", table)
+		}
+		var deleted int64
+		err := c.psqlPool.QueryRow(c.ctx, query, arg).Scan(&deleted)
+		if err != nil {
+			return 0, errors.Wrap(err, "unable to roll up "+table)
+		}
+		return deleted, nil
+	}
+
+	query, ok := pruneQueries[table]
+	if !ok {
+		return 0, errors.Errorf("no retention for table %s", table)
+	}
+	tag, err := c.psqlPool.Exec(c.ctx, query, arg)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to apply retention to "+table)
+	}
+	return tag.RowsAffected(), nil
+}
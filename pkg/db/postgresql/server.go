@@ -3,11 +3,15 @@ package postgresql
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/migalabs/armiarma/pkg/db/models"
 	"github.com/migalabs/armiarma/pkg/gossipsub"
 	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/filecoin"
+	"github.com/migalabs/armiarma/pkg/networks/portal"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
 	"github.com/migalabs/armiarma/pkg/utils"
 	log "github.com/sirupsen/logrus"
 
@@ -33,6 +37,8 @@ type DBClient struct {
 
 	// Network that we are Crawling
 	Network utils.NetworkType
+	// name of the network within its family, set when several networks share the DB
+	networkName string
 
 	// Pgx Postgres variables
 	loginStr string
@@ -42,9 +48,32 @@ type DBClient struct {
 	persistC chan interface{}
 	doneC    chan struct{}
 	wg       *sync.WaitGroup
+	// once closed, the items sent to persist are dropped (the persisters already flushed)
+	closeM  *sync.RWMutex
+	closed  bool
+	dropped int64
+	// run that the event rows get tagged with (0 until the run is inserted)
+	runID int64
 
 	// Control Variables
 	persistConnEvents bool
+	backupActivePeers bool
+	// static peers are excluded from the crawl statistics by default
+	staticPeersInStats bool
+	// deprecated peers re-discovered with an ENR only come back if the ENR is newer
+	resurrectOnNewENR bool
+	// pending schema migrations are applied at startup
+	applyMigrations bool
+	// interval of the snapshots of the active peers into peers_history (disabled if 0)
+	peersHistoryInterval time.Duration
+	// interval of the estimates of the validators behind the peers (disabled if 0)
+	validatorEstimatesInterval time.Duration
+
+	// optional copy of the high-volume tables (i.e. ClickHouse)
+	rowsMirror RowsMirror
+
+	// stats of the flushes of the batched writes
+	writerStats *writerStats
 }
 
 func NewDBClient(
@@ -101,7 +130,11 @@ func NewDBClient(
 		persistC:            persistC,
 		doneC:               make(chan struct{}),
 		wg:                  &wg,
+		closeM:              &sync.RWMutex{},
 		persistConnEvents:   true,
+		backupActivePeers:   true,
+		applyMigrations:     true,
+		writerStats:         newWriterStats(),
 	}
 
 	// Check for all the available options
@@ -112,12 +145,34 @@ func NewDBClient(
 		}
 	}
 
+	// bring the schema up to date before persisting anything
+	if dbClient.applyMigrations {
+		_, err = dbClient.ApplyMigrations(false)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply the db migrations")
+		}
+	}
+
 	// run the db persisters
 	for i := 0; i < maxPersisters; i++ {
-		go dbClient.launchPersister()
+		dbClient.launchPersister()
 	}
 	// launch the daily backup heartbeat
-	go dbClient.dailyBackupheartbeat()
+	if dbClient.backupActivePeers {
+		go dbClient.dailyBackupheartbeat()
+	}
+	// launch the snapshots of the peers history
+	if dbClient.peersHistoryInterval > 0 {
+		go dbClient.peersHistoryHeartbeat()
+	}
+	// launch the estimates of the validators of the peers
+	if dbClient.validatorEstimatesInterval > 0 {
+		go dbClient.validatorEstimatesHeartbeat()
+	}
+	// check the identities of the records stored by previous runs
+	if dbClient.linksIdentities() {
+		go dbClient.checkNodeIdentities()
+	}
 	return dbClient, nil
 }
 
@@ -150,6 +205,152 @@ func (c *DBClient) initTables() error {
 		return errors.Wrap(err, "initializing active_peers backup")
 	}
 
+	// crawler runs' metadata
+	err = c.InitCrawlerRunsTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing crawler_runs table")
+	}
+	err = c.InitRunResourceUsageTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing run_resource_usage table")
+	}
+
+	// trusted/static peers
+	err = c.InitStaticPeersTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing static_peers table")
+	}
+
+	// blocklisted ip ranges and asns
+	err = c.InitBlocklistTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing blocklist table")
+	}
+
+	// peers dialed in the target-list crawl mode
+	err = c.InitTargetPeersTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing target_peers table")
+	}
+
+	// persisted identities of the crawler and their changes
+	err = c.InitHostIdentitiesTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing host_identities table")
+	}
+	err = c.InitIdentityChangesTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing identity_changes table")
+	}
+
+	// periodic snapshots of the active peers
+	err = c.InitPeersHistoryTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peers_history table")
+	}
+
+	// daily rollups of the crawl statistics
+	err = c.InitStatsRollupsTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing stats_rollups table")
+	}
+
+	// signed peer records shared through identify
+	err = c.InitSignedPeerRecordsTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing signed_peer_records table")
+	}
+
+	// protocols announced by the peers through identify
+	err = c.InitPeerProtocolsTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_protocols table")
+	}
+
+	// summaries of the network at the end of each crawl round
+	err = c.InitRoundSnapshotTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing round_snapshot table")
+	}
+
+	// random samples of the known peers for the measurement experiments
+	err = c.InitPeerSamplesTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_samples table")
+	}
+
+	// discovery sources that found each peer
+	err = c.InitDiscoverySourcesTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_discovery_sources table")
+	}
+
+	// timing of the protocol negotiations with each peer
+	err = c.InitProtocolNegotiationsTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_protocol_negotiations table")
+	}
+
+	// first arrival and duplicates of each gossip message
+	err = c.InitGossipMessagesTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing gossip_messages table")
+	}
+
+	// snapshots of our gossipsub meshes and control messages
+	err = c.InitGossipMeshTables()
+	if err != nil {
+		return errors.Wrap(err, "initializing gossip mesh tables")
+	}
+
+	// snapshots of the gossipsub score of each peer
+	err = c.InitPeerGossipScoresTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_gossip_scores table")
+	}
+
+	// gossip messages delivered by each peer on each topic
+	err = c.InitPeerTopicMessagesTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_topic_messages table")
+	}
+
+	// history of the multiaddrs of each peer
+	err = c.InitPeerMultiaddrsTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_multiaddrs table")
+	}
+
+	// ips and ports at which each peer was seen, linking the peer across its address changes
+	err = c.InitPeerAddressHistoryTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_address_history table")
+	}
+
+	// actions and reactions of the connection churn experiments
+	err = c.InitChurnEventsTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing churn_events table")
+	}
+
+	// history of the reputation of each peer
+	err = c.InitPeerReputationTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing peer_reputation table")
+	}
+
+	// peers kept connected by the connection holder
+	err = c.InitHeldPeersTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing held_peers table")
+	}
+
+	// backoff state of the dial queue, to resume the crawl after a restart
+	err = c.InitDialQueueTable()
+	if err != nil {
+		return errors.Wrap(err, "initializing dial_queue table")
+	}
+
 	switch c.Network {
 	// ETHEREUM
 	case utils.EthereumNetwork:
@@ -159,12 +360,36 @@ func (c *DBClient) initTables() error {
 			return errors.Wrap(err, "initializing eth_nodes table")
 		}
 
+		// links between the discv5 and the libp2p identities of the nodes
+		err = c.InitNodeIdentitiesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing node_identities table")
+		}
+
+		// subnets advertised in the ENRs of the nodes
+		err = c.InitEthNodeSubnetsTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_node_subnets table")
+		}
+
 		// eth_status table
 		err = c.InitEthereumNodeStatus()
 		if err != nil {
 			return errors.Wrap(err, "initializing eth_status table")
 		}
 
+		// estimated validators behind the peers
+		err = c.InitValidatorEstimatesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_validator_estimates table")
+		}
+
+		// historical blocks served by the peers
+		err = c.InitEthBlocksByRangeProbesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_blocks_by_range_probes table")
+		}
+
 		// gossipsub messages
 		// eth_attestation
 		err = c.initEthereumAttestationsTable()
@@ -176,12 +401,75 @@ func (c *DBClient) initTables() error {
 		if err != nil {
 			return errors.Wrap(err, "initializing eth_blocks table")
 		}
-	//IPFS
+		// eth aggregates
+		err = c.initEthereumAggregatesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_aggregates table")
+		}
+		// eth blob sidecars
+		err = c.initEthereumBlobSidecarsTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_blob_sidecars table")
+		}
+	// ETHEREUM EL
+	case utils.EthereumELNetwork:
+		// eth_nodes table (records of the EL nodes)
+		err = c.InitEthNodesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_nodes table")
+		}
+	// PORTAL
+	case utils.PortalNetwork:
+		// eth_nodes table (records of the Portal nodes)
+		err = c.InitEthNodesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_nodes table")
+		}
+		// sub-networks and radius of the Portal nodes
+		err = c.InitPortalNodesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing portal_nodes table")
+		}
+	// WAKU
+	case utils.WakuNetwork:
+		// eth_nodes table (ENRs of the Waku nodes)
+		err = c.InitEthNodesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing eth_nodes table")
+		}
+		// services and relay shards of the Waku nodes
+		err = c.InitWakuNodesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing waku_nodes table")
+		}
+		// links between the discv5 and the libp2p identities of the nodes
+		err = c.InitNodeIdentitiesTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing node_identities table")
+		}
 	// FILECOIN
+	case utils.FilecoinNetwork:
+		// chain heads advertised in the hellos of the peers
+		err = c.InitFilecoinHelloTable()
+		if err != nil {
+			return errors.Wrap(err, "initializing filecoin_hello table")
+		}
+	//IPFS
 	default:
 
 	}
 
+	// run that recorded each of the events
+	err = c.InitRunIDColumns()
+	if err != nil {
+		return errors.Wrap(err, "initializing run_id columns")
+	}
+	// network of the peers and the runs, when several networks share the DB
+	err = c.InitNetworkNameColumns()
+	if err != nil {
+		return errors.Wrap(err, "initializing network_name columns")
+	}
+
 	return err
 }
 
@@ -195,6 +483,11 @@ func (c *DBClient) launchPersister() {
 
 		// batch to aggregate all the queries
 		batch := NewQueryBatch(c.ctx, c.psqlPool, batchSize)
+		// and one batch per high-volume table, persisted through COPY
+		connEventsBatch := NewCopyBatch(c.ctx, c.psqlPool, ConnEventsCopyTable, batchSize)
+		attestationsBatch := NewCopyBatch(c.ctx, c.psqlPool, AttestationsCopyTable, batchSize)
+		aggregatesBatch := NewCopyBatch(c.ctx, c.psqlPool, AggregatesCopyTable, batchSize)
+		copyBatches := []*CopyBatch{connEventsBatch, attestationsBatch, aggregatesBatch}
 
 		// batch flushing ticker
 		ticker := time.NewTicker(batchFlushingTimeout)
@@ -233,12 +526,34 @@ func (c *DBClient) launchPersister() {
 					// add raw new HostInfo
 					q, args := c.UpsertHostInfo(hostInfo)
 					batch.AddQuery(q, args...)
+					// the addresses of the connections are recorded by the host itself (as attributes)
+					if hostInfo.Origin == models.DiscoveredOrigin && len(hostInfo.MAddrs) > 0 {
+						q, args = c.UpsertPeerMultiaddrs(
+							models.NewMultiaddrObservation(hostInfo.ID, hostInfo.MAddrs, models.AdvertisedAddr, false, false),
+						)
+						batch.AddQuery(q, args...)
+					}
+					// the inbound connections come from ephemeral ports, which would look like address changes
+					if hostInfo.Origin != models.InboundOrigin && hostInfo.IP != "" {
+						q, args = c.UpsertPeerAddress(
+							models.NewPeerAddress(hostInfo.ID, enrNodeID(hostInfo), hostInfo.IP, hostInfo.Port),
+						)
+						batch.AddQuery(q, args...)
+					}
 
 					// check if the peerInfo needs to update anything else
 					if hostInfo.IsHostIdentified() {
 						logEntry.Tracef("host_info has peer_info %s\n", hostInfo.PeerInfo.RemotePeer.String())
 						q, args = c.UpdatePeerInfo(&hostInfo.PeerInfo)
 						batch.AddQuery(q, args...)
+						if len(hostInfo.PeerInfo.Protocols) > 0 {
+							q, args = c.UpsertPeerProtocols(hostInfo.ID, hostInfo.PeerInfo.Protocols, time.Now())
+							batch.AddQuery(q, args...)
+						}
+						if link := libp2pIdentityLink(hostInfo.ID); link != nil && c.linksIdentities() {
+							q, args = c.UpsertNodeIdentity(link)
+							batch.AddQuery(q, args...)
+						}
 					}
 					// Read all the Attributes in hInfo
 					for attName, att := range hostInfo.Attr {
@@ -252,11 +567,68 @@ func (c *DBClient) launchPersister() {
 							bmetadata := att.(eth.BeaconMetadataStamped)
 							q, args = c.UpsertEthereumNodeMetadata(bmetadata)
 							batch.AddQuery(q, args...)
+						case eth.BeaconPingStamped:
+							bping := att.(eth.BeaconPingStamped)
+							q, args = c.UpsertEthereumNodePing(bping)
+							batch.AddQuery(q, args...)
+						case (*eth.BlocksByRangeProbe):
+							probe := att.(*eth.BlocksByRangeProbe)
+							q, args := c.UpsertBlocksByRangeProbe(probe)
+							batch.AddQuery(q, args...)
 						case (*eth.EnrNode):
 							enrNode := att.(*eth.EnrNode)
 							logEntry.Tracef("persisting eth node_info %s\n", enrNode.ID.String())
 							q, args := c.UpsertEnrInfo(enrNode)
 							batch.AddQuery(q, args...)
+							q, args = c.UpsertEnrRecord(enrNode)
+							batch.AddQuery(q, args...)
+							if peerID, err := enrNode.GetPeerID(); err == nil && c.linksIdentities() {
+								q, args = c.UpsertNodeIdentity(
+									models.NewIdentityLink(enrNode.ID.String(), peerID.String(), models.Discv5Identity, ""),
+								)
+								batch.AddQuery(q, args...)
+							}
+							// only the CL nodes advertise subnets
+							if len(enrNode.AttSubnets())+len(enrNode.SyncSubnets()) > 0 {
+								q, args = c.UpsertEthNodeSubnets(enrNode)
+								batch.AddQuery(q, args...)
+							}
+						case (*portal.PortalNode):
+							portalNode := att.(*portal.PortalNode)
+							if len(portalNode.SubNetworks) > 0 {
+								q, args := c.UpsertPortalNode(portalNode)
+								batch.AddQuery(q, args...)
+							}
+						case filecoin.HelloStamped:
+							hello := att.(filecoin.HelloStamped)
+							q, args = c.UpsertFilecoinHello(hello)
+							batch.AddQuery(q, args...)
+						case (*waku.WakuNode):
+							wakuNode := att.(*waku.WakuNode)
+							q, args := c.UpsertWakuNode(wakuNode)
+							batch.AddQuery(q, args...)
+						case (*models.SignedPeerRecord):
+							rec := att.(*models.SignedPeerRecord)
+							logEntry.Tracef("persisting signed peer record %s\n", rec.PeerID.String())
+							q, args := c.UpsertSignedPeerRecord(rec)
+							batch.AddQuery(q, args...)
+						case (*models.MultiaddrObservation):
+							obs := att.(*models.MultiaddrObservation)
+							q, args := c.UpsertPeerMultiaddrs(obs)
+							batch.AddQuery(q, args...)
+						case (*models.DiscoverySource):
+							src := att.(*models.DiscoverySource)
+							q, args := c.UpsertDiscoverySource(src)
+							batch.AddQuery(q, args...)
+						case (*models.ProtocolNegotiation):
+							negotiation := att.(*models.ProtocolNegotiation)
+							q, args := c.UpsertProtocolNegotiation(negotiation)
+							batch.AddQuery(q, args...)
+						case (*models.ConnectionAttempt):
+							// attempts performed by the discovery itself (i.e. devp2p hellos)
+							connAttempt := att.(*models.ConnectionAttempt)
+							q, args := c.UpdateConnAttempt(connAttempt)
+							batch.AddQuery(q, args...)
 						default:
 							log.Warnf("not yet recognized type for attr %s - %T - %+v", attName, att, att)
 						}
@@ -273,13 +645,26 @@ func (c *DBClient) launchPersister() {
 					logEntry.Tracef("persisting conn_attempt")
 					q, args := c.UpdateConnAttempt(connAttempt)
 					batch.AddQuery(q, args...)
+					// the successful dials are recorded with the exact address of the connection
+					if connAttempt.Status == models.NegativeAttempt && len(connAttempt.Addrs) > 0 {
+						q, args = c.UpsertPeerMultiaddrs(
+							models.NewMultiaddrObservation(connAttempt.RemotePeer, connAttempt.Addrs, models.DialedAddr, true, false),
+						)
+						batch.AddQuery(q, args...)
+					}
+					// the addresses of the ENR are served by another key than the one of the ENR
+					if connAttempt.MismatchedPeer != "" && c.linksIdentities() {
+						if link := mismatchIdentityLink(connAttempt); link != nil {
+							q, args = c.UpsertNodeIdentity(link)
+							batch.AddQuery(q, args...)
+						}
+					}
 
 				case (*models.ConnEvent):
 					connEvent := obj.(*models.ConnEvent)
 					logEntry.Tracef("persisting conn_event for peer %s\n", connEvent.PeerID.String())
 					if c.persistConnEvents {
-						q, args := c.InsertNewConnEvent(connEvent)
-						batch.AddQuery(q, args...)
+						connEventsBatch.AddRow(connEventRow(connEvent, c.RunID())...)
 					}
 					// Control Info LastActivity based on last disconnection
 					// get the disconnection time to update the LastActivity timestamp in the peer_info table
@@ -292,6 +677,86 @@ func (c *DBClient) launchPersister() {
 					q, args := c.UpsertIpInfo(ipInfo)
 					batch.AddQuery(q, args...)
 
+				case (models.IpGeoCheck):
+					geoCheck := obj.(models.IpGeoCheck)
+					logEntry.Tracef("persisting geo check of ip %s\n", geoCheck.IP)
+					q, args := c.UpdateIpGeoCheck(geoCheck)
+					batch.AddQuery(q, args...)
+
+				case (*models.RoundSnapshot):
+					snapshot := obj.(*models.RoundSnapshot)
+					logEntry.Tracef("persisting snapshot of round %d\n", snapshot.Round)
+					q, args := c.InsertRoundSnapshot(snapshot)
+					batch.AddQuery(q, args...)
+
+				case (*models.PeerSample):
+					sample := obj.(*models.PeerSample)
+					logEntry.Tracef("persisting peer sample with seed %d\n", sample.Seed)
+					q, args := c.InsertPeerSample(sample)
+					batch.AddQuery(q, args...)
+
+				case (*models.ResourceUsage):
+					usage := obj.(*models.ResourceUsage)
+					logEntry.Tracef("persisting resource usage of run %d\n", usage.RunID)
+					q, args := c.InsertRunResourceUsage(usage)
+					batch.AddQuery(q, args...)
+
+				case (*models.ChurnEvent):
+					churnEvent := obj.(*models.ChurnEvent)
+					logEntry.Tracef("persisting churn event of peer %s\n", churnEvent.PeerID.String())
+					q, args := c.InsertChurnEvent(churnEvent)
+					batch.AddQuery(q, args...)
+
+				case (*models.HeldPeer):
+					held := obj.(*models.HeldPeer)
+					logEntry.Tracef("persisting held peer %s\n", held.PeerID.String())
+					q, args := c.UpsertHeldPeer(held)
+					batch.AddQuery(q, args...)
+
+				case (*models.PeerQuality):
+					quality := obj.(*models.PeerQuality)
+					logEntry.Tracef("persisting quality score of peer %s\n", quality.PeerID.String())
+					q, args := c.UpdatePeerQuality(quality)
+					batch.AddQuery(q, args...)
+
+				case (*models.PeerReputation):
+					rep := obj.(*models.PeerReputation)
+					logEntry.Tracef("persisting reputation of peer %s\n", rep.PeerID.String())
+					q, args := c.InsertPeerReputation(rep)
+					batch.AddQuery(q, args...)
+					q, args = c.UpdatePeerReputation(rep)
+					batch.AddQuery(q, args...)
+
+				case (*models.GossipMessage):
+					gossipMsg := obj.(*models.GossipMessage)
+					logEntry.Tracef("persisting propagation of gossip message %s\n", gossipMsg.MsgID)
+					q, args := c.UpsertGossipMessage(gossipMsg)
+					batch.AddQuery(q, args...)
+
+				case (*models.GossipMeshSnapshot):
+					meshSnapshot := obj.(*models.GossipMeshSnapshot)
+					logEntry.Tracef("persisting mesh snapshot of topic %s\n", meshSnapshot.Topic)
+					q, args := c.InsertGossipMeshSnapshot(meshSnapshot)
+					batch.AddQuery(q, args...)
+
+				case (*models.GossipControlCounts):
+					controlCounts := obj.(*models.GossipControlCounts)
+					logEntry.Tracef("persisting control messages sent to peer %s\n", controlCounts.PeerID.String())
+					q, args := c.InsertGossipControlCounts(controlCounts)
+					batch.AddQuery(q, args...)
+
+				case (*models.PeerGossipScore):
+					gossipScore := obj.(*models.PeerGossipScore)
+					logEntry.Tracef("persisting gossip score of peer %s\n", gossipScore.PeerID.String())
+					q, args := c.InsertPeerGossipScore(gossipScore)
+					batch.AddQuery(q, args...)
+
+				case (*models.PeerTopicMessages):
+					topicMsgs := obj.(*models.PeerTopicMessages)
+					logEntry.Tracef("persisting messages on %s of peer %s\n", topicMsgs.Topic, topicMsgs.PeerID.String())
+					q, args := c.UpsertPeerTopicMessages(topicMsgs)
+					batch.AddQuery(q, args...)
+
 				// GossipSub Messages
 				case (gossipsub.PersistableMsg):
 					prsMsg := obj.(gossipsub.PersistableMsg)
@@ -300,13 +765,21 @@ func (c *DBClient) launchPersister() {
 					case (*eth.TrackedAttestation):
 						attMsg := prsMsg.(*eth.TrackedAttestation)
 						log.Tracef("persisting eth_attestation %s", attMsg.MsgID)
-						q, args := c.InsertNewEthereumAttestation(attMsg)
-						batch.AddQuery(q, args...)
+						attestationsBatch.AddRow(attestationRow(attMsg, c.RunID())...)
 					case (*eth.TrackedBeaconBlock):
 						bblockMsg := prsMsg.(*eth.TrackedBeaconBlock)
 						log.Tracef("persisting eth_block %s", bblockMsg.MsgID)
 						q, args := c.InsertNewEthereumBeaconBlock(bblockMsg)
 						batch.AddQuery(q, args...)
+					case (*eth.TrackedAggregateAndProof):
+						aggregateMsg := prsMsg.(*eth.TrackedAggregateAndProof)
+						log.Tracef("persisting eth_aggregate %s", aggregateMsg.MsgID)
+						aggregatesBatch.AddRow(aggregateRow(aggregateMsg, c.RunID())...)
+					case (*eth.TrackedBlobSidecar):
+						blobMsg := prsMsg.(*eth.TrackedBlobSidecar)
+						log.Tracef("persisting eth_blob_sidecar %s", blobMsg.MsgID)
+						q, args := c.InsertNewEthereumBlobSidecar(blobMsg)
+						batch.AddQuery(q, args...)
 					}
 				default:
 					logEntry.Errorf("unrecognized type of object received to persist into DB %T", obj)
@@ -316,24 +789,62 @@ func (c *DBClient) launchPersister() {
 				// after adding whatever query we got check if we need to persist the batch
 				if batch.IsReadyToPersist() {
 					logEntry.Debug("batch-query full, ready to persist")
-					err := batch.PersistBatch()
-					if err != nil {
-						log.Error(err)
+					c.flushBatch(queryBatchLabel, batch)
+				}
+				for _, copyBatch := range copyBatches {
+					if copyBatch.IsReadyToPersist() {
+						logEntry.Debugf("copy-batch of %s full, ready to persist", copyBatch.Table())
+						c.flushBatch(copyBatch.Table(), copyBatch)
 					}
 				}
 
 			case <-ticker.C:
 				logEntry.Trace("ticker jumped - flushing content of query-batch")
-				// flush the batched queries
-				err := batch.PersistBatch()
-				if err != nil {
-					log.Error(err)
+				// flush the batched queries and rows
+				c.flushBatch(queryBatchLabel, batch)
+				for _, copyBatch := range copyBatches {
+					c.flushBatch(copyBatch.Table(), copyBatch)
 				}
 			}
 		}
+
+		// don't leave anything behind when closing
+		c.flushBatch(queryBatchLabel, batch)
+		for _, copyBatch := range copyBatches {
+			c.flushBatch(copyBatch.Table(), copyBatch)
+		}
 	}()
 }
 
+// flushBatch persists the content of the batch, keeping track of the latency of the flush
+func (c *DBClient) flushBatch(table string, batch persistableBatch) {
+	rows := batch.Len()
+	if rows == 0 {
+		return
+	}
+	// keep the rows of the high-volume tables for the mirror, the batch is emptied on persist
+	var mirrorRows [][]interface{}
+	copyBatch, isCopy := batch.(*CopyBatch)
+	if isCopy && c.rowsMirror != nil {
+		mirrorRows = copyBatch.Rows()
+	}
+	start := time.Now()
+	err := batch.PersistBatch()
+	if err != nil {
+		log.Error(err)
+	}
+	c.writerStats.addFlush(table, rows, time.Since(start), err != nil)
+
+	if len(mirrorRows) > 0 {
+		start = time.Now()
+		err = c.rowsMirror.MirrorRows(table, copyBatch.Columns(), mirrorRows)
+		if err != nil {
+			log.Error(errors.Wrap(err, "unable to mirror rows of "+table))
+		}
+		c.writerStats.addFlush(mirrorLabelPrefix+table, len(mirrorRows), time.Since(start), err != nil)
+	}
+}
+
 func (c *DBClient) dailyBackupheartbeat() {
 	// make a first backup of the active peers(if any)
 	err := c.activePeersBackup()
@@ -355,23 +866,42 @@ func (c *DBClient) dailyBackupheartbeat() {
 
 }
 
+// Close stops accepting items to persist, and waits until the persisters flushed all the queued
+// ones before closing the connection with the DB
 func (c *DBClient) Close() {
-	// Let the persister finish cleaning the batch
-	c.doneC <- struct{}{}
+	c.closeM.Lock()
+	if c.closed {
+		c.closeM.Unlock()
+		return
+	}
+	c.closed = true
+	c.closeM.Unlock()
+
+	// Let all the persisters finish cleaning their batches
+	close(c.doneC)
 	c.wg.Wait()
 
-	err := c.activePeersBackup()
-	if err != nil {
-		log.Error(err)
+	if c.backupActivePeers {
+		err := c.activePeersBackup()
+		if err != nil {
+			log.Error(err)
+		}
 	}
 	// close safelly the connection with PSQL
 	c.psqlPool.Close()
-
-	// close all the exisiting channels
-	close(c.persistC)
+	if dropped := atomic.LoadInt64(&c.dropped); dropped > 0 {
+		log.Warnf("%d items were sent to persist after closing the db client", dropped)
+	}
 }
 
 func (c *DBClient) PersistToDB(persItem interface{}) {
+	c.closeM.RLock()
+	defer c.closeM.RUnlock()
+	if c.closed {
+		// the modules that are still closing can't persist anything anymore
+		atomic.AddInt64(&c.dropped, 1)
+		return
+	}
 	c.persistC <- persItem
 }
 
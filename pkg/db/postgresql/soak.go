@@ -0,0 +1,148 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	// time per slot to translate the retention into slots for the gossip messages
+	ethSlotDuration = 12 * time.Second
+)
+
+func (c *DBClient) DropStatsRollupsTable() error {
+	log.Info("dropping table stats_rollups")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE stats_rollups;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitStatsRollupsTable() error {
+	log.Info("init stats_rollups table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS stats_rollups(
+				day DATE NOT NULL,
+				network TEXT NOT NULL,
+				dimension TEXT NOT NULL,
+				key TEXT NOT NULL,
+				value BIGINT NOT NULL,
+
+				PRIMARY KEY(day, network, dimension, key)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertStatsRollup persists the daily rollup of the given distribution of peers, overriding
+// any previous rollup of the same day
+func (c *DBClient) UpsertStatsRollup(day time.Time, dimension string, dist map[string]interface{}) error {
+	log.Debugf("rolling up %s distribution of %s", dimension, day.Format("2006-01-02"))
+	for key, val := range dist {
+		count, ok := val.(int)
+		if !ok {
+			continue
+		}
+		_, err := c.psqlPool.Exec(
+			c.ctx,
+			`
+				INSERT INTO stats_rollups(
+					day,
+					network,
+					dimension,
+					key,
+					value)
+				VALUES ($1,$2,$3,$4,$5)
+				ON CONFLICT (day, network, dimension, key) DO UPDATE SET
+					value = excluded.value;
+			`,
+			day,
+			c.networkKey(),
+			dimension,
+			key,
+			count,
+		)
+		if err != nil {
+			return errors.Wrap(err, "unable to upsert "+dimension+" rollup")
+		}
+	}
+	return nil
+}
+
+// GetStatsRollups returns the daily rollups of the network since the given day
+func (c *DBClient) GetStatsRollups(since time.Time) ([]models.StatsRollup, error) {
+	log.Debug("fetching stats rollups")
+	rollups := make([]models.StatsRollup, 0)
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT day, network, dimension, key, value
+		FROM stats_rollups
+		WHERE network = $1 and day >= $2
+		ORDER BY day, dimension, value DESC;
+		`,
+		c.networkKey(),
+		since,
+	)
+	if err != nil {
+		return rollups, errors.Wrap(err, "unable to fetch stats rollups")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rollup models.StatsRollup
+		err = rows.Scan(&rollup.Day, &rollup.Network, &rollup.Dimension, &rollup.Key, &rollup.Value)
+		if err != nil {
+			return rollups, errors.Wrap(err, "unable to parse fetched stats rollup")
+		}
+		rollups = append(rollups, rollup)
+	}
+	return rollups, nil
+}
+
+type retentionQuery struct {
+	query string
+	arg   interface{}
+}
+
+// ApplyRetention deletes the time-series rows older than the given retention, returning
+// the number of deleted rows per table
+func (c *DBClient) ApplyRetention(retention time.Duration) (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	limit := time.Now().Add(-retention)
+	log.Debugf("deleting time-series rows older than %s", limit)
+
+	retentionQueries := map[string]retentionQuery{
+		"conn_events":        {`DELETE FROM conn_events WHERE disconn_time < $1;`, limit.Unix()},
+		"active_peers":       {`DELETE FROM active_peers WHERE timestamp < $1;`, limit},
+		"run_resource_usage": {`DELETE FROM run_resource_usage WHERE timestamp < $1;`, limit},
+	}
+	if c.Network == utils.EthereumNetwork {
+		// gossip messages only track the time inside the slot, so use the slots instead
+		retentionSlots := int64(retention / ethSlotDuration)
+		retentionQueries["eth_attestations"] = retentionQuery{`DELETE FROM eth_attestations WHERE slot < (SELECT max(slot) FROM eth_attestations) - $1;`, retentionSlots}
+		retentionQueries["eth_blocks"] = retentionQuery{`DELETE FROM eth_blocks WHERE slot < (SELECT max(slot) FROM eth_blocks) - $1;`, retentionSlots}
+	}
+
+	for table, q := range retentionQueries {
+		tag, err := c.psqlPool.Exec(c.ctx, q.query, q.arg)
+		if err != nil {
+			return deleted, errors.Wrap(err, "unable to apply retention to "+table)
+		}
+		deleted[table] = tag.RowsAffected()
+	}
+	return deleted, nil
+}
@@ -0,0 +1,117 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) DropStaticPeersTable() error {
+	log.Info("dropping table static_peers")
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+		DROP TABLE static_peers;
+		`,
+	)
+	return err
+}
+
+func (c *DBClient) InitStaticPeersTable() error {
+	log.Info("init static_peers table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS static_peers(
+				peer_id TEXT NOT NULL,
+				network TEXT NOT NULL,
+				multi_addrs TEXT[],
+				active BOOL NOT NULL,
+				added_time TIMESTAMP NOT NULL,
+				last_connection TIMESTAMP,
+				connections INT NOT NULL DEFAULT 0,
+
+				PRIMARY KEY(peer_id)
+			);
+		`,
+	)
+	return err
+}
+
+// SetStaticPeers marks the given peers as the active static peers of the network,
+// deactivating any static peer of the network from previous runs that is no longer configured
+func (c *DBClient) SetStaticPeers(peers []peer.AddrInfo) error {
+	log.Debugf("setting %d static peers into static_peers", len(peers))
+
+	peerIDs := make([]string, 0, len(peers))
+	for _, p := range peers {
+		peerIDs = append(peerIDs, p.ID.String())
+	}
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			UPDATE static_peers SET
+				active = 'false'
+			WHERE network = $1 and NOT (peer_id = ANY($2));
+		`,
+		c.networkKey(),
+		peerIDs,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to deactivate old static peers")
+	}
+
+	for _, p := range peers {
+		mAddrs := make([]string, 0, len(p.Addrs))
+		for _, maddr := range p.Addrs {
+			mAddrs = append(mAddrs, maddr.String())
+		}
+		_, err = c.psqlPool.Exec(
+			c.ctx,
+			`
+				INSERT INTO static_peers(
+					peer_id,
+					network,
+					multi_addrs,
+					active,
+					added_time)
+				VALUES ($1,$2,$3,'true',$4)
+				ON CONFLICT (peer_id) DO UPDATE SET
+					network = excluded.network,
+					multi_addrs = excluded.multi_addrs,
+					active = 'true';
+			`,
+			p.ID.String(),
+			c.networkKey(),
+			mAddrs,
+			time.Now(),
+		)
+		if err != nil {
+			return errors.Wrap(err, "unable to insert static peer "+p.ID.String())
+		}
+	}
+	return nil
+}
+
+// RecordStaticPeerConnection tracks each of the (re)connections to a static peer
+func (c *DBClient) RecordStaticPeerConnection(peerID peer.ID, connTime time.Time) error {
+	log.Tracef("recording connection to static peer %s", peerID.String())
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			UPDATE static_peers SET
+				last_connection = $2,
+				connections = connections + 1
+			WHERE peer_id = $1;
+		`,
+		peerID.String(),
+		connTime,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to record connection to static peer "+peerID.String())
+	}
+	return nil
+}
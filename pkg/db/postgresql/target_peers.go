@@ -0,0 +1,52 @@
+package postgresql
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) InitTargetPeersTable() error {
+	log.Info("init target_peers table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS target_peers(
+				entry TEXT NOT NULL,
+				label TEXT,
+				added_time TIMESTAMP NOT NULL DEFAULT NOW(),
+
+				PRIMARY KEY(entry)
+			);
+		`,
+	)
+	return err
+}
+
+// GetTargetEntries returns the ENRs, multiaddrs, and peer IDs of the peers to dial in the target-list crawl mode
+func (c *DBClient) GetTargetEntries() ([]string, error) {
+	log.Debug("fetching target peers")
+	entries := make([]string, 0)
+
+	rows, err := c.psqlPool.Query(
+		c.ctx,
+		`
+		SELECT entry
+		FROM target_peers;
+		`,
+	)
+	if err != nil {
+		return entries, errors.Wrap(err, "unable to fetch target peers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry string
+		err = rows.Scan(&entry)
+		if err != nil {
+			return entries, errors.Wrap(err, "unable to parse fetched target peer")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
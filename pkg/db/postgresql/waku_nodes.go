@@ -0,0 +1,76 @@
+package postgresql
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+)
+
+func (c *DBClient) InitWakuNodesTable() error {
+	log.Info("init waku_nodes table")
+
+	_, err := c.psqlPool.Exec(
+		c.ctx,
+		`
+			CREATE TABLE IF NOT EXISTS waku_nodes(
+				node_id TEXT NOT NULL,
+				peer_id TEXT NOT NULL,
+				enr_seq BIGINT NOT NULL,
+				services TEXT[] NOT NULL,
+				cluster_id INT,
+				shards INT[],
+				multiaddrs TEXT[],
+				first_seen BIGINT NOT NULL,
+				last_seen BIGINT NOT NULL,
+
+				PRIMARY KEY(node_id)
+			);
+		`,
+	)
+	return err
+}
+
+// UpsertWakuNode records the services and the relay shards that the node advertises in its ENR,
+// keeping when the node was seen for the first and the last time
+func (c *DBClient) UpsertWakuNode(node *waku.WakuNode) (query string, args []interface{}) {
+	log.Trace("upserting waku node ", node.NodeID.String())
+
+	query = `
+		INSERT INTO waku_nodes(
+			node_id,
+			peer_id,
+			enr_seq,
+			services,
+			cluster_id,
+			shards,
+			multiaddrs,
+			first_seen,
+			last_seen)
+		VALUES ($1, $2, $3, $4, NULLIF($5, -1), $6, $7, $8, $8)
+		ON CONFLICT (node_id)
+		DO UPDATE SET
+			peer_id = excluded.peer_id,
+			enr_seq = excluded.enr_seq,
+			services = excluded.services,
+			cluster_id = excluded.cluster_id,
+			shards = excluded.shards,
+			multiaddrs = excluded.multiaddrs,
+			last_seen = GREATEST(waku_nodes.last_seen, excluded.last_seen);
+	`
+
+	maddrs := make([]string, 0, len(node.Multiaddrs))
+	for _, maddr := range node.Multiaddrs {
+		maddrs = append(maddrs, maddr.String())
+	}
+
+	args = append(args, node.NodeID.String())
+	args = append(args, node.PeerID.String())
+	args = append(args, int64(node.EnrSeq))
+	args = append(args, node.Services)
+	args = append(args, node.ClusterID)
+	args = append(args, node.Shards)
+	args = append(args, maddrs)
+	args = append(args, node.Timestamp.Unix())
+
+	return query, args
+}
@@ -0,0 +1,86 @@
+package postgresql
+
+import (
+	"sync"
+	"time"
+)
+
+// label of the batch that aggregates the regular queries (the rest of batches are labeled by table)
+const queryBatchLabel = "queries"
+
+// prefix of the labels of the flushes into the mirror of the high-volume tables
+const mirrorLabelPrefix = "mirror_"
+
+// persistableBatch is any batch of writes that the persisters flush into the DB
+type persistableBatch interface {
+	Len() int
+	PersistBatch() error
+}
+
+// FlushStats summarizes the flushes of the batches of a table since the last read
+type FlushStats struct {
+	Flushes    int
+	Failed     int
+	Rows       int
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+}
+
+type writerStats struct {
+	m      sync.Mutex
+	tables map[string]*FlushStats
+	// total latency of the flushes of each table, to compute the average
+	latencies map[string]time.Duration
+}
+
+func newWriterStats() *writerStats {
+	return &writerStats{
+		tables:    make(map[string]*FlushStats),
+		latencies: make(map[string]time.Duration),
+	}
+}
+
+func (s *writerStats) addFlush(table string, rows int, latency time.Duration, failed bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	stats, ok := s.tables[table]
+	if !ok {
+		stats = &FlushStats{}
+		s.tables[table] = stats
+	}
+	stats.Flushes++
+	stats.Rows += rows
+	if failed {
+		stats.Failed++
+	}
+	if latency > stats.MaxLatency {
+		stats.MaxLatency = latency
+	}
+	s.latencies[table] += latency
+}
+
+// read returns the stats of each table since the last read, resetting them
+func (s *writerStats) read() map[string]FlushStats {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	summary := make(map[string]FlushStats, len(s.tables))
+	for table, stats := range s.tables {
+		stats.AvgLatency = s.latencies[table] / time.Duration(stats.Flushes)
+		summary[table] = *stats
+	}
+	s.tables = make(map[string]*FlushStats)
+	s.latencies = make(map[string]time.Duration)
+	return summary
+}
+
+// QueueDepth returns the number of items waiting to be picked by the persisters
+func (c *DBClient) QueueDepth() int {
+	return len(c.persistC)
+}
+
+// FlushStats returns the stats of the flushes of each table since the last call
+func (c *DBClient) FlushStats() map[string]FlushStats {
+	return c.writerStats.read()
+}
@@ -0,0 +1,27 @@
+package postgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterStats(t *testing.T) {
+	stats := newWriterStats()
+	stats.addFlush("conn_events", 10, 2*time.Second, false)
+	stats.addFlush("conn_events", 20, 4*time.Second, true)
+	stats.addFlush(queryBatchLabel, 5, time.Second, false)
+
+	summary := stats.read()
+	require.Len(t, summary, 2)
+	require.Equal(t, 2, summary["conn_events"].Flushes)
+	require.Equal(t, 1, summary["conn_events"].Failed)
+	require.Equal(t, 30, summary["conn_events"].Rows)
+	require.Equal(t, 3*time.Second, summary["conn_events"].AvgLatency)
+	require.Equal(t, 4*time.Second, summary["conn_events"].MaxLatency)
+	require.Equal(t, 5, summary[queryBatchLabel].Rows)
+
+	// the stats are reset after each read
+	require.Empty(t, stats.read())
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) InitActivePeersTable() error {
+	return c.initTable("active_peers", `
+		CREATE TABLE IF NOT EXISTS active_peers(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp TIMESTAMP UNIQUE,
+			peers TEXT
+		);
+	`)
+}
+
+func (c *DBClient) getActivePeers() ([]int, error) {
+	activePeers := make([]int, 0)
+
+	rows, err := c.query(`
+		SELECT
+			id,
+			peer_id
+		FROM peer_info
+		WHERE deprecated = false and attempted = true and client_name IS NOT NULL and last_activity > unixepoch() - $1 * 86400 and
+		      ($2 = '' OR network_name = $2)
+		`,
+		LastActivityValidRange,
+		c.networkName,
+	)
+	if err != nil {
+		return activePeers, errors.Wrap(err, "unable to retrieve active peer's ids")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var pId string
+		err = rows.Scan(&id, &pId)
+		if err != nil {
+			return activePeers, errors.Wrap(err, "unable to retrieve active peer's ids")
+		}
+		activePeers = append(activePeers, id)
+	}
+	return activePeers, nil
+}
+
+func (c *DBClient) activePeersBackup() error {
+	log.Debug("making backup in DB of the actual active peers")
+
+	activePeers, err := c.getActivePeers()
+	if err != nil {
+		return errors.Wrap(err, "unable to backup active peers")
+	}
+	if len(activePeers) <= 0 {
+		log.Infof("tried to persist %d active peers (skipped)", len(activePeers))
+		return nil
+	}
+
+	// backup the list of active peers
+	_, err = c.exec(`
+			INSERT INTO active_peers(
+				timestamp,
+				peers)
+			VALUES ($1,$2)
+		`,
+		time.Now(),
+		jsonArray{activePeers},
+	)
+
+	return err
+}
@@ -0,0 +1,269 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+// columns of the peer summaries, scanned by scanPeerSummary
+var peerSummarySelect = `
+		SELECT
+			p.peer_id,
+			p.network,
+			p.ip,
+			COALESCE(i.country_code, ''),
+			COALESCE(p.client_name, ''),
+			COALESCE(p.client_version, ''),
+			COALESCE(p.user_agent, ''),
+			COALESCE(p.latency, 0),
+			COALESCE(p.deprecated, false),
+			COALESCE(p.last_activity, 0),
+			COALESCE(p.last_conn_attempt, 0),
+			COALESCE(p.last_error, '')
+		FROM peer_info p
+		LEFT JOIN ips i ON i.ip = p.ip
+		`
+
+// rowScanner is either a single row or the current one of the rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPeerSummary(row rowScanner) (models.PeerSummary, error) {
+	var peerStr string
+	var lastActivity, lastConnAttempt int64
+	var summary models.PeerSummary
+	err := row.Scan(
+		&peerStr,
+		&summary.Network,
+		&summary.IP,
+		&summary.Country,
+		&summary.ClientName,
+		&summary.ClientVersion,
+		&summary.UserAgent,
+		&summary.Latency,
+		&summary.Deprecated,
+		&lastActivity,
+		&lastConnAttempt,
+		&summary.LastError,
+	)
+	if err != nil {
+		return summary, err
+	}
+	summary.PeerID, err = peer.Decode(peerStr)
+	if err != nil {
+		return summary, errors.Wrap(err, "invalid peer id "+peerStr)
+	}
+	summary.LastActivity = time.Unix(lastActivity, 0)
+	summary.LastConnAttempt = time.Unix(lastConnAttempt, 0)
+	return summary, nil
+}
+
+// GetActivePeers returns the identified and non-deprecated peers that were active within the given window,
+// from the most to the least recently active
+func (c *DBClient) GetActivePeers(window time.Duration) ([]models.PeerSummary, error) {
+	log.Debugf("fetching peers active in the last %s", window)
+	peers := make([]models.PeerSummary, 0)
+
+	rows, err := c.query(
+		peerSummarySelect+`
+		WHERE p.deprecated = false and p.client_name IS NOT NULL and p.last_activity > $1
+		ORDER BY p.last_activity DESC;
+		`,
+		time.Now().Add(-window).Unix(),
+	)
+	if err != nil {
+		return peers, errors.Wrap(err, "unable to fetch active peers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		summary, err := scanPeerSummary(rows)
+		if err != nil {
+			return peers, errors.Wrap(err, "unable to parse fetched active peer")
+		}
+		peers = append(peers, summary)
+	}
+	return peers, nil
+}
+
+// GetPeerSummary returns the latest known state of the given peer (nil if the peer isn't known)
+func (c *DBClient) GetPeerSummary(pID peer.ID) (*models.PeerSummary, error) {
+	log.Debugf("fetching summary of peer %s", pID.String())
+	row := c.queryRow(
+		peerSummarySelect+`
+		WHERE p.peer_id = $1;
+		`,
+		pID.String(),
+	)
+	summary, err := scanPeerSummary(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch peer summary")
+	}
+	return &summary, nil
+}
+
+// GetPeersByClient returns the number of peers active within the given window per client and version,
+// from the most to the least used one
+func (c *DBClient) GetPeersByClient(window time.Duration) ([]models.ClientCount, error) {
+	log.Debugf("fetching client distribution of the last %s", window)
+	counts := make([]models.ClientCount, 0)
+
+	rows, err := c.query(`
+		SELECT
+			client_name,
+			COALESCE(client_version, ''),
+			count(*) as peers
+		FROM peer_info
+		WHERE deprecated = false and client_name IS NOT NULL and last_activity > $1 and
+		      ($2 = '' OR network_name = $2)
+		GROUP BY client_name, client_version
+		ORDER BY peers DESC, client_name, client_version;
+		`,
+		time.Now().Add(-window).Unix(),
+		c.networkName,
+	)
+	if err != nil {
+		return counts, errors.Wrap(err, "unable to fetch peers by client")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count models.ClientCount
+		err = rows.Scan(&count.Client, &count.Version, &count.Peers)
+		if err != nil {
+			return counts, errors.Wrap(err, "unable to parse fetched client count")
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// GetPeerTimeline returns the connections to the given peer in chronological order
+func (c *DBClient) GetPeerTimeline(pID peer.ID) ([]models.PeerTimelineEvent, error) {
+	log.Debugf("fetching timeline of peer %s", pID.String())
+	timeline := make([]models.PeerTimelineEvent, 0)
+
+	rows, err := c.query(`
+		SELECT
+			direction,
+			conn_time,
+			COALESCE(latency, 0),
+			disconn_time,
+			COALESCE(identified, false),
+			error
+		FROM conn_events
+		WHERE peer_id = $1
+		ORDER BY conn_time, id;
+		`,
+		pID.String(),
+	)
+	if err != nil {
+		return timeline, errors.Wrap(err, "unable to fetch peer timeline")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.PeerTimelineEvent
+		var connTime, disconnTime, latency int64
+		err = rows.Scan(&event.Direction, &connTime, &latency, &disconnTime, &event.Identified, &event.Error)
+		if err != nil {
+			return timeline, errors.Wrap(err, "unable to parse fetched peer timeline")
+		}
+		event.ConnTime = time.Unix(connTime, 0)
+		event.DisconnTime = time.Unix(disconnTime, 0)
+		event.Latency = time.Duration(latency) * time.Millisecond
+		timeline = append(timeline, event)
+	}
+	return timeline, nil
+}
+
+// GetTopicRates returns the unique messages (and their duplicates) received on each gossip topic
+// within the given window, from the busiest to the quietest topic
+func (c *DBClient) GetTopicRates(window time.Duration) ([]models.TopicRate, error) {
+	log.Debugf("fetching gossip topic rates of the last %s", window)
+	rates := make([]models.TopicRate, 0)
+
+	rows, err := c.query(`
+		SELECT
+			topic,
+			count(*) as messages,
+			COALESCE(sum(duplicates), 0)
+		FROM gossip_messages
+		WHERE first_seen > $1
+		GROUP BY topic
+		ORDER BY messages DESC, topic;
+		`,
+		time.Now().Add(-window),
+	)
+	if err != nil {
+		return rates, errors.Wrap(err, "unable to fetch topic rates")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate models.TopicRate
+		err = rows.Scan(&rate.Topic, &rate.Messages, &rate.Duplicates)
+		if err != nil {
+			return rates, errors.Wrap(err, "unable to parse fetched topic rate")
+		}
+		if window > 0 {
+			rate.MsgsPerMinute = float64(rate.Messages) / window.Minutes()
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// GetConnSessions returns the connection events within the given window (unbounded if zero)
+// as sessions, in chronological order
+func (c *DBClient) GetConnSessions(since, until time.Time) ([]models.ConnSession, error) {
+	log.Debug("fetching connection sessions")
+	sessions := make([]models.ConnSession, 0)
+
+	var from, to int64
+	if !since.IsZero() {
+		from = since.Unix()
+	}
+	if !until.IsZero() {
+		to = until.Unix()
+	}
+	rows, err := c.query(`
+		SELECT
+			peer_id,
+			conn_time,
+			disconn_time
+		FROM conn_events
+		WHERE conn_time >= $1 and ($2 = 0 OR conn_time < $2)
+		ORDER BY conn_time, id;
+		`,
+		from,
+		to,
+	)
+	if err != nil {
+		return sessions, errors.Wrap(err, "unable to fetch connection sessions")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session models.ConnSession
+		var connTime, disconnTime int64
+		err = rows.Scan(&session.PeerID, &connTime, &disconnTime)
+		if err != nil {
+			return sessions, errors.Wrap(err, "unable to parse fetched connection session")
+		}
+		session.Start = time.Unix(connTime, 0)
+		session.End = time.Unix(disconnTime, 0)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	QueryTimeout = 5 * time.Minute
+	MaxRetries   = 2
+)
+
+type batchedQuery struct {
+	query string
+	args  []interface{}
+}
+
+// QueryBatch aggregates the queries until they are persisted within a single transaction
+type QueryBatch struct {
+	ctx     context.Context
+	db      *sql.DB
+	queries []batchedQuery
+	size    int
+}
+
+func NewQueryBatch(ctx context.Context, db *sql.DB, batchSize int) *QueryBatch {
+	return &QueryBatch{
+		ctx:     ctx,
+		db:      db,
+		queries: make([]batchedQuery, 0, batchSize),
+		size:    batchSize,
+	}
+}
+
+func (q *QueryBatch) IsReadyToPersist() bool {
+	return len(q.queries) >= q.size
+}
+
+func (q *QueryBatch) AddQuery(query string, args ...interface{}) {
+	q.queries = append(q.queries, batchedQuery{query: query, args: sqlArgs(args)})
+}
+
+func (q *QueryBatch) Len() int {
+	return len(q.queries)
+}
+
+func (q *QueryBatch) PersistBatch() error {
+	logEntry := log.WithFields(log.Fields{
+		"mod": "batch-persister",
+	})
+	logEntry.Debugf("persisting batch of queries with len(%d)", q.Len())
+	var err error
+persistRetryLoop:
+	for i := 0; i <= MaxRetries; i++ {
+		t := time.Now()
+		err = q.persistBatch()
+		duration := time.Since(t)
+		switch err {
+		case nil:
+			logEntry.Debugf("persisted %d queries in %s seconds", q.Len(), duration)
+			break persistRetryLoop
+		default:
+			logEntry.Debugf("attempt numb %d failed %s", i+1, err.Error())
+		}
+	}
+	q.queries = make([]batchedQuery, 0, q.size)
+	return errors.Wrap(err, "unable to persist batch query")
+}
+
+func (q *QueryBatch) persistBatch() error {
+	// if batch len == 0, don't even query
+	if q.Len() == 0 {
+		return nil
+	}
+
+	// generate a timeout to the batch-persisting
+	ctx, cancel := context.WithTimeout(q.ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// none of the queries of the batch is applied if one fails, so the merge rules never see half of it
+	defer tx.Rollback()
+
+	for i, bq := range q.queries {
+		_, err = tx.ExecContext(ctx, bq.query, bq.args...)
+		if err != nil {
+			log.Errorf("unable to persist batch because an error on query %d \n %s \n %+v", i, bq.query, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
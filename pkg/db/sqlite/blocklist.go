@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) InitBlocklistTable() error {
+	return c.initTable("blocklist", `
+		CREATE TABLE IF NOT EXISTS blocklist(
+			entry TEXT NOT NULL,
+			reason TEXT,
+			added_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY(entry)
+		);
+	`)
+}
+
+// GetBlocklistEntries returns the CIDRs, IPs, ASNs, and peer IDs that the crawler shouldn't connect to
+func (c *DBClient) GetBlocklistEntries() ([]string, error) {
+	log.Debug("fetching blocklist entries")
+	return c.getEntries("blocklist", "blocklist entry")
+}
+
+// getEntries returns the entries of the lists that the users fill by hand (blocklist and target_peers)
+func (c *DBClient) getEntries(table, item string) ([]string, error) {
+	entries := make([]string, 0)
+
+	rows, err := c.query(`SELECT entry FROM ` + table + `;`)
+	if err != nil {
+		return entries, errors.Wrap(err, "unable to fetch "+table+" entries")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry string
+		err = rows.Scan(&entry)
+		if err != nil {
+			return entries, errors.Wrap(err, "unable to parse fetched "+item)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
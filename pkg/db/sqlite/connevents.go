@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"github.com/migalabs/armiarma/pkg/db/models"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) InitConnEventTable() error {
+	return c.initTable("conn_events",
+		`
+		CREATE TABLE IF NOT EXISTS conn_events(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			peer_id TEXT NOT NULL,
+			direction TEXT NOT NULL,
+			conn_time BIGINT NOT NULL,
+			latency BIGINT,
+			disconn_time BIGINT NOT NULL,
+			identified BOOL,
+			addr_family TEXT,
+			error TEXT NOT NULL,
+			run_id INT
+		);
+		`,
+		// the conn_events are queried per peer
+		`CREATE INDEX IF NOT EXISTS conn_events_peer_id_idx ON conn_events(peer_id);`,
+	)
+}
+
+func (c *DBClient) InsertNewConnEvent(connEv *models.ConnEvent) (query string, args []interface{}) {
+	log.Trace("inserting new connection event to conn_event in sqlite-db")
+	// compose query
+	query = `
+		INSERT INTO conn_events (
+			peer_id,
+			direction,
+			conn_time,
+			latency,
+			disconn_time,
+			identified,
+			addr_family,
+			error,
+			run_id)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		`
+
+	args = append(args, connEv.PeerID.String())
+	args = append(args, models.DirectionIndexToString(connEv.Direction))
+	args = append(args, connEv.ConnTime.Unix())
+	args = append(args, connEv.Latency.Milliseconds())
+	args = append(args, connEv.DiscTime.Unix())
+	args = append(args, connEv.Identified)
+	args = append(args, connEv.AddrFamily)
+	args = append(args, connEv.Error)
+	args = append(args, c.RunID())
+
+	return query, args
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitCrawlerRunsTable() error {
+	return c.initTable("crawler_runs", `
+		CREATE TABLE IF NOT EXISTS crawler_runs(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			network TEXT NOT NULL,
+			peer_id TEXT NOT NULL,
+			start_time TIMESTAMP NOT NULL,
+			signed_peer_record BOOL NOT NULL,
+			observed_addrs BOOL NOT NULL,
+			seed BIGINT,
+			stop_time TIMESTAMP,
+			version TEXT,
+			config_hash TEXT,
+			config TEXT,
+			network_name TEXT
+		);
+	`)
+}
+
+// RunID returns the id of the run that the event rows get tagged with (nil until the run is inserted)
+func (c *DBClient) RunID() interface{} {
+	runID := atomic.LoadInt64(&c.runID)
+	if runID == 0 {
+		return nil
+	}
+	return int(runID)
+}
+
+// InsertCrawlerRun persists the metadata of the current crawler execution, returning its run id
+func (c *DBClient) InsertCrawlerRun(run *models.CrawlerRun) (int, error) {
+	log.Debug("inserting new crawler run into crawler_runs")
+
+	var runID int
+	err := c.queryRow(
+		`
+			INSERT INTO crawler_runs(
+				network,
+				peer_id,
+				start_time,
+				signed_peer_record,
+				observed_addrs,
+				seed,
+				version,
+				config_hash,
+				config,
+				network_name)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,NULLIF($10, ''))
+			RETURNING id
+		`,
+		run.Network,
+		run.HostID.String(),
+		run.StartTime,
+		run.SignedPeerRecord,
+		run.ObservedAddrs,
+		run.Seed,
+		run.Version,
+		run.ConfigHash,
+		run.Config,
+		c.networkName,
+	).Scan(&runID)
+	if err != nil {
+		return runID, errors.Wrap(err, "unable to insert crawler run")
+	}
+	run.ID = runID
+	// from now on, the events get tagged with the run
+	atomic.StoreInt64(&c.runID, int64(runID))
+	return runID, nil
+}
+
+// FinishCrawlerRun sets the stop time of the run
+func (c *DBClient) FinishCrawlerRun(runID int, stopTime time.Time) error {
+	_, err := c.exec(
+		`
+			UPDATE crawler_runs SET stop_time = $2
+			WHERE id = $1;
+		`,
+		runID,
+		stopTime,
+	)
+	return errors.Wrap(err, "unable to finish crawler run")
+}
+
+func (c *DBClient) InitRunResourceUsageTable() error {
+	return c.initTable("run_resource_usage", `
+		CREATE TABLE IF NOT EXISTS run_resource_usage(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id INT NOT NULL,
+			timestamp TIMESTAMP NOT NULL,
+			cpu_seconds REAL NOT NULL,
+			mem_sys_bytes BIGINT NOT NULL,
+			heap_alloc_bytes BIGINT NOT NULL,
+			goroutines INT NOT NULL,
+			bytes_in BIGINT NOT NULL,
+			bytes_out BIGINT NOT NULL,
+			open_conns INT NOT NULL
+		);
+	`)
+}
+
+func (c *DBClient) InsertRunResourceUsage(usage *models.ResourceUsage) (query string, args []interface{}) {
+	query = `
+		INSERT INTO run_resource_usage(
+			run_id,
+			timestamp,
+			cpu_seconds,
+			mem_sys_bytes,
+			heap_alloc_bytes,
+			goroutines,
+			bytes_in,
+			bytes_out,
+			open_conns)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`
+
+	args = append(args, usage.RunID)
+	args = append(args, usage.Timestamp)
+	args = append(args, usage.CpuSeconds)
+	args = append(args, int64(usage.MemSysBytes))
+	args = append(args, int64(usage.HeapAllocBytes))
+	args = append(args, usage.Goroutines)
+	args = append(args, usage.BytesIn)
+	args = append(args, usage.BytesOut)
+	args = append(args, usage.OpenConns)
+
+	return query, args
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// the persisted dial queue replaces the previous one, and is loaded back in the same order
+func TestDialQueueRoundTrip(t *testing.T) {
+	dbCli := newTestDBClient(t, utils.EthereumNetwork)
+	pID := testPeerID(t)
+	now := time.Now().Truncate(time.Second)
+
+	states, _, err := dbCli.LoadDialQueue()
+	require.NoError(t, err)
+	require.Empty(t, states)
+
+	queue := []models.DialQueueState{
+		{PeerID: pID, Position: 0, Pending: true, LastError: "None", Delay: "positive", DelayDegree: 1, LastAttempt: now, LastPositive: now},
+	}
+	require.NoError(t, dbCli.ReplaceDialQueue(queue))
+	require.NoError(t, dbCli.ReplaceDialQueue(queue))
+
+	states, saved, err := dbCli.LoadDialQueue()
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	require.Equal(t, queue[0].PeerID, states[0].PeerID)
+	require.Equal(t, queue[0].Pending, states[0].Pending)
+	require.Equal(t, queue[0].Delay, states[0].Delay)
+	require.Equal(t, queue[0].DelayDegree, states[0].DelayDegree)
+	require.True(t, queue[0].LastAttempt.Equal(states[0].LastAttempt))
+	require.True(t, queue[0].LastPositive.Equal(states[0].LastPositive))
+	require.WithinDuration(t, time.Now(), saved, time.Minute)
+
+	// the never dialed peers keep the zero time
+	queue[0].LastAttempt = time.Time{}
+	require.NoError(t, dbCli.ReplaceDialQueue(queue))
+	states, _, err = dbCli.LoadDialQueue()
+	require.NoError(t, err)
+	require.True(t, states[0].LastAttempt.IsZero())
+}
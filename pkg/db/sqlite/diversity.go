@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// this file contains the queries that summarize the diversity of the active peers of the network
+// (the same set of peers as the crawler metrics)
+
+// GetClientVersionDistribution returns the number of active peers of each client and version
+func (db *DBClient) GetClientVersionDistribution() ([]models.ClientCount, error) {
+	log.Debug("fetching client and version distribution")
+	counts := make([]models.ClientCount, 0)
+
+	rows, err := db.query(`
+		SELECT
+			client_name,
+			COALESCE(NULLIF(client_version, ''), 'unknown') as version,
+			count(*) as peers
+		FROM peer_info
+		WHERE
+			deprecated = false and
+			attempted = true and
+			client_name IS NOT NULL and
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = true)) and
+			($3 = '' OR peer_info.network_name = $3) and
+			last_activity > unixepoch() - $1 * 86400
+		GROUP BY client_name, version
+		ORDER BY peers DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return counts, errors.Wrap(err, "unable to fetch client and version distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var count models.ClientCount
+		err = rows.Scan(&count.Client, &count.Version, &count.Peers)
+		if err != nil {
+			return counts, errors.Wrap(err, "unable to parse fetched client and version distribution")
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// GetASNDistribution returns the number of active peers hosted at each autonomous system
+func (db *DBClient) GetASNDistribution() (map[string]int, error) {
+	log.Debug("fetching asn distribution")
+	asnDist := make(map[string]int)
+
+	rows, err := db.query(`
+		SELECT
+			COALESCE(NULLIF(ips.as_raw, ''), 'unknown') as asn,
+			count(*) as peers
+		FROM peer_info
+		LEFT JOIN ips ON peer_info.ip = ips.ip
+		WHERE
+			deprecated = false and
+			attempted = true and
+			client_name IS NOT NULL and
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = true)) and
+			($3 = '' OR peer_info.network_name = $3) and
+			last_activity > unixepoch() - $1 * 86400
+		GROUP BY asn
+		ORDER BY peers DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return asnDist, errors.Wrap(err, "unable to fetch asn distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var asn string
+		var count int
+		err = rows.Scan(&asn, &count)
+		if err != nil {
+			return asnDist, errors.Wrap(err, "unable to parse fetched asn distribution")
+		}
+		asnDist[asn] = count
+	}
+	return asnDist, nil
+}
+
+// GetCloudProviderDistribution returns the number of active peers hosted at each cloud provider
+// ("none" for the IPs that aren't hosted at any of the tagged providers)
+func (db *DBClient) GetCloudProviderDistribution() (map[string]int, error) {
+	log.Debug("fetching cloud provider distribution")
+	cloudDist := make(map[string]int)
+
+	rows, err := db.query(`
+		SELECT
+			COALESCE(NULLIF(ips.cloud_provider, ''), 'none') as provider,
+			count(*) as peers
+		FROM peer_info
+		LEFT JOIN ips ON peer_info.ip = ips.ip
+		WHERE
+			deprecated = false and
+			attempted = true and
+			client_name IS NOT NULL and
+			($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = true)) and
+			($3 = '' OR peer_info.network_name = $3) and
+			last_activity > unixepoch() - $1 * 86400
+		GROUP BY provider
+		ORDER BY peers DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return cloudDist, errors.Wrap(err, "unable to fetch cloud provider distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var provider string
+		var count int
+		err = rows.Scan(&provider, &count)
+		if err != nil {
+			return cloudDist, errors.Wrap(err, "unable to parse fetched cloud provider distribution")
+		}
+		cloudDist[provider] = count
+	}
+	return cloudDist, nil
+}
+
+// GetAttnetSubscriptions returns the number of nodes seen in the last day that advertise each of the
+// attestation subnets in their ENR. Only Ethereum tracks attnets, empty for the rest of networks
+func (db *DBClient) GetAttnetSubscriptions() (map[int]int, error) {
+	subscriptions := make(map[int]int)
+	if db.Network != utils.EthereumNetwork {
+		return subscriptions, nil
+	}
+	log.Debug("fetching attnet subscriptions")
+
+	// SQLite can't read the bits of the attnets (the hex encoded SSZ bitvector), so they are
+	// counted out of the attnets of each node
+	rows, err := db.query(`
+		SELECT attnets
+		FROM eth_nodes
+		WHERE
+			fork_digest IS NOT NULL and
+			length(attnets) = $1 / 4 and
+			timestamp > unixepoch() - 86400;
+		`,
+		eth.SubnetLimit,
+	)
+	if err != nil {
+		return subscriptions, errors.Wrap(err, "unable to fetch attnet subscriptions")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var attnetsStr string
+		err = rows.Scan(&attnetsStr)
+		if err != nil {
+			return subscriptions, errors.Wrap(err, "unable to parse fetched attnet subscriptions")
+		}
+		attnets, err := hex.DecodeString(attnetsStr)
+		if err != nil {
+			continue
+		}
+		for _, subnet := range eth.SubnetIndexes(attnets, eth.SubnetLimit) {
+			subscriptions[subnet]++
+		}
+	}
+	return subscriptions, nil
+}
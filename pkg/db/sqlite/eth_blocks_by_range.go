@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+func (c *DBClient) InitEthBlocksByRangeProbesTable() error {
+	return c.initTable("eth_blocks_by_range_probes", `
+		CREATE TABLE IF NOT EXISTS eth_blocks_by_range_probes(
+			peer_id TEXT NOT NULL,
+			attempts INT NOT NULL DEFAULT 1,
+			successes INT NOT NULL DEFAULT 0,
+			last_start_slot BIGINT NOT NULL,
+			last_requested INT NOT NULL,
+			last_received INT NOT NULL,
+			last_bytes BIGINT NOT NULL,
+			last_latency_ms BIGINT NOT NULL,
+			last_duration_ms BIGINT NOT NULL,
+			last_throughput REAL NOT NULL,
+			last_error TEXT,
+			last_seen TIMESTAMP NOT NULL,
+
+			PRIMARY KEY(peer_id)
+		);
+	`)
+}
+
+// UpsertBlocksByRangeProbe accounts the BlocksByRange probe of the peer, aggregating the served
+// probes and keeping the blocks, latency and throughput of the last one
+func (c *DBClient) UpsertBlocksByRangeProbe(probe *eth.BlocksByRangeProbe) (query string, args []interface{}) {
+	log.Trace("upserting blocks by range probe of peer ", probe.PeerID.String())
+
+	query = `
+		INSERT INTO eth_blocks_by_range_probes(
+			peer_id,
+			successes,
+			last_start_slot,
+			last_requested,
+			last_received,
+			last_bytes,
+			last_latency_ms,
+			last_duration_ms,
+			last_throughput,
+			last_error,
+			last_seen)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT (peer_id)
+		DO UPDATE SET
+			attempts = eth_blocks_by_range_probes.attempts + 1,
+			successes = eth_blocks_by_range_probes.successes + excluded.successes,
+			last_start_slot = excluded.last_start_slot,
+			last_requested = excluded.last_requested,
+			last_received = excluded.last_received,
+			last_bytes = excluded.last_bytes,
+			last_latency_ms = excluded.last_latency_ms,
+			last_duration_ms = excluded.last_duration_ms,
+			last_throughput = excluded.last_throughput,
+			last_error = excluded.last_error,
+			last_seen = excluded.last_seen;
+	`
+
+	successes := 0
+	if probe.Success() {
+		successes = 1
+	}
+
+	args = append(args, probe.PeerID.String())
+	args = append(args, successes)
+	args = append(args, probe.StartSlot)
+	args = append(args, probe.Requested)
+	args = append(args, probe.Received)
+	args = append(args, probe.Bytes)
+	args = append(args, probe.Latency.Milliseconds())
+	args = append(args, probe.Duration.Milliseconds())
+	args = append(args, probe.Throughput())
+	args = append(args, probe.Error)
+	args = append(args, probe.Timestamp)
+
+	return query, args
+}
@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (db *DBClient) GetNodePerForkDistribution() (map[string]interface{}, error) {
+	log.Debug("fetching node per fork distribution")
+	nodeDist := make(map[string]interface{})
+
+	rows, err := db.query(`
+		SELECT
+			aux.fork_digest,
+			count(aux.fork_digest) as cnt
+		FROM (
+			SELECT
+				CURRENT_TIMESTAMP as c_t,
+				timestamp as t_s,
+				fork_digest
+			FROM eth_nodes
+			WHERE fork_digest IS NOT NULL and timestamp > unixepoch() - 86400
+		) as aux
+		GROUP BY fork_digest
+		ORDER BY cnt DESC;
+		`,
+	)
+	if err != nil {
+		return nodeDist, errors.Wrap(err, "unable to fetch node per fork distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var forkName string
+		var count int
+		err = rows.Scan(&forkName, &count)
+		if err != nil {
+			return nodeDist, errors.Wrap(err, "unable to parse fetched node per fork distribution")
+		}
+		nodeDist[forkName] = count
+	}
+
+	return nodeDist, nil
+}
+
+func (db *DBClient) GetAttnetsDistribution() (map[string]interface{}, error) {
+	log.Debug("fetching attnets distribution")
+	nodeDist := make(map[string]interface{})
+
+	rows, err := db.query(`
+		SELECT aux.attnets_number as attnets,
+			count(aux.attnets_number) as cnt
+		FROM (
+		SELECT
+				CURRENT_TIMESTAMP as c_t,
+				timestamp as t,
+				fork_digest,
+				attnets_number
+			FROM eth_nodes
+			WHERE fork_digest IS NOT NULL and timestamp > unixepoch() - 86400
+		) as aux
+		GROUP BY attnets
+		ORDER BY cnt DESC;	
+		`,
+	)
+	if err != nil {
+		return nodeDist, errors.Wrap(err, "unable to fetch attnet distribution")
+	}
+	// make sure we close the rows and we free the connection/session
+	defer rows.Close()
+
+	for rows.Next() {
+		var attnets int
+		var count int
+		err = rows.Scan(&attnets, &count)
+		if err != nil {
+			return nodeDist, errors.Wrap(err, "unable to parse fetched attnets distribution")
+		}
+		nodeDist[fmt.Sprintf("%d", attnets)] = count
+	}
+
+	return nodeDist, nil
+}
+
+func (db *DBClient) GetDeprecatedNodes() (int, error) {
+	log.Debug("fetching deprecated node count")
+
+	var deprecatedCount int
+	err := db.queryRow(`
+		select
+			count(deprecated)
+		from peer_info
+		where deprecated=true and
+		      ($1 = '' OR network_name = $1);
+		`,
+		db.networkName,
+	).Scan(
+		&deprecatedCount,
+	)
+	if err != nil {
+		return deprecatedCount, errors.Wrap(err, "unable to fetch deprecated node count")
+	}
+
+	return deprecatedCount, nil
+}
+
+// GetGossipArrivalBaselines returns the average time in slot at which the beacon blocks and the attestations
+// of the last given slots arrived, distinguishing those first received from our static peers (the
+// propagation-delay baseline) from those first received from the rest of the network
+func (db *DBClient) GetGossipArrivalBaselines(slots int) (map[string]map[string]float64, error) {
+	log.Debug("fetching gossip arrival baselines")
+	baselines := make(map[string]map[string]float64)
+
+	msgTables := map[string]string{
+		"beacon_block": "eth_blocks",
+		"attestation":  "eth_attestations",
+	}
+	for msgType, table := range msgTables {
+		rows, err := db.query(
+			fmt.Sprintf(`
+			SELECT
+				CASE
+					WHEN sender IN (SELECT peer_id FROM static_peers WHERE active = true) THEN 'static'
+					ELSE 'network'
+				END as source,
+				avg(time_in_slot) as avg_time_in_slot
+			FROM %s
+			WHERE slot > (SELECT max(slot) FROM %s) - $1
+			GROUP BY source;
+			`, table, table),
+			slots,
+		)
+		if err != nil {
+			return baselines, errors.Wrap(err, "unable to fetch arrival baselines from "+table)
+		}
+
+		baselines[msgType] = make(map[string]float64)
+		for rows.Next() {
+			var source string
+			var avgTime float64
+			err = rows.Scan(&source, &avgTime)
+			if err != nil {
+				rows.Close()
+				return baselines, errors.Wrap(err, "unable to parse fetched arrival baselines from "+table)
+			}
+			baselines[msgType][source] = avgTime
+		}
+		rows.Close()
+	}
+
+	return baselines, nil
+}
@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"encoding/json"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	log "github.com/sirupsen/logrus"
+)
+
+func (d *DBClient) InitEthNodesTable() error {
+	return d.initTable("eth_nodes",
+		`
+		CREATE TABLE IF NOT EXISTS eth_nodes(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp BIGINT NOT NULL,
+			peer_id TEXT,
+			node_id TEXT NOT NULL UNIQUE,
+			seq BIGINT NOT NULL,
+			ip TEXT NOT NULL,
+			tcp INT,
+			udp INT,
+			pubkey TEXT NOT NULL,
+			fork_digest TEXT,
+			next_fork_version TEXT,
+			attnets TEXT,
+			attnets_number INT,
+			quic INT,
+			csc INT,
+			enr TEXT,
+			enr_fields TEXT,
+
+			UNIQUE(peer_id, pubkey)
+		);
+		`,
+		// every version (seq) of the ENR of each node
+		`
+		CREATE TABLE IF NOT EXISTS eth_node_records(
+			node_id TEXT NOT NULL,
+			seq BIGINT NOT NULL,
+			first_seen BIGINT NOT NULL,
+			fields TEXT NOT NULL,
+			enr TEXT NOT NULL,
+
+			PRIMARY KEY(node_id, seq)
+		);
+		`,
+	)
+}
+
+// Insert ENR in the DB
+// insert into the db if new one, update the data if the ENR has a higher Seq number
+func (d *DBClient) UpsertEnrInfo(enr *eth.EnrNode) (query string, args []interface{}) {
+	log.Trace("upserting new enr to eth_nodes in sqlite-db")
+
+	query = `
+		INSERT INTO eth_nodes(
+			timestamp,
+			peer_id,
+			node_id,
+			seq,
+			ip,
+			tcp,
+			udp,
+			pubkey,
+			fork_digest,
+			next_fork_version,
+			attnets,
+			attnets_number,
+			quic,
+			csc,
+			enr,
+			enr_fields)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+		ON CONFLICT (node_id)
+		DO UPDATE SET
+			timestamp = excluded.timestamp,
+			seq = excluded.seq,
+			ip = excluded.ip,
+			tcp = excluded.tcp,
+			udp = excluded.udp,
+			fork_digest = excluded.fork_digest,
+			next_fork_version = excluded.next_fork_version,
+			attnets = excluded.attnets,
+			attnets_number = excluded.attnets_number,
+			quic = excluded.quic,
+			csc = excluded.csc,
+			enr = excluded.enr,
+			enr_fields = excluded.enr_fields;
+		`
+
+	// if peer_id goes empty, not my fault here we should have checked it before
+	var peerIDStr string
+	peerId, err := enr.GetPeerID()
+	if err == nil {
+		peerIDStr = peerId.String()
+	}
+
+	args = append(args, enr.Timestamp.Unix())
+	args = append(args, peerIDStr)
+	args = append(args, enr.ID.String())
+	args = append(args, enr.Seq)
+	args = append(args, enr.IP)
+	args = append(args, enr.TCP)
+	args = append(args, enr.UDP)
+	args = append(args, enr.GetPubkeyString())
+	args = append(args, enr.Eth2Data.ForkDigest.String())
+	args = append(args, enr.Eth2Data.NextForkVersion.String())
+	args = append(args, enr.GetAttnetsString())
+	args = append(args, enr.Attnets.NetNumber)
+	args = append(args, enr.QUIC)
+	args = append(args, enr.CSC)
+	args = append(args, enr.Raw)
+	args = append(args, enrFieldsJSON(enr))
+
+	return query, args
+}
+
+// UpsertEnrRecord keeps the version (seq) of the node's ENR, the first time it was seen
+func (d *DBClient) UpsertEnrRecord(enr *eth.EnrNode) (query string, args []interface{}) {
+	log.Trace("upserting new enr record to eth_node_records in sqlite-db")
+
+	query = `
+		INSERT INTO eth_node_records(
+			node_id,
+			seq,
+			first_seen,
+			fields,
+			enr)
+		VALUES($1,$2,$3,$4,$5)
+		ON CONFLICT (node_id, seq)
+		DO NOTHING;
+		`
+
+	args = append(args, enr.ID.String())
+	args = append(args, enr.Seq)
+	args = append(args, enr.Timestamp.Unix())
+	args = append(args, enrFieldsJSON(enr))
+	args = append(args, enr.Raw)
+
+	return query, args
+}
+
+// enrFieldsJSON serializes the decoded fields of the ENR ("{}" if they couldn't be serialized)
+func enrFieldsJSON(enr *eth.EnrNode) string {
+	if enr.Fields == nil {
+		return "{}"
+	}
+	fields, err := json.Marshal(enr.Fields)
+	if err != nil {
+		log.Warnf("unable to serialize the enr fields of node %s - %s", enr.ID.String(), err.Error())
+		return "{}"
+	}
+	return string(fields)
+}
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+func (d *DBClient) InitEthereumNodeStatus() error {
+	return d.initTable("eth_status", `
+		CREATE TABLE IF NOT EXISTS eth_status(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			peer_id TEXT UNIQUE,
+			timestamp BIGINT,
+			fork_digest TEXT,
+			finalized_root TEXT,
+			finalized_epoch BIGINT,
+			head_root TEXT,
+			head_slot BIGINT,
+			seq_number BIGINT,
+			attnets TEXT,
+			syncnets TEXT,
+			ping_seq_number BIGINT,
+			ping_latency INT,
+			ping_timestamp BIGINT,
+			metadata_timestamp BIGINT
+		);
+	`)
+}
+
+func (d *DBClient) UpsertEthereumNodeStatus(bstatus eth.BeaconStatusStamped) (query string, args []interface{}) {
+	log.Trace("upserting beacon status to eth_status in sqlite-db")
+	query = `
+		INSERT INTO eth_status(
+			peer_id,
+			timestamp,
+			fork_digest,
+			finalized_root,
+			finalized_epoch,
+			head_root,
+			head_slot)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (peer_id)
+		DO UPDATE SET
+			peer_id = excluded.peer_id,
+			timestamp = excluded.timestamp,
+			fork_digest = excluded.fork_digest,
+			finalized_root = excluded.finalized_root,
+			finalized_epoch = excluded.finalized_epoch,
+			head_root = excluded.head_root,
+			head_slot = excluded.head_slot;
+	`
+
+	args = append(args, bstatus.PeerID.String())
+	args = append(args, bstatus.Timestamp.Unix())
+	args = append(args, bstatus.Status.ForkDigest.String())
+	args = append(args, bstatus.Status.FinalizedRoot.String())
+	args = append(args, bstatus.Status.FinalizedEpoch)
+	args = append(args, bstatus.Status.HeadRoot.String())
+	args = append(args, bstatus.Status.HeadSlot)
+
+	return query, args
+}
+
+func (d *DBClient) UpsertEthereumNodeMetadata(bmetadata eth.BeaconMetadataStamped) (query string, args []interface{}) {
+	log.Trace("upserting beacon metadata to eth_status in sqlite-db")
+	// merge rules: only the successful metadata requests are persisted, so the metadata of a peer
+	// is never downgraded by a failed one, and an older metadata persisted after a newer one
+	// (i.e. from an interleaved connection) never replaces it
+	query = `
+		INSERT INTO eth_status(
+			peer_id,
+			timestamp,
+			metadata_timestamp,
+			seq_number,
+			attnets,
+			syncnets)
+		VALUES ($1,$2,$2,$3,$4,$5)
+		ON CONFLICT (peer_id)
+		DO UPDATE SET
+			peer_id = excluded.peer_id,
+			timestamp = max(COALESCE(eth_status.timestamp, excluded.timestamp), excluded.timestamp),
+			metadata_timestamp = max(COALESCE(eth_status.metadata_timestamp, excluded.metadata_timestamp), excluded.metadata_timestamp),
+			seq_number = CASE WHEN excluded.metadata_timestamp >= COALESCE(eth_status.metadata_timestamp, 0) THEN excluded.seq_number ELSE eth_status.seq_number END,
+			attnets = CASE WHEN excluded.metadata_timestamp >= COALESCE(eth_status.metadata_timestamp, 0) THEN excluded.attnets ELSE eth_status.attnets END,
+			syncnets = CASE WHEN excluded.metadata_timestamp >= COALESCE(eth_status.metadata_timestamp, 0) THEN excluded.syncnets ELSE eth_status.syncnets END;
+		`
+
+	args = append(args, bmetadata.PeerID.String())
+	args = append(args, bmetadata.Timestamp.Unix())
+	args = append(args, bmetadata.Metadata.SeqNumber)
+	args = append(args, bmetadata.Metadata.Attnets.String())
+	args = append(args, bmetadata.Metadata.Syncnets.String())
+
+	return query, args
+}
+
+func (d *DBClient) UpsertEthereumNodePing(bping eth.BeaconPingStamped) (query string, args []interface{}) {
+	log.Trace("upserting beacon ping to eth_status in sqlite-db")
+	query = `
+		INSERT INTO eth_status(
+			peer_id,
+			ping_timestamp,
+			ping_seq_number,
+			ping_latency)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (peer_id)
+		DO UPDATE SET
+			ping_timestamp = excluded.ping_timestamp,
+			ping_seq_number = excluded.ping_seq_number,
+			ping_latency = excluded.ping_latency;
+		`
+
+	args = append(args, bping.PeerID.String())
+	args = append(args, bping.Timestamp.Unix())
+	args = append(args, bping.SeqNumber)
+	args = append(args, bping.RTT.Milliseconds())
+
+	return query, args
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+func (c *DBClient) InitEthNodeSubnetsTable() error {
+	return c.initTable("eth_node_subnets", `
+		CREATE TABLE IF NOT EXISTS eth_node_subnets(
+			node_id TEXT NOT NULL,
+			subnet_type TEXT NOT NULL,
+			subnet INT NOT NULL,
+			first_seen BIGINT NOT NULL,
+			last_seen BIGINT NOT NULL,
+
+			PRIMARY KEY(node_id, subnet_type, subnet)
+		);
+	`)
+}
+
+// UpsertEthNodeSubnets records the attestation and sync committee subnets advertised in the ENR of
+// the node, keeping when the node was seen in each subnet for the first and the last time
+func (c *DBClient) UpsertEthNodeSubnets(enr *eth.EnrNode) (query string, args []interface{}) {
+	log.Trace("upserting subnets of eth node ", enr.ID.String())
+
+	// the subnet types and the subnets are zipped by their position in the arrays, and the WHERE
+	// tells SQLite that the ON CONFLICT isn't the join constraint of the FROM
+	query = `
+		INSERT INTO eth_node_subnets(
+			node_id,
+			subnet_type,
+			subnet,
+			first_seen,
+			last_seen)
+		SELECT $1, subnet_types.value, subnets.value, $4, $4
+		FROM json_each($2) AS subnet_types
+		INNER JOIN json_each($3) AS subnets ON subnets.key = subnet_types.key
+		WHERE true
+		ON CONFLICT (node_id, subnet_type, subnet)
+		DO UPDATE SET
+			last_seen = max(eth_node_subnets.last_seen, excluded.last_seen);
+	`
+
+	subnetTypes := make([]string, 0)
+	subnets := make([]int, 0)
+	for _, subnet := range enr.AttSubnets() {
+		subnetTypes = append(subnetTypes, eth.AttnetSubnet)
+		subnets = append(subnets, subnet)
+	}
+	for _, subnet := range enr.SyncSubnets() {
+		subnetTypes = append(subnetTypes, eth.SyncnetSubnet)
+		subnets = append(subnets, subnet)
+	}
+
+	args = append(args, enr.ID.String())
+	args = append(args, jsonArray{subnetTypes})
+	args = append(args, jsonArray{subnets})
+	args = append(args, enr.Timestamp.Unix())
+
+	return query, args
+}
+
+// GetPeersPerSubnet returns the number of nodes that advertise each of the subnets in their
+// latest ENR (only the nodes seen within the last day), by subnet type
+func (db *DBClient) GetPeersPerSubnet() (map[string]map[int]int, error) {
+	summary := map[string]map[int]int{
+		eth.AttnetSubnet:  make(map[int]int),
+		eth.SyncnetSubnet: make(map[int]int),
+	}
+	rows, err := db.query(`
+		SELECT
+			sub.subnet_type,
+			sub.subnet,
+			count(*) as nodes
+		FROM eth_node_subnets AS sub
+		INNER JOIN eth_nodes ON eth_nodes.node_id = sub.node_id
+		WHERE sub.last_seen >= eth_nodes.timestamp and
+		      eth_nodes.timestamp > unixepoch() - 86400
+		GROUP BY sub.subnet_type, sub.subnet;
+		`,
+	)
+	if err != nil {
+		return summary, errors.Wrap(err, "unable to fetch peers per subnet")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subnetType string
+		var subnet int
+		var count int
+		err = rows.Scan(&subnetType, &subnet, &count)
+		if err != nil {
+			return summary, errors.Wrap(err, "unable to parse peers per subnet")
+		}
+		if _, ok := summary[subnetType]; !ok {
+			summary[subnetType] = make(map[int]int)
+		}
+		summary[subnetType][subnet] = count
+	}
+	return summary, nil
+}
+
+// GetPeerAttnets returns the attestation subnets advertised in the latest ENR of each peer
+// (only the nodes seen within the last day)
+func (db *DBClient) GetPeerAttnets() (map[peer.ID][]int, error) {
+	attnets := make(map[peer.ID][]int)
+	rows, err := db.query(`
+		SELECT
+			eth_nodes.peer_id,
+			json_group_array(sub.subnet ORDER BY sub.subnet)
+		FROM eth_node_subnets AS sub
+		INNER JOIN eth_nodes ON eth_nodes.node_id = sub.node_id
+		WHERE sub.subnet_type = $1 and
+		      sub.last_seen >= eth_nodes.timestamp and
+		      eth_nodes.timestamp > unixepoch() - 86400 and
+		      eth_nodes.peer_id IS NOT NULL and eth_nodes.peer_id != ''
+		GROUP BY eth_nodes.peer_id;
+		`,
+		eth.AttnetSubnet,
+	)
+	if err != nil {
+		return attnets, errors.Wrap(err, "unable to fetch the attnets of the peers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peerIDStr string
+		var subnets []int
+		err = rows.Scan(&peerIDStr, jsonArray{&subnets})
+		if err != nil {
+			return attnets, errors.Wrap(err, "unable to parse the attnets of the peers")
+		}
+		peerID, err := peer.Decode(peerIDStr)
+		if err != nil {
+			log.Errorf("unable to get peerID from DB %s", peerIDStr)
+			continue
+		}
+		attnets[peerID] = subnets
+	}
+	return attnets, nil
+}
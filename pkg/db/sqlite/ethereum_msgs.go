@@ -0,0 +1,296 @@
+package sqlite
+
+import (
+	"time"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+func (c *DBClient) initEthereumAttestationsTable() error {
+	return c.initTable("eth_attestations", `
+		CREATE TABLE IF NOT EXISTS eth_attestations(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			msg_id TEXT NOT NULL UNIQUE,
+			sender TEXT NOT NULL,
+			subnet INT NOT NULL,
+			slot BIGINT NOT NULL,
+			arrival_time TIMESTAMP NOT NULL,
+			time_in_slot REAL NOT NULL,
+			val_pubkey TEXT,
+			committee_index BIGINT,
+			aggregation_bit BIGINT,
+			block_root TEXT,
+			target_epoch BIGINT,
+			run_id INT
+		);
+	`)
+}
+
+func (c *DBClient) InsertNewEthereumAttestation(attMsg *eth.TrackedAttestation) (query string, args []interface{}) {
+
+	query = `
+	INSERT INTO eth_attestations(
+		msg_id,
+		sender,
+		subnet,
+		slot,
+		arrival_time,
+		time_in_slot,
+		val_pubkey,
+		committee_index,
+		aggregation_bit,
+		block_root,
+		target_epoch,
+		run_id)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	ON CONFLICT (msg_id) DO NOTHING
+	`
+
+	// args
+	args = append(args, attMsg.MsgID)
+	args = append(args, attMsg.Sender.String())
+	args = append(args, attMsg.Subnet)
+	args = append(args, attMsg.Slot)
+	args = append(args, attMsg.ArrivalTime)
+	args = append(args, float64(attMsg.TimeInSlot)/float64(time.Second))
+	args = append(args, attMsg.ValPubkey)
+	args = append(args, attMsg.CommitteeIndex)
+	args = append(args, attMsg.AggregationBit)
+	args = append(args, attMsg.BlockRoot)
+	args = append(args, attMsg.TargetEpoch)
+	args = append(args, c.RunID())
+
+	return query, args
+}
+
+// Beacon Blocks
+func (c *DBClient) initEthereumBeaconBlocksTable() error {
+	return c.initTable("eth_blocks", `
+		CREATE TABLE IF NOT EXISTS eth_blocks(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			msg_id TEXT NOT NULL UNIQUE,
+			sender TEXT NOT NULL,
+			slot BIGINT NOT NULL,
+			arrival_time TIMESTAMP NOT NULL,
+			time_in_slot REAL NOT NULL,
+			val_idx BIGINT,
+			block_root TEXT,
+			parent_root TEXT,
+			blob_count BIGINT,
+			run_id INT
+		);
+	`)
+}
+
+// InsertNewEthereumBeaconBlock records the block (the delay of the blobs of the block that
+// arrived before it is set by UpdateBlobsBlockDelay)
+func (c *DBClient) InsertNewEthereumBeaconBlock(bblock *eth.TrackedBeaconBlock) (query string, args []interface{}) {
+
+	query = `
+	INSERT INTO eth_blocks(
+		msg_id,
+		sender,
+		slot,
+		arrival_time,
+		time_in_slot,
+		val_idx,
+		block_root,
+		parent_root,
+		blob_count,
+		run_id)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	ON CONFLICT (msg_id) DO NOTHING
+	`
+
+	// args
+	args = append(args, bblock.MsgID)
+	args = append(args, bblock.Sender.String())
+	args = append(args, bblock.Slot)
+	args = append(args, bblock.ArrivalTime)
+	args = append(args, float64(bblock.TimeInSlot)/float64(time.Second))
+	args = append(args, bblock.ValIndex)
+	args = append(args, bblock.BlockRoot)
+	args = append(args, bblock.ParentRoot)
+	args = append(args, bblock.BlobCount)
+	args = append(args, c.RunID())
+
+	return query, args
+}
+
+// UpdateBlobsBlockDelay sets the delay of the blobs of the block that arrived before it. It has to
+// follow the InsertNewEthereumBeaconBlock of the block, as it only applies if the block was inserted
+// (changes() returns the rows inserted by the previous query)
+func (c *DBClient) UpdateBlobsBlockDelay(bblock *eth.TrackedBeaconBlock) (query string, args []interface{}) {
+
+	query = `
+	UPDATE eth_blob_sidecars
+	SET block_delay = eth_blob_sidecars.time_in_slot - $2
+	WHERE changes() > 0 AND
+		eth_blob_sidecars.block_root = $1 AND
+		eth_blob_sidecars.block_delay IS NULL
+	`
+
+	args = append(args, bblock.BlockRoot)
+	args = append(args, float64(bblock.TimeInSlot)/float64(time.Second))
+
+	return query, args
+}
+
+// Aggregates
+func (c *DBClient) initEthereumAggregatesTable() error {
+	return c.initTable("eth_aggregates", `
+		CREATE TABLE IF NOT EXISTS eth_aggregates(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			msg_id TEXT NOT NULL UNIQUE,
+			sender TEXT NOT NULL,
+			slot BIGINT NOT NULL,
+			arrival_time TIMESTAMP NOT NULL,
+			time_in_slot REAL NOT NULL,
+			aggregator_idx BIGINT NOT NULL,
+			committee_index BIGINT NOT NULL,
+			participants BIGINT NOT NULL,
+			block_root TEXT NOT NULL,
+			target_epoch BIGINT NOT NULL,
+			run_id INT
+		);
+	`)
+}
+
+func (c *DBClient) InsertNewEthereumAggregate(aggregate *eth.TrackedAggregateAndProof) (query string, args []interface{}) {
+
+	query = `
+	INSERT INTO eth_aggregates(
+		msg_id,
+		sender,
+		slot,
+		arrival_time,
+		time_in_slot,
+		aggregator_idx,
+		committee_index,
+		participants,
+		block_root,
+		target_epoch,
+		run_id)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	ON CONFLICT (msg_id) DO NOTHING
+	`
+
+	// args
+	args = append(args, aggregate.MsgID)
+	args = append(args, aggregate.Sender.String())
+	args = append(args, aggregate.Slot)
+	args = append(args, aggregate.ArrivalTime)
+	args = append(args, float64(aggregate.TimeInSlot)/float64(time.Second))
+	args = append(args, aggregate.AggregatorIndex)
+	args = append(args, aggregate.CommitteeIndex)
+	args = append(args, aggregate.Participants)
+	args = append(args, aggregate.BlockRoot)
+	args = append(args, aggregate.TargetEpoch)
+	args = append(args, c.RunID())
+
+	return query, args
+}
+
+// Blob sidecars
+func (c *DBClient) initEthereumBlobSidecarsTable() error {
+	return c.initTable("eth_blob_sidecars",
+		`
+		CREATE TABLE IF NOT EXISTS eth_blob_sidecars(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			msg_id TEXT NOT NULL UNIQUE,
+			sender TEXT NOT NULL,
+			subnet INT NOT NULL,
+			slot BIGINT NOT NULL,
+			arrival_time TIMESTAMP NOT NULL,
+			time_in_slot REAL NOT NULL,
+			blob_index BIGINT NOT NULL,
+			proposer_idx BIGINT NOT NULL,
+			block_root TEXT NOT NULL,
+			kzg_commitment TEXT NOT NULL,
+			block_delay REAL,
+			run_id INT
+		);
+		`,
+		// blobs delivered by each of the peers (the ones that they delivered first to us)
+		`
+		CREATE TABLE IF NOT EXISTS eth_blob_peers(
+			peer_id TEXT NOT NULL,
+			blobs BIGINT NOT NULL,
+			total_time_in_slot REAL NOT NULL,
+			first_slot BIGINT NOT NULL,
+			last_slot BIGINT NOT NULL,
+
+			PRIMARY KEY(peer_id)
+		);
+		`,
+	)
+}
+
+// InsertNewEthereumBlobSidecar records the blob (it is accounted in the delivery stats of its sender
+// by UpsertBlobPeer). The delay of the blob relative to its block (in seconds) is set if the block
+// arrived before it, otherwise it is set once the block arrives (negative)
+func (c *DBClient) InsertNewEthereumBlobSidecar(blob *eth.TrackedBlobSidecar) (query string, args []interface{}) {
+
+	query = `
+	INSERT INTO eth_blob_sidecars(
+		msg_id,
+		sender,
+		subnet,
+		slot,
+		arrival_time,
+		time_in_slot,
+		blob_index,
+		proposer_idx,
+		block_root,
+		kzg_commitment,
+		block_delay,
+		run_id)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,
+		(SELECT $6 - eth_blocks.time_in_slot FROM eth_blocks WHERE eth_blocks.block_root = $9 LIMIT 1),
+		$11)
+	ON CONFLICT (msg_id) DO NOTHING
+	`
+
+	// args
+	args = append(args, blob.MsgID)
+	args = append(args, blob.Sender.String())
+	args = append(args, blob.Subnet)
+	args = append(args, blob.Slot)
+	args = append(args, blob.ArrivalTime)
+	args = append(args, float64(blob.TimeInSlot)/float64(time.Second))
+	args = append(args, blob.Index)
+	args = append(args, blob.ProposerIndex)
+	args = append(args, blob.BlockRoot)
+	args = append(args, blob.KZGCommitment)
+	args = append(args, c.RunID())
+
+	return query, args
+}
+
+// UpsertBlobPeer accounts the blob in the delivery stats of its sender. It has to follow the
+// InsertNewEthereumBlobSidecar of the blob, as only the blobs that were inserted (the ones that the
+// peer delivered first) are accounted (changes() returns the rows inserted by the previous query)
+func (c *DBClient) UpsertBlobPeer(blob *eth.TrackedBlobSidecar) (query string, args []interface{}) {
+
+	query = `
+	INSERT INTO eth_blob_peers(
+		peer_id,
+		blobs,
+		total_time_in_slot,
+		first_slot,
+		last_slot)
+	SELECT $1, 1, $2, $3, $3
+	WHERE changes() > 0
+	ON CONFLICT (peer_id) DO UPDATE SET
+		blobs = eth_blob_peers.blobs + 1,
+		total_time_in_slot = eth_blob_peers.total_time_in_slot + excluded.total_time_in_slot,
+		first_slot = min(eth_blob_peers.first_slot, excluded.first_slot),
+		last_slot = max(eth_blob_peers.last_slot, excluded.last_slot)
+	`
+
+	args = append(args, blob.Sender.String())
+	args = append(args, float64(blob.TimeInSlot)/float64(time.Second))
+	args = append(args, blob.Slot)
+
+	return query, args
+}
@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// only the first delivery of a blob is accounted for its sender, and the delay of the blob to its
+// block is set whatever arrived first
+func TestBlobSidecarsAccounting(t *testing.T) {
+	dbCli := newTestDBClient(t, utils.EthereumNetwork)
+	pID := testPeerID(t)
+	now := time.Now()
+
+	persistBlob := func(blob *eth.TrackedBlobSidecar) {
+		q, args := dbCli.InsertNewEthereumBlobSidecar(blob)
+		_, err := dbCli.exec(q, args...)
+		require.NoError(t, err)
+		q, args = dbCli.UpsertBlobPeer(blob)
+		_, err = dbCli.exec(q, args...)
+		require.NoError(t, err)
+	}
+	persistBlock := func(block *eth.TrackedBeaconBlock) {
+		q, args := dbCli.InsertNewEthereumBeaconBlock(block)
+		_, err := dbCli.exec(q, args...)
+		require.NoError(t, err)
+		q, args = dbCli.UpdateBlobsBlockDelay(block)
+		_, err = dbCli.exec(q, args...)
+		require.NoError(t, err)
+	}
+	blobDelay := func(msgID string) sql.NullFloat64 {
+		var delay sql.NullFloat64
+		err := dbCli.queryRow(`SELECT block_delay FROM eth_blob_sidecars WHERE msg_id = $1;`, msgID).Scan(&delay)
+		require.NoError(t, err)
+		return delay
+	}
+
+	// the blob arrives before its block
+	early := &eth.TrackedBlobSidecar{MsgID: "0x01", Sender: pID, ArrivalTime: now, TimeInSlot: 2 * time.Second, Slot: 100, BlockRoot: "0xaa"}
+	persistBlob(early)
+	persistBlob(early)
+	require.False(t, blobDelay(early.MsgID).Valid)
+
+	block := &eth.TrackedBeaconBlock{MsgID: "0x02", Sender: pID, ArrivalTime: now, TimeInSlot: 3 * time.Second, Slot: 100, BlockRoot: "0xaa", BlobCount: 2}
+	persistBlock(block)
+	require.Equal(t, sql.NullFloat64{Float64: -1, Valid: true}, blobDelay(early.MsgID))
+
+	// and after it
+	late := &eth.TrackedBlobSidecar{MsgID: "0x03", Sender: pID, ArrivalTime: now, TimeInSlot: 4 * time.Second, Slot: 101, Index: 1, BlockRoot: "0xaa"}
+	persistBlob(late)
+	require.Equal(t, sql.NullFloat64{Float64: 1, Valid: true}, blobDelay(late.MsgID))
+
+	var blobs, firstSlot, lastSlot int64
+	var totalTime float64
+	err := dbCli.queryRow(`
+		SELECT blobs, total_time_in_slot, first_slot, last_slot
+		FROM eth_blob_peers WHERE peer_id = $1;`,
+		pID.String(),
+	).Scan(&blobs, &totalTime, &firstSlot, &lastSlot)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), blobs)
+	require.Equal(t, float64(6), totalTime)
+	require.Equal(t, int64(100), firstSlot)
+	require.Equal(t, int64(101), lastSlot)
+}
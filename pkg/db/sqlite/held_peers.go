@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitHeldPeersTable() error {
+	return c.initTable("held_peers", `
+		CREATE TABLE IF NOT EXISTS held_peers(
+			run_id INT NOT NULL,
+			peer_id TEXT NOT NULL,
+			stratum TEXT NOT NULL,
+			held_since TIMESTAMP NOT NULL,
+			last_update TIMESTAMP NOT NULL,
+			connected BOOL NOT NULL,
+			uptime_secs REAL NOT NULL,
+			drops INT NOT NULL,
+			redials INT NOT NULL,
+			failed_redials INT NOT NULL,
+			pings INT NOT NULL,
+			failed_pings INT NOT NULL,
+			last_rtt_ms REAL,
+			released BOOL NOT NULL,
+
+			PRIMARY KEY(run_id, peer_id)
+		);
+	`)
+}
+
+// UpsertHeldPeer keeps the latest state of a peer held by the connection holder in the run
+func (c *DBClient) UpsertHeldPeer(held *models.HeldPeer) (query string, args []interface{}) {
+	log.Trace("upserting held peer ", held.PeerID.String())
+
+	query = `
+		INSERT INTO held_peers(
+			run_id,
+			peer_id,
+			stratum,
+			held_since,
+			last_update,
+			connected,
+			uptime_secs,
+			drops,
+			redials,
+			failed_redials,
+			pings,
+			failed_pings,
+			last_rtt_ms,
+			released)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+		ON CONFLICT (run_id, peer_id)
+		DO UPDATE SET
+			stratum = excluded.stratum,
+			last_update = excluded.last_update,
+			connected = excluded.connected,
+			uptime_secs = excluded.uptime_secs,
+			drops = excluded.drops,
+			redials = excluded.redials,
+			failed_redials = excluded.failed_redials,
+			pings = excluded.pings,
+			failed_pings = excluded.failed_pings,
+			last_rtt_ms = COALESCE(excluded.last_rtt_ms, held_peers.last_rtt_ms),
+			released = excluded.released;
+	`
+
+	// no RTT until the first successful keepalive
+	var lastRTT interface{}
+	if held.Pings > 0 {
+		lastRTT = float64(held.LastRTT.Microseconds()) / 1000
+	}
+
+	args = append(args, held.RunID)
+	args = append(args, held.PeerID.String())
+	args = append(args, held.Stratum)
+	args = append(args, held.HeldSince)
+	args = append(args, held.LastUpdate)
+	args = append(args, held.Connected)
+	args = append(args, held.Uptime.Seconds())
+	args = append(args, held.Drops)
+	args = append(args, held.Redials)
+	args = append(args, held.FailedRedials)
+	args = append(args, held.Pings)
+	args = append(args, held.FailedPings)
+	args = append(args, lastRTT)
+	args = append(args, held.Released)
+
+	return query, args
+}
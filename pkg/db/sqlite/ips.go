@@ -0,0 +1,300 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) InitIpTable() error {
+	return c.initTable("ips", `
+		CREATE TABLE IF NOT EXISTS ips(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ip TEXT NOT NULL UNIQUE,
+			expiration_time TIMESTAMP NOT NULL,
+			continent TEXT NOT NULL,
+			continent_code TEXT NOT NULL,
+			country TEXT NOT NULL,
+			country_code TEXT NOT NULL,
+			region TEXT NOT NULL,
+			region_name TEXT NOT NULL,
+			city TEXT NOT NULL,
+			zip TEXT NOT NULL,
+			lat REAL NOT NULL,
+			lon REAL NOT NULL,
+			isp TEXT NOT NULL,
+			org TEXT NOT NULL,
+			as_raw TEXT NOT NULL,
+			asname TEXT NOT NULL,
+			mobile BOOL NOT NULL,
+			proxy BOOL NOT NULL,
+			hosting BOOL NOT NULL,
+			min_rtt_ms BIGINT,
+			geo_confidence REAL,
+			geo_implausible BOOL NOT NULL DEFAULT false,
+			asn INT,
+			ip_class TEXT,
+			fetched_at TIMESTAMP,
+			rdns TEXT,
+			cloud_provider TEXT,
+			anonymous BOOL NOT NULL DEFAULT false,
+			anonymous_source TEXT
+		);
+	`)
+}
+
+// UpsertIpInfo attemtps to insert IP in the DB - or Updates the data info if they where already there
+func (c *DBClient) UpsertIpInfo(ipInfo models.IpInfo) (query string, args []interface{}) {
+	log.Trace("upsert ip_info in sqlite-db")
+	// compose query (the geo sanity check is only kept while the location doesn't change)
+	query = `
+		INSERT INTO ips(
+			ip,
+			expiration_time,
+			continent,
+			continent_code,
+			country,
+			country_code,
+			region,
+			region_name,
+			city,
+			zip,
+			lat,
+			lon,
+			isp,
+			org,
+			as_raw,
+			asname,
+			mobile,
+			proxy,
+			hosting,
+			asn,
+			ip_class,
+			fetched_at,
+			rdns,
+			cloud_provider,
+			anonymous,
+			anonymous_source)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26)
+		ON CONFLICT (ip)
+		DO UPDATE SET
+			expiration_time = excluded.expiration_time,
+			continent = excluded.continent,
+			continent_code = excluded.continent_code,
+			country = excluded.country,
+			country_code = excluded.country_code,
+			region = excluded.region,
+			region_name = excluded.region_name,
+			city = excluded.city,
+			zip = excluded.zip,
+			lat = excluded.lat,
+			lon = excluded.lon,
+			isp = excluded.isp,
+			org = excluded.org,
+			as_raw = excluded.as_raw,
+			asname = excluded.asname,
+			mobile = excluded.mobile,
+			proxy = excluded.proxy,
+			hosting = excluded.hosting,
+			asn = excluded.asn,
+			ip_class = excluded.ip_class,
+			fetched_at = excluded.fetched_at,
+			rdns = excluded.rdns,
+			cloud_provider = excluded.cloud_provider,
+			anonymous = excluded.anonymous,
+			anonymous_source = excluded.anonymous_source,
+			min_rtt_ms = CASE WHEN ips.lat = excluded.lat AND ips.lon = excluded.lon THEN ips.min_rtt_ms ELSE NULL END,
+			geo_confidence = CASE WHEN ips.lat = excluded.lat AND ips.lon = excluded.lon THEN ips.geo_confidence ELSE NULL END,
+			geo_implausible = CASE WHEN ips.lat = excluded.lat AND ips.lon = excluded.lon THEN ips.geo_implausible ELSE false END;
+		`
+
+	args = append(args, ipInfo.IP)
+	args = append(args, ipInfo.ExpirationTime)
+	args = append(args, ipInfo.Continent)
+	args = append(args, ipInfo.ContinentCode)
+	args = append(args, ipInfo.Country)
+	args = append(args, ipInfo.CountryCode)
+	args = append(args, ipInfo.Region)
+	args = append(args, ipInfo.RegionName)
+	args = append(args, ipInfo.City)
+	args = append(args, ipInfo.Zip)
+	args = append(args, ipInfo.Lat)
+	args = append(args, ipInfo.Lon)
+	args = append(args, ipInfo.Isp)
+	args = append(args, ipInfo.Org)
+	args = append(args, ipInfo.As)
+	args = append(args, ipInfo.AsName)
+	args = append(args, ipInfo.Mobile)
+	args = append(args, ipInfo.Proxy)
+	args = append(args, ipInfo.Hosting)
+	args = append(args, ipInfo.ASN)
+	args = append(args, ipInfo.IpClass)
+	args = append(args, ipInfo.FetchedAt)
+	args = append(args, ipInfo.Rdns)
+	args = append(args, ipInfo.CloudProvider)
+	args = append(args, ipInfo.Anonymous)
+	args = append(args, ipInfo.AnonymousSource)
+
+	return query, args
+}
+
+// UpdateIpGeoCheck stores the result of the RTT sanity check of the location of an IP,
+// as long as the RTT is the lowest one measured so far
+func (c *DBClient) UpdateIpGeoCheck(check models.IpGeoCheck) (query string, args []interface{}) {
+	log.Trace("updating geo check of ip in sqlite-db")
+	query = `
+		UPDATE ips SET
+			min_rtt_ms = $2,
+			geo_confidence = $3,
+			geo_implausible = $4
+		WHERE ip = $1 AND (min_rtt_ms IS NULL OR min_rtt_ms >= $2);
+		`
+
+	args = append(args, check.IP)
+	args = append(args, check.MinRTT.Milliseconds())
+	args = append(args, check.Confidence)
+	args = append(args, check.Implausible)
+
+	return query, args
+}
+
+// ReadIpInfo reads all the information available for that specific IP in the DB
+func (c *DBClient) ReadIpInfo(ip string) (models.IpInfo, error) {
+	log.Tracef("reading ip_info for ip %s from sqlite-db", ip)
+	var ipInfo models.IpInfo
+	var minRTTMillis int64
+	err := c.queryRow(`
+		SELECT
+			ip,
+			COALESCE(fetched_at, expiration_time),
+			expiration_time,
+			continent,
+			continent_code,
+			country,
+			country_code,
+			region,
+			region_name,
+			city,
+			zip,
+			lat,
+			lon,
+			isp,
+			org,
+			as_raw,
+			asname,
+			mobile,
+			proxy,
+			hosting,
+			COALESCE(asn, 0),
+			COALESCE(ip_class, ''),
+			COALESCE(rdns, ''),
+			COALESCE(cloud_provider, ''),
+			anonymous,
+			COALESCE(anonymous_source, ''),
+			COALESCE(min_rtt_ms, 0),
+			COALESCE(geo_confidence, 0),
+			geo_implausible
+		FROM ips
+		WHERE ip=$1
+	`, ip).Scan(
+		&ipInfo.IP,
+		timestamp{&ipInfo.FetchedAt},
+		&ipInfo.ExpirationTime,
+		&ipInfo.Continent,
+		&ipInfo.ContinentCode,
+		&ipInfo.Country,
+		&ipInfo.CountryCode,
+		&ipInfo.Region,
+		&ipInfo.RegionName,
+		&ipInfo.City,
+		&ipInfo.Zip,
+		&ipInfo.Lat,
+		&ipInfo.Lon,
+		&ipInfo.Isp,
+		&ipInfo.Org,
+		&ipInfo.As,
+		&ipInfo.AsName,
+		&ipInfo.Mobile,
+		&ipInfo.Proxy,
+		&ipInfo.Hosting,
+		&ipInfo.ASN,
+		&ipInfo.IpClass,
+		&ipInfo.Rdns,
+		&ipInfo.CloudProvider,
+		&ipInfo.Anonymous,
+		&ipInfo.AnonymousSource,
+		&minRTTMillis,
+		&ipInfo.GeoConfidence,
+		&ipInfo.GeoImplausible,
+	)
+	if err != nil {
+		return models.IpInfo{}, err
+	}
+	ipInfo.MinRTT = time.Duration(minRTTMillis) * time.Millisecond
+
+	return ipInfo, nil
+
+}
+
+// GetExpiredIpInfo returns up to limit IPs whos' TTL has already expired, the longest expired first
+func (c *DBClient) GetExpiredIpInfo(limit int) ([]string, error) {
+	log.Trace("fetching expired ips from sqlite-db")
+	expIps := make([]string, 0)
+	ipRows, err := c.query(`
+		SELECT ip
+		FROM ips
+		WHERE expiration_time < $2
+		ORDER BY expiration_time
+		LIMIT $1;
+	`, limit, time.Now())
+	if err != nil {
+		return expIps, errors.Wrap(err, "unable to get expired ip records")
+	}
+
+	defer ipRows.Close()
+
+	for ipRows.Next() {
+		var ip string
+		err := ipRows.Scan(&ip)
+		if err != nil {
+			return expIps, errors.Wrap(err, "error parsing readed row for expired ip records")
+		}
+		expIps = append(expIps, ip)
+	}
+
+	return expIps, nil
+}
+
+// CheckIpRecords checks if a given IP is already stored in the DB as whether its TTL has expired
+func (c *DBClient) CheckIpRecords(ip string) (exists bool, expired bool, err error) {
+	log.Tracef("checking if ip %s exists in ips table", ip)
+	var readIp string
+	var expTime time.Time
+
+	row := c.queryRow(`
+		SELECT
+			ip,
+			expiration_time
+		FROM ips
+		WHERE ip=$1;
+	`, ip)
+
+	err = row.Scan(&readIp, &expTime)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	} else if err != nil {
+		return
+	}
+
+	if readIp == ip {
+		exists = true
+	}
+	if expTime.Before(time.Now()) {
+		expired = true
+	}
+	return
+}
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	moduleName    = "db"
+	moduleDetails = "batched writer of the crawled data into the DB"
+)
+
+// dbMetrics are the collectors of the metrics of the DB writer, one set per crawler
+type dbMetrics struct {
+	PersistQueueDepth prometheus.Gauge
+	FlushLatency      *prometheus.GaugeVec
+	FlushedRows       *prometheus.GaugeVec
+	FailedFlushes     *prometheus.GaugeVec
+}
+
+func newDBMetrics() *dbMetrics {
+	return &dbMetrics{
+		PersistQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "persist_queue_depth",
+			Help:      "Number of items waiting to be batched by the DB persisters",
+		}),
+		FlushLatency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "flush_latency_secs",
+			Help:      "Average and max time (secs) that the flushes of the batches of each table took since the last update",
+		},
+			[]string{"table", "stat"},
+		),
+		FlushedRows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "flushed_rows",
+			Help:      "Number of rows (or queries) flushed into each table since the last update",
+		},
+			[]string{"table"},
+		),
+		FailedFlushes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "failed_flushes",
+			Help:      "Number of flushes of each table that failed since the last update",
+		},
+			[]string{"table"},
+		),
+	}
+}
+
+func (c *DBClient) GetMetrics() *metrics.MetricsModule {
+	metricsMod := metrics.NewMetricsModule(
+		moduleName,
+		moduleDetails,
+	)
+	m := newDBMetrics()
+	metricsMod.AddIndvMetric(c.queueDepth(m))
+	metricsMod.AddIndvMetric(c.flushStats(m))
+	return metricsMod
+}
+
+func (c *DBClient) queueDepth(m *dbMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.PersistQueueDepth)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		depth := c.QueueDepth()
+		m.PersistQueueDepth.Set(float64(depth))
+		return depth, nil
+	}
+
+	indvMetr, err := metrics.NewIndvMetrics(
+		"persist_queue_depth",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(errors.Wrap(err, "unable to init persist_queue_depth"))
+		return nil
+	}
+	return indvMetr
+}
+
+func (c *DBClient) flushStats(m *dbMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.FlushLatency)
+		reg.MustRegister(m.FlushedRows)
+		reg.MustRegister(m.FailedFlushes)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		summary := make(map[string]interface{})
+		// the tables that weren't flushed during the interval go back to zero
+		m.FlushLatency.Reset()
+		m.FlushedRows.Reset()
+		m.FailedFlushes.Reset()
+		for table, stats := range c.FlushStats() {
+			m.FlushLatency.WithLabelValues(table, "avg").Set(stats.AvgLatency.Seconds())
+			m.FlushLatency.WithLabelValues(table, "max").Set(stats.MaxLatency.Seconds())
+			m.FlushedRows.WithLabelValues(table).Set(float64(stats.Rows))
+			m.FailedFlushes.WithLabelValues(table).Set(float64(stats.Failed))
+			summary[table] = stats.Rows
+		}
+		return summary, nil
+	}
+
+	indvMetr, err := metrics.NewIndvMetrics(
+		"flush_stats",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(errors.Wrap(err, "unable to init flush_stats"))
+		return nil
+	}
+	return indvMetr
+}
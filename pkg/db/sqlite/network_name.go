@@ -0,0 +1,22 @@
+package sqlite
+
+/**
+Several crawlers of the same family of networks (i.e. mainnet and gnosis) can share the DB file. Each of
+them tags the peers and its run with the name of its network, and filters the peers by it, while the
+event rows get tied to their network through the run that recorded them. The rows of the tables keyed
+by network (dial queue, host identity, static peers, rollups) are keyed by the family and the name.
+Without a network name the crawler behaves as the only one of its family in the DB.
+*/
+
+// NetworkName returns the name of the network of the crawler (empty if it doesn't share the DB)
+func (c *DBClient) NetworkName() string {
+	return c.networkName
+}
+
+// networkKey returns the key of the rows of the tables keyed by network
+func (c *DBClient) networkKey() string {
+	if c.networkName == "" {
+		return string(c.Network)
+	}
+	return string(c.Network) + "/" + c.networkName
+}
@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// InitNodeIdentitiesTable creates the join table between the discv5 records (eth_nodes) and the
+// libp2p peers (peer_info)
+func (c *DBClient) InitNodeIdentitiesTable() error {
+	return c.initTable("node_identities", `
+		CREATE TABLE IF NOT EXISTS node_identities(
+			node_id TEXT NOT NULL,
+			peer_id TEXT NOT NULL,
+			discv5 BOOL NOT NULL DEFAULT false,
+			libp2p BOOL NOT NULL DEFAULT false,
+			consistent BOOL NOT NULL DEFAULT true,
+			inconsistency TEXT,
+			first_seen TIMESTAMP NOT NULL,
+			last_seen TIMESTAMP NOT NULL,
+
+			PRIMARY KEY(node_id, peer_id)
+		);
+	`)
+}
+
+// UpsertNodeIdentity links the node ID with the peer ID, keeping from which sides the link was seen.
+// Once a link is flagged as inconsistent it stays flagged
+func (c *DBClient) UpsertNodeIdentity(link *models.IdentityLink) (query string, args []interface{}) {
+	log.Tracef("upserting identity link %s - %s", link.NodeID, link.PeerID)
+
+	query = `
+		INSERT INTO node_identities(
+			node_id,
+			peer_id,
+			discv5,
+			libp2p,
+			consistent,
+			inconsistency,
+			first_seen,
+			last_seen)
+		VALUES ($1,$2,$3,$4,$5,NULLIF($6, ''),$7,$7)
+		ON CONFLICT (node_id, peer_id)
+		DO UPDATE SET
+			discv5 = node_identities.discv5 OR excluded.discv5,
+			libp2p = node_identities.libp2p OR excluded.libp2p,
+			consistent = node_identities.consistent AND excluded.consistent,
+			inconsistency = COALESCE(excluded.inconsistency, node_identities.inconsistency),
+			last_seen = max(node_identities.last_seen, excluded.last_seen);
+	`
+
+	args = append(args, link.NodeID)
+	args = append(args, link.PeerID)
+	args = append(args, link.Source == models.Discv5Identity)
+	args = append(args, link.Source == models.Libp2pIdentity)
+	args = append(args, link.Inconsistency == "")
+	args = append(args, link.Inconsistency)
+	args = append(args, link.Timestamp)
+
+	return query, args
+}
+
+// libp2pIdentityLink links the identified peer to the node ID of its key (nil for the peers
+// without discv5 identity, i.e. the ed25519 keys of IPFS)
+func libp2pIdentityLink(pID peer.ID) *models.IdentityLink {
+	nodeID, err := eth.NodeIDFromPeerID(pID)
+	if err != nil {
+		return nil
+	}
+	return models.NewIdentityLink(nodeID.String(), pID.String(), models.Libp2pIdentity, "")
+}
+
+// mismatchIdentityLink flags that the addresses of the dialed peer were served by another key
+func mismatchIdentityLink(connAttempt *models.ConnectionAttempt) *models.IdentityLink {
+	nodeID, err := eth.NodeIDFromPeerID(connAttempt.RemotePeer)
+	if err != nil {
+		return nil
+	}
+	return models.NewIdentityLink(nodeID.String(), connAttempt.MismatchedPeer.String(), models.DialIdentity, models.PeerIDMismatch)
+}
+
+// linksIdentities tells whether the crawled network has both discv5 and libp2p identities
+func (c *DBClient) linksIdentities() bool {
+	return c.Network == utils.EthereumNetwork || c.Network == utils.WakuNetwork
+}
+
+// GetInconsistentIdentities returns the identity links of the nodes whose discv5 and libp2p
+// identities don't match, from the most recent one
+func (c *DBClient) GetInconsistentIdentities() ([]models.IdentityLink, error) {
+	log.Debug("fetching inconsistent node identities")
+	links := make([]models.IdentityLink, 0)
+
+	rows, err := c.query(`
+		SELECT
+			node_id,
+			peer_id,
+			CASE WHEN discv5 THEN $1 WHEN libp2p THEN $2 ELSE $3 END,
+			COALESCE(inconsistency, ''),
+			last_seen
+		FROM node_identities
+		WHERE consistent = false
+		ORDER BY last_seen DESC;
+		`,
+		models.Discv5Identity,
+		models.Libp2pIdentity,
+		models.DialIdentity,
+	)
+	if err != nil {
+		return links, errors.Wrap(err, "unable to fetch inconsistent node identities")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link models.IdentityLink
+		err = rows.Scan(&link.NodeID, &link.PeerID, &link.Source, &link.Inconsistency, &link.Timestamp)
+		if err != nil {
+			return links, errors.Wrap(err, "unable to parse fetched node identity")
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+func (c *DBClient) checkNodeIdentities() {
+	checked, flagged, err := c.CheckNodeIdentities()
+	if err != nil {
+		log.WithError(err).Warn("unable to check the identities of the eth_nodes")
+		return
+	}
+	log.Infof("checked the identities of %d eth_nodes, %d flagged as inconsistent", checked, flagged)
+}
+
+// CheckNodeIdentities verifies that the node ID and the peer ID of each of the stored eth_nodes
+// derive from its pubkey, flagging the inconsistent ones in the node_identities
+func (c *DBClient) CheckNodeIdentities() (checked, flagged int, err error) {
+	log.Debug("checking the identities of the eth_nodes")
+
+	rows, err := c.query(`
+		SELECT node_id, COALESCE(peer_id, ''), pubkey
+		FROM eth_nodes;
+		`,
+	)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "unable to fetch the identities of the eth_nodes")
+	}
+	inconsistent := make([]*models.IdentityLink, 0)
+	for rows.Next() {
+		var nodeStr, peerStr, pubkey string
+		err = rows.Scan(&nodeStr, &peerStr, &pubkey)
+		if err != nil {
+			rows.Close()
+			return checked, 0, errors.Wrap(err, "unable to parse fetched eth_node identity")
+		}
+		checked++
+		nodeID, nodeErr := enode.ParseID(nodeStr)
+		pID, peerErr := peer.Decode(peerStr)
+		if nodeErr != nil || peerErr != nil || eth.CheckIdentity(nodeID, pID, pubkey) != nil {
+			inconsistent = append(inconsistent, models.NewIdentityLink(nodeStr, peerStr, models.Discv5Identity, models.PubkeyMismatch))
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return checked, 0, errors.Wrap(err, "unable to fetch the identities of the eth_nodes")
+	}
+
+	for _, link := range inconsistent {
+		q, args := c.UpsertNodeIdentity(link)
+		_, err = c.exec(q, args...)
+		if err != nil {
+			return checked, flagged, errors.Wrap(err, "unable to flag inconsistent node identity")
+		}
+		flagged++
+	}
+	return checked, flagged, nil
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type DBOption func(*DBClient) error
+
+func WithConnectionEventsPersist(persist bool) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.persistConnEvents = persist
+		return nil
+	}
+}
+
+func WithActivePeersBackup(backup bool) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.backupActivePeers = backup
+		return nil
+	}
+}
+
+func WithStaticPeersInStats(include bool) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.staticPeersInStats = include
+		return nil
+	}
+}
+
+// WithResurrectOnNewENR only un-deprecates the peers re-discovered with an ENR if its seq is
+// higher than the one of the last known ENR (the peers without ENR are always un-deprecated)
+func WithResurrectOnNewENR(resurrect bool) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.resurrectOnNewENR = resurrect
+		return nil
+	}
+}
+
+// WithPeersHistory appends a snapshot of the active peers to peers_history on every interval
+// (disabled if 0)
+func WithPeersHistory(interval time.Duration) DBOption {
+	return func(dbCli *DBClient) error {
+		if interval < 0 {
+			return errors.Errorf("invalid peers history interval %s", interval)
+		}
+		dbCli.peersHistoryInterval = interval
+		return nil
+	}
+}
+
+// WithValidatorEstimates estimates the validators behind each of the active peers on every interval,
+// appending the estimates to eth_validator_estimates (disabled if 0)
+func WithValidatorEstimates(interval time.Duration) DBOption {
+	return func(dbCli *DBClient) error {
+		if interval < 0 {
+			return errors.Errorf("invalid validator estimates interval %s", interval)
+		}
+		dbCli.validatorEstimatesInterval = interval
+		return nil
+	}
+}
+
+// WithNetworkName tags the peers and the run with the name of the network, so that several networks
+// of the same family can share the DB (i.e. mainnet and gnosis)
+func WithNetworkName(name string) DBOption {
+	return func(dbCli *DBClient) error {
+		dbCli.networkName = name
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitPeerGossipScoresTable() error {
+	return c.initTable("peer_gossip_scores", `
+		CREATE TABLE IF NOT EXISTS peer_gossip_scores(
+			peer_id TEXT NOT NULL,
+			timestamp TIMESTAMP NOT NULL,
+			score REAL NOT NULL,
+			time_in_mesh_s REAL NOT NULL,
+			first_deliveries REAL NOT NULL,
+			mesh_deliveries REAL NOT NULL,
+			invalid_deliveries REAL NOT NULL,
+			app_specific REAL NOT NULL,
+			ip_colocation REAL NOT NULL,
+			behaviour_penalty REAL NOT NULL,
+			run_id INT,
+
+			PRIMARY KEY(peer_id, timestamp)
+		);
+	`)
+}
+
+// InsertPeerGossipScore records a snapshot of the gossipsub score of the peer and of its components
+func (c *DBClient) InsertPeerGossipScore(score *models.PeerGossipScore) (query string, args []interface{}) {
+	log.Trace("inserting gossip score of peer ", score.PeerID.String())
+
+	query = `
+		INSERT INTO peer_gossip_scores(
+			peer_id,
+			timestamp,
+			score,
+			time_in_mesh_s,
+			first_deliveries,
+			mesh_deliveries,
+			invalid_deliveries,
+			app_specific,
+			ip_colocation,
+			behaviour_penalty,
+			run_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT DO NOTHING;
+	`
+
+	args = append(args, score.PeerID.String())
+	args = append(args, score.Timestamp)
+	args = append(args, score.Score)
+	args = append(args, score.TimeInMesh.Seconds())
+	args = append(args, score.FirstDeliveries)
+	args = append(args, score.MeshDeliveries)
+	args = append(args, score.InvalidDeliveries)
+	args = append(args, score.AppSpecificScore)
+	args = append(args, score.IPColocationFactor)
+	args = append(args, score.BehaviourPenalty)
+	args = append(args, c.RunID())
+
+	return query, args
+}
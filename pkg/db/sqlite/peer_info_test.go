@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// the identifications and the connection attempts of a peer can be persisted in any order,
+// so the merge rules have to end in the same state no matter the order
+func TestPeerInfoMergeRules(t *testing.T) {
+	network := utils.EthereumNetwork
+	dbCli := newTestDBClient(t, network)
+
+	peerStr := "12D3KooWKZbTx9hcXqkvEUVBDBaSpdd1TjoXcGpm1ZrmNxDyR6xe"
+	pID, err := peer.Decode(peerStr)
+	require.NoError(t, err)
+	host := models.NewHostInfo(pID, network, models.WithIPAndPorts("192.168.1.2", 9000))
+	identified := models.NewPeerInfo(pID, "Lighthouse/v4.5.0-441fc16/x86_64-linux", "ipfs/0.1.0", []string{}, 10*time.Millisecond)
+	failedIdentify := models.NewPeerInfo(host.ID, "", "", []string{}, 0)
+
+	now := time.Now()
+	newer := models.NewConnAttempt(host.ID, models.NegativeAttempt, hosts.DialErrorConnectionResetByPeer, true, false)
+	newer.RawError = "read tcp 192.168.1.2:9000: read: connection reset by peer"
+	newer.Timestamp = now
+	newer.DeprecationReason = "max-attempts"
+	older := models.NewConnAttempt(host.ID, models.PossitiveAttempt, hosts.NoConnError, false, false)
+	older.Timestamp = now.Add(-1 * time.Minute)
+
+	type step struct {
+		q    string
+		args []interface{}
+	}
+	hostQ, hostArgs := dbCli.UpsertHostInfo(host)
+	identQ, identArgs := dbCli.UpdatePeerInfo(identified)
+	failedQ, failedArgs := dbCli.UpdatePeerInfo(failedIdentify)
+	newerQ, newerArgs := dbCli.UpdateConnAttempt(newer)
+	olderQ, olderArgs := dbCli.UpdateConnAttempt(older)
+
+	interleavings := [][]step{
+		{{identQ, identArgs}, {failedQ, failedArgs}, {olderQ, olderArgs}, {newerQ, newerArgs}},
+		{{failedQ, failedArgs}, {identQ, identArgs}, {newerQ, newerArgs}, {olderQ, olderArgs}},
+		{{newerQ, newerArgs}, {identQ, identArgs}, {olderQ, olderArgs}, {failedQ, failedArgs}},
+	}
+	for i, steps := range interleavings {
+		_, err = dbCli.exec(`DELETE FROM peer_info WHERE peer_id=$1;`, peerStr)
+		require.NoError(t, err)
+		_, err = dbCli.exec(hostQ, hostArgs...)
+		require.NoError(t, err)
+		for _, s := range steps {
+			_, err = dbCli.exec(s.q, s.args...)
+			require.NoError(t, err)
+		}
+
+		var userAgent, clientName, lastError, lastErrorRaw string
+		var deprecated bool
+		var lastActivity, lastConnAttempt, attempts, successes int64
+		err = dbCli.queryRow(`
+			SELECT user_agent, client_name, deprecated, last_activity, last_conn_attempt, last_error, COALESCE(last_error_raw, ''), conn_attempts, conn_successes
+			FROM peer_info WHERE peer_id=$1;`,
+			peerStr,
+		).Scan(&userAgent, &clientName, &deprecated, &lastActivity, &lastConnAttempt, &lastError, &lastErrorRaw, &attempts, &successes)
+		require.NoError(t, err, "interleaving %d", i)
+
+		// the failed identify never downgrades the successful one
+		require.Equal(t, identified.UserAgent, userAgent, "interleaving %d", i)
+		require.Equal(t, "lighthouse", clientName, "interleaving %d", i)
+		// the newest attempt decides the state, the timestamps keep the max
+		require.True(t, deprecated, "interleaving %d", i)
+		require.Equal(t, newer.Error, lastError, "interleaving %d", i)
+		require.Equal(t, newer.RawError, lastErrorRaw, "interleaving %d", i)
+		require.Equal(t, newer.Timestamp.Unix(), lastConnAttempt, "interleaving %d", i)
+		require.Equal(t, older.Timestamp.Unix(), lastActivity, "interleaving %d", i)
+		// the counters are append-only
+		require.Equal(t, int64(2), attempts, "interleaving %d", i)
+		require.Equal(t, int64(1), successes, "interleaving %d", i)
+	}
+}
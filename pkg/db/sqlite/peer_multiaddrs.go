@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitPeerMultiaddrsTable() error {
+	return c.initTable("peer_multiaddrs", `
+		CREATE TABLE IF NOT EXISTS peer_multiaddrs(
+			peer_id TEXT NOT NULL,
+			multi_addr TEXT NOT NULL,
+			sources TEXT NOT NULL,
+			first_seen TIMESTAMP NOT NULL,
+			last_seen TIMESTAMP NOT NULL,
+			observations INT NOT NULL DEFAULT 0,
+			dial_attempts INT NOT NULL DEFAULT 0,
+			dial_successes INT NOT NULL DEFAULT 0,
+			last_success TIMESTAMP,
+
+			PRIMARY KEY(peer_id, multi_addr)
+		);
+	`)
+}
+
+// UpsertPeerMultiaddrs adds the observation to the history of each of the multiaddrs of the peer
+func (c *DBClient) UpsertPeerMultiaddrs(obs *models.MultiaddrObservation) (query string, args []interface{}) {
+	log.Trace("upserting multiaddrs of peer ", obs.PeerID.String())
+
+	// the WHERE tells SQLite that the ON CONFLICT isn't the join constraint of the FROM
+	query = `
+		INSERT INTO peer_multiaddrs(
+			peer_id,
+			multi_addr,
+			sources,
+			first_seen,
+			last_seen,
+			observations,
+			dial_attempts,
+			dial_successes,
+			last_success)
+		SELECT $1, addr.value, json_array($3), $4, $4, 1, $5, $6, CASE WHEN $6 > 0 THEN $4 END
+		FROM json_each($2) AS addr
+		WHERE true
+		ON CONFLICT (peer_id, multi_addr)
+		DO UPDATE SET
+			sources = CASE WHEN $3 IN (SELECT value FROM json_each(peer_multiaddrs.sources)) THEN peer_multiaddrs.sources
+				ELSE json_insert(peer_multiaddrs.sources, '$[#]', $3) END,
+			last_seen = excluded.last_seen,
+			observations = peer_multiaddrs.observations + 1,
+			dial_attempts = peer_multiaddrs.dial_attempts + excluded.dial_attempts,
+			dial_successes = peer_multiaddrs.dial_successes + excluded.dial_successes,
+			last_success = COALESCE(excluded.last_success, peer_multiaddrs.last_success);
+	`
+
+	dialed, succeeded := 0, 0
+	if obs.Dialed {
+		dialed = 1
+		if obs.Success {
+			succeeded = 1
+		}
+	}
+	args = append(args, obs.PeerID.String())
+	args = append(args, jsonArray{maddrStrings(obs.Addrs)})
+	args = append(args, obs.Source)
+	args = append(args, obs.Timestamp)
+	args = append(args, dialed)
+	args = append(args, succeeded)
+
+	return query, args
+}
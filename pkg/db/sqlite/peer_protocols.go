@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) InitPeerProtocolsTable() error {
+	return c.initTable("peer_protocols", `
+		CREATE TABLE IF NOT EXISTS peer_protocols(
+			peer_id TEXT NOT NULL,
+			protocol TEXT NOT NULL,
+			first_seen TIMESTAMP NOT NULL,
+			last_seen TIMESTAMP NOT NULL,
+			identifications INT NOT NULL DEFAULT 1,
+
+			PRIMARY KEY(peer_id, protocol)
+		);
+	`)
+}
+
+// UpsertPeerProtocols records the protocols that the peer announced in an identify exchange,
+// keeping when each protocol was first and last announced by the peer
+func (c *DBClient) UpsertPeerProtocols(peerID peer.ID, protocols []string, timestamp time.Time) (query string, args []interface{}) {
+	log.Trace("upserting protocols of peer ", peerID.String())
+
+	// the WHERE tells SQLite that the ON CONFLICT isn't the join constraint of the FROM
+	query = `
+		INSERT INTO peer_protocols(
+			peer_id,
+			protocol,
+			first_seen,
+			last_seen)
+		SELECT $1, prot.value, $3, $3
+		FROM json_each($2) AS prot
+		WHERE true
+		ON CONFLICT (peer_id, protocol)
+		DO UPDATE SET
+			last_seen = excluded.last_seen,
+			identifications = peer_protocols.identifications + 1;
+	`
+
+	args = append(args, peerID.String())
+	args = append(args, jsonArray{protocols})
+	args = append(args, timestamp)
+
+	return query, args
+}
+
+// GetProtocolDistribution returns the number of active peers that support each of the protocols
+func (db *DBClient) GetProtocolDistribution() (map[string]interface{}, error) {
+	summary := make(map[string]interface{}, 0)
+	rows, err := db.query(`
+		SELECT
+			prot.value,
+			count(*) as nodes
+		FROM peer_info, json_each(sup_protocols) AS prot
+		WHERE deprecated=false and
+		      attempted=true and
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = true)) and
+		      ($3 = '' OR peer_info.network_name = $3) and
+		      last_activity > unixepoch() - $1 * 86400
+		GROUP BY prot.value
+		ORDER BY nodes DESC;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return summary, errors.Wrap(err, "unable to fetch protocol distribution")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var prot string
+		var count int
+		err = rows.Scan(&prot, &count)
+		if err != nil {
+			return summary, errors.Wrap(err, "unable to parse fetch protocol distribution")
+		}
+		summary[prot] = count
+	}
+	return summary, nil
+}
+
+// GetActivePeersProtocols returns the protocols announced through identify by each of the active peers
+func (db *DBClient) GetActivePeersProtocols() ([][]string, error) {
+	peersProtocols := make([][]string, 0)
+	rows, err := db.query(`
+		SELECT
+			sup_protocols
+		FROM peer_info
+		WHERE deprecated=false and
+		      attempted=true and
+		      json_array_length(sup_protocols) > 0 and
+		      ($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = true)) and
+		      ($3 = '' OR peer_info.network_name = $3) and
+		      last_activity > unixepoch() - $1 * 86400;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	)
+	if err != nil {
+		return peersProtocols, errors.Wrap(err, "unable to fetch the protocols of the active peers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var protocols []string
+		err = rows.Scan(jsonArray{&protocols})
+		if err != nil {
+			return peersProtocols, errors.Wrap(err, "unable to parse the protocols of the active peers")
+		}
+		peersProtocols = append(peersProtocols, protocols)
+	}
+	return peersProtocols, nil
+}
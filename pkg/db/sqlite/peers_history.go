@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func (c *DBClient) InitPeersHistoryTable() error {
+	return c.initTable("peers_history", `
+		CREATE TABLE IF NOT EXISTS peers_history(
+			timestamp TIMESTAMP NOT NULL,
+			peer_id TEXT NOT NULL,
+			client_name TEXT,
+			client_version TEXT,
+			country_code TEXT,
+			attnets TEXT,
+			attnets_number INT,
+			latency INT,
+			last_activity BIGINT,
+			last_error TEXT,
+
+			PRIMARY KEY(timestamp, peer_id)
+		);
+	`)
+}
+
+// snapshotPeersHistory appends the current state of each active peer (client, country, subnets
+// and connectivity) to the peers_history, returning the number of recorded peers
+func (c *DBClient) snapshotPeersHistory() (int64, error) {
+	log.Debug("taking snapshot of the active peers into peers_history")
+
+	// only the eth nodes advertise their subnets
+	subnetCols := `NULL, NULL`
+	subnetJoin := ``
+	if c.Network == utils.EthereumNetwork {
+		subnetCols = `e.attnets, e.attnets_number`
+		subnetJoin = `LEFT JOIN eth_nodes e ON e.peer_id = p.peer_id`
+	}
+
+	res, err := c.exec(`
+		INSERT INTO peers_history(
+			timestamp,
+			peer_id,
+			client_name,
+			client_version,
+			country_code,
+			attnets,
+			attnets_number,
+			latency,
+			last_activity,
+			last_error)
+		SELECT
+			$1,
+			p.peer_id,
+			p.client_name,
+			p.client_version,
+			i.country_code,
+			`+subnetCols+`,
+			p.latency,
+			p.last_activity,
+			p.last_error
+		FROM peer_info p
+		LEFT JOIN ips i ON i.ip = p.ip
+		`+subnetJoin+`
+		WHERE p.deprecated = false and p.attempted = true and p.client_name IS NOT NULL and p.last_activity > unixepoch() - $2 * 86400 and
+		      ($3 = '' OR p.network_name = $3)
+		ON CONFLICT (timestamp, peer_id) DO NOTHING;
+		`,
+		time.Now(),
+		LastActivityValidRange,
+		c.networkName,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to snapshot the peers history")
+	}
+	return res.RowsAffected()
+}
+
+func (c *DBClient) peersHistoryHeartbeat() {
+	ticker := time.NewTicker(c.peersHistoryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			peers, err := c.snapshotPeersHistory()
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			log.Infof("recorded %d active peers in peers_history", peers)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
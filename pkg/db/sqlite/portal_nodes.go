@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/networks/portal"
+)
+
+func (c *DBClient) InitPortalNodesTable() error {
+	return c.initTable("portal_nodes", `
+		CREATE TABLE IF NOT EXISTS portal_nodes(
+			node_id TEXT NOT NULL,
+			peer_id TEXT NOT NULL,
+			sub_network TEXT NOT NULL,
+			data_radius TEXT NOT NULL,
+			radius_ratio REAL NOT NULL,
+			enr_seq BIGINT NOT NULL,
+			client_info TEXT,
+			enr_client TEXT,
+			capabilities TEXT,
+			first_seen BIGINT NOT NULL,
+			last_seen BIGINT NOT NULL,
+
+			PRIMARY KEY(node_id, sub_network)
+		);
+	`)
+}
+
+// UpsertPortalNode records the radius that the node advertised in each of the sub-networks
+// that answered our ping, keeping when the node was seen in each of them for the first and the last time
+func (c *DBClient) UpsertPortalNode(node *portal.PortalNode) (query string, args []interface{}) {
+	log.Trace("upserting portal node ", node.NodeID.String())
+
+	// the sub-networks are zipped by their position in the arrays, and the WHERE tells SQLite that
+	// the ON CONFLICT isn't the join constraint of the FROM
+	query = `
+		INSERT INTO portal_nodes(
+			node_id,
+			peer_id,
+			sub_network,
+			data_radius,
+			radius_ratio,
+			enr_seq,
+			client_info,
+			enr_client,
+			capabilities,
+			first_seen,
+			last_seen)
+		SELECT $1, $2, subs.value, radiuses.value, ratios.value, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, $10
+		FROM json_each($3) AS subs
+		INNER JOIN json_each($4) AS radiuses ON radiuses.key = subs.key
+		INNER JOIN json_each($5) AS ratios ON ratios.key = subs.key
+		WHERE true
+		ON CONFLICT (node_id, sub_network)
+		DO UPDATE SET
+			peer_id = excluded.peer_id,
+			data_radius = excluded.data_radius,
+			radius_ratio = excluded.radius_ratio,
+			enr_seq = excluded.enr_seq,
+			client_info = COALESCE(excluded.client_info, portal_nodes.client_info),
+			enr_client = COALESCE(excluded.enr_client, portal_nodes.enr_client),
+			capabilities = CASE WHEN excluded.client_info IS NULL THEN portal_nodes.capabilities ELSE excluded.capabilities END,
+			last_seen = max(portal_nodes.last_seen, excluded.last_seen);
+	`
+
+	subNetworks := make([]string, 0, len(node.SubNetworks))
+	radiuses := make([]string, 0, len(node.SubNetworks))
+	ratios := make([]float64, 0, len(node.SubNetworks))
+	for _, sub := range node.SubNetworks {
+		subNetworks = append(subNetworks, sub.SubNetwork)
+		radiuses = append(radiuses, sub.Radius.String())
+		ratios = append(ratios, sub.RadiusRatio())
+	}
+	capabilities := make([]int, 0, len(node.Capabilities))
+	for _, capability := range node.Capabilities {
+		capabilities = append(capabilities, int(capability))
+	}
+
+	args = append(args, node.NodeID.String())
+	args = append(args, node.PeerID.String())
+	args = append(args, jsonArray{subNetworks})
+	args = append(args, jsonArray{radiuses})
+	args = append(args, jsonArray{ratios})
+	args = append(args, int64(node.EnrSeq))
+	args = append(args, node.ClientInfo)
+	args = append(args, node.EnrClient)
+	args = append(args, jsonArray{capabilities})
+	args = append(args, node.Timestamp.Unix())
+
+	return query, args
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/networks/portal"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// each sub-network of the node gets its own row with its radius, and the client info of a pong
+// isn't lost when the next pings don't have it
+func TestUpsertPortalNode(t *testing.T) {
+	dbCli := newTestDBClient(t, utils.PortalNetwork)
+	now := time.Now()
+
+	// radius above the int64 range, as the radiuses are 256 bits
+	maxRadius := new(big.Int).Lsh(big.NewInt(1), 255)
+	node := &portal.PortalNode{
+		Timestamp:    now.Add(-time.Minute),
+		PeerID:       testPeerID(t),
+		NodeID:       enode.ID{0x01},
+		EnrSeq:       1,
+		ClientInfo:   "trin/0.1.0",
+		Capabilities: []uint16{0, 1},
+		SubNetworks: []portal.SubNetworkRadius{
+			{SubNetwork: "history", Radius: maxRadius},
+			{SubNetwork: "state", Radius: big.NewInt(1)},
+		},
+	}
+	q, args := dbCli.UpsertPortalNode(node)
+	_, err := dbCli.exec(q, args...)
+	require.NoError(t, err)
+
+	node.Timestamp = now
+	node.ClientInfo = ""
+	node.Capabilities = nil
+	node.SubNetworks = node.SubNetworks[:1]
+	q, args = dbCli.UpsertPortalNode(node)
+	_, err = dbCli.exec(q, args...)
+	require.NoError(t, err)
+
+	rows, err := dbCli.query(`
+		SELECT sub_network, data_radius, client_info, capabilities, first_seen, last_seen
+		FROM portal_nodes
+		ORDER BY sub_network;`,
+	)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	type portalRow struct {
+		radius              string
+		clientInfo          string
+		capabilities        []int
+		firstSeen, lastSeen int64
+	}
+	stored := make(map[string]portalRow)
+	for rows.Next() {
+		var subNetwork string
+		var row portalRow
+		err = rows.Scan(&subNetwork, &row.radius, &row.clientInfo, jsonArray{&row.capabilities}, &row.firstSeen, &row.lastSeen)
+		require.NoError(t, err)
+		stored[subNetwork] = row
+	}
+	require.NoError(t, rows.Err())
+
+	require.Equal(t, map[string]portalRow{
+		"history": {maxRadius.String(), "trin/0.1.0", []int{0, 1}, now.Add(-time.Minute).Unix(), now.Unix()},
+		"state":   {"1", "trin/0.1.0", []int{0, 1}, now.Add(-time.Minute).Unix(), now.Add(-time.Minute).Unix()},
+	}, stored)
+}
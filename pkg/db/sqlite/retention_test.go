@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// the pruned rows are aggregated into the daily rollups, and the rollups of a day split across
+// two retention passes add up
+func TestApplyTableRetention(t *testing.T) {
+	dbCli := newTestDBClient(t, utils.EthereumNetwork)
+	pID := testPeerID(t)
+	day := time.Date(2023, time.January, 2, 10, 0, 0, 0, time.UTC)
+
+	for i, connTime := range []time.Time{day, day.Add(time.Hour), time.Now()} {
+		connEvent := models.NewConnEvent(pID)
+		connEvent.AddConnInfo(models.ConnInfo{
+			Direction: models.OutboundConnection,
+			ConnTime:  connTime,
+			Att:       make(map[string]interface{}),
+			Error:     "None",
+		})
+		connEvent.AddDisconn(models.EndConnInfo{DiscTime: connTime.Add(time.Minute)})
+		q, args := dbCli.InsertNewConnEvent(connEvent)
+		_, err := dbCli.exec(q, args...)
+		require.NoError(t, err, "conn event %d", i)
+
+		q, args = dbCli.UpsertGossipMessage(&models.GossipMessage{
+			MsgID:      connTime.String(),
+			Topic:      "beacon_block",
+			FirstSeen:  connTime,
+			FirstPeer:  pID,
+			Duplicates: 2,
+			LastSeen:   connTime.Add(time.Second),
+		})
+		_, err = dbCli.exec(q, args...)
+		require.NoError(t, err, "gossip message %d", i)
+	}
+
+	for _, table := range []string{"conn_events", "gossip_messages"} {
+		deleted, err := dbCli.ApplyTableRetention(table, 24*time.Hour, true)
+		require.NoError(t, err, table)
+		require.Equal(t, int64(2), deleted, table)
+		// nothing left to prune or roll up
+		deleted, err = dbCli.ApplyTableRetention(table, 24*time.Hour, true)
+		require.NoError(t, err, table)
+		require.Equal(t, int64(0), deleted, table)
+	}
+
+	var rollupDay time.Time
+	var events, peers, duration int64
+	err := dbCli.queryRow(`
+		SELECT day, events, peers, total_duration
+		FROM conn_events_rollups;`,
+	).Scan(&rollupDay, &events, &peers, &duration)
+	require.NoError(t, err)
+	require.Equal(t, day.Truncate(24*time.Hour), rollupDay.UTC())
+	require.Equal(t, int64(2), events)
+	require.Equal(t, int64(1), peers)
+	require.Equal(t, int64(120), duration)
+
+	var messages, duplicates int64
+	err = dbCli.queryRow(`
+		SELECT day, messages, duplicates
+		FROM gossip_messages_rollups;`,
+	).Scan(&rollupDay, &messages, &duplicates)
+	require.NoError(t, err)
+	require.Equal(t, day.Truncate(24*time.Hour), rollupDay.UTC())
+	require.Equal(t, int64(2), messages)
+	require.Equal(t, int64(4), duplicates)
+
+	// the old records of a node are pruned, but never its latest one
+	for _, rec := range []struct {
+		nodeID    string
+		seq       int64
+		firstSeen time.Time
+	}{
+		{"node-a", 1, day},
+		{"node-a", 2, day},
+		{"node-a", 3, time.Now()},
+		{"node-b", 1, day},
+	} {
+		_, err = dbCli.exec(`
+			INSERT INTO eth_node_records(node_id, seq, first_seen, fields, enr)
+			VALUES ($1, $2, $3, '{}', '');`,
+			rec.nodeID, rec.seq, rec.firstSeen.Unix(),
+		)
+		require.NoError(t, err)
+	}
+	deleted, err := dbCli.ApplyTableRetention("eth_node_records", 24*time.Hour, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleted)
+
+	_, err = dbCli.ApplyTableRetention("eth_blocks", 24*time.Hour, false)
+	require.NoError(t, err)
+	_, err = dbCli.ApplyTableRetention("eth_blocks", 24*time.Hour, true)
+	require.Error(t, err)
+}
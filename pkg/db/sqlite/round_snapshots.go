@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func (c *DBClient) InitRoundSnapshotTable() error {
+	return c.initTable("round_snapshot", `
+		CREATE TABLE IF NOT EXISTS round_snapshot(
+			run_id INT NOT NULL,
+			round BIGINT NOT NULL,
+			start_time TIMESTAMP NOT NULL,
+			end_time TIMESTAMP NOT NULL,
+			attempted_peers INT NOT NULL,
+			total_peers INT NOT NULL,
+			active_peers INT NOT NULL,
+			deprecated_peers INT NOT NULL,
+			client_shares TEXT,
+			geo_shares TEXT,
+			error_shares TEXT,
+
+			PRIMARY KEY(run_id, round)
+		);
+	`)
+}
+
+// InsertRoundSnapshot records the summary of the network at the end of a crawl round
+func (c *DBClient) InsertRoundSnapshot(snapshot *models.RoundSnapshot) (query string, args []interface{}) {
+	log.Tracef("inserting snapshot of round %d", snapshot.Round)
+
+	query = `
+		INSERT INTO round_snapshot(
+			run_id,
+			round,
+			start_time,
+			end_time,
+			attempted_peers,
+			total_peers,
+			active_peers,
+			deprecated_peers,
+			client_shares,
+			geo_shares,
+			error_shares)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT (run_id, round) DO NOTHING;
+	`
+
+	args = append(args, snapshot.RunID)
+	args = append(args, snapshot.Round)
+	args = append(args, snapshot.Start)
+	args = append(args, snapshot.End)
+	args = append(args, snapshot.AttemptedPeers)
+	args = append(args, snapshot.TotalPeers)
+	args = append(args, snapshot.ActivePeers)
+	args = append(args, snapshot.DeprecatedPeers)
+	args = append(args, sharesJSON(snapshot.ClientShares))
+	args = append(args, sharesJSON(snapshot.GeoShares))
+	args = append(args, sharesJSON(snapshot.ErrorShares))
+
+	return query, args
+}
+
+func sharesJSON(shares map[string]float64) interface{} {
+	if len(shares) == 0 {
+		return nil
+	}
+	content, err := json.Marshal(shares)
+	if err != nil {
+		log.Warnf("unable to marshal shares %+v: %s", shares, err.Error())
+		return nil
+	}
+	return string(content)
+}
+
+// GetRoundTotals returns the number of peers known in the network, how many of them are active
+// (with the same criteria than the distributions), and how many are deprecated
+func (db *DBClient) GetRoundTotals() (total, active, deprecated int, err error) {
+	err = db.queryRow(`
+		SELECT
+			count(*) as total,
+			count(*) FILTER (WHERE
+				deprecated = false and
+				attempted = true and
+				($2 OR peer_info.peer_id NOT IN (SELECT peer_id FROM static_peers WHERE active = true)) and
+				($3 = '' OR peer_info.network_name = $3) and
+				last_activity > unixepoch() - $1 * 86400) as active,
+			count(*) FILTER (WHERE deprecated = true) as deprecated
+		FROM peer_info;
+		`,
+		LastActivityValidRange,
+		db.staticPeersInStats,
+		db.networkName,
+	).Scan(&total, &active, &deprecated)
+	if err != nil {
+		return 0, 0, 0, errors.Wrap(err, "unable to fetch the round totals")
+	}
+	return total, active, deprecated, nil
+}
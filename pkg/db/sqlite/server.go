@@ -0,0 +1,658 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/filecoin"
+	"github.com/migalabs/armiarma/pkg/networks/portal"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+	"github.com/migalabs/armiarma/pkg/utils"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pkg/errors"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	batchFlushingTimeout = 1 * time.Second
+	batchSize            = 512
+
+	// the file is opened in WAL mode, so that the readers don't block the writer, and the
+	// writes wait for the lock instead of failing right away. With WAL, the commits only sync
+	// at the checkpoints, which can only lose the last commits on a power loss
+	dsnParams = "?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(10000)&_time_format=sqlite"
+)
+
+// DBClient persists the crawled data into a SQLite file, with the same schema as the Postgres DB,
+// for the single-machine runs that don't need a DB server
+type DBClient struct {
+	// Control Variables
+	ctx                 context.Context
+	dailyBackupInterval time.Duration
+
+	// Network that we are Crawling
+	Network utils.NetworkType
+	// name of the network within its family, set when several networks share the DB
+	networkName string
+
+	// SQLite variables
+	file string
+	db   *sql.DB
+
+	// Request channels
+	persistC chan interface{}
+	doneC    chan struct{}
+	wg       *sync.WaitGroup
+	// once closed, the items sent to persist are dropped (the persister already flushed)
+	closeM  *sync.RWMutex
+	closed  bool
+	dropped int64
+	// run that the event rows get tagged with (0 until the run is inserted)
+	runID int64
+
+	// Control Variables
+	persistConnEvents bool
+	backupActivePeers bool
+	// static peers are excluded from the crawl statistics by default
+	staticPeersInStats bool
+	// deprecated peers re-discovered with an ENR only come back if the ENR is newer
+	resurrectOnNewENR bool
+	// interval of the snapshots of the active peers into peers_history (disabled if 0)
+	peersHistoryInterval time.Duration
+	// interval of the estimates of the validators behind the peers (disabled if 0)
+	validatorEstimatesInterval time.Duration
+
+	// stats of the flushes of the batched writes
+	writerStats *writerStats
+}
+
+func NewDBClient(
+	ctx context.Context,
+	p2pNetwork utils.NetworkType,
+	file string,
+	dailyBackupInt time.Duration,
+	options ...DBOption) (*DBClient, error) {
+	if len(file) == 0 {
+		return nil, errors.New("empty sqlite file provided")
+	}
+
+	db, err := sql.Open("sqlite", "file:"+file+dsnParams)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer, so a single connection avoids the lock contention between them
+	db.SetMaxOpenConns(1)
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open sqlite file "+file)
+	}
+	log.WithFields(log.Fields{"file": file}).Debug("successful connection to DB")
+
+	var wg sync.WaitGroup
+
+	// compose the DBClient
+	dbClient := &DBClient{
+		ctx:                 ctx,
+		dailyBackupInterval: dailyBackupInt,
+		Network:             p2pNetwork,
+		file:                file,
+		db:                  db,
+		persistC:            make(chan interface{}, batchSize),
+		doneC:               make(chan struct{}),
+		wg:                  &wg,
+		closeM:              &sync.RWMutex{},
+		persistConnEvents:   true,
+		backupActivePeers:   true,
+		writerStats:         newWriterStats(),
+	}
+
+	// Check for all the available options
+	for _, opt := range options {
+		err := opt(dbClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the schema is created from scratch, there are no previous versions to migrate
+	err = dbClient.initTables()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the SQL tables at "+file)
+	}
+
+	// run the db persister
+	dbClient.launchPersister()
+	// launch the daily backup heartbeat
+	if dbClient.backupActivePeers {
+		go dbClient.dailyBackupheartbeat()
+	}
+	// launch the snapshots of the peers history
+	if dbClient.peersHistoryInterval > 0 {
+		go dbClient.peersHistoryHeartbeat()
+	}
+	// launch the estimates of the validators of the peers
+	if dbClient.validatorEstimatesInterval > 0 {
+		go dbClient.validatorEstimatesHeartbeat()
+	}
+	// check the identities of the records stored by previous runs
+	if dbClient.linksIdentities() {
+		go dbClient.checkNodeIdentities()
+	}
+	return dbClient, nil
+}
+
+func (c *DBClient) exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(c.ctx, query, sqlArgs(args)...)
+}
+
+func (c *DBClient) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(c.ctx, query, sqlArgs(args)...)
+}
+
+func (c *DBClient) queryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(c.ctx, query, sqlArgs(args)...)
+}
+
+// initTable creates the table (and its indexes) with the given statements
+func (c *DBClient) initTable(table string, stmts ...string) error {
+	log.Infof("init %s table", table)
+	for _, stmt := range stmts {
+		_, err := c.exec(stmt)
+		if err != nil {
+			return errors.Wrap(err, "initializing "+table+" table")
+		}
+	}
+	return nil
+}
+
+func (c *DBClient) initTables() error {
+	// initialize all the necesary tables to perform the crawl
+	initFns := []func() error{
+		c.InitPeerInfoTable,
+		c.InitConnEventTable,
+		c.InitIpTable,
+		c.InitActivePeersTable,
+		c.InitCrawlerRunsTable,
+		c.InitRunResourceUsageTable,
+		c.InitStaticPeersTable,
+		c.InitBlocklistTable,
+		c.InitTargetPeersTable,
+		c.InitHostIdentitiesTable,
+		c.InitIdentityChangesTable,
+		c.InitPeersHistoryTable,
+		c.InitStatsRollupsTable,
+		c.InitRetentionRollupsTables,
+		c.InitSignedPeerRecordsTable,
+		c.InitPeerProtocolsTable,
+		c.InitRoundSnapshotTable,
+		c.InitPeerSamplesTable,
+		c.InitDiscoverySourcesTable,
+		c.InitProtocolNegotiationsTable,
+		c.InitGossipMessagesTable,
+		c.InitGossipMeshTables,
+		c.InitPeerGossipScoresTable,
+		c.InitPeerTopicMessagesTable,
+		c.InitPeerMultiaddrsTable,
+		c.InitPeerAddressHistoryTable,
+		c.InitChurnEventsTable,
+		c.InitPeerReputationTable,
+		c.InitHeldPeersTable,
+		c.InitDialQueueTable,
+	}
+
+	switch c.Network {
+	// ETHEREUM
+	case utils.EthereumNetwork:
+		initFns = append(initFns,
+			c.InitEthNodesTable,
+			c.InitNodeIdentitiesTable,
+			c.InitEthNodeSubnetsTable,
+			c.InitEthereumNodeStatus,
+			c.InitValidatorEstimatesTable,
+			c.InitEthBlocksByRangeProbesTable,
+			// gossipsub messages
+			c.initEthereumAttestationsTable,
+			c.initEthereumBeaconBlocksTable,
+			c.initEthereumAggregatesTable,
+			c.initEthereumBlobSidecarsTable,
+		)
+	// ETHEREUM EL
+	case utils.EthereumELNetwork:
+		initFns = append(initFns, c.InitEthNodesTable)
+	// PORTAL
+	case utils.PortalNetwork:
+		initFns = append(initFns, c.InitEthNodesTable, c.InitPortalNodesTable)
+	// WAKU
+	case utils.WakuNetwork:
+		initFns = append(initFns, c.InitEthNodesTable, c.InitWakuNodesTable, c.InitNodeIdentitiesTable)
+	// FILECOIN
+	case utils.FilecoinNetwork:
+		initFns = append(initFns, c.InitFilecoinHelloTable)
+	//IPFS
+	default:
+	}
+
+	for _, initFn := range initFns {
+		err := initFn()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *DBClient) launchPersister() {
+	logEntry := log.WithFields(log.Fields{
+		"mod": "db-persister",
+	})
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		// batch to aggregate all the queries (SQLite has no COPY, so the high-volume tables
+		// are batched as well)
+		batch := NewQueryBatch(c.ctx, c.db, batchSize)
+
+		// batch flushing ticker
+		ticker := time.NewTicker(batchFlushingTimeout)
+
+		var readyToFinish bool
+
+	persistingLoop:
+		for {
+			if readyToFinish && len(c.persistC) == 0 {
+				break persistingLoop
+			}
+
+			// check with higher priority if the main-ctx died
+			select {
+			case <-c.ctx.Done(): // check if the context of the tool died
+				logEntry.Info("context died, clossing persister")
+				readyToFinish = true
+			case <-c.doneC:
+				logEntry.Info("closed detected, clossing persister")
+				readyToFinish = true
+			default:
+			}
+
+			// load  or flush after
+			select {
+			case obj := <-c.persistC: // persist any kind of item
+				c.addItem(batch, obj, logEntry)
+
+				// after adding whatever query we got check if we need to persist the batch
+				if batch.IsReadyToPersist() {
+					logEntry.Debug("batch-query full, ready to persist")
+					c.flushBatch(queryBatchLabel, batch)
+				}
+
+			case <-ticker.C:
+				logEntry.Trace("ticker jumped - flushing content of query-batch")
+				c.flushBatch(queryBatchLabel, batch)
+			}
+		}
+
+		// don't leave anything behind when closing
+		c.flushBatch(queryBatchLabel, batch)
+	}()
+}
+
+// addItem adds the queries that persist the item to the batch
+func (c *DBClient) addItem(batch *QueryBatch, obj interface{}, logEntry *log.Entry) {
+	// Every item/SQL query  has to return (string. []interfaces)
+	switch obj.(type) {
+	case (*models.HostInfo):
+		hostInfo := obj.(*models.HostInfo)
+		logEntry.Tracef("persisting host_info %s\n", hostInfo.ID.String())
+		q, args := c.UpsertHostInfo(hostInfo)
+		batch.AddQuery(q, args...)
+		// the addresses of the connections are recorded by the host itself (as attributes)
+		if hostInfo.Origin == models.DiscoveredOrigin && len(hostInfo.MAddrs) > 0 {
+			q, args = c.UpsertPeerMultiaddrs(
+				models.NewMultiaddrObservation(hostInfo.ID, hostInfo.MAddrs, models.AdvertisedAddr, false, false),
+			)
+			batch.AddQuery(q, args...)
+		}
+		// the inbound connections come from ephemeral ports, which would look like address changes
+		if hostInfo.Origin != models.InboundOrigin && hostInfo.IP != "" {
+			q, args = c.UpsertPeerAddress(
+				models.NewPeerAddress(hostInfo.ID, enrNodeID(hostInfo), hostInfo.IP, hostInfo.Port),
+			)
+			batch.AddQuery(q, args...)
+		}
+
+		// check if the peerInfo needs to update anything else
+		if hostInfo.IsHostIdentified() {
+			logEntry.Tracef("host_info has peer_info %s\n", hostInfo.PeerInfo.RemotePeer.String())
+			q, args = c.UpdatePeerInfo(&hostInfo.PeerInfo)
+			batch.AddQuery(q, args...)
+			if len(hostInfo.PeerInfo.Protocols) > 0 {
+				q, args = c.UpsertPeerProtocols(hostInfo.ID, hostInfo.PeerInfo.Protocols, time.Now())
+				batch.AddQuery(q, args...)
+			}
+			if link := libp2pIdentityLink(hostInfo.ID); link != nil && c.linksIdentities() {
+				q, args = c.UpsertNodeIdentity(link)
+				batch.AddQuery(q, args...)
+			}
+		}
+		// Read all the Attributes in hInfo
+		for attName, att := range hostInfo.Attr {
+			log.Debugf("detected attribute %s on peer", attName)
+			switch att.(type) {
+			case eth.BeaconStatusStamped:
+				bstatus := att.(eth.BeaconStatusStamped)
+				q, args = c.UpsertEthereumNodeStatus(bstatus)
+				batch.AddQuery(q, args...)
+			case eth.BeaconMetadataStamped:
+				bmetadata := att.(eth.BeaconMetadataStamped)
+				q, args = c.UpsertEthereumNodeMetadata(bmetadata)
+				batch.AddQuery(q, args...)
+			case eth.BeaconPingStamped:
+				bping := att.(eth.BeaconPingStamped)
+				q, args = c.UpsertEthereumNodePing(bping)
+				batch.AddQuery(q, args...)
+			case (*eth.BlocksByRangeProbe):
+				probe := att.(*eth.BlocksByRangeProbe)
+				q, args := c.UpsertBlocksByRangeProbe(probe)
+				batch.AddQuery(q, args...)
+			case (*eth.EnrNode):
+				enrNode := att.(*eth.EnrNode)
+				logEntry.Tracef("persisting eth node_info %s\n", enrNode.ID.String())
+				q, args := c.UpsertEnrInfo(enrNode)
+				batch.AddQuery(q, args...)
+				q, args = c.UpsertEnrRecord(enrNode)
+				batch.AddQuery(q, args...)
+				if peerID, err := enrNode.GetPeerID(); err == nil && c.linksIdentities() {
+					q, args = c.UpsertNodeIdentity(
+						models.NewIdentityLink(enrNode.ID.String(), peerID.String(), models.Discv5Identity, ""),
+					)
+					batch.AddQuery(q, args...)
+				}
+				// only the CL nodes advertise subnets
+				if len(enrNode.AttSubnets())+len(enrNode.SyncSubnets()) > 0 {
+					q, args = c.UpsertEthNodeSubnets(enrNode)
+					batch.AddQuery(q, args...)
+				}
+			case (*portal.PortalNode):
+				portalNode := att.(*portal.PortalNode)
+				if len(portalNode.SubNetworks) > 0 {
+					q, args := c.UpsertPortalNode(portalNode)
+					batch.AddQuery(q, args...)
+				}
+			case filecoin.HelloStamped:
+				hello := att.(filecoin.HelloStamped)
+				q, args = c.UpsertFilecoinHello(hello)
+				batch.AddQuery(q, args...)
+			case (*waku.WakuNode):
+				wakuNode := att.(*waku.WakuNode)
+				q, args := c.UpsertWakuNode(wakuNode)
+				batch.AddQuery(q, args...)
+			case (*models.SignedPeerRecord):
+				rec := att.(*models.SignedPeerRecord)
+				logEntry.Tracef("persisting signed peer record %s\n", rec.PeerID.String())
+				q, args := c.UpsertSignedPeerRecord(rec)
+				batch.AddQuery(q, args...)
+			case (*models.MultiaddrObservation):
+				obs := att.(*models.MultiaddrObservation)
+				q, args := c.UpsertPeerMultiaddrs(obs)
+				batch.AddQuery(q, args...)
+			case (*models.DiscoverySource):
+				src := att.(*models.DiscoverySource)
+				q, args := c.UpsertDiscoverySource(src)
+				batch.AddQuery(q, args...)
+			case (*models.ProtocolNegotiation):
+				negotiation := att.(*models.ProtocolNegotiation)
+				q, args := c.UpsertProtocolNegotiation(negotiation)
+				batch.AddQuery(q, args...)
+			case (*models.ConnectionAttempt):
+				// attempts performed by the discovery itself (i.e. devp2p hellos)
+				connAttempt := att.(*models.ConnectionAttempt)
+				q, args := c.UpdateConnAttempt(connAttempt)
+				batch.AddQuery(q, args...)
+			default:
+				log.Warnf("not yet recognized type for attr %s - %T - %+v", attName, att, att)
+			}
+		}
+
+	case (*models.PeerInfo):
+		peerInfo := obj.(*models.PeerInfo)
+		logEntry.Tracef("persisting new peer_info %s\n", peerInfo.RemotePeer.String())
+		q, args := c.UpdatePeerInfo(peerInfo)
+		batch.AddQuery(q, args...)
+
+	case (*models.ConnectionAttempt):
+		connAttempt := obj.(*models.ConnectionAttempt)
+		logEntry.Tracef("persisting conn_attempt")
+		q, args := c.UpdateConnAttempt(connAttempt)
+		batch.AddQuery(q, args...)
+		// the successful dials are recorded with the exact address of the connection
+		if connAttempt.Status == models.NegativeAttempt && len(connAttempt.Addrs) > 0 {
+			q, args = c.UpsertPeerMultiaddrs(
+				models.NewMultiaddrObservation(connAttempt.RemotePeer, connAttempt.Addrs, models.DialedAddr, true, false),
+			)
+			batch.AddQuery(q, args...)
+		}
+		// the addresses of the ENR are served by another key than the one of the ENR
+		if connAttempt.MismatchedPeer != "" && c.linksIdentities() {
+			if link := mismatchIdentityLink(connAttempt); link != nil {
+				q, args = c.UpsertNodeIdentity(link)
+				batch.AddQuery(q, args...)
+			}
+		}
+
+	case (*models.ConnEvent):
+		connEvent := obj.(*models.ConnEvent)
+		logEntry.Tracef("persisting conn_event for peer %s\n", connEvent.PeerID.String())
+		if c.persistConnEvents {
+			q, args := c.InsertNewConnEvent(connEvent)
+			batch.AddQuery(q, args...)
+		}
+		// Control Info LastActivity based on last disconnection
+		q, args := c.UpdateLastActivityTimestamp(connEvent.PeerID, connEvent.DiscTime)
+		batch.AddQuery(q, args...)
+
+	case (models.IpInfo):
+		ipInfo := obj.(models.IpInfo)
+		logEntry.Tracef("persisting ip_info %s\n", ipInfo.IP)
+		q, args := c.UpsertIpInfo(ipInfo)
+		batch.AddQuery(q, args...)
+
+	case (models.IpGeoCheck):
+		geoCheck := obj.(models.IpGeoCheck)
+		logEntry.Tracef("persisting geo check of ip %s\n", geoCheck.IP)
+		q, args := c.UpdateIpGeoCheck(geoCheck)
+		batch.AddQuery(q, args...)
+
+	case (*models.RoundSnapshot):
+		snapshot := obj.(*models.RoundSnapshot)
+		logEntry.Tracef("persisting snapshot of round %d\n", snapshot.Round)
+		q, args := c.InsertRoundSnapshot(snapshot)
+		batch.AddQuery(q, args...)
+
+	case (*models.PeerSample):
+		sample := obj.(*models.PeerSample)
+		logEntry.Tracef("persisting peer sample with seed %d\n", sample.Seed)
+		q, args := c.InsertPeerSample(sample)
+		batch.AddQuery(q, args...)
+
+	case (*models.ResourceUsage):
+		usage := obj.(*models.ResourceUsage)
+		logEntry.Tracef("persisting resource usage of run %d\n", usage.RunID)
+		q, args := c.InsertRunResourceUsage(usage)
+		batch.AddQuery(q, args...)
+
+	case (*models.ChurnEvent):
+		churnEvent := obj.(*models.ChurnEvent)
+		logEntry.Tracef("persisting churn event of peer %s\n", churnEvent.PeerID.String())
+		q, args := c.InsertChurnEvent(churnEvent)
+		batch.AddQuery(q, args...)
+
+	case (*models.HeldPeer):
+		held := obj.(*models.HeldPeer)
+		logEntry.Tracef("persisting held peer %s\n", held.PeerID.String())
+		q, args := c.UpsertHeldPeer(held)
+		batch.AddQuery(q, args...)
+
+	case (*models.PeerQuality):
+		quality := obj.(*models.PeerQuality)
+		logEntry.Tracef("persisting quality score of peer %s\n", quality.PeerID.String())
+		q, args := c.UpdatePeerQuality(quality)
+		batch.AddQuery(q, args...)
+
+	case (*models.PeerReputation):
+		rep := obj.(*models.PeerReputation)
+		logEntry.Tracef("persisting reputation of peer %s\n", rep.PeerID.String())
+		q, args := c.InsertPeerReputation(rep)
+		batch.AddQuery(q, args...)
+		q, args = c.UpdatePeerReputation(rep)
+		batch.AddQuery(q, args...)
+
+	case (*models.GossipMessage):
+		gossipMsg := obj.(*models.GossipMessage)
+		logEntry.Tracef("persisting propagation of gossip message %s\n", gossipMsg.MsgID)
+		q, args := c.UpsertGossipMessage(gossipMsg)
+		batch.AddQuery(q, args...)
+
+	case (*models.GossipMeshSnapshot):
+		meshSnapshot := obj.(*models.GossipMeshSnapshot)
+		logEntry.Tracef("persisting mesh snapshot of topic %s\n", meshSnapshot.Topic)
+		q, args := c.InsertGossipMeshSnapshot(meshSnapshot)
+		batch.AddQuery(q, args...)
+
+	case (*models.GossipControlCounts):
+		controlCounts := obj.(*models.GossipControlCounts)
+		logEntry.Tracef("persisting control messages sent to peer %s\n", controlCounts.PeerID.String())
+		q, args := c.InsertGossipControlCounts(controlCounts)
+		batch.AddQuery(q, args...)
+
+	case (*models.PeerGossipScore):
+		gossipScore := obj.(*models.PeerGossipScore)
+		logEntry.Tracef("persisting gossip score of peer %s\n", gossipScore.PeerID.String())
+		q, args := c.InsertPeerGossipScore(gossipScore)
+		batch.AddQuery(q, args...)
+
+	case (*models.PeerTopicMessages):
+		topicMsgs := obj.(*models.PeerTopicMessages)
+		logEntry.Tracef("persisting messages on %s of peer %s\n", topicMsgs.Topic, topicMsgs.PeerID.String())
+		q, args := c.UpsertPeerTopicMessages(topicMsgs)
+		batch.AddQuery(q, args...)
+
+	// GossipSub Messages
+	case (gossipsub.PersistableMsg):
+		prsMsg := obj.(gossipsub.PersistableMsg)
+		// select the type of message inside the list of messages
+		switch prsMsg.(type) {
+		case (*eth.TrackedAttestation):
+			attMsg := prsMsg.(*eth.TrackedAttestation)
+			log.Tracef("persisting eth_attestation %s", attMsg.MsgID)
+			q, args := c.InsertNewEthereumAttestation(attMsg)
+			batch.AddQuery(q, args...)
+		case (*eth.TrackedBeaconBlock):
+			bblockMsg := prsMsg.(*eth.TrackedBeaconBlock)
+			log.Tracef("persisting eth_block %s", bblockMsg.MsgID)
+			q, args := c.InsertNewEthereumBeaconBlock(bblockMsg)
+			batch.AddQuery(q, args...)
+			q, args = c.UpdateBlobsBlockDelay(bblockMsg)
+			batch.AddQuery(q, args...)
+		case (*eth.TrackedAggregateAndProof):
+			aggregateMsg := prsMsg.(*eth.TrackedAggregateAndProof)
+			log.Tracef("persisting eth_aggregate %s", aggregateMsg.MsgID)
+			q, args := c.InsertNewEthereumAggregate(aggregateMsg)
+			batch.AddQuery(q, args...)
+		case (*eth.TrackedBlobSidecar):
+			blobMsg := prsMsg.(*eth.TrackedBlobSidecar)
+			log.Tracef("persisting eth_blob_sidecar %s", blobMsg.MsgID)
+			q, args := c.InsertNewEthereumBlobSidecar(blobMsg)
+			batch.AddQuery(q, args...)
+			q, args = c.UpsertBlobPeer(blobMsg)
+			batch.AddQuery(q, args...)
+		}
+	default:
+		logEntry.Errorf("unrecognized type of object received to persist into DB %T", obj)
+		logEntry.Error(obj)
+	}
+}
+
+// flushBatch persists the content of the batch, keeping track of the latency of the flush
+func (c *DBClient) flushBatch(table string, batch persistableBatch) {
+	rows := batch.Len()
+	if rows == 0 {
+		return
+	}
+	start := time.Now()
+	err := batch.PersistBatch()
+	if err != nil {
+		log.Error(err)
+	}
+	c.writerStats.addFlush(table, rows, time.Since(start), err != nil)
+}
+
+func (c *DBClient) dailyBackupheartbeat() {
+	// make a first backup of the active peers(if any)
+	err := c.activePeersBackup()
+	if err != nil {
+		log.Error(err)
+	}
+	ticker := time.NewTicker(c.dailyBackupInterval)
+	for {
+		select {
+		case <-ticker.C:
+			err := c.activePeersBackup()
+			if err != nil {
+				log.Error(err)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+
+}
+
+// Close stops accepting items to persist, and waits until the persister flushed all the queued
+// ones before closing the DB file
+func (c *DBClient) Close() {
+	c.closeM.Lock()
+	if c.closed {
+		c.closeM.Unlock()
+		return
+	}
+	c.closed = true
+	c.closeM.Unlock()
+
+	// Let the persister finish cleaning its batch
+	close(c.doneC)
+	c.wg.Wait()
+
+	if c.backupActivePeers {
+		err := c.activePeersBackup()
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	err := c.db.Close()
+	if err != nil {
+		log.Error(errors.Wrap(err, "unable to close sqlite file "+c.file))
+	}
+	if dropped := atomic.LoadInt64(&c.dropped); dropped > 0 {
+		log.Warnf("%d items were sent to persist after closing the db client", dropped)
+	}
+}
+
+func (c *DBClient) PersistToDB(persItem interface{}) {
+	c.closeM.RLock()
+	defer c.closeM.RUnlock()
+	if c.closed {
+		// the modules that are still closing can't persist anything anymore
+		atomic.AddInt64(&c.dropped, 1)
+		return
+	}
+	c.persistC <- persItem
+}
@@ -0,0 +1,279 @@
+package sqlite
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var _ storage.Client = (*DBClient)(nil)
+
+func newTestDBClient(t *testing.T, network utils.NetworkType, options ...DBOption) *DBClient {
+	file := filepath.Join(t.TempDir(), "armiarma.db")
+	options = append([]DBOption{WithActivePeersBackup(false)}, options...)
+	dbCli, err := NewDBClient(context.Background(), network, file, 24*time.Hour, options...)
+	require.NoError(t, err)
+	t.Cleanup(dbCli.Close)
+	return dbCli
+}
+
+// every network creates its own set of tables
+func TestInitTables(t *testing.T) {
+	for _, network := range []utils.NetworkType{
+		utils.EthereumNetwork,
+		utils.IpfsNetwork,
+		utils.FilecoinNetwork,
+		utils.PortalNetwork,
+		utils.WakuNetwork,
+		utils.EthereumELNetwork,
+	} {
+		newTestDBClient(t, network)
+	}
+}
+
+// the read queries run against the tables of a crawl, empty or not
+func TestReadQueries(t *testing.T) {
+	dbCli := newTestDBClient(t, utils.EthereumNetwork)
+
+	readAll := func() {
+		_, err := dbCli.GetNonDeprecatedPeers()
+		require.NoError(t, err)
+		_, err = dbCli.GetDeprecatedPeers(utils.EthereumNetwork, time.Now(), 10)
+		require.NoError(t, err)
+		_, err = dbCli.GetDialScoreInputs()
+		require.NoError(t, err)
+		_, err = dbCli.GetReputationInputs()
+		require.NoError(t, err)
+		_, err = dbCli.GetSampleCandidates(utils.EthereumNetwork)
+		require.NoError(t, err)
+		_, _, err = dbCli.LoadDialQueue()
+		require.NoError(t, err)
+		_, err = dbCli.GetBlocklistEntries()
+		require.NoError(t, err)
+		_, err = dbCli.GetTargetEntries()
+		require.NoError(t, err)
+		_, err = dbCli.GetInconsistentIdentities()
+		require.NoError(t, err)
+		_, err = dbCli.GetExpiredIpInfo(10)
+		require.NoError(t, err)
+
+		for _, dist := range []func() (map[string]interface{}, error){
+			dbCli.GetClientDistribution,
+			dbCli.GetVersionDistribution,
+			dbCli.GetMajorVersionDistribution,
+			dbCli.GetGeoDistribution,
+			dbCli.GetOsDistribution,
+			dbCli.GetArchDistribution,
+			dbCli.GetPlatformDistribution,
+			dbCli.GetHostingDistribution,
+			dbCli.GetRTTDistribution,
+			dbCli.GetIPDistribution,
+			dbCli.GetSecurityDistribution,
+			dbCli.GetOriginDistribution,
+			dbCli.GetDiscoverySourceDistribution,
+			dbCli.GetProtocolDistribution,
+			dbCli.GetNodePerForkDistribution,
+			dbCli.GetAttnetsDistribution,
+		} {
+			_, err = dist()
+			require.NoError(t, err)
+		}
+		for _, dist := range []func() (map[string]int, error){
+			dbCli.GetIpClassDistribution,
+			dbCli.GetASNDistribution,
+			dbCli.GetCloudProviderDistribution,
+		} {
+			_, err = dist()
+			require.NoError(t, err)
+		}
+		_, err = dbCli.GetClientVersionDistribution()
+		require.NoError(t, err)
+		_, err = dbCli.GetClientVersionAdoption("lighthouse", 4, 5, 0)
+		require.NoError(t, err)
+		_, err = dbCli.GetActivePeersProtocols()
+		require.NoError(t, err)
+		_, err = dbCli.GetNegotiationStatsByClient()
+		require.NoError(t, err)
+		_, _, _, err = dbCli.GetPeerSetDistributions([]string{testPeerStr})
+		require.NoError(t, err)
+		_, err = dbCli.GetMovedPeers(time.Hour)
+		require.NoError(t, err)
+		_, _, _, err = dbCli.GetRoundTotals()
+		require.NoError(t, err)
+
+		_, err = dbCli.GetPeersPerSubnet()
+		require.NoError(t, err)
+		_, err = dbCli.GetPeerAttnets()
+		require.NoError(t, err)
+		_, err = dbCli.GetAttnetSubscriptions()
+		require.NoError(t, err)
+		_, err = dbCli.GetDeprecatedNodes()
+		require.NoError(t, err)
+		_, err = dbCli.GetGossipArrivalBaselines(32)
+		require.NoError(t, err)
+
+		pID, err := peer.Decode(testPeerStr)
+		require.NoError(t, err)
+		_, err = dbCli.GetActivePeers(time.Hour)
+		require.NoError(t, err)
+		_, err = dbCli.GetPeerTimeline(pID)
+		require.NoError(t, err)
+		_, err = dbCli.GetPeerAddressHistory(pID)
+		require.NoError(t, err)
+		_, err = dbCli.GetPeersByClient(time.Hour)
+		require.NoError(t, err)
+		_, err = dbCli.GetTopicRates(time.Hour)
+		require.NoError(t, err)
+		_, err = dbCli.GetConnSessions(time.Now().Add(-time.Hour), time.Now())
+		require.NoError(t, err)
+		for _, dataset := range ExportDatasets() {
+			_, err = dbCli.QueryDataset(dataset, models.DatasetFilter{Since: time.Now().Add(-time.Hour), Limit: 10})
+			require.NoError(t, err, dataset)
+		}
+		_, err = dbCli.GetStatsRollups(time.Now().Add(-24 * time.Hour))
+		require.NoError(t, err)
+		_, _, err = dbCli.CheckNodeIdentities()
+		require.NoError(t, err)
+		_, err = dbCli.snapshotPeersHistory()
+		require.NoError(t, err)
+	}
+	readAll()
+
+	// and once more with a peer of each kind of row
+	persistTestPeer(t, dbCli)
+	readAll()
+
+	summary, err := dbCli.GetPeerSummary(testPeerID(t))
+	require.NoError(t, err)
+	require.Equal(t, testPeerID(t), summary.PeerID)
+	require.Equal(t, "lighthouse", summary.ClientName)
+
+	clients, err := dbCli.GetClientDistribution()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"lighthouse": 1}, clients)
+	protocols, err := dbCli.GetActivePeersProtocols()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"/eth2/beacon_chain/req/status/1/ssz_snappy"}}, protocols)
+	clientCounts, countries, asns, err := dbCli.GetPeerSetDistributions([]string{testPeerStr})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"lighthouse": 1}, clientCounts)
+	require.Equal(t, map[string]int{"ES": 1}, countries)
+	require.Equal(t, map[string]int{"AS3352 Telefonica": 1}, asns)
+
+	// the attnets 0x03 are the subnets 0 and 1 (LSB first)
+	subscriptions, err := dbCli.GetAttnetSubscriptions()
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{0: 1, 1: 1}, subscriptions)
+	attnets, err := dbCli.GetPeerAttnets()
+	require.NoError(t, err)
+	require.Len(t, attnets, 1)
+	for _, subnets := range attnets {
+		require.Equal(t, []int{0, 1}, subnets)
+	}
+
+	rows, err := dbCli.QueryDataset("conn_events", models.DatasetFilter{PeerID: testPeerStr})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, true, rows[0]["identified"])
+	require.IsType(t, time.Time{}, rows[0]["conn_time"])
+}
+
+const testPeerStr = "12D3KooW9pdHR2n4xvYU1RBEgrJMH1kd557QSXYURzEFWeEECjGn"
+
+func testPeerID(t *testing.T) peer.ID {
+	pID, err := peer.Decode(testPeerStr)
+	require.NoError(t, err)
+	return pID
+}
+
+// persistTestPeer persists a row of each kind for the test peer, the same way the persister does
+func persistTestPeer(t *testing.T, dbCli *DBClient) {
+	pID := testPeerID(t)
+	now := time.Now()
+	maddr, err := ma.NewMultiaddr("/ip4/192.168.1.1/tcp/9000")
+	require.NoError(t, err)
+
+	key, err := gcrypto.GenerateKey()
+	require.NoError(t, err)
+	enrNode := eth.NewEnrNode(enode.PubkeyToIDV4(&key.PublicKey))
+	enrNode.Pubkey = &key.PublicKey
+	enrNode.IP = net.ParseIP("192.168.1.1")
+	enrNode.TCP = 9000
+	enrNode.UDP = 9000
+	enrNode.Eth2Data.ForkDigest = common.ForkDigest{0x6a, 0x95, 0xa1, 0xa9}
+	enrNode.Attnets.Raw = eth.AttnetsENREntry{0x03, 0, 0, 0, 0, 0, 0, 0}
+	enrNode.Attnets.NetNumber = 2
+
+	host := models.NewHostInfo(pID, utils.EthereumNetwork, models.WithIPAndPorts("192.168.1.1", 9000))
+	host.MAddrs = []ma.Multiaddr{maddr}
+	host.PeerInfo = *models.NewPeerInfo(pID, "Lighthouse/v4.5.0-441fc16/x86_64-linux", "ipfs/0.1.0", []string{"/eth2/beacon_chain/req/status/1/ssz_snappy"}, 10*time.Millisecond)
+	host.AddAtt("enr", enrNode)
+	host.AddAtt("status", eth.NewBeaconStatus(pID, common.Status{HeadSlot: 100}))
+	host.AddAtt("metadata", eth.NewBeaconMetadata(pID, common.MetaData{SeqNumber: 1}))
+	host.AddAtt("ping", eth.NewBeaconPing(pID, common.Ping(1), 20*time.Millisecond))
+	host.AddAtt("record", &models.SignedPeerRecord{PeerID: pID, Seq: 1, Addrs: []ma.Multiaddr{maddr}, Envelope: []byte{0x01}, Timestamp: now})
+	host.AddAtt("source", models.NewDiscoverySource(pID, "discv5"))
+	host.AddAtt("negotiation", models.NewProtocolNegotiation(pID, "/eth2/beacon_chain/req/status/1/ssz_snappy", time.Millisecond, nil))
+
+	connEvent := models.NewConnEvent(pID)
+	connEvent.AddConnInfo(models.ConnInfo{
+		Direction:  models.OutboundConnection,
+		ConnTime:   now.Add(-time.Minute),
+		Latency:    10 * time.Millisecond,
+		Identified: true,
+		Att:        make(map[string]interface{}),
+		Error:      "None",
+	})
+	connEvent.AddDisconn(models.EndConnInfo{DiscTime: now})
+
+	ipInfo := models.IpInfo{
+		IpApiMsg:       models.IpApiMsg{IP: "192.168.1.1", Status: "success", Country: "Spain", CountryCode: "ES", City: "Barcelona", As: "AS3352 Telefonica"},
+		FetchedAt:      now,
+		ExpirationTime: now.Add(24 * time.Hour),
+		ASN:            3352,
+		IpClass:        "residential",
+	}
+	score := 1.0
+	topic := "/eth2/6a95a1a9/beacon_block/ssz_snappy"
+
+	items := []interface{}{
+		host,
+		models.NewConnAttempt(pID, models.PossitiveAttempt, "None", false, false),
+		connEvent,
+		ipInfo,
+		&models.RoundSnapshot{Round: 1, Start: now.Add(-time.Minute), End: now, TotalPeers: 1, ActivePeers: 1, ClientShares: map[string]float64{"lighthouse": 1}},
+		&models.ChurnEvent{Cycle: 1, PeerID: pID, Type: models.ChurnDial, Timestamp: now},
+		&models.HeldPeer{PeerID: pID, Stratum: "lighthouse", HeldSince: now.Add(-time.Minute), LastUpdate: now, Connected: true},
+		&models.PeerQuality{PeerID: pID, Timestamp: now, GossipScore: &score, Score: 1},
+		&models.PeerReputation{PeerID: pID, Timestamp: now, DialReliability: &score, Score: 1},
+		&models.GossipMessage{MsgID: "0x01", Topic: topic, FirstSeen: now, FirstPeer: pID, LastSeen: now},
+		&models.GossipMeshSnapshot{Timestamp: now, Topic: topic, Mesh: []peer.ID{pID}, TopicPeers: 1},
+		&models.GossipControlCounts{Timestamp: now, PeerID: pID, Graft: 1},
+		&models.PeerGossipScore{PeerID: pID, Timestamp: now, Score: 1},
+		models.NewPeerTopicMessages(pID, topic, 1),
+		&eth.TrackedBlobSidecar{MsgID: "0x02", Sender: pID, ArrivalTime: now, Slot: 100, BlockRoot: "0xaa"},
+		&eth.TrackedBeaconBlock{MsgID: "0x03", Sender: pID, ArrivalTime: now, Slot: 100, BlockRoot: "0xaa", BlobCount: 1},
+		&eth.TrackedAttestation{MsgID: "0x04", Sender: pID, ArrivalTime: now, Slot: 100, BlockRoot: "0xaa"},
+		&eth.TrackedAggregateAndProof{MsgID: "0x05", Sender: pID, ArrivalTime: now, Slot: 100, BlockRoot: "0xaa"},
+	}
+	batch := NewQueryBatch(context.Background(), dbCli.db, len(items))
+	for _, item := range items {
+		dbCli.addItem(batch, item, log.WithField("mod", "test"))
+	}
+	require.NoError(t, batch.persistBatch())
+}
@@ -0,0 +1,23 @@
+package sqlite
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *DBClient) InitTargetPeersTable() error {
+	return c.initTable("target_peers", `
+		CREATE TABLE IF NOT EXISTS target_peers(
+			entry TEXT NOT NULL,
+			label TEXT,
+			added_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY(entry)
+		);
+	`)
+}
+
+// GetTargetEntries returns the ENRs, multiaddrs, and peer IDs of the peers to dial in the target-list crawl mode
+func (c *DBClient) GetTargetEntries() ([]string, error) {
+	log.Debug("fetching target peers")
+	return c.getEntries("target_peers", "target peer")
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+)
+
+// layout in which the driver stores the time values (_time_format=sqlite), always in UTC so
+// that the text comparisons between the timestamps hold
+const timeLayout = "2006-01-02 15:04:05.999999999-07:00"
+
+// jsonArray stores a slice as a JSON array, as SQLite has no array columns. It wraps the slice
+// to insert, or a pointer to the slice to scan
+type jsonArray struct {
+	v interface{}
+}
+
+func (a jsonArray) Value() (driver.Value, error) {
+	b, err := json.Marshal(a.v)
+	if err != nil {
+		return nil, err
+	}
+	// the nil slices are stored as empty arrays, as the NOT NULL array columns of Postgres
+	if string(b) == "null" {
+		return "[]", nil
+	}
+	return string(b), nil
+}
+
+func (a jsonArray) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(s), a.v)
+	case []byte:
+		return json.Unmarshal(s, a.v)
+	default:
+		return errors.Errorf("unable to scan %T as json array", src)
+	}
+}
+
+// timestamp scans the time values that the driver can't parse on its own (i.e. the result of
+// aggregates, which have no column type), leaving the zero time for the NULL values
+type timestamp struct {
+	t *time.Time
+}
+
+func (ts timestamp) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		*ts.t = time.Time{}
+	case time.Time:
+		*ts.t = s
+	case string:
+		t, err := time.Parse(timeLayout, s)
+		if err != nil {
+			// the timestamps generated by SQLite itself (i.e. CURRENT_TIMESTAMP) have no zone
+			t, err = time.Parse("2006-01-02 15:04:05", s)
+			if err != nil {
+				return errors.Wrap(err, "unable to parse timestamp")
+			}
+		}
+		*ts.t = t
+	default:
+		return errors.Errorf("unable to scan %T as timestamp", src)
+	}
+	return nil
+}
+
+// sqlArgs adapts the args of the queries to the driver: the times are stored in UTC
+func sqlArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				args[i] = v.UTC()
+			}
+		}
+	}
+	return args
+}
+
+// maddrStrings returns the text representation of the multiaddrs
+func maddrStrings(maddrs []ma.Multiaddr) []string {
+	strs := make([]string, 0, len(maddrs))
+	for _, maddr := range maddrs {
+		strs = append(strs, maddr.String())
+	}
+	return strs
+}
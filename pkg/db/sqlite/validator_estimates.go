@@ -0,0 +1,212 @@
+package sqlite
+
+import (
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+// slots of the attestations over which the activity of the peers is measured
+var ValidatorActivitySlots int64 = 32 * 32
+
+func (c *DBClient) InitValidatorEstimatesTable() error {
+	return c.initTable("eth_validator_estimates", `
+		CREATE TABLE IF NOT EXISTS eth_validator_estimates(
+			peer_id TEXT NOT NULL,
+			timestamp TIMESTAMP NOT NULL,
+			attnets INT NOT NULL,
+			syncnets INT NOT NULL,
+			attesters INT NOT NULL,
+			estimate REAL NOT NULL,
+			lower_bound INT NOT NULL,
+			upper_bound INT NOT NULL,
+			saturated BOOL NOT NULL,
+			confidence REAL NOT NULL,
+
+			PRIMARY KEY(peer_id, timestamp)
+		);
+	`)
+}
+
+// estimateValidators estimates the validators behind each of the active peers out of their
+// last metadata and their attestations, appending the estimates to eth_validator_estimates
+func (c *DBClient) estimateValidators() (int, error) {
+	log.Debug("estimating the validators of the active peers")
+
+	subnets, err := c.activePeersSubnets()
+	if err != nil {
+		return 0, err
+	}
+	attesters, err := c.unsubscribedAttesters(subnets)
+	if err != nil {
+		return 0, err
+	}
+
+	t := time.Now()
+	batch := NewQueryBatch(c.ctx, c.db, batchSize)
+	for peerID, peerSubnets := range subnets {
+		est := eth.EstimateValidators(len(peerSubnets.attnets), peerSubnets.syncnets, attesters[peerID], eth.DefaultEstimateConfidence)
+		batch.AddQuery(
+			`
+			INSERT INTO eth_validator_estimates(
+				peer_id,
+				timestamp,
+				attnets,
+				syncnets,
+				attesters,
+				estimate,
+				lower_bound,
+				upper_bound,
+				saturated,
+				confidence)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+			ON CONFLICT (peer_id, timestamp) DO NOTHING;
+			`,
+			peerID,
+			t,
+			est.Attnets,
+			est.Syncnets,
+			est.Attesters,
+			est.Estimate,
+			est.Lower,
+			est.Upper,
+			est.Saturated,
+			est.Confidence,
+		)
+		if batch.IsReadyToPersist() {
+			if err := batch.PersistBatch(); err != nil {
+				return 0, errors.Wrap(err, "unable to persist the validator estimates")
+			}
+		}
+	}
+	if err := batch.PersistBatch(); err != nil {
+		return 0, errors.Wrap(err, "unable to persist the validator estimates")
+	}
+	return len(subnets), nil
+}
+
+type peerSubnets struct {
+	attnets  map[int]struct{}
+	syncnets int
+}
+
+// activePeersSubnets returns the subnets of the last metadata of each of the active peers
+func (c *DBClient) activePeersSubnets() (map[string]peerSubnets, error) {
+	subnets := make(map[string]peerSubnets)
+
+	rows, err := c.query(`
+		SELECT
+			s.peer_id,
+			s.attnets,
+			COALESCE(s.syncnets, '')
+		FROM eth_status s
+		INNER JOIN peer_info p ON p.peer_id = s.peer_id
+		WHERE p.deprecated = false and s.attnets IS NOT NULL and p.last_activity > unixepoch() - $1 * 86400;
+		`,
+		LastActivityValidRange,
+	)
+	if err != nil {
+		return subnets, errors.Wrap(err, "unable to fetch the subnets of the active peers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peerID, attnetsStr, syncnetsStr string
+		err = rows.Scan(&peerID, &attnetsStr, &syncnetsStr)
+		if err != nil {
+			return subnets, errors.Wrap(err, "unable to parse fetched subnets")
+		}
+		attnets, err := hex.DecodeString(strings.TrimPrefix(attnetsStr, "0x"))
+		if err != nil {
+			log.Warnf("invalid attnets %s of peer %s", attnetsStr, peerID)
+			continue
+		}
+		syncnets, _ := hex.DecodeString(strings.TrimPrefix(syncnetsStr, "0x"))
+		s := peerSubnets{
+			attnets:  make(map[int]struct{}),
+			syncnets: len(eth.SubnetIndexes(syncnets, eth.SyncSubnetLimit)),
+		}
+		for _, subnet := range eth.SubnetIndexes(attnets, eth.SubnetLimit) {
+			s.attnets[subnet] = struct{}{}
+		}
+		subnets[peerID] = s
+	}
+	return subnets, nil
+}
+
+// unsubscribedAttesters returns the max number of attesters per epoch that each peer sent us first
+// on the subnets that it isn't subscribed to (only its own validators publish there)
+func (c *DBClient) unsubscribedAttesters(subnets map[string]peerSubnets) (map[string]int, error) {
+	attesters := make(map[string]int)
+
+	rows, err := c.query(`
+		SELECT
+			sender,
+			subnet,
+			slot / 32 AS epoch,
+			count(DISTINCT slot || '/' || committee_index || '/' || aggregation_bit)
+		FROM eth_attestations
+		WHERE aggregation_bit >= 0 and slot > (SELECT max(slot) FROM eth_attestations) - $1
+		GROUP BY sender, subnet, epoch;
+		`,
+		ValidatorActivitySlots,
+	)
+	if err != nil {
+		return attesters, errors.Wrap(err, "unable to fetch the attesters of the peers")
+	}
+	defer rows.Close()
+
+	perEpoch := make(map[string]map[int64]int)
+	for rows.Next() {
+		var sender string
+		var subnet int
+		var epoch int64
+		var count int
+		err = rows.Scan(&sender, &subnet, &epoch, &count)
+		if err != nil {
+			return attesters, errors.Wrap(err, "unable to parse fetched attesters")
+		}
+		s, ok := subnets[sender]
+		if !ok {
+			continue
+		}
+		if _, subscribed := s.attnets[subnet]; subscribed {
+			continue
+		}
+		if perEpoch[sender] == nil {
+			perEpoch[sender] = make(map[int64]int)
+		}
+		perEpoch[sender][epoch] += count
+	}
+	for sender, epochs := range perEpoch {
+		for _, count := range epochs {
+			if count > attesters[sender] {
+				attesters[sender] = count
+			}
+		}
+	}
+	return attesters, nil
+}
+
+func (c *DBClient) validatorEstimatesHeartbeat() {
+	ticker := time.NewTicker(c.validatorEstimatesInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			peers, err := c.estimateValidators()
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			log.Infof("estimated the validators of %d active peers", peers)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// SQLiteScheme prefixes the db endpoints that point to a SQLite file instead of a Postgres server
+const SQLiteScheme = "sqlite:"
+
+// Client is the DB of a crawler, implemented by the Postgres and the SQLite backends with the same schema
+type Client interface {
+	// batched writes of the crawled items (peers, connections, messages, etc.)
+	PersistToDB(persItem interface{})
+	GetMetrics() *metrics.MetricsModule
+	Close()
+
+	// crawler runs
+	InsertCrawlerRun(run *models.CrawlerRun) (int, error)
+	FinishCrawlerRun(runID int, stopTime time.Time) error
+
+	// identity of the host
+	LoadIdentityKey() (key string, created time.Time, err error)
+	StoreIdentityKey(peerID string, key string, created time.Time) error
+	InsertIdentityChange(change *models.IdentityChange) error
+	GetInconsistentIdentities() ([]models.IdentityLink, error)
+
+	// peers to connect to
+	GetNonDeprecatedPeers() ([]*models.RemoteConnectablePeer, error)
+	GetDeprecatedPeers(network utils.NetworkType, before time.Time, limit int) ([]*models.RemoteConnectablePeer, error)
+	GetDialScoreInputs() (map[peer.ID]*models.DialScoreInput, error)
+	GetReputationInputs() (map[peer.ID]*models.ReputationInput, error)
+	GetSampleCandidates(network utils.NetworkType) ([]*models.SampledPeer, error)
+	LoadDialQueue() ([]models.DialQueueState, time.Time, error)
+	ReplaceDialQueue(states []models.DialQueueState) error
+	SetStaticPeers(peers []peer.AddrInfo) error
+	RecordStaticPeerConnection(peerID peer.ID, connTime time.Time) error
+	GetBlocklistEntries() ([]string, error)
+	GetTargetEntries() ([]string, error)
+
+	// ip locations
+	ReadIpInfo(ip string) (models.IpInfo, error)
+	CheckIpRecords(ip string) (exists bool, expired bool, err error)
+	GetExpiredIpInfo(limit int) ([]string, error)
+
+	// distributions of the active peers
+	GetClientDistribution() (map[string]interface{}, error)
+	GetVersionDistribution() (map[string]interface{}, error)
+	GetMajorVersionDistribution() (map[string]interface{}, error)
+	GetClientVersionDistribution() ([]models.ClientCount, error)
+	GetGeoDistribution() (map[string]interface{}, error)
+	GetOsDistribution() (map[string]interface{}, error)
+	GetArchDistribution() (map[string]interface{}, error)
+	GetPlatformDistribution() (map[string]interface{}, error)
+	GetHostingDistribution() (map[string]interface{}, error)
+	GetIpClassDistribution() (map[string]int, error)
+	GetASNDistribution() (map[string]int, error)
+	GetCloudProviderDistribution() (map[string]int, error)
+	GetRTTDistribution() (map[string]interface{}, error)
+	GetIPDistribution() (map[string]interface{}, error)
+	GetSecurityDistribution() (map[string]interface{}, error)
+	GetOriginDistribution() (map[string]interface{}, error)
+	GetDiscoverySourceDistribution() (map[string]interface{}, error)
+	GetProtocolDistribution() (map[string]interface{}, error)
+	GetActivePeersProtocols() ([][]string, error)
+	GetNegotiationStatsByClient() ([]models.NegotiationStats, error)
+	GetPeerSetDistributions(peerIDs []string) (clients, countries, asns map[string]int, err error)
+	GetMovedPeers(window time.Duration) (models.MovedPeers, error)
+	GetRoundTotals() (total, active, deprecated int, err error)
+
+	// ethereum nodes and gossip messages
+	GetNodePerForkDistribution() (map[string]interface{}, error)
+	GetAttnetsDistribution() (map[string]interface{}, error)
+	GetPeersPerSubnet() (map[string]map[int]int, error)
+	GetPeerAttnets() (map[peer.ID][]int, error)
+	GetAttnetSubscriptions() (map[int]int, error)
+	GetDeprecatedNodes() (int, error)
+	GetGossipArrivalBaselines(slots int) (map[string]map[string]float64, error)
+
+	// analytics of the REST API
+	GetActivePeers(window time.Duration) ([]models.PeerSummary, error)
+	GetPeerSummary(pID peer.ID) (*models.PeerSummary, error)
+	GetPeerTimeline(pID peer.ID) ([]models.PeerTimelineEvent, error)
+	GetPeerAddressHistory(pID peer.ID) ([]models.PeerAddressRecord, error)
+	GetPeersByClient(window time.Duration) ([]models.ClientCount, error)
+	GetTopicRates(window time.Duration) ([]models.TopicRate, error)
+	QueryDataset(dataset string, filter models.DatasetFilter) ([]map[string]interface{}, error)
+
+	// retention of the time-series tables
+	ApplyRetention(retention time.Duration) (map[string]int64, error)
+	ApplyTableRetention(table string, window time.Duration, rollup bool) (int64, error)
+	UpsertStatsRollup(day time.Time, dimension string, dist map[string]interface{}) error
+	GetStatsRollups(since time.Time) ([]models.StatsRollup, error)
+}
@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// time to fetch a remote bootnode list
+	BootnodesTimeout = 30 * time.Second
+	// max size of a bootnode list
+	MaxBootnodesSize int64 = 1 << 20
+
+	bootnodesClient = &http.Client{Timeout: BootnodesTimeout}
+)
+
+// LoadBootnodes reads the bootnodes of a local file or an HTTPS URL. The list can be given as:
+// - the JSON of the bootnode files ({"bootNodes": [...]}) or a JSON array
+// - one bootnode per line, like the bootstrap_nodes.txt/.yaml of the network configs (the
+// "- " prefix of the YAML lists, the quotes and the # comments are dropped)
+func LoadBootnodes(ctx context.Context, source string) ([]string, error) {
+	var content []byte
+	var err error
+	switch {
+	case strings.HasPrefix(source, "https://"):
+		content, err = fetchBootnodes(ctx, source)
+	case strings.HasPrefix(source, "http://"):
+		return nil, errors.Errorf("bootnode list %s has to be served over HTTPS", source)
+	default:
+		content, err = os.ReadFile(source)
+		err = errors.Wrap(err, "unable to read bootnode file "+source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	bootnodes, err := ParseBootnodeList(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse bootnode list "+source)
+	}
+	return bootnodes, nil
+}
+
+func fetchBootnodes(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to compose bootnode list request")
+	}
+	resp, err := bootnodesClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch bootnode list "+url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to fetch bootnode list %s: status %s", url, resp.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, MaxBootnodesSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read bootnode list "+url)
+	}
+	return content, nil
+}
+
+// ParseBootnodeList returns the bootnodes of a list in any of the formats of LoadBootnodes
+func ParseBootnodeList(content []byte) ([]string, error) {
+	content = bytes.TrimSpace(content)
+	var bootnodes []string
+	switch {
+	case bytes.HasPrefix(content, []byte("{")):
+		list := BootNodeListString{}
+		if err := json.Unmarshal(content, &list); err != nil {
+			return nil, err
+		}
+		bootnodes = list.BootNodes
+	case bytes.HasPrefix(content, []byte("[")):
+		if err := json.Unmarshal(content, &bootnodes); err != nil {
+			return nil, err
+		}
+	default:
+		for _, line := range strings.Split(string(content), "\n") {
+			if idx := strings.Index(line, "#"); idx >= 0 {
+				line = line[:idx]
+			}
+			line = strings.TrimSpace(line)
+			line = strings.TrimSpace(strings.TrimPrefix(line, "- "))
+			line = strings.Trim(line, `"'`)
+			if line != "" {
+				bootnodes = append(bootnodes, line)
+			}
+		}
+	}
+	if len(bootnodes) == 0 {
+		return nil, errors.New("no bootnodes in the list")
+	}
+	return bootnodes, nil
+}
+
+// MergeBootnodes joins the given bootnode lists, dropping the duplicates
+func MergeBootnodes(lists ...[]string) []string {
+	seen := make(map[string]struct{})
+	merged := make([]string, 0)
+	for _, list := range lists {
+		for _, bootnode := range list {
+			if _, ok := seen[bootnode]; ok {
+				continue
+			}
+			seen[bootnode] = struct{}{}
+			merged = append(merged, bootnode)
+		}
+	}
+	return merged
+}
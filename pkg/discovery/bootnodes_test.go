@@ -0,0 +1,73 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBootnodeList(t *testing.T) {
+	expected := []string{"enr:-a", "enr:-b"}
+	for name, content := range map[string]string{
+		"bootnode file": `{"bootNodes": ["enr:-a", "enr:-b"]}`,
+		"json array":    `["enr:-a", "enr:-b"]`,
+		"lines":         "# mainnet bootnodes\nenr:-a\n\nenr:-b # teku\n",
+		"yaml list":     "- \"enr:-a\"\n- 'enr:-b'\n",
+	} {
+		bootnodes, err := ParseBootnodeList([]byte(content))
+		require.NoError(t, err, name)
+		require.Equal(t, expected, bootnodes, name)
+	}
+
+	_, err := ParseBootnodeList([]byte("# no bootnodes\n"))
+	require.Error(t, err)
+	_, err = ParseBootnodeList([]byte(`{"bootNodes": `))
+	require.Error(t, err)
+}
+
+func TestLoadBootnodes(t *testing.T) {
+	ctx := context.Background()
+
+	file := filepath.Join(t.TempDir(), "bootstrap_nodes.txt")
+	require.NoError(t, os.WriteFile(file, []byte("enr:-a\nenr:-b\n"), 0644))
+	bootnodes, err := LoadBootnodes(ctx, file)
+	require.NoError(t, err)
+	require.Equal(t, []string{"enr:-a", "enr:-b"}, bootnodes)
+
+	_, err = LoadBootnodes(ctx, filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bootnodes.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "- enr:-c\n")
+	}))
+	defer server.Close()
+	defaultClient := bootnodesClient
+	bootnodesClient = server.Client()
+	defer func() { bootnodesClient = defaultClient }()
+
+	bootnodes, err = LoadBootnodes(ctx, server.URL+"/bootnodes.yaml")
+	require.NoError(t, err)
+	require.Equal(t, []string{"enr:-c"}, bootnodes)
+
+	_, err = LoadBootnodes(ctx, server.URL+"/missing.yaml")
+	require.Error(t, err)
+
+	// the remote lists have to be served over HTTPS
+	_, err = LoadBootnodes(ctx, "http://example.com/bootnodes.yaml")
+	require.Error(t, err)
+}
+
+func TestMergeBootnodes(t *testing.T) {
+	merged := MergeBootnodes([]string{"enr:-a", "enr:-b"}, nil, []string{"enr:-b", "enr:-c"})
+	require.Equal(t, []string{"enr:-a", "enr:-b", "enr:-c"}, merged)
+}
@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	DefaultRedialBatch = 500
+)
+
+// deprecatedPeersSource is the subset of the DB that the re-dial queue needs
+type deprecatedPeersSource interface {
+	GetDeprecatedPeers(network utils.NetworkType, before time.Time, limit int) ([]*models.RemoteConnectablePeer, error)
+}
+
+// RedialDiscovery re-notifies the deprecated peers of the DB that weren't attempted within an interval,
+// so that the peering tries again with the peers that might have come back to the network
+type RedialDiscovery struct {
+	ctx      context.Context
+	db       deprecatedPeersSource
+	network  utils.NetworkType
+	interval time.Duration
+	batch    int
+
+	nodeNotC chan *models.HostInfo
+	wg       sync.WaitGroup
+	doneC    chan struct{}
+}
+
+func NewRedialDiscovery(ctx context.Context, db deprecatedPeersSource, network utils.NetworkType, interval time.Duration) *RedialDiscovery {
+	return &RedialDiscovery{
+		ctx:      ctx,
+		db:       db,
+		network:  network,
+		interval: interval,
+		batch:    DefaultRedialBatch,
+		nodeNotC: make(chan *models.HostInfo),
+		doneC:    make(chan struct{}),
+	}
+}
+
+func (d *RedialDiscovery) Start() chan *models.HostInfo {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !d.redial() {
+					return
+				}
+			case <-d.doneC:
+				return
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+	return d.nodeNotC
+}
+
+// redial notifies a batch of deprecated peers, false if the service was closed meanwhile
+func (d *RedialDiscovery) redial() bool {
+	peers, err := d.db.GetDeprecatedPeers(d.network, time.Now().Add(-d.interval), d.batch)
+	if err != nil {
+		log.Errorf("unable to read the peers to re-dial: %s", err.Error())
+		return true
+	}
+	for _, p := range peers {
+		// the new HostInfo is persisted as non-deprecated, going back to the peering queue
+		hInfo := models.NewHostInfo(
+			p.ID,
+			p.Network,
+			models.WithMultiaddress(p.Addrs),
+		)
+		select {
+		case d.nodeNotC <- hInfo:
+		case <-d.doneC:
+			return false
+		case <-d.ctx.Done():
+			return false
+		}
+	}
+	log.Debugf("%d deprecated peers queued to be re-dialed", len(peers))
+	return true
+}
+
+func (d *RedialDiscovery) Stop() {
+	close(d.doneC)
+	d.wg.Wait()
+	close(d.nodeNotC)
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/pkg/errors"
 
+	"github.com/migalabs/armiarma/pkg/db/storage"
 	"github.com/migalabs/armiarma/pkg/utils"
 	"github.com/migalabs/armiarma/pkg/utils/apis"
 
@@ -23,12 +25,26 @@ With this implementation, you can create a Discovery5 Service and inititate the
 
 var (
 	ModuleName = "DISC"
+
+	// attribute of the HostInfo with the source that discovered the peer
+	SourceAttribute string = "discovery-source"
+)
+
+// names of the discovery sources that the crawlers can compose
+const (
+	SourceDv5    = "dv5"
+	SourceDv4    = "dv4"
+	SourceDHT    = "dht"
+	SourceStatic = "static"
+	SourceDB     = "db"
 )
 
 const (
 	minIterTime = 100 * time.Millisecond
 )
 
+// PeerDiscovery is a source of discovered peers, notified through the channel returned by Start.
+// The channel has to be closed on Stop
 type PeerDiscovery interface {
 	Start() chan *models.HostInfo
 	Stop()
@@ -38,68 +54,119 @@ type BootNodeListString struct {
 	BootNodes []string `json:"bootNodes"`
 }
 
+// discoverySource is a running PeerDiscovery, whose name tags the peers that it finds
+type discoverySource struct {
+	name string
+	serv PeerDiscovery
+}
+
 type Discovery struct {
 	// Service control variables
 	ctx context.Context
 
-	DiscService PeerDiscovery
-	DBClient    *psql.DBClient
-	IpLocator   *apis.IpLocator
+	sources   []discoverySource
+	DBClient  storage.Client
+	IpLocator *apis.IpLocator
+
+	// peers notified by each source
+	metrics    *discoveryMetrics
+	discovered map[string]*uint64
 
 	wg    sync.WaitGroup
 	doneC chan struct{}
 }
 
-// NewDiscovery generates a new module to discover peers in the given network with the given PeerDiscovery submodule
-func NewDiscovery(ctx context.Context, discServ PeerDiscovery, db *psql.DBClient, ipLoc *apis.IpLocator) *Discovery {
-	// return the Discovery object
-	return &Discovery{
-		ctx:         ctx,
-		DiscService: discServ,
-		DBClient:    db,
-		IpLocator:   ipLoc,
-		doneC:       make(chan struct{}),
+// NewDiscovery generates a new module to discover peers in the given network, composing the PeerDiscovery
+// sources given as options (they all run concurrently)
+func NewDiscovery(ctx context.Context, db storage.Client, ipLoc *apis.IpLocator, opts ...DiscoveryOption) (*Discovery, error) {
+	disc := &Discovery{
+		ctx:        ctx,
+		sources:    make([]discoverySource, 0),
+		DBClient:   db,
+		IpLocator:  ipLoc,
+		metrics:    newDiscoveryMetrics(),
+		discovered: make(map[string]*uint64),
+		doneC:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		err := opt(disc)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply discovery option")
+		}
+	}
+	if len(disc.sources) == 0 {
+		return nil, errors.New("no discovery source was given")
+	}
+	for _, src := range disc.sources {
+		disc.discovered[src.name] = new(uint64)
+	}
+	return disc, nil
+}
+
+// Sources returns the names of the discovery sources that compose the service
+func (d *Discovery) Sources() []string {
+	names := make([]string, 0, len(d.sources))
+	for _, src := range d.sources {
+		names = append(names, src.name)
 	}
+	return names
 }
 
-// Start spawns the discovery service in a separate go-routine
+// Start spawns each of the discovery sources, listening to their peers in separate go-routines
 func (d *Discovery) Start() {
-	nodeNotC := d.DiscService.Start()
-
-	d.wg.Add(1)
-	go func() {
-		defer d.wg.Done()
-		// check if the DiscPeer Obj has a new peer to read
-		for {
-			select {
-			case hInfo := <-nodeNotC:
-				d.peerHandler(hInfo)
-
-			case <-d.doneC:
-				log.Info("shutdown detected in discovery service, shutting down")
-				return
+	for _, src := range d.sources {
+		log.Infof("starting discovery source %s", src.name)
+		nodeNotC := src.serv.Start()
 
-			case <-d.ctx.Done():
-				log.Info("shutdown detected in discovery service, shutting down")
+		d.wg.Add(1)
+		go d.sourceListener(src.name, nodeNotC)
+	}
+}
+
+func (d *Discovery) sourceListener(source string, nodeNotC chan *models.HostInfo) {
+	defer d.wg.Done()
+	// check if the DiscPeer Obj has a new peer to read
+	for {
+		select {
+		case hInfo, ok := <-nodeNotC:
+			if !ok {
+				log.Infof("discovery source %s closed", source)
 				return
 			}
+			d.peerHandler(source, hInfo)
+
+		case <-d.doneC:
+			log.Info("shutdown detected in discovery service, shutting down")
+			return
+
+		case <-d.ctx.Done():
+			log.Info("shutdown detected in discovery service, shutting down")
+			return
 		}
-	}()
+	}
 }
 
 func (d *Discovery) Stop() {
-	d.DiscService.Stop()
-	d.doneC <- struct{}{}
+	// the listeners keep reading until the sources close their channels
+	for _, src := range d.sources {
+		src.serv.Stop()
+	}
+	close(d.doneC)
+	d.wg.Wait()
 }
 
 // peer handler for the discovered peers
-func (d *Discovery) peerHandler(hInfo *models.HostInfo) {
+func (d *Discovery) peerHandler(source string, hInfo *models.HostInfo) {
 	log.WithFields(log.Fields{
 		"peer_id": hInfo.ID.String(),
 		"ip":      hInfo.IP,
+		"source":  source,
 		"attrs":   hInfo.Attr,
 	}).Debugf("discovered new peer")
-	// if the peer
+	// tag the peer with the source that found it
+	hInfo.AddAtt(SourceAttribute, models.NewDiscoverySource(hInfo.ID, source))
+	atomic.AddUint64(d.discovered[source], 1)
+	d.metrics.DiscoveredPeers.WithLabelValues(source).Inc()
 
 	// Persist to DB the hInfo
 	d.DBClient.PersistToDB(hInfo)
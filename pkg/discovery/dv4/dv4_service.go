@@ -0,0 +1,296 @@
+package dv4
+
+/**
+This file implements the discovery4 service using the go-ethereum library,
+crawling the execution layer nodes of the Ethereum network.
+
+*/
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var (
+	ModuleName              = "DV4"
+	ErrorNotValidNode error = errors.New("not valid node - different fork id")
+
+	DefaultHelloTimeout    = 10 * time.Second
+	DefaultHelloWorkers    = 50
+	DefaultRecrawlInterval = 30 * time.Minute
+
+	// attribute of the HostInfo with the result of the hello
+	ConnAttemptAttribute string = "hello-attempt"
+)
+
+type Discovery4 struct {
+	// Service control variables
+	ctx context.Context
+
+	privkey     *ecdsa.PrivateKey
+	LocalNode   *enode.LocalNode
+	Dv4Listener *discover.UDPv4
+	Iterator    enode.Iterator
+
+	// node notifier
+	nodeNotC chan *models.HostInfo
+	wg       sync.WaitGroup
+	doneF    bool
+
+	// Filtering (all the nodes if empty)
+	forkHashes map[string]struct{}
+
+	// client identification through the devp2p hello
+	userAgent       string
+	helloTimeout    time.Duration
+	helloWorkers    int
+	recrawlInterval time.Duration
+
+	m    sync.Mutex
+	seen map[enode.ID]time.Time
+}
+
+// NewDiscovery4
+func NewDiscovery4(
+	ctx context.Context,
+	privkey *ecdsa.PrivateKey,
+	bootnodes []*enode.Node,
+	port int,
+	opts ...Discovery4Option) (*Discovery4, error) {
+
+	if len(bootnodes) == 0 {
+		return nil, errors.New("unable to start dv4 peer discovery, no bootnodes provided")
+	}
+
+	// the local node is only kept in memory
+	db, err := enode.OpenDB("")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open the enode db")
+	}
+	localNode := enode.NewLocalNode(db, privkey)
+
+	// udp address to listen
+	udpAddr := &net.UDPAddr{
+		IP:   net.IPv4zero,
+		Port: port,
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "unable to listen for discv4")
+	}
+	localNode.SetFallbackIP(net.IPv4(127, 0, 0, 1))
+	localNode.SetFallbackUDP(port)
+
+	// configuration of the discovery4
+	cfg := discover.Config{
+		PrivateKey:   privkey,
+		Bootnodes:    bootnodes,
+		Log:          gethlog.New(),
+		ValidSchemes: enode.ValidSchemes,
+	}
+	dv4Listener, err := discover.ListenV4(conn, localNode, cfg)
+	if err != nil {
+		conn.Close()
+		db.Close()
+		return nil, errors.Wrap(err, "unable to start discv4")
+	}
+
+	disc := &Discovery4{
+		ctx:             ctx,
+		privkey:         privkey,
+		LocalNode:       localNode,
+		Dv4Listener:     dv4Listener,
+		nodeNotC:        make(chan *models.HostInfo),
+		forkHashes:      make(map[string]struct{}),
+		helloTimeout:    DefaultHelloTimeout,
+		helloWorkers:    DefaultHelloWorkers,
+		recrawlInterval: DefaultRecrawlInterval,
+		seen:            make(map[enode.ID]time.Time),
+	}
+	for _, opt := range opts {
+		err := opt(disc)
+		if err != nil {
+			dv4Listener.Close()
+			return nil, errors.Wrap(err, "unable to apply discv4 option")
+		}
+	}
+	log.Infof("launching discovery4 at port %d", port)
+	return disc, nil
+}
+
+// Start
+func (d *Discovery4) Start() chan *models.HostInfo {
+	// Generate the iterator over the found nodes
+	d.Iterator = d.Dv4Listener.RandomNodes()
+
+	// the hellos are slow, so they are performed by a pool of workers
+	nodeC := make(chan *enode.Node, d.helloWorkers)
+	for i := 0; i < d.helloWorkers; i++ {
+		d.wg.Add(1)
+		go d.helloWorker(nodeC)
+	}
+
+	d.wg.Add(1)
+	go d.nodeIterator(nodeC)
+
+	return d.nodeNotC
+}
+
+func (d *Discovery4) nodeIterator(nodeC chan *enode.Node) {
+	defer d.wg.Done()
+	defer close(nodeC)
+
+	for {
+		if d.doneF || d.ctx.Err() != nil {
+			log.Info("shutdown detected, closing discv4 iterator")
+			return
+		}
+
+		if d.Iterator.Next() {
+			node := d.Iterator.Node()
+			if !d.shouldCrawl(node.ID()) {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"enode":   node.URLv4(),
+				"node_id": node.ID().String(),
+				"module":  "Discv4",
+			}).Debug("new node discovered")
+
+			select {
+			case nodeC <- node:
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (d *Discovery4) helloWorker(nodeC chan *enode.Node) {
+	defer d.wg.Done()
+
+	for node := range nodeC {
+		hInfo, err := d.handleNode(node)
+		if err != nil {
+			if err != ErrorNotValidNode { // don't show anything if the error is related to the fork id
+				log.Debug(errors.Wrap(err, "error handling new node"))
+			}
+			continue
+		}
+		select {
+		case d.nodeNotC <- hInfo:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// shouldCrawl checks that the node wasn't crawled within the recrawl interval
+// (the random walks return the same nodes over and over)
+func (d *Discovery4) shouldCrawl(id enode.ID) bool {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if last, ok := d.seen[id]; ok && time.Since(last) < d.recrawlInterval {
+		return false
+	}
+	d.seen[id] = time.Now()
+	return true
+}
+
+func (d *Discovery4) Stop() {
+	d.doneF = true
+	d.Iterator.Close()
+	d.wg.Wait()
+
+	d.Dv4Listener.Close()
+	d.LocalNode.Database().Close()
+	close(d.nodeNotC)
+}
+
+// handleNode requests the ENR of a newly discovered node and identifies its client
+func (d *Discovery4) handleNode(node *enode.Node) (*models.HostInfo, error) {
+	// get the latest record of the node (EIP-868), not all the clients support it
+	if n, err := d.Dv4Listener.RequestENR(node); err == nil {
+		node = n
+	}
+
+	// check the fork id filter
+	if len(d.forkHashes) > 0 {
+		forkID, ok := eth.ParseNodeForkID(*node)
+		if !ok {
+			return nil, ErrorNotValidNode
+		}
+		if _, target := d.forkHashes[forkID.HashString()]; !target {
+			return nil, ErrorNotValidNode
+		}
+	}
+
+	// Parse ENR
+	enr, err := eth.ParseEnr(node)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse new discovered ENR")
+	}
+	// Generate the peer ID from the pubkey
+	peerID, err := enr.GetPeerID()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to convert Geth pubkey to Libp2p")
+	}
+	// gen the HostInfo
+	hInfo := models.NewHostInfo(
+		peerID,
+		utils.EthereumELNetwork,
+		models.WithIPAndPorts(
+			enr.IP.String(),
+			enr.TCP,
+		),
+		models.WithOrigin(models.DiscoveredOrigin),
+	)
+	// add the enr as an attribute
+	hInfo.AddAtt(eth.EnrHostInfoAttribute, enr)
+
+	// identify the client through the devp2p hello
+	hello, rtt, err := requestHello(node, d.privkey, d.userAgent, d.helloTimeout)
+	if err != nil {
+		hInfo.AddAtt(ConnAttemptAttribute, models.NewConnAttempt(peerID, models.NegativeAttempt, err.Error(), false, false))
+		return hInfo, nil
+	}
+	hInfo.IdentifyHost(models.NewPeerInfo(
+		peerID,
+		hello.Name,
+		fmt.Sprintf("devp2p/%d", hello.Version),
+		hello.Protocols(),
+		rtt,
+	))
+	hInfo.AddAtt(ConnAttemptAttribute, models.NewConnAttempt(peerID, models.PossitiveAttempt, hosts.NoConnError, false, false))
+	return hInfo, nil
+}
+
+// ParseBootnodes parses the enode:// (or enr:) representation of the bootnodes
+func ParseBootnodes(bNodes []string) ([]*enode.Node, error) {
+	bootNodeList := make([]*enode.Node, 0, len(bNodes))
+	for _, element := range bNodes {
+		node, err := enode.Parse(enode.ValidSchemes, element)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid bootnode %s", element)
+		}
+		bootNodeList = append(bootNodeList, node)
+	}
+	return bootNodeList, nil
+}
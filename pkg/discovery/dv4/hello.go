@@ -0,0 +1,122 @@
+package dv4
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"time"
+
+	gcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+// devp2p base protocol (https://github.com/ethereum/devp2p/blob/master/rlpx.md)
+const (
+	helloMsg      = 0x00
+	disconnectMsg = 0x01
+
+	baseProtocolVersion = 5
+	// reason of our disconnections (client quitting)
+	discQuitting = 0x08
+)
+
+// capabilities that we advertise, so that the remote clients don't drop us before the hello
+var ourCaps = []Cap{{Name: "eth", Version: 67}, {Name: "eth", Version: 68}}
+
+// Cap is a sub-protocol supported by the remote client (i.e. eth/68, snap/1)
+type Cap struct {
+	Name    string
+	Version uint
+}
+
+func (c Cap) String() string {
+	return fmt.Sprintf("%s/%d", c.Name, c.Version)
+}
+
+// Hello is the handshake message of the devp2p base protocol, where the remote client identifies itself
+type Hello struct {
+	Version    uint64
+	Name       string
+	Caps       []Cap
+	ListenPort uint64
+	ID         []byte // secp256k1 public key
+
+	// ignore the additional fields that could be added in the future
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// Protocols returns the capabilities of the remote client in the "name/version" format
+func (h *Hello) Protocols() []string {
+	protocols := make([]string, 0, len(h.Caps))
+	for _, c := range h.Caps {
+		protocols = append(protocols, c.String())
+	}
+	return protocols
+}
+
+// requestHello performs the RLPx handshake with the node and exchanges the devp2p hello,
+// returning the remote hello and the time it took to establish the TCP connection
+func requestHello(node *enode.Node, privkey *ecdsa.PrivateKey, userAgent string, timeout time.Duration) (*Hello, time.Duration, error) {
+	if node.TCP() == 0 {
+		return nil, 0, errors.New("node doesn't advertise any tcp port")
+	}
+	start := time.Now()
+	fd, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", node.IP(), node.TCP()), timeout)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "unable to dial node")
+	}
+	rtt := time.Since(start)
+
+	conn := rlpx.NewConn(fd, node.Pubkey())
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Handshake(privkey); err != nil {
+		return nil, rtt, errors.Wrap(err, "rlpx handshake failed")
+	}
+
+	// both sides send the hello right after the handshake
+	ours, err := rlp.EncodeToBytes(&Hello{
+		Version: baseProtocolVersion,
+		Name:    userAgent,
+		Caps:    ourCaps,
+		ID:      gcrypto.FromECDSAPub(&privkey.PublicKey)[1:],
+	})
+	if err != nil {
+		return nil, rtt, errors.Wrap(err, "unable to encode hello")
+	}
+	if _, err := conn.Write(helloMsg, ours); err != nil {
+		return nil, rtt, errors.Wrap(err, "unable to send hello")
+	}
+
+	code, data, _, err := conn.Read()
+	if err != nil {
+		return nil, rtt, errors.Wrap(err, "unable to read hello")
+	}
+	switch code {
+	case helloMsg:
+		hello := new(Hello)
+		if err := rlp.DecodeBytes(data, hello); err != nil {
+			return nil, rtt, errors.Wrap(err, "unable to decode hello")
+		}
+		// the messages after the hello are compressed (protocol version >= 5)
+		conn.SetSnappy(hello.Version >= baseProtocolVersion)
+		reason, _ := rlp.EncodeToBytes([]uint{discQuitting})
+		conn.Write(disconnectMsg, reason)
+		return hello, rtt, nil
+
+	case disconnectMsg:
+		var reason []uint
+		rlp.DecodeBytes(data, &reason)
+		if len(reason) > 0 {
+			return nil, rtt, errors.Errorf("disconnected before hello with reason %d", reason[0])
+		}
+		return nil, rtt, errors.New("disconnected before hello")
+
+	default:
+		return nil, rtt, errors.Errorf("unexpected message %d instead of hello", code)
+	}
+}
@@ -0,0 +1,61 @@
+package dv4
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	gcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// servePeer accepts a single RLPx connection, answering with the given message after the hello
+func servePeer(t *testing.T, code uint64, msg interface{}) *enode.Node {
+	key, err := gcrypto.GenerateKey()
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		fd, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conn := rlpx.NewConn(fd, nil)
+		defer conn.Close()
+		if _, err := conn.Handshake(key); err != nil {
+			return
+		}
+		payload, _ := rlp.EncodeToBytes(msg)
+		conn.Write(code, payload)
+		conn.Read()
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	return enode.NewV4(&key.PublicKey, net.ParseIP("127.0.0.1"), port, port)
+}
+
+func Test_RequestHello(t *testing.T) {
+	key, err := gcrypto.GenerateKey()
+	require.NoError(t, err)
+
+	node := servePeer(t, helloMsg, &Hello{
+		Version: baseProtocolVersion,
+		Name:    "Geth/v1.13.14-stable-2bd6bd01/linux-amd64/go1.21.7",
+		Caps:    []Cap{{Name: "eth", Version: 68}, {Name: "snap", Version: 1}},
+		ID:      make([]byte, 64),
+	})
+	hello, _, err := requestHello(node, key, "armiarma", 5*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "Geth/v1.13.14-stable-2bd6bd01/linux-amd64/go1.21.7", hello.Name)
+	require.Equal(t, []string{"eth/68", "snap/1"}, hello.Protocols())
+
+	// too many peers
+	node = servePeer(t, disconnectMsg, []uint{0x04})
+	_, _, err = requestHello(node, key, "armiarma", 5*time.Second)
+	require.ErrorContains(t, err, "reason 4")
+}
@@ -0,0 +1,59 @@
+package dv4
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Discovery4Option func(*Discovery4) error
+
+// WithForkHashes only keeps the nodes whose ENR advertises one of the fork hashes (EIP-2124),
+// i.e. to crawl a single chain of all the ones sharing the discv4 DHT
+func WithForkHashes(hashes []string) Discovery4Option {
+	return func(d *Discovery4) error {
+		for _, hash := range hashes {
+			hash = strings.ToLower(hash)
+			if !strings.HasPrefix(hash, "0x") {
+				hash = "0x" + hash
+			}
+			if len(hash) != 10 {
+				return errors.Errorf("invalid fork hash %s, it has to be 4 bytes long", hash)
+			}
+			d.forkHashes[hash] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// WithUserAgent sets the client name advertised in our devp2p hello
+func WithUserAgent(userAgent string) Discovery4Option {
+	return func(d *Discovery4) error {
+		d.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithHelloSettings sets the timeout of the hellos and the number of them performed concurrently
+func WithHelloSettings(timeout time.Duration, workers int) Discovery4Option {
+	return func(d *Discovery4) error {
+		if timeout <= 0 || workers <= 0 {
+			return errors.Errorf("invalid hello settings (timeout %s, workers %d)", timeout, workers)
+		}
+		d.helloTimeout = timeout
+		d.helloWorkers = workers
+		return nil
+	}
+}
+
+// WithRecrawlInterval sets how long a node is not identified again after being crawled
+func WithRecrawlInterval(interval time.Duration) Discovery4Option {
+	return func(d *Discovery4) error {
+		if interval < 0 {
+			return errors.Errorf("invalid recrawl interval %s", interval)
+		}
+		d.recrawlInterval = interval
+		return nil
+	}
+}
@@ -0,0 +1,172 @@
+package dv5
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	ethenode "github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var (
+	// interval between the pings that check the health of the bootnodes
+	DefaultBootnodePingInterval = 5 * time.Minute
+)
+
+// BootnodeStatus is the dial health of a bootnode, checked with discv5 pings
+type BootnodeStatus struct {
+	Bootnode  string        `json:"bootnode"`
+	ENR       string        `json:"enr"`
+	Up        bool          `json:"up"`
+	Successes int64         `json:"successes"`
+	Failures  int64         `json:"failures"`
+	LastRTT   time.Duration `json:"last_rtt"`
+	LastPing  time.Time     `json:"last_ping"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// bootnodeHealth keeps the health of the bootnodes of the discv5 service
+type bootnodeHealth struct {
+	m      sync.Mutex
+	nodes  []*ethenode.Node
+	status map[ethenode.ID]*BootnodeStatus
+}
+
+func newBootnodeHealth(nodes []*ethenode.Node) *bootnodeHealth {
+	h := &bootnodeHealth{
+		status: make(map[ethenode.ID]*BootnodeStatus),
+	}
+	h.set(nodes)
+	return h
+}
+
+// set replaces the tracked bootnodes (keeping the health of the ones that were already
+// tracked), returning the ones that are new
+func (h *bootnodeHealth) set(nodes []*ethenode.Node) []*ethenode.Node {
+	h.m.Lock()
+	defer h.m.Unlock()
+	status := make(map[ethenode.ID]*BootnodeStatus, len(nodes))
+	added := make([]*ethenode.Node, 0)
+	for _, n := range nodes {
+		if s, ok := h.status[n.ID()]; ok {
+			status[n.ID()] = s
+			continue
+		}
+		status[n.ID()] = &BootnodeStatus{
+			Bootnode: bootnodeLabel(n),
+			ENR:      n.String(),
+		}
+		added = append(added, n)
+	}
+	h.nodes = nodes
+	h.status = status
+	return added
+}
+
+func (h *bootnodeHealth) list() []*ethenode.Node {
+	h.m.Lock()
+	defer h.m.Unlock()
+	return h.nodes
+}
+
+// record adds the result of a ping to the health of the bootnode, reporting when it goes down or up again
+func (h *bootnodeHealth) record(n *ethenode.Node, t time.Time, rtt time.Duration, err error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	s, ok := h.status[n.ID()]
+	if !ok {
+		// removed on a reload while it was being pinged
+		return
+	}
+	wasUp, firstPing := s.Up, s.LastPing.IsZero()
+	s.LastPing = t
+	if err != nil {
+		s.Up = false
+		s.Failures++
+		s.LastError = err.Error()
+		if wasUp || firstPing {
+			log.WithError(err).WithField("bootnode", s.Bootnode).Warn("bootnode not answering discv5 pings")
+		}
+		return
+	}
+	s.Up = true
+	s.Successes++
+	s.LastRTT = rtt
+	s.LastError = ""
+	if !wasUp && !firstPing {
+		log.WithField("bootnode", s.Bootnode).Info("bootnode answering discv5 pings again")
+	}
+}
+
+func (h *bootnodeHealth) snapshot() []BootnodeStatus {
+	h.m.Lock()
+	defer h.m.Unlock()
+	statuses := make([]BootnodeStatus, 0, len(h.nodes))
+	for _, n := range h.nodes {
+		statuses = append(statuses, *h.status[n.ID()])
+	}
+	return statuses
+}
+
+// bootnodeLabel identifies the bootnode in the logs and the metrics
+func bootnodeLabel(n *ethenode.Node) string {
+	return fmt.Sprintf("%s@%s:%d", n.ID().TerminalString(), n.IP(), n.UDP())
+}
+
+// bootnodeHealthCheck pings the bootnodes once per interval until the service is stopped
+func (d *Discovery5) bootnodeHealthCheck() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.pingInterval)
+	defer ticker.Stop()
+	for {
+		d.pingBootnodes(d.bootnodes.list())
+		select {
+		case <-ticker.C:
+		case <-d.doneC:
+			return
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Discovery5) pingBootnodes(nodes []*ethenode.Node) {
+	for _, n := range nodes {
+		start := time.Now()
+		err := d.Dv5Listener.Ping(n)
+		d.bootnodes.record(n, start, time.Since(start), err)
+	}
+}
+
+// SetBootnodes replaces the bootnodes whose health is checked, pinging the new ones right away.
+// The discv5 table is only seeded from the bootnodes given at start, so the new bootnodes are
+// used to discover nodes after a restart
+func (d *Discovery5) SetBootnodes(nodes []*ethenode.Node) {
+	added := d.bootnodes.set(nodes)
+	log.WithFields(log.Fields{
+		"bootnodes": len(nodes),
+		"new":       len(added),
+	}).Info("updated the tracked discv5 bootnodes")
+	go d.pingBootnodes(added)
+}
+
+// BootnodeStatuses returns the dial health of the bootnodes
+func (d *Discovery5) BootnodeStatuses() []BootnodeStatus {
+	return d.bootnodes.snapshot()
+}
+
+// ParseBootnodes parses the ENRs (or enodes) of the given bootnodes
+func ParseBootnodes(bNodes []string) ([]*ethenode.Node, error) {
+	bootNodeList := make([]*ethenode.Node, 0, len(bNodes))
+	for _, element := range bNodes {
+		n, err := ethenode.Parse(ethenode.ValidSchemes, element)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse bootnode "+element)
+		}
+		bootNodeList = append(bootNodeList, n)
+	}
+	return bootNodeList, nil
+}
@@ -0,0 +1,59 @@
+package dv5
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	ethenode "github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func testBootnode(t *testing.T) *ethenode.Node {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return ethenode.NewV4(&key.PublicKey, net.IPv4(10, 0, 0, 1), 9000, 9000)
+}
+
+func Test_BootnodeHealth(t *testing.T) {
+	a, b, c := testBootnode(t), testBootnode(t), testBootnode(t)
+	h := newBootnodeHealth([]*ethenode.Node{a, b})
+
+	now := time.Now()
+	h.record(a, now, 20*time.Millisecond, nil)
+	h.record(b, now, time.Second, errors.New("RPC timeout"))
+	h.record(a, now.Add(time.Minute), 0, errors.New("RPC timeout"))
+
+	statuses := h.snapshot()
+	require.Len(t, statuses, 2)
+	require.False(t, statuses[0].Up)
+	require.Equal(t, int64(1), statuses[0].Successes)
+	require.Equal(t, int64(1), statuses[0].Failures)
+	require.Equal(t, 20*time.Millisecond, statuses[0].LastRTT)
+	require.Equal(t, "RPC timeout", statuses[0].LastError)
+	require.Equal(t, int64(1), statuses[1].Failures)
+
+	// the health of the bootnodes that are kept on a reload isn't lost
+	added := h.set([]*ethenode.Node{a, c})
+	require.Equal(t, []*ethenode.Node{c}, added)
+	statuses = h.snapshot()
+	require.Len(t, statuses, 2)
+	require.Equal(t, int64(1), statuses[0].Successes)
+	require.Equal(t, c.String(), statuses[1].ENR)
+
+	// the pings of removed bootnodes are dropped
+	h.record(b, now, 0, nil)
+	require.Len(t, h.snapshot(), 2)
+}
+
+func Test_ParseBootnodes(t *testing.T) {
+	n := testBootnode(t)
+	nodes, err := ParseBootnodes([]string{n.String()})
+	require.NoError(t, err)
+	require.Equal(t, n.ID(), nodes[0].ID())
+
+	_, err = ParseBootnodes([]string{"enr:-invalid"})
+	require.Error(t, err)
+}
@@ -11,9 +11,11 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"io/ioutil"
+	"math/rand"
 	"net"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -48,7 +50,23 @@ type Discovery5 struct {
 	doneF    bool
 
 	// Filtering
-	FilterDigest string
+	FilterDigest    string
+	allowedDigests  map[string]struct{}
+	storeMismatched bool
+	fm              sync.Mutex
+	filtered        map[string]int64
+
+	// how the DHT is walked
+	strategy      Strategy
+	distances     []int
+	targetDigests map[string]struct{}
+	rng           *rand.Rand
+	stats         *strategyStats
+
+	// health of the bootnodes
+	bootnodes    *bootnodeHealth
+	pingInterval time.Duration
+	doneC        chan struct{}
 }
 
 // NewDiscovery
@@ -58,7 +76,8 @@ func NewDiscovery5(
 	privkey *ecdsa.PrivateKey,
 	bootnodes []*ethenode.Node,
 	fdigest string,
-	port int) (*Discovery5, error) {
+	port int,
+	opts ...Discovery5Option) (*Discovery5, error) {
 
 	log.Infof("launching discovery5 at fork %s", fdigest)
 
@@ -98,23 +117,42 @@ func NewDiscovery5(
 	}
 
 	// return the Discovery object
-	return &Discovery5{
-		ctx:          ctx,
-		Node:         node,
-		Dv5Listener:  dv5Listener,
-		FilterDigest: fdigest,
-		nodeNotC:     make(chan *models.HostInfo),
-		doneF:        false,
-	}, nil
+	disc := &Discovery5{
+		ctx:           ctx,
+		Node:          node,
+		Dv5Listener:   dv5Listener,
+		FilterDigest:  fdigest,
+		nodeNotC:      make(chan *models.HostInfo),
+		doneF:         false,
+		strategy:      RandomStrategy,
+		distances:     DefaultBucketDistances,
+		targetDigests: map[string]struct{}{fdigest: {}},
+		rng:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		stats:         newStrategyStats(),
+		filtered:      make(map[string]int64),
+		bootnodes:     newBootnodeHealth(bootnodes),
+		pingInterval:  DefaultBootnodePingInterval,
+		doneC:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		err := opt(disc)
+		if err != nil {
+			dv5Listener.Close()
+			return nil, errors.Wrap(err, "unable to apply discv5 option")
+		}
+	}
+	log.Infof("walking the discv5 DHT with the %s strategy", disc.strategy)
+	return disc, nil
 }
 
 // Start
 func (d *Discovery5) Start() chan *models.HostInfo {
 	// Generate the iterator over the foud peers
-	d.Iterator = d.Dv5Listener.RandomNodes()
+	d.Iterator = d.newStrategyIterator()
 
-	d.wg.Add(1)
+	d.wg.Add(2)
 	go d.nodeIterator()
+	go d.bootnodeHealthCheck()
 
 	return d.nodeNotC
 }
@@ -131,6 +169,7 @@ func (d *Discovery5) nodeIterator() {
 		if d.Iterator.Next() {
 			// fill the given DiscoveredPeer interface with the next found peer
 			node := d.Iterator.Node()
+			d.stats.addNode(node.ID())
 			log.WithFields(log.Fields{
 				"enr":     node.String(),
 				"node_id": node.ID().String(),
@@ -149,9 +188,20 @@ func (d *Discovery5) nodeIterator() {
 	}
 }
 
+// Stats returns the cost and the coverage of the strategy used to walk the DHT
+func (d *Discovery5) Stats() StrategyStats {
+	return d.stats.snapshot()
+}
+
+// Strategy returns the strategy used to walk the DHT
+func (d *Discovery5) Strategy() Strategy {
+	return d.strategy
+}
+
 // Stop closes the Disv5 node iterator properly :)
 func (d *Discovery5) Stop() {
 	d.doneF = true
+	close(d.doneC)
 	d.wg.Wait()
 
 	d.Iterator.Close()
@@ -167,10 +217,15 @@ func (d *Discovery5) handleENR(node *ethenode.Node) (*models.HostInfo, error) {
 		return nil, errors.Wrap(err, "unable to parse new discovered ENR")
 	}
 
-	// check if there is any fork digest filter only if the flag All is not set
-	if enr.Eth2Data.ForkDigest.String() != d.FilterDigest && d.FilterDigest != eth.ForkDigests[eth.AllForkDigest] {
-		log.Tracef("new node discovered - wrong fork %s - looking for %s", enr.Eth2Data.ForkDigest.String(), d.FilterDigest)
-		return nil, ErrorNotValidNode
+	// nodes of other forks are discarded, unless they have to be stored anyways
+	forkDigest := enr.Eth2Data.ForkDigest.String()
+	allowed := d.isAllowedDigest(forkDigest)
+	if !allowed {
+		d.addFiltered(forkDigest)
+		if !d.storeMismatched {
+			log.Tracef("new node discovered - wrong fork %s - looking for %s", forkDigest, d.FilterDigest)
+			return nil, ErrorNotValidNode
+		}
 	}
 
 	// Generate the peer ID from the pubkey
@@ -179,19 +234,58 @@ func (d *Discovery5) handleENR(node *ethenode.Node) (*models.HostInfo, error) {
 		return &models.HostInfo{}, errors.Wrap(err, "unable to convert Geth pubkey to Libp2p")
 	}
 	// gen the HostInfo
-	hInfo := models.NewHostInfo(
-		peerID,
-		utils.EthereumNetwork,
+	hOpts := []models.RemoteHostOptions{
 		models.WithIPAndPorts(
 			enr.IP.String(),
 			enr.TCP,
 		),
-	)
+		models.WithOrigin(models.DiscoveredOrigin),
+	}
+	// the nodes of other forks are stored, but never dialed
+	if !allowed {
+		hOpts = append(hOpts, models.WithNotDialable())
+	}
+	hInfo := models.NewHostInfo(peerID, utils.EthereumNetwork, hOpts...)
 	// add the enr as an attribute
 	hInfo.AddAtt(eth.EnrHostInfoAttribute, enr)
 	return hInfo, nil
 }
 
+// isAllowedDigest checks the fork digest of a discovered node against the fork digest filter:
+// - the fork strategy already picked the nodes of the targeted fork digests
+// - the allowlist (if any) replaces the fork digest of the crawler
+// - otherwise, only the fork digest of the crawler is allowed (all of them with the All flag)
+// The fork digest is computed from the fork version and the genesis validators root, so it
+// also tells apart the testnets that share the fork versions of a different deposit contract
+func (d *Discovery5) isAllowedDigest(forkDigest string) bool {
+	if d.strategy == ForkStrategy {
+		_, targeted := d.targetDigests[forkDigest]
+		return targeted
+	}
+	if len(d.allowedDigests) > 0 {
+		_, allowed := d.allowedDigests[forkDigest]
+		return allowed
+	}
+	return forkDigest == d.FilterDigest || d.FilterDigest == eth.ForkDigests[eth.AllForkDigest]
+}
+
+func (d *Discovery5) addFiltered(forkDigest string) {
+	d.fm.Lock()
+	defer d.fm.Unlock()
+	d.filtered[forkDigest]++
+}
+
+// FilteredNodes returns the number of discovered nodes that didn't pass the fork digest filter, by fork digest
+func (d *Discovery5) FilteredNodes() map[string]int64 {
+	d.fm.Lock()
+	defer d.fm.Unlock()
+	filtered := make(map[string]int64, len(d.filtered))
+	for digest, cnt := range d.filtered {
+		filtered[digest] = cnt
+	}
+	return filtered
+}
+
 func ParseBootnodesFromStringSlice(bNodes []string) []*ethenode.Node {
 	// where we will store the result
 	bootNodeList := make([]*ethenode.Node, 0)
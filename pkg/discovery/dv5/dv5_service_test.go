@@ -2,6 +2,8 @@ package dv5
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestReadJSON(t *testing.T) {
@@ -12,3 +14,22 @@ func TestReadJSON(t *testing.T) {
 	// require.Equal(t, dv5.GetBootNodeList()[0].String(), "enr:-KG4QOtcP9X1FbIMOe17QNMKqDxCpm14jcX5tiOE4_TyMrFqbmhPZHK_ZPG2Gxb1GE2xdtodOfx9-cgvNtxnRyHEmC0ghGV0aDKQ9aX9QgAAAAD__________4JpZIJ2NIJpcIQDE8KdiXNlY3AyNTZrMaEDhpehBDbZjM_L9ek699Y7vhUJ-eAdMyQW_Fil522Y0fODdGNwgiMog3VkcIIjKA")
 
 }
+
+func Test_ForkDigestFilter(t *testing.T) {
+	d := &Discovery5{
+		FilterDigest:  "0xbba4da96",
+		strategy:      RandomStrategy,
+		targetDigests: map[string]struct{}{"0xbba4da96": {}},
+	}
+	require.True(t, d.isAllowedDigest("0xbba4da96"))
+	require.False(t, d.isAllowedDigest("0x6a95a1a9"))
+
+	// the allowlist replaces the fork digest of the crawler
+	require.NoError(t, WithForkDigestFilter([]string{"0x6A95A1A9", "01020304"}, true)(d))
+	require.True(t, d.isAllowedDigest("0x6a95a1a9"))
+	require.True(t, d.isAllowedDigest("0x01020304"))
+	require.False(t, d.isAllowedDigest("0xbba4da96"))
+	require.True(t, d.storeMismatched)
+
+	require.Error(t, WithForkDigestFilter([]string{"0x0102"}, false)(d))
+}
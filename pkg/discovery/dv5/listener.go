@@ -0,0 +1,50 @@
+package dv5
+
+import (
+	"crypto/ecdsa"
+	"net"
+
+	"github.com/pkg/errors"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	ethenode "github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// listenV5 starts a discv5 listener at the given UDP port with a local node that is only kept
+// in memory, for the discoveries of the networks that don't have a local node of their own
+func listenV5(privkey *ecdsa.PrivateKey, bootnodes []*ethenode.Node, port int) (*ethenode.LocalNode, *discover.UDPv5, error) {
+	db, err := ethenode.OpenDB("")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to open the enode db")
+	}
+	localNode := ethenode.NewLocalNode(db, privkey)
+
+	// udp address to listen
+	udpAddr := &net.UDPAddr{
+		IP:   net.IPv4zero,
+		Port: port,
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		db.Close()
+		return nil, nil, errors.Wrap(err, "unable to listen for discv5")
+	}
+	localNode.SetFallbackIP(net.IPv4(127, 0, 0, 1))
+	localNode.SetFallbackUDP(port)
+
+	// configuration of the discovery5
+	cfg := discover.Config{
+		PrivateKey:   privkey,
+		Bootnodes:    bootnodes,
+		Log:          gethlog.New(),
+		ValidSchemes: ethenode.ValidSchemes,
+	}
+	dv5Listener, err := discover.ListenV5(conn, localNode, cfg)
+	if err != nil {
+		conn.Close()
+		db.Close()
+		return nil, nil, errors.Wrap(err, "unable to start discv5")
+	}
+	return localNode, dv5Listener, nil
+}
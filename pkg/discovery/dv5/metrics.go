@@ -0,0 +1,179 @@
+package dv5
+
+import (
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	modName    = "dv5"
+	modDetails = "cost and coverage of the discv5 strategy"
+)
+
+// dv5Metrics are the collectors of the metrics of the discv5 strategy, one set per crawler
+type dv5Metrics struct {
+	StrategyLookups     *prometheus.GaugeVec
+	StrategyNodes       *prometheus.GaugeVec
+	StrategyUniqueNodes *prometheus.GaugeVec
+	FilteredNodes       *prometheus.GaugeVec
+	BootnodeUp          *prometheus.GaugeVec
+	BootnodePings       *prometheus.GaugeVec
+	BootnodeRTT         *prometheus.GaugeVec
+}
+
+func newDv5Metrics() *dv5Metrics {
+	return &dv5Metrics{
+		StrategyLookups: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "strategy_lookups",
+			Help:      "Number of targeted lookups performed by the discv5 strategy",
+		},
+			[]string{"strategy"},
+		),
+		StrategyNodes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "strategy_nodes",
+			Help:      "Number of nodes returned by the discv5 strategy",
+		},
+			[]string{"strategy"},
+		),
+		StrategyUniqueNodes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "strategy_unique_nodes",
+			Help:      "Number of different nodes returned by the discv5 strategy",
+		},
+			[]string{"strategy"},
+		),
+		FilteredNodes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "filtered_nodes",
+			Help:      "Number of discovered nodes that didn't pass the fork digest filter, by fork digest",
+		},
+			[]string{"fork_digest"},
+		),
+		BootnodeUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "bootnode_up",
+			Help:      "Whether the bootnode answered the last discv5 ping (1) or not (0)",
+		},
+			[]string{"bootnode"},
+		),
+		BootnodePings: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "bootnode_pings",
+			Help:      "Number of discv5 pings to the bootnode, by result",
+		},
+			[]string{"bootnode", "result"},
+		),
+		BootnodeRTT: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "bootnode_rtt_seconds",
+			Help:      "Round-trip time of the last answered discv5 ping to the bootnode",
+		},
+			[]string{"bootnode"},
+		),
+	}
+}
+
+func (d *Discovery5) GetMetrics() *metrics.MetricsModule {
+	metricsMod := metrics.NewMetricsModule(
+		modName,
+		modDetails,
+	)
+	m := newDv5Metrics()
+	metricsMod.AddIndvMetric(d.strategyMetrics(m))
+	metricsMod.AddIndvMetric(d.filteredMetrics(m))
+	metricsMod.AddIndvMetric(d.bootnodeMetrics(m))
+	return metricsMod
+}
+
+func (d *Discovery5) strategyMetrics(m *dv5Metrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.StrategyLookups)
+		reg.MustRegister(m.StrategyNodes)
+		reg.MustRegister(m.StrategyUniqueNodes)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		stats := d.Stats()
+		strategy := string(d.Strategy())
+		m.StrategyLookups.WithLabelValues(strategy).Set(float64(stats.Lookups))
+		m.StrategyNodes.WithLabelValues(strategy).Set(float64(stats.Nodes))
+		m.StrategyUniqueNodes.WithLabelValues(strategy).Set(float64(stats.UniqueNodes))
+		return stats, nil
+	}
+
+	strategyStats, err := metrics.NewIndvMetrics(
+		"strategy_stats",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return strategyStats
+}
+
+func (d *Discovery5) filteredMetrics(m *dv5Metrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.FilteredNodes)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		filtered := d.FilteredNodes()
+		for digest, cnt := range filtered {
+			m.FilteredNodes.WithLabelValues(digest).Set(float64(cnt))
+		}
+		return filtered, nil
+	}
+
+	filteredNodes, err := metrics.NewIndvMetrics(
+		"filtered_nodes",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return filteredNodes
+}
+
+func (d *Discovery5) bootnodeMetrics(m *dv5Metrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.BootnodeUp)
+		reg.MustRegister(m.BootnodePings)
+		reg.MustRegister(m.BootnodeRTT)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		statuses := d.BootnodeStatuses()
+		// the bootnodes can change on a reload
+		m.BootnodeUp.Reset()
+		m.BootnodePings.Reset()
+		m.BootnodeRTT.Reset()
+		for _, s := range statuses {
+			up := 0.0
+			if s.Up {
+				up = 1
+			}
+			m.BootnodeUp.WithLabelValues(s.Bootnode).Set(up)
+			m.BootnodePings.WithLabelValues(s.Bootnode, "success").Set(float64(s.Successes))
+			m.BootnodePings.WithLabelValues(s.Bootnode, "failure").Set(float64(s.Failures))
+			m.BootnodeRTT.WithLabelValues(s.Bootnode).Set(s.LastRTT.Seconds())
+		}
+		return statuses, nil
+	}
+
+	bootnodeHealth, err := metrics.NewIndvMetrics(
+		"bootnode_health",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return bootnodeHealth
+}
@@ -0,0 +1,143 @@
+package dv5
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/portal"
+)
+
+type Discovery5Option func(*Discovery5) error
+
+// WithStrategy sets how the DHT is walked to discover new nodes
+func WithStrategy(strategy Strategy) Discovery5Option {
+	return func(d *Discovery5) error {
+		if _, err := ParseStrategy(string(strategy)); err != nil {
+			return err
+		}
+		d.strategy = strategy
+		return nil
+	}
+}
+
+// WithBucketDistances sets the log-distances that the buckets strategy looks up
+func WithBucketDistances(distances []int) Discovery5Option {
+	return func(d *Discovery5) error {
+		if len(distances) == 0 {
+			return errors.New("no log-distances for the buckets strategy")
+		}
+		d.distances = distances
+		return nil
+	}
+}
+
+// WithBootnodePingInterval sets the interval between the pings that check the health of the bootnodes
+func WithBootnodePingInterval(interval time.Duration) Discovery5Option {
+	return func(d *Discovery5) error {
+		if interval <= 0 {
+			return errors.New("bootnode ping interval has to be positive")
+		}
+		d.pingInterval = interval
+		return nil
+	}
+}
+
+// WithTargetForkDigests sets the fork digests that the fork strategy looks for in the ENRs
+func WithTargetForkDigests(digests []string) Discovery5Option {
+	return func(d *Discovery5) error {
+		if len(digests) == 0 {
+			return errors.New("no fork digests for the fork strategy")
+		}
+		d.targetDigests = make(map[string]struct{}, len(digests))
+		for _, digest := range digests {
+			d.targetDigests[digest] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// WithForkDigestFilter sets the fork digests of the nodes that are notified (the fork digest
+// of the crawler if none is given), and whether the nodes of other forks are stored without being dialed
+func WithForkDigestFilter(allowlist []string, storeMismatched bool) Discovery5Option {
+	return func(d *Discovery5) error {
+		d.allowedDigests = make(map[string]struct{}, len(allowlist))
+		for _, digest := range allowlist {
+			validDigest, err := parseForkDigest(digest)
+			if err != nil {
+				return err
+			}
+			d.allowedDigests[validDigest] = struct{}{}
+		}
+		d.storeMismatched = storeMismatched
+		return nil
+	}
+}
+
+// WithSeed seeds the targets of the walk, so that the exploration can be replayed
+func WithSeed(seed int64) Discovery5Option {
+	return func(d *Discovery5) error {
+		d.rng = rand.New(rand.NewSource(seed))
+		return nil
+	}
+}
+
+// parseForkDigest accepts the names of the known forks, or the hex representation of any
+// fork digest, returning it as it is read from the ENRs (0x prefixed)
+func parseForkDigest(digest string) (string, error) {
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if known, ok := eth.CheckValidForkDigest(digest); ok && strings.HasPrefix(known, "0x") {
+		return known, nil
+	}
+	digestBytes, err := hex.DecodeString(strings.TrimPrefix(digest, "0x"))
+	if err != nil || len(digestBytes) != 4 {
+		return "", errors.Errorf("invalid fork digest %s", digest)
+	}
+	return "0x" + hex.EncodeToString(digestBytes), nil
+}
+
+type PortalOption func(*PortalDiscovery) error
+
+// WithPortalSubNetworks sets the sub-networks where the nodes are pinged (all of them by default)
+func WithPortalSubNetworks(names []string) PortalOption {
+	return func(d *PortalDiscovery) error {
+		if len(names) == 0 {
+			return nil
+		}
+		subNetworks := make([]portal.SubNetwork, 0, len(names))
+		for _, name := range names {
+			sub, ok := portal.SubNetworkByName(name)
+			if !ok {
+				return errors.Errorf("unknown portal sub-network %s", name)
+			}
+			subNetworks = append(subNetworks, sub)
+		}
+		d.subNetworks = subNetworks
+		return nil
+	}
+}
+
+// WithPortalUserAgent sets the client info advertised in our pings
+func WithPortalUserAgent(userAgent string) PortalOption {
+	return func(d *PortalDiscovery) error {
+		d.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithPortalPingSettings sets the number of nodes pinged concurrently, and how long a node is not
+// pinged again after being crawled
+func WithPortalPingSettings(workers int, recrawlInterval time.Duration) PortalOption {
+	return func(d *PortalDiscovery) error {
+		if workers <= 0 || recrawlInterval < 0 {
+			return errors.Errorf("invalid ping settings (workers %d, recrawl interval %s)", workers, recrawlInterval)
+		}
+		d.workers = workers
+		d.recrawlInterval = recrawlInterval
+		return nil
+	}
+}
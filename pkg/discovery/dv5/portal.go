@@ -0,0 +1,280 @@
+package dv5
+
+/**
+This file implements the discovery of the Portal Network nodes: the discv5 DHT is walked as
+for the Ethereum CL, and each discovered node is pinged over TALKREQ in every Portal sub-network
+to know which ones it supports, the radius it advertises in them, and its client.
+
+*/
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/portal"
+	"github.com/migalabs/armiarma/pkg/utils"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	ethenode "github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var (
+	ErrorNotPortalNode error = errors.New("not a portal node - no sub-network answered")
+
+	DefaultPortalWorkers         = 50
+	DefaultPortalRecrawlInterval = 30 * time.Minute
+
+	// attribute of the HostInfo with the result of the pings
+	PingAttemptAttribute string = "portal-ping-attempt"
+)
+
+// PortalDiscovery walks the discv5 DHT identifying the Portal Network nodes
+type PortalDiscovery struct {
+	// Service control variables
+	ctx context.Context
+
+	LocalNode   *ethenode.LocalNode
+	Dv5Listener *discover.UDPv5
+	Iterator    ethenode.Iterator
+
+	// node notifier
+	nodeNotC chan *models.HostInfo
+	wg       sync.WaitGroup
+	doneF    bool
+
+	// sub-networks where the nodes are pinged
+	subNetworks     []portal.SubNetwork
+	userAgent       string
+	workers         int
+	recrawlInterval time.Duration
+
+	m    sync.Mutex
+	seen map[ethenode.ID]time.Time
+}
+
+// NewPortalDiscovery
+func NewPortalDiscovery(
+	ctx context.Context,
+	privkey *ecdsa.PrivateKey,
+	bootnodes []*ethenode.Node,
+	port int,
+	opts ...PortalOption) (*PortalDiscovery, error) {
+
+	if len(bootnodes) == 0 {
+		return nil, errors.New("unable to start portal peer discovery, no bootnodes provided")
+	}
+
+	disc := &PortalDiscovery{
+		ctx:             ctx,
+		nodeNotC:        make(chan *models.HostInfo),
+		subNetworks:     portal.SubNetworks,
+		workers:         DefaultPortalWorkers,
+		recrawlInterval: DefaultPortalRecrawlInterval,
+		seen:            make(map[ethenode.ID]time.Time),
+	}
+	for _, opt := range opts {
+		err := opt(disc)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply portal option")
+		}
+	}
+
+	localNode, dv5Listener, err := listenV5(privkey, bootnodes, port)
+	if err != nil {
+		return nil, err
+	}
+	disc.LocalNode = localNode
+	disc.Dv5Listener = dv5Listener
+
+	// answer the pings of the nodes that add us to their tables, so that they keep us there
+	for _, sub := range disc.subNetworks {
+		dv5Listener.RegisterTalkHandler(sub.ProtocolID, disc.handlePing)
+	}
+
+	log.Infof("launching portal discovery at port %d", port)
+	return disc, nil
+}
+
+// Start
+func (d *PortalDiscovery) Start() chan *models.HostInfo {
+	// Generate the iterator over the found nodes
+	d.Iterator = d.Dv5Listener.RandomNodes()
+
+	// each node is pinged in every sub-network, so the pings are performed by a pool of workers
+	nodeC := make(chan *ethenode.Node, d.workers)
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.pingWorker(nodeC)
+	}
+
+	d.wg.Add(1)
+	go d.nodeIterator(nodeC)
+
+	return d.nodeNotC
+}
+
+func (d *PortalDiscovery) nodeIterator(nodeC chan *ethenode.Node) {
+	defer d.wg.Done()
+	defer close(nodeC)
+
+	for {
+		if d.doneF || d.ctx.Err() != nil {
+			log.Info("shutdown detected, closing portal iterator")
+			return
+		}
+
+		if d.Iterator.Next() {
+			node := d.Iterator.Node()
+			if !d.shouldCrawl(node.ID()) {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"enr":     node.String(),
+				"node_id": node.ID().String(),
+				"module":  "Portal",
+			}).Debug("new ENR discovered")
+
+			select {
+			case nodeC <- node:
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (d *PortalDiscovery) pingWorker(nodeC chan *ethenode.Node) {
+	defer d.wg.Done()
+
+	for node := range nodeC {
+		hInfo, err := d.handleNode(node)
+		if err != nil {
+			if err != ErrorNotPortalNode { // most of the discv5 nodes are from the Ethereum CL
+				log.Debug(errors.Wrap(err, "error handling new node"))
+			}
+			continue
+		}
+		select {
+		case d.nodeNotC <- hInfo:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// shouldCrawl checks that the node wasn't crawled within the recrawl interval
+func (d *PortalDiscovery) shouldCrawl(id ethenode.ID) bool {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if last, ok := d.seen[id]; ok && time.Since(last) < d.recrawlInterval {
+		return false
+	}
+	d.seen[id] = time.Now()
+	return true
+}
+
+func (d *PortalDiscovery) Stop() {
+	d.doneF = true
+	d.Iterator.Close()
+	d.wg.Wait()
+
+	d.Dv5Listener.Close()
+	d.LocalNode.Database().Close()
+	close(d.nodeNotC)
+}
+
+// handlePing answers the pings of the wire protocol with a null radius
+func (d *PortalDiscovery) handlePing(id ethenode.ID, addr *net.UDPAddr, msg []byte) []byte {
+	if !portal.IsPing(msg) {
+		return nil
+	}
+	return portal.EncodePong(d.LocalNode.Seq())
+}
+
+// handleNode pings the node in each of the sub-networks, identifying the Portal nodes
+func (d *PortalDiscovery) handleNode(node *ethenode.Node) (*models.HostInfo, error) {
+	// Parse ENR
+	enr, err := eth.ParseEnr(node)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse new discovered ENR")
+	}
+	// Generate the peer ID from the pubkey
+	peerID, err := enr.GetPeerID()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to convert Geth pubkey to Libp2p")
+	}
+
+	portalNode := portal.NewPortalNode(peerID, node)
+	ping := portal.EncodePing(d.LocalNode.Seq(), d.userAgent)
+	var rtt time.Duration
+	errs := make([]string, 0)
+	for _, sub := range d.subNetworks {
+		start := time.Now()
+		resp, err := d.Dv5Listener.TalkRequest(node, sub.ProtocolID, ping)
+		if err != nil {
+			errs = append(errs, sub.Name+": "+err.Error())
+			// don't wait for the timeouts of the rest of the sub-networks if the node never answered
+			if rtt == 0 {
+				break
+			}
+			continue
+		}
+		if rtt == 0 {
+			rtt = time.Since(start)
+		}
+		pong, err := portal.DecodePong(resp)
+		if err != nil {
+			// the nodes answer with an empty TALKRESP the protocols they don't support
+			if len(resp) > 0 {
+				errs = append(errs, sub.Name+": "+err.Error())
+			}
+			continue
+		}
+		portalNode.AddPong(sub.Name, pong)
+	}
+	// only the nodes that advertise their client in the ENR are kept if no sub-network answered
+	if len(portalNode.SubNetworks) == 0 && portalNode.EnrClient == "" {
+		return nil, ErrorNotPortalNode
+	}
+
+	// gen the HostInfo (the Portal nodes are reached over UDP)
+	hInfo := models.NewHostInfo(
+		peerID,
+		utils.PortalNetwork,
+		models.WithIPAndPorts(
+			enr.IP.String(),
+			enr.UDP,
+		),
+		models.WithOrigin(models.DiscoveredOrigin),
+	)
+	// add the enr and the sub-networks as attributes
+	hInfo.AddAtt(eth.EnrHostInfoAttribute, enr)
+	hInfo.AddAtt(portal.PortalNodeAttribute, portalNode)
+
+	// the clients that don't answer are still identified by their ENR client entry
+	if userAgent := portalNode.UserAgent(); userAgent != "" {
+		hInfo.IdentifyHost(models.NewPeerInfo(
+			peerID,
+			userAgent,
+			"portal-wire",
+			portalNode.Protocols(),
+			rtt,
+		))
+	}
+	if len(portalNode.SubNetworks) == 0 {
+		hInfo.AddAtt(PingAttemptAttribute, models.NewConnAttempt(peerID, models.NegativeAttempt, strings.Join(errs, "; "), false, false))
+		return hInfo, nil
+	}
+	hInfo.AddAtt(PingAttemptAttribute, models.NewConnAttempt(peerID, models.PossitiveAttempt, hosts.NoConnError, false, false))
+	return hInfo, nil
+}
@@ -0,0 +1,68 @@
+package dv5
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	gcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	ethenode "github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/networks/portal"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// servePortalNode starts a discv5 node that only supports the history sub-network
+func servePortalNode(t *testing.T) *ethenode.Node {
+	key, err := gcrypto.GenerateKey()
+	require.NoError(t, err)
+	db, err := ethenode.OpenDB("")
+	require.NoError(t, err)
+	localNode := ethenode.NewLocalNode(db, key)
+	localNode.Set(enr.WithEntry(portal.ClientEnrKey, "t 0.1.0"))
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	localNode.SetStaticIP(net.IPv4(127, 0, 0, 1))
+	localNode.SetFallbackUDP(conn.LocalAddr().(*net.UDPAddr).Port)
+	listener, err := discover.ListenV5(conn, localNode, discover.Config{PrivateKey: key})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+		db.Close()
+	})
+
+	history, _ := portal.SubNetworkByName("history")
+	listener.RegisterTalkHandler(history.ProtocolID, func(ethenode.ID, *net.UDPAddr, []byte) []byte {
+		return portal.EncodePong(localNode.Seq())
+	})
+	return localNode.Node()
+}
+
+func Test_PortalNode(t *testing.T) {
+	remote := servePortalNode(t)
+	key, err := gcrypto.GenerateKey()
+	require.NoError(t, err)
+	disc, err := NewPortalDiscovery(context.Background(), key, []*ethenode.Node{remote}, 0,
+		WithPortalUserAgent("armiarma"),
+		WithPortalSubNetworks([]string{"state", "history"}),
+	)
+	require.NoError(t, err)
+	defer func() {
+		disc.Dv5Listener.Close()
+		disc.LocalNode.Database().Close()
+	}()
+
+	hInfo, err := disc.handleNode(remote)
+	require.NoError(t, err)
+	require.Equal(t, utils.PortalNetwork, hInfo.Network)
+	require.Equal(t, "trin/0.1.0", hInfo.PeerInfo.UserAgent)
+	require.Equal(t, []string{"history"}, hInfo.PeerInfo.Protocols)
+	portalNode := hInfo.Attr[portal.PortalNodeAttribute].(*portal.PortalNode)
+	require.Len(t, portalNode.SubNetworks, 1)
+	require.Zero(t, portalNode.SubNetworks[0].Radius.Sign())
+
+	require.Error(t, WithPortalSubNetworks([]string{"gossip"})(disc))
+}
@@ -0,0 +1,209 @@
+package dv5
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/pkg/errors"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+// Strategy defines how the discv5 DHT is walked to discover new nodes
+type Strategy string
+
+const (
+	// RandomStrategy walks the DHT looking up random targets
+	RandomStrategy Strategy = "random"
+	// BucketsStrategy looks up targets at the given log-distances from our node,
+	// FINDNODE-ing the buckets of those distances in the nodes of the walk
+	BucketsStrategy Strategy = "buckets"
+	// SweepStrategy looks up targets at decreasing log-distances (256, 255, ...),
+	// starting over once a distance doesn't return any node
+	SweepStrategy Strategy = "sweep"
+	// ForkStrategy walks the DHT randomly, but only keeps the nodes whose ENR advertises one of the fork digests
+	ForkStrategy Strategy = "fork"
+)
+
+const (
+	maxDistance = 256
+)
+
+var DefaultBucketDistances = []int{256, 255, 254, 253, 252, 251, 250, 249}
+
+// ParseStrategy checks that the given strategy is supported
+func ParseStrategy(s string) (Strategy, error) {
+	switch strategy := Strategy(strings.ToLower(s)); strategy {
+	case RandomStrategy, BucketsStrategy, SweepStrategy, ForkStrategy:
+		return strategy, nil
+	default:
+		return "", errors.Errorf("unknown discv5 strategy %s (random, buckets, sweep, fork)", s)
+	}
+}
+
+// ParseDistances parses a comma separated list of log-distances (e.g. "256,255,254")
+func ParseDistances(s string) ([]int, error) {
+	distances := make([]int, 0)
+	for _, str := range strings.Split(s, ",") {
+		str = strings.TrimSpace(str)
+		if str == "" {
+			continue
+		}
+		d, err := strconv.Atoi(str)
+		if err != nil || d < 1 || d > maxDistance {
+			return nil, errors.Errorf("invalid log-distance %s, it has to be between [1, %d]", str, maxDistance)
+		}
+		distances = append(distances, d)
+	}
+	return distances, nil
+}
+
+// StrategyStats accounts the cost and the coverage of the strategy
+type StrategyStats struct {
+	Lookups     int64 // targeted lookups performed (the random walk doesn't account them)
+	Nodes       int64 // nodes returned by the walk
+	UniqueNodes int64 // different nodes returned by the walk
+}
+
+type strategyStats struct {
+	lookups int64
+	nodes   int64
+
+	m    sync.Mutex
+	seen map[enode.ID]struct{}
+}
+
+func newStrategyStats() *strategyStats {
+	return &strategyStats{
+		seen: make(map[enode.ID]struct{}),
+	}
+}
+
+func (s *strategyStats) addNode(id enode.ID) {
+	atomic.AddInt64(&s.nodes, 1)
+	s.m.Lock()
+	s.seen[id] = struct{}{}
+	s.m.Unlock()
+}
+
+func (s *strategyStats) snapshot() StrategyStats {
+	s.m.Lock()
+	unique := int64(len(s.seen))
+	s.m.Unlock()
+	return StrategyStats{
+		Lookups:     atomic.LoadInt64(&s.lookups),
+		Nodes:       atomic.LoadInt64(&s.nodes),
+		UniqueNodes: unique,
+	}
+}
+
+// newStrategyIterator composes the node iterator of the given strategy
+func (d *Discovery5) newStrategyIterator() enode.Iterator {
+	self := d.Dv5Listener.Self().ID()
+	switch d.strategy {
+	case BucketsStrategy:
+		distances := d.distances
+		i := 0
+		return newTargetIterator(d.Dv5Listener.Lookup, d.stats, func([]*enode.Node) enode.ID {
+			target := idAtDistance(self, distances[i%len(distances)], d.rng)
+			i++
+			return target
+		})
+
+	case SweepStrategy:
+		distance := maxDistance + 1
+		return newTargetIterator(d.Dv5Listener.Lookup, d.stats, func(prev []*enode.Node) enode.ID {
+			// keep going deeper while the previous distance still had nodes
+			if distance <= maxDistance && !anyAtDistance(self, prev, distance) || distance <= 1 {
+				distance = maxDistance + 1
+			}
+			distance--
+			return idAtDistance(self, distance, d.rng)
+		})
+
+	case ForkStrategy:
+		return enode.Filter(d.Dv5Listener.RandomNodes(), func(n *enode.Node) bool {
+			eth2Data, ok, err := eth.ParseNodeEth2Data(*n)
+			if err != nil || !ok {
+				return false
+			}
+			_, target := d.targetDigests[eth2Data.ForkDigest.String()]
+			return target
+		})
+
+	default:
+		return d.Dv5Listener.RandomNodes()
+	}
+}
+
+// targetIterator is an enode.Iterator over the results of consecutive lookups of the given targets
+type targetIterator struct {
+	lookup     func(enode.ID) []*enode.Node
+	stats      *strategyStats
+	nextTarget func(prev []*enode.Node) enode.ID
+
+	buffer []*enode.Node
+	prev   []*enode.Node
+	cur    *enode.Node
+	closed int32
+}
+
+func newTargetIterator(lookup func(enode.ID) []*enode.Node, stats *strategyStats, nextTarget func([]*enode.Node) enode.ID) *targetIterator {
+	return &targetIterator{
+		lookup:     lookup,
+		stats:      stats,
+		nextTarget: nextTarget,
+	}
+}
+
+func (it *targetIterator) Next() bool {
+	for len(it.buffer) == 0 {
+		if atomic.LoadInt32(&it.closed) == 1 {
+			return false
+		}
+		atomic.AddInt64(&it.stats.lookups, 1)
+		it.buffer = it.lookup(it.nextTarget(it.prev))
+		it.prev = it.buffer
+	}
+	it.cur, it.buffer = it.buffer[0], it.buffer[1:]
+	return true
+}
+
+func (it *targetIterator) Node() *enode.Node {
+	return it.cur
+}
+
+func (it *targetIterator) Close() {
+	atomic.StoreInt32(&it.closed, 1)
+}
+
+// idAtDistance returns a random ID at the given log-distance from the given one
+func idAtDistance(id enode.ID, distance int, rng *rand.Rand) enode.ID {
+	if distance <= 0 {
+		return id
+	}
+	target := id
+	// the first differing bit (from the most significant one) sets the distance
+	bit := maxDistance - distance
+	target[bit/8] ^= 1 << uint(7-bit%8)
+	// the rest of the bits can take any value
+	for i := bit + 1; i < maxDistance; i++ {
+		if rng.Intn(2) == 1 {
+			target[i/8] ^= 1 << uint(7-i%8)
+		}
+	}
+	return target
+}
+
+func anyAtDistance(id enode.ID, nodes []*enode.Node, distance int) bool {
+	for _, n := range nodes {
+		if enode.LogDist(id, n.ID()) == distance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package dv5
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IdAtDistance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var self enode.ID
+	rng.Read(self[:])
+	for _, distance := range []int{256, 255, 240, 128, 9, 8, 1} {
+		target := idAtDistance(self, distance, rng)
+		require.Equal(t, distance, enode.LogDist(self, target))
+	}
+	require.Equal(t, self, idAtDistance(self, 0, rng))
+}
+
+func Test_ParseStrategyOptions(t *testing.T) {
+	strategy, err := ParseStrategy("Sweep")
+	require.NoError(t, err)
+	require.Equal(t, SweepStrategy, strategy)
+	_, err = ParseStrategy("topics")
+	require.Error(t, err)
+
+	distances, err := ParseDistances("256, 255,254")
+	require.NoError(t, err)
+	require.Equal(t, []int{256, 255, 254}, distances)
+	_, err = ParseDistances("257")
+	require.Error(t, err)
+}
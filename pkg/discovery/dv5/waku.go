@@ -0,0 +1,155 @@
+package dv5
+
+/**
+This file implements the discovery of the Waku v2 nodes: the discv5 DHT of Waku is walked as the
+one of the Ethereum CL, notifying the nodes whose ENR has the waku2 field, so that they get dialed
+and identified over libp2p.
+
+*/
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+	"github.com/migalabs/armiarma/pkg/utils"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	ethenode "github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var ErrorNotReachableNode error = errors.New("not reachable node - no tcp address in the ENR")
+
+// WakuDiscovery walks the discv5 DHT notifying the Waku nodes
+type WakuDiscovery struct {
+	// Service control variables
+	ctx context.Context
+
+	LocalNode   *ethenode.LocalNode
+	Dv5Listener *discover.UDPv5
+	Iterator    ethenode.Iterator
+
+	// node notifier
+	nodeNotC chan *models.HostInfo
+	wg       sync.WaitGroup
+	doneF    bool
+}
+
+// NewWakuDiscovery
+func NewWakuDiscovery(
+	ctx context.Context,
+	privkey *ecdsa.PrivateKey,
+	bootnodes []*ethenode.Node,
+	port int) (*WakuDiscovery, error) {
+
+	if len(bootnodes) == 0 {
+		return nil, errors.New("unable to start waku peer discovery, no bootnodes provided")
+	}
+
+	localNode, dv5Listener, err := listenV5(privkey, bootnodes, port)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("launching waku discovery at port %d", port)
+	return &WakuDiscovery{
+		ctx:         ctx,
+		LocalNode:   localNode,
+		Dv5Listener: dv5Listener,
+		nodeNotC:    make(chan *models.HostInfo),
+	}, nil
+}
+
+// Start
+func (d *WakuDiscovery) Start() chan *models.HostInfo {
+	// Generate the iterator over the found nodes
+	d.Iterator = d.Dv5Listener.RandomNodes()
+
+	d.wg.Add(1)
+	go d.nodeIterator()
+
+	return d.nodeNotC
+}
+
+func (d *WakuDiscovery) nodeIterator() {
+	defer d.wg.Done()
+
+	for {
+		if d.doneF || d.ctx.Err() != nil {
+			log.Info("shutdown detected, closing waku iterator")
+			return
+		}
+
+		if d.Iterator.Next() {
+			node := d.Iterator.Node()
+			log.WithFields(log.Fields{
+				"enr":     node.String(),
+				"node_id": node.ID().String(),
+				"module":  "Waku",
+			}).Debug("new ENR discovered")
+
+			hInfo, err := d.handleNode(node)
+			if err != nil {
+				if err != waku.ErrNotWakuNode {
+					log.Debug(errors.Wrap(err, "error handling new ENR"))
+				}
+				continue
+			}
+			select {
+			case d.nodeNotC <- hInfo:
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (d *WakuDiscovery) Stop() {
+	d.doneF = true
+	d.Iterator.Close()
+	d.wg.Wait()
+
+	d.Dv5Listener.Close()
+	d.LocalNode.Database().Close()
+	close(d.nodeNotC)
+}
+
+// handleNode composes the HostInfo of the Waku nodes, with the tcp address and the multiaddrs of their ENR
+func (d *WakuDiscovery) handleNode(node *ethenode.Node) (*models.HostInfo, error) {
+	// Parse ENR
+	enr, err := eth.ParseEnr(node)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse new discovered ENR")
+	}
+	// Generate the peer ID from the pubkey
+	peerID, err := enr.GetPeerID()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to convert Geth pubkey to Libp2p")
+	}
+	wakuNode, err := waku.ParseWakuNode(peerID, node)
+	if err != nil {
+		return nil, err
+	}
+
+	hostOpts := []models.RemoteHostOptions{
+		models.WithOrigin(models.DiscoveredOrigin),
+	}
+	if enr.IP != nil && enr.TCP != 0 {
+		hostOpts = append(hostOpts, models.WithIPAndPorts(enr.IP.String(), enr.TCP))
+	} else if len(wakuNode.Multiaddrs) == 0 {
+		return nil, ErrorNotReachableNode
+	}
+	// the nodes behind a NAT or the browser ones advertise their websocket and circuit-relay addresses
+	hostOpts = append(hostOpts, models.WithMultiaddress(wakuNode.Multiaddrs))
+	hInfo := models.NewHostInfo(peerID, utils.WakuNetwork, hostOpts...)
+	// add the enr and its waku entries as attributes
+	hInfo.AddAtt(eth.EnrHostInfoAttribute, enr)
+	hInfo.AddAtt(waku.WakuNodeAttribute, wakuNode)
+	return hInfo, nil
+}
@@ -1,49 +1,71 @@
 package discovery
 
 import (
+	"fmt"
+
 	"github.com/migalabs/armiarma/pkg/metrics"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
 	modName    = "discovery"
 	modDetails = "general metrics about the crawler"
-
-	// List of metrics that we are going to export
-	NodesPerForkDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "node_per_fork_distribution",
-		Help:      "Number of non-deprecated nodes per fork in the Ethereum network",
-	},
-		[]string{"fork"},
-	)
-	// List of metrics that we are going to export
-	AttnetsDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "attnets_distribution",
-		Help:      "Distribution of number of nodes subscribed to number of subnets",
-	},
-		[]string{"att_number"},
-	)
 )
 
+// ethMetrics are the collectors of the Ethereum metrics of the discovery, one set per crawler
+type ethMetrics struct {
+	NodesPerForkDistribution *prometheus.GaugeVec
+	AttnetsDistribution      *prometheus.GaugeVec
+	PeersPerSubnet           *prometheus.GaugeVec
+}
+
+func newEthMetrics() *ethMetrics {
+	return &ethMetrics{
+		NodesPerForkDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "node_per_fork_distribution",
+			Help:      "Number of non-deprecated nodes per fork in the Ethereum network",
+		},
+			[]string{"fork"},
+		),
+		AttnetsDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "attnets_distribution",
+			Help:      "Distribution of number of nodes subscribed to number of subnets",
+		},
+			[]string{"att_number"},
+		),
+		PeersPerSubnet: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "peers_per_subnet",
+			Help:      "Number of nodes advertising each attestation and sync committee subnet in their ENR",
+		},
+			[]string{"subnet_type", "subnet"},
+		),
+	}
+}
+
 func (d *Discovery) GetEthereumMetrics() *metrics.MetricsModule {
 
 	metricsMod := metrics.NewMetricsModule(
 		modName,
 		modDetails,
 	)
+	m := newEthMetrics()
 
 	// compose all the metrics
-	metricsMod.AddIndvMetric(d.nodesPerForkMetrics())
-	metricsMod.AddIndvMetric(d.AttnetsDistMetrics())
+	metricsMod.AddIndvMetric(d.discoveredPeersMetrics())
+	metricsMod.AddIndvMetric(d.nodesPerForkMetrics(m))
+	metricsMod.AddIndvMetric(d.attnetsDistMetrics(m))
+	metricsMod.AddIndvMetric(d.peersPerSubnetMetrics(m))
 
 	return metricsMod
 }
 
-func (d *Discovery) nodesPerForkMetrics() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(NodesPerForkDistribution)
+func (d *Discovery) nodesPerForkMetrics(m *ethMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.NodesPerForkDistribution)
 		return nil
 	}
 
@@ -53,7 +75,7 @@ func (d *Discovery) nodesPerForkMetrics() *metrics.IndvMetrics {
 			return nil, err
 		}
 		for forkName, cnt := range summary {
-			NodesPerForkDistribution.WithLabelValues(forkName).Set(float64(cnt.(int)))
+			m.NodesPerForkDistribution.WithLabelValues(forkName).Set(float64(cnt.(int)))
 		}
 		return summary, nil
 	}
@@ -69,9 +91,9 @@ func (d *Discovery) nodesPerForkMetrics() *metrics.IndvMetrics {
 	return nodeDist
 }
 
-func (c *Discovery) AttnetsDistMetrics() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(AttnetsDistribution)
+func (c *Discovery) attnetsDistMetrics(m *ethMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.AttnetsDistribution)
 		return nil
 	}
 
@@ -81,7 +103,7 @@ func (c *Discovery) AttnetsDistMetrics() *metrics.IndvMetrics {
 			return nil, err
 		}
 		for attnets, cnt := range summary {
-			AttnetsDistribution.WithLabelValues(attnets).Set(float64(cnt.(int)))
+			m.AttnetsDistribution.WithLabelValues(attnets).Set(float64(cnt.(int)))
 		}
 		return summary, nil
 	}
@@ -96,3 +118,38 @@ func (c *Discovery) AttnetsDistMetrics() *metrics.IndvMetrics {
 	}
 	return nodeDist
 }
+
+func (d *Discovery) peersPerSubnetMetrics(m *ethMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.PeersPerSubnet)
+		return nil
+	}
+
+	subnetLimits := map[string]int{
+		eth.AttnetSubnet:  eth.SubnetLimit,
+		eth.SyncnetSubnet: eth.SyncSubnetLimit,
+	}
+	updateFn := func() (interface{}, error) {
+		summary, err := d.DBClient.GetPeersPerSubnet()
+		if err != nil {
+			return nil, err
+		}
+		// the subnets without nodes are exported as well, they are the under-provisioned ones
+		for subnetType, limit := range subnetLimits {
+			for subnet := 0; subnet < limit; subnet++ {
+				m.PeersPerSubnet.WithLabelValues(subnetType, fmt.Sprintf("%d", subnet)).Set(float64(summary[subnetType][subnet]))
+			}
+		}
+		return summary, nil
+	}
+
+	peersPerSubnet, err := metrics.NewIndvMetrics(
+		"peers_per_subnet",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return peersPerSubnet
+}
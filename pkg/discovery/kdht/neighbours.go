@@ -14,6 +14,7 @@ import (
 
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
 	ma "github.com/multiformats/go-multiaddr"
 
 	kbucket "github.com/libp2p/go-libp2p-kbucket"
@@ -113,6 +114,7 @@ func (c *KadDHTDiscService) extractHostInfo(p peer.AddrInfo) *models.HostInfo {
 		p.ID,
 		c.network,
 		models.WithMultiaddress(mAddrs),
+		models.WithOrigin(models.DiscoveredOrigin),
 	)
 
 	err := ReqIpfsPeerInfo(c.h, p.ID, hInfo)
@@ -145,7 +147,7 @@ func ReqIpfsPeerInfo(h host.Host, peerID peer.ID, hInfo *models.HostInfo) error
 	protocols := make([]string, 0)
 	// Extract protocols
 	if ps, err := h.Peerstore().GetProtocols(peerID); err == nil {
-		copy(protocols, ps)
+		protocols = protocol.ConvertToStrings(ps)
 	}
 
 	pInfo := models.NewPeerInfo(
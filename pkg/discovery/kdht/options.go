@@ -0,0 +1,37 @@
+package kdht
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type KadDHTOption func(*KadDHTDiscService) error
+
+// WithSeed seeds the keys of the random walks, so that the exploration can be replayed
+func WithSeed(seed int64) KadDHTOption {
+	return func(d *KadDHTDiscService) error {
+		d.discPeers.m.Lock()
+		defer d.discPeers.m.Unlock()
+		d.discPeers.rng = rand.New(rand.NewSource(seed))
+		return nil
+	}
+}
+
+// WithWalkSettings sets the number of peers whose buckets are enumerated concurrently,
+// the interval between random walks, and the time before a peer is enumerated again
+func WithWalkSettings(walkers int, walkInterval, recrawlInterval time.Duration) KadDHTOption {
+	return func(d *KadDHTDiscService) error {
+		if walkers <= 0 {
+			return errors.New("the number of dht walkers has to be positive")
+		}
+		if walkInterval <= 0 || recrawlInterval <= 0 {
+			return errors.New("the dht walk and recrawl intervals have to be positive")
+		}
+		d.walkers = walkers
+		d.walkInterval = walkInterval
+		d.recrawlInterval = recrawlInterval
+		return nil
+	}
+}
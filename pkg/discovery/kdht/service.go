@@ -15,7 +15,6 @@ import (
 
 	"github.com/pkg/errors"
 
-	net "github.com/libp2p/go-libp2p/core/network"
 	"github.com/migalabs/armiarma/pkg/db/models"
 	"github.com/migalabs/armiarma/pkg/discovery"
 	"github.com/migalabs/armiarma/pkg/utils"
@@ -30,10 +29,9 @@ import (
 )
 
 var (
-	graceTime = 3 * time.Second
-	timeout   = 30 * time.Second
-
-	workers = 25
+	graceTime     = 3 * time.Second
+	iteratorSleep = 100 * time.Millisecond
+	timeout       = 30 * time.Second
 )
 
 // IPFS discovery service with Kademlia DHT https://github.com/libp2p/go-libp2p-kad-dht
@@ -52,12 +50,20 @@ type KadDHTDiscService struct {
 
 	bootnodes []peer.AddrInfo
 
+	// dht walk
+	walkers         int
+	walkInterval    time.Duration
+	recrawlInterval time.Duration
+	cm              sync.Mutex
+	crawled         map[peer.ID]time.Time
+
 	nodeNotC chan *models.HostInfo
 	wg       sync.WaitGroup
 	doneF    bool
+	quitC    chan struct{}
 }
 
-func NewKadDHTDiscService(ctx context.Context, h host.Host, network utils.NetworkType, protocols []string, bootstrapnodes []peer.AddrInfo, timeout time.Duration) *KadDHTDiscService {
+func NewKadDHTDiscService(ctx context.Context, h host.Host, network utils.NetworkType, protocols []string, bootstrapnodes []peer.AddrInfo, timeout time.Duration, opts ...KadDHTOption) *KadDHTDiscService {
 
 	ms := &msgSender{
 		h:         h,
@@ -70,8 +76,15 @@ func NewKadDHTDiscService(ctx context.Context, h host.Host, network utils.Networ
 		log.Panicf("unable to generate protocol messenger for kdht %s", err)
 	}
 
-	// Generate the new Kademlia DHT
-	peerkdht, err := kdht.New(ctx, h)
+	// Generate the new Kademlia DHT (used for the random walks) speaking the protocol of the network
+	dhtOpts := make([]kdht.Option, 0)
+	if len(protocols) > 0 {
+		dhtOpts = append(dhtOpts, kdht.V1ProtocolOverride(protocol.ID(protocols[0])))
+	}
+	if len(bootstrapnodes) > 0 {
+		dhtOpts = append(dhtOpts, kdht.BootstrapPeers(bootstrapnodes...))
+	}
+	peerkdht, err := kdht.New(ctx, h, dhtOpts...)
 	if err != nil {
 		log.Panicf("unable to generate the kdht %s", err)
 	}
@@ -100,6 +113,18 @@ func NewKadDHTDiscService(ctx context.Context, h host.Host, network utils.Networ
 		bootnodes: bootstrapnodes,
 		nodeNotC:  make(chan *models.HostInfo),
 		doneF:     false,
+		quitC:     make(chan struct{}),
+
+		walkers:         DefaultWalkers,
+		walkInterval:    DefaultWalkInterval,
+		recrawlInterval: DefaultRecrawlInterval,
+		crawled:         make(map[peer.ID]time.Time),
+	}
+	for _, opt := range opts {
+		err := opt(kadDiscv)
+		if err != nil {
+			log.Panicf("unable to apply kdht option %s", err)
+		}
 	}
 	return kadDiscv
 }
@@ -115,93 +140,18 @@ func (disc *KadDHTDiscService) Start() chan *models.HostInfo {
 	}
 	log.Infof("Adding %d bootstrap nodes", bnCnt)
 
-	workerTask := func(workerlog log.FieldLogger, nextp peer.AddrInfo) {
-		ctx := net.WithDialPeerTimeout(disc.ctx, timeout)
-		// Force direct dials will prevent swarm to run into dial backoff errors. It also prevents proxied connections.
-		ctx = net.WithForceDirectDial(ctx, "prevent backoff")
-
-		workerlog.Debugf("connecting %s", nextp.ID.String())
-		if err := disc.h.Connect(ctx, nextp); err != nil {
-			workerlog.Debugf("unable to connect peer %s - %s", nextp.ID.String(), err.Error())
-		} else {
-			workerlog.Debugf("Connection established with bootstrap node: %s", nextp.ID.String())
-			// If peer was connectable, req all the possible info
-			// try to request neighbors to connected peer
-			//addinfo, _ := utils.CompAddrInfo(nextp.ID.String(), nextp.MAddrs)
-			neighborsRt, err := disc.fetchNeighbors(disc.ctx, nextp)
-			if err != nil {
-				workerlog.Debugf("unable to request neibors to peer. %s", err.Error())
-			}
-			// add peer to connectable list
-			log.Debugf("%d neighbours for peer", len(neighborsRt.Neighbors))
-			for _, newPeer := range neighborsRt.Neighbors {
-				// add neihbors to the peer list
-				disc.discPeers.addPeer(newPeer)
-			}
-			// Free connection resources
-			if err := disc.h.Network().ClosePeer(nextp.ID); err != nil {
-				log.Warnf("Could not close connection to peer %s", err)
-			}
-		}
-	}
-
-	for i := 0; i < workers; i++ {
+	// bucket enumeration of the known peers
+	for i := 0; i < disc.walkers; i++ {
 		log.Debugf("launching worker %d", i)
-		workerid := i
 		workerlog := log.WithField(
-			"worker", workerid,
+			"worker", i,
 		)
 		disc.wg.Add(1)
-		go func() {
-			for {
-				defer disc.wg.Done()
-				// get a new random peer to connect and request new peers
-				nextp, ok := disc.discPeers.getBootstrapPeer() //getRandomPeer()
-				if !ok {
-					workerlog.Debugf("there is no new peer to dial")
-					time.Sleep(graceTime)
-					continue
-				}
-
-				workerTask(workerlog, nextp)
-
-				// check if the context has died to cancel the routine
-				if disc.ctx.Err() != nil || disc.doneF {
-					workerlog.Info("closing discover peer worker")
-					return
-				}
-			}
-		}()
-	}
-	// Random Peers
-	for i := 0; i < workers; i++ {
-		log.Debugf("launching worker %d", i)
-		workerid := workers + i
-		workerlog := log.WithField(
-			"worker", workerid,
-		)
-		disc.wg.Add(1)
-		go func() {
-			defer disc.wg.Done()
-			for {
-				// get a new random peer to connect and request new peers
-				nextp, ok := disc.discPeers.getRandomPeer()
-				if !ok {
-					workerlog.Debugf("there is no new peer to dial")
-					time.Sleep(graceTime)
-					continue
-				}
-
-				workerTask(workerlog, nextp)
-
-				// check if the context has died to cancel the routine
-				if disc.ctx.Err() != nil || disc.doneF {
-					workerlog.Info("closing discover peer worker")
-					return
-				}
-			}
-		}()
+		go disc.bucketWalker(workerlog)
 	}
+	// random walk over the keyspace
+	disc.wg.Add(1)
+	go disc.randomWalker()
 
 	disc.wg.Add(1)
 	go disc.nodeIterator()
@@ -218,16 +168,28 @@ func (d *KadDHTDiscService) nodeIterator() {
 			return
 		}
 
-		if d.Next() {
-			log.Debug("new Kad Peer discovered")
-			// fill the given DiscoveredPeer interface with the next found peer
-			hInfo, ok := d.Peer()
-			if !ok {
-				log.Warn("received peer with invalid maddrs")
+		if !d.Next() {
+			select {
+			case <-time.After(iteratorSleep):
+			case <-d.quitC:
+			case <-d.ctx.Done():
 			}
-			log.Debug("notifying of new Kad Peer")
-			d.nodeNotC <- hInfo
-			log.Debug("notification done!")
+			continue
+		}
+		log.Debug("new Kad Peer discovered")
+		// fill the given DiscoveredPeer interface with the next found peer
+		hInfo, ok := d.Peer()
+		if !ok {
+			log.Warn("received peer with invalid maddrs")
+			continue
+		}
+		log.Debug("notifying of new Kad Peer")
+		select {
+		case d.nodeNotC <- hInfo:
+		case <-d.quitC:
+			return
+		case <-d.ctx.Done():
+			return
 		}
 	}
 }
@@ -250,6 +212,7 @@ func (disc *KadDHTDiscService) Peer() (*models.HostInfo, bool) {
 		addrinfo.ID,
 		disc.network,
 		models.WithMultiaddress(addrinfo.Addrs),
+		models.WithOrigin(models.DiscoveredOrigin),
 	)
 
 	// TODO: Not sure if there is actually an iterest to return IP / UserAgent / Protocols... /
@@ -258,6 +221,7 @@ func (disc *KadDHTDiscService) Peer() (*models.HostInfo, bool) {
 
 func (d *KadDHTDiscService) Stop() {
 	d.doneF = true
+	close(d.quitC)
 	d.wg.Wait()
 
 	close(d.nodeNotC)
@@ -268,6 +232,7 @@ type discoveredPeers struct {
 	m      sync.Mutex
 	pMap   sync.Map
 	pArray []*peer.AddrInfo
+	rng    *rand.Rand
 	rp     *uint64
 	wp     *uint64
 	bp     *uint64
@@ -281,6 +246,7 @@ func NewDiscoveryPeers(ctx context.Context) *discoveredPeers {
 	dp := &discoveredPeers{
 		ctx:    ctx,
 		pArray: make([]*peer.AddrInfo, 0),
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
 		rp:     &rp,
 		wp:     &wp,
 		bp:     &bp,
@@ -378,28 +344,14 @@ func (d *discoveredPeers) getBootstrapPeer() (peer.AddrInfo, bool) {
 	return *addinfo, true
 }
 
-// returns the addrinfo of the next discovered peer, only if there a new one to read
-// empty addrinfo otherwise
-func (d *discoveredPeers) getRandomPeer() (peer.AddrInfo, bool) {
-	log.Debugf("getting random peer to request")
-	// check if there list of peers is empty
-	if d.isEmpty() {
-		log.Debugf("empty list of peers to bootstrap")
-		return peer.AddrInfo{}, false
-	}
-	// get random number
-	rand.Seed(time.Now().Unix())
-	randpointer := rand.Intn(d.getLen())
-	log.Debugf("len of pArray: %d", d.getLen())
-	log.Debugf("random pointer = %d", randpointer)
-
-	// get the next peer found from the array
-	log.Debugf("Lock reading peer from array")
+// randomKey returns a random key of the given length to walk the DHT
+func (d *discoveredPeers) randomKey(length int) []byte {
+	key := make([]byte, length)
 	d.m.Lock()
-	addinfo := d.pArray[randpointer]
+	// the rng isn't safe for concurrent use
+	d.rng.Read(key)
 	d.m.Unlock()
-	log.Debugf("Unlock reading peer from array")
-	return *addinfo, true
+	return key
 }
 
 // ImportBootNodeList
@@ -454,3 +406,21 @@ func ReadIpfsBootnodeFile(jfile string) ([]peer.AddrInfo, error) {
 	return bootNodeList, nil
 
 }
+
+// ParseBootnodesFromStringSlice parses the list of bootnode multiaddresses (with the /p2p/<peer_id> suffix)
+// into the AddrInfo of each bootnode
+func ParseBootnodesFromStringSlice(bNodes []string) ([]peer.AddrInfo, error) {
+	bootNodeList := make([]peer.AddrInfo, 0, len(bNodes))
+	for _, element := range bNodes {
+		maddr, err := utils.UnmarshalMaddr(element)
+		if err != nil {
+			return bootNodeList, errors.Wrap(err, "unable to parse bootnode "+element)
+		}
+		addinfo, err := peer.AddrInfoFromP2pAddr(maddr)
+		if err != nil {
+			return bootNodeList, errors.Wrap(err, "unable to compose AddrInfo from bootnode "+element)
+		}
+		bootNodeList = append(bootNodeList, *addinfo)
+	}
+	return bootNodeList, nil
+}
@@ -0,0 +1,152 @@
+package kdht
+
+import (
+	"context"
+	"time"
+
+	net "github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/**
+The DHT walk combines two strategies to discover the peers of the network:
+- bucket enumeration: each known peer is asked for the content of its k-buckets
+  (see fetchNeighbors), once per recrawl interval.
+- random walk: lookups of random keys through the local DHT, which reach the
+  regions of the keyspace that the enumeration didn't cover yet.
+
+Every discovered AddrInfo is added to the discoveredPeers, which are notified
+to the discovery service (and from there to the peering and identification).
+*/
+
+var (
+	DefaultWalkers         = 25
+	DefaultWalkInterval    = 30 * time.Second
+	DefaultRecrawlInterval = 30 * time.Minute
+
+	// length of the random keys of the random walk (sha256 size)
+	randomKeyLen = 32
+)
+
+// bucketWalker enumerates the k-buckets of the known peers until the service is closed
+func (d *KadDHTDiscService) bucketWalker(workerlog log.FieldLogger) {
+	defer d.wg.Done()
+
+	for {
+		if d.doneF || d.ctx.Err() != nil {
+			workerlog.Info("closing discover peer worker")
+			return
+		}
+		nextp, ok := d.nextPeerToCrawl()
+		if !ok {
+			workerlog.Debugf("there is no new peer to dial")
+			select {
+			case <-time.After(graceTime):
+			case <-d.quitC:
+			case <-d.ctx.Done():
+			}
+			continue
+		}
+		d.crawlPeer(workerlog, nextp)
+	}
+}
+
+// nextPeerToCrawl returns the next known peer that wasn't crawled within the recrawl interval,
+// false if all of them were already crawled
+func (d *KadDHTDiscService) nextPeerToCrawl() (peer.AddrInfo, bool) {
+	for i := 0; i < d.discPeers.getLen(); i++ {
+		nextp, ok := d.discPeers.getBootstrapPeer()
+		if !ok {
+			return peer.AddrInfo{}, false
+		}
+		if d.shouldCrawl(nextp.ID) {
+			return nextp, true
+		}
+	}
+	return peer.AddrInfo{}, false
+}
+
+// shouldCrawl checks that the peer wasn't crawled within the recrawl interval
+func (d *KadDHTDiscService) shouldCrawl(id peer.ID) bool {
+	d.cm.Lock()
+	defer d.cm.Unlock()
+	if last, ok := d.crawled[id]; ok && time.Since(last) < d.recrawlInterval {
+		return false
+	}
+	d.crawled[id] = time.Now()
+	return true
+}
+
+// crawlPeer connects the given peer and requests the content of its k-buckets
+func (d *KadDHTDiscService) crawlPeer(workerlog log.FieldLogger, nextp peer.AddrInfo) {
+	ctx := net.WithDialPeerTimeout(d.ctx, timeout)
+	// Force direct dials will prevent swarm to run into dial backoff errors. It also prevents proxied connections.
+	ctx = net.WithForceDirectDial(ctx, "prevent backoff")
+
+	workerlog.Debugf("connecting %s", nextp.ID.String())
+	if err := d.h.Connect(ctx, nextp); err != nil {
+		workerlog.Debugf("unable to connect peer %s - %s", nextp.ID.String(), err.Error())
+		return
+	}
+	// If peer was connectable, request the neighbours of all the buckets
+	neighborsRt, err := d.fetchNeighbors(d.ctx, nextp)
+	if err != nil {
+		workerlog.Debugf("unable to request neighbours to peer. %s", err.Error())
+	}
+	if neighborsRt != nil {
+		workerlog.Debugf("%d neighbours for peer %s", len(neighborsRt.Neighbors), nextp.ID.String())
+		for _, newPeer := range neighborsRt.Neighbors {
+			d.discPeers.addPeer(newPeer)
+		}
+	}
+	// Free connection resources
+	if err := d.h.Network().ClosePeer(nextp.ID); err != nil {
+		workerlog.Warnf("Could not close connection to peer %s", err)
+	}
+}
+
+// randomWalker looks up a random key in the DHT at each walk interval
+func (d *KadDHTDiscService) randomWalker() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.walkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.randomWalk()
+		case <-d.quitC:
+			log.Info("closing dht random walk")
+			return
+		case <-d.ctx.Done():
+			log.Info("closing dht random walk")
+			return
+		}
+	}
+}
+
+// randomWalk adds the closest peers to a random key to the discovered peers
+func (d *KadDHTDiscService) randomWalk() {
+	ctx, cancel := context.WithTimeout(d.ctx, d.walkInterval)
+	defer cancel()
+
+	key := d.discPeers.randomKey(randomKeyLen)
+	closest, err := d.ipfsDHT.GetClosestPeers(ctx, string(key))
+	if err != nil {
+		log.Debugf("random walk failed: %s", err.Error())
+		return
+	}
+	added := 0
+	for _, p := range closest {
+		addrInfo := d.h.Peerstore().PeerInfo(p)
+		if len(addrInfo.Addrs) == 0 {
+			continue
+		}
+		d.discPeers.addPeer(addrInfo)
+		added++
+	}
+	log.Debugf("random walk found %d peers (%d with addrs)", len(closest), added)
+}
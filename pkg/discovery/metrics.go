@@ -0,0 +1,62 @@
+package discovery
+
+import (
+	"sync/atomic"
+
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// discoveryMetrics are the collectors of the peers found by the sources of the discovery,
+// which get increased as the peers arrive (not from the DB) to follow the discovery rate live
+type discoveryMetrics struct {
+	DiscoveredPeers *prometheus.CounterVec
+}
+
+func newDiscoveryMetrics() *discoveryMetrics {
+	return &discoveryMetrics{
+		DiscoveredPeers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: modName,
+			Name:      "discovered_peers",
+			Help:      "Number of peers notified by each of the discovery sources",
+		},
+			[]string{"source"},
+		),
+	}
+}
+
+// GetMetrics returns the metrics of the discovery that every network supports
+func (d *Discovery) GetMetrics() *metrics.MetricsModule {
+	metricsMod := metrics.NewMetricsModule(
+		modName,
+		modDetails,
+	)
+	metricsMod.AddIndvMetric(d.discoveredPeersMetrics())
+	return metricsMod
+}
+
+func (d *Discovery) discoveredPeersMetrics() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(d.metrics.DiscoveredPeers)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		// the counters are increased by the listeners of the sources
+		summary := make(map[string]uint64, len(d.sources))
+		for _, src := range d.sources {
+			summary[src.name] = atomic.LoadUint64(d.discovered[src.name])
+		}
+		return summary, nil
+	}
+
+	discovered, err := metrics.NewIndvMetrics(
+		"discovered_peers",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		return nil
+	}
+	return discovered
+}
@@ -0,0 +1,26 @@
+package discovery
+
+import (
+	"github.com/pkg/errors"
+)
+
+type DiscoveryOption func(*Discovery) error
+
+// WithSource adds a PeerDiscovery to the sources of the discovery, tagging the peers that it finds with the given name
+func WithSource(name string, serv PeerDiscovery) DiscoveryOption {
+	return func(d *Discovery) error {
+		if serv == nil {
+			return errors.Errorf("nil discovery source %s", name)
+		}
+		for _, src := range d.sources {
+			if src.name == name {
+				return errors.Errorf("duplicated discovery source %s", name)
+			}
+		}
+		d.sources = append(d.sources, discoverySource{
+			name: name,
+			serv: serv,
+		})
+		return nil
+	}
+}
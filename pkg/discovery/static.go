@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// StaticDiscovery notifies the peers of a static list (i.e. a bootnode file), once per interval
+type StaticDiscovery struct {
+	ctx      context.Context
+	network  utils.NetworkType
+	peers    []peer.AddrInfo
+	interval time.Duration
+
+	nodeNotC chan *models.HostInfo
+	wg       sync.WaitGroup
+	doneC    chan struct{}
+}
+
+func NewStaticDiscovery(ctx context.Context, network utils.NetworkType, peers []peer.AddrInfo, interval time.Duration) *StaticDiscovery {
+	return &StaticDiscovery{
+		ctx:      ctx,
+		network:  network,
+		peers:    peers,
+		interval: interval,
+		nodeNotC: make(chan *models.HostInfo),
+		doneC:    make(chan struct{}),
+	}
+}
+
+func (d *StaticDiscovery) Start() chan *models.HostInfo {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			for _, addrInfo := range d.peers {
+				hInfo := models.NewHostInfo(
+					addrInfo.ID,
+					d.network,
+					models.WithMultiaddress(addrInfo.Addrs),
+					models.WithOrigin(models.DiscoveredOrigin),
+				)
+				select {
+				case d.nodeNotC <- hInfo:
+				case <-d.doneC:
+					return
+				case <-d.ctx.Done():
+					return
+				}
+			}
+			log.Debugf("notified %d static peers", len(d.peers))
+			select {
+			case <-ticker.C:
+			case <-d.doneC:
+				return
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+	return d.nodeNotC
+}
+
+func (d *StaticDiscovery) Stop() {
+	close(d.doneC)
+	d.wg.Wait()
+	close(d.nodeNotC)
+}
+
+// ReadPeersFile reads the multiaddrs (with the /p2p/<peer_id> suffix) of the peers in the
+// given file, which has the same format than the bootnode files
+func ReadPeersFile(jfile string) ([]peer.AddrInfo, error) {
+	content, err := os.ReadFile(jfile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read peers file "+jfile)
+	}
+	peersList := BootNodeListString{}
+	err = json.Unmarshal(content, &peersList)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal peers file "+jfile)
+	}
+	peers := make([]peer.AddrInfo, 0, len(peersList.BootNodes))
+	for _, element := range peersList.BootNodes {
+		maddr, err := utils.UnmarshalMaddr(element)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse peer "+element)
+		}
+		addrInfo, err := peer.AddrInfoFromP2pAddr(maddr)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to compose AddrInfo from peer "+element)
+		}
+		peers = append(peers, *addrInfo)
+	}
+	return peers, nil
+}
@@ -5,13 +5,22 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
+	"github.com/migalabs/armiarma/pkg/db/models"
 	"github.com/migalabs/armiarma/pkg/hosts"
 	"github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils/clientinfo"
 	"github.com/r3labs/sse/v2"
 	log "github.com/sirupsen/logrus"
 )
 
+// HostEvents is the source of the connection and identification events of the hosts
+type HostEvents interface {
+	OnConnEvent(func(*models.EventTrace))
+	OnIdentEvent(func(hosts.IdentificationEvent))
+}
+
 // Forwarder subscribes to internal events that Armiarma emits, hydrates them
 // with extra data, and publishes a new sanitized event to a SSE server.
 type Forwarder struct {
@@ -21,17 +30,24 @@ type Forwarder struct {
 
 	server        *sse.Server
 	h             *hosts.BasicLibp2pHost
+	hostEvents    HostEvents
 	ethMsgHandler *ethereum.EthMessageHandler
 
 	// Store downstream attestation events in a channel so
 	// that we don't block the eth2 handler.
 	attestationCh chan *ethereum.AttestationReceievedEvent
 
+	// the host events are dropped if the channels are full, so that
+	// a slow consumer never delays the notification pipeline of the hosts
+	connEventCh  chan *models.EventTrace
+	identEventCh chan hosts.IdentificationEvent
+	dropped      int64
+
 	once sync.Once
 }
 
 // NewForwarder creates a new Forwarder
-func NewForwarder(ip string, port int, h *hosts.BasicLibp2pHost, ethMsgHandler *ethereum.EthMessageHandler) *Forwarder {
+func NewForwarder(ip string, port int, h *hosts.BasicLibp2pHost, hostEvents HostEvents, ethMsgHandler *ethereum.EthMessageHandler) *Forwarder {
 	server := sse.New()
 
 	// Disable auto replay. If a consumer is not connected, it will never receive the event.
@@ -42,8 +58,11 @@ func NewForwarder(ip string, port int, h *hosts.BasicLibp2pHost, ethMsgHandler *
 		port:          port,
 		server:        server,
 		h:             h,
+		hostEvents:    hostEvents,
 		ethMsgHandler: ethMsgHandler,
 		attestationCh: make(chan *ethereum.AttestationReceievedEvent, 10000),
+		connEventCh:   make(chan *models.EventTrace, 10000),
+		identEventCh:  make(chan hosts.IdentificationEvent, 10000),
 	}
 }
 
@@ -67,6 +86,9 @@ func (f *Forwarder) Start(ctx context.Context) error {
 
 		f.server.CreateStream(TopicEthereumAttestation)
 		f.server.CreateStream(TopicTimedEthereumAttestation)
+		f.server.CreateStream(TopicConnection)
+		f.server.CreateStream(TopicDisconnection)
+		f.server.CreateStream(TopicIdentification)
 
 		err = f.startHTTPServer()
 		if err != nil {
@@ -106,6 +128,28 @@ func (f *Forwarder) subscribeDownstream(ctx context.Context) {
 	f.ethMsgHandler.OnAttestation(func(event *ethereum.AttestationReceievedEvent) {
 		f.attestationCh <- event
 	})
+	f.hostEvents.OnConnEvent(func(event *models.EventTrace) {
+		select {
+		case f.connEventCh <- event:
+		default:
+			f.dropEvent()
+		}
+	})
+	f.hostEvents.OnIdentEvent(func(event hosts.IdentificationEvent) {
+		select {
+		case f.identEventCh <- event:
+		default:
+			f.dropEvent()
+		}
+	})
+}
+
+// dropEvent accounts a host event that didn't fit in the channels
+func (f *Forwarder) dropEvent() {
+	dropped := atomic.AddInt64(&f.dropped, 1)
+	if dropped%1000 == 1 {
+		log.Warnf("SSE host event channels full, %d events dropped so far", dropped)
+	}
 }
 
 // startWorkers initializes the workers that process events out of the channels
@@ -122,6 +166,10 @@ func (f *Forwarder) eventWorker() {
 		select {
 		case event := <-f.attestationCh:
 			f.processAttestationEvent(event)
+		case event := <-f.connEventCh:
+			f.processConnEvent(event)
+		case event := <-f.identEventCh:
+			f.processIdentEvent(event)
 		case <-f.ctx.Done():
 			return
 		}
@@ -166,3 +214,62 @@ func (f *Forwarder) processAttestationEvent(e *ethereum.AttestationReceievedEven
 	}
 
 }
+
+// processConnEvent publishes a connection or disconnection of the host
+func (f *Forwarder) processConnEvent(e *models.EventTrace) {
+	var err error
+	switch event := e.Event.(type) {
+	case *models.ConnInfo:
+		err = f.publishConnection(&Connection{
+			PeerID:     e.PeerID.String(),
+			Direction:  models.DirectionIndexToString(event.Direction),
+			Timestamp:  event.ConnTime,
+			Latency:    event.Latency,
+			Identified: event.Identified,
+			AddrFamily: event.AddrFamily,
+			Error:      event.Error,
+		})
+	case *models.EndConnInfo:
+		err = f.publishDisconnection(&Disconnection{
+			PeerID:    e.PeerID.String(),
+			Timestamp: event.DiscTime,
+		})
+	default:
+		log.Warnf("unknown host event type %T", e.Event)
+	}
+	if err != nil {
+		log.WithError(err).Error("error publishing host connection event to SSE server")
+	}
+}
+
+// processIdentEvent publishes the identification of a peer with its parsed client
+func (f *Forwarder) processIdentEvent(e hosts.IdentificationEvent) {
+	hInfo := e.HostInfo
+	hInfo.RLock()
+	event := &Identification{
+		Timestamp:  e.Timestamp,
+		Network:    string(hInfo.Network),
+		Identified: hInfo.PeerInfo.IsPeerIdentified(),
+		PeerInfo: &PeerInfo{
+			ID:              hInfo.ID.String(),
+			IP:              hInfo.IP,
+			Port:            hInfo.Port,
+			UserAgent:       hInfo.PeerInfo.UserAgent,
+			Latency:         hInfo.PeerInfo.Latency,
+			Protocols:       hInfo.PeerInfo.Protocols,
+			ProtocolVersion: hInfo.PeerInfo.ProtocolVersion,
+		},
+	}
+	hInfo.RUnlock()
+
+	if event.PeerInfo.UserAgent != "" {
+		cliInfo := clientinfo.Parse(hInfo.Network, event.PeerInfo.UserAgent)
+		event.ClientName = string(cliInfo.Name)
+		event.ClientVersion = cliInfo.Version
+		event.ClientOS = string(cliInfo.OS)
+		event.ClientArch = string(cliInfo.Arch)
+	}
+	if err := f.publishIdentification(event); err != nil {
+		log.WithError(err).Error("error publishing identification event to SSE server")
+	}
+}
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"time"
+)
+
+// Connection contains the data of a new connection of the host
+type Connection struct {
+	PeerID     string        `json:"peer_id"`
+	Direction  string        `json:"direction"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Latency    time.Duration `json:"latency"`
+	Identified bool          `json:"identified"`
+	AddrFamily string        `json:"addr_family"`
+	Error      string        `json:"error"`
+}
+
+// Disconnection contains the data of a closed connection of the host
+type Disconnection struct {
+	PeerID    string    `json:"peer_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Identification contains the result of identifying a connected peer,
+// along with the client parsed from its user agent
+type Identification struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Network       string    `json:"network"`
+	Identified    bool      `json:"identified"`
+	ClientName    string    `json:"client_name"`
+	ClientVersion string    `json:"client_version"`
+	ClientOS      string    `json:"client_os"`
+	ClientArch    string    `json:"client_arch"`
+	PeerInfo      *PeerInfo `json:"peer_info"`
+}
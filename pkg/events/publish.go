@@ -33,3 +33,45 @@ func (f *Forwarder) publishTimedEthereumAttestation(event *TimedEthereumAttestat
 
 	return nil
 }
+
+// publishConnection publishes a Connection event
+func (f *Forwarder) publishConnection(event *Connection) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f.server.Publish(string(TopicConnection), &sse.Event{
+		Data: data,
+	})
+
+	return nil
+}
+
+// publishDisconnection publishes a Disconnection event
+func (f *Forwarder) publishDisconnection(event *Disconnection) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f.server.Publish(string(TopicDisconnection), &sse.Event{
+		Data: data,
+	})
+
+	return nil
+}
+
+// publishIdentification publishes an Identification event
+func (f *Forwarder) publishIdentification(event *Identification) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f.server.Publish(string(TopicIdentification), &sse.Event{
+		Data: data,
+	})
+
+	return nil
+}
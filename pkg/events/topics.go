@@ -9,3 +9,13 @@ const (
 	// TopicTimedEthereumAttestation is the topic for Timed Ethereum Attestation events
 	TopicTimedEthereumAttestation string = "timed_ethereum_attestation"
 )
+
+// Host events
+const (
+	// TopicConnection is the topic for new connections of the host
+	TopicConnection string = "connection"
+	// TopicDisconnection is the topic for closed connections of the host
+	TopicDisconnection string = "disconnection"
+	// TopicIdentification is the topic for the identification of the connected peers
+	TopicIdentification string = "identification"
+)
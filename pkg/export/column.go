@@ -0,0 +1,83 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Kind is the type of the values of an exported column
+type Kind string
+
+const (
+	StringKind Kind = "string"
+	IntKind    Kind = "int"
+	FloatKind  Kind = "float"
+	BoolKind   Kind = "bool"
+	TimeKind   Kind = "time"
+)
+
+// Column is a named and typed column of an exported dataset
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// Normalize converts the value read from the DB into the Go type of the column kind
+// (string, int64, float64, bool or time.Time), or nil for the NULL values
+func Normalize(kind Kind, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch kind {
+	case StringKind:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		case fmt.Stringer:
+			return v.String(), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case IntKind:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int32:
+			return int64(v), nil
+		case int16:
+			return int64(v), nil
+		case int:
+			return int64(v), nil
+		case float64:
+			return int64(math.Round(v)), nil
+		}
+	case FloatKind:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case int32:
+			return float64(v), nil
+		}
+	case BoolKind:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case TimeKind:
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case int64:
+			return time.Unix(v, 0), nil
+		}
+	}
+	return nil, errors.Errorf("unable to export %T as %s", value, kind)
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CSVWriter writes the rows as CSV with a header line, the times as RFC3339 in UTC
+// and the NULL values as empty fields
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func NewCSVWriter(w io.Writer, columns []Column) (*CSVWriter, error) {
+	csvW := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	if err := csvW.Write(header); err != nil {
+		return nil, errors.Wrap(err, "unable to write the csv header")
+	}
+	return &CSVWriter{
+		w:       csvW,
+		columns: columns,
+		record:  make([]string, len(columns)),
+	}, nil
+}
+
+func (c *CSVWriter) WriteRow(row []interface{}) error {
+	if len(row) != len(c.columns) {
+		return errors.Errorf("row has %d values for %d columns", len(row), len(c.columns))
+	}
+	for i, col := range c.columns {
+		value, err := Normalize(col.Kind, row[i])
+		if err != nil {
+			return errors.Wrap(err, col.Name)
+		}
+		switch v := value.(type) {
+		case nil:
+			c.record[i] = ""
+		case string:
+			c.record[i] = v
+		case int64:
+			c.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			c.record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			c.record[i] = strconv.FormatBool(v)
+		case time.Time:
+			c.record[i] = v.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
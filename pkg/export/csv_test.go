@@ -0,0 +1,42 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter("CSV", &buf, []Column{
+		{"peer_id", StringKind},
+		{"latency", IntKind},
+		{"score", FloatKind},
+		{"deprecated", BoolKind},
+		{"last_activity", TimeKind},
+	})
+	require.NoError(t, err)
+
+	ts := time.Date(2023, time.January, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, w.WriteRow([]interface{}{"16Uiu2HAm", int32(12), float32(0.5), false, ts}))
+	require.NoError(t, w.WriteRow([]interface{}{"with,comma", nil, nil, nil, int64(0)}))
+	require.Error(t, w.WriteRow([]interface{}{"missing values"}))
+	require.Error(t, w.WriteRow([]interface{}{"wrong kind", "12", nil, nil, nil}))
+	require.NoError(t, w.Close())
+
+	require.Equal(t,
+		"peer_id,latency,score,deprecated,last_activity\n"+
+			"16Uiu2HAm,12,0.5,false,2023-01-02T03:04:05Z\n"+
+			"\"with,comma\",,,,1970-01-01T00:00:00Z\n",
+		buf.String(),
+	)
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewWriter("xlsx", &bytes.Buffer{}, []Column{{"peer_id", StringKind}})
+	require.Error(t, err)
+	_, err = NewWriter(CSVFormat, &bytes.Buffer{}, nil)
+	require.Error(t, err)
+}
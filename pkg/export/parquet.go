@@ -0,0 +1,277 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the parquet files are written uncompressed and PLAIN encoded, with all the columns flat and
+// optional (NULL-able), which any reader (pandas, DuckDB, Spark) understands
+const (
+	parquetMagic = "PAR1"
+
+	// physical types
+	parquetBoolean   int32 = 0
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+
+	// converted (logical) types
+	parquetUTF8            int32 = 0
+	parquetTimestampMillis int32 = 9
+
+	parquetOptional            int32 = 1
+	parquetPlain               int32 = 0
+	parquetRLE                 int32 = 3
+	parquetUncompressed        int32 = 0
+	parquetDataPage            int32 = 0
+	parquetCreatedBy                 = "armiarma export"
+	DefaultParquetRowGroupSize       = 100000
+)
+
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// ParquetWriter buffers the rows of each row group column by column, writing a row group once it
+// is full and the metadata of the file on Close
+type ParquetWriter struct {
+	w       io.Writer
+	offset  int64
+	columns []Column
+
+	rowGroupSize int
+	// values of the current row group per column (nil for NULL)
+	pending [][]interface{}
+	rows    int
+
+	rowGroups [][]parquetColumnChunk
+	groupRows []int64
+}
+
+func NewParquetWriter(w io.Writer, columns []Column) (*ParquetWriter, error) {
+	p := &ParquetWriter{
+		w:            w,
+		columns:      columns,
+		rowGroupSize: DefaultParquetRowGroupSize,
+		pending:      make([][]interface{}, len(columns)),
+	}
+	for _, col := range columns {
+		if _, ok := parquetTypes[col.Kind]; !ok {
+			return nil, errors.Errorf("unsupported parquet kind %s of column %s", col.Kind, col.Name)
+		}
+	}
+	if err := p.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+var parquetTypes = map[Kind]int32{
+	StringKind: parquetByteArray,
+	IntKind:    parquetInt64,
+	FloatKind:  parquetDouble,
+	BoolKind:   parquetBoolean,
+	TimeKind:   parquetInt64,
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *ParquetWriter) WriteRow(row []interface{}) error {
+	if len(row) != len(p.columns) {
+		return errors.Errorf("row has %d values for %d columns", len(row), len(p.columns))
+	}
+	for i, col := range p.columns {
+		value, err := Normalize(col.Kind, row[i])
+		if err != nil {
+			return errors.Wrap(err, col.Name)
+		}
+		p.pending[i] = append(p.pending[i], value)
+	}
+	p.rows++
+	if p.rows >= p.rowGroupSize {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+// flushRowGroup writes a single data page per column with the pending rows
+func (p *ParquetWriter) flushRowGroup() error {
+	if p.rows == 0 {
+		return nil
+	}
+	chunks := make([]parquetColumnChunk, len(p.columns))
+	for i, col := range p.columns {
+		page := encodeParquetPage(col.Kind, p.pending[i])
+
+		header := &thriftWriter{}
+		header.structBegin()
+		header.i32Field(1, parquetDataPage)
+		header.i32Field(2, int32(len(page)))
+		header.i32Field(3, int32(len(page)))
+		header.structField(5)
+		header.i32Field(1, int32(len(p.pending[i])))
+		header.i32Field(2, parquetPlain)
+		header.i32Field(3, parquetRLE)
+		header.i32Field(4, parquetRLE)
+		header.structEnd()
+		header.structEnd()
+
+		chunks[i] = parquetColumnChunk{
+			offset: p.offset,
+			size:   int64(len(header.Bytes()) + len(page)),
+			values: int64(len(p.pending[i])),
+		}
+		if err := p.write(header.Bytes()); err != nil {
+			return errors.Wrap(err, "unable to write parquet page header")
+		}
+		if err := p.write(page); err != nil {
+			return errors.Wrap(err, "unable to write parquet page")
+		}
+		p.pending[i] = p.pending[i][:0]
+	}
+	p.rowGroups = append(p.rowGroups, chunks)
+	p.groupRows = append(p.groupRows, int64(p.rows))
+	p.rows = 0
+	return nil
+}
+
+// encodeParquetPage encodes the definition levels (RLE/bit-packed hybrid) and the PLAIN non-NULL values
+func encodeParquetPage(kind Kind, values []interface{}) []byte {
+	// definition levels of bit width 1, as bit-packed groups of 8 values
+	groups := (len(values) + 7) / 8
+	levels := make([]byte, 0, groups+binary.MaxVarintLen32)
+	levels = binary.AppendUvarint(levels, uint64(groups<<1|1))
+	packed := make([]byte, groups)
+	for i, v := range values {
+		if v != nil {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	levels = append(levels, packed...)
+
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+	page.Write(levels)
+
+	var bools []byte
+	nBools := 0
+	var tmp [8]byte
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		switch kind {
+		case StringKind:
+			s := v.(string)
+			binary.LittleEndian.PutUint32(tmp[:4], uint32(len(s)))
+			page.Write(tmp[:4])
+			page.WriteString(s)
+		case IntKind:
+			binary.LittleEndian.PutUint64(tmp[:], uint64(v.(int64)))
+			page.Write(tmp[:])
+		case FloatKind:
+			binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(v.(float64)))
+			page.Write(tmp[:])
+		case TimeKind:
+			binary.LittleEndian.PutUint64(tmp[:], uint64(v.(time.Time).UnixMilli()))
+			page.Write(tmp[:])
+		case BoolKind:
+			if nBools%8 == 0 {
+				bools = append(bools, 0)
+			}
+			if v.(bool) {
+				bools[nBools/8] |= 1 << (nBools % 8)
+			}
+			nBools++
+		}
+	}
+	page.Write(bools)
+	return page.Bytes()
+}
+
+// Close writes the last row group and the metadata of the file
+func (p *ParquetWriter) Close() error {
+	if err := p.flushRowGroup(); err != nil {
+		return err
+	}
+	var totalRows int64
+	for _, rows := range p.groupRows {
+		totalRows += rows
+	}
+
+	meta := &thriftWriter{}
+	meta.structBegin()
+	meta.i32Field(1, 1) // version
+	// flat schema: the root with one optional leaf per column
+	meta.listField(2, thriftStruct, len(p.columns)+1)
+	meta.structBegin()
+	meta.stringField(4, "schema")
+	meta.i32Field(5, int32(len(p.columns)))
+	meta.structEnd()
+	for _, col := range p.columns {
+		meta.structBegin()
+		meta.i32Field(1, parquetTypes[col.Kind])
+		meta.i32Field(3, parquetOptional)
+		meta.stringField(4, col.Name)
+		switch col.Kind {
+		case StringKind:
+			meta.i32Field(6, parquetUTF8)
+		case TimeKind:
+			meta.i32Field(6, parquetTimestampMillis)
+		}
+		meta.structEnd()
+	}
+	meta.i64Field(3, totalRows)
+	meta.listField(4, thriftStruct, len(p.rowGroups))
+	for g, chunks := range p.rowGroups {
+		var groupSize int64
+		meta.structBegin()
+		meta.listField(1, thriftStruct, len(chunks))
+		for i, chunk := range chunks {
+			groupSize += chunk.size
+			meta.structBegin()
+			meta.i64Field(2, chunk.offset)
+			meta.structField(3)
+			meta.i32Field(1, parquetTypes[p.columns[i].Kind])
+			meta.listField(2, thriftI32, 2)
+			meta.zigzag(int64(parquetPlain))
+			meta.zigzag(int64(parquetRLE))
+			meta.listField(3, thriftBinary, 1)
+			meta.str(p.columns[i].Name)
+			meta.i32Field(4, parquetUncompressed)
+			meta.i64Field(5, chunk.values)
+			meta.i64Field(6, chunk.size)
+			meta.i64Field(7, chunk.size)
+			meta.i64Field(9, chunk.offset)
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.i64Field(2, groupSize)
+		meta.i64Field(3, p.groupRows[g])
+		meta.structEnd()
+	}
+	meta.stringField(6, parquetCreatedBy)
+	meta.structEnd()
+
+	if err := p.write(meta.Bytes()); err != nil {
+		return errors.Wrap(err, "unable to write parquet metadata")
+	}
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], uint32(len(meta.Bytes())))
+	if err := p.write(footer[:]); err != nil {
+		return err
+	}
+	return p.write([]byte(parquetMagic))
+}
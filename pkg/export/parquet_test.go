@@ -0,0 +1,65 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetPageEncoding(t *testing.T) {
+	page := encodeParquetPage(IntKind, []interface{}{int64(1), nil, int64(-1)})
+	require.Equal(t, []byte{
+		2, 0, 0, 0, // length of the definition levels
+		3, 0b101, // 1 bit-packed group of 8 levels
+		1, 0, 0, 0, 0, 0, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	}, page)
+
+	page = encodeParquetPage(StringKind, []interface{}{"ab", nil})
+	require.Equal(t, []byte{2, 0, 0, 0, 3, 0b01, 2, 0, 0, 0, 'a', 'b'}, page)
+
+	page = encodeParquetPage(BoolKind, []interface{}{true, false, nil, true})
+	require.Equal(t, []byte{2, 0, 0, 0, 3, 0b1011, 0b101}, page)
+}
+
+func TestParquetWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(ParquetFormat, &buf, []Column{
+		{"peer_id", StringKind},
+		{"latency", IntKind},
+	})
+	require.NoError(t, err)
+	pw := w.(*ParquetWriter)
+	pw.rowGroupSize = 2
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.WriteRow([]interface{}{"peer", int64(i)}))
+	}
+	require.NoError(t, w.Close())
+	require.Len(t, pw.rowGroups, 3)
+	require.Equal(t, []int64{2, 2, 1}, pw.groupRows)
+
+	content := buf.Bytes()
+	require.Equal(t, parquetMagic, string(content[:4]))
+	require.Equal(t, parquetMagic, string(content[len(content)-4:]))
+	metaLen := binary.LittleEndian.Uint32(content[len(content)-8 : len(content)-4])
+	// the metadata starts right after the last column chunk
+	last := pw.rowGroups[2][1]
+	require.Equal(t, int64(len(content))-8-int64(metaLen), last.offset+last.size)
+}
+
+func TestThriftCompactEncoding(t *testing.T) {
+	w := &thriftWriter{}
+	w.structBegin()
+	w.i32Field(1, -1)
+	w.stringField(4, "a")
+	w.i64Field(20, 1)
+	w.structEnd()
+	require.Equal(t, []byte{
+		0x15, 0x01, // field 1 i32 zigzag(-1)
+		0x38, 0x01, 'a', // field 4 (delta 3) binary
+		0x06, 0x28, 0x02, // field 20 (delta 16) i64 zigzag(1)
+		0x00,
+	}, w.Bytes())
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// compact protocol types of thrift, used by the metadata of the parquet files
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter encodes structs with the thrift compact protocol
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last field id of each nested struct
+	lastFields []int16
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	t.buf.Write(tmp[:n])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := t.lastFields[len(t.lastFields)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	t.lastFields[len(t.lastFields)-1] = id
+}
+
+func (t *thriftWriter) structBegin() {
+	t.lastFields = append(t.lastFields, 0)
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0) // stop field
+	t.lastFields = t.lastFields[:len(t.lastFields)-1]
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) stringField(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.str(v)
+}
+
+func (t *thriftWriter) str(v string) {
+	t.uvarint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.structBegin()
+}
+
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.uvarint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) Bytes() []byte {
+	return t.buf.Bytes()
+}
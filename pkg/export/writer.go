@@ -0,0 +1,36 @@
+package export
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	CSVFormat     = "csv"
+	ParquetFormat = "parquet"
+)
+
+// Writer encodes the rows of a dataset into a file format
+type Writer interface {
+	// WriteRow writes a row with one value per column, in the order of the columns
+	WriteRow(row []interface{}) error
+	// Close flushes the pending rows and the footer (if any) of the file, not the underlying writer
+	Close() error
+}
+
+// NewWriter returns the writer of the given format (csv or parquet) for the columns
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("no columns to export")
+	}
+	switch strings.ToLower(format) {
+	case CSVFormat:
+		return NewCSVWriter(w, columns)
+	case ParquetFormat:
+		return NewParquetWriter(w, columns)
+	default:
+		return nil, errors.Errorf("unknown export format %s (%s, %s)", format, CSVFormat, ParquetFormat)
+	}
+}
@@ -11,15 +11,17 @@ package gossipsub
 
 import (
 	"context"
-	"encoding/base64"
+	"sync"
+	"time"
 
-	"github.com/minio/sha256-simd"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
-	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
 
+	"github.com/migalabs/armiarma/pkg/db/models"
 	"github.com/migalabs/armiarma/pkg/metrics"
 )
 
@@ -43,7 +45,15 @@ type GossipSub struct {
 	DBClient      database
 	PubsubService *pubsub.PubSub
 	Metrics       *metrics.MetricsModule
+	// gossip behaviour of each of the remote peers
+	peerStats *PeerStatsTracer
+	// messages received on each of the joined topics
+	MessageMetrics *MessageMetrics
+	// subnet of the topics, to aggregate the message rates per subnet (optional)
+	subnetOfTopic SubnetTopicFn
 	// map where the key are the topic names in string, and the values are the TopicSubscription
+	// (topics can be joined and left at runtime, so it is guarded by topicsM)
+	topicsM    sync.RWMutex
 	TopicArray map[string]*TopicSubscription
 }
 
@@ -51,11 +61,85 @@ func NewEmptyGossipSub() *GossipSub {
 	return &GossipSub{}
 }
 
+// GossipSubOption allows adjusting the GossipSub service to the specs of each network
+type GossipSubOption func(*gossipSubConfig) error
+
+type gossipSubConfig struct {
+	msgIDFn           pubsub.MsgIdFunction
+	propagationWindow time.Duration
+	meshInterval      time.Duration
+	subnetOfTopic     SubnetTopicFn
+}
+
+// SubnetTopicFn returns the type and the index of the subnet of a topic, false if the topic isn't a subnet one
+type SubnetTopicFn func(topic string) (string, int, bool)
+
+// WithMsgIDFunction sets the function that computes the message-id of the messages,
+// which has to match the one of the network's clients for the deduplication to be comparable
+func WithMsgIDFunction(msgIDFn pubsub.MsgIdFunction) GossipSubOption {
+	return func(c *gossipSubConfig) error {
+		if msgIDFn == nil {
+			return errors.New("nil message-id function")
+		}
+		c.msgIDFn = msgIDFn
+		return nil
+	}
+}
+
+// WithPropagationTracking records the first arrival of each message and its duplicates within the given window
+func WithPropagationTracking(window time.Duration) GossipSubOption {
+	return func(c *gossipSubConfig) error {
+		if window <= 0 {
+			return errors.Errorf("invalid propagation window %s", window)
+		}
+		c.propagationWindow = window
+		return nil
+	}
+}
+
+// WithMeshSnapshots persists a snapshot of our mesh in each topic, and of the control messages
+// exchanged with the peers, at every given interval
+func WithMeshSnapshots(interval time.Duration) GossipSubOption {
+	return func(c *gossipSubConfig) error {
+		if interval <= 0 {
+			return errors.Errorf("invalid mesh snapshot interval %s", interval)
+		}
+		c.meshInterval = interval
+		return nil
+	}
+}
+
+// WithSubnetMetrics exports the rate of the messages received on each subnet, identifying
+// the subnet of the topics with the given function
+func WithSubnetMetrics(subnetOfTopic SubnetTopicFn) GossipSubOption {
+	return func(c *gossipSubConfig) error {
+		if subnetOfTopic == nil {
+			return errors.New("nil subnet-of-topic function")
+		}
+		c.subnetOfTopic = subnetOfTopic
+		return nil
+	}
+}
+
 // NewGossipSub sumarizes the control fields necesary to manage and govern over a joined and subscribed topic.
-func NewGossipSub(ctx context.Context, h host.Host, dbClient database) *GossipSub {
+// By default, the message-id of the messages is computed as libp2p does (from + seqno)
+func NewGossipSub(ctx context.Context, h host.Host, dbClient database, opts ...GossipSubOption) (*GossipSub, error) {
+
+	// default configuration
+	gsConfig := &gossipSubConfig{
+		msgIDFn: pubsub.DefaultMsgIdFn,
+	}
+	for _, opt := range opts {
+		err := opt(gsConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply gossipsub option")
+		}
+	}
 
 	// Setup the params
 	gossipParams := pubsub.DefaultGossipSubParams()
+	peerStats := NewPeerStatsTracer()
+	scoreParams, scoreThresholds := passivePeerScore()
 
 	// define gossipsub option
 	// Signature is not used in Eth2, therefore it is not needed
@@ -64,43 +148,71 @@ func NewGossipSub(ctx context.Context, h host.Host, dbClient database) *GossipSu
 	psOptions := []pubsub.Option{
 		pubsub.WithMessageSigning(false),
 		pubsub.WithStrictSignatureVerification(false),
-		pubsub.WithMessageIdFn(MsgIDFunction),
+		pubsub.WithMessageIdFn(gsConfig.msgIDFn),
 		pubsub.WithGossipSubParams(gossipParams),
+		// measure the peers without penalizing them
+		pubsub.WithRawTracer(peerStats),
+		pubsub.WithPeerScore(scoreParams, scoreThresholds),
+		pubsub.WithPeerScoreInspect(pubsub.ExtendedPeerScoreInspectFn(peerStats.inspectScores), PeerScoreInspectInterval),
+	}
+	var propagation *PropagationTracer
+	if gsConfig.propagationWindow > 0 {
+		propagation = NewPropagationTracer(gsConfig.propagationWindow)
+		psOptions = append(psOptions, pubsub.WithRawTracer(propagation))
+	}
+	var meshTracer *MeshTracer
+	if gsConfig.meshInterval > 0 {
+		meshTracer = NewMeshTracer()
+		psOptions = append(psOptions, pubsub.WithRawTracer(meshTracer))
 	}
 	ps, err := pubsub.NewGossipSub(ctx, h, psOptions...)
 	if err != nil {
-		log.Panic(err)
+		return nil, errors.Wrap(err, "unable to create gossipsub service")
 	}
 
-	// return the GossipSub object
-	return &GossipSub{
+	gs := &GossipSub{
 		ctx:           ctx,
 		host:          h,
 		DBClient:      dbClient,
 		PubsubService: ps,
 		// Metrics:        metrMod, // TODO: finish this
-		TopicArray: make(map[string]*TopicSubscription),
+		TopicArray:     make(map[string]*TopicSubscription),
+		peerStats:      peerStats,
+		MessageMetrics: NewMessageMetrics(),
+		subnetOfTopic:  gsConfig.subnetOfTopic,
+	}
+	go gs.persistPeerStatsLoop()
+	if propagation != nil && dbClient != nil {
+		go propagation.run(ctx, dbClient)
+	}
+	if meshTracer != nil && dbClient != nil {
+		go meshTracer.run(ctx, gsConfig.meshInterval, dbClient, func(topic string) int {
+			return len(ps.ListPeers(topic))
+		})
 	}
-}
-
-// WithMessageIdFn is an option to customize the way a message ID is computed for a pubsub message
-func MsgIDFunction(pmsg *pubsub_pb.Message) string {
-	h := sha256.New()
-	// never errors, see crypto/sha256 Go doc
 
-	_, _ = h.Write(pmsg.Data)
-	id := h.Sum(nil)
-	return base64.URLEncoding.EncodeToString(id)
+	return gs, nil
 }
 
 // JoinAndSubscribe this method allows the GossipSub service to join and subscribe to a topic.
 func (gs *GossipSub) JoinAndSubscribe(topicName string, handlerFn MessageHandler, persistMsgs bool) {
+	gs.topicsM.Lock()
+	defer gs.topicsM.Unlock()
+	if _, ok := gs.TopicArray[topicName]; ok {
+		log.Debugf("already subscribed to %s", topicName)
+		return
+	}
 	// Join topic
 	topic, err := gs.PubsubService.Join(topicName)
 	if err != nil {
 		log.Errorf("Could not join topic: %s", topicName)
 		log.Errorf(err.Error())
 	}
+	// keep the topic components of the score of the peers
+	if err := topic.SetScoreParams(passiveTopicScore()); err != nil {
+		log.Errorf("Could not set the score params of topic: %s", topicName)
+		log.Errorf(err.Error())
+	}
 	// Subscribe to the topic
 	sub, err := topic.Subscribe()
 	if err != nil {
@@ -109,8 +221,125 @@ func (gs *GossipSub) JoinAndSubscribe(topicName string, handlerFn MessageHandler
 	}
 
 	log.Debugf("subscribed to %s", topicName)
-	topicSub := NewTopicSubscription(gs.ctx, topic, *sub, handlerFn, persistMsgs)
+	gs.MessageMetrics.NewTopic(topicName)
+	topicSub := NewTopicSubscription(gs.ctx, topic, *sub, handlerFn, persistMsgs, gs.peerStats, gs.MessageMetrics)
 	// Add the new Topic to the list of supported/subscribed topics in GossipSub
 	gs.TopicArray[topicName] = topicSub
 	go gs.TopicArray[topicName].MessageReadingLoop(gs.host.ID(), gs.DBClient)
 }
+
+// Leave unsubscribes from the topic and leaves it, dropping its validator (if any)
+func (gs *GossipSub) Leave(topicName string) error {
+	gs.topicsM.Lock()
+	defer gs.topicsM.Unlock()
+	topicSub, ok := gs.TopicArray[topicName]
+	if !ok {
+		return errors.Errorf("not subscribed to topic %s", topicName)
+	}
+	delete(gs.TopicArray, topicName)
+	if err := topicSub.Close(); err != nil {
+		return errors.Wrapf(err, "unable to leave topic %s", topicName)
+	}
+	// the topics without validator return an error, nothing to drop there
+	if err := gs.PubsubService.UnregisterTopicValidator(topicName); err != nil {
+		log.Debugf("no validator dropped for %s: %s", topicName, err.Error())
+	}
+	log.Debugf("left %s", topicName)
+	return nil
+}
+
+// Topics returns the names of the subscribed topics
+func (gs *GossipSub) Topics() []string {
+	gs.topicsM.RLock()
+	defer gs.topicsM.RUnlock()
+	topics := make([]string, 0, len(gs.TopicArray))
+	for topicName := range gs.TopicArray {
+		topics = append(topics, topicName)
+	}
+	return topics
+}
+
+// persistPeerStatsLoop periodically persists the messages that each peer delivered us on each topic,
+// and a snapshot of the score of each peer
+func (gs *GossipSub) persistPeerStatsLoop() {
+	ticker := time.NewTicker(PeerTopicsPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			gs.persistPeerTopics()
+			gs.persistPeerScores()
+		case <-gs.ctx.Done():
+			return
+		}
+	}
+}
+
+func (gs *GossipSub) persistPeerTopics() {
+	if gs.DBClient == nil {
+		return
+	}
+	for p, topics := range gs.peerStats.TopicMessages() {
+		for topic, msgs := range topics {
+			if msgs == 0 {
+				continue
+			}
+			gs.DBClient.PersistToDB(models.NewPeerTopicMessages(p, topic, int64(msgs)))
+		}
+	}
+}
+
+func (gs *GossipSub) persistPeerScores() {
+	if gs.DBClient == nil {
+		return
+	}
+	now := time.Now()
+	for p, stats := range gs.peerStats.PeerStats() {
+		if !stats.HasScore {
+			continue
+		}
+		gs.DBClient.PersistToDB(&models.PeerGossipScore{
+			PeerID:             p,
+			Timestamp:          now,
+			Score:              stats.Score,
+			TimeInMesh:         stats.Components.TimeInMesh,
+			FirstDeliveries:    stats.Components.FirstDeliveries,
+			MeshDeliveries:     stats.Components.MeshDeliveries,
+			InvalidDeliveries:  stats.Components.InvalidDeliveries,
+			AppSpecificScore:   stats.Components.AppSpecificScore,
+			IPColocationFactor: stats.Components.IPColocationFactor,
+			BehaviourPenalty:   stats.Components.BehaviourPenalty,
+		})
+	}
+}
+
+// CountMessageHandler only accounts the messages of the topic in the MessageMetrics, without parsing nor persisting them
+func CountMessageHandler(msg *pubsub.Message) (PersistableMsg, error) {
+	return countedMsg{}, nil
+}
+
+type countedMsg struct{}
+
+func (m countedMsg) IsZero() bool {
+	return true
+}
+
+// TopicPeers returns the list of unique peers that we track in any of the joined topics
+func (gs *GossipSub) TopicPeers() []peer.ID {
+	peerSet := make(map[peer.ID]struct{})
+	for _, topicName := range gs.Topics() {
+		for _, p := range gs.PubsubService.ListPeers(topicName) {
+			peerSet[p] = struct{}{}
+		}
+	}
+	peers := make([]peer.ID, 0, len(peerSet))
+	for p := range peerSet {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// PeerGossipStats returns the accumulated gossip behaviour of each of the peers that forwarded us messages
+func (gs *GossipSub) PeerGossipStats() map[peer.ID]PeerGossipStats {
+	return gs.peerStats.PeerStats()
+}
@@ -0,0 +1,202 @@
+package gossipsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+/**
+The mesh tracer follows the GRAFT/PRUNE decisions of our router to keep the mesh of each topic,
+and counts the control messages that we exchange with the peers. The RPCs that we receive
+don't carry the peer that sent them to the tracer, so the received control messages are only
+accounted by topic, while the sent ones are accounted by peer. The crawler doesn't publish,
+so there is no fanout to follow.
+*/
+
+// topicMesh is the mesh of a topic, and the changes since the previous snapshot
+type topicMesh struct {
+	mesh      map[peer.ID]struct{}
+	grafted   []peer.ID
+	pruned    []peer.ID
+	recvGraft int64
+	recvPrune int64
+	recvIHave int64
+}
+
+func newTopicMesh() *topicMesh {
+	return &topicMesh{
+		mesh: make(map[peer.ID]struct{}),
+	}
+}
+
+// MeshTracer implements the pubsub.RawTracer to take periodic snapshots of our mesh in each topic
+type MeshTracer struct {
+	m       sync.Mutex
+	topics  map[string]*topicMesh
+	control map[peer.ID]*models.GossipControlCounts
+}
+
+func NewMeshTracer() *MeshTracer {
+	return &MeshTracer{
+		topics:  make(map[string]*topicMesh),
+		control: make(map[peer.ID]*models.GossipControlCounts),
+	}
+}
+
+// topic returns the mesh of the topic (needs the lock)
+func (t *MeshTracer) topic(topic string) *topicMesh {
+	tm, ok := t.topics[topic]
+	if !ok {
+		tm = newTopicMesh()
+		t.topics[topic] = tm
+	}
+	return tm
+}
+
+// Snapshot returns the state of the mesh of each topic and the control messages sent to each peer,
+// resetting the changes and the counters
+func (t *MeshTracer) Snapshot(topicPeers func(topic string) int) ([]*models.GossipMeshSnapshot, []*models.GossipControlCounts) {
+	now := time.Now()
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	meshes := make([]*models.GossipMeshSnapshot, 0, len(t.topics))
+	for topic, tm := range t.topics {
+		mesh := make([]peer.ID, 0, len(tm.mesh))
+		for p := range tm.mesh {
+			mesh = append(mesh, p)
+		}
+		meshes = append(meshes, &models.GossipMeshSnapshot{
+			Timestamp:  now,
+			Topic:      topic,
+			Mesh:       mesh,
+			TopicPeers: topicPeers(topic),
+			Grafted:    tm.grafted,
+			Pruned:     tm.pruned,
+			RecvGraft:  tm.recvGraft,
+			RecvPrune:  tm.recvPrune,
+			RecvIHave:  tm.recvIHave,
+		})
+		tm.grafted = nil
+		tm.pruned = nil
+		tm.recvGraft, tm.recvPrune, tm.recvIHave = 0, 0, 0
+	}
+
+	control := make([]*models.GossipControlCounts, 0, len(t.control))
+	for _, counts := range t.control {
+		counts.Timestamp = now
+		control = append(control, counts)
+	}
+	t.control = make(map[peer.ID]*models.GossipControlCounts)
+
+	return meshes, control
+}
+
+// run persists a snapshot of the meshes at every interval
+func (t *MeshTracer) run(ctx context.Context, interval time.Duration, db database, topicPeers func(topic string) int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			meshes, control := t.Snapshot(topicPeers)
+			for _, mesh := range meshes {
+				db.PersistToDB(mesh)
+			}
+			for _, counts := range control {
+				db.PersistToDB(counts)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *MeshTracer) Graft(p peer.ID, topic string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	tm := t.topic(topic)
+	tm.mesh[p] = struct{}{}
+	tm.grafted = append(tm.grafted, p)
+}
+
+func (t *MeshTracer) Prune(p peer.ID, topic string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	tm := t.topic(topic)
+	delete(tm.mesh, p)
+	tm.pruned = append(tm.pruned, p)
+}
+
+func (t *MeshTracer) RemovePeer(p peer.ID) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	// the peers leave the meshes without being pruned when they disconnect
+	for _, tm := range t.topics {
+		delete(tm.mesh, p)
+	}
+}
+
+func (t *MeshTracer) Leave(topic string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	delete(t.topics, topic)
+}
+
+func (t *MeshTracer) RecvRPC(rpc *pubsub.RPC) {
+	ctl := rpc.GetControl()
+	if ctl == nil {
+		return
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	for _, graft := range ctl.GetGraft() {
+		t.topic(graft.GetTopicID()).recvGraft++
+	}
+	for _, prune := range ctl.GetPrune() {
+		t.topic(prune.GetTopicID()).recvPrune++
+	}
+	for _, ihave := range ctl.GetIhave() {
+		t.topic(ihave.GetTopicID()).recvIHave++
+	}
+}
+
+func (t *MeshTracer) SendRPC(rpc *pubsub.RPC, p peer.ID) {
+	ctl := rpc.GetControl()
+	if ctl == nil {
+		return
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	counts, ok := t.control[p]
+	if !ok {
+		counts = &models.GossipControlCounts{PeerID: p}
+		t.control[p] = counts
+	}
+	countControl(ctl, counts)
+}
+
+func countControl(ctl *pubsub_pb.ControlMessage, counts *models.GossipControlCounts) {
+	counts.Graft += int64(len(ctl.GetGraft()))
+	counts.Prune += int64(len(ctl.GetPrune()))
+	counts.IHave += int64(len(ctl.GetIhave()))
+	counts.IWant += int64(len(ctl.GetIwant()))
+}
+
+func (t *MeshTracer) AddPeer(p peer.ID, proto protocol.ID)             {}
+func (t *MeshTracer) Join(topic string)                                {}
+func (t *MeshTracer) ValidateMessage(msg *pubsub.Message)              {}
+func (t *MeshTracer) DeliverMessage(msg *pubsub.Message)               {}
+func (t *MeshTracer) RejectMessage(msg *pubsub.Message, reason string) {}
+func (t *MeshTracer) DuplicateMessage(msg *pubsub.Message)             {}
+func (t *MeshTracer) ThrottlePeer(p peer.ID)                           {}
+func (t *MeshTracer) DropRPC(rpc *pubsub.RPC, p peer.ID)               {}
+func (t *MeshTracer) UndeliverableMessage(*pubsub.Message)             {}
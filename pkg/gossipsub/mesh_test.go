@@ -0,0 +1,105 @@
+package gossipsub
+
+import (
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func newTestControlRPC(graft, prune, ihave []string, iwants int) *pubsub.RPC {
+	ctl := &pubsub_pb.ControlMessage{}
+	for i := range graft {
+		ctl.Graft = append(ctl.Graft, &pubsub_pb.ControlGraft{TopicID: &graft[i]})
+	}
+	for i := range prune {
+		ctl.Prune = append(ctl.Prune, &pubsub_pb.ControlPrune{TopicID: &prune[i]})
+	}
+	for i := range ihave {
+		ctl.Ihave = append(ctl.Ihave, &pubsub_pb.ControlIHave{TopicID: &ihave[i]})
+	}
+	for i := 0; i < iwants; i++ {
+		ctl.Iwant = append(ctl.Iwant, &pubsub_pb.ControlIWant{})
+	}
+	return &pubsub.RPC{RPC: pubsub_pb.RPC{Control: ctl}}
+}
+
+func meshesByTopic(meshes []*models.GossipMeshSnapshot) map[string]*models.GossipMeshSnapshot {
+	byTopic := make(map[string]*models.GossipMeshSnapshot, len(meshes))
+	for _, mesh := range meshes {
+		byTopic[mesh.Topic] = mesh
+	}
+	return byTopic
+}
+
+func Test_MeshTracerSnapshot(t *testing.T) {
+	tracer := NewMeshTracer()
+	peerA, peerB, peerC := peer.ID("peer-a"), peer.ID("peer-b"), peer.ID("peer-c")
+	topicPeers := func(topic string) int { return len(topic) }
+
+	tracer.Graft(peerA, "beacon_block")
+	tracer.Graft(peerB, "beacon_block")
+	tracer.Graft(peerC, "beacon_block")
+	tracer.Prune(peerB, "beacon_block")
+	tracer.Graft(peerA, "voluntary_exit")
+	// the disconnected peers leave every mesh, without being accounted as pruned
+	tracer.RemovePeer(peerC)
+	tracer.RecvRPC(newTestControlRPC([]string{"beacon_block"}, []string{"beacon_block", "voluntary_exit"}, []string{"beacon_block", "beacon_block"}, 1))
+	// the RPCs without control messages are ignored
+	tracer.RecvRPC(&pubsub.RPC{})
+
+	meshes, _ := tracer.Snapshot(topicPeers)
+	require.Len(t, meshes, 2)
+	block := meshesByTopic(meshes)["beacon_block"]
+	require.ElementsMatch(t, []peer.ID{peerA}, block.Mesh)
+	require.Equal(t, []peer.ID{peerA, peerB, peerC}, block.Grafted)
+	require.Equal(t, []peer.ID{peerB}, block.Pruned)
+	require.Equal(t, len("beacon_block"), block.TopicPeers)
+	require.Equal(t, int64(1), block.RecvGraft)
+	require.Equal(t, int64(1), block.RecvPrune)
+	require.Equal(t, int64(2), block.RecvIHave)
+	require.Equal(t, int64(1), meshesByTopic(meshes)["voluntary_exit"].RecvPrune)
+
+	// the changes restart after each snapshot, the mesh stays
+	meshes, _ = tracer.Snapshot(topicPeers)
+	block = meshesByTopic(meshes)["beacon_block"]
+	require.ElementsMatch(t, []peer.ID{peerA}, block.Mesh)
+	require.Empty(t, block.Grafted)
+	require.Empty(t, block.Pruned)
+	require.Zero(t, block.RecvGraft+block.RecvPrune+block.RecvIHave)
+
+	// the topics that we leave aren't snapshotted anymore
+	tracer.Leave("voluntary_exit")
+	meshes, _ = tracer.Snapshot(topicPeers)
+	require.Len(t, meshes, 1)
+}
+
+func Test_MeshTracerSentControl(t *testing.T) {
+	tracer := NewMeshTracer()
+	peerA, peerB := peer.ID("peer-a"), peer.ID("peer-b")
+
+	tracer.SendRPC(newTestControlRPC([]string{"beacon_block"}, nil, []string{"beacon_block", "voluntary_exit"}, 0), peerA)
+	tracer.SendRPC(newTestControlRPC(nil, []string{"beacon_block"}, nil, 3), peerA)
+	tracer.SendRPC(newTestControlRPC([]string{"beacon_block"}, nil, nil, 0), peerB)
+	tracer.SendRPC(&pubsub.RPC{}, peerB)
+
+	_, control := tracer.Snapshot(func(string) int { return 0 })
+	require.Len(t, control, 2)
+	byPeer := make(map[peer.ID]models.GossipControlCounts)
+	for _, counts := range control {
+		require.False(t, counts.Timestamp.IsZero())
+		counts.Timestamp = time.Time{}
+		byPeer[counts.PeerID] = *counts
+	}
+	require.Equal(t, models.GossipControlCounts{PeerID: peerA, Graft: 1, Prune: 1, IHave: 2, IWant: 3}, byPeer[peerA])
+	require.Equal(t, models.GossipControlCounts{PeerID: peerB, Graft: 1}, byPeer[peerB])
+
+	// the counters restart after each snapshot
+	_, control = tracer.Snapshot(func(string) int { return 0 })
+	require.Empty(t, control)
+}
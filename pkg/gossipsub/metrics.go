@@ -1,6 +1,9 @@
 package gossipsub
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/migalabs/armiarma/pkg/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
@@ -9,51 +12,81 @@ import (
 var (
 	moduleName    = "gossipsub"
 	moduleDetails = "All the metrics around the gossipsub host"
-
-	ReceivedTotalMessages = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: moduleName,
-		Name:      "total_received_messages_psec",
-		Help:      "The number of messages received in the last second",
-	})
-	ReceivedMessages = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: moduleName,
-		Name:      "received_messages_psec",
-		Help:      "Number of messages received per second on each topic",
-	},
-		[]string{"topic"},
-	)
-	PeersPerTopic = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: moduleName,
-		Name:      "peers_per_topic",
-		Help:      "Number of peers that we have connected per topic",
-	},
-		[]string{"topic"},
-	)
 )
 
+// gossipMetrics are the collectors of the metrics of the gossipsub host, one set per crawler
+type gossipMetrics struct {
+	ReceivedTotalMessages  prometheus.Gauge
+	ReceivedMessages       *prometheus.GaugeVec
+	ReceivedSubnetMessages *prometheus.GaugeVec
+	PeersPerTopic          *prometheus.GaugeVec
+	PeerScoreComponents    *prometheus.GaugeVec
+}
+
+func newGossipMetrics() *gossipMetrics {
+	return &gossipMetrics{
+		ReceivedTotalMessages: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "total_received_messages_psec",
+			Help:      "The number of messages received in the last second",
+		}),
+		ReceivedMessages: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "received_messages_psec",
+			Help:      "Number of messages received per second on each topic",
+		},
+			[]string{"topic"},
+		),
+		ReceivedSubnetMessages: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "received_subnet_messages_psec",
+			Help:      "Number of messages received per second on each subnet",
+		},
+			[]string{"subnet_type", "subnet"},
+		),
+		PeersPerTopic: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "peers_per_topic",
+			Help:      "Number of peers that we have connected per topic",
+		},
+			[]string{"topic"},
+		),
+		PeerScoreComponents: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "peer_score_components",
+			Help:      "Gossipsub v1.1 score that we give to each peer, and its components (P1-P7)",
+		},
+			[]string{"peer_id", "component"},
+		),
+	}
+}
+
 func (gs *GossipSub) GetMetrics() *metrics.MetricsModule {
 
 	metricsMod := metrics.NewMetricsModule(
 		moduleName,
 		moduleDetails,
 	)
+	m := newGossipMetrics()
 
-	metricsMod.AddIndvMetric(gs.peersPerTopic())
+	metricsMod.AddIndvMetric(gs.peersPerTopic(m))
+	metricsMod.AddIndvMetric(gs.receivedMessages(m))
+	metricsMod.AddIndvMetric(gs.peerScores(m))
 
 	return metricsMod
 }
 
-func (gs *GossipSub) peersPerTopic() *metrics.IndvMetrics {
+func (gs *GossipSub) peersPerTopic(m *gossipMetrics) *metrics.IndvMetrics {
 
-	initFn := func() error {
-		prometheus.Register(PeersPerTopic)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(m.PeersPerTopic)
 		return nil
 	}
 
 	updateFn := func() (interface{}, error) {
 		summary := gs.PubsubService.TopicsPerPeer()
 		for top, value := range summary {
-			PeersPerTopic.WithLabelValues(top).Set(float64(value))
+			m.PeersPerTopic.WithLabelValues(top).Set(float64(value))
 		}
 		return summary, nil
 	}
@@ -69,3 +102,88 @@ func (gs *GossipSub) peersPerTopic() *metrics.IndvMetrics {
 	}
 	return peersTop
 }
+
+func (gs *GossipSub) receivedMessages(m *gossipMetrics) *metrics.IndvMetrics {
+	lastUpdate := time.Now()
+
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(m.ReceivedMessages)
+		reg.Register(m.ReceivedTotalMessages)
+		reg.Register(m.ReceivedSubnetMessages)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		// messages per second on each topic since the last update
+		elapsed := time.Since(lastUpdate).Seconds()
+		lastUpdate = time.Now()
+		summary := make(map[string]float64)
+		var total float64
+		for top, msgs := range gs.MessageMetrics.Snapshot() {
+			rate := float64(msgs) / elapsed
+			m.ReceivedMessages.WithLabelValues(top).Set(rate)
+			summary[top] = rate
+			total += rate
+			if gs.subnetOfTopic == nil {
+				continue
+			}
+			if subnetType, subnet, ok := gs.subnetOfTopic(top); ok {
+				m.ReceivedSubnetMessages.WithLabelValues(subnetType, strconv.Itoa(subnet)).Set(rate)
+			}
+		}
+		m.ReceivedTotalMessages.Set(total)
+		return summary, nil
+	}
+
+	receivedMsgs, err := metrics.NewIndvMetrics(
+		"received_messages",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return receivedMsgs
+}
+
+func (gs *GossipSub) peerScores(m *gossipMetrics) *metrics.IndvMetrics {
+
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(m.PeerScoreComponents)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		// drop the peers that we don't score anymore
+		m.PeerScoreComponents.Reset()
+		scored := 0
+		for p, stats := range gs.peerStats.PeerStats() {
+			if !stats.HasScore {
+				continue
+			}
+			scored++
+			peerID := p.String()
+			m.PeerScoreComponents.WithLabelValues(peerID, "score").Set(stats.Score)
+			m.PeerScoreComponents.WithLabelValues(peerID, "p1_time_in_mesh").Set(stats.Components.TimeInMesh.Seconds())
+			m.PeerScoreComponents.WithLabelValues(peerID, "p2_first_deliveries").Set(stats.Components.FirstDeliveries)
+			m.PeerScoreComponents.WithLabelValues(peerID, "p3_mesh_deliveries").Set(stats.Components.MeshDeliveries)
+			m.PeerScoreComponents.WithLabelValues(peerID, "p4_invalid_deliveries").Set(stats.Components.InvalidDeliveries)
+			m.PeerScoreComponents.WithLabelValues(peerID, "p5_app_specific").Set(stats.Components.AppSpecificScore)
+			m.PeerScoreComponents.WithLabelValues(peerID, "p6_ip_colocation").Set(stats.Components.IPColocationFactor)
+			m.PeerScoreComponents.WithLabelValues(peerID, "p7_behaviour_penalty").Set(stats.Components.BehaviourPenalty)
+		}
+		return scored, nil
+	}
+
+	scores, err := metrics.NewIndvMetrics(
+		"peer_score_components",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return scores
+}
@@ -2,22 +2,22 @@ package gossipsub
 
 import (
 	"fmt"
+	"sync"
 	"sync/atomic"
-
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 // MessageMetrics
-// fgdgdfgdfgSummarizes all the metrics that could be obtained from the received msgs.
+// Summarizes all the metrics that could be obtained from the received msgs.
 // Right now divided by topic and containing only the local counter between server ticker.
 type MessageMetrics struct {
+	m         sync.RWMutex
 	topicList map[string]*int32
 }
 
 // NewMessageMetrics:
 // @return intialized MessageMetrics struct
-func NewMessageMetrics() MessageMetrics {
-	return MessageMetrics{
+func NewMessageMetrics() *MessageMetrics {
+	return &MessageMetrics{
 		topicList: make(map[string]*int32, 0),
 	}
 }
@@ -27,6 +27,8 @@ func NewMessageMetrics() MessageMetrics {
 // @return a possitive boolean if the topic was
 // already in Metrics, negative one otherwise
 func (c *MessageMetrics) NewTopic(topic string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
 	var counter int32
 	atomic.StoreInt32(&counter, 0)
 	_, exists := c.topicList[topic]
@@ -41,6 +43,8 @@ func (c *MessageMetrics) NewTopic(topic string) bool {
 // @param gossipsub topic name.
 // @return curren message counter, or -1 if there was an error (non-existing topic).
 func (c *MessageMetrics) AddMessgeToTopic(topic string) int32 {
+	c.m.RLock()
+	defer c.m.RUnlock()
 	v, exists := c.topicList[topic]
 	if !exists {
 		return int32(-1)
@@ -48,10 +52,24 @@ func (c *MessageMetrics) AddMessgeToTopic(topic string) int32 {
 	return atomic.AddInt32(v, 1)
 }
 
+// AddMessage:
+// Same as AddMessgeToTopic, but starts tracking the topic if it wasn't tracked yet.
+// @param gossipsub topic name.
+// @return curren message counter.
+func (c *MessageMetrics) AddMessage(topic string) int32 {
+	if r := c.AddMessgeToTopic(topic); r >= 0 {
+		return r
+	}
+	c.NewTopic(topic)
+	return c.AddMessgeToTopic(topic)
+}
+
 // ResetTopic:
 // @param gossipsub topic name.
 // @return curren message counter, or -1 if there was an error (non-existing topic).
 func (c *MessageMetrics) ResetTopic(topic string) int32 {
+	c.m.RLock()
+	defer c.m.RUnlock()
 	v, exists := c.topicList[topic]
 	if !exists {
 		return int32(-1)
@@ -63,7 +81,7 @@ func (c *MessageMetrics) ResetTopic(topic string) int32 {
 // Resets all the topic counters to 0.
 // @return current message counter, or -1 if there was an error (non-existing topic).
 func (c *MessageMetrics) ResetAllTopics() error {
-	for k, _ := range c.topicList {
+	for _, k := range c.Topics() {
 		r := c.ResetTopic(k)
 		if r < int32(0) {
 			return fmt.Errorf("non existing topic %s in list", k)
@@ -76,6 +94,8 @@ func (c *MessageMetrics) ResetAllTopics() error {
 // Obtain the counter of messages from last ticker of given topic.
 // @return current message counter, or -1 if there was an error (non-existing topic).
 func (c *MessageMetrics) GetTopicMsgs(topic string) int32 {
+	c.m.RLock()
+	defer c.m.RUnlock()
 	v, exists := c.topicList[topic]
 	if !exists {
 		return int32(-1)
@@ -88,7 +108,7 @@ func (c *MessageMetrics) GetTopicMsgs(topic string) int32 {
 // @return total message counter, or -1 if there was an error (non-existing topic).
 func (c *MessageMetrics) GetTotalMessages() int64 {
 	var total int64
-	for k, _ := range c.topicList {
+	for _, k := range c.Topics() {
 		r := c.ResetTopic(k)
 		if r < int32(0) {
 			continue
@@ -98,37 +118,27 @@ func (c *MessageMetrics) GetTotalMessages() int64 {
 	return total
 }
 
-func (gs *GossipSub) initGossipPrometheusMetrics() {
-	// register variables
-	prometheus.MustRegister(ReceivedTotalMessages)
-	prometheus.MustRegister(ReceivedMessages)
+// Topics:
+// @return the list of tracked topics.
+func (c *MessageMetrics) Topics() []string {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	topics := make([]string, 0, len(c.topicList))
+	for k := range c.topicList {
+		topics = append(topics, k)
+	}
+	return topics
 }
 
-// func (gs *GossipSub) runGossipPrometheusMetrics() {
-// 	var totMsg int64
-// 	msgPerMin := make(map[string]float64, 0)
-// 	// get the total of the messages
-// 	for k, _ := range gs.MessageMetrics.topicList {
-// 		r := gs.MessageMetrics.GetTopicMsgs(k)
-// 		if r < int32(0) {
-// 			log.Warnf("Unable to get message count for topic %s", k)
-// 			continue
-// 		}
-// 		msgC := (float64(r) / (exporters.MetricLoopInterval.Seconds())) * 60 // messages per minute
-// 		totMsg += int64(r)
-// 		ReceivedMessages.WithLabelValues(eth.Eth2TopicPretty(k)).Set(msgC)
-// 		msgPerMin[eth.Eth2TopicPretty(k)] = msgC
-// 	}
-// 	// get total of msgs
-// 	tot := (float64(totMsg) / (exporters.MetricLoopInterval.Seconds())) * 60 // messages per minute
-// 	ReceivedTotalMessages.Set(tot)
-// 	// reset the values
-// 	err := gs.MessageMetrics.ResetAllTopics()
-// 	if err != nil {
-// 		log.Warnf("Unable to reset the gossip topic metrics. ", err.Error())
-// 	}
-// 	log.WithFields(log.Fields{
-// 		"TopicMsg/min": msgPerMin,
-// 		"TotalMsg/min": tot,
-// 	}).Info("gossip metrics summary")
-// }
+// Snapshot:
+// Obtain the counter of messages of each topic since the last snapshot, resetting them.
+// @return map of message counters by topic.
+func (c *MessageMetrics) Snapshot() map[string]int32 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	snapshot := make(map[string]int32, len(c.topicList))
+	for k, v := range c.topicList {
+		snapshot[k] = atomic.SwapInt32(v, int32(0))
+	}
+	return snapshot
+}
@@ -0,0 +1,34 @@
+package gossipsub
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MessageMetricsSnapshot(t *testing.T) {
+	msgMetrics := NewMessageMetrics()
+	require.False(t, msgMetrics.NewTopic("beacon_block"))
+	require.True(t, msgMetrics.NewTopic("beacon_block"))
+	require.False(t, msgMetrics.NewTopic("sync_committee_0"))
+
+	// the messages of untracked topics aren't counted
+	require.Equal(t, int32(-1), msgMetrics.AddMessgeToTopic("blob_sidecar_0"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msgMetrics.AddMessgeToTopic("beacon_block")
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), msgMetrics.AddMessgeToTopic("sync_committee_0"))
+
+	require.Equal(t, map[string]int32{"beacon_block": 50, "sync_committee_0": 1}, msgMetrics.Snapshot())
+	// the counters are reset by the snapshot, but the topics are still tracked
+	require.Equal(t, map[string]int32{"beacon_block": 0, "sync_committee_0": 0}, msgMetrics.Snapshot())
+	require.ElementsMatch(t, []string{"beacon_block", "sync_committee_0"}, msgMetrics.Topics())
+}
@@ -0,0 +1,206 @@
+package gossipsub
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+var (
+	// periodicity of the peer score inspection
+	PeerScoreInspectInterval = 1 * time.Minute
+	// periodicity of the persistence of the messages delivered by each peer on each topic, and of their scores
+	PeerTopicsPersistInterval = 5 * time.Minute
+)
+
+// PeerGossipStats accumulates the gossip behaviour of a remote peer
+type PeerGossipStats struct {
+	Score      float64 // latest gossipsub peer score
+	HasScore   bool
+	Delivered  int64 // first deliveries of messages
+	Duplicates int64
+	Invalid    int64 // rejected or undecodable messages
+	// latest components of the score (the topic ones, P1-P4, added up for all the topics)
+	Components ScoreComponents
+}
+
+// ScoreComponents are the values of the gossipsub v1.1 score components of a peer, as we score it.
+// The topic components (P1-P4) are the raw counters, as the topics don't weight in the passive score
+type ScoreComponents struct {
+	TimeInMesh         time.Duration // P1
+	FirstDeliveries    float64       // P2
+	MeshDeliveries     float64       // P3
+	InvalidDeliveries  float64       // P4
+	AppSpecificScore   float64       // P5
+	IPColocationFactor float64       // P6
+	BehaviourPenalty   float64       // P7
+}
+
+// PeerStatsTracer implements the pubsub.RawTracer to account the messages that each peer forwards us
+// (in total and by topic), and keeps the latest gossipsub score of each of them
+type PeerStatsTracer struct {
+	m      sync.Mutex
+	stats  map[peer.ID]*PeerGossipStats
+	topics map[peer.ID]*MessageMetrics
+}
+
+func NewPeerStatsTracer() *PeerStatsTracer {
+	return &PeerStatsTracer{
+		stats:  make(map[peer.ID]*PeerGossipStats),
+		topics: make(map[peer.ID]*MessageMetrics),
+	}
+}
+
+// PeerStats returns a copy of the accumulated stats of each peer
+func (t *PeerStatsTracer) PeerStats() map[peer.ID]PeerGossipStats {
+	t.m.Lock()
+	defer t.m.Unlock()
+	stats := make(map[peer.ID]PeerGossipStats, len(t.stats))
+	for p, s := range t.stats {
+		stats[p] = *s
+	}
+	return stats
+}
+
+// PeerTopics returns the messages delivered by the peer on each topic (nil if the peer didn't deliver any)
+func (t *PeerStatsTracer) PeerTopics(p peer.ID) *MessageMetrics {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return t.topics[p]
+}
+
+// TopicMessages returns the messages delivered by each peer on each topic since the previous call
+func (t *PeerStatsTracer) TopicMessages() map[peer.ID]map[string]int32 {
+	t.m.Lock()
+	peers := make(map[peer.ID]*MessageMetrics, len(t.topics))
+	for p, msgMetrics := range t.topics {
+		peers[p] = msgMetrics
+	}
+	t.m.Unlock()
+
+	topicMsgs := make(map[peer.ID]map[string]int32, len(peers))
+	for p, msgMetrics := range peers {
+		topicMsgs[p] = msgMetrics.Snapshot()
+	}
+	return topicMsgs
+}
+
+// RecordInvalid accounts a message from the peer that we were unable to process
+func (t *PeerStatsTracer) RecordInvalid(p peer.ID) {
+	t.update(p, func(s *PeerGossipStats) { s.Invalid++ })
+}
+
+// inspectScores receives the periodic snapshot of the gossipsub peer scores and their components
+func (t *PeerStatsTracer) inspectScores(snapshots map[peer.ID]*pubsub.PeerScoreSnapshot) {
+	for p, snapshot := range snapshots {
+		components := ScoreComponents{
+			AppSpecificScore:   snapshot.AppSpecificScore,
+			IPColocationFactor: snapshot.IPColocationFactor,
+			BehaviourPenalty:   snapshot.BehaviourPenalty,
+		}
+		for _, topic := range snapshot.Topics {
+			components.TimeInMesh += topic.TimeInMesh
+			components.FirstDeliveries += topic.FirstMessageDeliveries
+			components.MeshDeliveries += topic.MeshMessageDeliveries
+			components.InvalidDeliveries += topic.InvalidMessageDeliveries
+		}
+		score := snapshot.Score
+		t.update(p, func(s *PeerGossipStats) {
+			s.Score = score
+			s.HasScore = true
+			s.Components = components
+		})
+	}
+}
+
+func (t *PeerStatsTracer) update(p peer.ID, fn func(*PeerGossipStats)) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	s, ok := t.stats[p]
+	if !ok {
+		s = &PeerGossipStats{}
+		t.stats[p] = s
+	}
+	fn(s)
+}
+
+func (t *PeerStatsTracer) DeliverMessage(msg *pubsub.Message) {
+	t.update(msg.ReceivedFrom, func(s *PeerGossipStats) { s.Delivered++ })
+
+	t.m.Lock()
+	msgMetrics, ok := t.topics[msg.ReceivedFrom]
+	if !ok {
+		msgMetrics = NewMessageMetrics()
+		t.topics[msg.ReceivedFrom] = msgMetrics
+	}
+	t.m.Unlock()
+	msgMetrics.AddMessage(msg.GetTopic())
+}
+
+func (t *PeerStatsTracer) DuplicateMessage(msg *pubsub.Message) {
+	t.update(msg.ReceivedFrom, func(s *PeerGossipStats) { s.Duplicates++ })
+}
+
+func (t *PeerStatsTracer) RejectMessage(msg *pubsub.Message, reason string) {
+	switch reason {
+	// rejections caused by our own validation pipeline say nothing about the peer
+	case pubsub.RejectValidationQueueFull, pubsub.RejectValidationThrottled, pubsub.RejectValidationIgnored, pubsub.RejectSelfOrigin:
+		return
+	}
+	t.update(msg.ReceivedFrom, func(s *PeerGossipStats) { s.Invalid++ })
+}
+
+func (t *PeerStatsTracer) AddPeer(p peer.ID, proto protocol.ID) {}
+func (t *PeerStatsTracer) RemovePeer(p peer.ID)                 {}
+func (t *PeerStatsTracer) Join(topic string)                    {}
+func (t *PeerStatsTracer) Leave(topic string)                   {}
+func (t *PeerStatsTracer) Graft(p peer.ID, topic string)        {}
+func (t *PeerStatsTracer) Prune(p peer.ID, topic string)        {}
+func (t *PeerStatsTracer) ValidateMessage(msg *pubsub.Message)  {}
+func (t *PeerStatsTracer) ThrottlePeer(p peer.ID)               {}
+func (t *PeerStatsTracer) RecvRPC(rpc *pubsub.RPC)              {}
+func (t *PeerStatsTracer) SendRPC(rpc *pubsub.RPC, p peer.ID)   {}
+func (t *PeerStatsTracer) DropRPC(rpc *pubsub.RPC, p peer.ID)   {}
+func (t *PeerStatsTracer) UndeliverableMessage(*pubsub.Message) {}
+
+// passivePeerScore returns the gossipsub scoring parameters used to measure the peers,
+// with thresholds low enough to never graylist, or stop gossiping with, any of them
+func passivePeerScore() (*pubsub.PeerScoreParams, *pubsub.PeerScoreThresholds) {
+	params := &pubsub.PeerScoreParams{
+		Topics:                      make(map[string]*pubsub.TopicScoreParams),
+		AppSpecificScore:            func(peer.ID) float64 { return 0 },
+		IPColocationFactorWeight:    -5,
+		IPColocationFactorThreshold: 10,
+		BehaviourPenaltyWeight:      -10,
+		BehaviourPenaltyThreshold:   6,
+		BehaviourPenaltyDecay:       0.9,
+		DecayInterval:               1 * time.Minute,
+		DecayToZero:                 0.01,
+		RetainScore:                 10 * time.Minute,
+	}
+	thresholds := &pubsub.PeerScoreThresholds{
+		GossipThreshold:   -math.MaxFloat64,
+		PublishThreshold:  -math.MaxFloat64,
+		GraylistThreshold: -math.MaxFloat64,
+	}
+	return params, thresholds
+}
+
+// passiveTopicScore returns the scoring parameters of the joined topics, which keep the counters
+// of the topic components (P1-P4) of the peers without weighting them in their score
+func passiveTopicScore() *pubsub.TopicScoreParams {
+	return &pubsub.TopicScoreParams{
+		TopicWeight:                   0,
+		TimeInMeshQuantum:             1 * time.Second,
+		TimeInMeshCap:                 3600,
+		FirstMessageDeliveriesDecay:   0.9,
+		FirstMessageDeliveriesCap:     math.MaxInt32,
+		MeshMessageDeliveriesDecay:    0.9,
+		MeshMessageDeliveriesCap:      math.MaxInt32,
+		InvalidMessageDeliveriesDecay: 0.9,
+	}
+}
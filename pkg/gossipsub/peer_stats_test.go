@@ -0,0 +1,129 @@
+package gossipsub
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMessage(from peer.ID, topic string) *pubsub.Message {
+	return &pubsub.Message{
+		Message:      &pubsub_pb.Message{Topic: &topic},
+		ReceivedFrom: from,
+	}
+}
+
+func Test_PeerTopicMessages(t *testing.T) {
+	tracer := NewPeerStatsTracer()
+	peerA, peerB := peer.ID("peer-a"), peer.ID("peer-b")
+
+	for _, msg := range []*pubsub.Message{
+		newTestMessage(peerA, "beacon_block"),
+		newTestMessage(peerA, "beacon_block"),
+		newTestMessage(peerA, "sync_committee_1"),
+		newTestMessage(peerB, "beacon_block"),
+	} {
+		tracer.DeliverMessage(msg)
+	}
+	// the duplicates aren't deliveries
+	tracer.DuplicateMessage(newTestMessage(peerB, "beacon_block"))
+
+	require.ElementsMatch(t, []string{"beacon_block", "sync_committee_1"}, tracer.PeerTopics(peerA).Topics())
+	require.Nil(t, tracer.PeerTopics(peer.ID("peer-c")))
+
+	stats := tracer.PeerStats()
+	require.Equal(t, int64(3), stats[peerA].Delivered)
+	require.Equal(t, int64(1), stats[peerB].Delivered)
+	require.Equal(t, int64(1), stats[peerB].Duplicates)
+
+	require.Equal(t, map[peer.ID]map[string]int32{
+		peerA: {"beacon_block": 2, "sync_committee_1": 1},
+		peerB: {"beacon_block": 1},
+	}, tracer.TopicMessages())
+
+	// the counters restart after each call, the totals of the stats don't
+	tracer.DeliverMessage(newTestMessage(peerB, "blob_sidecar_0"))
+	require.Equal(t, map[peer.ID]map[string]int32{
+		peerA: {"beacon_block": 0, "sync_committee_1": 0},
+		peerB: {"beacon_block": 0, "blob_sidecar_0": 1},
+	}, tracer.TopicMessages())
+	require.Equal(t, int64(2), tracer.PeerStats()[peerB].Delivered)
+}
+
+func Test_InspectScores(t *testing.T) {
+	tracer := NewPeerStatsTracer()
+	scored, unscored := peer.ID("scored"), peer.ID("unscored")
+	tracer.DeliverMessage(newTestMessage(unscored, "beacon_block"))
+
+	tracer.inspectScores(map[peer.ID]*pubsub.PeerScoreSnapshot{
+		scored: {
+			Score:              -12.5,
+			AppSpecificScore:   1,
+			IPColocationFactor: 4,
+			BehaviourPenalty:   2,
+			Topics: map[string]*pubsub.TopicScoreSnapshot{
+				"beacon_block":     {TimeInMesh: time.Minute, FirstMessageDeliveries: 3, MeshMessageDeliveries: 5, InvalidMessageDeliveries: 1},
+				"sync_committee_0": {TimeInMesh: 30 * time.Second, FirstMessageDeliveries: 2, MeshMessageDeliveries: 1},
+			},
+		},
+	})
+
+	stats := tracer.PeerStats()
+	require.True(t, stats[scored].HasScore)
+	require.Equal(t, -12.5, stats[scored].Score)
+	// the topic components are added up for all the topics
+	require.Equal(t, ScoreComponents{
+		TimeInMesh:         90 * time.Second,
+		FirstDeliveries:    5,
+		MeshDeliveries:     6,
+		InvalidDeliveries:  1,
+		AppSpecificScore:   1,
+		IPColocationFactor: 4,
+		BehaviourPenalty:   2,
+	}, stats[scored].Components)
+	// the peers missing from the snapshot keep their stats, without a score
+	require.False(t, stats[unscored].HasScore)
+	require.Equal(t, int64(1), stats[unscored].Delivered)
+
+	// a new snapshot replaces the previous components
+	tracer.inspectScores(map[peer.ID]*pubsub.PeerScoreSnapshot{scored: {Score: 3}})
+	stats = tracer.PeerStats()
+	require.Equal(t, 3.0, stats[scored].Score)
+	require.Equal(t, ScoreComponents{}, stats[scored].Components)
+}
+
+func Test_PeerStatsRejections(t *testing.T) {
+	tracer := NewPeerStatsTracer()
+	p := peer.ID("peer")
+
+	tracer.RejectMessage(newTestMessage(p, "beacon_block"), pubsub.RejectInvalidSignature)
+	// the rejections of our own validation pipeline aren't blamed on the peer
+	for _, reason := range []string{pubsub.RejectValidationQueueFull, pubsub.RejectValidationThrottled, pubsub.RejectValidationIgnored, pubsub.RejectSelfOrigin} {
+		tracer.RejectMessage(newTestMessage(p, "beacon_block"), reason)
+	}
+	tracer.RecordInvalid(p)
+	require.Equal(t, int64(2), tracer.PeerStats()[p].Invalid)
+}
+
+func Test_PassiveScore(t *testing.T) {
+	params, thresholds := passivePeerScore()
+	// the peers are measured, never graylisted or ignored
+	require.Equal(t, -math.MaxFloat64, thresholds.GossipThreshold)
+	require.Equal(t, -math.MaxFloat64, thresholds.PublishThreshold)
+	require.Equal(t, -math.MaxFloat64, thresholds.GraylistThreshold)
+	require.Zero(t, params.AppSpecificScore(peer.ID("peer")))
+
+	// the topic components are counted without weighting the score
+	topicParams := passiveTopicScore()
+	require.Zero(t, topicParams.TopicWeight)
+	require.Zero(t, topicParams.TimeInMeshWeight)
+	require.Zero(t, topicParams.FirstMessageDeliveriesWeight)
+	require.Zero(t, topicParams.MeshMessageDeliveriesWeight)
+	require.Zero(t, topicParams.InvalidMessageDeliveriesWeight)
+	require.Greater(t, topicParams.FirstMessageDeliveriesCap, 0.0)
+}
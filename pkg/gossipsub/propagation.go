@@ -0,0 +1,116 @@
+package gossipsub
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	// time during which the duplicates of a message are accounted
+	DefaultPropagationWindow = 1 * time.Minute
+	// periodicity of the persistence of the messages out of the tracking window
+	propagationFlushInterval = 10 * time.Second
+)
+
+// PropagationTracer implements the pubsub.RawTracer to record when and from which peer each message
+// arrived for the first time, and how many duplicates of it we got within the tracking window.
+// Once the window of a message is over, the message is persisted and forgotten
+type PropagationTracer struct {
+	m        sync.Mutex
+	window   time.Duration
+	messages map[string]*models.GossipMessage
+}
+
+func NewPropagationTracer(window time.Duration) *PropagationTracer {
+	return &PropagationTracer{
+		window:   window,
+		messages: make(map[string]*models.GossipMessage),
+	}
+}
+
+// track accounts a new arrival of the message, the first one of each message id sets its origin
+func (t *PropagationTracer) track(msg *pubsub.Message, duplicate bool) {
+	// the messages dropped before computing their id can't be tracked
+	if msg.ID == "" {
+		return
+	}
+	now := time.Now()
+	msgID := "0x" + hex.EncodeToString([]byte(msg.ID))
+
+	t.m.Lock()
+	defer t.m.Unlock()
+	gossipMsg, ok := t.messages[msgID]
+	if !ok {
+		t.messages[msgID] = &models.GossipMessage{
+			MsgID:     msgID,
+			Topic:     msg.GetTopic(),
+			FirstSeen: now,
+			FirstPeer: msg.ReceivedFrom,
+			LastSeen:  now,
+		}
+		return
+	}
+	if duplicate {
+		gossipMsg.Duplicates++
+		gossipMsg.LastSeen = now
+	}
+}
+
+// expired removes and returns the messages whose tracking window is over
+func (t *PropagationTracer) expired(now time.Time) []*models.GossipMessage {
+	t.m.Lock()
+	defer t.m.Unlock()
+	msgs := make([]*models.GossipMessage, 0)
+	for msgID, gossipMsg := range t.messages {
+		if now.Sub(gossipMsg.FirstSeen) < t.window {
+			continue
+		}
+		msgs = append(msgs, gossipMsg)
+		delete(t.messages, msgID)
+	}
+	return msgs
+}
+
+// run persists the messages as soon as their tracking window is over
+func (t *PropagationTracer) run(ctx context.Context, db database) {
+	ticker := time.NewTicker(propagationFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, gossipMsg := range t.expired(time.Now()) {
+				db.PersistToDB(gossipMsg)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// the messages that skip the validation are directly delivered, so any of the events can be the first one
+func (t *PropagationTracer) ValidateMessage(msg *pubsub.Message) { t.track(msg, false) }
+func (t *PropagationTracer) DeliverMessage(msg *pubsub.Message)  { t.track(msg, false) }
+func (t *PropagationTracer) RejectMessage(msg *pubsub.Message, reason string) {
+	t.track(msg, false)
+}
+func (t *PropagationTracer) DuplicateMessage(msg *pubsub.Message) { t.track(msg, true) }
+
+func (t *PropagationTracer) AddPeer(p peer.ID, proto protocol.ID) {}
+func (t *PropagationTracer) RemovePeer(p peer.ID)                 {}
+func (t *PropagationTracer) Join(topic string)                    {}
+func (t *PropagationTracer) Leave(topic string)                   {}
+func (t *PropagationTracer) Graft(p peer.ID, topic string)        {}
+func (t *PropagationTracer) Prune(p peer.ID, topic string)        {}
+func (t *PropagationTracer) ThrottlePeer(p peer.ID)               {}
+func (t *PropagationTracer) RecvRPC(rpc *pubsub.RPC)              {}
+func (t *PropagationTracer) SendRPC(rpc *pubsub.RPC, p peer.ID)   {}
+func (t *PropagationTracer) DropRPC(rpc *pubsub.RPC, p peer.ID)   {}
+func (t *PropagationTracer) UndeliverableMessage(*pubsub.Message) {}
@@ -0,0 +1,91 @@
+package gossipsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+type testDB struct {
+	m         sync.Mutex
+	persisted []interface{}
+}
+
+func (db *testDB) PersistToDB(v interface{}) {
+	db.m.Lock()
+	defer db.m.Unlock()
+	db.persisted = append(db.persisted, v)
+}
+
+func (db *testDB) len() int {
+	db.m.Lock()
+	defer db.m.Unlock()
+	return len(db.persisted)
+}
+
+func newTestIDMessage(id string, from peer.ID, topic string) *pubsub.Message {
+	msg := newTestMessage(from, topic)
+	msg.ID = id
+	return msg
+}
+
+func Test_PropagationTracer(t *testing.T) {
+	tracer := NewPropagationTracer(time.Minute)
+	first, second := peer.ID("first"), peer.ID("second")
+
+	// the first arrival sets the origin, whatever the event that reports it
+	tracer.ValidateMessage(newTestIDMessage("a", first, "beacon_block"))
+	tracer.DeliverMessage(newTestIDMessage("a", first, "beacon_block"))
+	tracer.DuplicateMessage(newTestIDMessage("a", second, "beacon_block"))
+	tracer.DuplicateMessage(newTestIDMessage("a", second, "beacon_block"))
+	tracer.RejectMessage(newTestIDMessage("b", second, "voluntary_exit"), pubsub.RejectInvalidSignature)
+	// the messages without an id aren't tracked
+	tracer.DeliverMessage(newTestIDMessage("", first, "beacon_block"))
+
+	require.Empty(t, tracer.expired(time.Now()))
+
+	msgs := tracer.expired(time.Now().Add(time.Minute))
+	require.Len(t, msgs, 2)
+	byID := make(map[string]*models.GossipMessage)
+	for _, msg := range msgs {
+		byID[msg.MsgID] = msg
+	}
+
+	// the ids are hex encoded
+	msgA := byID["0x61"]
+	require.NotNil(t, msgA)
+	require.Equal(t, "beacon_block", msgA.Topic)
+	require.Equal(t, first, msgA.FirstPeer)
+	require.Equal(t, int64(2), msgA.Duplicates)
+	require.False(t, msgA.LastSeen.Before(msgA.FirstSeen))
+
+	msgB := byID["0x62"]
+	require.NotNil(t, msgB)
+	require.Equal(t, second, msgB.FirstPeer)
+	require.Zero(t, msgB.Duplicates)
+
+	// the expired messages are forgotten
+	require.Empty(t, tracer.expired(time.Now().Add(time.Hour)))
+}
+
+func Test_PropagationTracerRun(t *testing.T) {
+	defer func(interval time.Duration) { propagationFlushInterval = interval }(propagationFlushInterval)
+	propagationFlushInterval = 10 * time.Millisecond
+
+	tracer := NewPropagationTracer(50 * time.Millisecond)
+	db := &testDB{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tracer.run(ctx, db)
+
+	tracer.DeliverMessage(newTestIDMessage("a", peer.ID("first"), "beacon_block"))
+	require.Eventually(t, func() bool { return db.len() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "0x61", db.persisted[0].(*models.GossipMessage).MsgID)
+}
@@ -15,7 +15,8 @@ import (
 // message logging or record.
 // Serves as a server for a singe topic subscription.
 type TopicSubscription struct {
-	ctx context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	// Messages is a channel of messages received from other peers in the chat room
 	psub        *pubsub.PubSub
@@ -24,6 +25,8 @@ type TopicSubscription struct {
 	sub         *pubsub.Subscription
 	handlerFn   MessageHandler
 	persistMsgs bool
+	peerStats   *PeerStatsTracer
+	msgMetrics  *MessageMetrics
 }
 
 // NewTopicSubscription sumarizes the control fields necesary to manage and
@@ -33,14 +36,21 @@ func NewTopicSubscription(
 	topic *pubsub.Topic,
 	sub pubsub.Subscription,
 	msgHandlerFn MessageHandler,
-	persistMsgs bool) *TopicSubscription {
+	persistMsgs bool,
+	peerStats *PeerStatsTracer,
+	msgMetrics *MessageMetrics) *TopicSubscription {
+	// the subscription can be closed before the service
+	ctx, cancel := context.WithCancel(ctx)
 	return &TopicSubscription{
 		ctx:         ctx,
+		cancel:      cancel,
 		topic:       topic,
 		sub:         &sub,
 		messages:    make(chan []byte),
 		handlerFn:   msgHandlerFn,
 		persistMsgs: persistMsgs,
+		peerStats:   peerStats,
+		msgMetrics:  msgMetrics,
 	}
 }
 
@@ -56,15 +66,25 @@ func (c *TopicSubscription) MessageReadingLoop(selfId peer.ID, dbClient database
 				log.Errorf("context of the subsciption %s has been canceled", c.sub.Topic())
 				break
 			}
+			if err == pubsub.ErrSubscriptionCancelled {
+				log.Debugf("subscription %s has been canceled", c.sub.Topic())
+				break
+			}
 			log.Errorf("error reading next message in topic %s. %slol", c.sub.Topic(), err.Error())
 		} else {
 			// To avoid getting track of our own messages, check if we are the senders
 			if msg.ReceivedFrom != selfId {
 				log.Debugf("new message on %s from %s", c.sub.Topic(), msg.ReceivedFrom)
+				if c.msgMetrics != nil {
+					c.msgMetrics.AddMessgeToTopic(c.sub.Topic())
+				}
 				// use the msg handler for that specific topic that we have
 				content, err := c.handlerFn(msg)
 				if err != nil {
 					log.Error(errors.Wrap(err, "unable to unwrap message on topic "+c.sub.Topic()))
+					if c.peerStats != nil {
+						c.peerStats.RecordInvalid(msg.ReceivedFrom)
+					}
 					continue
 				}
 				if !content.IsZero() && c.persistMsgs {
@@ -76,6 +96,13 @@ func (c *TopicSubscription) MessageReadingLoop(selfId peer.ID, dbClient database
 			}
 		}
 	}
-	<-subsCtx.Done()
+	c.cancel()
 	log.Debugf("ending %s reading loop", c.sub.Topic())
 }
+
+// Close stops the reading loop, cancels the subscription and closes the topic
+func (c *TopicSubscription) Close() error {
+	c.cancel()
+	c.sub.Cancel()
+	return c.topic.Close()
+}
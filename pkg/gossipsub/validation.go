@@ -0,0 +1,34 @@
+package gossipsub
+
+import (
+	"context"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+)
+
+// time that a message can take to be validated before being ignored
+var ValidationTimeout = 5 * time.Second
+
+// MessageValidator decides whether a message received on a topic is accepted, ignored, or rejected.
+// The rejected messages are accounted as invalid deliveries of the peer that forwarded them
+// (in its stats and its gossipsub score), and none of the messages but the accepted ones are handled
+type MessageValidator interface {
+	Validate(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult
+}
+
+// RegisterValidator sets the validator of the messages of the topic, it has to be registered before
+// subscribing to the topic so that all the messages are validated
+func (gs *GossipSub) RegisterValidator(topicName string, validator MessageValidator) error {
+	if validator == nil {
+		return errors.New("nil message validator")
+	}
+	err := gs.PubsubService.RegisterTopicValidator(
+		topicName,
+		validator.Validate,
+		pubsub.WithValidatorTimeout(ValidationTimeout),
+	)
+	return errors.Wrapf(err, "unable to register validator of topic %s", topicName)
+}
@@ -1,88 +1,185 @@
 package hosts
 
 import (
+	"context"
+	"net"
 	"strings"
+	"syscall"
 
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/sec"
 	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	"github.com/pkg/errors"
 )
 
-// Much easier/prettier way of filtering the Error returned by the libp2p.Host.Connect
-// extracted from `@dennis-tra`'s nebula-crawler repo:
-// https://github.com/dennis-tra/nebula-crawler/blob/f2b3ba376d221fed886dad204acfc0dfe8e492ea/pkg/db/errors.go#L28
+// machine-readable codes of the errors of the connections with the remote peers
+// (the codes of our own timeouts are in timeouts.go)
 const (
 	// list errors
-	NoConnError                                         = "none"
-	ErrorRequestingMetadta                              = "error requesting metadata"
-	ResourceLimitError                                  = "resource_limit"
-	DialErrorRoutingNotFound                            = "routing_not_found"
-	DialErrorNoRecentNetworkActivity                    = "no_recent_network_activity"
-	DialErrorStreamErrorCode0                           = "canceled_stream_err_code_0"
-	DialErrorMsgSenderInvalidated                       = "msg_sender_invalidated"
-	DialBlacklistedPeer                                 = "hydra_booster_peer"
-	DialErrorIoTimeout                                  = "io_timeout"
-	DialErrorSelfAttempt                                = "dial to self attempted"
-	DialErrorConnectionRefused                          = "connection_refused"
-	DialErrorConnectionResetByPeer                      = "connection_reset_by_peer"
-	DialErrorBackOff                                    = "backoff"
-	DialErrorProtocolNotSupported                       = "protocol_not_supported"
-	DialErrorPeerIDMismatch                             = "peer_id_mismatch"
-	DialErrorNoRouteToHost                              = "no_route_to_host"
-	DialErrorNetworkUnreachable                         = "network_unreachable"
-	DialErrorNoGoodAddresses                            = "no_good_addresses"
-	DialErrorNoAddress                                  = "no_addresses"
-	DialErrorMaddrReset                                 = "maddr_reset"
-	DialErrorContextDeadlineExceeded                    = "context_deadline_exceeded"
-	DialErrorNoPublicIP                                 = "no_public_ip"
-	DialErrorSecurityProtocolNegotiation                = "security_protocol_negotiation"
-	DialErrorMaxDialAttemptsExceeded                    = "max_dial_attempts_exceeded"
-	DialErrorUnknown                                    = "unknown"
-	DialErrorStreamReset                                = "stream_reset"
-	DialErrorHostIsDown                                 = "host_is_down"
-	DialErrorTooManyOpenFiles                           = "too_many_open_files"
-	DialErrorNegotiateSecurityProtocolNoTrailingNewLine = "negotiate_security_protocol_no_trailing_new_line"
+	NoConnError                          = "none"
+	ErrorRequestingMetadta               = "metadata_request_failed"
+	ResourceLimitError                   = "resource_limit"
+	DialErrorRoutingNotFound             = "routing_not_found"
+	DialErrorNoRecentNetworkActivity     = "no_recent_network_activity"
+	DialErrorStreamErrorCode0            = "canceled_stream_err_code_0"
+	DialErrorMsgSenderInvalidated        = "msg_sender_invalidated"
+	DialBlacklistedPeer                  = "hydra_booster_peer"
+	DialErrorIoTimeout                   = "io_timeout"
+	DialErrorSelfAttempt                 = "dial_self"
+	DialErrorConnectionRefused           = "conn_refused"
+	DialErrorConnectionResetByPeer       = "peer_reset"
+	DialErrorBackOff                     = "backoff"
+	DialErrorProtocolNotSupported        = "protocol_not_supported"
+	DialErrorPeerIDMismatch              = "peer_id_mismatch"
+	DialErrorNoRouteToHost               = "no_route_to_host"
+	DialErrorNetworkUnreachable          = "network_unreachable"
+	DialErrorNoGoodAddresses             = "no_good_addresses"
+	DialErrorNoAddress                   = "no_addresses"
+	DialErrorMaddrReset                  = "maddr_reset"
+	DialErrorContextDeadlineExceeded     = "context_deadline_exceeded"
+	DialErrorContextCanceled             = "context_canceled"
+	DialErrorNoPublicIP                  = "no_public_ip"
+	DialErrorSecurityProtocolNegotiation = "security_negotiation_failed"
+	DialErrorMaxDialAttemptsExceeded     = "max_dial_attempts_exceeded"
+	DialErrorUnknown                     = "unknown"
+	DialErrorStreamReset                 = "stream_reset"
+	DialErrorHostIsDown                  = "host_is_down"
+	DialErrorTooManyOpenFiles            = "too_many_open_files"
+	DialErrorDNSFailure                  = "dns_failure"
+	DialErrorGated                       = "gated"
 )
 
-var KnownErrors = map[string]string{
-	ResourceLimitError:                                  "resource limit exceeded",
-	DialErrorRoutingNotFound:                            "routing: not found",
-	DialErrorNoRecentNetworkActivity:                    "no recent network activity",
-	DialErrorStreamErrorCode0:                           "canceled with error code 0",
-	DialErrorMsgSenderInvalidated:                       "message sender has been invalidated",
-	DialErrorIoTimeout:                                  "i/o timeout",
-	DialErrorSelfAttempt:                                "dial to self attempted",
-	DialErrorConnectionRefused:                          "connection refused",
-	DialErrorConnectionResetByPeer:                      "connection reset by peer",
-	DialErrorBackOff:                                    "backoff",
-	DialErrorProtocolNotSupported:                       "protocol not supported",
-	DialErrorPeerIDMismatch:                             "peer id mismatch",
-	DialErrorNoRouteToHost:                              "no route to host",
-	DialErrorNetworkUnreachable:                         "network is unreachable",
-	DialErrorNoGoodAddresses:                            "no good addresses",
-	DialErrorNoAddress:                                  "no addresses",
-	DialErrorContextDeadlineExceeded:                    "context deadline exceeded",
-	DialErrorNoPublicIP:                                 "no public IP address",
-	DialErrorSecurityProtocolNegotiation:                "failed to negotiate security protocol: EOF",
-	DialErrorMaxDialAttemptsExceeded:                    "max dial attempts exceeded",
-	DialErrorHostIsDown:                                 "host is down",
-	DialErrorStreamReset:                                "stream reset",
-	DialErrorTooManyOpenFiles:                           "too many open files",
-	DialErrorNegotiateSecurityProtocolNoTrailingNewLine: "failed to negotiate security protocol: message did not have trailing newline",
+// ConnError is the classification of an error of a connection with a remote peer: the code of
+// the error, and the raw error it was taken from
+type ConnError struct {
+	Code string
+	Raw  string
 }
 
-func ParseConError(err error) string {
-	// nested error casting to the swarm.DialError to process all the errors that might come
-	connErr, ok := err.(*swarm.DialError)
-	if !ok && connErr != nil {
-		if connErr.Cause != nil {
-			return ParseConError(connErr.Cause)
+// typedErrors are the errors (or the wrapped ones) that can be matched by their value,
+// in the order they are checked: a dial error aggregates the errors of each of the addresses
+// of the peer, so the errors that tell the most about the peer go first
+var typedErrors = []struct {
+	code string
+	err  error
+}{
+	{DialErrorSelfAttempt, swarm.ErrDialToSelf},
+	{DialErrorBackOff, swarm.ErrDialBackoff},
+	{DialErrorGated, swarm.ErrGaterDisallowedConnection},
+	{DialErrorNoAddress, swarm.ErrNoAddresses},
+	{DialErrorNoGoodAddresses, swarm.ErrNoGoodAddresses},
+	{ResourceLimitError, network.ErrResourceLimitExceeded},
+	{DialErrorConnectionRefused, syscall.ECONNREFUSED},
+	{DialErrorConnectionResetByPeer, syscall.ECONNRESET},
+	{DialErrorNoRouteToHost, syscall.EHOSTUNREACH},
+	{DialErrorNetworkUnreachable, syscall.ENETUNREACH},
+	{DialErrorHostIsDown, syscall.EHOSTDOWN},
+	{DialErrorTooManyOpenFiles, syscall.EMFILE},
+	{DialErrorContextCanceled, context.Canceled},
+}
+
+// KnownErrors are the substrings of the errors that can only be told apart by their message,
+// in the order they are checked
+// Much easier/prettier way of filtering the Error returned by the libp2p.Host.Connect
+// extracted from `@dennis-tra`'s nebula-crawler repo:
+// https://github.com/dennis-tra/nebula-crawler/blob/f2b3ba376d221fed886dad204acfc0dfe8e492ea/pkg/db/errors.go#L28
+var KnownErrors = []struct {
+	Code   string
+	Substr string
+}{
+	{ResourceLimitError, "resource limit exceeded"},
+	{DialErrorSelfAttempt, "dial to self attempted"},
+	{DialErrorBackOff, "backoff"},
+	{DialErrorPeerIDMismatch, "peer id mismatch"},
+	{DialErrorConnectionRefused, "connection refused"},
+	{DialErrorConnectionResetByPeer, "connection reset by peer"},
+	{DialErrorNoRouteToHost, "no route to host"},
+	{DialErrorNetworkUnreachable, "network is unreachable"},
+	{DialErrorHostIsDown, "host is down"},
+	{DialErrorTooManyOpenFiles, "too many open files"},
+	{DialErrorSecurityProtocolNegotiation, "failed to negotiate security protocol"},
+	{DialErrorProtocolNotSupported, "protocols not supported"},
+	{DialErrorProtocolNotSupported, "protocol not supported"},
+	{DialErrorNoGoodAddresses, "no good addresses"},
+	{DialErrorNoAddress, "no addresses"},
+	{DialErrorNoPublicIP, "no public IP address"},
+	{DialErrorMaxDialAttemptsExceeded, "max dial attempts exceeded"},
+	{DialErrorRoutingNotFound, "routing: not found"},
+	{DialErrorNoRecentNetworkActivity, "no recent network activity"},
+	{DialErrorStreamErrorCode0, "canceled with error code 0"},
+	{DialErrorMsgSenderInvalidated, "message sender has been invalidated"},
+	{DialErrorStreamReset, "stream reset"},
+	{DialErrorContextDeadlineExceeded, "context deadline exceeded"},
+	{DialErrorIoTimeout, "i/o timeout"},
+}
+
+// ClassifyConnError returns the code of the given error of a connection, keeping the raw error next to it
+func ClassifyConnError(err error) ConnError {
+	if err == nil {
+		return ConnError{Code: NoConnError}
+	}
+	return ConnError{
+		Code: classifyConnError(err),
+		Raw:  err.Error(),
+	}
+}
+
+func classifyConnError(err error) string {
+	for _, typed := range typedErrors {
+		if errors.Is(err, typed.err) {
+			return typed.code
 		}
 	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return DialErrorDNSFailure
+	}
+	var mismatch sec.ErrPeerIDMismatch
+	if errors.As(err, &mismatch) {
+		return DialErrorPeerIDMismatch
+	}
 
-	// check if the connError is one of the ones that we have identified
-	for key, errStr := range KnownErrors {
-		if strings.Contains(err.Error(), errStr) {
-			return key
+	// the errors that can't be matched by their type
+	msg := err.Error()
+	for _, known := range KnownErrors {
+		if strings.Contains(msg, known.Substr) {
+			return known.Code
 		}
 	}
+
+	// timeouts that we didn't set ourselves
+	if errors.Is(err, context.DeadlineExceeded) {
+		return DialErrorContextDeadlineExceeded
+	}
+	var timeoutErr interface{ Timeout() bool }
+	if errors.As(err, &timeoutErr) && timeoutErr.Timeout() {
+		return DialErrorIoTimeout
+	}
 	return DialErrorUnknown
 }
+
+// ClassifyDialError returns the code of the error of a dial made with the given context, telling
+// our own dial timeout apart from the timeouts of the remote peer
+func ClassifyDialError(ctx context.Context, err error) ConnError {
+	connErr := ClassifyConnError(err)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		connErr.Code = DialErrorDialTimeout
+	}
+	return connErr
+}
+
+// ParseConError returns the code of the given error of a connection (see ClassifyConnError)
+func ParseConError(err error) string {
+	return ClassifyConnError(err).Code
+}
+
+// MismatchedPeer returns the peer that answered the dial instead of the dialed one, if the dial
+// failed because the key behind the addresses isn't the one of the dialed peer ID
+func MismatchedPeer(err error) (peer.ID, bool) {
+	var mismatch sec.ErrPeerIDMismatch
+	if errors.As(err, &mismatch) {
+		return mismatch.Actual, true
+	}
+	return "", false
+}
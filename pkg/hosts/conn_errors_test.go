@@ -0,0 +1,98 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/sec"
+	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func dialError(cause error, addrErrs ...error) error {
+	dialErr := &swarm.DialError{Peer: peer.ID("remote"), Cause: cause}
+	for _, err := range addrErrs {
+		dialErr.DialErrors = append(dialErr.DialErrors, swarm.TransportError{
+			Address: ma.StringCast("/ip4/127.0.0.1/tcp/9000"),
+			Cause:   err,
+		})
+	}
+	return dialErr
+}
+
+func syscallError(errno syscall.Errno) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+}
+
+func Test_ClassifyConnError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"no error", nil, NoConnError},
+		{"connection refused", dialError(swarm.ErrAllDialsFailed, syscallError(syscall.ECONNREFUSED)), DialErrorConnectionRefused},
+		{"connection reset", errors.Wrap(syscallError(syscall.ECONNRESET), "upgrading"), DialErrorConnectionResetByPeer},
+		{"no route to host", dialError(swarm.ErrAllDialsFailed, syscallError(syscall.EHOSTUNREACH)), DialErrorNoRouteToHost},
+		{"network unreachable", dialError(swarm.ErrAllDialsFailed, syscallError(syscall.ENETUNREACH)), DialErrorNetworkUnreachable},
+		{"too many open files", syscallError(syscall.EMFILE), DialErrorTooManyOpenFiles},
+		{"dns failure", dialError(swarm.ErrAllDialsFailed, &net.DNSError{Err: "no such host", Name: "node.example"}), DialErrorDNSFailure},
+		{"backoff", dialError(swarm.ErrDialBackoff), DialErrorBackOff},
+		{"dial to self", dialError(swarm.ErrDialToSelf), DialErrorSelfAttempt},
+		{"gated", dialError(swarm.ErrGaterDisallowedConnection), DialErrorGated},
+		{"no addresses", dialError(swarm.ErrNoAddresses), DialErrorNoAddress},
+		{"no good addresses", dialError(swarm.ErrNoGoodAddresses), DialErrorNoGoodAddresses},
+		{"resource limit", errors.Wrap(network.ErrResourceLimitExceeded, "opening stream"), ResourceLimitError},
+		{"peer id mismatch", dialError(swarm.ErrAllDialsFailed, sec.ErrPeerIDMismatch{Expected: "a", Actual: "b"}), DialErrorPeerIDMismatch},
+		{"security negotiation eof", errors.New("failed to negotiate security protocol: EOF"), DialErrorSecurityProtocolNegotiation},
+		{"security negotiation newline", errors.New("failed to negotiate security protocol: message did not have trailing newline"), DialErrorSecurityProtocolNegotiation},
+		{"protocol not supported", errors.New("protocols not supported: [/eth2/beacon_chain/req/status/1/ssz_snappy]"), DialErrorProtocolNotSupported},
+		{"stream reset", errors.New("stream reset"), DialErrorStreamReset},
+		{"context deadline", errors.Wrap(context.DeadlineExceeded, "identifying"), DialErrorContextDeadlineExceeded},
+		{"context canceled", dialError(context.Canceled), DialErrorContextCanceled},
+		{"io timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, DialErrorIoTimeout},
+		{"unknown", errors.New("something odd happened"), DialErrorUnknown},
+		// the errors that tell the most about the peer win over the others of the same dial
+		{"refused over timeout", dialError(swarm.ErrAllDialsFailed, os.ErrDeadlineExceeded, syscallError(syscall.ECONNREFUSED)), DialErrorConnectionRefused},
+		{"resource limit over refused", dialError(swarm.ErrAllDialsFailed, syscallError(syscall.ECONNREFUSED), network.ErrResourceLimitExceeded), ResourceLimitError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connErr := ClassifyConnError(test.err)
+			require.Equal(t, test.code, connErr.Code)
+			require.Equal(t, test.code, ParseConError(test.err))
+			if test.err == nil {
+				require.Empty(t, connErr.Raw)
+			} else {
+				require.Equal(t, test.err.Error(), connErr.Raw)
+			}
+		})
+	}
+}
+
+func Test_ClassifyDialError(t *testing.T) {
+	err := dialError(context.DeadlineExceeded)
+
+	// a dial that failed before our timeout keeps the code of its error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, DialErrorContextDeadlineExceeded, ClassifyDialError(ctx, err).Code)
+
+	// our own timeout fired
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	connErr := ClassifyDialError(ctx, err)
+	require.Equal(t, DialErrorDialTimeout, connErr.Code)
+	require.Equal(t, err.Error(), connErr.Raw)
+
+	// a successful dial has no error, even if the context expired afterwards
+	require.Equal(t, ConnError{Code: NoConnError}, ClassifyDialError(ctx, nil))
+}
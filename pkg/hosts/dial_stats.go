@@ -0,0 +1,77 @@
+package hosts
+
+import (
+	"sync"
+)
+
+// DialStats accounts the dials of the host, and the identifications of the peers it got connected to
+type DialStats struct {
+	Attempts  int64
+	Successes int64
+	// failed dials by error code (see ClassifyConnError)
+	Failures map[string]int64
+
+	Identifications   int64
+	IdentifySuccesses int64
+}
+
+// IdentifySuccessRate returns the share of connections whose peer got identified
+func (s DialStats) IdentifySuccessRate() float64 {
+	if s.Identifications == 0 {
+		return 0
+	}
+	return float64(s.IdentifySuccesses) / float64(s.Identifications)
+}
+
+type dialTracker struct {
+	m       sync.Mutex
+	stats   DialStats
+	metrics *hostMetrics
+}
+
+func newDialTracker(metrics *hostMetrics) *dialTracker {
+	return &dialTracker{
+		metrics: metrics,
+		stats: DialStats{
+			Failures: make(map[string]int64),
+		},
+	}
+}
+
+func (t *dialTracker) recordDial(connErr ConnError) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.stats.Attempts++
+	t.metrics.DialAttempts.Inc()
+	if connErr.Code == NoConnError {
+		t.stats.Successes++
+		t.metrics.DialSuccesses.Inc()
+		return
+	}
+	t.stats.Failures[connErr.Code]++
+	t.metrics.DialFailures.WithLabelValues(connErr.Code).Inc()
+}
+
+func (t *dialTracker) recordIdentification(identified bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.stats.Identifications++
+	result := "failure"
+	if identified {
+		t.stats.IdentifySuccesses++
+		result = "success"
+	}
+	t.metrics.Identifications.WithLabelValues(result).Inc()
+}
+
+// DialStats returns a copy of the dial and identification stats of the host
+func (b *BasicLibp2pHost) DialStats() DialStats {
+	b.dials.m.Lock()
+	defer b.dials.m.Unlock()
+	stats := b.dials.stats
+	stats.Failures = make(map[string]int64, len(b.dials.stats.Failures))
+	for code, n := range b.dials.stats.Failures {
+		stats.Failures[code] = n
+	}
+	return stats
+}
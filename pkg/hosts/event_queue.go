@@ -0,0 +1,158 @@
+package hosts
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+const (
+	ConnEventQueue  = "conn"
+	IdentEventQueue = "ident"
+)
+
+var (
+	// DefaultEventQueueSize is the max number of events that are held in memory before dropping new ones
+	DefaultEventQueueSize = 100000
+	// initial capacity of the queues, they shrink back to it once they get drained
+	eventQueueInitCap = 256
+	// number of dropped events between warnings
+	dropWarnPeriod int64 = 1000
+)
+
+// eventQueue is a FIFO between the libp2p notifiees and the consumers of the events, which grows on demand
+// (absorbing connection storms without blocking libp2p) up to a max size, after which the new events are dropped.
+// The events are only held in memory, nothing spills to disk: the max size bounds the memory of a storm, and
+// the drops are exported instead. Once the context dies, pop keeps draining the queued events before giving up
+type eventQueue struct {
+	name    string
+	maxSize int
+
+	m     sync.Mutex
+	items []interface{}
+	head  int
+
+	notifyC chan struct{}
+	dropped int64
+	// counter of the dropped events of the queue
+	droppedC prometheus.Counter
+}
+
+func newEventQueue(name string, maxSize int, droppedC prometheus.Counter) *eventQueue {
+	return &eventQueue{
+		name:     name,
+		maxSize:  maxSize,
+		items:    make([]interface{}, 0, eventQueueInitCap),
+		notifyC:  make(chan struct{}, 1),
+		droppedC: droppedC,
+	}
+}
+
+// push adds the item at the end of the queue without blocking, returns false if the item was dropped
+func (q *eventQueue) push(item interface{}) bool {
+	q.m.Lock()
+	if len(q.items)-q.head >= q.maxSize {
+		q.m.Unlock()
+		dropped := atomic.AddInt64(&q.dropped, 1)
+		q.droppedC.Inc()
+		if dropped%dropWarnPeriod == 1 {
+			log.Warnf("%s event queue full (%d events), %d events dropped so far", q.name, q.maxSize, dropped)
+		}
+		return false
+	}
+	q.items = append(q.items, item)
+	q.m.Unlock()
+
+	// wake up the consumer if it was waiting
+	select {
+	case q.notifyC <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop returns the first item of the queue, waiting for one if empty (false if the context dies)
+func (q *eventQueue) pop(ctx context.Context) (interface{}, bool) {
+	for {
+		q.m.Lock()
+		if q.head < len(q.items) {
+			item := q.items[q.head]
+			q.items[q.head] = nil
+			q.head++
+			if q.head == len(q.items) {
+				// drained, release the memory that a storm could have taken
+				if cap(q.items) > 4*eventQueueInitCap {
+					q.items = make([]interface{}, 0, eventQueueInitCap)
+				} else {
+					q.items = q.items[:0]
+				}
+				q.head = 0
+			}
+			q.m.Unlock()
+			return item, true
+		}
+		q.m.Unlock()
+
+		select {
+		case <-q.notifyC:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// len returns the number of events waiting in the queue
+func (q *eventQueue) len() int {
+	q.m.Lock()
+	defer q.m.Unlock()
+	return len(q.items) - q.head
+}
+
+// droppedEvents returns the number of events dropped because the queue was full
+func (q *eventQueue) droppedEvents() int64 {
+	return atomic.LoadInt64(&q.dropped)
+}
+
+// pumpEvents moves the queued events into the notification channels, blocking only while the consumers are busy
+func (b *BasicLibp2pHost) pumpEvents() {
+	go func() {
+		for {
+			item, ok := b.connEventQueue.pop(b.ctx)
+			if !ok {
+				return
+			}
+			select {
+			case b.connEventNotChannel <- item.(*models.EventTrace):
+			case <-b.ctx.Done():
+				return
+			}
+		}
+	}()
+	for {
+		item, ok := b.identQueue.pop(b.ctx)
+		if !ok {
+			return
+		}
+		select {
+		case b.identNotChannel <- item.(IdentificationEvent):
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+// EventQueueStats returns the number of queued and dropped events of each of the notification queues
+func (b *BasicLibp2pHost) EventQueueStats() (queued map[string]int, dropped map[string]int64) {
+	queued = make(map[string]int)
+	dropped = make(map[string]int64)
+	for _, q := range []*eventQueue{b.connEventQueue, b.identQueue} {
+		queued[q.name] = q.len()
+		dropped[q.name] = q.droppedEvents()
+	}
+	return queued, dropped
+}
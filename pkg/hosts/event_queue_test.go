@@ -0,0 +1,131 @@
+package hosts
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEventQueue(maxSize int) (*eventQueue, prometheus.Counter) {
+	droppedC := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_dropped_events"})
+	return newEventQueue("test", maxSize, droppedC), droppedC
+}
+
+func Test_EventQueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int
+		pushed  int
+		// expected queued and dropped events, and whether the buffer is released once drained
+		queued   int
+		dropped  int64
+		released bool
+	}{
+		{"empty", 10, 0, 0, 0, false},
+		{"under the max size", 10, 7, 7, 0, false},
+		{"overflow drops the new events", 10, 15, 10, 5, false},
+		// a small growth keeps the buffer for the next burst
+		{"grows", 10 * eventQueueInitCap, 2 * eventQueueInitCap, 2 * eventQueueInitCap, 0, false},
+		// the memory of a storm is released once drained
+		{"shrinks after a storm", 10 * eventQueueInitCap, 8 * eventQueueInitCap, 8 * eventQueueInitCap, 0, true},
+	}
+
+	for _, test := range tests {
+		q, droppedC := newTestEventQueue(test.maxSize)
+		for i := 0; i < test.pushed; i++ {
+			require.Equal(t, i < test.maxSize, q.push(i), test.name)
+		}
+		require.Equal(t, test.queued, q.len(), test.name)
+		require.Equal(t, test.dropped, q.droppedEvents(), test.name)
+		require.Equal(t, float64(test.dropped), testutil.ToFloat64(droppedC), test.name)
+		require.GreaterOrEqual(t, cap(q.items), test.queued, test.name)
+
+		// the queued events come out in order, and the dropped ones were the last pushed
+		for i := 0; i < test.queued; i++ {
+			item, ok := q.pop(context.Background())
+			require.True(t, ok, test.name)
+			require.Equal(t, i, item, test.name)
+		}
+		require.Equal(t, 0, q.len(), test.name)
+		if test.released {
+			require.Equal(t, eventQueueInitCap, cap(q.items), test.name)
+		} else {
+			require.GreaterOrEqual(t, cap(q.items), test.queued, test.name)
+		}
+	}
+}
+
+func Test_EventQueueDrainOnClose(t *testing.T) {
+	q, _ := newTestEventQueue(10)
+	for i := 0; i < 3; i++ {
+		require.True(t, q.push(i))
+	}
+
+	// the queued events are still delivered once the context dies
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		item, ok := q.pop(ctx)
+		require.True(t, ok)
+		require.Equal(t, i, item)
+	}
+	_, ok := q.pop(ctx)
+	require.False(t, ok)
+
+	// a consumer waiting on an empty queue returns when the context dies
+	ctx, cancel = context.WithCancel(context.Background())
+	doneC := make(chan bool)
+	go func() {
+		_, ok := q.pop(ctx)
+		doneC <- ok
+	}()
+	cancel()
+	select {
+	case ok := <-doneC:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("pop didn't return after the context was cancelled")
+	}
+}
+
+// the producers never block, and every event is either consumed or accounted as dropped
+func Test_EventQueueConcurrent(t *testing.T) {
+	producers, events := 8, 1000
+	q, droppedC := newTestEventQueue(500)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumed := 0
+	consumerDoneC := make(chan struct{})
+	go func() {
+		defer close(consumerDoneC)
+		for {
+			if _, ok := q.pop(ctx); !ok {
+				return
+			}
+			consumed++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < events; i++ {
+				q.push(i)
+			}
+		}()
+	}
+	wg.Wait()
+	cancel()
+	<-consumerDoneC
+
+	require.Equal(t, 0, q.len())
+	require.Equal(t, int64(producers*events), int64(consumed)+q.droppedEvents())
+	require.Equal(t, float64(q.droppedEvents()), testutil.ToFloat64(droppedC))
+}
@@ -0,0 +1,299 @@
+package hosts
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CIDRGated   = "cidr"
+	ASNGated    = "asn"
+	PeerGated   = "peer"
+	NoDialGated = "no-dial"
+)
+
+var (
+	// time that a resolved (or unknown) ASN is cached by the gater
+	asnCacheTTL = 30 * time.Minute
+)
+
+// Blocklist contains the IP ranges, ASNs and peers that the host won't connect to
+type Blocklist struct {
+	cidrs []*net.IPNet
+	asns  map[string]struct{}
+	peers map[peer.ID]struct{}
+}
+
+// NewBlocklist composes a Blocklist from the given entries, which can be
+// CIDRs (10.0.0.0/8), single IPs, ASNs (AS1234), or peer IDs
+func NewBlocklist(entries []string) (*Blocklist, error) {
+	b := &Blocklist{
+		cidrs: make([]*net.IPNet, 0),
+		asns:  make(map[string]struct{}),
+		peers: make(map[peer.ID]struct{}),
+	}
+	for _, entry := range entries {
+		err := b.Add(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// LoadBlocklistFile reads the blocklist entries from a file (one entry per line, # for comments)
+func LoadBlocklistFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open blocklist file")
+	}
+	defer f.Close()
+
+	entries := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read blocklist file")
+	}
+	return entries, nil
+}
+
+// Add includes a new CIDR, IP, ASN, or peer ID into the blocklist
+func (b *Blocklist) Add(entry string) error {
+	entry = strings.TrimSpace(entry)
+	upper := strings.ToUpper(entry)
+	switch {
+	case strings.HasPrefix(upper, "AS"):
+		asn := ParseASN(upper)
+		if asn == "" {
+			return errors.Errorf("invalid asn in blocklist %s", entry)
+		}
+		b.asns[asn] = struct{}{}
+	case strings.Contains(entry, "/"):
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return errors.Wrap(err, "invalid cidr in blocklist "+entry)
+		}
+		b.cidrs = append(b.cidrs, ipNet)
+	default:
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			pID, err := peer.Decode(entry)
+			if err != nil {
+				return errors.Errorf("invalid blocklist entry %s", entry)
+			}
+			b.peers[pID] = struct{}{}
+			return nil
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		b.cidrs = append(b.cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nil
+}
+
+// IsEmpty returns whether the blocklist doesn't block anything
+func (b *Blocklist) IsEmpty() bool {
+	return b == nil || (len(b.cidrs) == 0 && len(b.asns) == 0 && len(b.peers) == 0)
+}
+
+// BlocksIP returns whether the IP is inside any of the blocked ranges
+func (b *Blocklist) BlocksIP(ip net.IP) bool {
+	for _, ipNet := range b.cidrs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlocksASN returns whether the ASN (AS1234) is blocked
+func (b *Blocklist) BlocksASN(asn string) bool {
+	_, ok := b.asns[asn]
+	return ok
+}
+
+// BlocksPeer returns whether the peer is blocked
+func (b *Blocklist) BlocksPeer(p peer.ID) bool {
+	if b == nil {
+		return false
+	}
+	_, ok := b.peers[p]
+	return ok
+}
+
+// Len returns the number of CIDRs, ASNs and peers in the blocklist
+func (b *Blocklist) Len() (cidrs, asns, peers int) {
+	return len(b.cidrs), len(b.asns), len(b.peers)
+}
+
+// ParseASN extracts the ASN (AS1234) from a raw AS description (i.e. "AS1234 Some Org")
+func ParseASN(asRaw string) string {
+	fields := strings.Fields(strings.ToUpper(asRaw))
+	if len(fields) == 0 || len(fields[0]) <= 2 || !strings.HasPrefix(fields[0], "AS") {
+		return ""
+	}
+	for _, c := range fields[0][2:] {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return fields[0]
+}
+
+// ASNResolver returns the raw AS description of the given IP (empty if unknown)
+type ASNResolver func(ip string) string
+
+type asnCacheItem struct {
+	asn       string
+	timestamp time.Time
+}
+
+// ConnGater implements the libp2p ConnectionGater, refusing any dial or inbound
+// connection with a blocked peer or a remote address in a blocked range or ASN (and every dial in listen-only mode)
+type ConnGater struct {
+	blocklist *Blocklist
+	resolver  ASNResolver
+	noDial    bool
+
+	m        sync.Mutex
+	asnCache map[string]asnCacheItem
+
+	gatedDials   int64
+	gatedAccepts int64
+	// counter of the gated connections by direction and reason
+	gatedC *prometheus.CounterVec
+}
+
+func NewConnGater(blocklist *Blocklist, resolver ASNResolver, noDial bool, gatedC *prometheus.CounterVec) *ConnGater {
+	return &ConnGater{
+		blocklist: blocklist,
+		resolver:  resolver,
+		noDial:    noDial,
+		asnCache:  make(map[string]asnCacheItem),
+		gatedC:    gatedC,
+	}
+}
+
+// blocked returns whether the multiaddress is blocked, and the reason
+func (g *ConnGater) blocked(maddr ma.Multiaddr) (bool, string) {
+	if g.blocklist.IsEmpty() {
+		return false, ""
+	}
+	ip, err := manet.ToIP(maddr)
+	if err != nil {
+		// non-ip multiaddrs (i.e. dns) can't be gated
+		return false, ""
+	}
+	if g.blocklist.BlocksIP(ip) {
+		return true, CIDRGated
+	}
+	if len(g.blocklist.asns) > 0 && g.resolver != nil {
+		if g.blocklist.BlocksASN(g.asn(ip.String())) {
+			return true, ASNGated
+		}
+	}
+	return false, ""
+}
+
+func (g *ConnGater) asn(ip string) string {
+	g.m.Lock()
+	item, ok := g.asnCache[ip]
+	g.m.Unlock()
+	if ok && time.Since(item.timestamp) < asnCacheTTL {
+		return item.asn
+	}
+	asn := ParseASN(g.resolver(ip))
+	g.m.Lock()
+	g.asnCache[ip] = asnCacheItem{asn: asn, timestamp: time.Now()}
+	g.m.Unlock()
+	return asn
+}
+
+func (g *ConnGater) InterceptPeerDial(p peer.ID) bool {
+	reason := ""
+	switch {
+	case g.noDial:
+		reason = NoDialGated
+	case g.blocklist.BlocksPeer(p):
+		reason = PeerGated
+	default:
+		return true
+	}
+	log.Tracef("gated dial to %s (%s)", p.String(), reason)
+	atomic.AddInt64(&g.gatedDials, 1)
+	g.gatedC.WithLabelValues("outbound", reason).Inc()
+	return false
+}
+
+func (g *ConnGater) InterceptAddrDial(p peer.ID, maddr ma.Multiaddr) bool {
+	blocked, reason := g.blocked(maddr)
+	if blocked {
+		log.Tracef("gated dial to %s at %s (%s)", p.String(), maddr.String(), reason)
+		atomic.AddInt64(&g.gatedDials, 1)
+		g.gatedC.WithLabelValues("outbound", reason).Inc()
+	}
+	return !blocked
+}
+
+func (g *ConnGater) InterceptAccept(connAddrs network.ConnMultiaddrs) bool {
+	blocked, reason := g.blocked(connAddrs.RemoteMultiaddr())
+	if blocked {
+		log.Tracef("gated inbound connection from %s (%s)", connAddrs.RemoteMultiaddr().String(), reason)
+		atomic.AddInt64(&g.gatedAccepts, 1)
+		g.gatedC.WithLabelValues("inbound", reason).Inc()
+	}
+	return !blocked
+}
+
+// InterceptSecured refuses the blocked peers, whose ID isn't known before the security handshake of the inbound connections
+func (g *ConnGater) InterceptSecured(dir network.Direction, p peer.ID, connAddrs network.ConnMultiaddrs) bool {
+	if !g.blocklist.BlocksPeer(p) {
+		return true
+	}
+	if dir == network.DirInbound {
+		log.Tracef("gated inbound connection from %s at %s (%s)", p.String(), connAddrs.RemoteMultiaddr().String(), PeerGated)
+		atomic.AddInt64(&g.gatedAccepts, 1)
+		g.gatedC.WithLabelValues("inbound", PeerGated).Inc()
+	} else {
+		log.Tracef("gated dial to %s at %s (%s)", p.String(), connAddrs.RemoteMultiaddr().String(), PeerGated)
+		atomic.AddInt64(&g.gatedDials, 1)
+		g.gatedC.WithLabelValues("outbound", PeerGated).Inc()
+	}
+	return false
+}
+
+func (g *ConnGater) InterceptUpgraded(conn network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
+
+// GatedCount returns the number of dials and inbound connections refused by the gater
+func (g *ConnGater) GatedCount() (dials, accepts int64) {
+	return atomic.LoadInt64(&g.gatedDials), atomic.LoadInt64(&g.gatedAccepts)
+}
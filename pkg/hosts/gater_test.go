@@ -0,0 +1,139 @@
+package hosts
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testConnAddrs struct {
+	remote ma.Multiaddr
+}
+
+func (c testConnAddrs) LocalMultiaddr() ma.Multiaddr  { return ma.StringCast("/ip4/127.0.0.1/tcp/9000") }
+func (c testConnAddrs) RemoteMultiaddr() ma.Multiaddr { return c.remote }
+
+func newTestPeerID(t *testing.T) peer.ID {
+	_, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	pID, err := peer.IDFromPublicKey(pub)
+	require.NoError(t, err)
+	return pID
+}
+
+func newTestGater(t *testing.T, entries []string, asns map[string]string) (*ConnGater, *prometheus.CounterVec) {
+	blocklist, err := NewBlocklist(entries)
+	require.NoError(t, err)
+	gatedC := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_gated_connections"}, []string{"direction", "reason"})
+	resolver := func(ip string) string {
+		return asns[ip]
+	}
+	return NewConnGater(blocklist, resolver, false, gatedC), gatedC
+}
+
+func Test_NewBlocklist(t *testing.T) {
+	pID := newTestPeerID(t)
+	blocklist, err := NewBlocklist([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "::1", "as1234", pID.String()})
+	require.NoError(t, err)
+	cidrs, asns, peers := blocklist.Len()
+	require.Equal(t, 4, cidrs)
+	require.Equal(t, 1, asns)
+	require.Equal(t, 1, peers)
+	require.True(t, blocklist.BlocksASN("AS1234"))
+	require.True(t, blocklist.BlocksPeer(pID))
+
+	for _, entry := range []string{"10.0.0.0/33", "ASxyz", "not-an-entry", "2001:db8::/129"} {
+		_, err := NewBlocklist([]string{entry})
+		require.Error(t, err, entry)
+	}
+
+	var empty *Blocklist
+	require.True(t, empty.IsEmpty())
+	require.False(t, empty.BlocksPeer(pID))
+}
+
+func Test_ConnGaterAddrDial(t *testing.T) {
+	gater, gatedC := newTestGater(t,
+		[]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "AS1234"},
+		map[string]string{"8.8.8.8": "AS1234 Some Org", "1.1.1.1": "AS5678 Other Org"},
+	)
+	pID := newTestPeerID(t)
+
+	tests := []struct {
+		name    string
+		maddr   string
+		allowed bool
+		reason  string
+	}{
+		{"ipv4 cidr match", "/ip4/10.1.2.3/tcp/9000", false, CIDRGated},
+		{"ipv4 cidr non-match", "/ip4/11.1.2.3/tcp/9000", true, ""},
+		{"single ip match", "/ip4/192.168.1.1/udp/9000/quic-v1", false, CIDRGated},
+		{"single ip non-match", "/ip4/192.168.1.2/tcp/9000", true, ""},
+		{"ipv6 cidr match", "/ip6/2001:db8::1/tcp/9000", false, CIDRGated},
+		{"ipv6 cidr non-match", "/ip6/2001:db9::1/tcp/9000", true, ""},
+		{"asn match", "/ip4/8.8.8.8/tcp/9000", false, ASNGated},
+		{"asn non-match", "/ip4/1.1.1.1/tcp/9000", true, ""},
+		{"unknown asn", "/ip4/9.9.9.9/tcp/9000", true, ""},
+		{"dns can't be gated", "/dns4/node.example/tcp/9000", true, ""},
+	}
+
+	for _, test := range tests {
+		before := testutil.ToFloat64(gatedC.WithLabelValues("outbound", test.reason))
+		dials, _ := gater.GatedCount()
+		require.Equal(t, test.allowed, gater.InterceptAddrDial(pID, ma.StringCast(test.maddr)), test.name)
+
+		gatedDials, _ := gater.GatedCount()
+		if test.allowed {
+			require.Equal(t, dials, gatedDials, test.name)
+			continue
+		}
+		require.Equal(t, dials+1, gatedDials, test.name)
+		require.Equal(t, before+1, testutil.ToFloat64(gatedC.WithLabelValues("outbound", test.reason)), test.name)
+	}
+}
+
+func Test_ConnGaterPeers(t *testing.T) {
+	blocked, allowed := newTestPeerID(t), newTestPeerID(t)
+	gater, gatedC := newTestGater(t, []string{blocked.String(), "10.0.0.0/8"}, nil)
+	remote := testConnAddrs{remote: ma.StringCast("/ip4/11.1.2.3/tcp/9000")}
+
+	// dials
+	require.True(t, gater.InterceptPeerDial(allowed))
+	require.False(t, gater.InterceptPeerDial(blocked))
+	require.Equal(t, 1.0, testutil.ToFloat64(gatedC.WithLabelValues("outbound", PeerGated)))
+
+	// inbound connections, only identified once secured
+	require.True(t, gater.InterceptAccept(remote))
+	require.True(t, gater.InterceptSecured(network.DirInbound, allowed, remote))
+	require.False(t, gater.InterceptSecured(network.DirInbound, blocked, remote))
+	require.Equal(t, 1.0, testutil.ToFloat64(gatedC.WithLabelValues("inbound", PeerGated)))
+	require.False(t, gater.InterceptAccept(testConnAddrs{remote: ma.StringCast("/ip4/10.1.2.3/tcp/9000")}))
+	require.Equal(t, 1.0, testutil.ToFloat64(gatedC.WithLabelValues("inbound", CIDRGated)))
+	// a dial that skipped the peer check is still refused once secured
+	require.False(t, gater.InterceptSecured(network.DirOutbound, blocked, remote))
+	require.Equal(t, 2.0, testutil.ToFloat64(gatedC.WithLabelValues("outbound", PeerGated)))
+
+	dials, accepts := gater.GatedCount()
+	require.Equal(t, int64(2), dials)
+	require.Equal(t, int64(2), accepts)
+}
+
+func Test_ConnGaterNoDial(t *testing.T) {
+	gatedC := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_gated_connections"}, []string{"direction", "reason"})
+	gater := NewConnGater(nil, nil, true, gatedC)
+	pID := newTestPeerID(t)
+
+	require.False(t, gater.InterceptPeerDial(pID))
+	require.Equal(t, 1.0, testutil.ToFloat64(gatedC.WithLabelValues("outbound", NoDialGated)))
+	// the inbound connections are still accepted
+	remote := testConnAddrs{remote: ma.StringCast("/ip4/10.1.2.3/tcp/9000")}
+	require.True(t, gater.InterceptAccept(remote))
+	require.True(t, gater.InterceptSecured(network.DirInbound, pID, remote))
+}
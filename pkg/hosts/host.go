@@ -2,19 +2,15 @@ package hosts
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/libp2p/go-libp2p"
-	mplex "github.com/libp2p/go-libp2p-mplex"
 	"github.com/libp2p/go-libp2p/core/connmgr"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/metrics"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
-	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
-	"github.com/libp2p/go-libp2p/p2p/muxer/yamux"
 	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
-	"github.com/libp2p/go-libp2p/p2p/security/noise"
-	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
 
 	log "github.com/sirupsen/logrus"
 
@@ -46,63 +42,125 @@ type BasicLibp2pHost struct {
 	NetworkNode P2pNetwork
 
 	// Basic Host Metadata
+	netOpts   NetworkOptions
 	multiAddr ma.Multiaddr
-
+	bandwidth *metrics.BandwidthCounter
+	rm        network.ResourceManager
+	gater     *ConnGater
+	reqResp   *reqRespTracker
+	dials     *dialTracker
+	inbound   *inboundTracker
+	metrics   *hostMetrics
+
+	// the events are buffered in the queues (without blocking libp2p) until the consumers read them from the channels
+	connEventQueue      *eventQueue
+	identQueue          *eventQueue
 	connEventNotChannel chan *models.EventTrace
 	identNotChannel     chan IdentificationEvent
 	peerID              peer.ID
 }
 
-// NewBasicLibp2pEth2Host generate a new Libp2p host from the given context and Options, for Eth2 network (or similar).
-func NewBasicLibp2pEth2Host(
+// NewBasicLibp2pHost generates a new Libp2p host from the given context and NetworkOptions, for any supported network.
+func NewBasicLibp2pHost(
 	ctx context.Context,
-	ip string,
-	port int,
-	privKey crypto.PrivKey,
-	userAgent string,
+	netOpts NetworkOptions,
 	netNode P2pNetwork,
 	ipLocator *apis.IpLocator) (*BasicLibp2pHost, error) {
 
-	// generate de multiaddress
-	multiaddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/%s/tcp/%d", ip, port))
+	// compose the libp2p options from the network ones
+	hostOpts, err := netOpts.libp2pOptions()
 	if err != nil {
-		return nil, errors.Wrap(err, fmt.Sprintf("couldn't generate multiaddress from ip %s and tcp %s", ip, port))
+		return nil, errors.Wrap(err, "unable to compose host options")
 	}
 
-	// resource manager we don't want the host to be limited by anything
-	limiter := rcmgr.NewFixedLimiter(rcmgr.InfiniteLimits)
-	rm, err := rcmgr.NewResourceManager(limiter)
-	if err != nil {
-		return nil, fmt.Errorf("new resource manager: %w", err)
+	// resource manager, by default we don't want the host to be limited by anything
+	rm := netOpts.ResourceManager
+	if rm == nil {
+		rm, err = NewResourceManager(netOpts.ResourceLimits)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !netOpts.ResourceLimits.IsUnlimited() {
+		log.WithFields(log.Fields{
+			"max-conns":                netOpts.ResourceLimits.MaxConns,
+			"max-conns-per-peer":       netOpts.ResourceLimits.MaxConnsPerPeer,
+			"max-streams-per-peer":     netOpts.ResourceLimits.MaxStreamsPerPeer,
+			"max-streams-per-protocol": netOpts.ResourceLimits.MaxStreamsPerProtocol,
+			"limits-file":              netOpts.ResourceLimits.LimitsFile,
+		}).Info("limiting the resources of the host")
 	}
 
-	// Generate the main Libp2p host that will be exposed to the network
-	host, err := libp2p.New(
-		libp2p.ListenAddrs(multiaddr),
-		libp2p.Identity(privKey),
-		libp2p.UserAgent(userAgent),
-		libp2p.Transport(tcp.NewTCPTransport),
-		libp2p.Security(noise.ID, noise.New),
-		libp2p.Muxer(mplex.ID, mplex.DefaultTransport),
-		libp2p.Muxer(yamux.ID, yamux.DefaultTransport),
-		libp2p.NATPortMap(),
+	// keep track of the bandwidth used by the host
+	bwCounter := metrics.NewBandwidthCounter()
+
+	// collectors of the metrics of the host (shared with the rest of the hosts of the pool)
+	hostMetrics := netOpts.metrics
+	if hostMetrics == nil {
+		hostMetrics = newHostMetrics()
+	}
+
+	hostOpts = append(hostOpts,
+		libp2p.BandwidthReporter(bwCounter),
 		libp2p.ResourceManager(rm),
 		libp2p.ConnectionManager(connmgr.NullConnMgr{}),
 	)
+	if netOpts.NATPortMap {
+		hostOpts = append(hostOpts, libp2p.NATPortMap())
+	}
+
+	// gate the connections to the blocked IP ranges and ASNs, and every dial in listen-only mode
+	var gater *ConnGater
+	if !netOpts.Blocklist.IsEmpty() || netOpts.NoDial {
+		var resolver ASNResolver
+		if ipLocator != nil {
+			resolver = func(ip string) string {
+				ipInfo, err := ipLocator.GetIpInfo(ip)
+				if err != nil {
+					return ""
+				}
+				return ipInfo.As
+			}
+		}
+		cidrs, asns, peers := netOpts.Blocklist.Len()
+		log.WithFields(log.Fields{
+			"cidrs": cidrs,
+			"asns":  asns,
+			"peers": peers,
+		}).Info("gating connections to blocklisted peers")
+		if netOpts.NoDial {
+			log.Info("listen-only host, refusing every dial")
+		}
+		gater = NewConnGater(netOpts.Blocklist, resolver, netOpts.NoDial, hostMetrics.GatedConnections)
+		hostOpts = append(hostOpts, libp2p.ConnectionGater(gater))
+	}
+
+	// Generate the main Libp2p host that will be exposed to the network
+	host, err := libp2p.New(hostOpts...)
 	if err != nil {
 		return nil, err
 	}
+	// ListenMultiaddrs was already validated while composing the options
+	mAddrs, _ := netOpts.ListenMultiaddrs()
 	log.WithFields(log.Fields{
-		"maddrs": multiaddr.String(),
-		"peerID": host.ID().String(),
+		"network": netOpts.Network,
+		"maddrs":  mAddrs,
+		"peerID":  host.ID().String(),
 	}).Info("libp2p successfully generated")
 
+	log.WithFields(log.Fields{
+		"signed-peer-record": netOpts.SignedPeerRecord,
+		"observed-addrs":     netOpts.ObservedAddrs,
+	}).Info("identify mode of the host")
+
 	// generate the identify service
-	ids, err := identify.NewIDService(
-		host,
-		identify.UserAgent(userAgent),
-		identify.DisableSignedPeerRecord(),
-	)
+	idOpts := []identify.Option{
+		identify.UserAgent(netOpts.UserAgent),
+	}
+	if !netOpts.SignedPeerRecord {
+		idOpts = append(idOpts, identify.DisableSignedPeerRecord())
+	}
+	ids, err := identify.NewIDService(host, idOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -114,81 +172,99 @@ func NewBasicLibp2pEth2Host(
 		host:                host,
 		identify:            ids,
 		IpLocator:           ipLocator,
-		multiAddr:           multiaddr,
+		netOpts:             netOpts,
+		bandwidth:           bwCounter,
+		rm:                  rm,
+		gater:               gater,
+		reqResp:             newReqRespTracker(),
+		dials:               newDialTracker(hostMetrics),
+		inbound:             newInboundTracker(hostMetrics),
+		metrics:             hostMetrics,
 		peerID:              host.ID(),
 		connEventNotChannel: make(chan *models.EventTrace, ConnNotChannSize),
 		identNotChannel:     make(chan IdentificationEvent, ConnNotChannSize),
 	}
+	// the discovery-only hosts don't listen
+	if len(mAddrs) > 0 {
+		basicHost.multiAddr = mAddrs[0]
+	}
+	queueSize := netOpts.EventQueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultEventQueueSize
+	}
+	basicHost.connEventQueue = newEventQueue(ConnEventQueue, queueSize, hostMetrics.DroppedEvents.WithLabelValues(ConnEventQueue))
+	basicHost.identQueue = newEventQueue(IdentEventQueue, queueSize, hostMetrics.DroppedEvents.WithLabelValues(IdentEventQueue))
+	go basicHost.pumpEvents()
+
 	log.Debug("setting custom notification functions")
 	basicHost.SetCustomNotifications()
 
 	return basicHost, nil
 }
 
-// This should be move to to a range of options inside the NewBasicHost
-
-// NewBasicLibp2pFilecoinHost:
-// Generate a new Libp2p host from the given context and Options, for Filecoin network.
-// func NewBasicLibp2pIpfsHost(ctx context.Context, infObj info.IpfsInfoData, ipLocator *apis., ps *db.PeerStore) (*BasicLibp2pHost, error) {
-// 	ip := infObj.IP.String()
-// 	tcp := fmt.Sprintf("%d", infObj.TcpPort)
-// 	privkey := infObj.PrivateKey
-// 	userAgent := infObj.UserAgent
-
-// 	// generate de multiaddress
-// 	multiaddr := fmt.Sprintf("/ip4/%s/tcp/%s", ip, tcp)
-// 	muladdr, err := ma.NewMultiaddr(multiaddr)
-// 	if err != nil {
-// 		log.Debugf("couldn't generate multiaddress from ip %s and tcp %s", ip, tcp)
-// 		multiaddr = fmt.Sprintf("/ip4/%s/tcp/%s", DefaultIP, DefaultTCP)
-// 		muladdr, _ = ma.NewMultiaddr(multiaddr)
-// 	}
-// 	log.Debugf("setting multiaddress to %s", muladdr)
-
-// 	// Generate the main Libp2p host that will be exposed to the network
-// 	host, err := libp2p.New(
-// 		libp2p.Identity(privkey),
-// 		libp2p.ListenAddrs(muladdr),
-// 		libp2p.Ping(true),
-// 		libp2p.UserAgent(userAgent),
-// 		libp2p.NATPortMap(),
-// 	)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	peerId := host.ID().String()
-// 	fmaddr := host.Addrs()[0].String() + "/p2p/" + host.ID().String()
-// 	localMultiaddr, _ := ma.NewMultiaddr(fmaddr)
-// 	log.Debugf("full multiaddress %s", localMultiaddr)
-// 	// generate the identify service
-// 	ids, err := identify.NewIDService(host, identify.UserAgent(userAgent), identify.DisableSignedPeerRecord())
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	// Gererate the struct that contains all the configuration and structs surrounding the Libp2p Host
-// 	basicHost := &BasicLibp2pHost{
-// 		ctx:                 ctx,
-// 		Network:             "ipfs",
-// 		host:                host,
-// 		identify:            ids,
-// 		PeerStore:           ps,
-// 		IpLocator:           ipLocator,
-// 		multiAddr:           muladdr,
-// 		fullMultiAddr:       localMultiaddr,
-// 		peerID:              peer.ID(peerId),
-// 		connEventNotChannel: make(chan ConnectionEvent, ConnNotChannSize),
-// 		identNotChannel:     make(chan IdentificationEvent, ConnNotChannSize),
-// 	}
-// 	log.Debug("setting custom notification functions")
-// 	basicHost.SetCustomNotifications()
-
-// 	return basicHost, nil
-// }
+// NewHost composes a new Libp2p host for the network of the given node, applying the HostOptions
+// over the default NetworkOptions of that network.
+func NewHost(
+	ctx context.Context,
+	netNode P2pNetwork,
+	ipLocator *apis.IpLocator,
+	opts ...HostOption) (*BasicLibp2pHost, error) {
+
+	netOpts := DefaultNetworkOptions(netNode.Network())
+	for _, opt := range opts {
+		err := opt(&netOpts)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply host option")
+		}
+	}
+	return NewBasicLibp2pHost(ctx, netOpts, netNode, ipLocator)
+}
+
+// NewBasicLibp2pEth2Host generate a new Libp2p host from the given context and Options, for Eth2 network (or similar).
+func NewBasicLibp2pEth2Host(
+	ctx context.Context,
+	ip string,
+	port int,
+	privKey crypto.PrivKey,
+	userAgent string,
+	netNode P2pNetwork,
+	ipLocator *apis.IpLocator) (*BasicLibp2pHost, error) {
+
+	return NewBasicLibp2pHost(
+		ctx,
+		DefaultEth2NetworkOptions(ip, port, privKey, userAgent),
+		netNode,
+		ipLocator,
+	)
+}
+
+// NewBasicLibp2pIpfsHost generate a new Libp2p host from the given context and Options, for IPFS-like networks (IPFS or Filecoin).
+func NewBasicLibp2pIpfsHost(
+	ctx context.Context,
+	ip string,
+	port int,
+	privKey crypto.PrivKey,
+	userAgent string,
+	netNode P2pNetwork,
+	ipLocator *apis.IpLocator) (*BasicLibp2pHost, error) {
+
+	return NewBasicLibp2pHost(
+		ctx,
+		DefaultIpfsNetworkOptions(netNode.Network(), ip, port, privKey, userAgent),
+		netNode,
+		ipLocator,
+	)
+}
 
 func (b *BasicLibp2pHost) Host() host.Host {
 	return b.host
 }
 
+// Options returns the NetworkOptions that were used to compose the host
+func (b *BasicLibp2pHost) Options() NetworkOptions {
+	return b.netOpts
+}
+
 // Start spawns the libp2pHost module
 // So far, start listening on the multiAddrs.
 func (b *BasicLibp2pHost) Start() error {
@@ -199,11 +275,34 @@ func (b *BasicLibp2pHost) Ctx() context.Context {
 	return b.ctx
 }
 
+// BandwidthTotals returns the total number of bytes received and sent by the host
+func (b *BasicLibp2pHost) BandwidthTotals() (in int64, out int64) {
+	stats := b.bandwidth.GetBandwidthTotals()
+	return stats.TotalIn, stats.TotalOut
+}
+
+// OpenConnections returns the number of connections that the host has currently open
+func (b *BasicLibp2pHost) OpenConnections() int {
+	return len(b.host.Network().Conns())
+}
+
+// Connect dials the given peer
+func (b *BasicLibp2pHost) Connect(ctx context.Context, addrInfo peer.AddrInfo) error {
+	err := b.host.Connect(ctx, addrInfo)
+	b.dials.recordDial(ClassifyDialError(ctx, err))
+	return err
+}
+
+// IsConnected returns whether the host has any open connection with the peer
+func (b *BasicLibp2pHost) IsConnected(peerID peer.ID) bool {
+	return b.host.Network().Connectedness(peerID) == network.Connected
+}
+
 // RecConnEvent
 // Record Connection Event
-// @param connEvent: the event to insert in the notification channel
+// @param connEvent: the event to insert in the notification queue (dropped if the queue is full)
 func (b *BasicLibp2pHost) RecConnEvent(eventTrace *models.EventTrace) {
-	b.connEventNotChannel <- eventTrace
+	b.connEventQueue.push(eventTrace)
 }
 
 func (b *BasicLibp2pHost) ConnEventNotChannel() chan *models.EventTrace {
@@ -212,9 +311,9 @@ func (b *BasicLibp2pHost) ConnEventNotChannel() chan *models.EventTrace {
 
 // RecIdentEvent
 // Record Identification Event
-// @param identEvent: the event to insert in the notification channel
+// @param identEvent: the event to insert in the notification queue (dropped if the queue is full)
 func (b *BasicLibp2pHost) RecIdentEvent(identEvent IdentificationEvent) {
-	b.identNotChannel <- identEvent
+	b.identQueue.push(identEvent)
 }
 
 func (b *BasicLibp2pHost) IdentEventNotChannel() chan IdentificationEvent {
@@ -248,5 +347,10 @@ func (b *BasicLibp2pHost) GetHostInfo(peerID peer.ID) (models.HostInfo, error) {
 	if err == nil {
 		hostInfo.PeerInfo.UserAgent = ua.(string)
 	}
+	// Security protocol negotiated (if we are still connected)
+	conns := b.host.Network().ConnsToPeer(peerID)
+	if len(conns) > 0 {
+		hostInfo.PeerInfo.Security = string(conns[0].ConnState().Security)
+	}
 	return hostInfo, nil
 }
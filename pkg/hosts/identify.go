@@ -52,6 +52,9 @@ func ReqHostInfo(ctx context.Context, wg *sync.WaitGroup, h host.Host, ipLoc *ap
 		rtt = time.Since(t)
 	case <-ctx.Done():
 		finErr = errors.Errorf("identification error caused by timed out")
+		if ctx.Err() == context.DeadlineExceeded {
+			finErr = TimeoutError(IdentifyTimeout, finErr)
+		}
 		*errIdent = finErr
 		return
 	}
@@ -72,6 +75,7 @@ func ReqHostInfo(ctx context.Context, wg *sync.WaitGroup, h host.Host, ipLoc *ap
 	}
 	// Update the values of the
 	hInfo.PeerInfo.Latency = rtt
+	hInfo.PeerInfo.Security = string(conn.ConnState().Security)
 	hInfo.PeerInfo.RemotePeer = peerID
 
 	// Fulfill the hInfo struct
@@ -0,0 +1,72 @@
+package hosts
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// InboundStats accounts the connections that the remote peers opened to the host on their own
+type InboundStats struct {
+	Connections int64
+	Identified  int64
+	// different peers that connected to the host, by client
+	Peers map[string]int64
+
+	FirstConnection time.Time
+	LastConnection  time.Time
+}
+
+type inboundTracker struct {
+	m       sync.Mutex
+	stats   InboundStats
+	seen    map[peer.ID]struct{}
+	metrics *hostMetrics
+}
+
+func newInboundTracker(metrics *hostMetrics) *inboundTracker {
+	return &inboundTracker{
+		metrics: metrics,
+		seen:    make(map[peer.ID]struct{}),
+		stats: InboundStats{
+			Peers: make(map[string]int64),
+		},
+	}
+}
+
+// record accounts an inbound connection, whose peer is counted with the client it was identified
+// with the first time it connected
+func (t *inboundTracker) record(peerID peer.ID, client string, identified bool, connTime time.Time) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.stats.Connections++
+	result := "unidentified"
+	if identified {
+		t.stats.Identified++
+		result = "identified"
+	}
+	t.metrics.InboundConnections.WithLabelValues(result).Inc()
+	if t.stats.FirstConnection.IsZero() {
+		t.stats.FirstConnection = connTime
+	}
+	t.stats.LastConnection = connTime
+	if _, ok := t.seen[peerID]; ok {
+		return
+	}
+	t.seen[peerID] = struct{}{}
+	t.stats.Peers[client]++
+	t.metrics.InboundPeers.WithLabelValues(client).Inc()
+}
+
+// InboundStats returns a copy of the stats of the connections that the peers opened to the host
+func (b *BasicLibp2pHost) InboundStats() InboundStats {
+	b.inbound.m.Lock()
+	defer b.inbound.m.Unlock()
+	stats := b.inbound.stats
+	stats.Peers = make(map[string]int64, len(b.inbound.stats.Peers))
+	for client, n := range b.inbound.stats.Peers {
+		stats.Peers[client] = n
+	}
+	return stats
+}
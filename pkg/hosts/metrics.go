@@ -1,7 +1,9 @@
 package hosts
 
 import (
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/migalabs/armiarma/pkg/utils/clientinfo"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
@@ -9,21 +11,146 @@ import (
 var (
 	moduleName    = "host"
 	moduleDetails = "All the metrics around the libp2p host"
-
-	ConnectedPeers = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: moduleName,
-		Name:      "connected_peers",
-		Help:      "The number of connected peers to our host",
-	})
-	SupportedProtocols = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: moduleName,
-		Name:      "supported_protocols",
-		Help:      "The list of supported procols that the crawler's libp2p host supports",
-	},
-		[]string{"protocol"},
-	)
 )
 
+// hostMetrics are the collectors of the metrics of the hosts. Each crawler has its own set (shared
+// by the hosts of its pool), so that the crawlers of several networks can run in the same process
+type hostMetrics struct {
+	ConnectedPeers           prometheus.Gauge
+	ConnectedPeersByClient   *prometheus.GaugeVec
+	SupportedProtocols       *prometheus.GaugeVec
+	ResourceUsage            *prometheus.GaugeVec
+	GatedConnections         *prometheus.CounterVec
+	ProtocolStreams          *prometheus.GaugeVec
+	QueuedEvents             *prometheus.GaugeVec
+	DroppedEvents            *prometheus.CounterVec
+	OpenConnections          *prometheus.GaugeVec
+	DialAttempts             prometheus.Counter
+	DialSuccesses            prometheus.Counter
+	DialFailures             *prometheus.CounterVec
+	Identifications          *prometheus.CounterVec
+	IdentifySuccessRate      prometheus.Gauge
+	NotificationChannelDepth *prometheus.GaugeVec
+	InboundConnections       *prometheus.CounterVec
+	InboundPeers             *prometheus.CounterVec
+}
+
+func newHostMetrics() *hostMetrics {
+	return &hostMetrics{
+		ConnectedPeers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "connected_peers",
+			Help:      "The number of connected peers to our host",
+		}),
+		ConnectedPeersByClient: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "connected_peers_by_client",
+			Help:      "The number of connected peers to our host of each client (as advertised in their UserAgent)",
+		},
+			[]string{"client"},
+		),
+		SupportedProtocols: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "supported_protocols",
+			Help:      "The list of supported procols that the crawler's libp2p host supports",
+		},
+			[]string{"protocol"},
+		),
+		ResourceUsage: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "resource_usage",
+			Help:      "Resources currently in use at the system and transient scopes of the resource manager",
+		},
+			[]string{"scope", "resource"},
+		),
+		GatedConnections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "gated_connections",
+			Help:      "Number of dials and inbound connections refused by the connection gater",
+		},
+			[]string{"direction", "reason"},
+		),
+		ProtocolStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "protocol_streams",
+			Help:      "Streams currently open per protocol and direction",
+		},
+			[]string{"protocol", "direction"},
+		),
+		QueuedEvents: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "queued_events",
+			Help:      "Connection and identification events waiting to be processed",
+		},
+			[]string{"queue"},
+		),
+		DroppedEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "dropped_events",
+			Help:      "Connection and identification events dropped because the queue was full",
+		},
+			[]string{"queue"},
+		),
+		OpenConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "open_connections",
+			Help:      "Connections currently open by the host per direction",
+		},
+			[]string{"direction"},
+		),
+		DialAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "dial_attempts",
+			Help:      "Number of dials attempted by the host",
+		}),
+		DialSuccesses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "dial_successes",
+			Help:      "Number of dials that ended up in a connection",
+		}),
+		DialFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "dial_failures",
+			Help:      "Number of failed dials per error code",
+		},
+			[]string{"code"},
+		),
+		Identifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "identifications",
+			Help:      "Number of identifications of connected peers per result",
+		},
+			[]string{"result"},
+		),
+		IdentifySuccessRate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "identify_success_rate",
+			Help:      "Share of the connections whose peer got identified",
+		}),
+		NotificationChannelDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "notification_channel_depth",
+			Help:      "Events waiting in the notification channels to be read by the consumers",
+		},
+			[]string{"channel"},
+		),
+		InboundConnections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "inbound_connections",
+			Help:      "Number of connections opened by the remote peers, by whether the peer got identified",
+		},
+			[]string{"result"},
+		),
+		InboundPeers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: moduleName,
+			Name:      "inbound_peers",
+			Help:      "Number of different peers that connected to the host on their own, by client",
+		},
+			[]string{"client"},
+		),
+	}
+}
+
 func (bh *BasicLibp2pHost) GetMetrics() *metrics.MetricsModule {
 	metricsMod := metrics.NewMetricsModule(
 		moduleName,
@@ -31,17 +158,37 @@ func (bh *BasicLibp2pHost) GetMetrics() *metrics.MetricsModule {
 	)
 	metricsMod.AddIndvMetric(bh.connectedPeers())
 	metricsMod.AddIndvMetric(bh.supportedProtocols())
+	metricsMod.AddIndvMetric(bh.resourceUsage())
+	metricsMod.AddIndvMetric(bh.gatedConnections())
+	metricsMod.AddIndvMetric(bh.eventQueues())
+	metricsMod.AddIndvMetric(bh.openConnections())
+	metricsMod.AddIndvMetric(bh.dialStats())
+	metricsMod.AddIndvMetric(bh.inboundStats())
 	return metricsMod
 }
 
 func (bh *BasicLibp2pHost) connectedPeers() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.Register(ConnectedPeers)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.ConnectedPeers)
+		reg.Register(bh.metrics.ConnectedPeersByClient)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
 		peers := bh.host.Network().Peers()
-		ConnectedPeers.Set(float64(len(peers)))
+		bh.metrics.ConnectedPeers.Set(float64(len(peers)))
+		// the peers that weren't identified yet have no UserAgent
+		byClient := make(map[string]int)
+		for _, peerID := range peers {
+			client := "unidentified"
+			if ua, err := bh.host.Peerstore().Get(peerID, "AgentVersion"); err == nil {
+				client = string(clientinfo.ClientName(bh.NetworkNode.Network(), ua.(string)))
+			}
+			byClient[client]++
+		}
+		bh.metrics.ConnectedPeersByClient.Reset()
+		for client, n := range byClient {
+			bh.metrics.ConnectedPeersByClient.WithLabelValues(client).Set(float64(n))
+		}
 		return len(peers), nil
 	}
 	peersTop, err := metrics.NewIndvMetrics(
@@ -57,18 +204,18 @@ func (bh *BasicLibp2pHost) connectedPeers() *metrics.IndvMetrics {
 }
 
 func (bh *BasicLibp2pHost) supportedProtocols() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.Register(SupportedProtocols)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.SupportedProtocols)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
 		protocols := bh.host.Mux().Protocols()
 		summary := make(map[string]int8)
-		SupportedProtocols.Reset()
+		bh.metrics.SupportedProtocols.Reset()
 		for _, prot := range protocols {
 			protStr := string(prot)
 			summary[protStr] = int8(1)
-			SupportedProtocols.WithLabelValues(protStr).Set(float64(1))
+			bh.metrics.SupportedProtocols.WithLabelValues(protStr).Set(float64(1))
 		}
 		return summary, nil
 	}
@@ -83,3 +230,207 @@ func (bh *BasicLibp2pHost) supportedProtocols() *metrics.IndvMetrics {
 	}
 	return peersTop
 }
+
+func (bh *BasicLibp2pHost) resourceUsage() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.ResourceUsage)
+		reg.Register(bh.metrics.ProtocolStreams)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		summary := make(map[string]interface{})
+		system, transient := bh.ResourceUsage()
+		for scope, stat := range map[string]network.ScopeStat{"system": system, "transient": transient} {
+			usage := map[string]int64{
+				"conns_inbound":    int64(stat.NumConnsInbound),
+				"conns_outbound":   int64(stat.NumConnsOutbound),
+				"streams_inbound":  int64(stat.NumStreamsInbound),
+				"streams_outbound": int64(stat.NumStreamsOutbound),
+				"fd":               int64(stat.NumFD),
+				"memory":           stat.Memory,
+			}
+			for resource, val := range usage {
+				bh.metrics.ResourceUsage.WithLabelValues(scope, resource).Set(float64(val))
+			}
+			summary[scope] = usage
+		}
+		bh.metrics.ProtocolStreams.Reset()
+		for _, prot := range bh.host.Mux().Protocols() {
+			stat := bh.ProtocolResourceUsage(prot)
+			bh.metrics.ProtocolStreams.WithLabelValues(string(prot), "inbound").Set(float64(stat.NumStreamsInbound))
+			bh.metrics.ProtocolStreams.WithLabelValues(string(prot), "outbound").Set(float64(stat.NumStreamsOutbound))
+		}
+		return summary, nil
+	}
+	rmUsage, err := metrics.NewIndvMetrics(
+		"resource_usage",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return rmUsage
+}
+
+func (bh *BasicLibp2pHost) gatedConnections() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.GatedConnections)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		summary := make(map[string]int64)
+		if bh.gater == nil {
+			return summary, nil
+		}
+		// the counters are increased by the gater itself
+		summary["outbound"], summary["inbound"] = bh.gater.GatedCount()
+		return summary, nil
+	}
+	gated, err := metrics.NewIndvMetrics(
+		"gated_connections",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return gated
+}
+
+func (bh *BasicLibp2pHost) eventQueues() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.QueuedEvents)
+		reg.Register(bh.metrics.DroppedEvents)
+		reg.Register(bh.metrics.NotificationChannelDepth)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		// the dropped events are counted by the queues themselves
+		queued, dropped := bh.EventQueueStats()
+		for queue, n := range queued {
+			bh.metrics.QueuedEvents.WithLabelValues(queue).Set(float64(n))
+		}
+		channels := map[string]int{
+			ConnEventQueue:  len(bh.connEventNotChannel),
+			IdentEventQueue: len(bh.identNotChannel),
+		}
+		for channel, n := range channels {
+			bh.metrics.NotificationChannelDepth.WithLabelValues(channel).Set(float64(n))
+		}
+		summary := map[string]interface{}{
+			"queued":   queued,
+			"dropped":  dropped,
+			"channels": channels,
+		}
+		return summary, nil
+	}
+	queues, err := metrics.NewIndvMetrics(
+		"event_queues",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return queues
+}
+
+func (bh *BasicLibp2pHost) openConnections() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.OpenConnections)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		summary := map[string]int{
+			"inbound":  0,
+			"outbound": 0,
+		}
+		for _, conn := range bh.host.Network().Conns() {
+			switch conn.Stat().Direction {
+			case network.DirInbound:
+				summary["inbound"]++
+			case network.DirOutbound:
+				summary["outbound"]++
+			}
+		}
+		for direction, n := range summary {
+			bh.metrics.OpenConnections.WithLabelValues(direction).Set(float64(n))
+		}
+		return summary, nil
+	}
+	conns, err := metrics.NewIndvMetrics(
+		"open_connections",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return conns
+}
+
+func (bh *BasicLibp2pHost) dialStats() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.DialAttempts)
+		reg.Register(bh.metrics.DialSuccesses)
+		reg.Register(bh.metrics.DialFailures)
+		reg.Register(bh.metrics.Identifications)
+		reg.Register(bh.metrics.IdentifySuccessRate)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		// the counters are increased by the host itself on each dial and identification
+		stats := bh.DialStats()
+		bh.metrics.IdentifySuccessRate.Set(stats.IdentifySuccessRate())
+		summary := map[string]interface{}{
+			"attempts":              stats.Attempts,
+			"successes":             stats.Successes,
+			"failures":              stats.Failures,
+			"identify_success_rate": stats.IdentifySuccessRate(),
+		}
+		return summary, nil
+	}
+	dials, err := metrics.NewIndvMetrics(
+		"dial_stats",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return dials
+}
+
+func (bh *BasicLibp2pHost) inboundStats() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.Register(bh.metrics.InboundConnections)
+		reg.Register(bh.metrics.InboundPeers)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		// the counters are increased by the host itself on each inbound connection
+		stats := bh.InboundStats()
+		summary := map[string]interface{}{
+			"connections": stats.Connections,
+			"identified":  stats.Identified,
+			"peers":       stats.Peers,
+		}
+		return summary, nil
+	}
+	inbound, err := metrics.NewIndvMetrics(
+		"inbound_stats",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return inbound
+}
@@ -0,0 +1,74 @@
+package hosts
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	msmux "github.com/multiformats/go-multistream"
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+// NegotiationAttPrefix prefixes the HostInfo attributes with the negotiation of each protocol
+const NegotiationAttPrefix = "negotiation-"
+
+var (
+	// time to negotiate all the protocols of a peer
+	negotiationTimeout = 10 * time.Second
+)
+
+// ReqProtocolNegotiations proposes to the peer, one by one, the protocols that both of us support,
+// timing how long the multistream-select negotiation of each of them takes. The negotiation is done
+// on raw streams, as the host skips it (lazy negotiation) for the protocols announced through identify
+func ReqProtocolNegotiations(ctx context.Context, wg *sync.WaitGroup, h host.Host, peerID peer.ID, protocols []string, negotiations *[]*models.ProtocolNegotiation) {
+	defer wg.Done()
+
+	for _, prot := range sharedProtocols(h, protocols) {
+		if ctx.Err() != nil {
+			return
+		}
+		duration, err := negotiateProtocol(ctx, h, peerID, prot)
+		*negotiations = append(*negotiations, models.NewProtocolNegotiation(peerID, string(prot), duration, err))
+	}
+}
+
+// negotiateProtocol opens a stream with the peer and times the multistream-select of the given protocol
+func negotiateProtocol(ctx context.Context, h host.Host, peerID peer.ID, prot protocol.ID) (time.Duration, error) {
+	s, err := h.Network().NewStream(ctx, peerID)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to open stream")
+	}
+	// we aren't going to speak the protocol, just agree on it
+	defer s.Reset()
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+	err = msmux.SelectProtoOrFail(prot, s)
+	duration := time.Since(start)
+	if err != nil {
+		return duration, errors.Wrap(err, "protocol negotiation failed")
+	}
+	return duration, nil
+}
+
+// sharedProtocols returns the announced protocols of the peer that our host supports as well
+func sharedProtocols(h host.Host, protocols []string) []protocol.ID {
+	ours := make(map[protocol.ID]struct{})
+	for _, prot := range h.Mux().Protocols() {
+		ours[prot] = struct{}{}
+	}
+	shared := make([]protocol.ID, 0)
+	for _, prot := range protocol.ConvertFromStrings(protocols) {
+		if _, ok := ours[prot]; ok {
+			shared = append(shared, prot)
+		}
+	}
+	return shared
+}
@@ -7,7 +7,10 @@ import (
 
 	"github.com/migalabs/armiarma/pkg/db/models"
 	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/filecoin"
+	"github.com/migalabs/armiarma/pkg/networks/ipfs"
 	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/clientinfo"
 
 	"github.com/libp2p/go-libp2p/core/network"
 	ma "github.com/multiformats/go-multiaddr"
@@ -19,6 +22,13 @@ import (
 	File that includes the methods to set the custom modification channels for the Libp2p host
 */
 
+var (
+	// slots behind the head of the peer from which the blocks are probed
+	blocksByRangeProbeDepth uint64 = 64
+	// time to receive the probed range of blocks
+	blocksByRangeProbeTimeout = 10 * time.Second
+)
+
 type IdentificationEvent struct {
 	HostInfo  *models.HostInfo
 	Timestamp time.Time // Timestamp of when was the attempt done
@@ -43,7 +53,8 @@ func (c *BasicLibp2pHost) standardConnectF(net network.Network, conn network.Con
 
 	// Only locate new IP if the connection is "Inbound"
 	// if it's outbound - we should already have it in the DB
-	if conn.Stat().Direction.String() == "Inbound" {
+	inbound := conn.Stat().Direction == network.DirInbound
+	if inbound {
 		ip := utils.ExtractIPFromMAddr(conn.RemoteMultiaddr()).String()
 		c.IpLocator.LocateIP(ip)
 	}
@@ -58,10 +69,17 @@ func (c *BasicLibp2pHost) standardConnectF(net network.Network, conn network.Con
 		c.NetworkNode.Network(),
 		models.WithMultiaddress(mAddrs),
 	)
+	// peers that dial us might have never been discovered (the origin of already known peers is kept in the DB)
+	if inbound {
+		hInfo.Origin = models.InboundOrigin
+	}
 
-	// Aggregate timeout context for the different
-	mainCtx, cancel := context.WithTimeout(c.Ctx(), 5*time.Second)
-	defer cancel()
+	// the identification and the req/resps have their own timeouts, which depend on the class of the peer
+	_, timeouts := c.netOpts.Timeouts.ForPeer(mAddrs)
+	identCtx, identCancel := context.WithTimeout(c.Ctx(), timeouts.Identify)
+	defer identCancel()
+	reqRespCtx, reqRespCancel := context.WithTimeout(c.Ctx(), timeouts.ReqResp)
+	defer reqRespCancel()
 	// set sync group and error groups to handle different reqresps
 	var wg sync.WaitGroup
 
@@ -71,26 +89,56 @@ func (c *BasicLibp2pHost) standardConnectF(net network.Network, conn network.Con
 	// for Eth2
 	var bStatus common.Status
 	var bMetadata common.MetaData
+	var bPing common.Ping
+	var pingRTT time.Duration
 
 	var hinfoErr error
-	var statusErr, metadataErr error
+	var statusErr, metadataErr, pingErr error
+	var peerRecord *models.SignedPeerRecord
+	var peerRecordErr error
+
+	// for Filecoin
+	var fHello filecoin.HelloStamped
+	var helloErr error
 
 	wg.Add(1)
-	go ReqHostInfo(mainCtx, &wg, h, c.IpLocator, conn, hInfo, &hinfoErr)
+	go ReqHostInfo(identCtx, &wg, h, c.IpLocator, conn, hInfo, &hinfoErr)
+
+	if c.netOpts.CollectPeerRecords {
+		wg.Add(1)
+		go ReqSignedPeerRecord(identCtx, &wg, h, conn.RemotePeer(), &peerRecord, &peerRecordErr)
+	}
 
 	switch c.NetworkNode.(type) {
 	case (*eth.LocalEthereumNode):
 		ethNet := c.NetworkNode.(*eth.LocalEthereumNode)
 		// request BeaconStatus metadata as we connect to a peer
 		wg.Add(1)
-		go ethNet.ReqBeaconStatus(mainCtx, &wg, h, conn.RemotePeer(), &bStatus, &statusErr)
+		go ethNet.ReqBeaconStatus(reqRespCtx, &wg, h, conn.RemotePeer(), &bStatus, &statusErr)
 		// request the BeaconMetadata
 		wg.Add(1)
-		go ethNet.ReqBeaconMetadata(mainCtx, &wg, h, conn.RemotePeer(), &bMetadata, &metadataErr)
+		go ethNet.ReqBeaconMetadata(reqRespCtx, &wg, h, conn.RemotePeer(), &bMetadata, &metadataErr)
+		// ping the peer (seq number of its metadata)
+		wg.Add(1)
+		go ethNet.ReqBeaconPing(reqRespCtx, &wg, h, conn.RemotePeer(), &bPing, &pingRTT, &pingErr)
+	case (*ipfs.LocalIpfsNode):
+		// the Filecoin nodes say hello with their chain head once they identify us
+		if hello := c.NetworkNode.(*ipfs.LocalIpfsNode).Hello(); hello != nil {
+			wg.Add(1)
+			go hello.WaitHello(reqRespCtx, &wg, conn.RemotePeer(), &fHello, &helloErr)
+		}
 	default:
 	}
 
 	wg.Wait()
+	// label the req/resp errors caused by our own timeout (the identification labels its own)
+	if reqRespCtx.Err() == context.DeadlineExceeded {
+		for _, reqErr := range []*error{&statusErr, &metadataErr, &pingErr, &helloErr} {
+			if *reqErr != nil {
+				*reqErr = TimeoutError(ReqRespTimeout, *reqErr)
+			}
+		}
+	}
 	// Parse the errors from the different go routines,
 	// if there wasn't anything in the channel, or if the err is nil fetch peer info
 	if hinfoErr != nil {
@@ -100,11 +148,90 @@ func (c *BasicLibp2pHost) standardConnectF(net network.Network, conn network.Con
 		}).Debug("ReqHostInfo Peer: ", conn.RemotePeer().String())
 	} else {
 		log.Debug("peer identified, succeed")
+		// the identify RTT is an upper bound of the network RTT to the IP of the connection,
+		// enough to detect locations that are too far away
+		connIP := utils.ExtractIPFromMAddr(conn.RemoteMultiaddr())
+		if connIP != nil && utils.IsIPPublic(connIP) {
+			c.IpLocator.CheckRTT(connIP.String(), hInfo.PeerInfo.Latency)
+		}
+	}
+
+	if peerRecordErr != nil {
+		log.WithFields(log.Fields{
+			"ERROR": peerRecordErr.Error(),
+		}).Debug("ReqSignedPeerRecord Peer: ", conn.RemotePeer().String())
+	} else if peerRecord != nil {
+		hInfo.AddAtt("signed-peer-record", peerRecord)
+	}
+
+	// time the multistream-select negotiation of the protocols that the peer announced
+	if hinfoErr == nil && c.netOpts.NegotiationTiming {
+		negCtx, negCancel := context.WithTimeout(c.Ctx(), negotiationTimeout)
+		negotiations := make([]*models.ProtocolNegotiation, 0)
+		wg.Add(1)
+		ReqProtocolNegotiations(negCtx, &wg, h, conn.RemotePeer(), hInfo.PeerInfo.Protocols, &negotiations)
+		negCancel()
+		for _, negotiation := range negotiations {
+			hInfo.AddAtt(NegotiationAttPrefix+negotiation.Protocol, negotiation)
+		}
+	}
+
+	// check whether the peer serves historical blocks, requesting a range behind its head
+	if statusErr == nil && c.netOpts.BlocksByRangeProbe > 0 {
+		if ethNet, ok := c.NetworkNode.(*eth.LocalEthereumNode); ok {
+			startSlot := common.Slot(0)
+			if uint64(bStatus.HeadSlot) > blocksByRangeProbeDepth {
+				startSlot = bStatus.HeadSlot - common.Slot(blocksByRangeProbeDepth)
+			}
+			probeCtx, probeCancel := context.WithTimeout(c.Ctx(), blocksByRangeProbeTimeout)
+			probe := new(eth.BlocksByRangeProbe)
+			wg.Add(1)
+			ethNet.ReqBeaconBlocksByRange(probeCtx, &wg, h, conn.RemotePeer(), startSlot, c.netOpts.BlocksByRangeProbe, probe)
+			probeCancel()
+			if probe.Success() {
+				log.WithFields(log.Fields{
+					"blocks":     probe.Received,
+					"latency":    probe.Latency,
+					"throughput": probe.Throughput(),
+				}).Debug("peer blocks by range req, succeed")
+			} else {
+				log.WithFields(log.Fields{
+					"ERROR": probe.Error,
+				}).Debug("ReqBlocksByRange Peer: ", conn.RemotePeer().String())
+			}
+			hInfo.AddAtt("blocks-by-range", probe)
+		}
+	}
+
+	// keep the history of the addresses of the peer (the dialed one succeeded, as we got connected)
+	if inbound {
+		hInfo.AddAtt("conn-multiaddr", models.NewMultiaddrObservation(
+			conn.RemotePeer(), []ma.Multiaddr{conn.RemoteMultiaddr()}, models.InboundAddr, false, false))
+	} else {
+		hInfo.AddAtt("conn-multiaddr", models.NewMultiaddrObservation(
+			conn.RemotePeer(), []ma.Multiaddr{conn.RemoteMultiaddr()}, models.DialedAddr, true, true))
+	}
+	if hInfo.IsHostIdentified() {
+		listenAddrs := h.Peerstore().Addrs(conn.RemotePeer())
+		if len(listenAddrs) > 0 {
+			hInfo.AddAtt("listen-multiaddrs", models.NewMultiaddrObservation(
+				conn.RemotePeer(), listenAddrs, models.AdvertisedAddr, false, false))
+		}
+	}
+
+	// the remote address of inbound connections has an ephemeral port,
+	// replace it with the listen addrs that the peer advertised through identify
+	if inbound && hInfo.IsHostIdentified() {
+		c.useListenAddrs(hInfo)
 	}
 
 	// If the network was eth2, wait for the metadata echange to reply
 	switch c.NetworkNode.(type) {
 	case (*eth.LocalEthereumNode):
+		// account whether the peer answered our requests
+		c.reqResp.record(conn.RemotePeer(), statusErr)
+		c.reqResp.record(conn.RemotePeer(), metadataErr)
+		c.reqResp.record(conn.RemotePeer(), pingErr)
 		// Beacon Status reqresp error check
 		// if there is an error  in the channel, print error
 		if statusErr != nil {
@@ -125,9 +252,39 @@ func (c *BasicLibp2pHost) standardConnectF(net network.Network, conn network.Con
 			log.Debug("peer metadata req, succeed", bMetadata)
 			hInfo.AddAtt("beaconmetadata", eth.NewBeaconMetadata(conn.RemotePeer(), bMetadata))
 		}
+		if pingErr != nil {
+			log.WithFields(log.Fields{
+				"ERROR": pingErr.Error(),
+			}).Debug("ReqPing Peer: ", conn.RemotePeer().String())
+		} else {
+			log.Debug("peer ping req, succeed", bPing)
+			hInfo.AddAtt("beacon-ping", eth.NewBeaconPing(conn.RemotePeer(), bPing, pingRTT))
+		}
+	case (*ipfs.LocalIpfsNode):
+		if c.NetworkNode.(*ipfs.LocalIpfsNode).Hello() == nil {
+			break
+		}
+		c.reqResp.record(conn.RemotePeer(), helloErr)
+		if helloErr != nil {
+			log.WithFields(log.Fields{
+				"ERROR": helloErr.Error(),
+			}).Debug("Hello Peer: ", conn.RemotePeer().String())
+		} else {
+			log.Debug("peer hello, succeed", fHello.HeadHeight)
+			hInfo.AddAtt("filecoin-hello", fHello)
+		}
 	default:
 	}
 
+	c.dials.recordIdentification(hInfo.IsHostIdentified())
+	if inbound {
+		client := "unidentified"
+		if hInfo.IsHostIdentified() {
+			client = string(clientinfo.ClientName(c.NetworkNode.Network(), hInfo.PeerInfo.UserAgent))
+		}
+		c.inbound.record(conn.RemotePeer(), client, hInfo.IsHostIdentified(), t)
+	}
+
 	identStat := IdentificationEvent{
 		HostInfo:  hInfo,
 		Timestamp: t,
@@ -139,6 +296,7 @@ func (c *BasicLibp2pHost) standardConnectF(net network.Network, conn network.Con
 		ConnTime:   t,
 		Latency:    hInfo.PeerInfo.Latency,
 		Identified: hInfo.IsHostIdentified(),
+		AddrFamily: utils.GetAddrFamily(conn.RemoteMultiaddr()),
 		Error:      hinfoErr.Error(),
 	}
 
@@ -152,6 +310,28 @@ func (c *BasicLibp2pHost) standardConnectF(net network.Network, conn network.Con
 	c.RecIdentEvent(identStat)
 }
 
+// useListenAddrs replaces the multiaddrs of the HostInfo with the listen addrs of the peer in the peerstore,
+// locating the new public IP if it differs from the one of the connection
+func (c *BasicLibp2pHost) useListenAddrs(hInfo *models.HostInfo) {
+	listenAddrs := c.Host().Peerstore().Addrs(hInfo.ID)
+	public := false
+	for _, addr := range listenAddrs {
+		if utils.IsIPPublic(utils.ExtractIPFromMAddr(addr)) {
+			public = true
+			break
+		}
+	}
+	// keep the address of the connection if the peer didn't advertise any public addr
+	if !public {
+		return
+	}
+	connIP := hInfo.IP
+	hInfo.ReplaceMultiaddrs(listenAddrs)
+	if hInfo.IP != connIP {
+		c.IpLocator.LocateIP(hInfo.IP)
+	}
+}
+
 func (c *BasicLibp2pHost) standardDisconnectF(net network.Network, conn network.Conn) {
 	t := time.Now()
 	log.WithFields(log.Fields{
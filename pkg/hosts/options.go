@@ -0,0 +1,577 @@
+package hosts
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	mplex "github.com/libp2p/go-libp2p-mplex"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/p2p/muxer/yamux"
+	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	"github.com/libp2p/go-libp2p/p2p/security/noise"
+	tls "github.com/libp2p/go-libp2p/p2p/security/tls"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/migalabs/armiarma/pkg/networks/ethereum/rpc/methods"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+const (
+	// Transports
+	TcpTransport = "tcp"
+
+	// Security protocols
+	NoiseSecurity = "noise"
+	TlsSecurity   = "tls"
+
+	// Stream multiplexers
+	MplexMuxer = "mplex"
+	YamuxMuxer = "yamux"
+
+	// IP families that the host listens on and prefers to dial
+	IPv4Family = "v4"
+	IPv6Family = "v6"
+	DualStack  = "both"
+)
+
+var (
+	// delay applied to the dials of the non-preferred IP family
+	NonPreferredFamilyDialDelay = 1 * time.Second
+)
+
+// NetworkOptions compiles the set of parameters that define how the libp2p host
+// is exposed to a given network (identity, listening address, transports, security...)
+type NetworkOptions struct {
+	// Network that the host will join
+	Network utils.NetworkType
+
+	// Identity
+	PrivKey   crypto.PrivKey
+	UserAgent string
+
+	// Listening address
+	IP       string
+	IP6      string
+	Port     int
+	IPFamily string
+
+	// Ordered by preference
+	Transports []string
+	Security   []string
+	Muxers     []string
+
+	// Identify behavior (affects how remote peers perceive the crawler)
+	// SignedPeerRecord: whether our own signed peer record is shared through identify
+	// ObservedAddrs: whether the addresses that remote peers observed for us are advertised back
+	SignedPeerRecord bool
+	ObservedAddrs    bool
+	// CollectPeerRecords: whether the signed peer records of the remote peers are requested and stored
+	CollectPeerRecords bool
+	// NegotiationTiming: whether the multistream-select negotiation of the protocols of the remote peers is timed
+	NegotiationTiming bool
+	// BlocksByRangeProbe: number of blocks requested to the eth2 peers to check if they serve historical data (0 disabled)
+	BlocksByRangeProbe uint64
+
+	// Connectivity
+	NATPortMap bool
+	// NoListen: the host doesn't listen for inbound connections (i.e. the discovery-only mode)
+	NoListen bool
+	// NoDial: the host never dials, only accepting inbound connections (i.e. the listen-only mode)
+	NoDial bool
+	// ResourceManager limits the resources of the host (composed from the ResourceLimits if nil)
+	ResourceManager network.ResourceManager
+	ResourceLimits  ResourceLimits
+
+	// Blocklist of IP ranges and ASNs that the host won't connect to
+	Blocklist *Blocklist
+
+	// Max number of connection/identification events buffered before dropping new ones
+	EventQueueSize int
+
+	// Timeouts of the identification and req/resp interactions with each class of peer
+	Timeouts TimeoutPolicy
+
+	// Registerer of the libp2p metrics (the prometheus default one if nil)
+	MetricsRegisterer prometheus.Registerer
+
+	// collectors of the host metrics, shared by the hosts of a pool (new ones if nil)
+	metrics *hostMetrics
+}
+
+// DefaultNetworkOptions returns the default host options for the given network,
+// listening on every IPv4 interface on a random port
+func DefaultNetworkOptions(network utils.NetworkType) NetworkOptions {
+	switch network {
+	case utils.EthereumNetwork:
+		return DefaultEth2NetworkOptions("0.0.0.0", 0, nil, "")
+	default:
+		return DefaultIpfsNetworkOptions(network, "0.0.0.0", 0, nil, "")
+	}
+}
+
+// DefaultEth2NetworkOptions returns the host options used to join an Ethereum CL network
+func DefaultEth2NetworkOptions(ip string, port int, privKey crypto.PrivKey, userAgent string) NetworkOptions {
+	return NetworkOptions{
+		Network:    utils.EthereumNetwork,
+		PrivKey:    privKey,
+		UserAgent:  userAgent,
+		IP:         ip,
+		IP6:        "::",
+		Port:       port,
+		IPFamily:   IPv4Family,
+		Transports: []string{TcpTransport},
+		Security:   []string{NoiseSecurity},
+		Muxers:     []string{MplexMuxer, YamuxMuxer},
+
+		SignedPeerRecord: false,
+		ObservedAddrs:    true,
+		NATPortMap:       true,
+
+		EventQueueSize: DefaultEventQueueSize,
+		Timeouts:       DefaultTimeoutPolicy(),
+	}
+}
+
+// DefaultIpfsNetworkOptions returns the host options used to join an IPFS-like network (IPFS, Filecoin, Waku)
+func DefaultIpfsNetworkOptions(network utils.NetworkType, ip string, port int, privKey crypto.PrivKey, userAgent string) NetworkOptions {
+	return NetworkOptions{
+		Network:    network,
+		PrivKey:    privKey,
+		UserAgent:  userAgent,
+		IP:         ip,
+		IP6:        "::",
+		Port:       port,
+		IPFamily:   IPv4Family,
+		Transports: []string{TcpTransport},
+		Security:   []string{NoiseSecurity, TlsSecurity},
+		Muxers:     []string{YamuxMuxer, MplexMuxer},
+
+		SignedPeerRecord: false,
+		ObservedAddrs:    true,
+		NATPortMap:       true,
+
+		EventQueueSize: DefaultEventQueueSize,
+		Timeouts:       DefaultTimeoutPolicy(),
+	}
+}
+
+// HostOption modifies the NetworkOptions of the host that is being composed
+type HostOption func(*NetworkOptions) error
+
+// WithListenAddr sets the IPv4 and the port where the host will listen
+func WithListenAddr(ip string, port int) HostOption {
+	return func(o *NetworkOptions) error {
+		if net.ParseIP(ip) == nil || net.ParseIP(ip).To4() == nil {
+			return errors.Errorf("invalid ipv4 %s", ip)
+		}
+		if port < 0 || port > 65535 {
+			return errors.Errorf("invalid port %d", port)
+		}
+		o.IP = ip
+		o.Port = port
+		return nil
+	}
+}
+
+// WithListenAddr6 sets the IPv6 where the host will listen (when the IP family includes v6)
+func WithListenAddr6(ip6 string) HostOption {
+	return func(o *NetworkOptions) error {
+		if net.ParseIP(ip6) == nil || net.ParseIP(ip6).To4() != nil {
+			return errors.Errorf("invalid ipv6 %s", ip6)
+		}
+		o.IP6 = ip6
+		return nil
+	}
+}
+
+// WithIPFamily sets the IP family (v4, v6, both) that the host will listen on and prefer to dial
+func WithIPFamily(ipFamily string) HostOption {
+	return func(o *NetworkOptions) error {
+		switch ipFamily {
+		case IPv4Family, IPv6Family, DualStack:
+			o.IPFamily = ipFamily
+			return nil
+		default:
+			return errors.Errorf("unsupported ip family %s", ipFamily)
+		}
+	}
+}
+
+// WithIdentity sets the private key that defines the peer ID of the host
+func WithIdentity(privKey crypto.PrivKey) HostOption {
+	return func(o *NetworkOptions) error {
+		if privKey == nil {
+			return errors.New("nil private key")
+		}
+		o.PrivKey = privKey
+		return nil
+	}
+}
+
+// WithUserAgent sets the user agent that the host will share through identify
+func WithUserAgent(userAgent string) HostOption {
+	return func(o *NetworkOptions) error {
+		o.UserAgent = userAgent
+		return nil
+	}
+}
+
+// WithTransports sets the transports of the host (ordered by preference)
+func WithTransports(transports ...string) HostOption {
+	return func(o *NetworkOptions) error {
+		if len(transports) == 0 {
+			return errors.New("no transports given")
+		}
+		o.Transports = transports
+		return nil
+	}
+}
+
+// WithSecurity sets the security protocols of the host (ordered by preference)
+func WithSecurity(security ...string) HostOption {
+	return func(o *NetworkOptions) error {
+		if len(security) == 0 {
+			return errors.New("no security protocols given")
+		}
+		o.Security = security
+		return nil
+	}
+}
+
+// WithMuxers sets the stream multiplexers of the host (ordered by preference)
+func WithMuxers(muxers ...string) HostOption {
+	return func(o *NetworkOptions) error {
+		if len(muxers) == 0 {
+			return errors.New("no stream muxers given")
+		}
+		o.Muxers = muxers
+		return nil
+	}
+}
+
+// WithSignedPeerRecord decides whether the host shares its own signed peer record through identify
+func WithSignedPeerRecord(signedPeerRecord bool) HostOption {
+	return func(o *NetworkOptions) error {
+		o.SignedPeerRecord = signedPeerRecord
+		return nil
+	}
+}
+
+// WithObservedAddrs decides whether the host advertises the addresses that remote peers observed for it
+func WithObservedAddrs(observedAddrs bool) HostOption {
+	return func(o *NetworkOptions) error {
+		o.ObservedAddrs = observedAddrs
+		return nil
+	}
+}
+
+// WithPeerRecordCollection decides whether the host requests and stores the signed peer records of the remote peers
+func WithPeerRecordCollection(collect bool) HostOption {
+	return func(o *NetworkOptions) error {
+		o.CollectPeerRecords = collect
+		return nil
+	}
+}
+
+// WithNegotiationTiming decides whether the host times the negotiation of each of the protocols
+// that it shares with the remote peers
+func WithNegotiationTiming(timing bool) HostOption {
+	return func(o *NetworkOptions) error {
+		o.NegotiationTiming = timing
+		return nil
+	}
+}
+
+// WithBlocksByRangeProbe sets the number of blocks requested to each of the connected eth2 peers
+// to check whether they serve historical blocks (0 disables the probe)
+func WithBlocksByRangeProbe(count int) HostOption {
+	return func(o *NetworkOptions) error {
+		if count < 0 || count > methods.MAX_REQUEST_BLOCKS {
+			return errors.Errorf("invalid number of blocks to probe %d (0-%d)", count, methods.MAX_REQUEST_BLOCKS)
+		}
+		o.BlocksByRangeProbe = uint64(count)
+		return nil
+	}
+}
+
+// WithNATPortMap decides whether the host tries to open a port in the NAT's firewall (UPnP)
+func WithNATPortMap(natPortMap bool) HostOption {
+	return func(o *NetworkOptions) error {
+		o.NATPortMap = natPortMap
+		return nil
+	}
+}
+
+// WithNoListen decides whether the host doesn't listen for inbound connections
+func WithNoListen(noListen bool) HostOption {
+	return func(o *NetworkOptions) error {
+		o.NoListen = noListen
+		return nil
+	}
+}
+
+// WithNoDial decides whether the host refuses every dial, only accepting inbound connections
+func WithNoDial(noDial bool) HostOption {
+	return func(o *NetworkOptions) error {
+		o.NoDial = noDial
+		return nil
+	}
+}
+
+// WithResourceManager sets the resource manager that limits the resources of the host
+func WithResourceManager(rm network.ResourceManager) HostOption {
+	return func(o *NetworkOptions) error {
+		if rm == nil {
+			return errors.New("nil resource manager")
+		}
+		o.ResourceManager = rm
+		return nil
+	}
+}
+
+// WithResourceLimits sets the limits of the resource manager of the host (ignored if WithResourceManager is given)
+func WithResourceLimits(limits ResourceLimits) HostOption {
+	return func(o *NetworkOptions) error {
+		o.ResourceLimits = limits
+		return nil
+	}
+}
+
+// WithBlocklist sets the IP ranges and ASNs that the host won't connect to
+func WithBlocklist(blocklist *Blocklist) HostOption {
+	return func(o *NetworkOptions) error {
+		o.Blocklist = blocklist
+		return nil
+	}
+}
+
+// WithEventQueueSize sets the max number of connection/identification events buffered by the host
+func WithEventQueueSize(size int) HostOption {
+	return func(o *NetworkOptions) error {
+		if size <= 0 {
+			return errors.Errorf("invalid event queue size %d", size)
+		}
+		o.EventQueueSize = size
+		return nil
+	}
+}
+
+// WithTimeouts sets the timeouts of the identification and req/resp interactions with the remote peers
+func WithTimeouts(policy TimeoutPolicy) HostOption {
+	return func(o *NetworkOptions) error {
+		if err := policy.Default.check(); err != nil {
+			return err
+		}
+		o.Timeouts = policy
+		return nil
+	}
+}
+
+// withHostMetrics makes the host account its metrics in the given collectors
+func withHostMetrics(metrics *hostMetrics) HostOption {
+	return func(o *NetworkOptions) error {
+		o.metrics = metrics
+		return nil
+	}
+}
+
+// WithMetricsRegisterer sets the prometheus registerer where the libp2p metrics of the host are registered
+func WithMetricsRegisterer(reg prometheus.Registerer) HostOption {
+	return func(o *NetworkOptions) error {
+		o.MetricsRegisterer = reg
+		return nil
+	}
+}
+
+// ipPrefixes returns the multiaddress prefixes (/ip4/<ip>, /ip6/<ip>) of the IP families that the host listens on
+func (o NetworkOptions) ipPrefixes() ([]string, error) {
+	switch o.IPFamily {
+	case IPv4Family, "":
+		return []string{fmt.Sprintf("/ip4/%s", o.IP)}, nil
+	case IPv6Family:
+		return []string{fmt.Sprintf("/ip6/%s", o.IP6)}, nil
+	case DualStack:
+		return []string{fmt.Sprintf("/ip4/%s", o.IP), fmt.Sprintf("/ip6/%s", o.IP6)}, nil
+	default:
+		return nil, errors.Errorf("unsupported ip family %s", o.IPFamily)
+	}
+}
+
+// ListenMultiaddrs composes the multiaddresses where the host will listen for each of the transports and IP families
+func (o NetworkOptions) ListenMultiaddrs() ([]ma.Multiaddr, error) {
+	mAddrs := make([]ma.Multiaddr, 0, len(o.Transports))
+	if o.NoListen {
+		return mAddrs, nil
+	}
+	ipPrefixes, err := o.ipPrefixes()
+	if err != nil {
+		return mAddrs, err
+	}
+	for _, transport := range o.Transports {
+		for _, ipPrefix := range ipPrefixes {
+			var mAddrStr string
+			switch transport {
+			case TcpTransport:
+				mAddrStr = fmt.Sprintf("%s/tcp/%d", ipPrefix, o.Port)
+			default:
+				return mAddrs, errors.Errorf("unsupported transport %s", transport)
+			}
+			mAddr, err := ma.NewMultiaddr(mAddrStr)
+			if err != nil {
+				return mAddrs, errors.Wrap(err, fmt.Sprintf("couldn't generate multiaddress from %s and port %d", ipPrefix, o.Port))
+			}
+			mAddrs = append(mAddrs, mAddr)
+		}
+	}
+	return mAddrs, nil
+}
+
+// libp2pOptions translates the NetworkOptions into the libp2p.Options of the host
+func (o NetworkOptions) libp2pOptions() ([]libp2p.Option, error) {
+	if o.PrivKey == nil {
+		return nil, errors.New("no private key given for the host identity")
+	}
+	if len(o.Transports) == 0 || len(o.Security) == 0 || len(o.Muxers) == 0 {
+		return nil, errors.New("at least one transport, security protocol, and muxer are needed")
+	}
+
+	mAddrs, err := o.ListenMultiaddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	listen := libp2p.ListenAddrs(mAddrs...)
+	if o.NoListen {
+		listen = libp2p.NoListenAddrs
+	}
+	opts := []libp2p.Option{
+		listen,
+		libp2p.Identity(o.PrivKey),
+		libp2p.UserAgent(o.UserAgent),
+	}
+	if o.MetricsRegisterer != nil {
+		opts = append(opts, libp2p.PrometheusRegisterer(o.MetricsRegisterer))
+	}
+
+	for _, transport := range o.Transports {
+		switch transport {
+		case TcpTransport:
+			opts = append(opts, libp2p.Transport(tcp.NewTCPTransport))
+		default:
+			return nil, errors.Errorf("unsupported transport %s", transport)
+		}
+	}
+
+	for _, security := range o.Security {
+		switch security {
+		case NoiseSecurity:
+			opts = append(opts, libp2p.Security(noise.ID, noise.New))
+		case TlsSecurity:
+			opts = append(opts, libp2p.Security(tls.ID, tls.New))
+		default:
+			return nil, errors.Errorf("unsupported security protocol %s", security)
+		}
+	}
+
+	for _, muxer := range o.Muxers {
+		switch muxer {
+		case MplexMuxer:
+			opts = append(opts, libp2p.Muxer(mplex.ID, mplex.DefaultTransport))
+		case YamuxMuxer:
+			opts = append(opts, libp2p.Muxer(yamux.ID, yamux.DefaultTransport))
+		default:
+			return nil, errors.Errorf("unsupported stream muxer %s", muxer)
+		}
+	}
+
+	// the peerstore decides whether identify can share our own signed peer record
+	ps, err := newHostPeerstore(o.PrivKey, o.SignedPeerRecord)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, libp2p.Peerstore(ps))
+
+	// dial first the addresses of the preferred IP family (if any)
+	if o.IPFamily == IPv4Family || o.IPFamily == IPv6Family {
+		opts = append(opts, libp2p.SwarmOpts(swarm.WithDialRanker(familyDialRanker(o.IPFamily))))
+	}
+
+	if !o.ObservedAddrs {
+		addrsFactory, err := localAddrsFactory()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, libp2p.AddrsFactory(addrsFactory))
+	}
+
+	return opts, nil
+}
+
+// familyDialRanker returns a dial ranker that keeps the default ranking of libp2p for the preferred IP family,
+// delaying the dials to the addresses of the other family (they are only used as fallback)
+func familyDialRanker(preferredFamily string) network.DialRanker {
+	preferred := utils.IPv4AddrFamily
+	if preferredFamily == IPv6Family {
+		preferred = utils.IPv6AddrFamily
+	}
+	return func(addrs []ma.Multiaddr) []network.AddrDelay {
+		preferredAddrs := make([]ma.Multiaddr, 0, len(addrs))
+		fallbackAddrs := make([]ma.Multiaddr, 0)
+		for _, addr := range addrs {
+			if utils.GetAddrFamily(addr) == preferred {
+				preferredAddrs = append(preferredAddrs, addr)
+			} else {
+				fallbackAddrs = append(fallbackAddrs, addr)
+			}
+		}
+		ranking := swarm.DefaultDialRanker(preferredAddrs)
+		// only delay the fallback addresses if there is something to dial first
+		var delay time.Duration
+		if len(preferredAddrs) > 0 {
+			delay = NonPreferredFamilyDialDelay
+		}
+		for _, addrDelay := range swarm.DefaultDialRanker(fallbackAddrs) {
+			addrDelay.Delay += delay
+			ranking = append(ranking, addrDelay)
+		}
+		return ranking
+	}
+}
+
+// localAddrsFactory returns an AddrsFactory that only keeps the addresses bound to the local interfaces,
+// discarding the ones that remote peers observed for us (or the ones mapped by the NAT)
+func localAddrsFactory() (func([]ma.Multiaddr) []ma.Multiaddr, error) {
+	ifaceAddrs, err := manet.InterfaceMultiaddrs()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the addresses of the local interfaces")
+	}
+	localIPs := make(map[string]struct{}, len(ifaceAddrs))
+	for _, addr := range ifaceAddrs {
+		ip, err := manet.ToIP(addr)
+		if err != nil {
+			continue
+		}
+		localIPs[ip.String()] = struct{}{}
+	}
+
+	return func(addrs []ma.Multiaddr) []ma.Multiaddr {
+		filtered := make([]ma.Multiaddr, 0, len(addrs))
+		for _, addr := range addrs {
+			ip, err := manet.ToIP(addr)
+			if err != nil {
+				continue
+			}
+			if _, ok := localIPs[ip.String()]; ok {
+				filtered = append(filtered, addr)
+			}
+		}
+		return filtered
+	}, nil
+}
@@ -0,0 +1,72 @@
+package hosts
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/record"
+	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
+	pb "github.com/libp2p/go-libp2p/p2p/protocol/identify/pb"
+	"github.com/libp2p/go-msgio/pbio"
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	// max size of the identify message (same as the one of libp2p when signed peer records are included)
+	maxIdentifySize = 8 * 1024
+)
+
+// ReqSignedPeerRecord requests the identify message of the peer, returning the signed peer record that it contains
+// (nil if the peer doesn't share any). libp2p only keeps the addresses of the records, so we ask for the raw message
+func ReqSignedPeerRecord(ctx context.Context, wg *sync.WaitGroup, h host.Host, peerID peer.ID, rec **models.SignedPeerRecord, errRec *error) {
+	defer wg.Done()
+
+	s, err := h.NewStream(ctx, peerID, identify.ID)
+	if err != nil {
+		*errRec = errors.Wrap(err, "unable to open identify stream")
+		return
+	}
+	defer s.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetDeadline(deadline)
+	}
+
+	var msg pb.Identify
+	err = pbio.NewDelimitedReader(s, maxIdentifySize).ReadMsg(&msg)
+	if err != nil {
+		*errRec = errors.Wrap(err, "unable to read identify message")
+		return
+	}
+	if len(msg.SignedPeerRecord) == 0 {
+		return
+	}
+
+	// check the signature and that the record belongs to the peer
+	env, r, err := record.ConsumeEnvelope(msg.SignedPeerRecord, peer.PeerRecordEnvelopeDomain)
+	if err != nil {
+		*errRec = errors.Wrap(err, "invalid signed peer record envelope")
+		return
+	}
+	peerRec, ok := r.(*peer.PeerRecord)
+	if !ok {
+		*errRec = errors.New("signed envelope doesn't contain a peer record")
+		return
+	}
+	signer, err := peer.IDFromPublicKey(env.PublicKey)
+	if err != nil || signer != peerID || peerRec.PeerID != peerID {
+		*errRec = errors.Errorf("signed peer record of %s doesn't belong to the peer", peerRec.PeerID.String())
+		return
+	}
+	*rec = &models.SignedPeerRecord{
+		PeerID:    peerID,
+		Seq:       peerRec.Seq,
+		Addrs:     peerRec.Addrs,
+		Envelope:  msg.SignedPeerRecord,
+		Timestamp: time.Now(),
+	}
+}
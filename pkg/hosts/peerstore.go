@@ -0,0 +1,57 @@
+package hosts
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/record"
+	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	"github.com/pkg/errors"
+)
+
+// hostPeerstore wraps the in-memory peerstore of the host to control whether
+// identify is able to share our own signed peer record with remote peers.
+// The records of remote peers are still accepted and stored as usual.
+type hostPeerstore struct {
+	peerstore.Peerstore
+	cab peerstore.CertifiedAddrBook
+
+	self             peer.ID
+	signedPeerRecord bool
+}
+
+func newHostPeerstore(privKey crypto.PrivKey, signedPeerRecord bool) (*hostPeerstore, error) {
+	self, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get peer ID from private key")
+	}
+	ps, err := pstoremem.NewPeerstore()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create host peerstore")
+	}
+	cab, ok := peerstore.GetCertifiedAddrBook(ps)
+	if !ok {
+		return nil, errors.New("host peerstore is not a certified address book")
+	}
+	return &hostPeerstore{
+		Peerstore:        ps,
+		cab:              cab,
+		self:             self,
+		signedPeerRecord: signedPeerRecord,
+	}, nil
+}
+
+func (ps *hostPeerstore) ConsumePeerRecord(s *record.Envelope, ttl time.Duration) (bool, error) {
+	return ps.cab.ConsumePeerRecord(s, ttl)
+}
+
+// GetPeerRecord hides our own record when the host isn't supposed to share it,
+// which is the one that identify attaches to its responses
+func (ps *hostPeerstore) GetPeerRecord(p peer.ID) *record.Envelope {
+	if p == ps.self && !ps.signedPeerRecord {
+		return nil
+	}
+	return ps.cab.GetPeerRecord(p)
+}
@@ -0,0 +1,229 @@
+package hosts
+
+import (
+	"context"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/apis"
+)
+
+// HostPool spreads the dials of the crawler across several libp2p hosts (each of them with its own port and identity),
+// lifting the dial concurrency limits of a single host. The events of all the hosts are merged into a single
+// notification pipeline, and the first host (primary) keeps the configured identity
+type HostPool struct {
+	ctx context.Context
+
+	hosts []*BasicLibp2pHost
+
+	connEventNotChannel chan *models.EventTrace
+	identNotChannel     chan IdentificationEvent
+
+	// extra consumers of the events (i.e. the event streams), called before the events reach the channels
+	subsM          sync.RWMutex
+	connEventSubs  []func(*models.EventTrace)
+	identEventSubs []func(IdentificationEvent)
+}
+
+// NewHostPool composes size hosts with the given HostOptions. The extra hosts listen on the consecutive
+// ports of the primary one (or on random ones if the primary port is random), with newly generated identities
+func NewHostPool(
+	ctx context.Context,
+	netNode P2pNetwork,
+	ipLocator *apis.IpLocator,
+	size int,
+	opts ...HostOption) (*HostPool, error) {
+
+	if size < 1 {
+		return nil, errors.Errorf("invalid host pool size %d", size)
+	}
+	primary, err := NewHost(ctx, netNode, ipLocator, opts...)
+	if err != nil {
+		return nil, err
+	}
+	pool := &HostPool{
+		ctx:                 ctx,
+		hosts:               []*BasicLibp2pHost{primary},
+		connEventNotChannel: make(chan *models.EventTrace, ConnNotChannSize),
+		identNotChannel:     make(chan IdentificationEvent, ConnNotChannSize),
+	}
+
+	baseOpts := primary.Options()
+	for i := 1; i < size; i++ {
+		ecdsaKey, err := utils.GenerateECDSAPrivKey()
+		if err != nil {
+			return nil, err
+		}
+		privKey, err := utils.AdaptSecp256k1FromECDSA(ecdsaKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to adapt pool host key")
+		}
+		port := 0
+		if baseOpts.Port != 0 {
+			port = baseOpts.Port + i
+		}
+		hostOpts := append(append([]HostOption{}, opts...),
+			WithListenAddr(baseOpts.IP, port),
+			WithIdentity(privKey),
+			withHostMetrics(primary.metrics),
+		)
+		h, err := NewHost(ctx, netNode, ipLocator, hostOpts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to compose pool host %d", i)
+		}
+		pool.hosts = append(pool.hosts, h)
+	}
+
+	// merge the events of every host into the pool channels
+	for _, h := range pool.hosts {
+		go pool.forwardEvents(h)
+	}
+	log.WithFields(log.Fields{
+		"hosts":   len(pool.hosts),
+		"primary": primary.Host().ID().String(),
+	}).Info("host pool composed")
+	return pool, nil
+}
+
+func (p *HostPool) forwardEvents(h *BasicLibp2pHost) {
+	connC := h.ConnEventNotChannel()
+	identC := h.IdentEventNotChannel()
+	for {
+		select {
+		case event := <-connC:
+			p.subsM.RLock()
+			for _, fn := range p.connEventSubs {
+				fn(event)
+			}
+			p.subsM.RUnlock()
+			p.connEventNotChannel <- event
+		case ident := <-identC:
+			p.subsM.RLock()
+			for _, fn := range p.identEventSubs {
+				fn(ident)
+			}
+			p.subsM.RUnlock()
+			p.identNotChannel <- ident
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// Primary returns the host with the configured identity (the one that discovery, gossipsub, etc. rely on)
+func (p *HostPool) Primary() *BasicLibp2pHost {
+	return p.hosts[0]
+}
+
+// Hosts returns all the hosts of the pool
+func (p *HostPool) Hosts() []*BasicLibp2pHost {
+	return p.hosts
+}
+
+// Start makes every host of the pool listen on its addresses
+func (p *HostPool) Start() error {
+	for _, h := range p.hosts {
+		err := h.Start()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect dials the peer from the host of the pool with the fewest open connections
+func (p *HostPool) Connect(ctx context.Context, addrInfo peer.AddrInfo) error {
+	selected := p.hosts[0]
+	minConns := selected.OpenConnections()
+	for _, h := range p.hosts[1:] {
+		if conns := h.OpenConnections(); conns < minConns {
+			selected = h
+			minConns = conns
+		}
+	}
+	return selected.Connect(ctx, addrInfo)
+}
+
+// IsConnected returns whether any of the hosts of the pool is connected to the peer
+func (p *HostPool) IsConnected(peerID peer.ID) bool {
+	for _, h := range p.hosts {
+		if h.IsConnected(peerID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *HostPool) ConnEventNotChannel() chan *models.EventTrace {
+	return p.connEventNotChannel
+}
+
+func (p *HostPool) IdentEventNotChannel() chan IdentificationEvent {
+	return p.identNotChannel
+}
+
+// OnConnEvent registers a callback that receives a copy of every connection and disconnection event.
+// The callback must not block, as it delays the notification pipeline of the hosts
+func (p *HostPool) OnConnEvent(fn func(*models.EventTrace)) {
+	p.subsM.Lock()
+	defer p.subsM.Unlock()
+	p.connEventSubs = append(p.connEventSubs, fn)
+}
+
+// OnIdentEvent registers a callback that receives a copy of every identification event.
+// The callback must not block, as it delays the notification pipeline of the hosts
+func (p *HostPool) OnIdentEvent(fn func(IdentificationEvent)) {
+	p.subsM.Lock()
+	defer p.subsM.Unlock()
+	p.identEventSubs = append(p.identEventSubs, fn)
+}
+
+// BandwidthTotals returns the total number of bytes received and sent by all the hosts
+func (p *HostPool) BandwidthTotals() (in int64, out int64) {
+	for _, h := range p.hosts {
+		hIn, hOut := h.BandwidthTotals()
+		in += hIn
+		out += hOut
+	}
+	return in, out
+}
+
+// OpenConnections returns the number of connections open across all the hosts
+func (p *HostPool) OpenConnections() int {
+	conns := 0
+	for _, h := range p.hosts {
+		conns += h.OpenConnections()
+	}
+	return conns
+}
+
+// ReqRespStats merges the req/resp stats of all the hosts
+func (p *HostPool) ReqRespStats() map[peer.ID]ReqRespStats {
+	stats := make(map[peer.ID]ReqRespStats)
+	for _, h := range p.hosts {
+		for peerID, s := range h.ReqRespStats() {
+			merged := stats[peerID]
+			merged.Requests += s.Requests
+			merged.Responses += s.Responses
+			stats[peerID] = merged
+		}
+	}
+	return stats
+}
+
+// Close closes every host of the pool
+func (p *HostPool) Close() error {
+	var closeErr error
+	for _, h := range p.hosts {
+		err := h.Host().Close()
+		if err != nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
@@ -0,0 +1,92 @@
+package hosts
+
+import (
+	"os"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+	"github.com/pkg/errors"
+)
+
+// ResourceLimits defines the limits of the libp2p resource manager of the host (zero means unlimited)
+type ResourceLimits struct {
+	MaxConns              int // connections of the whole host
+	MaxConnsPerPeer       int
+	MaxStreamsPerPeer     int
+	MaxStreamsPerProtocol int
+	// JSON file with limits in the go-libp2p resource manager format, applied over the ones above
+	LimitsFile string
+}
+
+// IsUnlimited returns whether the limits leave the resources of the host unbounded
+func (l ResourceLimits) IsUnlimited() bool {
+	return l.MaxConns <= 0 &&
+		l.MaxConnsPerPeer <= 0 &&
+		l.MaxStreamsPerPeer <= 0 &&
+		l.MaxStreamsPerProtocol <= 0 &&
+		l.LimitsFile == ""
+}
+
+// limitConfig composes the concrete limits of the resource manager, taking the infinite limits as base
+func (l ResourceLimits) limitConfig() rcmgr.ConcreteLimitConfig {
+	partial := rcmgr.PartialLimitConfig{}
+	if l.MaxConns > 0 {
+		partial.System.Conns = rcmgr.LimitVal(l.MaxConns)
+	}
+	if l.MaxConnsPerPeer > 0 {
+		partial.PeerDefault.Conns = rcmgr.LimitVal(l.MaxConnsPerPeer)
+	}
+	if l.MaxStreamsPerPeer > 0 {
+		partial.PeerDefault.Streams = rcmgr.LimitVal(l.MaxStreamsPerPeer)
+	}
+	if l.MaxStreamsPerProtocol > 0 {
+		partial.ProtocolDefault.Streams = rcmgr.LimitVal(l.MaxStreamsPerProtocol)
+	}
+	return partial.Build(rcmgr.InfiniteLimits)
+}
+
+// NewResourceManager composes the libp2p resource manager that will limit the resources of the host
+func NewResourceManager(limits ResourceLimits) (network.ResourceManager, error) {
+	var limiter rcmgr.Limiter
+	if limits.LimitsFile != "" {
+		f, err := os.Open(limits.LimitsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open resource manager limits file")
+		}
+		defer f.Close()
+		limiter, err = rcmgr.NewLimiterFromJSON(f, limits.limitConfig())
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse resource manager limits file")
+		}
+	} else {
+		limiter = rcmgr.NewFixedLimiter(limits.limitConfig())
+	}
+	rm, err := rcmgr.NewResourceManager(limiter)
+	if err != nil {
+		return nil, errors.Wrap(err, "new resource manager")
+	}
+	return rm, nil
+}
+
+// ResourceUsage returns the resources that are currently in use at the system and transient scopes
+func (b *BasicLibp2pHost) ResourceUsage() (system, transient network.ScopeStat) {
+	b.rm.ViewSystem(func(s network.ResourceScope) error {
+		system = s.Stat()
+		return nil
+	})
+	b.rm.ViewTransient(func(s network.ResourceScope) error {
+		transient = s.Stat()
+		return nil
+	})
+	return system, transient
+}
+
+// ProtocolResourceUsage returns the resources that are currently in use by the given protocol
+func (b *BasicLibp2pHost) ProtocolResourceUsage(proto protocol.ID) (stat network.ScopeStat) {
+	b.rm.ViewProtocol(proto, func(s network.ProtocolScope) error {
+		stat = s.Stat()
+		return nil
+	})
+	return stat
+}
@@ -0,0 +1,57 @@
+package hosts
+
+import (
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ReqRespStats accounts the req/resp requests sent to a remote peer, and how many of them it answered
+type ReqRespStats struct {
+	Requests  int64
+	Responses int64
+}
+
+// Reliability returns the share of requests that the peer answered
+func (s ReqRespStats) Reliability() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Responses) / float64(s.Requests)
+}
+
+type reqRespTracker struct {
+	m     sync.Mutex
+	stats map[peer.ID]*ReqRespStats
+}
+
+func newReqRespTracker() *reqRespTracker {
+	return &reqRespTracker{
+		stats: make(map[peer.ID]*ReqRespStats),
+	}
+}
+
+func (t *reqRespTracker) record(p peer.ID, err error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	s, ok := t.stats[p]
+	if !ok {
+		s = &ReqRespStats{}
+		t.stats[p] = s
+	}
+	s.Requests++
+	if err == nil {
+		s.Responses++
+	}
+}
+
+// ReqRespStats returns a copy of the req/resp stats of each of the peers that we sent requests to
+func (b *BasicLibp2pHost) ReqRespStats() map[peer.ID]ReqRespStats {
+	b.reqResp.m.Lock()
+	defer b.reqResp.m.Unlock()
+	stats := make(map[peer.ID]ReqRespStats, len(b.reqResp.stats))
+	for p, s := range b.reqResp.stats {
+		stats[p] = *s
+	}
+	return stats
+}
@@ -0,0 +1,173 @@
+package hosts
+
+import (
+	"strings"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+)
+
+// classes of peers that can get their own timeouts, from the addresses that we dial them with
+const (
+	PeerClassDefault = "default"
+	// reachable through a circuit relay
+	PeerClassRelay = "relay"
+	// reachable through an onion address
+	PeerClassTor = "tor"
+)
+
+// timeouts that the host enforces on the remote peers
+const (
+	DialTimeout     = "dial"
+	IdentifyTimeout = "identify"
+	ReqRespTimeout  = "reqresp"
+)
+
+// error codes recorded when one of our timeouts fired (rather than the remote peer timing out)
+const (
+	DialErrorDialTimeout = "dial_timeout"
+	IdentifyErrorTimeout = "identify_timeout"
+	ReqRespErrorTimeout  = "reqresp_timeout"
+)
+
+var (
+	DefaultDialTimeout     = 20 * time.Second
+	DefaultIdentifyTimeout = 5 * time.Second
+	DefaultReqRespTimeout  = 5 * time.Second
+
+	PeerClasses = []string{PeerClassDefault, PeerClassRelay, PeerClassTor}
+
+	timeoutErrors = map[string]string{
+		DialTimeout:     DialErrorDialTimeout,
+		IdentifyTimeout: IdentifyErrorTimeout,
+		ReqRespTimeout:  ReqRespErrorTimeout,
+	}
+)
+
+// Timeouts are the time limits of the interactions with a remote peer
+type Timeouts struct {
+	Dial     time.Duration
+	Identify time.Duration
+	ReqResp  time.Duration
+}
+
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Dial:     DefaultDialTimeout,
+		Identify: DefaultIdentifyTimeout,
+		ReqResp:  DefaultReqRespTimeout,
+	}
+}
+
+// TimeoutPolicy gives the timeouts of each peer, overriding the default ones for some classes of peers
+type TimeoutPolicy struct {
+	Default Timeouts
+	Classes map[string]Timeouts
+}
+
+func DefaultTimeoutPolicy() TimeoutPolicy {
+	return TimeoutPolicy{
+		Default: DefaultTimeouts(),
+		Classes: make(map[string]Timeouts),
+	}
+}
+
+// ParseTimeoutPolicy composes the policy with the given default timeouts and the overrides per class
+// of peer, in the "relay.dial=60s,tor.identify=30s" format (the timeouts not overridden are the default ones)
+func ParseTimeoutPolicy(base Timeouts, overrides string) (TimeoutPolicy, error) {
+	policy := TimeoutPolicy{
+		Default: base,
+		Classes: make(map[string]Timeouts),
+	}
+	if err := base.check(); err != nil {
+		return policy, err
+	}
+	if strings.TrimSpace(overrides) == "" {
+		return policy, nil
+	}
+	for _, item := range strings.Split(overrides, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			return policy, errors.Errorf("invalid timeout override %s", item)
+		}
+		key := strings.SplitN(strings.TrimSpace(kv[0]), ".", 2)
+		if len(key) != 2 || !isPeerClass(key[0]) || key[0] == PeerClassDefault {
+			return policy, errors.Errorf("invalid timeout override %s (%s.<timeout>)", item,
+				strings.Join(PeerClasses[1:], "|"))
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+		if err != nil || timeout <= 0 {
+			return policy, errors.Errorf("invalid timeout override %s", item)
+		}
+		timeouts, ok := policy.Classes[key[0]]
+		if !ok {
+			timeouts = base
+		}
+		switch key[1] {
+		case DialTimeout:
+			timeouts.Dial = timeout
+		case IdentifyTimeout:
+			timeouts.Identify = timeout
+		case ReqRespTimeout:
+			timeouts.ReqResp = timeout
+		default:
+			return policy, errors.Errorf("unknown timeout %s (%s, %s, %s)", key[1], DialTimeout, IdentifyTimeout, ReqRespTimeout)
+		}
+		policy.Classes[key[0]] = timeouts
+	}
+	return policy, nil
+}
+
+// ForPeer returns the class of the peer with the given addresses, and its timeouts
+func (p TimeoutPolicy) ForPeer(addrs []ma.Multiaddr) (string, Timeouts) {
+	class := PeerClass(addrs)
+	if timeouts, ok := p.Classes[class]; ok {
+		return class, timeouts
+	}
+	return class, p.Default
+}
+
+func (t Timeouts) check() error {
+	if t.Dial <= 0 || t.Identify <= 0 || t.ReqResp <= 0 {
+		return errors.Errorf("invalid timeouts (dial %s, identify %s, reqresp %s)", t.Dial, t.Identify, t.ReqResp)
+	}
+	return nil
+}
+
+// PeerClass returns the class of the peer from the addresses that we reach it with: relay if all
+// of them go through a circuit relay, tor if any of them is an onion address, default otherwise
+func PeerClass(addrs []ma.Multiaddr) string {
+	relayed := 0
+	for _, addr := range addrs {
+		if hasProtocol(addr, ma.P_ONION) || hasProtocol(addr, ma.P_ONION3) {
+			return PeerClassTor
+		}
+		if hasProtocol(addr, ma.P_CIRCUIT) {
+			relayed++
+		}
+	}
+	if len(addrs) > 0 && relayed == len(addrs) {
+		return PeerClassRelay
+	}
+	return PeerClassDefault
+}
+
+func hasProtocol(addr ma.Multiaddr, code int) bool {
+	_, err := addr.ValueForProtocol(code)
+	return err == nil
+}
+
+func isPeerClass(class string) bool {
+	for _, c := range PeerClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeoutError labels the error of an interaction with the timeout of ours that fired
+func TimeoutError(timeout string, err error) error {
+	return errors.Wrap(err, timeoutErrors[timeout])
+}
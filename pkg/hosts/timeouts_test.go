@@ -0,0 +1,96 @@
+package hosts
+
+import (
+	"testing"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseTimeoutPolicy(t *testing.T) {
+	base := Timeouts{Dial: 10 * time.Second, Identify: 4 * time.Second, ReqResp: 3 * time.Second}
+
+	tests := []struct {
+		name      string
+		base      Timeouts
+		overrides string
+		classes   map[string]Timeouts
+		valid     bool
+	}{
+		{"no overrides", base, "", map[string]Timeouts{}, true},
+		{"blank overrides", base, "  ", map[string]Timeouts{}, true},
+		{
+			// the timeouts that aren't overridden keep the default ones
+			"override of a class", base, "relay.dial=60s",
+			map[string]Timeouts{PeerClassRelay: {Dial: time.Minute, Identify: 4 * time.Second, ReqResp: 3 * time.Second}},
+			true,
+		},
+		{
+			"several overrides", base, "relay.dial=60s, tor.identify=30s,relay.reqresp=15s",
+			map[string]Timeouts{
+				PeerClassRelay: {Dial: time.Minute, Identify: 4 * time.Second, ReqResp: 15 * time.Second},
+				PeerClassTor:   {Dial: 10 * time.Second, Identify: 30 * time.Second, ReqResp: 3 * time.Second},
+			},
+			true,
+		},
+		{"zero default", Timeouts{Dial: 0, Identify: time.Second, ReqResp: time.Second}, "", nil, false},
+		{"negative default", Timeouts{Dial: time.Second, Identify: -time.Second, ReqResp: time.Second}, "", nil, false},
+		{"zero override", base, "relay.dial=0s", nil, false},
+		{"negative override", base, "tor.dial=-5s", nil, false},
+		{"invalid duration", base, "relay.dial=soon", nil, false},
+		{"missing value", base, "relay.dial", nil, false},
+		{"unknown class", base, "quic.dial=10s", nil, false},
+		{"default class", base, "default.dial=10s", nil, false},
+		{"unknown timeout", base, "relay.handshake=10s", nil, false},
+		{"missing timeout", base, "relay=10s", nil, false},
+	}
+
+	for _, test := range tests {
+		policy, err := ParseTimeoutPolicy(test.base, test.overrides)
+		if !test.valid {
+			require.Error(t, err, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.base, policy.Default, test.name)
+		require.Equal(t, test.classes, policy.Classes, test.name)
+	}
+}
+
+func Test_TimeoutPolicyForPeer(t *testing.T) {
+	policy, err := ParseTimeoutPolicy(DefaultTimeouts(), "relay.dial=60s")
+	require.NoError(t, err)
+
+	direct := ma.StringCast("/ip4/1.2.3.4/tcp/9000")
+	relayed := ma.StringCast("/ip4/1.2.3.4/tcp/9000/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit")
+	onion := ma.StringCast("/onion3/vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd:1234")
+
+	tests := []struct {
+		name     string
+		addrs    []ma.Multiaddr
+		class    string
+		timeouts Timeouts
+	}{
+		{"no addresses", nil, PeerClassDefault, DefaultTimeouts()},
+		{"direct", []ma.Multiaddr{direct}, PeerClassDefault, DefaultTimeouts()},
+		// the override of the class applies
+		{"relayed", []ma.Multiaddr{relayed}, PeerClassRelay, policy.Classes[PeerClassRelay]},
+		// a peer with a direct address isn't considered relayed
+		{"relayed and direct", []ma.Multiaddr{relayed, direct}, PeerClassDefault, DefaultTimeouts()},
+		// a class without overrides falls back to the default timeouts
+		{"tor", []ma.Multiaddr{direct, onion}, PeerClassTor, DefaultTimeouts()},
+	}
+
+	for _, test := range tests {
+		class, timeouts := policy.ForPeer(test.addrs)
+		require.Equal(t, test.class, class, test.name)
+		require.Equal(t, test.timeouts, timeouts, test.name)
+	}
+}
+
+func Test_TimeoutError(t *testing.T) {
+	err := TimeoutError(IdentifyTimeout, errors.New("context deadline exceeded"))
+	require.EqualError(t, err, IdentifyErrorTimeout+": context deadline exceeded")
+}
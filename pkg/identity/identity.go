@@ -0,0 +1,201 @@
+package identity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+type changeRecorder interface {
+	InsertIdentityChange(change *models.IdentityChange) error
+}
+
+// KeyManager keeps the peer identity of the crawler across restarts, rotating it once it gets older than
+// the rotation period. Since the identity of a running host can't be changed, the rotated key gets persisted
+// and notified through the Rotated channel, so that the crawler can be restarted with it
+type KeyManager struct {
+	ctx context.Context
+
+	network  string
+	store    KeyStore
+	recorder changeRecorder
+	rotation time.Duration
+
+	m       sync.RWMutex
+	key     *ecdsa.PrivateKey
+	peerID  peer.ID
+	created time.Time
+
+	rotatedC chan struct{}
+}
+
+// NewKeyManager loads the persisted identity, generating a new one if there is none or if it already expired
+func NewKeyManager(
+	ctx context.Context,
+	network string,
+	store KeyStore,
+	recorder changeRecorder,
+	opts ...KeyManagerOption) (*KeyManager, error) {
+
+	k := &KeyManager{
+		ctx:      ctx,
+		network:  network,
+		store:    store,
+		recorder: recorder,
+		rotatedC: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		err := opt(k)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply key manager option")
+		}
+	}
+
+	hexKey, created, err := store.LoadIdentityKey()
+	if err != nil {
+		return nil, err
+	}
+	if hexKey == "" {
+		err = k.renew(models.NewIdentity, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return k, nil
+	}
+	key, err := utils.ParseECDSAPrivateKey(hexKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse persisted identity key")
+	}
+	err = k.setKey(key, created)
+	if err != nil {
+		return nil, err
+	}
+	if k.expired(time.Now()) {
+		err = k.renew(models.RotatedIdentity, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.WithFields(log.Fields{
+		"peer-id": k.peerID.String(),
+		"created": k.created,
+	}).Info("loaded persisted host identity")
+	return k, nil
+}
+
+// Start spawns the routine that rotates the identity once it expires
+func (k *KeyManager) Start() {
+	if k.rotation == 0 {
+		return
+	}
+	go func() {
+		timer := time.NewTimer(time.Until(k.ExpirationTime()))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			err := k.renew(models.RotatedIdentity, time.Now())
+			if err != nil {
+				log.WithError(err).Error("unable to rotate host identity")
+				return
+			}
+			close(k.rotatedC)
+		case <-k.ctx.Done():
+		}
+	}()
+}
+
+// PrivKey returns the current private key of the crawler
+func (k *KeyManager) PrivKey() *ecdsa.PrivateKey {
+	k.m.RLock()
+	defer k.m.RUnlock()
+	return k.key
+}
+
+// PeerID returns the peer id of the current identity
+func (k *KeyManager) PeerID() peer.ID {
+	k.m.RLock()
+	defer k.m.RUnlock()
+	return k.peerID
+}
+
+// ExpirationTime returns when the current identity will be rotated (zero time if it never will)
+func (k *KeyManager) ExpirationTime() time.Time {
+	k.m.RLock()
+	defer k.m.RUnlock()
+	if k.rotation == 0 {
+		return time.Time{}
+	}
+	return k.created.Add(k.rotation)
+}
+
+// Rotated returns the channel that gets closed once a new identity was persisted (restart required)
+func (k *KeyManager) Rotated() <-chan struct{} {
+	return k.rotatedC
+}
+
+func (k *KeyManager) expired(now time.Time) bool {
+	exp := k.ExpirationTime()
+	return !exp.IsZero() && !now.Before(exp)
+}
+
+// renew generates, persists, and records a new identity
+func (k *KeyManager) renew(reason string, now time.Time) error {
+	key, err := utils.GenerateECDSAPrivKey()
+	if err != nil {
+		return err
+	}
+	prevPeerID := k.PeerID()
+	err = k.setKey(key, now)
+	if err != nil {
+		return err
+	}
+	libp2pKey, err := utils.AdaptSecp256k1FromECDSA(key)
+	if err != nil {
+		return errors.Wrap(err, "unable to adapt identity key")
+	}
+	newPeerID := k.PeerID()
+	err = k.store.StoreIdentityKey(newPeerID.String(), utils.Secp256k1ToString(libp2pKey), now)
+	if err != nil {
+		return err
+	}
+	change := &models.IdentityChange{
+		Network:   k.network,
+		PeerID:    newPeerID.String(),
+		Reason:    reason,
+		Timestamp: now,
+	}
+	if prevPeerID != "" {
+		change.PrevPeerID = prevPeerID.String()
+	}
+	log.WithFields(log.Fields{
+		"prev-peer-id": change.PrevPeerID,
+		"peer-id":      change.PeerID,
+		"reason":       reason,
+	}).Info("new host identity")
+	return k.recorder.InsertIdentityChange(change)
+}
+
+func (k *KeyManager) setKey(key *ecdsa.PrivateKey, created time.Time) error {
+	libp2pKey, err := utils.AdaptSecp256k1FromECDSA(key)
+	if err != nil {
+		return errors.Wrap(err, "unable to adapt identity key")
+	}
+	peerID, err := peer.IDFromPrivateKey(libp2pKey)
+	if err != nil {
+		return errors.Wrap(err, "unable to get peer id from identity key")
+	}
+	k.m.Lock()
+	defer k.m.Unlock()
+	k.key = key
+	k.peerID = peerID
+	k.created = created
+	return nil
+}
@@ -0,0 +1,66 @@
+package identity
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+type mockRecorder struct {
+	changes []*models.IdentityChange
+}
+
+func (r *mockRecorder) InsertIdentityChange(change *models.IdentityChange) error {
+	r.changes = append(r.changes, change)
+	return nil
+}
+
+func Test_KeyManagerPersistence(t *testing.T) {
+	store, err := NewFileKeyStore(filepath.Join(t.TempDir(), "keys", "identity.json"))
+	require.NoError(t, err)
+	recorder := &mockRecorder{}
+
+	// first run generates a new identity
+	k1, err := NewKeyManager(context.Background(), "ethereum", store, recorder)
+	require.NoError(t, err)
+	require.Len(t, recorder.changes, 1)
+	require.Equal(t, models.NewIdentity, recorder.changes[0].Reason)
+	require.Equal(t, "", recorder.changes[0].PrevPeerID)
+
+	// a restart keeps the same identity
+	k2, err := NewKeyManager(context.Background(), "ethereum", store, recorder)
+	require.NoError(t, err)
+	require.Equal(t, k1.PeerID(), k2.PeerID())
+	require.Len(t, recorder.changes, 1)
+	require.True(t, k2.ExpirationTime().IsZero())
+}
+
+func Test_KeyManagerRotation(t *testing.T) {
+	store, err := NewFileKeyStore(filepath.Join(t.TempDir(), "identity.json"))
+	require.NoError(t, err)
+	recorder := &mockRecorder{}
+
+	k1, err := NewKeyManager(context.Background(), "ethereum", store, recorder, WithRotation(24*time.Hour))
+	require.NoError(t, err)
+
+	// age the persisted identity beyond the rotation period
+	key, created, err := store.LoadIdentityKey()
+	require.NoError(t, err)
+	require.NoError(t, store.StoreIdentityKey(k1.PeerID().String(), key, created.Add(-25*time.Hour)))
+
+	k2, err := NewKeyManager(context.Background(), "ethereum", store, recorder, WithRotation(24*time.Hour))
+	require.NoError(t, err)
+	require.NotEqual(t, k1.PeerID(), k2.PeerID())
+	require.Len(t, recorder.changes, 2)
+	require.Equal(t, models.RotatedIdentity, recorder.changes[1].Reason)
+	require.Equal(t, k1.PeerID().String(), recorder.changes[1].PrevPeerID)
+	require.Equal(t, k2.PeerID().String(), recorder.changes[1].PeerID)
+
+	_, err = NewKeyManager(context.Background(), "ethereum", store, recorder, WithRotation(-time.Hour))
+	require.Error(t, err)
+}
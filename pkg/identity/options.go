@@ -0,0 +1,20 @@
+package identity
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type KeyManagerOption func(*KeyManager) error
+
+// WithRotation sets the age after which the identity of the crawler gets rotated (zero disables it)
+func WithRotation(rotation time.Duration) KeyManagerOption {
+	return func(k *KeyManager) error {
+		if rotation < 0 {
+			return errors.Errorf("invalid key rotation %s", rotation)
+		}
+		k.rotation = rotation
+		return nil
+	}
+}
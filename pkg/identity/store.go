@@ -0,0 +1,78 @@
+package identity
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PostgresKeyStore is the key store value that persists the identity of the host in the DB
+const PostgresKeyStore = "postgres"
+
+// KeyStore persists the private key of the crawler between restarts
+type KeyStore interface {
+	// LoadIdentityKey returns the persisted hex private key (empty if there is none) and its creation time
+	LoadIdentityKey() (key string, created time.Time, err error)
+	StoreIdentityKey(peerID string, key string, created time.Time) error
+}
+
+type storedKey struct {
+	PeerID  string    `json:"peer-id"`
+	PrivKey string    `json:"priv-key"`
+	Created time.Time `json:"created"`
+}
+
+// FileKeyStore persists the private key of the crawler into a json file on disk
+type FileKeyStore struct {
+	path string
+}
+
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	if path == "" {
+		return nil, errors.New("empty key store path")
+	}
+	return &FileKeyStore{
+		path: path,
+	}, nil
+}
+
+func (s *FileKeyStore) LoadIdentityKey() (string, time.Time, error) {
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "unable to read key store file")
+	}
+	var stored storedKey
+	err = json.Unmarshal(content, &stored)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "unable to parse key store file")
+	}
+	return stored.PrivKey, stored.Created, nil
+}
+
+func (s *FileKeyStore) StoreIdentityKey(peerID string, key string, created time.Time) error {
+	content, err := json.MarshalIndent(storedKey{
+		PeerID:  peerID,
+		PrivKey: key,
+		Created: created,
+	}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal identity key")
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "unable to create key store folder")
+	}
+	// write it first into a tmp file, so that a crash never leaves a corrupted key behind
+	tmpPath := s.path + ".tmp"
+	err = os.WriteFile(tmpPath, content, 0600)
+	if err != nil {
+		return errors.Wrap(err, "unable to write key store file")
+	}
+	return errors.Wrap(os.Rename(tmpPath, s.path), "unable to replace key store file")
+}
@@ -0,0 +1,72 @@
+package inspect
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// AddrInfo is the content of a multiaddr, as the crawlers read it
+type AddrInfo struct {
+	Multiaddr  string      `json:"multiaddr"`
+	Components []Component `json:"components"`
+	PeerID     string      `json:"peer_id,omitempty"`
+	// ip4, ip6 or unknown (i.e. dns addresses)
+	Family string `json:"family"`
+	IP     string `json:"ip,omitempty"`
+	Public bool   `json:"public"`
+	Host   string `json:"host,omitempty"`
+	// transport of the port (tcp, udp)
+	Transport string `json:"transport,omitempty"`
+	Port      int    `json:"port,omitempty"`
+}
+
+// Component is each protocol/value pair of the multiaddr
+type Component struct {
+	Protocol string `json:"protocol"`
+	Value    string `json:"value,omitempty"`
+}
+
+// ParseAddr decodes the given multiaddr
+func ParseAddr(raw string) (*AddrInfo, error) {
+	maddr, err := ma.NewMultiaddr(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse the multiaddr")
+	}
+	info := &AddrInfo{
+		Multiaddr:  maddr.String(),
+		Components: make([]Component, 0),
+		Family:     utils.GetAddrFamily(maddr),
+	}
+	ma.ForEach(maddr, func(c ma.Component) bool {
+		info.Components = append(info.Components, Component{
+			Protocol: c.Protocol().Name,
+			Value:    c.Value(),
+		})
+		switch c.Protocol().Code {
+		case ma.P_DNS, ma.P_DNS4, ma.P_DNS6, ma.P_DNSADDR:
+			info.Host = c.Value()
+		case ma.P_TCP, ma.P_UDP:
+			// the first port is the one of the transport (i.e. not the one of the relay)
+			if info.Transport == "" {
+				info.Transport = c.Protocol().Name
+			}
+		}
+		return true
+	})
+
+	// the address is dialed through the peer ID (/p2p/<peer-id>)
+	if addrInfo, err := peer.AddrInfoFromP2pAddr(maddr); err == nil {
+		info.PeerID = addrInfo.ID.String()
+	}
+	if ip := utils.ExtractIPFromMAddr(maddr); ip != nil && info.Family != utils.UnknownAddrFamily {
+		info.IP = ip.String()
+		info.Public = utils.IsIPPublic(ip)
+	}
+	if port := utils.GetPortFromMaddrs(maddr); port > 0 {
+		info.Port = port
+	}
+	return info, nil
+}
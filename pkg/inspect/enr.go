@@ -0,0 +1,217 @@
+package inspect
+
+/**
+This package decodes and composes the records that the crawlers read from the networks (ENRs and
+multiaddrs) with the same parsing code that the crawlers use, so that what the CLI shows is exactly
+what the crawlers would store.
+
+*/
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"net"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/pkg/errors"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// EnrInfo is the content of an ENR, as the crawlers read it
+type EnrInfo struct {
+	Enr        string   `json:"enr"`
+	NodeID     string   `json:"node_id"`
+	PeerID     string   `json:"peer_id"`
+	Seq        uint64   `json:"seq"`
+	Pubkey     string   `json:"pubkey"`
+	IP         string   `json:"ip,omitempty"`
+	TCP        int      `json:"tcp,omitempty"`
+	UDP        int      `json:"udp,omitempty"`
+	QUIC       int      `json:"quic,omitempty"`
+	Multiaddrs []string `json:"multiaddrs"`
+	// Ethereum CL entries
+	ForkDigest string `json:"fork_digest,omitempty"`
+	Network    string `json:"network,omitempty"`
+	Attnets    []int  `json:"attnets,omitempty"`
+	Syncnets   []int  `json:"syncnets,omitempty"`
+	CSC        *int   `json:"csc,omitempty"`
+	// Waku entries
+	Waku *WakuInfo `json:"waku,omitempty"`
+	// every key/value pair of the record
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type WakuInfo struct {
+	Services   []string `json:"services"`
+	ClusterID  int      `json:"cluster_id"`
+	Shards     []int    `json:"shards"`
+	Multiaddrs []string `json:"multiaddrs"`
+}
+
+// DecodeEnr decodes the text representation of an ENR ("enr:...")
+func DecodeEnr(raw string) (*EnrInfo, error) {
+	node, err := enode.Parse(enode.ValidSchemes, raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse raw enr")
+	}
+	enrNode, err := eth.ParseEnr(node)
+	if err != nil {
+		return nil, err
+	}
+	peerID, err := enrNode.GetPeerID()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &EnrInfo{
+		Enr:        enrNode.Raw,
+		NodeID:     enrNode.ID.String(),
+		PeerID:     peerID.String(),
+		Seq:        enrNode.Seq,
+		Pubkey:     enrNode.GetPubkeyString(),
+		TCP:        enrNode.TCP,
+		UDP:        enrNode.UDP,
+		QUIC:       enrNode.QUIC,
+		Multiaddrs: make([]string, 0),
+		Fields:     enrNode.Fields,
+	}
+	if enrNode.IP != nil {
+		info.IP = enrNode.IP.String()
+	}
+	if maddr, err := enrNode.TCPMultiaddr(); err == nil {
+		info.Multiaddrs = append(info.Multiaddrs, maddr.String())
+	}
+	if maddr, err := enrNode.UDPMultiaddr(); err == nil {
+		info.Multiaddrs = append(info.Multiaddrs, maddr.String())
+	}
+
+	if _, ok := enrNode.Fields[eth.ETH2_ENR_KEY]; ok {
+		info.ForkDigest = eth.GetForkDigestFromEth2Data(*enrNode.Eth2Data)
+		info.Network = eth.ForkDigestNetwork(info.ForkDigest)
+	}
+	if _, ok := enrNode.Fields[eth.ATTNETS_KEY]; ok {
+		info.Attnets = enrNode.AttSubnets()
+	}
+	if _, ok := enrNode.Fields[eth.SYNCNETS_KEY]; ok {
+		info.Syncnets = enrNode.SyncSubnets()
+	}
+	if enrNode.CSC >= 0 {
+		csc := enrNode.CSC
+		info.CSC = &csc
+	}
+
+	wakuNode, err := waku.ParseWakuNode(peerID, node)
+	switch {
+	case err == nil:
+		info.Waku = &WakuInfo{
+			Services:   wakuNode.Services,
+			ClusterID:  wakuNode.ClusterID,
+			Shards:     wakuNode.Shards,
+			Multiaddrs: make([]string, 0, len(wakuNode.Multiaddrs)),
+		}
+		for _, maddr := range wakuNode.Multiaddrs {
+			info.Waku.Multiaddrs = append(info.Waku.Multiaddrs, maddr.String())
+		}
+	case err != waku.ErrNotWakuNode:
+		return nil, errors.Wrap(err, "unable to parse the waku entries")
+	}
+	return info, nil
+}
+
+// EnrRecord holds the entries of the ENR to compose
+type EnrRecord struct {
+	// signing key (a new one is generated if nil)
+	PrivKey *ecdsa.PrivateKey
+	Seq     uint64
+	IP      net.IP
+	TCP     int
+	UDP     int
+	QUIC    int
+	// Ethereum CL entries (skipped if the fork digest is empty)
+	ForkDigest      string
+	NextForkVersion string
+	NextForkEpoch   uint64
+	// hex bitvectors of the subnets (skipped if empty)
+	Attnets  string
+	Syncnets string
+}
+
+// BuildEnr composes and signs the ENR of the record, returning its key as well
+func BuildEnr(record EnrRecord) (*enode.Node, *ecdsa.PrivateKey, error) {
+	privKey := record.PrivKey
+	if privKey == nil {
+		var err error
+		privKey, err = utils.GenerateECDSAPrivKey()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	entries := make([]enr.Entry, 0)
+	if record.IP != nil {
+		if ip4 := record.IP.To4(); ip4 != nil {
+			entries = append(entries, enr.IPv4(ip4))
+			if record.TCP > 0 {
+				entries = append(entries, enr.TCP(record.TCP))
+			}
+			if record.UDP > 0 {
+				entries = append(entries, enr.UDP(record.UDP))
+			}
+		} else {
+			entries = append(entries, enr.IPv6(record.IP))
+			if record.TCP > 0 {
+				entries = append(entries, enr.TCP6(record.TCP))
+			}
+			if record.UDP > 0 {
+				entries = append(entries, enr.UDP6(record.UDP))
+			}
+		}
+	}
+	if record.QUIC > 0 {
+		entries = append(entries, eth.QuicENREntry(record.QUIC))
+	}
+	if record.ForkDigest != "" {
+		eth2, err := eth.ComposeEth2DataEntry(record.ForkDigest, record.NextForkVersion, record.NextForkEpoch)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, eth2)
+	}
+	if record.Attnets != "" {
+		attnets, err := decodeBitvector(record.Attnets, eth.SubnetLimit)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid attnets")
+		}
+		entries = append(entries, eth.AttnetsENREntry(attnets))
+	}
+	if record.Syncnets != "" {
+		syncnets, err := decodeBitvector(record.Syncnets, eth.SyncSubnetLimit)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid syncnets")
+		}
+		entries = append(entries, eth.SyncnetsENREntry(syncnets))
+	}
+
+	node, err := eth.ComposeEnr(privKey, record.Seq, entries...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return node, privKey, nil
+}
+
+// decodeBitvector decodes the hex bitvector of the given number of subnets
+func decodeBitvector(raw string, subnets int) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(raw, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if expected := (subnets + 7) / 8; len(b) != expected {
+		return nil, errors.Errorf("expected %d bytes, got %d", expected, len(b))
+	}
+	return b, nil
+}
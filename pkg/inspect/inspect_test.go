@@ -0,0 +1,116 @@
+package inspect
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func Test_BuildAndDecodeEnr(t *testing.T) {
+	node, key, err := BuildEnr(EnrRecord{
+		Seq:             3,
+		IP:              net.ParseIP("1.2.3.4"),
+		TCP:             9000,
+		UDP:             9001,
+		ForkDigest:      "0x6a95a1a9",
+		NextForkVersion: "0x04000000",
+		NextForkEpoch:   269568,
+		Attnets:         "0x0300000000000000",
+		Syncnets:        "04",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, key)
+
+	info, err := DecodeEnr(node.String())
+	require.NoError(t, err)
+	require.Equal(t, node.ID().String(), info.NodeID)
+	require.Equal(t, uint64(3), info.Seq)
+	require.Equal(t, "1.2.3.4", info.IP)
+	require.Equal(t, []string{"/ip4/1.2.3.4/tcp/9000", "/ip4/1.2.3.4/udp/9001"}, info.Multiaddrs)
+	require.Equal(t, "0x6a95a1a9", info.ForkDigest)
+	require.Equal(t, "mainnet", info.Network)
+	require.Equal(t, []int{0, 1}, info.Attnets)
+	require.Equal(t, []int{2}, info.Syncnets)
+	require.Nil(t, info.CSC)
+	require.Nil(t, info.Waku)
+	require.Equal(t, map[string]interface{}{
+		"fork_digest":       "0x6a95a1a9",
+		"next_fork_version": "0x04000000",
+		"next_fork_epoch":   uint64(269568),
+	}, info.Fields[eth.ETH2_ENR_KEY])
+
+	// the same key gives the same peer ID, and the records without eth2 entries have no fork digest
+	privKey, err := utils.AdaptSecp256k1FromECDSA(key)
+	require.NoError(t, err)
+	peerID, err := peer.IDFromPrivateKey(privKey)
+	require.NoError(t, err)
+	require.Equal(t, peerID.String(), info.PeerID)
+	resigned, _, err := BuildEnr(EnrRecord{PrivKey: key, Seq: 4, IP: net.ParseIP("1.2.3.4"), UDP: 9001})
+	require.NoError(t, err)
+	info, err = DecodeEnr(resigned.String())
+	require.NoError(t, err)
+	require.Equal(t, peerID.String(), info.PeerID)
+	require.Empty(t, info.ForkDigest)
+	require.Equal(t, []string{"/ip4/1.2.3.4/udp/9001"}, info.Multiaddrs)
+
+	// the crawlers discard the records that can't be contacted over discovery
+	incomplete, _, err := BuildEnr(EnrRecord{Seq: 1})
+	require.NoError(t, err)
+	_, err = DecodeEnr(incomplete.String())
+	require.ErrorIs(t, err, eth.EnrValidationError)
+
+	// the waku entries are decoded as the waku crawler does
+	wakuNode, err := eth.ComposeEnr(key, 1, enr.IPv4(net.ParseIP("1.2.3.4")), enr.UDP(9000),
+		enr.WithEntry(waku.WakuEnrKey, []byte{0b00001}), enr.WithEntry(waku.ShardsEnrKey, []byte{0, 1, 1, 0, 0}))
+	require.NoError(t, err)
+	info, err = DecodeEnr(wakuNode.String())
+	require.NoError(t, err)
+	require.NotNil(t, info.Waku)
+	require.Equal(t, []string{waku.Relay}, info.Waku.Services)
+	require.Equal(t, 1, info.Waku.ClusterID)
+	require.Equal(t, []int{0}, info.Waku.Shards)
+
+	_, _, err = BuildEnr(EnrRecord{ForkDigest: "0x6a95"})
+	require.Error(t, err)
+	_, _, err = BuildEnr(EnrRecord{Attnets: "ff"})
+	require.Error(t, err)
+	_, err = DecodeEnr("enr:invalid")
+	require.Error(t, err)
+}
+
+func Test_ParseAddr(t *testing.T) {
+	info, err := ParseAddr("/ip4/1.2.3.4/tcp/9000/p2p/16Uiu2HAmJKhj3fGm78y1Wxi3vrQWxipJgn561RcuwhhaQtERoHT5")
+	require.NoError(t, err)
+	require.Equal(t, utils.IPv4AddrFamily, info.Family)
+	require.Equal(t, "1.2.3.4", info.IP)
+	require.True(t, info.Public)
+	require.Equal(t, "tcp", info.Transport)
+	require.Equal(t, 9000, info.Port)
+	require.Equal(t, "16Uiu2HAmJKhj3fGm78y1Wxi3vrQWxipJgn561RcuwhhaQtERoHT5", info.PeerID)
+	require.Len(t, info.Components, 3)
+	require.Equal(t, Component{Protocol: "ip4", Value: "1.2.3.4"}, info.Components[0])
+
+	info, err = ParseAddr("/ip6/fe80::1/udp/9000/quic-v1")
+	require.NoError(t, err)
+	require.Equal(t, utils.IPv6AddrFamily, info.Family)
+	require.False(t, info.Public)
+	require.Equal(t, "udp", info.Transport)
+	require.Empty(t, info.PeerID)
+
+	info, err = ParseAddr("/dns4/bootstrap.libp2p.io/tcp/4001")
+	require.NoError(t, err)
+	require.Equal(t, utils.UnknownAddrFamily, info.Family)
+	require.Equal(t, "bootstrap.libp2p.io", info.Host)
+	require.Empty(t, info.IP)
+	require.Equal(t, 4001, info.Port)
+
+	_, err = ParseAddr("/ip4/1.2.3.4/tcp")
+	require.Error(t, err)
+}
@@ -0,0 +1,254 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	InfluxMeasurementPrefix = "armiarma"
+	InfluxWriteTimeout      = 10 * time.Second
+)
+
+// SummaryExporter receives the summaries of the metrics modules (module name -> metric name -> summary)
+// after each round of updates
+type SummaryExporter interface {
+	Export(t time.Time, summaries map[string]map[string]interface{}) error
+}
+
+// InfluxExporter writes the summaries of each round into InfluxDB (or Telegraf) using the line protocol:
+// - distributions (e.g. client_distribution) write one point per key with its value and share
+// - numeric summaries write a single point with their value
+// - structs write a single point with one field per numeric field
+type InfluxExporter struct {
+	ctx      context.Context
+	network  string
+	writeURL string
+	token    string
+	client   *http.Client
+}
+
+// NewInfluxExporter composes the exporter for the given InfluxDB endpoint, the v2 write API is used
+// if an organization is given (bucket and token), the v1 one otherwise (bucket as database)
+func NewInfluxExporter(ctx context.Context, network, endpoint, org, bucket, token string) (*InfluxExporter, error) {
+	if endpoint == "" || bucket == "" {
+		return nil, errors.New("influx endpoint and bucket/database are required")
+	}
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid influx endpoint")
+	}
+	query := url.Values{}
+	if org != "" {
+		base.Path += "/api/v2/write"
+		query.Set("org", org)
+		query.Set("bucket", bucket)
+	} else {
+		base.Path += "/write"
+		query.Set("db", bucket)
+	}
+	query.Set("precision", "s")
+	base.RawQuery = query.Encode()
+
+	return &InfluxExporter{
+		ctx:      ctx,
+		network:  network,
+		writeURL: base.String(),
+		token:    token,
+		client:   &http.Client{Timeout: InfluxWriteTimeout},
+	}, nil
+}
+
+// Export writes the summaries of the round in a single request
+func (e *InfluxExporter) Export(t time.Time, summaries map[string]map[string]interface{}) error {
+	lines := e.lines(t, summaries)
+	if len(lines) == 0 {
+		return nil
+	}
+	body := strings.Join(lines, "\n") + "\n"
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodPost, e.writeURL, bytes.NewBufferString(body))
+	if err != nil {
+		return errors.Wrap(err, "unable to compose influx write")
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Token "+e.token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to write into influx")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("influx write failed with status %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
+
+// lines composes the line protocol points of the summaries (sorted to keep the writes deterministic)
+func (e *InfluxExporter) lines(t time.Time, summaries map[string]map[string]interface{}) []string {
+	lines := make([]string, 0)
+	for _, module := range sortedKeys(summaries) {
+		for _, metric := range sortedKeys(summaries[module]) {
+			measurement := escapeInflux(InfluxMeasurementPrefix+"_"+metric, false)
+			tags := fmt.Sprintf("network=%s,module=%s", escapeInflux(e.network, true), escapeInflux(module, true))
+			for _, point := range influxPoints(summaries[module][metric]) {
+				pointTags := tags
+				if point.key != "" {
+					pointTags += ",key=" + escapeInflux(point.key, true)
+				}
+				lines = append(lines, fmt.Sprintf("%s,%s %s %d", measurement, pointTags, point.fields, t.Unix()))
+			}
+		}
+	}
+	return lines
+}
+
+type influxPoint struct {
+	key    string
+	fields string
+}
+
+func influxPoints(summary interface{}) []influxPoint {
+	if summary == nil {
+		return nil
+	}
+	v := reflect.ValueOf(summary)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		values := make(map[string]string, v.Len())
+		nums := make(map[string]float64, v.Len())
+		total := 0.0
+		for _, k := range v.MapKeys() {
+			num, ok := numericValue(v.MapIndex(k))
+			if !ok {
+				continue
+			}
+			values[k.String()] = formatNumeric(v.MapIndex(k))
+			nums[k.String()] = num
+			total += num
+		}
+		points := make([]influxPoint, 0, len(values))
+		for _, key := range sortedKeys(values) {
+			fields := "value=" + values[key]
+			if total > 0 {
+				fields += ",share=" + strconv.FormatFloat(nums[key]/total, 'f', -1, 64)
+			}
+			points = append(points, influxPoint{key: key, fields: fields})
+		}
+		return points
+
+	case reflect.Struct:
+		fields := make([]string, 0, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if _, ok := numericValue(v.Field(i)); ok {
+				fields = append(fields, fmt.Sprintf("%s=%s", escapeInflux(toSnakeCase(field.Name), true), formatNumeric(v.Field(i))))
+			}
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		return []influxPoint{{fields: strings.Join(fields, ",")}}
+
+	default:
+		if _, ok := numericValue(v); ok {
+			return []influxPoint{{fields: "value=" + formatNumeric(v)}}
+		}
+		return nil
+	}
+}
+
+func numericValue(v reflect.Value) (float64, bool) {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
+
+// formatNumeric keeps the integers as integer fields (i.e. 12i) and the floats as floats
+func formatNumeric(v reflect.Value) string {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10) + "i"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10) + "i"
+	default:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+}
+
+// escapeInflux escapes the special characters of measurements, and of tag keys and values
+func escapeInflux(s string, tag bool) string {
+	replacer := strings.NewReplacer(",", `\,`, " ", `\ `)
+	if tag {
+		replacer = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+	}
+	return replacer.Replace(s)
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 && !(s[i-1] >= 'A' && s[i-1] <= 'Z') {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sortedKeys returns the sorted keys of a map with string keys
+func sortedKeys(m interface{}) []string {
+	mapKeys := reflect.ValueOf(m).MapKeys()
+	keys := make([]string, 0, len(mapKeys))
+	for _, k := range mapKeys {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
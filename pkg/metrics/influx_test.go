@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type dialStats struct {
+	Attempts   int64
+	Successes  int64
+	SuccessPct float64
+	hidden     int
+}
+
+func Test_InfluxExport(t *testing.T) {
+	var path, auth, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.String()
+		auth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exporter, err := NewInfluxExporter(context.Background(), "mainnet", server.URL, "migalabs", "crawler", "secret")
+	require.NoError(t, err)
+
+	summaries := map[string]map[string]interface{}{
+		"crawler": {
+			"client_distribution": map[string]interface{}{"prysm": 3, "lighthouse nodes": 1},
+			"total_peers":         4,
+			"unknown":             "not exported",
+		},
+		"peering": {
+			"dial_stats": &dialStats{Attempts: 10, Successes: 4, SuccessPct: 40, hidden: 1},
+		},
+	}
+	require.NoError(t, exporter.Export(time.Unix(1700000000, 0), summaries))
+	require.Equal(t, "/api/v2/write?bucket=crawler&org=migalabs&precision=s", path)
+	require.Equal(t, "Token secret", auth)
+	require.Equal(t,
+		"armiarma_client_distribution,network=mainnet,module=crawler,key=lighthouse\\ nodes value=1i,share=0.25 1700000000\n"+
+			"armiarma_client_distribution,network=mainnet,module=crawler,key=prysm value=3i,share=0.75 1700000000\n"+
+			"armiarma_total_peers,network=mainnet,module=crawler value=4i 1700000000\n"+
+			"armiarma_dial_stats,network=mainnet,module=peering attempts=10i,successes=4i,success_pct=40 1700000000\n",
+		body)
+
+	// v1 API (also served by Telegraf) when no organization is given
+	exporter, err = NewInfluxExporter(context.Background(), "mainnet", server.URL+"/", "", "crawler", "")
+	require.NoError(t, err)
+	require.NoError(t, exporter.Export(time.Now(), summaries))
+	require.Equal(t, "/write?db=crawler&precision=s", path)
+	require.Empty(t, auth)
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -29,9 +30,10 @@ func (m *MetricsModule) AddIndvMetric(indvMetric *IndvMetrics) error {
 	return nil
 }
 
-func (m *MetricsModule) Init() error {
+// Init registers the metrics of the module in the given registerer
+func (m *MetricsModule) Init(reg prometheus.Registerer) error {
 	for _, metric := range m.IndvMetrics {
-		err := metric.Init()
+		err := metric.Init(reg)
 		if err != nil {
 			return errors.Wrap(err, "error registering metric "+metric.Name())
 		}
@@ -63,15 +65,15 @@ func (m *MetricsModule) Details() string {
 
 type IndvMetrics struct {
 	// TODO: add metrics to the export (time¿?)
-	name     string                      // name the defines the exporter (Example Peer-Prometheus-Exporter)
-	initFn   func() error                // Initialization of the exporter
-	updateFn func() (interface{}, error) // function that will be executed in the running loop (the func needs to run a go routine)
+	name     string                            // name the defines the exporter (Example Peer-Prometheus-Exporter)
+	initFn   func(prometheus.Registerer) error // Initialization of the exporter (registers the metrics)
+	updateFn func() (interface{}, error)       // function that will be executed in the running loop (the func needs to run a go routine)
 }
 
 // NewIndvMetrics
 func NewIndvMetrics(
 	name string,
-	initFn func() error,
+	initFn func(prometheus.Registerer) error,
 	updateFn func() (interface{}, error)) (*IndvMetrics, error) {
 
 	// check if all the necesaty parameters where given
@@ -87,10 +89,10 @@ func NewIndvMetrics(
 	return module, nil
 }
 
-func (m *IndvMetrics) Init() error {
+func (m *IndvMetrics) Init(reg prometheus.Registerer) error {
 	// Init loop for each of the Exporters
 	log.Infof("initializing exporter %s", m.name)
-	return m.initFn()
+	return m.initFn(reg)
 }
 
 func (m *IndvMetrics) UpdateMetrics() (interface{}, error) {
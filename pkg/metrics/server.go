@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// MetricsServer exposes the metrics of the several crawlers of a process in a single endpoint,
+// where each metric keeps the network label of its crawler. The handlers of each crawler
+// (i.e. the dial queue) are served under the name of its network: /<network>/<path>
+type MetricsServer struct {
+	ip       string
+	port     int
+	registry *prometheus.Registry
+
+	startOnce sync.Once
+}
+
+func NewMetricsServer(ip string, port int) *MetricsServer {
+	return &MetricsServer{
+		ip:       ip,
+		port:     port,
+		registry: prometheus.NewRegistry(),
+	}
+}
+
+// NewPrometheusMetrics returns the exporting service of the crawler of the given network,
+// which registers its metrics in the registry of the server
+func (s *MetricsServer) NewPrometheusMetrics(ctx context.Context, network string) *PrometheusMetrics {
+	p := newPrometheusMetrics(ctx, network, s.registry, s.ip, s.port)
+	p.server = s
+	return p
+}
+
+// Start serves the combined metrics, only the first call has any effect
+func (s *MetricsServer) Start() {
+	s.startOnce.Do(func() {
+		http.Handle("/"+EndpointUrl, promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
+		go func() {
+			log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%d", s.ip, s.port), nil))
+		}()
+	})
+}
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SharedMetricsServer(t *testing.T) {
+	server := NewMetricsServer("localhost", 9080)
+	// the crawlers of each network register the same metrics in the shared registry
+	for _, network := range []string{"mainnet", "gnosis"} {
+		promethMetrics := server.NewPrometheusMetrics(context.Background(), network)
+		peers := prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "crawler",
+			Name:      "connected_peers",
+			Help:      "Number of connected peers",
+		})
+		require.NoError(t, promethMetrics.Registerer().Register(peers))
+		peers.Set(float64(len(network)))
+	}
+	count, err := testutil.GatherAndCount(server.registry, "crawler_connected_peers")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
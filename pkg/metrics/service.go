@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 )
@@ -23,20 +25,42 @@ var (
 type PrometheusMetrics struct {
 	ctx context.Context
 
+	// every metric is registered in the registry of the network, labeled with its name,
+	// so that a single scrape target can separate the data of each network
+	network    string
+	registry   *prometheus.Registry
+	registerer prometheus.Registerer
+	// shared server of the crawlers of the process (nil if the crawler serves its own metrics)
+	server *MetricsServer
+
 	ExposedIp       string
 	ExposedPort     string
 	EndpointUrl     string
 	RefreshInterval time.Duration
 
 	Modules []*MetricsModule
+	// exporters that get the summaries of the modules after each round of updates
+	exporters []SummaryExporter
 
 	wg     sync.WaitGroup
 	closeC chan struct{}
 }
 
-func NewPrometheusMetrics(ctx context.Context, ip string, port int) *PrometheusMetrics {
+func NewPrometheusMetrics(ctx context.Context, network string, ip string, port int) *PrometheusMetrics {
+	return newPrometheusMetrics(ctx, network, prometheus.NewRegistry(), ip, port)
+}
+
+func newPrometheusMetrics(ctx context.Context, network string, registry *prometheus.Registry, ip string, port int) *PrometheusMetrics {
+	registerer := prometheus.WrapRegistererWith(prometheus.Labels{"network": network}, registry)
+	registerer.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
 	return &PrometheusMetrics{
 		ctx:             ctx,
+		network:         network,
+		registry:        registry,
+		registerer:      registerer,
 		ExposedIp:       ip,
 		ExposedPort:     fmt.Sprintf("%d", port),
 		EndpointUrl:     EndpointUrl,
@@ -46,15 +70,38 @@ func NewPrometheusMetrics(ctx context.Context, ip string, port int) *PrometheusM
 	}
 }
 
+// Registerer returns the registerer of the network, which adds the network label to the registered metrics
+func (p *PrometheusMetrics) Registerer() prometheus.Registerer {
+	return p.registerer
+}
+
 func (p *PrometheusMetrics) AddMeticsModule(newMod *MetricsModule) {
 	p.Modules = append(p.Modules, newMod)
 }
 
+// AddSummaryExporter forwards the summaries of every round of updates to the given exporter
+func (p *PrometheusMetrics) AddSummaryExporter(exporter SummaryExporter) {
+	p.exporters = append(p.exporters, exporter)
+}
+
+// AddHandler exposes an extra http handler (i.e. status endpoints) on the same server as the metrics
+// (under the name of the network if the server is shared with other crawlers)
+func (p *PrometheusMetrics) AddHandler(pattern string, handler http.Handler) {
+	if p.server != nil {
+		pattern = "/" + p.network + pattern
+	}
+	http.Handle(pattern, handler)
+}
+
 func (p *PrometheusMetrics) Start() error {
-	http.Handle("/"+p.EndpointUrl, promhttp.Handler())
-	go func() {
-		log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%s", p.ExposedIp, p.ExposedPort), nil))
-	}()
+	if p.server != nil {
+		p.server.Start()
+	} else {
+		http.Handle("/"+p.EndpointUrl, promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
+		go func() {
+			log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%s", p.ExposedIp, p.ExposedPort), nil))
+		}()
+	}
 
 	err := p.initPrometheusMetrics()
 	if err != nil {
@@ -72,7 +119,7 @@ func (p *PrometheusMetrics) initPrometheusMetrics() error {
 	// iter through all the available modules - and call the
 	// mudule.InitMetrics() method
 	for _, mod := range p.Modules {
-		err := mod.Init()
+		err := mod.Init(p.registerer)
 		if err != nil {
 			return err
 		}
@@ -90,6 +137,8 @@ metricsUpdateLoop:
 		select {
 		case <-ticker.C:
 			log.Trace("updating values for prometheus metrics")
+			roundTime := time.Now()
+			roundSummaries := make(map[string]map[string]interface{}, len(p.Modules))
 			// update all the submodules on prometheus
 			for _, mod := range p.Modules {
 				summary := make(map[string]interface{}, 0)
@@ -97,10 +146,17 @@ metricsUpdateLoop:
 				for key, value := range modSum {
 					summary[key] = value
 				}
+				roundSummaries[mod.Name()] = summary
 				// compose a message with the give summary
 				logFields := log.Fields(modSum)
 				log.WithFields(logFields).Infof("summary for %s", mod.Name())
 			}
+			for _, exporter := range p.exporters {
+				err := exporter.Export(roundTime, roundSummaries)
+				if err != nil {
+					log.WithError(err).Warn("unable to export the summaries of the round")
+				}
+			}
 
 		case <-p.closeC:
 			log.Debug("detected a controled shutdown")
@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var DefaultDiversityInterval = 5 * time.Minute
+
+type diversitySource interface {
+	GetClientVersionDistribution() ([]models.ClientCount, error)
+	GetGeoDistribution() (map[string]interface{}, error)
+	GetASNDistribution() (map[string]int, error)
+	GetCloudProviderDistribution() (map[string]int, error)
+	GetAttnetSubscriptions() (map[int]int, error)
+}
+
+// DiversitySnapshot is the distribution of the active peers across clients, countries, ASNs, cloud providers and attnets
+type DiversitySnapshot struct {
+	Timestamp time.Time
+	Clients   []models.ClientCount
+	Countries map[string]int
+	ASNs      map[string]int
+	Clouds    map[string]int
+	Attnets   map[int]int
+}
+
+// DiversityExporter periodically queries the DB for the diversity of the active peers and publishes
+// it as gauges, so that the dashboards don't need to query the DB themselves
+type DiversityExporter struct {
+	ctx context.Context
+
+	db       diversitySource
+	interval time.Duration
+
+	m    sync.RWMutex
+	last *DiversitySnapshot
+
+	gauges *diversityMetrics
+
+	wg sync.WaitGroup
+}
+
+func NewDiversityExporter(ctx context.Context, db diversitySource, interval time.Duration) (*DiversityExporter, error) {
+	if interval <= 0 {
+		return nil, errors.Errorf("invalid diversity refresh interval %s", interval)
+	}
+	return &DiversityExporter{
+		ctx:      ctx,
+		db:       db,
+		interval: interval,
+		gauges:   newDiversityMetrics(),
+	}, nil
+}
+
+// Start spawns the routine that refreshes the diversity gauges every interval
+func (e *DiversityExporter) Start() {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := e.Refresh(time.Now()); err != nil {
+				log.WithError(err).Warn("unable to refresh the diversity of the peers")
+			}
+			select {
+			case <-ticker.C:
+			case <-e.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Refresh queries the current diversity of the peers and updates the gauges with it
+func (e *DiversityExporter) Refresh(now time.Time) (*DiversitySnapshot, error) {
+	clients, err := e.db.GetClientVersionDistribution()
+	if err != nil {
+		return nil, err
+	}
+	geoDist, err := e.db.GetGeoDistribution()
+	if err != nil {
+		return nil, err
+	}
+	asns, err := e.db.GetASNDistribution()
+	if err != nil {
+		return nil, err
+	}
+	clouds, err := e.db.GetCloudProviderDistribution()
+	if err != nil {
+		return nil, err
+	}
+	attnets, err := e.db.GetAttnetSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	countries := make(map[string]int, len(geoDist))
+	for country, cnt := range geoDist {
+		if n, ok := cnt.(int); ok {
+			countries[country] = n
+		}
+	}
+	snapshot := &DiversitySnapshot{
+		Timestamp: now,
+		Clients:   clients,
+		Countries: countries,
+		ASNs:      asns,
+		Clouds:    clouds,
+		Attnets:   attnets,
+	}
+
+	// reset the gauges so that the values that disappeared aren't exported anymore
+	e.gauges.PeersByClient.Reset()
+	for _, c := range clients {
+		e.gauges.PeersByClient.WithLabelValues(c.Client, c.Version).Set(float64(c.Peers))
+	}
+	e.gauges.PeersByCountry.Reset()
+	for country, n := range countries {
+		e.gauges.PeersByCountry.WithLabelValues(country).Set(float64(n))
+	}
+	e.gauges.PeersByASN.Reset()
+	for asn, n := range asns {
+		e.gauges.PeersByASN.WithLabelValues(asn).Set(float64(n))
+	}
+	e.gauges.PeersByCloudProvider.Reset()
+	for provider, n := range clouds {
+		e.gauges.PeersByCloudProvider.WithLabelValues(provider).Set(float64(n))
+	}
+	e.gauges.PeersPerAttnet.Reset()
+	for subnet, n := range attnets {
+		e.gauges.PeersPerAttnet.WithLabelValues(strconv.Itoa(subnet)).Set(float64(n))
+	}
+
+	e.m.Lock()
+	e.last = snapshot
+	e.m.Unlock()
+	return snapshot, nil
+}
+
+// Last returns the last refreshed snapshot, nil if there wasn't any yet
+func (e *DiversityExporter) Last() *DiversitySnapshot {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.last
+}
+
+// Stop waits until the exporter routine finishes (it dies with the context)
+func (e *DiversityExporter) Stop() {
+	e.wg.Wait()
+}
@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+type mockDiversitySource struct {
+	clients []models.ClientCount
+	geo     map[string]interface{}
+	asns    map[string]int
+	clouds  map[string]int
+	attnets map[int]int
+}
+
+func (s *mockDiversitySource) GetClientVersionDistribution() ([]models.ClientCount, error) {
+	return s.clients, nil
+}
+
+func (s *mockDiversitySource) GetGeoDistribution() (map[string]interface{}, error) {
+	return s.geo, nil
+}
+
+func (s *mockDiversitySource) GetASNDistribution() (map[string]int, error) {
+	return s.asns, nil
+}
+
+func (s *mockDiversitySource) GetCloudProviderDistribution() (map[string]int, error) {
+	return s.clouds, nil
+}
+
+func (s *mockDiversitySource) GetAttnetSubscriptions() (map[int]int, error) {
+	return s.attnets, nil
+}
+
+func Test_DiversityRefresh(t *testing.T) {
+	src := &mockDiversitySource{
+		clients: []models.ClientCount{
+			{Client: "lighthouse", Version: "v4.5.0", Peers: 10},
+			{Client: "prysm", Version: "v4.1.1", Peers: 7},
+		},
+		geo:     map[string]interface{}{"DE": 12, "US": 5},
+		asns:    map[string]int{"AS16509 Amazon.com, Inc.": 9},
+		clouds:  map[string]int{"aws": 9, "none": 8},
+		attnets: map[int]int{0: 3, 63: 1},
+	}
+	_, err := NewDiversityExporter(context.Background(), src, 0)
+	require.Error(t, err)
+	exporter, err := NewDiversityExporter(context.Background(), src, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, exporter.Last())
+
+	snapshot, err := exporter.Refresh(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"DE": 12, "US": 5}, snapshot.Countries)
+	require.Equal(t, snapshot, exporter.Last())
+
+	require.Equal(t, 10.0, testutil.ToFloat64(exporter.gauges.PeersByClient.WithLabelValues("lighthouse", "v4.5.0")))
+	require.Equal(t, 5.0, testutil.ToFloat64(exporter.gauges.PeersByCountry.WithLabelValues("US")))
+	require.Equal(t, 9.0, testutil.ToFloat64(exporter.gauges.PeersByASN.WithLabelValues("AS16509 Amazon.com, Inc.")))
+	require.Equal(t, 8.0, testutil.ToFloat64(exporter.gauges.PeersByCloudProvider.WithLabelValues("none")))
+	require.Equal(t, 1.0, testutil.ToFloat64(exporter.gauges.PeersPerAttnet.WithLabelValues("63")))
+
+	// the values that disappear from the DB aren't exported anymore
+	src.clients = src.clients[:1]
+	_, err = exporter.Refresh(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, testutil.CollectAndCount(exporter.gauges.PeersByClient))
+}
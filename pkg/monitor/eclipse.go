@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Peer sets that are monitored
+	ConnectedSet = "connected"
+	GossipSet    = "gossip"
+
+	// Dimensions in which a peer set can collapse
+	ClientDimension  = "client"
+	CountryDimension = "country"
+	AsnDimension     = "asn"
+)
+
+var (
+	DefaultConcentrationThreshold float64 = 0.5
+	DefaultMinMonitoredPeers      int     = 10
+)
+
+type database interface {
+	GetPeerSetDistributions(peerIDs []string) (clients, countries, asns map[string]int, err error)
+}
+
+type gossipPeers interface {
+	TopicPeers() []peer.ID
+}
+
+// Concentration summarizes the biggest share that a single value (client, country, asn) has over a peer set
+type Concentration struct {
+	Set       string
+	Dimension string
+	Value     string
+	Peers     int
+	Total     int
+	Share     float64
+}
+
+// EclipseMonitor periodically checks whether our connected set (and the gossip mesh, if any)
+// collapses onto a single client, country, or ASN. Which might indicate an eclipse attempt
+// on the crawler or an extreme concentration of the network.
+type EclipseMonitor struct {
+	ctx context.Context
+
+	host   host.Host
+	db     database
+	gossip gossipPeers
+
+	threshold float64
+	minPeers  int
+}
+
+func NewEclipseMonitor(
+	ctx context.Context,
+	h host.Host,
+	db database,
+	opts ...EclipseMonitorOption) (*EclipseMonitor, error) {
+
+	monitor := &EclipseMonitor{
+		ctx:       ctx,
+		host:      h,
+		db:        db,
+		threshold: DefaultConcentrationThreshold,
+		minPeers:  DefaultMinMonitoredPeers,
+	}
+
+	for _, opt := range opts {
+		err := opt(monitor)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply eclipse monitor option")
+		}
+	}
+	return monitor, nil
+}
+
+// CheckConcentrations computes the concentrations of all the monitored peer sets,
+// alerting (warning) about those that exceed the threshold
+func (m *EclipseMonitor) CheckConcentrations() ([]Concentration, error) {
+	peerSets := map[string][]peer.ID{
+		ConnectedSet: m.host.Network().Peers(),
+	}
+	if m.gossip != nil {
+		peerSets[GossipSet] = m.gossip.TopicPeers()
+	}
+
+	concentrations := make([]Concentration, 0)
+	for setName, peers := range peerSets {
+		peerIDs := make([]string, 0, len(peers))
+		for _, p := range peers {
+			peerIDs = append(peerIDs, p.String())
+		}
+		clients, countries, asns, err := m.db.GetPeerSetDistributions(peerIDs)
+		if err != nil {
+			return concentrations, errors.Wrap(err, "unable to check concentration of "+setName+" peers")
+		}
+		for dimension, dist := range map[string]map[string]int{
+			ClientDimension:  clients,
+			CountryDimension: countries,
+			AsnDimension:     asns,
+		} {
+			conc := MaxConcentration(dist)
+			conc.Set = setName
+			conc.Dimension = dimension
+			concentrations = append(concentrations, conc)
+
+			if m.IsAlarming(conc) {
+				log.WithFields(log.Fields{
+					"set":       conc.Set,
+					"dimension": conc.Dimension,
+					"value":     conc.Value,
+					"peers":     conc.Peers,
+					"total":     conc.Total,
+					"share":     conc.Share,
+				}).Warn("peer set collapsed onto a single value, possible eclipse attempt")
+			}
+		}
+	}
+	return concentrations, nil
+}
+
+// IsAlarming returns true if the concentration exceeds the threshold with enough peers to be meaningful
+// "unknown" values are not taken into account, as they only reflect missing data
+func (m *EclipseMonitor) IsAlarming(conc Concentration) bool {
+	return conc.Total >= m.minPeers && conc.Value != "unknown" && conc.Share >= m.threshold
+}
+
+// MaxConcentration returns the value that gathers the biggest share of peers in the given distribution
+func MaxConcentration(dist map[string]int) Concentration {
+	conc := Concentration{}
+	// sort the keys to get a deterministic result on ties
+	values := make([]string, 0, len(dist))
+	for value, count := range dist {
+		values = append(values, value)
+		conc.Total += count
+	}
+	sort.Strings(values)
+	for _, value := range values {
+		if dist[value] > conc.Peers {
+			conc.Value = value
+			conc.Peers = dist[value]
+		}
+	}
+	if conc.Total > 0 {
+		conc.Share = float64(conc.Peers) / float64(conc.Total)
+	}
+	return conc
+}
@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MaxConcentration(t *testing.T) {
+	conc := MaxConcentration(map[string]int{
+		"lighthouse": 6,
+		"prysm":      3,
+		"teku":       1,
+	})
+	require.Equal(t, "lighthouse", conc.Value)
+	require.Equal(t, 6, conc.Peers)
+	require.Equal(t, 10, conc.Total)
+	require.Equal(t, 0.6, conc.Share)
+
+	// ties are solved alphabetically
+	conc = MaxConcentration(map[string]int{"US": 2, "DE": 2})
+	require.Equal(t, "DE", conc.Value)
+
+	// empty sets have no concentration
+	conc = MaxConcentration(map[string]int{})
+	require.Equal(t, 0, conc.Total)
+	require.Equal(t, float64(0), conc.Share)
+}
+
+func Test_IsAlarming(t *testing.T) {
+	monitor, err := NewEclipseMonitor(
+		context.Background(),
+		nil,
+		nil,
+		WithConcentrationThreshold(0.5),
+		WithMinMonitoredPeers(10),
+	)
+	require.NoError(t, err)
+
+	require.True(t, monitor.IsAlarming(Concentration{Value: "AS16509", Peers: 8, Total: 10, Share: 0.8}))
+	require.False(t, monitor.IsAlarming(Concentration{Value: "AS16509", Peers: 4, Total: 10, Share: 0.4}))
+	// not enough peers to be meaningful
+	require.False(t, monitor.IsAlarming(Concentration{Value: "AS16509", Peers: 4, Total: 5, Share: 0.8}))
+	// missing data isn't an eclipse
+	require.False(t, monitor.IsAlarming(Concentration{Value: "unknown", Peers: 8, Total: 10, Share: 0.8}))
+
+	_, err = NewEclipseMonitor(context.Background(), nil, nil, WithConcentrationThreshold(1.5))
+	require.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// eclipseMetrics are the collectors of the eclipse monitor, one set per crawler
+type eclipseMetrics struct {
+	PeerSetConcentration *prometheus.GaugeVec
+	EclipseAlerts        *prometheus.CounterVec
+}
+
+func newEclipseMetrics() *eclipseMetrics {
+	return &eclipseMetrics{
+		PeerSetConcentration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "monitor",
+			Name:      "peer_set_concentration",
+			Help:      "Biggest share of the peer set (connected, gossip) gathered by a single client, country, or asn",
+		},
+			[]string{"set", "dimension"},
+		),
+		EclipseAlerts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "monitor",
+			Name:      "eclipse_alerts",
+			Help:      "Number of times that a peer set exceeded the concentration threshold",
+		},
+			[]string{"set", "dimension"},
+		),
+	}
+}
+
+// diversityMetrics are the diversity gauges of the exporter, named after the panels of the
+// standard Grafana dashboard
+type diversityMetrics struct {
+	PeersByClient        *prometheus.GaugeVec
+	PeersByCountry       *prometheus.GaugeVec
+	PeersByASN           *prometheus.GaugeVec
+	PeersByCloudProvider *prometheus.GaugeVec
+	PeersPerAttnet       *prometheus.GaugeVec
+}
+
+func newDiversityMetrics() *diversityMetrics {
+	return &diversityMetrics{
+		PeersByClient: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "peers_by_client",
+			Help: "Number of active peers of each client and version",
+		},
+			[]string{"client", "version"},
+		),
+		PeersByCountry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "peers_by_country",
+			Help: "Number of active peers located at each country",
+		},
+			[]string{"country"},
+		),
+		PeersByASN: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "peers_by_asn",
+			Help: "Number of active peers hosted at each autonomous system",
+		},
+			[]string{"asn"},
+		),
+		PeersByCloudProvider: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "peers_by_cloud_provider",
+			Help: "Number of active peers hosted at each cloud provider",
+		},
+			[]string{"provider"},
+		),
+		PeersPerAttnet: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "peers_per_attnet",
+			Help: "Number of nodes seen in the last day that advertise each attestation subnet",
+		},
+			[]string{"attnet"},
+		),
+	}
+}
+
+func (m *EclipseMonitor) GetMetrics() *metrics.MetricsModule {
+	metricsMod := metrics.NewMetricsModule(
+		"eclipse-monitor",
+		"monitor of the concentration of our own connected set and gossip mesh",
+	)
+	metricsMod.AddIndvMetric(m.getPeerSetConcentration(newEclipseMetrics()))
+	return metricsMod
+}
+
+func (m *EclipseMonitor) getPeerSetConcentration(collectors *eclipseMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(collectors.PeerSetConcentration)
+		reg.MustRegister(collectors.EclipseAlerts)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		summary := make(map[string]interface{})
+		concentrations, err := m.CheckConcentrations()
+		if err != nil {
+			return nil, err
+		}
+		for _, conc := range concentrations {
+			collectors.PeerSetConcentration.WithLabelValues(conc.Set, conc.Dimension).Set(conc.Share)
+			if m.IsAlarming(conc) {
+				collectors.EclipseAlerts.WithLabelValues(conc.Set, conc.Dimension).Inc()
+			}
+			summary[conc.Set+"_"+conc.Dimension] = conc.Value
+		}
+		log.Tracef("peer set concentrations %+v", summary)
+		return summary, nil
+	}
+	indvMetr, err := metrics.NewIndvMetrics(
+		"peer_set_concentration",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return indvMetr
+}
+
+func (e *DiversityExporter) GetMetrics() *metrics.MetricsModule {
+	metricsMod := metrics.NewMetricsModule(
+		"diversity-exporter",
+		"distribution of the active peers across clients, countries, asns, cloud providers and attnets",
+	)
+	metricsMod.AddIndvMetric(e.getDiversity())
+	return metricsMod
+}
+
+func (e *DiversityExporter) getDiversity() *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(e.gauges.PeersByClient)
+		reg.MustRegister(e.gauges.PeersByCountry)
+		reg.MustRegister(e.gauges.PeersByASN)
+		reg.MustRegister(e.gauges.PeersByCloudProvider)
+		reg.MustRegister(e.gauges.PeersPerAttnet)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		// the gauges are refreshed by the exporter routine, here we only summarize the last snapshot
+		summary := make(map[string]interface{})
+		snapshot := e.Last()
+		if snapshot == nil {
+			return summary, nil
+		}
+		summary["clients"] = len(snapshot.Clients)
+		summary["countries"] = len(snapshot.Countries)
+		summary["asns"] = len(snapshot.ASNs)
+		summary["clouds"] = snapshot.Clouds
+		summary["attnets"] = snapshot.Attnets
+		return summary, nil
+	}
+	indvMetr, err := metrics.NewIndvMetrics(
+		"peer_diversity",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return indvMetr
+}
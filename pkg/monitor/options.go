@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type EclipseMonitorOption func(*EclipseMonitor) error
+
+// WithGossipPeers adds the peers of the gossip topics to the monitored peer sets
+func WithGossipPeers(gossip gossipPeers) EclipseMonitorOption {
+	return func(m *EclipseMonitor) error {
+		m.gossip = gossip
+		return nil
+	}
+}
+
+// WithConcentrationThreshold sets the share (0-1] of a peer set over which a single value is considered alarming
+func WithConcentrationThreshold(threshold float64) EclipseMonitorOption {
+	return func(m *EclipseMonitor) error {
+		if threshold <= 0 || threshold > 1 {
+			return errors.Errorf("invalid concentration threshold %f, it has to be between (0, 1]", threshold)
+		}
+		m.threshold = threshold
+		return nil
+	}
+}
+
+// WithMinMonitoredPeers sets the minimum number of peers in a set to raise alerts
+func WithMinMonitoredPeers(minPeers int) EclipseMonitorOption {
+	return func(m *EclipseMonitor) error {
+		if minPeers < 0 {
+			return errors.Errorf("invalid min number of monitored peers %d", minPeers)
+		}
+		m.minPeers = minPeers
+		return nil
+	}
+}
+
+type QualityMonitorOption func(*QualityMonitor) error
+
+// WithGossipStats sets the source of the gossip behaviour of the peers
+func WithGossipStats(gossip gossipStats) QualityMonitorOption {
+	return func(m *QualityMonitor) error {
+		m.gossip = gossip
+		return nil
+	}
+}
+
+// WithReqRespStats sets the source of the req/resp reliability of the peers
+func WithReqRespStats(reqResp reqRespStats) QualityMonitorOption {
+	return func(m *QualityMonitor) error {
+		m.reqResp = reqResp
+		return nil
+	}
+}
+
+// WithQualityWeights sets the weights of the components of the quality score
+func WithQualityWeights(weights QualityWeights) QualityMonitorOption {
+	return func(m *QualityMonitor) error {
+		if weights.GossipScore+weights.Duplicates+weights.Invalid+weights.ReqResp <= 0 {
+			return errors.New("all the quality weights are zero")
+		}
+		m.weights = weights
+		return nil
+	}
+}
+
+// WithQualityInterval sets how often the quality scores are refreshed
+func WithQualityInterval(interval time.Duration) QualityMonitorOption {
+	return func(m *QualityMonitor) error {
+		if interval <= 0 {
+			return errors.Errorf("invalid quality refresh interval %s", interval)
+		}
+		m.interval = interval
+		return nil
+	}
+}
+
+type ReputationMonitorOption func(*ReputationMonitor) error
+
+// WithReputationWeights sets the weights of the components of the reputation
+func WithReputationWeights(weights ReputationWeights) ReputationMonitorOption {
+	return func(m *ReputationMonitor) error {
+		if weights.Dials+weights.Identify+weights.Gossip+weights.Invalid <= 0 {
+			return errors.New("all the reputation weights are zero")
+		}
+		m.weights = weights
+		return nil
+	}
+}
+
+// WithReputationInterval sets how often the reputation of the peers is refreshed
+func WithReputationInterval(interval time.Duration) ReputationMonitorOption {
+	return func(m *ReputationMonitor) error {
+		if interval <= 0 {
+			return errors.Errorf("invalid reputation refresh interval %s", interval)
+		}
+		m.interval = interval
+		return nil
+	}
+}
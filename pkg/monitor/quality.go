@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	"github.com/migalabs/armiarma/pkg/hosts"
+)
+
+var (
+	DefaultQualityInterval = 10 * time.Minute
+	DefaultQualityWeights  = QualityWeights{
+		GossipScore: 0.4,
+		Duplicates:  0.2,
+		Invalid:     0.2,
+		ReqResp:     0.2,
+	}
+
+	// gossipsub score at which the gossip component of the quality drops to 1/e
+	gossipScoreScale = 10.0
+)
+
+type gossipStats interface {
+	PeerGossipStats() map[peer.ID]gossipsub.PeerGossipStats
+}
+
+type reqRespStats interface {
+	ReqRespStats() map[peer.ID]hosts.ReqRespStats
+}
+
+// QualityWeights defines how much each of the components (normalized between 0 and 1) weights in the quality score
+type QualityWeights struct {
+	GossipScore float64 // exp(score/10), capped at 1 for non-negative scores
+	Duplicates  float64 // 1 - duplicated messages / received messages
+	Invalid     float64 // 1 / (1 + invalid messages)
+	ReqResp     float64 // answered requests / sent requests
+}
+
+// ParseQualityWeights reads the weights from the "score=0.4,duplicates=0.2,invalid=0.2,reqresp=0.2" format,
+// the components that aren't given weight zero
+func ParseQualityWeights(raw string) (QualityWeights, error) {
+	weights := QualityWeights{}
+	for _, item := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			return weights, errors.Errorf("invalid quality weight %s", item)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || w < 0 {
+			return weights, errors.Errorf("invalid quality weight %s", item)
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "score":
+			weights.GossipScore = w
+		case "duplicates":
+			weights.Duplicates = w
+		case "invalid":
+			weights.Invalid = w
+		case "reqresp":
+			weights.ReqResp = w
+		default:
+			return weights, errors.Errorf("unknown quality component %s", kv[0])
+		}
+	}
+	if weights.GossipScore+weights.Duplicates+weights.Invalid+weights.ReqResp == 0 {
+		return weights, errors.New("all the quality weights are zero")
+	}
+	return weights, nil
+}
+
+// Score combines the measured components of the peer, renormalizing the weights over the ones available.
+// It returns false if none of the weighted components could be measured
+func (w QualityWeights) Score(q *models.PeerQuality) (float64, bool) {
+	var total, weights float64
+	add := func(weight, value float64) {
+		total += weight * value
+		weights += weight
+	}
+	if q.GossipScore != nil {
+		add(w.GossipScore, math.Min(1, math.Exp(*q.GossipScore/gossipScoreScale)))
+	}
+	if q.DuplicateRatio != nil {
+		add(w.Duplicates, 1-*q.DuplicateRatio)
+	}
+	if q.InvalidMsgs != nil {
+		add(w.Invalid, 1/(1+float64(*q.InvalidMsgs)))
+	}
+	if q.ReqRespReliability != nil {
+		add(w.ReqResp, *q.ReqRespReliability)
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return total / weights, true
+}
+
+// QualityMonitor periodically computes the quality score of the peers from their gossip
+// and req/resp behaviour, persisting the score of the peers whose behaviour changed
+type QualityMonitor struct {
+	ctx context.Context
+
+	db       persister
+	gossip   gossipStats
+	reqResp  reqRespStats
+	interval time.Duration
+	weights  QualityWeights
+
+	// stats used in the last refresh, to only update the peers that changed
+	lastGossip  map[peer.ID]gossipsub.PeerGossipStats
+	lastReqResp map[peer.ID]hosts.ReqRespStats
+
+	wg sync.WaitGroup
+}
+
+func NewQualityMonitor(ctx context.Context, db persister, opts ...QualityMonitorOption) (*QualityMonitor, error) {
+	m := &QualityMonitor{
+		ctx:         ctx,
+		db:          db,
+		interval:    DefaultQualityInterval,
+		weights:     DefaultQualityWeights,
+		lastGossip:  make(map[peer.ID]gossipsub.PeerGossipStats),
+		lastReqResp: make(map[peer.ID]hosts.ReqRespStats),
+	}
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply quality monitor option")
+		}
+	}
+	return m, nil
+}
+
+// Start spawns the routine that refreshes the quality scores every interval
+func (m *QualityMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				qualities := m.Refresh(time.Now())
+				for _, q := range qualities {
+					m.db.PersistToDB(q)
+				}
+				log.Debugf("refreshed the quality score of %d peers", len(qualities))
+			case <-m.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Refresh computes the quality of the peers whose stats changed since the last refresh
+func (m *QualityMonitor) Refresh(now time.Time) []*models.PeerQuality {
+	gossip := make(map[peer.ID]gossipsub.PeerGossipStats)
+	if m.gossip != nil {
+		gossip = m.gossip.PeerGossipStats()
+	}
+	reqResp := make(map[peer.ID]hosts.ReqRespStats)
+	if m.reqResp != nil {
+		reqResp = m.reqResp.ReqRespStats()
+	}
+
+	changed := make(map[peer.ID]struct{})
+	for p, s := range gossip {
+		if last, ok := m.lastGossip[p]; !ok || last != s {
+			changed[p] = struct{}{}
+		}
+	}
+	for p, s := range reqResp {
+		if last, ok := m.lastReqResp[p]; !ok || last != s {
+			changed[p] = struct{}{}
+		}
+	}
+	m.lastGossip = gossip
+	m.lastReqResp = reqResp
+
+	qualities := make([]*models.PeerQuality, 0, len(changed))
+	for p := range changed {
+		q := &models.PeerQuality{
+			PeerID:    p,
+			Timestamp: now,
+		}
+		if s, ok := gossip[p]; ok {
+			if s.HasScore {
+				score := s.Score
+				q.GossipScore = &score
+			}
+			if received := s.Delivered + s.Duplicates; received > 0 {
+				ratio := float64(s.Duplicates) / float64(received)
+				q.DuplicateRatio = &ratio
+			}
+			invalid := s.Invalid
+			q.InvalidMsgs = &invalid
+		}
+		if s, ok := reqResp[p]; ok && s.Requests > 0 {
+			reliability := s.Reliability()
+			q.ReqRespReliability = &reliability
+		}
+		score, ok := m.weights.Score(q)
+		if !ok {
+			continue
+		}
+		q.Score = score
+		qualities = append(qualities, q)
+	}
+	return qualities
+}
+
+// Stop waits until the monitor routine finishes (it dies with the context)
+func (m *QualityMonitor) Stop() {
+	m.wg.Wait()
+}
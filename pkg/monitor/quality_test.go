@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	"github.com/migalabs/armiarma/pkg/hosts"
+)
+
+type mockQualitySources struct {
+	gossip  map[peer.ID]gossipsub.PeerGossipStats
+	reqResp map[peer.ID]hosts.ReqRespStats
+}
+
+func (s *mockQualitySources) PeerGossipStats() map[peer.ID]gossipsub.PeerGossipStats {
+	return s.gossip
+}
+
+func (s *mockQualitySources) ReqRespStats() map[peer.ID]hosts.ReqRespStats {
+	return s.reqResp
+}
+
+func Test_ParseQualityWeights(t *testing.T) {
+	weights, err := ParseQualityWeights("score=0.5, reqresp=0.5")
+	require.NoError(t, err)
+	require.Equal(t, QualityWeights{GossipScore: 0.5, ReqResp: 0.5}, weights)
+
+	_, err = ParseQualityWeights("score=0,reqresp=0")
+	require.Error(t, err)
+	_, err = ParseQualityWeights("latency=1")
+	require.Error(t, err)
+	_, err = ParseQualityWeights("score=-1")
+	require.Error(t, err)
+}
+
+func Test_QualityRefresh(t *testing.T) {
+	good := peer.ID("good")
+	bad := peer.ID("bad")
+	sources := &mockQualitySources{
+		gossip: map[peer.ID]gossipsub.PeerGossipStats{
+			good: {Score: 2, HasScore: true, Delivered: 10},
+			bad:  {Score: -10, HasScore: true, Delivered: 1, Duplicates: 9, Invalid: 3},
+		},
+		reqResp: map[peer.ID]hosts.ReqRespStats{
+			good: {Requests: 2, Responses: 2},
+		},
+	}
+	m, err := NewQualityMonitor(
+		context.Background(),
+		nil,
+		WithGossipStats(sources),
+		WithReqRespStats(sources),
+	)
+	require.NoError(t, err)
+
+	qualities := m.Refresh(time.Now())
+	require.Len(t, qualities, 2)
+	scores := make(map[peer.ID]float64)
+	for _, q := range qualities {
+		scores[q.PeerID] = q.Score
+	}
+	require.InDelta(t, 1.0, scores[good], 1e-9)
+	require.Less(t, scores[bad], 0.5)
+
+	// only the peers whose stats changed get refreshed
+	sources.reqResp = map[peer.ID]hosts.ReqRespStats{
+		good: {Requests: 2, Responses: 2},
+		bad:  {Requests: 2},
+	}
+	qualities = m.Refresh(time.Now())
+	require.Len(t, qualities, 1)
+	require.Equal(t, bad, qualities[0].PeerID)
+	require.Equal(t, 0.0, *qualities[0].ReqRespReliability)
+}
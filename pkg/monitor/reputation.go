@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	DefaultReputationInterval = 30 * time.Minute
+	DefaultReputationWeights  = ReputationWeights{
+		Dials:    0.3,
+		Identify: 0.2,
+		Gossip:   0.3,
+		Invalid:  0.2,
+	}
+)
+
+type reputationDB interface {
+	GetReputationInputs() (map[peer.ID]*models.ReputationInput, error)
+	PersistToDB(interface{})
+}
+
+// ReputationWeights defines how much each of the components (normalized between 0 and 1) weights in the reputation
+type ReputationWeights struct {
+	Dials    float64 // successful dials / dials (laplace smoothed)
+	Identify float64 // identified connections / connections (laplace smoothed)
+	Gossip   float64 // exp(score/10), capped at 1 for non-negative scores
+	Invalid  float64 // 1 / (1 + invalid messages)
+}
+
+// ParseReputationWeights reads the weights from the "dials=0.3,identify=0.2,gossip=0.3,invalid=0.2" format,
+// the components that aren't given weight zero
+func ParseReputationWeights(raw string) (ReputationWeights, error) {
+	weights := ReputationWeights{}
+	for _, item := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			return weights, errors.Errorf("invalid reputation weight %s", item)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || w < 0 {
+			return weights, errors.Errorf("invalid reputation weight %s", item)
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "dials":
+			weights.Dials = w
+		case "identify":
+			weights.Identify = w
+		case "gossip":
+			weights.Gossip = w
+		case "invalid":
+			weights.Invalid = w
+		default:
+			return weights, errors.Errorf("unknown reputation component %s", kv[0])
+		}
+	}
+	if weights.Dials+weights.Identify+weights.Gossip+weights.Invalid == 0 {
+		return weights, errors.New("all the reputation weights are zero")
+	}
+	return weights, nil
+}
+
+// Reputation computes the components of the reputation of the peer and combines them, renormalizing
+// the weights over the ones available. It returns false if none of the weighted components could be measured
+func (w ReputationWeights) Reputation(input *models.ReputationInput, now time.Time) (*models.PeerReputation, bool) {
+	rep := &models.PeerReputation{
+		PeerID:    input.PeerID,
+		Timestamp: now,
+	}
+	var total, weights float64
+	add := func(weight, value float64) {
+		total += weight * value
+		weights += weight
+	}
+	if input.DialAttempts > 0 {
+		reliability := float64(input.DialSuccesses+1) / float64(input.DialAttempts+2)
+		rep.DialReliability = &reliability
+		add(w.Dials, reliability)
+	}
+	if input.Connections > 0 {
+		identify := float64(input.Identified+1) / float64(input.Connections+2)
+		rep.IdentifySuccess = &identify
+		add(w.Identify, identify)
+	}
+	if input.GossipScore != nil {
+		gossip := math.Min(1, math.Exp(*input.GossipScore/gossipScoreScale))
+		rep.GossipBehaviour = &gossip
+		add(w.Gossip, gossip)
+	}
+	if input.InvalidMsgs != nil {
+		invalid := *input.InvalidMsgs
+		rep.InvalidMsgs = &invalid
+		add(w.Invalid, 1/(1+float64(invalid)))
+	}
+	if weights == 0 {
+		return nil, false
+	}
+	rep.Score = total / weights
+	return rep, true
+}
+
+// ReputationMonitor periodically aggregates what the DB knows about the behaviour of the peers into
+// their reputation, persisting the reputation of the peers that changed to keep its history. The
+// priority peering strategy reads the latest reputation to deprioritize the dials of the bad peers
+type ReputationMonitor struct {
+	ctx context.Context
+
+	db       reputationDB
+	interval time.Duration
+	weights  ReputationWeights
+
+	// scores of the last refresh, to only persist the peers that changed
+	lastScores map[peer.ID]float64
+
+	wg sync.WaitGroup
+}
+
+func NewReputationMonitor(ctx context.Context, db reputationDB, opts ...ReputationMonitorOption) (*ReputationMonitor, error) {
+	m := &ReputationMonitor{
+		ctx:        ctx,
+		db:         db,
+		interval:   DefaultReputationInterval,
+		weights:    DefaultReputationWeights,
+		lastScores: make(map[peer.ID]float64),
+	}
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply reputation monitor option")
+		}
+	}
+	return m, nil
+}
+
+// Start spawns the routine that refreshes the reputation of the peers every interval
+func (m *ReputationMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reputations, err := m.Refresh(time.Now())
+				if err != nil {
+					log.Error(err)
+					continue
+				}
+				for _, rep := range reputations {
+					m.db.PersistToDB(rep)
+				}
+				log.Debugf("refreshed the reputation of %d peers", len(reputations))
+			case <-m.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Refresh computes the reputation of the peers whose score changed since the last refresh
+func (m *ReputationMonitor) Refresh(now time.Time) ([]*models.PeerReputation, error) {
+	inputs, err := m.db.GetReputationInputs()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the reputation inputs")
+	}
+	reputations := make([]*models.PeerReputation, 0)
+	scores := make(map[peer.ID]float64, len(inputs))
+	for p, input := range inputs {
+		rep, ok := m.weights.Reputation(input, now)
+		if !ok {
+			continue
+		}
+		scores[p] = rep.Score
+		if last, ok := m.lastScores[p]; ok && last == rep.Score {
+			continue
+		}
+		reputations = append(reputations, rep)
+	}
+	m.lastScores = scores
+	return reputations, nil
+}
+
+// Stop waits until the monitor routine finishes (it dies with the context)
+func (m *ReputationMonitor) Stop() {
+	m.wg.Wait()
+}
@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+type mockReputationDB struct {
+	inputs map[peer.ID]*models.ReputationInput
+}
+
+func (d *mockReputationDB) GetReputationInputs() (map[peer.ID]*models.ReputationInput, error) {
+	return d.inputs, nil
+}
+
+func (d *mockReputationDB) PersistToDB(interface{}) {}
+
+func Test_ParseReputationWeights(t *testing.T) {
+	weights, err := ParseReputationWeights("dials=0.5, invalid=0.5")
+	require.NoError(t, err)
+	require.Equal(t, ReputationWeights{Dials: 0.5, Invalid: 0.5}, weights)
+
+	_, err = ParseReputationWeights("dials=0,gossip=0")
+	require.Error(t, err)
+	_, err = ParseReputationWeights("latency=1")
+	require.Error(t, err)
+	_, err = ParseReputationWeights("gossip=-1")
+	require.Error(t, err)
+}
+
+func Test_ReputationRefresh(t *testing.T) {
+	good := peer.ID("good")
+	bad := peer.ID("bad")
+	unknown := peer.ID("unknown")
+	goodScore, badScore := 2.0, -20.0
+	noInvalid, invalid := int64(0), int64(9)
+	db := &mockReputationDB{
+		inputs: map[peer.ID]*models.ReputationInput{
+			good:    {PeerID: good, DialAttempts: 98, DialSuccesses: 98, Connections: 98, Identified: 98, GossipScore: &goodScore, InvalidMsgs: &noInvalid},
+			bad:     {PeerID: bad, DialAttempts: 8, DialSuccesses: 1, Connections: 1, GossipScore: &badScore, InvalidMsgs: &invalid},
+			unknown: {PeerID: unknown},
+		},
+	}
+	m, err := NewReputationMonitor(context.Background(), db)
+	require.NoError(t, err)
+
+	reputations, err := m.Refresh(time.Now())
+	require.NoError(t, err)
+	// the peers without any measured component get no reputation
+	require.Len(t, reputations, 2)
+	scores := make(map[peer.ID]*models.PeerReputation)
+	for _, rep := range reputations {
+		scores[rep.PeerID] = rep
+	}
+	require.InDelta(t, 0.99, scores[good].Score, 0.01)
+	require.Less(t, scores[bad].Score, 0.3)
+	require.InDelta(t, 0.2, *scores[bad].DialReliability, 1e-9)
+	require.Equal(t, int64(9), *scores[bad].InvalidMsgs)
+
+	// only the peers whose reputation changed are refreshed
+	db.inputs[bad].DialSuccesses = 2
+	reputations, err = m.Refresh(time.Now())
+	require.NoError(t, err)
+	require.Len(t, reputations, 1)
+	require.Equal(t, bad, reputations[0].PeerID)
+
+	// the missing components don't weight
+	rep, ok := ReputationWeights{Dials: 1, Gossip: 1}.Reputation(&models.ReputationInput{DialAttempts: 2, DialSuccesses: 2}, time.Now())
+	require.True(t, ok)
+	require.InDelta(t, 0.75, rep.Score, 1e-9)
+	require.Nil(t, rep.GossipBehaviour)
+}
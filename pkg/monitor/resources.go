@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	DefaultResourceUsageInterval = 1 * time.Minute
+)
+
+type resourceHost interface {
+	BandwidthTotals() (in int64, out int64)
+	OpenConnections() int
+}
+
+type persister interface {
+	PersistToDB(interface{})
+}
+
+// ResourceMonitor periodically records the resources that the crawler uses during a run
+// (CPU, memory, bandwidth, and open connections), so that the cost of the observation is tracked
+type ResourceMonitor struct {
+	ctx context.Context
+
+	runID    int
+	host     resourceHost
+	db       persister
+	interval time.Duration
+
+	wg    sync.WaitGroup
+	doneC chan struct{}
+}
+
+func NewResourceMonitor(
+	ctx context.Context,
+	runID int,
+	h resourceHost,
+	db persister,
+	interval time.Duration) (*ResourceMonitor, error) {
+
+	if interval <= 0 {
+		return nil, errors.Errorf("invalid resource usage interval %s", interval)
+	}
+	return &ResourceMonitor{
+		ctx:      ctx,
+		runID:    runID,
+		host:     h,
+		db:       db,
+		interval: interval,
+		doneC:    make(chan struct{}),
+	}, nil
+}
+
+// Start spawns the routine that records the resource usage every interval
+func (m *ResourceMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.db.PersistToDB(m.Snapshot())
+			case <-m.doneC:
+				log.Info("shutdown detected in resource monitor, recording last usage")
+				m.db.PersistToDB(m.Snapshot())
+				return
+			case <-m.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Snapshot composes the current resource usage of the crawler
+func (m *ResourceMonitor) Snapshot() *models.ResourceUsage {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	bytesIn, bytesOut := m.host.BandwidthTotals()
+
+	return &models.ResourceUsage{
+		RunID:          m.runID,
+		Timestamp:      time.Now(),
+		CpuSeconds:     processCpuSeconds(),
+		MemSysBytes:    memStats.Sys,
+		HeapAllocBytes: memStats.HeapAlloc,
+		Goroutines:     runtime.NumGoroutine(),
+		BytesIn:        bytesIn,
+		BytesOut:       bytesOut,
+		OpenConns:      m.host.OpenConnections(),
+	}
+}
+
+// Stop records a last snapshot and stops the monitor
+func (m *ResourceMonitor) Stop() {
+	close(m.doneC)
+	m.wg.Wait()
+}
+
+// processCpuSeconds returns the CPU time (user + system) used by the process, as accounted by the kernel
+func processCpuSeconds() float64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		log.WithError(err).Warn("unable to read the cpu usage of the process")
+		return 0
+	}
+	return timevalSeconds(usage.Utime) + timevalSeconds(usage.Stime)
+}
+
+func timevalSeconds(tv syscall.Timeval) float64 {
+	return float64(tv.Sec) + float64(tv.Usec)/1e6
+}
@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+type testResourceHost struct {
+	in, out int64
+	conns   int
+}
+
+func (h *testResourceHost) BandwidthTotals() (int64, int64) { return h.in, h.out }
+func (h *testResourceHost) OpenConnections() int            { return h.conns }
+
+type testPersister struct {
+	m     sync.Mutex
+	items []interface{}
+}
+
+func (p *testPersister) PersistToDB(i interface{}) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.items = append(p.items, i)
+}
+
+func Test_ResourceSnapshot(t *testing.T) {
+	host := &testResourceHost{in: 1024, out: 512, conns: 7}
+	db := &testPersister{}
+	monitor, err := NewResourceMonitor(context.Background(), 3, host, db, time.Hour)
+	require.NoError(t, err)
+
+	first := monitor.Snapshot()
+	require.Equal(t, 3, first.RunID)
+	require.Equal(t, int64(1024), first.BytesIn)
+	require.Equal(t, int64(512), first.BytesOut)
+	require.Equal(t, 7, first.OpenConns)
+	require.NotZero(t, first.MemSysBytes)
+	require.NotZero(t, first.HeapAllocBytes)
+	require.Positive(t, first.Goroutines)
+
+	// burn some CPU so that the accounted CPU time grows
+	deadline := time.Now().Add(50 * time.Millisecond)
+	x := 0
+	for time.Now().Before(deadline) {
+		x++
+	}
+	require.Positive(t, x)
+	second := monitor.Snapshot()
+	require.Greater(t, second.CpuSeconds, first.CpuSeconds)
+	// the process can't use more CPU time than the wall time of all the CPUs
+	require.LessOrEqual(t, second.CpuSeconds-first.CpuSeconds, time.Since(first.Timestamp).Seconds()*float64(runtime.NumCPU()))
+
+	// stopping the monitor records a last snapshot
+	monitor.Start()
+	monitor.Stop()
+	require.Len(t, db.items, 1)
+	require.IsType(t, &models.ResourceUsage{}, db.items[0])
+
+	_, err = NewResourceMonitor(context.Background(), 3, host, db, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/peering"
+)
+
+type snapshotSource interface {
+	GetRoundTotals() (total, active, deprecated int, err error)
+	GetClientDistribution() (map[string]interface{}, error)
+	GetGeoDistribution() (map[string]interface{}, error)
+}
+
+// RoundSnapshotter writes a compact snapshot of the network (totals, client, geo, and error shares)
+// at the end of each crawl round, so that dashboards can plot its evolution from a single small table
+type RoundSnapshotter struct {
+	ctx context.Context
+
+	runID  int
+	rounds <-chan peering.RoundStats
+	source snapshotSource
+	db     persister
+
+	wg    sync.WaitGroup
+	doneC chan struct{}
+}
+
+func NewRoundSnapshotter(ctx context.Context, runID int, rounds <-chan peering.RoundStats, source snapshotSource, db persister) *RoundSnapshotter {
+	return &RoundSnapshotter{
+		ctx:    ctx,
+		runID:  runID,
+		rounds: rounds,
+		source: source,
+		db:     db,
+		doneC:  make(chan struct{}),
+	}
+}
+
+// Start spawns the routine that snapshots the network after each round
+func (s *RoundSnapshotter) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case round, ok := <-s.rounds:
+				if !ok {
+					return
+				}
+				snapshot, err := s.Snapshot(round)
+				if err != nil {
+					log.WithError(err).Warnf("unable to snapshot round %d", round.Round)
+					continue
+				}
+				s.db.PersistToDB(snapshot)
+			case <-s.doneC:
+				return
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Snapshot composes the snapshot of the network at the end of the given round
+func (s *RoundSnapshotter) Snapshot(round peering.RoundStats) (*models.RoundSnapshot, error) {
+	total, active, deprecated, err := s.source.GetRoundTotals()
+	if err != nil {
+		return nil, err
+	}
+	clients, err := s.source.GetClientDistribution()
+	if err != nil {
+		return nil, err
+	}
+	countries, err := s.source.GetGeoDistribution()
+	if err != nil {
+		return nil, err
+	}
+	connErrors := make(map[string]interface{}, len(round.ConnErrors))
+	for connErr, cnt := range round.ConnErrors {
+		connErrors[connErr] = int(cnt)
+	}
+
+	return &models.RoundSnapshot{
+		RunID:           s.runID,
+		Round:           round.Round,
+		Start:           round.Start,
+		End:             round.End,
+		AttemptedPeers:  round.Attempted,
+		TotalPeers:      total,
+		ActivePeers:     active,
+		DeprecatedPeers: deprecated,
+		ClientShares:    shares(clients),
+		GeoShares:       shares(countries),
+		ErrorShares:     shares(connErrors),
+	}, nil
+}
+
+func (s *RoundSnapshotter) Stop() {
+	close(s.doneC)
+	s.wg.Wait()
+}
+
+// shares normalizes the counts of a distribution into shares (0-1) of its total
+func shares(dist map[string]interface{}) map[string]float64 {
+	total := 0
+	for _, cnt := range dist {
+		if c, ok := cnt.(int); ok {
+			total += c
+		}
+	}
+	shares := make(map[string]float64, len(dist))
+	if total == 0 {
+		return shares
+	}
+	for key, cnt := range dist {
+		if c, ok := cnt.(int); ok {
+			shares[key] = float64(c) / float64(total)
+		}
+	}
+	return shares
+}
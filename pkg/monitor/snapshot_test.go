@@ -0,0 +1,47 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/peering"
+)
+
+type mockSnapshotSource struct{}
+
+func (s *mockSnapshotSource) GetRoundTotals() (int, int, int, error) {
+	return 10, 4, 3, nil
+}
+
+func (s *mockSnapshotSource) GetClientDistribution() (map[string]interface{}, error) {
+	return map[string]interface{}{"lighthouse": 3, "prysm": 1}, nil
+}
+
+func (s *mockSnapshotSource) GetGeoDistribution() (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func Test_RoundSnapshot(t *testing.T) {
+	s := NewRoundSnapshotter(context.Background(), 7, nil, &mockSnapshotSource{}, nil)
+	start := time.Now()
+	snapshot, err := s.Snapshot(peering.RoundStats{
+		Round:      2,
+		Start:      start,
+		End:        start.Add(time.Minute),
+		Attempted:  5,
+		ConnErrors: map[string]int64{"none": 4, "timeout": 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 7, snapshot.RunID)
+	require.Equal(t, int64(2), snapshot.Round)
+	require.Equal(t, 5, snapshot.AttemptedPeers)
+	require.Equal(t, 10, snapshot.TotalPeers)
+	require.Equal(t, 4, snapshot.ActivePeers)
+	require.Equal(t, 3, snapshot.DeprecatedPeers)
+	require.Equal(t, map[string]float64{"lighthouse": 0.75, "prysm": 0.25}, snapshot.ClientShares)
+	require.Empty(t, snapshot.GeoShares)
+	require.Equal(t, map[string]float64{"none": 0.8, "timeout": 0.2}, snapshot.ErrorShares)
+}
@@ -83,7 +83,8 @@ func (en *LocalEthereumNode) ServeBeaconMetadata(h host.Host) {
 				_ = handler.WriteErrorChunk(reqresp.InvalidReqCode, "could not parse status request")
 				log.Tracef("failed to read metadata request: %v from %s", err, peerId.String())
 			} else {
-				if err := handler.WriteResponseChunk(reqresp.SuccessCode, &en.LocalMetadata); err != nil {
+				localMetadata := en.Metadata()
+				if err := handler.WriteResponseChunk(reqresp.SuccessCode, &localMetadata); err != nil {
 					log.Tracef("failed to respond to metadata request: %v", err)
 				} else {
 					log.Tracef("handled metadata request")
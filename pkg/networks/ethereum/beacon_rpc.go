@@ -2,15 +2,64 @@ package ethereum
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/migalabs/armiarma/pkg/networks/ethereum/rpc/methods"
 	"github.com/migalabs/armiarma/pkg/networks/ethereum/rpc/reqresp"
+	"github.com/pkg/errors"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	log "github.com/sirupsen/logrus"
 )
 
+// ReqBeaconPing opens a new Stream from the given host to send a Ping RPC to the given peer.ID.
+// Returns the seq number of the metadata of the peer and the RTT of the request if succeed, error if failed.
+func (en *LocalEthereumNode) ReqBeaconPing(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	h host.Host,
+	peerID peer.ID,
+	result *common.Ping,
+	rtt *time.Duration,
+	finErr *error) {
+
+	defer wg.Done()
+	// declare the result obj of the RPC call
+	var remotePing common.Ping
+	localPing := common.Ping(en.Metadata().SeqNumber)
+
+	t := time.Now()
+	var resCode reqresp.ResponseCode // error by default
+	err := methods.PingRPCv1NoSnappy.RunRequest(ctx, h.NewStream, peerID, new(reqresp.SnappyCompression),
+		reqresp.RequestSSZInput{Obj: &localPing}, 1,
+		func() error {
+			return nil
+		},
+		func(chunk reqresp.ChunkedResponseHandler) error {
+			resCode = chunk.ResultCode()
+			switch resCode {
+			case reqresp.ServerErrCode, reqresp.InvalidReqCode:
+				msg, err := chunk.ReadErrMsg()
+				if err != nil {
+					return errors.Wrap(err, msg)
+				}
+				return errors.Errorf("error reqresping Ping RPC: %s", msg)
+			case reqresp.SuccessCode:
+				if err := chunk.ReadObj(&remotePing); err != nil {
+					return errors.Wrap(err, "from reqresping Ping RPC")
+				}
+			default:
+				return errors.New("unexpected result code for Ping RPC reqresp")
+			}
+			return nil
+		})
+	*finErr = err
+	*result = remotePing
+	*rtt = time.Since(t)
+}
+
 func (en *LocalEthereumNode) ServeBeaconPing(h host.Host) {
 	go func() {
 		sCtxFn := func() context.Context {
@@ -25,10 +74,12 @@ func (en *LocalEthereumNode) ServeBeaconPing(h host.Host) {
 				_ = handler.WriteErrorChunk(reqresp.InvalidReqCode, "could not parse ping request")
 				log.Tracef("failed to read ping request: %v from %s", err, peerId.String())
 			} else {
-				if err := handler.WriteResponseChunk(reqresp.SuccessCode, &ping); err != nil {
+				// the ping is answered with the seq number of our metadata
+				localPing := common.Ping(en.Metadata().SeqNumber)
+				if err := handler.WriteResponseChunk(reqresp.SuccessCode, &localPing); err != nil {
 					log.Tracef("failed to respond to ping request: %v", err)
 				} else {
-					log.Tracef("handled ping request", ping)
+					log.Tracef("handled ping request %v", ping)
 				}
 			}
 		}
@@ -56,10 +107,11 @@ func (en *LocalEthereumNode) ServeBeaconGoodbye(h host.Host) {
 				_ = handler.WriteErrorChunk(reqresp.InvalidReqCode, "could not parse goodbye request")
 				log.Tracef("failed to read goodbye request: %v from %s", err, peerId.String())
 			} else {
+				en.notifyGoodbye(peerId, uint64(goodbye))
 				if err := handler.WriteResponseChunk(reqresp.SuccessCode, &goodbye); err != nil {
 					log.Tracef("failed to respond to goodbye request: %v", err)
 				} else {
-					log.Tracef("handled goodbye request", goodbye)
+					log.Tracef("handled goodbye request %v", goodbye)
 				}
 			}
 		}
@@ -72,3 +124,30 @@ func (en *LocalEthereumNode) ServeBeaconGoodbye(h host.Host) {
 		log.Info("Stopped serving goodbye")
 	}()
 }
+
+// OnGoodbye registers a function that gets called with the reason of each goodbye that a peer sends us
+func (en *LocalEthereumNode) OnGoodbye(fn func(peer.ID, uint64)) {
+	en.goodbyeM.Lock()
+	defer en.goodbyeM.Unlock()
+	en.goodbyeHandlers = append(en.goodbyeHandlers, fn)
+}
+
+func (en *LocalEthereumNode) notifyGoodbye(p peer.ID, reason uint64) {
+	en.goodbyeM.Lock()
+	handlers := en.goodbyeHandlers
+	en.goodbyeM.Unlock()
+	for _, fn := range handlers {
+		fn(p, reason)
+	}
+}
+
+// BeaconPing sends a Ping RPC to the given peer.ID from the given host, returning the RTT of the request
+func (en *LocalEthereumNode) BeaconPing(ctx context.Context, h host.Host, peerID peer.ID) (time.Duration, error) {
+	var wg sync.WaitGroup
+	var result common.Ping
+	var rtt time.Duration
+	var err error
+	wg.Add(1)
+	en.ReqBeaconPing(ctx, &wg, h, peerID, &result, &rtt, &err)
+	return rtt, err
+}
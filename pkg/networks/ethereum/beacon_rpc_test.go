@@ -0,0 +1,63 @@
+package ethereum
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func newTestBeaconNode(ctx context.Context, t *testing.T, seq common.SeqNr) (*LocalEthereumNode, host.Host) {
+	key, err := utils.GenerateECDSAPrivKey()
+	require.NoError(t, err)
+	privKey, err := utils.AdaptSecp256k1FromECDSA(key)
+	require.NoError(t, err)
+	metadata := ComposeQuickBeaconMetaData()
+	metadata.SeqNumber = seq
+	node := NewLocalEthereumNode(ctx, key, ComposeQuickBeaconStatus(DefaultForkDigest), metadata, DefaultForkDigest)
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"), libp2p.Identity(privKey))
+	require.NoError(t, err)
+	t.Cleanup(func() { h.Close() })
+	return node, h
+}
+
+func reqTestPing(ctx context.Context, node *LocalEthereumNode, h host.Host, remote peer.ID) (common.Ping, time.Duration, error) {
+	var wg sync.WaitGroup
+	var ping common.Ping
+	var rtt time.Duration
+	var err error
+	wg.Add(1)
+	node.ReqBeaconPing(ctx, &wg, h, remote, &ping, &rtt, &err)
+	wg.Wait()
+	return ping, rtt, err
+}
+
+// the pings are answered with the seq number of the metadata of the remote node, not echoed back
+func Test_BeaconPing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	local, localHost := newTestBeaconNode(ctx, t, 3)
+	remote, remoteHost := newTestBeaconNode(ctx, t, 7)
+	remote.ServeBeaconPing(remoteHost)
+	require.NoError(t, localHost.Connect(ctx, peer.AddrInfo{ID: remoteHost.ID(), Addrs: remoteHost.Addrs()}))
+
+	ping, rtt, err := reqTestPing(ctx, local, localHost, remoteHost.ID())
+	require.NoError(t, err)
+	require.Equal(t, common.Ping(7), ping)
+	require.Greater(t, rtt, time.Duration(0))
+
+	// a peer that doesn't serve the pings fails the request
+	_, silentHost := newTestBeaconNode(ctx, t, 1)
+	require.NoError(t, localHost.Connect(ctx, peer.AddrInfo{ID: silentHost.ID(), Addrs: silentHost.Addrs()}))
+	_, _, err = reqTestPing(ctx, local, localHost, silentHost.ID())
+	require.Error(t, err)
+}
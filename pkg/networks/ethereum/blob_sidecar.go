@@ -0,0 +1,69 @@
+package ethereum
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/codec"
+)
+
+// sizes of the fields of the Deneb BlobSidecar, which isn't part of zrnt yet
+const (
+	BlobSize                         = 4096 * 32 // field elements per blob * bytes per field element
+	KZGProofSize                     = 48
+	SignedBlockHeaderSize            = 112 + 96 // header + signature
+	KZGCommitmentInclusionProofDepth = 17
+	BlobSidecarSize                  = 8 + BlobSize + common.KZGCommitmentSize + KZGProofSize +
+		SignedBlockHeaderSize + KZGCommitmentInclusionProofDepth*32
+)
+
+var blobSubnetTopicRe = regexp.MustCompile(`blob_sidecar_([0-9]+)`)
+
+// BlobSidecar keeps the fields of a gossiped blob sidecar that identify it, the blob itself and
+// the proofs are skipped
+type BlobSidecar struct {
+	Index         uint64
+	KZGCommitment common.KZGCommitment
+	Header        common.SignedBeaconBlockHeader
+}
+
+// decodeBlobSidecar reads the SSZ encoded BlobSidecar (all of its fields have a fixed size)
+func decodeBlobSidecar(dr *codec.DecodingReader) (*BlobSidecar, error) {
+	if dr.Scope() != BlobSidecarSize {
+		return nil, errors.Errorf("invalid blob sidecar size %d (expected %d)", dr.Scope(), BlobSidecarSize)
+	}
+	blob := new(BlobSidecar)
+	index, err := dr.ReadUint64()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read blob index")
+	}
+	blob.Index = index
+	if _, err := dr.Skip(BlobSize); err != nil {
+		return nil, errors.Wrap(err, "unable to skip blob")
+	}
+	if err := blob.KZGCommitment.Deserialize(dr); err != nil {
+		return nil, errors.Wrap(err, "unable to read blob kzg commitment")
+	}
+	if _, err := dr.Skip(KZGProofSize); err != nil {
+		return nil, errors.Wrap(err, "unable to skip blob kzg proof")
+	}
+	if err := blob.Header.Deserialize(dr); err != nil {
+		return nil, errors.Wrap(err, "unable to read blob block header")
+	}
+	return blob, nil
+}
+
+// GetBlobSubnetFromTopic returns the subnet of the given blob_sidecar topic
+func GetBlobSubnetFromTopic(topic string) (int, error) {
+	match := blobSubnetTopicRe.FindStringSubmatch(topic)
+	if len(match) < 2 {
+		return -1, ErrorNoSubnet
+	}
+	subnet, err := strconv.Atoi(match[1])
+	if err != nil {
+		return -1, errors.Wrap(err, "unable to conver subnet to int")
+	}
+	return subnet, nil
+}
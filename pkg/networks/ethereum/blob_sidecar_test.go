@@ -0,0 +1,100 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeTestBlobSidecar returns the SSZ encoding of a blob sidecar, with an empty blob and proofs
+func encodeTestBlobSidecar(t *testing.T, index uint64, commitment common.KZGCommitment, header *common.SignedBeaconBlockHeader) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, index))
+	buf.Write(make([]byte, BlobSize))
+	buf.Write(commitment[:])
+	buf.Write(make([]byte, KZGProofSize))
+	require.NoError(t, header.Serialize(codec.NewEncodingWriter(&buf)))
+	buf.Write(make([]byte, KZGCommitmentInclusionProofDepth*32))
+	require.Equal(t, BlobSidecarSize, buf.Len())
+	return buf.Bytes()
+}
+
+func Test_GetBlobSubnetFromTopic(t *testing.T) {
+	tests := []struct {
+		name   string
+		topic  string
+		subnet int
+		valid  bool
+	}{
+		{"first subnet", "/eth2/6a95a1a9/blob_sidecar_0/ssz_snappy", 0, true},
+		{"last subnet", "/eth2/6a95a1a9/blob_sidecar_5/ssz_snappy", 5, true},
+		{"message type", "blob_sidecar_3", 3, true},
+		{"other topic", "/eth2/6a95a1a9/beacon_attestation_3/ssz_snappy", -1, false},
+		{"no subnet", "/eth2/6a95a1a9/blob_sidecar_/ssz_snappy", -1, false},
+	}
+
+	for _, test := range tests {
+		subnet, err := GetBlobSubnetFromTopic(test.topic)
+		require.Equal(t, test.subnet, subnet, test.name)
+		if test.valid {
+			require.NoError(t, err, test.name)
+		} else {
+			require.Error(t, err, test.name)
+		}
+	}
+}
+
+func Test_BlobSidecarMessageHandler(t *testing.T) {
+	genesis := time.Unix(1606824023, 0)
+	handler, err := NewEthMessageHandler(genesis, nil)
+	require.NoError(t, err)
+
+	header := &common.SignedBeaconBlockHeader{
+		Message: common.BeaconBlockHeader{
+			Slot:          200,
+			ProposerIndex: 42,
+			BodyRoot:      common.Root{0x02},
+		},
+	}
+	commitment := common.KZGCommitment{0xaa, 0xbb}
+	data := encodeTestBlobSidecar(t, 2, commitment, header)
+
+	topic := ComposeTopic(ForkDigests[DenebKey], SubnetMessageType(BlobSidecarTopicBase, 2))
+	sender := peer.ID("sender")
+	msg := &pubsub.Message{
+		Message:      &pubsub_pb.Message{Topic: &topic, Data: snappy.Encode(nil, data)},
+		ID:           "msg-id",
+		ReceivedFrom: sender,
+		ArrivalTime:  genesis.Add(200*12*time.Second + 1500*time.Millisecond),
+	}
+
+	persistable, err := handler.BlobSidecarMessageHandler(msg)
+	require.NoError(t, err)
+	tracked, ok := persistable.(*TrackedBlobSidecar)
+	require.True(t, ok)
+	require.Equal(t, EncodeMsgID(msg.ID), tracked.MsgID)
+	require.Equal(t, sender, tracked.Sender)
+	require.Equal(t, 2, tracked.Subnet)
+	require.Equal(t, int64(200), tracked.Slot)
+	require.Equal(t, int64(2), tracked.Index)
+	require.Equal(t, int64(42), tracked.ProposerIndex)
+	require.Equal(t, 1500*time.Millisecond, tracked.TimeInSlot)
+	require.Equal(t, commitment.String(), tracked.KZGCommitment)
+	// the blobs are linked to the block through the root of its header
+	require.Equal(t, header.Message.HashTreeRoot(tree.GetHashFn()).String(), tracked.BlockRoot)
+
+	// the sidecars of a different size aren't decoded
+	msg.Data = snappy.Encode(nil, data[:len(data)-1])
+	_, err = handler.BlobSidecarMessageHandler(msg)
+	require.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package ethereum
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/migalabs/armiarma/pkg/networks/ethereum/rpc/methods"
+	"github.com/migalabs/armiarma/pkg/networks/ethereum/rpc/reqresp"
+	"github.com/pkg/errors"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/view"
+)
+
+// BlocksByRangeProbe is the result of requesting a range of blocks to a peer, which tells
+// whether the peer serves the historical blocks, and how fast it does it
+type BlocksByRangeProbe struct {
+	Timestamp time.Time
+	PeerID    peer.ID
+	StartSlot uint64
+	Requested uint64
+	Received  uint64
+	Bytes     uint64        // uncompressed SSZ bytes of the received blocks
+	Latency   time.Duration // until the first block was received
+	Duration  time.Duration // until the whole range was received
+	Error     string
+}
+
+// Success returns whether the peer served the requested range
+func (p *BlocksByRangeProbe) Success() bool {
+	return p.Error == ""
+}
+
+// Throughput returns the bytes per second at which the peer served the range
+func (p *BlocksByRangeProbe) Throughput() float64 {
+	if p.Duration <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Duration.Seconds()
+}
+
+// ReqBeaconBlocksByRange opens a new Stream from the given host to request the given range of blocks to the peer.
+// The probe is filled with the blocks and the bytes received, and the error of the request if it failed.
+func (en *LocalEthereumNode) ReqBeaconBlocksByRange(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	h host.Host,
+	peerID peer.ID,
+	startSlot common.Slot,
+	count uint64,
+	probe *BlocksByRangeProbe) {
+
+	defer wg.Done()
+	req := methods.BlocksByRangeReqV1{
+		StartSlot: startSlot,
+		Count:     view.Uint64View(count),
+		Step:      1,
+	}
+	probe.Timestamp = time.Now()
+	probe.PeerID = peerID
+	probe.StartSlot = uint64(startSlot)
+	probe.Requested = count
+
+	t := time.Now()
+	err := methods.BlocksByRangeRPCv2NoSnappy.RunRequest(ctx, h.NewStream, peerID, new(reqresp.SnappyCompression),
+		reqresp.RequestSSZInput{Obj: &req}, count,
+		func() error {
+			return nil
+		},
+		func(chunk reqresp.ChunkedResponseHandler) error {
+			switch chunk.ResultCode() {
+			case reqresp.ServerErrCode, reqresp.InvalidReqCode:
+				msg, err := chunk.ReadErrMsg()
+				if err != nil {
+					return errors.Wrap(err, msg)
+				}
+				return errors.Errorf("error reqresping BlocksByRange RPC: %s", msg)
+			case reqresp.SuccessCode:
+				if probe.Received == 0 {
+					probe.Latency = time.Since(t)
+				}
+				block, err := chunk.ReadRaw()
+				if err != nil {
+					return errors.Wrap(err, "from reqresping BlocksByRange RPC")
+				}
+				probe.Received++
+				probe.Bytes += uint64(len(block))
+			default:
+				return errors.New("unexpected result code for BlocksByRange RPC reqresp")
+			}
+			return nil
+		})
+	probe.Duration = time.Since(t)
+	if err != nil {
+		probe.Error = err.Error()
+	}
+}
@@ -0,0 +1,54 @@
+package ethereum
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BlocksByRangeProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		probe      BlocksByRangeProbe
+		success    bool
+		throughput float64
+	}{
+		{"served", BlocksByRangeProbe{Received: 4, Bytes: 2000, Duration: 2 * time.Second}, true, 1000},
+		// a peer that serves none of the blocks of the range without erroring still succeeds
+		{"empty range", BlocksByRangeProbe{Duration: time.Second}, true, 0},
+		{"failed", BlocksByRangeProbe{Bytes: 500, Duration: time.Second, Error: "stream reset"}, false, 500},
+		{"no duration", BlocksByRangeProbe{Bytes: 500}, true, 0},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.success, test.probe.Success(), test.name)
+		require.Equal(t, test.throughput, test.probe.Throughput(), test.name)
+	}
+}
+
+// the peers that don't serve the range get a failed probe, with the request recorded
+func Test_ReqBeaconBlocksByRange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	local, localHost := newTestBeaconNode(ctx, t, 1)
+	_, remoteHost := newTestBeaconNode(ctx, t, 1)
+	require.NoError(t, localHost.Connect(ctx, peer.AddrInfo{ID: remoteHost.ID(), Addrs: remoteHost.Addrs()}))
+
+	var wg sync.WaitGroup
+	probe := new(BlocksByRangeProbe)
+	wg.Add(1)
+	local.ReqBeaconBlocksByRange(ctx, &wg, localHost, remoteHost.ID(), 100, 8, probe)
+	wg.Wait()
+
+	require.False(t, probe.Success())
+	require.Equal(t, remoteHost.ID(), probe.PeerID)
+	require.Equal(t, uint64(100), probe.StartSlot)
+	require.Equal(t, uint64(8), probe.Requested)
+	require.Zero(t, probe.Received)
+	require.False(t, probe.Timestamp.IsZero())
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"net"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 
 	"github.com/migalabs/armiarma/pkg/utils"
@@ -25,8 +28,12 @@ type LocalEthereumNode struct {
 	// node's metadata in the network
 	LocalStatus   common.Status
 	LocalMetadata common.MetaData
+	metadataM     sync.RWMutex
 	// Network Details
 	networkGenesis time.Time
+	// subscribers to the goodbyes that the peers send us
+	goodbyeM        sync.Mutex
+	goodbyeHandlers []func(peer.ID, uint64)
 }
 
 // NewLocalNode will create a LocalNode object using the given arguments.
@@ -45,25 +52,13 @@ func NewLocalEthereumNode(
 	log.Infof("Creating Local Node")
 
 	// select network based on the network that we are participating in
-	var genesis time.Time
-	switch forkDigest {
-	// Mainnet
-	case ForkDigests[Phase0Key], ForkDigests[AltairKey], ForkDigests[BellatrixKey]:
-		genesis = MainnetGenesis
-	// Prater
-	case ForkDigests[PraterPhase0Key], ForkDigests[PraterBellatrixKey]:
-		genesis = GoerliGenesis
-	// Gnosis
-	case ForkDigests[GnosisPhase0Key], ForkDigests[GnosisAltairKey], ForkDigests[GnosisBellatrixKey], ForkDigests[GnosisCapellaKey], ForkDigests[GnosisDenebKey]:
-		genesis = GnosisGenesis
-	// Mainnet
-	default:
-		genesis = MainnetGenesis
-	}
+	genesis := NetworkGenesis(forkDigest)
 
 	return &LocalEthereumNode{
 		ctx:            ctx,
 		ethNode:        enode.NewLocalNode(ethDB, privKey),
+		LocalStatus:    status,
+		LocalMetadata:  matadata,
 		networkGenesis: genesis,
 	}
 }
@@ -89,16 +84,51 @@ func (en *LocalEthereumNode) Network() utils.NetworkType {
 	return utils.EthereumNetwork
 }
 
-// SetForkDigest adds any given ForkDigest into the local node's enr
-func (en *LocalEthereumNode) SetForkDigest(forkDigest string) {
-	// TODO: parse to see if it's a valid ForkDigets (len, blabla)
-	en.addEntries(NewEth2DataEntry(forkDigest))
+// SetForkDigest adds the eth2 entry of the given fork digest into the local node's enr, with no
+// fork scheduled (the given fork version as the next one, at FAR_FUTURE_EPOCH)
+func (en *LocalEthereumNode) SetForkDigest(forkDigest, forkVersion string) error {
+	entry, err := ComposeEth2DataEntry(forkDigest, forkVersion, FarFutureEpoch)
+	if err != nil {
+		return err
+	}
+	en.addEntries(entry)
+	return nil
+}
+
+// SetAttnets publishes the given attestation subnets in the attnets entry of the local node's enr
+// and in its metadata, whose seq number is increased if they changed
+func (en *LocalEthereumNode) SetAttnets(subnets []int) {
+	var attnets common.AttnetBits
+	for _, subnet := range subnets {
+		if subnet >= 0 && subnet < SubnetLimit {
+			attnets[subnet/8] |= 1 << (subnet % 8)
+		}
+	}
+	en.metadataM.Lock()
+	if attnets != en.LocalMetadata.Attnets {
+		en.LocalMetadata.Attnets = attnets
+		en.LocalMetadata.SeqNumber++
+	}
+	en.metadataM.Unlock()
+	en.addEntries(AttnetsENREntry(attnets[:]))
 }
 
-// SetAttNetworks adds any given set of Attnets into the local node's enr
-func (en *LocalEthereumNode) SetAttNetworks(networks string) {
-	// TODO: parse to see if it's a valid ForkDigets (len, blabla)
-	en.addEntries(NewAttnetsENREntry(networks))
+// Metadata returns the metadata that the local node serves
+func (en *LocalEthereumNode) Metadata() common.MetaData {
+	en.metadataM.RLock()
+	defer en.metadataM.RUnlock()
+	return en.LocalMetadata
+}
+
+// PublishEndpoint adds the ports where the node can be reached to its ENR, so that the discv5
+// nodes that find it can dial it. The IP is the given one, or the one that the discv5 peers
+// see for us if none is given
+func (en *LocalEthereumNode) PublishEndpoint(ip net.IP, tcpPort, udpPort int) {
+	if ip != nil {
+		en.ethNode.SetStaticIP(ip)
+	}
+	en.addEntries(enr.TCP(tcpPort))
+	en.ethNode.SetFallbackUDP(udpPort)
 }
 
 // AddEntries modifies the local Ethereum Node's ENR adding a new entry to the Key-Value
@@ -0,0 +1,47 @@
+package ethereum
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func Test_LocalNodeENR(t *testing.T) {
+	key, err := utils.GenerateECDSAPrivKey()
+	require.NoError(t, err)
+	node := NewLocalEthereumNode(context.Background(), key, ComposeQuickBeaconStatus(DefaultForkDigest),
+		ComposeQuickBeaconMetaData(), DefaultForkDigest)
+
+	version, ok := ForkVersionOfDigest(DefaultForkDigest)
+	require.True(t, ok)
+	require.NoError(t, node.SetForkDigest(DefaultForkDigest, version))
+	require.Error(t, node.SetForkDigest("all", version))
+	node.SetAttnets([]int{0, 9, 63, 64})
+	node.PublishEndpoint(net.ParseIP("1.2.3.4"), 9000, 9001)
+
+	enrNode, err := ParseEnr(node.EthNode().Node())
+	require.NoError(t, err)
+	require.Equal(t, DefaultForkDigest, enrNode.Eth2Data.ForkDigest.String())
+	require.Equal(t, version, enrNode.Eth2Data.NextForkVersion.String())
+	require.Equal(t, FarFutureEpoch, uint64(enrNode.Eth2Data.NextForkEpoch))
+	require.Equal(t, []int{0, 9, 63}, enrNode.AttSubnets())
+	require.Equal(t, "1.2.3.4", enrNode.IP.String())
+	require.Equal(t, 9000, enrNode.TCP)
+	require.Equal(t, 9001, enrNode.UDP)
+
+	// the metadata follows the attnets, increasing its seq number when they change
+	metadata := node.Metadata()
+	seq := metadata.SeqNumber
+	require.Equal(t, enrNode.Attnets.Raw, AttnetsENREntry(metadata.Attnets[:]))
+	node.SetAttnets([]int{0, 9, 63})
+	require.Equal(t, seq, node.Metadata().SeqNumber)
+	node.SetAttnets(nil)
+	require.Equal(t, seq+1, node.Metadata().SeqNumber)
+	enrNode, err = ParseEnr(node.EthNode().Node())
+	require.NoError(t, err)
+	require.Empty(t, enrNode.AttSubnets())
+}
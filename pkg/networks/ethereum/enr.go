@@ -4,22 +4,27 @@ import (
 	"crypto/ecdsa"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"math/bits"
 	"net"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/migalabs/armiarma/pkg/utils"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/pkg/errors"
 
 	gcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 )
 
 var (
 	EnrValidationError   error = errors.New("error validating ENR")
 	Eth2DataParsingError error = errors.New("error parsing eth2 data")
+	ErrNoTCPAddr         error = errors.New("the ENR has no tcp address")
+	ErrNoUDPAddr         error = errors.New("the ENR has no udp address")
 )
 
 var (
@@ -33,9 +38,18 @@ type EnrNode struct {
 	Seq       uint64
 	UDP       int
 	TCP       int
+	QUIC      int
 	Pubkey    *ecdsa.PublicKey
 	Eth2Data  *common.Eth2Data
 	Attnets   *Attnets
+	// Sync committee subnets (empty if the ENR doesn't have the key)
+	Syncnets SyncnetsENREntry
+	// Custody Subnet Count (-1 if the ENR doesn't have the key)
+	CSC int
+	// Text representation of the ENR ("enr:..."), kept to re-decode it in the future
+	Raw string
+	// Every key/value pair of the ENR (see DecodeEnrFields)
+	Fields map[string]interface{}
 }
 
 func NewEnrNode(nodeID enode.ID) *EnrNode {
@@ -46,6 +60,8 @@ func NewEnrNode(nodeID enode.ID) *EnrNode {
 		Pubkey:    new(ecdsa.PublicKey),
 		Eth2Data:  new(common.Eth2Data),
 		Attnets:   new(Attnets),
+		CSC:       -1,
+		Fields:    make(map[string]interface{}),
 	}
 }
 
@@ -66,6 +82,22 @@ func ParseEnr(node *enode.Node) (*EnrNode, error) {
 	enrNode.UDP = node.UDP()
 	enrNode.TCP = node.TCP()
 	enrNode.Pubkey = node.Pubkey()
+	enrNode.Raw = node.String()
+	enrNode.Fields = DecodeEnrFields(node)
+
+	// Optional entries that not all the clients advertise
+	var quic QuicENREntry
+	if err := node.Load(&quic); err == nil {
+		enrNode.QUIC = int(quic)
+	}
+	var csc CscENREntry
+	if err := node.Load(&csc); err == nil {
+		enrNode.CSC = int(csc)
+	}
+	var syncnets SyncnetsENREntry
+	if err := node.Load(&syncnets); err == nil {
+		enrNode.Syncnets = syncnets
+	}
 
 	// Retrieve the Fork Digest and the attestnets
 	eth2Data, ok, err := ParseNodeEth2Data(*node)
@@ -85,6 +117,15 @@ func ParseEnr(node *enode.Node) (*EnrNode, error) {
 	return enrNode, nil
 }
 
+// ParseEnrString decodes the text representation of an ENR ("enr:...") into an EnrNode
+func ParseEnrString(rawEnr string) (*EnrNode, error) {
+	node, err := enode.Parse(enode.ValidSchemes, rawEnr)
+	if err != nil {
+		return &EnrNode{}, errors.Wrap(err, "unable to parse raw enr")
+	}
+	return ParseEnr(node)
+}
+
 func (enr *EnrNode) GetPeerID() (peer.ID, error) {
 	// Get the public key and the peer.ID of the discovered peer
 	pubkey, err := utils.ConvertECDSAPubkeyToSecp2561k(enr.Pubkey)
@@ -99,6 +140,35 @@ func (enr *EnrNode) GetPeerID() (peer.ID, error) {
 	return peerId, nil
 }
 
+// TCPMultiaddr returns the libp2p multiaddr (tcp) advertised in the ENR
+func (enr *EnrNode) TCPMultiaddr() (ma.Multiaddr, error) {
+	if enr.IP == nil || enr.TCP == 0 {
+		return nil, ErrNoTCPAddr
+	}
+	return ma.NewMultiaddr(fmt.Sprintf("/%s/%s/tcp/%d", utils.GetIPFamily(enr.IP), enr.IP.String(), enr.TCP))
+}
+
+// UDPMultiaddr returns the discovery multiaddr (udp) advertised in the ENR
+func (enr *EnrNode) UDPMultiaddr() (ma.Multiaddr, error) {
+	if enr.IP == nil || enr.UDP == 0 {
+		return nil, ErrNoUDPAddr
+	}
+	return ma.NewMultiaddr(fmt.Sprintf("/%s/%s/udp/%d", utils.GetIPFamily(enr.IP), enr.IP.String(), enr.UDP))
+}
+
+// ComposeEnr signs a new ENR with the given key, sequence number and entries
+func ComposeEnr(privKey *ecdsa.PrivateKey, seq uint64, entries ...enr.Entry) (*enode.Node, error) {
+	var record enr.Record
+	record.SetSeq(seq)
+	for _, entry := range entries {
+		record.Set(entry)
+	}
+	if err := enode.SignV4(&record, privKey); err != nil {
+		return nil, errors.Wrap(err, "unable to sign the enr")
+	}
+	return enode.New(enode.ValidSchemes, &record)
+}
+
 func (enr *EnrNode) GetPubkeyString() string {
 	pubBytes := gcrypto.FromECDSAPub(enr.Pubkey)
 	pubkey := hex.EncodeToString(pubBytes)
@@ -109,6 +179,28 @@ func (enr *EnrNode) GetAttnetsString() string {
 	return hex.EncodeToString(enr.Attnets.Raw[:])
 }
 
+// AttSubnets returns the attestation subnets that the node advertises in its ENR
+func (enr *EnrNode) AttSubnets() []int {
+	return SubnetIndexes(enr.Attnets.Raw, SubnetLimit)
+}
+
+// SyncSubnets returns the sync committee subnets that the node advertises in its ENR
+func (enr *EnrNode) SyncSubnets() []int {
+	return SubnetIndexes(enr.Syncnets, SyncSubnetLimit)
+}
+
+// SubnetIndexes returns the indexes of the bits set in the given SSZ bitvector
+// (little-endian bit order), up to the given number of subnets
+func SubnetIndexes(bitvector []byte, limit int) []int {
+	subnets := make([]int, 0)
+	for i := 0; i < limit && i/8 < len(bitvector); i++ {
+		if bitvector[i/8]&(1<<(i%8)) != 0 {
+			subnets = append(subnets, i)
+		}
+	}
+	return subnets
+}
+
 type Attnets struct {
 	Raw       AttnetsENREntry
 	NetNumber int
@@ -0,0 +1,137 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"math/bits"
+	"net"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const SYNCNETS_KEY = "syncnets"
+
+// enrFieldDecoder decodes the RLP value of a known ENR key into a JSON friendly value
+type enrFieldDecoder func(raw []byte) (interface{}, error)
+
+var enrFieldDecoders = map[string]enrFieldDecoder{
+	"id":         decodeEnrString,
+	"secp256k1":  decodeEnrBytes,
+	"ip":         decodeEnrIP,
+	"ip6":        decodeEnrIP,
+	"tcp":        decodeEnrUint,
+	"tcp6":       decodeEnrUint,
+	"udp":        decodeEnrUint,
+	"udp6":       decodeEnrUint,
+	QUIC_ENR_KEY: decodeEnrUint,
+	"quic6":      decodeEnrUint,
+	CSC_ENR_KEY:  decodeEnrUint,
+	ETH_ENR_KEY:  decodeEnrForkID,
+	ETH2_ENR_KEY: decodeEnrEth2,
+	ATTNETS_KEY:  decodeEnrBitvector,
+	SYNCNETS_KEY: decodeEnrBitvector,
+	// client entry of the Portal nodes
+	"c": decodeEnrString,
+}
+
+// DecodeEnrFields returns every key/value pair of the node's ENR, decoding the known keys
+// and keeping the unknown ones (or the ones that don't match the expected format) as raw hex
+func DecodeEnrFields(node *enode.Node) map[string]interface{} {
+	fields := make(map[string]interface{})
+	// the elements are the seq followed by the sorted key/value pairs
+	elems := node.Record().AppendElements(nil)
+	for i := 1; i+1 < len(elems); i += 2 {
+		key, ok := elems[i].(string)
+		if !ok {
+			continue
+		}
+		raw, ok := elems[i+1].(rlp.RawValue)
+		if !ok {
+			continue
+		}
+		fields[key] = decodeEnrField(key, raw)
+	}
+	return fields
+}
+
+func decodeEnrField(key string, raw []byte) interface{} {
+	if decoder, ok := enrFieldDecoders[key]; ok {
+		if value, err := decoder(raw); err == nil {
+			return value
+		}
+	}
+	// the value could still be a plain byte string (i.e. custom keys)
+	var b []byte
+	if err := rlp.DecodeBytes(raw, &b); err == nil {
+		return "0x" + hex.EncodeToString(b)
+	}
+	return "0x" + hex.EncodeToString(raw)
+}
+
+func decodeEnrString(raw []byte) (interface{}, error) {
+	var s string
+	err := rlp.DecodeBytes(raw, &s)
+	return s, err
+}
+
+func decodeEnrBytes(raw []byte) (interface{}, error) {
+	var b []byte
+	err := rlp.DecodeBytes(raw, &b)
+	return "0x" + hex.EncodeToString(b), err
+}
+
+func decodeEnrUint(raw []byte) (interface{}, error) {
+	var u uint64
+	err := rlp.DecodeBytes(raw, &u)
+	return u, err
+}
+
+func decodeEnrIP(raw []byte) (interface{}, error) {
+	var ip net.IP
+	err := rlp.DecodeBytes(raw, &ip)
+	return ip.String(), err
+}
+
+func decodeEnrEth2(raw []byte) (interface{}, error) {
+	var entry Eth2ENREntry
+	if err := rlp.DecodeBytes(raw, &entry); err != nil {
+		return nil, err
+	}
+	eth2Data, err := entry.Eth2Data()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"fork_digest":       eth2Data.ForkDigest.String(),
+		"next_fork_version": eth2Data.NextForkVersion.String(),
+		"next_fork_epoch":   uint64(eth2Data.NextForkEpoch),
+	}, nil
+}
+
+// decodeEnrForkID decodes the fork ID of the EL clients
+func decodeEnrForkID(raw []byte) (interface{}, error) {
+	var entry EthENREntry
+	if err := rlp.DecodeBytes(raw, &entry); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"fork_hash": entry.ForkID.HashString(),
+		"fork_next": entry.ForkID.Next,
+	}, nil
+}
+
+// decodeEnrBitvector decodes the subnet bitvectors (attnets, syncnets)
+func decodeEnrBitvector(raw []byte) (interface{}, error) {
+	var b []byte
+	if err := rlp.DecodeBytes(raw, &b); err != nil {
+		return nil, err
+	}
+	count := 0
+	for _, by := range b {
+		count += bits.OnesCount8(by)
+	}
+	return map[string]interface{}{
+		"raw":   hex.EncodeToString(b),
+		"count": count,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SubnetIndexes(t *testing.T) {
+	tests := []struct {
+		name      string
+		bitvector []byte
+		limit     int
+		subnets   []int
+	}{
+		{"empty", nil, SubnetLimit, []int{}},
+		{"no subnets", make([]byte, 8), SubnetLimit, []int{}},
+		// the bits are in little-endian order inside each byte
+		{"first and last attnets", []byte{0x01, 0, 0, 0, 0, 0, 0, 0x80}, SubnetLimit, []int{0, 63}},
+		{"all the bits of a byte", []byte{0xff}, SubnetLimit, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{"second byte", []byte{0x00, 0x06}, SubnetLimit, []int{9, 10}},
+		// the bits past the limit are ignored
+		{"syncnets", []byte{0x29}, SyncSubnetLimit, []int{0, 3}},
+		// a short bitvector only covers its own bits
+		{"short bitvector", []byte{0x02}, SubnetLimit, []int{1}},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.subnets, SubnetIndexes(test.bitvector, test.limit), test.name)
+	}
+
+	enrNode := &EnrNode{
+		Attnets:  &Attnets{Raw: AttnetsENREntry{0x03, 0, 0, 0, 0, 0, 0, 0}},
+		Syncnets: SyncnetsENREntry{0x0c},
+	}
+	require.Equal(t, []int{0, 1}, enrNode.AttSubnets())
+	require.Equal(t, []int{2, 3}, enrNode.SyncSubnets())
+	// nodes without the syncnets key advertise none
+	enrNode.Syncnets = nil
+	require.Empty(t, enrNode.SyncSubnets())
+}
@@ -4,14 +4,22 @@ import (
 	"bytes"
 	"encoding/hex"
 	"errors"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
 	beacon "github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
 )
 
 const ATTNETS_KEY = "attnets"
 const ETH2_ENR_KEY = "eth2"
+const CSC_ENR_KEY = "csc"
+const QUIC_ENR_KEY = "quic"
+const ETH_ENR_KEY = "eth"
+
+// epoch of the next fork in the eth2 entry when there isn't any scheduled
+const FarFutureEpoch uint64 = 1<<64 - 1
 
 // Attended networks are the networks the node will be participating in
 type AttnetsENREntry []byte
@@ -31,6 +39,16 @@ func (aee AttnetsENREntry) ENRKey() string {
 	return ATTNETS_KEY
 }
 
+// Sync committee subnets the node is participating in (Altair)
+type SyncnetsENREntry []byte
+
+// number of sync committee subnets (bits of the syncnets bitvector)
+const SyncSubnetLimit = 4
+
+func (see SyncnetsENREntry) ENRKey() string {
+	return SYNCNETS_KEY
+}
+
 // With this entry we allow the node to have a registered fork digest
 type Eth2ENREntry []byte
 
@@ -44,6 +62,31 @@ func NewEth2DataEntry(input_bytes string) Eth2ENREntry {
 	return result
 }
 
+// ComposeEth2DataEntry composes the eth2 entry of the given fork digest, with the version and the epoch
+// of the next scheduled fork (the current version and FAR_FUTURE_EPOCH if there isn't any)
+func ComposeEth2DataEntry(forkDigest, nextForkVersion string, nextForkEpoch uint64) (Eth2ENREntry, error) {
+	var eth2Data beacon.Eth2Data
+	if err := eth2Data.ForkDigest.UnmarshalText([]byte(ensureHexPrefix(forkDigest))); err != nil {
+		return nil, errors.New("invalid fork digest " + forkDigest)
+	}
+	if err := eth2Data.NextForkVersion.UnmarshalText([]byte(ensureHexPrefix(nextForkVersion))); err != nil {
+		return nil, errors.New("invalid fork version " + nextForkVersion)
+	}
+	eth2Data.NextForkEpoch = beacon.Epoch(nextForkEpoch)
+	var buf bytes.Buffer
+	if err := eth2Data.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func ensureHexPrefix(s string) string {
+	if strings.HasPrefix(s, "0x") {
+		return s
+	}
+	return "0x" + s
+}
+
 func (eee Eth2ENREntry) ENRKey() string {
 	return ETH2_ENR_KEY
 }
@@ -56,6 +99,50 @@ func (eee Eth2ENREntry) Eth2Data() (*beacon.Eth2Data, error) {
 	return &dat, nil
 }
 
+// Custody Subnet Count advertised by the node (PeerDAS)
+type CscENREntry uint64
+
+func (cee CscENREntry) ENRKey() string {
+	return CSC_ENR_KEY
+}
+
+// QUIC port where the node is listening for libp2p connections
+type QuicENREntry uint16
+
+func (qee QuicENREntry) ENRKey() string {
+	return QUIC_ENR_KEY
+}
+
+// Fork ID of the execution layer (EIP-2124) advertised by the EL clients (EIP-2364)
+type EthENREntry struct {
+	ForkID ForkID
+	// ignore the additional fields that could be added in the future
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+type ForkID struct {
+	Hash [4]byte // CRC32 checksum of the genesis block and the passed fork blocks/timestamps
+	Next uint64  // next upcoming fork block/timestamp, 0 if not known
+}
+
+func (eee EthENREntry) ENRKey() string {
+	return ETH_ENR_KEY
+}
+
+// HashString returns the hex representation of the fork hash (i.e. 0x9f3d2254)
+func (f ForkID) HashString() string {
+	return "0x" + hex.EncodeToString(f.Hash[:])
+}
+
+// ParseNodeForkID returns the EL fork ID advertised in the ENR of the node
+func ParseNodeForkID(n enode.Node) (forkID ForkID, exists bool) {
+	var eth EthENREntry
+	if err := n.Load(&eth); err != nil {
+		return ForkID{}, false
+	}
+	return eth.ForkID, true
+}
+
 // ParseNodeEth2Data
 // * This method will parse the Node and obtain information about it
 // @param n: the enode from where to get the information
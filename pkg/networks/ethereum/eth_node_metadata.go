@@ -55,6 +55,25 @@ func NewBeaconStatus(peerId peer.ID, bStatus common.Status) BeaconStatusStamped
 	}
 }
 
+// BeaconPingStamped is the result of a Ping RPC: the seq number of the metadata of the peer,
+// and the RTT of the request
+type BeaconPingStamped struct {
+	Timestamp time.Time
+	PeerID    peer.ID
+	SeqNumber uint64
+	RTT       time.Duration
+}
+
+// NewBeaconPing generates a timestamped OBJ with the result of the Ping RPC
+func NewBeaconPing(peerId peer.ID, ping common.Ping, rtt time.Duration) BeaconPingStamped {
+	return BeaconPingStamped{
+		Timestamp: time.Now(),
+		PeerID:    peerId,
+		SeqNumber: uint64(ping),
+		RTT:       rtt,
+	}
+}
+
 // --- Parsers ----
 
 // ParseBeaconStatusFromInterfaced returns the Timestamped beaconStatus structure from a input interface
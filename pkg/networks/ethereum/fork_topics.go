@@ -0,0 +1,147 @@
+package ethereum
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// names of the forks, in activation order
+const (
+	Phase0Fork    = "phase0"
+	AltairFork    = "altair"
+	BellatrixFork = "bellatrix"
+	CapellaFork   = "capella"
+	DenebFork     = "deneb"
+)
+
+// types of the subnets of the gossipsub topics
+const (
+	AttnetSubnet  = "attnet"
+	SyncnetSubnet = "syncnet"
+)
+
+var (
+	Forks = []string{Phase0Fork, AltairFork, BellatrixFork, CapellaFork, DenebFork}
+
+	// message types introduced by each of the forks, the ones with a subnet id are expanded for each subnet
+	ForkMessageTypes = map[string][]string{
+		Phase0Fork: {
+			BeaconBlockTopicBase,
+			BeaconAggregateAndProofTopicBase,
+			VoluntaryExitTopicBase,
+			ProposerSlashingTopicBase,
+			AttesterSlashingTopicBase,
+		},
+		AltairFork: {
+			SyncCommitteeContributionAndProofTopicBase,
+			SyncCommitteeTopicBase,
+		},
+		BellatrixFork: {},
+		CapellaFork: {
+			BlsToExecutionChangeTopicBase,
+		},
+		DenebFork: {
+			BlobSidecarTopicBase,
+		},
+	}
+
+	// number of subnets of the message types with a subnet id (the attestations aren't part of the fork topics)
+	topicSubnets = map[string]int{
+		SyncCommitteeTopicBase: SyncSubnetLimit,
+		BlobSidecarTopicBase:   BlobSidecarSubnetLimit,
+	}
+
+	attnetTopicRe  = regexp.MustCompile(`/beacon_attestation_([0-9]+)/`)
+	syncnetTopicRe = regexp.MustCompile(`/sync_committee_([0-9]+)/`)
+)
+
+// TopicsOfFork returns the message types of the gossipsub topics active in the given fork
+// (the ones introduced by the fork and by the previous ones), with the subnet topics expanded
+func TopicsOfFork(fork string) []string {
+	topics := make([]string, 0)
+	for _, f := range Forks {
+		for _, msgType := range ForkMessageTypes[f] {
+			subnets, ok := topicSubnets[msgType]
+			if !ok {
+				topics = append(topics, msgType)
+				continue
+			}
+			for subnet := 0; subnet < subnets; subnet++ {
+				topics = append(topics, SubnetMessageType(msgType, subnet))
+			}
+		}
+		if f == fork {
+			break
+		}
+	}
+	return topics
+}
+
+// ForkOfDigest returns the fork of the given fork digest, the latest fork if the digest is unknown
+func ForkOfDigest(forkDigest string) string {
+	for forkDigestKey, digest := range ForkDigests {
+		if digest != forkDigest {
+			continue
+		}
+		key := strings.ToLower(forkDigestKey)
+		switch {
+		case key == strings.ToLower(Phase0Key) || strings.HasSuffix(key, Phase0Fork):
+			return Phase0Fork
+		case strings.HasSuffix(key, AltairFork):
+			return AltairFork
+		case strings.HasSuffix(key, BellatrixFork):
+			return BellatrixFork
+		case strings.HasSuffix(key, CapellaFork):
+			return CapellaFork
+		case strings.HasPrefix(key, DenebFork) || strings.HasSuffix(key, DenebFork):
+			return DenebFork
+		}
+	}
+	return Forks[len(Forks)-1]
+}
+
+// IsForkTopic returns whether the given message type is one of the gossipsub topics of the given fork
+func IsForkTopic(fork string, msgType string) bool {
+	for _, top := range TopicsOfFork(fork) {
+		if top == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// SubnetMessageType returns the message type of the given subnet of a message type with a subnet id
+func SubnetMessageType(msgType string, subnet int) string {
+	return strings.Replace(msgType, "{__subnet_id__}", fmt.Sprintf("%d", subnet), -1)
+}
+
+// SyncCommitteeMessageTypes returns the message types of all the sync committee subnets
+func SyncCommitteeMessageTypes() []string {
+	msgTypes := make([]string, 0, SyncSubnetLimit)
+	for subnet := 0; subnet < SyncSubnetLimit; subnet++ {
+		msgTypes = append(msgTypes, SubnetMessageType(SyncCommitteeTopicBase, subnet))
+	}
+	return msgTypes
+}
+
+// SubnetOfTopic returns the type and the index of the subnet of the given gossipsub topic,
+// false if the topic isn't the one of an attestation or a sync committee subnet
+func SubnetOfTopic(topic string) (string, int, bool) {
+	for subnetType, re := range map[string]*regexp.Regexp{
+		AttnetSubnet:  attnetTopicRe,
+		SyncnetSubnet: syncnetTopicRe,
+	} {
+		match := re.FindStringSubmatch(topic)
+		if len(match) < 2 {
+			continue
+		}
+		subnet, err := strconv.Atoi(match[1])
+		if err != nil {
+			return "", -1, false
+		}
+		return subnetType, subnet, true
+	}
+	return "", -1, false
+}
@@ -0,0 +1,116 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TopicsOfFork(t *testing.T) {
+	tests := []struct {
+		name    string
+		fork    string
+		len     int
+		topics  []string
+		missing []string
+	}{
+		{
+			"phase0", Phase0Fork, 5,
+			[]string{BeaconBlockTopicBase, AttesterSlashingTopicBase},
+			[]string{SyncCommitteeContributionAndProofTopicBase, "sync_committee_0", BlsToExecutionChangeTopicBase},
+		},
+		{
+			// the sync committee topics are expanded for each subnet
+			"altair", AltairFork, 5 + 1 + SyncSubnetLimit,
+			[]string{BeaconBlockTopicBase, SyncCommitteeContributionAndProofTopicBase, "sync_committee_0", "sync_committee_3"},
+			[]string{SyncCommitteeTopicBase, "sync_committee_4", BlsToExecutionChangeTopicBase},
+		},
+		// bellatrix doesn't introduce any topic
+		{"bellatrix", BellatrixFork, 5 + 1 + SyncSubnetLimit, []string{"sync_committee_1"}, []string{BlsToExecutionChangeTopicBase}},
+		{"capella", CapellaFork, 5 + 1 + SyncSubnetLimit + 1, []string{BlsToExecutionChangeTopicBase}, []string{"blob_sidecar_0"}},
+		{
+			"deneb", DenebFork, 5 + 1 + SyncSubnetLimit + 1 + BlobSidecarSubnetLimit,
+			[]string{BeaconBlockTopicBase, BlsToExecutionChangeTopicBase, "blob_sidecar_0", "blob_sidecar_5"},
+			[]string{BlobSidecarTopicBase, "blob_sidecar_6"},
+		},
+	}
+
+	for _, test := range tests {
+		topics := TopicsOfFork(test.fork)
+		require.Len(t, topics, test.len, test.name)
+		for _, topic := range test.topics {
+			require.Contains(t, topics, topic, test.name)
+			require.True(t, IsForkTopic(test.fork, topic), test.name)
+		}
+		for _, topic := range test.missing {
+			require.NotContains(t, topics, topic, test.name)
+			require.False(t, IsForkTopic(test.fork, topic), test.name)
+		}
+	}
+}
+
+func Test_ForkOfDigest(t *testing.T) {
+	tests := []struct {
+		name   string
+		digest string
+		fork   string
+	}{
+		{"mainnet phase0", ForkDigests[Phase0Key], Phase0Fork},
+		{"mainnet altair", ForkDigests[AltairKey], AltairFork},
+		{"mainnet bellatrix", ForkDigests[BellatrixKey], BellatrixFork},
+		{"mainnet capella", ForkDigests[CapellaKey], CapellaFork},
+		{"mainnet deneb", ForkDigests[DenebKey], DenebFork},
+		{"gnosis phase0", ForkDigests[GnosisPhase0Key], Phase0Fork},
+		{"gnosis bellatrix", ForkDigests[GnosisBellatrixKey], BellatrixFork},
+		{"gnosis deneb", ForkDigests[GnosisDenebKey], DenebFork},
+		{"prater capella", ForkDigests[PraterCapellaKey], CapellaFork},
+		// the unknown digests get the topics of the latest fork
+		{"unknown digest", "0x00000000", DenebFork},
+		{"all the digests", ForkDigests[AllForkDigest], DenebFork},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.fork, ForkOfDigest(test.digest), test.name)
+	}
+}
+
+func Test_SubnetOfTopic(t *testing.T) {
+	tests := []struct {
+		name       string
+		topic      string
+		subnetType string
+		subnet     int
+		ok         bool
+	}{
+		{"first attnet", ComposeAttnetsTopic(DefaultForkDigest, 0), AttnetSubnet, 0, true},
+		{"last attnet", ComposeAttnetsTopic(DefaultForkDigest, SubnetLimit-1), AttnetSubnet, SubnetLimit - 1, true},
+		{"syncnet", ComposeTopic(DefaultForkDigest, SubnetMessageType(SyncCommitteeTopicBase, 3)), SyncnetSubnet, 3, true},
+		// the aggregates of the sync committees don't belong to any subnet
+		{"sync contributions", ComposeTopic(DefaultForkDigest, SyncCommitteeContributionAndProofTopicBase), "", -1, false},
+		{"beacon block", ComposeTopic(DefaultForkDigest, BeaconBlockTopicBase), "", -1, false},
+		{"blob sidecar", ComposeTopic(DefaultForkDigest, SubnetMessageType(BlobSidecarTopicBase, 2)), "", -1, false},
+		{"empty", "", "", -1, false},
+	}
+
+	for _, test := range tests {
+		subnetType, subnet, ok := SubnetOfTopic(test.topic)
+		require.Equal(t, test.ok, ok, test.name)
+		require.Equal(t, test.subnetType, subnetType, test.name)
+		require.Equal(t, test.subnet, subnet, test.name)
+	}
+}
+
+func Test_SubnetMessageTypes(t *testing.T) {
+	require.Equal(t, "sync_committee_2", SubnetMessageType(SyncCommitteeTopicBase, 2))
+	require.Equal(t, "beacon_attestation_63", SubnetMessageType(AttestationTopicBase, 63))
+	// the message types without a subnet id don't change
+	require.Equal(t, BeaconBlockTopicBase, SubnetMessageType(BeaconBlockTopicBase, 2))
+
+	require.Equal(t, []string{"sync_committee_0", "sync_committee_1", "sync_committee_2", "sync_committee_3"}, SyncCommitteeMessageTypes())
+
+	// the attestation subnets go from 0 to 63
+	require.Equal(t, "/eth2/bba4da96/beacon_attestation_0/ssz_snappy", ComposeAttnetsTopic(ForkDigests[CapellaKey], 0))
+	require.Equal(t, "/eth2/bba4da96/beacon_attestation_63/ssz_snappy", ComposeAttnetsTopic(ForkDigests[CapellaKey], SubnetLimit-1))
+	require.Empty(t, ComposeAttnetsTopic(ForkDigests[CapellaKey], SubnetLimit))
+	require.Empty(t, ComposeAttnetsTopic(ForkDigests[CapellaKey], -1))
+}
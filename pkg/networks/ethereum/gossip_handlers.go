@@ -13,6 +13,7 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/deneb"
 	"github.com/protolambda/zrnt/eth2/configs"
 	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
 
 	"github.com/golang/snappy"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -106,13 +107,17 @@ func (s *EthMessageHandler) SubnetMessageHandler(msg *pubsub.Message) (gossipsub
 	}
 
 	trackedAttestation := &TrackedAttestation{
-		MsgID:       msg.ID,
-		ArrivalTime: msg.ArrivalTime,
-		Subnet:      subnet,
-		Slot:        int64(attestation.Data.Slot),
-		TimeInSlot:  GetTimeInSlot(s.genesisTime, msg.ArrivalTime, int64(attestation.Data.Slot)),
-		Sender:      msg.ReceivedFrom,
-		ValPubkey:   "",
+		MsgID:          EncodeMsgID(msg.ID),
+		ArrivalTime:    msg.ArrivalTime,
+		Subnet:         subnet,
+		Slot:           int64(attestation.Data.Slot),
+		TimeInSlot:     GetTimeInSlot(s.genesisTime, msg.ArrivalTime, int64(attestation.Data.Slot)),
+		Sender:         msg.ReceivedFrom,
+		ValPubkey:      "",
+		CommitteeIndex: int64(attestation.Data.Index),
+		AggregationBit: singleAggregationBit(attestation.AggregationBits),
+		BlockRoot:      attestation.Data.BeaconBlockRoot.String(),
+		TargetEpoch:    int64(attestation.Data.Target.Epoch),
 	}
 
 	// Publish the event
@@ -148,13 +153,115 @@ func (mh *EthMessageHandler) BeaconBlockMessageHandler(msg *pubsub.Message) (gos
 	}
 
 	trackedBlock := &TrackedBeaconBlock{
-		MsgID:       msg.ID,
+		MsgID:       EncodeMsgID(msg.ID),
 		Sender:      msg.ReceivedFrom,
 		ArrivalTime: msg.ArrivalTime,
 		TimeInSlot:  GetTimeInSlot(mh.genesisTime, msg.ArrivalTime, int64(bblock.Message.Slot)),
 		ValIndex:    int64(bblock.Message.ProposerIndex),
 		Slot:        int64(bblock.Message.Slot),
+		BlockRoot:   bblock.Message.HashTreeRoot(configs.Mainnet, tree.GetHashFn()).String(),
+		ParentRoot:  bblock.Message.ParentRoot.String(),
+		BlobCount:   int64(len(bblock.Message.Body.BlobKZGCommitments)),
 	}
 
 	return trackedBlock, nil
 }
+
+func (mh *EthMessageHandler) AggregateAndProofMessageHandler(msg *pubsub.Message) (gossipsub.PersistableMsg, error) {
+	t := time.Now()
+	defer func() { log.Trace("total time to handle msg:", time.Since(t)) }()
+	topic := *msg.Topic
+
+	// extract the data from the raw message
+	msgBytes, err := EthMessageBaseHandler(topic, msg)
+	if err != nil {
+		return nil, err
+	}
+	msgBuf := bytes.NewBuffer(msgBytes)
+	aggregate := new(phase0.SignedAggregateAndProof)
+
+	err = aggregate.Deserialize(configs.Mainnet, codec.NewDecodingReader(msgBuf, uint64(len(msgBuf.Bytes()))))
+	if err != nil {
+		return nil, err
+	}
+	attData := aggregate.Message.Aggregate.Data
+
+	trackedAggregate := &TrackedAggregateAndProof{
+		MsgID:           EncodeMsgID(msg.ID),
+		Sender:          msg.ReceivedFrom,
+		ArrivalTime:     msg.ArrivalTime,
+		TimeInSlot:      GetTimeInSlot(mh.genesisTime, msg.ArrivalTime, int64(attData.Slot)),
+		AggregatorIndex: int64(aggregate.Message.AggregatorIndex),
+		Slot:            int64(attData.Slot),
+		CommitteeIndex:  int64(attData.Index),
+		Participants:    int64(aggregate.Message.Aggregate.AggregationBits.OnesCount()),
+		BlockRoot:       attData.BeaconBlockRoot.String(),
+		TargetEpoch:     int64(attData.Target.Epoch),
+	}
+
+	return trackedAggregate, nil
+}
+
+func (mh *EthMessageHandler) BlobSidecarMessageHandler(msg *pubsub.Message) (gossipsub.PersistableMsg, error) {
+	t := time.Now()
+	defer func() { log.Trace("total time to handle msg:", time.Since(t)) }()
+	topic := *msg.Topic
+
+	// extract the data from the raw message
+	msgBytes, err := EthMessageBaseHandler(topic, msg)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := decodeBlobSidecar(codec.NewDecodingReader(bytes.NewReader(msgBytes), uint64(len(msgBytes))))
+	if err != nil {
+		return nil, err
+	}
+	subnet, err := GetBlobSubnetFromTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+	header := blob.Header.Message
+
+	trackedBlob := &TrackedBlobSidecar{
+		MsgID:         EncodeMsgID(msg.ID),
+		Sender:        msg.ReceivedFrom,
+		Subnet:        subnet,
+		ArrivalTime:   msg.ArrivalTime,
+		TimeInSlot:    GetTimeInSlot(mh.genesisTime, msg.ArrivalTime, int64(header.Slot)),
+		Slot:          int64(header.Slot),
+		Index:         int64(blob.Index),
+		ProposerIndex: int64(header.ProposerIndex),
+		// the root of the header is the one of the block that includes the blob
+		BlockRoot:     header.HashTreeRoot(tree.GetHashFn()).String(),
+		KZGCommitment: blob.KZGCommitment.String(),
+	}
+
+	return trackedBlob, nil
+}
+
+// MessageHandlers returns the decoder of each of the message types whose content is tracked,
+// the messages of the rest of the topics are only accounted
+func (mh *EthMessageHandler) MessageHandlers() map[string]gossipsub.MessageHandler {
+	handlers := map[string]gossipsub.MessageHandler{
+		BeaconBlockTopicBase:             mh.BeaconBlockMessageHandler,
+		BeaconAggregateAndProofTopicBase: mh.AggregateAndProofMessageHandler,
+	}
+	for subnet := 0; subnet < BlobSidecarSubnetLimit; subnet++ {
+		handlers[SubnetMessageType(BlobSidecarTopicBase, subnet)] = mh.BlobSidecarMessageHandler
+	}
+	return handlers
+}
+
+// singleAggregationBit returns the position in the committee of the only attester of the
+// attestation, -1 if there are several of them (aggregated) or none
+func singleAggregationBit(bits phase0.AttestationBits) int64 {
+	if bits.OnesCount() != 1 {
+		return -1
+	}
+	for i := uint64(0); i < bits.BitLen(); i++ {
+		if bits.GetBit(i) {
+			return int64(i)
+		}
+	}
+	return -1
+}
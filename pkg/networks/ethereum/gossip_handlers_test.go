@@ -0,0 +1,89 @@
+package ethereum
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SingleAggregationBit(t *testing.T) {
+	tests := []struct {
+		name string
+		bits phase0.AttestationBits
+		bit  int64
+	}{
+		// the highest bit set of the last byte only delimits the length of the bitlist
+		{"no attester", phase0.AttestationBits{0x04}, -1},
+		{"first attester", phase0.AttestationBits{0x05}, 0},
+		{"attester of the second byte", phase0.AttestationBits{0x00, 0x03}, 8},
+		{"last attester", phase0.AttestationBits{0x00, 0x80, 0x01}, 15},
+		{"aggregated", phase0.AttestationBits{0x07}, -1},
+		{"empty", phase0.AttestationBits{}, -1},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.bit, singleAggregationBit(test.bits), test.name)
+	}
+}
+
+func Test_AggregateAndProofMessageHandler(t *testing.T) {
+	genesis := time.Unix(1606824023, 0)
+	handler, err := NewEthMessageHandler(genesis, nil)
+	require.NoError(t, err)
+
+	aggregate := &phase0.SignedAggregateAndProof{
+		Message: phase0.AggregateAndProof{
+			AggregatorIndex: 1234,
+			Aggregate: phase0.Attestation{
+				AggregationBits: phase0.AttestationBits{0x0b, 0x01},
+				Data: phase0.AttestationData{
+					Slot:            100,
+					Index:           3,
+					BeaconBlockRoot: common.Root{0x01},
+					Target:          common.Checkpoint{Epoch: 3},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, aggregate.Serialize(configs.Mainnet, codec.NewEncodingWriter(&buf)))
+
+	topic := "/eth2/" + ForkDigests[DenebKey][2:] + "/" + BeaconAggregateAndProofTopicBase + "/" + Encoding
+	sender := peer.ID("sender")
+	arrival := genesis.Add(100*12*time.Second + 2*time.Second)
+	msg := &pubsub.Message{
+		Message:      &pubsub_pb.Message{Topic: &topic, Data: snappy.Encode(nil, buf.Bytes())},
+		ID:           "msg-id",
+		ReceivedFrom: sender,
+		ArrivalTime:  arrival,
+	}
+
+	persistable, err := handler.AggregateAndProofMessageHandler(msg)
+	require.NoError(t, err)
+	tracked, ok := persistable.(*TrackedAggregateAndProof)
+	require.True(t, ok)
+	require.Equal(t, EncodeMsgID(msg.ID), tracked.MsgID)
+	require.Equal(t, sender, tracked.Sender)
+	require.Equal(t, int64(1234), tracked.AggregatorIndex)
+	require.Equal(t, int64(100), tracked.Slot)
+	require.Equal(t, int64(3), tracked.CommitteeIndex)
+	require.Equal(t, int64(3), tracked.Participants)
+	require.Equal(t, common.Root{0x01}.String(), tracked.BlockRoot)
+	require.Equal(t, int64(3), tracked.TargetEpoch)
+	require.Equal(t, 2*time.Second, tracked.TimeInSlot)
+
+	// the messages that aren't snappy compressed can't be decoded
+	msg.Data = buf.Bytes()
+	_, err = handler.AggregateAndProofMessageHandler(msg)
+	require.Error(t, err)
+}
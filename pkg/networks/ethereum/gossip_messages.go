@@ -24,6 +24,12 @@ type TrackedAttestation struct {
 
 	ValPubkey string
 	Slot      int64
+
+	CommitteeIndex int64
+	// position of the attester in the committee, -1 if the attestation is aggregated
+	AggregationBit int64
+	BlockRoot      string
+	TargetEpoch    int64
 }
 
 func (a *TrackedAttestation) IsZero() bool {
@@ -39,12 +45,54 @@ type TrackedBeaconBlock struct {
 
 	ValIndex int64
 	Slot     int64
+
+	BlockRoot  string
+	ParentRoot string
+	BlobCount  int64 // blob commitments included in the block
 }
 
 func (a *TrackedBeaconBlock) IsZero() bool {
 	return a.Slot == 0
 }
 
+type TrackedAggregateAndProof struct {
+	MsgID  string
+	Sender peer.ID
+
+	ArrivalTime time.Time     // time of arrival
+	TimeInSlot  time.Duration // exact time inside the slot (range between 0secs and 12s*32slots)
+
+	AggregatorIndex int64
+	Slot            int64
+	CommitteeIndex  int64
+	Participants    int64 // attesters included in the aggregate
+	BlockRoot       string
+	TargetEpoch     int64
+}
+
+func (a *TrackedAggregateAndProof) IsZero() bool {
+	return a.Slot == 0
+}
+
+type TrackedBlobSidecar struct {
+	MsgID  string
+	Sender peer.ID
+	Subnet int
+
+	ArrivalTime time.Time     // time of arrival
+	TimeInSlot  time.Duration // exact time inside the slot (range between 0secs and 12s*32slots)
+
+	Slot          int64
+	Index         int64 // index of the blob in the block
+	ProposerIndex int64
+	BlockRoot     string
+	KZGCommitment string
+}
+
+func (b *TrackedBlobSidecar) IsZero() bool {
+	return b.Slot == 0
+}
+
 func GetSubnetFromTopic(topic string) (int, error) {
 	re := regexp.MustCompile(`attestation_([0-9]+)`)
 	match := re.FindAllString(topic, -1)
@@ -0,0 +1,51 @@
+package ethereum
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/golang/snappy"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/minio/sha256-simd"
+)
+
+const (
+	// Length of the message-id in the Eth2 gossipsub domain
+	MsgIDLength = 20
+)
+
+var (
+	// Message domains defined by the Eth2 p2p specs (Altair and onwards)
+	MessageDomainInvalidSnappy = [4]byte{0x00, 0x00, 0x00, 0x00}
+	MessageDomainValidSnappy   = [4]byte{0x01, 0x00, 0x00, 0x00}
+)
+
+// MsgIDFunction computes the message-id of a gossipsub message as the Eth2 specs define it (Altair and onwards):
+// SHA256(MESSAGE_DOMAIN + uint_to_bytes(uint64(len(topic))) + topic + message_data)[:20]
+// where the message_data is the snappy-decompressed payload if valid, or the raw payload otherwise
+func MsgIDFunction(pmsg *pubsub_pb.Message) string {
+	topic := pmsg.GetTopic()
+	topicLen := make([]byte, 8)
+	binary.LittleEndian.PutUint64(topicLen, uint64(len(topic)))
+
+	h := sha256.New()
+	// never errors, see crypto/sha256 Go doc
+	decodedData, err := snappy.Decode(nil, pmsg.Data)
+	if err != nil {
+		_, _ = h.Write(MessageDomainInvalidSnappy[:])
+		_, _ = h.Write(topicLen)
+		_, _ = h.Write([]byte(topic))
+		_, _ = h.Write(pmsg.Data)
+	} else {
+		_, _ = h.Write(MessageDomainValidSnappy[:])
+		_, _ = h.Write(topicLen)
+		_, _ = h.Write([]byte(topic))
+		_, _ = h.Write(decodedData)
+	}
+	return string(h.Sum(nil)[:MsgIDLength])
+}
+
+// EncodeMsgID returns the hex representation of a raw message-id, so that it can be persisted and compared with the ones of other clients
+func EncodeMsgID(msgID string) string {
+	return "0x" + hex.EncodeToString([]byte(msgID))
+}
@@ -0,0 +1,71 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/golang/snappy"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MsgIDFunction(t *testing.T) {
+	topic := "/eth2/6a95a1a9/beacon_block/ssz_snappy"
+
+	// the vectors follow the Altair specs:
+	// SHA256(MESSAGE_DOMAIN + uint_to_bytes(uint64(len(topic))) + topic + message_data)[:20]
+	tests := []struct {
+		name  string
+		data  []byte
+		msgID string // hex, without prefix
+		full  string // hex of the whole SHA256, for the valid snappy vector
+	}{
+		{
+			// snappy block of "hello": the data is decompressed and hashed with MESSAGE_DOMAIN_VALID_SNAPPY
+			name:  "valid snappy",
+			data:  []byte{0x05, 0x10, 'h', 'e', 'l', 'l', 'o'},
+			msgID: "d1346976629ef3d2c04a2a53ccacb9499c3db63a",
+			full:  "d1346976629ef3d2c04a2a53ccacb9499c3db63ac31ce5e71e71a0c4c0d1be08",
+		},
+		{
+			// the raw data is hashed with MESSAGE_DOMAIN_INVALID_SNAPPY
+			name:  "invalid snappy",
+			data:  []byte("\xff\x00not snappy"),
+			msgID: "552ecb6d1336ce4655a3807e613c985d30555884",
+		},
+	}
+
+	for _, test := range tests {
+		msgID := MsgIDFunction(&pubsub_pb.Message{Topic: &topic, Data: test.data})
+		require.Len(t, msgID, MsgIDLength, test.name)
+		require.Equal(t, test.msgID, hex.EncodeToString([]byte(msgID)), test.name)
+		// the message-id is the SHA256 truncated to its first 20 bytes
+		if test.full != "" {
+			require.Equal(t, test.full[:2*MsgIDLength], hex.EncodeToString([]byte(msgID)), test.name)
+		}
+		require.Equal(t, "0x"+test.msgID, EncodeMsgID(msgID), test.name)
+	}
+
+	_, err := snappy.Decode(nil, tests[1].data)
+	require.Error(t, err)
+
+	// the same data under a different domain gives a different message-id
+	decoded := []byte("hello")
+	require.NotEqual(t,
+		MsgIDFunction(&pubsub_pb.Message{Topic: &topic, Data: snappy.Encode(nil, decoded)}),
+		MsgIDFunction(&pubsub_pb.Message{Topic: &topic, Data: decoded}),
+	)
+
+	// the length prefix keeps the topic and the data apart
+	topicA, topicB := "ab", "a"
+	require.NotEqual(t,
+		MsgIDFunction(&pubsub_pb.Message{Topic: &topicA, Data: snappy.Encode(nil, []byte("c"))}),
+		MsgIDFunction(&pubsub_pb.Message{Topic: &topicB, Data: snappy.Encode(nil, []byte("bc"))}),
+	)
+}
+
+func Test_EncodeMsgID(t *testing.T) {
+	require.Equal(t, "0x", EncodeMsgID(""))
+	require.Equal(t, "0x00ff10", EncodeMsgID(string([]byte{0x00, 0xff, 0x10})))
+	require.Len(t, EncodeMsgID(string(make([]byte, MsgIDLength))), 2+2*MsgIDLength)
+}
@@ -0,0 +1,324 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	rendp "github.com/migalabs/armiarma/pkg/networks/ethereum/remoteendpoint"
+	"github.com/pkg/errors"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	log "github.com/sirupsen/logrus"
+)
+
+// strategies to validate the gossip messages, from the fastest to the most accurate
+const (
+	NoValidation   = "none" // all the messages are accepted
+	SSZValidation  = "ssz"  // the messages have to decode as the type of their topic and pass the stateless checks
+	FullValidation = "full" // the messages are also checked against the view of a beacon node
+)
+
+var (
+	ValidationStrategies = []string{NoValidation, SSZValidation, FullValidation}
+
+	SlotsPerEpochMainnet int64 = 32
+	SlotsPerEpochGnosis  int64 = 16
+
+	// tolerance of the spec for the messages of slots that haven't started yet
+	MaximumGossipClockDisparity = 500 * time.Millisecond
+
+	// proposer duties kept in memory (current epoch and the previous ones)
+	proposerDutiesEpochs = 4
+
+	subnetIDRe = regexp.MustCompile(`_[0-9]+$`)
+)
+
+// CheckValidationStrategy returns an error if the given strategy is unknown
+func CheckValidationStrategy(strategy string) error {
+	for _, s := range ValidationStrategies {
+		if s == strategy {
+			return nil
+		}
+	}
+	return errors.Errorf("unknown gossip validation strategy %s (none, ssz or full)", strategy)
+}
+
+// NewMessageValidator returns the validator of the given message type for the given strategy,
+// nil if the messages don't need to be validated. The beacon node is only needed by the full validation
+func NewMessageValidator(strategy string, msgType string, genesis time.Time, beaconNode *rendp.InfuraClient) (gossipsub.MessageValidator, error) {
+	switch strategy {
+	case NoValidation:
+		return nil, nil
+	case SSZValidation:
+		return NewSSZValidator(msgType, genesis), nil
+	case FullValidation:
+		if beaconNode == nil || !beaconNode.IsInitialized() {
+			return nil, errors.New("full gossip validation requires a remote beacon node")
+		}
+		return NewBeaconNodeValidator(NewSSZValidator(msgType, genesis), beaconNode), nil
+	default:
+		return nil, CheckValidationStrategy(strategy)
+	}
+}
+
+// SSZValidator rejects the messages that don't decode as the type of their topic, or that
+// fail the checks of the spec that don't need the beacon state
+type SSZValidator struct {
+	// message type of the topic, without the subnet id
+	msgType     string
+	genesisTime time.Time
+}
+
+func NewSSZValidator(msgType string, genesis time.Time) *SSZValidator {
+	return &SSZValidator{
+		msgType:     subnetIDRe.ReplaceAllString(msgType, "_{__subnet_id__}"),
+		genesisTime: genesis,
+	}
+}
+
+func (v *SSZValidator) Validate(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	_, result := v.validate(msg)
+	return result
+}
+
+// validate returns the decoded message along with the result of the validation
+func (v *SSZValidator) validate(msg *pubsub.Message) (interface{}, pubsub.ValidationResult) {
+	msgBytes, err := EthMessageBaseHandler(*msg.Topic, msg)
+	if err != nil {
+		log.Tracef("rejecting msg on %s: %s", *msg.Topic, err.Error())
+		return nil, pubsub.ValidationReject
+	}
+	obj, err := v.decode(msgBytes)
+	if err != nil {
+		log.Tracef("rejecting msg on %s: %s", *msg.Topic, err.Error())
+		return nil, pubsub.ValidationReject
+	}
+	return obj, v.check(obj)
+}
+
+// decode reads the message as the type of the topic, only the snappy compression is checked for unknown types
+func (v *SSZValidator) decode(msgBytes []byte) (interface{}, error) {
+	dr := codec.NewDecodingReader(bytes.NewReader(msgBytes), uint64(len(msgBytes)))
+	var err error
+	var obj interface{}
+	switch v.msgType {
+	case BeaconBlockTopicBase:
+		block := new(deneb.SignedBeaconBlock)
+		err = block.Deserialize(configs.Mainnet, dr)
+		obj = block
+	case AttestationTopicBase:
+		attestation := new(phase0.Attestation)
+		err = attestation.Deserialize(configs.Mainnet, dr)
+		obj = attestation
+	case BeaconAggregateAndProofTopicBase:
+		aggregate := new(phase0.SignedAggregateAndProof)
+		err = aggregate.Deserialize(configs.Mainnet, dr)
+		obj = aggregate
+	case VoluntaryExitTopicBase:
+		exit := new(phase0.SignedVoluntaryExit)
+		err = exit.Deserialize(dr)
+		obj = exit
+	case ProposerSlashingTopicBase:
+		slashing := new(phase0.ProposerSlashing)
+		err = slashing.Deserialize(dr)
+		obj = slashing
+	case AttesterSlashingTopicBase:
+		slashing := new(phase0.AttesterSlashing)
+		err = slashing.Deserialize(configs.Mainnet, dr)
+		obj = slashing
+	case SyncCommitteeContributionAndProofTopicBase:
+		contribution := new(altair.SignedContributionAndProof)
+		err = contribution.Deserialize(configs.Mainnet, dr)
+		obj = contribution
+	case SyncCommitteeTopicBase:
+		syncMsg := new(altair.SyncCommitteeMessage)
+		err = syncMsg.Deserialize(dr)
+		obj = syncMsg
+	case BlsToExecutionChangeTopicBase:
+		change := new(common.SignedBLSToExecutionChange)
+		err = change.Deserialize(dr)
+		obj = change
+	case BlobSidecarTopicBase:
+		obj, err = decodeBlobSidecar(dr)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to decode %s", v.msgType)
+	}
+	return obj, nil
+}
+
+// check applies the stateless checks of the spec to the decoded message
+func (v *SSZValidator) check(obj interface{}) pubsub.ValidationResult {
+	switch msg := obj.(type) {
+	case *deneb.SignedBeaconBlock:
+		return v.checkSlot(int64(msg.Message.Slot))
+	case *BlobSidecar:
+		return v.checkSlot(int64(msg.Header.Message.Slot))
+	case *phase0.Attestation:
+		// the attestations of the subnets can't be aggregated
+		if msg.AggregationBits.OnesCount() != 1 {
+			return pubsub.ValidationReject
+		}
+		if !v.matchesTargetEpoch(msg.Data) {
+			return pubsub.ValidationReject
+		}
+		return v.checkSlot(int64(msg.Data.Slot))
+	case *phase0.SignedAggregateAndProof:
+		if msg.Message.Aggregate.AggregationBits.OnesCount() == 0 {
+			return pubsub.ValidationReject
+		}
+		if !v.matchesTargetEpoch(msg.Message.Aggregate.Data) {
+			return pubsub.ValidationReject
+		}
+		return v.checkSlot(int64(msg.Message.Aggregate.Data.Slot))
+	}
+	return pubsub.ValidationAccept
+}
+
+// checkSlot ignores the messages of slots that haven't started yet
+func (v *SSZValidator) checkSlot(slot int64) pubsub.ValidationResult {
+	if GetTimeInSlot(v.genesisTime, time.Now(), slot) < -MaximumGossipClockDisparity {
+		return pubsub.ValidationIgnore
+	}
+	return pubsub.ValidationAccept
+}
+
+func (v *SSZValidator) matchesTargetEpoch(data phase0.AttestationData) bool {
+	return int64(data.Target.Epoch) == int64(data.Slot)/v.slotsPerEpoch()
+}
+
+func (v *SSZValidator) slotsPerEpoch() int64 {
+	if v.genesisTime.Equal(GnosisGenesis) {
+		return SlotsPerEpochGnosis
+	}
+	return SlotsPerEpochMainnet
+}
+
+// BeaconNodeValidator completes the SSZ validation checking the messages against the view of
+// a beacon node (the expected proposers, and the blocks that the messages refer to). The checks
+// that need the beacon state (signatures, committees) aren't performed. The messages are accepted
+// if the beacon node can't be reached, so that the throughput doesn't depend on the node
+type BeaconNodeValidator struct {
+	ssz        *SSZValidator
+	beaconNode *rendp.InfuraClient
+
+	m sync.Mutex
+	// expected proposer of each slot, by epoch
+	proposers map[int64]map[int64]int64
+}
+
+func NewBeaconNodeValidator(ssz *SSZValidator, beaconNode *rendp.InfuraClient) *BeaconNodeValidator {
+	return &BeaconNodeValidator{
+		ssz:        ssz,
+		beaconNode: beaconNode,
+		proposers:  make(map[int64]map[int64]int64),
+	}
+}
+
+func (v *BeaconNodeValidator) Validate(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	obj, result := v.ssz.validate(msg)
+	if result != pubsub.ValidationAccept {
+		return result
+	}
+	switch m := obj.(type) {
+	case *deneb.SignedBeaconBlock:
+		result = v.checkProposer(ctx, int64(m.Message.Slot), int64(m.Message.ProposerIndex))
+		if result != pubsub.ValidationAccept {
+			return result
+		}
+		// the parent of the block has to be known (otherwise it is ignored until it is)
+		_, result = v.blockSlot(ctx, m.Message.ParentRoot)
+		return result
+	case *BlobSidecar:
+		return v.checkProposer(ctx, int64(m.Header.Message.Slot), int64(m.Header.Message.ProposerIndex))
+	case *phase0.Attestation:
+		return v.checkVotedBlock(ctx, m.Data)
+	case *phase0.SignedAggregateAndProof:
+		return v.checkVotedBlock(ctx, m.Message.Aggregate.Data)
+	}
+	return pubsub.ValidationAccept
+}
+
+// checkVotedBlock checks that the block voted by the attestation is known, and previous to the attestation
+func (v *BeaconNodeValidator) checkVotedBlock(ctx context.Context, data phase0.AttestationData) pubsub.ValidationResult {
+	slot, result := v.blockSlot(ctx, data.BeaconBlockRoot)
+	if result != pubsub.ValidationAccept {
+		return result
+	}
+	if slot > int64(data.Slot) {
+		return pubsub.ValidationReject
+	}
+	return pubsub.ValidationAccept
+}
+
+// blockSlot returns the slot of the given block, ignoring the message if the block is unknown
+func (v *BeaconNodeValidator) blockSlot(ctx context.Context, root common.Root) (int64, pubsub.ValidationResult) {
+	header, err := v.beaconNode.ReqBlockHeader(ctx, root.String())
+	switch {
+	case err == rendp.ErrNotFound:
+		return -1, pubsub.ValidationIgnore
+	case err != nil:
+		log.Debugf("unable to request block header %s: %s", root.String(), err.Error())
+		return -1, pubsub.ValidationAccept
+	}
+	return int64(header.Header.Message.Slot), pubsub.ValidationAccept
+}
+
+// checkProposer rejects the blocks (and blobs) that weren't proposed by the expected proposer of the slot
+func (v *BeaconNodeValidator) checkProposer(ctx context.Context, slot int64, proposerIndex int64) pubsub.ValidationResult {
+	proposers, err := v.epochProposers(ctx, slot/v.ssz.slotsPerEpoch())
+	if err != nil {
+		log.Debugf("unable to request proposer duties of slot %d: %s", slot, err.Error())
+		return pubsub.ValidationAccept
+	}
+	expected, ok := proposers[slot]
+	if !ok {
+		return pubsub.ValidationAccept
+	}
+	if expected != proposerIndex {
+		return pubsub.ValidationReject
+	}
+	return pubsub.ValidationAccept
+}
+
+// epochProposers returns the expected proposer of each slot of the epoch, requesting them only once
+func (v *BeaconNodeValidator) epochProposers(ctx context.Context, epoch int64) (map[int64]int64, error) {
+	v.m.Lock()
+	proposers, ok := v.proposers[epoch]
+	v.m.Unlock()
+	if ok {
+		return proposers, nil
+	}
+	duties, err := v.beaconNode.ReqProposerDuties(ctx, uint64(epoch))
+	if err != nil {
+		return nil, err
+	}
+	proposers = make(map[int64]int64, len(duties))
+	for _, duty := range duties {
+		proposers[int64(duty.Slot)] = int64(duty.ValidatorIndex)
+	}
+
+	v.m.Lock()
+	defer v.m.Unlock()
+	v.proposers[epoch] = proposers
+	// drop the oldest epochs
+	for e := range v.proposers {
+		if e <= epoch-int64(proposerDutiesEpochs) {
+			delete(v.proposers, e)
+		}
+	}
+	return proposers, nil
+}
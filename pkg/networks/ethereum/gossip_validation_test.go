@@ -0,0 +1,207 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/stretchr/testify/require"
+
+	rendp "github.com/migalabs/armiarma/pkg/networks/ethereum/remoteendpoint"
+	"github.com/migalabs/armiarma/pkg/networks/ethereum/remoteendpoint/types"
+)
+
+type specSerializable interface {
+	Serialize(spec *common.Spec, w *codec.EncodingWriter) error
+}
+
+// encodeTestGossip returns the snappy compressed SSZ encoding of the object
+func encodeTestGossip(t *testing.T, obj specSerializable) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, obj.Serialize(configs.Mainnet, codec.NewEncodingWriter(&buf)))
+	return snappy.Encode(nil, buf.Bytes())
+}
+
+func newTestGossipMessage(msgType string, data []byte) *pubsub.Message {
+	topic := ComposeTopic(ForkDigests[DenebKey], msgType)
+	return &pubsub.Message{Message: &pubsub_pb.Message{Topic: &topic, Data: data}}
+}
+
+func newTestAttestation(bits phase0.AttestationBits, slot common.Slot, epoch common.Epoch, root common.Root) *phase0.Attestation {
+	return &phase0.Attestation{
+		AggregationBits: bits,
+		Data: phase0.AttestationData{
+			Slot:            slot,
+			BeaconBlockRoot: root,
+			Target:          common.Checkpoint{Epoch: epoch},
+		},
+	}
+}
+
+func newTestAggregate(bits phase0.AttestationBits, slot common.Slot, epoch common.Epoch, root common.Root) *phase0.SignedAggregateAndProof {
+	return &phase0.SignedAggregateAndProof{
+		Message: phase0.AggregateAndProof{Aggregate: *newTestAttestation(bits, slot, epoch, root)},
+	}
+}
+
+func encodeTestBlob(t *testing.T, slot common.Slot, proposer common.ValidatorIndex) []byte {
+	header := &common.SignedBeaconBlockHeader{Message: common.BeaconBlockHeader{Slot: slot, ProposerIndex: proposer}}
+	return snappy.Encode(nil, encodeTestBlobSidecar(t, 0, common.KZGCommitment{}, header))
+}
+
+func Test_CheckValidationStrategy(t *testing.T) {
+	for _, strategy := range ValidationStrategies {
+		require.NoError(t, CheckValidationStrategy(strategy), strategy)
+	}
+	require.Error(t, CheckValidationStrategy("partial"))
+	require.Error(t, CheckValidationStrategy(""))
+
+	validator, err := NewMessageValidator(NoValidation, BeaconBlockTopicBase, time.Now(), nil)
+	require.NoError(t, err)
+	require.Nil(t, validator)
+	validator, err = NewMessageValidator(SSZValidation, "beacon_attestation_12", time.Now(), nil)
+	require.NoError(t, err)
+	// the validators of the subnets are the ones of their message type
+	require.Equal(t, AttestationTopicBase, validator.(*SSZValidator).msgType)
+	// the full validation can't go without a beacon node
+	_, err = NewMessageValidator(FullValidation, BeaconBlockTopicBase, time.Now(), nil)
+	require.Error(t, err)
+	_, err = NewMessageValidator(FullValidation, BeaconBlockTopicBase, time.Now(), &rendp.InfuraClient{})
+	require.Error(t, err)
+	_, err = NewMessageValidator("partial", BeaconBlockTopicBase, time.Now(), nil)
+	require.Error(t, err)
+}
+
+func Test_SSZValidator(t *testing.T) {
+	// slot 1000 is the current one
+	genesis := time.Now().Add(-1000 * 12 * time.Second)
+	attnet := SubnetMessageType(AttestationTopicBase, 5)
+	root := common.Root{0x01}
+
+	tests := []struct {
+		name    string
+		msgType string
+		data    []byte
+		result  pubsub.ValidationResult
+	}{
+		{"attestation", attnet, encodeTestGossip(t, newTestAttestation(phase0.AttestationBits{0x05}, 100, 3, root)), pubsub.ValidationAccept},
+		{"aggregated attestation", attnet, encodeTestGossip(t, newTestAttestation(phase0.AttestationBits{0x07}, 100, 3, root)), pubsub.ValidationReject},
+		{"attestation without attester", attnet, encodeTestGossip(t, newTestAttestation(phase0.AttestationBits{0x04}, 100, 3, root)), pubsub.ValidationReject},
+		{"attestation of another epoch", attnet, encodeTestGossip(t, newTestAttestation(phase0.AttestationBits{0x05}, 100, 4, root)), pubsub.ValidationReject},
+		// the messages of the slots that haven't started yet are ignored, not rejected
+		{"future attestation", attnet, encodeTestGossip(t, newTestAttestation(phase0.AttestationBits{0x05}, 1100, 34, root)), pubsub.ValidationIgnore},
+		{"aggregate", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 100, 3, root)), pubsub.ValidationAccept},
+		{"empty aggregate", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x04}, 100, 3, root)), pubsub.ValidationReject},
+		{"aggregate of another epoch", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 100, 2, root)), pubsub.ValidationReject},
+		{"future aggregate", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 1100, 34, root)), pubsub.ValidationIgnore},
+		{"blob sidecar", SubnetMessageType(BlobSidecarTopicBase, 1), encodeTestBlob(t, 100, 7), pubsub.ValidationAccept},
+		{"future blob sidecar", SubnetMessageType(BlobSidecarTopicBase, 1), encodeTestBlob(t, 1100, 7), pubsub.ValidationIgnore},
+		{"blob sidecar of another size", SubnetMessageType(BlobSidecarTopicBase, 1), snappy.Encode(nil, []byte("short")), pubsub.ValidationReject},
+		{"not snappy", BeaconAggregateAndProofTopicBase, []byte("\xff\x00not snappy"), pubsub.ValidationReject},
+		{"wrong type", VoluntaryExitTopicBase, snappy.Encode(nil, []byte("short")), pubsub.ValidationReject},
+		// only the compression of the messages of unknown types is checked
+		{"unknown type", "light_client_finality_update", snappy.Encode(nil, []byte("anything")), pubsub.ValidationAccept},
+	}
+
+	for _, test := range tests {
+		validator := NewSSZValidator(test.msgType, genesis)
+		require.Equal(t, test.result, validator.Validate(context.Background(), "", newTestGossipMessage(test.msgType, test.data)), test.name)
+	}
+
+	// the epochs of gnosis are 16 slots long
+	gnosis := NewSSZValidator(BeaconAggregateAndProofTopicBase, GnosisGenesis)
+	accepted := newTestGossipMessage(BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 32, 2, root)))
+	require.Equal(t, pubsub.ValidationAccept, gnosis.Validate(context.Background(), "", accepted))
+	rejected := newTestGossipMessage(BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 32, 1, root)))
+	require.Equal(t, pubsub.ValidationReject, gnosis.Validate(context.Background(), "", rejected))
+}
+
+func writeTestBeaconData(t *testing.T, w http.ResponseWriter, data interface{}) {
+	require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"data": data}))
+}
+
+func Test_BeaconNodeValidator(t *testing.T) {
+	genesis := time.Now().Add(-1000 * 12 * time.Second)
+	knownRoot, laterRoot, failingRoot := common.Root{0x01}, common.Root{0x02}, common.Root{0x03}
+
+	var dutiesReqs int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/eth/v1/validator/duties/proposer/3":
+			atomic.AddInt32(&dutiesReqs, 1)
+			writeTestBeaconData(t, w, []types.ProposerDuty{{ValidatorIndex: 7, Slot: 100}})
+		case r.URL.Path == "/eth/v1/beacon/headers/"+knownRoot.String():
+			writeTestBeaconData(t, w, types.BlockHeader{Header: common.SignedBeaconBlockHeader{Message: common.BeaconBlockHeader{Slot: 99}}})
+		case r.URL.Path == "/eth/v1/beacon/headers/"+laterRoot.String():
+			writeTestBeaconData(t, w, types.BlockHeader{Header: common.SignedBeaconBlockHeader{Message: common.BeaconBlockHeader{Slot: 101}}})
+		case r.URL.Path == "/eth/v1/beacon/headers/"+failingRoot.String():
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	beaconNode, err := rendp.NewInfuraClient(server.URL)
+	require.NoError(t, err)
+
+	newBlock := func(proposer common.ValidatorIndex, parent common.Root) []byte {
+		block := new(deneb.SignedBeaconBlock)
+		block.Message.Slot = 100
+		block.Message.ProposerIndex = proposer
+		block.Message.ParentRoot = parent
+		block.Message.Body.SyncAggregate.SyncCommitteeBits = make(altair.SyncCommitteeBits, configs.Mainnet.SYNC_COMMITTEE_SIZE/8)
+		return encodeTestGossip(t, block)
+	}
+
+	tests := []struct {
+		name    string
+		msgType string
+		data    []byte
+		result  pubsub.ValidationResult
+	}{
+		{"block", BeaconBlockTopicBase, newBlock(7, knownRoot), pubsub.ValidationAccept},
+		{"block of another proposer", BeaconBlockTopicBase, newBlock(8, knownRoot), pubsub.ValidationReject},
+		// the blocks with an unknown parent are ignored until the node knows it
+		{"block of an unknown parent", BeaconBlockTopicBase, newBlock(7, common.Root{0x09}), pubsub.ValidationIgnore},
+		{"blob sidecar", SubnetMessageType(BlobSidecarTopicBase, 0), encodeTestBlob(t, 100, 7), pubsub.ValidationAccept},
+		{"blob sidecar of another proposer", SubnetMessageType(BlobSidecarTopicBase, 0), encodeTestBlob(t, 100, 8), pubsub.ValidationReject},
+		// the slots without a known duty are accepted
+		{"blob sidecar without duty", SubnetMessageType(BlobSidecarTopicBase, 0), encodeTestBlob(t, 101, 8), pubsub.ValidationAccept},
+		{"aggregate", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 100, 3, knownRoot)), pubsub.ValidationAccept},
+		{"aggregate of an unknown block", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 100, 3, common.Root{0x09})), pubsub.ValidationIgnore},
+		{"aggregate of a later block", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 100, 3, laterRoot)), pubsub.ValidationReject},
+		// the messages are accepted when the node fails
+		{"aggregate with a failing node", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x07}, 100, 3, failingRoot)), pubsub.ValidationAccept},
+		{"attestation of a later block", SubnetMessageType(AttestationTopicBase, 2), encodeTestGossip(t, newTestAttestation(phase0.AttestationBits{0x05}, 100, 3, laterRoot)), pubsub.ValidationReject},
+		// the failures of the SSZ validation don't reach the node
+		{"empty aggregate", BeaconAggregateAndProofTopicBase, encodeTestGossip(t, newTestAggregate(phase0.AttestationBits{0x04}, 100, 3, knownRoot)), pubsub.ValidationReject},
+	}
+
+	for _, test := range tests {
+		validator, err := NewMessageValidator(FullValidation, test.msgType, genesis, &beaconNode)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.result, validator.Validate(context.Background(), "", newTestGossipMessage(test.msgType, test.data)), test.name)
+	}
+
+	// the proposer duties of an epoch are only requested once by each validator
+	validator := NewBeaconNodeValidator(NewSSZValidator(BeaconBlockTopicBase, genesis), &beaconNode)
+	atomic.StoreInt32(&dutiesReqs, 0)
+	for i := 0; i < 3; i++ {
+		require.Equal(t, pubsub.ValidationAccept, validator.Validate(context.Background(), "", newTestGossipMessage(BeaconBlockTopicBase, newBlock(7, knownRoot))))
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&dutiesReqs))
+}
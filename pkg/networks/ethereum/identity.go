@@ -0,0 +1,70 @@
+package ethereum
+
+import (
+	"encoding/hex"
+
+	gcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// The discv5 node ID (keccak256 of the pubkey) and the libp2p peer ID (multihash of the pubkey)
+// of a node derive from the same secp256k1 key, which links the records of both identities
+var (
+	ErrNotSecp256k1Key = errors.New("the peer id doesn't come from a secp256k1 key")
+	ErrNodeIDMismatch  = errors.New("the node id doesn't derive from the pubkey")
+	ErrPeerIDMismatch  = errors.New("the peer id doesn't derive from the pubkey")
+)
+
+// NodeIDFromPeerID returns the discv5 node ID of the key of the given libp2p peer ID
+func NodeIDFromPeerID(pID peer.ID) (enode.ID, error) {
+	pubkey, err := pID.ExtractPublicKey()
+	if err != nil {
+		return enode.ID{}, errors.Wrap(err, "unable to extract the pubkey of the peer id")
+	}
+	secpKey, ok := pubkey.(*crypto.Secp256k1PublicKey)
+	if !ok {
+		return enode.ID{}, ErrNotSecp256k1Key
+	}
+	raw, err := secpKey.Raw()
+	if err != nil {
+		return enode.ID{}, errors.Wrap(err, "unable to read the pubkey of the peer id")
+	}
+	ecdsaKey, err := gcrypto.DecompressPubkey(raw)
+	if err != nil {
+		return enode.ID{}, errors.Wrap(err, "unable to decompress the pubkey of the peer id")
+	}
+	return enode.PubkeyToIDV4(ecdsaKey), nil
+}
+
+// CheckIdentity verifies that both the node ID and the peer ID of a record derive from its
+// pubkey (hex of the uncompressed key, as stored in the eth_nodes)
+func CheckIdentity(nodeID enode.ID, pID peer.ID, pubkeyHex string) error {
+	pubBytes, err := hex.DecodeString(pubkeyHex)
+	if err != nil {
+		return errors.Wrap(err, "invalid pubkey")
+	}
+	ecdsaKey, err := gcrypto.UnmarshalPubkey(pubBytes)
+	if err != nil {
+		return errors.Wrap(err, "invalid pubkey")
+	}
+	if enode.PubkeyToIDV4(ecdsaKey) != nodeID {
+		return ErrNodeIDMismatch
+	}
+	secpKey, err := utils.ConvertECDSAPubkeyToSecp2561k(ecdsaKey)
+	if err != nil {
+		return err
+	}
+	derived, err := peer.IDFromPublicKey(secpKey)
+	if err != nil {
+		return errors.Wrap(err, "unable to derive the peer id of the pubkey")
+	}
+	if derived != pID {
+		return ErrPeerIDMismatch
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package ethereum
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func Test_Identity(t *testing.T) {
+	key, err := utils.GenerateECDSAPrivKey()
+	require.NoError(t, err)
+	node, err := ComposeEnr(key, 1, enr.IPv4(net.ParseIP("1.2.3.4")), enr.UDP(9000))
+	require.NoError(t, err)
+	enrNode, err := ParseEnr(node)
+	require.NoError(t, err)
+	peerID, err := enrNode.GetPeerID()
+	require.NoError(t, err)
+
+	// the libp2p identity leads back to the discv5 one
+	nodeID, err := NodeIDFromPeerID(peerID)
+	require.NoError(t, err)
+	require.Equal(t, node.ID(), nodeID)
+	require.NoError(t, CheckIdentity(node.ID(), peerID, enrNode.GetPubkeyString()))
+
+	// records of another key
+	otherKey, err := utils.GenerateECDSAPrivKey()
+	require.NoError(t, err)
+	otherNode, err := ComposeEnr(otherKey, 1, enr.IPv4(net.ParseIP("1.2.3.4")), enr.UDP(9000))
+	require.NoError(t, err)
+	otherEnr, err := ParseEnr(otherNode)
+	require.NoError(t, err)
+	otherPeerID, err := otherEnr.GetPeerID()
+	require.NoError(t, err)
+	require.ErrorIs(t, CheckIdentity(otherNode.ID(), peerID, otherEnr.GetPubkeyString()), ErrPeerIDMismatch)
+	require.ErrorIs(t, CheckIdentity(node.ID(), otherPeerID, otherEnr.GetPubkeyString()), ErrNodeIDMismatch)
+	require.Error(t, CheckIdentity(enode.ID{}, peerID, "not-a-key"))
+
+	// the ed25519 peers (i.e. IPFS) have no discv5 identity
+	_, edPub, err := crypto.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	edPeerID, err := peer.IDFromPublicKey(edPub)
+	require.NoError(t, err)
+	_, err = NodeIDFromPeerID(edPeerID)
+	require.ErrorIs(t, err, ErrNotSecp256k1Key)
+}
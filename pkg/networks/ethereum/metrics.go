@@ -8,35 +8,43 @@ import (
 var (
 	modName    = "local_node"
 	modDetails = "general metrics about the local ethereum node"
+)
 
-	// List of metrics that we are going to export
+// localNodeMetrics are the collectors of the metrics of the local node, one set per crawler
+type localNodeMetrics struct {
+	localHeadSlot prometheus.Gauge
+}
 
-	localHeadSlot = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: modName,
-		Name:      "local_head_slot",
-		Help:      "Number of the last slot that the crawler saw and that it advertises as his last slot",
-	})
-)
+func newLocalNodeMetrics() *localNodeMetrics {
+	return &localNodeMetrics{
+		localHeadSlot: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: modName,
+			Name:      "local_head_slot",
+			Help:      "Number of the last slot that the crawler saw and that it advertises as his last slot",
+		}),
+	}
+}
 
 func (c *LocalEthereumNode) GetMetrics() *metrics.MetricsModule {
 	metricsMod := metrics.NewMetricsModule(
 		modName,
 		modDetails,
 	)
+	m := newLocalNodeMetrics()
 	// compose all the metrics
-	metricsMod.AddIndvMetric(c.localHeadSlot())
+	metricsMod.AddIndvMetric(c.localHeadSlot(m))
 	return metricsMod
 }
 
-func (c *LocalEthereumNode) localHeadSlot() *metrics.IndvMetrics {
-	initFn := func() error {
-		prometheus.MustRegister(localHeadSlot)
+func (c *LocalEthereumNode) localHeadSlot(m *localNodeMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.localHeadSlot)
 		return nil
 	}
 	updateFn := func() (interface{}, error) {
 		summary := make(map[string]interface{}, 0)
 		summary["local_head_slot"] = c.LocalStatus.HeadSlot
-		localHeadSlot.Set(float64(c.LocalStatus.HeadSlot))
+		m.localHeadSlot.Set(float64(c.LocalStatus.HeadSlot))
 		return summary, nil
 	}
 	indvMetr, err := metrics.NewIndvMetrics(
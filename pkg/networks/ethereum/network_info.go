@@ -2,7 +2,6 @@ package ethereum
 
 import (
 	"encoding/hex"
-	"fmt"
 	"strings"
 	"time"
 )
@@ -65,13 +64,8 @@ var (
 		DenebCancunKey: "0xee7b3a32",
 	}
 
-	MessageTypes = []string{
-		BeaconBlockTopicBase,
-		BeaconAggregateAndProofTopicBase,
-		VoluntaryExitTopicBase,
-		ProposerSlashingTopicBase,
-		AttesterSlashingTopicBase,
-	}
+	// message types of the latest fork (see ForkMessageTypes)
+	MessageTypes = TopicsOfFork(DenebFork)
 
 	BeaconBlockTopicBase             string = "beacon_block"
 	BeaconAggregateAndProofTopicBase string = "beacon_aggregate_and_proof"
@@ -80,6 +74,14 @@ var (
 	AttesterSlashingTopicBase        string = "attester_slashing"
 	AttestationTopicBase             string = "beacon_attestation_{__subnet_id__}"
 	SubnetLimit                             = 64
+	// Altair
+	SyncCommitteeContributionAndProofTopicBase string = "sync_committee_contribution_and_proof"
+	SyncCommitteeTopicBase                     string = "sync_committee_{__subnet_id__}"
+	// Capella
+	BlsToExecutionChangeTopicBase string = "bls_to_execution_change"
+	// Deneb
+	BlobSidecarTopicBase   string = "blob_sidecar_{__subnet_id__}"
+	BlobSidecarSubnetLimit        = 6
 
 	Encoding string = "ssz_snappy"
 )
@@ -105,13 +107,13 @@ func ComposeTopic(forkDigest string, messageTypeName string) string {
 
 // ComposeAttnetsTopic generates the GossipSub topic for the given ForkDigest and subnet
 func ComposeAttnetsTopic(forkDigest string, subnet int) string {
-	if subnet > SubnetLimit || subnet <= 0 {
+	if subnet >= SubnetLimit || subnet < 0 {
 		return ""
 	}
 
 	// trim "0x" if exists
 	forkDigest = strings.Trim(forkDigest, "0x")
-	name := strings.Replace(AttestationTopicBase, "{__subnet_id__}", fmt.Sprintf("%d", subnet), -1)
+	name := SubnetMessageType(AttestationTopicBase, subnet)
 	return "/" + BlockchainName +
 		"/" + forkDigest +
 		"/" + name +
@@ -154,6 +156,26 @@ func ComposeTopics(forkDigest string, messageTypeName []string) []string {
 	return result_array
 }
 
+// ForkDigestNetwork returns the name of the network (mainnet, gnosis, holesky...) of the given fork digest,
+// or the fork digest itself if it belongs to an unknown network.
+func ForkDigestNetwork(forkDigest string) string {
+	for forkDigestKey, digest := range ForkDigests {
+		if digest != forkDigest {
+			continue
+		}
+		for _, network := range []string{"Gnosis", "Prater", "Sepolia", "Holesky"} {
+			if strings.HasPrefix(forkDigestKey, network) {
+				return strings.ToLower(network)
+			}
+		}
+		switch forkDigestKey {
+		case Phase0Key, AltairKey, BellatrixKey, CapellaKey, DenebKey:
+			return "mainnet"
+		}
+	}
+	return forkDigest
+}
+
 // CheckValidForkDigest:
 // This method will check if Fork Digest exists in the corresponding map (ForkDigests).
 // @return the fork digest of the given network.
@@ -167,7 +189,7 @@ func CheckValidForkDigest(inStr string) (string, bool) {
 			return forkDigest, true
 		}
 	}
-	forkDigestBytes, err := hex.DecodeString(inStr)
+	forkDigestBytes, err := hex.DecodeString(strings.TrimPrefix(inStr, ForkDigestPrefix))
 	if err != nil {
 		return "", false
 	}
@@ -0,0 +1,163 @@
+package ethereum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NetworkPreset gathers what the crawler needs to join the gossip of an Ethereum CL network,
+// so that it can be selected by its name instead of giving its fork digest, bootnodes and topics
+type NetworkPreset struct {
+	Name string
+	// fork digest of the latest supported fork, empty for the networks that reset their genesis
+	ForkDigest string
+	// fork version of the latest supported fork, which with the genesis validators root gives the fork digest
+	ForkVersion           string
+	GenesisTime           time.Time
+	GenesisValidatorsRoot string
+	SecondsPerSlot        time.Duration
+	SlotsPerEpoch         int64
+	// empty for the testnets, whose bootnodes have to be given
+	Bootnodes []string
+	// period after which the network starts again from a new genesis (zero if it doesn't)
+	ResetPeriod time.Duration
+}
+
+// Ethereum CL networks that can be crawled by name
+var NetworkPresets = map[string]NetworkPreset{
+	"mainnet": {
+		Name:                  "mainnet",
+		ForkDigest:            DefaultForkDigest,
+		ForkVersion:           "0x03000000",
+		GenesisTime:           MainnetGenesis,
+		GenesisValidatorsRoot: "0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95",
+		SecondsPerSlot:        SecondsPerSlotMainnet,
+		SlotsPerEpoch:         SlotsPerEpochMainnet,
+		Bootnodes:             MainnetBootnodes,
+	},
+	"gnosis": {
+		Name:                  "gnosis",
+		ForkDigest:            ForkDigests[GnosisDenebKey],
+		ForkVersion:           "0x04000064",
+		GenesisTime:           GnosisGenesis,
+		GenesisValidatorsRoot: "0xf5dcb5564e829aab27264b9becd5dfaa017085611224cb3036f573368dbb9d47",
+		SecondsPerSlot:        SecondsPerSlotGnosis,
+		SlotsPerEpoch:         SlotsPerEpochGnosis,
+		Bootnodes:             GnosisBootnodes,
+	},
+	"holesky": {
+		Name:                  "holesky",
+		ForkDigest:            ForkDigests[HoleskyCapellaKey],
+		ForkVersion:           "0x04017000",
+		GenesisTime:           time.Unix(1695902400, 0),
+		GenesisValidatorsRoot: "0x9143aa7c615a7f7115e2b6aac319c03529df8242ae705fba9df39b79c59fa8b1",
+		SecondsPerSlot:        SecondsPerSlotMainnet,
+		SlotsPerEpoch:         SlotsPerEpochMainnet,
+	},
+	"sepolia": {
+		Name:                  "sepolia",
+		ForkDigest:            ForkDigests[SepoliaCapellaKey],
+		ForkVersion:           "0x90000072",
+		GenesisTime:           time.Unix(1655733600, 0),
+		GenesisValidatorsRoot: "0xd8ea171f3c94aea21ebc42a1ed61052acf3f9209c00e4efbaaddac09ed9b8078",
+		SecondsPerSlot:        SecondsPerSlotMainnet,
+		SlotsPerEpoch:         SlotsPerEpochMainnet,
+	},
+	// Ephemery starts again from a new genesis every 28 days, so its fork digest has to be
+	// given, or computed with ComputeForkDigest out of the genesis validators root of the iteration
+	"ephemery": {
+		Name:           "ephemery",
+		ForkVersion:    "0x5000101b",
+		SecondsPerSlot: SecondsPerSlotMainnet,
+		SlotsPerEpoch:  SlotsPerEpochMainnet,
+		ResetPeriod:    28 * 24 * time.Hour,
+	},
+}
+
+// Preset returns the preset of the network with the given name
+func Preset(name string) (NetworkPreset, bool) {
+	preset, ok := NetworkPresets[strings.ToLower(name)]
+	return preset, ok
+}
+
+// ForkVersions are the fork versions of the fork digests of ForkDigests (the fork digest is
+// computed out of the fork version and the genesis validators root of the network)
+var ForkVersions = map[string]string{
+	// Mainnet
+	Phase0Key:    "0x00000000",
+	AltairKey:    "0x01000000",
+	BellatrixKey: "0x02000000",
+	CapellaKey:   "0x03000000",
+	DenebKey:     "0x04000000",
+	// Gnosis
+	GnosisAltairKey:    "0x01000064",
+	GnosisBellatrixKey: "0x02000064",
+	GnosisCapellaKey:   "0x03000064",
+	GnosisDenebKey:     "0x04000064",
+	// Goerli-Prater
+	PraterPhase0Key:    "0x00001020",
+	PraterBellatrixKey: "0x02001020",
+	PraterCapellaKey:   "0x03001020",
+	// Sepolia
+	SepoliaCapellaKey: "0x90000072",
+	// Holesky
+	HoleskyCapellaKey: "0x04017000",
+}
+
+// ForkVersionOfDigest returns the fork version of the given fork digest (false if the fork
+// digest is unknown, i.e. the digest of a custom network or "all")
+func ForkVersionOfDigest(forkDigest string) (string, bool) {
+	for forkDigestKey, digest := range ForkDigests {
+		if digest != forkDigest {
+			continue
+		}
+		version, ok := ForkVersions[forkDigestKey]
+		return version, ok
+	}
+	return "", false
+}
+
+// TopicPrefix returns the prefix of the gossipsub topics of the network, i.e. "/eth2/bba4da96/"
+func (p NetworkPreset) TopicPrefix() string {
+	return "/" + BlockchainName + "/" + strings.TrimPrefix(p.ForkDigest, ForkDigestPrefix) + "/"
+}
+
+// Topics returns the gossipsub topics of the given message types in the network
+func (p NetworkPreset) Topics(messageTypes []string) []string {
+	return ComposeTopics(p.ForkDigest, messageTypes)
+}
+
+// NetworkGenesis returns the genesis of the network of the given fork digest (mainnet's one if unknown)
+func NetworkGenesis(forkDigest string) time.Time {
+	network := ForkDigestNetwork(forkDigest)
+	if preset, ok := NetworkPresets[network]; ok && !preset.GenesisTime.IsZero() {
+		return preset.GenesisTime
+	}
+	if network == "prater" {
+		return GoerliGenesis
+	}
+	return MainnetGenesis
+}
+
+// ComputeForkDigest returns the fork digest of the given fork version and genesis validators root:
+// the first 4 bytes of the hash tree root of the ForkData container
+func ComputeForkDigest(forkVersion, genesisValidatorsRoot string) (string, error) {
+	version, err := hex.DecodeString(strings.TrimPrefix(forkVersion, ForkDigestPrefix))
+	if err != nil || len(version) != 4 {
+		return "", errors.Errorf("fork version %q isn't a 4 bytes hex value", forkVersion)
+	}
+	root, err := hex.DecodeString(strings.TrimPrefix(genesisValidatorsRoot, ForkDigestPrefix))
+	if err != nil || len(root) != 32 {
+		return "", errors.Errorf("genesis validators root %q isn't a 32 bytes hex value", genesisValidatorsRoot)
+	}
+	// both fields fit in a single chunk, the version padded to 32 bytes
+	chunks := make([]byte, 64)
+	copy(chunks, version)
+	copy(chunks[32:], root)
+	hash := sha256.Sum256(chunks)
+	return ForkDigestPrefix + hex.EncodeToString(hash[:4]), nil
+}
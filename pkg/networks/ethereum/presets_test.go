@@ -0,0 +1,48 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ForkVersionOfDigest(t *testing.T) {
+	for forkDigestKey, version := range ForkVersions {
+		digest := ForkDigests[forkDigestKey]
+		got, ok := ForkVersionOfDigest(digest)
+		require.True(t, ok, forkDigestKey)
+		require.Equal(t, version, got, forkDigestKey)
+
+		// the fork version gives back the fork digest with the genesis validators root of the network
+		preset, ok := NetworkPresets[ForkDigestNetwork(digest)]
+		if !ok {
+			continue
+		}
+		computed, err := ComputeForkDigest(version, preset.GenesisValidatorsRoot)
+		require.NoError(t, err, forkDigestKey)
+		require.Equal(t, digest, computed, forkDigestKey)
+	}
+
+	// the digests of the older forks don't take the version of the latest one
+	version, ok := ForkVersionOfDigest(ForkDigests[DenebKey])
+	require.True(t, ok)
+	require.Equal(t, "0x04000000", version)
+	version, ok = ForkVersionOfDigest(ForkDigests[AltairKey])
+	require.True(t, ok)
+	require.Equal(t, "0x01000000", version)
+
+	for _, preset := range NetworkPresets {
+		if preset.ForkDigest == "" {
+			continue
+		}
+		version, ok := ForkVersionOfDigest(preset.ForkDigest)
+		require.True(t, ok, preset.Name)
+		require.Equal(t, preset.ForkVersion, version, preset.Name)
+	}
+
+	// custom networks and "all" have no fork version
+	_, ok = ForkVersionOfDigest("0x0a1b2c3d")
+	require.False(t, ok)
+	_, ok = ForkVersionOfDigest(ForkDigests[AllForkDigest])
+	require.False(t, ok)
+}
@@ -0,0 +1,31 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/migalabs/armiarma/pkg/networks/ethereum/remoteendpoint/types"
+	"github.com/pkg/errors"
+)
+
+// receives: block as string
+// Block identifier. Can be one of: "head" (canonical head in node's view), "genesis", "finalized", <slot>, <hex encoded blockRoot with 0x prefix>.
+// ErrNotFound is returned if the node doesn't know the block
+func (c *InfuraClient) ReqBlockHeader(ctx context.Context, block string) (header types.BlockHeader, err error) {
+	if !c.IsInitialized() {
+		return header, errors.New("infura client is not initialized")
+	}
+	req := ReplaceEndpointWithRequest(BEACON_BLOCK_HEADER, "block_id", block)
+	err = c.NewHttpsRequest(ctx, req, &header)
+	return header, err
+}
+
+// ReqProposerDuties returns the validators expected to propose the blocks of the given epoch
+func (c *InfuraClient) ReqProposerDuties(ctx context.Context, epoch uint64) (duties []types.ProposerDuty, err error) {
+	if !c.IsInitialized() {
+		return duties, errors.New("infura client is not initialized")
+	}
+	req := ReplaceEndpointWithRequest(VALIDATOR_PROPOSER_DUTIES, "epoch", fmt.Sprintf("%d", epoch))
+	err = c.NewHttpsRequest(ctx, req, &duties)
+	return duties, err
+}
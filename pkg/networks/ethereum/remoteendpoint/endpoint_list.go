@@ -2,3 +2,5 @@ package endpoint
 
 const GENESIS_ENPOINT = "/eth/v1/beacon/genesis"
 const BEACON_STATE_FORK = "/eth/v1/beacon/states/{state}/fork"
+const BEACON_BLOCK_HEADER = "/eth/v1/beacon/headers/{block_id}"
+const VALIDATOR_PROPOSER_DUTIES = "/eth/v1/validator/duties/proposer/{epoch}"
@@ -12,7 +12,10 @@ import (
 
 // ***** TODO: Move all this code into an Infura - Eth2 golang SDK *****
 
-var ()
+var (
+	// the requested object is unknown by the node
+	ErrNotFound = errors.New("not found in the beacon node")
+)
 
 type InfuraClient struct {
 	endpoint string
@@ -62,6 +65,12 @@ func (c *InfuraClient) NewHttpsRequest(ctx context.Context, request string, outp
 		return errors.Wrap(err, "failed to get API request from Infura endpoint")
 	}
 	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return errors.Errorf("unexpected status code %d from the endpoint", resp.StatusCode)
+	}
 
 	bodyBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// BlockHeader is the header of a block known by the beacon node
+type BlockHeader struct {
+	Root      Root                           `json:"root"`
+	Canonical bool                           `json:"canonical"`
+	Header    common.SignedBeaconBlockHeader `json:"header"`
+}
+
+// ProposerDuty is the validator expected to propose the block of a slot
+type ProposerDuty struct {
+	Pubkey         common.BLSPubkey      `json:"pubkey"`
+	ValidatorIndex common.ValidatorIndex `json:"validator_index"`
+	Slot           common.Slot           `json:"slot"`
+}
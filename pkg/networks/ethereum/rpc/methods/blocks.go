@@ -82,6 +82,45 @@ func BlocksByRangeRPCv1(spec *common.Spec, opcBlock beacon.OpaqueBlock) *reqresp
 	}
 }
 
+// MAX_REQUEST_BLOCKS is the max number of blocks that can be requested in a single BlocksByRange request
+const MAX_REQUEST_BLOCKS = 1024
+
+// MAX_CHUNK_SIZE caps the (uncompressed) size of each of the chunks of the response (10 MiB since Deneb)
+const MAX_CHUNK_SIZE = 10 << 20
+
+// ForkDigestLen is the length of the context bytes of the v2 responses
+const ForkDigestLen = 4
+
+// SignedBeaconBlockRaw keeps the SSZ encoding of a signed block of any fork, as its layout
+// depends on the fork given by the context bytes of the chunk
+type SignedBeaconBlockRaw []byte
+
+func (b *SignedBeaconBlockRaw) Deserialize(dr *codec.DecodingReader) error {
+	return dr.ByteList((*[]byte)(b), MAX_CHUNK_SIZE)
+}
+
+func (b SignedBeaconBlockRaw) Serialize(w *codec.EncodingWriter) error {
+	return w.Write(b)
+}
+
+func (b SignedBeaconBlockRaw) ByteLength() uint64 {
+	return uint64(len(b))
+}
+
+func (b *SignedBeaconBlockRaw) FixedLength() uint64 {
+	return 0
+}
+
+// BlocksByRangeRPCv2NoSnappy requests the blocks of any fork, which are prefixed by the fork digest.
+// The blocks are kept raw, as the method is only used to measure how the peers serve historical data
+var BlocksByRangeRPCv2NoSnappy = reqresp.RPCMethod{
+	Protocol:                  "/eth2/beacon_chain/req/beacon_blocks_by_range/2/ssz",
+	RequestCodec:              reqresp.NewSSZCodec(func() reqresp.SerDes { return new(BlocksByRangeReqV1) }, blocksByRangeReqByteLen, blocksByRangeReqByteLen),
+	ResponseChunkCodec:        reqresp.NewSSZCodec(func() reqresp.SerDes { return new(SignedBeaconBlockRaw) }, 0, MAX_CHUNK_SIZE),
+	DefaultResponseChunkCount: MAX_REQUEST_BLOCKS,
+	ResponseContextBytes:      ForkDigestLen,
+}
+
 const MAX_REQUEST_BLOCKS_BY_ROOT = 1024
 
 type BlocksByRootReq []Root
@@ -11,3 +11,10 @@ var PingRPCv1 = reqresp.RPCMethod{
 	ResponseChunkCodec:        reqresp.NewSSZCodec(func() reqresp.SerDes { return new(common.Ping) }, 8, 8),
 	DefaultResponseChunkCount: 1,
 }
+
+var PingRPCv1NoSnappy = reqresp.RPCMethod{
+	Protocol:                  "/eth2/beacon_chain/req/ping/1/ssz",
+	RequestCodec:              reqresp.NewSSZCodec(func() reqresp.SerDes { return new(common.Ping) }, 8, 8),
+	ResponseChunkCodec:        reqresp.NewSSZCodec(func() reqresp.SerDes { return new(common.Ping) }, 8, 8),
+	DefaultResponseChunkCount: 1,
+}
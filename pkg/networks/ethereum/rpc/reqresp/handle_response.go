@@ -8,10 +8,10 @@ import (
 	"io"
 )
 
-// ResponseChunkHandler is a function that processes a response chunk. The index, size and result-code are already parsed.
-// The contents (decompressed if previously compressed) can be read from r. Optionally an answer can be written back to w.
-// If the response chunk could not be processed, an error may be returned.
-type ResponseChunkHandler func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, r io.Reader, w io.Writer) error
+// ResponseChunkHandler is a function that processes a response chunk. The index, size, result-code and context bytes
+// (if any) are already parsed. The contents (decompressed if previously compressed) can be read from r.
+// Optionally an answer can be written back to w. If the response chunk could not be processed, an error may be returned.
+type ResponseChunkHandler func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, contextBytes []byte, r io.Reader, w io.Writer) error
 
 // ResponseHandler processes a response by internally processing chunks, any error is propagated up.
 type ResponseHandler func(ctx context.Context, r io.Reader, w io.WriteCloser) error
@@ -19,10 +19,11 @@ type ResponseHandler func(ctx context.Context, r io.Reader, w io.WriteCloser) er
 type OnRequested func()
 
 // MakeResponseHandler builds a ResponseHandler, which won't take more than maxChunkCount chunks, or chunk contents larger than maxChunkContentSize.
-// Compression is optional and may be nil. Chunks are processed by the given ResponseChunkHandler.
-func (handleChunk ResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64, maxChunkContentSize uint64, comp Compression) ResponseHandler {
+// Compression is optional and may be nil. The successful chunks of the versioned methods (v2) are prefixed by
+// contextBytesLen context bytes (the fork digest). Chunks are processed by the given ResponseChunkHandler.
+func (handleChunk ResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64, maxChunkContentSize uint64, contextBytesLen uint64, comp Compression) ResponseHandler {
 	//		response  ::= <response_chunk>*
-	//		response_chunk  ::= <result> | <encoding-dependent-header> | <encoded-payload>
+	//		response_chunk  ::= <result> | <context-bytes> | <encoding-dependent-header> | <encoded-payload>
 	//		result    ::= “0” | “1” | “2” | [“128” ... ”255”]
 	return func(ctx context.Context, r io.Reader, w io.WriteCloser) error {
 		defer w.Close()
@@ -39,6 +40,14 @@ func (handleChunk ResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64
 			if err != nil {
 				return fmt.Errorf("failed to read chunk %d result byte: %w", chunkIndex, err)
 			}
+			var contextBytes []byte
+			if resByte == byte(SuccessCode) && contextBytesLen > 0 {
+				contextBytes = make([]byte, contextBytesLen)
+				blr.N = int(contextBytesLen)
+				if _, err := io.ReadFull(blr, contextBytes); err != nil {
+					return fmt.Errorf("failed to read chunk %d context bytes: %w", chunkIndex, err)
+				}
+			}
 			// varints need to be read byte by byte.
 			blr.N = 1
 			blr.PerRead = true
@@ -65,7 +74,7 @@ func (handleChunk ResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64
 				cr = comp.Decompress(cr)
 				cw = comp.Compress(cw)
 			}
-			if err := handleChunk(ctx, chunkIndex, chunkSize, ResponseCode(resByte), cr, cw); err != nil {
+			if err := handleChunk(ctx, chunkIndex, chunkSize, ResponseCode(resByte), contextBytes, cr, cw); err != nil {
 				_ = cw.Close()
 				return err
 			}
@@ -91,6 +91,8 @@ type RPCMethod struct {
 	RequestCodec              Codec
 	ResponseChunkCodec        Codec
 	DefaultResponseChunkCount uint64
+	// number of context bytes (fork digest) that prefix the successful response chunks (v2 methods)
+	ResponseContextBytes uint64
 }
 
 type ResponseCode uint8
@@ -132,17 +134,19 @@ type ChunkedResponseHandler interface {
 	ChunkSize() uint64
 	ChunkIndex() uint64
 	ResultCode() ResponseCode
+	ContextBytes() []byte
 	ReadRaw() ([]byte, error)
 	ReadErrMsg() (string, error)
 	ReadObj(dest codec.Deserializable) error
 }
 
 type chRespHandler struct {
-	m          *RPCMethod
-	r          io.Reader
-	result     ResponseCode
-	chunkSize  uint64
-	chunkIndex uint64
+	m            *RPCMethod
+	r            io.Reader
+	result       ResponseCode
+	contextBytes []byte
+	chunkSize    uint64
+	chunkIndex   uint64
 }
 
 func (c *chRespHandler) ChunkSize() uint64 {
@@ -157,6 +161,10 @@ func (c *chRespHandler) ResultCode() ResponseCode {
 	return c.result
 }
 
+func (c *chRespHandler) ContextBytes() []byte {
+	return c.contextBytes
+}
+
 func (c *chRespHandler) ReadRaw() ([]byte, error) {
 	var buf bytes.Buffer
 	_, err := buf.ReadFrom(io.LimitReader(c.r, int64(c.chunkSize)))
@@ -176,13 +184,14 @@ func (c *chRespHandler) ReadObj(dest codec.Deserializable) error {
 func (m *RPCMethod) RunRequest(ctx context.Context, newStreamFn NewStreamFn,
 	peerID peer.ID, comp Compression, req RequestInput, maxRespChunks uint64, madeRequest func() error,
 	onResponse OnResponseListener) error {
-	handleChunks := ResponseChunkHandler(func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, r io.Reader, w io.Writer) error {
+	handleChunks := ResponseChunkHandler(func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, contextBytes []byte, r io.Reader, w io.Writer) error {
 		return onResponse(&chRespHandler{
-			m:          m,
-			r:          r,
-			result:     result,
-			chunkSize:  chunkSize,
-			chunkIndex: chunkIndex,
+			m:            m,
+			r:            r,
+			result:       result,
+			contextBytes: contextBytes,
+			chunkSize:    chunkSize,
+			chunkIndex:   chunkIndex,
 		})
 	})
 
@@ -202,7 +211,7 @@ func (m *RPCMethod) RunRequest(ctx context.Context, newStreamFn NewStreamFn,
 		}
 	}
 
-	respHandler := handleChunks.MakeResponseHandler(maxRespChunks, maxChunkContentSize, comp)
+	respHandler := handleChunks.MakeResponseHandler(maxRespChunks, maxChunkContentSize, m.ResponseContextBytes, comp)
 
 	handler := ResponseHandler(func(ctx context.Context, r io.Reader, w io.WriteCloser) error {
 		if err := madeRequest(); err != nil {
@@ -0,0 +1,126 @@
+package ethereum
+
+import (
+	"math"
+)
+
+const (
+	// long-lived attestation subnets that the beacon nodes keep regardless of their validators (since Deneb)
+	SubnetsPerNode = 2
+	// validators explored when computing the bounds of the estimates
+	MaxEstimatedValidators = 1024
+	// confidence of the bounds of the estimates
+	DefaultEstimateConfidence = 0.95
+)
+
+// ValidatorEstimate is the estimated number of validators behind a beacon node
+type ValidatorEstimate struct {
+	Attnets  int `json:"attnets"`
+	Syncnets int `json:"syncnets"`
+	// max number of attesters per epoch that only the peer's own validators could have published
+	Attesters int     `json:"attesters"`
+	Estimate  float64 `json:"estimate"`
+	Lower     int     `json:"lower"`
+	Upper     int     `json:"upper"`
+	// all the subnets are subscribed (i.e. --subscribe-all-subnets), so the upper bound is the limit of the search
+	Saturated  bool    `json:"saturated"`
+	Confidence float64 `json:"confidence"`
+}
+
+// EstimateValidators estimates the validators behind a beacon node from its long-lived subnet
+// subscriptions (attnets and syncnets) and its attestation activity.
+//
+// Each validator keeps one long-lived attestation subnet chosen at random, and the node advertises
+// the union of them on top of the SubnetsPerNode that it keeps on its own. With V validators over
+// the n remaining subnets, the number of distinct subnets k follows the occupancy distribution,
+// E[k] = n(1-(1-1/n)^V), which gives the estimate V = ln(1-k/n) / ln(1-1/n). The bounds are the
+// range of V for which k falls within the central confidence interval of the distribution.
+//
+// The attestations that a peer sends on the subnets it isn't subscribed to can only come from its
+// own validators, so the max number of such attesters per epoch bounds the estimate from below, as
+// does being subscribed to any sync committee subnet.
+func EstimateValidators(attnets, syncnets, attesters int, confidence float64) ValidatorEstimate {
+	est := ValidatorEstimate{
+		Attnets:    attnets,
+		Syncnets:   syncnets,
+		Attesters:  attesters,
+		Confidence: confidence,
+	}
+	n := SubnetLimit - SubnetsPerNode
+	k := attnets - SubnetsPerNode
+	if k < 0 {
+		k = 0
+	}
+	if k > n {
+		k = n
+	}
+	est.Saturated = k == n
+	alpha := (1 - confidence) / 2
+
+	// occupancy distribution of the subnets for each number of validators, from 0 on
+	pmf := make([]float64, n+1)
+	pmf[0] = 1
+	est.Lower, est.Upper = -1, MaxEstimatedValidators
+	for v := 0; v <= MaxEstimatedValidators; v++ {
+		var atLeast, atMost float64
+		for d, p := range pmf {
+			if d >= k {
+				atLeast += p
+			}
+			if d <= k {
+				atMost += p
+			}
+		}
+		if est.Lower < 0 && atLeast > alpha {
+			est.Lower = v
+		}
+		if atMost <= alpha {
+			est.Upper = v - 1
+			break
+		}
+		pmf = nextOccupancy(pmf)
+	}
+	if est.Lower < 0 {
+		est.Lower = MaxEstimatedValidators
+	}
+
+	switch {
+	case est.Saturated:
+		est.Estimate = float64(est.Lower)
+	case k > 0:
+		est.Estimate = math.Log(1-float64(k)/float64(n)) / math.Log(1-1/float64(n))
+	}
+
+	// the activity and the sync committees give a hard lower bound
+	minimum := attesters
+	if syncnets > 0 && minimum < 1 {
+		minimum = 1
+	}
+	if est.Estimate < float64(minimum) {
+		est.Estimate = float64(minimum)
+	}
+	if est.Lower < minimum {
+		est.Lower = minimum
+	}
+	if est.Upper < minimum {
+		est.Upper = minimum
+	}
+	return est
+}
+
+// nextOccupancy adds one validator to the occupancy distribution of the subnets
+func nextOccupancy(pmf []float64) []float64 {
+	n := len(pmf) - 1
+	next := make([]float64, len(pmf))
+	for d, p := range pmf {
+		if p == 0 {
+			continue
+		}
+		// the validator picks an already subscribed subnet, or a new one
+		next[d] += p * float64(d) / float64(n)
+		if d < n {
+			next[d+1] += p * float64(n-d) / float64(n)
+		}
+	}
+	return next
+}
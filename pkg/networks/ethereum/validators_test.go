@@ -0,0 +1,47 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EstimateValidators(t *testing.T) {
+	// only the subnets of the node itself
+	est := EstimateValidators(SubnetsPerNode, 0, 0, DefaultEstimateConfidence)
+	require.Equal(t, float64(0), est.Estimate)
+	require.Equal(t, 0, est.Lower)
+	require.False(t, est.Saturated)
+	require.Equal(t, 0, est.Upper)
+
+	// a few validators rarely collide
+	est = EstimateValidators(SubnetsPerNode+1, 0, 0, DefaultEstimateConfidence)
+	require.InDelta(t, 1, est.Estimate, 0.001)
+	require.Equal(t, 1, est.Lower)
+
+	est = EstimateValidators(SubnetsPerNode+31, 0, 0, DefaultEstimateConfidence)
+	require.InDelta(t, 42.6, est.Estimate, 0.1)
+	require.Less(t, est.Lower, 42)
+	require.Greater(t, est.Upper, 43)
+
+	// the bounds with less confidence are narrower
+	narrow := EstimateValidators(SubnetsPerNode+31, 0, 0, 0.5)
+	require.GreaterOrEqual(t, narrow.Lower, est.Lower)
+	require.LessOrEqual(t, narrow.Upper, est.Upper)
+
+	// all the subnets have no upper bound
+	est = EstimateValidators(SubnetLimit, 0, 0, DefaultEstimateConfidence)
+	require.True(t, est.Saturated)
+	require.Equal(t, MaxEstimatedValidators, est.Upper)
+	require.Equal(t, float64(est.Lower), est.Estimate)
+	require.Greater(t, est.Lower, 100)
+
+	// the activity and the sync committees are hard lower bounds
+	est = EstimateValidators(SubnetsPerNode, 1, 0, DefaultEstimateConfidence)
+	require.Equal(t, float64(1), est.Estimate)
+	require.Equal(t, 1, est.Lower)
+	est = EstimateValidators(SubnetsPerNode+1, 0, 5, DefaultEstimateConfidence)
+	require.Equal(t, float64(5), est.Estimate)
+	require.Equal(t, 5, est.Lower)
+	require.GreaterOrEqual(t, est.Upper, 5)
+}
@@ -0,0 +1,324 @@
+package filecoin
+
+/**
+This file implements the messages of the Filecoin hello protocol, that the nodes exchange right
+after getting connected to compare their genesis and their heaviest tipset
+(https://spec.filecoin.io/#section-systems.filecoin_nodes.network.hello).
+The messages are CBOR tuples, whose CIDs are encoded with the tag 42.
+
+*/
+
+import (
+	"encoding/binary"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+)
+
+const HelloProtocolID = "/fil/hello/1.0.0"
+
+// CBOR major types
+const (
+	cborUint   byte = 0
+	cborNegInt byte = 1
+	cborBytes  byte = 2
+	cborArray  byte = 4
+	cborTag    byte = 6
+
+	cidTag = 42
+	// the CIDs of the tipsets are limited by the number of blocks per epoch
+	maxTipSetCids = 64
+	// size limit of the byte strings of the messages (CIDs and weights)
+	maxBytesSize = 512
+	maxHelloSize = 8192
+)
+
+var (
+	ErrInvalidHello = errors.New("invalid filecoin hello")
+	// the message is still incomplete (it is read from a stream that is kept open)
+	errShortMessage = errors.Wrap(ErrInvalidHello, "unexpected end of message")
+)
+
+// HelloMessage is the message that the nodes send advertising their chain
+type HelloMessage struct {
+	HeaviestTipSet       []cid.Cid
+	HeaviestTipSetHeight int64
+	HeaviestTipSetWeight *big.Int
+	GenesisHash          cid.Cid
+}
+
+// LatencyMessage is the answer to a hello, with the time at which it arrived and was answered
+type LatencyMessage struct {
+	TArrival int64
+	TSent    int64
+}
+
+// EncodeHello composes the CBOR tuple of the hello message
+func EncodeHello(msg *HelloMessage) []byte {
+	buf := appendHeader(nil, cborArray, 4)
+	buf = appendHeader(buf, cborArray, uint64(len(msg.HeaviestTipSet)))
+	for _, c := range msg.HeaviestTipSet {
+		buf = appendCid(buf, c)
+	}
+	buf = appendInt(buf, msg.HeaviestTipSetHeight)
+	buf = appendBigInt(buf, msg.HeaviestTipSetWeight)
+	return appendCid(buf, msg.GenesisHash)
+}
+
+// DecodeHello parses the CBOR tuple of a hello message
+func DecodeHello(raw []byte) (*HelloMessage, error) {
+	r := &cborReader{buf: raw}
+	if err := r.expectArray(4); err != nil {
+		return nil, err
+	}
+	count, err := r.readLength(cborArray)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxTipSetCids {
+		return nil, errors.Wrapf(ErrInvalidHello, "tipset of %d blocks", count)
+	}
+	msg := &HelloMessage{
+		HeaviestTipSet: make([]cid.Cid, 0, count),
+	}
+	for i := uint64(0); i < count; i++ {
+		c, err := r.readCid()
+		if err != nil {
+			return nil, err
+		}
+		msg.HeaviestTipSet = append(msg.HeaviestTipSet, c)
+	}
+	if msg.HeaviestTipSetHeight, err = r.readInt(); err != nil {
+		return nil, err
+	}
+	if msg.HeaviestTipSetWeight, err = r.readBigInt(); err != nil {
+		return nil, err
+	}
+	if msg.GenesisHash, err = r.readCid(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// ReadHello reads a hello message from the stream, which the sender keeps open to read our answer
+func ReadHello(r io.Reader) (*HelloMessage, error) {
+	raw := make([]byte, 0, 256)
+	chunk := make([]byte, 256)
+	for len(raw) < maxHelloSize {
+		n, err := r.Read(chunk)
+		raw = append(raw, chunk[:n]...)
+		if n > 0 {
+			msg, decErr := DecodeHello(raw)
+			if decErr != errShortMessage {
+				return msg, decErr
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read the hello")
+		}
+	}
+	return nil, errors.Wrapf(ErrInvalidHello, "hello larger than %d bytes", maxHelloSize)
+}
+
+// EncodeLatency composes the CBOR tuple of the answer to a hello
+func EncodeLatency(arrival, sent time.Time) []byte {
+	buf := appendHeader(nil, cborArray, 2)
+	buf = appendInt(buf, arrival.UnixNano())
+	return appendInt(buf, sent.UnixNano())
+}
+
+// DecodeLatency parses the CBOR tuple of the answer to a hello
+func DecodeLatency(raw []byte) (*LatencyMessage, error) {
+	r := &cborReader{buf: raw}
+	if err := r.expectArray(2); err != nil {
+		return nil, err
+	}
+	arrival, err := r.readInt()
+	if err != nil {
+		return nil, err
+	}
+	sent, err := r.readInt()
+	if err != nil {
+		return nil, err
+	}
+	return &LatencyMessage{TArrival: arrival, TSent: sent}, nil
+}
+
+func appendHeader(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= 0xff:
+		return append(buf, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major<<5|27), n)
+	}
+}
+
+func appendInt(buf []byte, n int64) []byte {
+	if n < 0 {
+		return appendHeader(buf, cborNegInt, uint64(-1-n))
+	}
+	return appendHeader(buf, cborUint, uint64(n))
+}
+
+// appendBigInt encodes the big ints as Filecoin does: a byte string with the sign byte followed
+// by the big-endian magnitude (empty for zero)
+func appendBigInt(buf []byte, n *big.Int) []byte {
+	if n == nil || n.Sign() == 0 {
+		return appendHeader(buf, cborBytes, 0)
+	}
+	sign := byte(0)
+	if n.Sign() < 0 {
+		sign = 1
+	}
+	b := append([]byte{sign}, n.Bytes()...)
+	return append(appendHeader(buf, cborBytes, uint64(len(b))), b...)
+}
+
+// appendCid encodes the CID as the tag 42 over its bytes, prefixed by the identity multibase
+func appendCid(buf []byte, c cid.Cid) []byte {
+	b := append([]byte{0}, c.Bytes()...)
+	buf = appendHeader(buf, cborTag, cidTag)
+	return append(appendHeader(buf, cborBytes, uint64(len(b))), b...)
+}
+
+type cborReader struct {
+	buf []byte
+}
+
+func (r *cborReader) readHeader() (byte, uint64, error) {
+	if len(r.buf) == 0 {
+		return 0, 0, errShortMessage
+	}
+	major, info := r.buf[0]>>5, r.buf[0]&0x1f
+	r.buf = r.buf[1:]
+	size := 0
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, errors.Wrapf(ErrInvalidHello, "unsupported additional info %d", info)
+	}
+	if len(r.buf) < size {
+		return 0, 0, errShortMessage
+	}
+	var n uint64
+	for _, b := range r.buf[:size] {
+		n = n<<8 | uint64(b)
+	}
+	r.buf = r.buf[size:]
+	return major, n, nil
+}
+
+func (r *cborReader) readLength(major byte) (uint64, error) {
+	m, n, err := r.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	if m != major {
+		return 0, errors.Wrapf(ErrInvalidHello, "expected major type %d, got %d", major, m)
+	}
+	return n, nil
+}
+
+func (r *cborReader) expectArray(size uint64) error {
+	n, err := r.readLength(cborArray)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return errors.Wrapf(ErrInvalidHello, "tuple of %d fields, expected %d", n, size)
+	}
+	return nil
+}
+
+func (r *cborReader) readInt() (int64, error) {
+	major, n, err := r.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	if n > 1<<63-1 {
+		return 0, errors.Wrap(ErrInvalidHello, "integer overflow")
+	}
+	switch major {
+	case cborUint:
+		return int64(n), nil
+	case cborNegInt:
+		return -1 - int64(n), nil
+	default:
+		return 0, errors.Wrapf(ErrInvalidHello, "expected an integer, got major type %d", major)
+	}
+}
+
+func (r *cborReader) readBytes() ([]byte, error) {
+	n, err := r.readLength(cborBytes)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBytesSize {
+		return nil, errors.Wrapf(ErrInvalidHello, "byte string of %d bytes", n)
+	}
+	if uint64(len(r.buf)) < n {
+		return nil, errShortMessage
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *cborReader) readBigInt() (*big.Int, error) {
+	b, err := r.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return new(big.Int), nil
+	}
+	n := new(big.Int).SetBytes(b[1:])
+	switch b[0] {
+	case 0:
+	case 1:
+		n.Neg(n)
+	default:
+		return nil, errors.Wrapf(ErrInvalidHello, "invalid sign byte %d of big int", b[0])
+	}
+	return n, nil
+}
+
+func (r *cborReader) readCid() (cid.Cid, error) {
+	tag, err := r.readLength(cborTag)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if tag != cidTag {
+		return cid.Undef, errors.Wrapf(ErrInvalidHello, "expected the cid tag, got %d", tag)
+	}
+	b, err := r.readBytes()
+	if err != nil {
+		return cid.Undef, err
+	}
+	if len(b) == 0 || b[0] != 0 {
+		return cid.Undef, errors.Wrap(ErrInvalidHello, "cid without the identity multibase prefix")
+	}
+	c, err := cid.Cast(b[1:])
+	if err != nil {
+		return cid.Undef, errors.Wrap(ErrInvalidHello, err.Error())
+	}
+	return c, nil
+}
@@ -0,0 +1,147 @@
+package filecoin
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// time to read a hello and to write our answer
+	HelloStreamTimeout = 10 * time.Second
+	// time that a hello is kept for the identification of its peer
+	helloRetention = time.Minute
+)
+
+// HelloStamped is the chain that a peer advertised in its hello
+type HelloStamped struct {
+	Timestamp   time.Time
+	PeerID      peer.ID
+	GenesisHash string
+	HeadTipSet  []string
+	HeadHeight  int64
+	HeadWeight  *big.Int
+}
+
+func NewHelloStamped(peerID peer.ID, msg *HelloMessage) HelloStamped {
+	tipSet := make([]string, 0, len(msg.HeaviestTipSet))
+	for _, c := range msg.HeaviestTipSet {
+		tipSet = append(tipSet, c.String())
+	}
+	return HelloStamped{
+		Timestamp:   time.Now(),
+		PeerID:      peerID,
+		GenesisHash: msg.GenesisHash.String(),
+		HeadTipSet:  tipSet,
+		HeadHeight:  msg.HeaviestTipSetHeight,
+		HeadWeight:  msg.HeaviestTipSetWeight,
+	}
+}
+
+// HelloService answers the hellos of the Filecoin nodes, which say hello to the peers that
+// support the protocol once they identify them, and hands them to the identification of the peers
+type HelloService struct {
+	m        sync.Mutex
+	received map[peer.ID]HelloStamped
+	waiters  map[peer.ID][]chan HelloStamped
+}
+
+func NewHelloService() *HelloService {
+	return &HelloService{
+		received: make(map[peer.ID]HelloStamped),
+		waiters:  make(map[peer.ID][]chan HelloStamped),
+	}
+}
+
+// Serve sets the handler of the hello protocol in the host, which has to be done before
+// it gets connected to any peer, so that the protocol is announced through identify
+func (s *HelloService) Serve(h host.Host) {
+	h.SetStreamHandler(HelloProtocolID, s.handleStream)
+	log.Info("Started serving Filecoin Hello")
+}
+
+func (s *HelloService) handleStream(stream network.Stream) {
+	defer stream.Close()
+	_ = stream.SetDeadline(time.Now().Add(HelloStreamTimeout))
+
+	peerID := stream.Conn().RemotePeer()
+	msg, err := ReadHello(stream)
+	if err != nil {
+		_ = stream.Reset()
+		log.Tracef("failed to read hello from %s: %v", peerID.String(), err)
+		return
+	}
+	arrival := time.Now()
+	if _, err := stream.Write(EncodeLatency(arrival, time.Now())); err != nil {
+		log.Tracef("failed to answer the hello of %s: %v", peerID.String(), err)
+	}
+	s.deliver(NewHelloStamped(peerID, msg))
+}
+
+// deliver hands the hello to the identification of the peer, or keeps it until it waits for it
+func (s *HelloService) deliver(hello HelloStamped) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if waiters := s.waiters[hello.PeerID]; len(waiters) > 0 {
+		for _, waiter := range waiters {
+			waiter <- hello
+		}
+		delete(s.waiters, hello.PeerID)
+		return
+	}
+	// drop the hellos of the peers that were never waited for
+	for peerID, received := range s.received {
+		if time.Since(received.Timestamp) > helloRetention {
+			delete(s.received, peerID)
+		}
+	}
+	s.received[hello.PeerID] = hello
+}
+
+// WaitHello waits for the hello of the given peer, which arrives right after it identifies us
+func (s *HelloService) WaitHello(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	peerID peer.ID,
+	result *HelloStamped,
+	finErr *error) {
+
+	defer wg.Done()
+
+	s.m.Lock()
+	if hello, ok := s.received[peerID]; ok {
+		delete(s.received, peerID)
+		s.m.Unlock()
+		*result = hello
+		return
+	}
+	waiter := make(chan HelloStamped, 1)
+	s.waiters[peerID] = append(s.waiters[peerID], waiter)
+	s.m.Unlock()
+
+	select {
+	case hello := <-waiter:
+		*result = hello
+	case <-ctx.Done():
+		s.m.Lock()
+		waiters := s.waiters[peerID]
+		for i, w := range waiters {
+			if w == waiter {
+				s.waiters[peerID] = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(s.waiters[peerID]) == 0 {
+			delete(s.waiters, peerID)
+		}
+		s.m.Unlock()
+		*finErr = errors.Wrap(ctx.Err(), "no hello received from the peer")
+	}
+}
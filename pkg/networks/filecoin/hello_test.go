@@ -0,0 +1,102 @@
+package filecoin
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func testHello(t *testing.T) *HelloMessage {
+	genesis, err := cid.Decode("bafy2bzacecnamqgqmifpluoeldx7zzglxcljo6oja4vrmtj7432rphldpdmm2")
+	require.NoError(t, err)
+	head, err := cid.Decode("bafy2bzacedgdplxwkbj2ibl6nznqpbkvcgxrsamzcqxndvq6gmlvtd5epn2eg")
+	require.NoError(t, err)
+	return &HelloMessage{
+		HeaviestTipSet:       []cid.Cid{head, genesis},
+		HeaviestTipSetHeight: 4_235_102,
+		HeaviestTipSetWeight: new(big.Int).Lsh(big.NewInt(91), 80),
+		GenesisHash:          genesis,
+	}
+}
+
+func Test_HelloCodec(t *testing.T) {
+	msg := testHello(t)
+	raw := EncodeHello(msg)
+	decoded, err := DecodeHello(raw)
+	require.NoError(t, err)
+	require.Equal(t, msg.HeaviestTipSet, decoded.HeaviestTipSet)
+	require.Equal(t, msg.HeaviestTipSetHeight, decoded.HeaviestTipSetHeight)
+	require.Zero(t, msg.HeaviestTipSetWeight.Cmp(decoded.HeaviestTipSetWeight))
+	require.Equal(t, msg.GenesisHash, decoded.GenesisHash)
+
+	// the message is read from a stream that is kept open, so the truncated ones have to be told apart
+	_, err = DecodeHello(raw[:len(raw)-3])
+	require.Equal(t, errShortMessage, err)
+	read, err := ReadHello(bytes.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, msg.GenesisHash, read.GenesisHash)
+	_, err = DecodeHello(EncodeLatency(time.Now(), time.Now()))
+	require.ErrorIs(t, err, ErrInvalidHello)
+
+	arrival := time.Unix(1700000000, 5)
+	latency, err := DecodeLatency(EncodeLatency(arrival, arrival.Add(time.Millisecond)))
+	require.NoError(t, err)
+	require.Equal(t, arrival.UnixNano(), latency.TArrival)
+	require.Equal(t, int64(time.Millisecond), latency.TSent-latency.TArrival)
+}
+
+func Test_HelloService(t *testing.T) {
+	local, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer local.Close()
+	remote, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer remote.Close()
+
+	service := NewHelloService()
+	service.Serve(local)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, remote.Connect(ctx, peer.AddrInfo{ID: local.ID(), Addrs: local.Addrs()}))
+
+	// the remote peer says hello, and reads our answer
+	msg := testHello(t)
+	stream, err := remote.NewStream(ctx, local.ID(), HelloProtocolID)
+	require.NoError(t, err)
+	_, err = stream.Write(EncodeHello(msg))
+	require.NoError(t, err)
+	answer := make([]byte, 64)
+	n, err := stream.Read(answer)
+	require.NoError(t, err)
+	_, err = DecodeLatency(answer[:n])
+	require.NoError(t, err)
+	stream.Close()
+
+	// the hello arrived before the identification of the peer waited for it
+	var wg sync.WaitGroup
+	var hello HelloStamped
+	var helloErr error
+	wg.Add(1)
+	service.WaitHello(ctx, &wg, remote.ID(), &hello, &helloErr)
+	require.NoError(t, helloErr)
+	require.Equal(t, remote.ID(), hello.PeerID)
+	require.Equal(t, msg.GenesisHash.String(), hello.GenesisHash)
+	require.Equal(t, msg.HeaviestTipSetHeight, hello.HeadHeight)
+
+	// the peers that never say hello time out
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer timeoutCancel()
+	wg.Add(1)
+	service.WaitHello(timeoutCtx, &wg, remote.ID(), &hello, &helloErr)
+	require.Error(t, helloErr)
+	require.Empty(t, service.waiters)
+}
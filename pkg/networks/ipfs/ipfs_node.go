@@ -0,0 +1,38 @@
+package ipfs
+
+import (
+	"github.com/migalabs/armiarma/pkg/networks/filecoin"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// LocalIpfsNode represents our local node in an IPFS-like network (IPFS, Filecoin or Waku)
+type LocalIpfsNode struct {
+	network utils.NetworkType
+	// answers the hellos of the Filecoin nodes (nil in the rest of the networks)
+	hello *filecoin.HelloService
+}
+
+func NewLocalIpfsNode(network utils.NetworkType) (*LocalIpfsNode, error) {
+	switch network {
+	case utils.IpfsNetwork, utils.FilecoinNetwork, utils.WakuNetwork:
+	default:
+		return nil, errors.Errorf("network %s is not an IPFS-like network", network)
+	}
+	node := &LocalIpfsNode{
+		network: network,
+	}
+	if network == utils.FilecoinNetwork {
+		node.hello = filecoin.NewHelloService()
+	}
+	return node, nil
+}
+
+func (n *LocalIpfsNode) Network() utils.NetworkType {
+	return n.network
+}
+
+// Hello returns the service that answers the hellos of the Filecoin nodes (nil in the rest of the networks)
+func (n *LocalIpfsNode) Hello() *filecoin.HelloService {
+	return n.hello
+}
@@ -0,0 +1,124 @@
+package portal
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+var (
+	// attribute of the HostInfo with the sub-networks of the Portal node
+	PortalNodeAttribute string = "portal-node"
+
+	// ENR key where the Portal clients advertise their name and version (i.e. "t 0.1.0")
+	ClientEnrKey string = "c"
+
+	// short names of the clients in the ENR client entry
+	enrClientNames = map[string]string{
+		"t": "trin",
+		"f": "fluffy",
+		"u": "ultralight",
+		"s": "shisui",
+	}
+
+	// size of the content id space, to express the radius as the share of it stored by the node
+	idSpace = new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 256))
+)
+
+// SubNetworkRadius is the radius that a node advertised in one of the sub-networks it supports
+type SubNetworkRadius struct {
+	SubNetwork string
+	Radius     *big.Int
+}
+
+// RadiusRatio returns the share of the content id space that the node stores in the sub-network
+func (s SubNetworkRadius) RadiusRatio() float64 {
+	ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(s.Radius), idSpace).Float64()
+	return ratio
+}
+
+// PortalNode gathers what the crawler learnt pinging a node in each of the Portal sub-networks
+type PortalNode struct {
+	Timestamp time.Time
+	PeerID    peer.ID
+	NodeID    enode.ID
+	EnrSeq    uint64
+	// client info of the pongs with the ping extensions
+	ClientInfo string
+	// client entry of the ENR
+	EnrClient    string
+	Capabilities []uint16
+	SubNetworks  []SubNetworkRadius
+}
+
+func NewPortalNode(peerID peer.ID, node *enode.Node) *PortalNode {
+	return &PortalNode{
+		Timestamp:    time.Now(),
+		PeerID:       peerID,
+		NodeID:       node.ID(),
+		EnrSeq:       node.Seq(),
+		EnrClient:    EnrClient(node),
+		Capabilities: make([]uint16, 0),
+		SubNetworks:  make([]SubNetworkRadius, 0),
+	}
+}
+
+// AddPong records the answer of the node in the given sub-network
+func (n *PortalNode) AddPong(subNetwork string, pong *Pong) {
+	n.SubNetworks = append(n.SubNetworks, SubNetworkRadius{
+		SubNetwork: subNetwork,
+		Radius:     pong.Radius,
+	})
+	if pong.ClientInfo != "" {
+		n.ClientInfo = pong.ClientInfo
+		n.Capabilities = pong.Capabilities
+	}
+}
+
+// UserAgent returns the client info of the pongs, or the one composed out of the ENR client
+// entry (i.e. "trin/0.1.0" for "t 0.1.0") for the clients without the ping extensions
+func (n *PortalNode) UserAgent() string {
+	if n.ClientInfo != "" {
+		return n.ClientInfo
+	}
+	return EnrClientUserAgent(n.EnrClient)
+}
+
+// Protocols returns the names of the sub-networks supported by the node
+func (n *PortalNode) Protocols() []string {
+	protocols := make([]string, 0, len(n.SubNetworks))
+	for _, sub := range n.SubNetworks {
+		protocols = append(protocols, sub.SubNetwork)
+	}
+	return protocols
+}
+
+// EnrClient returns the client entry of the ENR of the node (empty if it doesn't have it)
+func EnrClient(node *enode.Node) string {
+	var client string
+	if err := node.Load(enr.WithEntry(ClientEnrKey, &client)); err != nil {
+		return ""
+	}
+	return client
+}
+
+// EnrClientUserAgent composes a user agent out of the ENR client entry, so that the client can
+// be identified as the ones that advertise it in the pongs
+func EnrClientUserAgent(enrClient string) string {
+	fields := strings.Fields(enrClient)
+	if len(fields) == 0 {
+		return ""
+	}
+	name, ok := enrClientNames[fields[0]]
+	if !ok {
+		name = fields[0]
+	}
+	if len(fields) == 1 {
+		return name
+	}
+	return name + "/" + fields[1]
+}
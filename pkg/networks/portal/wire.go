@@ -0,0 +1,200 @@
+package portal
+
+/**
+This file implements the messages of the Portal Network wire protocol that the crawler
+needs to identify the Portal clients: the PING/PONG exchanged over discv5 TALKREQ/TALKRESP
+(https://github.com/ethereum/portal-network-specs/blob/master/portal-wire-protocol.md).
+
+*/
+
+import (
+	"encoding/binary"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// message selectors of the wire protocol
+const (
+	pingMsg byte = 0x00
+	pongMsg byte = 0x01
+)
+
+// payload types of the ping extensions
+const (
+	ClientInfoPayload    uint16 = 0
+	BasicRadiusPayload   uint16 = 1
+	HistoryRadiusPayload uint16 = 2
+	ErrorPayload         uint16 = 65535
+)
+
+const (
+	// enr_seq (8) + payload_type (2) + offset of the payload (4)
+	pingFixedSize = 14
+	// enr_seq (8) + offset of the custom payload (4), before the ping extensions
+	legacyPingFixedSize = 12
+	// offset of client_info (4) + data_radius (32) + offset of capabilities (4)
+	clientInfoFixedSize = 40
+
+	maxClientInfoSize = 200
+)
+
+var ErrInvalidPong = errors.New("invalid portal pong")
+
+// SubNetwork is one of the overlay networks of Portal, each with its own TALKREQ protocol id
+type SubNetwork struct {
+	Name       string
+	ProtocolID string
+}
+
+// sub-networks of the Portal mainnet
+var SubNetworks = []SubNetwork{
+	{Name: "state", ProtocolID: "\x50\x0a"},
+	{Name: "history", ProtocolID: "\x50\x0b"},
+	{Name: "beacon", ProtocolID: "\x50\x0c"},
+	{Name: "transaction_index", ProtocolID: "\x50\x0d"},
+	{Name: "verkle", ProtocolID: "\x50\x0e"},
+	{Name: "transaction_gossip", ProtocolID: "\x50\x0f"},
+}
+
+// SubNetworkByName returns the sub-network with the given name
+func SubNetworkByName(name string) (SubNetwork, bool) {
+	for _, sub := range SubNetworks {
+		if sub.Name == strings.ToLower(name) {
+			return sub, true
+		}
+	}
+	return SubNetwork{}, false
+}
+
+// Pong is the answer of a node to our ping in one of the sub-networks
+type Pong struct {
+	EnrSeq      uint64
+	PayloadType uint16
+	// amount of the content id space that the node stores, as a uint256
+	Radius       *big.Int
+	ClientInfo   string
+	Capabilities []uint16
+}
+
+// EncodePing composes the ping that advertises our client info and a null radius
+// (the crawler doesn't store any content)
+func EncodePing(enrSeq uint64, clientInfo string) []byte {
+	payload := encodeClientInfoPayload(clientInfo, new(big.Int), []uint16{ClientInfoPayload})
+	return encodePingPong(pingMsg, enrSeq, ClientInfoPayload, payload)
+}
+
+// EncodePong composes the pong that answers the pings of the nodes that add us to their tables
+func EncodePong(enrSeq uint64) []byte {
+	radius := make([]byte, 32)
+	encodeRadius(radius, new(big.Int))
+	return encodePingPong(pongMsg, enrSeq, BasicRadiusPayload, radius)
+}
+
+func encodeClientInfoPayload(clientInfo string, radius *big.Int, capabilities []uint16) []byte {
+	if len(clientInfo) > maxClientInfoSize {
+		clientInfo = clientInfo[:maxClientInfoSize]
+	}
+	payload := make([]byte, clientInfoFixedSize, clientInfoFixedSize+len(clientInfo)+2*len(capabilities))
+	binary.LittleEndian.PutUint32(payload[0:4], clientInfoFixedSize)
+	encodeRadius(payload[4:36], radius)
+	binary.LittleEndian.PutUint32(payload[36:40], uint32(clientInfoFixedSize+len(clientInfo)))
+	payload = append(payload, clientInfo...)
+	for _, capability := range capabilities {
+		payload = binary.LittleEndian.AppendUint16(payload, capability)
+	}
+	return payload
+}
+
+func encodePingPong(selector byte, enrSeq uint64, payloadType uint16, payload []byte) []byte {
+	msg := make([]byte, 1+pingFixedSize, 1+pingFixedSize+len(payload))
+	msg[0] = selector
+	binary.LittleEndian.PutUint64(msg[1:9], enrSeq)
+	binary.LittleEndian.PutUint16(msg[9:11], payloadType)
+	binary.LittleEndian.PutUint32(msg[11:15], pingFixedSize)
+	return append(msg, payload...)
+}
+
+// IsPing checks whether the given TALKREQ is a ping of the wire protocol
+func IsPing(msg []byte) bool {
+	return len(msg) > 0 && msg[0] == pingMsg
+}
+
+// DecodePong parses the pong of a node, both with the ping extensions and with the
+// previous format (whose custom payload only has the radius)
+func DecodePong(msg []byte) (*Pong, error) {
+	if len(msg) < 1+legacyPingFixedSize || msg[0] != pongMsg {
+		return nil, ErrInvalidPong
+	}
+	pong := &Pong{
+		EnrSeq: binary.LittleEndian.Uint64(msg[1:9]),
+	}
+	if binary.LittleEndian.Uint32(msg[9:13]) == legacyPingFixedSize {
+		radius, err := decodeRadius(msg[1+legacyPingFixedSize:])
+		if err != nil {
+			return nil, err
+		}
+		pong.PayloadType = BasicRadiusPayload
+		pong.Radius = radius
+		return pong, nil
+	}
+
+	if len(msg) < 1+pingFixedSize || binary.LittleEndian.Uint32(msg[11:15]) != pingFixedSize {
+		return nil, ErrInvalidPong
+	}
+	pong.PayloadType = binary.LittleEndian.Uint16(msg[9:11])
+	payload := msg[1+pingFixedSize:]
+	switch pong.PayloadType {
+	case ClientInfoPayload:
+		if len(payload) < clientInfoFixedSize {
+			return nil, errors.Wrap(ErrInvalidPong, "short client info payload")
+		}
+		infoOffset := binary.LittleEndian.Uint32(payload[0:4])
+		capsOffset := binary.LittleEndian.Uint32(payload[36:40])
+		if infoOffset != clientInfoFixedSize || capsOffset < infoOffset || int(capsOffset) > len(payload) ||
+			(len(payload)-int(capsOffset))%2 != 0 {
+			return nil, errors.Wrap(ErrInvalidPong, "wrong offsets in the client info payload")
+		}
+		radius, err := decodeRadius(payload[4:36])
+		if err != nil {
+			return nil, err
+		}
+		pong.Radius = radius
+		pong.ClientInfo = string(payload[infoOffset:capsOffset])
+		for i := int(capsOffset); i < len(payload); i += 2 {
+			pong.Capabilities = append(pong.Capabilities, binary.LittleEndian.Uint16(payload[i:i+2]))
+		}
+	case BasicRadiusPayload, HistoryRadiusPayload:
+		radius, err := decodeRadius(payload)
+		if err != nil {
+			return nil, err
+		}
+		pong.Radius = radius
+	case ErrorPayload:
+		return nil, errors.Wrap(ErrInvalidPong, "the node answered with an error payload")
+	default:
+		return nil, errors.Wrapf(ErrInvalidPong, "unknown payload type %d", pong.PayloadType)
+	}
+	return pong, nil
+}
+
+// decodeRadius reads the little-endian uint256 at the beginning of the payload
+func decodeRadius(payload []byte) (*big.Int, error) {
+	if len(payload) < 32 {
+		return nil, errors.Wrap(ErrInvalidPong, "short radius")
+	}
+	be := make([]byte, 32)
+	for i := 0; i < 32; i++ {
+		be[31-i] = payload[i]
+	}
+	return new(big.Int).SetBytes(be), nil
+}
+
+// encodeRadius writes the radius as a little-endian uint256 in the given 32 bytes
+func encodeRadius(dst []byte, radius *big.Int) {
+	be := radius.FillBytes(make([]byte, 32))
+	for i := 0; i < 32; i++ {
+		dst[31-i] = be[i]
+	}
+}
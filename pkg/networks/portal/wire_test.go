@@ -0,0 +1,59 @@
+package portal
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PingPong(t *testing.T) {
+	ping := EncodePing(7, "armiarma/v2.0.0")
+	require.True(t, IsPing(ping))
+	require.Equal(t, uint64(7), binary.LittleEndian.Uint64(ping[1:9]))
+
+	// pong with the ping extensions, advertising the client and its capabilities
+	radius := new(big.Int).Lsh(big.NewInt(1), 255)
+	clientInfo := "trin/v0.1.1-2b00d730/linux-x86_64/rustc1.81.0"
+	msg := encodePingPong(pongMsg, 3, ClientInfoPayload, encodeClientInfoPayload(clientInfo, radius, []uint16{0, 1, 2}))
+	pong, err := DecodePong(msg)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), pong.EnrSeq)
+	require.Equal(t, clientInfo, pong.ClientInfo)
+	require.Equal(t, []uint16{0, 1, 2}, pong.Capabilities)
+	require.Equal(t, 0, radius.Cmp(pong.Radius))
+	require.Equal(t, 0.5, SubNetworkRadius{Radius: pong.Radius}.RadiusRatio())
+
+	// our own pong
+	pong, err = DecodePong(EncodePong(1))
+	require.NoError(t, err)
+	require.Equal(t, BasicRadiusPayload, pong.PayloadType)
+	require.Zero(t, pong.Radius.Sign())
+
+	// pong of the clients without the ping extensions (custom payload with the radius)
+	legacy := make([]byte, 1+legacyPingFixedSize+32)
+	legacy[0] = pongMsg
+	binary.LittleEndian.PutUint32(legacy[9:13], legacyPingFixedSize)
+	legacy[1+legacyPingFixedSize] = 0xff
+	pong, err = DecodePong(legacy)
+	require.NoError(t, err)
+	require.Equal(t, int64(0xff), pong.Radius.Int64())
+
+	for _, invalid := range [][]byte{
+		nil,
+		ping,
+		encodePingPong(pongMsg, 1, ErrorPayload, []byte{0, 0}),
+		encodePingPong(pongMsg, 1, ClientInfoPayload, make([]byte, 12)),
+	} {
+		_, err = DecodePong(invalid)
+		require.Error(t, err)
+	}
+}
+
+func Test_EnrClientUserAgent(t *testing.T) {
+	require.Equal(t, "trin/0.1.1-2b00d730", EnrClientUserAgent("t 0.1.1-2b00d730"))
+	require.Equal(t, "fluffy", EnrClientUserAgent("f"))
+	require.Equal(t, "x/1.0", EnrClientUserAgent("x 1.0"))
+	require.Equal(t, "", EnrClientUserAgent(""))
+}
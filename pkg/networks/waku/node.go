@@ -0,0 +1,162 @@
+package waku
+
+/**
+This file parses the Waku entries of the ENRs (https://rfc.vac.dev/waku/standards/core/31/enr):
+the services that the node advertises, the relay shards it is subscribed to, and the multiaddrs
+through which it can be reached besides the ip/tcp of the record.
+
+*/
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+)
+
+var (
+	// attribute of the HostInfo with the Waku entries of the ENR
+	WakuNodeAttribute string = "waku-node"
+
+	// ENR keys of the Waku nodes
+	WakuEnrKey        string = "waku2"
+	MultiaddrsEnrKey  string = "multiaddrs"
+	ShardsEnrKey      string = "rs"
+	ShardsVectorKey   string = "rsv"
+	shardsVectorBytes int    = 128
+
+	// bits of the waku2 field, from the least significant one
+	enrServices = []string{Relay, Store, Filter, Lightpush, Sync}
+
+	ErrNotWakuNode = errors.New("not a waku node - no waku2 entry in the ENR")
+)
+
+// WakuNode gathers the Waku entries of the ENR of a node
+type WakuNode struct {
+	Timestamp time.Time
+	PeerID    peer.ID
+	NodeID    enode.ID
+	EnrSeq    uint64
+	// services advertised in the waku2 field
+	Services []string
+	// relay shards (-1 as cluster if the ENR doesn't advertise them)
+	ClusterID  int
+	Shards     []int
+	Multiaddrs []ma.Multiaddr
+}
+
+// ParseWakuNode reads the Waku entries of the ENR of the node, failing with ErrNotWakuNode
+// if the node doesn't have the waku2 field
+func ParseWakuNode(peerID peer.ID, node *enode.Node) (*WakuNode, error) {
+	var field []byte
+	if err := node.Load(enr.WithEntry(WakuEnrKey, &field)); err != nil || len(field) == 0 {
+		return nil, ErrNotWakuNode
+	}
+	wakuNode := &WakuNode{
+		Timestamp:  time.Now(),
+		PeerID:     peerID,
+		NodeID:     node.ID(),
+		EnrSeq:     node.Seq(),
+		Services:   EnrServices(field[0]),
+		ClusterID:  -1,
+		Shards:     make([]int, 0),
+		Multiaddrs: make([]ma.Multiaddr, 0),
+	}
+
+	// the shards can be given as a list or as a bit vector
+	var shards []byte
+	if err := node.Load(enr.WithEntry(ShardsEnrKey, &shards)); err == nil {
+		clusterID, indexes, err := DecodeShardsList(shards)
+		if err != nil {
+			return nil, err
+		}
+		wakuNode.ClusterID, wakuNode.Shards = clusterID, indexes
+	} else if err := node.Load(enr.WithEntry(ShardsVectorKey, &shards)); err == nil {
+		clusterID, indexes, err := DecodeShardsVector(shards)
+		if err != nil {
+			return nil, err
+		}
+		wakuNode.ClusterID, wakuNode.Shards = clusterID, indexes
+	}
+
+	var maddrs []byte
+	if err := node.Load(enr.WithEntry(MultiaddrsEnrKey, &maddrs)); err == nil {
+		decoded, err := DecodeMultiaddrs(maddrs)
+		if err != nil {
+			return nil, err
+		}
+		wakuNode.Multiaddrs = decoded
+	}
+	return wakuNode, nil
+}
+
+// EnrServices returns the services flagged in the waku2 field of the ENR
+func EnrServices(field byte) []string {
+	services := make([]string, 0, len(enrServices))
+	for i, service := range enrServices {
+		if field&(1<<i) != 0 {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+// DecodeShardsList decodes the rs field: the cluster (uint16), the number of shards (uint8)
+// and the index of each shard (uint16)
+func DecodeShardsList(raw []byte) (int, []int, error) {
+	if len(raw) < 3 {
+		return 0, nil, errors.New("short rs field")
+	}
+	clusterID := int(binary.BigEndian.Uint16(raw[0:2]))
+	count := int(raw[2])
+	if len(raw) != 3+2*count {
+		return 0, nil, errors.Errorf("rs field of %d bytes with %d shards", len(raw), count)
+	}
+	shards := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		shards = append(shards, int(binary.BigEndian.Uint16(raw[3+2*i:5+2*i])))
+	}
+	return clusterID, shards, nil
+}
+
+// DecodeShardsVector decodes the rsv field: the cluster (uint16) and a bit vector of the 1024 shards
+func DecodeShardsVector(raw []byte) (int, []int, error) {
+	if len(raw) != 2+shardsVectorBytes {
+		return 0, nil, errors.Errorf("rsv field of %d bytes", len(raw))
+	}
+	clusterID := int(binary.BigEndian.Uint16(raw[0:2]))
+	shards := make([]int, 0)
+	for i, b := range raw[2:] {
+		for j := 0; j < 8; j++ {
+			if b&(1<<j) != 0 {
+				shards = append(shards, i*8+j)
+			}
+		}
+	}
+	return clusterID, shards, nil
+}
+
+// DecodeMultiaddrs decodes the multiaddrs field: each multiaddr prefixed by its size (uint16)
+func DecodeMultiaddrs(raw []byte) ([]ma.Multiaddr, error) {
+	maddrs := make([]ma.Multiaddr, 0)
+	for len(raw) > 0 {
+		if len(raw) < 2 {
+			return nil, errors.New("short multiaddrs field")
+		}
+		size := int(binary.BigEndian.Uint16(raw[0:2]))
+		if len(raw) < 2+size {
+			return nil, errors.Errorf("multiaddr of %d bytes out of the multiaddrs field", size)
+		}
+		maddr, err := ma.NewMultiaddrBytes(raw[2 : 2+size])
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid multiaddr in the multiaddrs field")
+		}
+		maddrs = append(maddrs, maddr)
+		raw = raw[2+size:]
+	}
+	return maddrs, nil
+}
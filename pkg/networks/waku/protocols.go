@@ -0,0 +1,114 @@
+package waku
+
+/**
+This file describes what the crawler needs to identify the Waku v2 nodes over libp2p: the
+protocols that each service mounts (https://rfc.vac.dev/waku/standards/core/), and the pubsub
+topics of the relay shards whose messages are accounted in the gossip metrics.
+
+*/
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+// services of a Waku node
+const (
+	Relay        = "relay"
+	Store        = "store"
+	Filter       = "filter"
+	Lightpush    = "lightpush"
+	Sync         = "sync"
+	PeerExchange = "peer-exchange"
+	Metadata     = "metadata"
+)
+
+// prefixes of the protocol ids of the services (the filter-push protocol is mounted by the
+// filter clients, so it isn't accounted as a service)
+var serviceProtocols = map[string]string{
+	"/vac/waku/relay/":            Relay,
+	"/vac/waku/store/":            Store,
+	"/vac/waku/store-query/":      Store,
+	"/vac/waku/filter-subscribe/": Filter,
+	"/vac/waku/lightpush/":        Lightpush,
+	"/vac/waku/sync/":             Sync,
+	"/vac/waku/reconciliation/":   Sync,
+	"/vac/waku/peer-exchange/":    PeerExchange,
+	"/vac/waku/metadata/":         Metadata,
+}
+
+// Services returns the Waku services that a node supports, out of the protocols it
+// advertised in the identify (sorted as the protocols, without duplicates)
+func Services(protocols []string) []string {
+	services := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, protocol := range protocols {
+		if !strings.HasPrefix(protocol, "/vac/waku/") {
+			continue
+		}
+		for prefix, service := range serviceProtocols {
+			if !strings.HasPrefix(protocol, prefix) {
+				continue
+			}
+			if _, ok := seen[service]; !ok {
+				seen[service] = struct{}{}
+				services = append(services, service)
+			}
+		}
+	}
+	return services
+}
+
+const (
+	// cluster of The Waku Network, the public network of the Status app
+	DefaultClusterID = 1
+	// shards of the relay in The Waku Network
+	DefaultShards = 8
+
+	// pubsub topic of the relay before the static sharding
+	DefaultPubsubTopic = "/waku/2/default-waku/proto"
+
+	shardTopicPrefix = "/waku/2/rs/"
+)
+
+// ShardTopic composes the pubsub topic of the given static shard (i.e. "/waku/2/rs/1/0")
+func ShardTopic(clusterID, shard int) string {
+	return fmt.Sprintf("%s%d/%d", shardTopicPrefix, clusterID, shard)
+}
+
+// ShardTopics composes the pubsub topics of the given shards of a cluster
+func ShardTopics(clusterID int, shards []int) []string {
+	topics := make([]string, 0, len(shards))
+	for _, shard := range shards {
+		topics = append(topics, ShardTopic(clusterID, shard))
+	}
+	return topics
+}
+
+// SubnetOfTopic returns the shard of the relay topic, so that the message rates are
+// aggregated per shard
+func SubnetOfTopic(topic string) (string, int, bool) {
+	if !strings.HasPrefix(topic, shardTopicPrefix) {
+		return "", 0, false
+	}
+	chunks := strings.Split(strings.TrimPrefix(topic, shardTopicPrefix), "/")
+	if len(chunks) != 2 {
+		return "", 0, false
+	}
+	shard, err := strconv.Atoi(chunks[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return "shard", shard, true
+}
+
+// MsgIDFunction computes the message-id of the relay messages as the Waku clients do,
+// with the sha256 of the data of the message
+func MsgIDFunction(pmsg *pubsub_pb.Message) string {
+	h := sha256.Sum256(pmsg.Data)
+	return string(h[:])
+}
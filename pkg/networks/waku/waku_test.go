@@ -0,0 +1,90 @@
+package waku
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Services(t *testing.T) {
+	protocols := []string{
+		"/ipfs/id/1.0.0",
+		"/vac/waku/relay/2.0.0",
+		"/vac/waku/store-query/3.0.0",
+		"/vac/waku/store/2.0.0-beta4",
+		"/vac/waku/filter-push/2.0.0-beta1",
+		"/vac/waku/lightpush/2.0.0-beta1",
+		"/vac/waku/peer-exchange/2.0.0-alpha1",
+		"/vac/waku/metadata/1.0.0",
+	}
+	require.Equal(t, []string{Relay, Store, Lightpush, PeerExchange, Metadata}, Services(protocols))
+	require.Empty(t, Services([]string{"/ipfs/kad/1.0.0"}))
+}
+
+func Test_ShardTopics(t *testing.T) {
+	require.Equal(t, []string{"/waku/2/rs/1/0", "/waku/2/rs/1/7"}, ShardTopics(DefaultClusterID, []int{0, 7}))
+
+	kind, shard, ok := SubnetOfTopic("/waku/2/rs/1/5")
+	require.True(t, ok)
+	require.Equal(t, "shard", kind)
+	require.Equal(t, 5, shard)
+
+	_, _, ok = SubnetOfTopic(DefaultPubsubTopic)
+	require.False(t, ok)
+}
+
+func Test_ParseWakuNode(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	// the nodes without the waku2 field aren't Waku nodes
+	_, err = ParseWakuNode("", signedNode(t, key))
+	require.ErrorIs(t, err, ErrNotWakuNode)
+
+	// relay + store + lightpush in the shards 0 and 3 of the cluster 1
+	shards := []byte{0, 1, 2, 0, 0, 0, 3}
+	maddr, err := ma.NewMultiaddr("/dns4/node.example.org/tcp/8000/wss")
+	require.NoError(t, err)
+	maddrs := binary.BigEndian.AppendUint16(nil, uint16(len(maddr.Bytes())))
+	maddrs = append(maddrs, maddr.Bytes()...)
+
+	node := signedNode(t, key,
+		enr.WithEntry(WakuEnrKey, []byte{0b01011}),
+		enr.WithEntry(ShardsEnrKey, shards),
+		enr.WithEntry(MultiaddrsEnrKey, maddrs),
+	)
+	wakuNode, err := ParseWakuNode("", node)
+	require.NoError(t, err)
+	require.Equal(t, []string{Relay, Store, Lightpush}, wakuNode.Services)
+	require.Equal(t, 1, wakuNode.ClusterID)
+	require.Equal(t, []int{0, 3}, wakuNode.Shards)
+	require.Len(t, wakuNode.Multiaddrs, 1)
+	require.True(t, maddr.Equal(wakuNode.Multiaddrs[0]))
+
+	// the shards can also be given as a bit vector
+	vector := make([]byte, 2+shardsVectorBytes)
+	binary.BigEndian.PutUint16(vector[0:2], 16)
+	vector[2] = 0b10000001
+	vector[3] = 0b00000010
+	clusterID, indexes, err := DecodeShardsVector(vector)
+	require.NoError(t, err)
+	require.Equal(t, 16, clusterID)
+	require.Equal(t, []int{0, 7, 9}, indexes)
+}
+
+func signedNode(t *testing.T, key *ecdsa.PrivateKey, entries ...enr.Entry) *enode.Node {
+	var r enr.Record
+	for _, entry := range entries {
+		r.Set(entry)
+	}
+	require.NoError(t, enode.SignV4(&r, key))
+	node, err := enode.New(enode.ValidSchemes, &r)
+	require.NoError(t, err)
+	return node
+}
@@ -0,0 +1,370 @@
+package peering
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// BatchStrategy is a Peering Strategy that dials, round after round, the batches of peers that its
+// PeerSelector picks among the known peers of the DB, notifying the selector of the result of each dial.
+// Unlike the pruning strategy, it never deprecates the peers (that is left to the selector).
+type BatchStrategy struct {
+	ctx context.Context
+
+	network  utils.NetworkType
+	DBClient storage.Client
+	selector PeerSelector
+
+	// communication with the peering service
+	peerStreamChan chan *models.HostInfo
+	nextPeerChan   chan struct{}
+	connAttemptNot chan *models.ConnectionAttempt
+	connEventNot   chan *models.EventTrace
+	identEventNot  chan hosts.IdentificationEvent
+
+	// notification of the finished rounds (batches)
+	roundC chan RoundStats
+	round  int64
+
+	m        sync.RWMutex
+	known    int
+	batch    []*models.RemoteConnectablePeer
+	batchPtr int
+	// last error of each of the dialed peers
+	lastErrors map[peer.ID]string
+	lastDials  map[peer.ID]time.Time
+	// pending peers of the batch interrupted by the previous run, dialed in the first round
+	resumed []peer.ID
+	// Prometheus Control Variables
+	lastIterTime   time.Duration
+	lastAttempted  int64
+	attemptsStatus map[string]int64
+	connErrors     map[string]int64
+}
+
+func NewBatchStrategy(
+	ctx context.Context,
+	network utils.NetworkType,
+	dbClient storage.Client,
+	selector PeerSelector) (*BatchStrategy, error) {
+
+	if selector == nil {
+		return nil, errors.New("nil peer selector")
+	}
+	return &BatchStrategy{
+		ctx:            ctx,
+		network:        network,
+		DBClient:       dbClient,
+		selector:       selector,
+		peerStreamChan: make(chan *models.HostInfo, DefaultWorkers),
+		nextPeerChan:   make(chan struct{}, DefaultWorkers),
+		connAttemptNot: make(chan *models.ConnectionAttempt),
+		connEventNot:   make(chan *models.EventTrace),
+		identEventNot:  make(chan hosts.IdentificationEvent),
+		roundC:         make(chan RoundStats, roundBuffer),
+		batch:          make([]*models.RemoteConnectablePeer, 0),
+		lastErrors:     make(map[peer.ID]string),
+		lastDials:      make(map[peer.ID]time.Time),
+		attemptsStatus: make(map[string]int64),
+		connErrors:     make(map[string]int64),
+	}, nil
+}
+
+// Type returns the type of the selector of the strategy
+func (c *BatchStrategy) Type() string {
+	return c.selector.Type()
+}
+
+func (c *BatchStrategy) Run() chan *models.HostInfo {
+	go c.batchIteratorRoutine()
+	go c.eventRecorderRoutine()
+	return c.peerStreamChan
+}
+
+// Rounds returns the channel where the stats of each finished batch are notified
+func (c *BatchStrategy) Rounds() <-chan RoundStats {
+	return c.roundC
+}
+
+// batchIteratorRoutine hands the peers of the current batch to the peering service, and asks
+// the selector for a new batch once the current one is over
+func (c *BatchStrategy) batchIteratorRoutine() {
+	logEntry := log.WithFields(log.Fields{
+		"mod": "batch-strgy-itr",
+	})
+	logEntry.Debug("init")
+
+	iterStartTime := time.Now()
+	validIterTimer := time.NewTimer(MinIterTime)
+	c.nextBatch()
+
+	for {
+		select {
+		case <-c.nextPeerChan:
+			if nextPeer, ok := c.popPeer(); ok {
+				logEntry.Tracef("pushing next peer %s into peer stream", nextPeer.ID.String())
+				c.peerStreamChan <- models.NewHostInfo(
+					nextPeer.ID,
+					nextPeer.Network,
+					models.WithMultiaddress(nextPeer.Addrs),
+				)
+				continue
+			}
+			// the batch is over, wait the minimum iteration time before the next one
+			<-validIterTimer.C
+			c.endRound(iterStartTime)
+			c.nextBatch()
+			validIterTimer = time.NewTimer(MinIterTime)
+			iterStartTime = time.Now()
+			// recreate the call of the peer that the iterator just used
+			c.NextPeer()
+
+		case <-c.ctx.Done():
+			logEntry.Debug("closing")
+			close(c.peerStreamChan)
+			close(c.nextPeerChan)
+			close(c.connEventNot)
+			return
+		}
+	}
+}
+
+// nextBatch asks the selector for the peers of the next round among the known ones
+func (c *BatchStrategy) nextBatch() {
+	known, err := c.DBClient.GetNonDeprecatedPeers()
+	if err != nil {
+		log.Error(errors.Wrap(err, "fail to update the known peers of the batch strategy"))
+	}
+	batch := c.resumedBatch(known)
+	if len(batch) == 0 {
+		batch = c.selector.NextPeerBatch(known)
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.known = len(known)
+	c.batch = batch
+	c.batchPtr = 0
+	c.connErrors = make(map[string]int64)
+	c.attemptsStatus = make(map[string]int64)
+	log.Debugf("new %s batch of %d peers out of %d known ones", c.selector.Type(), len(batch), len(known))
+}
+
+func (c *BatchStrategy) popPeer() (*models.RemoteConnectablePeer, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.batchPtr >= len(c.batch) {
+		return nil, false
+	}
+	nextPeer := c.batch[c.batchPtr]
+	c.batchPtr++
+	return nextPeer, true
+}
+
+func (c *BatchStrategy) endRound(start time.Time) {
+	c.m.Lock()
+	c.round++
+	c.lastIterTime = time.Since(start)
+	c.lastAttempted = int64(c.batchPtr)
+	stats := RoundStats{
+		Round:      c.round,
+		Start:      start,
+		End:        time.Now(),
+		Attempted:  c.batchPtr,
+		ConnErrors: make(map[string]int64, len(c.connErrors)),
+	}
+	for connErr, cnt := range c.connErrors {
+		stats.ConnErrors[connErr] = cnt
+	}
+	c.m.Unlock()
+
+	select {
+	case c.roundC <- stats:
+	default:
+		log.Debugf("no listener for round %d, dropping its stats", stats.Round)
+	}
+}
+
+// eventRecorderRoutine notifies the selector of the result of the dials, and persists the
+// connection attempts, events and identifications
+func (c *BatchStrategy) eventRecorderRoutine() {
+	logEntry := log.WithFields(log.Fields{
+		"mod": "batch-evnt-rec",
+	})
+	logEntry.Debugf("init")
+
+	connEventBuffer := make(map[peer.ID]*models.ConnEvent, 0)
+
+	for {
+		select {
+		case connAttempt := <-c.connAttemptNot:
+			logEntry.Tracef("new connection attempt has been received from peer %s", connAttempt.RemotePeer.String())
+			c.selector.ProcessResult(connAttempt)
+			c.m.Lock()
+			c.lastErrors[connAttempt.RemotePeer] = connAttempt.Error
+			c.lastDials[connAttempt.RemotePeer] = connAttempt.Timestamp
+			c.connErrors[connAttempt.Error]++
+			c.attemptsStatus[string(connAttempt.Status)]++
+			c.m.Unlock()
+			c.DBClient.PersistToDB(connAttempt)
+
+		case eventTrace := <-c.connEventNot:
+			recordConnEvent(connEventBuffer, eventTrace, c.DBClient, logEntry)
+
+		case identEvent := <-c.identEventNot:
+			logEntry.Debugf("new identification from peer %s", identEvent.HostInfo.ID.String())
+			c.DBClient.PersistToDB(identEvent.HostInfo)
+
+		case <-c.ctx.Done():
+			logEntry.Debug("closing event recorder routine")
+			return
+		}
+	}
+}
+
+// NextPeer notifies the batch iterator that a new peer has been requested
+func (c *BatchStrategy) NextPeer() {
+	c.nextPeerChan <- struct{}{}
+}
+
+func (c *BatchStrategy) NewConnectionAttempt(connAttStat *models.ConnectionAttempt) {
+	c.connAttemptNot <- connAttStat
+}
+
+func (c *BatchStrategy) NewConnectionEvent(eventTrace *models.EventTrace) {
+	c.connEventNot <- eventTrace
+}
+
+func (c *BatchStrategy) NewIdentificationEvent(newIdent hosts.IdentificationEvent) {
+	c.identEventNot <- newIdent
+}
+
+// --------------------------------------------------
+// Metrics Exporting Functions for Peering Prometheus
+// --------------------------------------------------
+
+func (c *BatchStrategy) LastIterTime() float64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	return c.lastIterTime.Seconds()
+}
+
+func (c *BatchStrategy) AttemptedPeersSinceLastIter() int64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	return c.lastAttempted
+}
+
+// ControlDistribution returns the number of known peers, and the ones of the current batch
+func (c *BatchStrategy) ControlDistribution() map[string]int64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	return map[string]int64{
+		"known":   int64(c.known),
+		"batch":   int64(len(c.batch)),
+		"pending": int64(len(c.batch) - c.batchPtr),
+	}
+}
+
+// GetErrorAttemptDistribution returns the status of the attempts of the current batch
+func (c *BatchStrategy) GetErrorAttemptDistribution() map[string]int64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	attemptDist := make(map[string]int64, len(c.attemptsStatus))
+	for k, v := range c.attemptsStatus {
+		attemptDist[k] = v
+	}
+	return attemptDist
+}
+
+// GetTotalConnErrorDistribution returns the last error of all the dialed peers
+func (c *BatchStrategy) GetTotalConnErrorDistribution() map[string]int64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	totConnErrors := make(map[string]int64)
+	for _, connErr := range c.lastErrors {
+		totConnErrors[connErr]++
+	}
+	return totConnErrors
+}
+
+// GetConnErrorDistribution returns the errors of the attempts of the current batch
+func (c *BatchStrategy) GetConnErrorDistribution() map[string]int64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	errDistr := make(map[string]int64, len(c.connErrors))
+	for k, v := range c.connErrors {
+		errDistr[k] = v
+	}
+	return errDistr
+}
+
+// --------------------------------------------------
+// Dial queue inspection
+// --------------------------------------------------
+
+// DialQueue returns the first limit peers of the current batch (all of them if limit <= 0)
+func (c *BatchStrategy) DialQueue(limit int) DialQueueStatus {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	status := DialQueueStatus{
+		Strategy:  c.selector.Type(),
+		Len:       len(c.batch),
+		Pointer:   c.batchPtr,
+		Ready:     len(c.batch) - c.batchPtr,
+		Timestamp: time.Now(),
+		Peers:     make([]DialQueueEntry, 0),
+	}
+	for idx, p := range c.batch {
+		if limit > 0 && idx >= limit {
+			break
+		}
+		status.Peers = append(status.Peers, c.dialQueueEntry(idx, p))
+	}
+	return status
+}
+
+// InspectPeer returns the state of the given peer in the current batch (false if it isn't in it)
+func (c *BatchStrategy) InspectPeer(id peer.ID) (DialQueueEntry, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	for idx, p := range c.batch {
+		if p.ID == id {
+			return c.dialQueueEntry(idx, p), true
+		}
+	}
+	return DialQueueEntry{}, false
+}
+
+// dialQueueEntry returns the state of the peer of the batch at the given position (needs the lock)
+func (c *BatchStrategy) dialQueueEntry(position int, p *models.RemoteConnectablePeer) DialQueueEntry {
+	addrs := make([]string, 0, len(p.Addrs))
+	for _, addr := range p.Addrs {
+		addrs = append(addrs, addr.String())
+	}
+	entry := DialQueueEntry{
+		PeerID:      p.ID.String(),
+		Addrs:       addrs,
+		Position:    position,
+		Attempted:   position < c.batchPtr,
+		LastError:   c.lastErrors[p.ID],
+		LastAttempt: c.lastDials[p.ID],
+		ReadyToDial: position >= c.batchPtr,
+	}
+	// the selectors that prioritize the peers expose their score
+	if scorer, ok := c.selector.(interface {
+		Score(peer.ID) (float64, bool)
+	}); ok {
+		entry.Score, _ = scorer.Score(p.ID)
+	}
+	return entry
+}
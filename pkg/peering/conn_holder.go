@@ -0,0 +1,509 @@
+package peering
+
+/**
+This file implements the connection holder
+Unlike the holder peering strategy (which re-dials the peers that we managed to connect among all the
+known ones), the connection holder keeps a fixed set of N peers connected for long periods, which is
+what the gossip studies need. The peers are chosen by policy (a random sample stratified by client,
+country or attestation subnet), kept alive with pings, and re-dialed whenever the connection drops.
+Each peer has a keepalive budget: the failed pings and re-dials in a row that it can accumulate before
+it gets released and replaced by another peer of the same stratum.
+*/
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/sampling"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	DefaultHoldPingInterval = 30 * time.Second
+	DefaultKeepAliveBudget  = 5
+	DefaultHoldSeed         = int64(1)
+
+	// peers can also be stratified by the attestation subnets of their ENR
+	StratifySubnet = "subnet"
+
+	// tag of the held connections in the connection manager
+	holdProtectTag = "held-peer"
+)
+
+// KeepAliveFn pings the given peer, returning the RTT of the ping
+type KeepAliveFn func(ctx context.Context, p peer.ID) (time.Duration, error)
+
+type holderDB interface {
+	GetSampleCandidates(network utils.NetworkType) ([]*models.SampledPeer, error)
+	GetPeerAttnets() (map[peer.ID][]int, error)
+	PersistToDB(interface{})
+}
+
+type heldConn struct {
+	info        peer.AddrInfo
+	record      *models.HeldPeer
+	connectedAt time.Time // zero if disconnected
+	failures    int       // failed keepalives and re-dials in a row
+}
+
+// ConnectionHolder keeps a stratified set of peers connected, pinging and re-dialing them
+type ConnectionHolder struct {
+	ctx     context.Context
+	runID   int
+	network utils.NetworkType
+	h       host.Host
+	db      holderDB
+
+	size         int
+	stratify     string
+	seed         int64
+	pingInterval time.Duration
+	budget       int
+	keepAlive    KeepAliveFn
+
+	m    sync.Mutex
+	held map[peer.ID]*heldConn
+	// candidates left to replace the released peers, by stratum
+	spares   map[string][]*models.SampledPeer
+	released map[peer.ID]struct{}
+
+	wg sync.WaitGroup
+}
+
+func NewConnectionHolder(
+	ctx context.Context,
+	runID int,
+	network utils.NetworkType,
+	h host.Host,
+	db holderDB,
+	size int,
+	opts ...HolderOption) (*ConnectionHolder, error) {
+
+	if size <= 0 {
+		return nil, errors.Errorf("invalid number of held peers %d", size)
+	}
+	holder := &ConnectionHolder{
+		ctx:          ctx,
+		runID:        runID,
+		network:      network,
+		h:            h,
+		db:           db,
+		size:         size,
+		stratify:     sampling.NoStratify,
+		seed:         DefaultHoldSeed,
+		pingInterval: DefaultHoldPingInterval,
+		budget:       DefaultKeepAliveBudget,
+		held:         make(map[peer.ID]*heldConn),
+		spares:       make(map[string][]*models.SampledPeer),
+		released:     make(map[peer.ID]struct{}),
+	}
+	holder.keepAlive = holder.libp2pPing
+	for _, opt := range opts {
+		err := opt(holder)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply connection holder option")
+		}
+	}
+	return holder, nil
+}
+
+// Start chooses the peers to hold and keeps them connected in the background
+func (c *ConnectionHolder) Start() error {
+	candidates, err := c.db.GetSampleCandidates(c.network)
+	if err != nil {
+		return errors.Wrap(err, "unable to read the peers to hold")
+	}
+	var selected []*models.SampledPeer
+	var strata map[peer.ID]string
+	if c.stratify == StratifySubnet {
+		attnets, err := c.db.GetPeerAttnets()
+		if err != nil {
+			return errors.Wrap(err, "unable to read the subnets of the peers to hold")
+		}
+		selected, strata, c.spares = selectBySubnet(candidates, attnets, c.size, c.seed)
+	} else {
+		selected, strata, c.spares = selectByAttribute(candidates, c.stratify, c.size, c.seed)
+	}
+	if len(selected) == 0 {
+		return errors.New("no peers to hold")
+	}
+
+	c.m.Lock()
+	for _, p := range selected {
+		c.hold(p, strata[p.PeerID])
+	}
+	c.m.Unlock()
+
+	c.h.Network().Notify(&network.NotifyBundle{
+		DisconnectedF: c.disconnected,
+	})
+
+	log.WithFields(log.Fields{
+		"held":          len(selected),
+		"stratify":      c.stratify,
+		"ping-interval": c.pingInterval,
+		"budget":        c.budget,
+		"seed":          c.seed,
+	}).Info("starting connection holder")
+
+	c.wg.Add(1)
+	go c.run()
+	return nil
+}
+
+// Stop waits until the holder routine is over (it dies with the context)
+func (c *ConnectionHolder) Stop() {
+	c.wg.Wait()
+}
+
+func (c *ConnectionHolder) run() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+	for {
+		c.keepAliveRound()
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			log.Debug("closing connection holder")
+			return
+		}
+	}
+}
+
+// keepAliveRound pings the connected peers and re-dials the disconnected ones, replacing
+// the ones that exhausted their budget, and persists the state of all of them
+func (c *ConnectionHolder) keepAliveRound() {
+	c.m.Lock()
+	conns := make([]*heldConn, 0, len(c.held))
+	for _, conn := range c.held {
+		conns = append(conns, conn)
+	}
+	c.m.Unlock()
+
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *heldConn) {
+			defer wg.Done()
+			c.keepConn(conn)
+		}(conn)
+	}
+	wg.Wait()
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	connected := 0
+	for id, conn := range c.held {
+		if conn.failures >= c.budget {
+			c.release(id, conn)
+			continue
+		}
+		if !conn.connectedAt.IsZero() {
+			connected++
+		}
+		c.persist(conn)
+	}
+	log.Debugf("connection holder keeps %d/%d peers connected", connected, len(c.held))
+}
+
+func (c *ConnectionHolder) keepConn(conn *heldConn) {
+	ctx, cancel := context.WithTimeout(c.ctx, ConnectionRefuseTimeout)
+	defer cancel()
+	if c.h.Network().Connectedness(conn.info.ID) != network.Connected {
+		err := c.h.Connect(ctx, conn.info)
+		c.m.Lock()
+		defer c.m.Unlock()
+		conn.record.Redials++
+		if err != nil {
+			conn.record.FailedRedials++
+			conn.failures++
+			return
+		}
+		conn.connectedAt = time.Now()
+		conn.failures = 0
+		return
+	}
+	rtt, err := c.keepAlive(ctx, conn.info.ID)
+	c.m.Lock()
+	defer c.m.Unlock()
+	if conn.connectedAt.IsZero() {
+		// the peer connected to us meanwhile
+		conn.connectedAt = time.Now()
+	}
+	if err != nil {
+		conn.record.FailedPings++
+		conn.failures++
+		return
+	}
+	conn.record.Pings++
+	conn.record.LastRTT = rtt
+	conn.failures = 0
+}
+
+// hold adds the peer to the held set (needs the lock)
+func (c *ConnectionHolder) hold(p *models.SampledPeer, stratum string) {
+	maddrs := make([]ma.Multiaddr, 0, len(p.Addrs))
+	for _, addr := range p.Addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			continue
+		}
+		maddrs = append(maddrs, maddr)
+	}
+	c.h.ConnManager().Protect(p.PeerID, holdProtectTag)
+	c.held[p.PeerID] = &heldConn{
+		info: peer.AddrInfo{ID: p.PeerID, Addrs: maddrs},
+		record: &models.HeldPeer{
+			RunID:     c.runID,
+			PeerID:    p.PeerID,
+			Stratum:   stratum,
+			HeldSince: time.Now(),
+		},
+	}
+}
+
+// release drops a peer that exhausted its budget, holding a spare of its stratum instead (needs the lock)
+func (c *ConnectionHolder) release(id peer.ID, conn *heldConn) {
+	c.h.ConnManager().Unprotect(id, holdProtectTag)
+	conn.record.Released = true
+	c.persist(conn)
+	delete(c.held, id)
+	c.released[id] = struct{}{}
+
+	stratum := conn.record.Stratum
+	spare, ok := c.popSpare(stratum)
+	if !ok {
+		log.Warnf("released held peer %s, no peers left to replace it", id.String())
+		return
+	}
+	log.Debugf("released held peer %s, replaced by %s (%s)", id.String(), spare.PeerID.String(), stratum)
+	c.hold(spare, stratum)
+}
+
+// popSpare returns the next spare of the given stratum, or of any other if there are none (needs the lock)
+func (c *ConnectionHolder) popSpare(stratum string) (*models.SampledPeer, bool) {
+	keys := []string{stratum}
+	others := make([]string, 0, len(c.spares))
+	for key := range c.spares {
+		if key != stratum {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	keys = append(keys, others...)
+	for _, key := range keys {
+		for len(c.spares[key]) > 0 {
+			spare := c.spares[key][0]
+			c.spares[key] = c.spares[key][1:]
+			// with the subnets a peer can be the spare of several strata
+			_, held := c.held[spare.PeerID]
+			_, released := c.released[spare.PeerID]
+			if !held && !released {
+				return spare, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// persist records the current state of the held peer (needs the lock)
+func (c *ConnectionHolder) persist(conn *heldConn) {
+	now := time.Now()
+	record := *conn.record
+	record.LastUpdate = now
+	record.Connected = !conn.connectedAt.IsZero()
+	if record.Connected {
+		record.Uptime += now.Sub(conn.connectedAt)
+	}
+	c.db.PersistToDB(&record)
+}
+
+func (c *ConnectionHolder) disconnected(net network.Network, netConn network.Conn) {
+	id := netConn.RemotePeer()
+	// other connections to the peer might still be open
+	if net.Connectedness(id) == network.Connected {
+		return
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	conn, ok := c.held[id]
+	if !ok || conn.connectedAt.IsZero() {
+		return
+	}
+	conn.record.Uptime += time.Since(conn.connectedAt)
+	conn.record.Drops++
+	conn.connectedAt = time.Time{}
+}
+
+// libp2pPing is the default keepalive, a libp2p ping
+func (c *ConnectionHolder) libp2pPing(ctx context.Context, p peer.ID) (time.Duration, error) {
+	select {
+	case res := <-ping.Ping(ctx, c.h, p):
+		return res.RTT, res.Error
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// selectByAttribute samples the peers stratified by client or country (or not stratified),
+// leaving the rest of the candidates as spares of their stratum
+func selectByAttribute(
+	candidates []*models.SampledPeer,
+	stratify string,
+	size int,
+	seed int64) ([]*models.SampledPeer, map[peer.ID]string, map[string][]*models.SampledPeer) {
+
+	selected := sampling.SamplePeers(candidates, size, stratify, seed)
+	strata := make(map[peer.ID]string, len(selected))
+	for _, p := range selected {
+		strata[p.PeerID] = sampling.StratumKey(p, stratify)
+	}
+	// the spares are the whole population shuffled with the same seed
+	spares := make(map[string][]*models.SampledPeer)
+	for _, p := range sampling.SamplePeers(candidates, len(candidates), sampling.NoStratify, seed) {
+		if _, ok := strata[p.PeerID]; ok {
+			continue
+		}
+		key := sampling.StratumKey(p, stratify)
+		spares[key] = append(spares[key], p)
+	}
+	return selected, strata, spares
+}
+
+// selectBySubnet picks the peers round-robin over the attestation subnets, so that all of them are
+// covered by the held peers, leaving the rest of the peers of each subnet as its spares
+func selectBySubnet(
+	candidates []*models.SampledPeer,
+	attnets map[peer.ID][]int,
+	size int,
+	seed int64) ([]*models.SampledPeer, map[peer.ID]string, map[string][]*models.SampledPeer) {
+
+	shuffled := append([]*models.SampledPeer{}, candidates...)
+	sort.Slice(shuffled, func(i, j int) bool {
+		return shuffled[i].PeerID < shuffled[j].PeerID
+	})
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	bySubnet := make(map[int][]*models.SampledPeer)
+	subnets := make([]int, 0)
+	for _, p := range shuffled {
+		for _, subnet := range attnets[p.PeerID] {
+			if _, ok := bySubnet[subnet]; !ok {
+				subnets = append(subnets, subnet)
+			}
+			bySubnet[subnet] = append(bySubnet[subnet], p)
+		}
+	}
+	sort.Ints(subnets)
+
+	selected := make([]*models.SampledPeer, 0, size)
+	strata := make(map[peer.ID]string, size)
+	for len(selected) < size {
+		progress := false
+		for _, subnet := range subnets {
+			if len(selected) >= size {
+				break
+			}
+			for len(bySubnet[subnet]) > 0 {
+				p := bySubnet[subnet][0]
+				bySubnet[subnet] = bySubnet[subnet][1:]
+				if _, ok := strata[p.PeerID]; ok {
+					continue
+				}
+				selected = append(selected, p)
+				strata[p.PeerID] = subnetStratum(subnet)
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			break
+		}
+	}
+	spares := make(map[string][]*models.SampledPeer, len(bySubnet))
+	for subnet, peers := range bySubnet {
+		spares[subnetStratum(subnet)] = peers
+	}
+	return selected, strata, spares
+}
+
+func subnetStratum(subnet int) string {
+	return fmt.Sprintf("%s-%d", eth.AttnetSubnet, subnet)
+}
+
+// --------------------------------------------------
+// Connection holder options
+// --------------------------------------------------
+
+type HolderOption func(*ConnectionHolder) error
+
+// WithHoldStratify sets the attribute that the held peers are stratified by (client, country or subnet)
+func WithHoldStratify(stratify string) HolderOption {
+	return func(c *ConnectionHolder) error {
+		if stratify != StratifySubnet {
+			if err := sampling.CheckStratify(stratify); err != nil {
+				return err
+			}
+		}
+		c.stratify = stratify
+		return nil
+	}
+}
+
+// WithHoldSeed sets the seed of the selection of the held peers
+func WithHoldSeed(seed int64) HolderOption {
+	return func(c *ConnectionHolder) error {
+		c.seed = seed
+		return nil
+	}
+}
+
+// WithHoldPingInterval sets the interval between the keepalives of each held peer
+func WithHoldPingInterval(interval time.Duration) HolderOption {
+	return func(c *ConnectionHolder) error {
+		if interval <= 0 {
+			return errors.Errorf("invalid hold ping interval %s", interval)
+		}
+		c.pingInterval = interval
+		return nil
+	}
+}
+
+// WithKeepAliveBudget sets the failed keepalives and re-dials in a row before a held peer is replaced
+func WithKeepAliveBudget(budget int) HolderOption {
+	return func(c *ConnectionHolder) error {
+		if budget <= 0 {
+			return errors.Errorf("invalid keepalive budget %d", budget)
+		}
+		c.budget = budget
+		return nil
+	}
+}
+
+// WithKeepAlive replaces the libp2p ping with the given keepalive (i.e. the Ping RPC of the network)
+func WithKeepAlive(keepAlive KeepAliveFn) HolderOption {
+	return func(c *ConnectionHolder) error {
+		if keepAlive == nil {
+			return errors.New("nil keepalive")
+		}
+		c.keepAlive = keepAlive
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package peering
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/sampling"
+)
+
+func Test_SelectBySubnet(t *testing.T) {
+	candidates := []*models.SampledPeer{{PeerID: "a"}, {PeerID: "b"}, {PeerID: "c"}, {PeerID: "d"}}
+	attnets := map[peer.ID][]int{
+		"a": {1, 2},
+		"b": {1},
+		"c": {3},
+		"d": {},
+	}
+
+	// the held peers cover all the subnets
+	selected, strata, spares := selectBySubnet(candidates, attnets, 3, 42)
+	require.Len(t, selected, 3)
+	covered := make(map[int]struct{})
+	for _, p := range selected {
+		for _, subnet := range attnets[p.PeerID] {
+			covered[subnet] = struct{}{}
+		}
+	}
+	require.Len(t, covered, 3)
+	require.Contains(t, strata, peer.ID("c"))
+	require.Equal(t, subnetStratum(3), strata["c"])
+	// the peers without subnets are never held
+	require.NotContains(t, strata, peer.ID("d"))
+	require.Contains(t, spares, subnetStratum(1))
+
+	// replayable with the same seed
+	again, _, _ := selectBySubnet(candidates, attnets, 3, 42)
+	require.Equal(t, selected, again)
+
+	// no more peers than the ones with subnets
+	selected, _, _ = selectBySubnet(candidates, attnets, 10, 42)
+	require.Len(t, selected, 3)
+}
+
+func Test_SelectByAttribute(t *testing.T) {
+	candidates := []*models.SampledPeer{
+		{PeerID: "a", Client: "lighthouse"},
+		{PeerID: "b", Client: "lighthouse"},
+		{PeerID: "c", Client: "prysm"},
+		{PeerID: "d", Client: "teku"},
+	}
+	selected, strata, spares := selectByAttribute(candidates, sampling.StratifyClient, 2, 42)
+	require.Len(t, selected, 2)
+	require.Len(t, strata, 2)
+	// the rest of the candidates are the spares of their stratum
+	total := 0
+	for key, stratumSpares := range spares {
+		for _, p := range stratumSpares {
+			require.NotContains(t, strata, p.PeerID)
+			require.Equal(t, key, p.Client)
+		}
+		total += len(stratumSpares)
+	}
+	require.Equal(t, 2, total)
+}
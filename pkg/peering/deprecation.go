@@ -0,0 +1,97 @@
+package peering
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// backoff curves applied to the peers whose dials fail
+const (
+	// fixed delay per type of error, growing exponentially only for the timeouts
+	BackoffByError     = "by-error"
+	BackoffConstant    = "constant"
+	BackoffLinear      = "linear"
+	BackoffExponential = "exponential"
+)
+
+// reasons why a peer gets deprecated
+const (
+	// no successful connection within the expiry window
+	DeprecationExpired = "expired"
+	// too many failed dials in a row
+	DeprecationMaxAttempts = "max-attempts"
+)
+
+var BackoffCurves = []string{BackoffByError, BackoffConstant, BackoffLinear, BackoffExponential}
+
+// DeprecationPolicy defines how long the pruning strategy waits before re-dialing a failing peer,
+// and when it gives up on it (deprecation). Whether a deprecated peer comes back when it is
+// re-discovered is decided by the DB client (see postgresql.WithResurrectOnNewENR)
+type DeprecationPolicy struct {
+	// failed dials in a row before deprecating the peer (0 for no limit)
+	MaxAttempts int
+	// curve of the delays between the failed dials, starting at BaseDelay and capped at MaxDelay
+	Backoff   string
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// time without a successful connection before deprecating the peer
+	ExpiryWindow time.Duration
+}
+
+// DefaultDeprecationPolicy returns the policy that the pruning strategy has always applied
+func DefaultDeprecationPolicy() DeprecationPolicy {
+	return DeprecationPolicy{
+		MaxAttempts:  0,
+		Backoff:      BackoffByError,
+		BaseDelay:    StartExpD,
+		MaxDelay:     MaxDelayTime,
+		ExpiryWindow: DeprecationTime,
+	}
+}
+
+// Check returns an error if any of the parameters of the policy is invalid
+func (p DeprecationPolicy) Check() error {
+	if p.MaxAttempts < 0 {
+		return errors.Errorf("invalid max attempts %d", p.MaxAttempts)
+	}
+	if p.BaseDelay <= 0 || p.MaxDelay < p.BaseDelay {
+		return errors.Errorf("invalid backoff delays (base %s, max %s)", p.BaseDelay, p.MaxDelay)
+	}
+	if p.ExpiryWindow <= 0 {
+		return errors.Errorf("invalid expiry window %s", p.ExpiryWindow)
+	}
+	for _, curve := range BackoffCurves {
+		if curve == p.Backoff {
+			return nil
+		}
+	}
+	return errors.Errorf("unknown backoff curve %s (%s)", p.Backoff, strings.Join(BackoffCurves, ", "))
+}
+
+// delay returns the time to wait before dialing again a peer with the given delay object
+func (p DeprecationPolicy) delay(d DelayObject) time.Duration {
+	var delay time.Duration
+	switch d.dtype {
+	case NegativeWithHopeDelay, NegativeWithNoHopeDelay, TimeoutDelay:
+		degree := float64(max(d.delayDegree, 1))
+		switch p.Backoff {
+		case BackoffConstant:
+			delay = p.BaseDelay
+		case BackoffLinear:
+			delay = time.Duration(degree * float64(p.BaseDelay))
+		case BackoffExponential:
+			delay = time.Duration(math.Pow(2, degree-1) * float64(p.BaseDelay))
+		default:
+			delay = d.CalculateDelay()
+		}
+	default:
+		delay = d.CalculateDelay()
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,88 @@
+package peering
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func Test_DeprecationPolicyCheck(t *testing.T) {
+	require.NoError(t, DefaultDeprecationPolicy().Check())
+
+	for name, modify := range map[string]func(*DeprecationPolicy){
+		"negative attempts": func(p *DeprecationPolicy) { p.MaxAttempts = -1 },
+		"zero base delay":   func(p *DeprecationPolicy) { p.BaseDelay = 0 },
+		"max below base":    func(p *DeprecationPolicy) { p.MaxDelay = p.BaseDelay / 2 },
+		"zero expiry":       func(p *DeprecationPolicy) { p.ExpiryWindow = 0 },
+		"unknown curve":     func(p *DeprecationPolicy) { p.Backoff = "fibonacci" },
+	} {
+		policy := DefaultDeprecationPolicy()
+		modify(&policy)
+		require.Error(t, policy.Check(), name)
+	}
+}
+
+func Test_DeprecationPolicyDelay(t *testing.T) {
+	policy := DeprecationPolicy{
+		Backoff:      BackoffExponential,
+		BaseDelay:    time.Minute,
+		MaxDelay:     10 * time.Minute,
+		ExpiryWindow: time.Hour,
+	}
+	delays := func(curve string) []time.Duration {
+		policy.Backoff = curve
+		d := NewDelayObject(NegativeWithHopeDelay)
+		delays := make([]time.Duration, 0)
+		for i := 0; i < 5; i++ {
+			d.IncreaseDegree()
+			delays = append(delays, policy.delay(d))
+		}
+		return delays
+	}
+	require.Equal(t, []time.Duration{time.Minute, time.Minute, time.Minute, time.Minute, time.Minute}, delays(BackoffConstant))
+	require.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 5 * time.Minute}, delays(BackoffLinear))
+	// capped at the max delay
+	require.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute}, delays(BackoffExponential))
+
+	// the successful peers always wait the positive delay
+	positive := NewDelayObject(PositiveDelay)
+	positive.IncreaseDegree()
+	positive.IncreaseDegree()
+	require.Equal(t, 2*time.Minute, policy.delay(positive))
+}
+
+func Test_DeprecationReason(t *testing.T) {
+	pp := NewPrunedPeer("Peer1", nil, utils.EthereumNetwork, Minus1Delay)
+	pp.policy = DeprecationPolicy{
+		MaxAttempts:  3,
+		Backoff:      BackoffConstant,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		ExpiryWindow: time.Hour,
+	}
+	require.Empty(t, pp.DeprecationReason())
+
+	pp.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	pp.ConnEventHandler(hosts.DialErrorIoTimeout)
+	require.False(t, pp.Deprecable())
+	// a success resets the failures in a row
+	pp.ConnEventHandler(hosts.NoConnError)
+	pp.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	pp.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	require.Empty(t, pp.DeprecationReason())
+	pp.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	require.Equal(t, DeprecationMaxAttempts, pp.DeprecationReason())
+
+	// without a limit of attempts, only the expiry window deprecates the peer
+	pp.policy.MaxAttempts = 0
+	require.False(t, pp.Deprecable())
+	pp.baseDeprecationTimestamp = time.Now().Add(-time.Hour)
+	require.Equal(t, DeprecationExpired, pp.DeprecationReason())
+	// and a success restarts it
+	pp.ConnEventHandler(hosts.NoConnError)
+	require.Empty(t, pp.DeprecationReason())
+}
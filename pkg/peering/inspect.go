@@ -0,0 +1,126 @@
+package peering
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DialQueueEntry is the state of a peer in the dial queue of the pruning strategy,
+// which explains when (and why) the peer will be re-dialed or deprecated
+type DialQueueEntry struct {
+	PeerID   string   `json:"peer_id"`
+	Addrs    []string `json:"addrs"`
+	Position int      `json:"position"`
+	// whether the peer was already dialed in the current iteration of the queue
+	Attempted bool `json:"attempted"`
+
+	// backoff timer
+	LastError   string    `json:"last_error"`
+	Delay       string    `json:"delay"`
+	DelayDegree int       `json:"delay_degree"`
+	Backoff     string    `json:"backoff"`
+	LastAttempt time.Time `json:"last_attempt"`
+	NextDial    time.Time `json:"next_dial"`
+	ReadyToDial bool      `json:"ready_to_dial"`
+
+	// deprecation state
+	Failures        int       `json:"failures"`
+	DeprecationTime time.Time `json:"deprecation_time"`
+	Deprecable      bool      `json:"deprecable"`
+	Reason          string    `json:"deprecation_reason,omitempty"`
+
+	// dial priority (only for the priority strategy)
+	Score float64 `json:"score,omitempty"`
+}
+
+// DialQueueStatus is the snapshot of the whole dial queue
+type DialQueueStatus struct {
+	Strategy  string           `json:"strategy"`
+	Len       int              `json:"len"`
+	Pointer   int              `json:"pointer"`
+	Ready     int              `json:"ready"`
+	Timestamp time.Time        `json:"timestamp"`
+	Peers     []DialQueueEntry `json:"peers"`
+}
+
+func (c *PrunedPeer) dialQueueEntry(position, pointer int) DialQueueEntry {
+	addrs := make([]string, 0, len(c.addr))
+	for _, addr := range c.addr {
+		addrs = append(addrs, addr.String())
+	}
+	backoff := c.policy.delay(c.delayObj)
+	if backoff < 0 {
+		backoff = 0
+	}
+	entry := DialQueueEntry{
+		PeerID:          c.iD.String(),
+		Addrs:           addrs,
+		Position:        position,
+		Attempted:       position < pointer,
+		LastError:       c.connError,
+		Delay:           string(c.delayObj.dtype),
+		DelayDegree:     c.delayObj.delayDegree,
+		Backoff:         backoff.String(),
+		NextDial:        c.NextConnection(),
+		ReadyToDial:     c.IsReadyForConnection(),
+		Failures:        c.failures,
+		DeprecationTime: c.baseDeprecationTimestamp.Add(c.policy.ExpiryWindow),
+		Deprecable:      c.Deprecable(),
+		Reason:          c.DeprecationReason(),
+	}
+	// new peers haven't been dialed yet
+	if c.delayObj.dtype != Minus1Delay {
+		entry.LastAttempt = c.baseConnectionTimestamp
+	}
+	return entry
+}
+
+// Snapshot returns the state of the first limit peers of the queue (all of them if limit <= 0)
+func (c *PeerQueue) Snapshot(limit int) DialQueueStatus {
+	c.RLock()
+	defer c.RUnlock()
+	status := DialQueueStatus{
+		Len:       len(c.peerList),
+		Pointer:   c.peerPtr,
+		Timestamp: time.Now(),
+		Peers:     make([]DialQueueEntry, 0),
+	}
+	for idx, pPeer := range c.peerList {
+		entry := pPeer.dialQueueEntry(idx, c.peerPtr)
+		if entry.ReadyToDial {
+			status.Ready++
+		}
+		if limit <= 0 || idx < limit {
+			status.Peers = append(status.Peers, entry)
+		}
+	}
+	return status
+}
+
+// Inspect returns the state of the given peer in the queue (false if the peer isn't queued)
+func (c *PeerQueue) Inspect(id peer.ID) (DialQueueEntry, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	if _, ok := c.peerMap[id]; !ok {
+		return DialQueueEntry{}, false
+	}
+	for idx, pPeer := range c.peerList {
+		if pPeer.iD == id {
+			return pPeer.dialQueueEntry(idx, c.peerPtr), true
+		}
+	}
+	return DialQueueEntry{}, false
+}
+
+// DialQueue returns the snapshot of the first limit peers of the dial queue
+func (c *PruningStrategy) DialQueue(limit int) DialQueueStatus {
+	status := c.PeerQueue.Snapshot(limit)
+	status.Strategy = c.Type()
+	return status
+}
+
+// InspectPeer returns the dial state of the given peer (false if it isn't in the queue)
+func (c *PruningStrategy) InspectPeer(id peer.ID) (DialQueueEntry, bool) {
+	return c.PeerQueue.Inspect(id)
+}
@@ -7,47 +7,92 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// List of metrics that we are going to export
-var (
-	PrunedErrorDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "peering",
-		Name:      "pruned_error_distribution",
-		Help:      "Filter peers in Peer Queue by errors that were tracked",
-	},
-		[]string{"controldist"},
-	)
-	ErrorAttemptDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "peering",
-		Name:      "iteration_attempts_by_category_distribution",
-		Help:      "Filter attempts in Peer Queue by errors that were tracked",
-	},
-		[]string{"controlAttemptdist"},
-	)
-	PeersAttemptedInLastIteration = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: "peering",
-		Name:      "peers_attempted_last_iteration",
-		Help:      "The number of discovered peers with the crawler",
-	})
-	PeerstoreIterTime = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: "peering",
-		Name:      "peerstore_iteration_time_secs",
-		Help:      "The time that the crawler takes to connect the entire peerstore in secs",
-	})
-	ConnectionErrorDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "peering",
-		Name:      "conn_error_distribution",
-		Help:      "The error distribtuion of the attempted to connect peers since the last iteration",
-	},
-		[]string{"error_type"},
-	)
-	TotalConnectionErrorDistribution = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "peering",
-		Name:      "total_conn_error_distribution",
-		Help:      "The total error distribtuion the active peers",
-	},
-		[]string{"error_type"},
-	)
-)
+// peeringMetrics are the collectors of the metrics of the peering, one set per crawler
+type peeringMetrics struct {
+	PrunedErrorDistribution          *prometheus.GaugeVec
+	ErrorAttemptDistribution         *prometheus.GaugeVec
+	PeersAttemptedInLastIteration    prometheus.Gauge
+	PeerstoreIterTime                prometheus.Gauge
+	ConnectionErrorDistribution      *prometheus.GaugeVec
+	TotalConnectionErrorDistribution *prometheus.GaugeVec
+	DialWorkerLimit                  prometheus.Gauge
+	ActiveDials                      prometheus.Gauge
+	DialRate                         *prometheus.GaugeVec
+	DialTimeoutRate                  prometheus.Gauge
+	OpenFileDescriptors              *prometheus.GaugeVec
+}
+
+func newPeeringMetrics() *peeringMetrics {
+	return &peeringMetrics{
+		PrunedErrorDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "pruned_error_distribution",
+			Help:      "Filter peers in Peer Queue by errors that were tracked",
+		},
+			[]string{"controldist"},
+		),
+		ErrorAttemptDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "iteration_attempts_by_category_distribution",
+			Help:      "Filter attempts in Peer Queue by errors that were tracked",
+		},
+			[]string{"controlAttemptdist"},
+		),
+		PeersAttemptedInLastIteration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "peers_attempted_last_iteration",
+			Help:      "The number of discovered peers with the crawler",
+		}),
+		PeerstoreIterTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "peerstore_iteration_time_secs",
+			Help:      "The time that the crawler takes to connect the entire peerstore in secs",
+		}),
+		ConnectionErrorDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "conn_error_distribution",
+			Help:      "The error distribtuion of the attempted to connect peers since the last iteration",
+		},
+			[]string{"error_type"},
+		),
+		TotalConnectionErrorDistribution: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "total_conn_error_distribution",
+			Help:      "The total error distribtuion the active peers",
+		},
+			[]string{"error_type"},
+		),
+		DialWorkerLimit: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "dial_worker_limit",
+			Help:      "The number of concurrent dials currently allowed by the rate controller",
+		}),
+		ActiveDials: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "active_dials",
+			Help:      "The number of dials in progress",
+		}),
+		DialRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "dial_rate_psec",
+			Help:      "The dials per second allowed by the rate controller, and the ones observed during the last interval",
+		},
+			[]string{"rate"},
+		),
+		DialTimeoutRate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "dial_timeout_rate",
+			Help:      "The share of the dials that timed out during the last interval of the rate controller",
+		}),
+		OpenFileDescriptors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "peering",
+			Name:      "open_fds",
+			Help:      "The open file descriptors of the process and their limit",
+		},
+			[]string{"fds"},
+		),
+	}
+}
 
 // ServeMetrics:
 // This method will serve the global peerstore values to the
@@ -59,22 +104,26 @@ func (p *PeeringService) GetMetrics() *metrics.MetricsModule {
 		"peering",
 		"internal module of the crawler used for peering and prunning peers",
 	)
-
-	metricsMod.AddIndvMetric(p.getPrunedErrorDistribtuion())
-	metricsMod.AddIndvMetric(p.getErrorAttemptDistribtuion())
-	metricsMod.AddIndvMetric(p.getAttemptedPeersInLastIteration())
-	metricsMod.AddIndvMetric(p.getPeerstoreIterTime())
-	metricsMod.AddIndvMetric(p.getConnErrorDistribution())
-	metricsMod.AddIndvMetric(p.getTotalConnErrorDistribution())
+	m := newPeeringMetrics()
+
+	metricsMod.AddIndvMetric(p.getPrunedErrorDistribtuion(m))
+	metricsMod.AddIndvMetric(p.getErrorAttemptDistribtuion(m))
+	metricsMod.AddIndvMetric(p.getAttemptedPeersInLastIteration(m))
+	metricsMod.AddIndvMetric(p.getPeerstoreIterTime(m))
+	metricsMod.AddIndvMetric(p.getConnErrorDistribution(m))
+	metricsMod.AddIndvMetric(p.getTotalConnErrorDistribution(m))
+	if p.rateCtl != nil {
+		metricsMod.AddIndvMetric(p.getRateControllerStats(m))
+	}
 
 	return metricsMod
 
 }
 
-func (p *PeeringService) getPrunedErrorDistribtuion() *metrics.IndvMetrics {
+func (p *PeeringService) getPrunedErrorDistribtuion(m *peeringMetrics) *metrics.IndvMetrics {
 
-	initFn := func() error {
-		prometheus.MustRegister(PrunedErrorDistribution)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.PrunedErrorDistribution)
 		return nil
 	}
 
@@ -82,7 +131,7 @@ func (p *PeeringService) getPrunedErrorDistribtuion() *metrics.IndvMetrics {
 		summary := make(map[string]interface{}, 0)
 		controlDist := p.strategy.ControlDistribution()
 		for key, val := range controlDist {
-			PrunedErrorDistribution.WithLabelValues(key).Set(float64(val))
+			m.PrunedErrorDistribution.WithLabelValues(key).Set(float64(val))
 			summary[key] = val
 		}
 		return summary, nil
@@ -101,10 +150,10 @@ func (p *PeeringService) getPrunedErrorDistribtuion() *metrics.IndvMetrics {
 	return indvMetr
 }
 
-func (p *PeeringService) getErrorAttemptDistribtuion() *metrics.IndvMetrics {
+func (p *PeeringService) getErrorAttemptDistribtuion(m *peeringMetrics) *metrics.IndvMetrics {
 
-	initFn := func() error {
-		prometheus.MustRegister(ErrorAttemptDistribution)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.ErrorAttemptDistribution)
 		return nil
 	}
 
@@ -113,7 +162,7 @@ func (p *PeeringService) getErrorAttemptDistribtuion() *metrics.IndvMetrics {
 		errorAttemptDist := p.strategy.GetErrorAttemptDistribution()
 		for key, val := range errorAttemptDist {
 			summary[key] = val
-			ErrorAttemptDistribution.WithLabelValues(key).Set(float64(val))
+			m.ErrorAttemptDistribution.WithLabelValues(key).Set(float64(val))
 		}
 		return summary, nil
 	}
@@ -131,16 +180,16 @@ func (p *PeeringService) getErrorAttemptDistribtuion() *metrics.IndvMetrics {
 	return indvMetr
 }
 
-func (p *PeeringService) getAttemptedPeersInLastIteration() *metrics.IndvMetrics {
+func (p *PeeringService) getAttemptedPeersInLastIteration(m *peeringMetrics) *metrics.IndvMetrics {
 
-	initFn := func() error {
-		prometheus.MustRegister(PeersAttemptedInLastIteration)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.PeersAttemptedInLastIteration)
 		return nil
 	}
 
 	updateFn := func() (interface{}, error) {
 		peersAttemtpted := p.strategy.AttemptedPeersSinceLastIter()
-		PeersAttemptedInLastIteration.Set(float64(peersAttemtpted))
+		m.PeersAttemptedInLastIteration.Set(float64(peersAttemtpted))
 		return peersAttemtpted, nil
 	}
 
@@ -157,16 +206,16 @@ func (p *PeeringService) getAttemptedPeersInLastIteration() *metrics.IndvMetrics
 	return indvMetr
 }
 
-func (p *PeeringService) getPeerstoreIterTime() *metrics.IndvMetrics {
+func (p *PeeringService) getPeerstoreIterTime(m *peeringMetrics) *metrics.IndvMetrics {
 
-	initFn := func() error {
-		prometheus.MustRegister(PeerstoreIterTime)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.PeerstoreIterTime)
 		return nil
 	}
 
 	updateFn := func() (interface{}, error) {
 		iterTime := p.strategy.LastIterTime()
-		PeerstoreIterTime.Set(float64(iterTime))
+		m.PeerstoreIterTime.Set(float64(iterTime))
 		return iterTime, nil
 	}
 
@@ -183,10 +232,10 @@ func (p *PeeringService) getPeerstoreIterTime() *metrics.IndvMetrics {
 	return indvMetr
 }
 
-func (p *PeeringService) getConnErrorDistribution() *metrics.IndvMetrics {
+func (p *PeeringService) getConnErrorDistribution(m *peeringMetrics) *metrics.IndvMetrics {
 
-	initFn := func() error {
-		prometheus.MustRegister(ConnectionErrorDistribution)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.ConnectionErrorDistribution)
 		return nil
 	}
 
@@ -195,7 +244,7 @@ func (p *PeeringService) getConnErrorDistribution() *metrics.IndvMetrics {
 		errDist := p.strategy.GetConnErrorDistribution()
 		for key, val := range errDist {
 			summary[key] = val
-			ConnectionErrorDistribution.WithLabelValues(key).Set(float64(val))
+			m.ConnectionErrorDistribution.WithLabelValues(key).Set(float64(val))
 		}
 		return summary, nil
 	}
@@ -212,10 +261,10 @@ func (p *PeeringService) getConnErrorDistribution() *metrics.IndvMetrics {
 	return IndvMetr
 }
 
-func (p *PeeringService) getTotalConnErrorDistribution() *metrics.IndvMetrics {
+func (p *PeeringService) getTotalConnErrorDistribution(m *peeringMetrics) *metrics.IndvMetrics {
 
-	initFn := func() error {
-		prometheus.MustRegister(TotalConnectionErrorDistribution)
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.TotalConnectionErrorDistribution)
 		return nil
 	}
 
@@ -224,7 +273,7 @@ func (p *PeeringService) getTotalConnErrorDistribution() *metrics.IndvMetrics {
 		errDist := p.strategy.GetTotalConnErrorDistribution()
 		for key, val := range errDist {
 			summary[key] = val
-			TotalConnectionErrorDistribution.WithLabelValues(key).Set(float64(val))
+			m.TotalConnectionErrorDistribution.WithLabelValues(key).Set(float64(val))
 		}
 		return summary, nil
 	}
@@ -240,3 +289,46 @@ func (p *PeeringService) getTotalConnErrorDistribution() *metrics.IndvMetrics {
 
 	return IndvMetr
 }
+
+func (p *PeeringService) getRateControllerStats(m *peeringMetrics) *metrics.IndvMetrics {
+
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.DialWorkerLimit)
+		reg.MustRegister(m.ActiveDials)
+		reg.MustRegister(m.DialRate)
+		reg.MustRegister(m.DialTimeoutRate)
+		reg.MustRegister(m.OpenFileDescriptors)
+		return nil
+	}
+
+	updateFn := func() (interface{}, error) {
+		stats := p.rateCtl.Stats()
+		m.DialWorkerLimit.Set(float64(stats.WorkerLimit))
+		m.ActiveDials.Set(float64(stats.ActiveDials))
+		m.DialRate.WithLabelValues("allowed").Set(stats.DialRate)
+		m.DialRate.WithLabelValues("observed").Set(stats.ObservedRate)
+		m.DialTimeoutRate.Set(stats.TimeoutRate)
+		m.OpenFileDescriptors.WithLabelValues("open").Set(float64(stats.OpenFDs))
+		m.OpenFileDescriptors.WithLabelValues("limit").Set(float64(stats.FDLimit))
+		return map[string]interface{}{
+			"worker-limit":  stats.WorkerLimit,
+			"active-dials":  stats.ActiveDials,
+			"dial-rate":     stats.DialRate,
+			"observed-rate": stats.ObservedRate,
+			"timeout-rate":  stats.TimeoutRate,
+			"open-fds":      stats.OpenFDs,
+		}, nil
+	}
+
+	indvMetr, err := metrics.NewIndvMetrics(
+		"rate_controller",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(errors.Wrap(err, "unable to init rate_controller"))
+		return nil
+	}
+
+	return indvMetr
+}
@@ -12,8 +12,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/migalabs/armiarma/pkg/db/models"
-	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/db/storage"
 	"github.com/migalabs/armiarma/pkg/hosts"
 	log "github.com/sirupsen/logrus"
 )
@@ -22,21 +23,41 @@ var (
 	ConnectionRefuseTimeout = 20 * time.Second
 	MaxRetries              = 1
 	DefaultWorkers          = 500
+	// time that the ongoing dials have to finish when the service is stopped
+	StopTimeout = 30 * time.Second
 )
 
 type PeeringOption func(*PeeringService) error
 
+// peeringHost is the host (or pool of hosts) that dials the peers and notifies the connection events
+type peeringHost interface {
+	Connect(ctx context.Context, addrInfo peer.AddrInfo) error
+	IsConnected(peerID peer.ID) bool
+	ConnEventNotChannel() chan *models.EventTrace
+	IdentEventNotChannel() chan hosts.IdentificationEvent
+}
+
 // PeeringService is the main service that will connect peers from the given peerstore and using the given Host.
 // It will use the specified peering strategy, which might difer/change from the testing or desired purposes of the run.
 type PeeringService struct {
 	ctx context.Context
+	// canceled on Stop, the workers don't pick new peers but finish their ongoing dials
+	stopCtx  context.Context
+	stop     context.CancelFunc
+	workers  *sync.WaitGroup
+	stopOnce *sync.Once
 
-	host     *hosts.BasicLibp2pHost
-	DBClient *psql.DBClient
+	host     peeringHost
+	DBClient storage.Client
 	strategy PeeringStrategy
 	// Control Flags
-	Timeout    time.Duration
 	MaxRetries int
+	// in observer mode the discovered peers aren't dialed (only the events are recorded)
+	observer bool
+	// adapts the concurrent dials and the dial rate to the load of the host (static if nil)
+	rateCtl *RateController
+	// dial timeouts per class of peer
+	timeouts hosts.TimeoutPolicy
 
 	// metrics
 	m                 sync.RWMutex
@@ -46,16 +67,23 @@ type PeeringService struct {
 // Constructor
 func NewPeeringService(
 	ctx context.Context,
-	h *hosts.BasicLibp2pHost,
-	dbClient *psql.DBClient,
+	h peeringHost,
+	dbClient storage.Client,
 	opts ...PeeringOption) (PeeringService, error) {
 
+	timeouts := hosts.DefaultTimeoutPolicy()
+	timeouts.Default.Dial = ConnectionRefuseTimeout
+	stopCtx, stop := context.WithCancel(ctx)
 	pServ := PeeringService{
 		ctx:               ctx,
+		stopCtx:           stopCtx,
+		stop:              stop,
+		workers:           &sync.WaitGroup{},
+		stopOnce:          &sync.Once{},
 		host:              h,
 		DBClient:          dbClient,
-		Timeout:           ConnectionRefuseTimeout,
 		MaxRetries:        MaxRetries,
+		timeouts:          timeouts,
 		errorDistribution: make(map[string]int, 0),
 	}
 	// iterate through the Options given as args
@@ -73,12 +101,43 @@ func WithPeeringStrategy(strategy PeeringStrategy) PeeringOption {
 		if strategy == nil {
 			return fmt.Errorf("given peering strategy is empty")
 		}
-		log.Info("configuring crawler with peering strategy: %s", strategy.Type())
+		log.Infof("configuring crawler with peering strategy: %s", strategy.Type())
 		p.strategy = strategy
 		return nil
 	}
 }
 
+// WithObserverMode prevents the peering service from dialing the peers of the strategy,
+// while it keeps recording the connections and identifications that reach the host
+func WithObserverMode(observer bool) PeeringOption {
+	return func(p *PeeringService) error {
+		p.observer = observer
+		return nil
+	}
+}
+
+// WithRateController makes the peering workers dial under the limits of the given rate controller
+func WithRateController(rateCtl *RateController) PeeringOption {
+	return func(p *PeeringService) error {
+		if rateCtl == nil {
+			return fmt.Errorf("given rate controller is empty")
+		}
+		p.rateCtl = rateCtl
+		return nil
+	}
+}
+
+// WithTimeoutPolicy sets the dial timeouts of the peers, which depend on their class
+func WithTimeoutPolicy(policy hosts.TimeoutPolicy) PeeringOption {
+	return func(p *PeeringService) error {
+		if policy.Default.Dial <= 0 {
+			return fmt.Errorf("invalid dial timeout %s", policy.Default.Dial)
+		}
+		p.timeouts = policy
+		return nil
+	}
+}
+
 // Run:
 // Main peering event selector.
 // For every next peer received from the strategy, attempt the connection and record the status of this one.
@@ -90,9 +149,20 @@ func (c *PeeringService) Run() {
 	peerStreamChan := c.strategy.Run()
 
 	// set up the routines that will peer and record connections
-	for worker := 1; worker <= DefaultWorkers; worker++ {
-		workerName := fmt.Sprintf("Peering Worker %d", worker)
-		go c.peeringWorker(workerName, peerStreamChan)
+	if c.observer {
+		log.Info("running in observer mode, the discovered peers won't be dialed")
+	} else {
+		if c.rateCtl != nil {
+			c.rateCtl.Run()
+		}
+		for worker := 1; worker <= DefaultWorkers; worker++ {
+			workerName := fmt.Sprintf("Peering Worker %d", worker)
+			c.workers.Add(1)
+			go func() {
+				defer c.workers.Done()
+				c.peeringWorker(workerName, peerStreamChan)
+			}()
+		}
 	}
 	go c.eventRecorderRoutine()
 }
@@ -107,8 +177,6 @@ func (c *PeeringService) peeringWorker(workerID string, peerStreamChan chan *mod
 	})
 	logEntry.Debug("launching worker")
 
-	h := c.host.Host()
-
 	// Request new peer from the peering strategy
 	c.strategy.NextPeer()
 
@@ -117,18 +185,15 @@ func (c *PeeringService) peeringWorker(workerID string, peerStreamChan chan *mod
 		select {
 		// Next peer arrives
 		case nextPeer := <-peerStreamChan:
+			// the service might have been stopped while waiting for the peer
+			if c.stopCtx.Err() != nil {
+				logEntry.Infof("closing")
+				return
+			}
 			logEntry.Tracef("%s -> new peer %+v to connect", workerID, nextPeer)
 
 			// Check if the peer is already connected by the host
-			peerList := h.Network().Peers()
-			connected := false
-			for _, p := range peerList {
-				if p.String() == nextPeer.ID.String() {
-					connected = true
-					break
-				}
-			}
-			if connected {
+			if c.host.IsConnected(nextPeer.ID) {
 				logEntry.Tracef("%s -> Peer %s was already connected", workerID, nextPeer.ID.String())
 				c.strategy.NextPeer()
 				continue
@@ -139,28 +204,47 @@ func (c *PeeringService) peeringWorker(workerID string, peerStreamChan chan *mod
 			// control info for the attempt
 			var attStatus models.AttemptStatus = models.NegativeAttempt
 			var attError string = ""
+			var attRawError string = ""
 			var deprecable bool = false
 			var leftNet bool = false
+			var mismatchedPeer peer.ID
+
+			// wait until the rate controller allows a new dial
+			if c.rateCtl != nil {
+				if err := c.rateCtl.Acquire(c.stopCtx); err != nil {
+					logEntry.Infof("closing")
+					return
+				}
+			}
 
 			// try to connect the peer
 			logEntry.Debugf("%s addrs %s attempting connection to peer", workerID, addrInfo.Addrs)
 			attempts := 0
-			timeoutctx, cancel := context.WithTimeout(c.ctx, c.Timeout)
+			_, timeouts := c.timeouts.ForPeer(addrInfo.Addrs)
+			timeoutctx, cancel := context.WithTimeout(c.ctx, timeouts.Dial)
 			for attempts < c.MaxRetries {
-				if err := h.Connect(timeoutctx, addrInfo); err != nil { // there was an error
+				if err := c.host.Connect(timeoutctx, addrInfo); err != nil { // there was an error
 					logEntry.WithError(err).Debugf("%s attempts %d failed connection attempt to %+v",
 						workerID, attempts+1, addrInfo)
-					attError = hosts.ParseConError(err)
+					// distinguish our own dial timeout from the timeouts of the remote peer
+					connErr := hosts.ClassifyDialError(timeoutctx, err)
+					attError, attRawError = connErr.Code, connErr.Raw
+					if answered, ok := hosts.MismatchedPeer(err); ok {
+						mismatchedPeer = answered
+					}
 					attempts++
 					continue
 				} else { // connection successfuly made
 					logEntry.Debugf("successful connection to %s", nextPeer.ID.String())
 					attStatus = models.PossitiveAttempt
-					attError = hosts.NoConnError
+					attError, attRawError = hosts.NoConnError, ""
 					break
 				}
 			}
 			cancel()
+			if c.rateCtl != nil {
+				c.rateCtl.Release(attError)
+			}
 
 			// generate the connectionAttempt
 			connAttempt := models.NewConnAttempt(
@@ -170,6 +254,9 @@ func (c *PeeringService) peeringWorker(workerID string, peerStreamChan chan *mod
 				deprecable,
 				leftNet,
 			)
+			connAttempt.RawError = attRawError
+			connAttempt.Addrs = addrInfo.Addrs
+			connAttempt.MismatchedPeer = mismatchedPeer
 
 			// send it to the strategy
 			c.strategy.NewConnectionAttempt(connAttempt)
@@ -177,7 +264,7 @@ func (c *PeeringService) peeringWorker(workerID string, peerStreamChan chan *mod
 			c.strategy.NextPeer()
 
 		// Stoping go routine
-		case <-c.ctx.Done():
+		case <-c.stopCtx.Done():
 			logEntry.Infof("closing")
 			return
 		}
@@ -185,6 +272,30 @@ func (c *PeeringService) peeringWorker(workerID string, peerStreamChan chan *mod
 
 }
 
+// Stop stops dialing new peers, waits (up to the StopTimeout) for the ongoing dials to finish,
+// and persists the dial queue of the strategy so that the next run can resume it
+func (c *PeeringService) Stop() {
+	c.stopOnce.Do(func() {
+		log.Info("stopping the peering service")
+		c.stop()
+		doneC := make(chan struct{})
+		go func() {
+			c.workers.Wait()
+			close(doneC)
+		}()
+		select {
+		case <-doneC:
+		case <-time.After(StopTimeout):
+			log.Warnf("ongoing dials didn't finish after %s, stopping anyway", StopTimeout)
+		}
+		if err := c.strategy.SaveDialQueue(); err != nil {
+			log.WithError(err).Error("unable to persist the dial queue")
+			return
+		}
+		log.Info("dial queue persisted")
+	})
+}
+
 // eventRecorderRoutine:
 // The event selector records the status of any incoming connection and disconnection and
 // notifies the strategy of any recorded conn/disconn.
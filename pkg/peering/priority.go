@@ -0,0 +1,213 @@
+package peering
+
+import (
+	"container/heap"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	PriorityStrategy = "priority"
+
+	// age at which the freshness of an ENR halves
+	ENRFreshnessHalfLife = 6 * time.Hour
+)
+
+// DialScoreWeights are the weights of the components of the dial score of a peer
+type DialScoreWeights struct {
+	// how recently we saw an ENR of the peer
+	Freshness float64
+	// share of the dials of the peer that succeeded
+	Success float64
+	// missing data of the peer (never identified)
+	Gaps float64
+}
+
+// ParseDialScoreWeights parses the weights from the "freshness=0.4,success=0.3,gaps=0.3" format
+func ParseDialScoreWeights(raw string) (DialScoreWeights, error) {
+	weights := DialScoreWeights{}
+	for _, item := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			return weights, errors.Errorf("invalid dial score weight %s", item)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || w < 0 {
+			return weights, errors.Errorf("invalid dial score weight %s", item)
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "freshness":
+			weights.Freshness = w
+		case "success":
+			weights.Success = w
+		case "gaps":
+			weights.Gaps = w
+		default:
+			return weights, errors.Errorf("unknown dial score component %s", kv[0])
+		}
+	}
+	if weights.Freshness+weights.Success+weights.Gaps == 0 {
+		return weights, errors.New("all the dial score weights are zero")
+	}
+	return weights, nil
+}
+
+// Score combines the components of the peer, each of them between 0 and 1
+func (w DialScoreWeights) Score(freshness, success, gaps float64) float64 {
+	return w.Freshness*freshness + w.Success*success + w.Gaps*gaps
+}
+
+// dialScoreDB is the part of the DB client that the priority selector reads the peers from
+type dialScoreDB interface {
+	GetDialScoreInputs() (map[peer.ID]*models.DialScoreInput, error)
+}
+
+// dialRecord keeps the dials of a peer during the run
+type dialRecord struct {
+	attempts  int64
+	successes int64
+}
+
+// PrioritySelector dials in each round all the known peers sorted by a score, so that the crawl
+// time is spent first on the most informative dials: the peers with a fresh ENR, the ones that
+// usually accept our connections, and the ones that we still miss data of (never identified).
+// The score is scaled by the reputation of the peer (if any), so the bad peers are dialed last
+type PrioritySelector struct {
+	db      dialScoreDB
+	weights DialScoreWeights
+
+	m      sync.Mutex
+	dials  map[peer.ID]*dialRecord
+	scores map[peer.ID]float64
+}
+
+func NewPrioritySelector(db dialScoreDB, weights DialScoreWeights) *PrioritySelector {
+	return &PrioritySelector{
+		db:      db,
+		weights: weights,
+		dials:   make(map[peer.ID]*dialRecord),
+		scores:  make(map[peer.ID]float64),
+	}
+}
+
+func (s *PrioritySelector) Type() string {
+	return PriorityStrategy
+}
+
+func (s *PrioritySelector) NextPeerBatch(known []*models.RemoteConnectablePeer) []*models.RemoteConnectablePeer {
+	inputs, err := s.db.GetDialScoreInputs()
+	if err != nil {
+		// the peers are still dialed, only scored with the dials of the run
+		log.Error(errors.Wrap(err, "unable to read the dial score inputs"))
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	now := time.Now()
+	queue := make(dialQueue, 0, len(known))
+	s.scores = make(map[peer.ID]float64, len(known))
+	for _, p := range known {
+		score := s.score(p.ID, inputs[p.ID], now)
+		s.scores[p.ID] = score
+		queue = append(queue, &scoredPeer{peer: p, score: score})
+	}
+	heap.Init(&queue)
+	batch := make([]*models.RemoteConnectablePeer, 0, len(known))
+	for queue.Len() > 0 {
+		batch = append(batch, heap.Pop(&queue).(*scoredPeer).peer)
+	}
+	return batch
+}
+
+func (s *PrioritySelector) ProcessResult(attempt *models.ConnectionAttempt) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	record, ok := s.dials[attempt.RemotePeer]
+	if !ok {
+		record = &dialRecord{}
+		s.dials[attempt.RemotePeer] = record
+	}
+	record.attempts++
+	if attempt.Status == models.PossitiveAttempt {
+		record.successes++
+	}
+}
+
+// Score returns the score of the peer in the current batch (false if it isn't in it)
+func (s *PrioritySelector) Score(id peer.ID) (float64, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	score, ok := s.scores[id]
+	return score, ok
+}
+
+// score computes the dial score of the peer, input might be nil if the DB knows nothing about it (needs the lock)
+func (s *PrioritySelector) score(id peer.ID, input *models.DialScoreInput, now time.Time) float64 {
+	var freshness, gaps float64
+	var successes, attempts float64
+	if record, ok := s.dials[id]; ok {
+		successes, attempts = float64(record.successes), float64(record.attempts)
+	}
+	if input != nil {
+		if !input.LastENR.IsZero() {
+			age := math.Max(0, now.Sub(input.LastENR).Seconds())
+			freshness = math.Pow(2, -age/ENRFreshnessHalfLife.Seconds())
+		}
+		// a peer that connected before counts as one extra successful dial
+		if input.Connections > 0 {
+			successes++
+			attempts++
+		}
+		if !input.Identified {
+			gaps = 1
+		}
+	}
+	// laplace smoothing, so the peers never dialed start at 0.5
+	success := (successes + 1) / (attempts + 2)
+	score := s.weights.Score(freshness, success, gaps)
+	if input != nil && input.Reputation != nil {
+		score *= math.Max(0, math.Min(1, *input.Reputation))
+	}
+	return score
+}
+
+// scoredPeer is an item of the dial priority queue
+type scoredPeer struct {
+	peer  *models.RemoteConnectablePeer
+	score float64
+}
+
+// dialQueue is a max-heap of peers by their dial score (ties broken by peer ID to be deterministic)
+type dialQueue []*scoredPeer
+
+func (q dialQueue) Len() int { return len(q) }
+
+func (q dialQueue) Less(i, j int) bool {
+	if q[i].score == q[j].score {
+		return q[i].peer.ID < q[j].peer.ID
+	}
+	return q[i].score > q[j].score
+}
+
+func (q dialQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *dialQueue) Push(x any) {
+	*q = append(*q, x.(*scoredPeer))
+}
+
+func (q *dialQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,140 @@
+package peering
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+type testDialScoreDB struct {
+	inputs map[peer.ID]*models.DialScoreInput
+	err    error
+}
+
+func (db *testDialScoreDB) GetDialScoreInputs() (map[peer.ID]*models.DialScoreInput, error) {
+	return db.inputs, db.err
+}
+
+func testConnectablePeers(ids ...peer.ID) []*models.RemoteConnectablePeer {
+	peers := make([]*models.RemoteConnectablePeer, 0, len(ids))
+	for _, id := range ids {
+		peers = append(peers, models.NewRemoteConnectablePeer(id, nil, utils.EthereumNetwork))
+	}
+	return peers
+}
+
+func batchIDs(batch []*models.RemoteConnectablePeer) []peer.ID {
+	ids := make([]peer.ID, 0, len(batch))
+	for _, p := range batch {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func Test_ParseDialScoreWeights(t *testing.T) {
+	weights, err := ParseDialScoreWeights("freshness=0.4, success=0.3,gaps=0.3")
+	require.NoError(t, err)
+	require.Equal(t, DialScoreWeights{Freshness: 0.4, Success: 0.3, Gaps: 0.3}, weights)
+
+	// the missing components weight zero
+	weights, err = ParseDialScoreWeights("success=1")
+	require.NoError(t, err)
+	require.Equal(t, DialScoreWeights{Success: 1}, weights)
+
+	for _, raw := range []string{
+		"",
+		"freshness",
+		"freshness=high",
+		"freshness=-0.5",
+		"latency=0.5",
+		"freshness=0,success=0,gaps=0",
+	} {
+		_, err := ParseDialScoreWeights(raw)
+		require.Error(t, err, raw)
+	}
+}
+
+func Test_PriorityNextPeerBatch(t *testing.T) {
+	now := time.Now()
+	db := &testDialScoreDB{
+		inputs: map[peer.ID]*models.DialScoreInput{
+			"fresh":    {LastENR: now, Identified: true},
+			"stale":    {LastENR: now.Add(-2 * ENRFreshnessHalfLife), Identified: true},
+			"unknown":  {Identified: false},
+			"reliable": {LastENR: now.Add(-ENRFreshnessHalfLife), Connections: 10, Identified: true},
+		},
+	}
+	selector := NewPrioritySelector(db, DialScoreWeights{Freshness: 1})
+	require.Equal(t, PriorityStrategy, selector.Type())
+
+	// sorted by the freshness of their ENRs
+	batch := selector.NextPeerBatch(testConnectablePeers("stale", "unknown", "fresh", "reliable"))
+	require.Equal(t, []peer.ID{"fresh", "reliable", "stale", "unknown"}, batchIDs(batch))
+	score, ok := selector.Score("reliable")
+	require.True(t, ok)
+	require.InDelta(t, 0.5, score, 0.01)
+	_, ok = selector.Score("missing")
+	require.False(t, ok)
+
+	// the peers we miss data of go first when only the gaps count
+	selector = NewPrioritySelector(db, DialScoreWeights{Gaps: 1})
+	batch = selector.NextPeerBatch(testConnectablePeers("stale", "unknown", "fresh", "reliable"))
+	require.Equal(t, peer.ID("unknown"), batch[0].ID)
+	// and the rest tie, broken by peer ID
+	require.Equal(t, []peer.ID{"fresh", "reliable", "stale"}, batchIDs(batch[1:]))
+}
+
+func Test_PrioritySuccessScore(t *testing.T) {
+	db := &testDialScoreDB{inputs: map[peer.ID]*models.DialScoreInput{}}
+	selector := NewPrioritySelector(db, DialScoreWeights{Success: 1})
+
+	// never dialed peers start at 0.5
+	batch := selector.NextPeerBatch(testConnectablePeers("a", "b", "c"))
+	require.Equal(t, []peer.ID{"a", "b", "c"}, batchIDs(batch))
+	score, _ := selector.Score("b")
+	require.Equal(t, 0.5, score)
+
+	// the dials of the run move the peers up and down
+	selector.ProcessResult(models.NewConnAttempt("c", models.PossitiveAttempt, hosts.NoConnError, false, false))
+	selector.ProcessResult(models.NewConnAttempt("a", models.NegativeAttempt, "connection refused", false, false))
+	batch = selector.NextPeerBatch(testConnectablePeers("a", "b", "c"))
+	require.Equal(t, []peer.ID{"c", "b", "a"}, batchIDs(batch))
+	score, _ = selector.Score("c")
+	require.InDelta(t, 2.0/3.0, score, 1e-9)
+
+	// a failing DB doesn't stop the dials, scored with the dials of the run only
+	db.err = errors.New("db down")
+	batch = selector.NextPeerBatch(testConnectablePeers("a", "b", "c"))
+	require.Equal(t, []peer.ID{"c", "b", "a"}, batchIDs(batch))
+}
+
+func Test_PriorityReputation(t *testing.T) {
+	now := time.Now()
+	bad, fair, good, over := 0.1, 0.9, 1.0, 1.5
+	db := &testDialScoreDB{
+		inputs: map[peer.ID]*models.DialScoreInput{
+			"bad":     {LastENR: now, Reputation: &bad},
+			"good":    {LastENR: now.Add(-ENRFreshnessHalfLife), Reputation: &good},
+			"neutral": {LastENR: now.Add(-2 * ENRFreshnessHalfLife)},
+			"fair":    {LastENR: now, Reputation: &fair},
+			"capped":  {LastENR: now.Add(-3 * ENRFreshnessHalfLife), Reputation: &over},
+		},
+	}
+	selector := NewPrioritySelector(db, DialScoreWeights{Freshness: 1})
+
+	// the freshest peer is dialed last when its reputation is bad
+	batch := selector.NextPeerBatch(testConnectablePeers("bad", "good", "neutral", "fair", "capped"))
+	require.Equal(t, []peer.ID{"fair", "good", "neutral", "capped", "bad"}, batchIDs(batch))
+	score, _ := selector.Score("bad")
+	require.InDelta(t, 0.1, score, 0.01)
+	// reputations over 1 don't boost the score
+	score, _ = selector.Score("capped")
+	require.InDelta(t, 0.125, score, 0.01)
+}
@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/migalabs/armiarma/pkg/db/models"
-	psql "github.com/migalabs/armiarma/pkg/db/postgresql"
+	"github.com/migalabs/armiarma/pkg/db/storage"
 	"github.com/migalabs/armiarma/pkg/hosts"
 	"github.com/migalabs/armiarma/pkg/utils"
 
@@ -26,15 +26,27 @@ var (
 	MinIterTime = 5 * time.Second // Minimum time that has to pass before iterating again.
 	//
 	PruneStrategy = "pruning"
+
+	// rounds buffered for slow listeners (they are dropped if nobody listens)
+	roundBuffer = 8
 )
 
+// RoundStats summarizes a crawl round, a full iteration over the peer queue
+type RoundStats struct {
+	Round      int64
+	Start      time.Time
+	End        time.Time
+	Attempted  int
+	ConnErrors map[string]int64
+}
+
 // Pruning Strategy is a Peering Strategy that applies penalties to peers that haven't shown activity when attempting to connect them.
 // Combined with the Deprecated flag in the models.Peer struct, it produces more accurate metrics when exporting pruning peers that are no longer active.
 type PruningStrategy struct {
 	ctx context.Context
 
 	network  utils.NetworkType
-	DBClient *psql.DBClient
+	DBClient storage.Client
 
 	// Peer Stream and Return ConnectionStatus channels (communication between modules)
 	// both empty by default (need for initialization)
@@ -47,6 +59,10 @@ type PruningStrategy struct {
 	// List of peers sorted by the amount of time thatwe have to wait
 	PeerQueue *PeerQueue
 
+	// notification of the finished rounds (iterations of the peer queue)
+	roundC chan RoundStats
+	round  int64
+
 	// Prometheus Control Variables
 	m              sync.RWMutex
 	lastIterTime   time.Duration
@@ -59,13 +75,17 @@ type PruningStrategy struct {
 func NewPruningStrategy(
 	ctx context.Context,
 	network utils.NetworkType,
-	dbClient *psql.DBClient) (*PruningStrategy, error) {
+	dbClient storage.Client,
+	policy DeprecationPolicy) (*PruningStrategy, error) {
 
+	if err := policy.Check(); err != nil {
+		return nil, errors.Wrap(err, "invalid deprecation policy")
+	}
 	return &PruningStrategy{
 		ctx:            ctx,
 		network:        network,
 		DBClient:       dbClient,
-		PeerQueue:      NewPeerQueue(dbClient),
+		PeerQueue:      NewPeerQueue(dbClient, policy),
 		peerStreamChan: make(chan *models.HostInfo, DefaultWorkers),
 		nextPeerChan:   make(chan struct{}, DefaultWorkers),
 		connAttemptNot: make(chan *models.ConnectionAttempt),
@@ -73,6 +93,7 @@ func NewPruningStrategy(
 		identEventNot:  make(chan hosts.IdentificationEvent),
 		attemptedPeers: make(map[Delay]int64, 0),
 		connErrors:     make(map[string]int64, 0),
+		roundC:         make(chan RoundStats, roundBuffer),
 	}, nil
 }
 
@@ -92,6 +113,35 @@ func (c *PruningStrategy) Run() chan *models.HostInfo {
 	return c.peerStreamChan
 }
 
+// Rounds returns the channel where the stats of each finished crawl round are notified
+func (c *PruningStrategy) Rounds() <-chan RoundStats {
+	return c.roundC
+}
+
+// notifyRound notifies the end of a round with the conn errors of the attempted peers,
+// without blocking the iteration if nobody is reading the rounds
+func (c *PruningStrategy) notifyRound(start time.Time, attempted int) {
+	c.m.Lock()
+	c.round++
+	stats := RoundStats{
+		Round:      c.round,
+		Start:      start,
+		End:        time.Now(),
+		Attempted:  attempted,
+		ConnErrors: make(map[string]int64, len(c.connErrors)),
+	}
+	for connErr, cnt := range c.connErrors {
+		stats.ConnErrors[connErr] = cnt
+	}
+	c.m.Unlock()
+
+	select {
+	case c.roundC <- stats:
+	default:
+		log.Debugf("no listener for round %d, dropping its stats", stats.Round)
+	}
+}
+
 // ResetMapValues iterates over a string int map and resets all values to 0.
 func (c *PruningStrategy) composeDelayDistFromAttemptedPeers(prunedPeers map[peer.ID]*PrunedPeer) {
 	c.m.Lock()
@@ -198,6 +248,7 @@ func (c *PruningStrategy) peerstoreIteratorRoutine() {
 				// save attempted peers' values and reset the map
 				c.composeDelayDistFromAttemptedPeers(attemptedPeers)
 				c.composeConnErrorsFromAttemptedPeers(attemptedPeers)
+				c.notifyRound(iterStartTime, len(attemptedPeers))
 				// reset the attempted peers for the next iteration
 				attemptedPeers = make(map[peer.ID]*PrunedPeer)
 
@@ -266,9 +317,10 @@ func (c *PruningStrategy) eventRecorderRoutine() {
 			} else {
 				p.ConnEventHandler(connAttempt.Error)
 				// Check if peer needs to be deprecated
-				if p.Deprecable() {
-					logEntry.Warnf("deprecating peer %s", connAttempt.RemotePeer.String())
+				if reason := p.DeprecationReason(); reason != "" {
+					logEntry.Warnf("deprecating peer %s (%s)", connAttempt.RemotePeer.String(), reason)
 					connAttempt.Deprecable = true
+					connAttempt.DeprecationReason = reason
 					// remove p from list of peers to ping (if it appears again in the discovery, it will be updated as undeprecated in the DB)
 					c.PeerQueue.RemovePeer(connAttempt.RemotePeer)
 				}
@@ -278,30 +330,7 @@ func (c *PruningStrategy) eventRecorderRoutine() {
 
 		// Receive a notification of a connection event
 		case eventTrace := <-c.connEventNot:
-			// check if we already have a connectionEvent for that Peer waiting for it pair to come
-			bEvent, ok := connEventBuffer[eventTrace.PeerID]
-			if !ok {
-				// if there is no prev trace - create a new one
-				bEvent = models.NewConnEvent(eventTrace.PeerID)
-				connEventBuffer[eventTrace.PeerID] = bEvent
-			}
-			// check what event came in the trace and add it to the matching prev event
-			switch eventTrace.Event.(type) {
-			case (*models.ConnInfo):
-				cInfo := eventTrace.Event.(*models.ConnInfo)
-				bEvent.AddConnInfo(*cInfo)
-			case (*models.EndConnInfo):
-				endConnInfo := eventTrace.Event.(*models.EndConnInfo)
-				bEvent.AddDisconn(*endConnInfo)
-			default:
-				logEntry.Warnf("invalid event trace for peer %s - %x\n", eventTrace.PeerID.String(), eventTrace.Event)
-			}
-
-			// check if the ConnEvent is ready to be persisted
-			if bEvent.IsReadyToPersist() {
-				logEntry.Debugf("persising full conn event for peer %s", bEvent.PeerID.String())
-				c.DBClient.PersistToDB(bEvent)
-			}
+			recordConnEvent(connEventBuffer, eventTrace, c.DBClient, logEntry)
 
 		case identEvent := <-c.identEventNot:
 			logEntry.Debugf("new identification from peer %s", identEvent.HostInfo.ID.String())
@@ -315,6 +344,35 @@ func (c *PruningStrategy) eventRecorderRoutine() {
 	}
 }
 
+// recordConnEvent pairs the connection and the disconnection of the peers, persisting the
+// connection event once both of them are known
+func recordConnEvent(connEventBuffer map[peer.ID]*models.ConnEvent, eventTrace *models.EventTrace, dbClient storage.Client, logEntry *log.Entry) {
+	// check if we already have a connectionEvent for that Peer waiting for it pair to come
+	bEvent, ok := connEventBuffer[eventTrace.PeerID]
+	if !ok {
+		// if there is no prev trace - create a new one
+		bEvent = models.NewConnEvent(eventTrace.PeerID)
+		connEventBuffer[eventTrace.PeerID] = bEvent
+	}
+	// check what event came in the trace and add it to the matching prev event
+	switch eventTrace.Event.(type) {
+	case (*models.ConnInfo):
+		cInfo := eventTrace.Event.(*models.ConnInfo)
+		bEvent.AddConnInfo(*cInfo)
+	case (*models.EndConnInfo):
+		endConnInfo := eventTrace.Event.(*models.EndConnInfo)
+		bEvent.AddDisconn(*endConnInfo)
+	default:
+		logEntry.Warnf("invalid event trace for peer %s - %x\n", eventTrace.PeerID.String(), eventTrace.Event)
+	}
+
+	// check if the ConnEvent is ready to be persisted
+	if bEvent.IsReadyToPersist() {
+		logEntry.Debugf("persising full conn event for peer %s", bEvent.PeerID.String())
+		dbClient.PersistToDB(bEvent)
+	}
+}
+
 // NextPeer notifies the peerstore iterator that a new peer has been requested.
 // After it, the peerstore iterator will put the new peer in the PeerStreamChan.
 func (c *PruningStrategy) NextPeer() {
@@ -394,18 +452,23 @@ type PeerQueue struct {
 	sync.RWMutex
 
 	// DBs
-	dbClient *psql.DBClient
+	dbClient storage.Client
+	// applied to all the peers of the queue
+	policy DeprecationPolicy
 
 	// control variables
 	peerPtr  int
 	peerList []*PrunedPeer
 	peerMap  map[peer.ID]*PrunedPeer
+	// backoff state persisted by the previous run, applied on the first load of the peers
+	resumed map[peer.ID]models.DialQueueState
 }
 
 // NewPeerQueue is the constructor of a NewPeerQueue
-func NewPeerQueue(dbClient *psql.DBClient) *PeerQueue {
+func NewPeerQueue(dbClient storage.Client, policy DeprecationPolicy) *PeerQueue {
 	return &PeerQueue{
 		dbClient: dbClient,
+		policy:   policy,
 		peerPtr:  0,
 		peerList: make([]*PrunedPeer, 0),
 		peerMap:  make(map[peer.ID]*PrunedPeer),
@@ -583,10 +646,16 @@ func (c *PeerQueue) UpdatePeerListFromRemoteDB() error {
 			// Whenever we find a new peer that we didn't have locally, add zero delay
 			// even when we read all the peerstore from the DB Endpoint when restarting
 			newPrunnedPeer := NewPrunedPeer(connectablePeer.ID, connectablePeer.Addrs, connectablePeer.Network, Minus1Delay)
+			newPrunnedPeer.policy = c.policy
+			// unless the previous run already dialed it
+			if state, ok := c.popResumed(connectablePeer.ID); ok {
+				newPrunnedPeer.restore(state)
+			}
 			// add the new item to the list
 			c.AddPeer(newPrunnedPeer)
 		}
 	}
+	c.dropResumed()
 	// Sort the list of peers based on the next connection
 	c.SortPeerList()
 	log.Debugf("Num of peers in PeerQueue: %d\n", c.Len())
@@ -600,8 +669,10 @@ type PrunedPeer struct {
 	// control variables
 	connError                string
 	delayObj                 DelayObject // define the delay to connect based on error
+	failures                 int         // failed attempts in a row
 	baseConnectionTimestamp  time.Time   // define the first event. To calculate the next connection we sum this with delay.
-	baseDeprecationTimestamp time.Time   // this + DeprecationTime defines when we are ready to deprecate
+	baseDeprecationTimestamp time.Time   // this + ExpiryWindow of the policy defines when we are ready to deprecate
+	policy                   DeprecationPolicy
 }
 
 func NewPrunedPeer(id peer.ID, maddrs []ma.Multiaddr, network utils.NetworkType, delay Delay) *PrunedPeer {
@@ -612,6 +683,7 @@ func NewPrunedPeer(id peer.ID, maddrs []ma.Multiaddr, network utils.NetworkType,
 		connError:                "--", // init connError to undefined
 		network:                  network,
 		delayObj:                 NewDelayObject(delay),
+		policy:                   DefaultDeprecationPolicy(),
 		baseConnectionTimestamp:  t,
 		baseDeprecationTimestamp: t, // by default we set it now, so if no positive connection it will be deprecated in 24 hours since creation of this prunned peer
 	}
@@ -631,20 +703,25 @@ func (c *PrunedPeer) NextConnection() time.Time {
 	if c.delayObj.dtype == Minus1Delay { // in case of Minus1, this is new peer and we want it to connect as soon as possible
 		return time.Time{}
 	}
-	if c.delayObj.CalculateDelay() > MaxDelayTime {
-		return c.baseConnectionTimestamp.Add(MaxDelayTime)
-	}
-	// nextConnection should be from first event + the applied delay
-	return c.baseConnectionTimestamp.Add(c.delayObj.CalculateDelay())
+	// nextConnection should be from first event + the delay of the policy (capped at its max)
+	return c.baseConnectionTimestamp.Add(c.policy.delay(c.delayObj))
 }
 
 // Deprecable evaluates if the peer is in time to be deprecated.
 func (c *PrunedPeer) Deprecable() bool {
-	// if the difference between now and the BaseDeprecationTimestampo is more than the DeprecationTime, true
-	if time.Now().Sub(c.baseDeprecationTimestamp) >= DeprecationTime {
-		return true
+	return c.DeprecationReason() != ""
+}
+
+// DeprecationReason returns why the peer has to be deprecated under the policy (empty if it doesn't)
+func (c *PrunedPeer) DeprecationReason() string {
+	if c.policy.MaxAttempts > 0 && c.failures >= c.policy.MaxAttempts {
+		return DeprecationMaxAttempts
 	}
-	return false
+	// if the difference between now and the BaseDeprecationTimestampo is more than the expiry window
+	if time.Since(c.baseDeprecationTimestamp) >= c.policy.ExpiryWindow {
+		return DeprecationExpired
+	}
+	return ""
 }
 
 // RecErrorHandler selects actuation method for each of the possible errors while actively dialing peers.
@@ -670,6 +747,9 @@ func (c *PrunedPeer) UpdateDelay(recErr string) {
 	// therefore, we start counting from now to deprecate
 	if c.delayObj.dtype == PositiveDelay {
 		c.baseDeprecationTimestamp = time.Now()
+		c.failures = 0
+	} else {
+		c.failures++
 	}
 
 	c.delayObj.IncreaseDegree()
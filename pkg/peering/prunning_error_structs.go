@@ -41,6 +41,7 @@ func ErrorToDelayType(errString string) Delay {
 	case hosts.DialErrorConnectionResetByPeer,
 		hosts.DialErrorConnectionRefused,
 		hosts.DialErrorContextDeadlineExceeded,
+		hosts.DialErrorDialTimeout,
 		hosts.DialErrorBackOff,
 		hosts.ErrorRequestingMetadta,
 		"unknown":
@@ -4,61 +4,69 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/utils"
 )
 
 func Test_Deprecation(t *testing.T) {
-	require.Equal(t, 1, 1)
-
-	testPeer := NewPrunedPeer("test", PositiveDelayType)
-	testPeer.BaseDeprecationTimestamp = testPeer.BaseDeprecationTimestamp.Add(-DeprecationTime)
-
-	require.Equal(t, true, testPeer.Deprecable())
-
-	testPeer.ConnEventHandler("None")
-
-	require.Equal(t, false, testPeer.Deprecable())
+	testPeer := NewPrunedPeer("test", nil, utils.EthereumNetwork, PositiveDelay)
+	require.False(t, testPeer.Deprecable())
+	testPeer.baseDeprecationTimestamp = testPeer.baseDeprecationTimestamp.Add(-DeprecationTime)
 
-	testPeer.BaseDeprecationTimestamp = testPeer.BaseDeprecationTimestamp.Add(-DeprecationTime)
+	require.True(t, testPeer.Deprecable())
+	require.Equal(t, DeprecationExpired, testPeer.DeprecationReason())
 
-	testPeer.ConnEventHandler("error requesting metadata")
-	require.Equal(t, true, testPeer.Deprecable())
+	testPeer.ConnEventHandler(hosts.NoConnError)
+	require.False(t, testPeer.Deprecable())
 
-	testPeer.ConnEventHandler("i/o timeout")
-	require.Equal(t, true, testPeer.Deprecable())
+	testPeer.baseDeprecationTimestamp = testPeer.baseDeprecationTimestamp.Add(-DeprecationTime)
 
-	testPeer.ConnEventHandler("None")
+	testPeer.ConnEventHandler(hosts.ErrorRequestingMetadta)
+	require.True(t, testPeer.Deprecable())
 
-	require.Equal(t, false, testPeer.Deprecable())
+	testPeer.ConnEventHandler(hosts.DialErrorIoTimeout)
+	require.True(t, testPeer.Deprecable())
 
-	testPeer.ConnEventHandler("connection reset by peer")
-	require.Equal(t, false, testPeer.Deprecable())
+	testPeer.ConnEventHandler(hosts.NoConnError)
+	require.False(t, testPeer.Deprecable())
 
-	testPeer.ConnEventHandler("dial to self attempted")
-	require.Equal(t, false, testPeer.Deprecable())
+	testPeer.ConnEventHandler(hosts.DialErrorConnectionResetByPeer)
+	require.False(t, testPeer.Deprecable())
 
-	testPeer.BaseDeprecationTimestamp = testPeer.BaseDeprecationTimestamp.Add(-DeprecationTime)
+	testPeer.ConnEventHandler(hosts.DialErrorSelfAttempt)
+	require.False(t, testPeer.Deprecable())
 
-	testPeer.ConnEventHandler("connection refused")
-	require.Equal(t, true, testPeer.Deprecable())
+	testPeer.baseDeprecationTimestamp = testPeer.baseDeprecationTimestamp.Add(-DeprecationTime)
 
-	testPeer.ConnEventHandler("context deadline exceeded")
-	require.Equal(t, true, testPeer.Deprecable())
-
-	testPeer.ConnEventHandler("no route to host")
-	require.Equal(t, true, testPeer.Deprecable())
-
-	testPeer.ConnEventHandler("peer id mismatch")
-	require.Equal(t, true, testPeer.Deprecable())
-
-	testPeer.ConnEventHandler("rfgdsfghsdfh")
-	require.Equal(t, true, testPeer.Deprecable())
-
-	testPeer.ConnEventHandler("None")
-	require.Equal(t, false, testPeer.Deprecable())
+	for _, connErr := range []string{
+		hosts.DialErrorConnectionRefused,
+		hosts.DialErrorContextDeadlineExceeded,
+		hosts.DialErrorNoRouteToHost,
+		hosts.DialErrorPeerIDMismatch,
+		"rfgdsfghsdfh",
+	} {
+		testPeer.ConnEventHandler(connErr)
+		require.True(t, testPeer.Deprecable(), connErr)
+	}
 
+	testPeer.ConnEventHandler(hosts.NoConnError)
+	require.False(t, testPeer.Deprecable())
 }
 
-// Test the overall functionning of the pruning strategy
-func Test_Pruning(t *testing.T) {
-
+func Test_DeprecationMaxAttempts(t *testing.T) {
+	testPeer := NewPrunedPeer("test", nil, utils.EthereumNetwork, Minus1Delay)
+	testPeer.policy.MaxAttempts = 3
+
+	testPeer.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	testPeer.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	require.False(t, testPeer.Deprecable())
+	// a successful connection resets the failures in a row
+	testPeer.ConnEventHandler(hosts.NoConnError)
+	testPeer.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	testPeer.ConnEventHandler(hosts.DialErrorConnectionRefused)
+	require.False(t, testPeer.Deprecable())
+	testPeer.ConnEventHandler(hosts.DialErrorIoTimeout)
+	require.True(t, testPeer.Deprecable())
+	require.Equal(t, DeprecationMaxAttempts, testPeer.DeprecationReason())
 }
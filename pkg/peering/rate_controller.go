@@ -0,0 +1,282 @@
+package peering
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+/**
+The rate controller adapts the number of concurrent dials and the dials per second of the peering
+service to what the host can handle. Every control interval it checks the dials of the interval:
+if too many of them timed out, if the resource manager refused any of them, or if the process is
+close to its limit of open file descriptors, the concurrency and the rate are halved (multiplicative
+decrease). Otherwise, if the dials kept up with at least half of the allowed rate, both grow by a
+fixed step (additive increase), up to the configured maximums.
+*/
+
+var (
+	RateControlInterval = 10 * time.Second
+
+	DefaultMinDialWorkers = 10
+	DefaultMinDialRate    = 5.0   // dials per second
+	DefaultMaxDialRate    = 100.0 // dials per second
+
+	// additive increase of the concurrency and the rate on each healthy interval
+	DialWorkersStep = 25
+	DialRateStep    = 5.0
+
+	// share of timed out dials, and of open file descriptors, at which the controller backs off
+	MaxDialTimeoutRate = 0.6
+	MaxFDUsage         = 0.8
+)
+
+// dial errors that mean that our host (rather than the remote peer) is overloaded
+var (
+	overloadErrors = map[string]struct{}{
+		hosts.DialErrorIoTimeout:               {},
+		hosts.DialErrorContextDeadlineExceeded: {},
+		hosts.DialErrorDialTimeout:             {},
+	}
+	resourceErrors = map[string]struct{}{
+		hosts.ResourceLimitError:        {},
+		hosts.DialErrorTooManyOpenFiles: {},
+	}
+)
+
+// RateControllerStats is the current state of the rate controller
+type RateControllerStats struct {
+	WorkerLimit  int
+	ActiveDials  int
+	DialRate     float64 // allowed dials per second
+	ObservedRate float64 // dials per second during the last interval
+	TimeoutRate  float64 // share of dials that timed out during the last interval
+	ResourceErrs int64   // dials refused by the resource manager during the last interval
+	OpenFDs      int
+	FDLimit      int
+}
+
+// RateController limits the concurrent dials and the dials per second of the peering workers
+type RateController struct {
+	ctx context.Context
+
+	m          sync.Mutex
+	minWorkers int
+	maxWorkers int
+	minRate    float64
+	maxRate    float64
+
+	workerLimit int
+	active      int
+	rate        float64
+	nextDial    time.Time
+	// closed (and replaced) each time a slot might have been released
+	wakeC chan struct{}
+
+	// dials of the current interval
+	dials        int64
+	timeouts     int64
+	resourceErrs int64
+
+	lastStats RateControllerStats
+}
+
+func NewRateController(ctx context.Context, maxWorkers int, maxRate float64) (*RateController, error) {
+	if maxWorkers < DefaultMinDialWorkers {
+		return nil, errors.Errorf("max dial workers %d below the minimum of %d", maxWorkers, DefaultMinDialWorkers)
+	}
+	if maxRate < DefaultMinDialRate {
+		return nil, errors.Errorf("max dial rate %.2f below the minimum of %.2f", maxRate, DefaultMinDialRate)
+	}
+	return &RateController{
+		ctx:        ctx,
+		minWorkers: DefaultMinDialWorkers,
+		maxWorkers: maxWorkers,
+		minRate:    DefaultMinDialRate,
+		maxRate:    maxRate,
+		// start slow, the controller ramps up while the host copes with it
+		workerLimit: DefaultMinDialWorkers,
+		rate:        DefaultMinDialRate,
+		wakeC:       make(chan struct{}),
+	}, nil
+}
+
+// SetLimits changes the maximum concurrency and dial rate at runtime, capping the current
+// limits if they were over the new maximums
+func (r *RateController) SetLimits(maxWorkers int, maxRate float64) error {
+	if maxWorkers < DefaultMinDialWorkers {
+		return errors.Errorf("max dial workers %d below the minimum of %d", maxWorkers, DefaultMinDialWorkers)
+	}
+	if maxRate < DefaultMinDialRate {
+		return errors.Errorf("max dial rate %.2f below the minimum of %.2f", maxRate, DefaultMinDialRate)
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.maxWorkers = maxWorkers
+	r.maxRate = maxRate
+	r.workerLimit = min(r.workerLimit, maxWorkers)
+	r.rate = min(r.rate, maxRate)
+	return nil
+}
+
+// Run launches the routine that adapts the limits every control interval
+func (r *RateController) Run() {
+	go r.controlRoutine()
+}
+
+func (r *RateController) controlRoutine() {
+	logEntry := log.WithFields(log.Fields{
+		"mod": "dial-rate-ctrl",
+	})
+	logEntry.Debug("init")
+	ticker := time.NewTicker(RateControlInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stats := r.adjust(RateControlInterval)
+			logEntry.WithFields(log.Fields{
+				"workers":   stats.WorkerLimit,
+				"rate":      stats.DialRate,
+				"observed":  stats.ObservedRate,
+				"timeouts":  stats.TimeoutRate,
+				"rcmgr-err": stats.ResourceErrs,
+				"open-fds":  stats.OpenFDs,
+			}).Debug("dial limits updated")
+
+		case <-r.ctx.Done():
+			logEntry.Debug("closing")
+			return
+		}
+	}
+}
+
+// Acquire blocks until the worker is allowed to dial, which means that there is a free dial slot
+// and that the pace of the dial rate allows it. Each successful Acquire must be followed by a Release
+func (r *RateController) Acquire(ctx context.Context) error {
+	for {
+		r.m.Lock()
+		if r.active < r.workerLimit {
+			r.active++
+			now := time.Now()
+			if r.nextDial.Before(now) {
+				r.nextDial = now
+			}
+			wait := r.nextDial.Sub(now)
+			r.nextDial = r.nextDial.Add(time.Duration(float64(time.Second) / r.rate))
+			r.m.Unlock()
+			if wait <= 0 {
+				return nil
+			}
+			select {
+			case <-time.After(wait):
+				return nil
+			case <-ctx.Done():
+				r.cancel()
+				return ctx.Err()
+			}
+		}
+		wakeC := r.wakeC
+		r.m.Unlock()
+		select {
+		case <-wakeC:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release frees the dial slot of a worker, recording the parsed error of its dial
+func (r *RateController) Release(connErr string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.dials++
+	if _, ok := overloadErrors[connErr]; ok {
+		r.timeouts++
+	}
+	if _, ok := resourceErrors[connErr]; ok {
+		r.resourceErrs++
+	}
+	r.active--
+	r.wake()
+}
+
+// cancel frees the dial slot of a worker that didn't dial
+func (r *RateController) cancel() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.active--
+	r.wake()
+}
+
+// wake notifies the workers waiting for a slot (needs the lock)
+func (r *RateController) wake() {
+	close(r.wakeC)
+	r.wakeC = make(chan struct{})
+}
+
+// adjust applies the AIMD step with the dials of the last interval and resets them
+func (r *RateController) adjust(interval time.Duration) RateControllerStats {
+	openFDs, fdLimit := fdUsage()
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	stats := RateControllerStats{
+		ActiveDials:  r.active,
+		ObservedRate: float64(r.dials) / interval.Seconds(),
+		ResourceErrs: r.resourceErrs,
+		OpenFDs:      openFDs,
+		FDLimit:      fdLimit,
+	}
+	if r.dials > 0 {
+		stats.TimeoutRate = float64(r.timeouts) / float64(r.dials)
+	}
+	fdPressure := fdLimit > 0 && float64(openFDs) >= MaxFDUsage*float64(fdLimit)
+
+	switch {
+	case stats.ResourceErrs > 0, fdPressure, stats.TimeoutRate >= MaxDialTimeoutRate:
+		r.workerLimit = max(r.minWorkers, r.workerLimit/2)
+		r.rate = max(r.minRate, r.rate/2)
+	case r.dials > 0 && stats.ObservedRate >= r.rate/2:
+		// only grow if the current limits are actually being used
+		r.workerLimit = min(r.maxWorkers, r.workerLimit+DialWorkersStep)
+		r.rate = min(r.maxRate, r.rate+DialRateStep)
+		r.wake()
+	}
+	r.dials, r.timeouts, r.resourceErrs = 0, 0, 0
+
+	stats.WorkerLimit = r.workerLimit
+	stats.DialRate = r.rate
+	r.lastStats = stats
+	return stats
+}
+
+// Stats returns the state of the controller at the end of the last interval
+func (r *RateController) Stats() RateControllerStats {
+	r.m.Lock()
+	defer r.m.Unlock()
+	stats := r.lastStats
+	stats.WorkerLimit = r.workerLimit
+	stats.ActiveDials = r.active
+	stats.DialRate = r.rate
+	return stats
+}
+
+// fdUsage returns the open file descriptors of the process and its soft limit (-1 if unknown)
+func fdUsage() (open int, limit int) {
+	open, limit = -1, -1
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		open = len(fds)
+	}
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err == nil {
+		limit = int(rlimit.Cur)
+	}
+	return open, limit
+}
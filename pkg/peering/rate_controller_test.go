@@ -0,0 +1,194 @@
+package peering
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/hosts"
+)
+
+func Test_NewRateController(t *testing.T) {
+	_, err := NewRateController(context.Background(), DefaultMinDialWorkers-1, DefaultMaxDialRate)
+	require.Error(t, err)
+	_, err = NewRateController(context.Background(), 100, DefaultMinDialRate/2)
+	require.Error(t, err)
+
+	ctrl, err := NewRateController(context.Background(), 100, DefaultMaxDialRate)
+	require.NoError(t, err)
+	// it starts slow
+	stats := ctrl.Stats()
+	require.Equal(t, DefaultMinDialWorkers, stats.WorkerLimit)
+	require.Equal(t, DefaultMinDialRate, stats.DialRate)
+}
+
+func Test_RateControllerAdjust(t *testing.T) {
+	ctrl, err := NewRateController(context.Background(), 50, 12)
+	require.NoError(t, err)
+
+	// the limits don't grow if they aren't used
+	ctrl.dials = 2
+	stats := ctrl.adjust(time.Second)
+	require.Equal(t, DefaultMinDialWorkers, stats.WorkerLimit)
+	require.Equal(t, DefaultMinDialRate, stats.DialRate)
+	require.Equal(t, 2.0, stats.ObservedRate)
+
+	// additive increase while the dials keep up with the rate, up to the maximums
+	ctrl.dials = 5
+	stats = ctrl.adjust(time.Second)
+	require.Equal(t, DefaultMinDialWorkers+DialWorkersStep, stats.WorkerLimit)
+	require.Equal(t, DefaultMinDialRate+DialRateStep, stats.DialRate)
+	ctrl.dials = 10
+	stats = ctrl.adjust(time.Second)
+	require.Equal(t, 50, stats.WorkerLimit)
+	require.Equal(t, 12.0, stats.DialRate)
+
+	// the dials of each interval are reset
+	stats = ctrl.adjust(time.Second)
+	require.Equal(t, 0.0, stats.ObservedRate)
+	require.Equal(t, 50, stats.WorkerLimit)
+
+	// multiplicative decrease when most of the dials time out
+	for i := 0; i < 10; i++ {
+		ctrl.active++
+		if i < 6 {
+			ctrl.Release(hosts.DialErrorIoTimeout)
+		} else {
+			ctrl.Release(hosts.NoConnError)
+		}
+	}
+	stats = ctrl.adjust(time.Second)
+	require.Equal(t, 0.6, stats.TimeoutRate)
+	require.Equal(t, 25, stats.WorkerLimit)
+	require.Equal(t, 6.0, stats.DialRate)
+
+	// or when the resource manager refuses any dial, never below the minimums
+	ctrl.dials = 100
+	ctrl.resourceErrs = 1
+	stats = ctrl.adjust(time.Second)
+	require.Equal(t, int64(1), stats.ResourceErrs)
+	require.Equal(t, 12, stats.WorkerLimit)
+	require.Equal(t, DefaultMinDialRate, stats.DialRate)
+	ctrl.resourceErrs = 1
+	stats = ctrl.adjust(time.Second)
+	require.Equal(t, DefaultMinDialWorkers, stats.WorkerLimit)
+	require.Equal(t, DefaultMinDialRate, stats.DialRate)
+	require.Equal(t, stats, ctrl.Stats())
+
+	// lowering the maximums caps the current limits
+	ctrl.workerLimit, ctrl.rate = 50, 12
+	require.Error(t, ctrl.SetLimits(DefaultMinDialWorkers-1, 12))
+	require.NoError(t, ctrl.SetLimits(20, 8))
+	stats = ctrl.Stats()
+	require.Equal(t, 20, stats.WorkerLimit)
+	require.Equal(t, 8.0, stats.DialRate)
+}
+
+func Test_RateControllerAcquire(t *testing.T) {
+	ctx := context.Background()
+	ctrl, err := NewRateController(ctx, 100, DefaultMaxDialRate)
+	require.NoError(t, err)
+	// don't pace the dials
+	ctrl.rate = 1e9
+
+	for i := 0; i < DefaultMinDialWorkers; i++ {
+		require.NoError(t, ctrl.Acquire(ctx))
+	}
+	require.Equal(t, DefaultMinDialWorkers, ctrl.Stats().ActiveDials)
+
+	// no slot left until a dial is released
+	acquired := make(chan error)
+	go func() { acquired <- ctrl.Acquire(ctx) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquired a slot over the worker limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ctrl.Release(hosts.NoConnError)
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the released slot wasn't given to the waiting worker")
+	}
+	require.Equal(t, DefaultMinDialWorkers, ctrl.Stats().ActiveDials)
+
+	// a canceled wait doesn't take any slot
+	cancelCtx, cancel := context.WithCancel(ctx)
+	go func() { acquired <- ctrl.Acquire(cancelCtx) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-acquired:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("the canceled worker kept waiting")
+	}
+	require.Equal(t, DefaultMinDialWorkers, ctrl.Stats().ActiveDials)
+	for i := 0; i < DefaultMinDialWorkers; i++ {
+		ctrl.Release(hosts.NoConnError)
+	}
+	require.Equal(t, 0, ctrl.Stats().ActiveDials)
+}
+
+func Test_RateControllerPace(t *testing.T) {
+	ctx := context.Background()
+	ctrl, err := NewRateController(ctx, 100, DefaultMaxDialRate)
+	require.NoError(t, err)
+	ctrl.rate = 20 // a dial every 50ms
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ctrl.Acquire(ctx))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// canceling the worker while it waits for its turn frees its slot
+	ctrl.nextDial = time.Now().Add(time.Second)
+	cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, ctrl.Acquire(cancelCtx), context.DeadlineExceeded)
+	require.Equal(t, 3, ctrl.Stats().ActiveDials)
+}
+
+func Test_RateControllerBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		errors  []string
+		fdUsage float64
+		backoff bool
+	}{
+		{"healthy dials", []string{hosts.NoConnError, hosts.NoConnError}, MaxFDUsage, false},
+		{"context deadline", []string{hosts.DialErrorContextDeadlineExceeded, hosts.DialErrorDialTimeout}, MaxFDUsage, true},
+		// the timeouts under the threshold are blamed on the remote peers
+		{"few timeouts", []string{hosts.DialErrorIoTimeout, hosts.NoConnError, hosts.NoConnError}, MaxFDUsage, false},
+		{"resource manager", []string{hosts.ResourceLimitError, hosts.NoConnError, hosts.NoConnError}, MaxFDUsage, true},
+		{"too many open files", []string{hosts.DialErrorTooManyOpenFiles, hosts.NoConnError, hosts.NoConnError}, MaxFDUsage, true},
+		// any process is over a null share of open file descriptors
+		{"file descriptors", []string{hosts.NoConnError, hosts.NoConnError}, 0, true},
+	}
+
+	defer func(fdUsage float64) { MaxFDUsage = fdUsage }(MaxFDUsage)
+	for _, test := range tests {
+		MaxFDUsage = test.fdUsage
+		ctrl, err := NewRateController(context.Background(), 100, DefaultMaxDialRate)
+		require.NoError(t, err, test.name)
+		ctrl.workerLimit, ctrl.rate = 40, 40
+		for _, connErr := range test.errors {
+			ctrl.active++
+			ctrl.Release(connErr)
+		}
+
+		stats := ctrl.adjust(time.Second)
+		if test.backoff {
+			require.Equal(t, 20, stats.WorkerLimit, test.name)
+			require.Equal(t, 20.0, stats.DialRate, test.name)
+			continue
+		}
+		// the dials didn't keep up with the rate, so the limits stay
+		require.Equal(t, 40, stats.WorkerLimit, test.name)
+		require.Equal(t, 40.0, stats.DialRate, test.name)
+	}
+}
@@ -0,0 +1,188 @@
+package peering
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+/**
+On shutdown, the strategies persist the state of their dial queue into the DB: the backoff of each
+peer in the pruning strategy, and the pending peers of the current batch (plus the last dial of each
+peer) in the batch strategies. On the next start, the persisted queue is loaded before the first
+round, so that the peers keep their backoff instead of being re-dialed all at once, and the batch
+that got interrupted is finished before asking the selector for a new one.
+*/
+
+// --- Pruning strategy ---
+
+// SaveDialQueue persists the backoff state of all the peers of the queue
+func (c *PruningStrategy) SaveDialQueue() error {
+	return c.DBClient.ReplaceDialQueue(c.PeerQueue.States())
+}
+
+// ResumeDialQueue loads the dial queue persisted by the previous run, the peers keep their backoff
+// state once they get loaded from the DB in the first round
+func (c *PruningStrategy) ResumeDialQueue() (int, error) {
+	states, _, err := c.DBClient.LoadDialQueue()
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to resume the dial queue")
+	}
+	c.PeerQueue.Restore(states)
+	return len(states), nil
+}
+
+// States returns the backoff state of each of the peers of the queue
+func (c *PeerQueue) States() []models.DialQueueState {
+	c.RLock()
+	defer c.RUnlock()
+	states := make([]models.DialQueueState, 0, len(c.peerList))
+	for idx, pPeer := range c.peerList {
+		states = append(states, pPeer.dialQueueState(idx, c.peerPtr))
+	}
+	return states
+}
+
+// Restore keeps the given states, to be applied to their peers when they are added to the queue
+func (c *PeerQueue) Restore(states []models.DialQueueState) {
+	c.Lock()
+	defer c.Unlock()
+	c.resumed = make(map[peer.ID]models.DialQueueState, len(states))
+	for _, state := range states {
+		c.resumed[state.PeerID] = state
+	}
+}
+
+// popResumed returns the persisted state of the peer (if any), only once
+func (c *PeerQueue) popResumed(id peer.ID) (models.DialQueueState, bool) {
+	c.Lock()
+	defer c.Unlock()
+	state, ok := c.resumed[id]
+	if ok {
+		delete(c.resumed, id)
+	}
+	return state, ok
+}
+
+// dropResumed forgets the persisted states that weren't applied (peers deprecated in the meantime)
+func (c *PeerQueue) dropResumed() {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.resumed) > 0 {
+		log.Debugf("%d peers of the resumed dial queue are no longer dialable", len(c.resumed))
+	}
+	c.resumed = nil
+}
+
+func (c *PrunedPeer) dialQueueState(position, pointer int) models.DialQueueState {
+	state := models.DialQueueState{
+		PeerID:       c.iD,
+		Position:     position,
+		Pending:      position >= pointer,
+		LastError:    c.connError,
+		Delay:        string(c.delayObj.dtype),
+		DelayDegree:  c.delayObj.delayDegree,
+		Failures:     c.failures,
+		LastPositive: c.baseDeprecationTimestamp,
+	}
+	// new peers haven't been dialed yet
+	if c.delayObj.dtype != Minus1Delay {
+		state.LastAttempt = c.baseConnectionTimestamp
+	}
+	return state
+}
+
+// restore sets the backoff state persisted by the previous run
+func (c *PrunedPeer) restore(state models.DialQueueState) {
+	c.connError = state.LastError
+	c.delayObj = DelayObject{
+		delayDegree: state.DelayDegree,
+		dtype:       Delay(state.Delay),
+	}
+	c.failures = state.Failures
+	c.baseDeprecationTimestamp = state.LastPositive
+	if !state.LastAttempt.IsZero() {
+		c.baseConnectionTimestamp = state.LastAttempt
+	}
+}
+
+// --- Batch strategy ---
+
+// SaveDialQueue persists the peers of the current batch that weren't dialed yet, and the last dial
+// of the rest of the peers dialed by the strategy
+func (c *BatchStrategy) SaveDialQueue() error {
+	c.m.RLock()
+	states := make([]models.DialQueueState, 0, len(c.lastDials)+len(c.batch))
+	pending := make(map[peer.ID]struct{})
+	for idx := c.batchPtr; idx < len(c.batch); idx++ {
+		p := c.batch[idx]
+		pending[p.ID] = struct{}{}
+		states = append(states, models.DialQueueState{
+			PeerID:      p.ID,
+			Position:    idx - c.batchPtr,
+			Pending:     true,
+			LastError:   c.lastErrors[p.ID],
+			LastAttempt: c.lastDials[p.ID],
+		})
+	}
+	for id, lastDial := range c.lastDials {
+		if _, ok := pending[id]; ok {
+			continue
+		}
+		states = append(states, models.DialQueueState{
+			PeerID:      id,
+			Position:    len(states),
+			LastError:   c.lastErrors[id],
+			LastAttempt: lastDial,
+		})
+	}
+	c.m.RUnlock()
+	return c.DBClient.ReplaceDialQueue(states)
+}
+
+// ResumeDialQueue loads the dial queue persisted by the previous run, the pending peers are dialed
+// in the first round, before asking the selector for a new batch
+func (c *BatchStrategy) ResumeDialQueue() (int, error) {
+	states, _, err := c.DBClient.LoadDialQueue()
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to resume the dial queue")
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.resumed = make([]peer.ID, 0)
+	for _, state := range states {
+		if state.Pending {
+			c.resumed = append(c.resumed, state.PeerID)
+		}
+		if !state.LastAttempt.IsZero() {
+			c.lastDials[state.PeerID] = state.LastAttempt
+			c.lastErrors[state.PeerID] = state.LastError
+		}
+	}
+	return len(states), nil
+}
+
+// resumedBatch returns the pending peers of the resumed batch that are still known, in their
+// original order, only once
+func (c *BatchStrategy) resumedBatch(known []*models.RemoteConnectablePeer) []*models.RemoteConnectablePeer {
+	c.m.Lock()
+	resumed := c.resumed
+	c.resumed = nil
+	c.m.Unlock()
+	if len(resumed) == 0 {
+		return nil
+	}
+	knownPeers := make(map[peer.ID]*models.RemoteConnectablePeer, len(known))
+	for _, p := range known {
+		knownPeers[p.ID] = p
+	}
+	batch := make([]*models.RemoteConnectablePeer, 0, len(resumed))
+	for _, id := range resumed {
+		if p, ok := knownPeers[id]; ok {
+			batch = append(batch, p)
+		}
+	}
+	return batch
+}
@@ -0,0 +1,205 @@
+package peering
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/pkg/errors"
+)
+
+var (
+	RedialStrategy = "redial"
+	HolderStrategy = "holder"
+	QuotaStrategy  = "quota"
+
+	// strategies that can be selected for the peering service
+	PeeringStrategies = []string{PruneStrategy, RedialStrategy, HolderStrategy, QuotaStrategy, PriorityStrategy}
+
+	// holder: time before re-dialing a peer that failed, and failures in a row before giving up on it
+	HolderRetryDelay  = 30 * time.Minute
+	HolderMaxFailures = 5
+
+	// quota: peers dialed per round, and dials per peer allowed within the quota window
+	DefaultPeeringQuota  = 100
+	DefaultPeerDialQuota = 3
+	QuotaWindow          = 1 * time.Hour
+)
+
+// RedialSelector aggressively re-dials all the known peers in every round, without any backoff,
+// starting by the ones that were dialed the longest ago
+type RedialSelector struct {
+	m           sync.Mutex
+	lastAttempt map[peer.ID]time.Time
+}
+
+func NewRedialSelector() *RedialSelector {
+	return &RedialSelector{
+		lastAttempt: make(map[peer.ID]time.Time),
+	}
+}
+
+func (s *RedialSelector) Type() string {
+	return RedialStrategy
+}
+
+func (s *RedialSelector) NextPeerBatch(known []*models.RemoteConnectablePeer) []*models.RemoteConnectablePeer {
+	s.m.Lock()
+	defer s.m.Unlock()
+	batch := append([]*models.RemoteConnectablePeer{}, known...)
+	// the peers never dialed have a zero time, so they go first
+	sort.SliceStable(batch, func(i, j int) bool {
+		return s.lastAttempt[batch[i].ID].Before(s.lastAttempt[batch[j].ID])
+	})
+	return batch
+}
+
+func (s *RedialSelector) ProcessResult(attempt *models.ConnectionAttempt) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.lastAttempt[attempt.RemotePeer] = attempt.Timestamp
+}
+
+// heldPeer is the dial record of a peer for the HolderSelector
+type heldPeer struct {
+	held        bool // we managed to connect it at least once
+	failures    int  // failed dials in a row
+	lastAttempt time.Time
+}
+
+// HolderSelector keeps long-lived connections: it re-dials the peers that we already held as soon
+// as they get disconnected (the connected ones are skipped by the peering service), then tries the
+// new peers, and only retries the failing ones after a delay, giving up after several failures in a row
+type HolderSelector struct {
+	m     sync.Mutex
+	peers map[peer.ID]*heldPeer
+}
+
+func NewHolderSelector() *HolderSelector {
+	return &HolderSelector{
+		peers: make(map[peer.ID]*heldPeer),
+	}
+}
+
+func (s *HolderSelector) Type() string {
+	return HolderStrategy
+}
+
+func (s *HolderSelector) NextPeerBatch(known []*models.RemoteConnectablePeer) []*models.RemoteConnectablePeer {
+	s.m.Lock()
+	defer s.m.Unlock()
+	held := make([]*models.RemoteConnectablePeer, 0)
+	fresh := make([]*models.RemoteConnectablePeer, 0)
+	retried := make([]*models.RemoteConnectablePeer, 0)
+	for _, p := range known {
+		record, ok := s.peers[p.ID]
+		switch {
+		case !ok:
+			fresh = append(fresh, p)
+		case record.held && record.failures == 0:
+			held = append(held, p)
+		case record.failures >= HolderMaxFailures:
+			continue
+		case time.Since(record.lastAttempt) >= HolderRetryDelay:
+			retried = append(retried, p)
+		}
+	}
+	batch := append(held, fresh...)
+	return append(batch, retried...)
+}
+
+func (s *HolderSelector) ProcessResult(attempt *models.ConnectionAttempt) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	record, ok := s.peers[attempt.RemotePeer]
+	if !ok {
+		record = &heldPeer{}
+		s.peers[attempt.RemotePeer] = record
+	}
+	record.lastAttempt = attempt.Timestamp
+	if attempt.Status == models.PossitiveAttempt {
+		record.held = true
+		record.failures = 0
+		return
+	}
+	record.failures++
+}
+
+// QuotaSelector dials in each round a uniformly random sample of the known peers, with a quota of
+// dials per peer within a time window, so that the dialed peers are a statistically sound subset
+// of the network and no peer is over-represented. The samples are replayable with the same seed
+type QuotaSelector struct {
+	m         sync.Mutex
+	batchSize int
+	peerQuota int
+	rng       *rand.Rand
+	dials     map[peer.ID][]time.Time
+}
+
+func NewQuotaSelector(batchSize, peerQuota int, seed int64) (*QuotaSelector, error) {
+	if batchSize <= 0 {
+		return nil, errors.Errorf("invalid peering quota %d", batchSize)
+	}
+	if peerQuota <= 0 {
+		return nil, errors.Errorf("invalid dial quota per peer %d", peerQuota)
+	}
+	return &QuotaSelector{
+		batchSize: batchSize,
+		peerQuota: peerQuota,
+		rng:       rand.New(rand.NewSource(seed)),
+		dials:     make(map[peer.ID][]time.Time),
+	}, nil
+}
+
+func (s *QuotaSelector) Type() string {
+	return QuotaStrategy
+}
+
+func (s *QuotaSelector) NextPeerBatch(known []*models.RemoteConnectablePeer) []*models.RemoteConnectablePeer {
+	s.m.Lock()
+	defer s.m.Unlock()
+	// only the peers that didn't exhaust their quota within the window can be sampled
+	candidates := make([]*models.RemoteConnectablePeer, 0, len(known))
+	for _, p := range known {
+		if len(s.recentDials(p.ID)) < s.peerQuota {
+			candidates = append(candidates, p)
+		}
+	}
+	// sorted before shuffling, so the same seed gives the same samples
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ID < candidates[j].ID
+	})
+	s.rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if len(candidates) > s.batchSize {
+		candidates = candidates[:s.batchSize]
+	}
+	return candidates
+}
+
+func (s *QuotaSelector) ProcessResult(attempt *models.ConnectionAttempt) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.dials[attempt.RemotePeer] = append(s.recentDials(attempt.RemotePeer), attempt.Timestamp)
+}
+
+// recentDials returns the dials of the peer within the quota window, dropping the older ones (needs the lock)
+func (s *QuotaSelector) recentDials(id peer.ID) []time.Time {
+	dials := s.dials[id]
+	recent := dials[:0]
+	for _, t := range dials {
+		if time.Since(t) < QuotaWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(s.dials, id)
+		return nil
+	}
+	s.dials[id] = recent
+	return recent
+}
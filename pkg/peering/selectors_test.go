@@ -0,0 +1,94 @@
+package peering
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func testAttempt(id peer.ID, status models.AttemptStatus, t time.Time) *models.ConnectionAttempt {
+	attempt := models.NewConnAttempt(id, status, "", false, false)
+	attempt.Timestamp = t
+	return attempt
+}
+
+func Test_RedialSelector(t *testing.T) {
+	selector := NewRedialSelector()
+	now := time.Now()
+	selector.ProcessResult(testAttempt("a", models.PossitiveAttempt, now))
+	selector.ProcessResult(testAttempt("b", models.NegativeAttempt, now.Add(-time.Minute)))
+
+	// the never dialed first, then the ones dialed the longest ago
+	batch := selector.NextPeerBatch(testConnectablePeers("a", "b", "c"))
+	require.Equal(t, []peer.ID{"c", "b", "a"}, batchIDs(batch))
+}
+
+func Test_HolderSelector(t *testing.T) {
+	selector := NewHolderSelector()
+	now := time.Now()
+	selector.ProcessResult(testAttempt("held", models.PossitiveAttempt, now))
+	selector.ProcessResult(testAttempt("recent-fail", models.NegativeAttempt, now))
+	selector.ProcessResult(testAttempt("old-fail", models.NegativeAttempt, now.Add(-HolderRetryDelay)))
+	for i := 0; i < HolderMaxFailures; i++ {
+		selector.ProcessResult(testAttempt("dead", models.NegativeAttempt, now.Add(-2*HolderRetryDelay)))
+	}
+
+	// held peers first, then the new ones, then the failing ones after the retry delay
+	batch := selector.NextPeerBatch(testConnectablePeers("old-fail", "dead", "new", "recent-fail", "held"))
+	require.Equal(t, []peer.ID{"held", "new", "old-fail"}, batchIDs(batch))
+}
+
+func Test_QuotaSelector(t *testing.T) {
+	_, err := NewQuotaSelector(0, 1, 42)
+	require.Error(t, err)
+	_, err = NewQuotaSelector(2, 0, 42)
+	require.Error(t, err)
+
+	known := testConnectablePeers("a", "b", "c", "d", "e")
+	selector, err := NewQuotaSelector(2, 1, 42)
+	require.NoError(t, err)
+	batch := selector.NextPeerBatch(known)
+	require.Len(t, batch, 2)
+
+	// replayable with the same seed, whatever the order of the known peers
+	replay, err := NewQuotaSelector(2, 1, 42)
+	require.NoError(t, err)
+	reversed := testConnectablePeers("e", "d", "c", "b", "a")
+	require.Equal(t, batchIDs(batch), batchIDs(replay.NextPeerBatch(reversed)))
+
+	// the peers that exhausted their quota within the window aren't sampled again
+	for _, p := range batch {
+		selector.ProcessResult(testAttempt(p.ID, models.PossitiveAttempt, time.Now()))
+	}
+	selector.ProcessResult(testAttempt("c", models.NegativeAttempt, time.Now().Add(-QuotaWindow)))
+	sampled := make(map[peer.ID]struct{})
+	for i := 0; i < 10; i++ {
+		for _, p := range selector.NextPeerBatch(known) {
+			sampled[p.ID] = struct{}{}
+		}
+	}
+	for _, p := range batch {
+		require.NotContains(t, sampled, p.ID)
+	}
+	require.Len(t, sampled, 3)
+}
+
+func Test_CheckPeeringStrategy(t *testing.T) {
+	for _, strategy := range PeeringStrategies {
+		require.NoError(t, CheckPeeringStrategy(strategy), strategy)
+	}
+	require.Error(t, CheckPeeringStrategy("random"))
+	require.Error(t, CheckPeeringStrategy(""))
+
+	// the unknown strategies and the invalid quotas are refused before touching the database
+	_, err := NewPeeringStrategy(context.Background(), "random", utils.EthereumNetwork, nil, 42, 10, DefaultDeprecationPolicy(), DialScoreWeights{})
+	require.Error(t, err)
+	_, err = NewPeeringStrategy(context.Background(), QuotaStrategy, utils.EthereumNetwork, nil, 42, 0, DefaultDeprecationPolicy(), DialScoreWeights{})
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package peering
+
+/**
+This file implements the keeper of the static peers
+Static peers are trusted nodes (i.e. our own beacon nodes) that the host will always keep connected.
+*/
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/storage"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var (
+	StaticPeersCheckInterval = 30 * time.Second
+)
+
+// StaticPeersKeeper makes sure that the host is always connected to the configured static peers
+type StaticPeersKeeper struct {
+	ctx context.Context
+
+	host  *hosts.BasicLibp2pHost
+	db    storage.Client
+	peers []peer.AddrInfo
+}
+
+// ParseStaticPeers composes the AddrInfo of the given static peers' multiaddresses (including their /p2p/ peer ID)
+func ParseStaticPeers(staticPeers []string) ([]peer.AddrInfo, error) {
+	maddrs := make([]ma.Multiaddr, 0, len(staticPeers))
+	for _, staticPeer := range staticPeers {
+		maddr, err := utils.UnmarshalMaddr(staticPeer)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse static peer "+staticPeer)
+		}
+		maddrs = append(maddrs, maddr)
+	}
+	// aggregate the multiaddrs of the same peer
+	addrInfos, err := peer.AddrInfosFromP2pAddrs(maddrs...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to compose AddrInfo from static peers")
+	}
+	return addrInfos, nil
+}
+
+func NewStaticPeersKeeper(
+	ctx context.Context,
+	h *hosts.BasicLibp2pHost,
+	dbClient storage.Client,
+	staticPeers []peer.AddrInfo) (*StaticPeersKeeper, error) {
+
+	// register the static peers in the DB, so that they can be excluded from the statistics
+	err := dbClient.SetStaticPeers(staticPeers)
+	if err != nil {
+		return nil, err
+	}
+	// make sure that we never forget their addresses
+	for _, p := range staticPeers {
+		h.Host().Peerstore().AddAddrs(p.ID, p.Addrs, peerstore.PermanentAddrTTL)
+	}
+	return &StaticPeersKeeper{
+		ctx:   ctx,
+		host:  h,
+		db:    dbClient,
+		peers: staticPeers,
+	}, nil
+}
+
+// IsStatic returns whether the given peer is one of the static peers
+func (k *StaticPeersKeeper) IsStatic(peerID peer.ID) bool {
+	for _, p := range k.peers {
+		if p.ID == peerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Peers returns the list of static peers
+func (k *StaticPeersKeeper) Peers() []peer.AddrInfo {
+	return k.peers
+}
+
+// Run launches the routine that (re)connects the static peers whenever they are not connected
+func (k *StaticPeersKeeper) Run() {
+	if len(k.peers) == 0 {
+		return
+	}
+	log.Infof("keeping connected %d static peers", len(k.peers))
+	go func() {
+		ticker := time.NewTicker(StaticPeersCheckInterval)
+		defer ticker.Stop()
+		for {
+			k.connectStaticPeers()
+			select {
+			case <-ticker.C:
+			case <-k.ctx.Done():
+				log.Debug("closing static peers keeper")
+				return
+			}
+		}
+	}()
+}
+
+func (k *StaticPeersKeeper) connectStaticPeers() {
+	h := k.host.Host()
+	for _, p := range k.peers {
+		if h.Network().Connectedness(p.ID) == network.Connected {
+			continue
+		}
+		logEntry := log.WithField("static-peer", p.ID.String())
+		timeoutCtx, cancel := context.WithTimeout(k.ctx, ConnectionRefuseTimeout)
+		err := h.Connect(timeoutCtx, p)
+		cancel()
+		if err != nil {
+			logEntry.WithError(err).Warn("unable to connect static peer")
+			continue
+		}
+		logEntry.Debug("static peer connected")
+		err = k.db.RecordStaticPeerConnection(p.ID, time.Now())
+		if err != nil {
+			logEntry.WithError(err).Warn("unable to record static peer connection")
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package peering
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// DialQueuePath is the path of the http endpoint that exposes the dial queue
+	DialQueuePath = "/peering/dial-queue"
+	// max number of peers returned by default when listing the dial queue
+	DefaultDialQueueLimit = 1000
+)
+
+// StatusHandler returns the http handler that exposes the dial queue of the peering strategy:
+//   - GET /peering/dial-queue?limit=N lists the first N peers of the queue (limit=0 for all of them)
+//   - GET /peering/dial-queue/<peer-id> returns the backoff timer and deprecation state of a peer
+func (c *PeeringService) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		peerStr := strings.Trim(strings.TrimPrefix(r.URL.Path, DialQueuePath), "/")
+		if peerStr == "" {
+			limit := DefaultDialQueueLimit
+			if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+				l, err := strconv.Atoi(limitStr)
+				if err != nil {
+					http.Error(w, "invalid limit "+limitStr, http.StatusBadRequest)
+					return
+				}
+				limit = l
+			}
+			writeJSON(w, c.strategy.DialQueue(limit))
+			return
+		}
+		peerID, err := peer.Decode(peerStr)
+		if err != nil {
+			http.Error(w, "invalid peer id "+peerStr, http.StatusBadRequest)
+			return
+		}
+		entry, ok := c.strategy.InspectPeer(peerID)
+		if !ok {
+			http.Error(w, "peer not in the dial queue (deprecated or not discovered yet)", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, entry)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.WithError(err).Warn("unable to write status response")
+	}
+}
@@ -1,8 +1,16 @@
 package peering
 
 import (
+	"context"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+
 	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/db/storage"
 	"github.com/migalabs/armiarma/pkg/hosts"
+	"github.com/migalabs/armiarma/pkg/utils"
 )
 
 // Strategy is the common interface the any desired Peering Strategy should follow
@@ -23,4 +31,67 @@ type PeeringStrategy interface {
 	GetTotalConnErrorDistribution() map[string]int64
 	GetErrorAttemptDistribution() map[string]int64
 	GetConnErrorDistribution() map[string]int64
+	// Dial queue inspection
+	DialQueue(limit int) DialQueueStatus
+	InspectPeer(peer.ID) (DialQueueEntry, bool)
+	// Finished rounds (iterations over the peers to dial)
+	Rounds() <-chan RoundStats
+	// Persistence of the dial queue, to resume it after a restart
+	SaveDialQueue() error
+	ResumeDialQueue() (int, error)
+}
+
+// PeerSelector is the pluggable part of a peering strategy: it picks the peers dialed in each
+// round and learns from the result of each dial. The selectors are run by the BatchStrategy,
+// which takes care of the communication with the peering service and of the persistence
+type PeerSelector interface {
+	Type() string
+	// NextPeerBatch returns the peers to dial in the next round among the known (non-deprecated)
+	// ones, an empty batch means that none of them is ready to be dialed yet
+	NextPeerBatch(known []*models.RemoteConnectablePeer) []*models.RemoteConnectablePeer
+	// ProcessResult receives the outcome of each of the dials
+	ProcessResult(attempt *models.ConnectionAttempt)
+}
+
+// NewPeeringStrategy returns the peering strategy of the given type, the seed and the quota
+// are only used by the sampling strategy, the deprecation policy by the pruning one, and the
+// dial score weights by the priority one
+func NewPeeringStrategy(
+	ctx context.Context,
+	strategyType string,
+	network utils.NetworkType,
+	dbClient storage.Client,
+	seed int64,
+	quota int,
+	policy DeprecationPolicy,
+	weights DialScoreWeights) (PeeringStrategy, error) {
+
+	switch strategyType {
+	case PruneStrategy:
+		return NewPruningStrategy(ctx, network, dbClient, policy)
+	case RedialStrategy:
+		return NewBatchStrategy(ctx, network, dbClient, NewRedialSelector())
+	case HolderStrategy:
+		return NewBatchStrategy(ctx, network, dbClient, NewHolderSelector())
+	case QuotaStrategy:
+		selector, err := NewQuotaSelector(quota, DefaultPeerDialQuota, seed)
+		if err != nil {
+			return nil, err
+		}
+		return NewBatchStrategy(ctx, network, dbClient, selector)
+	case PriorityStrategy:
+		return NewBatchStrategy(ctx, network, dbClient, NewPrioritySelector(dbClient, weights))
+	default:
+		return nil, CheckPeeringStrategy(strategyType)
+	}
+}
+
+// CheckPeeringStrategy returns an error if the given type of strategy is unknown
+func CheckPeeringStrategy(strategyType string) error {
+	for _, s := range PeeringStrategies {
+		if s == strategyType {
+			return nil
+		}
+	}
+	return errors.Errorf("unknown peering strategy %s (%s)", strategyType, strings.Join(PeeringStrategies, ", "))
 }
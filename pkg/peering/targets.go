@@ -0,0 +1,167 @@
+package peering
+
+/**
+This file implements the target-list crawl mode, where the crawler only dials a given set of peers
+(i.e. for the longitudinal monitoring of specific nodes) instead of the discovered ones
+*/
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var TargetStrategy = "target"
+
+// LoadTargetsFile reads the target peers of the given file, one per line ('#' starts a comment)
+func LoadTargetsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open targets file")
+	}
+	defer f.Close()
+
+	entries := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	// the ENRs can be long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read targets file")
+	}
+	return entries, nil
+}
+
+// ParseTargets composes the AddrInfo of the given target peers, which can be ENRs ("enr:..."),
+// multiaddrs with the /p2p/ peer ID, or bare peer IDs (dialed with the addresses of the DB).
+// The addresses of the entries of the same peer are aggregated
+func ParseTargets(entries []string) ([]peer.AddrInfo, error) {
+	targets := make([]peer.AddrInfo, 0, len(entries))
+	index := make(map[peer.ID]int, len(entries))
+	for _, entry := range entries {
+		target, err := parseTarget(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse target peer "+entry)
+		}
+		if idx, ok := index[target.ID]; ok {
+			targets[idx].Addrs = append(targets[idx].Addrs, target.Addrs...)
+			continue
+		}
+		index[target.ID] = len(targets)
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func parseTarget(entry string) (peer.AddrInfo, error) {
+	switch {
+	case strings.HasPrefix(entry, "enr:"):
+		enr, err := eth.ParseEnrString(entry)
+		if err != nil {
+			return peer.AddrInfo{}, err
+		}
+		peerID, err := enr.GetPeerID()
+		if err != nil {
+			return peer.AddrInfo{}, err
+		}
+		target := peer.AddrInfo{ID: peerID}
+		// ENRs without a tcp address still identify the target, whose addresses come from the DB
+		maddr, err := enr.TCPMultiaddr()
+		switch {
+		case errors.Is(err, eth.ErrNoTCPAddr):
+		case err != nil:
+			return peer.AddrInfo{}, err
+		default:
+			target.Addrs = append(target.Addrs, maddr)
+		}
+		return target, nil
+
+	case strings.HasPrefix(entry, "/"):
+		maddr, err := utils.UnmarshalMaddr(entry)
+		if err != nil {
+			return peer.AddrInfo{}, err
+		}
+		target, err := peer.AddrInfoFromP2pAddr(maddr)
+		if err != nil {
+			return peer.AddrInfo{}, err
+		}
+		return *target, nil
+
+	default:
+		peerID, err := peer.Decode(entry)
+		if err != nil {
+			return peer.AddrInfo{}, err
+		}
+		return peer.AddrInfo{ID: peerID}, nil
+	}
+}
+
+// TargetSelector dials in every round all the target peers, and only them, with their given
+// addresses plus the ones that the DB knows of them
+type TargetSelector struct {
+	network utils.NetworkType
+	targets []peer.AddrInfo
+
+	m      sync.Mutex
+	missed map[peer.ID]struct{}
+}
+
+func NewTargetSelector(network utils.NetworkType, targets []peer.AddrInfo) (*TargetSelector, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no target peers to dial")
+	}
+	return &TargetSelector{
+		network: network,
+		targets: targets,
+		missed:  make(map[peer.ID]struct{}),
+	}, nil
+}
+
+func (s *TargetSelector) Type() string {
+	return TargetStrategy
+}
+
+func (s *TargetSelector) NextPeerBatch(known []*models.RemoteConnectablePeer) []*models.RemoteConnectablePeer {
+	s.m.Lock()
+	defer s.m.Unlock()
+	knownAddrs := make(map[peer.ID][]ma.Multiaddr, len(known))
+	for _, p := range known {
+		knownAddrs[p.ID] = p.Addrs
+	}
+	batch := make([]*models.RemoteConnectablePeer, 0, len(s.targets))
+	for _, target := range s.targets {
+		addrs := append(append([]ma.Multiaddr{}, target.Addrs...), knownAddrs[target.ID]...)
+		if len(addrs) == 0 {
+			// only warn once per peer, it is retried every round in case the DB learns its addresses
+			if _, ok := s.missed[target.ID]; !ok {
+				log.Warnf("no addresses to dial target peer %s", target.ID.String())
+				s.missed[target.ID] = struct{}{}
+			}
+			continue
+		}
+		delete(s.missed, target.ID)
+		batch = append(batch, models.NewRemoteConnectablePeer(target.ID, addrs, s.network))
+	}
+	return batch
+}
+
+func (s *TargetSelector) ProcessResult(attempt *models.ConnectionAttempt) {}
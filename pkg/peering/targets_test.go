@@ -0,0 +1,95 @@
+package peering
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+func testTargetENR(t *testing.T, entries ...enr.Entry) (string, peer.ID) {
+	key, err := utils.GenerateECDSAPrivKey()
+	require.NoError(t, err)
+	node, err := eth.ComposeEnr(key, 1, entries...)
+	require.NoError(t, err)
+	privKey, err := utils.AdaptSecp256k1FromECDSA(key)
+	require.NoError(t, err)
+	peerID, err := peer.IDFromPrivateKey(privKey)
+	require.NoError(t, err)
+	return node.String(), peerID
+}
+
+func Test_ParseTarget(t *testing.T) {
+	// ipv4 ENR
+	rawEnr, peerID := testTargetENR(t, enr.IPv4(net.ParseIP("1.2.3.4")), enr.TCP(9000), enr.UDP(9000))
+	target, err := parseTarget(rawEnr)
+	require.NoError(t, err)
+	require.Equal(t, peerID, target.ID)
+	require.Len(t, target.Addrs, 1)
+	require.Equal(t, "/ip4/1.2.3.4/tcp/9000", target.Addrs[0].String())
+
+	// ipv6-only ENR
+	rawEnr, peerID = testTargetENR(t, enr.IPv6(net.ParseIP("2001:db8::1")), enr.TCP(9000), enr.UDP(9000))
+	target, err = parseTarget(rawEnr)
+	require.NoError(t, err)
+	require.Equal(t, peerID, target.ID)
+	require.Len(t, target.Addrs, 1)
+	require.Equal(t, "/ip6/2001:db8::1/tcp/9000", target.Addrs[0].String())
+
+	// ENRs without tcp address still identify the target
+	rawEnr, peerID = testTargetENR(t, enr.IPv4(net.ParseIP("1.2.3.4")), enr.UDP(9000))
+	target, err = parseTarget(rawEnr)
+	require.NoError(t, err)
+	require.Equal(t, peerID, target.ID)
+	require.Empty(t, target.Addrs)
+
+	_, err = parseTarget("enr:-invalid")
+	require.Error(t, err)
+
+	// multiaddrs need the peer ID
+	target, err = parseTarget(fmt.Sprintf("/ip6/2001:db8::1/tcp/9000/p2p/%s", peerID))
+	require.NoError(t, err)
+	require.Equal(t, peerID, target.ID)
+	require.Equal(t, "/ip6/2001:db8::1/tcp/9000", target.Addrs[0].String())
+	_, err = parseTarget("/ip4/1.2.3.4/tcp/9000")
+	require.Error(t, err)
+
+	// bare peer IDs are dialed with the addresses of the DB
+	target, err = parseTarget(peerID.String())
+	require.NoError(t, err)
+	require.Equal(t, peerID, target.ID)
+	require.Empty(t, target.Addrs)
+	_, err = parseTarget("not-a-peer")
+	require.Error(t, err)
+}
+
+func Test_LoadAndParseTargets(t *testing.T) {
+	rawEnr, peerID := testTargetENR(t, enr.IPv4(net.ParseIP("1.2.3.4")), enr.TCP(9000), enr.UDP(9000))
+	content := fmt.Sprintf("# monitored peers\n%s\n\n/ip4/5.6.7.8/tcp/9000/p2p/%s # same peer\n", rawEnr, peerID)
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	entries, err := LoadTargetsFile(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	// the addresses of the same peer are aggregated
+	targets, err := ParseTargets(entries)
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	require.Equal(t, peerID, targets[0].ID)
+	require.Len(t, targets[0].Addrs, 2)
+
+	_, err = ParseTargets([]string{"not-a-peer"})
+	require.Error(t, err)
+	_, err = LoadTargetsFile(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package probe
+
+/**
+This package implements the probe of a single peer, which goes through the same steps that the
+crawlers do with every peer (dial, identify, and the req/resps of its network) plus an optional
+gossipsub subscription, and reports what the peer answered to each of them. It is meant to debug
+why a specific node isn't recorded correctly, without running a crawler and querying its DB.
+
+*/
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	"github.com/migalabs/armiarma/pkg/hosts"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/networks/ipfs"
+	"github.com/migalabs/armiarma/pkg/networks/waku"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/apis"
+)
+
+var (
+	DefaultUserAgent = "Armiarma Probe"
+	DefaultTimeout   = 30 * time.Second
+)
+
+// Config of the probe
+type Config struct {
+	Network utils.NetworkType
+	// fork digest of the Ethereum CL network (eth.DefaultForkDigest if empty)
+	ForkDigest string
+	UserAgent  string
+	// max time to connect to the peer and to get it identified
+	Timeout time.Duration
+	// time that we stay subscribed to the gossipsub topics with the peer (0 disables the gossip)
+	GossipDuration time.Duration
+	// topics to subscribe, by default the beacon_block topic of the fork digest in Ethereum CL
+	GossipTopics []string
+}
+
+func DefaultConfig(network utils.NetworkType) Config {
+	return Config{
+		Network:   network,
+		UserAgent: DefaultUserAgent,
+		Timeout:   DefaultTimeout,
+	}
+}
+
+// Probe dials the target, waits until the host identifies it (running the req/resps of the network),
+// and, if enabled, stays subscribed to the gossipsub topics with it, reporting the outcome of each step
+func Probe(ctx context.Context, target *Target, conf Config) (*Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultTimeout
+	}
+	if conf.UserAgent == "" {
+		conf.UserAgent = DefaultUserAgent
+	}
+	isEth := conf.Network == utils.EthereumNetwork
+	if isEth && conf.ForkDigest == "" {
+		conf.ForkDigest = eth.DefaultForkDigest
+	}
+
+	report := NewReport(target, conf)
+
+	// a fresh identity on every probe, so that the peer doesn't know us from previous connections
+	gethPrivKey, err := utils.GenerateECDSAPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	privKey, err := utils.AdaptSecp256k1FromECDSA(gethPrivKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var netNode hosts.P2pNetwork
+	var ethNode *eth.LocalEthereumNode
+	var ipfsNode *ipfs.LocalIpfsNode
+	switch conf.Network {
+	case utils.EthereumNetwork:
+		ethNode = eth.NewLocalEthereumNode(
+			ctx,
+			gethPrivKey,
+			eth.ComposeQuickBeaconStatus(conf.ForkDigest),
+			eth.ComposeQuickBeaconMetaData(),
+			conf.ForkDigest,
+		)
+		// the probed peer checks our status, not the eth2 entry of our ENR
+		if version, ok := eth.ForkVersionOfDigest(conf.ForkDigest); ok {
+			if err := ethNode.SetForkDigest(conf.ForkDigest, version); err != nil {
+				return nil, err
+			}
+		}
+		netNode = ethNode
+	default:
+		ipfsNode, err = ipfs.NewLocalIpfsNode(conf.Network)
+		if err != nil {
+			return nil, err
+		}
+		netNode = ipfsNode
+	}
+
+	// the IPs are never located (nor persisted), the probe only reports the peer
+	ipLocator := apis.NewIpLocator(ctx, noIpDB{})
+
+	h, err := hosts.NewHost(
+		ctx,
+		netNode,
+		ipLocator,
+		hosts.WithIdentity(privKey),
+		hosts.WithUserAgent(conf.UserAgent),
+		hosts.WithNATPortMap(false),
+		hosts.WithPeerRecordCollection(true),
+		hosts.WithNegotiationTiming(true),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the libp2p host of the probe")
+	}
+	defer h.Host().Close()
+	if err := h.Start(); err != nil {
+		return nil, errors.Wrap(err, "unable to start the libp2p host of the probe")
+	}
+	report.LocalPeerID = h.Host().ID().String()
+
+	// answer the req/resps of the peer, as it might disconnect us otherwise
+	var goodbyeM sync.Mutex
+	var goodbyeReason *uint64
+	if ethNode != nil {
+		ethNode.ServeBeaconPing(h.Host())
+		ethNode.ServeBeaconStatus(h.Host())
+		ethNode.ServeBeaconMetadata(h.Host())
+		ethNode.ServeBeaconGoodbye(h.Host())
+		ethNode.OnGoodbye(func(p peer.ID, reason uint64) {
+			if p != target.AddrInfo.ID {
+				return
+			}
+			goodbyeM.Lock()
+			goodbyeReason = &reason
+			goodbyeM.Unlock()
+		})
+	}
+	if ipfsNode != nil && ipfsNode.Hello() != nil {
+		ipfsNode.Hello().Serve(h.Host())
+	}
+
+	// subscribe the topics before connecting, so that our subscriptions are sent with the first RPC
+	var gs *gossipsub.GossipSub
+	if conf.GossipDuration > 0 {
+		topics := conf.GossipTopics
+		if len(topics) == 0 && isEth {
+			topics = []string{eth.ComposeTopic(conf.ForkDigest, eth.BeaconBlockTopicBase)}
+		}
+		if len(topics) == 0 {
+			log.Warnf("no gossipsub topics given for %s, skipping the gossip", conf.Network)
+		} else {
+			gsOpts := make([]gossipsub.GossipSubOption, 0)
+			if msgIDFn := msgIDFunction(conf.Network); msgIDFn != nil {
+				gsOpts = append(gsOpts, gossipsub.WithMsgIDFunction(msgIDFn))
+			}
+			gs, err = gossipsub.NewGossipSub(ctx, h.Host(), nil, gsOpts...)
+			if err != nil {
+				return nil, err
+			}
+			for _, topic := range topics {
+				gs.JoinAndSubscribe(topic, gossipsub.CountMessageHandler, false)
+			}
+			report.Gossip = &GossipReport{
+				Topics: topics,
+			}
+		}
+	}
+
+	// dial the peer
+	log.WithFields(log.Fields{
+		"peer":  target.AddrInfo.ID.String(),
+		"addrs": target.AddrInfo.Addrs,
+	}).Info("dialing the peer")
+	dialCtx, dialCancel := context.WithTimeout(ctx, conf.Timeout)
+	start := time.Now()
+	err = h.Connect(dialCtx, target.AddrInfo)
+	dialCancel()
+	report.Dial = newStep(start, err)
+	if err != nil {
+		report.Dial.ErrorClass = hosts.ClassifyDialError(dialCtx, err).Code
+		return report, nil
+	}
+
+	// wait until the host is done with the identification and the req/resps
+	start = time.Now()
+	identCtx, identCancel := context.WithTimeout(ctx, conf.Timeout)
+	defer identCancel()
+	hInfo, err := waitIdentification(identCtx, h, target.AddrInfo.ID)
+	report.Identification = newStep(start, err)
+	if hInfo != nil {
+		report.addHostInfo(hInfo)
+	}
+
+	// stay subscribed with the peer
+	if gs != nil {
+		log.Infof("waiting %s for the gossip of the peer", conf.GossipDuration)
+		select {
+		case <-time.After(conf.GossipDuration):
+		case <-ctx.Done():
+		}
+		report.Gossip.addPeerStats(gs, target.AddrInfo.ID, conf.GossipDuration)
+	}
+
+	report.ConnectedAtEnd = h.IsConnected(target.AddrInfo.ID)
+	goodbyeM.Lock()
+	report.GoodbyeReason = goodbyeReason
+	goodbyeM.Unlock()
+	return report, nil
+}
+
+// waitIdentification returns the HostInfo of the identification event of the peer
+func waitIdentification(ctx context.Context, h *hosts.BasicLibp2pHost, peerID peer.ID) (*models.HostInfo, error) {
+	for {
+		select {
+		case identEvent := <-h.IdentEventNotChannel():
+			if identEvent.HostInfo == nil || identEvent.HostInfo.ID != peerID {
+				continue
+			}
+			if !identEvent.HostInfo.IsHostIdentified() {
+				return identEvent.HostInfo, errors.New("unable to identify peer")
+			}
+			return identEvent.HostInfo, nil
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "no identification event for the peer")
+		}
+	}
+}
+
+func msgIDFunction(network utils.NetworkType) pubsub.MsgIdFunction {
+	switch network {
+	case utils.EthereumNetwork:
+		return eth.MsgIDFunction
+	case utils.WakuNetwork:
+		return waku.MsgIDFunction
+	default:
+		return nil
+	}
+}
+
+// noIpDB is the DB of the IpLocator of the probe, which reports every IP as already located
+type noIpDB struct{}
+
+func (noIpDB) PersistToDB(interface{}) {}
+
+func (noIpDB) ReadIpInfo(string) (models.IpInfo, error) {
+	return models.IpInfo{}, nil
+}
+
+func (noIpDB) CheckIpRecords(string) (bool, bool, error) {
+	return true, false, nil
+}
+
+func (noIpDB) GetExpiredIpInfo(int) ([]string, error) {
+	return make([]string, 0), nil
+}
@@ -0,0 +1,123 @@
+package probe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+var testForkDigest = "0xbba4da96"
+
+func Test_ParseTarget(t *testing.T) {
+	key, err := utils.GenerateECDSAPrivKey()
+	require.NoError(t, err)
+	// fork digest + next fork version + next fork epoch
+	eth2 := eth.NewEth2DataEntry("bba4da96" + "00000000" + "ffffffffffffffff")
+
+	target, err := ParseTarget(signedNode(t, key, enr.IPv4(net.ParseIP("10.0.0.1")), enr.TCP(9000), enr.UDP(9000), eth2).String())
+	require.NoError(t, err)
+	privKey, err := utils.AdaptSecp256k1FromECDSA(key)
+	require.NoError(t, err)
+	peerID, err := peer.IDFromPrivateKey(privKey)
+	require.NoError(t, err)
+	require.Equal(t, peerID, target.AddrInfo.ID)
+	require.Len(t, target.AddrInfo.Addrs, 1)
+	require.Equal(t, "/ip4/10.0.0.1/tcp/9000", target.AddrInfo.Addrs[0].String())
+	require.Equal(t, testForkDigest, target.ForkDigest())
+
+	// the ENRs without tcp port can't be dialed
+	_, err = ParseTarget(signedNode(t, key, enr.IPv4(net.ParseIP("10.0.0.1")), enr.UDP(9000)).String())
+	require.ErrorIs(t, err, eth.ErrNoTCPAddr)
+
+	// the multiaddrs need the peer ID
+	target, err = ParseTarget(fmt.Sprintf("/ip4/10.0.0.1/tcp/9000/p2p/%s", peerID))
+	require.NoError(t, err)
+	require.Equal(t, peerID, target.AddrInfo.ID)
+	require.Empty(t, target.ForkDigest())
+	_, err = ParseTarget("/ip4/10.0.0.1/tcp/9000")
+	require.Error(t, err)
+	_, err = ParseTarget("not-a-peer")
+	require.Error(t, err)
+}
+
+func Test_Probe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// remote beacon node that answers the req/resps and gossips the beacon blocks
+	key, err := utils.GenerateECDSAPrivKey()
+	require.NoError(t, err)
+	privKey, err := utils.AdaptSecp256k1FromECDSA(key)
+	require.NoError(t, err)
+	ethNode := eth.NewLocalEthereumNode(ctx, key, eth.ComposeQuickBeaconStatus(testForkDigest), eth.ComposeQuickBeaconMetaData(), testForkDigest)
+	// a plain libp2p host, as the ones of the crawler don't accept streams until they are done identifying the peer
+	remote, err := libp2p.New(
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		libp2p.Identity(privKey),
+		libp2p.UserAgent("Lighthouse/v4.5.0-441fc16/x86_64-linux"),
+	)
+	require.NoError(t, err)
+	defer remote.Close()
+	ethNode.ServeBeaconPing(remote)
+	ethNode.ServeBeaconStatus(remote)
+	ethNode.ServeBeaconMetadata(remote)
+	topic := eth.ComposeTopic(testForkDigest, eth.BeaconBlockTopicBase)
+	gs, err := gossipsub.NewGossipSub(ctx, remote, nil, gossipsub.WithMsgIDFunction(eth.MsgIDFunction))
+	require.NoError(t, err)
+	gs.JoinAndSubscribe(topic, gossipsub.CountMessageHandler, false)
+
+	target, err := ParseTarget(fmt.Sprintf("%s/p2p/%s", remote.Addrs()[0], remote.ID()))
+	require.NoError(t, err)
+
+	conf := DefaultConfig(utils.EthereumNetwork)
+	conf.ForkDigest = testForkDigest
+	conf.Timeout = 10 * time.Second
+	conf.GossipDuration = 2 * time.Second
+	report, err := Probe(ctx, target, conf)
+	require.NoError(t, err)
+	require.True(t, report.Dial.Success)
+	require.True(t, report.Identification.Success, report.Identification.Error)
+	require.NotNil(t, report.Identify)
+	require.Equal(t, "Lighthouse/v4.5.0-441fc16/x86_64-linux", report.Identify.UserAgent)
+	require.Equal(t, "lighthouse", report.Identify.ClientName)
+	require.Contains(t, report.Attributes, "beacon-status")
+	require.Contains(t, report.Attributes, "beaconmetadata")
+	require.Equal(t, []string{topic}, report.Gossip.PeerTopics)
+	require.True(t, report.ConnectedAtEnd)
+	_, err = json.Marshal(report)
+	require.NoError(t, err)
+
+	// the peers that don't listen are reported with the error of the dial
+	remote.Close()
+	conf.GossipDuration = 0
+	report, err = Probe(ctx, target, conf)
+	require.NoError(t, err)
+	require.False(t, report.Dial.Success)
+	require.NotEmpty(t, report.Dial.ErrorClass)
+	require.False(t, report.Identification.Done)
+}
+
+func signedNode(t *testing.T, key *ecdsa.PrivateKey, entries ...enr.Entry) *enode.Node {
+	var r enr.Record
+	for _, entry := range entries {
+		r.Set(entry)
+	}
+	require.NoError(t, enode.SignV4(&r, key))
+	node, err := enode.New(enode.ValidSchemes, &r)
+	require.NoError(t, err)
+	return node
+}
@@ -0,0 +1,171 @@
+package probe
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/gossipsub"
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/migalabs/armiarma/pkg/utils/clientinfo"
+)
+
+// Report gathers everything that the probe learnt about the peer
+type Report struct {
+	Target      string            `json:"target"`
+	Network     utils.NetworkType `json:"network"`
+	ForkDigest  string            `json:"fork_digest,omitempty"`
+	PeerID      string            `json:"peer_id"`
+	Addrs       []string          `json:"addrs"`
+	Enr         *EnrReport        `json:"enr,omitempty"`
+	LocalPeerID string            `json:"local_peer_id"`
+	Time        time.Time         `json:"time"`
+
+	Dial           Step            `json:"dial"`
+	Identification Step            `json:"identification"`
+	Identify       *IdentifyReport `json:"identify,omitempty"`
+	// attributes of the HostInfo (beacon-status, beaconmetadata, filecoin-hello, signed-peer-record...)
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Gossip     *GossipReport          `json:"gossip,omitempty"`
+
+	// reason of the goodbye that the peer sent us (if any)
+	GoodbyeReason  *uint64 `json:"goodbye_reason,omitempty"`
+	ConnectedAtEnd bool    `json:"connected_at_end"`
+}
+
+// EnrReport summarizes the ENR of the target
+type EnrReport struct {
+	Raw    string                 `json:"raw"`
+	NodeID string                 `json:"node_id"`
+	Seq    uint64                 `json:"seq"`
+	IP     string                 `json:"ip"`
+	TCP    int                    `json:"tcp"`
+	UDP    int                    `json:"udp"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// Step is the outcome of each of the interactions with the peer
+type Step struct {
+	Done       bool   `json:"done"`
+	Success    bool   `json:"success"`
+	Duration   string `json:"duration,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+}
+
+func newStep(start time.Time, err error) Step {
+	step := Step{
+		Done:     true,
+		Success:  err == nil,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	return step
+}
+
+// IdentifyReport is what the peer shared through the libp2p identify
+type IdentifyReport struct {
+	UserAgent       string   `json:"user_agent"`
+	ClientName      string   `json:"client_name"`
+	ClientVersion   string   `json:"client_version"`
+	ClientOS        string   `json:"client_os"`
+	ClientArch      string   `json:"client_arch"`
+	ProtocolVersion string   `json:"protocol_version"`
+	Protocols       []string `json:"protocols"`
+	Latency         string   `json:"latency"`
+	Security        string   `json:"security"`
+	// addrs of the HostInfo, which are the listen ones of the peer once it got identified
+	Addrs []string `json:"addrs"`
+}
+
+// GossipReport is the gossipsub behaviour of the peer in the topics that we subscribed
+type GossipReport struct {
+	Topics   []string `json:"topics"`
+	Duration string   `json:"duration"`
+	// topics (among ours) in which the peer is subscribed
+	PeerTopics []string                   `json:"peer_topics"`
+	Stats      *gossipsub.PeerGossipStats `json:"stats,omitempty"`
+}
+
+func NewReport(target *Target, conf Config) *Report {
+	report := &Report{
+		Target:     target.Raw,
+		Network:    conf.Network,
+		ForkDigest: conf.ForkDigest,
+		PeerID:     target.AddrInfo.ID.String(),
+		Addrs:      make([]string, 0, len(target.AddrInfo.Addrs)),
+		Time:       time.Now(),
+	}
+	for _, addr := range target.AddrInfo.Addrs {
+		report.Addrs = append(report.Addrs, addr.String())
+	}
+	if target.Enr != nil {
+		report.Enr = newEnrReport(target.Enr)
+	}
+	return report
+}
+
+func newEnrReport(enr *eth.EnrNode) *EnrReport {
+	enrReport := &EnrReport{
+		Raw:    enr.Raw,
+		NodeID: enr.ID.String(),
+		Seq:    enr.Seq,
+		TCP:    enr.TCP,
+		UDP:    enr.UDP,
+		Fields: enr.Fields,
+	}
+	if enr.IP != nil {
+		enrReport.IP = enr.IP.String()
+	}
+	return enrReport
+}
+
+func (r *Report) addHostInfo(hInfo *models.HostInfo) {
+	hInfo.RLock()
+	defer hInfo.RUnlock()
+
+	if hInfo.IsHostIdentified() {
+		info := clientinfo.Parse(hInfo.Network, hInfo.PeerInfo.UserAgent)
+		r.Identify = &IdentifyReport{
+			UserAgent:       hInfo.PeerInfo.UserAgent,
+			ClientName:      string(info.Name),
+			ClientVersion:   info.Version,
+			ClientOS:        string(info.OS),
+			ClientArch:      string(info.Arch),
+			ProtocolVersion: hInfo.PeerInfo.ProtocolVersion,
+			Protocols:       hInfo.PeerInfo.Protocols,
+			Latency:         hInfo.PeerInfo.Latency.String(),
+			Security:        hInfo.PeerInfo.Security,
+			Addrs:           make([]string, 0, len(hInfo.MAddrs)),
+		}
+		for _, addr := range hInfo.MAddrs {
+			r.Identify.Addrs = append(r.Identify.Addrs, addr.String())
+		}
+	}
+	if len(hInfo.Attr) > 0 {
+		r.Attributes = make(map[string]interface{}, len(hInfo.Attr))
+		for key, value := range hInfo.Attr {
+			r.Attributes[key] = value
+		}
+	}
+}
+
+func (g *GossipReport) addPeerStats(gs *gossipsub.GossipSub, peerID peer.ID, duration time.Duration) {
+	g.Duration = duration.String()
+	g.PeerTopics = make([]string, 0)
+	for _, topic := range g.Topics {
+		for _, p := range gs.PubsubService.ListPeers(topic) {
+			if p == peerID {
+				g.PeerTopics = append(g.PeerTopics, topic)
+				break
+			}
+		}
+	}
+	if stats, ok := gs.PeerGossipStats()[peerID]; ok {
+		g.Stats = &stats
+	}
+}
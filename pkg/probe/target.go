@@ -0,0 +1,73 @@
+package probe
+
+import (
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+
+	eth "github.com/migalabs/armiarma/pkg/networks/ethereum"
+)
+
+// Target is the peer to probe, given by its ENR or by its multiaddr (with its peer ID)
+type Target struct {
+	Raw      string
+	AddrInfo peer.AddrInfo
+	// decoded ENR of the target (nil if it was given by its multiaddr)
+	Enr *eth.EnrNode
+}
+
+// ParseTarget decodes the ENR ("enr:...") or the multiaddr ("/ip4/.../p2p/<peer-id>") of the peer to probe
+func ParseTarget(raw string) (*Target, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "enr:") {
+		return targetFromEnr(raw)
+	}
+	maddr, err := ma.NewMultiaddr(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "the target is neither an ENR nor a multiaddr")
+	}
+	addrInfo, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "the multiaddr of the target needs its peer ID (/p2p/<peer-id>)")
+	}
+	return &Target{
+		Raw:      raw,
+		AddrInfo: *addrInfo,
+	}, nil
+}
+
+func targetFromEnr(raw string) (*Target, error) {
+	enr, err := eth.ParseEnrString(raw)
+	if err != nil {
+		return nil, err
+	}
+	peerID, err := enr.GetPeerID()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to convert the pubkey of the ENR to a peer ID")
+	}
+	maddr, err := enr.TCPMultiaddr()
+	if err != nil {
+		return nil, err
+	}
+	return &Target{
+		Raw: raw,
+		AddrInfo: peer.AddrInfo{
+			ID:    peerID,
+			Addrs: []ma.Multiaddr{maddr},
+		},
+		Enr: enr,
+	}, nil
+}
+
+// ForkDigest returns the fork digest advertised in the ENR of the target (empty if there is none)
+func (t *Target) ForkDigest() string {
+	if t.Enr == nil || t.Enr.Eth2Data == nil {
+		return ""
+	}
+	if _, ok := t.Enr.Fields[eth.ETH2_ENR_KEY]; !ok {
+		return ""
+	}
+	return eth.GetForkDigestFromEth2Data(*t.Enr.Eth2Data)
+}
@@ -0,0 +1,89 @@
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type database interface {
+	ApplyTableRetention(table string, window time.Duration, rollup bool) (int64, error)
+}
+
+// Manager prunes (or rolls up) the rows of each table older than its retention window,
+// on the given cron-like schedule
+type Manager struct {
+	ctx context.Context
+
+	db       database
+	rules    []Rule
+	schedule Schedule
+
+	wg sync.WaitGroup
+}
+
+func NewManager(ctx context.Context, db database, rules []Rule, schedule Schedule) (*Manager, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no retention rules given")
+	}
+	if schedule == nil {
+		return nil, errors.New("no retention schedule given")
+	}
+	return &Manager{
+		ctx:      ctx,
+		db:       db,
+		rules:    rules,
+		schedule: schedule,
+	}, nil
+}
+
+// Start spawns the routine that applies the retention rules on each scheduled time
+func (m *Manager) Start() {
+	log.WithField("rules", len(m.rules)).Info("starting retention manager")
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			next := m.schedule.Next(time.Now())
+			log.Debugf("next retention pass at %s", next)
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				m.RunOnce()
+			case <-m.ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits until the ongoing retention pass finishes (the routine dies with the context)
+func (m *Manager) Stop() {
+	m.wg.Wait()
+}
+
+// RunOnce applies all the retention rules, returning the number of deleted rows per table
+func (m *Manager) RunOnce() map[string]int64 {
+	deleted := make(map[string]int64, len(m.rules))
+	for _, rule := range m.rules {
+		start := time.Now()
+		rows, err := m.db.ApplyTableRetention(rule.Table, rule.Window, rule.Action == RollupAction)
+		if err != nil {
+			log.WithError(err).Errorf("unable to apply the retention of %s", rule.Table)
+			continue
+		}
+		deleted[rule.Table] = rows
+		log.WithFields(log.Fields{
+			"table":    rule.Table,
+			"window":   rule.Window,
+			"action":   rule.Action,
+			"deleted":  rows,
+			"duration": time.Since(start),
+		}).Info("applied retention rule")
+	}
+	return deleted
+}
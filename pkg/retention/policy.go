@@ -0,0 +1,92 @@
+package retention
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// rows older than the window are deleted
+	PruneAction = "prune"
+	// rows older than the window are aggregated into daily rollups before being deleted
+	RollupAction = "rollup"
+)
+
+// tables whose old rows can be pruned, and whether they can also be rolled up
+var retainableTables = map[string]bool{
+	"conn_events":       true,
+	"gossip_messages":   true,
+	"peers_history":     false,
+	"eth_node_records":  false,
+	"eth_attestations":  false,
+	"eth_aggregates":    false,
+	"eth_blob_sidecars": false,
+	"eth_blocks":        false,
+}
+
+// Rule is the retention window of a table, and what to do with the rows older than it
+type Rule struct {
+	Table  string
+	Window time.Duration
+	Action string
+}
+
+// ParsePolicy parses the comma separated retention rules of each table (<table>=<window>[:rollup]),
+// i.e. conn_events=720h:rollup,gossip_messages=168h,eth_node_records=2160h
+func ParsePolicy(policy string) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	if strings.TrimSpace(policy) == "" {
+		return rules, nil
+	}
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(policy, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid retention rule %s", item)
+		}
+		table := strings.TrimSpace(kv[0])
+		canRollup, ok := retainableTables[table]
+		if !ok {
+			return nil, errors.Errorf("unknown retention table %s (%s)", table, strings.Join(RetainableTables(), ", "))
+		}
+		if seen[table] {
+			return nil, errors.Errorf("duplicated retention rule for %s", table)
+		}
+		seen[table] = true
+
+		rule := Rule{Table: table, Action: PruneAction}
+		value := strings.SplitN(strings.TrimSpace(kv[1]), ":", 2)
+		window, err := time.ParseDuration(value[0])
+		if err != nil || window <= 0 {
+			return nil, errors.Errorf("invalid retention window %s", item)
+		}
+		rule.Window = window
+		if len(value) == 2 {
+			switch value[1] {
+			case PruneAction:
+			case RollupAction:
+				if !canRollup {
+					return nil, errors.Errorf("%s can't be rolled up, only pruned", table)
+				}
+				rule.Action = RollupAction
+			default:
+				return nil, errors.Errorf("unknown retention action %s (%s, %s)", value[1], PruneAction, RollupAction)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// RetainableTables returns the tables that accept a retention rule
+func RetainableTables() []string {
+	tables := make([]string, 0, len(retainableTables))
+	for table := range retainableTables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
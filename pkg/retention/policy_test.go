@@ -0,0 +1,35 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	rules, err := ParsePolicy("conn_events=720h:rollup, gossip_messages=168h:prune,eth_node_records=2160h")
+	require.NoError(t, err)
+	require.Equal(t, []Rule{
+		{Table: "conn_events", Window: 720 * time.Hour, Action: RollupAction},
+		{Table: "gossip_messages", Window: 168 * time.Hour, Action: PruneAction},
+		{Table: "eth_node_records", Window: 2160 * time.Hour, Action: PruneAction},
+	}, rules)
+
+	rules, err = ParsePolicy("")
+	require.NoError(t, err)
+	require.Empty(t, rules)
+
+	for _, policy := range []string{
+		"conn_events",
+		"conn_events=forever",
+		"conn_events=-1h",
+		"peer_info=24h",
+		"conn_events=24h,conn_events=48h",
+		"eth_blocks=24h:rollup",
+		"conn_events=24h:archive",
+	} {
+		_, err = ParsePolicy(policy)
+		require.Error(t, err, policy)
+	}
+}
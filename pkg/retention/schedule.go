@@ -0,0 +1,156 @@
+package retention
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule tells when the next retention pass has to run
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// everySchedule runs at a fixed interval (@every <duration>)
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// cronSchedule runs at the minutes matching the 5 fields of a cron expression
+// (minute hour day-of-month month day-of-week)
+type cronSchedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+}
+
+// cron fields and their ranges
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+var cronAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// ParseSchedule parses a cron expression (i.e. "30 3 * * *"), one of its aliases
+// (@hourly, @daily, @weekly, @monthly) or a fixed interval (@every 6h)
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || interval <= 0 {
+			return nil, errors.Errorf("invalid interval in schedule %s", spec)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+	if alias, ok := cronAliases[spec]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("invalid schedule %s (expected 5 cron fields)", spec)
+	}
+	sets := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s in schedule %s", cronFields[i].name, spec)
+		}
+		sets[i] = set
+	}
+	return &cronSchedule{
+		minutes:  sets[0],
+		hours:    sets[1],
+		days:     sets[2],
+		months:   sets[3],
+		weekdays: sets[4],
+	}, nil
+}
+
+// parseCronField parses the comma separated list of values, ranges (a-b) and steps (*/n, a-b/n)
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if parts := strings.SplitN(item, "/", 2); len(parts) == 2 {
+			var err error
+			step, err = strconv.Atoi(parts[1])
+			if err != nil || step <= 0 {
+				return nil, errors.Errorf("invalid step %s", item)
+			}
+			item = parts[0]
+		}
+		from, to := min, max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+			var err1, err2 error
+			from, err1 = strconv.Atoi(bounds[0])
+			to, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return nil, errors.Errorf("invalid range %s", item)
+			}
+		default:
+			value, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, errors.Errorf("invalid value %s", item)
+			}
+			from, to = value, value
+		}
+		if from < min || to > max || from > to {
+			return nil, errors.Errorf("%s out of range [%d-%d]", item, min, max)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first matching minute after t (within the next 5 years). Unlike in the
+// classic cron, a restricted day of month and day of week have to match both
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		if !s.months[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.days[next.Day()] || !s.weekdays[int(next.Weekday())] {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+	return limit
+}
@@ -0,0 +1,55 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule(t *testing.T) {
+	base := time.Date(2023, time.March, 14, 10, 17, 30, 0, time.UTC) // tuesday
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"@hourly", time.Date(2023, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, time.March, 14, 10, 30, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2023, time.March, 15, 3, 30, 0, 0, time.UTC)},
+		{"0 9-11 * * 1-5", time.Date(2023, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 6 *", time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		s, err := ParseSchedule(test.spec)
+		require.NoError(t, err, test.spec)
+		require.Equal(t, test.next, s.Next(base), test.spec)
+	}
+}
+
+func TestEverySchedule(t *testing.T) {
+	s, err := ParseSchedule("@every 6h")
+	require.NoError(t, err)
+	base := time.Date(2023, time.March, 14, 10, 17, 30, 0, time.UTC)
+	require.Equal(t, base.Add(6*time.Hour), s.Next(base))
+}
+
+func TestInvalidSchedules(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"@yearly",
+		"@every",
+		"@every -1h",
+		"* * * *",
+		"60 * * * *",
+		"* 5-2 * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		_, err := ParseSchedule(spec)
+		require.Error(t, err, spec)
+	}
+}
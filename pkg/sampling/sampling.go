@@ -0,0 +1,217 @@
+package sampling
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+/**
+The sampler picks uniformly random subsets of the known peers, so that the active probing
+experiments are run on statistically sound subsets rather than on the first N rows of the DB.
+The candidates are sorted before being shuffled, so the same seed over the same set of known
+peers always gives the same sample. When stratified, each stratum (client or country) gets a
+share of the sample proportional to its share of the candidates (largest remainder), and its
+peers are sampled uniformly within the stratum.
+*/
+
+var (
+	// path where the samples are requested to a running crawler
+	SamplePath = "/peers/sample"
+
+	DefaultSampleSize = 100
+)
+
+// attributes that a sample can be stratified by
+const (
+	NoStratify      = ""
+	StratifyClient  = "client"
+	StratifyCountry = "country"
+)
+
+// samplingDB is the part of the DB client used to sample the peers
+type samplingDB interface {
+	GetSampleCandidates(network utils.NetworkType) ([]*models.SampledPeer, error)
+	PersistToDB(interface{})
+}
+
+// Sampler returns random samples of the known peers of the network, recording them in the DB
+type Sampler struct {
+	network utils.NetworkType
+	db      samplingDB
+}
+
+func NewSampler(network utils.NetworkType, db samplingDB) *Sampler {
+	return &Sampler{
+		network: network,
+		db:      db,
+	}
+}
+
+// Sample picks a random sample of the given size from the known peers, stratified by the given
+// attribute (if any). A random seed is used (and recorded) if the given one is 0
+func (s *Sampler) Sample(size int, stratify string, seed int64) (*models.PeerSample, error) {
+	if size <= 0 {
+		return nil, errors.Errorf("invalid sample size %d", size)
+	}
+	if err := CheckStratify(stratify); err != nil {
+		return nil, err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	candidates, err := s.db.GetSampleCandidates(s.network)
+	if err != nil {
+		return nil, err
+	}
+	sample := &models.PeerSample{
+		Timestamp:  time.Now(),
+		Network:    string(s.network),
+		Seed:       seed,
+		Stratify:   stratify,
+		Size:       size,
+		Candidates: len(candidates),
+		Peers:      SamplePeers(candidates, size, stratify, seed),
+	}
+	s.db.PersistToDB(sample)
+	log.WithFields(log.Fields{
+		"seed":       sample.Seed,
+		"stratify":   sample.Stratify,
+		"size":       len(sample.Peers),
+		"candidates": sample.Candidates,
+	}).Info("new peer sample")
+	return sample, nil
+}
+
+// Handler returns a new sample on GET, with the "size", "stratify" and "seed" query parameters
+func (s *Sampler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		size := DefaultSampleSize
+		if sizeStr := query.Get("size"); sizeStr != "" {
+			sz, err := strconv.Atoi(sizeStr)
+			if err != nil {
+				http.Error(w, "invalid size "+sizeStr, http.StatusBadRequest)
+				return
+			}
+			size = sz
+		}
+		var seed int64
+		if seedStr := query.Get("seed"); seedStr != "" {
+			sd, err := strconv.ParseInt(seedStr, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid seed "+seedStr, http.StatusBadRequest)
+				return
+			}
+			seed = sd
+		}
+		sample, err := s.Sample(size, query.Get("stratify"), seed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(sample); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// SamplePeers picks the given number of candidates with a deterministic seed, the same
+// candidates and seed always give the same sample no matter the order of the candidates
+func SamplePeers(candidates []*models.SampledPeer, size int, stratify string, seed int64) []*models.SampledPeer {
+	// group the candidates by stratum (all together if not stratified)
+	strata := make(map[string][]*models.SampledPeer)
+	for _, c := range candidates {
+		key := StratumKey(c, stratify)
+		strata[key] = append(strata[key], c)
+	}
+	keys := make([]string, 0, len(strata))
+	for key := range strata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rng := rand.New(rand.NewSource(seed))
+	quotas := allocate(strata, keys, size, len(candidates))
+	sample := make([]*models.SampledPeer, 0, size)
+	for _, key := range keys {
+		stratum := strata[key]
+		sort.Slice(stratum, func(i, j int) bool {
+			return stratum[i].PeerID < stratum[j].PeerID
+		})
+		rng.Shuffle(len(stratum), func(i, j int) {
+			stratum[i], stratum[j] = stratum[j], stratum[i]
+		})
+		sample = append(sample, stratum[:quotas[key]]...)
+	}
+	return sample
+}
+
+// allocate splits the sample size among the strata proportionally to their size (largest remainder)
+func allocate(strata map[string][]*models.SampledPeer, keys []string, size, total int) map[string]int {
+	quotas := make(map[string]int, len(keys))
+	if size >= total {
+		for _, key := range keys {
+			quotas[key] = len(strata[key])
+		}
+		return quotas
+	}
+	remainders := make(map[string]float64, len(keys))
+	assigned := 0
+	for _, key := range keys {
+		exact := float64(size) * float64(len(strata[key])) / float64(total)
+		quotas[key] = int(math.Floor(exact))
+		remainders[key] = exact - math.Floor(exact)
+		assigned += quotas[key]
+	}
+	// the seats left go to the largest remainders (ties by stratum name)
+	byRemainder := append([]string{}, keys...)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return remainders[byRemainder[i]] > remainders[byRemainder[j]]
+	})
+	for i := 0; assigned < size && i < len(byRemainder); i++ {
+		key := byRemainder[i]
+		if quotas[key] < len(strata[key]) {
+			quotas[key]++
+			assigned++
+		}
+	}
+	return quotas
+}
+
+// StratumKey returns the stratum of the peer for the given attribute (empty if not stratified)
+func StratumKey(c *models.SampledPeer, stratify string) string {
+	switch stratify {
+	case StratifyClient:
+		return c.Client
+	case StratifyCountry:
+		return c.Country
+	default:
+		return ""
+	}
+}
+
+// CheckStratify returns an error if the peers can't be stratified by the given attribute
+func CheckStratify(stratify string) error {
+	switch stratify {
+	case NoStratify, StratifyClient, StratifyCountry:
+		return nil
+	default:
+		return errors.Errorf("unknown stratify attribute %s (client or country)", stratify)
+	}
+}
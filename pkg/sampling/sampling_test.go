@@ -0,0 +1,57 @@
+package sampling
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func testCandidates() []*models.SampledPeer {
+	candidates := make([]*models.SampledPeer, 0)
+	// 60 lighthouse, 30 prysm, 10 teku
+	clients := map[string]int{"lighthouse": 60, "prysm": 30, "teku": 10}
+	for client, n := range clients {
+		for i := 0; i < n; i++ {
+			candidates = append(candidates, &models.SampledPeer{
+				PeerID:  peer.ID(fmt.Sprintf("%s-%02d", client, i)),
+				Client:  client,
+				Country: []string{"DE", "US"}[i%2],
+			})
+		}
+	}
+	return candidates
+}
+
+func Test_SamplePeers(t *testing.T) {
+	candidates := testCandidates()
+	sample := SamplePeers(candidates, 10, NoStratify, 42)
+	require.Len(t, sample, 10)
+
+	// same seed gives the same sample, no matter the order of the candidates
+	reversed := make([]*models.SampledPeer, len(candidates))
+	for i, c := range candidates {
+		reversed[len(candidates)-1-i] = c
+	}
+	require.Equal(t, sample, SamplePeers(reversed, 10, NoStratify, 42))
+	require.NotEqual(t, sample, SamplePeers(candidates, 10, NoStratify, 43))
+
+	// bigger samples than the candidates return all of them
+	require.Len(t, SamplePeers(candidates, 1000, StratifyClient, 42), len(candidates))
+}
+
+func Test_StratifiedSample(t *testing.T) {
+	sample := SamplePeers(testCandidates(), 15, StratifyClient, 42)
+	require.Len(t, sample, 15)
+	perClient := make(map[string]int)
+	for _, p := range sample {
+		perClient[p.Client]++
+	}
+	// 9, 4.5 and 1.5 seats, the remainders are tied and go to the first strata
+	require.Equal(t, map[string]int{"lighthouse": 9, "prysm": 5, "teku": 1}, perClient)
+
+	require.Error(t, CheckStratify("asn"))
+}
@@ -0,0 +1,31 @@
+package soak
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type SoakOption func(*SoakService) error
+
+// WithRetention sets the age over which the time-series rows are deleted
+func WithRetention(retention time.Duration) SoakOption {
+	return func(s *SoakService) error {
+		if retention <= 0 {
+			return errors.Errorf("invalid retention %s", retention)
+		}
+		s.retention = retention
+		return nil
+	}
+}
+
+// WithExportDir sets the folder where the weekly summaries are exported
+func WithExportDir(dir string) SoakOption {
+	return func(s *SoakService) error {
+		if dir == "" {
+			return errors.New("empty summary export dir")
+		}
+		s.exportDir = dir
+		return nil
+	}
+}
@@ -0,0 +1,204 @@
+package soak
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+var (
+	DefaultRetention       = 30 * 24 * time.Hour
+	DefaultExportDir       = "./soak-summaries"
+	DefaultWatchdogTimeout = 30 * time.Minute
+
+	// periodicity of each of the hygiene tasks
+	RetentionInterval = 1 * time.Hour
+	RollupInterval    = 24 * time.Hour
+	ExportInterval    = 7 * 24 * time.Hour
+)
+
+type database interface {
+	ApplyRetention(retention time.Duration) (map[string]int64, error)
+	UpsertStatsRollup(day time.Time, dimension string, dist map[string]interface{}) error
+	GetStatsRollups(since time.Time) ([]models.StatsRollup, error)
+
+	GetClientDistribution() (map[string]interface{}, error)
+	GetVersionDistribution() (map[string]interface{}, error)
+	GetGeoDistribution() (map[string]interface{}, error)
+	GetOsDistribution() (map[string]interface{}, error)
+	GetArchDistribution() (map[string]interface{}, error)
+	GetSecurityDistribution() (map[string]interface{}, error)
+	GetOriginDistribution() (map[string]interface{}, error)
+	GetProtocolDistribution() (map[string]interface{}, error)
+}
+
+// Summary is the periodic export of the daily rollups of a soak deployment
+type Summary struct {
+	Network     string               `json:"network"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Rollups     []models.StatsRollup `json:"rollups"`
+	RetainedFor string               `json:"retention"`
+}
+
+// SoakService takes care of the data hygiene of long-running (unattended) deployments:
+// it applies the retention policy, rolls up the daily statistics, and exports weekly summaries
+type SoakService struct {
+	ctx context.Context
+
+	network   string
+	db        database
+	retention time.Duration
+	exportDir string
+
+	wg sync.WaitGroup
+}
+
+func NewSoakService(
+	ctx context.Context,
+	network string,
+	db database,
+	opts ...SoakOption) (*SoakService, error) {
+
+	soak := &SoakService{
+		ctx:       ctx,
+		network:   network,
+		db:        db,
+		retention: DefaultRetention,
+		exportDir: DefaultExportDir,
+	}
+	for _, opt := range opts {
+		err := opt(soak)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to apply soak option")
+		}
+	}
+	err := os.MkdirAll(soak.exportDir, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create summary export dir")
+	}
+	return soak, nil
+}
+
+// Start spawns the routines of each of the hygiene tasks
+func (s *SoakService) Start() {
+	log.WithFields(log.Fields{
+		"retention":  s.retention,
+		"export-dir": s.exportDir,
+	}).Info("starting soak service")
+	s.runEvery(RetentionInterval, s.applyRetention)
+	s.runEvery(RollupInterval, s.rollup)
+	s.runEvery(ExportInterval, s.export)
+}
+
+// Stop waits until the ongoing tasks finish (the routines die with the context)
+func (s *SoakService) Stop() {
+	s.wg.Wait()
+}
+
+func (s *SoakService) runEvery(interval time.Duration, task func() error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := task()
+				if err != nil {
+					log.WithError(err).Error("soak task failed")
+				}
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *SoakService) applyRetention() error {
+	deleted, err := s.db.ApplyRetention(s.retention)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"retention": s.retention,
+		"deleted":   deleted,
+	}).Info("applied retention policy")
+	return nil
+}
+
+// rollup persists the current distributions as the rollup of the day
+func (s *SoakService) rollup() error {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	distributions := map[string]func() (map[string]interface{}, error){
+		"client":   s.db.GetClientDistribution,
+		"version":  s.db.GetVersionDistribution,
+		"country":  s.db.GetGeoDistribution,
+		"os":       s.db.GetOsDistribution,
+		"arch":     s.db.GetArchDistribution,
+		"security": s.db.GetSecurityDistribution,
+		"origin":   s.db.GetOriginDistribution,
+		"protocol": s.db.GetProtocolDistribution,
+	}
+	for dimension, getDist := range distributions {
+		dist, err := getDist()
+		if err != nil {
+			return errors.Wrap(err, "unable to get "+dimension+" distribution")
+		}
+		err = s.db.UpsertStatsRollup(day, dimension, dist)
+		if err != nil {
+			return err
+		}
+	}
+	log.Infof("rolled up the statistics of %s", day.Format("2006-01-02"))
+	return nil
+}
+
+// export writes the summary of the last week of rollups into a json file
+func (s *SoakService) export() error {
+	to := time.Now().UTC()
+	from := to.Add(-ExportInterval)
+	rollups, err := s.db.GetStatsRollups(from.Truncate(24 * time.Hour))
+	if err != nil {
+		return err
+	}
+	summary := Summary{
+		Network:     s.network,
+		From:        from,
+		To:          to,
+		Rollups:     rollups,
+		RetainedFor: s.retention.String(),
+	}
+	path, err := s.writeSummary(summary)
+	if err != nil {
+		return err
+	}
+	log.Infof("exported weekly summary to %s", path)
+	return nil
+}
+
+func (s *SoakService) writeSummary(summary Summary) (string, error) {
+	path := filepath.Join(
+		s.exportDir,
+		fmt.Sprintf("summary_%s_%s.json", summary.Network, summary.To.Format("2006-01-02")),
+	)
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return path, errors.Wrap(err, "unable to marshal summary")
+	}
+	err = os.WriteFile(path, content, 0644)
+	if err != nil {
+		return path, errors.Wrap(err, "unable to write summary")
+	}
+	return path, nil
+}
@@ -0,0 +1,63 @@
+package soak
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+type mockHost struct {
+	conns int
+}
+
+func (h *mockHost) OpenConnections() int {
+	return h.conns
+}
+
+func Test_WatchdogCheck(t *testing.T) {
+	h := &mockHost{conns: 3}
+	w, err := NewWatchdog(context.Background(), h, 10*time.Minute)
+	require.NoError(t, err)
+
+	start := time.Now()
+	w.lastAlive = start
+	require.False(t, w.check(start.Add(5*time.Minute)))
+
+	// no connections, but still inside the timeout
+	h.conns = 0
+	require.False(t, w.check(start.Add(10*time.Minute)))
+	require.True(t, w.check(start.Add(16*time.Minute)))
+
+	_, err = NewWatchdog(context.Background(), h, 0)
+	require.Error(t, err)
+}
+
+func Test_WriteSummary(t *testing.T) {
+	s, err := NewSoakService(context.Background(), "ethereum", nil, WithExportDir(t.TempDir()))
+	require.NoError(t, err)
+
+	to := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	summary := Summary{
+		Network: "ethereum",
+		From:    to.Add(-ExportInterval),
+		To:      to,
+		Rollups: []models.StatsRollup{
+			{Day: to, Network: "ethereum", Dimension: "client", Key: "lighthouse", Value: 10},
+		},
+	}
+	path, err := s.writeSummary(summary)
+	require.NoError(t, err)
+	require.Contains(t, path, "summary_ethereum_2024-03-10.json")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var readSummary Summary
+	require.NoError(t, json.Unmarshal(content, &readSummary))
+	require.Equal(t, summary.Rollups, readSummary.Rollups)
+}
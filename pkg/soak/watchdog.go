@@ -0,0 +1,76 @@
+package soak
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	watchdogCheckInterval = 1 * time.Minute
+)
+
+type connHost interface {
+	OpenConnections() int
+}
+
+// Watchdog detects when the crawler stalls (no open connections during the whole timeout),
+// notifying it through the Stalled channel so that the crawler can be restarted
+type Watchdog struct {
+	ctx context.Context
+
+	host    connHost
+	timeout time.Duration
+
+	lastAlive time.Time
+	stalledC  chan struct{}
+}
+
+func NewWatchdog(ctx context.Context, h connHost, timeout time.Duration) (*Watchdog, error) {
+	if timeout <= 0 {
+		return nil, errors.Errorf("invalid watchdog timeout %s", timeout)
+	}
+	return &Watchdog{
+		ctx:      ctx,
+		host:     h,
+		timeout:  timeout,
+		stalledC: make(chan struct{}),
+	}, nil
+}
+
+// Start spawns the routine that checks the liveness of the crawler
+func (w *Watchdog) Start() {
+	w.lastAlive = time.Now()
+	go func() {
+		ticker := time.NewTicker(watchdogCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if w.check(time.Now()) {
+					log.Errorf("watchdog: no open connections during the last %s, crawler stalled", w.timeout)
+					close(w.stalledC)
+					return
+				}
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// check returns whether the crawler has been stalled for longer than the timeout
+func (w *Watchdog) check(now time.Time) bool {
+	if w.host.OpenConnections() > 0 {
+		w.lastAlive = now
+		return false
+	}
+	return now.Sub(w.lastAlive) > w.timeout
+}
+
+// Stalled returns the channel that gets closed once the crawler is considered stalled
+func (w *Watchdog) Stalled() <-chan struct{} {
+	return w.stalledC
+}
@@ -0,0 +1,141 @@
+package tui
+
+/**
+This package implements the terminal dashboard of a running crawler. It reads the metrics that the
+crawler keeps in memory through its metrics endpoint (instead of the DB, which only gets the peers
+once they are persisted), showing the live dial and discovery rates, the clients of the connected
+peers, and the errors of the dials.
+
+*/
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	DefaultRefreshInterval = 2 * time.Second
+	DefaultTopEntries      = 10
+)
+
+// Dashboard periodically scrapes the metrics of the crawler and redraws them
+type Dashboard struct {
+	scraper  *Scraper
+	network  string
+	interval time.Duration
+	top      int
+}
+
+func NewDashboard(scraper *Scraper, network string, interval time.Duration, top int) *Dashboard {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	return &Dashboard{
+		scraper:  scraper,
+		network:  network,
+		interval: interval,
+		top:      top,
+	}
+}
+
+// Run draws the dashboard on out until the context is done or "q" is read from in,
+// where "n" switches to the next network of the endpoint
+func (d *Dashboard) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	commandC := make(chan string)
+	go readCommands(ctx, in, commandC)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	var prev, cur *Snapshot
+	var scrapeErr error
+	network := d.network
+	draw := func() error {
+		view := &View{
+			URL:  d.scraper.URL(),
+			Time: time.Now(),
+			Top:  d.top,
+			Err:  scrapeErr,
+		}
+		if cur != nil {
+			view.Networks = cur.Networks()
+			if network == "" && len(view.Networks) > 0 {
+				network = view.Networks[0]
+			}
+			view.Stats = ComputeStats(network, prev, cur)
+		}
+		return Render(out, view)
+	}
+	scrape := func() {
+		snapshot, err := d.scraper.Scrape(ctx)
+		scrapeErr = err
+		if err != nil {
+			log.Debug(err)
+			return
+		}
+		prev, cur = cur, snapshot
+	}
+
+	scrape()
+	for {
+		if err := draw(); err != nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+			scrape()
+
+		case command, ok := <-commandC:
+			if !ok {
+				// the input was closed, keep refreshing until the context is done
+				commandC = nil
+				continue
+			}
+			switch command {
+			case "q", "quit", "exit":
+				return nil
+			case "n", "next":
+				// the snapshots hold every network, so the rates of the next one are already known
+				if cur != nil {
+					network = nextNetwork(cur.Networks(), network)
+				}
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func nextNetwork(networks []string, current string) string {
+	if len(networks) == 0 {
+		return current
+	}
+	for i, network := range networks {
+		if network == current {
+			return networks[(i+1)%len(networks)]
+		}
+	}
+	return networks[0]
+}
+
+// readCommands reads the commands line by line (the terminal stays in its normal mode)
+func readCommands(ctx context.Context, in io.Reader, commandC chan string) {
+	defer close(commandC)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		select {
+		case commandC <- strings.ToLower(strings.TrimSpace(scanner.Text())):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// ANSI sequences to move the cursor home and clear the screen
+	clearScreen = "\033[H\033[2J"
+	barWidth    = 30
+)
+
+// View is what the dashboard draws on each refresh
+type View struct {
+	URL      string
+	Time     time.Time
+	Networks []string
+	Stats    *Stats
+	// rows of each of the breakdowns
+	Top int
+	// error of the last scrape (the last stats are kept on screen)
+	Err error
+}
+
+// Render draws the view, clearing the terminal first
+func Render(w io.Writer, v *View) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, clearScreen)
+	fmt.Fprintf(tw, "armiarma dashboard - %s - %s\n", v.URL, v.Time.Format("15:04:05"))
+	fmt.Fprintf(tw, "networks: %s   (n + enter: next network, q + enter: quit)\n", networksLine(v))
+	if v.Err != nil {
+		fmt.Fprintf(tw, "last scrape failed: %v\n", v.Err)
+	}
+	s := v.Stats
+	if s == nil {
+		fmt.Fprintln(tw, "\nwaiting for the metrics of the crawler...")
+		return tw.Flush()
+	}
+
+	fmt.Fprintln(tw, "\nDIALS")
+	if !s.HasHost {
+		fmt.Fprintln(tw, "  the crawler of the network doesn't dial over libp2p")
+	} else {
+		fmt.Fprintf(tw, "  dials/sec\t%s\tsuccesses/sec\t%s\tsuccess rate\t%s\n",
+			rateString(s, s.DialAttempts.Rate), rateString(s, s.DialSuccesses.Rate), share(s.DialSuccesses.Value, s.DialAttempts.Value))
+		fmt.Fprintf(tw, "  active dials\t%.0f\tconnected peers\t%.0f\ttotal dials\t%.0f\n",
+			s.ActiveDials, s.ConnectedPeers, s.DialAttempts.Value)
+
+		fmt.Fprintln(tw, "\nCONNECTED PEERS BY CLIENT")
+		writeBreakdown(tw, s, s.PeersByClient, v.Top, false)
+
+		fmt.Fprintln(tw, "\nDIAL ERRORS\ttotal\t\tshare\t/sec")
+		writeBreakdown(tw, s, s.DialErrors, v.Top, true)
+	}
+
+	fmt.Fprintln(tw, "\nDISCOVERY\ttotal\t\tshare\t/sec")
+	fmt.Fprintf(tw, "  all sources\t%.0f\t\t\t%s\n", s.Discovered.Value, rateString(s, s.Discovered.Rate))
+	writeBreakdown(tw, s, s.DiscoverySources, v.Top, true)
+	return tw.Flush()
+}
+
+func networksLine(v *View) string {
+	names := make([]string, 0, len(v.Networks))
+	for _, network := range v.Networks {
+		if v.Stats != nil && network == v.Stats.Network {
+			network = "[" + network + "]"
+		}
+		names = append(names, network)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, " ")
+}
+
+// writeBreakdown draws the entries with a bar proportional to their share of the total
+func writeBreakdown(w io.Writer, s *Stats, entries []Entry, top int, withRate bool) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "  -")
+		return
+	}
+	total := 0.0
+	for _, entry := range entries {
+		total += entry.Value
+	}
+	if top > 0 && len(entries) > top {
+		others := Entry{Name: fmt.Sprintf("(%d others)", len(entries)-top)}
+		for _, entry := range entries[top:] {
+			others.Value += entry.Value
+			others.Rate += entry.Rate
+		}
+		entries = append(entries[:top:top], others)
+	}
+	for _, entry := range entries {
+		bar := ""
+		if total > 0 {
+			bar = strings.Repeat("#", int(entry.Value/total*barWidth+0.5))
+		}
+		line := fmt.Sprintf("  %s\t%.0f\t%s\t%s", entry.Name, entry.Value, bar, share(entry.Value, total))
+		if withRate {
+			line += "\t" + rateString(s, entry.Rate)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// rateString shows the rate, which isn't known until the second snapshot
+func rateString(s *Stats, rate float64) string {
+	if s.Interval <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", rate)
+}
+
+func share(part, total float64) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", part/total*100)
+}
@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// label that the crawlers add to each of their metrics
+const networkLabel = "network"
+
+// Sample is a single value of a metric, with its labels
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Snapshot holds the values of the metrics exposed by a crawler at the time of the scrape
+type Snapshot struct {
+	Time    time.Time
+	Samples map[string][]Sample
+}
+
+// ParseSnapshot reads the metrics from their text exposition
+func ParseSnapshot(t time.Time, r io.Reader) (*Snapshot, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse the metrics")
+	}
+	snapshot := &Snapshot{
+		Time:    t,
+		Samples: make(map[string][]Sample, len(families)),
+	}
+	for name, family := range families {
+		samples := make([]Sample, 0, len(family.GetMetric()))
+		for _, metric := range family.GetMetric() {
+			value, ok := metricValue(family.GetType(), metric)
+			if !ok {
+				continue
+			}
+			labels := make(map[string]string, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			samples = append(samples, Sample{Labels: labels, Value: value})
+		}
+		snapshot.Samples[name] = samples
+	}
+	return snapshot, nil
+}
+
+// the histograms and summaries aren't shown in the dashboard
+func metricValue(kind dto.MetricType, metric *dto.Metric) (float64, bool) {
+	switch kind {
+	case dto.MetricType_COUNTER:
+		return metric.GetCounter().GetValue(), true
+	case dto.MetricType_GAUGE:
+		return metric.GetGauge().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return metric.GetUntyped().GetValue(), true
+	default:
+		return 0, false
+	}
+}
+
+// Networks returns the networks whose crawlers expose metrics (several crawlers can share the endpoint)
+func (s *Snapshot) Networks() []string {
+	unique := make(map[string]struct{})
+	for _, samples := range s.Samples {
+		for _, sample := range samples {
+			if network, ok := sample.Labels[networkLabel]; ok {
+				unique[network] = struct{}{}
+			}
+		}
+	}
+	networks := make([]string, 0, len(unique))
+	for network := range unique {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// Has returns whether the crawler of the network exposes the metric
+func (s *Snapshot) Has(name, network string) bool {
+	for _, sample := range s.Samples[name] {
+		if sample.Labels[networkLabel] == network {
+			return true
+		}
+	}
+	return false
+}
+
+// Sum adds up the values of the metric of the network
+func (s *Snapshot) Sum(name, network string) float64 {
+	total := 0.0
+	for _, sample := range s.Samples[name] {
+		if sample.Labels[networkLabel] == network {
+			total += sample.Value
+		}
+	}
+	return total
+}
+
+// ByLabel adds up the values of the metric of the network per value of the given label
+func (s *Snapshot) ByLabel(name, network, label string) map[string]float64 {
+	values := make(map[string]float64)
+	for _, sample := range s.Samples[name] {
+		if sample.Labels[networkLabel] == network {
+			values[sample.Labels[label]] += sample.Value
+		}
+	}
+	return values
+}
+
+// Scraper reads the metrics of a running crawler from its metrics endpoint
+type Scraper struct {
+	url    string
+	client *http.Client
+}
+
+func NewScraper(endpoint string, timeout time.Duration) *Scraper {
+	url := endpoint
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "http://" + url
+	}
+	if !strings.HasSuffix(url, "/"+metrics.EndpointUrl) {
+		url = strings.TrimSuffix(url, "/") + "/" + metrics.EndpointUrl
+	}
+	return &Scraper{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// URL returns the url of the metrics that are scraped
+func (s *Scraper) URL() string {
+	return s.url
+}
+
+// Scrape takes a snapshot of the metrics of the crawler
+func (s *Scraper) Scrape(ctx context.Context) (*Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to compose the metrics request")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to reach the crawler")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to read the metrics: %s", resp.Status)
+	}
+	return ParseSnapshot(time.Now(), resp.Body)
+}
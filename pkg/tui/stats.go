@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"sort"
+)
+
+// metrics of the crawler that the dashboard shows
+const (
+	dialAttemptsMetric    = "host_dial_attempts"
+	dialSuccessesMetric   = "host_dial_successes"
+	dialFailuresMetric    = "host_dial_failures"
+	activeDialsMetric     = "peering_active_dials"
+	connectedPeersMetric  = "host_connected_peers"
+	peersByClientMetric   = "host_connected_peers_by_client"
+	discoveredPeersMetric = "discovery_discovered_peers"
+)
+
+// Entry is a labeled value of the dashboard, with its rate per second when it is a counter
+type Entry struct {
+	Name  string
+	Value float64
+	Rate  float64
+}
+
+// Stats are the figures of the crawler of a network between two snapshots of its metrics
+type Stats struct {
+	Network string
+	// seconds between the snapshots (0 for the first one, without rates)
+	Interval float64
+
+	// whether the crawler runs libp2p hosts (the discv4/discv5 crawlers don't dial over libp2p)
+	HasHost        bool
+	DialAttempts   Entry
+	DialSuccesses  Entry
+	ActiveDials    float64
+	ConnectedPeers float64
+	PeersByClient  []Entry
+	DialErrors     []Entry
+
+	Discovered       Entry
+	DiscoverySources []Entry
+}
+
+// ComputeStats composes the stats of the network from the current snapshot, and the rates of
+// the counters from the previous one (if any)
+func ComputeStats(network string, prev, cur *Snapshot) *Stats {
+	stats := &Stats{
+		Network: network,
+		HasHost: cur.Has(dialAttemptsMetric, network) || cur.Has(connectedPeersMetric, network),
+	}
+	if prev != nil {
+		stats.Interval = cur.Time.Sub(prev.Time).Seconds()
+	}
+	counter := func(name string) Entry {
+		entry := Entry{Name: name, Value: cur.Sum(name, network)}
+		if prev != nil {
+			entry.Rate = rate(prev.Sum(name, network), entry.Value, stats.Interval)
+		}
+		return entry
+	}
+	counterByLabel := func(name, label string) []Entry {
+		var prevValues map[string]float64
+		if prev != nil {
+			prevValues = prev.ByLabel(name, network, label)
+		}
+		entries := make([]Entry, 0)
+		for key, value := range cur.ByLabel(name, network, label) {
+			entry := Entry{Name: key, Value: value}
+			if prevValues != nil {
+				entry.Rate = rate(prevValues[key], value, stats.Interval)
+			}
+			entries = append(entries, entry)
+		}
+		return sortEntries(entries)
+	}
+
+	stats.DialAttempts = counter(dialAttemptsMetric)
+	stats.DialSuccesses = counter(dialSuccessesMetric)
+	stats.DialErrors = counterByLabel(dialFailuresMetric, "code")
+	stats.ActiveDials = cur.Sum(activeDialsMetric, network)
+	stats.ConnectedPeers = cur.Sum(connectedPeersMetric, network)
+	for client, n := range cur.ByLabel(peersByClientMetric, network, "client") {
+		stats.PeersByClient = append(stats.PeersByClient, Entry{Name: client, Value: n})
+	}
+	stats.PeersByClient = sortEntries(stats.PeersByClient)
+
+	stats.Discovered = counter(discoveredPeersMetric)
+	stats.DiscoverySources = counterByLabel(discoveredPeersMetric, "source")
+	return stats
+}
+
+// rate of a counter between two snapshots (the counters start from zero again if the crawler restarted)
+func rate(prev, cur, interval float64) float64 {
+	if interval <= 0 {
+		return 0
+	}
+	if cur < prev {
+		return cur / interval
+	}
+	return (cur - prev) / interval
+}
+
+// sortEntries sorts the entries from the biggest to the smallest value (by name on ties)
+func sortEntries(entries []Entry) []Entry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return entries[i].Value > entries[j].Value
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
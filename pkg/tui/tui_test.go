@@ -0,0 +1,96 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func exposition(attempts, successes, timeouts, dv5 int) string {
+	return fmt.Sprintf(`# TYPE host_dial_attempts counter
+host_dial_attempts{network="mainnet"} %d
+host_dial_attempts{network="ipfs"} 7
+# TYPE host_dial_successes counter
+host_dial_successes{network="mainnet"} %d
+# TYPE host_dial_failures counter
+host_dial_failures{code="dial_timeout",network="mainnet"} %d
+host_dial_failures{code="conn_refused",network="mainnet"} 10
+# TYPE host_connected_peers gauge
+host_connected_peers{network="mainnet"} 30
+# TYPE host_connected_peers_by_client gauge
+host_connected_peers_by_client{client="lighthouse",network="mainnet"} 12
+host_connected_peers_by_client{client="prysm",network="mainnet"} 15
+host_connected_peers_by_client{client="unidentified",network="mainnet"} 3
+# TYPE discovery_discovered_peers counter
+discovery_discovered_peers{network="mainnet",source="dv5"} %d
+discovery_discovered_peers{network="mainnet",source="db"} 40
+`, attempts, successes, timeouts, dv5)
+}
+
+func Test_ComputeStats(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	prev, err := ParseSnapshot(t0, strings.NewReader(exposition(100, 20, 50, 200)))
+	require.NoError(t, err)
+	cur, err := ParseSnapshot(t0.Add(10*time.Second), strings.NewReader(exposition(150, 30, 80, 300)))
+	require.NoError(t, err)
+	require.Equal(t, []string{"ipfs", "mainnet"}, cur.Networks())
+
+	// without a previous snapshot there are no rates
+	stats := ComputeStats("mainnet", nil, cur)
+	require.Zero(t, stats.Interval)
+	require.Zero(t, stats.DialAttempts.Rate)
+
+	stats = ComputeStats("mainnet", prev, cur)
+	require.True(t, stats.HasHost)
+	require.Equal(t, 10.0, stats.Interval)
+	require.Equal(t, 150.0, stats.DialAttempts.Value)
+	require.Equal(t, 5.0, stats.DialAttempts.Rate)
+	require.Equal(t, 1.0, stats.DialSuccesses.Rate)
+	require.Equal(t, 30.0, stats.ConnectedPeers)
+	require.Equal(t, []Entry{{Name: "prysm", Value: 15}, {Name: "lighthouse", Value: 12}, {Name: "unidentified", Value: 3}}, stats.PeersByClient)
+	require.Equal(t, []Entry{{Name: "dial_timeout", Value: 80, Rate: 3}, {Name: "conn_refused", Value: 10}}, stats.DialErrors)
+	require.Equal(t, 10.0, stats.Discovered.Rate)
+	require.Equal(t, "dv5", stats.DiscoverySources[0].Name)
+
+	// the counters start again from zero when the crawler restarts
+	restarted, err := ParseSnapshot(t0.Add(20*time.Second), strings.NewReader(exposition(20, 0, 0, 0)))
+	require.NoError(t, err)
+	require.Equal(t, 2.0, ComputeStats("mainnet", cur, restarted).DialAttempts.Rate)
+
+	// the crawlers without libp2p hosts only show the discovery
+	require.False(t, ComputeStats("portal", prev, cur).HasHost)
+
+	var out bytes.Buffer
+	require.NoError(t, Render(&out, &View{URL: "test", Networks: cur.Networks(), Stats: stats, Top: 1}))
+	require.Contains(t, out.String(), "[mainnet]")
+	require.Contains(t, out.String(), "prysm")
+	require.Contains(t, out.String(), "(2 others)")
+}
+
+func Test_Dashboard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/metrics", r.URL.Path)
+		fmt.Fprint(w, exposition(100, 20, 50, 200))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(strings.TrimPrefix(server.URL, "http://"), time.Second)
+	require.Equal(t, server.URL+"/metrics", scraper.URL())
+
+	// the network switches to the next one, and the dashboard quits on "q"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var out bytes.Buffer
+	dashboard := NewDashboard(scraper, "", time.Hour, DefaultTopEntries)
+	require.NoError(t, dashboard.Run(ctx, strings.NewReader("n\nq\n"), &out))
+	require.Contains(t, out.String(), "[ipfs] mainnet")
+	require.Contains(t, out.String(), "ipfs [mainnet]")
+	require.NoError(t, ctx.Err())
+}
@@ -0,0 +1,200 @@
+package apis
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+const (
+	// list of the IPs of the Tor exit nodes, refreshed by the Tor project every hour
+	torExitListURL             = "https://check.torproject.org/torbulkexitlist"
+	DefaultTorExitListInterval = 6 * time.Hour
+
+	TorSource   = "tor"
+	IpApiSource = "ip-api"
+)
+
+// AnonymizerSource tells whether an IP belongs to an anonymizing network (Tor exit, VPN endpoint, proxy)
+type AnonymizerSource interface {
+	Name() string
+	IsAnonymous(ipInfo models.IpInfo) bool
+}
+
+// AnonymizerDetector flags the located IPs that any of its sources reports, in the given order
+type AnonymizerDetector struct {
+	sources []AnonymizerSource
+}
+
+func NewAnonymizerDetector(sources ...AnonymizerSource) *AnonymizerDetector {
+	return &AnonymizerDetector{
+		sources: sources,
+	}
+}
+
+// Detect flags the IP as anonymous with the name of the first source that reports it
+func (d *AnonymizerDetector) Detect(ipInfo *models.IpInfo) {
+	ipInfo.Anonymous = false
+	ipInfo.AnonymousSource = ""
+	for _, source := range d.sources {
+		if source.IsAnonymous(*ipInfo) {
+			ipInfo.Anonymous = true
+			ipInfo.AnonymousSource = source.Name()
+			return
+		}
+	}
+}
+
+// IpApiAnonymizer reports the IPs that the IP-API flagged as proxy, VPN or Tor
+type IpApiAnonymizer struct{}
+
+func (s IpApiAnonymizer) Name() string {
+	return IpApiSource
+}
+
+func (s IpApiAnonymizer) IsAnonymous(ipInfo models.IpInfo) bool {
+	return ipInfo.Proxy
+}
+
+// IpListAnonymizer reports the IPs within the networks of a list (i.e. the Tor exit nodes or the
+// endpoints of a VPN provider)
+type IpListAnonymizer struct {
+	name string
+
+	m        sync.RWMutex
+	ips      map[string]struct{}
+	networks []*net.IPNet
+}
+
+func NewIpListAnonymizer(name string) *IpListAnonymizer {
+	return &IpListAnonymizer{
+		name: name,
+		ips:  make(map[string]struct{}),
+	}
+}
+
+// LoadIpListFile reads the list of IPs or CIDRs (one per line) of the given file
+func LoadIpListFile(name, path string) (*IpListAnonymizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open ip list file")
+	}
+	defer f.Close()
+	list := NewIpListAnonymizer(name)
+	if err := list.Load(f); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (l *IpListAnonymizer) Name() string {
+	return l.name
+}
+
+// Load replaces the list with the IPs or CIDRs (one per line, # for comments) of the reader
+func (l *IpListAnonymizer) Load(reader io.Reader) error {
+	ips := make(map[string]struct{})
+	networks := make([]*net.IPNet, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, "/") {
+			_, network, err := net.ParseCIDR(line)
+			if err != nil {
+				return errors.Wrap(err, "unable to parse "+l.name+" list")
+			}
+			networks = append(networks, network)
+			continue
+		}
+		ip := net.ParseIP(line)
+		if ip == nil {
+			return errors.Errorf("invalid ip %q in %s list", line, l.name)
+		}
+		ips[ip.String()] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "unable to read "+l.name+" list")
+	}
+	l.m.Lock()
+	l.ips = ips
+	l.networks = networks
+	l.m.Unlock()
+	return nil
+}
+
+// Len returns the number of IPs and networks of the list
+func (l *IpListAnonymizer) Len() int {
+	l.m.RLock()
+	defer l.m.RUnlock()
+	return len(l.ips) + len(l.networks)
+}
+
+func (l *IpListAnonymizer) IsAnonymous(ipInfo models.IpInfo) bool {
+	ip := net.ParseIP(ipInfo.IP)
+	if ip == nil {
+		return false
+	}
+	l.m.RLock()
+	defer l.m.RUnlock()
+	if _, ok := l.ips[ip.String()]; ok {
+		return true
+	}
+	for _, network := range l.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchTorExitList downloads the current list of Tor exit nodes
+func (l *IpListAnonymizer) FetchTorExitList(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, torExitListURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to download the tor exit list")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unable to download the tor exit list, status %d", resp.StatusCode)
+	}
+	return l.Load(resp.Body)
+}
+
+// RunTorExitList keeps the list of Tor exit nodes up to date, downloading it every interval
+// until the context dies
+func (l *IpListAnonymizer) RunTorExitList(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := l.FetchTorExitList(ctx); err != nil {
+				log.Warnf("unable to refresh the tor exit list - %s", err.Error())
+			} else {
+				log.Debugf("tor exit list refreshed with %d exits", l.Len())
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package apis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func Test_AnonymizerDetector(t *testing.T) {
+	torExits := NewIpListAnonymizer(TorSource)
+	require.NoError(t, torExits.Load(strings.NewReader("185.220.101.1\n2001:67c:e60:c0c:192:42:116:16\n")))
+	require.Error(t, torExits.Load(strings.NewReader("not an ip\n")))
+	// a failed load keeps the previous list
+	require.Equal(t, 2, torExits.Len())
+
+	path := filepath.Join(t.TempDir(), "vpn.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# vpn endpoints\n146.70.0.0/16\n"), 0644))
+	vpns, err := LoadIpListFile("vpn", path)
+	require.NoError(t, err)
+
+	detector := NewAnonymizerDetector(torExits, vpns, IpApiAnonymizer{})
+	detect := func(ip string, proxy bool) models.IpInfo {
+		ipInfo := models.IpInfo{IpApiMsg: models.IpApiMsg{IP: ip, Proxy: proxy}}
+		detector.Detect(&ipInfo)
+		return ipInfo
+	}
+
+	ipInfo := detect("185.220.101.1", true)
+	require.True(t, ipInfo.Anonymous)
+	require.Equal(t, TorSource, ipInfo.AnonymousSource)
+	require.Equal(t, TorSource, detect("2001:67c:e60:c0c:192:42:116:16", false).AnonymousSource)
+	require.Equal(t, "vpn", detect("146.70.1.2", false).AnonymousSource)
+	require.Equal(t, IpApiSource, detect("8.8.8.8", true).AnonymousSource)
+
+	ipInfo = detect("8.8.8.8", false)
+	require.False(t, ipInfo.Anonymous)
+	require.Equal(t, "", ipInfo.AnonymousSource)
+}
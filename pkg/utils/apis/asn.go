@@ -0,0 +1,43 @@
+package apis
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+// classification of the network that hosts an IP
+const (
+	HostingIpClass     = "hosting"     // data centers and cloud providers
+	MobileIpClass      = "mobile"      // cellular networks
+	ResidentialIpClass = "residential" // the rest of the access networks
+)
+
+// the AS field of IP-API is "AS<number> <org name>" (i.e. "AS16509 Amazon.com, Inc.")
+var asRegex = regexp.MustCompile(`^AS(\d+)`)
+
+// ParseASN returns the number of the autonomous system in the raw AS field, 0 if unknown
+func ParseASN(asRaw string) int {
+	match := asRegex.FindStringSubmatch(asRaw)
+	if match == nil {
+		return 0
+	}
+	asn, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+	return asn
+}
+
+// ClassifyIp returns whether the IP is hosted at a data center, a cellular network or a residential one
+func ClassifyIp(msg models.IpApiMsg) string {
+	switch {
+	case msg.Hosting:
+		return HostingIpClass
+	case msg.Mobile:
+		return MobileIpClass
+	default:
+		return ResidentialIpClass
+	}
+}
@@ -0,0 +1,22 @@
+package apis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func Test_ParseASN(t *testing.T) {
+	require.Equal(t, 16509, ParseASN("AS16509 Amazon.com, Inc."))
+	require.Equal(t, 3320, ParseASN("AS3320"))
+	require.Equal(t, 0, ParseASN(""))
+	require.Equal(t, 0, ParseASN("Amazon.com, Inc."))
+}
+
+func Test_ClassifyIp(t *testing.T) {
+	require.Equal(t, HostingIpClass, ClassifyIp(models.IpApiMsg{Hosting: true, Mobile: true}))
+	require.Equal(t, MobileIpClass, ClassifyIp(models.IpApiMsg{Mobile: true}))
+	require.Equal(t, ResidentialIpClass, ClassifyIp(models.IpApiMsg{Proxy: true}))
+}
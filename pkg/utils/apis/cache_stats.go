@@ -0,0 +1,70 @@
+package apis
+
+import (
+	"sync/atomic"
+)
+
+// cacheStats accounts the lookups of the IP cache and the calls to the providers
+type cacheStats struct {
+	hits         int64
+	misses       int64
+	expiredHits  int64
+	providerHits int64
+	apiCalls     int64
+	refreshedIps int64
+}
+
+func (s *cacheStats) hit() {
+	atomic.AddInt64(&s.hits, 1)
+}
+
+func (s *cacheStats) miss() {
+	atomic.AddInt64(&s.misses, 1)
+}
+
+func (s *cacheStats) expired() {
+	atomic.AddInt64(&s.expiredHits, 1)
+}
+
+func (s *cacheStats) providerHit() {
+	atomic.AddInt64(&s.providerHits, 1)
+}
+
+func (s *cacheStats) apiCall() {
+	atomic.AddInt64(&s.apiCalls, 1)
+}
+
+func (s *cacheStats) refreshed(n int) {
+	atomic.AddInt64(&s.refreshedIps, int64(n))
+}
+
+// IpCacheStats summarizes the lookups of the IP cache since the locator started
+type IpCacheStats struct {
+	Hits         int64 // located and not expired
+	Misses       int64 // never located
+	Expired      int64 // located, but the TTL expired
+	ProviderHits int64 // located by the offline provider
+	ApiCalls     int64
+	Refreshed    int64 // expired IPs re-located by the background refresher
+}
+
+// HitRate returns the share of lookups that didn't need to locate the IP again
+func (s IpCacheStats) HitRate() float64 {
+	lookups := s.Hits + s.Misses + s.Expired
+	if lookups == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(lookups)
+}
+
+// CacheStats returns the lookups of the IP cache since the locator started
+func (c *IpLocator) CacheStats() IpCacheStats {
+	return IpCacheStats{
+		Hits:         atomic.LoadInt64(&c.cache.hits),
+		Misses:       atomic.LoadInt64(&c.cache.misses),
+		Expired:      atomic.LoadInt64(&c.cache.expiredHits),
+		ProviderHits: atomic.LoadInt64(&c.cache.providerHits),
+		ApiCalls:     atomic.LoadInt64(&c.cache.apiCalls),
+		Refreshed:    atomic.LoadInt64(&c.cache.refreshedIps),
+	}
+}
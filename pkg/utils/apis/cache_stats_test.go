@@ -0,0 +1,93 @@
+package apis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+// testIpCache keeps the located IPs in memory, mimicking the ips table of the DB
+type testIpCache struct {
+	sync.Mutex
+	ips map[string]models.IpInfo
+}
+
+func (db *testIpCache) PersistToDB(obj interface{}) {
+	db.Lock()
+	defer db.Unlock()
+	if ipInfo, ok := obj.(models.IpInfo); ok {
+		db.ips[ipInfo.IP] = ipInfo
+	}
+}
+
+func (db *testIpCache) ReadIpInfo(ip string) (models.IpInfo, error) {
+	db.Lock()
+	defer db.Unlock()
+	return db.ips[ip], nil
+}
+
+func (db *testIpCache) CheckIpRecords(ip string) (bool, bool, error) {
+	db.Lock()
+	defer db.Unlock()
+	ipInfo, ok := db.ips[ip]
+	return ok, ok && time.Now().After(ipInfo.ExpirationTime), nil
+}
+
+func (db *testIpCache) GetExpiredIpInfo(limit int) ([]string, error) {
+	db.Lock()
+	defer db.Unlock()
+	expired := make([]string, 0)
+	for ip, ipInfo := range db.ips {
+		if len(expired) < limit && time.Now().After(ipInfo.ExpirationTime) {
+			expired = append(expired, ip)
+		}
+	}
+	return expired, nil
+}
+
+type testIpProvider struct{}
+
+func (p testIpProvider) Locate(ip string) (models.IpInfo, error) {
+	if ip != "1.2.3.4" {
+		return models.IpInfo{}, ErrMMDBNotFound
+	}
+	return models.IpInfo{IpApiMsg: models.IpApiMsg{IP: ip, Country: "Germany"}}, nil
+}
+
+func Test_IpCache(t *testing.T) {
+	db := &testIpCache{ips: make(map[string]models.IpInfo)}
+	locator := NewIpLocator(context.Background(), db, WithIpProvider(testIpProvider{}), WithIpTTL(time.Hour))
+
+	// miss, located by the provider and persisted with the TTL of the locator
+	locator.LocateIP("1.2.3.4")
+	ipInfo, _ := db.ReadIpInfo("1.2.3.4")
+	require.Equal(t, "Germany", ipInfo.Country)
+	require.WithinDuration(t, ipInfo.FetchedAt.Add(time.Hour), ipInfo.ExpirationTime, time.Second)
+
+	// hit, not located again
+	locator.LocateIP("1.2.3.4")
+
+	// expired, unknown for the provider so it is queued for the API
+	db.PersistToDB(models.IpInfo{
+		IpApiMsg:       models.IpApiMsg{IP: "5.6.7.8"},
+		ExpirationTime: time.Now().Add(-time.Minute),
+	})
+	locator.LocateIP("5.6.7.8")
+	require.Equal(t, 1, locator.ipQueue.Len())
+
+	stats := locator.CacheStats()
+	require.Equal(t, int64(1), stats.Hits)
+	require.Equal(t, int64(1), stats.Misses)
+	require.Equal(t, int64(1), stats.Expired)
+	require.Equal(t, int64(1), stats.ProviderHits)
+	require.InDelta(t, 1.0/3.0, stats.HitRate(), 1e-9)
+
+	expired, err := db.GetExpiredIpInfo(ipRefreshBatch)
+	require.NoError(t, err)
+	require.Equal(t, []string{"5.6.7.8"}, expired)
+}
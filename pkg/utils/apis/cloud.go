@@ -0,0 +1,256 @@
+package apis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+// cloud providers the IPs are tagged with
+const (
+	AWSProvider     = "aws"
+	GCPProvider     = "gcp"
+	AzureProvider   = "azure"
+	OVHProvider     = "ovh"
+	HetznerProvider = "hetzner"
+)
+
+const (
+	// published IP ranges of the providers that have a stable URL for them
+	awsRangesURL = "https://ip-ranges.amazonaws.com/ip-ranges.json"
+	gcpRangesURL = "https://www.gstatic.com/ipranges/cloud.json"
+
+	rdnsTimeout = 2 * time.Second
+)
+
+var (
+	// autonomous systems of the providers, used when the IP isn't in any of the published ranges
+	cloudASNs = map[int]string{
+		16509:  AWSProvider,
+		14618:  AWSProvider,
+		15169:  GCPProvider,
+		396982: GCPProvider,
+		8075:   AzureProvider,
+		16276:  OVHProvider,
+		24940:  HetznerProvider,
+		213230: HetznerProvider,
+	}
+	// domains of the PTR records that the providers assign to their IPs
+	cloudRdnsSuffixes = map[string]string{
+		".amazonaws.com":         AWSProvider,
+		".googleusercontent.com": GCPProvider,
+		".cloudapp.azure.com":    AzureProvider,
+		".cloudapp.net":          AzureProvider,
+		".ovh.net":               OVHProvider,
+		".ovh.ca":                OVHProvider,
+		".your-server.de":        HetznerProvider,
+		".hetzner.com":           HetznerProvider,
+	}
+)
+
+type cloudRange struct {
+	network  *net.IPNet
+	provider string
+}
+
+// CloudRanges matches the IPs against the ranges published by the cloud providers
+type CloudRanges struct {
+	ranges []cloudRange
+}
+
+func NewCloudRanges() *CloudRanges {
+	return &CloudRanges{
+		ranges: make([]cloudRange, 0),
+	}
+}
+
+// AddRange adds the CIDR to the ranges of the provider
+func (r *CloudRanges) AddRange(provider, cidr string) error {
+	_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return errors.Wrap(err, "unable to parse cloud range")
+	}
+	r.ranges = append(r.ranges, cloudRange{network: network, provider: provider})
+	return nil
+}
+
+// Len returns the number of ranges loaded
+func (r *CloudRanges) Len() int {
+	return len(r.ranges)
+}
+
+// Match returns the provider of the most specific range that contains the IP, empty if none
+func (r *CloudRanges) Match(ip net.IP) string {
+	provider, bestOnes := "", -1
+	for _, cr := range r.ranges {
+		if !cr.network.Contains(ip) {
+			continue
+		}
+		if ones, _ := cr.network.Mask.Size(); ones > bestOnes {
+			provider, bestOnes = cr.provider, ones
+		}
+	}
+	return provider
+}
+
+// LoadAWSRanges adds the prefixes of the ip-ranges.json published by AWS
+func (r *CloudRanges) LoadAWSRanges(reader io.Reader) error {
+	var published struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.NewDecoder(reader).Decode(&published); err != nil {
+		return errors.Wrap(err, "unable to decode aws ranges")
+	}
+	for _, p := range published.Prefixes {
+		if err := r.AddRange(AWSProvider, p.IPPrefix); err != nil {
+			return err
+		}
+	}
+	for _, p := range published.IPv6Prefixes {
+		if err := r.AddRange(AWSProvider, p.IPv6Prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadGCPRanges adds the prefixes of the cloud.json published by Google Cloud
+func (r *CloudRanges) LoadGCPRanges(reader io.Reader) error {
+	var published struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+		} `json:"prefixes"`
+	}
+	if err := json.NewDecoder(reader).Decode(&published); err != nil {
+		return errors.Wrap(err, "unable to decode gcp ranges")
+	}
+	for _, p := range published.Prefixes {
+		cidr := p.IPv4Prefix
+		if cidr == "" {
+			cidr = p.IPv6Prefix
+		}
+		if err := r.AddRange(GCPProvider, cidr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadRangesFile adds the ranges of a file with a "<provider> <cidr>" line per range (i.e. to
+// add the Azure service tags or the OVH and Hetzner networks, which aren't published at a stable URL)
+func (r *CloudRanges) LoadRangesFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to open cloud ranges file")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return errors.Errorf("invalid cloud range line %q", line)
+		}
+		if err := r.AddRange(strings.ToLower(fields[0]), fields[1]); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// FetchPublishedRanges downloads the ranges published by AWS and Google Cloud
+func (r *CloudRanges) FetchPublishedRanges(ctx context.Context) error {
+	fetch := func(url string, load func(io.Reader) error) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "unable to download cloud ranges from "+url)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return errors.Errorf("unable to download cloud ranges from %s, status %d", url, resp.StatusCode)
+		}
+		return load(resp.Body)
+	}
+	if err := fetch(awsRangesURL, r.LoadAWSRanges); err != nil {
+		return err
+	}
+	return fetch(gcpRangesURL, r.LoadGCPRanges)
+}
+
+// CloudTagger tags the located IPs with their reverse DNS name and the cloud provider hosting them
+type CloudTagger struct {
+	ranges *CloudRanges
+	rdns   bool
+	// resolves the PTR records of the IP (net.DefaultResolver unless testing)
+	lookupAddr func(ctx context.Context, ip string) ([]string, error)
+}
+
+// NewCloudTagger returns a tagger that matches the IPs against the given ranges (if any) and the
+// ASNs of the providers, and that resolves their PTR records if rdns is true
+func NewCloudTagger(ranges *CloudRanges, rdns bool) *CloudTagger {
+	if ranges == nil {
+		ranges = NewCloudRanges()
+	}
+	return &CloudTagger{
+		ranges:     ranges,
+		rdns:       rdns,
+		lookupAddr: net.DefaultResolver.LookupAddr,
+	}
+}
+
+// Tag fills the reverse DNS name and the cloud provider of the IP. The published ranges take
+// precedence over the PTR record, and the PTR record over the ASN
+func (t *CloudTagger) Tag(ipInfo *models.IpInfo) {
+	if t.rdns {
+		ctx, cancel := context.WithTimeout(context.Background(), rdnsTimeout)
+		names, err := t.lookupAddr(ctx, ipInfo.IP)
+		cancel()
+		if err != nil {
+			log.Tracef("unable to resolve the ptr of %s - %s", ipInfo.IP, err.Error())
+		} else if len(names) > 0 {
+			ipInfo.Rdns = strings.TrimSuffix(names[0], ".")
+		}
+	}
+
+	if ip := net.ParseIP(ipInfo.IP); ip != nil {
+		if provider := t.ranges.Match(ip); provider != "" {
+			ipInfo.CloudProvider = provider
+			return
+		}
+	}
+	if ipInfo.Rdns != "" {
+		name := strings.ToLower(ipInfo.Rdns)
+		for suffix, provider := range cloudRdnsSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				ipInfo.CloudProvider = provider
+				return
+			}
+		}
+	}
+	ipInfo.CloudProvider = cloudASNs[ipInfo.ASN]
+}
@@ -0,0 +1,68 @@
+package apis
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+func Test_CloudRanges(t *testing.T) {
+	ranges := NewCloudRanges()
+	require.NoError(t, ranges.LoadAWSRanges(strings.NewReader(`{
+		"prefixes": [{"ip_prefix": "3.0.0.0/8", "region": "us-east-1"}],
+		"ipv6_prefixes": [{"ipv6_prefix": "2600:1f00::/24"}]
+	}`)))
+	require.NoError(t, ranges.LoadGCPRanges(strings.NewReader(`{
+		"prefixes": [{"ipv4Prefix": "34.64.0.0/10"}, {"ipv6Prefix": "2600:1900::/28"}]
+	}`)))
+	path := filepath.Join(t.TempDir(), "ranges.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# extra ranges\nHetzner 3.1.0.0/16\n"), 0644))
+	require.NoError(t, ranges.LoadRangesFile(path))
+	require.Equal(t, 5, ranges.Len())
+
+	require.Equal(t, AWSProvider, ranges.Match(net.ParseIP("3.2.3.4")))
+	require.Equal(t, AWSProvider, ranges.Match(net.ParseIP("2600:1f00::1")))
+	require.Equal(t, GCPProvider, ranges.Match(net.ParseIP("34.65.0.1")))
+	// the most specific range wins
+	require.Equal(t, HetznerProvider, ranges.Match(net.ParseIP("3.1.2.3")))
+	require.Equal(t, "", ranges.Match(net.ParseIP("8.8.8.8")))
+
+	require.Error(t, ranges.LoadAWSRanges(strings.NewReader(`{"prefixes": [{"ip_prefix": "invalid"}]}`)))
+}
+
+func Test_CloudTagger(t *testing.T) {
+	ranges := NewCloudRanges()
+	require.NoError(t, ranges.AddRange(AWSProvider, "3.0.0.0/8"))
+	tagger := NewCloudTagger(ranges, true)
+	tagger.lookupAddr = func(ctx context.Context, ip string) ([]string, error) {
+		if ip == "5.9.1.1" {
+			return []string{"static.1.1.9.5.clients.your-server.de."}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: ip, IsNotFound: true}
+	}
+
+	tag := func(ip string, asn int) models.IpInfo {
+		ipInfo := models.IpInfo{IpApiMsg: models.IpApiMsg{IP: ip}, ASN: asn}
+		tagger.Tag(&ipInfo)
+		return ipInfo
+	}
+	// published ranges
+	require.Equal(t, AWSProvider, tag("3.2.3.4", 0).CloudProvider)
+	// rdns name
+	ipInfo := tag("5.9.1.1", 0)
+	require.Equal(t, "static.1.1.9.5.clients.your-server.de", ipInfo.Rdns)
+	require.Equal(t, HetznerProvider, ipInfo.CloudProvider)
+	// asn
+	require.Equal(t, OVHProvider, tag("51.1.1.1", 16276).CloudProvider)
+	// residential
+	ipInfo = tag("80.1.1.1", 3320)
+	require.Equal(t, "", ipInfo.Rdns)
+	require.Equal(t, "", ipInfo.CloudProvider)
+}
@@ -0,0 +1,41 @@
+package apis
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	earthRadiusKm = 6371.0
+	// light travels at ~2/3 c through the fiber, ~200 km per ms
+	fiberKmPerMs = 200.0
+	// error margin of the location of an IP (IP-API resolves at city level in the best case)
+	geoAccuracyKm = 500.0
+)
+
+// GeoDistance returns the great-circle distance in km between two coordinates
+func GeoDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// MinRTT returns the lowest RTT physically possible to a host at the given distance
+func MinRTT(distanceKm float64) time.Duration {
+	distanceKm = math.Max(0, distanceKm-geoAccuracyKm)
+	return time.Duration(2 * distanceKm / fiberKmPerMs * float64(time.Millisecond))
+}
+
+// GeoConfidence returns how plausible is a location at the given distance for the measured RTT:
+// 1 if the RTT is compatible with the distance, or the ratio between the measured and the
+// minimum possible RTT otherwise (e.g. 5ms to a host located 15000km away gives ~0.03)
+func GeoConfidence(distanceKm float64, rtt time.Duration) float64 {
+	minRTT := MinRTT(distanceKm)
+	if rtt >= minRTT {
+		return 1
+	}
+	return float64(rtt) / float64(minRTT)
+}
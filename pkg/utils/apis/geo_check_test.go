@@ -0,0 +1,20 @@
+package apis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GeoConfidence(t *testing.T) {
+	// Frankfurt -> Sydney
+	distance := GeoDistance(50.11, 8.68, -33.87, 151.21)
+	require.InDelta(t, 16500, distance, 100)
+	require.Less(t, GeoConfidence(distance, 5*time.Millisecond), 0.1)
+	require.Equal(t, 1.0, GeoConfidence(distance, 290*time.Millisecond))
+
+	// Frankfurt -> Amsterdam (within the error margin of the location)
+	distance = GeoDistance(50.11, 8.68, 52.37, 4.90)
+	require.Equal(t, 1.0, GeoConfidence(distance, 1*time.Millisecond))
+}
@@ -9,7 +9,6 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/migalabs/armiarma/pkg/db/models"
@@ -23,6 +22,10 @@ const (
 	ipBuffSize     = 8192                // number of ip queries that can be queued in the ipQueue
 	ipApiEndpoint  = "http://ip-api.com/json/{__ip__}?fields=status,continent,continentCode,country,countryCode,region,regionName,city,zip,lat,lon,isp,org,as,asname,mobile,proxy,hosting,query"
 	minIterTime    = 100 * time.Millisecond
+
+	// expired IPs re-located per refresh, and how often they are
+	ipRefreshBatch           = 1000
+	defaultIpRefreshInterval = 1 * time.Hour
 )
 
 var TooManyRequestError error = fmt.Errorf("error HTTP 429")
@@ -32,7 +35,7 @@ type DBWriter interface {
 	PersistToDB(interface{})
 	ReadIpInfo(string) (models.IpInfo, error)
 	CheckIpRecords(string) (bool, bool, error)
-	GetExpiredIpInfo() ([]string, error)
+	GetExpiredIpInfo(limit int) ([]string, error)
 }
 
 // PEER LOCALIZER
@@ -44,27 +47,46 @@ type IpLocator struct {
 	// dbClient
 	dbClient DBWriter
 
+	// offline provider consulted before the HTTP API (nil if there is none)
+	provider IpProvider
+	// tags the IPs with their rdns and cloud provider (nil if disabled)
+	tagger *CloudTagger
+	// flags the IPs of Tor exits, VPNs and proxies (nil if disabled)
+	anonymizers *AnonymizerDetector
+
 	ipQueue *ipQueue
-	// control variables for IP-API request
-	// Control flags from prometheus
-	apiCalls *int32
+	// TTL of the located IPs in the cache (the ips table), and how often the expired ones are re-located
+	ttl             time.Duration
+	refreshInterval time.Duration
+	// lookups of the cache and calls to the providers
+	cache *cacheStats
+
+	// location of the crawler, used to sanity check the location of the IPs against their RTT
+	vantageM sync.RWMutex
+	vantage  *models.IpInfo
 }
 
-func NewIpLocator(ctx context.Context, dbCli DBWriter) *IpLocator {
-	calls := int32(0)
-	return &IpLocator{
+func NewIpLocator(ctx context.Context, dbCli DBWriter, opts ...IpLocatorOption) *IpLocator {
+	locator := &IpLocator{
 		ctx:             ctx,
 		locationRequest: make(chan string, ipChanBuffSize),
 		dbClient:        dbCli,
-		apiCalls:        &calls,
+		ttl:             defaultIpTTL,
+		refreshInterval: defaultIpRefreshInterval,
+		cache:           &cacheStats{},
 		ipQueue:         newIpQueue(ipBuffSize),
 	}
+	for _, opt := range opts {
+		opt(locator)
+	}
+	return locator
 }
 
 // Run the necessary routines to locate the IPs
 func (c *IpLocator) Run() {
 	//l.SetLevel(Logrus.TraceLevel)
 	c.locatorRoutine()
+	go c.refreshRoutine()
 }
 
 // locatorRoutine is the main routine that will wait until an request to identify an IP arrives
@@ -93,6 +115,8 @@ func (c *IpLocator) locatorRoutine() {
 	// ip locating routien
 	go func() {
 		var nextDelayRequest time.Duration
+		// locate the crawler itself before any other IP
+		nextDelayRequest = c.locateVantagePoint()
 		for {
 			select {
 			// New request to identify an IP
@@ -103,7 +127,7 @@ func (c *IpLocator) locatorRoutine() {
 					// since it didn't exist or did expire, request the ip
 					// new API call needs to be done
 					log.Tracef(" making API call for %s", reqIp)
-					atomic.AddInt32(c.apiCalls, 1)
+					c.cache.apiCall()
 					respC := c.locateIp(reqIp)
 					select {
 					case apiResp := <-respC:
@@ -129,7 +153,7 @@ func (c *IpLocator) locatorRoutine() {
 							// if the error is different from TooManyRequestError break loop and store the request
 							log.Debugf("call %s-> api req success", reqIp)
 							// Upsert the IP into the db
-							c.dbClient.PersistToDB(apiResp.IpInfo)
+							c.persist(apiResp.IpInfo)
 							break reqLoop
 
 						default:
@@ -179,24 +203,163 @@ func (c *IpLocator) LocateIP(ip string) {
 	if err != nil {
 		log.Error("unable to check if IP already exists -", err.Error()) // Should it be a Panic?
 	}
-	// if exists and it didn't expired, don't do anything
-	if exists && !expired {
+	switch {
+	case exists && !expired:
+		// if exists and it didn't expired, don't do anything
+		c.cache.hit()
 		return
+	case exists:
+		c.cache.expired()
+	default:
+		c.cache.miss()
 	}
 
 	// since it didn't exist or it is expired, locate it again
+	c.relocate(ip, true)
+}
+
+// relocate locates the IP with the offline provider, or queues it for the API. If wait is false,
+// it returns false instead of waiting when the queue is full
+func (c *IpLocator) relocate(ip string, wait bool) bool {
+	// the offline provider doesn't have rate limits, only the IPs it doesn't know are queued for the API
+	if c.provider != nil {
+		ipInfo, err := c.provider.Locate(ip)
+		switch err {
+		case nil:
+			c.cache.providerHit()
+			c.persist(ipInfo)
+			return true
+		case ErrMMDBNotFound:
+		default:
+			log.Debugf("unable to locate %s with the offline provider - %s", ip, err.Error())
+		}
+	}
+
 	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
 	// wait 1 sec because is the normal time to wait untill we can start querying again
 	for {
 		err := c.ipQueue.addItem(ip)
 		if err == nil {
-			break
+			return true
+		}
+		if !wait {
+			return false
 		}
 		<-ticker.C
 		ticker.Reset(1 * time.Second)
 		log.Debug("waiting to alocate a new IP request")
 	}
-	ticker.Stop()
+}
+
+// persist stores the located IP in the cache with the TTL of the locator
+func (c *IpLocator) persist(ipInfo models.IpInfo) {
+	ipInfo.FetchedAt = time.Now().UTC()
+	ipInfo.ExpirationTime = ipInfo.FetchedAt.Add(c.ttl)
+	if c.tagger != nil {
+		c.tagger.Tag(&ipInfo)
+	}
+	if c.anonymizers != nil {
+		c.anonymizers.Detect(&ipInfo)
+	}
+	c.dbClient.PersistToDB(ipInfo)
+}
+
+// refreshRoutine periodically re-locates the cached IPs whose TTL expired, so that their info
+// is already fresh when the peers connect again
+func (c *IpLocator) refreshRoutine() {
+	if c.refreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			expired, err := c.dbClient.GetExpiredIpInfo(ipRefreshBatch)
+			if err != nil {
+				log.Warnf("unable to fetch the expired ips - %s", err.Error())
+				continue
+			}
+			refreshed := 0
+			for _, ip := range expired {
+				if c.ipQueue.ipExists(ip) {
+					continue
+				}
+				// leave room in the queue for the IPs of the new connections
+				if !c.relocate(ip, false) {
+					break
+				}
+				refreshed++
+			}
+			c.cache.refreshed(refreshed)
+			log.Debugf("refreshing %d expired ips", refreshed)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// locateVantagePoint locates the public IP of the crawler, returning the delay to respect before the next request
+func (c *IpLocator) locateVantagePoint() time.Duration {
+	c.cache.apiCall()
+	// IP-API locates the IP of the requester if no IP is given
+	ipInfo, delay, _, err := CallIpApi("")
+	if err != nil {
+		log.Warnf("unable to locate the vantage point, geolocation sanity checks disabled - %s", err.Error())
+		return delay
+	}
+	log.WithFields(log.Fields{
+		"country": ipInfo.Country,
+		"city":    ipInfo.City,
+	}).Info("located the vantage point of the crawler")
+	c.vantageM.Lock()
+	c.vantage = &ipInfo
+	c.vantageM.Unlock()
+	return delay
+}
+
+// CheckRTT cross-checks the location of an already located IP with the RTT measured to it,
+// flagging the location as implausible if it is too far away for such RTT
+// (e.g. 5ms to an IP located in Australia from Europe)
+func (c *IpLocator) CheckRTT(ip string, rtt time.Duration) {
+	c.vantageM.RLock()
+	vantage := c.vantage
+	c.vantageM.RUnlock()
+	if vantage == nil || rtt <= 0 {
+		return
+	}
+	ipInfo, err := c.dbClient.ReadIpInfo(ip)
+	if err != nil || ipInfo.IsEmpty() {
+		// not located yet
+		return
+	}
+	// only the lowest RTT gives any guarantee about the distance
+	if ipInfo.MinRTT > 0 && ipInfo.MinRTT <= rtt {
+		return
+	}
+	distance := GeoDistance(vantage.Lat, vantage.Lon, ipInfo.Lat, ipInfo.Lon)
+	confidence := GeoConfidence(distance, rtt)
+	geoCheck := models.IpGeoCheck{
+		IP:          ip,
+		MinRTT:      rtt,
+		Confidence:  confidence,
+		Implausible: confidence < 1,
+	}
+	if geoCheck.Implausible {
+		log.WithFields(log.Fields{
+			"ip":       ip,
+			"country":  ipInfo.Country,
+			"distance": int(distance),
+			"rtt":      rtt,
+		}).Debug("implausible location for the measured rtt")
+	}
+	c.dbClient.PersistToDB(geoCheck)
+}
+
+// GetIpInfo returns the information of an already located IP
+func (c *IpLocator) GetIpInfo(ip string) (models.IpInfo, error) {
+	return c.dbClient.ReadIpInfo(ip)
 }
 
 func (c *IpLocator) Close() {
@@ -268,8 +431,11 @@ func CallIpApi(ip string) (ipInfo models.IpInfo, delay time.Duration, attemptsLe
 		return
 	}
 
-	ipInfo.ExpirationTime = time.Now().UTC().Add(defaultIpTTL)
+	ipInfo.FetchedAt = time.Now().UTC()
+	ipInfo.ExpirationTime = ipInfo.FetchedAt.Add(defaultIpTTL)
 	ipInfo.IpApiMsg = apiMsg
+	ipInfo.ASN = ParseASN(apiMsg.As)
+	ipInfo.IpClass = ClassifyIp(apiMsg)
 	return
 }
 
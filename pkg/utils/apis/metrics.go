@@ -0,0 +1,92 @@
+package apis
+
+import (
+	"github.com/migalabs/armiarma/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	moduleName    = "ip_locator"
+	moduleDetails = "lookups of the IP cache and calls to the IP providers"
+)
+
+// locatorMetrics are the collectors of the metrics of the IP locator, one set per crawler
+type locatorMetrics struct {
+	IpCacheLookups  *prometheus.GaugeVec
+	IpCacheHitRate  prometheus.Gauge
+	IpProviderCalls *prometheus.GaugeVec
+}
+
+func newLocatorMetrics() *locatorMetrics {
+	return &locatorMetrics{
+		IpCacheLookups: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "cache_lookups",
+			Help:      "Lookups of the IP cache since the start per result (hit, miss, expired)",
+		},
+			[]string{"result"},
+		),
+		IpCacheHitRate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "cache_hit_rate",
+			Help:      "Share of the lookups of the IP cache that didn't need to locate the IP again",
+		}),
+		IpProviderCalls: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: moduleName,
+			Name:      "provider_calls",
+			Help:      "IPs located by each provider (offline, api) and re-located by the refresher since the start",
+		},
+			[]string{"provider"},
+		),
+	}
+}
+
+func (c *IpLocator) GetMetrics() *metrics.MetricsModule {
+	metricsMod := metrics.NewMetricsModule(
+		moduleName,
+		moduleDetails,
+	)
+	m := newLocatorMetrics()
+	metricsMod.AddIndvMetric(c.cacheLookups(m))
+	return metricsMod
+}
+
+func (c *IpLocator) cacheLookups(m *locatorMetrics) *metrics.IndvMetrics {
+	initFn := func(reg prometheus.Registerer) error {
+		reg.MustRegister(m.IpCacheLookups)
+		reg.MustRegister(m.IpCacheHitRate)
+		reg.MustRegister(m.IpProviderCalls)
+		return nil
+	}
+	updateFn := func() (interface{}, error) {
+		stats := c.CacheStats()
+		m.IpCacheLookups.WithLabelValues("hit").Set(float64(stats.Hits))
+		m.IpCacheLookups.WithLabelValues("miss").Set(float64(stats.Misses))
+		m.IpCacheLookups.WithLabelValues("expired").Set(float64(stats.Expired))
+		m.IpCacheHitRate.Set(stats.HitRate())
+		m.IpProviderCalls.WithLabelValues("offline").Set(float64(stats.ProviderHits))
+		m.IpProviderCalls.WithLabelValues("api").Set(float64(stats.ApiCalls))
+		m.IpProviderCalls.WithLabelValues("refresher").Set(float64(stats.Refreshed))
+		summary := map[string]interface{}{
+			"hits":          stats.Hits,
+			"misses":        stats.Misses,
+			"expired":       stats.Expired,
+			"hit_rate":      stats.HitRate(),
+			"provider_hits": stats.ProviderHits,
+			"api_calls":     stats.ApiCalls,
+			"refreshed":     stats.Refreshed,
+		}
+		return summary, nil
+	}
+	lookups, err := metrics.NewIndvMetrics(
+		"ip_cache_lookups",
+		initFn,
+		updateFn,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return lookups
+}
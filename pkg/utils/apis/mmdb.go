@@ -0,0 +1,361 @@
+package apis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Minimal reader of the MaxMind DB format (https://maxmind.github.io/MaxMind-DB/), enough to
+// look up the records of the GeoLite2 City and ASN databases without any external dependency
+
+var (
+	mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+	// the metadata is at most 128KiB away from the end of the file
+	mmdbMetadataMaxSize = 128 * 1024
+	// zeroed bytes between the search tree and the data section
+	mmdbDataSectionSeparator = 16
+
+	ErrMMDBNotFound = errors.New("ip not found in the mmdb")
+)
+
+// data types of the MMDB data section
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// MMDBReader looks up the records of the IPs in a MaxMind DB loaded in memory
+type MMDBReader struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	dbType     string
+	// node where the IPv4 addresses start in an IPv6 tree (::/96)
+	ipv4Start uint
+}
+
+// OpenMMDB reads the whole MaxMind DB file into memory
+func OpenMMDB(path string) (*MMDBReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read mmdb file")
+	}
+	return NewMMDBReader(buf)
+}
+
+// NewMMDBReader parses the metadata of the given MaxMind DB
+func NewMMDBReader(buf []byte) (*MMDBReader, error) {
+	searchFrom := 0
+	if len(buf) > mmdbMetadataMaxSize {
+		searchFrom = len(buf) - mmdbMetadataMaxSize
+	}
+	markerIdx := bytes.LastIndex(buf[searchFrom:], mmdbMetadataMarker)
+	if markerIdx < 0 {
+		return nil, errors.New("invalid mmdb, metadata marker not found")
+	}
+	metaStart := searchFrom + markerIdx + len(mmdbMetadataMarker)
+	metaDec := &mmdbDecoder{buf: buf[metaStart:]}
+	rawMeta, _, err := metaDec.decode(0)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode mmdb metadata")
+	}
+	meta, ok := rawMeta.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid mmdb metadata")
+	}
+
+	r := &MMDBReader{buf: buf}
+	r.nodeCount = uint(mmdbUint(meta["node_count"]))
+	r.recordSize = uint(mmdbUint(meta["record_size"]))
+	r.ipVersion = uint(mmdbUint(meta["ip_version"]))
+	r.dbType, _ = meta["database_type"].(string)
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, errors.Errorf("unsupported mmdb record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, errors.Errorf("unsupported mmdb ip version %d", r.ipVersion)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	dataStart := int(treeSize) + mmdbDataSectionSeparator
+	if dataStart > searchFrom+markerIdx {
+		return nil, errors.New("invalid mmdb, search tree exceeds the file size")
+	}
+	r.data = buf[dataStart : searchFrom+markerIdx]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// DatabaseType returns the type of the database (i.e. GeoLite2-City, GeoLite2-ASN)
+func (r *MMDBReader) DatabaseType() string {
+	return r.dbType
+}
+
+// Lookup returns the decoded record of the network that contains the IP
+func (r *MMDBReader) Lookup(ip net.IP) (map[string]interface{}, error) {
+	node, bits := uint(0), 0
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		node = r.ipv4Start
+		bits = 32
+	} else {
+		if r.ipVersion == 4 {
+			return nil, errors.New("ipv6 lookup in an ipv4 mmdb")
+		}
+		ip = ip.To16()
+		if ip == nil {
+			return nil, errors.New("invalid ip")
+		}
+		bits = 128
+	}
+
+	for i := 0; i < bits && node < r.nodeCount; i++ {
+		bit := uint(ip[i>>3]>>(7-uint(i&7))) & 1
+		node = r.readNode(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, ErrMMDBNotFound
+	}
+	if node < r.nodeCount {
+		return nil, errors.New("invalid mmdb, ip bits exhausted in the search tree")
+	}
+	offset := int(node-r.nodeCount) - mmdbDataSectionSeparator
+	if offset < 0 || offset >= len(r.data) {
+		return nil, errors.New("invalid mmdb, data pointer out of bounds")
+	}
+	dec := &mmdbDecoder{buf: r.data}
+	value, _, err := dec.decode(offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode mmdb record")
+	}
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("mmdb record isn't a map")
+	}
+	return record, nil
+}
+
+// readNode returns the left (0) or right (1) record of the node
+func (r *MMDBReader) readNode(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.buf[node*6:]
+		if bit == 0 {
+			return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5])
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		b := r.buf[node*8:]
+		if bit == 0 {
+			return uint(binary.BigEndian.Uint32(b[0:4]))
+		}
+		return uint(binary.BigEndian.Uint32(b[4:8]))
+	}
+}
+
+type mmdbDecoder struct {
+	buf []byte
+}
+
+// decode returns the value at the given offset and the offset right after it
+func (d *mmdbDecoder) decode(offset int) (interface{}, int, error) {
+	if offset >= len(d.buf) {
+		return nil, 0, errors.New("offset out of bounds")
+	}
+	ctrl := d.buf[offset]
+	offset++
+	kind := int(ctrl >> 5)
+
+	if kind == mmdbPointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+	if kind == mmdbExtended {
+		if offset >= len(d.buf) {
+			return nil, 0, errors.New("offset out of bounds")
+		}
+		kind = 7 + int(d.buf[offset])
+		offset++
+	}
+	size := int(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > len(d.buf) {
+			return nil, 0, errors.New("offset out of bounds")
+		}
+		ext := 0
+		for _, b := range d.buf[offset : offset+n] {
+			ext = ext<<8 | int(b)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + ext
+		case 30:
+			size = 285 + ext
+		default:
+			size = 65821 + ext
+		}
+	}
+
+	switch kind {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for i := 0; i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("mmdb map key isn't a string")
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[keyStr] = value
+			offset = next
+		}
+		return m, offset, nil
+	case mmdbArray:
+		a := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	case mmdbContainer, mmdbEndMarker:
+		return nil, offset, nil
+	}
+
+	if offset+size > len(d.buf) {
+		return nil, 0, errors.New("offset out of bounds")
+	}
+	raw := d.buf[offset : offset+size]
+	offset += size
+	switch kind {
+	case mmdbString:
+		return string(raw), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.Errorf("invalid mmdb double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.Errorf("invalid mmdb float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), offset, nil
+	case mmdbBytes, mmdbUint128:
+		return append([]byte{}, raw...), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		var v uint64
+		for _, b := range raw {
+			v = v<<8 | uint64(b)
+		}
+		return v, offset, nil
+	case mmdbInt32:
+		var v uint32
+		for _, b := range raw {
+			v = v<<8 | uint32(b)
+		}
+		return int64(int32(v)), offset, nil
+	default:
+		return nil, 0, errors.Errorf("unknown mmdb data type %d", kind)
+	}
+}
+
+// pointer returns the offset the pointer points to and the offset right after the pointer
+func (d *mmdbDecoder) pointer(ctrl byte, offset int) (int, int, error) {
+	size := int((ctrl>>3)&0x3) + 1
+	if offset+size > len(d.buf) {
+		return 0, 0, errors.New("offset out of bounds")
+	}
+	raw := d.buf[offset : offset+size]
+	var pointer int
+	switch size {
+	case 1:
+		pointer = int(ctrl&0x7)<<8 | int(raw[0])
+	case 2:
+		pointer = (int(ctrl&0x7)<<16 | int(raw[0])<<8 | int(raw[1])) + 2048
+	case 3:
+		pointer = (int(ctrl&0x7)<<24 | int(raw[0])<<16 | int(raw[1])<<8 | int(raw[2])) + 526336
+	default:
+		pointer = int(binary.BigEndian.Uint32(raw))
+	}
+	return pointer, offset + size, nil
+}
+
+// mmdbUint returns the unsigned value of a decoded field, 0 if missing
+func mmdbUint(v interface{}) uint64 {
+	u, _ := v.(uint64)
+	return u
+}
+
+// mmdbPath walks the nested maps (and arrays, by index) of a decoded record
+func mmdbPath(record map[string]interface{}, path ...interface{}) interface{} {
+	var current interface{} = record
+	for _, step := range path {
+		switch key := step.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = m[key]
+		case int:
+			a, ok := current.([]interface{})
+			if !ok || key >= len(a) {
+				return nil
+			}
+			current = a[key]
+		}
+	}
+	return current
+}
@@ -0,0 +1,176 @@
+package apis
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mmdbEncode encodes the value in the MMDB data section format (without pointers)
+func mmdbEncode(v interface{}) []byte {
+	ctrl := func(kind, size int) []byte {
+		var head []byte
+		extended := kind > 7
+		t := kind
+		if extended {
+			t = 0
+		}
+		switch {
+		case size < 29:
+			head = []byte{byte(t<<5 | size)}
+		case size < 285:
+			head = []byte{byte(t<<5 | 29), byte(size - 29)}
+		default:
+			head = []byte{byte(t<<5 | 30), byte((size - 285) >> 8), byte(size - 285)}
+		}
+		if extended {
+			head = append(head[:1], append([]byte{byte(kind - 7)}, head[1:]...)...)
+		}
+		return head
+	}
+	switch val := v.(type) {
+	case string:
+		return append(ctrl(mmdbString, len(val)), val...)
+	case float64:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(val))
+		return append(ctrl(mmdbDouble, 8), b...)
+	case uint32:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, val)
+		return append(ctrl(mmdbUint32, 4), b...)
+	case uint16:
+		return append(ctrl(mmdbUint16, 2), byte(val>>8), byte(val))
+	case []interface{}:
+		out := ctrl(mmdbArray, len(val))
+		for _, item := range val {
+			out = append(out, mmdbEncode(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := ctrl(mmdbMap, len(val))
+		for _, k := range keys {
+			out = append(out, mmdbEncode(k)...)
+			out = append(out, mmdbEncode(val[k])...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// buildMMDB composes a database with 24 bit records where only the given network has a record
+func buildMMDB(ipVersion uint16, network *net.IPNet, record map[string]interface{}) []byte {
+	ones, _ := network.Mask.Size()
+	ip := network.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ipVersion == 6 {
+			// the IPv4 addresses are stored at ::/96 in the IPv6 trees
+			ip = append(make(net.IP, 12), ip4...)
+			ones += 96
+		}
+	}
+	nodeCount := ones
+	data := mmdbEncode(record)
+	dataRecord := nodeCount + mmdbDataSectionSeparator
+
+	tree := make([]byte, 0, nodeCount*6)
+	for i := 0; i < nodeCount; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		next := i + 1
+		if i == nodeCount-1 {
+			next = dataRecord
+		}
+		left, right := nodeCount, nodeCount
+		if bit == 0 {
+			left = next
+		} else {
+			right = next
+		}
+		tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+	}
+
+	buf := append(tree, make([]byte, mmdbDataSectionSeparator)...)
+	buf = append(buf, data...)
+	buf = append(buf, mmdbMetadataMarker...)
+	buf = append(buf, mmdbEncode(map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(24),
+		"ip_version":    ipVersion,
+		"database_type": "GeoLite2-City",
+		"languages":     []interface{}{"en"},
+	})...)
+	return buf
+}
+
+func Test_MMDBLookup(t *testing.T) {
+	_, network, _ := net.ParseCIDR("1.2.3.0/24")
+	record := map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "DE",
+			"names":    map[string]interface{}{"en": "Germany"},
+		},
+		"location": map[string]interface{}{
+			"latitude":  50.11,
+			"longitude": 8.68,
+		},
+	}
+	for _, version := range []uint16{4, 6} {
+		reader, err := NewMMDBReader(buildMMDB(version, network, record))
+		require.NoError(t, err)
+		require.Equal(t, "GeoLite2-City", reader.DatabaseType())
+
+		found, err := reader.Lookup(net.ParseIP("1.2.3.4"))
+		require.NoError(t, err)
+		require.Equal(t, "DE", mmdbPath(found, "country", "iso_code"))
+		require.Equal(t, 8.68, mmdbPath(found, "location", "longitude"))
+
+		_, err = reader.Lookup(net.ParseIP("1.2.4.4"))
+		require.Equal(t, ErrMMDBNotFound, err)
+	}
+
+	_, err := NewMMDBReader([]byte("not a mmdb"))
+	require.Error(t, err)
+}
+
+func Test_MMDBProvider(t *testing.T) {
+	dir := t.TempDir()
+	_, network, _ := net.ParseCIDR("1.2.3.0/24")
+	cityPath := filepath.Join(dir, "city.mmdb")
+	require.NoError(t, os.WriteFile(cityPath, buildMMDB(6, network, map[string]interface{}{
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": "Frankfurt am Main"}},
+		"continent":    map[string]interface{}{"code": "EU", "names": map[string]interface{}{"en": "Europe"}},
+		"country":      map[string]interface{}{"iso_code": "DE", "names": map[string]interface{}{"en": "Germany"}},
+		"subdivisions": []interface{}{map[string]interface{}{"iso_code": "HE", "names": map[string]interface{}{"en": "Hesse"}}},
+	}), 0644))
+	asnPath := filepath.Join(dir, "asn.mmdb")
+	require.NoError(t, os.WriteFile(asnPath, buildMMDB(4, network, map[string]interface{}{
+		"autonomous_system_number":       uint32(3320),
+		"autonomous_system_organization": "Deutsche Telekom AG",
+	}), 0644))
+
+	provider, err := NewMMDBProvider(cityPath, asnPath)
+	require.NoError(t, err)
+	ipInfo, err := provider.Locate("1.2.3.4")
+	require.NoError(t, err)
+	require.Equal(t, "Germany", ipInfo.Country)
+	require.Equal(t, "Hesse", ipInfo.RegionName)
+	require.Equal(t, "Frankfurt am Main", ipInfo.City)
+	require.Equal(t, "AS3320 Deutsche Telekom AG", ipInfo.As)
+	require.Equal(t, 3320, ipInfo.ASN)
+	require.Equal(t, ResidentialIpClass, ipInfo.IpClass)
+
+	_, err = provider.Locate("8.8.8.8")
+	require.Equal(t, ErrMMDBNotFound, err)
+}
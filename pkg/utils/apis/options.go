@@ -0,0 +1,44 @@
+package apis
+
+import (
+	"time"
+)
+
+type IpLocatorOption func(*IpLocator)
+
+// WithIpProvider locates the IPs with the given provider before calling the HTTP API
+func WithIpProvider(provider IpProvider) IpLocatorOption {
+	return func(c *IpLocator) {
+		c.provider = provider
+	}
+}
+
+// WithIpTTL sets how long the located IPs are kept in the cache before locating them again
+func WithIpTTL(ttl time.Duration) IpLocatorOption {
+	return func(c *IpLocator) {
+		if ttl > 0 {
+			c.ttl = ttl
+		}
+	}
+}
+
+// WithIpRefreshInterval sets how often the expired IPs of the cache are located again (disabled if 0)
+func WithIpRefreshInterval(interval time.Duration) IpLocatorOption {
+	return func(c *IpLocator) {
+		c.refreshInterval = interval
+	}
+}
+
+// WithCloudTagger tags the located IPs with their reverse DNS name and cloud provider before caching them
+func WithCloudTagger(tagger *CloudTagger) IpLocatorOption {
+	return func(c *IpLocator) {
+		c.tagger = tagger
+	}
+}
+
+// WithAnonymizerDetector flags the located IPs that belong to Tor, VPNs or proxies before caching them
+func WithAnonymizerDetector(detector *AnonymizerDetector) IpLocatorOption {
+	return func(c *IpLocator) {
+		c.anonymizers = detector
+	}
+}
@@ -0,0 +1,102 @@
+package apis
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/migalabs/armiarma/pkg/db/models"
+)
+
+// IpProvider locates IPs without the rate limits of the HTTP API (i.e. an offline database).
+// It returns ErrMMDBNotFound if it doesn't know the IP, so that the locator falls back to the API
+type IpProvider interface {
+	Locate(ip string) (models.IpInfo, error)
+}
+
+// MMDBProvider locates the IPs with the GeoLite2 City database, enriched with the GeoLite2 ASN one if given
+type MMDBProvider struct {
+	city *MMDBReader
+	asn  *MMDBReader
+}
+
+// NewMMDBProvider opens the given GeoLite2 City and (optional) ASN databases
+func NewMMDBProvider(cityPath, asnPath string) (*MMDBProvider, error) {
+	city, err := OpenMMDB(cityPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open city mmdb")
+	}
+	provider := &MMDBProvider{city: city}
+	if asnPath != "" {
+		provider.asn, err = OpenMMDB(asnPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open asn mmdb")
+		}
+	}
+	return provider, nil
+}
+
+// Locate composes the IpInfo from the records of the databases. GeoLite2 doesn't tell the hosting or mobile
+// networks apart, so the IPs located offline are classified as residential
+func (p *MMDBProvider) Locate(ip string) (models.IpInfo, error) {
+	var ipInfo models.IpInfo
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ipInfo, errors.Errorf("invalid ip %s", ip)
+	}
+	record, err := p.city.Lookup(parsed)
+	if err != nil {
+		return ipInfo, err
+	}
+	str := func(path ...interface{}) string {
+		s, _ := mmdbPath(record, path...).(string)
+		return s
+	}
+	num := func(path ...interface{}) float64 {
+		f, _ := mmdbPath(record, path...).(float64)
+		return f
+	}
+	msg := models.IpApiMsg{
+		IP:            ip,
+		Status:        "success",
+		Continent:     str("continent", "names", "en"),
+		ContinentCode: str("continent", "code"),
+		Country:       str("country", "names", "en"),
+		CountryCode:   str("country", "iso_code"),
+		Region:        str("subdivisions", 0, "iso_code"),
+		RegionName:    str("subdivisions", 0, "names", "en"),
+		City:          str("city", "names", "en"),
+		Zip:           str("postal", "code"),
+		Lat:           num("location", "latitude"),
+		Lon:           num("location", "longitude"),
+	}
+	if msg.IsEmpty() {
+		return ipInfo, ErrMMDBNotFound
+	}
+
+	if p.asn != nil {
+		asnRecord, err := p.asn.Lookup(parsed)
+		if err != nil && err != ErrMMDBNotFound {
+			return ipInfo, err
+		}
+		if asnRecord != nil {
+			asName, _ := asnRecord["autonomous_system_organization"].(string)
+			if asn := mmdbUint(asnRecord["autonomous_system_number"]); asn > 0 {
+				msg.As = strings.TrimSpace(fmt.Sprintf("AS%d %s", asn, asName))
+			}
+			msg.AsName = asName
+			msg.Org = asName
+			msg.Isp = asName
+		}
+	}
+
+	ipInfo.IpApiMsg = msg
+	ipInfo.FetchedAt = time.Now().UTC()
+	ipInfo.ExpirationTime = ipInfo.FetchedAt.Add(defaultIpTTL)
+	ipInfo.ASN = ParseASN(msg.As)
+	ipInfo.IpClass = ClassifyIp(msg)
+	return ipInfo, nil
+}
@@ -101,3 +101,12 @@ func ExistsInMapValue(inputMap map[string]string, inputValue string) bool {
 func BytesFromString(s string) []byte {
 	return []byte(s)
 }
+
+// ResolveSeed returns the given seed, or a new one if it is 0,
+// so that the random choices of any run can be replayed afterwards
+func ResolveSeed(seed int64) int64 {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return seed
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ClientVersion is the semver representation of the version advertised by a client
+// Examples of the raw versions that it can parse:
+// v21.7.0+9-g77b4b9e  ->  21.7.0 (commit 77b4b9e)
+// v1.5.1-b0ac346      ->  1.5.1 (commit b0ac346)
+// v1.3.8-hotfix+6c0942 -> 1.3.8 (commit 6c0942)
+// 1.13.0+mainnet+git.7a55e8e8 -> 1.13.0 (commit 7a55e8e8)
+type ClientVersion struct {
+	Major  int
+	Minor  int
+	Patch  int
+	Commit string
+	// Whether the version could be parsed into major/minor/patch
+	Valid bool
+}
+
+// ParseVersion splits a raw version string into its semver components
+func ParseVersion(rawVersion string) ClientVersion {
+	cliVersion := ClientVersion{}
+	if rawVersion == "" || rawVersion == Unknown {
+		return cliVersion
+	}
+
+	// split the core version from the pre-release and build metadata
+	core := strings.TrimPrefix(strings.TrimPrefix(rawVersion, "v"), "V")
+	var suffix string
+	if idx := strings.IndexAny(core, "-+"); idx >= 0 {
+		suffix = core[idx+1:]
+		core = core[:idx]
+	}
+
+	numbers := strings.Split(core, ".")
+	if len(numbers) == 0 || len(numbers) > 3 {
+		return cliVersion
+	}
+	components := make([]int, 3)
+	for i, number := range numbers {
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 0 {
+			return cliVersion
+		}
+		components[i] = n
+	}
+	cliVersion.Major = components[0]
+	cliVersion.Minor = components[1]
+	cliVersion.Patch = components[2]
+	cliVersion.Valid = true
+
+	// look for the commit in the pre-release and build metadata chunks
+	for _, chunk := range strings.FieldsFunc(suffix, func(r rune) bool { return r == '-' || r == '+' }) {
+		chunk = strings.TrimPrefix(chunk, "git.")
+		// git describe format (e.g. g77b4b9e)
+		if strings.HasPrefix(chunk, "g") && IsCommitHash(chunk[1:]) {
+			chunk = chunk[1:]
+		}
+		if IsCommitHash(chunk) {
+			cliVersion.Commit = chunk
+			break
+		}
+	}
+	return cliVersion
+}
+
+// Compare returns -1, 0, or 1 if the version is lower, equal, or higher than the given one
+// (the commit is not taken into account)
+func (v ClientVersion) Compare(o ClientVersion) int {
+	for _, diff := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if diff < 0 {
+			return -1
+		} else if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// IsAtLeast checks whether the version is equal or higher than the given major.minor.patch
+func (v ClientVersion) IsAtLeast(major, minor, patch int) bool {
+	if !v.Valid {
+		return false
+	}
+	return v.Compare(ClientVersion{Major: major, Minor: minor, Patch: patch, Valid: true}) >= 0
+}
+
+func (v ClientVersion) String() string {
+	if !v.Valid {
+		return Unknown
+	}
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsCommitHash checks if the given string looks like a (short or full) git commit hash
+func IsCommitHash(s string) bool {
+	if len(s) < 6 || len(s) > 40 {
+		return false
+	}
+	var hasLetter bool
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+		case r >= 'a' && r <= 'f':
+			hasLetter = true
+		default:
+			return false
+		}
+	}
+	// avoid confusing plain numbers with commits
+	return hasLetter
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CompareClientVersion(t *testing.T) {
+	v5 := ParseVersion("v5.1.3")
+	require.True(t, v5.IsAtLeast(5, 0, 0))
+	require.True(t, v5.IsAtLeast(5, 1, 3))
+	require.False(t, v5.IsAtLeast(5, 2, 0))
+	require.Equal(t, -1, ParseVersion("v4.6.0").Compare(v5))
+	require.Equal(t, 1, ParseVersion("v5.1.10").Compare(v5))
+	require.Equal(t, 0, ParseVersion("v5.1.3-abcdef1").Compare(v5))
+	require.False(t, ParseVersion(Unknown).IsAtLeast(0, 0, 0))
+	require.Equal(t, "v5.1.3", v5.String())
+}
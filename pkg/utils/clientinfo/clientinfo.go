@@ -0,0 +1,131 @@
+package clientinfo
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// ClientInfo summarizes everything that can be extracted from the UserAgent of a peer
+type ClientInfo struct {
+	Name    utils.ClientName
+	Version string // cleaned version (e.g. "v21.7.0" for "v21.7.0+9-g77b4b9e")
+	SemVer  utils.ClientVersion
+	OS      utils.ClientOS
+	Arch    utils.ClientArch
+}
+
+// Fingerprint describes how a client identifies itself in the UserAgent
+type Fingerprint struct {
+	Client utils.ClientName
+	// Aliases are matched (case-insensitive) against the first "/" chunk of the UserAgent
+	Aliases []string
+	// Version returns the raw version of the UserAgent or utils.Unknown,
+	// if nil, the first chunk that looks like a version is used
+	Version func(userAgent string) string
+}
+
+var (
+	mu           sync.RWMutex
+	fingerprints = map[utils.NetworkType][]Fingerprint{
+		utils.EthereumNetwork:   ethFingerprints,
+		utils.EthereumELNetwork: elFingerprints,
+		utils.PortalNetwork:     portalFingerprints,
+		utils.IpfsNetwork:       ipfsFingerprints,
+		utils.FilecoinNetwork:   filecoinFingerprints,
+		utils.WakuNetwork:       wakuFingerprints,
+	}
+)
+
+// Register adds a fingerprint for the given network, taking precedence over the
+// existing ones (allows overriding the default rules or adding new clients)
+func Register(network utils.NetworkType, fp Fingerprint) {
+	mu.Lock()
+	defer mu.Unlock()
+	fingerprints[network] = append([]Fingerprint{fp}, fingerprints[network]...)
+}
+
+// Parse returns the client name, version, OS and architecture advertised in the UserAgent
+// Examples:
+// Teku: teku/teku/v21.8.2/linux-x86_64/corretto-java-16
+// Prysm: Prysm/v1.4.3/8bca66ac6408a03af52d65541f58384007ed50ef
+// Lighthouse: Lighthouse/v1.5.1-b0ac346/x86_64-linux
+// Nimbus: nimbus
+// go-ipfs: go-ipfs/0.8.0/48f94e2
+// lotus: lotus-1.13.0+mainnet+git.7a55e8e8
+func Parse(network utils.NetworkType, userAgent string) ClientInfo {
+	info := ClientInfo{
+		Name:    utils.ClientName(utils.Unknown),
+		Version: utils.Unknown,
+		OS:      utils.ClientOSParser(utils.ValidOs, userAgent),
+		Arch:    utils.ClientArchParser(utils.ValidArchs, userAgent),
+	}
+
+	fp, ok := matchFingerprint(network, strings.Split(userAgent, "/")[0])
+	if !ok {
+		log.Errorf("unable to determine client name for UserAgent %s", userAgent)
+		return info
+	}
+	info.Name = fp.Client
+
+	versionFn := fp.Version
+	if versionFn == nil {
+		versionFn = firstVersionChunk
+	}
+	rawVersion := versionFn(userAgent)
+	if rawVersion == utils.Unknown {
+		return info
+	}
+	info.Version = cleanVersion(rawVersion)
+	info.SemVer = parseSemVer(userAgent, rawVersion)
+	return info
+}
+
+// ClientName returns the client advertised in the UserAgent (utils.Unknown if no fingerprint matches),
+// without parsing the rest of it nor complaining about the unknown ones, for the periodic tallies
+func ClientName(network utils.NetworkType, userAgent string) utils.ClientName {
+	fp, ok := matchFingerprint(network, strings.Split(userAgent, "/")[0])
+	if !ok {
+		return utils.ClientName(utils.Unknown)
+	}
+	return fp.Client
+}
+
+func matchFingerprint(network utils.NetworkType, name string) (Fingerprint, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	name = strings.ToLower(name)
+	for _, fp := range fingerprints[network] {
+		for _, alias := range fp.Aliases {
+			if strings.Contains(name, strings.ToLower(alias)) {
+				return fp, true
+			}
+		}
+	}
+	return Fingerprint{}, false
+}
+
+// parseSemVer returns the semver components of the raw version
+func parseSemVer(userAgent, rawVersion string) utils.ClientVersion {
+	semVer := utils.ParseVersion(rawVersion)
+
+	// some clients add the full commit right after the version (e.g. Prysm/v1.4.3/8bca66ac...)
+	if semVer.Valid && semVer.Commit == "" {
+		splUserAgent := strings.Split(userAgent, "/")
+		for i, chunk := range splUserAgent {
+			if chunk == rawVersion && i+1 < len(splUserAgent) && utils.IsCommitHash(splUserAgent[i+1]) {
+				semVer.Commit = splUserAgent[i+1]
+				break
+			}
+		}
+	}
+	return semVer
+}
+
+func cleanVersion(version string) string {
+	cleaned := strings.Split(version, "+")[0]
+	cleaned = strings.Split(cleaned, "-")[0]
+	return cleaned
+}
@@ -0,0 +1,363 @@
+package clientinfo
+
+import (
+	"testing"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+type clientInfoTest struct {
+	network       utils.NetworkType
+	userAgent     string
+	clientName    string
+	clientVersion string
+	clientOS      string
+	clientArch    string
+}
+
+var Eth2TestClients []clientInfoTest = []clientInfoTest{
+	{
+		userAgent:     "teku/teku/v21.8.2/linux-x86_64/corretto-java-16",
+		clientName:    "teku",
+		clientVersion: "v21.8.2",
+		clientOS:      "linux",
+		clientArch:    "x86_64",
+	},
+	{
+		userAgent:     "teku/teku/v21.7.0+9-g77b4b9e/linux-x86_64/-ubuntu-openjdk64bitservervm-java-11",
+		clientName:    "teku",
+		clientVersion: "v21.7.0",
+		clientOS:      "linux",
+		clientArch:    "x86_64",
+	},
+	{
+		userAgent:     "Prysm/v1.4.3/8bca66ac6408a03af52d65541f58384007ed50ef",
+		clientName:    "prysm",
+		clientVersion: "v1.4.3",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "Prysm/v1.3.8-hotfix+6c0942/6c09424feb3141b96016bed817d7ade1cd75deb7",
+		clientName:    "prysm",
+		clientVersion: "v1.3.8",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "Lighthouse/v1.5.1-b0ac346/x86_64-linux",
+		clientName:    "lighthouse",
+		clientVersion: "v1.5.1",
+		clientOS:      "linux",
+		clientArch:    "x86_64",
+	},
+	{
+		userAgent:     "Lighthouse/v3.1.2/aarch64-macos",
+		clientName:    "lighthouse",
+		clientVersion: "v3.1.2",
+		clientOS:      "mac",
+		clientArch:    "arm",
+	},
+	{
+		userAgent:     "Lighthouse/v2.5.1-df51a73/aarch64-linux",
+		clientName:    "lighthouse",
+		clientVersion: "v2.5.1",
+		clientOS:      "linux",
+		clientArch:    "arm",
+	},
+	{
+		userAgent:     "nimbus",
+		clientName:    "nimbus",
+		clientVersion: "unknown",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "rust-libp2p/0.36.1",
+		clientName:    "grandine",
+		clientVersion: "0.36.1",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "js-libp2p/0.36.2",
+		clientName:    "lodestar",
+		clientVersion: "0.36.2",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "lodestar/v1.2.0",
+		clientName:    "lodestar",
+		clientVersion: "v1.2.0",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "nim-libp2p/0.0.1",
+		clientName:    "nimbus",
+		clientVersion: "0.0.1",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "erigon/lightclient",
+		clientName:    "erigon",
+		clientVersion: "unknown",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "erigon",
+		clientName:    "erigon",
+		clientVersion: "unknown",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "Lighthouse/v4.6.0-1be5253/x86_64-linux",
+		clientName:    "lighthouse",
+		clientVersion: "v4.6.0",
+		clientOS:      "linux",
+		clientArch:    "x86_64",
+	},
+	{
+		userAgent:     "teku/v24.1.1/linux-x86_64/-eclipseadoptium-openjdk64bitservervm-java-21",
+		clientName:    "teku",
+		clientVersion: "v24.1.1",
+		clientOS:      "linux",
+		clientArch:    "x86_64",
+	},
+	{
+		userAgent:     "lodestar/v1.15.0/2a9b8b0",
+		clientName:    "lodestar",
+		clientVersion: "v1.15.0",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "Grandine/0.4.0-a4e29bd/x86_64-linux",
+		clientName:    "grandine",
+		clientVersion: "0.4.0",
+		clientOS:      "linux",
+		clientArch:    "x86_64",
+	},
+	{
+		userAgent:     "erigon/v2.55.1/linux-amd64/go1.21.5",
+		clientName:    "erigon",
+		clientVersion: "v2.55.1",
+		clientOS:      "linux",
+		clientArch:    "x86_64",
+	},
+	{
+		userAgent:     "caplin",
+		clientName:    "erigon",
+		clientVersion: "unknown",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+	{
+		userAgent:     "unknown-client/v1.0.0",
+		clientName:    "unknown",
+		clientVersion: "unknown",
+		clientOS:      "unknown",
+		clientArch:    "unknown",
+	},
+}
+
+var ELTestClients []clientInfoTest = []clientInfoTest{
+	{userAgent: "Geth/v1.13.14-stable-2bd6bd01/linux-amd64/go1.21.7", clientName: "geth", clientVersion: "v1.13.14", clientOS: "linux", clientArch: "x86_64"},
+	{userAgent: "Nethermind/v1.25.4+20b10b35/linux-x64/dotnet8.0.2", clientName: "nethermind", clientVersion: "v1.25.4", clientOS: "linux", clientArch: "x86_64"},
+	{userAgent: "besu/v24.1.2/linux-x86_64/openjdk-java-17", clientName: "besu", clientVersion: "v24.1.2", clientOS: "linux", clientArch: "x86_64"},
+	{userAgent: "erigon/v2.58.1-stable-b0f1bb9b/linux-amd64/go1.21.5", clientName: "erigon", clientVersion: "v2.58.1", clientOS: "linux", clientArch: "x86_64"},
+	{userAgent: "reth/v0.2.0-beta.2-3b0cd4a9/x86_64-unknown-linux-gnu", clientName: "reth", clientVersion: "v0.2.0", clientOS: "linux", clientArch: "x86_64"},
+}
+
+var PortalTestClients []clientInfoTest = []clientInfoTest{
+	{userAgent: "trin/v0.1.1-2b00d730/linux-x86_64/rustc1.81.0", clientName: "trin", clientVersion: "v0.1.1", clientOS: "linux", clientArch: "x86_64"},
+	{userAgent: "fluffy/v0.1.0-8e2cd2b4/linux-amd64/nim2.0.8", clientName: "fluffy", clientVersion: "v0.1.0", clientOS: "linux", clientArch: "x86_64"},
+	{userAgent: "trin/0.1.1-2b00d730", clientName: "trin", clientVersion: "0.1.1", clientOS: "unknown", clientArch: "unknown"},
+}
+
+var IPFSTestClients []clientInfoTest = []clientInfoTest{
+	{userAgent: "go-ipfs/0.8.0/48f94e2", clientName: "go-ipfs", clientVersion: "0.8.0"},
+	{userAgent: "hydra-booster/0.7.4", clientName: "hydra-booster", clientVersion: "0.7.4"},
+	{userAgent: "storm", clientName: "storm", clientVersion: "unknown"},
+	{userAgent: "kubo/0.15.0-dev/", clientName: "kubo", clientVersion: "0.15.0"},
+	{userAgent: "kubo/0.26.0/", clientName: "kubo", clientVersion: "0.26.0"},
+	{userAgent: "ioi", clientName: "ioi", clientVersion: "unknown"},
+	{userAgent: "punchr/honeypot/dev+", clientName: "punchr", clientVersion: "unknown"},
+}
+
+var FilecoinTestClients []clientInfoTest = []clientInfoTest{
+	{userAgent: "lotus-1.13.0+mainnet+git.7a55e8e8", clientName: "lotus", clientVersion: "1.13.0"},
+	{userAgent: "lotus-1.25.2+mainnet+git.6f4a4f2a9", clientName: "lotus", clientVersion: "1.25.2"},
+	{userAgent: "lotus", clientName: "lotus", clientVersion: "unknown"},
+}
+
+var WakuTestClients []clientInfoTest = []clientInfoTest{
+	{userAgent: "nwaku", clientName: "nwaku", clientVersion: "unknown"},
+	{userAgent: "go-waku/v0.8.1", clientName: "go-waku", clientVersion: "v0.8.1"},
+	{userAgent: "js-waku", clientName: "js-waku", clientVersion: "unknown"},
+}
+
+func Test_FilterClientType(t *testing.T) {
+	for _, cliInf := range Eth2TestClients {
+		info := Parse(utils.EthereumNetwork, cliInf.userAgent)
+		require.Equal(t, cliInf.clientName, string(info.Name), cliInf.userAgent)
+		require.Equal(t, cliInf.clientVersion, info.Version, cliInf.userAgent)
+		require.Equal(t, cliInf.clientOS, string(info.OS), cliInf.userAgent)
+		require.Equal(t, cliInf.clientArch, string(info.Arch), cliInf.userAgent)
+	}
+	for _, cliInf := range ELTestClients {
+		info := Parse(utils.EthereumELNetwork, cliInf.userAgent)
+		require.Equal(t, cliInf.clientName, string(info.Name), cliInf.userAgent)
+		require.Equal(t, cliInf.clientVersion, info.Version, cliInf.userAgent)
+		require.Equal(t, cliInf.clientOS, string(info.OS), cliInf.userAgent)
+		require.Equal(t, cliInf.clientArch, string(info.Arch), cliInf.userAgent)
+	}
+	for _, cliInf := range PortalTestClients {
+		info := Parse(utils.PortalNetwork, cliInf.userAgent)
+		require.Equal(t, cliInf.clientName, string(info.Name), cliInf.userAgent)
+		require.Equal(t, cliInf.clientVersion, info.Version, cliInf.userAgent)
+		require.Equal(t, cliInf.clientOS, string(info.OS), cliInf.userAgent)
+		require.Equal(t, cliInf.clientArch, string(info.Arch), cliInf.userAgent)
+	}
+	for _, cliInf := range IPFSTestClients {
+		info := Parse(utils.IpfsNetwork, cliInf.userAgent)
+		require.Equal(t, cliInf.clientName, string(info.Name), cliInf.userAgent)
+		require.Equal(t, cliInf.clientVersion, info.Version, cliInf.userAgent)
+	}
+	for _, cliInf := range FilecoinTestClients {
+		info := Parse(utils.FilecoinNetwork, cliInf.userAgent)
+		require.Equal(t, cliInf.clientName, string(info.Name), cliInf.userAgent)
+		require.Equal(t, cliInf.clientVersion, info.Version, cliInf.userAgent)
+	}
+	for _, cliInf := range WakuTestClients {
+		info := Parse(utils.WakuNetwork, cliInf.userAgent)
+		require.Equal(t, cliInf.clientName, string(info.Name), cliInf.userAgent)
+		require.Equal(t, cliInf.clientVersion, info.Version, cliInf.userAgent)
+	}
+}
+
+var PlatformTestClients []clientInfoTest = []clientInfoTest{
+	{
+		userAgent:  "Lighthouse/v4.5.0-441fc16/x86_64-darwin",
+		clientOS:   "mac",
+		clientArch: "x86_64",
+	},
+	{
+		userAgent:  "Lighthouse/v4.5.0-441fc16/x86_64-windows",
+		clientOS:   "windows",
+		clientArch: "x86_64",
+	},
+	{
+		userAgent:  "erigon/v2.48.1/linux-amd64/go1.20.5",
+		clientOS:   "linux",
+		clientArch: "x86_64",
+	},
+	{
+		userAgent:  "lodestar/v1.11.3/linux-arm64/nodejs",
+		clientOS:   "linux",
+		clientArch: "arm",
+	},
+	{
+		userAgent:  "teku/v23.10.0/freebsd-x86_64/-eclipseadoptium-openjdk64bitservervm-java-17",
+		clientOS:   "freebsd",
+		clientArch: "x86_64",
+	},
+	{
+		userAgent:  "Prysm/v4.1.1/2a0d3d7c1e27e3c84d1b74c7f7a3e5ce6d4fa4e7",
+		clientOS:   "unknown",
+		clientArch: "unknown",
+	},
+}
+
+func Test_ClientPlatform(t *testing.T) {
+	for _, cliInf := range PlatformTestClients {
+		info := Parse(utils.EthereumNetwork, cliInf.userAgent)
+		require.Equal(t, cliInf.clientOS, string(info.OS), cliInf.userAgent)
+		require.Equal(t, cliInf.clientArch, string(info.Arch), cliInf.userAgent)
+	}
+}
+
+type clientVersionTest struct {
+	userAgent string
+	network   utils.NetworkType
+	version   utils.ClientVersion
+}
+
+var ClientVersionTests []clientVersionTest = []clientVersionTest{
+	{
+		userAgent: "teku/teku/v21.7.0+9-g77b4b9e/linux-x86_64/-ubuntu-openjdk64bitservervm-java-11",
+		network:   utils.EthereumNetwork,
+		version:   utils.ClientVersion{Major: 21, Minor: 7, Patch: 0, Commit: "77b4b9e", Valid: true},
+	},
+	{
+		userAgent: "Prysm/v1.4.3/8bca66ac6408a03af52d65541f58384007ed50ef",
+		network:   utils.EthereumNetwork,
+		version:   utils.ClientVersion{Major: 1, Minor: 4, Patch: 3, Commit: "8bca66ac6408a03af52d65541f58384007ed50ef", Valid: true},
+	},
+	{
+		userAgent: "Prysm/v1.3.8-hotfix+6c0942/6c09424feb3141b96016bed817d7ade1cd75deb7",
+		network:   utils.EthereumNetwork,
+		version:   utils.ClientVersion{Major: 1, Minor: 3, Patch: 8, Commit: "6c0942", Valid: true},
+	},
+	{
+		userAgent: "Lighthouse/v1.5.1-b0ac346/x86_64-linux",
+		network:   utils.EthereumNetwork,
+		version:   utils.ClientVersion{Major: 1, Minor: 5, Patch: 1, Commit: "b0ac346", Valid: true},
+	},
+	{
+		userAgent: "lodestar/v1.2.0",
+		network:   utils.EthereumNetwork,
+		version:   utils.ClientVersion{Major: 1, Minor: 2, Patch: 0, Valid: true},
+	},
+	{
+		userAgent: "nimbus",
+		network:   utils.EthereumNetwork,
+		version:   utils.ClientVersion{},
+	},
+	{
+		userAgent: "kubo/0.15.0-dev/",
+		network:   utils.IpfsNetwork,
+		version:   utils.ClientVersion{Major: 0, Minor: 15, Patch: 0, Valid: true},
+	},
+	{
+		userAgent: "lotus-1.13.0+mainnet+git.7a55e8e8",
+		network:   utils.FilecoinNetwork,
+		version:   utils.ClientVersion{Major: 1, Minor: 13, Patch: 0, Commit: "7a55e8e8", Valid: true},
+	},
+}
+
+func Test_ParseClientVersion(t *testing.T) {
+	for _, test := range ClientVersionTests {
+		info := Parse(test.network, test.userAgent)
+		require.Equal(t, test.version, info.SemVer, test.userAgent)
+	}
+}
+
+func Test_RegisterFingerprint(t *testing.T) {
+	// custom rules take precedence over the default ones
+	Register(utils.EthereumNetwork, Fingerprint{
+		Client:  utils.ClientName("custom"),
+		Aliases: []string{"custom-cl"},
+	})
+	info := Parse(utils.EthereumNetwork, "custom-cl/v0.1.0/linux-arm64")
+	require.Equal(t, utils.ClientName("custom"), info.Name)
+	require.Equal(t, "v0.1.0", info.Version)
+	require.Equal(t, utils.Linux, info.OS)
+	require.Equal(t, utils.Arm, info.Arch)
+
+	// the default rules remain in place
+	info = Parse(utils.EthereumNetwork, "Prysm/v4.2.1/59b310a2216ab10d1f0f5ed0d9b7bbb34e1a3ac6")
+	require.Equal(t, utils.Prysm, info.Name)
+}
+
+func Test_ClientName(t *testing.T) {
+	for _, cliInf := range Eth2TestClients {
+		require.Equal(t, cliInf.clientName, string(ClientName(utils.EthereumNetwork, cliInf.userAgent)), cliInf.userAgent)
+	}
+	require.Equal(t, utils.ClientName(utils.Unknown), ClientName(utils.IpfsNetwork, "some-unknown-client/0.1.0"))
+}
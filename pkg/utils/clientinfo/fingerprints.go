@@ -0,0 +1,84 @@
+package clientinfo
+
+import (
+	"strings"
+
+	"github.com/migalabs/armiarma/pkg/utils"
+)
+
+// The order matters: the first fingerprint with a matching alias wins
+
+// Ethereum CL Clients
+var ethFingerprints = []Fingerprint{
+	{Client: utils.Prysm, Aliases: []string{"prysm"}},
+	{Client: utils.Lighthouse, Aliases: []string{"lighthouse"}},
+	{Client: utils.Teku, Aliases: []string{"teku"}},
+	{Client: utils.Nimbus, Aliases: []string{"nimbus", "nim-libp2p"}},
+	{Client: utils.Lodestar, Aliases: []string{"lodestar", "js-libp2p"}},
+	{Client: utils.Grandine, Aliases: []string{"grandine", "rust-libp2p"}},
+	{Client: utils.Erigon, Aliases: []string{"erigon", "caplin"}},
+	{Client: utils.Cortex, Aliases: []string{"cortex"}},
+	{Client: utils.Trinity, Aliases: []string{"trinity"}},
+}
+
+// Ethereum EL Clients
+var elFingerprints = []Fingerprint{
+	{Client: utils.Geth, Aliases: []string{"geth"}},
+	{Client: utils.Nethermind, Aliases: []string{"nethermind"}},
+	{Client: utils.Besu, Aliases: []string{"besu"}},
+	{Client: utils.Erigon, Aliases: []string{"erigon"}},
+	{Client: utils.Reth, Aliases: []string{"reth"}},
+}
+
+// Portal Network Clients
+var portalFingerprints = []Fingerprint{
+	{Client: utils.Trin, Aliases: []string{"trin"}},
+	{Client: utils.Fluffy, Aliases: []string{"fluffy", "nimbus"}},
+	{Client: utils.Ultralight, Aliases: []string{"ultralight"}},
+	{Client: utils.Shisui, Aliases: []string{"shisui"}},
+}
+
+// IPFS Clients
+var ipfsFingerprints = []Fingerprint{
+	{Client: utils.Kubo, Aliases: []string{"kubo"}},
+	{Client: utils.GoIpfs, Aliases: []string{"go-ipfs"}},
+	{Client: utils.HydraBooster, Aliases: []string{"hydra-booster"}},
+	{Client: utils.Storm, Aliases: []string{"storm"}},
+	{Client: utils.Ioi, Aliases: []string{"ioi"}},
+	{Client: utils.Punchr, Aliases: []string{"punchr"}},
+}
+
+// Filecoin Clients
+var filecoinFingerprints = []Fingerprint{
+	{Client: utils.Lotus, Aliases: []string{"lotus"}, Version: dashVersion},
+}
+
+// Waku Clients
+var wakuFingerprints = []Fingerprint{
+	{Client: utils.Nwaku, Aliases: []string{"nwaku", "nim-waku"}},
+	{Client: utils.GoWaku, Aliases: []string{"go-waku", "status-go"}},
+	{Client: utils.JsWaku, Aliases: []string{"js-waku"}},
+}
+
+// firstVersionChunk returns the first "/" chunk after the client name that looks like a
+// version (e.g. "v21.8.2" for both "teku/teku/v21.8.2/..." and "teku/v21.8.2/...")
+func firstVersionChunk(userAgent string) string {
+	chunks := strings.Split(userAgent, "/")
+	for _, chunk := range chunks[1:] {
+		v := strings.TrimPrefix(strings.ToLower(chunk), "v")
+		if len(v) > 0 && v[0] >= '0' && v[0] <= '9' {
+			return chunk
+		}
+	}
+	return utils.Unknown
+}
+
+// dashVersion returns everything after the client name (e.g. "1.13.0+mainnet+git.7a55e8e8")
+func dashVersion(userAgent string) string {
+	name := strings.Split(userAgent, "/")[0]
+	idx := strings.Index(name, "-")
+	if idx < 0 || idx == len(name)-1 {
+		return utils.Unknown
+	}
+	return name[idx+1:]
+}
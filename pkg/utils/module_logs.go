@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+/**
+The crawler logs through the standard logrus logger, so the level of each module
+can't be set on a logger of its own. Instead, the caller of each entry is reported,
+and the entries of a module are filtered by the level of the module (the package
+of the caller) before being formatted. The level of the logger is kept at the most
+verbose of the levels, so that debugging a single module doesn't require to write
+the debug logs of all the others.
+*/
+
+var (
+	// path to check and modify the log levels at runtime
+	LogLevelPath = "/log-level"
+
+	// name of the module that refers to the global log level
+	GlobalLogModule = "global"
+
+	// packages that belong to each module of the crawler
+	LogModules = map[string]string{
+		"host":      "/pkg/hosts/",
+		"discovery": "/pkg/discovery/",
+		"gossip":    "/pkg/gossipsub/",
+		"db":        "/pkg/db/",
+		"peering":   "/pkg/peering/",
+	}
+)
+
+// ModuleLevels filters the entries of a logger with the level of the module that logs them
+type ModuleLevels struct {
+	m      sync.RWMutex
+	logger *logrus.Logger
+	inner  logrus.Formatter
+
+	global logrus.Level
+	levels map[string]logrus.Level
+}
+
+// NewModuleLevels installs the per-module levels in the given logger
+func NewModuleLevels(logger *logrus.Logger, global logrus.Level, levels map[string]logrus.Level) *ModuleLevels {
+	l := &ModuleLevels{
+		logger: logger,
+		inner:  logger.Formatter,
+		global: global,
+		levels: make(map[string]logrus.Level),
+	}
+	for module, lvl := range levels {
+		l.levels[module] = lvl
+	}
+	logger.SetReportCaller(true)
+	logger.SetFormatter(l)
+	l.updateLoggerLevel()
+	return l
+}
+
+// ParseModuleLevels parses the levels of the modules from a "host=debug,db=warn" like string
+func ParseModuleLevels(raw string) (map[string]logrus.Level, error) {
+	levels := make(map[string]logrus.Level)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return levels, errors.Errorf("invalid module log level %s", item)
+		}
+		module := strings.TrimSpace(kv[0])
+		if _, ok := LogModules[module]; !ok {
+			return levels, errors.Errorf("unknown log module %s", module)
+		}
+		lvl, err := logrus.ParseLevel(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return levels, errors.Wrapf(err, "invalid log level for module %s", module)
+		}
+		levels[module] = lvl
+	}
+	return levels, nil
+}
+
+// SetLevel changes the level of the given module (or the global one)
+func (l *ModuleLevels) SetLevel(module string, lvl logrus.Level) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if module == GlobalLogModule {
+		l.global = lvl
+	} else {
+		if _, ok := LogModules[module]; !ok {
+			return errors.Errorf("unknown log module %s", module)
+		}
+		l.levels[module] = lvl
+	}
+	l.updateLoggerLevel()
+	return nil
+}
+
+// SetModuleLevels replaces the levels of the modules, the ones that aren't given fall back to the global one
+func (l *ModuleLevels) SetModuleLevels(levels map[string]logrus.Level) error {
+	for module := range levels {
+		if _, ok := LogModules[module]; !ok {
+			return errors.Errorf("unknown log module %s", module)
+		}
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.levels = make(map[string]logrus.Level)
+	for module, lvl := range levels {
+		l.levels[module] = lvl
+	}
+	l.updateLoggerLevel()
+	return nil
+}
+
+// Levels returns the current levels of the modules, including the global one
+func (l *ModuleLevels) Levels() map[string]string {
+	l.m.RLock()
+	defer l.m.RUnlock()
+	levels := map[string]string{GlobalLogModule: l.global.String()}
+	for module := range LogModules {
+		levels[module] = l.moduleLevel(module).String()
+	}
+	return levels
+}
+
+// Format drops the entries over the level of their module, and formats the rest with the original formatter
+func (l *ModuleLevels) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Caller != nil {
+		module := callerModule(entry.Caller.File)
+		l.m.RLock()
+		lvl := l.moduleLevel(module)
+		l.m.RUnlock()
+		if entry.Level > lvl {
+			return nil, nil
+		}
+		// the caller is only needed for the filtering, keep the original output
+		trimmed := *entry
+		trimmed.Caller = nil
+		entry = &trimmed
+	}
+	return l.inner.Format(entry)
+}
+
+// Handler returns the levels of the modules on GET, and changes the level of a module
+// on PUT/POST with the "module" and "level" query parameters
+func (l *ModuleLevels) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			module := r.URL.Query().Get("module")
+			if module == "" {
+				module = GlobalLogModule
+			}
+			lvl, err := logrus.ParseLevel(r.URL.Query().Get("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := l.SetLevel(module, lvl); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logrus.WithFields(logrus.Fields{
+				"module": module,
+				"level":  lvl.String(),
+			}).Info("log level updated")
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(l.Levels()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// moduleLevel returns the level of the module, the global one if it doesn't have its own (needs the lock)
+func (l *ModuleLevels) moduleLevel(module string) logrus.Level {
+	if lvl, ok := l.levels[module]; ok {
+		return lvl
+	}
+	return l.global
+}
+
+// updateLoggerLevel sets the logger to the most verbose of the levels (needs the lock)
+func (l *ModuleLevels) updateLoggerLevel() {
+	lvl := l.global
+	for _, modLvl := range l.levels {
+		if modLvl > lvl {
+			lvl = modLvl
+		}
+	}
+	l.logger.SetLevel(lvl)
+}
+
+// callerModule returns the module of the file that logged the entry, "" if it doesn't belong to any
+func callerModule(file string) string {
+	for module, pkg := range LogModules {
+		if strings.Contains(file, pkg) {
+			return module
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ModuleLevels(t *testing.T) {
+	levels, err := ParseModuleLevels("db=debug, host=warn")
+	require.NoError(t, err)
+	_, err = ParseModuleLevels("unknown=debug")
+	require.Error(t, err)
+	_, err = ParseModuleLevels("db=verbose")
+	require.Error(t, err)
+
+	logger := logrus.New()
+	logLevels := NewModuleLevels(logger, logrus.InfoLevel, levels)
+	// the logger has to let pass the most verbose of the levels
+	require.Equal(t, logrus.DebugLevel, logger.GetLevel())
+
+	entry := func(file string, lvl logrus.Level) *logrus.Entry {
+		e := logrus.NewEntry(logger)
+		e.Level = lvl
+		e.Message = "test"
+		e.Caller = &runtime.Frame{File: file}
+		return e
+	}
+	formatted := func(e *logrus.Entry) bool {
+		out, err := logLevels.Format(e)
+		require.NoError(t, err)
+		return len(out) > 0
+	}
+	require.True(t, formatted(entry("/armiarma/pkg/db/postgresql/peer_info.go", logrus.DebugLevel)))
+	require.False(t, formatted(entry("/armiarma/pkg/hosts/host.go", logrus.InfoLevel)))
+	require.True(t, formatted(entry("/armiarma/pkg/hosts/host.go", logrus.WarnLevel)))
+	require.False(t, formatted(entry("/armiarma/pkg/crawler/ethereum.go", logrus.DebugLevel)))
+	require.True(t, formatted(entry("/armiarma/pkg/crawler/ethereum.go", logrus.InfoLevel)))
+
+	// runtime adjustment
+	require.NoError(t, logLevels.SetLevel("host", logrus.TraceLevel))
+	require.Equal(t, logrus.TraceLevel, logger.GetLevel())
+	require.True(t, formatted(entry("/armiarma/pkg/hosts/host.go", logrus.DebugLevel)))
+	require.Error(t, logLevels.SetLevel("unknown", logrus.DebugLevel))
+	require.Equal(t, "trace", logLevels.Levels()["host"])
+	require.Equal(t, "info", logLevels.Levels()["gossip"])
+}
+
+func Test_SetModuleLevels(t *testing.T) {
+	logger := logrus.New()
+	logLevels := NewModuleLevels(logger, logrus.InfoLevel, map[string]logrus.Level{"db": logrus.DebugLevel})
+	require.Equal(t, logrus.DebugLevel, logger.GetLevel())
+
+	// the modules that aren't given fall back to the global level
+	require.NoError(t, logLevels.SetModuleLevels(map[string]logrus.Level{"host": logrus.WarnLevel}))
+	require.Equal(t, logrus.InfoLevel, logger.GetLevel())
+	require.Equal(t, "info", logLevels.Levels()["db"])
+	require.Equal(t, "warning", logLevels.Levels()["host"])
+
+	// unknown modules leave the levels untouched
+	require.Error(t, logLevels.SetModuleLevels(map[string]logrus.Level{"unknown": logrus.DebugLevel}))
+	require.Equal(t, "warning", logLevels.Levels()["host"])
+}
@@ -13,6 +13,13 @@ import (
 
 const MADDR_SEPARATOR string = "/"
 
+// IP families of the multiaddresses
+const (
+	IPv4AddrFamily    string = "ip4"
+	IPv6AddrFamily    string = "ip6"
+	UnknownAddrFamily string = "unknown"
+)
+
 var PrivateIPNetworks = []net.IPNet{
 	net.IPNet{
 		IP:   net.ParseIP("10.0.0.0"),
@@ -44,9 +51,39 @@ func IsIPPublic(ip net.IP) bool {
 			return false
 		}
 	}
+	// IPv6 unique-local and link-local ranges
+	if ip.To4() == nil && (ip.IsPrivate() || ip.IsLinkLocalUnicast()) {
+		return false
+	}
 	return true
 }
 
+// GetIPFamily returns the multiaddress protocol (ip4, ip6) of the given IP
+func GetIPFamily(ip net.IP) string {
+	switch {
+	case ip.To4() != nil:
+		return IPv4AddrFamily
+	case ip.To16() != nil:
+		return IPv6AddrFamily
+	default:
+		return UnknownAddrFamily
+	}
+}
+
+// GetAddrFamily returns the IP family (ip4, ip6) of the given multiaddress
+func GetAddrFamily(maddr ma.Multiaddr) string {
+	if maddr == nil {
+		return UnknownAddrFamily
+	}
+	if _, err := maddr.ValueForProtocol(ma.P_IP4); err == nil {
+		return IPv4AddrFamily
+	}
+	if _, err := maddr.ValueForProtocol(ma.P_IP6); err == nil {
+		return IPv6AddrFamily
+	}
+	return UnknownAddrFamily
+}
+
 func CompAddrInfo(pid string, maddrs []ma.Multiaddr) (peer.AddrInfo, error) {
 	peerid, err := peer.Decode(pid)
 	if err != nil {
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"net"
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetAddrFamily(t *testing.T) {
+	ip4Addr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/9000")
+	require.NoError(t, err)
+	ip6Addr, err := ma.NewMultiaddr("/ip6/2001:db8::1/tcp/9000")
+	require.NoError(t, err)
+	dnsAddr, err := ma.NewMultiaddr("/dns4/bootstrap.libp2p.io/tcp/4001")
+	require.NoError(t, err)
+
+	require.Equal(t, IPv4AddrFamily, GetAddrFamily(ip4Addr))
+	require.Equal(t, IPv6AddrFamily, GetAddrFamily(ip6Addr))
+	require.Equal(t, UnknownAddrFamily, GetAddrFamily(dnsAddr))
+	require.Equal(t, UnknownAddrFamily, GetAddrFamily(nil))
+}
+
+func Test_GetIPFamily(t *testing.T) {
+	require.Equal(t, IPv4AddrFamily, GetIPFamily(net.ParseIP("1.2.3.4")))
+	require.Equal(t, IPv4AddrFamily, GetIPFamily(net.ParseIP("::ffff:1.2.3.4")))
+	require.Equal(t, IPv6AddrFamily, GetIPFamily(net.ParseIP("2001:db8::1")))
+	require.Equal(t, UnknownAddrFamily, GetIPFamily(nil))
+}
+
+func Test_IsIPPublic(t *testing.T) {
+	require.True(t, IsIPPublic(net.ParseIP("1.2.3.4")))
+	require.False(t, IsIPPublic(net.ParseIP("192.168.1.10")))
+	require.True(t, IsIPPublic(net.ParseIP("2001:db8::1")))
+	require.False(t, IsIPPublic(net.ParseIP("::1")))
+	require.False(t, IsIPPublic(net.ParseIP("fe80::1")))
+	require.False(t, IsIPPublic(net.ParseIP("fd00::1")))
+}
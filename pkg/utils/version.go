@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"runtime/debug"
+)
+
+// GitVersion is the `git describe` of the source of the binary, set at build time through:
+// -ldflags "-X github.com/migalabs/armiarma/pkg/utils.GitVersion=<version>"
+var GitVersion = ""
+
+// BuildVersion returns the git version of the binary, falling back to the vcs revision
+// that go embeds in the builds from a git checkout
+func BuildVersion() string {
+	if GitVersion != "" {
+		return GitVersion
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return Unknown
+	}
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return Unknown
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}